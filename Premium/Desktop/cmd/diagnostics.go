@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/diagnostics"
+	"waddlebot-bridge/internal/gateway/client"
+	"waddlebot-bridge/internal/logger"
+)
+
+var diagnosticsCmd = &cobra.Command{
+	Use:   "diagnostics [file]",
+	Short: "Write a diagnostics bundle for a support ticket",
+	Long: `Write a zip file with the bridge's version and platform, its config with
+secrets redacted, recent logs, modules and OBS connection to attach to a
+support ticket. Modules and OBS are collected from the running bridge through
+the gateway when it is enabled.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runDiagnostics,
+}
+
+func runDiagnostics(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	_, linked := linkedAccount(cfg, quiet)
+	applyLinkedAccount(cfg, linked)
+
+	bundle := &diagnostics.Bundle{
+		Version:  version,
+		Config:   cfg,
+		LogFiles: logger.RecentFiles(filepath.Join(cfg.DataDir, "logs")),
+	}
+	if file := logger.DefaultFile(); file != "" {
+		bundle.LogFiles = append(bundle.LogFiles, file)
+	}
+	if gateway, err := client.FromConfig(cfg); err == nil {
+		bundle.Gateway = gateway
+	}
+
+	path := fmt.Sprintf("waddlebot-bridge-diagnostics-%s.zip", time.Now().Format("20060102-150405"))
+	if len(args) > 0 {
+		path = args[0]
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Fatalf("Failed to create diagnostics bundle: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := bundle.Write(ctx, file); err != nil {
+		file.Close()
+		os.Remove(path)
+		log.Fatalf("Failed to write diagnostics bundle: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatalf("Failed to write diagnostics bundle: %v", err)
+	}
+	fmt.Printf("Diagnostics written to %s\n", path)
+}
@@ -1,28 +1,78 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"waddlebot-bridge/internal/account"
+	"waddlebot-bridge/internal/apikeys"
 	"waddlebot-bridge/internal/auth"
+	"waddlebot-bridge/internal/backup"
 	"waddlebot-bridge/internal/bridge"
+	"waddlebot-bridge/internal/capabilities"
+	"waddlebot-bridge/internal/clips"
+	"waddlebot-bridge/internal/commands"
 	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/diagnostics"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/features"
 	"waddlebot-bridge/internal/gateway"
+	"waddlebot-bridge/internal/gateway/client"
+	"waddlebot-bridge/internal/gateway/handlers"
+	"waddlebot-bridge/internal/guardian"
+	"waddlebot-bridge/internal/keychain"
 	"waddlebot-bridge/internal/license"
+	"waddlebot-bridge/internal/localtls"
 	"waddlebot-bridge/internal/logger"
 	"waddlebot-bridge/internal/modules"
+	"waddlebot-bridge/internal/modules/builtin/fileops"
+	"waddlebot-bridge/internal/modules/builtin/hotkeys"
+	"waddlebot-bridge/internal/modules/builtin/httpreq"
+	"waddlebot-bridge/internal/modules/builtin/overlays"
+	"waddlebot-bridge/internal/modules/builtin/soundboard"
+	"waddlebot-bridge/internal/mqtt"
 	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/osc"
+	"waddlebot-bridge/internal/outbox"
+	"waddlebot-bridge/internal/policy"
 	"waddlebot-bridge/internal/poller"
+	"waddlebot-bridge/internal/power"
+	"waddlebot-bridge/internal/rtc"
+	"waddlebot-bridge/internal/rules"
+	"waddlebot-bridge/internal/schedules"
 	"waddlebot-bridge/internal/scripting"
+	"waddlebot-bridge/internal/secrets"
 	"waddlebot-bridge/internal/server"
+	"waddlebot-bridge/internal/server/dashboard"
+	"waddlebot-bridge/internal/shutdown"
 	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/supervisor"
+	"waddlebot-bridge/internal/telemetry"
+	"waddlebot-bridge/internal/textsources"
+	"waddlebot-bridge/internal/timers"
+	"waddlebot-bridge/internal/tracing"
+	"waddlebot-bridge/internal/transfer"
+	"waddlebot-bridge/internal/tray"
+	"waddlebot-bridge/internal/triggers"
+	"waddlebot-bridge/internal/update"
+	"waddlebot-bridge/internal/webhooks"
 )
 
 var (
@@ -38,6 +88,177 @@ var rootCmd = &cobra.Command{
 	Run:     runBridge,
 }
 
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Link the bridge to a WaddleBot account",
+	Long: `Link the bridge to a WaddleBot account by approving a code on another device.
+The account's tokens are kept in the OS keychain, and the bridge's user and
+community are taken from the account.`,
+	Args: cobra.NoArgs,
+	Run:  runLogin,
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Unlink the bridge from its WaddleBot account",
+	Args:  cobra.NoArgs,
+	Run:   runLogout,
+}
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Manage the bridge's storage",
+}
+
+var storageMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy all data from one storage backend to another",
+	Long: `Copy all data from one storage backend to another in the data directory,
+such as from bolt to sqlite. Stop the bridge first, then set storage-backend
+to the new backend.`,
+	Args: cobra.NoArgs,
+	Run:  runStorageMigrate,
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up the bridge's storage now",
+	Long: `Back up the bridge's storage to backup.dir, keeping the newest backup.retain
+backups. Stop the bridge first when using the bolt backend; the running bridge
+also takes backups every backup.interval.`,
+	Args: cobra.NoArgs,
+	Run:  runBackup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup>",
+	Short: "Restore the bridge's storage from a backup",
+	Long: `Restore the bridge's storage from a backup after checking its checksum and
+integrity. Stop the bridge first. The replaced database is kept with a
+.before-restore suffix.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runRestore,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Export the bridge to move it to another machine",
+	Long: `Export users, credentials, module settings, webhooks, secrets, the config
+file, scripts and the bridge's keys to an archive encrypted with a passphrase,
+taken from WADDLEBOT_EXPORT_PASSPHRASE or asked for. Stop the bridge first
+when using the bolt backend.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runExport,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import an exported bridge on a fresh install",
+	Long: `Import an archive made by export, keeping the bridge's identity. The bridge
+must not be running and must have no users yet unless --force is given.
+Linked accounts and device tokens are kept in the OS keychain and are not
+exported; run login again afterwards.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runImport,
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the bridge's configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration and list every problem found",
+	Long: `Check the configuration the bridge would start with, including ports, URLs,
+files and script interpreters, and list every problem found. Exits with status
+1 when there are errors the bridge cannot start with.`,
+	Args: cobra.NoArgs,
+	Run:  runConfigValidate,
+}
+
+var configEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "List the environment variables that set each setting",
+	Long: `List the environment variable for every setting that can be set from the
+environment, such as WADDLEBOT_GATEWAY_API_KEY for gateway.api-key. Lists
+take comma-separated values.`,
+	Args: cobra.NoArgs,
+	Run:  runConfigEnv,
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the running bridge",
+	Args:  cobra.NoArgs,
+	Run:   runStatus,
+}
+
+var obsCmd = &cobra.Command{
+	Use:   "obs",
+	Short: "Control OBS through the running bridge",
+}
+
+var obsSceneCmd = &cobra.Command{
+	Use:   "scene",
+	Short: "List and switch OBS scenes",
+}
+
+var obsSceneListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the OBS scenes",
+	Args:  cobra.NoArgs,
+	Run:   runOBSSceneList,
+}
+
+var obsSceneSwitchCmd = &cobra.Command{
+	Use:   "switch <name>",
+	Short: "Switch OBS to a scene",
+	Args:  cobra.ExactArgs(1),
+	Run:   runOBSSceneSwitch,
+}
+
+var scriptsCmd = &cobra.Command{
+	Use:   "scripts",
+	Short: "List and run scripts through the running bridge",
+}
+
+var scriptsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the scripts the bridge can run",
+	Args:  cobra.NoArgs,
+	Run:   runScriptsList,
+}
+
+var scriptsRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a script from the scripts directory",
+	Long: `Run a script from the scripts directory, such as intro.lua, and print its
+result. Parameters given with --param are passed to the script.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runScriptsRun,
+}
+
+var modulesCmd = &cobra.Command{
+	Use:   "modules",
+	Short: "Inspect the running bridge's modules",
+}
+
+var modulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the loaded modules and their actions",
+	Args:  cobra.NoArgs,
+	Run:   runModulesList,
+}
+
+var deviceTokenCmd = &cobra.Command{
+	Use:   "device-token",
+	Short: "Print the device token used to sign in without a browser",
+	Long:  `Print the device token kept in the OS keychain when auth-method is device. POST it to /auth/device to sign in.`,
+	Args:  cobra.NoArgs,
+	Run:   runDeviceToken,
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.waddlebot-bridge.yaml)")
@@ -47,6 +268,7 @@ func init() {
 	rootCmd.PersistentFlags().Int("poll-interval", 30, "Polling interval in seconds (minimum 5)")
 	rootCmd.PersistentFlags().String("log-level", "info", "Log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().String("data-dir", "", "Data directory for storage (default: $HOME/.waddlebot-bridge)")
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile to activate")
 	
 	viper.BindPFlag("api-url", rootCmd.PersistentFlags().Lookup("api-url"))
 	viper.BindPFlag("community-id", rootCmd.PersistentFlags().Lookup("community-id"))
@@ -54,6 +276,35 @@ func init() {
 	viper.BindPFlag("poll-interval", rootCmd.PersistentFlags().Lookup("poll-interval"))
 	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
 	viper.BindPFlag("data-dir", rootCmd.PersistentFlags().Lookup("data-dir"))
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+
+	loginCmd.Flags().String("community", "", "Community to bridge when the account administers several")
+	storageMigrateCmd.Flags().String("from", storage.BackendBolt, "Backend to copy from")
+	storageMigrateCmd.Flags().String("to", storage.BackendSQLite, "Backend to copy to")
+	storageCmd.AddCommand(storageMigrateCmd)
+	backupCmd.Flags().Bool("list", false, "List the backups instead of taking one")
+	importCmd.Flags().Bool("force", false, "Import into a bridge that already has users, replacing existing files")
+	configValidateCmd.Flags().Bool("json", false, "Print the problems as JSON")
+	configCmd.AddCommand(configValidateCmd, configEnvCmd)
+	obsSceneCmd.AddCommand(obsSceneListCmd, obsSceneSwitchCmd)
+	obsCmd.AddCommand(obsSceneCmd)
+	scriptsRunCmd.Flags().StringToString("param", nil, "Parameter passed to the script, as key=value (repeatable)")
+	scriptsCmd.AddCommand(scriptsListCmd, scriptsRunCmd)
+	modulesCmd.AddCommand(modulesListCmd)
+	for _, cmd := range []*cobra.Command{statusCmd, obsCmd, scriptsCmd, modulesCmd} {
+		cmd.PersistentFlags().String("gateway-url", "", "Gateway URL (default: from the gateway settings)")
+		cmd.PersistentFlags().String("api-key", "", "Gateway API key (default: gateway.api-key or the generated admin key)")
+		cmd.PersistentFlags().Bool("json", false, "Print the gateway's response as JSON")
+	}
+	rootCmd.AddCommand(statusCmd, obsCmd, scriptsCmd, modulesCmd)
+	serviceCmd.PersistentFlags().Bool("system", false, "Install a system service that starts at boot instead of a user service")
+	serviceCmd.AddCommand(serviceInstallCmd, serviceUninstallCmd, serviceStartCmd, serviceStopCmd, serviceStatusCmd, serviceRunCmd)
+	rootCmd.AddCommand(serviceCmd)
+	updateCmd.Flags().Bool("check", false, "Only report whether an update is available")
+	updateCmd.Flags().String("channel", "", "Release channel to update from, stable or beta (default: update.channel)")
+	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(diagnosticsCmd)
+	rootCmd.AddCommand(loginCmd, logoutCmd, deviceTokenCmd, storageCmd, backupCmd, restoreCmd, exportCmd, importCmd, configCmd)
 }
 
 func initConfig() {
@@ -69,11 +320,19 @@ func initConfig() {
 		viper.SetConfigName(".waddlebot-bridge")
 	}
 
-	viper.AutomaticEnv()
+	config.BindEnv()
 	viper.ReadInConfig()
 }
 
 func runBridge(cmd *cobra.Command, args []string) {
+	serveBridge(nil)
+}
+
+// serveBridge runs the bridge until it receives SIGINT or SIGTERM or stop is
+// closed, then shuts it down
+func serveBridge(stop <-chan struct{}) {
+	startedAt := time.Now()
+
 	// Initialize logger
 	logger.Init(viper.GetString("log-level"))
 	log := logger.GetLogger()
@@ -92,26 +351,61 @@ func runBridge(cmd *cobra.Command, args []string) {
 		log.WithError(err).Fatal("Failed to load configuration")
 	}
 
-	// Validate required configuration
-	if cfg.CommunityID == "" {
-		log.Fatal("Community ID is required. Use --community-id flag or set in config file.")
+	// A bridge linked to a WaddleBot account takes its user and community
+	// from the account
+	accountManager, linked := linkedAccount(cfg, log)
+	applyLinkedAccount(cfg, linked)
+
+	// Validate the configuration, reporting every problem before giving up
+	problems := cfg.Validate()
+	for _, problem := range problems {
+		entry := log.WithField("setting", problem.Key)
+		if problem.Severity == config.SeverityError {
+			entry.Error(problem.Message)
+		} else {
+			entry.Warn(problem.Message)
+		}
 	}
-	if cfg.UserID == "" {
-		log.Fatal("User ID is required. Use --user-id flag or set in config file.")
+	if errs := problems.Errors(); len(errs) > 0 {
+		log.Fatalf("Invalid configuration: %d problems found; run config validate to list them", len(errs))
 	}
-
-	// Validate poll interval
 	if cfg.PollInterval < 5 {
-		log.Warn("Poll interval cannot be less than 5 seconds. Setting to 5 seconds.")
 		cfg.PollInterval = 5
 	}
 
+	// Trace tasks if enabled
+	stopTracing, err := tracing.Setup(context.Background(), cfg.Tracing, version, log)
+	if err != nil {
+		log.WithError(err).Warn("Failed to set up tracing")
+		stopTracing = func(context.Context) error { return nil }
+	}
+
+	// Keep recent logs for the diagnostics command
+	if err := logger.KeepRecent(filepath.Join(cfg.DataDir, "logs")); err != nil {
+		log.WithError(err).Warn("Failed to keep recent logs for diagnostics")
+	}
+
+	// Keep the latest logs in memory for the activity dashboard
+	logTail := logger.KeepTail()
+
 	// Initialize storage
-	store, err := storage.NewBoltStorage(cfg.DataDir)
+	store, err := storage.Open(cfg.StorageBackend, cfg.DataDir)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to initialize storage")
 	}
-	defer store.Close()
+	storageMonitor := storage.NewMonitor(store, cfg.StorageBackend, cfg.DataDir, storage.MonitorOptions{
+		CompactRatio: cfg.StorageCompactRatio,
+		MinFreeDisk:  uint64(cfg.StorageMinFreeDiskMB) << 20,
+	}, log)
+
+	// Open the encrypted secret store and resolve the secrets of the config
+	secretStore, err := openSecretStore(cfg, store, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to open secret store")
+	}
+	if err := secretStore.ResolveConfig(cfg, viper.ConfigFileUsed()); err != nil {
+		log.WithError(err).Fatal("Failed to resolve secrets")
+	}
 
 	// Initialize WebAuthn authenticator
 	authenticator, err := auth.NewWebAuthnManager(cfg, store)
@@ -122,20 +416,79 @@ func runBridge(cmd *cobra.Command, args []string) {
 	// Initialize module manager
 	moduleManager := modules.NewManager(cfg, store)
 
+	// Register built-in modules before plugins so plugins can depend on them
+	registerBuiltinModules(cfg, moduleManager, log)
+
+	// Load plugin modules
+	if err := moduleManager.LoadModules(); err != nil {
+		log.WithError(err).Warn("Failed to load plugin modules")
+	}
+
+	// Initialize task policy
+	policyEngine, err := policy.New(cfg.Policy, store, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load task policy")
+	}
+
+	// Initialize a bridge client, outbox and poller for every community
+	var communities []*communityBridge
+	for i, communityCfg := range cfg.ForCommunities() {
+		community, err := newCommunityBridge(communityCfg, i == 0, authenticator, moduleManager, store, log)
+		if err != nil {
+			log.WithError(err).WithField("community_id", communityCfg.CommunityID).Fatal("Failed to initialize bridge client")
+		}
+		if linked != nil && communityCfg.UserID == linked.UserID {
+			community.client.SetAccount(accountManager)
+		}
+		community.poller.SetPolicy(policyEngine)
+		communities = append(communities, community)
+	}
+
+	// The primary community's client serves the local web server, gateway
+	// and event bus
+	bridgeClient := communities[0].client
+	resultOutbox := communities[0].outbox
+
+	// Verify the premium subscription, falling back to the last verification
+	// while the API cannot be reached
+	licenseVerifier, err := license.NewVerifier(cfg, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to set up subscription verification")
+	}
+	checkCtx, cancelCheck := context.WithTimeout(context.Background(), 30*time.Second)
+	entitlement, err := licenseVerifier.Check(checkCtx, bridgeClient)
+	cancelCheck()
+	switch {
+	case errors.Is(err, license.ErrNoSubscription) || errors.Is(err, license.ErrInvalidToken):
+		log.WithError(err).Fatal("Invalid or missing premium license. Please ensure you have a valid WaddleBot Premium subscription.")
+	case err != nil:
+		log.WithError(err).Warn("Failed to verify the subscription; retrying until it can be verified")
+	default:
+		log.WithFields(logrus.Fields{
+			"tier":       entitlement.Tier,
+			"features":   entitlement.AllFeatures(),
+			"expires_at": entitlement.ExpiresAt,
+		}).Info("Subscription verified")
+	}
+
+	// Subsystems start with the features of the subscription
+	featureSet := features.New(entitlement, log)
+	applyFeatures(cfg, featureSet)
+
 	// Initialize OBS client if enabled
 	var obsClient *obs.Client
 	if cfg.OBS.Enabled {
-		obsConfig := obs.Config{
-			Host:                 cfg.OBS.Host,
-			Port:                 cfg.OBS.Port,
-			Password:             cfg.OBS.Password,
-			AutoReconnect:        cfg.OBS.AutoReconnect,
-			ReconnectInterval:    cfg.OBS.ReconnectInterval,
-			MaxReconnectInterval: cfg.OBS.MaxReconnectInterval,
-			Timeout:              cfg.OBS.Timeout,
-			Enabled:              cfg.OBS.Enabled,
-		}
-		obsClient = obs.NewClient(obsConfig, log)
+		obsClient = obs.NewClient(obsConfig(cfg.OBS), log)
+
+		historyRepo, err := storage.NewOBSHistoryRepo(store)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to open OBS connection history")
+		}
+		history, err := obs.NewHistory(historyRepo, cfg.OBS.HistorySize, cfg.OBS.HistoryRetention)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load OBS connection history")
+		}
+		obsClient.SetHistory(history)
 		log.Info("OBS integration enabled")
 	}
 
@@ -146,43 +499,387 @@ func runBridge(cmd *cobra.Command, args []string) {
 		if err != nil {
 			log.WithError(err).Warn("Failed to initialize scripting manager")
 		} else {
+			scriptManager.SetActionExecutor(moduleManager)
+			if err := scriptManager.SetStorage(store); err != nil {
+				log.WithError(err).Warn("Failed to open script storage; script values will not persist")
+			}
 			log.WithField("engines", scriptManager.GetEnabledTypes()).Info("Scripting engine initialized")
 		}
 	}
 
-	// Initialize bridge client
-	bridgeClient, err := bridge.NewClient(cfg, authenticator, moduleManager)
-	if err != nil {
-		log.WithError(err).Fatal("Failed to initialize bridge client")
+	for _, community := range communities {
+		if scriptManager != nil {
+			community.poller.RegisterExecutor(poller.TaskTypeScript, poller.NewScriptExecutor(scriptManager, cfg.Scripting))
+		}
+		if obsClient != nil {
+			executor := poller.NewOBSExecutor(obsClient)
+			if cfg.Clips.Enabled {
+				clipsCfg := cfg.Clips
+				if community != communities[0] {
+					// Replays and recordings saved in OBS go to the primary
+					// community; the others get the clips they ask for
+					clipsCfg.UploadReplays, clipsCfg.UploadRecordings = false, false
+				}
+				uploader, err := clips.New(clipsCfg, community.config.CommunityID, community.client, obsClient, store, log)
+				if err != nil {
+					log.WithError(err).Fatal("Failed to create clip uploader")
+				}
+				executor.SetClipper(uploader)
+				community.clips = uploader
+			}
+			community.poller.RegisterExecutor(poller.TaskTypeOBS, executor)
+		}
 	}
 
-	// Initialize poller
-	pollerInstance := poller.NewPoller(cfg, bridgeClient, moduleManager)
+	var outboxQueue handlers.OutboxQueue
+	if resultOutbox != nil {
+		outboxQueue = resultOutbox
+	}
+
+	// Collect telemetry for heartbeats
+	collector := telemetry.NewCollector()
+	collector.SetModules(moduleManager)
+	if obsClient != nil {
+		collector.SetOBS(obsClient)
+	}
+	for i, community := range communities {
+		suffix := ""
+		if i > 0 {
+			suffix = ":" + community.config.CommunityID
+		}
+		collector.AddQueue("pending_results"+suffix, community.poller.PendingResults)
+		if community.outbox != nil {
+			ob := community.outbox
+			collector.AddQueue("outbox"+suffix, func() int {
+				stats, err := ob.Stats()
+				if err != nil {
+					return 0
+				}
+				return stats.Total
+			})
+		}
+		community.client.SetTelemetry(collector)
+
+		// Advertise what the bridge can do, limited to the task types the
+		// community accepts
+		capabilitySet := capabilities.New()
+		capabilitySet.SetModules(moduleManager)
+		capabilitySet.SetFeatures(featureSet)
+		capabilitySet.SetTaskTypes(community.config.TaskTypes)
+		if scriptManager != nil {
+			capabilitySet.SetScripts(scriptManager)
+		}
+		if obsClient != nil {
+			capabilitySet.SetOBS(obsClient)
+		}
+		community.client.SetCapabilities(capabilitySet)
+	}
+
+	// Initialize event bus
+	var eventBus *events.Bus
+	var registryBus webhooks.Bus
+	if cfg.Events.Enabled {
+		eventBus = events.NewBus(cfg.Events, log)
+		registryBus = eventBus
+		if cfg.Events.API.Enabled {
+			eventBus.AddSink(events.NewAPISink(bridgeClient, resultOutbox, cfg.Upload.BatchSize), cfg.Events.API.EventFilter)
+		}
+		moduleManager.SetEventPublisher(eventBus)
+		if scriptManager != nil {
+			scriptManager.SetEventPublisher(eventBus)
+		}
+		if obsClient != nil {
+			events.ForwardOBS(eventBus, obsClient)
+		}
+		for _, community := range communities {
+			if community.clips != nil {
+				community.clips.SetEventPublisher(eventBus)
+			}
+		}
+	}
+
+	// Restore webhooks registered through the gateway and attach the
+	// configured ones; both record their deliveries in the registry
+	webhookRegistry, err := webhooks.NewRegistry(secretStore.Storage(), registryBus, cfg.Events.Delivery, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load webhooks")
+	}
+	if eventBus != nil {
+		for _, webhook := range cfg.Events.Webhooks {
+			eventBus.AddSink(webhookRegistry.NewSink(webhook.Name, webhook.URL, webhook.Secret), webhook.EventFilter)
+		}
+	}
 
 	// Initialize web server for WebAuthn
 	webServer := server.NewWebServer(cfg, authenticator, bridgeClient)
+	if cfg.WebTLS.Enabled {
+		webTLS, err := localtls.NewServerConfig(cfg.WebTLS, cfg.DataDir, "web", cfg.WebHost)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to configure web server TLS")
+		}
+		webServer.SetTLSConfig(webTLS)
+	}
+	webServer.SetDashboard(dashboardSources(communities, obsClient, moduleManager, logTail))
+
+	// Build the command palette shared by the gateway and MQTT
+	catalog := commands.NewCatalog(log)
+	catalog.SetModules(moduleManager)
+	if scriptManager != nil {
+		catalog.SetScripts(scriptManager, cfg.Scripting)
+	}
+	if obsClient != nil {
+		catalog.SetOBS(obsClient, cfg.OBS.Macros)
+	}
+
+	// Load the scene switching rules managed through the gateway
+	var ruleEngine *rules.Engine
+	var ruleStore handlers.RuleStore
+	if cfg.Rules.Enabled && obsClient != nil {
+		var ruleCommands rules.Commands
+		if scriptManager != nil {
+			ruleCommands = catalog
+		}
+		var publisher events.Publisher
+		if eventBus != nil {
+			publisher = eventBus
+		}
+		ruleEngine, err = rules.NewEngine(store, obsClient, ruleCommands, publisher, cfg.Rules, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load scene switching rules")
+		}
+		ruleStore = ruleEngine
+	}
+
+	// Load the source visibility schedules managed through the gateway
+	var scheduler *schedules.Scheduler
+	var scheduleStore handlers.ScheduleStore
+	if cfg.Schedules.Enabled && obsClient != nil {
+		var publisher events.Publisher
+		if eventBus != nil {
+			publisher = eventBus
+		}
+		scheduler, err = schedules.NewScheduler(store, obsClient, publisher, cfg.Schedules, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load source visibility schedules")
+		}
+		scheduleStore = scheduler
+	}
+
+	// Load the triggers managed through the gateway, which fire on bus
+	// events and run commands from the palette
+	var triggerEngine *triggers.Engine
+	var triggerStore handlers.TriggerStore
+	if cfg.Triggers.Enabled {
+		var publisher events.Publisher
+		if eventBus != nil {
+			publisher = eventBus
+		}
+		triggerEngine, err = triggers.NewEngine(store, catalog, moduleManager, publisher, cfg.Triggers, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load triggers")
+		}
+		triggerStore = triggerEngine
+		if eventBus != nil {
+			eventBus.AddSink(triggerEngine, config.EventFilter{})
+		}
+	}
+
+	// Load the countdowns and stopwatches managed through the gateway and
+	// scripts
+	var timerManager *timers.Manager
+	var timerStore handlers.TimerStore
+	if cfg.Timers.Enabled {
+		timerManager, err = timers.NewManager(store, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load timers")
+		}
+		timerStore = timerManager
+		if eventBus != nil {
+			timerManager.SetEventPublisher(eventBus)
+		}
+		if scriptManager != nil {
+			scriptManager.SetTimers(timerManager)
+		}
+	}
+
+	// Load the text sources managed through the gateway and scripts
+	var textManager *textsources.Manager
+	var textStore handlers.TextSourceStore
+	if cfg.TextSources.Enabled && obsClient != nil {
+		textManager, err = textsources.NewManager(store, obsClient, cfg.TextSources, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load text sources")
+		}
+		textStore = textManager
+		if timerManager != nil {
+			textManager.SetTimers(timerManager)
+		}
+		if scriptManager != nil {
+			scriptManager.SetTextVariables(textManager)
+		}
+	}
+
+	// Reach the primary community's calls through the RTC module, as the
+	// bridge's user
+	var rtcClient handlers.RTCClient
+	if cfg.RTC.Enabled {
+		var rtcOBS rtc.OBS
+		if obsClient != nil {
+			rtcOBS = obsClient
+		}
+		rtcClient = rtc.NewClient(cfg, bridgeClient, bridgeClient.HTTPClient(), rtcOBS, log)
+	}
+
+	// Apply configuration changes while running, on SIGHUP and, in watch
+	// mode, whenever the config file is saved
+	reloader := config.NewReloader(cfg, func() (*config.Config, error) {
+		updated, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		applyLinkedAccount(updated, linked)
+		if errs := updated.Validate().Errors(); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid configuration:\n%w", errs)
+		}
+		if updated.PollInterval < 5 {
+			updated.PollInterval = 5
+		}
+		if err := secretStore.ResolveConfig(updated, viper.ConfigFileUsed()); err != nil {
+			return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+		}
+		applyFeatures(updated, featureSet)
+		return updated, nil
+	}, log)
 
 	// Initialize local API gateway if enabled
 	var gatewayServer *gateway.Gateway
 	if cfg.Gateway.Enabled {
-		gatewayServer = gateway.New(cfg.Gateway, obsClient, log)
+		keyStore, err := apikeys.NewStore(store, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load gateway API keys")
+		}
+
+		gatewayServer = gateway.New(cfg.Gateway, gateway.Services{
+			OBS:      obsClient,
+			Modules:  moduleManager,
+			Outbox:   outboxQueue,
+			Policy:   policyEngine,
+			Webhooks: webhookRegistry,
+			Commands: catalog,
+			APIKeys:  keyStore,
+			Bridge:   bridgeClient,
+			Poller:   communities[0].poller,
+			Sessions: authenticator,
+			Storage:  storageMonitor,
+			Profiles: reloader,
+			Features: featureSet,
+			Rules:    ruleStore,
+
+			Schedules:   scheduleStore,
+			Triggers:    triggerStore,
+			TextSources: textStore,
+			Timers:      timerStore,
+			RTC:         rtcClient,
+			SigningKeys: authenticator,
+
+			Version:   version,
+			StartedAt: startedAt,
+		}, log)
+		if cfg.Gateway.EnableAuth && cfg.Gateway.APIKey == "" {
+			// Without a static key the admin secret is generated once and
+			// kept in the data directory, so keys can still be managed
+			adminKeyFile := filepath.Join(cfg.DataDir, "gateway-admin.key")
+			adminKey, err := apikeys.LoadOrCreateAdminSecret(adminKeyFile)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to load gateway admin key")
+			}
+			gatewayServer.SetAdminKey(adminKey)
+			log.WithField("file", adminKeyFile).Info("Using generated gateway admin key")
+		}
+		if cfg.Gateway.TLS.Enabled {
+			gatewayTLS, err := localtls.NewServerConfig(cfg.Gateway.TLS, cfg.DataDir, "gateway", cfg.Gateway.Host)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to configure gateway TLS")
+			}
+			gatewayServer.SetTLSConfig(gatewayTLS)
+		}
+		if eventBus != nil && cfg.Events.WebSocket.Enabled {
+			eventBus.AddSink(gatewayServer.EventSink(), cfg.Events.WebSocket.EventFilter)
+		}
 		log.WithFields(map[string]interface{}{
 			"host": cfg.Gateway.Host,
 			"port": cfg.Gateway.Port,
 		}).Info("Local API gateway enabled")
 	}
 
+	// Initialize MQTT bridge if enabled
+	var mqttClient *mqtt.Client
+	if cfg.MQTT.Enabled {
+		mqttClient = mqtt.New(cfg.MQTT, catalog, log)
+		if eventBus != nil {
+			eventBus.AddSink(mqttClient, cfg.MQTT.EventFilter)
+		}
+		log.WithField("broker", cfg.MQTT.Broker).Info("MQTT bridge enabled")
+	}
+
+	// Initialize OSC if enabled
+	var oscServer *osc.Server
+	if cfg.OSC.Enabled {
+		oscServer = osc.New(cfg.OSC, catalog, log)
+	}
+
+	registerReloadHandlers(reloader, communities, gatewayServer, obsClient, scriptManager)
+	if cfg.WatchConfig && viper.ConfigFileUsed() != "" {
+		reloader.Watch()
+	}
+
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Handle signals for graceful shutdown
+	// Handle signals for graceful shutdown and configuration reloads
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	// Start components
 	log.Info("Starting WaddleBot Premium Desktop Bridge...")
 
+	// Purge expired keys, such as abandoned sign-ins
+	go storage.RunExpirySweeps(ctx, store, cfg.StorageSweepInterval, log)
+
+	// Compact storage as it fills with free pages and watch disk space
+	go storageMonitor.Run(ctx, cfg.StorageSweepInterval)
+
+	// Start scheduled backups if enabled
+	if cfg.Backup.Enabled {
+		go backup.New(store, cfg, log).Run(ctx)
+	}
+
+	// Verify the subscription again periodically, stopping when it lapses
+	licenseLapsed := make(chan error, 1)
+	go func() {
+		if err := licenseVerifier.Run(ctx, bridgeClient); err != nil {
+			licenseLapsed <- err
+		}
+	}()
+
+	// Forward warnings and errors to WaddleBot support if enabled
+	if cfg.LogShipping.Enabled {
+		shipper := diagnostics.NewLogShipper(cfg.LogShipping, bridgeClient, log)
+		log.AddHook(shipper)
+		go shipper.Run(ctx)
+	}
+
+	// Check for updates if enabled, cleaning up after the last one first
+	update.RemoveOld()
+	if cfg.Update.AutoCheck {
+		if updater, err := update.New(cfg, version); err != nil {
+			log.WithError(err).Warn("Failed to set up update checks")
+		} else {
+			go updater.Run(ctx)
+		}
+	}
+
 	// Start OBS client if enabled
 	if obsClient != nil {
 		go func() {
@@ -200,13 +897,26 @@ func runBridge(cmd *cobra.Command, args []string) {
 
 	// Start local API gateway if enabled
 	if gatewayServer != nil {
-		go func() {
-			if err := gatewayServer.Start(ctx); err != nil {
-				log.WithError(err).Error("Gateway server error")
-			}
-		}()
+		go supervisor.Run(ctx, "gateway", supervisor.DefaultPolicy, gatewayServer.Start)
+	}
+
+	// Connect to the MQTT broker if enabled
+	if mqttClient != nil {
+		mqttClient.Start()
+	}
+
+	// Start receiving and sending OSC messages if enabled
+	if oscServer != nil {
+		if err := oscServer.Start(); err != nil {
+			log.WithError(err).Error("Failed to start OSC")
+		} else if eventBus != nil && len(cfg.OSC.Outputs) > 0 {
+			eventBus.AddSink(oscServer, config.EventFilter{})
+		}
 	}
 
+	// Rotate the JWT signing key on schedule
+	go authenticator.RunKeyRotation(ctx)
+
 	// Start web server
 	go func() {
 		if err := webServer.Start(ctx); err != nil {
@@ -214,12 +924,54 @@ func runBridge(cmd *cobra.Command, args []string) {
 		}
 	}()
 
-	// Start poller
-	go func() {
-		if err := pollerInstance.Start(ctx); err != nil {
-			log.WithError(err).Error("Poller error")
+	// Start every community's outbox, heartbeats and poller
+	for _, community := range communities {
+		community.start(ctx)
+	}
+
+	// Pause for sleep and reconnect on wake
+	if cfg.Power.Enabled {
+		go power.New(cfg.Power, powerOptions(communities, obsClient), log).Run(ctx)
+	}
+
+	// Watch the stream's health and remediate when it degrades
+	if cfg.Guardian.Enabled && obsClient != nil {
+		var chats []guardian.Chat
+		for _, community := range communities {
+			chats = append(chats, community.client)
 		}
-	}()
+		var publisher events.Publisher
+		if eventBus != nil {
+			publisher = eventBus
+		}
+		streamGuardian := guardian.New(cfg.Guardian, obsClient, chats, publisher, log)
+		go supervisor.Run(ctx, "guardian", supervisor.DefaultPolicy, streamGuardian.Run)
+	}
+
+	// Switch scenes when the rules' conditions hold
+	if ruleEngine != nil {
+		go supervisor.Run(ctx, "rules", supervisor.DefaultPolicy, ruleEngine.Run)
+	}
+
+	// Show and hide sources on their schedules
+	if scheduler != nil {
+		go supervisor.Run(ctx, "schedules", supervisor.DefaultPolicy, scheduler.Run)
+	}
+
+	// Sample system metrics and fire triggers whose conditions start to hold
+	if triggerEngine != nil {
+		go supervisor.Run(ctx, "triggers", supervisor.DefaultPolicy, triggerEngine.Run)
+	}
+
+	// Finish countdowns and send timer values as they change
+	if timerManager != nil {
+		go supervisor.Run(ctx, "timers", supervisor.DefaultPolicy, timerManager.Run)
+	}
+
+	// Keep the text inputs bound to templates up to date
+	if textManager != nil {
+		go supervisor.Run(ctx, "text-sources", supervisor.DefaultPolicy, textManager.Run)
+	}
 
 	// Display connection info
 	connectionInfo := map[string]interface{}{
@@ -230,6 +982,18 @@ func runBridge(cmd *cobra.Command, args []string) {
 		"web_port":      cfg.WebPort,
 	}
 
+	if len(communities) > 1 {
+		communityIDs := make([]string, 0, len(communities))
+		for _, community := range communities {
+			communityIDs = append(communityIDs, community.config.CommunityID)
+		}
+		connectionInfo["communities"] = communityIDs
+	}
+
+	if cfg.Profile != "" {
+		connectionInfo["profile"] = cfg.Profile
+	}
+
 	if cfg.OBS.Enabled {
 		connectionInfo["obs_enabled"] = true
 		connectionInfo["obs_host"] = cfg.OBS.Host
@@ -248,48 +1012,933 @@ func runBridge(cmd *cobra.Command, args []string) {
 
 	log.WithFields(connectionInfo).Info("Bridge initialized successfully")
 
-	// Wait for shutdown signal
-	<-sigChan
-	log.Info("Shutting down WaddleBot Bridge...")
-
-	// Cancel context to stop all components
-	cancel()
+	// Show the tray icon in desktop sessions; services run without one.
+	// Its Quit item stops the bridge like a signal.
+	var trayIcon *tray.Tray
+	if cfg.Tray.Enabled && stop == nil {
+		quit := make(chan struct{})
+		var quitOnce sync.Once
+		trayIcon = tray.New(trayOptions(cfg, communities, obsClient, func() {
+			quitOnce.Do(func() { close(quit) })
+		}), log)
+		stop = quit
+	}
 
-	// Shutdown OBS client
-	if obsClient != nil {
-		if err := obsClient.Disconnect(); err != nil {
-			log.WithError(err).Warn("Error disconnecting from OBS")
-		} else {
-			log.Info("Disconnected from OBS Studio")
+	// Wait for shutdown, reloading the configuration on SIGHUP
+	var shutdownReason string
+	waitForShutdown := func() {
+		for {
+			select {
+			case sig := <-sigChan:
+				if sig != syscall.SIGHUP {
+					shutdownReason = "signal " + sig.String()
+					return
+				}
+				log.Info("Reloading configuration")
+				if _, err := reloader.Reload(); err != nil {
+					log.WithError(err).Error("Failed to reload configuration; keeping the current settings")
+				}
+			case <-stop:
+				shutdownReason = "stopped"
+				return
+			case err := <-licenseLapsed:
+				log.WithError(err).Error("The WaddleBot Premium subscription is no longer active; stopping the bridge")
+				shutdownReason = "subscription lapsed"
+				return
+			}
 		}
 	}
 
-	// Shutdown gateway server
-	if gatewayServer != nil {
-		if err := gatewayServer.Stop(); err != nil {
-			log.WithError(err).Warn("Error stopping gateway server")
-		} else {
-			log.Info("Gateway server stopped")
+	if trayIcon != nil {
+		// The tray needs the main goroutine, so wait on another one
+		trayCtx, closeTray := context.WithCancel(ctx)
+		waited := make(chan struct{})
+		go func() {
+			defer close(waited)
+			waitForShutdown()
+			closeTray()
+		}()
+		if err := trayIcon.Run(trayCtx); err != nil {
+			log.WithError(err).Warn("Failed to show the tray icon")
 		}
+		<-waited
+	} else {
+		waitForShutdown()
 	}
+	log.WithField("reason", shutdownReason).Info("Shutting down WaddleBot Bridge...")
 
-	// Give components time to shutdown gracefully
-	time.Sleep(2 * time.Second)
-	log.Info("WaddleBot Bridge stopped")
-}
+	// Stop in stages, each bounded, so a stuck component cannot keep the
+	// bridge from exiting
+	coordinator := shutdown.New(log)
+	drained, undelivered := true, 0
 
-func displayBanner() {
-	fmt.Println(`
-██╗    ██╗ █████╗ ██████╗ ██████╗ ██╗     ███████╗██████╗  ██████╗ ████████╗
-██║    ██║██╔══██╗██╔══██╗██╔══██╗██║     ██╔════╝██╔══██╗██╔═══██╗╚══██╔══╝
-██║ █╗ ██║███████║██║  ██║██║  ██║██║     █████╗  ██████╔╝██║   ██║   ██║   
-██║███╗██║██╔══██║██║  ██║██║  ██║██║     ██╔══╝  ██╔══██╗██║   ██║   ██║   
-╚███╔███╔╝██║  ██║██████╔╝██████╔╝███████╗███████╗██████╔╝╚██████╔╝   ██║   
- ╚══╝╚══╝ ╚═╝  ╚═╝╚═════╝ ╚═════╝ ╚══════╝╚══════╝╚═════╝  ╚═════╝    ╚═╝   
-                                                                              
-                    Premium Desktop Bridge v` + version + `
-                    Local System Integration Platform
-`)
+	// Stop taking commands and tasks, letting those in flight finish
+	coordinator.Add("drain", cfg.Shutdown.DrainTimeout, func(ctx context.Context) error {
+		communitiesDrained := make(chan error, 1)
+		go func() { communitiesDrained <- drainCommunities(ctx, communities) }()
+
+		if mqttClient != nil {
+			mqttClient.Stop()
+		}
+		if oscServer != nil {
+			oscServer.Stop()
+		}
+		var errs []error
+		if gatewayServer != nil {
+			// Requests in progress are completed
+			if err := gatewayServer.Stop(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to stop gateway: %w", err))
+			}
+		}
+		if err := <-communitiesDrained; err != nil {
+			drained = false
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	})
+
+	// Cancel whatever is still running and stop the components
+	coordinator.Add("stop", 5*time.Second, func(ctx context.Context) error {
+		cancel()
+		if obsClient != nil {
+			if err := obsClient.Disconnect(); err != nil {
+				log.WithError(err).Warn("Error disconnecting from OBS")
+			} else {
+				log.Info("Disconnected from OBS Studio")
+			}
+		}
+		if eventBus != nil {
+			eventBus.Close()
+		}
+		return nil
+	})
+
+	// Deliver task results and queued items while the API is reachable
+	coordinator.Add("flush", cfg.Shutdown.FlushTimeout, func(ctx context.Context) error {
+		var errs []error
+		for _, community := range communities {
+			undelivered += community.poller.FlushResults(ctx)
+			if community.outbox != nil {
+				if _, err := community.outbox.Flush(ctx, community.client); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		if undelivered > 0 {
+			errs = append(errs, fmt.Errorf("%d task results were not delivered", undelivered))
+		}
+		return errors.Join(errs...)
+	})
+
+	// Tell the API the bridge stopped on purpose
+	coordinator.Add("report", 5*time.Second, func(ctx context.Context) error {
+		report := bridge.ShutdownReport{
+			Reason:             shutdownReason,
+			Clean:              drained && undelivered == 0,
+			UndeliveredResults: undelivered,
+		}
+		var errs []error
+		for _, community := range communities {
+			if err := community.client.ReportShutdown(ctx, report); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+
+	// Export the remaining spans
+	coordinator.Add("tracing", 5*time.Second, stopTracing)
+
+	// Close storage last, once nothing writes to it
+	coordinator.Add("storage", 5*time.Second, func(context.Context) error {
+		return store.Close()
+	})
+
+	if coordinator.Run() {
+		log.Info("WaddleBot Bridge stopped")
+	} else {
+		log.Warn("WaddleBot Bridge stopped; some shutdown stages did not complete")
+	}
+}
+
+// drainCommunities stops every community's poller taking tasks and waits
+// for the tasks in flight to finish
+func drainCommunities(ctx context.Context, communities []*communityBridge) error {
+	errs := make([]error, len(communities))
+	var wg sync.WaitGroup
+	for i, community := range communities {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := community.poller.Drain(ctx); err != nil {
+				errs[i] = fmt.Errorf("community %s: %w", community.config.CommunityID, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// applyLinkedAccount gives a bridge linked to a WaddleBot account the user
+// and community of the account, unless they are configured
+func applyLinkedAccount(cfg *config.Config, linked *account.Account) {
+	if linked == nil {
+		return
+	}
+	if cfg.UserID == "" {
+		cfg.UserID = linked.UserID
+	}
+	if cfg.CommunityID == "" && len(linked.Communities) > 0 {
+		cfg.CommunityID = linked.Communities[0].ID
+	}
+}
+
+// obsConfig returns the OBS client settings of cfg
+func obsConfig(cfg config.OBSConfig) obs.Config {
+	return obs.Config{
+		Host:                 cfg.Host,
+		Port:                 cfg.Port,
+		Password:             cfg.Password,
+		AutoReconnect:        cfg.AutoReconnect,
+		ReconnectInterval:    cfg.ReconnectInterval,
+		MaxReconnectInterval: cfg.MaxReconnectInterval,
+		Timeout:              cfg.Timeout,
+		Enabled:              cfg.Enabled,
+		AudioMeters:          cfg.AudioMeters.Enabled,
+		AudioMeterInterval:   cfg.AudioMeters.Interval,
+		SilenceThreshold:     cfg.AudioMeters.SilenceThreshold,
+		SilenceDelay:         cfg.AudioMeters.SilenceDelay,
+	}
+}
+
+// applyFeatures turns off what cfg enables beyond the subscription's
+// features. It runs on every reload too, so a reload cannot turn them on.
+func applyFeatures(cfg *config.Config, featureSet *features.Set) {
+	if cfg.Scripting.EnableLua && !featureSet.Require(features.ScriptingLua, "scripting.enable-lua") {
+		cfg.Scripting.EnableLua = false
+	}
+	if cfg.Scripting.EnablePython || cfg.Scripting.EnablePowerShell || cfg.Scripting.EnableBash {
+		if !featureSet.Require(features.ScriptingExternal, "scripting.enable-python, scripting.enable-powershell, scripting.enable-bash") {
+			cfg.Scripting.EnablePython = false
+			cfg.Scripting.EnablePowerShell = false
+			cfg.Scripting.EnableBash = false
+		}
+	}
+	if len(cfg.OBS.Macros) > 0 && !featureSet.Require(features.Macros, "obs.macros") {
+		cfg.OBS.Macros = nil
+	}
+	if cfg.Gateway.Enabled && !isLoopback(cfg.Gateway.Host) && !featureSet.Require(features.GatewayExternal, "gateway.host") {
+		cfg.Gateway.Host = "127.0.0.1"
+	}
+}
+
+// isLoopback reports whether host only accepts connections from this
+// machine
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// registerReloadHandlers applies the settings that can change while the
+// bridge runs; changing any other setting takes a restart
+func registerReloadHandlers(reloader *config.Reloader, communities []*communityBridge, gatewayServer *gateway.Gateway, obsClient *obs.Client, scriptManager *scripting.Manager) {
+	reloader.Handle(func(_, updated *config.Config) {
+		logger.SetLevel(updated.LogLevel)
+	}, "log-level")
+
+	reloader.Handle(func(_, updated *config.Config) {
+		for _, community := range communities {
+			community.poller.UpdatePollInterval(updated.PollInterval)
+		}
+	}, "poll-interval")
+
+	if gatewayServer != nil {
+		reloader.Handle(func(_, updated *config.Config) {
+			gatewayServer.SetLimits(updated.Gateway)
+		}, "gateway.enable-cors", "gateway.allowed-origins", "gateway.rate-limit-rps")
+	}
+
+	if obsClient != nil {
+		reloader.Handle(func(_, updated *config.Config) {
+			obsClient.Reconfigure(obsConfig(updated.OBS))
+		}, "obs.host", "obs.port", "obs.password", "obs.auto-reconnect", "obs.reconnect-interval", "obs.max-reconnect-interval", "obs.timeout")
+	}
+
+	if scriptManager != nil {
+		reloader.Handle(func(_, updated *config.Config) {
+			scriptManager.SetPermissions(updated.Scripting)
+		}, "scripting.enabled", "scripting.enable-lua", "scripting.enable-python", "scripting.enable-powershell", "scripting.enable-bash")
+	}
+}
+
+// communityBridge is the API connection of one community served by the
+// bridge
+type communityBridge struct {
+	config *config.Config
+	client *bridge.Client
+	outbox *outbox.Outbox
+	poller *poller.Poller
+	clips  *clips.Uploader // nil when clip uploads are disabled
+}
+
+// trayOptions returns the parts of the bridge controlled from the tray
+func trayOptions(cfg *config.Config, communities []*communityBridge, obsClient *obs.Client, quit func()) tray.Options {
+	opts := tray.Options{
+		WebURL: cfg.GetWebAuthnURL(),
+		Quit:   quit,
+	}
+	if obsClient != nil {
+		opts.OBS = obsClient
+	}
+	for _, community := range communities {
+		opts.Bridges = append(opts.Bridges, community.client)
+		opts.Tasks = append(opts.Tasks, community.poller)
+	}
+	return opts
+}
+
+// dashboardSources returns the parts of the bridge shown on the activity
+// dashboard
+func dashboardSources(communities []*communityBridge, obsClient *obs.Client, moduleManager *modules.Manager, logTail *logger.Tail) dashboard.Sources {
+	sources := dashboard.Sources{
+		Modules: moduleManager,
+		Tasks:   make(map[string]dashboard.TaskHistory, len(communities)),
+		Logs:    logTail,
+	}
+	if obsClient != nil {
+		sources.OBS = obsClient
+	}
+	for _, community := range communities {
+		sources.Tasks[community.config.CommunityID] = community.poller
+	}
+	return sources
+}
+
+// powerOptions returns the parts of the bridge that follow the power state
+func powerOptions(communities []*communityBridge, obsClient *obs.Client) power.Options {
+	var opts power.Options
+	if obsClient != nil {
+		opts.OBS = obsClient
+	}
+	for _, community := range communities {
+		opts.Bridges = append(opts.Bridges, community.client)
+		opts.Tasks = append(opts.Tasks, community.poller)
+	}
+	return opts
+}
+
+// newCommunityBridge creates the bridge client, outbox and poller of a
+// community. The primary community keeps the outbox bucket used before
+// additional communities were supported.
+func newCommunityBridge(cfg *config.Config, primary bool, authenticator *auth.WebAuthnManager, moduleManager *modules.Manager, store storage.Storage, log *logrus.Logger) (*communityBridge, error) {
+	client, err := bridge.NewClient(cfg, authenticator, moduleManager)
+	if err != nil {
+		return nil, err
+	}
+	client.SetStorage(store)
+
+	community := &communityBridge{config: cfg, client: client}
+
+	// Initialize durable outbound queue
+	if cfg.Outbox.Enabled {
+		if primary {
+			community.outbox = outbox.New(store, cfg.Outbox, log)
+		} else {
+			community.outbox, err = outbox.NewForCommunity(store, cfg.CommunityID, cfg.Outbox, log)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create outbox: %w", err)
+			}
+		}
+		client.SetOutbox(community.outbox)
+	}
+
+	// Initialize poller
+	community.poller = poller.NewPoller(cfg, client, moduleManager)
+	if community.outbox != nil {
+		community.poller.SetOutbox(community.outbox)
+	}
+
+	return community, nil
+}
+
+// start runs the community's outbox delivery, registration and heartbeats,
+// and task poller until ctx is cancelled
+func (c *communityBridge) start(ctx context.Context) {
+	if c.outbox != nil {
+		go c.outbox.Run(ctx, c.client)
+	}
+
+	go c.client.RunHeartbeat(ctx)
+
+	go supervisor.Run(ctx, "poller."+c.config.CommunityID, supervisor.DefaultPolicy, c.poller.Start)
+
+	if c.clips != nil {
+		go supervisor.Run(ctx, "clips."+c.config.CommunityID, supervisor.DefaultPolicy, c.clips.Run)
+	}
+}
+
+// registerBuiltinModules registers the first-party modules enabled in config
+func registerBuiltinModules(cfg *config.Config, manager *modules.Manager, log *logrus.Logger) {
+	if cfg.Builtin.FileOps.Enabled {
+		if err := manager.RegisterBuiltin(fileops.New(cfg.Builtin.FileOps)); err != nil {
+			log.WithError(err).Warn("Failed to register file operations module")
+		}
+	}
+	if cfg.Builtin.HTTPRequest.Enabled {
+		if err := manager.RegisterBuiltin(httpreq.New(cfg.Builtin.HTTPRequest)); err != nil {
+			log.WithError(err).Warn("Failed to register HTTP request module")
+		}
+	}
+	if cfg.Builtin.Soundboard.Enabled {
+		if err := manager.RegisterBuiltin(soundboard.New(cfg.Builtin.Soundboard)); err != nil {
+			log.WithError(err).Warn("Failed to register soundboard module")
+		}
+	}
+	if cfg.Builtin.Overlays.Enabled {
+		if err := manager.RegisterBuiltin(overlays.New(cfg.Builtin.Overlays)); err != nil {
+			log.WithError(err).Warn("Failed to register overlays module")
+		}
+	}
+	if cfg.Builtin.Hotkeys.Enabled {
+		if !cfg.Builtin.Hotkeys.PermissionGranted {
+			log.Warn("Hotkeys module enabled without permission-granted, key mappings will be refused")
+		}
+		if err := manager.RegisterBuiltin(hotkeys.New(cfg.Builtin.Hotkeys)); err != nil {
+			log.WithError(err).Warn("Failed to register hotkeys module")
+		}
+	}
+}
+
+func displayBanner() {
+	fmt.Println(`
+██╗    ██╗ █████╗ ██████╗ ██████╗ ██╗     ███████╗██████╗  ██████╗ ████████╗
+██║    ██║██╔══██╗██╔══██╗██╔══██╗██║     ██╔════╝██╔══██╗██╔═══██╗╚══██╔══╝
+██║ █╗ ██║███████║██║  ██║██║  ██║██║     █████╗  ██████╔╝██║   ██║   ██║   
+██║███╗██║██╔══██║██║  ██║██║  ██║██║     ██╔══╝  ██╔══██╗██║   ██║   ██║   
+╚███╔███╔╝██║  ██║██████╔╝██████╔╝███████╗███████╗██████╔╝╚██████╔╝   ██║   
+ ╚══╝╚══╝ ╚═╝  ╚═╝╚═════╝ ╚═════╝ ╚══════╝╚══════╝╚═════╝  ╚═════╝    ╚═╝   
+                                                                              
+                    Premium Desktop Bridge v` + version + `
+                    Local System Integration Platform
+`)
+}
+
+// linkedAccount returns the WaddleBot account the bridge is linked to, if
+// any
+func linkedAccount(cfg *config.Config, log *logrus.Logger) (*account.Manager, *account.Account) {
+	if !keychain.Supported() {
+		return nil, nil
+	}
+
+	manager := account.NewManager(cfg, nil, keychain.New(keychain.Service, cfg.DataDir))
+	linked, err := manager.Load()
+	if err != nil {
+		if !errors.Is(err, account.ErrNotLinked) {
+			log.WithError(err).Warn("Failed to read the linked WaddleBot account")
+		}
+		return nil, nil
+	}
+
+	log.WithFields(logrus.Fields{
+		"user_id":     linked.UserID,
+		"communities": len(linked.Communities),
+	}).Info("Using linked WaddleBot account")
+	return manager, linked
+}
+
+// runLogin links the bridge to a WaddleBot account with the OAuth2 device
+// authorization flow and saves the account's user and community to the
+// config file
+func runLogin(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	manager := account.NewManager(cfg, nil, keychain.New(keychain.Service, cfg.DataDir))
+	linked, err := manager.Login(ctx, func(authorization account.DeviceAuthorization) {
+		fmt.Printf("To link this bridge, visit %s and enter the code %s\n", authorization.VerificationURI, authorization.UserCode)
+		if authorization.VerificationURIComplete != "" {
+			fmt.Printf("or open %s\n", authorization.VerificationURIComplete)
+		}
+		fmt.Println("Waiting for approval...")
+	})
+	if err != nil {
+		log.Fatalf("Login failed: %v", err)
+	}
+
+	fmt.Printf("Linked to WaddleBot account %s (%s)\n", linked.DisplayName, linked.UserID)
+	if len(linked.Communities) == 0 {
+		fmt.Println("The account administers no communities yet")
+		return
+	}
+
+	fmt.Println("Communities you administer:")
+	for _, community := range linked.Communities {
+		fmt.Printf("  %s  %s (%s)\n", community.ID, community.Name, community.Role)
+	}
+
+	communityID, _ := cmd.Flags().GetString("community")
+	if communityID == "" {
+		communityID = linked.Communities[0].ID
+	}
+	if err := saveAccountConfig(linked.UserID, communityID); err != nil {
+		log.Fatalf("Failed to save configuration: %v", err)
+	}
+	fmt.Printf("The bridge will connect to community %s\n", communityID)
+}
+
+// runLogout unlinks the bridge from its WaddleBot account
+func runLogout(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	manager := account.NewManager(cfg, nil, keychain.New(keychain.Service, cfg.DataDir))
+	if err := manager.Logout(); err != nil {
+		log.Fatalf("Logout failed: %v", err)
+	}
+	fmt.Println("Unlinked the bridge from its WaddleBot account")
+}
+
+// saveAccountConfig sets the user and community in the config file in use,
+// or in a new $HOME/.waddlebot-bridge.yaml, leaving its other settings as
+// they are
+func saveAccountConfig(userID, communityID string) error {
+	path, err := configFilePath()
+	if err != nil {
+		return err
+	}
+
+	file := viper.New()
+	file.SetConfigFile(path)
+	if _, err := os.Stat(path); err == nil {
+		if err := file.ReadInConfig(); err != nil {
+			return err
+		}
+	}
+	file.Set("user-id", userID)
+	file.Set("community-id", communityID)
+	return file.WriteConfigAs(path)
+}
+
+// configFilePath returns the config file in use, or the default
+// $HOME/.waddlebot-bridge.yaml when there is none
+func configFilePath() (string, error) {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".waddlebot-bridge.yaml"), nil
+}
+
+// openSecretStore opens the encrypted secret store, which also encrypts
+// the webhooks registered through the gateway
+func openSecretStore(cfg *config.Config, store storage.Storage, log *logrus.Logger) (*secrets.Store, error) {
+	var secretKeychain keychain.Keychain
+	if keychain.Supported() {
+		secretKeychain = keychain.New(keychain.Service, cfg.DataDir)
+	}
+	return secrets.Open(store, secretKeychain, cfg.DataDir, log, webhooks.RegistrationsBucket)
+}
+
+// runDeviceToken prints the device token, which the OS may ask to unlock
+func runDeviceToken(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	token, err := auth.ReadDeviceToken(cfg)
+	if err != nil {
+		log.Fatalf("Failed to read device token: %v", err)
+	}
+	fmt.Println(token)
+}
+
+func runStorageMigrate(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	if from == to {
+		log.Fatalf("Source and destination backends are both %s", from)
+	}
+
+	src, err := storage.Open(from, cfg.DataDir)
+	if err != nil {
+		log.Fatalf("Failed to open %s storage: %v", from, err)
+	}
+	defer src.Close()
+
+	dst, err := storage.Open(to, cfg.DataDir)
+	if err != nil {
+		log.Fatalf("Failed to open %s storage: %v", to, err)
+	}
+	defer dst.Close()
+
+	copied, err := storage.Migrate(dst, src)
+	if err != nil {
+		log.Fatalf("Migration failed after %d keys: %v", copied, err)
+	}
+	fmt.Printf("Copied %d keys from %s to %s storage\n", copied, from, to)
+	if cfg.StorageBackend != to {
+		fmt.Printf("Set storage-backend: %s in the config file to use it\n", to)
+	}
+}
+
+// runConfigValidate prints the problems of the configuration, including the
+// user and community of a linked account
+func runConfigValidate(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	_, linked := linkedAccount(cfg, quiet)
+	applyLinkedAccount(cfg, linked)
+
+	problems := cfg.Validate()
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		if problems == nil {
+			problems = config.Problems{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(problems)
+	} else if len(problems) == 0 {
+		fmt.Println("Configuration is valid")
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+	}
+
+	if len(problems.Errors()) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runConfigEnv(cmd *cobra.Command, args []string) {
+	for _, key := range config.EnvKeys() {
+		fmt.Printf("%s\t%s\n", config.EnvVar(key), key)
+	}
+}
+
+// gatewayClient returns a client for the running bridge's gateway
+func gatewayClient(cmd *cobra.Command) *client.Client {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if apiKey, _ := cmd.Flags().GetString("api-key"); apiKey != "" {
+		cfg.Gateway.APIKey = apiKey
+	}
+	if gatewayURL, _ := cmd.Flags().GetString("gateway-url"); gatewayURL != "" {
+		return client.New(gatewayURL, cfg.Gateway.APIKey, nil)
+	}
+
+	c, err := client.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return c
+}
+
+// printJSON prints v indented when --json is given and reports whether it
+// did
+func printJSON(cmd *cobra.Command, v interface{}) bool {
+	if asJSON, _ := cmd.Flags().GetBool("json"); !asJSON {
+		return false
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+	return true
+}
+
+func runStatus(cmd *cobra.Command, args []string) {
+	status, err := gatewayClient(cmd).Status(cmd.Context())
+	if err != nil {
+		log.Fatalf("Failed to get bridge status: %v", err)
+	}
+	if printJSON(cmd, status) {
+		return
+	}
+
+	fmt.Printf("Status:      %s\n", status.Status)
+	fmt.Printf("Version:     %s\n", status.Version)
+	fmt.Printf("Uptime:      %s\n", time.Duration(status.Uptime)*time.Second)
+	fmt.Printf("Connected:   %t\n", status.Connected)
+	fmt.Printf("Registered:  %t\n", status.Registered)
+	if status.Transport != "" {
+		fmt.Printf("Transport:   %s\n", status.Transport)
+	}
+	if status.OBS != "" {
+		fmt.Printf("OBS:         %s\n", status.OBS)
+	}
+	for name, length := range status.Queues {
+		fmt.Printf("Queue:       %s %d\n", name, length)
+	}
+	for _, issue := range status.Issues {
+		fmt.Printf("Issue:       %s\n", issue)
+	}
+}
+
+func runOBSSceneList(cmd *cobra.Command, args []string) {
+	scenes, err := gatewayClient(cmd).Scenes(cmd.Context())
+	if err != nil {
+		log.Fatalf("Failed to list scenes: %v", err)
+	}
+	if printJSON(cmd, scenes) {
+		return
+	}
+	for _, scene := range scenes {
+		marker := " "
+		if scene.IsCurrent {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, scene.Name)
+	}
+}
+
+func runOBSSceneSwitch(cmd *cobra.Command, args []string) {
+	if err := gatewayClient(cmd).SwitchScene(cmd.Context(), args[0]); err != nil {
+		log.Fatalf("Failed to switch scene: %v", err)
+	}
+	fmt.Printf("Switched to scene %s\n", args[0])
+}
+
+func runScriptsList(cmd *cobra.Command, args []string) {
+	scripts, err := gatewayClient(cmd).Commands(cmd.Context(), commands.KindScript)
+	if err != nil {
+		log.Fatalf("Failed to list scripts: %v", err)
+	}
+	if printJSON(cmd, scripts) {
+		return
+	}
+	for _, script := range scripts {
+		fmt.Println(strings.TrimPrefix(script.ID, string(commands.KindScript)+":"))
+	}
+}
+
+func runScriptsRun(cmd *cobra.Command, args []string) {
+	parameters, _ := cmd.Flags().GetStringToString("param")
+	id := string(commands.KindScript) + ":" + args[0]
+	result, err := gatewayClient(cmd).Execute(cmd.Context(), id, parameters)
+	if err != nil {
+		log.Fatalf("Failed to run script: %v", err)
+	}
+	if printJSON(cmd, result) {
+		return
+	}
+	if output, ok := result["output"].(string); ok && output != "" {
+		fmt.Print(output)
+		if !strings.HasSuffix(output, "\n") {
+			fmt.Println()
+		}
+		return
+	}
+	fmt.Printf("Ran script %s\n", args[0])
+}
+
+func runModulesList(cmd *cobra.Command, args []string) {
+	infos, err := gatewayClient(cmd).Modules(cmd.Context())
+	if err != nil {
+		log.Fatalf("Failed to list modules: %v", err)
+	}
+	if printJSON(cmd, infos) {
+		return
+	}
+	for _, module := range infos {
+		state := "enabled"
+		if !module.Enabled {
+			state = "disabled"
+		}
+		fmt.Printf("%s\t%s\t%s\n", module.Name, module.Version, state)
+		for _, action := range module.Actions {
+			fmt.Printf("  %s\n", action.Name)
+		}
+	}
+}
+
+func runBackup(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if list, _ := cmd.Flags().GetBool("list"); list {
+		backups, err := backup.List(cfg.Backup.Dir)
+		if err != nil {
+			log.Fatalf("Failed to list backups: %v", err)
+		}
+		for _, b := range backups {
+			fmt.Printf("%s\t%s\t%d bytes\t%s\n", b.TakenAt.Local().Format(time.RFC3339), b.Backend, b.Size, b.Path)
+		}
+		return
+	}
+
+	store, err := storage.Open(cfg.StorageBackend, cfg.DataDir)
+	if err != nil {
+		log.Fatalf("Failed to open storage (is the bridge running?): %v", err)
+	}
+	defer store.Close()
+
+	logger.Init(cfg.LogLevel)
+	path, err := backup.New(store, cfg, logger.GetLogger()).Backup()
+	if err != nil {
+		log.Fatalf("Backup failed: %v", err)
+	}
+	fmt.Printf("Backed up storage to %s\n", path)
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	backend, err := backup.Restore(args[0], cfg.DataDir)
+	if err != nil {
+		log.Fatalf("Restore failed: %v", err)
+	}
+	fmt.Printf("Restored %s storage from %s\n", backend, args[0])
+	if cfg.StorageBackend != backend {
+		fmt.Printf("Set storage-backend: %s in the config file to use it\n", backend)
+	}
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	logger.Init(cfg.LogLevel)
+
+	store, err := storage.Open(cfg.StorageBackend, cfg.DataDir)
+	if err != nil {
+		log.Fatalf("Failed to open storage (is the bridge running?): %v", err)
+	}
+	defer store.Close()
+	secretStore, err := openSecretStore(cfg, store, logger.GetLogger())
+	if err != nil {
+		log.Fatalf("Failed to open secret store: %v", err)
+	}
+
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		log.Fatalf("Failed to read passphrase: %v", err)
+	}
+
+	var archive bytes.Buffer
+	summary, err := transfer.Export(&archive, secretStore.Storage(), transferPaths(cfg), passphrase)
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+	if err := os.WriteFile(args[0], archive.Bytes(), 0600); err != nil {
+		log.Fatalf("Failed to write %s: %v", args[0], err)
+	}
+	fmt.Printf("Exported %d stored keys, %d key files and %d scripts to %s\n", summary.Keys, summary.DataFiles, summary.Scripts, args[0])
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	logger.Init(cfg.LogLevel)
+	force, _ := cmd.Flags().GetBool("force")
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", args[0], err)
+	}
+	defer file.Close()
+
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		log.Fatalf("Failed to read passphrase: %v", err)
+	}
+	archive, err := transfer.Read(file, passphrase)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", args[0], err)
+	}
+
+	store, err := storage.Open(cfg.StorageBackend, cfg.DataDir)
+	if err != nil {
+		log.Fatalf("Failed to open storage (is the bridge running?): %v", err)
+	}
+	defer store.Close()
+	secretStore, err := openSecretStore(cfg, store, logger.GetLogger())
+	if err != nil {
+		log.Fatalf("Failed to open secret store: %v", err)
+	}
+
+	summary, err := transfer.Import(archive, secretStore.Storage(), transferPaths(cfg), force)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	fmt.Printf("Imported %d stored keys, %d key files and %d scripts exported at %s\n",
+		summary.Keys, summary.DataFiles, summary.Scripts, archive.ExportedAt.Local().Format(time.RFC3339))
+	fmt.Println("Run login again to link the bridge to its WaddleBot account")
+}
+
+// transferPaths returns the files carried by export and import
+func transferPaths(cfg *config.Config) transfer.Paths {
+	configFile, err := configFilePath()
+	if err != nil {
+		configFile = ""
+	}
+	return transfer.Paths{
+		ConfigFile: configFile,
+		DataDir:    cfg.DataDir,
+		ScriptsDir: cfg.Scripting.ScriptsDir,
+	}
+}
+
+// readPassphrase reads the export passphrase from the environment or asks
+// for it, twice when confirm is set
+func readPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv("WADDLEBOT_EXPORT_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("Passphrase: ")
+	passphrase, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	passphrase = strings.TrimRight(passphrase, "\r\n")
+
+	if confirm {
+		fmt.Print("Repeat passphrase: ")
+		repeated, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimRight(repeated, "\r\n") != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
 }
 
 func main() {
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/kardianos/service"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"waddlebot-bridge/internal/logger"
+)
+
+// serviceStopTimeout is how long the service manager waits for the bridge
+// to shut down
+const serviceStopTimeout = 20 * time.Second
+
+// serviceFlags are the global flags passed on to the installed service
+var serviceFlags = []string{"api-url", "community-id", "user-id", "poll-interval", "log-level", "data-dir", "profile"}
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Run the bridge as a background service",
+	Long: `Install the bridge as a service that starts at login without a terminal:
+a launchd agent on macOS, a systemd user unit on Linux and a Windows service.
+Use --system to install a system service that starts at boot instead, which
+needs administrator rights.`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the bridge as a service",
+	Long: `Install the bridge as a service with the config file and global flags given
+now, such as --config and --profile.`,
+	Args: cobra.NoArgs,
+	Run:  runServiceControl,
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the bridge's service",
+	Args:  cobra.NoArgs,
+	Run:   runServiceControl,
+}
+
+var serviceStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the bridge's service",
+	Args:  cobra.NoArgs,
+	Run:   runServiceControl,
+}
+
+var serviceStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the bridge's service",
+	Args:  cobra.NoArgs,
+	Run:   runServiceControl,
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the bridge's service is running",
+	Args:  cobra.NoArgs,
+	Run:   runServiceStatus,
+}
+
+var serviceRunCmd = &cobra.Command{
+	Use:    "run",
+	Short:  "Run the bridge under the service manager",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Run:    runService,
+}
+
+// bridgeService runs the bridge for the service manager, which starts and
+// stops it
+type bridgeService struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (p *bridgeService) Start(s service.Service) error {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		serveBridge(p.stop)
+	}()
+	return nil
+}
+
+func (p *bridgeService) Stop(s service.Service) error {
+	close(p.stop)
+	select {
+	case <-p.done:
+	case <-time.After(serviceStopTimeout):
+		logger.GetLogger().Warn("Bridge did not stop in time")
+	}
+	return nil
+}
+
+// newService returns the bridge's service as installed by cmd's flags
+func newService(cmd *cobra.Command) (service.Service, error) {
+	cfg, err := serviceConfig(cmd, viper.ConfigFileUsed(), runtime.GOOS)
+	if err != nil {
+		return nil, err
+	}
+	return service.New(&bridgeService{}, cfg)
+}
+
+// serviceConfig returns the service on goos that runs the bridge with
+// configFile and the global flags given to cmd
+func serviceConfig(cmd *cobra.Command, configFile, goos string) (*service.Config, error) {
+	system, _ := cmd.Flags().GetBool("system")
+	if system && goos == "windows" {
+		system = false // Windows services always run for the whole system
+	}
+
+	arguments := []string{"service", "run"}
+	if configFile != "" {
+		abs, err := filepath.Abs(configFile)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, "--config", abs)
+	}
+	for _, name := range serviceFlags {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			arguments = append(arguments, "--"+name+"="+flag.Value.String())
+		}
+	}
+
+	return &service.Config{
+		Name:        "waddlebot-bridge",
+		DisplayName: "WaddleBot Bridge",
+		Description: "Connects this computer to WaddleBot communities",
+		Arguments:   arguments,
+		Option: service.KeyValue{
+			"UserService": goos != "windows" && !system,
+			"RunAtLoad":   true,
+			"KeepAlive":   true,
+			"Restart":     "on-failure",
+			"OnFailure":   "restart",
+		},
+	}, nil
+}
+
+func runServiceControl(cmd *cobra.Command, args []string) {
+	s, err := newService(cmd)
+	if err != nil {
+		log.Fatalf("Failed to set up service: %v", err)
+	}
+	if err := service.Control(s, cmd.Name()); err != nil {
+		log.Fatalf("Failed to %s service: %v", cmd.Name(), err)
+	}
+
+	switch cmd.Name() {
+	case "install":
+		fmt.Printf("Installed the bridge as a %s service; start it with service start\n", service.ChosenSystem())
+	case "uninstall":
+		fmt.Println("Removed the bridge's service")
+	case "start":
+		fmt.Println("Started the bridge's service")
+	case "stop":
+		fmt.Println("Stopped the bridge's service")
+	}
+}
+
+func runServiceStatus(cmd *cobra.Command, args []string) {
+	s, err := newService(cmd)
+	if err != nil {
+		log.Fatalf("Failed to set up service: %v", err)
+	}
+	status, err := s.Status()
+	if errors.Is(err, service.ErrNotInstalled) {
+		fmt.Println("not installed")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to get service status: %v", err)
+	}
+
+	switch status {
+	case service.StatusRunning:
+		fmt.Println("running")
+	case service.StatusStopped:
+		fmt.Println("stopped")
+	default:
+		fmt.Println("unknown")
+	}
+}
+
+// runService runs the bridge under the service manager, logging to the
+// platform's log file where the manager does not keep the output
+func runService(cmd *cobra.Command, args []string) {
+	if !service.Interactive() {
+		if path := logger.DefaultFile(); path != "" {
+			if err := logger.SetFile(path); err != nil {
+				log.Fatalf("Failed to open log file: %v", err)
+			}
+			log.SetOutput(logger.Output())
+		}
+	}
+
+	s, err := newService(cmd)
+	if err != nil {
+		log.Fatalf("Failed to set up service: %v", err)
+	}
+	if err := s.Run(); err != nil {
+		log.Fatalf("Service failed: %v", err)
+	}
+}
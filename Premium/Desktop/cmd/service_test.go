@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// newServiceTestCmd returns a service command with the global flags,
+// parsed from args
+func newServiceTestCmd(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{Use: "install"}
+	cmd.Flags().String("api-url", "https://api.waddlebot.io", "")
+	cmd.Flags().String("profile", "", "")
+	cmd.Flags().String("log-level", "info", "")
+	cmd.Flags().String("gateway-url", "", "")
+	cmd.Flags().Bool("system", false, "")
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags failed: %v", err)
+	}
+	return cmd
+}
+
+func TestServiceConfig_Arguments(t *testing.T) {
+	configFile, _ := filepath.Abs("bridge.yaml")
+
+	tests := []struct {
+		name       string
+		args       []string
+		configFile string
+		want       []string
+	}{
+		{"defaults", nil, "", []string{"service", "run"}},
+		{"config file", nil, "bridge.yaml", []string{"service", "run", "--config", configFile}},
+		{"global flags", []string{"--profile", "stream", "--log-level=debug"}, "", []string{"service", "run", "--log-level=debug", "--profile=stream"}},
+		{"other flags left out", []string{"--gateway-url", "http://localhost:8090", "--system"}, "", []string{"service", "run"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := serviceConfig(newServiceTestCmd(t, tt.args...), tt.configFile, "linux")
+			if err != nil {
+				t.Fatalf("serviceConfig failed: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.Arguments, tt.want) {
+				t.Errorf("Expected arguments %v, got %v", tt.want, cfg.Arguments)
+			}
+		})
+	}
+}
+
+func TestServiceConfig_System(t *testing.T) {
+	tests := []struct {
+		name string
+		goos string
+		args []string
+		user bool
+	}{
+		{"user service", "linux", nil, true},
+		{"system service", "linux", []string{"--system"}, false},
+		{"launchd agent", "darwin", nil, true},
+		{"windows service", "windows", nil, false},
+		{"windows ignores --system", "windows", []string{"--system"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := serviceConfig(newServiceTestCmd(t, tt.args...), "", tt.goos)
+			if err != nil {
+				t.Fatalf("serviceConfig failed: %v", err)
+			}
+			if user := cfg.Option["UserService"]; user != tt.user {
+				t.Errorf("Expected UserService %v, got %v", tt.user, user)
+			}
+			if cfg.Name != "waddlebot-bridge" {
+				t.Errorf("Expected the same service name to install and uninstall, got %q", cfg.Name)
+			}
+		})
+	}
+}
+
+func TestServiceCommands_Args(t *testing.T) {
+	for _, cmd := range []*cobra.Command{serviceInstallCmd, serviceUninstallCmd} {
+		if err := cmd.Args(cmd, []string{"extra"}); err == nil {
+			t.Errorf("Expected %s to reject arguments", cmd.Name())
+		}
+		if err := cmd.Args(cmd, nil); err != nil {
+			t.Errorf("Expected %s without arguments to be accepted, got %v", cmd.Name(), err)
+		}
+		if cmd.Flags().Lookup("system") == nil && cmd.InheritedFlags().Lookup("system") == nil {
+			t.Errorf("Expected %s to take --system", cmd.Name())
+		}
+	}
+}
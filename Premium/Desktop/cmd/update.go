@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/update"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update the bridge to the latest release",
+	Long: `Download the latest release on the configured channel (update.channel),
+verify its signature and replace this executable with it. A running bridge
+keeps its version until it restarts.`,
+	Args: cobra.NoArgs,
+	Run:  runUpdate,
+}
+
+func runUpdate(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	updater, err := update.New(cfg, version)
+	if err != nil {
+		log.Fatalf("Failed to set up updates: %v", err)
+	}
+
+	ctx := context.Background()
+	channel, _ := cmd.Flags().GetString("channel")
+	if channel != "" && channel != "stable" && channel != "beta" {
+		log.Fatalf("Unknown channel %q; use stable or beta", channel)
+	}
+	release, err := updater.Check(ctx, channel)
+	if errors.Is(err, update.ErrUpToDate) {
+		fmt.Printf("Already up to date (%s)\n", version)
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to check for updates: %v", err)
+	}
+
+	fmt.Printf("Update available: %s (%s channel)\n", release.Version, release.Channel)
+	if release.Notes != "" {
+		fmt.Println(release.Notes)
+	}
+	if checkOnly, _ := cmd.Flags().GetBool("check"); checkOnly {
+		return
+	}
+
+	if err := updater.Install(ctx, release); err != nil {
+		log.Fatalf("Failed to install update: %v", err)
+	}
+	fmt.Printf("Updated to %s; restart the bridge to use it\n", release.Version)
+}
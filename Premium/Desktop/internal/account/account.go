@@ -0,0 +1,419 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/keychain"
+)
+
+// Paths of the WaddleBot API's OAuth2 device authorization endpoints
+// (RFC 8628) and of the linked account
+const (
+	deviceCodePath = "/oauth/device/code"
+	tokenPath      = "/oauth/token"
+	accountPath    = "/api/bridge/account"
+)
+
+const (
+	// deviceCodeGrant is the grant type of the device access token request
+	deviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// keychainAccount is the keychain account holding the linked account
+	keychainAccount = "waddlebot-account"
+
+	// refreshMargin renews access tokens this long before they expire
+	refreshMargin = time.Minute
+)
+
+// pollUnit is the unit of the polling intervals the API sends
+var pollUnit = time.Second
+
+var (
+	// ErrNotLinked is returned when the bridge is not linked to an account
+	ErrNotLinked = errors.New("bridge is not linked to a WaddleBot account")
+
+	// ErrAccessDenied is returned when the user declines the login
+	ErrAccessDenied = errors.New("login was denied")
+
+	// ErrExpired is returned when the user code expires before the login
+	// is approved
+	ErrExpired = errors.New("login code expired")
+)
+
+// DeviceAuthorization is what the user needs to approve a login on another
+// device
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"` // seconds
+	Interval                int    `json:"interval"`   // seconds between polls
+}
+
+// Tokens are the OAuth2 tokens of a linked account
+type Tokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Scope        string    `json:"scope,omitempty"`
+}
+
+// Community is a community the account administers
+type Community struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// Account is a linked WaddleBot account, kept in the OS keychain
+type Account struct {
+	UserID      string      `json:"user_id"`
+	DisplayName string      `json:"display_name"`
+	Communities []Community `json:"communities"`
+	Tokens      Tokens      `json:"tokens"`
+}
+
+// tokenResponse is the token endpoint's answer, successful or not
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// Manager links the bridge to a WaddleBot account and keeps its access
+// token fresh
+type Manager struct {
+	config     *config.Config
+	httpClient *http.Client
+	keychain   keychain.Keychain
+
+	mu      sync.Mutex
+	account *Account // cached so the keychain is not read on every request
+}
+
+// NewManager creates an account manager keeping the account in k
+func NewManager(cfg *config.Config, httpClient *http.Client, k keychain.Keychain) *Manager {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Manager{config: cfg, httpClient: httpClient, keychain: k}
+}
+
+// Login runs the device authorization flow: it requests a user code,
+// hands it to prompt for the user to approve on another device, waits for
+// the approval and stores the linked account
+func (m *Manager) Login(ctx context.Context, prompt func(DeviceAuthorization)) (*Account, error) {
+	authorization, err := m.requestDeviceCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prompt(*authorization)
+
+	tokens, err := m.pollToken(ctx, authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := m.fetchAccount(ctx, tokens.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	account.Tokens = *tokens
+
+	if err := m.save(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Logout forgets the linked account
+func (m *Manager) Logout() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.account = nil
+	return m.keychain.Delete(keychainAccount)
+}
+
+// Load returns the linked account
+func (m *Manager) Load() (*Account, error) {
+	data, err := m.keychain.Get(keychainAccount)
+	if errors.Is(err, keychain.ErrNotFound) {
+		return nil, ErrNotLinked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account: %w", err)
+	}
+
+	var account Account
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse account: %w", err)
+	}
+	return &account, nil
+}
+
+// AccessToken returns a valid access token of the linked account,
+// refreshing it when it is about to expire
+func (m *Manager) AccessToken(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.account == nil {
+		account, err := m.Load()
+		if err != nil {
+			return "", err
+		}
+		m.account = account
+	}
+	account := m.account
+	if time.Until(account.Tokens.ExpiresAt) > refreshMargin {
+		return account.Tokens.AccessToken, nil
+	}
+	if account.Tokens.RefreshToken == "" {
+		return "", fmt.Errorf("%w: access token expired, run login again", ErrNotLinked)
+	}
+
+	response, err := m.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {account.Tokens.RefreshToken},
+		"client_id":     {m.config.OAuth.ClientID},
+	})
+	if err != nil {
+		return "", err
+	}
+	if response.Error != "" {
+		return "", fmt.Errorf("token refresh failed: %s", response.describe())
+	}
+
+	tokens := response.tokens()
+	if tokens.RefreshToken == "" {
+		tokens.RefreshToken = account.Tokens.RefreshToken
+	}
+	refreshed := *account
+	refreshed.Tokens = tokens
+	if err := m.saveLocked(&refreshed); err != nil {
+		return "", err
+	}
+	return tokens.AccessToken, nil
+}
+
+// requestDeviceCode starts the device authorization flow
+func (m *Manager) requestDeviceCode(ctx context.Context) (*DeviceAuthorization, error) {
+	form := url.Values{"client_id": {m.config.OAuth.ClientID}}
+	if m.config.OAuth.Scope != "" {
+		form.Set("scope", m.config.OAuth.Scope)
+	}
+
+	body, status, err := m.postForm(ctx, deviceCodePath, form)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("device authorization failed with status %d: %s", status, string(body))
+	}
+
+	var authorization DeviceAuthorization
+	if err := json.Unmarshal(body, &authorization); err != nil {
+		return nil, fmt.Errorf("failed to parse device authorization: %w", err)
+	}
+	if authorization.DeviceCode == "" || authorization.UserCode == "" {
+		return nil, fmt.Errorf("device authorization response is missing its codes")
+	}
+	return &authorization, nil
+}
+
+// pollToken polls the token endpoint until the user approves or declines
+// the login, or the code expires
+func (m *Manager) pollToken(ctx context.Context, authorization *DeviceAuthorization) (*Tokens, error) {
+	interval := time.Duration(authorization.Interval) * pollUnit
+	if interval <= 0 {
+		interval = 5 * pollUnit
+	}
+	expiresIn := time.Duration(authorization.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 15 * time.Minute
+	}
+	deadline := time.Now().Add(expiresIn)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrExpired
+		}
+
+		response, err := m.requestToken(ctx, url.Values{
+			"grant_type":  {deviceCodeGrant},
+			"device_code": {authorization.DeviceCode},
+			"client_id":   {m.config.OAuth.ClientID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch response.Error {
+		case "":
+			tokens := response.tokens()
+			return &tokens, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * pollUnit
+		case "access_denied":
+			return nil, ErrAccessDenied
+		case "expired_token":
+			return nil, ErrExpired
+		default:
+			return nil, fmt.Errorf("login failed: %s", response.describe())
+		}
+	}
+}
+
+// requestToken calls the token endpoint. OAuth errors are returned in the
+// response rather than as an error.
+func (m *Manager) requestToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	body, status, err := m.postForm(ctx, tokenPath, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var response tokenResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("token endpoint returned status %d: %s", status, string(body))
+	}
+	if status != http.StatusOK && response.Error == "" {
+		return nil, fmt.Errorf("token endpoint returned status %d: %s", status, string(body))
+	}
+	if response.Error == "" && response.AccessToken == "" {
+		return nil, fmt.Errorf("token response is missing the access token")
+	}
+	return &response, nil
+}
+
+// fetchAccount returns the user behind an access token and the
+// communities they administer
+func (m *Manager) fetchAccount(ctx context.Context, accessToken string) (*Account, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", m.config.GetAPIEndpoint(accountPath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("User-Agent", m.config.GetUserAgent())
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var account Account
+	if err := json.Unmarshal(body, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse account: %w", err)
+	}
+	if account.UserID == "" {
+		return nil, fmt.Errorf("account response is missing the user ID")
+	}
+
+	// Only communities the user administers can be bridged
+	administered := account.Communities[:0]
+	for _, community := range account.Communities {
+		if community.Role == "owner" || community.Role == "admin" {
+			administered = append(administered, community)
+		}
+	}
+	account.Communities = administered
+	return &account, nil
+}
+
+// postForm posts a form to the API and returns the response body and
+// status
+func (m *Manager) postForm(ctx context.Context, path string, form url.Values) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", m.config.GetAPIEndpoint(path), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", m.config.GetUserAgent())
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
+
+// save stores the linked account in the keychain
+func (m *Manager) save(account *Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.saveLocked(account)
+}
+
+func (m *Manager) saveLocked(account *Account) error {
+	data, err := json.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("failed to marshal account: %w", err)
+	}
+	if err := m.keychain.Set(keychainAccount, data); err != nil {
+		return fmt.Errorf("failed to store account: %w", err)
+	}
+	m.account = account
+	return nil
+}
+
+// tokens converts a successful token response
+func (r *tokenResponse) tokens() Tokens {
+	tokens := Tokens{
+		AccessToken:  r.AccessToken,
+		RefreshToken: r.RefreshToken,
+		TokenType:    r.TokenType,
+		Scope:        r.Scope,
+	}
+	if r.ExpiresIn > 0 {
+		tokens.ExpiresAt = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	} else {
+		tokens.ExpiresAt = time.Now().Add(time.Hour)
+	}
+	return tokens
+}
+
+// describe returns the OAuth error of a failed token response
+func (r *tokenResponse) describe() string {
+	if r.Description != "" {
+		return r.Error + ": " + r.Description
+	}
+	return r.Error
+}
@@ -0,0 +1,162 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/keychain"
+)
+
+func init() {
+	pollUnit = time.Millisecond
+}
+
+// newTestAPI serves the device authorization endpoints. The login is
+// approved after pending polls.
+func newTestAPI(t *testing.T, pending int32, deny bool) (*httptest.Server, *int32) {
+	t.Helper()
+	var polls, refreshes int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(deviceCodePath, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("client_id") != "waddlebot-bridge" {
+			t.Errorf("unexpected client_id %q", r.Form.Get("client_id"))
+		}
+		json.NewEncoder(w).Encode(DeviceAuthorization{
+			DeviceCode:      "device-code",
+			UserCode:        "ABCD-EFGH",
+			VerificationURI: "https://waddlebot.io/link",
+			ExpiresIn:       600,
+			Interval:        1,
+		})
+	})
+	mux.HandleFunc(tokenPath, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.Form.Get("grant_type") {
+		case deviceCodeGrant:
+			if atomic.AddInt32(&polls, 1) <= pending {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+				return
+			}
+			if deny {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "access_denied"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access-1", "refresh_token": "refresh-1", "token_type": "Bearer", "expires_in": 3600,
+			})
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh-1" {
+				t.Errorf("unexpected refresh token %q", r.Form.Get("refresh_token"))
+			}
+			atomic.AddInt32(&refreshes, 1)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access-2", "token_type": "Bearer", "expires_in": 3600,
+			})
+		}
+	})
+	mux.HandleFunc(accountPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"user_id":      "streamer",
+			"display_name": "Streamer",
+			"communities": []Community{
+				{ID: "penguins", Name: "Penguins", Role: "owner"},
+				{ID: "lurkers", Name: "Lurkers", Role: "member"},
+				{ID: "modsquad", Name: "Mod Squad", Role: "admin"},
+			},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &refreshes
+}
+
+func newTestManager(url string) *Manager {
+	cfg := &config.Config{APIURL: url}
+	cfg.OAuth.ClientID = "waddlebot-bridge"
+	return NewManager(cfg, nil, keychain.NewMemory())
+}
+
+func TestLogin(t *testing.T) {
+	server, refreshes := newTestAPI(t, 2, false)
+	manager := newTestManager(server.URL)
+
+	var prompted DeviceAuthorization
+	linked, err := manager.Login(context.Background(), func(authorization DeviceAuthorization) {
+		prompted = authorization
+	})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if prompted.UserCode != "ABCD-EFGH" {
+		t.Errorf("expected the user code to be shown, got %+v", prompted)
+	}
+	if linked.UserID != "streamer" || linked.Tokens.AccessToken != "access-1" {
+		t.Errorf("unexpected account %+v", linked)
+	}
+	if len(linked.Communities) != 2 || linked.Communities[0].ID != "penguins" || linked.Communities[1].ID != "modsquad" {
+		t.Errorf("expected only administered communities, got %+v", linked.Communities)
+	}
+
+	// The account is kept in the keychain
+	loaded, err := newManagerWithKeychain(manager).Load()
+	if err != nil || loaded.Tokens.RefreshToken != "refresh-1" {
+		t.Fatalf("expected the stored account, got %+v, %v", loaded, err)
+	}
+
+	if token, err := manager.AccessToken(context.Background()); err != nil || token != "access-1" {
+		t.Errorf("expected the current access token, got %q, %v", token, err)
+	}
+
+	// An expiring access token is refreshed, keeping the refresh token
+	manager.account.Tokens.ExpiresAt = time.Now()
+	if token, err := manager.AccessToken(context.Background()); err != nil || token != "access-2" {
+		t.Errorf("expected a refreshed access token, got %q, %v", token, err)
+	}
+	if *refreshes != 1 {
+		t.Errorf("expected one refresh, got %d", *refreshes)
+	}
+	if loaded, _ := manager.Load(); loaded.Tokens.RefreshToken != "refresh-1" {
+		t.Errorf("expected the refresh token to be kept, got %q", loaded.Tokens.RefreshToken)
+	}
+
+	if err := manager.Logout(); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	if _, err := manager.AccessToken(context.Background()); !errors.Is(err, ErrNotLinked) {
+		t.Errorf("expected ErrNotLinked after logout, got %v", err)
+	}
+}
+
+func TestLoginDenied(t *testing.T) {
+	server, _ := newTestAPI(t, 1, true)
+	manager := newTestManager(server.URL)
+
+	if _, err := manager.Login(context.Background(), func(DeviceAuthorization) {}); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied, got %v", err)
+	}
+	if _, err := manager.Load(); !errors.Is(err, ErrNotLinked) {
+		t.Errorf("expected no account after a denied login, got %v", err)
+	}
+}
+
+// newManagerWithKeychain returns a manager sharing m's keychain but not
+// its cached account
+func newManagerWithKeychain(m *Manager) *Manager {
+	return NewManager(m.config, nil, m.keychain)
+}
@@ -0,0 +1,353 @@
+// Package apikeys stores the API keys of the local gateway. Each key has a
+// name and a set of scopes; only a hash of the secret is persisted, and the
+// secret is shown once when the key is created or rotated.
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/storage"
+)
+
+// Bucket is the storage bucket holding API keys
+const Bucket = "api_keys"
+
+// SecretPrefix starts every generated key secret
+const SecretPrefix = "wbk_"
+
+// lastUsedInterval limits how often last-used times are persisted
+const lastUsedInterval = time.Minute
+
+// Scopes grant access to groups of gateway endpoints
+const (
+	ScopeAdmin          = "admin"
+	ScopeOBSRead        = "obs:read"
+	ScopeOBSWrite       = "obs:write"
+	ScopeScriptsRun     = "scripts:run"
+	ScopeWebhooksManage = "webhooks:manage"
+	ScopeBridgeRead     = "bridge:read"
+	ScopeBridgeManage   = "bridge:manage"
+	ScopeEventsRead     = "events:read"
+	ScopeOverlaysWrite  = "overlays:write"
+)
+
+// Scopes lists every scope a key can be given
+var Scopes = []string{
+	ScopeAdmin,
+	ScopeOBSRead,
+	ScopeOBSWrite,
+	ScopeScriptsRun,
+	ScopeWebhooksManage,
+	ScopeBridgeRead,
+	ScopeBridgeManage,
+	ScopeEventsRead,
+	ScopeOverlaysWrite,
+}
+
+var (
+	// ErrKeyNotFound is returned for an unknown key ID
+	ErrKeyNotFound = errors.New("API key not found")
+
+	// ErrInvalidKey is returned when a secret matches no active key
+	ErrInvalidKey = errors.New("invalid API key")
+
+	// ErrInvalidScope is returned when a key is given an unknown scope
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+// Key is a gateway API key
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Hash       string     `json:"hash,omitempty"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// HasScope reports whether the key grants a scope. The admin scope grants
+// every scope.
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminKey is the key the configured static gateway API key authenticates
+// as
+var AdminKey = Key{ID: "admin", Name: "admin", Scopes: []string{ScopeAdmin}}
+
+// Store holds API keys in storage and caches them by secret hash
+type Store struct {
+	store  storage.Storage
+	logger *logrus.Logger
+	keys   map[string]*Key // by ID
+	byHash map[string]*Key
+	saved  map[string]time.Time // last persisted last-used time by ID
+	mu     sync.RWMutex
+}
+
+// NewStore creates a key store backed by store and loads existing keys
+func NewStore(store storage.Storage, logger *logrus.Logger) (*Store, error) {
+	if err := store.EnsureBucket(Bucket); err != nil {
+		return nil, fmt.Errorf("failed to create API key bucket: %w", err)
+	}
+
+	s := &Store{
+		store:  store,
+		logger: logger,
+		keys:   make(map[string]*Key),
+		byHash: make(map[string]*Key),
+		saved:  make(map[string]time.Time),
+	}
+
+	stored, err := store.GetAllFromBucket(Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API keys: %w", err)
+	}
+	for id, data := range stored {
+		var key Key
+		if err := json.Unmarshal(data, &key); err != nil {
+			logger.WithError(err).WithField("id", id).Warn("Skipping unreadable API key")
+			continue
+		}
+		s.keys[key.ID] = &key
+		s.byHash[key.Hash] = &key
+	}
+
+	return s, nil
+}
+
+// List returns all keys, without their hashes, ordered by creation time
+func (s *Store) List() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		listed := *key
+		listed.Hash = ""
+		list = append(list, listed)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Create adds a key with the given scopes and returns it with its secret
+func (s *Store) Create(name string, scopes []string) (Key, string, error) {
+	if err := ValidateScopes(scopes); err != nil {
+		return Key{}, "", err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	key := &Key{
+		ID:        "key_" + uuid.NewString(),
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      hashSecret(secret),
+		Prefix:    secret[:len(SecretPrefix)+6],
+		CreatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.save(key); err != nil {
+		return Key{}, "", err
+	}
+	s.keys[key.ID] = key
+	s.byHash[key.Hash] = key
+
+	created := *key
+	created.Hash = ""
+	return created, secret, nil
+}
+
+// Rotate replaces a key's secret, keeping its name and scopes, and returns
+// the new secret. The old secret stops working immediately.
+func (s *Store) Rotate(id string) (Key, string, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, exists := s.keys[id]
+	if !exists {
+		return Key{}, "", fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+
+	now := time.Now()
+	rotated := *key
+	rotated.Hash = hashSecret(secret)
+	rotated.Prefix = secret[:len(SecretPrefix)+6]
+	rotated.RotatedAt = &now
+	if err := s.save(&rotated); err != nil {
+		return Key{}, "", err
+	}
+
+	delete(s.byHash, key.Hash)
+	*key = rotated
+	s.byHash[key.Hash] = key
+
+	rotated.Hash = ""
+	return rotated, secret, nil
+}
+
+// Revoke deletes a key
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, exists := s.keys[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	if err := s.store.DeleteWithBucket(Bucket, id); err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+	delete(s.keys, id)
+	delete(s.byHash, key.Hash)
+	delete(s.saved, id)
+	return nil
+}
+
+// Authenticate returns the key a secret belongs to and records its use.
+// Last-used times are persisted at most once a minute per key.
+func (s *Store) Authenticate(secret string) (Key, error) {
+	if !strings.HasPrefix(secret, SecretPrefix) {
+		return Key{}, ErrInvalidKey
+	}
+	hash := hashSecret(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, exists := s.byHash[hash]
+	if !exists {
+		return Key{}, ErrInvalidKey
+	}
+
+	now := time.Now()
+	key.LastUsedAt = &now
+	if now.Sub(s.saved[key.ID]) >= lastUsedInterval {
+		if err := s.save(key); err != nil {
+			s.logger.WithError(err).Warn("Failed to record API key use")
+		} else {
+			s.saved[key.ID] = now
+		}
+	}
+
+	authenticated := *key
+	authenticated.Hash = ""
+	return authenticated, nil
+}
+
+// save persists a key; the caller must hold the lock
+func (s *Store) save(key *Key) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal API key: %w", err)
+	}
+	if err := s.store.SetWithBucket(Bucket, key.ID, data); err != nil {
+		return fmt.Errorf("failed to store API key: %w", err)
+	}
+	return nil
+}
+
+// ValidateScopes checks that every scope is known and at least one is given
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, s := range Scopes {
+			if scope == s {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+// generateSecret creates a new random key secret
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashSecret returns the stored hash of a secret. Secrets are random, so a
+// plain SHA-256 is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey struct{}
+
+// WithKey returns a context carrying the key a request authenticated with
+func WithKey(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key a request authenticated with
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
+
+// LoadOrCreateAdminSecret returns the admin secret stored at path,
+// generating and saving one when the file does not exist. It is used when
+// the gateway requires authentication but no static API key is configured.
+func LoadOrCreateAdminSecret(path string) (string, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if secret := strings.TrimSpace(string(data)); secret != "" {
+			return secret, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read admin key: %w", err)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(secret+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write admin key: %w", err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,119 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/testutils"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestStore_CreateAuthenticateRevoke(t *testing.T) {
+	backend := testutils.NewMockStorage()
+	store, err := NewStore(backend, testLogger())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+
+	key, secret, err := store.Create("overlay", []string{ScopeOBSRead})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if key.Hash != "" {
+		t.Error("Expected the hash to be hidden")
+	}
+
+	authenticated, err := store.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if authenticated.ID != key.ID || authenticated.LastUsedAt == nil {
+		t.Errorf("Unexpected authenticated key %+v", authenticated)
+	}
+	if !authenticated.HasScope(ScopeOBSRead) || authenticated.HasScope(ScopeOBSWrite) {
+		t.Error("Expected only the obs:read scope")
+	}
+
+	// Keys survive a restart
+	reloaded, err := NewStore(backend, testLogger())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	if _, err := reloaded.Authenticate(secret); err != nil {
+		t.Errorf("Expected persisted key to authenticate: %v", err)
+	}
+
+	if err := reloaded.Revoke(key.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := reloaded.Authenticate(secret); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected revoked key to be rejected, got %v", err)
+	}
+	if err := reloaded.Revoke(key.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestStore_Rotate(t *testing.T) {
+	store, _ := NewStore(testutils.NewMockStorage(), testLogger())
+	key, oldSecret, _ := store.Create("deck", []string{ScopeOBSWrite, ScopeScriptsRun})
+
+	rotated, newSecret, err := store.Rotate(key.ID)
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if rotated.RotatedAt == nil || len(rotated.Scopes) != 2 {
+		t.Errorf("Unexpected rotated key %+v", rotated)
+	}
+	if _, err := store.Authenticate(oldSecret); !errors.Is(err, ErrInvalidKey) {
+		t.Error("Expected the old secret to stop working")
+	}
+	if _, err := store.Authenticate(newSecret); err != nil {
+		t.Errorf("Expected the new secret to work: %v", err)
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	if err := ValidateScopes(nil); !errors.Is(err, ErrInvalidScope) {
+		t.Error("Expected an error without scopes")
+	}
+	if err := ValidateScopes([]string{"obs:delete"}); !errors.Is(err, ErrInvalidScope) {
+		t.Error("Expected an error for an unknown scope")
+	}
+	if err := ValidateScopes([]string{ScopeAdmin, ScopeWebhooksManage}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("Expected no key in an empty context")
+	}
+	key, ok := FromContext(WithKey(context.Background(), AdminKey))
+	if !ok || !key.HasScope(ScopeWebhooksManage) {
+		t.Error("Expected the admin key to grant every scope")
+	}
+}
+
+func TestLoadOrCreateAdminSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway-admin.key")
+
+	secret, err := LoadOrCreateAdminSecret(path)
+	if err != nil || !strings.HasPrefix(secret, SecretPrefix) {
+		t.Fatalf("Unexpected secret %q: %v", secret, err)
+	}
+	if again, _ := LoadOrCreateAdminSecret(path); again != secret {
+		t.Error("Expected the stored secret to be reused")
+	}
+}
@@ -0,0 +1,179 @@
+package apitls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+)
+
+var (
+	// ErrPinMismatch is returned when the API server presents a certificate
+	// that matches none of the configured pins
+	ErrPinMismatch = errors.New("server certificate does not match any pinned key")
+
+	// ErrKeystoreUnsupported is returned when the OS keystore cannot be used
+	// on this platform
+	ErrKeystoreUnsupported = errors.New("OS keystore is not supported on this platform")
+)
+
+// NewConfig builds the TLS configuration for connections to the WaddleBot
+// API: an optional custom CA, an optional client certificate for mutual TLS
+// and optional pinning of the server certificate or its public key.
+func NewConfig(cfg config.APITLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.CAFile != "" {
+		pemData, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	cert, err := loadClientCertificate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
+
+	verify, err := pinVerifier(cfg.PinnedSPKI, cfg.PinnedCerts)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.VerifyConnection = verify
+
+	return tlsConfig, nil
+}
+
+// NewTransport returns an HTTP transport using the given TLS configuration
+func NewTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// SPKIHash returns the base64 SHA-256 hash of a certificate's public key,
+// the format used for pinned-spki-sha256
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// CertHash returns the hex SHA-256 fingerprint of a certificate, the format
+// used for pinned-cert-sha256
+func CertHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadClientCertificate loads the client certificate from files, inline PEM
+// or the OS keystore, whichever is configured
+func loadClientCertificate(cfg config.APITLSConfig) (*tls.Certificate, error) {
+	switch {
+	case cfg.ClientCertFile != "" || cfg.ClientKeyFile != "":
+		if cfg.ClientCertFile == "" || cfg.ClientKeyFile == "" {
+			return nil, fmt.Errorf("both client-cert-file and client-key-file are required")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		return &cert, nil
+
+	case cfg.ClientCertPEM != "" || cfg.ClientKeyPEM != "":
+		cert, err := tls.X509KeyPair([]byte(cfg.ClientCertPEM), []byte(cfg.ClientKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		return &cert, nil
+
+	case cfg.ClientCertKeystore != "":
+		cert, err := loadKeystoreCertificate(cfg.ClientCertKeystore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %q from keystore: %w", cfg.ClientCertKeystore, err)
+		}
+		return cert, nil
+	}
+
+	return nil, nil
+}
+
+// pinVerifier returns a connection check that accepts the server only if a
+// certificate in its chain matches one of the pins
+func pinVerifier(spkiPins, certPins []string) (func(tls.ConnectionState) error, error) {
+	if len(spkiPins) == 0 && len(certPins) == 0 {
+		return nil, nil
+	}
+
+	spki := make(map[string]bool, len(spkiPins))
+	for _, pin := range spkiPins {
+		pin = strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")
+		decoded, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid SPKI pin %q: expected base64 SHA-256 hash", pin)
+		}
+		spki[pin] = true
+	}
+
+	certs := make(map[string]bool, len(certPins))
+	for _, pin := range certPins {
+		pin = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(pin), ":", ""))
+		decoded, err := hex.DecodeString(pin)
+		if err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid certificate pin %q: expected hex SHA-256 fingerprint", pin)
+		}
+		certs[pin] = true
+	}
+
+	matches := func(chain []*x509.Certificate) bool {
+		for _, cert := range chain {
+			if spki[SPKIHash(cert)] || certs[CertHash(cert)] {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(state tls.ConnectionState) error {
+		// Verified chains include the local root, so a CA can be pinned even
+		// when the server does not send it
+		for _, chain := range state.VerifiedChains {
+			if matches(chain) {
+				return nil
+			}
+		}
+		if matches(state.PeerCertificates) {
+			return nil
+		}
+		return ErrPinMismatch
+	}, nil
+}
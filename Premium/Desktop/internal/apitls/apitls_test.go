@@ -0,0 +1,250 @@
+package apitls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if isCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// newMutualTLSServer starts a server that requires a client certificate
+// signed by ca
+func newMutualTLSServer(t *testing.T, ca, server *testCert) *httptest.Server {
+	t.Helper()
+
+	pair, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load server certificate: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestNewConfig_MutualTLS(t *testing.T) {
+	ca := newTestCert(t, "Test CA", nil, true)
+	server := newTestCert(t, "api.test", ca, false)
+	client := newTestCert(t, "bridge-client", ca, false)
+	srv := newMutualTLSServer(t, ca, server)
+
+	dir := t.TempDir()
+	cfg := config.APITLSConfig{
+		CAFile:         writeFile(t, dir, "ca.pem", ca.certPEM),
+		ClientCertFile: writeFile(t, dir, "client.pem", client.certPEM),
+		ClientKeyFile:  writeFile(t, dir, "client-key.pem", client.keyPEM),
+	}
+
+	tlsConfig, err := NewConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+
+	httpClient := &http.Client{Transport: NewTransport(tlsConfig)}
+	resp, err := httpClient.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	buf := make([]byte, 64)
+	n, _ := resp.Body.Read(buf)
+	if got := string(buf[:n]); got != "bridge-client" {
+		t.Errorf("expected server to see client certificate bridge-client, got %q", got)
+	}
+}
+
+func TestNewConfig_InlinePEM(t *testing.T) {
+	ca := newTestCert(t, "Test CA", nil, true)
+	client := newTestCert(t, "bridge-client", ca, false)
+
+	tlsConfig, err := NewConfig(config.APITLSConfig{
+		ClientCertPEM: string(client.certPEM),
+		ClientKeyPEM:  string(client.keyPEM),
+	})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if len(tlsConfig.Certificates) != 1 {
+		t.Errorf("expected 1 client certificate, got %d", len(tlsConfig.Certificates))
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("expected minimum TLS 1.2, got %x", tlsConfig.MinVersion)
+	}
+}
+
+func TestNewConfig_Pinning(t *testing.T) {
+	ca := newTestCert(t, "Test CA", nil, true)
+	server := newTestCert(t, "api.test", ca, false)
+	client := newTestCert(t, "bridge-client", ca, false)
+	other := newTestCert(t, "other", nil, true)
+	srv := newMutualTLSServer(t, ca, server)
+
+	dir := t.TempDir()
+	base := config.APITLSConfig{
+		CAFile:        writeFile(t, dir, "ca.pem", ca.certPEM),
+		ClientCertPEM: string(client.certPEM),
+		ClientKeyPEM:  string(client.keyPEM),
+	}
+
+	tests := []struct {
+		name    string
+		spki    []string
+		certs   []string
+		wantErr bool
+	}{
+		{name: "server spki", spki: []string{SPKIHash(server.cert)}},
+		{name: "ca spki with prefix", spki: []string{"sha256/" + SPKIHash(ca.cert)}},
+		{name: "server cert", certs: []string{CertHash(server.cert)}},
+		{name: "wrong spki", spki: []string{SPKIHash(other.cert)}, wantErr: true},
+		{name: "wrong cert", certs: []string{CertHash(other.cert)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.PinnedSPKI = tt.spki
+			cfg.PinnedCerts = tt.certs
+
+			tlsConfig, err := NewConfig(cfg)
+			if err != nil {
+				t.Fatalf("NewConfig failed: %v", err)
+			}
+
+			httpClient := &http.Client{Transport: NewTransport(tlsConfig)}
+			resp, err := httpClient.Get(srv.URL)
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrPinMismatch) {
+					t.Errorf("expected ErrPinMismatch, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("expected request to succeed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.APITLSConfig
+	}{
+		{name: "missing ca file", cfg: config.APITLSConfig{CAFile: "/nonexistent/ca.pem"}},
+		{name: "cert without key", cfg: config.APITLSConfig{ClientCertFile: "client.pem"}},
+		{name: "bad inline pem", cfg: config.APITLSConfig{ClientCertPEM: "not a cert", ClientKeyPEM: "not a key"}},
+		{name: "bad spki pin", cfg: config.APITLSConfig{PinnedSPKI: []string{"abc"}}},
+		{name: "bad cert pin", cfg: config.APITLSConfig{PinnedCerts: []string{"zz:zz"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewConfig(tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestExportCommand_Unsupported(t *testing.T) {
+	if _, err := exportCommand("linux", "bridge-client", "secret", "out.p12"); !errors.Is(err, ErrKeystoreUnsupported) {
+		t.Errorf("expected ErrKeystoreUnsupported, got %v", err)
+	}
+
+	cmd, err := exportCommand("windows", "bridge-client", "secret", "out.p12")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, arg := range cmd.Args {
+		if arg == "bridge-client" || arg == "secret" {
+			t.Errorf("expected name and password to be passed via environment, found %q in args", arg)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package apitls
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// loadKeystoreCertificate exports the identity whose certificate common name
+// is name from the OS keystore: the login keychain on macOS or the current
+// user's personal certificate store on Windows. The private key must be
+// exportable.
+func loadKeystoreCertificate(name string) (*tls.Certificate, error) {
+	dir, err := os.MkdirTemp("", "waddlebot-keystore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate export password: %w", err)
+	}
+	password := hex.EncodeToString(secret)
+	pfxPath := filepath.Join(dir, "identity.p12")
+
+	cmd, err := exportCommand(runtime.GOOS, name, password, pfxPath)
+	if err != nil {
+		return nil, err
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("keystore export failed: %v: %s", err, output)
+	}
+
+	pfxData, err := os.ReadFile(pfxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exported identity: %w", err)
+	}
+
+	return identityFromPKCS12(pfxData, password, name)
+}
+
+// exportCommand returns the command that exports identities to a PKCS#12
+// file. The name and password are passed through the environment rather
+// than interpolated into the script.
+func exportCommand(goos, name, password, pfxPath string) (*exec.Cmd, error) {
+	switch goos {
+	case "darwin":
+		return exec.Command("security", "export", "-t", "identities", "-f", "pkcs12",
+			"-P", password, "-o", pfxPath), nil
+	case "windows":
+		script := `$c = Get-ChildItem Cert:\CurrentUser\My | ` +
+			`Where-Object { $_.HasPrivateKey -and $_.GetNameInfo('SimpleName', $false) -eq $env:WADDLEBOT_CERT_NAME } | ` +
+			`Select-Object -First 1; ` +
+			`if (-not $c) { Write-Error 'certificate not found'; exit 2 }; ` +
+			`$p = ConvertTo-SecureString -String $env:WADDLEBOT_CERT_PASSWORD -Force -AsPlainText; ` +
+			`Export-PfxCertificate -Cert $c -FilePath $env:WADDLEBOT_CERT_PATH -Password $p -CryptoAlgorithmOption TripleDES_SHA1 | Out-Null`
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Env = append(os.Environ(),
+			"WADDLEBOT_CERT_NAME="+name,
+			"WADDLEBOT_CERT_PASSWORD="+password,
+			"WADDLEBOT_CERT_PATH="+pfxPath,
+		)
+		return cmd, nil
+	default:
+		return nil, ErrKeystoreUnsupported
+	}
+}
+
+// identityFromPKCS12 picks the certificate with the given common name, and
+// its private key, out of a PKCS#12 bundle that may hold several identities
+func identityFromPKCS12(pfxData []byte, password, name string) (*tls.Certificate, error) {
+	blocks, err := pkcs12.ToPEM(pfxData, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode exported identity: %w", err)
+	}
+
+	keys := make(map[string]*pem.Block)
+	for _, block := range blocks {
+		if block.Type == "PRIVATE KEY" {
+			keys[block.Headers["localKeyId"]] = block
+		}
+	}
+
+	for _, block := range blocks {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || cert.Subject.CommonName != name {
+			continue
+		}
+		key, exists := keys[block.Headers["localKeyId"]]
+		if !exists {
+			continue
+		}
+
+		pair, err := tls.X509KeyPair(
+			pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes}),
+			pem.EncodeToMemory(&pem.Block{Type: key.Type, Bytes: key.Bytes}),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load exported identity: %w", err)
+		}
+		return &pair, nil
+	}
+
+	return nil, fmt.Errorf("no identity with common name %q and a private key found", name)
+}
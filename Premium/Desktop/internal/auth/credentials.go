@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/models"
+)
+
+// CredentialMeta is what the bridge records about a credential besides
+// the credential itself
+type CredentialMeta struct {
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+// CredentialInfo describes a registered credential
+type CredentialInfo struct {
+	ID         string     `json:"id"`
+	AAGUID     string     `json:"aaguid,omitempty"`
+	Transports []string   `json:"transports,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// credentialKey encodes a credential ID the way it is shown to clients
+func credentialKey(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// setCredentialMeta records metadata for a credential
+func (u *User) setCredentialMeta(id []byte, meta CredentialMeta) {
+	if u.CredentialMeta == nil {
+		u.CredentialMeta = make(map[string]CredentialMeta)
+	}
+	u.CredentialMeta[credentialKey(id)] = meta
+}
+
+// credentialInfo describes one of the user's credentials. Credentials
+// registered before metadata was kept have no times.
+func (u *User) credentialInfo(credential webauthn.Credential) CredentialInfo {
+	info := CredentialInfo{ID: credentialKey(credential.ID)}
+	if aaguid, err := uuid.FromBytes(credential.Authenticator.AAGUID); err == nil && aaguid != uuid.Nil {
+		info.AAGUID = aaguid.String()
+	}
+	for _, transport := range credential.Transport {
+		info.Transports = append(info.Transports, string(transport))
+	}
+
+	meta := u.CredentialMeta[info.ID]
+	if !meta.CreatedAt.IsZero() {
+		info.CreatedAt = &meta.CreatedAt
+	}
+	if !meta.LastUsedAt.IsZero() {
+		info.LastUsedAt = &meta.LastUsedAt
+	}
+	return info
+}
+
+// CompleteCredentialRegistration completes adding a credential to an
+// existing user, started with StartCredentialRegistration
+func (m *WebAuthnManager) CompleteCredentialRegistration(userID string, response []byte) (CredentialInfo, error) {
+	if _, exists := m.getUserByID(userID); !exists {
+		return CredentialInfo{}, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+
+	user, credential, err := m.finishRegistration(userID, response)
+	if err != nil {
+		return CredentialInfo{}, err
+	}
+
+	info := user.credentialInfo(*credential)
+	m.logger.WithFields(logrus.Fields{
+		"user_id":       userID,
+		"credential_id": info.ID,
+		"aaguid":        info.AAGUID,
+	}).Info("Added WebAuthn credential")
+
+	return info, nil
+}
+
+// ListCredentials returns the credentials registered for a user
+func (m *WebAuthnManager) ListCredentials(userID string) ([]CredentialInfo, error) {
+	user, exists := m.getUserByID(userID)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+
+	infos := make([]CredentialInfo, 0, len(user.Credentials))
+	for _, credential := range user.Credentials {
+		infos = append(infos, user.credentialInfo(credential))
+	}
+	return infos, nil
+}
+
+// RevokeCredential removes one of a user's credentials and ends the
+// sessions signed in with it. A user's last credential cannot be revoked;
+// remove the user instead.
+func (m *WebAuthnManager) RevokeCredential(userID, credentialID string) error {
+	user, exists := m.getUserByID(userID)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+
+	index := -1
+	for i, credential := range user.Credentials {
+		if credentialKey(credential.ID) == credentialID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("%w: %s", ErrCredentialNotFound, credentialID)
+	}
+	if len(user.Credentials) == 1 {
+		return ErrLastCredential
+	}
+
+	revoked := user.Credentials[index].ID
+	user.Credentials = append(user.Credentials[:index], user.Credentials[index+1:]...)
+	delete(user.CredentialMeta, credentialID)
+	if err := m.saveUser(user); err != nil {
+		return err
+	}
+
+	m.sessions.RevokeWhere(func(session *models.AuthSession) bool {
+		return session.UserID == userID && bytes.Equal(session.Credential, revoked)
+	})
+
+	m.logger.WithFields(logrus.Fields{
+		"user_id":       userID,
+		"credential_id": credentialID,
+	}).Info("Revoked WebAuthn credential")
+	return nil
+}
+
+// recordCredentialUse stores the credential's new signature counter and
+// when it was used to sign in
+func (m *WebAuthnManager) recordCredentialUse(user *User, used *webauthn.Credential) {
+	for i := range user.Credentials {
+		if !bytes.Equal(user.Credentials[i].ID, used.ID) {
+			continue
+		}
+		user.Credentials[i].Authenticator = used.Authenticator
+
+		meta := user.CredentialMeta[credentialKey(used.ID)]
+		meta.LastUsedAt = time.Now()
+		user.setCredentialMeta(used.ID, meta)
+	}
+
+	if err := m.saveUser(user); err != nil {
+		m.logger.WithError(err).Warn("Failed to record credential use")
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+func TestWebAuthnManager_Credentials(t *testing.T) {
+	manager := newTestManager(t)
+
+	aaguid := uuid.MustParse("08987058-cadc-4b81-b6e1-30de50dcbe96")
+	created := time.Now().Add(-time.Hour)
+	user := &User{
+		ID:   []byte("streamer"),
+		Name: "streamer",
+		Credentials: []webauthn.Credential{
+			{ID: []byte("laptop"), Authenticator: webauthn.Authenticator{AAGUID: aaguid[:]}},
+			{ID: []byte("phone"), Transport: []protocol.AuthenticatorTransport{protocol.Hybrid}},
+		},
+	}
+	user.setCredentialMeta([]byte("laptop"), CredentialMeta{CreatedAt: created})
+	if err := manager.saveUser(user); err != nil {
+		t.Fatalf("saveUser failed: %v", err)
+	}
+
+	manager.recordCredentialUse(user, &webauthn.Credential{ID: []byte("laptop"), Authenticator: webauthn.Authenticator{AAGUID: aaguid[:], SignCount: 7}})
+
+	credentials, err := manager.ListCredentials("streamer")
+	if err != nil {
+		t.Fatalf("ListCredentials failed: %v", err)
+	}
+	if len(credentials) != 2 {
+		t.Fatalf("Expected 2 credentials, got %+v", credentials)
+	}
+	laptop, phone := credentials[0], credentials[1]
+	if laptop.AAGUID != aaguid.String() || laptop.CreatedAt == nil || !laptop.CreatedAt.Equal(created) || laptop.LastUsedAt == nil {
+		t.Errorf("Expected the laptop's AAGUID, creation and last use, got %+v", laptop)
+	}
+	if phone.AAGUID != "" || phone.CreatedAt != nil || len(phone.Transports) != 1 {
+		t.Errorf("Expected a credential without metadata, got %+v", phone)
+	}
+
+	stored, _ := manager.getUserByID("streamer")
+	if stored.Credentials[0].Authenticator.SignCount != 7 {
+		t.Errorf("Expected the signature counter to be stored, got %d", stored.Credentials[0].Authenticator.SignCount)
+	}
+
+	manager.sessions.Put(&Session{ID: "laptop-session", UserID: "streamer", Credential: []byte("laptop"), ExpiresAt: time.Now().Add(time.Hour)})
+	manager.sessions.Put(&Session{ID: "phone-session", UserID: "streamer", Credential: []byte("phone"), ExpiresAt: time.Now().Add(time.Hour)})
+
+	if err := manager.RevokeCredential("streamer", "unknown"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Expected ErrCredentialNotFound, got %v", err)
+	}
+	if err := manager.RevokeCredential("streamer", laptop.ID); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	if _, err := manager.sessions.Get("laptop-session"); err == nil {
+		t.Error("Expected sessions of the revoked credential to end")
+	}
+	if _, err := manager.sessions.Get("phone-session"); err != nil {
+		t.Error("Expected sessions of other credentials to remain")
+	}
+	if err := manager.RevokeCredential("streamer", phone.ID); !errors.Is(err, ErrLastCredential) {
+		t.Errorf("Expected ErrLastCredential, got %v", err)
+	}
+}
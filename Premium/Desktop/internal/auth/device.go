@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/keychain"
+	"waddlebot-bridge/internal/models"
+)
+
+// Sign-in methods selected with auth-method
+const (
+	AuthMethodWebAuthn = "webauthn"
+	AuthMethodDevice   = "device"
+)
+
+const (
+	// deviceTokenAccount is the keychain account holding the device token
+	deviceTokenAccount = "device-token"
+
+	// deviceTokenPrefix starts every device token
+	deviceTokenPrefix = "wbd_"
+
+	// defaultDeviceUserID signs in with the device token when no user-id
+	// is configured
+	defaultDeviceUserID = "local"
+)
+
+// deviceCredential marks sessions signed in with the device token rather
+// than a passkey
+var deviceCredential = []byte("device")
+
+// DeviceAuthEnabled reports whether the device token sign-in is enabled
+func (m *WebAuthnManager) DeviceAuthEnabled() bool {
+	return m.keychain != nil
+}
+
+// setupDevice makes sure the keychain holds a device token and that the
+// user it signs in as exists. The first user of the bridge owns it.
+func (m *WebAuthnManager) setupDevice() error {
+	if _, err := m.keychain.Get(deviceTokenAccount); errors.Is(err, keychain.ErrNotFound) {
+		if err := m.newDeviceToken(); err != nil {
+			return err
+		}
+		m.logger.Info("Stored a new device token in the OS keychain")
+	} else if err != nil {
+		return fmt.Errorf("failed to read device token: %w", err)
+	}
+
+	userID := m.deviceUserID()
+	if _, exists := m.getUserByID(userID); exists {
+		return nil
+	}
+
+	role := RoleViewer
+	if !m.HasUsers() {
+		role = RoleOwner
+	}
+	user := &User{
+		ID:          []byte(userID),
+		Name:        userID,
+		DisplayName: userID,
+		CommunityID: m.config.CommunityID,
+		Role:        role,
+		CreatedAt:   time.Now(),
+	}
+	if err := m.saveUser(user); err != nil {
+		return err
+	}
+
+	m.logger.WithField("user_id", userID).WithField("role", role).Info("Created device user")
+	return nil
+}
+
+// deviceUserID returns the user the device token signs in as
+func (m *WebAuthnManager) deviceUserID() string {
+	if m.config.UserID != "" {
+		return m.config.UserID
+	}
+	return defaultDeviceUserID
+}
+
+// newDeviceToken stores a new random device token in the keychain
+func (m *WebAuthnManager) newDeviceToken() error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate device token: %w", err)
+	}
+	if err := m.keychain.Set(deviceTokenAccount, secret); err != nil {
+		return fmt.Errorf("failed to store device token: %w", err)
+	}
+	return nil
+}
+
+// DeviceToken reads the device token from the OS keychain, which may ask
+// the user to unlock it
+func (m *WebAuthnManager) DeviceToken() (string, error) {
+	if !m.DeviceAuthEnabled() {
+		return "", ErrDeviceAuthDisabled
+	}
+	return readDeviceToken(m.keychain)
+}
+
+// ReadDeviceToken reads the device token from the OS keychain without a
+// running bridge
+func ReadDeviceToken(cfg *config.Config) (string, error) {
+	if cfg.AuthMethod != AuthMethodDevice {
+		return "", ErrDeviceAuthDisabled
+	}
+	return readDeviceToken(keychain.New(keychain.Service, cfg.DataDir))
+}
+
+func readDeviceToken(k keychain.Keychain) (string, error) {
+	secret, err := k.Get(deviceTokenAccount)
+	if err != nil {
+		return "", fmt.Errorf("failed to read device token: %w", err)
+	}
+	return deviceTokenPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// DeviceLogin signs in with the device token
+func (m *WebAuthnManager) DeviceLogin(token string) (*models.AuthSession, error) {
+	expected, err := m.DeviceToken()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(token, deviceTokenPrefix) ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	user, exists := m.getUserByID(m.deviceUserID())
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, m.deviceUserID())
+	}
+
+	session := m.startSession(user, deviceCredential)
+	m.logger.WithField("user_id", session.UserID).Info("Signed in with device token")
+	return session, nil
+}
+
+// SignInDevice signs the bridge itself in with the device token from the
+// keychain, for machines where nobody signs in through a browser
+func (m *WebAuthnManager) SignInDevice() (*models.AuthSession, error) {
+	token, err := m.DeviceToken()
+	if err != nil {
+		return nil, err
+	}
+	return m.DeviceLogin(token)
+}
+
+// ResetDeviceToken replaces the device token and ends the sessions signed
+// in with the old one
+func (m *WebAuthnManager) ResetDeviceToken() error {
+	if !m.DeviceAuthEnabled() {
+		return ErrDeviceAuthDisabled
+	}
+	if err := m.newDeviceToken(); err != nil {
+		return err
+	}
+
+	m.sessions.RevokeWhere(func(session *models.AuthSession) bool {
+		return bytes.Equal(session.Credential, deviceCredential)
+	})
+
+	m.logger.Info("Reset device token")
+	return nil
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"waddlebot-bridge/internal/keychain"
+)
+
+func newDeviceTestManager(t *testing.T) *WebAuthnManager {
+	t.Helper()
+	manager := newTestManager(t)
+	manager.keychain = keychain.NewMemory()
+	if err := manager.setupDevice(); err != nil {
+		t.Fatalf("setupDevice failed: %v", err)
+	}
+	return manager
+}
+
+func TestWebAuthnManager_DeviceLogin(t *testing.T) {
+	manager := newDeviceTestManager(t)
+
+	user, err := manager.GetUser("test-user")
+	if err != nil || user.Role != RoleOwner {
+		t.Fatalf("Expected the device user to own a new bridge, got %+v, %v", user, err)
+	}
+
+	token, err := manager.DeviceToken()
+	if err != nil {
+		t.Fatalf("DeviceToken failed: %v", err)
+	}
+	session, err := manager.DeviceLogin(token)
+	if err != nil {
+		t.Fatalf("DeviceLogin failed: %v", err)
+	}
+	if session.UserID != "test-user" || session.Role != string(RoleOwner) {
+		t.Errorf("Expected an owner session for the device user, got %+v", session)
+	}
+
+	// The session works like any other
+	tokens, err := manager.IssueTokens(session)
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	if _, err := manager.ValidateJWT(tokens.AccessToken); err != nil {
+		t.Errorf("Expected the access token to be valid: %v", err)
+	}
+
+	if _, err := manager.DeviceLogin("wbd_wrong"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestWebAuthnManager_ResetDeviceToken(t *testing.T) {
+	manager := newDeviceTestManager(t)
+
+	oldToken, _ := manager.DeviceToken()
+	session, err := manager.SignInDevice()
+	if err != nil {
+		t.Fatalf("SignInDevice failed: %v", err)
+	}
+
+	if err := manager.ResetDeviceToken(); err != nil {
+		t.Fatalf("ResetDeviceToken failed: %v", err)
+	}
+	if _, err := manager.ValidateSession(session.ID); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected device sessions to end, got %v", err)
+	}
+	if _, err := manager.DeviceLogin(oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected the old token to stop working, got %v", err)
+	}
+
+	// The token is kept across restarts rather than replaced
+	newToken, _ := manager.DeviceToken()
+	if err := manager.setupDevice(); err != nil {
+		t.Fatalf("setupDevice failed: %v", err)
+	}
+	if token, _ := manager.DeviceToken(); token != newToken {
+		t.Error("Expected setupDevice to keep the existing token")
+	}
+}
+
+func TestWebAuthnManager_DeviceAuthDisabled(t *testing.T) {
+	manager := newTestManager(t)
+	if _, err := manager.DeviceLogin("wbd_token"); !errors.Is(err, ErrDeviceAuthDisabled) {
+		t.Errorf("Expected ErrDeviceAuthDisabled, got %v", err)
+	}
+	if err := manager.ResetDeviceToken(); !errors.Is(err, ErrDeviceAuthDisabled) {
+		t.Errorf("Expected ErrDeviceAuthDisabled, got %v", err)
+	}
+}
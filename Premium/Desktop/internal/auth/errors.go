@@ -6,6 +6,7 @@ import "fmt"
 var (
 	ErrSessionNotFound     = fmt.Errorf("session not found")
 	ErrSessionExpired      = fmt.Errorf("session expired")
+	ErrSessionRevoked      = fmt.Errorf("session revoked")
 	ErrInvalidCredentials  = fmt.Errorf("invalid credentials")
 	ErrUserNotFound        = fmt.Errorf("user not found")
 	ErrRegistrationFailed  = fmt.Errorf("registration failed")
@@ -13,4 +14,9 @@ var (
 	ErrInvalidToken        = fmt.Errorf("invalid token")
 	ErrTokenExpired        = fmt.Errorf("token expired")
 	ErrPermissionDenied    = fmt.Errorf("permission denied")
+	ErrInvalidRole         = fmt.Errorf("invalid role")
+	ErrLastOwner           = fmt.Errorf("the bridge must keep an owner")
+	ErrCredentialNotFound  = fmt.Errorf("credential not found")
+	ErrLastCredential      = fmt.Errorf("a user must keep a credential")
+	ErrDeviceAuthDisabled  = fmt.Errorf("device authentication is not enabled")
 )
\ No newline at end of file
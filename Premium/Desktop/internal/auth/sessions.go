@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/storage"
+)
+
+// Storage keys of the sessions and of the sessions revoked before they
+// expired
+const (
+	sessionsKey        = "auth_sessions"
+	revokedSessionsKey = "auth_revoked_sessions"
+)
+
+// SessionStore keeps authentication sessions and persists them to
+// storage. Revoked sessions are remembered until they would have expired,
+// so a session revoked before a restart stays revoked after it. It is safe
+// for concurrent use; sessions are returned as copies.
+type SessionStore struct {
+	mu       sync.RWMutex
+	storage  storage.KeyValue
+	logger   *logrus.Logger
+	sessions map[string]*models.AuthSession
+	revoked  map[string]time.Time // session ID to its expiry
+}
+
+// NewSessionStore creates a session store with the unexpired, unrevoked
+// sessions kept in storage
+func NewSessionStore(store storage.KeyValue, logger *logrus.Logger) *SessionStore {
+	s := &SessionStore{
+		storage:  store,
+		logger:   logger,
+		sessions: make(map[string]*models.AuthSession),
+		revoked:  make(map[string]time.Time),
+	}
+	s.load()
+	return s
+}
+
+// Get returns an active session
+func (s *SessionStore) Get(id string) (*models.AuthSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, revoked := s.revoked[id]; revoked {
+		return nil, ErrSessionRevoked
+	}
+	session, exists := s.sessions[id]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	if !time.Now().Before(session.ExpiresAt) {
+		delete(s.sessions, id)
+		s.saveLocked()
+		return nil, ErrSessionExpired
+	}
+
+	copied := *session
+	return &copied, nil
+}
+
+// Put stores a session. Revoked sessions cannot be stored again.
+func (s *SessionStore) Put(session *models.AuthSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, revoked := s.revoked[session.ID]; revoked {
+		return ErrSessionRevoked
+	}
+	copied := *session
+	s.sessions[session.ID] = &copied
+	s.saveLocked()
+	return nil
+}
+
+// Update changes a session with fn. Nothing changes when fn returns an
+// error, which Update returns.
+func (s *SessionStore) Update(id string, fn func(*models.AuthSession) error) (*models.AuthSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[id]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	updated := *session
+	if err := fn(&updated); err != nil {
+		return nil, err
+	}
+	s.sessions[id] = &updated
+	s.saveLocked()
+
+	copied := updated
+	return &copied, nil
+}
+
+// UpdateWhere applies fn to every session and stores the sessions it
+// reports changed
+func (s *SessionStore) UpdateWhere(fn func(*models.AuthSession) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for id, session := range s.sessions {
+		updated := *session
+		if fn(&updated) {
+			s.sessions[id] = &updated
+			changed = true
+		}
+	}
+	if changed {
+		s.saveLocked()
+	}
+}
+
+// Revoke ends a session
+func (s *SessionStore) Revoke(id string) {
+	s.RevokeWhere(func(session *models.AuthSession) bool { return session.ID == id })
+}
+
+// RevokeWhere ends every session matching fn and returns how many ended
+func (s *SessionStore) RevokeWhere(fn func(*models.AuthSession) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for id, session := range s.sessions {
+		if fn(session) {
+			s.revoked[id] = session.ExpiresAt
+			delete(s.sessions, id)
+			count++
+		}
+	}
+	if count > 0 {
+		s.saveLocked()
+	}
+	return count
+}
+
+// Active returns the unexpired sessions
+func (s *SessionStore) Active() []*models.AuthSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	active := make([]*models.AuthSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		if now.Before(session.ExpiresAt) {
+			copied := *session
+			active = append(active, &copied)
+		}
+	}
+	return active
+}
+
+// load reads the sessions and revocations from storage, dropping expired
+// ones
+func (s *SessionStore) load() {
+	now := time.Now()
+
+	if data, err := s.storage.Get(revokedSessionsKey); err == nil {
+		var revoked map[string]time.Time
+		if err := json.Unmarshal(data, &revoked); err != nil {
+			s.logger.WithError(err).Error("Failed to unmarshal revoked sessions")
+		}
+		for id, expiresAt := range revoked {
+			if now.Before(expiresAt) {
+				s.revoked[id] = expiresAt
+			}
+		}
+	}
+
+	data, err := s.storage.Get(sessionsKey)
+	if err != nil {
+		return // No existing sessions
+	}
+
+	var sessions map[string]*models.AuthSession
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		s.logger.WithError(err).Error("Failed to unmarshal sessions")
+		return
+	}
+	for id, session := range sessions {
+		if _, revoked := s.revoked[id]; !revoked && now.Before(session.ExpiresAt) {
+			s.sessions[id] = session
+		}
+	}
+}
+
+// saveLocked writes the sessions and revocations to storage, dropping
+// revocations of sessions that have expired anyway. The caller holds the
+// write lock.
+func (s *SessionStore) saveLocked() {
+	now := time.Now()
+	for id, expiresAt := range s.revoked {
+		if !now.Before(expiresAt) {
+			delete(s.revoked, id)
+		}
+	}
+
+	if data, err := json.Marshal(s.sessions); err != nil {
+		s.logger.WithError(err).Error("Failed to marshal sessions")
+	} else if err := s.storage.Set(sessionsKey, data); err != nil {
+		s.logger.WithError(err).Error("Failed to save sessions")
+	}
+
+	if data, err := json.Marshal(s.revoked); err != nil {
+		s.logger.WithError(err).Error("Failed to marshal revoked sessions")
+	} else if err := s.storage.Set(revokedSessionsKey, data); err != nil {
+		s.logger.WithError(err).Error("Failed to save revoked sessions")
+	}
+}
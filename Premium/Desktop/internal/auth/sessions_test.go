@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/testutils"
+)
+
+func TestSessionStore_RevocationSurvivesRestart(t *testing.T) {
+	store := testutils.NewMockStorage()
+	sessions := NewSessionStore(store, logrus.New())
+
+	expiresAt := time.Now().Add(time.Hour)
+	sessions.Put(&Session{ID: "kept", UserID: "streamer", ExpiresAt: expiresAt})
+	sessions.Put(&Session{ID: "revoked", UserID: "streamer", ExpiresAt: expiresAt})
+	sessions.Revoke("revoked")
+
+	restarted := NewSessionStore(store, logrus.New())
+	if _, err := restarted.Get("kept"); err != nil {
+		t.Errorf("Expected the session to survive a restart: %v", err)
+	}
+	if _, err := restarted.Get("revoked"); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked after a restart, got %v", err)
+	}
+	if err := restarted.Put(&Session{ID: "revoked", ExpiresAt: expiresAt}); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected a revoked session not to be stored again, got %v", err)
+	}
+}
+
+func TestSessionStore_ReturnsCopies(t *testing.T) {
+	sessions := NewSessionStore(testutils.NewMockStorage(), logrus.New())
+	session := &Session{ID: "s1", Role: string(RoleViewer), ExpiresAt: time.Now().Add(time.Hour)}
+	sessions.Put(session)
+
+	session.Role = string(RoleOwner)
+	got, _ := sessions.Get("s1")
+	got.Role = string(RoleOwner)
+	if stored, _ := sessions.Get("s1"); stored.Role != string(RoleViewer) {
+		t.Errorf("Expected the stored session to be unchanged, got role %q", stored.Role)
+	}
+
+	if _, err := sessions.Update("s1", func(*Session) error { return ErrInvalidToken }); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected Update to return the error of fn, got %v", err)
+	}
+}
+
+func TestSessionStore_ConcurrentUse(t *testing.T) {
+	sessions := NewSessionStore(testutils.NewMockStorage(), logrus.New())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("s%d", i)
+			sessions.Put(&Session{ID: id, ExpiresAt: time.Now().Add(time.Hour)})
+			for j := 0; j < 20; j++ {
+				sessions.Get(id)
+				sessions.Active()
+				sessions.Update(id, func(session *Session) error {
+					session.ExpiresAt = session.ExpiresAt.Add(time.Second)
+					return nil
+				})
+			}
+			if i%2 == 0 {
+				sessions.Revoke(id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if active := sessions.Active(); len(active) != 4 {
+		t.Errorf("Expected 4 active sessions, got %d", len(active))
+	}
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// signingKeysKey is the storage key of the encrypted JWT signing keys
+const signingKeysKey = "jwt_signing_keys"
+
+// signingKeyFile is the file in the data directory holding the key that
+// encrypts the stored signing keys
+const signingKeyFile = "jwt-signing.key"
+
+// defaultRotationGrace is how long a replaced signing key keeps validating
+// tokens when no grace period is configured
+const defaultRotationGrace = time.Hour
+
+// ErrStaticSigningKey is returned when rotating the signing key while a
+// jwt-secret is configured
+var ErrStaticSigningKey = errors.New("the JWT signing secret is configured and cannot be rotated")
+
+// signingKey signs session tokens. A replaced key keeps validating tokens
+// until RetiresAt.
+type signingKey struct {
+	ID        string    `json:"id"`
+	Secret    []byte    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+	RetiresAt time.Time `json:"retires_at,omitempty"`
+}
+
+// SigningKeyInfo describes a signing key without its secret
+type SigningKeyInfo struct {
+	ID        string     `json:"id"`
+	Current   bool       `json:"current"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiresAt *time.Time `json:"retires_at,omitempty"`
+}
+
+// signingKeys holds the keys tokens are signed and validated with. The
+// first key signs; the others only validate until they retire.
+type signingKeys struct {
+	mu     sync.RWMutex
+	keys   []signingKey
+	static bool
+}
+
+// loadSigningKeys returns the configured jwt-secret as the only key, or
+// the generated keys kept encrypted in storage, creating the first one
+func (m *WebAuthnManager) loadSigningKeys() (*signingKeys, error) {
+	if m.config.JWTSecret != "" {
+		return &signingKeys{
+			keys:   []signingKey{{ID: "static", Secret: []byte(m.config.JWTSecret)}},
+			static: true,
+		}, nil
+	}
+
+	keys := &signingKeys{}
+	if data, err := m.storage.Get(signingKeysKey); err == nil {
+		plain, err := m.decryptSigningKeys(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(plain, &keys.keys); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal signing keys: %w", err)
+		}
+		keys.prune(time.Now())
+	}
+
+	if len(keys.keys) == 0 {
+		key, err := newSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		keys.keys = []signingKey{key}
+		if err := m.saveSigningKeys(keys.keys); err != nil {
+			return nil, err
+		}
+		m.logger.WithField("key_id", key.ID).Info("Generated JWT signing key")
+	}
+	return keys, nil
+}
+
+// current returns the key new tokens are signed with
+func (k *signingKeys) current() signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[0]
+}
+
+// lookup returns the secret of an unretired key. Tokens signed before keys
+// had IDs are checked against the current key.
+func (k *signingKeys) lookup(id string, now time.Time) ([]byte, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if id == "" {
+		return k.keys[0].Secret, true
+	}
+	for _, key := range k.keys {
+		if key.ID == id && (key.RetiresAt.IsZero() || now.Before(key.RetiresAt)) {
+			return key.Secret, true
+		}
+	}
+	return nil, false
+}
+
+// prune drops retired keys. The caller holds the lock or owns k.
+func (k *signingKeys) prune(now time.Time) {
+	keys := k.keys[:0]
+	for _, key := range k.keys {
+		if key.RetiresAt.IsZero() || now.Before(key.RetiresAt) {
+			keys = append(keys, key)
+		}
+	}
+	k.keys = keys
+}
+
+// SigningKeys describes the keys session tokens are signed and validated
+// with
+func (m *WebAuthnManager) SigningKeys() []SigningKeyInfo {
+	m.signing.mu.RLock()
+	defer m.signing.mu.RUnlock()
+
+	infos := make([]SigningKeyInfo, 0, len(m.signing.keys))
+	for i, key := range m.signing.keys {
+		info := SigningKeyInfo{ID: key.ID, Current: i == 0, CreatedAt: key.CreatedAt}
+		if !key.RetiresAt.IsZero() {
+			retiresAt := key.RetiresAt
+			info.RetiresAt = &retiresAt
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// RotateSigningKey signs new tokens with a new key. Tokens signed with the
+// previous key stay valid for the rotation grace period.
+func (m *WebAuthnManager) RotateSigningKey() (SigningKeyInfo, error) {
+	if m.signing.static {
+		return SigningKeyInfo{}, ErrStaticSigningKey
+	}
+
+	key, err := newSigningKey()
+	if err != nil {
+		return SigningKeyInfo{}, err
+	}
+
+	m.signing.mu.Lock()
+	now := time.Now()
+	m.signing.prune(now)
+	keys := append([]signingKey{key}, m.signing.keys...)
+	for i := range keys[1:] {
+		if keys[i+1].RetiresAt.IsZero() {
+			keys[i+1].RetiresAt = now.Add(m.rotationGrace())
+		}
+	}
+	if err := m.saveSigningKeys(keys); err != nil {
+		m.signing.mu.Unlock()
+		return SigningKeyInfo{}, err
+	}
+	m.signing.keys = keys
+	m.signing.mu.Unlock()
+
+	m.logger.WithField("key_id", key.ID).Info("Rotated JWT signing key")
+	return SigningKeyInfo{ID: key.ID, Current: true, CreatedAt: key.CreatedAt}, nil
+}
+
+// RunKeyRotation rotates the signing key every configured rotation
+// interval until ctx is done. It returns at once when rotation is off or
+// the secret is configured.
+func (m *WebAuthnManager) RunKeyRotation(ctx context.Context) {
+	interval := m.config.Sessions.SigningKeyRotation
+	if interval <= 0 || m.signing.static {
+		return
+	}
+
+	for {
+		wait := time.Until(m.signing.current().CreatedAt.Add(interval))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+			if _, err := m.RotateSigningKey(); err != nil {
+				m.logger.WithError(err).Error("Failed to rotate JWT signing key")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Minute):
+				}
+			}
+		}
+	}
+}
+
+// rotationGrace returns how long replaced keys keep validating tokens. It
+// is never shorter than an access token's lifetime.
+func (m *WebAuthnManager) rotationGrace() time.Duration {
+	grace := m.config.Sessions.SigningKeyGrace
+	if grace <= 0 {
+		grace = defaultRotationGrace
+	}
+	if access := m.lifetimes().AccessTokenLifetime; grace < access {
+		grace = access
+	}
+	return grace
+}
+
+// saveSigningKeys stores the keys encrypted
+func (m *WebAuthnManager) saveSigningKeys(keys []signingKey) error {
+	plain, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signing keys: %w", err)
+	}
+	data, err := m.encryptSigningKeys(plain)
+	if err != nil {
+		return err
+	}
+	if err := m.storage.Set(signingKeysKey, data); err != nil {
+		return fmt.Errorf("failed to store signing keys: %w", err)
+	}
+	return nil
+}
+
+func (m *WebAuthnManager) encryptSigningKeys(plain []byte) ([]byte, error) {
+	aead, err := m.signingKeysCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plain, []byte(signingKeysKey)), nil
+}
+
+func (m *WebAuthnManager) decryptSigningKeys(data []byte) ([]byte, error) {
+	aead, err := m.signingKeysCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("stored signing keys are corrupt")
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(signingKeysKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing keys: %w", err)
+	}
+	return plain, nil
+}
+
+// signingKeysCipher returns the cipher for the stored signing keys. Its
+// key is generated once and kept readable only by the user in the data
+// directory, apart from the database.
+func (m *WebAuthnManager) signingKeysCipher() (cipher.AEAD, error) {
+	path := filepath.Join(m.config.DataDir, signingKeyFile)
+
+	var key []byte
+	if data, err := os.ReadFile(path); err == nil {
+		if key, err = hex.DecodeString(strings.TrimSpace(string(data))); err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid signing key encryption key in %s", path)
+		}
+	} else if os.IsNotExist(err) {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write encryption key: %w", err)
+		}
+	} else {
+		return nil, fmt.Errorf("failed to read encryption key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newSigningKey generates a signing key
+func newSigningKey() (signingKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return signingKey{}, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return signingKey{
+		ID:        uuid.New().String()[:8],
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/testutils"
+)
+
+func TestWebAuthnManager_PersistedSigningKey(t *testing.T) {
+	cfg := testutils.TestConfig()
+	cfg.JWTSecret = ""
+	cfg.DataDir = t.TempDir()
+	store := testutils.NewMockStorage()
+
+	manager, err := NewWebAuthnManager(cfg, store)
+	if err != nil {
+		t.Fatalf("NewWebAuthnManager failed: %v", err)
+	}
+	secret := manager.signing.current().Secret
+
+	stored, err := store.GetWithBucket(storage.AuthBucket, signingKeysKey)
+	if err != nil {
+		t.Fatalf("Expected the signing key to be stored: %v", err)
+	}
+	if bytes.Contains(stored, []byte("secret")) {
+		t.Error("Expected the stored signing keys to be encrypted")
+	}
+
+	session := &Session{ID: "s1", UserID: "streamer", IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	manager.sessions.Put(session)
+	token, err := manager.GenerateJWT(session)
+	if err != nil {
+		t.Fatalf("GenerateJWT failed: %v", err)
+	}
+
+	// A restarted bridge keeps accepting its tokens
+	restarted, err := NewWebAuthnManager(cfg, store)
+	if err != nil {
+		t.Fatalf("NewWebAuthnManager failed: %v", err)
+	}
+	if !bytes.Equal(restarted.signing.current().Secret, secret) {
+		t.Error("Expected the same signing key after a restart")
+	}
+	if _, err := restarted.ValidateJWT(token); err != nil {
+		t.Errorf("Expected the token to survive a restart: %v", err)
+	}
+}
+
+func TestWebAuthnManager_RotateSigningKey(t *testing.T) {
+	cfg := testutils.TestConfig()
+	cfg.JWTSecret = ""
+	cfg.DataDir = t.TempDir()
+	cfg.Sessions.SigningKeyGrace = time.Hour
+	manager, err := NewWebAuthnManager(cfg, testutils.NewMockStorage())
+	if err != nil {
+		t.Fatalf("NewWebAuthnManager failed: %v", err)
+	}
+
+	session := &Session{ID: "s1", UserID: "streamer", IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	manager.sessions.Put(session)
+	oldToken, _ := manager.GenerateJWT(session)
+	oldKey := manager.signing.current().ID
+
+	rotated, err := manager.RotateSigningKey()
+	if err != nil {
+		t.Fatalf("RotateSigningKey failed: %v", err)
+	}
+	if rotated.ID == oldKey || !rotated.Current {
+		t.Errorf("Expected a new current key, got %+v", rotated)
+	}
+
+	keys := manager.SigningKeys()
+	if len(keys) != 2 || keys[1].ID != oldKey || keys[1].RetiresAt == nil {
+		t.Fatalf("Expected the old key to be retiring, got %+v", keys)
+	}
+
+	// Both keys validate during the grace period
+	newToken, _ := manager.GenerateJWT(session)
+	for _, token := range []string{oldToken, newToken} {
+		if _, err := manager.ValidateJWT(token); err != nil {
+			t.Errorf("Expected the token to be valid during the grace period: %v", err)
+		}
+	}
+
+	// Once retired, the old key no longer validates
+	manager.signing.keys[1].RetiresAt = time.Now().Add(-time.Second)
+	if _, err := manager.ValidateJWT(oldToken); err == nil {
+		t.Error("Expected tokens of a retired key to be rejected")
+	}
+	if _, err := manager.ValidateJWT(newToken); err != nil {
+		t.Errorf("Expected tokens of the current key to stay valid: %v", err)
+	}
+}
+
+func TestWebAuthnManager_StaticSigningKey(t *testing.T) {
+	manager := newTestManager(t)
+	if _, err := manager.RotateSigningKey(); !errors.Is(err, ErrStaticSigningKey) {
+		t.Errorf("Expected ErrStaticSigningKey with a configured secret, got %v", err)
+	}
+	if keys := manager.SigningKeys(); len(keys) != 1 || !keys[0].Current {
+		t.Errorf("Expected the configured secret as the only key, got %+v", keys)
+	}
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/models"
+)
+
+// Session lifetimes used when the configuration leaves them unset
+const (
+	defaultAccessTokenLifetime  = 15 * time.Minute
+	defaultRefreshTokenLifetime = 24 * time.Hour
+	defaultMaxSessionLifetime   = 30 * 24 * time.Hour
+)
+
+// keepAliveInterval limits how often KeepAlive persists a later expiry
+const keepAliveInterval = time.Minute
+
+// TokenPair is what a client holds for a session: a short-lived access
+// token and the refresh token that renews it
+type TokenPair struct {
+	AccessToken    string    `json:"token"`
+	RefreshToken   string    `json:"refresh_token"`
+	ExpiresIn      int64     `json:"expires_in"` // seconds until the access token expires
+	SessionExpires time.Time `json:"session_expires"`
+}
+
+// lifetimes returns the configured session lifetimes with defaults for
+// those left unset
+func (m *WebAuthnManager) lifetimes() config.SessionConfig {
+	lifetimes := m.config.Sessions
+	if lifetimes.AccessTokenLifetime <= 0 {
+		lifetimes.AccessTokenLifetime = defaultAccessTokenLifetime
+	}
+	if lifetimes.RefreshTokenLifetime <= 0 {
+		lifetimes.RefreshTokenLifetime = defaultRefreshTokenLifetime
+	}
+	if lifetimes.MaxLifetime <= 0 {
+		lifetimes.MaxLifetime = defaultMaxSessionLifetime
+	}
+	return lifetimes
+}
+
+// startSession signs a user in with one of its credentials
+func (m *WebAuthnManager) startSession(user *User, credentialID []byte) *models.AuthSession {
+	now := time.Now()
+	session := &models.AuthSession{
+		ID:          uuid.New().String(),
+		UserID:      string(user.ID),
+		CommunityID: user.CommunityID,
+		Role:        string(user.role()),
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(m.lifetimes().RefreshTokenLifetime),
+		Credential:  credentialID,
+	}
+
+	if err := m.sessions.Put(session); err != nil {
+		m.logger.WithError(err).Error("Failed to store session")
+	}
+	return session
+}
+
+// errRefreshReused reports an exchanged refresh token used again
+var errRefreshReused = fmt.Errorf("refresh token reused")
+
+// IssueTokens returns a new access token and refresh token for a session.
+// Earlier refresh tokens of the session stop working.
+func (m *WebAuthnManager) IssueTokens(session *models.AuthSession) (TokenPair, error) {
+	return m.issueTokens(session, nil)
+}
+
+// issueTokens rotates the session's refresh token and issues tokens for
+// it. check runs on the stored session first, under the store's lock, so
+// that two exchanges of the same refresh token cannot both succeed.
+func (m *WebAuthnManager) issueTokens(session *models.AuthSession, check func(*models.AuthSession) error) (TokenPair, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return TokenPair{}, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	updated, err := m.sessions.Update(session.ID, func(stored *models.AuthSession) error {
+		if check != nil {
+			if err := check(stored); err != nil {
+				return err
+			}
+		}
+		stored.PreviousRefreshHash = stored.RefreshHash
+		stored.RefreshHash = hashRefreshSecret(refreshSecret)
+		return nil
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+	*session = *updated
+
+	accessToken, err := m.GenerateJWT(session)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	now := time.Now()
+	return TokenPair{
+		AccessToken:    accessToken,
+		RefreshToken:   session.ID + "." + refreshSecret,
+		ExpiresIn:      int64(m.accessExpiry(session, now).Sub(now).Seconds()),
+		SessionExpires: session.ExpiresAt,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for new tokens and extends the
+// session. A refresh token that was already exchanged ends the session,
+// as it has probably been stolen.
+func (m *WebAuthnManager) Refresh(refreshToken string) (TokenPair, *models.AuthSession, error) {
+	sessionID, secret, found := strings.Cut(refreshToken, ".")
+	if !found {
+		return TokenPair{}, nil, ErrInvalidToken
+	}
+
+	session, err := m.ValidateSession(sessionID)
+	if err != nil {
+		return TokenPair{}, nil, ErrInvalidToken
+	}
+
+	hash := hashRefreshSecret(secret)
+	tokens, err := m.issueTokens(session, func(stored *models.AuthSession) error {
+		switch {
+		case subtle.ConstantTimeCompare([]byte(hash), []byte(stored.RefreshHash)) == 1:
+		case subtle.ConstantTimeCompare([]byte(hash), []byte(stored.PreviousRefreshHash)) == 1:
+			return errRefreshReused
+		default:
+			return ErrInvalidToken
+		}
+		m.extend(stored, time.Now())
+		return nil
+	})
+	switch {
+	case errors.Is(err, errRefreshReused):
+		m.logger.WithFields(logrus.Fields{
+			"user_id":    session.UserID,
+			"session_id": session.ID,
+		}).Warn("Refresh token reused, ending session")
+		m.RevokeSession(session.ID)
+		return TokenPair{}, nil, ErrInvalidToken
+	case errors.Is(err, ErrSessionNotFound):
+		return TokenPair{}, nil, ErrInvalidToken
+	case err != nil:
+		return TokenPair{}, nil, err
+	}
+	return tokens, session, nil
+}
+
+// KeepAlive extends a session the bridge itself is using, so it does not
+// expire while the bridge runs
+func (m *WebAuthnManager) KeepAlive(session *models.AuthSession) {
+	now := time.Now()
+	remaining := session.ExpiresAt.Sub(now)
+	if m.lifetimes().RefreshTokenLifetime-remaining < keepAliveInterval {
+		return
+	}
+
+	updated, err := m.sessions.Update(session.ID, func(stored *models.AuthSession) error {
+		m.extend(stored, now)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	*session = *updated
+}
+
+// extend moves the session's expiry to a refresh token lifetime from now,
+// but no further than the maximum lifetime after sign in
+func (m *WebAuthnManager) extend(session *models.AuthSession, now time.Time) {
+	lifetimes := m.lifetimes()
+	expiresAt := now.Add(lifetimes.RefreshTokenLifetime)
+	if limit := session.IssuedAt.Add(lifetimes.MaxLifetime); expiresAt.After(limit) {
+		expiresAt = limit
+	}
+	if expiresAt.After(session.ExpiresAt) {
+		session.ExpiresAt = expiresAt
+	}
+}
+
+// accessExpiry returns when an access token issued now for the session
+// expires: after the access token lifetime, or with the session
+func (m *WebAuthnManager) accessExpiry(session *models.AuthSession, now time.Time) time.Time {
+	expiresAt := now.Add(m.lifetimes().AccessTokenLifetime)
+	if session.ExpiresAt.Before(expiresAt) {
+		return session.ExpiresAt
+	}
+	return expiresAt
+}
+
+// hashRefreshSecret hashes the secret part of a refresh token for storage
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func TestWebAuthnManager_RefreshTokens(t *testing.T) {
+	manager := newTestManager(t)
+	manager.config.Sessions = config.SessionConfig{
+		AccessTokenLifetime:  5 * time.Minute,
+		RefreshTokenLifetime: time.Hour,
+		MaxLifetime:          3 * time.Hour,
+	}
+
+	session := manager.startSession(&User{ID: []byte("streamer"), Role: RoleOperator}, []byte("laptop"))
+	if until := time.Until(session.ExpiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("Expected the session to last the refresh token lifetime, got %s", until)
+	}
+
+	tokens, err := manager.IssueTokens(session)
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	if tokens.ExpiresIn > 300 || tokens.ExpiresIn < 290 {
+		t.Errorf("Expected a 5 minute access token, got %d seconds", tokens.ExpiresIn)
+	}
+
+	claims := jwt.MapClaims{}
+	jwt.ParseWithClaims(tokens.AccessToken, claims, func(*jwt.Token) (interface{}, error) { return manager.signing.current().Secret, nil })
+	if exp, _ := claims.GetExpirationTime(); exp == nil || time.Until(exp.Time) > 5*time.Minute {
+		t.Errorf("Expected the access token to expire within 5 minutes, got %v", exp)
+	}
+	if validated, err := manager.ValidateJWT(tokens.AccessToken); err != nil || validated.ID != session.ID {
+		t.Fatalf("Expected the access token to be valid: %v", err)
+	}
+
+	// Refreshing slides the expiry and rotates the refresh token
+	manager.sessions.Update(session.ID, func(stored *Session) error {
+		stored.ExpiresAt = time.Now().Add(10 * time.Minute)
+		return nil
+	})
+	refreshed, extended, err := manager.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if time.Until(extended.ExpiresAt) < 59*time.Minute {
+		t.Errorf("Expected the refresh to extend the session, expires %s", extended.ExpiresAt)
+	}
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Error("Expected a new refresh token")
+	}
+
+	// Reusing the exchanged refresh token ends the session
+	if _, _, err := manager.Refresh(tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for a reused refresh token, got %v", err)
+	}
+	if _, err := manager.ValidateSession(session.ID); err == nil {
+		t.Error("Expected the session to end after refresh token reuse")
+	}
+	if _, _, err := manager.Refresh(refreshed.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken once the session ended, got %v", err)
+	}
+	if _, _, err := manager.Refresh("not-a-token"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestWebAuthnManager_MaxSessionLifetime(t *testing.T) {
+	manager := newTestManager(t)
+	manager.config.Sessions = config.SessionConfig{
+		RefreshTokenLifetime: time.Hour,
+		MaxLifetime:          3 * time.Hour,
+	}
+
+	now := time.Now()
+	session := &Session{ID: "long", UserID: "streamer", IssuedAt: now.Add(-150 * time.Minute), ExpiresAt: now.Add(time.Minute)}
+	manager.sessions.Put(session)
+
+	manager.KeepAlive(session)
+	if want := session.IssuedAt.Add(3 * time.Hour); !session.ExpiresAt.Equal(want) {
+		t.Errorf("Expected the session to end at its maximum lifetime %s, got %s", want, session.ExpiresAt)
+	}
+
+	// Access tokens never outlive their session
+	if expiry := manager.accessExpiry(session, now); !expiry.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("Expected the default access token lifetime, got %s", expiry.Sub(now))
+	}
+	session.ExpiresAt = now.Add(time.Minute)
+	if expiry := manager.accessExpiry(session, now); !expiry.Equal(session.ExpiresAt) {
+		t.Errorf("Expected the access token to expire with the session, got %s", expiry)
+	}
+}
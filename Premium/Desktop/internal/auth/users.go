@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"waddlebot-bridge/internal/models"
+)
+
+// userPrefix starts the storage key of every registered user
+const userPrefix = "user_"
+
+// Role is what a user may do with the bridge
+type Role string
+
+// Roles from most to least privileged. Owners manage the bridge and its
+// users, operators control OBS and run commands, viewers only watch.
+const (
+	RoleOwner    Role = "owner"
+	RoleOperator Role = "operator"
+	RoleViewer   Role = "viewer"
+)
+
+// Roles lists every role a user can have
+var Roles = []Role{RoleOwner, RoleOperator, RoleViewer}
+
+// ParseRole returns the role named s
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	if !role.Valid() {
+		return "", fmt.Errorf("%w: %s", ErrInvalidRole, s)
+	}
+	return role, nil
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	for _, role := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// role returns the user's role. Users registered before roles existed
+// were the only user, so they own the bridge.
+func (u *User) role() Role {
+	if u.Role == "" {
+		return RoleOwner
+	}
+	return u.Role
+}
+
+// UserInfo describes a registered user without its credentials
+type UserInfo struct {
+	ID          string    `json:"id"`
+	DisplayName string    `json:"display_name"`
+	CommunityID string    `json:"community_id"`
+	Role        Role      `json:"role"`
+	Credentials int       `json:"credentials"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+}
+
+func (u *User) info() UserInfo {
+	return UserInfo{
+		ID:          string(u.ID),
+		DisplayName: u.DisplayName,
+		CommunityID: u.CommunityID,
+		Role:        u.role(),
+		Credentials: len(u.Credentials),
+		CreatedAt:   u.CreatedAt,
+	}
+}
+
+// HasUsers reports whether any user has completed registration
+func (m *WebAuthnManager) HasUsers() bool {
+	keys, err := m.storage.List(userPrefix)
+	return err == nil && len(keys) > 0
+}
+
+// ListUsers returns every registered user, ordered by ID
+func (m *WebAuthnManager) ListUsers() ([]UserInfo, error) {
+	users, err := m.users()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]UserInfo, 0, len(users))
+	for _, user := range users {
+		infos = append(infos, user.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos, nil
+}
+
+// GetUser returns a registered user
+func (m *WebAuthnManager) GetUser(userID string) (UserInfo, error) {
+	user, exists := m.getUserByID(userID)
+	if !exists {
+		return UserInfo{}, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+	return user.info(), nil
+}
+
+// SetUserRole changes a user's role, including the role of its active
+// sessions. The last owner cannot be demoted.
+func (m *WebAuthnManager) SetUserRole(userID string, role Role) error {
+	if !role.Valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+
+	user, exists := m.getUserByID(userID)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+	if user.role() == RoleOwner && role != RoleOwner {
+		if err := m.checkOtherOwner(userID); err != nil {
+			return err
+		}
+	}
+
+	user.Role = role
+	if err := m.saveUser(user); err != nil {
+		return err
+	}
+
+	m.sessions.UpdateWhere(func(session *models.AuthSession) bool {
+		if session.UserID != userID {
+			return false
+		}
+		session.Role = string(role)
+		return true
+	})
+
+	m.logger.WithField("user_id", userID).WithField("role", role).Info("Changed user role")
+	return nil
+}
+
+// DeleteUser removes a user, its credentials and its sessions. The last
+// owner cannot be removed.
+func (m *WebAuthnManager) DeleteUser(userID string) error {
+	user, exists := m.getUserByID(userID)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+	if user.role() == RoleOwner {
+		if err := m.checkOtherOwner(userID); err != nil {
+			return err
+		}
+	}
+
+	if err := m.storage.Delete(userPrefix + userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	m.sessions.RevokeWhere(func(session *models.AuthSession) bool {
+		return session.UserID == userID
+	})
+
+	m.logger.WithField("user_id", userID).Info("Deleted user")
+	return nil
+}
+
+// checkOtherOwner returns ErrLastOwner unless a user other than userID
+// owns the bridge
+func (m *WebAuthnManager) checkOtherOwner(userID string) error {
+	users, err := m.users()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if string(user.ID) != userID && user.role() == RoleOwner {
+			return nil
+		}
+	}
+	return ErrLastOwner
+}
+
+// users loads every registered user
+func (m *WebAuthnManager) users() ([]*User, error) {
+	keys, err := m.storage.List(userPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	users := make([]*User, 0, len(keys))
+	for _, key := range keys {
+		if user, exists := m.getUserByID(strings.TrimPrefix(key, userPrefix)); exists {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// saveUser stores a registered user
+func (m *WebAuthnManager) saveUser(user *User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user: %w", err)
+	}
+	if err := m.storage.Set(userPrefix+string(user.ID), data); err != nil {
+		return fmt.Errorf("failed to store user: %w", err)
+	}
+	return nil
+}
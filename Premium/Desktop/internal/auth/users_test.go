@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/testutils"
+)
+
+func newTestManager(t *testing.T) *WebAuthnManager {
+	t.Helper()
+	manager, err := NewWebAuthnManager(testutils.TestConfig(), testutils.NewMockStorage())
+	if err != nil {
+		t.Fatalf("NewWebAuthnManager failed: %v", err)
+	}
+	return manager
+}
+
+func addTestUser(t *testing.T, manager *WebAuthnManager, id string, role Role) {
+	t.Helper()
+	if err := manager.saveUser(&User{ID: []byte(id), Name: id, Role: role}); err != nil {
+		t.Fatalf("saveUser failed: %v", err)
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	if role, err := ParseRole(" Operator "); err != nil || role != RoleOperator {
+		t.Errorf("Expected operator, got %q: %v", role, err)
+	}
+	if _, err := ParseRole("admin"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("Expected ErrInvalidRole, got %v", err)
+	}
+}
+
+func TestWebAuthnManager_RegistrationRoles(t *testing.T) {
+	manager := newTestManager(t)
+
+	// The first user owns the bridge whatever role is asked for
+	if _, err := manager.StartRegistrationWithRole("streamer", "community", RoleViewer); err != nil {
+		t.Fatalf("StartRegistrationWithRole failed: %v", err)
+	}
+	user := pendingUser(t, manager, "streamer")
+	if user.Role != RoleOwner {
+		t.Errorf("Expected the first user to be the owner, got %q", user.Role)
+	}
+
+	addTestUser(t, manager, "streamer", RoleOwner)
+	if _, err := manager.StartRegistration("cohost", "community"); err != nil {
+		t.Fatalf("StartRegistration failed: %v", err)
+	}
+	if user := pendingUser(t, manager, "cohost"); user.Role != RoleViewer {
+		t.Errorf("Expected later users to default to viewer, got %q", user.Role)
+	}
+
+	if _, err := manager.StartRegistrationWithRole("mod", "community", Role("admin")); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("Expected ErrInvalidRole, got %v", err)
+	}
+	if _, err := manager.StartCredentialRegistration("nobody"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func pendingUser(t *testing.T, m *WebAuthnManager, id string) *User {
+	t.Helper()
+	data, err := m.storage.Get("temp_user_" + id)
+	if err != nil {
+		t.Fatalf("Expected a pending user %s: %v", id, err)
+	}
+	var user User
+	if err := json.Unmarshal(data, &user); err != nil {
+		t.Fatalf("Failed to decode pending user: %v", err)
+	}
+	return &user
+}
+
+func TestWebAuthnManager_Users(t *testing.T) {
+	manager := newTestManager(t)
+	addTestUser(t, manager, "streamer", "")
+	addTestUser(t, manager, "cohost", RoleOperator)
+
+	users, err := manager.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 2 || users[0].ID != "cohost" || users[1].Role != RoleOwner {
+		t.Errorf("Expected the co-host and the legacy user as owner, got %+v", users)
+	}
+
+	manager.sessions.Put(&Session{ID: "s1", UserID: "cohost", Role: string(RoleOperator), ExpiresAt: time.Now().Add(time.Hour)})
+	if err := manager.SetUserRole("cohost", RoleViewer); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	if session, _ := manager.sessions.Get("s1"); session == nil || session.Role != string(RoleViewer) {
+		t.Errorf("Expected the session role to follow the user, got %+v", session)
+	}
+
+	if err := manager.SetUserRole("streamer", RoleOperator); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("Expected ErrLastOwner when demoting the last owner, got %v", err)
+	}
+	if err := manager.DeleteUser("streamer"); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("Expected ErrLastOwner when deleting the last owner, got %v", err)
+	}
+
+	if err := manager.DeleteUser("cohost"); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if _, err := manager.sessions.Get("s1"); !errors.Is(err, ErrSessionRevoked) {
+		t.Error("Expected the deleted user's sessions to be revoked")
+	}
+	if _, err := manager.GetUser("cohost"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestWebAuthnManager_CurrentSessionPrefersOwner(t *testing.T) {
+	manager := newTestManager(t)
+	manager.config.UserID = ""
+
+	now := time.Now()
+	manager.sessions.Put(&Session{ID: "owner", UserID: "streamer", Role: string(RoleOwner), IssuedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)})
+	manager.sessions.Put(&Session{ID: "viewer", UserID: "guest", Role: string(RoleViewer), IssuedAt: now, ExpiresAt: now.Add(time.Hour)})
+
+	for i := 0; i < 10; i++ {
+		if session := manager.GetCurrentSession(); session.ID != "owner" {
+			t.Fatalf("Expected the owner's session, got %s", session.ID)
+		}
+	}
+
+	manager.config.UserID = "guest"
+	if session := manager.GetCurrentSession(); session.ID != "viewer" {
+		t.Errorf("Expected the configured user's session, got %s", session.ID)
+	}
+}
@@ -8,22 +8,27 @@ import (
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/keychain"
 	"waddlebot-bridge/internal/logger"
 	"waddlebot-bridge/internal/models"
 	"waddlebot-bridge/internal/storage"
 )
 
+// ceremonyTTL is how long an unfinished registration or sign-in is kept
+// before storage purges it
+const ceremonyTTL = 15 * time.Minute
+
 // WebAuthnManager handles WebAuthn authentication
 type WebAuthnManager struct {
 	config     *config.Config
-	storage    storage.Storage
+	storage    *storage.AuthRepo
 	webauthn   *webauthn.WebAuthn
 	logger     *logrus.Logger
-	sessions   map[string]*models.AuthSession
-	jwtSecret  []byte
+	sessions   *SessionStore
+	signing    *signingKeys
+	keychain   keychain.Keychain // holds the device token, nil unless auth-method is device
 }
 
 // Session is an alias for models.AuthSession to avoid package name stuttering
@@ -35,7 +40,13 @@ type User struct {
 	Name        string `json:"name"`
 	DisplayName string `json:"display_name"`
 	CommunityID string `json:"community_id"`
+	Role        Role   `json:"role,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
 	Credentials []webauthn.Credential `json:"credentials"`
+
+	// CredentialMeta holds when each credential was added and last used,
+	// keyed by credential ID
+	CredentialMeta map[string]CredentialMeta `json:"credential_meta,omitempty"`
 }
 
 // WebAuthnID returns the user's WebAuthn ID
@@ -67,10 +78,14 @@ func (u *User) WebAuthnIcon() string {
 func NewWebAuthnManager(cfg *config.Config, store storage.Storage) (*WebAuthnManager, error) {
 	// Configure WebAuthn
 	timeoutDuration := time.Duration(cfg.WebAuthnTimeout) * time.Second
+	rpID := cfg.WebAuthnRPID
+	if rpID == "" {
+		rpID = "localhost"
+	}
 	wconfig := &webauthn.Config{
 		RPDisplayName: cfg.WebAuthnDisplayName,
-		RPID:          "localhost",
-		RPOrigins:     []string{cfg.GetWebAuthnURL()},
+		RPID:          rpID,
+		RPOrigins:     cfg.GetWebAuthnOrigins(),
 		AuthenticatorSelection: protocol.AuthenticatorSelection{
 			ResidentKey:      protocol.ResidentKeyRequirementDiscouraged,
 			UserVerification: protocol.VerificationRequired,
@@ -91,45 +106,139 @@ func NewWebAuthnManager(cfg *config.Config, store storage.Storage) (*WebAuthnMan
 		return nil, fmt.Errorf("failed to create WebAuthn instance: %w", err)
 	}
 
-	// Generate JWT secret if not provided
-	jwtSecret := []byte(cfg.JWTSecret)
-	if len(jwtSecret) == 0 {
-		jwtSecret = []byte(uuid.New().String())
+	// Auth data lives in its own bucket; keys kept in the default bucket
+	// by earlier versions are moved there
+	repo, err := storage.NewAuthRepo(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open auth storage: %w", err)
 	}
 
 	manager := &WebAuthnManager{
-		config:    cfg,
-		storage:   store,
-		webauthn:  webAuthn,
-		logger:    logger.GetLogger(),
-		sessions:  make(map[string]*Session),
-		jwtSecret: jwtSecret,
+		config:   cfg,
+		storage:  repo,
+		webauthn: webAuthn,
+		logger:   logger.GetLogger(),
+		sessions: NewSessionStore(repo, logger.GetLogger()),
 	}
 
-	// Load existing sessions from storage
-	manager.loadSessions()
+	// Use the configured JWT secret, or keys generated and kept in storage
+	// so tokens outlive a restart
+	if manager.signing, err = manager.loadSigningKeys(); err != nil {
+		return nil, fmt.Errorf("failed to load JWT signing keys: %w", err)
+	}
+
+	// Sessions from before roles existed take the role of their user
+	manager.sessions.UpdateWhere(func(session *models.AuthSession) bool {
+		if session.Role != "" {
+			return false
+		}
+		user, exists := manager.getUserByID(session.UserID)
+		if !exists {
+			return false
+		}
+		session.Role = string(user.role())
+		return true
+	})
+
+	// Machines without a browser sign in with a device token kept in the
+	// OS keychain
+	switch cfg.AuthMethod {
+	case "", AuthMethodWebAuthn:
+	case AuthMethodDevice:
+		manager.keychain = keychain.New(keychain.Service, cfg.DataDir)
+		if err := manager.setupDevice(); err != nil {
+			return nil, fmt.Errorf("failed to set up device authentication: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth-method %q", cfg.AuthMethod)
+	}
 
 	return manager, nil
 }
 
-// StartRegistration starts the WebAuthn registration process
+// StartRegistration starts the WebAuthn registration process for a new
+// user with the default role
 func (m *WebAuthnManager) StartRegistration(userID, communityID string) (*protocol.CredentialCreation, error) {
+	return m.StartRegistrationWithRole(userID, communityID, "")
+}
+
+// StartRegistrationWithRole starts the WebAuthn registration process for a
+// new user with the given role. The first user is always the owner; later
+// users default to viewer.
+func (m *WebAuthnManager) StartRegistrationWithRole(userID, communityID string, role Role) (*protocol.CredentialCreation, error) {
 	// Check if user is already registered
 	if _, exists := m.getUserByID(userID); exists {
 		return nil, fmt.Errorf("user %s is already registered", userID)
 	}
 
+	switch {
+	case !m.HasUsers():
+		role = RoleOwner
+	case role == "":
+		role = RoleViewer
+	case !role.Valid():
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+
 	// Create new user
 	user := &User{
 		ID:          []byte(userID),
 		Name:        userID,
 		DisplayName: fmt.Sprintf("User %s", userID),
 		CommunityID: communityID,
+		Role:        role,
+		CreatedAt:   time.Now(),
 		Credentials: []webauthn.Credential{},
 	}
 
+	creation, err := m.beginRegistration(user)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"user_id":      userID,
+		"community_id": communityID,
+		"role":         role,
+	}).Info("Started WebAuthn registration")
+
+	return creation, nil
+}
+
+// StartCredentialRegistration starts registering another credential, such
+// as a second device, for an existing user
+func (m *WebAuthnManager) StartCredentialRegistration(userID string) (*protocol.CredentialCreation, error) {
+	user, exists := m.getUserByID(userID)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+
+	creation, err := m.beginRegistration(user)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"credentials": len(user.Credentials),
+	}).Info("Started WebAuthn credential registration")
+
+	return creation, nil
+}
+
+// beginRegistration begins registering a credential for user, excluding
+// the credentials it already has, and stores the user until the
+// registration completes
+func (m *WebAuthnManager) beginRegistration(user *User) (*protocol.CredentialCreation, error) {
+	userID := string(user.ID)
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.Credentials))
+	for _, credential := range user.Credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
 	// Begin registration
-	creation, session, err := m.webauthn.BeginRegistration(user)
+	creation, session, err := m.webauthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin registration: %w", err)
 	}
@@ -141,7 +250,7 @@ func (m *WebAuthnManager) StartRegistration(userID, communityID string) (*protoc
 	}
 
 	key := fmt.Sprintf("registration_session_%s", userID)
-	if err := m.storage.Set(key, sessionData); err != nil {
+	if err := m.storage.SetWithTTL(key, sessionData, ceremonyTTL); err != nil {
 		return nil, fmt.Errorf("failed to store session: %w", err)
 	}
 
@@ -152,95 +261,86 @@ func (m *WebAuthnManager) StartRegistration(userID, communityID string) (*protoc
 	}
 
 	userKey := fmt.Sprintf("temp_user_%s", userID)
-	if err := m.storage.Set(userKey, userData); err != nil {
+	if err := m.storage.SetWithTTL(userKey, userData, ceremonyTTL); err != nil {
 		return nil, fmt.Errorf("failed to store user: %w", err)
 	}
 
+	return creation, nil
+}
+
+// CompleteRegistration completes the WebAuthn registration process and
+// signs the user in
+func (m *WebAuthnManager) CompleteRegistration(userID string, response []byte) (*models.AuthSession, error) {
+	user, credential, err := m.finishRegistration(userID, response)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create auth session
+	authSession := m.startSession(user, credential.ID)
+
 	m.logger.WithFields(logrus.Fields{
 		"user_id":      userID,
-		"community_id": communityID,
-	}).Info("Started WebAuthn registration")
+		"community_id": user.CommunityID,
+		"session_id":   authSession.ID,
+	}).Info("Completed WebAuthn registration")
 
-	return creation, nil
+	return authSession, nil
 }
 
-// CompleteRegistration completes the WebAuthn registration process
-func (m *WebAuthnManager) CompleteRegistration(userID string, response []byte) (*models.AuthSession, error) {
+// finishRegistration verifies a credential creation response and stores
+// the new credential with the user
+func (m *WebAuthnManager) finishRegistration(userID string, response []byte) (*User, *webauthn.Credential, error) {
 	// Get stored session
 	sessionKey := fmt.Sprintf("registration_session_%s", userID)
 	sessionData, err := m.storage.Get(sessionKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		return nil, nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
 	var session webauthn.SessionData
 	if err := json.Unmarshal(sessionData, &session); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal session: %w", err)
 	}
 
 	// Get temporary user
 	userKey := fmt.Sprintf("temp_user_%s", userID)
 	userData, err := m.storage.Get(userKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	var user User
 	if err := json.Unmarshal(userData, &user); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal user: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal user: %w", err)
 	}
 
 	// Parse the credential creation response
 	parsedResponse, err := protocol.ParseCredentialCreationResponseBytes(response)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse credential response: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse credential response: %w", err)
 	}
 
 	// Complete registration
 	credential, err := m.webauthn.CreateCredential(&user, session, parsedResponse)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create credential: %w", err)
+		return nil, nil, fmt.Errorf("failed to create credential: %w", err)
 	}
 
 	// Add credential to user
 	user.Credentials = append(user.Credentials, *credential)
+	user.setCredentialMeta(credential.ID, CredentialMeta{CreatedAt: time.Now()})
 
 	// Store user permanently
-	finalUserData, err := json.Marshal(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal final user: %w", err)
-	}
-
-	permanentUserKey := fmt.Sprintf("user_%s", userID)
-	if err := m.storage.Set(permanentUserKey, finalUserData); err != nil {
-		return nil, fmt.Errorf("failed to store final user: %w", err)
+	if err := m.saveUser(&user); err != nil {
+		return nil, nil, err
 	}
 
 	// Clean up temporary data
 	m.storage.Delete(sessionKey)
 	m.storage.Delete(userKey)
 
-	// Create auth session
-	authSession := &models.AuthSession{
-		ID:          uuid.New().String(),
-		UserID:      userID,
-		CommunityID: user.CommunityID,
-		IssuedAt:    time.Now(),
-		ExpiresAt:   time.Now().Add(24 * time.Hour),
-		Credential:  credential.ID,
-	}
-
-	// Store auth session
-	m.sessions[authSession.ID] = authSession
-	m.saveSessions()
-
-	m.logger.WithFields(logrus.Fields{
-		"user_id":      userID,
-		"community_id": user.CommunityID,
-		"session_id":   authSession.ID,
-	}).Info("Completed WebAuthn registration")
-
-	return authSession, nil
+	return &user, credential, nil
 }
 
 // StartAuthentication starts the WebAuthn authentication process
@@ -264,7 +364,7 @@ func (m *WebAuthnManager) StartAuthentication(userID string) (*protocol.Credenti
 	}
 
 	key := fmt.Sprintf("auth_session_%s", userID)
-	if err := m.storage.Set(key, sessionData); err != nil {
+	if err := m.storage.SetWithTTL(key, sessionData, ceremonyTTL); err != nil {
 		return nil, fmt.Errorf("failed to store session: %w", err)
 	}
 
@@ -311,19 +411,11 @@ func (m *WebAuthnManager) CompleteAuthentication(userID string, response []byte)
 	// Clean up session
 	m.storage.Delete(sessionKey)
 
-	// Create auth session
-	authSession := &models.AuthSession{
-		ID:          uuid.New().String(),
-		UserID:      userID,
-		CommunityID: user.CommunityID,
-		IssuedAt:    time.Now(),
-		ExpiresAt:   time.Now().Add(24 * time.Hour),
-		Credential:  credential.ID,
-	}
+	// Keep the signature counter and note when the credential was used
+	m.recordCredentialUse(user, credential)
 
-	// Store auth session
-	m.sessions[authSession.ID] = authSession
-	m.saveSessions()
+	// Create auth session
+	authSession := m.startSession(user, credential.ID)
 
 	m.logger.WithFields(logrus.Fields{
 		"user_id":      userID,
@@ -336,32 +428,42 @@ func (m *WebAuthnManager) CompleteAuthentication(userID string, response []byte)
 
 // ValidateSession validates an authentication session
 func (m *WebAuthnManager) ValidateSession(sessionID string) (*models.AuthSession, error) {
-	session, exists := m.sessions[sessionID]
-	if !exists {
-		return nil, fmt.Errorf("session not found")
-	}
-
-	if time.Now().After(session.ExpiresAt) {
-		delete(m.sessions, sessionID)
-		m.saveSessions()
-		return nil, fmt.Errorf("session expired")
-	}
-
-	return session, nil
+	return m.sessions.Get(sessionID)
 }
 
 // GenerateJWT generates a JWT token for the session
 func (m *WebAuthnManager) GenerateJWT(session *models.AuthSession) (string, error) {
+	return m.GenerateCommunityJWT(session, session.CommunityID, session.UserID, nil)
+}
+
+// GenerateCommunityJWT generates a short-lived JWT token for the session
+// that acts in another community, signed with that community's secret. A nil secret
+// uses the manager's current signing key.
+func (m *WebAuthnManager) GenerateCommunityJWT(session *models.AuthSession, communityID, userID string, secret []byte) (string, error) {
+	keyID := ""
+	if len(secret) == 0 {
+		key := m.signing.current()
+		secret = key.Secret
+		if !m.signing.static {
+			keyID = key.ID
+		}
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub":          session.UserID,
-		"community_id": session.CommunityID,
+		"sub":          userID,
+		"community_id": communityID,
 		"session_id":   session.ID,
-		"iat":          session.IssuedAt.Unix(),
-		"exp":          session.ExpiresAt.Unix(),
+		"role":         session.Role,
+		"iat":          now.Unix(),
+		"exp":          m.accessExpiry(session, now).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.jwtSecret)
+	if keyID != "" {
+		token.Header["kid"] = keyID
+	}
+	return token.SignedString(secret)
 }
 
 // ValidateJWT validates a JWT token
@@ -370,7 +472,12 @@ func (m *WebAuthnManager) ValidateJWT(tokenString string) (*models.AuthSession,
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return m.jwtSecret, nil
+		keyID, _ := token.Header["kid"].(string)
+		secret, ok := m.signing.lookup(keyID, time.Now())
+		if !ok {
+			return nil, fmt.Errorf("unknown or retired signing key %q", keyID)
+		}
+		return secret, nil
 	})
 
 	if err != nil {
@@ -391,9 +498,8 @@ func (m *WebAuthnManager) ValidateJWT(tokenString string) (*models.AuthSession,
 
 // RevokeSession revokes an authentication session
 func (m *WebAuthnManager) RevokeSession(sessionID string) error {
-	delete(m.sessions, sessionID)
-	m.saveSessions()
-	
+	m.sessions.Revoke(sessionID)
+
 	m.logger.WithField("session_id", sessionID).Info("Revoked authentication session")
 	return nil
 }
@@ -414,52 +520,31 @@ func (m *WebAuthnManager) getUserByID(userID string) (*User, bool) {
 	return &user, true
 }
 
-// loadSessions loads existing sessions from storage
-func (m *WebAuthnManager) loadSessions() {
-	data, err := m.storage.Get("auth_sessions")
-	if err != nil {
-		return // No existing sessions
-	}
-
-	var sessions map[string]*models.AuthSession
-	if err := json.Unmarshal(data, &sessions); err != nil {
-		m.logger.WithError(err).Error("Failed to unmarshal sessions")
-		return
-	}
-
-	// Filter out expired sessions
-	now := time.Now()
-	for id, session := range sessions {
-		if now.Before(session.ExpiresAt) {
-			m.sessions[id] = session
-		}
-	}
-}
-
-// saveSessions saves current sessions to storage
-func (m *WebAuthnManager) saveSessions() {
-	data, err := json.Marshal(m.sessions)
-	if err != nil {
-		m.logger.WithError(err).Error("Failed to marshal sessions")
-		return
-	}
-
-	if err := m.storage.Set("auth_sessions", data); err != nil {
-		m.logger.WithError(err).Error("Failed to save sessions")
-	}
-}
-
 // IsAuthenticated checks if the current session is authenticated
 func (m *WebAuthnManager) IsAuthenticated() bool {
-	return len(m.sessions) > 0
+	return len(m.sessions.Active()) > 0
 }
 
-// GetCurrentSession returns the current active session (if any)
+// GetCurrentSession returns the session the bridge acts with: the newest
+// valid session of the configured user, else of an owner, else of anyone
 func (m *WebAuthnManager) GetCurrentSession() *models.AuthSession {
-	for _, session := range m.sessions {
-		if time.Now().Before(session.ExpiresAt) {
-			return session
+	var current *models.AuthSession
+	rank := func(session *models.AuthSession) int {
+		switch {
+		case m.config.UserID != "" && session.UserID == m.config.UserID:
+			return 2
+		case Role(session.Role) == RoleOwner:
+			return 1
+		default:
+			return 0
 		}
 	}
-	return nil
-}
\ No newline at end of file
+
+	for _, session := range m.sessions.Active() {
+		if current == nil || rank(session) > rank(current) ||
+			(rank(session) == rank(current) && session.IssuedAt.After(current.IssuedAt)) {
+			current = session
+		}
+	}
+	return current
+}
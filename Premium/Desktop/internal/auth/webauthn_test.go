@@ -25,12 +25,12 @@ func TestNewWebAuthnManager(t *testing.T) {
 		t.Error("Expected config to be set")
 	}
 
-	if manager.storage != storage {
+	if manager.storage.Storage() != storage {
 		t.Error("Expected storage to be set")
 	}
 
 	if manager.sessions == nil {
-		t.Error("Expected sessions map to be initialized")
+		t.Error("Expected session store to be initialized")
 	}
 }
 
@@ -108,7 +108,7 @@ func TestWebAuthnManager_ValidateSession(t *testing.T) {
 		IssuedAt:    time.Now(),
 		ExpiresAt:   time.Now().Add(time.Hour),
 	}
-	manager.sessions[sessionID] = session
+	manager.sessions.Put(session)
 
 	// Test valid session
 	validatedSession, err := manager.ValidateSession(sessionID)
@@ -126,6 +126,7 @@ func TestWebAuthnManager_ValidateSession(t *testing.T) {
 
 	// Test expired session
 	session.ExpiresAt = time.Now().Add(-time.Hour)
+	manager.sessions.Put(session)
 	_, err = manager.ValidateSession(sessionID)
 	if err == nil {
 		t.Error("Expected error for expired session")
@@ -210,10 +211,10 @@ func TestWebAuthnManager_RevokeSession(t *testing.T) {
 		ExpiresAt:   time.Now().Add(time.Hour),
 	}
 
-	manager.sessions[sessionID] = session
+	manager.sessions.Put(session)
 
 	// Test session exists
-	if _, exists := manager.sessions[sessionID]; !exists {
+	if _, err := manager.sessions.Get(sessionID); err != nil {
 		t.Error("Expected session to exist")
 	}
 
@@ -224,7 +225,7 @@ func TestWebAuthnManager_RevokeSession(t *testing.T) {
 	}
 
 	// Test session no longer exists
-	if _, exists := manager.sessions[sessionID]; exists {
+	if _, err := manager.sessions.Get(sessionID); err == nil {
 		t.Error("Expected session to be removed")
 	}
 }
@@ -250,7 +251,7 @@ func TestWebAuthnManager_IsAuthenticated(t *testing.T) {
 		IssuedAt:    time.Now(),
 		ExpiresAt:   time.Now().Add(time.Hour),
 	}
-	manager.sessions[session.ID] = session
+	manager.sessions.Put(session)
 
 	// Test with sessions
 	if !manager.IsAuthenticated() {
@@ -280,7 +281,7 @@ func TestWebAuthnManager_GetCurrentSession(t *testing.T) {
 		IssuedAt:    time.Now(),
 		ExpiresAt:   time.Now().Add(time.Hour),
 	}
-	manager.sessions[validSession.ID] = validSession
+	manager.sessions.Put(validSession)
 
 	// Test with valid session
 	currentSession := manager.GetCurrentSession()
@@ -300,7 +301,7 @@ func TestWebAuthnManager_GetCurrentSession(t *testing.T) {
 		IssuedAt:    time.Now().Add(-2 * time.Hour),
 		ExpiresAt:   time.Now().Add(-time.Hour),
 	}
-	manager.sessions[expiredSession.ID] = expiredSession
+	manager.sessions.Put(expiredSession)
 
 	// Should still return the valid session
 	currentSession = manager.GetCurrentSession()
@@ -0,0 +1,403 @@
+// Package backup takes scheduled backups of the bridge's storage, keeps a
+// number of them, and restores them after checking their integrity.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/storage"
+)
+
+const (
+	// filePrefix starts the name of every backup
+	filePrefix = "waddlebot-bridge-"
+
+	// timeFormat orders backup names by when they were taken
+	timeFormat = "20060102-150405"
+
+	gzipExt     = ".gz"
+	checksumExt = ".sha256"
+)
+
+// ErrChecksumMismatch is returned when a backup does not match the checksum
+// recorded when it was taken
+var ErrChecksumMismatch = errors.New("backup does not match its checksum")
+
+// Manager takes backups of storage on a schedule
+type Manager struct {
+	store   storage.Storage
+	backend string
+	config  config.BackupConfig
+	logger  *logrus.Logger
+}
+
+// Info describes a backup
+type Info struct {
+	Path    string    `json:"path"`
+	Backend string    `json:"backend"`
+	Size    int64     `json:"size"`
+	TakenAt time.Time `json:"taken_at"`
+}
+
+// New creates a backup manager for store, which uses the configured
+// storage backend
+func New(store storage.Storage, cfg *config.Config, logger *logrus.Logger) *Manager {
+	backend := cfg.StorageBackend
+	if backend == "" {
+		backend = storage.BackendBolt
+	}
+	return &Manager{
+		store:   store,
+		backend: backend,
+		config:  cfg.Backup,
+		logger:  logger,
+	}
+}
+
+// Backup takes a backup now, removes the backups beyond the retention
+// count, and returns the new backup's path
+func (m *Manager) Backup() (string, error) {
+	if err := os.MkdirAll(m.config.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	file, err := storage.File(m.backend, "")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(m.config.Dir, filePrefix+time.Now().UTC().Format(timeFormat)+filepath.Ext(file))
+
+	// The storage writes a plain copy of the database first
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := m.store.Backup(tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to back up storage: %w", err)
+	}
+
+	if m.config.Compress {
+		path += gzipExt
+		err = compress(tmp, path)
+		os.Remove(tmp)
+	} else {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	if err := writeChecksum(path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	m.logger.WithField("path", path).Info("Backed up storage")
+
+	if err := m.prune(); err != nil {
+		m.logger.WithError(err).Warn("Failed to remove old backups")
+	}
+	return path, nil
+}
+
+// List returns the backups in the backup directory, newest first
+func (m *Manager) List() ([]Info, error) {
+	return List(m.config.Dir)
+}
+
+// Run takes a backup every interval until ctx is cancelled. The first
+// backup is taken once an interval has passed since the latest one, so
+// restarts do not postpone backups.
+func (m *Manager) Run(ctx context.Context) {
+	interval := m.config.Interval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	wait := time.Duration(0)
+	if backups, err := m.List(); err == nil && len(backups) > 0 {
+		wait = max(interval-time.Since(backups[0].TakenAt), 0)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if _, err := m.Backup(); err != nil {
+				m.logger.WithError(err).Error("Scheduled backup failed")
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// prune removes the oldest backups beyond the retention count
+func (m *Manager) prune() error {
+	if m.config.Retain <= 0 {
+		return nil
+	}
+	backups, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range backups[min(m.config.Retain, len(backups)):] {
+		if err := os.Remove(backup.Path); err != nil {
+			return err
+		}
+		os.Remove(backup.Path + checksumExt)
+		m.logger.WithField("path", backup.Path).Debug("Removed old backup")
+	}
+	return nil
+}
+
+// List returns the backups in dir, newest first
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []Info
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) {
+			continue
+		}
+		backend, takenAt, ok := parseName(name)
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Info{
+			Path:    filepath.Join(dir, name),
+			Backend: backend,
+			Size:    info.Size(),
+			TakenAt: takenAt,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].TakenAt.After(backups[j].TakenAt)
+	})
+	return backups, nil
+}
+
+// parseName returns the backend and time of a backup from its file name
+func parseName(name string) (string, time.Time, bool) {
+	name = strings.TrimSuffix(name, gzipExt)
+	ext := filepath.Ext(name)
+	takenAt, err := time.Parse(timeFormat, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), ext))
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	backend, ok := backendOf(ext)
+	return backend, takenAt, ok
+}
+
+// backendOf returns the storage backend whose database files have ext
+func backendOf(ext string) (string, bool) {
+	for _, backend := range storage.Backends() {
+		if file, _ := storage.File(backend, ""); filepath.Ext(file) == ext {
+			return backend, true
+		}
+	}
+	return "", false
+}
+
+// Restore replaces the database in dataDir with a backup, after checking
+// the backup against its checksum and the database's integrity. The bridge
+// must not be running. The replaced database is kept next to it with a
+// .before-restore suffix. Restore returns the backend of the backup.
+func Restore(path, dataDir string) (string, error) {
+	backend, ok := backendOf(filepath.Ext(strings.TrimSuffix(path, gzipExt)))
+	if !ok {
+		return "", fmt.Errorf("%s is not a storage backup", path)
+	}
+
+	if err := verifyChecksum(path); err != nil {
+		return "", err
+	}
+
+	live, err := storage.File(backend, dataDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Make sure no bridge has the database open
+	if _, err := os.Stat(live); err == nil {
+		store, err := storage.Open(backend, dataDir)
+		if err != nil {
+			return "", fmt.Errorf("storage is in use, stop the bridge first: %w", err)
+		}
+		store.Close()
+	}
+
+	tmp := live + ".restore"
+	if strings.HasSuffix(path, gzipExt) {
+		err = decompress(path, tmp)
+	} else {
+		err = copyFile(path, tmp)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	if err := storage.Verify(backend, tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("backup failed verification: %w", err)
+	}
+
+	if _, err := os.Stat(live); err == nil {
+		if err := os.Rename(live, live+".before-restore"); err != nil {
+			os.Remove(tmp)
+			return "", fmt.Errorf("failed to keep the current database: %w", err)
+		}
+	}
+	// SQLite's journal files belong to the replaced database
+	os.Remove(live + "-wal")
+	os.Remove(live + "-shm")
+
+	if err := os.Rename(tmp, live); err != nil {
+		return "", fmt.Errorf("failed to restore database: %w", err)
+	}
+	return backend, nil
+}
+
+// writeChecksum records the SHA-256 of a backup next to it, in the format
+// of sha256sum
+func writeChecksum(path string) error {
+	sum, err := checksum(path)
+	if err != nil {
+		return err
+	}
+	line := sum + "  " + filepath.Base(path) + "\n"
+	if err := os.WriteFile(path+checksumExt, []byte(line), 0600); err != nil {
+		return fmt.Errorf("failed to write backup checksum: %w", err)
+	}
+	return nil
+}
+
+// verifyChecksum checks a backup against its recorded checksum, if it has
+// one
+func verifyChecksum(path string) error {
+	data, err := os.ReadFile(path + checksumExt)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read backup checksum: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: empty checksum file", ErrChecksumMismatch)
+	}
+	sum, err := checksum(path)
+	if err != nil {
+		return err
+	}
+	if sum != fields[0] {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+func checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func compress(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func decompress(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	return writeFile(dst, zr)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dst, in)
+}
+
+func writeFile(dst string, r io.Reader) error {
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,125 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/storage"
+)
+
+func newTestManager(t *testing.T, backend string, compress bool) (*Manager, storage.Storage, string) {
+	t.Helper()
+	dataDir := t.TempDir()
+	store, err := storage.Open(backend, dataDir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	cfg := &config.Config{DataDir: dataDir, StorageBackend: backend}
+	cfg.Backup = config.BackupConfig{Dir: filepath.Join(dataDir, "backups"), Retain: 2, Compress: compress}
+	return New(store, cfg, logrus.New()), store, dataDir
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	for _, backend := range storage.Backends() {
+		for _, compress := range []bool{false, true} {
+			t.Run(backend, func(t *testing.T) {
+				m, store, dataDir := newTestManager(t, backend, compress)
+				store.Set("user_1", []byte("before"))
+
+				path, err := m.Backup()
+				if err != nil {
+					t.Fatalf("Backup failed: %v", err)
+				}
+				if _, err := os.Stat(path + checksumExt); err != nil {
+					t.Errorf("expected a checksum file: %v", err)
+				}
+
+				store.Set("user_1", []byte("after"))
+				store.Close()
+
+				restored, err := Restore(path, dataDir)
+				if err != nil || restored != backend {
+					t.Fatalf("Restore failed: %q, %v", restored, err)
+				}
+
+				store, err = storage.Open(backend, dataDir)
+				if err != nil {
+					t.Fatalf("Open after restore failed: %v", err)
+				}
+				defer store.Close()
+				if value, err := store.Get("user_1"); err != nil || string(value) != "before" {
+					t.Errorf("expected the backed up value, got %q, %v", value, err)
+				}
+
+				live, _ := storage.File(backend, dataDir)
+				if _, err := os.Stat(live + ".before-restore"); err != nil {
+					t.Errorf("expected the replaced database to be kept: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestRestoreRejectsTamperedBackup(t *testing.T) {
+	m, store, dataDir := newTestManager(t, storage.BackendSQLite, true)
+	path, err := m.Backup()
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	store.Close()
+
+	data, _ := os.ReadFile(path)
+	data[len(data)/2] ^= 0xff
+	os.WriteFile(path, data, 0600)
+
+	if _, err := Restore(path, dataDir); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	// Without a checksum the database itself is checked
+	os.Remove(path + checksumExt)
+	if _, err := Restore(path, dataDir); err == nil {
+		t.Error("expected a corrupt backup to be rejected")
+	}
+}
+
+func TestRetention(t *testing.T) {
+	m, store, _ := newTestManager(t, storage.BackendBolt, false)
+	defer store.Close()
+
+	// Older backups
+	os.MkdirAll(m.config.Dir, 0700)
+	for _, age := range []time.Duration{72 * time.Hour, 48 * time.Hour} {
+		name := filePrefix + time.Now().Add(-age).UTC().Format(timeFormat) + ".db"
+		os.WriteFile(filepath.Join(m.config.Dir, name), []byte("old"), 0600)
+		os.WriteFile(filepath.Join(m.config.Dir, name+checksumExt), []byte("sum"), 0600)
+	}
+
+	path, err := m.Backup()
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	backups, err := m.List()
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("expected 2 backups to be kept, got %+v, %v", backups, err)
+	}
+	if backups[0].Path != path || backups[0].Backend != storage.BackendBolt {
+		t.Errorf("expected the new backup first, got %+v", backups[0])
+	}
+	if time.Since(backups[1].TakenAt) < 47*time.Hour {
+		t.Errorf("expected the oldest backup to be removed, got %+v", backups[1])
+	}
+
+	entries, _ := os.ReadDir(m.config.Dir)
+	if len(entries) != 4 {
+		t.Errorf("expected 2 backups with checksums, got %d files", len(entries))
+	}
+}
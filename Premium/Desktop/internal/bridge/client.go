@@ -1,28 +1,63 @@
 package bridge
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/account"
+	"waddlebot-bridge/internal/apitls"
 	"waddlebot-bridge/internal/auth"
+	"waddlebot-bridge/internal/capabilities"
 	"waddlebot-bridge/internal/config"
 	"waddlebot-bridge/internal/logger"
 	"waddlebot-bridge/internal/modules"
+	"waddlebot-bridge/internal/outbox"
+	"waddlebot-bridge/internal/resilience"
+	"waddlebot-bridge/internal/signing"
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/telemetry"
+	"waddlebot-bridge/internal/upload"
 )
 
+// heartbeatPath is the API path heartbeats are posted to
+const heartbeatPath = "/api/bridge/heartbeat"
+
 // Client handles communication with the WaddleBot API
 type Client struct {
 	config        *config.Config
 	authenticator *auth.WebAuthnManager
+	account       *account.Manager
 	moduleManager *modules.Manager
 	logger        *logrus.Logger
 	httpClient    *http.Client
+	transport     *resilience.Transport
+	tlsConfig     *tls.Config
+	proxy         func(*http.Request) (*url.URL, error)
+	signer        *signing.Signer
+	outbox        *outbox.Outbox
+	telemetry     *telemetry.Collector
+	capabilities  *capabilities.Set
+	store         storage.Storage
+	reregister    chan struct{}
+
+	mu                sync.RWMutex
+	heartbeatInterval time.Duration
+	uploadOptions     upload.Options
+	registered        bool
+	bridgeID          string
+	lastHeartbeat     time.Time
 }
 
 // Info represents bridge information
@@ -35,14 +70,28 @@ type Info struct {
 	Platform     string    `json:"platform"`
 	LastSeen     time.Time `json:"last_seen"`
 	Capabilities []string  `json:"capabilities"`
+
+	// CapabilityDescriptors describe the capabilities with their versions
+	CapabilityDescriptors []capabilities.Capability `json:"capability_descriptors"`
 }
 
 // RegistrationRequest represents a bridge registration request
 type RegistrationRequest struct {
+	BridgeID    string               `json:"bridge_id,omitempty"`
 	UserID      string               `json:"user_id"`
 	CommunityID string               `json:"community_id"`
 	BridgeInfo  Info                 `json:"bridge_info"`
 	Modules     []modules.ModuleInfo `json:"modules"`
+	SigningKey  *SigningKey          `json:"signing_key,omitempty"`
+	Upload      upload.Capabilities  `json:"upload"`
+}
+
+// SigningKey describes how the bridge signs its requests so the server can
+// verify them. PublicKey is empty for shared-secret algorithms.
+type SigningKey struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
 }
 
 // RegistrationResponse represents the response from bridge registration
@@ -51,29 +100,220 @@ type RegistrationResponse struct {
 	BridgeID     string `json:"bridge_id"`
 	Message      string `json:"message"`
 	PollInterval int    `json:"poll_interval"`
+
+	// Upload lists the upload features the server accepts; when absent
+	// payloads are sent as plain JSON
+	Upload *upload.Capabilities `json:"upload,omitempty"`
 }
 
 // NewClient creates a new bridge client
 func NewClient(cfg *config.Config, authenticator *auth.WebAuthnManager, moduleManager *modules.Manager) (*Client, error) {
-	return &Client{
+	tlsConfig, err := apitls.NewConfig(cfg.APITLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure API TLS: %w", err)
+	}
+
+	baseTransport := apitls.NewTransport(tlsConfig)
+	if err := resilience.ConfigureTransport(baseTransport, cfg.HTTP); err != nil {
+		return nil, fmt.Errorf("failed to configure API HTTP client: %w", err)
+	}
+	transport := resilience.NewTransport(baseTransport, cfg.HTTP, logger.GetLogger())
+
+	timeout := cfg.HTTP.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	var signer *signing.Signer
+	if cfg.Signing.Enabled {
+		signer, err = signing.New(cfg.Signing, cfg.DataDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure request signing: %w", err)
+		}
+	}
+
+	c := &Client{
 		config:        cfg,
 		authenticator: authenticator,
 		moduleManager: moduleManager,
 		logger:        logger.GetLogger(),
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   timeout,
+			Transport: transport,
 		},
-	}, nil
+		transport:         transport,
+		proxy:             baseTransport.Proxy,
+		tlsConfig:         tlsConfig,
+		signer:            signer,
+		heartbeatInterval: cfg.Heartbeat.Interval,
+		reregister:        make(chan struct{}, 1),
+	}
+	transport.Resign = c.SignRequest
+
+	c.capabilities = capabilities.New()
+	if moduleManager != nil {
+		c.capabilities.SetModules(moduleManager)
+	}
+
+	return c, nil
+}
+
+// SignRequest signs a request to the API with the bridge's signing key.
+// body must be the exact request body. It does nothing when signing is
+// disabled.
+func (c *Client) SignRequest(req *http.Request, body []byte) error {
+	if c.signer == nil {
+		return nil
+	}
+	if err := c.signer.SignRequest(req, body); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	return nil
+}
+
+// HTTPClient returns the HTTP client used for API requests, so other
+// components talk to the API with the same TLS settings
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// SetCapabilities sets the capabilities advertised at registration and in
+// heartbeats, replacing those of the module manager alone
+func (c *Client) SetCapabilities(set *capabilities.Set) {
+	c.capabilities = set
+}
+
+// SetOutbox sets the durable queue used for heartbeats that cannot be
+// delivered
+func (c *Client) SetOutbox(ob *outbox.Outbox) {
+	c.outbox = ob
+}
+
+// PostJSON posts a JSON payload to an API path, treating any 2xx status as
+// success
+func (c *Client) PostJSON(ctx context.Context, path string, payload []byte) error {
+	_, err := c.postJSON(ctx, path, payload)
+	return err
 }
 
-// GetAuthToken gets the current authentication token
+// postJSON posts a JSON payload to an API path and returns the response body
+func (c *Client) postJSON(ctx context.Context, path string, payload []byte) ([]byte, error) {
+	return c.post(ctx, path, upload.ContentTypeJSON, payload)
+}
+
+// post sends a payload to an API path using the negotiated upload encoding
+// and returns the response body
+func (c *Client) post(ctx context.Context, path, contentType string, payload []byte) ([]byte, error) {
+	// Get authentication token
+	token, err := c.GetAuthToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	return upload.Send(ctx, c.UploadOptions(), path, contentType, payload, func(ctx context.Context, part upload.Part) ([]byte, error) {
+		return c.sendPart(ctx, token, part)
+	})
+}
+
+// sendPart sends a single encoded request to the API
+func (c *Client) sendPart(ctx context.Context, token string, part upload.Part) ([]byte, error) {
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.GetAPIEndpoint(part.Path),
+		bytes.NewReader(part.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Add headers
+	for key, values := range part.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", c.config.GetUserAgent())
+	req.Header.Set("X-Community-ID", c.config.CommunityID)
+	req.Header.Set("X-User-ID", c.config.UserID)
+	req.Header.Set(resilience.HeaderIdempotencyKey, partIdempotencyKey(part))
+	c.setBridgeHeader(req)
+	if err := c.SignRequest(req, part.Body); err != nil {
+		return nil, err
+	}
+
+	// Make request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read response
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Check status code
+	if isDeregistrationStatus(resp.StatusCode) {
+		c.Deregistered(resp.StatusCode)
+		return nil, fmt.Errorf("%w: server returned status %d: %s", ErrDeregistered, resp.StatusCode, string(body))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// partIdempotencyKey derives the Idempotency-Key of an encoded request.
+// Chunks are keyed by their upload ID and index.
+func partIdempotencyKey(part upload.Part) string {
+	if id := part.Header.Get(upload.HeaderUploadID); id != "" {
+		return idempotencyKey(part.Path, []byte(id+"/"+part.Header.Get(upload.HeaderChunkIndex)))
+	}
+	return idempotencyKey(part.Path, part.Body)
+}
+
+// idempotencyKey derives the Idempotency-Key of a POST from its path and
+// payload, so retries and redeliveries of the same message share a key
+func idempotencyKey(path string, payload []byte) string {
+	sum := sha256.Sum256(append([]byte(path+"\n"), payload...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// SetAccount makes the client authenticate with the access token of the
+// WaddleBot account the bridge is linked to
+func (c *Client) SetAccount(m *account.Manager) {
+	c.account = m
+}
+
+// GetAuthToken gets the current authentication token. A configured API
+// token is used as is, then the linked account's access token; otherwise a short-lived token for the client's
+// community is generated from the current session, which is kept alive
+// while the bridge uses it. With device authentication the bridge signs
+// itself in when there is no session.
 func (c *Client) GetAuthToken() (string, error) {
+	if c.config.APIToken != "" {
+		return c.config.APIToken, nil
+	}
+	if c.account != nil {
+		return c.account.AccessToken(context.Background())
+	}
+
 	session := c.authenticator.GetCurrentSession()
+	if session == nil && c.authenticator.DeviceAuthEnabled() {
+		var err error
+		if session, err = c.authenticator.SignInDevice(); err != nil {
+			return "", fmt.Errorf("device sign-in failed: %w", err)
+		}
+	}
 	if session == nil {
 		return "", fmt.Errorf("no authenticated session found")
 	}
+	c.authenticator.KeepAlive(session)
 
-	return c.authenticator.GenerateJWT(session)
+	if session.CommunityID == c.config.CommunityID && session.UserID == c.config.UserID {
+		return c.authenticator.GenerateJWT(session)
+	}
+	return c.authenticator.GenerateCommunityJWT(session, c.config.CommunityID, c.config.UserID, []byte(c.config.JWTSecret))
 }
 
 // RegisterBridge registers the bridge with the WaddleBot API
@@ -90,27 +330,35 @@ func (c *Client) RegisterBridge(ctx context.Context) error {
 	moduleInfos := c.moduleManager.GetModuleInfos()
 
 	// Create registration request
+	bridgeID := c.BridgeID()
+	capabilityList := c.capabilities.Collect()
 	bridgeInfo := Info{
-		UserID:      c.config.UserID,
-		CommunityID: c.config.CommunityID,
-		Status:      "active",
-		Version:     "1.0.0",
-		Platform:    fmt.Sprintf("%s/%s", c.config.GetUserAgent(), "desktop"),
-		LastSeen:    time.Now(),
-		Capabilities: []string{
-			"local_execution",
-			"file_operations",
-			"system_info",
-			"process_management",
-			"network_operations",
-		},
+		BridgeID:     bridgeID,
+		UserID:       c.config.UserID,
+		CommunityID:  c.config.CommunityID,
+		Status:       "active",
+		Version:      "1.0.0",
+		Platform:     fmt.Sprintf("%s/%s", c.config.GetUserAgent(), "desktop"),
+		LastSeen:     time.Now(),
+		Capabilities: capabilities.Names(capabilityList),
+
+		CapabilityDescriptors: capabilityList,
 	}
 
 	request := RegistrationRequest{
+		BridgeID:    bridgeID,
 		UserID:      c.config.UserID,
 		CommunityID: c.config.CommunityID,
 		BridgeInfo:  bridgeInfo,
 		Modules:     moduleInfos,
+		Upload:      upload.Offer(c.config.Upload),
+	}
+	if c.signer != nil {
+		request.SigningKey = &SigningKey{
+			Algorithm: c.signer.Algorithm(),
+			KeyID:     c.signer.KeyID(),
+			PublicKey: c.signer.PublicKey(),
+		}
 	}
 
 	// Marshal request
@@ -135,6 +383,11 @@ func (c *Client) RegisterBridge(ctx context.Context) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Community-ID", c.config.CommunityID)
 	req.Header.Set("X-User-ID", c.config.UserID)
+	req.Header.Set(resilience.HeaderIdempotencyKey, idempotencyKey("/api/bridge/register", requestData))
+	c.setBridgeHeader(req)
+	if err := c.SignRequest(req, requestData); err != nil {
+		return err
+	}
 
 	// Make request
 	resp, err := c.httpClient.Do(req)
@@ -149,7 +402,12 @@ func (c *Client) RegisterBridge(ctx context.Context) error {
 		return fmt.Errorf("failed to read response: %w", err)
 	}
 
-	// Check status code
+	// Check status code. A 410 for a known ID means the API deleted the
+	// bridge, so the next attempt registers a new one.
+	if resp.StatusCode == http.StatusGone && bridgeID != "" {
+		c.forgetBridgeID()
+		return fmt.Errorf("%w: bridge %s no longer exists", ErrDeregistered, bridgeID)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
@@ -164,6 +422,10 @@ func (c *Client) RegisterBridge(ctx context.Context) error {
 		return fmt.Errorf("registration failed: %s", registrationResponse.Message)
 	}
 
+	c.saveBridgeID(registrationResponse.BridgeID)
+	c.setHeartbeatInterval(registrationResponse.PollInterval)
+	c.setUploadOptions(upload.Negotiate(c.config.Upload, registrationResponse.Upload))
+
 	c.logger.WithFields(logrus.Fields{
 		"bridge_id":     registrationResponse.BridgeID,
 		"poll_interval": registrationResponse.PollInterval,
@@ -172,26 +434,29 @@ func (c *Client) RegisterBridge(ctx context.Context) error {
 	return nil
 }
 
-// SendHeartbeat sends a heartbeat to the server
+// SendHeartbeat sends a heartbeat with the bridge's current telemetry. The
+// response may carry a new heartbeat interval.
 func (c *Client) SendHeartbeat(ctx context.Context) error {
-	// Get authentication token
-	token, err := c.GetAuthToken()
-	if err != nil {
+	// Heartbeats are only meaningful for an authenticated bridge
+	if _, err := c.GetAuthToken(); err != nil {
 		return fmt.Errorf("failed to get auth token: %w", err)
 	}
 
 	// Create heartbeat data
-	heartbeat := map[string]interface{}{
-		"timestamp":    time.Now(),
-		"status":       "active",
-		"module_count": len(c.moduleManager.GetModuleInfos()),
-		"capabilities": []string{
-			"local_execution",
-			"file_operations",
-			"system_info",
-			"process_management",
-			"network_operations",
-		},
+	capabilityList := c.capabilities.Collect()
+	heartbeat := Heartbeat{
+		BridgeID:     c.BridgeID(),
+		Timestamp:    time.Now(),
+		Status:       heartbeatStatus(),
+		ModuleCount:  len(c.moduleManager.GetModuleInfos()),
+		Capabilities: capabilities.Names(capabilityList),
+		Interval:     int(c.HeartbeatInterval().Seconds()),
+
+		CapabilityDescriptors: capabilityList,
+	}
+	if c.telemetry != nil {
+		snapshot := c.telemetry.Collect()
+		heartbeat.Telemetry = &snapshot
 	}
 
 	// Marshal heartbeat
@@ -200,34 +465,26 @@ func (c *Client) SendHeartbeat(ctx context.Context) error {
 		return fmt.Errorf("failed to marshal heartbeat: %w", err)
 	}
 
-	// Build heartbeat URL
-	heartbeatURL := c.config.GetAPIEndpoint("/api/bridge/heartbeat")
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", heartbeatURL,
-		strings.NewReader(string(heartbeatData)))
+	body, err := c.postJSON(ctx, heartbeatPath, heartbeatData)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		// Queue the heartbeat so the server sees the bridge's activity
+		// once it is reachable again
+		if c.outbox != nil {
+			key := heartbeat.Timestamp.UTC().Format(time.RFC3339Nano)
+			if qErr := c.outbox.Enqueue(outbox.KindHeartbeat, key, heartbeatPath, heartbeat); qErr != nil {
+				c.logger.WithError(qErr).Warn("Failed to queue heartbeat")
+			}
+		}
+		return err
 	}
 
-	// Add headers
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("User-Agent", c.config.GetUserAgent())
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Community-ID", c.config.CommunityID)
-	req.Header.Set("X-User-ID", c.config.UserID)
-
-	// Make request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
+	c.mu.Lock()
+	c.lastHeartbeat = heartbeat.Timestamp
+	c.mu.Unlock()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	var response HeartbeatResponse
+	if len(body) > 0 && json.Unmarshal(body, &response) == nil {
+		c.setHeartbeatInterval(response.PollInterval)
 	}
 
 	c.logger.Debug("Heartbeat sent successfully")
@@ -308,6 +565,10 @@ func (c *Client) UnregisterBridge(ctx context.Context) error {
 	req.Header.Set("User-Agent", c.config.GetUserAgent())
 	req.Header.Set("X-Community-ID", c.config.CommunityID)
 	req.Header.Set("X-User-ID", c.config.UserID)
+	c.setBridgeHeader(req)
+	if err := c.SignRequest(req, nil); err != nil {
+		return err
+	}
 
 	// Make request
 	resp, err := c.httpClient.Do(req)
@@ -322,6 +583,10 @@ func (c *Client) UnregisterBridge(ctx context.Context) error {
 		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
+	c.mu.Lock()
+	c.registered = false
+	c.mu.Unlock()
+
 	c.logger.Info("Bridge unregistered successfully")
 	return nil
 }
@@ -340,5 +605,6 @@ func (c *Client) GetStats() map[string]interface{} {
 		"api_url":       c.config.APIURL,
 		"user_agent":    c.config.GetUserAgent(),
 		"modules":       len(c.moduleManager.GetModuleInfos()),
+		"http":          c.transport.Stats(),
 	}
 }
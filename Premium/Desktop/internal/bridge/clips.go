@@ -0,0 +1,104 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"waddlebot-bridge/internal/upload"
+)
+
+// ClipsPath is the API path clips are uploaded to
+const ClipsPath = "/api/bridge/clips"
+
+// HeaderUploadOffset is the byte offset of a clip chunk in the file
+const HeaderUploadOffset = "X-Upload-Offset"
+
+// ClipUpload describes a clip to upload. Passing the ID of an unfinished
+// upload of the same file resumes it.
+type ClipUpload struct {
+	UploadID    string `json:"upload_id,omitempty"`
+	FileName    string `json:"file_name"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+	Kind        string `json:"kind"`              // replay or recording
+	TaskID      string `json:"task_id,omitempty"` // task that saved the clip
+}
+
+// ClipSession is where a clip upload stands on the server
+type ClipSession struct {
+	UploadID  string `json:"upload_id"`
+	Offset    int64  `json:"offset"`               // bytes the server has received
+	ChunkSize int64  `json:"chunk_size,omitempty"` // largest chunk the server accepts
+}
+
+// Clip is an uploaded clip
+type Clip struct {
+	ID  string `json:"id"`
+	URL string `json:"url"` // shareable link to the clip
+}
+
+// StartClipUpload starts or resumes a clip upload
+func (c *Client) StartClipUpload(ctx context.Context, clip ClipUpload) (*ClipSession, error) {
+	payload, err := json.Marshal(clip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal clip upload: %w", err)
+	}
+
+	body, err := c.postJSON(ctx, ClipsPath, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var session ClipSession
+	if err := json.Unmarshal(body, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &session, nil
+}
+
+// SendClipChunk sends the bytes of a clip starting at offset, returning
+// the offset the server has received up to
+func (c *Client) SendClipChunk(ctx context.Context, uploadID string, offset int64, data []byte) (int64, error) {
+	token, err := c.GetAuthToken()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	// Chunks are sent as they are; video does not compress
+	header := http.Header{}
+	header.Set("Content-Type", upload.ContentTypeChunk)
+	header.Set(HeaderUploadOffset, strconv.FormatInt(offset, 10))
+	body, err := c.sendPart(ctx, token, upload.Part{
+		Path:   ClipsPath + "/" + uploadID + "/chunks",
+		Header: header,
+		Body:   data,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var session ClipSession
+	if err := json.Unmarshal(body, &session); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return session.Offset, nil
+}
+
+// CompleteClipUpload finishes a clip upload once every byte is sent and
+// returns the clip
+func (c *Client) CompleteClipUpload(ctx context.Context, uploadID string) (*Clip, error) {
+	body, err := c.postJSON(ctx, ClipsPath+"/"+uploadID+"/complete", []byte("{}"))
+	if err != nil {
+		return nil, err
+	}
+
+	var clip Clip
+	if err := json.Unmarshal(body, &clip); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &clip, nil
+}
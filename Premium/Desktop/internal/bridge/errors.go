@@ -0,0 +1,7 @@
+package bridge
+
+import "errors"
+
+// ErrDeregistered is returned when the API responds 401 or 410, meaning it
+// no longer recognizes the bridge and it must register again
+var ErrDeregistered = errors.New("bridge is not registered with the API")
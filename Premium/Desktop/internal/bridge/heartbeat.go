@@ -0,0 +1,213 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/capabilities"
+	"waddlebot-bridge/internal/resilience"
+	"waddlebot-bridge/internal/supervisor"
+	"waddlebot-bridge/internal/telemetry"
+)
+
+// defaultHeartbeatInterval is used when no interval is configured
+const defaultHeartbeatInterval = 30 * time.Second
+
+// Heartbeat is the payload posted to the heartbeat endpoint
+type Heartbeat struct {
+	BridgeID     string              `json:"bridge_id,omitempty"`
+	Timestamp    time.Time           `json:"timestamp"`
+	Status       string              `json:"status"`
+	ModuleCount  int                 `json:"module_count"`
+	Capabilities []string            `json:"capabilities"`
+	Interval     int                 `json:"interval"` // seconds until the next heartbeat
+	Telemetry    *telemetry.Snapshot `json:"telemetry,omitempty"`
+
+	// CapabilityDescriptors describe the capabilities with their versions
+	CapabilityDescriptors []capabilities.Capability `json:"capability_descriptors"`
+
+	// Shutdown is set on the last heartbeat of a bridge that is stopping
+	Shutdown *ShutdownReport `json:"shutdown,omitempty"`
+}
+
+// ShutdownReport describes how the bridge stopped
+type ShutdownReport struct {
+	Reason             string `json:"reason"`
+	Clean              bool   `json:"clean"` // tasks in flight finished and results were delivered
+	UndeliveredResults int    `json:"undelivered_results"`
+}
+
+// HeartbeatResponse is the API's reply to a heartbeat. A positive
+// PollInterval changes the heartbeat interval.
+type HeartbeatResponse struct {
+	PollInterval int `json:"poll_interval"`
+}
+
+// SetTelemetry sets the collector whose snapshot is sent with heartbeats
+func (c *Client) SetTelemetry(collector *telemetry.Collector) {
+	c.telemetry = collector
+}
+
+// HeartbeatInterval returns the current heartbeat interval
+func (c *Client) HeartbeatInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.heartbeatInterval <= 0 {
+		return defaultHeartbeatInterval
+	}
+	return c.heartbeatInterval
+}
+
+// LastHeartbeat returns when the API last accepted a heartbeat, or the zero
+// time if it has not yet
+func (c *Client) LastHeartbeat() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastHeartbeat
+}
+
+// Reconnect starts a heartbeat cycle right away, registering the bridge
+// again first
+func (c *Client) Reconnect() {
+	c.mu.Lock()
+	c.registered = false
+	c.mu.Unlock()
+
+	select {
+	case c.reregister <- struct{}{}:
+	default:
+	}
+}
+
+// setHeartbeatInterval applies an interval requested by the API, in
+// seconds, clamped to the configured bounds. Non-positive values are
+// ignored.
+func (c *Client) setHeartbeatInterval(seconds int) {
+	if seconds <= 0 {
+		return
+	}
+
+	interval := time.Duration(seconds) * time.Second
+	if min := c.config.Heartbeat.MinInterval; min > 0 && interval < min {
+		interval = min
+	}
+	if max := c.config.Heartbeat.MaxInterval; max > 0 && interval > max {
+		interval = max
+	}
+
+	c.mu.Lock()
+	changed := interval != c.heartbeatInterval
+	c.heartbeatInterval = interval
+	c.mu.Unlock()
+
+	if changed {
+		c.logger.WithField("interval", interval).Info("Updated heartbeat interval")
+	}
+}
+
+// heartbeatStatus is "degraded" while a subsystem is crash looping, with
+// its crashes described in the telemetry, and "active" otherwise
+func heartbeatStatus() string {
+	for _, status := range supervisor.Statuses() {
+		if status.CrashLoop {
+			return "degraded"
+		}
+	}
+	return "active"
+}
+
+// ReportShutdown sends a last heartbeat telling the API the bridge is
+// stopping, so no tasks are sent to it until it registers again. It is not
+// queued when the API cannot be reached.
+func (c *Client) ReportShutdown(ctx context.Context, report ShutdownReport) error {
+	if c.BridgeID() == "" {
+		return nil
+	}
+
+	heartbeat := Heartbeat{
+		BridgeID:     c.BridgeID(),
+		Timestamp:    time.Now(),
+		Status:       "stopped",
+		ModuleCount:  len(c.moduleManager.GetModuleInfos()),
+		Capabilities: []string{},
+		Shutdown:     &report,
+
+		CapabilityDescriptors: []capabilities.Capability{},
+	}
+	heartbeatData, err := json.Marshal(heartbeat)
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+
+	_, err = c.postJSON(ctx, heartbeatPath, heartbeatData)
+	return err
+}
+
+// RunHeartbeat registers the bridge once a session is available and then
+// sends heartbeats at the current interval until ctx is cancelled. When the
+// API deregisters the bridge a new cycle starts immediately.
+func (c *Client) RunHeartbeat(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-c.reregister:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		c.heartbeat(ctx)
+		timer.Reset(c.HeartbeatInterval())
+	}
+}
+
+// heartbeat performs one heartbeat cycle, registering first if needed
+func (c *Client) heartbeat(ctx context.Context) {
+	// Deregistrations seen during this cycle are handled by it; dropping
+	// their signals keeps a misbehaving API from causing a tight loop
+	defer func() {
+		select {
+		case <-c.reregister:
+		default:
+		}
+	}()
+
+	if !c.IsAuthenticated() {
+		c.logger.Debug("Skipping heartbeat: not authenticated")
+		return
+	}
+
+	if !c.IsRegistered() {
+		if err := c.RegisterBridge(ctx); err != nil {
+			c.logger.WithError(err).Warn("Bridge registration failed")
+			return
+		}
+	}
+
+	err := c.SendHeartbeat(ctx)
+	if errors.Is(err, ErrDeregistered) {
+		if err = c.RegisterBridge(ctx); err == nil {
+			err = c.SendHeartbeat(ctx)
+		}
+	}
+	if errors.Is(err, resilience.ErrCircuitOpen) {
+		c.logger.Debug("Skipping heartbeat while API calls are paused")
+		return
+	}
+	if err != nil {
+		c.logger.WithFields(logrus.Fields{
+			"error":    err.Error(),
+			"interval": c.HeartbeatInterval(),
+		}).Warn("Heartbeat failed")
+	}
+}
@@ -0,0 +1,156 @@
+package bridge
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/storage"
+)
+
+// bridgeIDKey is the storage key of the persisted bridge ID. IDs are kept
+// per community under "bridge_id:<community>"; the bare key holds the ID
+// saved by versions that served a single community.
+const bridgeIDKey = "bridge_id"
+
+// SetStorage sets the store the bridge ID is persisted in and loads any ID
+// saved by a previous run, so the bridge keeps its identity across restarts.
+// An ID saved by a single-community version is taken over by the first
+// client to load, which is the primary community's.
+func (c *Client) SetStorage(store storage.Storage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store = store
+
+	data, err := store.Get(c.identityKey())
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		data, err = c.migrateBridgeID()
+	}
+	if err != nil {
+		if !errors.Is(err, storage.ErrKeyNotFound) {
+			c.logger.WithError(err).Warn("Failed to load bridge ID")
+		}
+		return
+	}
+
+	c.bridgeID = string(data)
+	c.logger.WithField("bridge_id", c.bridgeID).Debug("Loaded bridge ID")
+}
+
+// BridgeID returns the ID assigned by the API, or an empty string if the
+// bridge has never registered
+func (c *Client) BridgeID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.bridgeID
+}
+
+// IsRegistered reports whether the bridge is currently registered
+func (c *Client) IsRegistered() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.registered
+}
+
+// Deregistered records that the API answered a request with statusCode
+// 401 or 410. The bridge is marked unregistered and registers again on the
+// next heartbeat cycle, which is started right away. On 410 the API has
+// deleted the bridge, so the stored ID is discarded as well.
+func (c *Client) Deregistered(statusCode int) {
+	if !isDeregistrationStatus(statusCode) {
+		return
+	}
+
+	c.mu.Lock()
+	wasRegistered := c.registered
+	c.registered = false
+	c.mu.Unlock()
+
+	if statusCode == http.StatusGone {
+		c.forgetBridgeID()
+	}
+
+	if wasRegistered {
+		c.logger.WithField("status", statusCode).Warn("Bridge was deregistered by the API, registering again")
+	}
+
+	select {
+	case c.reregister <- struct{}{}:
+	default:
+	}
+}
+
+// saveBridgeID stores the ID assigned at registration and marks the bridge
+// registered
+func (c *Client) saveBridgeID(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.registered = true
+	if id == "" || id == c.bridgeID {
+		return
+	}
+
+	if c.bridgeID != "" {
+		c.logger.WithFields(logrus.Fields{
+			"old_bridge_id": c.bridgeID,
+			"new_bridge_id": id,
+		}).Info("API assigned a new bridge ID")
+	}
+	c.bridgeID = id
+
+	if c.store != nil {
+		if err := c.store.Set(c.identityKey(), []byte(id)); err != nil {
+			c.logger.WithError(err).Warn("Failed to persist bridge ID")
+		}
+	}
+}
+
+// forgetBridgeID discards the stored bridge ID so the next registration
+// creates a new bridge
+func (c *Client) forgetBridgeID() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bridgeID = ""
+	if c.store != nil {
+		if err := c.store.Delete(c.identityKey()); err != nil {
+			c.logger.WithError(err).Warn("Failed to delete bridge ID")
+		}
+	}
+}
+
+// identityKey is the storage key of the bridge ID for the client's community
+func (c *Client) identityKey() string {
+	return bridgeIDKey + ":" + c.config.CommunityID
+}
+
+// migrateBridgeID moves an ID saved under the single-community key to the
+// client's community key. Callers hold c.mu.
+func (c *Client) migrateBridgeID() ([]byte, error) {
+	data, err := c.store.Get(bridgeIDKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.Set(c.identityKey(), data); err != nil {
+		return nil, err
+	}
+	if err := c.store.Delete(bridgeIDKey); err != nil {
+		c.logger.WithError(err).Warn("Failed to delete migrated bridge ID")
+	}
+	return data, nil
+}
+
+// setBridgeHeader adds the bridge ID to a request, if one is known
+func (c *Client) setBridgeHeader(req *http.Request) {
+	if id := c.BridgeID(); id != "" {
+		req.Header.Set("X-Bridge-ID", id)
+	}
+}
+
+// isDeregistrationStatus reports whether an API status code means the
+// bridge must register again
+func isDeregistrationStatus(statusCode int) bool {
+	return statusCode == http.StatusUnauthorized || statusCode == http.StatusGone
+}
@@ -0,0 +1,244 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"waddlebot-bridge/internal/signing"
+)
+
+// Stream message types exchanged over the task stream
+const (
+	StreamMessageTask   = "task"
+	StreamMessageResult = "result"
+	StreamMessageAck    = "ack"
+)
+
+// TaskStreamPath is the API path of the task stream endpoint
+const TaskStreamPath = "/api/bridge/stream"
+
+const (
+	streamWriteWait   = 10 * time.Second
+	streamMinPing     = time.Second
+	streamDefaultPing = 30 * time.Second
+)
+
+// StreamMessage is the envelope for messages on the task stream
+type StreamMessage struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+}
+
+// MessageSigner signs outgoing stream messages. Messages are signed as
+// method "WS" with path "<type>/<id>" over the encoded data.
+type MessageSigner interface {
+	Sign(method, path string, body []byte) (string, error)
+}
+
+// TaskStream is a persistent WebSocket connection that receives tasks from
+// the WaddleBot API and streams results back
+type TaskStream struct {
+	conn         *websocket.Conn
+	pingInterval time.Duration
+	signer       MessageSigner
+	writeMu      sync.Mutex
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// OpenTaskStream connects to the task stream endpoint of the WaddleBot API
+func (c *Client) OpenTaskStream(ctx context.Context) (*TaskStream, error) {
+	token, err := c.GetAuthToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	header.Set("User-Agent", c.config.GetUserAgent())
+	header.Set("X-Community-ID", c.config.CommunityID)
+	header.Set("X-User-ID", c.config.UserID)
+
+	endpoint := c.config.GetAPIEndpoint(TaskStreamPath)
+	if c.signer != nil {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid task stream URL: %w", err)
+		}
+		signature, err := c.signer.Sign(http.MethodGet, u.Path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+		header.Set(signing.HeaderSignature, signature)
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = c.tlsConfig
+	dialer.Proxy = c.proxy
+
+	stream, err := DialTaskStream(ctx, endpoint, header, c.config.Transport.PingInterval, &dialer)
+	if err != nil {
+		return nil, err
+	}
+	if c.signer != nil {
+		stream.SetSigner(c.signer)
+	}
+	return stream, nil
+}
+
+// SetSigner makes the stream sign every message it sends
+func (s *TaskStream) SetSigner(signer MessageSigner) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.signer = signer
+}
+
+// DialTaskStream opens a task stream to the given HTTP(S) or WS(S) URL. The
+// connection is kept alive with pings every pingInterval and considered dead
+// if nothing is received for two intervals. A nil dialer uses
+// websocket.DefaultDialer.
+func DialTaskStream(ctx context.Context, endpoint string, header http.Header, pingInterval time.Duration, dialer *websocket.Dialer) (*TaskStream, error) {
+	wsURL, err := toWebSocketURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if pingInterval <= 0 {
+		pingInterval = streamDefaultPing
+	}
+	if pingInterval < streamMinPing {
+		pingInterval = streamMinPing
+	}
+
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect task stream: server returned status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to connect task stream: %w", err)
+	}
+
+	stream := &TaskStream{
+		conn:         conn,
+		pingInterval: pingInterval,
+		done:         make(chan struct{}),
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+	})
+
+	go stream.keepalive()
+
+	return stream, nil
+}
+
+// Receive blocks until the next message arrives
+func (s *TaskStream) Receive() (*StreamMessage, error) {
+	_, data, err := s.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	s.conn.SetReadDeadline(time.Now().Add(2 * s.pingInterval))
+
+	var msg StreamMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to parse stream message: %w", err)
+	}
+
+	return &msg, nil
+}
+
+// Send writes a message to the stream, encoding data as the message payload
+func (s *TaskStream) Send(msgType, id string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream message: %w", err)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	msg := StreamMessage{Type: msgType, ID: id, Data: payload}
+	if s.signer != nil {
+		msg.Signature, err = s.signer.Sign("WS", msgType+"/"+id, payload)
+		if err != nil {
+			return fmt.Errorf("failed to sign stream message: %w", err)
+		}
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+	return s.conn.WriteJSON(msg)
+}
+
+// Close closes the stream
+func (s *TaskStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		s.writeMu.Lock()
+		s.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		s.writeMu.Unlock()
+
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// keepalive pings the server until the stream is closed
+func (s *TaskStream) keepalive() {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.writeMu.Lock()
+			err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait))
+			s.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// toWebSocketURL converts an HTTP(S) URL to the matching WS(S) URL
+func toWebSocketURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream URL: %w", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return "", fmt.Errorf("invalid stream URL scheme: %s", u.Scheme)
+	}
+
+	return u.String(), nil
+}
@@ -0,0 +1,47 @@
+package bridge
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/upload"
+)
+
+// UploadOptions returns the upload features negotiated at the last
+// registration
+func (c *Client) UploadOptions() upload.Options {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.uploadOptions
+}
+
+// setUploadOptions records the negotiated upload features
+func (c *Client) setUploadOptions(opts upload.Options) {
+	c.mu.Lock()
+	c.uploadOptions = opts
+	c.mu.Unlock()
+
+	c.logger.WithFields(logrus.Fields{
+		"gzip":              opts.Gzip,
+		"batching":          opts.Batching,
+		"chunking":          opts.Chunking,
+		"max_payload_bytes": opts.MaxPayloadBytes,
+	}).Debug("Negotiated upload options")
+}
+
+// SupportsBatching reports whether the server accepts NDJSON batches
+func (c *Client) SupportsBatching() bool {
+	return c.UploadOptions().Batching
+}
+
+// PostNDJSON posts JSON documents to an API path as NDJSON batches that fit
+// the negotiated batch size and payload limit
+func (c *Client) PostNDJSON(ctx context.Context, path string, lines [][]byte) error {
+	opts := c.UploadOptions()
+	for _, batch := range upload.Batches(lines, opts.BatchSize, opts.MaxPayloadBytes) {
+		if _, err := c.post(ctx, path, upload.ContentTypeNDJSON, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
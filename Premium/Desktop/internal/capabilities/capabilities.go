@@ -0,0 +1,224 @@
+// Package capabilities describes what the bridge can do. The description is
+// sent at registration and with every heartbeat, so the server only sends
+// tasks the bridge can run. It is built from the bridge's components each
+// time, following modules, script engines and OBS as they come and go.
+package capabilities
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"waddlebot-bridge/internal/features"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/scripting/common"
+)
+
+// protocolVersion is the version of capabilities the bridge defines itself,
+// raised when the tasks they accept change incompatibly
+const protocolVersion = "1"
+
+// Capability describes one thing the bridge can do. Names are prefixed
+// with their kind: task., module., script., obs and feature.
+type Capability struct {
+	Name       string            `json:"name"`
+	Version    string            `json:"version"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// legacyNames are the flat names servers matched on before capabilities
+// were described, kept for the capabilities that still exist
+var legacyNames = map[string]string{
+	"task.module_action":     "local_execution",
+	"module.file_operations": "file_operations",
+	"module.http_request":    "network_operations",
+}
+
+// ModuleSource lists the loaded modules
+type ModuleSource interface {
+	GetModuleInfos() []models.ModuleInfo
+}
+
+// ScriptSource lists the enabled script engines
+type ScriptSource interface {
+	GetEnabledTypes() []common.ScriptType
+}
+
+// OBSSource reports the OBS connection
+type OBSSource interface {
+	IsConnected() bool
+	GetConnectionInfo() obs.ConnectionInfo
+}
+
+// FeatureSource reports the subscription's features
+type FeatureSource interface {
+	Tier() string
+	List() []features.Info
+}
+
+// Set builds the bridge's capabilities from its components. Sources are
+// optional; capabilities of missing ones are left out.
+type Set struct {
+	mu        sync.RWMutex
+	modules   ModuleSource
+	scripts   ScriptSource
+	obs       OBSSource
+	features  FeatureSource
+	taskTypes []string
+}
+
+// New creates an empty capability set
+func New() *Set {
+	return &Set{}
+}
+
+// SetModules sets the module source
+func (s *Set) SetModules(source ModuleSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modules = source
+}
+
+// SetScripts sets the script engine source
+func (s *Set) SetScripts(source ScriptSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts = source
+}
+
+// SetOBS sets the OBS connection source
+func (s *Set) SetOBS(source OBSSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.obs = source
+}
+
+// SetFeatures sets the subscription feature source
+func (s *Set) SetFeatures(source FeatureSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.features = source
+}
+
+// SetTaskTypes limits the task types offered to those the community
+// accepts, as in task-types; all are offered when types is empty
+func (s *Set) SetTaskTypes(types []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.taskTypes = types
+}
+
+// Collect describes the bridge's current capabilities, sorted by name
+func (s *Set) Collect() []Capability {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	capabilities := []Capability{{Name: "task.module_action", Version: protocolVersion}}
+
+	if s.modules != nil {
+		for _, info := range s.modules.GetModuleInfos() {
+			if !info.Enabled {
+				continue
+			}
+			actions := make([]string, len(info.Actions))
+			for i, action := range info.Actions {
+				actions[i] = action.Name
+			}
+			sort.Strings(actions)
+			capabilities = append(capabilities, Capability{
+				Name:       "module." + info.Name,
+				Version:    info.Version,
+				Attributes: map[string]string{"actions": strings.Join(actions, ",")},
+			})
+		}
+	}
+
+	if s.scripts != nil {
+		if engines := s.scripts.GetEnabledTypes(); len(engines) > 0 {
+			capabilities = append(capabilities, Capability{Name: "task.script", Version: protocolVersion})
+			for _, engine := range engines {
+				capabilities = append(capabilities, Capability{Name: "script." + string(engine), Version: protocolVersion})
+			}
+		}
+	}
+
+	// OBS tasks fail while OBS is not connected, so they are only offered
+	// while it is
+	if s.obs != nil && s.obs.IsConnected() {
+		info := s.obs.GetConnectionInfo()
+		capabilities = append(capabilities,
+			Capability{Name: "task.obs", Version: protocolVersion},
+			Capability{
+				Name:       "obs",
+				Version:    info.WebSocketVersion,
+				Attributes: map[string]string{"obs_version": info.OBSVersion},
+			})
+	}
+
+	if s.features != nil {
+		tier := s.features.Tier()
+		for _, feature := range s.features.List() {
+			if feature.Enabled {
+				capabilities = append(capabilities, Capability{
+					Name:       "feature." + string(feature.Name),
+					Version:    protocolVersion,
+					Attributes: map[string]string{"tier": tier},
+				})
+			}
+		}
+	}
+
+	if len(s.taskTypes) > 0 {
+		offered := capabilities[:0]
+		for _, capability := range capabilities {
+			if taskType, ok := taskTypeOf(capability.Name); !ok || s.acceptsTask(taskType) {
+				offered = append(offered, capability)
+			}
+		}
+		capabilities = offered
+	}
+
+	sort.Slice(capabilities, func(i, j int) bool {
+		return capabilities[i].Name < capabilities[j].Name
+	})
+	return capabilities
+}
+
+// taskTypeOf returns the task type a capability is only used by, if any.
+// Modules are also used by scripts, so they are offered either way.
+func taskTypeOf(name string) (string, bool) {
+	switch {
+	case strings.HasPrefix(name, "task."):
+		return strings.TrimPrefix(name, "task."), true
+	case strings.HasPrefix(name, "script."):
+		return "script", true
+	case name == "obs":
+		return "obs", true
+	}
+	return "", false
+}
+
+func (s *Set) acceptsTask(taskType string) bool {
+	for _, accepted := range s.taskTypes {
+		if accepted == taskType {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names of capabilities, followed by the legacy names of
+// those that have one
+func Names(capabilities []Capability) []string {
+	names := make([]string, 0, len(capabilities))
+	var legacy []string
+	for _, capability := range capabilities {
+		names = append(names, capability.Name)
+		if name, ok := legacyNames[capability.Name]; ok {
+			legacy = append(legacy, name)
+		}
+	}
+	sort.Strings(legacy)
+	return append(names, legacy...)
+}
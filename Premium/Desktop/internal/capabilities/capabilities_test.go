@@ -0,0 +1,99 @@
+package capabilities
+
+import (
+	"reflect"
+	"testing"
+
+	"waddlebot-bridge/internal/features"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/scripting/common"
+)
+
+type fakeModules []models.ModuleInfo
+
+func (f fakeModules) GetModuleInfos() []models.ModuleInfo { return f }
+
+type fakeScripts []common.ScriptType
+
+func (f fakeScripts) GetEnabledTypes() []common.ScriptType { return f }
+
+type fakeOBS struct{ connected bool }
+
+func (f fakeOBS) IsConnected() bool { return f.connected }
+
+func (f fakeOBS) GetConnectionInfo() obs.ConnectionInfo {
+	return obs.ConnectionInfo{OBSVersion: "30.1.0", WebSocketVersion: "5.4.2"}
+}
+
+type fakeFeatures struct{}
+
+func (fakeFeatures) Tier() string { return "premium" }
+
+func (fakeFeatures) List() []features.Info {
+	return []features.Info{
+		{Name: features.Macros, Enabled: true},
+		{Name: features.MultiOBS},
+	}
+}
+
+func TestSet_Collect(t *testing.T) {
+	set := New()
+	set.SetModules(fakeModules{
+		{Name: "file_operations", Version: "1.2.0", Enabled: true, Actions: []models.ActionInfo{{Name: "write_file"}, {Name: "read_file"}}},
+		{Name: "disabled", Version: "1.0.0"},
+	})
+	set.SetScripts(fakeScripts{common.ScriptTypeLua})
+	set.SetOBS(fakeOBS{connected: true})
+	set.SetFeatures(fakeFeatures{})
+
+	collected := set.Collect()
+	want := []string{
+		"feature.macros", "module.file_operations", "obs", "script.lua",
+		"task.module_action", "task.obs", "task.script",
+		"file_operations", "local_execution",
+	}
+	if names := Names(collected); !reflect.DeepEqual(names, want) {
+		t.Errorf("Names = %v, want %v", names, want)
+	}
+
+	for _, capability := range collected {
+		switch capability.Name {
+		case "module.file_operations":
+			if capability.Version != "1.2.0" || capability.Attributes["actions"] != "read_file,write_file" {
+				t.Errorf("Unexpected module capability %+v", capability)
+			}
+		case "obs":
+			if capability.Version != "5.4.2" || capability.Attributes["obs_version"] != "30.1.0" {
+				t.Errorf("Unexpected OBS capability %+v", capability)
+			}
+		case "feature.macros":
+			if capability.Attributes["tier"] != "premium" {
+				t.Errorf("Unexpected feature capability %+v", capability)
+			}
+		}
+	}
+}
+
+func TestSet_Collect_Unavailable(t *testing.T) {
+	set := New()
+	set.SetScripts(fakeScripts{})
+	set.SetOBS(fakeOBS{connected: false})
+
+	want := []string{"task.module_action", "local_execution"}
+	if names := Names(set.Collect()); !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected no script or OBS capabilities, got %v", names)
+	}
+}
+
+func TestSet_TaskTypes(t *testing.T) {
+	set := New()
+	set.SetScripts(fakeScripts{common.ScriptTypeBash})
+	set.SetOBS(fakeOBS{connected: true})
+	set.SetTaskTypes([]string{"obs"})
+
+	want := []string{"obs", "task.obs"}
+	if names := Names(set.Collect()); !reflect.DeepEqual(names, want) {
+		t.Errorf("Names = %v, want %v", names, want)
+	}
+}
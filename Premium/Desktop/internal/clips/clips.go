@@ -0,0 +1,494 @@
+// Package clips uploads replay buffer clips and recordings to the WaddleBot
+// API. A clip task saves the replay buffer and returns the uploaded clip's
+// link to the task; other saved replays and finished recordings can be
+// uploaded as they appear. Files can be trimmed and transcoded with ffmpeg
+// first. Uploads are sent in chunks and resume where they stopped, across
+// restarts of the bridge.
+package clips
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/bridge"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/storage"
+)
+
+// Kinds of clips
+const (
+	KindReplay    = "replay"
+	KindRecording = "recording"
+)
+
+// Events published on the bus
+const (
+	EventUploaded = "clips.uploaded"
+	EventFailed   = "clips.failed"
+)
+
+const (
+	// maxChunkRetries is how often a chunk is retried before an upload
+	// is given up
+	maxChunkRetries = 5
+
+	// queueSize bounds the uploads waiting in the background
+	queueSize = 32
+
+	// recentTTL is how long a file is remembered, so a file OBS reports
+	// twice is uploaded once
+	recentTTL = 10 * time.Minute
+)
+
+// ErrTooLarge is returned for files over the configured size cap
+var ErrTooLarge = errors.New("clip is larger than the upload limit")
+
+// API is the part of the bridge client clips are uploaded with
+type API interface {
+	StartClipUpload(ctx context.Context, clip bridge.ClipUpload) (*bridge.ClipSession, error)
+	SendClipChunk(ctx context.Context, uploadID string, offset int64, data []byte) (int64, error)
+	CompleteClipUpload(ctx context.Context, uploadID string) (*bridge.Clip, error)
+}
+
+// OBS is the part of the OBS client clips are saved with
+type OBS interface {
+	SaveReplay(ctx context.Context) (string, error)
+	Subscribe(callback obs.EventCallback, eventTypes ...obs.EventType) obs.SubscriptionID
+	Unsubscribe(id obs.SubscriptionID)
+}
+
+// pending is an upload that has not finished
+type pending struct {
+	UploadID string `json:"upload_id"`
+	Path     string `json:"path"` // the file saved by OBS, before ffmpeg
+	Kind     string `json:"kind"`
+	TaskID   string `json:"task_id,omitempty"`
+}
+
+// job is a file to upload in the background
+type job struct {
+	path   string
+	kind   string
+	taskID string
+}
+
+// Uploader uploads clips to one community
+type Uploader struct {
+	cfg       config.ClipsConfig
+	api       API
+	obs       OBS
+	store     *storage.ClipRepo
+	community string // prefixes the keys of unfinished uploads
+	logger    *logrus.Logger
+	jobs      chan job
+
+	// uploadMu sends one upload at a time; they share the uplink anyway
+	uploadMu sync.Mutex
+
+	mu        sync.Mutex
+	publisher events.Publisher     // nil when the event bus is disabled
+	saving    int                  // replays being saved by clip tasks, which upload them themselves
+	recent    map[string]time.Time // files queued or uploaded, by path
+}
+
+// New creates an uploader for a community
+func New(cfg config.ClipsConfig, community string, api API, obsClient OBS, store storage.Storage, logger *logrus.Logger) (*Uploader, error) {
+	repo, err := storage.NewClipRepo(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clips bucket: %w", err)
+	}
+
+	return &Uploader{
+		cfg:       cfg,
+		api:       api,
+		obs:       obsClient,
+		store:     repo,
+		community: community,
+		logger:    logger,
+		jobs:      make(chan job, queueSize),
+		recent:    make(map[string]time.Time),
+	}, nil
+}
+
+// SetEventPublisher publishes clip uploads and failures on the event bus
+func (u *Uploader) SetEventPublisher(publisher events.Publisher) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.publisher = publisher
+}
+
+// Run uploads files in the background until ctx is done: uploads left
+// unfinished at the last run, the replays and recordings OBS saves if
+// they are enabled, and clips whose task gave up waiting
+func (u *Uploader) Run(ctx context.Context) error {
+	var types []obs.EventType
+	if u.cfg.UploadReplays {
+		types = append(types, obs.EventReplayBufferSaved)
+	}
+	if u.cfg.UploadRecordings {
+		types = append(types, obs.EventRecordingStopped)
+	}
+	if len(types) > 0 {
+		id := u.obs.Subscribe(u.handleEvent, types...)
+		defer u.obs.Unsubscribe(id)
+	}
+
+	u.resume()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case j := <-u.jobs:
+			if _, err := u.Upload(ctx, j.path, j.kind, j.taskID); err != nil && ctx.Err() == nil {
+				u.logger.WithError(err).WithField("path", j.path).Warn("Failed to upload clip")
+			}
+		}
+	}
+}
+
+// handleEvent queues the file of a saved replay or finished recording
+func (u *Uploader) handleEvent(event obs.Event) {
+	path, _ := event.Data["output_path"].(string)
+	if path == "" {
+		return
+	}
+
+	kind := KindRecording
+	if event.Type == obs.EventReplayBufferSaved {
+		kind = KindReplay
+		u.mu.Lock()
+		saving := u.saving > 0
+		u.mu.Unlock()
+		if saving {
+			return
+		}
+	}
+
+	if u.remember(path) {
+		u.enqueue(job{path: path, kind: kind})
+	}
+}
+
+// resume queues the uploads left unfinished at the last run
+func (u *Uploader) resume() {
+	keys, err := u.store.List(u.community + "/")
+	if err != nil {
+		u.logger.WithError(err).Warn("Failed to load unfinished clip uploads")
+		return
+	}
+
+	for _, key := range keys {
+		data, err := u.store.Get(key)
+		if err != nil {
+			continue
+		}
+		var p pending
+		if err := json.Unmarshal(data, &p); err != nil {
+			u.store.Delete(key)
+			continue
+		}
+		if _, err := os.Stat(p.Path); err != nil {
+			// The file is gone; the upload cannot be finished
+			u.store.Delete(key)
+			continue
+		}
+		if u.remember(p.Path) {
+			u.enqueue(job{path: p.Path, kind: p.Kind, taskID: p.TaskID})
+		}
+	}
+}
+
+// remember records a file, returning false if it was seen recently
+func (u *Uploader) remember(path string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := time.Now()
+	for seen, at := range u.recent {
+		if now.Sub(at) > recentTTL {
+			delete(u.recent, seen)
+		}
+	}
+	if _, seen := u.recent[path]; seen {
+		return false
+	}
+	u.recent[path] = now
+	return true
+}
+
+// enqueue queues a background upload
+func (u *Uploader) enqueue(j job) {
+	select {
+	case u.jobs <- j:
+	default:
+		u.logger.WithField("path", j.path).Warn("Clip upload queue is full, skipping clip")
+	}
+}
+
+// Clip saves the replay buffer and uploads the clip for a task. If the
+// upload does not finish in time, it carries on in the background and
+// publishes its link when done.
+func (u *Uploader) Clip(ctx context.Context, taskID string) (*bridge.Clip, error) {
+	u.mu.Lock()
+	u.saving++
+	u.mu.Unlock()
+
+	path, err := u.obs.SaveReplay(ctx)
+
+	u.mu.Lock()
+	if err == nil {
+		u.recent[path] = time.Now()
+	}
+	u.saving--
+	u.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save replay buffer: %w", err)
+	}
+
+	clip, err := u.Upload(ctx, path, KindReplay, taskID)
+	if err != nil && !errors.Is(err, ErrTooLarge) && ctx.Err() != nil {
+		u.enqueue(job{path: path, kind: KindReplay, taskID: taskID})
+		return nil, fmt.Errorf("%w; the upload continues in the background", err)
+	}
+	return clip, err
+}
+
+// Upload prepares a file with ffmpeg, if enabled, and uploads it,
+// resuming an earlier upload of the same file
+func (u *Uploader) Upload(ctx context.Context, path, kind, taskID string) (_ *bridge.Clip, err error) {
+	u.uploadMu.Lock()
+	defer u.uploadMu.Unlock()
+
+	log := u.logger.WithFields(logrus.Fields{"path": path, "kind": kind})
+	defer func() {
+		// An upload cut short by its context is resumed later
+		if err != nil && ctx.Err() == nil {
+			u.publish(EventFailed, map[string]interface{}{
+				"file_name": filepath.Base(path),
+				"kind":      kind,
+				"task_id":   taskID,
+				"error":     err.Error(),
+			})
+		}
+	}()
+
+	file, cleanup, err := u.prepare(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open clip: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clip: %w", err)
+	}
+	if limit := int64(u.cfg.MaxSizeMB) << 20; info.Size() > limit {
+		return nil, fmt.Errorf("%w: %d MB", ErrTooLarge, info.Size()>>20)
+	}
+
+	sum, err := hashFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	request := bridge.ClipUpload{
+		FileName:    filepath.Base(file),
+		Size:        info.Size(),
+		SHA256:      sum,
+		ContentType: contentType(file),
+		Kind:        kind,
+		TaskID:      taskID,
+	}
+	key := u.community + "/" + sum
+	if data, err := u.store.Get(key); err == nil {
+		var p pending
+		if json.Unmarshal(data, &p) == nil {
+			request.UploadID = p.UploadID
+		}
+	}
+
+	session, err := u.api.StartClipUpload(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start clip upload: %w", err)
+	}
+	request.UploadID = session.UploadID
+	if data, err := json.Marshal(pending{UploadID: session.UploadID, Path: path, Kind: kind, TaskID: taskID}); err == nil {
+		if err := u.store.Set(key, data); err != nil {
+			log.WithError(err).Debug("Failed to record clip upload")
+		}
+	}
+
+	if session.Offset > 0 {
+		log.WithField("offset", session.Offset).Info("Resuming clip upload")
+	}
+	if err := u.send(ctx, f, request, session); err != nil {
+		return nil, err
+	}
+
+	clip, err := u.api.CompleteClipUpload(ctx, session.UploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete clip upload: %w", err)
+	}
+	u.store.Delete(key)
+
+	log.WithFields(logrus.Fields{"url": clip.URL, "size": info.Size()}).Info("Uploaded clip")
+	u.publish(EventUploaded, map[string]interface{}{
+		"clip_id":   clip.ID,
+		"url":       clip.URL,
+		"file_name": request.FileName,
+		"size":      info.Size(),
+		"kind":      kind,
+		"task_id":   taskID,
+	})
+	return clip, nil
+}
+
+// send sends the file in chunks from where the server stands. A failed
+// chunk is retried after asking the server how much it has.
+func (u *Uploader) send(ctx context.Context, f *os.File, request bridge.ClipUpload, session *bridge.ClipSession) error {
+	chunkSize := int64(u.cfg.ChunkSizeMB) << 20
+	if session.ChunkSize > 0 && session.ChunkSize < chunkSize {
+		chunkSize = session.ChunkSize
+	}
+
+	buf := make([]byte, chunkSize)
+	offset := session.Offset
+	failures := 0
+	for offset < request.Size {
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read clip: %w", err)
+		}
+
+		next, err := u.api.SendClipChunk(ctx, session.UploadID, offset, buf[:n])
+		if err == nil && next <= offset {
+			err = fmt.Errorf("server did not accept the chunk at %d", offset)
+		}
+		if err == nil {
+			offset, failures = next, 0
+			continue
+		}
+
+		failures++
+		if failures > maxChunkRetries || ctx.Err() != nil {
+			return fmt.Errorf("failed to send clip chunk: %w", err)
+		}
+		u.logger.WithError(err).WithField("attempt", failures).Debug("Retrying clip chunk")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(failures) * time.Second):
+		}
+
+		// The server may have kept part of the chunk
+		if resumed, err := u.api.StartClipUpload(ctx, request); err == nil {
+			offset = resumed.Offset
+		}
+	}
+	return nil
+}
+
+// prepare runs ffmpeg on a file, if enabled, returning the file to upload
+// and a function that removes any temporary file
+func (u *Uploader) prepare(ctx context.Context, path string) (string, func(), error) {
+	if !u.cfg.FFmpeg.Enabled {
+		return path, func() {}, nil
+	}
+
+	ext := filepath.Ext(path)
+	if u.cfg.FFmpeg.Format != "" {
+		ext = "." + strings.TrimPrefix(u.cfg.FFmpeg.Format, ".")
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	dir, err := os.MkdirTemp("", "waddlebot-clip-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	out := filepath.Join(dir, base+ext)
+
+	cmd := exec.CommandContext(ctx, u.cfg.FFmpeg.Path, ffmpegArgs(u.cfg.FFmpeg, path, out)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return out, cleanup, nil
+}
+
+// ffmpegArgs returns the ffmpeg arguments that trim and transcode in to out
+func ffmpegArgs(cfg config.FFmpegConfig, in, out string) []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-y"}
+	if cfg.TrimSeconds > 0 {
+		args = append(args, "-sseof", "-"+strconv.Itoa(cfg.TrimSeconds))
+	}
+	args = append(args, "-i", in)
+	if len(cfg.Args) > 0 {
+		args = append(args, cfg.Args...)
+	} else {
+		args = append(args, "-c", "copy")
+	}
+	return append(args, out)
+}
+
+// hashFile returns the hex SHA-256 of a file, which identifies its upload
+func hashFile(f *os.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, 1<<62)); err != nil {
+		return "", fmt.Errorf("failed to hash clip: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contentType returns the media type of a clip from its extension
+func contentType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mkv":
+		return "video/x-matroska"
+	case ".flv":
+		return "video/x-flv"
+	}
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// publish publishes a clip event on the bus, if there is one
+func (u *Uploader) publish(eventType string, data map[string]interface{}) {
+	u.mu.Lock()
+	publisher := u.publisher
+	u.mu.Unlock()
+	if publisher == nil {
+		return
+	}
+	publisher.Publish(events.Event{
+		Type:   eventType,
+		Source: events.SourceBridge,
+		Data:   data,
+	})
+}
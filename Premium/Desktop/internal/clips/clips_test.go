@@ -0,0 +1,153 @@
+package clips
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/bridge"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/testutils"
+)
+
+// fakeAPI keeps uploads in memory. failAt makes the chunk at that offset
+// fail once, after the server kept half of it.
+type fakeAPI struct {
+	received map[string][]byte
+	starts   int
+	failAt   int64
+	failed   bool
+}
+
+func (f *fakeAPI) StartClipUpload(ctx context.Context, clip bridge.ClipUpload) (*bridge.ClipSession, error) {
+	f.starts++
+	id := clip.UploadID
+	if id == "" {
+		id = "upload_1"
+		f.received[id] = nil
+	}
+	return &bridge.ClipSession{UploadID: id, Offset: int64(len(f.received[id]))}, nil
+}
+
+func (f *fakeAPI) SendClipChunk(ctx context.Context, uploadID string, offset int64, data []byte) (int64, error) {
+	if offset != int64(len(f.received[uploadID])) {
+		return 0, errors.New("offset mismatch")
+	}
+	if offset == f.failAt && !f.failed {
+		f.failed = true
+		f.received[uploadID] = append(f.received[uploadID], data[:len(data)/2]...)
+		return 0, errors.New("connection reset")
+	}
+	f.received[uploadID] = append(f.received[uploadID], data...)
+	return int64(len(f.received[uploadID])), nil
+}
+
+func (f *fakeAPI) CompleteClipUpload(ctx context.Context, uploadID string) (*bridge.Clip, error) {
+	return &bridge.Clip{ID: "clip_1", URL: "https://waddlebot.example/clips/clip_1"}, nil
+}
+
+type fakeOBS struct {
+	path string
+}
+
+func (f *fakeOBS) SaveReplay(ctx context.Context) (string, error) { return f.path, nil }
+
+func (f *fakeOBS) Subscribe(callback obs.EventCallback, eventTypes ...obs.EventType) obs.SubscriptionID {
+	return "sub"
+}
+
+func (f *fakeOBS) Unsubscribe(id obs.SubscriptionID) {}
+
+func newTestUploader(t *testing.T, size int) (*Uploader, *fakeAPI, string) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	path := filepath.Join(t.TempDir(), "Replay 2024-05-01.mkv")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("clip"), size/4), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	api := &fakeAPI{received: make(map[string][]byte), failAt: -1}
+	cfg := config.ClipsConfig{Enabled: true, MaxSizeMB: 1, ChunkSizeMB: 1}
+	u, err := New(cfg, "community_1", api, &fakeOBS{path: path}, testutils.NewMockStorage(), logger)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return u, api, path
+}
+
+func TestUploader_Clip(t *testing.T) {
+	u, api, path := newTestUploader(t, 4096)
+
+	clip, err := u.Clip(context.Background(), "task_1")
+	if err != nil {
+		t.Fatalf("Clip failed: %v", err)
+	}
+	if clip.URL != "https://waddlebot.example/clips/clip_1" {
+		t.Errorf("Unexpected clip %+v", clip)
+	}
+
+	want, _ := os.ReadFile(path)
+	if !bytes.Equal(api.received["upload_1"], want) {
+		t.Error("Expected the server to receive the whole file")
+	}
+
+	// The replay saved event that follows must not upload it again
+	u.handleEvent(obs.Event{Type: obs.EventReplayBufferSaved, Data: map[string]interface{}{"output_path": path}})
+	if len(u.jobs) != 0 {
+		t.Error("Expected the clip not to be queued twice")
+	}
+
+	if stored, _ := u.store.All(); len(stored) != 0 {
+		t.Errorf("Expected no unfinished uploads, got %d", len(stored))
+	}
+}
+
+func TestUploader_ResumesFailedChunk(t *testing.T) {
+	u, api, path := newTestUploader(t, 3<<20)
+	u.cfg.MaxSizeMB = 4
+	api.failAt = 1 << 20
+
+	if _, err := u.Upload(context.Background(), path, KindRecording, ""); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	want, _ := os.ReadFile(path)
+	if !bytes.Equal(api.received["upload_1"], want) {
+		t.Error("Expected the upload to resume from what the server kept")
+	}
+	if api.starts != 2 {
+		t.Errorf("Expected the upload to be resumed once, got %d starts", api.starts)
+	}
+}
+
+func TestUploader_TooLarge(t *testing.T) {
+	u, _, path := newTestUploader(t, 2<<20)
+
+	if _, err := u.Upload(context.Background(), path, KindRecording, ""); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestFFmpegArgs(t *testing.T) {
+	got := ffmpegArgs(config.FFmpegConfig{TrimSeconds: 30}, "in.mkv", "out.mp4")
+	want := []string{"-hide_banner", "-loglevel", "error", "-y", "-sseof", "-30", "-i", "in.mkv", "-c", "copy", "out.mp4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ffmpegArgs() = %v, want %v", got, want)
+	}
+
+	got = ffmpegArgs(config.FFmpegConfig{Args: []string{"-c:v", "libx264"}}, "in.mkv", "out.mp4")
+	want = []string{"-hide_banner", "-loglevel", "error", "-y", "-i", "in.mkv", "-c:v", "libx264", "out.mp4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ffmpegArgs() = %v, want %v", got, want)
+	}
+}
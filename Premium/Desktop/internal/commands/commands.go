@@ -0,0 +1,413 @@
+// Package commands is the command palette: a single catalog of everything
+// the bridge can run locally (module actions, scripts, OBS actions and OBS
+// macros) with parameter schemas, so a desktop UI or Stream Deck plugin can
+// discover and run them through one API.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/poller"
+	"waddlebot-bridge/internal/scripting"
+)
+
+// Kind identifies what runs a command
+type Kind string
+
+// Command kinds. A command ID is its kind and name joined by a colon, such
+// as "module:soundboard/play", "script:intro.lua", "obs:toggle_stream" or
+// "macro:go_live".
+const (
+	KindModule Kind = "module"
+	KindScript Kind = "script"
+	KindOBS    Kind = "obs"
+	KindMacro  Kind = "macro"
+)
+
+// Parameter describes a command parameter
+type Parameter struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// Command is an executable action in the catalog
+type Command struct {
+	ID          string               `json:"id"`
+	Kind        Kind                 `json:"kind"`
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Parameters  map[string]Parameter `json:"parameters"`
+}
+
+// ModuleManager executes module actions and lists loaded modules
+type ModuleManager interface {
+	ExecuteAction(ctx context.Context, moduleName, action string, parameters map[string]string) (map[string]interface{}, error)
+	GetModuleInfos() []models.ModuleInfo
+}
+
+// scriptExtensions maps script file extensions to script types
+var scriptExtensions = map[string]scripting.ScriptType{
+	".lua": scripting.ScriptTypeLua,
+	".py":  scripting.ScriptTypePython,
+	".ps1": scripting.ScriptTypePowerShell,
+	".sh":  scripting.ScriptTypeBash,
+}
+
+// obsCommands are the OBS actions supported by the OBS task executor
+var obsCommands = []Command{
+	{Name: "set_scene", Description: "Switch to a scene", Parameters: map[string]Parameter{
+		"scene": {Type: "string", Description: "Scene name", Required: true},
+	}},
+	{Name: "set_source_visibility", Description: "Show or hide a source in a scene", Parameters: map[string]Parameter{
+		"scene":   {Type: "string", Description: "Scene name", Required: true},
+		"source":  {Type: "string", Description: "Source name", Required: true},
+		"visible": {Type: "boolean", Description: "Whether the source is shown", Required: true},
+	}},
+	{Name: "toggle_filter", Description: "Enable or disable a source filter", Parameters: map[string]Parameter{
+		"source": {Type: "string", Description: "Source name", Required: true},
+		"filter": {Type: "string", Description: "Filter name", Required: true},
+	}},
+	{Name: "start_stream", Description: "Start streaming"},
+	{Name: "stop_stream", Description: "Stop streaming"},
+	{Name: "toggle_stream", Description: "Start or stop streaming"},
+	{Name: "start_recording", Description: "Start recording"},
+	{Name: "stop_recording", Description: "Stop recording"},
+	{Name: "toggle_recording", Description: "Start or stop recording"},
+	{Name: "save_replay", Description: "Save the replay buffer"},
+}
+
+// Catalog lists and runs commands. Each source of commands is optional and
+// is added with its setter; commands of a missing source are not listed.
+type Catalog struct {
+	modules    ModuleManager
+	moduleExec poller.TaskExecutor
+
+	scriptExec    poller.TaskExecutor
+	scriptsDir    string
+	scriptEnabled func(scripting.ScriptType) bool
+
+	obsExec poller.TaskExecutor
+	macros  []config.OBSMacroConfig
+
+	logger *logrus.Logger
+}
+
+// NewCatalog creates an empty command catalog
+func NewCatalog(logger *logrus.Logger) *Catalog {
+	return &Catalog{logger: logger}
+}
+
+// SetModules adds the actions of loaded modules
+func (c *Catalog) SetModules(manager ModuleManager) {
+	c.modules = manager
+	c.moduleExec = poller.NewModuleExecutor(manager)
+}
+
+// SetScripts adds the scripts in the configured scripts directory whose
+// script type is enabled
+func (c *Catalog) SetScripts(manager *scripting.Manager, cfg config.ScriptingConfig) {
+	c.scriptExec = poller.NewScriptExecutor(manager, cfg)
+	c.scriptsDir = cfg.ScriptsDir
+	c.scriptEnabled = manager.IsTypeEnabled
+}
+
+// SetOBS adds the OBS actions and the configured OBS macros
+func (c *Catalog) SetOBS(client *obs.Client, macros []config.OBSMacroConfig) {
+	c.obsExec = poller.NewOBSExecutor(client)
+	c.macros = macros
+}
+
+// List returns every available command sorted by ID
+func (c *Catalog) List() []Command {
+	var list []Command
+
+	if c.modules != nil {
+		for _, info := range c.modules.GetModuleInfos() {
+			if !info.Enabled {
+				continue
+			}
+			for _, action := range info.Actions {
+				list = append(list, Command{
+					ID:          commandID(KindModule, info.Name+"/"+action.Name),
+					Kind:        KindModule,
+					Name:        info.Name + "/" + action.Name,
+					Description: action.Description,
+					Parameters:  moduleParameters(action.Parameters),
+				})
+			}
+		}
+	}
+
+	if c.scriptExec != nil {
+		scripts, err := c.scripts()
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to list scripts")
+		}
+		for name := range scripts {
+			list = append(list, Command{
+				ID:          commandID(KindScript, name),
+				Kind:        KindScript,
+				Name:        name,
+				Description: "Run the " + string(scripts[name]) + " script " + name + "; parameters are passed as environment variables",
+				Parameters:  map[string]Parameter{},
+			})
+		}
+	}
+
+	if c.obsExec != nil {
+		for _, command := range obsCommands {
+			command.ID = commandID(KindOBS, command.Name)
+			command.Kind = KindOBS
+			if command.Parameters == nil {
+				command.Parameters = map[string]Parameter{}
+			}
+			list = append(list, command)
+		}
+		for _, macro := range c.macros {
+			list = append(list, Command{
+				ID:          commandID(KindMacro, macro.Name),
+				Kind:        KindMacro,
+				Name:        macro.Name,
+				Description: macro.Description,
+				Parameters:  map[string]Parameter{},
+			})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Execute runs the command with the given ID
+func (c *Catalog) Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error) {
+	kind, name, ok := strings.Cut(id, ":")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, id)
+	}
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+
+	switch Kind(kind) {
+	case KindModule:
+		if c.moduleExec == nil {
+			return nil, fmt.Errorf("%w: modules", ErrUnavailable)
+		}
+		module, action, ok := strings.Cut(name, "/")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, id)
+		}
+		return c.moduleExec.Execute(ctx, poller.ActionRequest{
+			Type:       poller.TaskTypeModule,
+			ModuleName: module,
+			Action:     action,
+			Parameters: parameters,
+		})
+
+	case KindScript:
+		return c.executeScript(ctx, id, name, parameters)
+
+	case KindOBS:
+		if c.obsExec == nil {
+			return nil, fmt.Errorf("%w: obs", ErrUnavailable)
+		}
+		command, found := findOBSCommand(name)
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, id)
+		}
+		if err := checkRequired(command.Parameters, parameters); err != nil {
+			return nil, err
+		}
+		return c.obsExec.Execute(ctx, poller.ActionRequest{
+			Type:       poller.TaskTypeOBS,
+			Action:     name,
+			Parameters: parameters,
+		})
+
+	case KindMacro:
+		return c.executeMacro(ctx, id, name)
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, id)
+	}
+}
+
+// executeScript runs a script from the scripts directory. Only scripts
+// found by scripts can be run, so a command ID cannot reach files outside
+// the directory.
+func (c *Catalog) executeScript(ctx context.Context, id, name string, parameters map[string]string) (map[string]interface{}, error) {
+	if c.scriptExec == nil {
+		return nil, fmt.Errorf("%w: scripting", ErrUnavailable)
+	}
+	scripts, err := c.scripts()
+	if err != nil {
+		return nil, err
+	}
+	scriptType, found := scripts[name]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, id)
+	}
+
+	source, err := os.ReadFile(filepath.Join(c.scriptsDir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script %s: %w", name, err)
+	}
+
+	params := make(map[string]string, len(parameters)+1)
+	for key, value := range parameters {
+		params[key] = value
+	}
+	params["source"] = string(source)
+
+	return c.scriptExec.Execute(ctx, poller.ActionRequest{
+		Type:       poller.TaskTypeScript,
+		Action:     string(scriptType),
+		Parameters: params,
+	})
+}
+
+// executeMacro runs the steps of an OBS macro in order, stopping at the
+// first failure
+func (c *Catalog) executeMacro(ctx context.Context, id, name string) (map[string]interface{}, error) {
+	if c.obsExec == nil {
+		return nil, fmt.Errorf("%w: obs", ErrUnavailable)
+	}
+
+	for _, macro := range c.macros {
+		if macro.Name != name {
+			continue
+		}
+
+		results := make([]map[string]interface{}, 0, len(macro.Steps))
+		for i, step := range macro.Steps {
+			result, err := c.obsExec.Execute(ctx, poller.ActionRequest{
+				Type:       poller.TaskTypeOBS,
+				Action:     step.Action,
+				Parameters: step.Parameters,
+			})
+			if err != nil {
+				return map[string]interface{}{"steps": results}, fmt.Errorf("macro %s step %d (%s) failed: %w", name, i+1, step.Action, err)
+			}
+			results = append(results, result)
+		}
+		return map[string]interface{}{"steps": results}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, id)
+}
+
+// scripts returns the runnable scripts in the scripts directory by their
+// slash-separated path relative to it
+func (c *Catalog) scripts() (map[string]scripting.ScriptType, error) {
+	scripts := make(map[string]scripting.ScriptType)
+	if c.scriptsDir == "" {
+		return scripts, nil
+	}
+
+	err := filepath.WalkDir(c.scriptsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		scriptType, ok := scriptExtensions[strings.ToLower(filepath.Ext(path))]
+		if !ok || (c.scriptEnabled != nil && !c.scriptEnabled(scriptType)) {
+			return nil
+		}
+		rel, err := filepath.Rel(c.scriptsDir, path)
+		if err != nil {
+			return err
+		}
+		scripts[filepath.ToSlash(rel)] = scriptType
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return scripts, fmt.Errorf("failed to read scripts directory: %w", err)
+	}
+	return scripts, nil
+}
+
+// commandID joins a kind and name into a command ID
+func commandID(kind Kind, name string) string {
+	return string(kind) + ":" + name
+}
+
+// Scope returns the API key scope needed to run a command: obs:write for
+// OBS actions and macros, scripts:run for modules and scripts
+func Scope(id string) string {
+	kind, _, _ := strings.Cut(id, ":")
+	switch Kind(kind) {
+	case KindOBS, KindMacro:
+		return apikeys.ScopeOBSWrite
+	default:
+		return apikeys.ScopeScriptsRun
+	}
+}
+
+// findOBSCommand looks up a supported OBS action
+func findOBSCommand(name string) (Command, bool) {
+	for _, command := range obsCommands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return Command{}, false
+}
+
+// checkRequired reports the first required parameter that is missing
+func checkRequired(schema map[string]Parameter, parameters map[string]string) error {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if schema[name].Required && parameters[name] == "" {
+			return fmt.Errorf("%w: %s", ErrMissingParameter, name)
+		}
+	}
+	return nil
+}
+
+// moduleParameters converts a module action's parameter description into
+// parameter schemas. Modules describe a parameter either by its type name
+// or by a map with "type", "description" and "required" keys.
+func moduleParameters(params map[string]interface{}) map[string]Parameter {
+	schema := make(map[string]Parameter, len(params))
+	for name, value := range params {
+		switch v := value.(type) {
+		case string:
+			schema[name] = Parameter{Type: v}
+		case map[string]interface{}:
+			param := Parameter{Type: "string"}
+			if t, ok := v["type"].(string); ok {
+				param.Type = t
+			}
+			if d, ok := v["description"].(string); ok {
+				param.Description = d
+			}
+			if r, ok := v["required"].(bool); ok {
+				param.Required = r
+			}
+			schema[name] = param
+		default:
+			schema[name] = Parameter{Type: "string"}
+		}
+	}
+	return schema
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/poller"
+	"waddlebot-bridge/internal/scripting"
+)
+
+type recordingExecutor struct {
+	requests []poller.ActionRequest
+	err      error
+}
+
+func (e *recordingExecutor) Execute(ctx context.Context, task poller.ActionRequest) (map[string]interface{}, error) {
+	e.requests = append(e.requests, task)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return map[string]interface{}{"action": task.Action}, nil
+}
+
+type stubModules struct{}
+
+func (stubModules) ExecuteAction(ctx context.Context, moduleName, action string, parameters map[string]string) (map[string]interface{}, error) {
+	return map[string]interface{}{"module": moduleName, "action": action}, nil
+}
+
+func (stubModules) GetModuleInfos() []models.ModuleInfo {
+	return []models.ModuleInfo{
+		{Name: "soundboard", Enabled: true, Actions: []models.ActionInfo{
+			{Name: "play", Parameters: map[string]interface{}{
+				"sound": map[string]interface{}{"type": "string", "required": true},
+			}},
+		}},
+		{Name: "disabled", Actions: []models.ActionInfo{{Name: "run"}}},
+	}
+}
+
+func newTestCatalog(t *testing.T) (*Catalog, *recordingExecutor, *recordingExecutor) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "intro.lua"), []byte("print('hi')"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a script"), 0644)
+	os.Mkdir(filepath.Join(dir, "raid"), 0755)
+	os.WriteFile(filepath.Join(dir, "raid", "shoutout.py"), []byte("print('raid')"), 0644)
+
+	scripts := &recordingExecutor{}
+	obsExec := &recordingExecutor{}
+
+	catalog := NewCatalog(logrus.New())
+	catalog.SetModules(stubModules{})
+	catalog.scriptExec = scripts
+	catalog.scriptsDir = dir
+	catalog.scriptEnabled = func(t scripting.ScriptType) bool { return t == scripting.ScriptTypeLua }
+	catalog.obsExec = obsExec
+	catalog.macros = []config.OBSMacroConfig{{
+		Name: "go_live",
+		Steps: []config.OBSMacroStep{
+			{Action: "set_scene", Parameters: map[string]string{"scene": "Starting"}},
+			{Action: "start_stream"},
+		},
+	}}
+	return catalog, scripts, obsExec
+}
+
+func TestCatalog_List(t *testing.T) {
+	catalog, _, _ := newTestCatalog(t)
+
+	commands := make(map[string]Command)
+	for _, command := range catalog.List() {
+		commands[command.ID] = command
+	}
+
+	play, ok := commands["module:soundboard/play"]
+	if !ok {
+		t.Fatal("Expected soundboard play command")
+	}
+	if !play.Parameters["sound"].Required {
+		t.Error("Expected sound parameter to be required")
+	}
+	if _, ok := commands["module:disabled/run"]; ok {
+		t.Error("Expected actions of disabled modules to be hidden")
+	}
+	if _, ok := commands["script:intro.lua"]; !ok {
+		t.Error("Expected lua script command")
+	}
+	if _, ok := commands["script:raid/shoutout.py"]; ok {
+		t.Error("Expected scripts of disabled types to be hidden")
+	}
+	if _, ok := commands["obs:toggle_stream"]; !ok {
+		t.Error("Expected OBS command")
+	}
+	if _, ok := commands["macro:go_live"]; !ok {
+		t.Error("Expected OBS macro command")
+	}
+}
+
+func TestCatalog_Execute(t *testing.T) {
+	catalog, scripts, obsExec := newTestCatalog(t)
+	ctx := context.Background()
+
+	result, err := catalog.Execute(ctx, "module:soundboard/play", map[string]string{"sound": "airhorn"})
+	if err != nil || result["module"] != "soundboard" {
+		t.Errorf("Unexpected module result %v: %v", result, err)
+	}
+
+	if _, err := catalog.Execute(ctx, "script:intro.lua", map[string]string{"NAME": "viewer"}); err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	if len(scripts.requests) != 1 || scripts.requests[0].Parameters["source"] != "print('hi')" || scripts.requests[0].Action != "lua" {
+		t.Errorf("Unexpected script request %+v", scripts.requests)
+	}
+
+	if _, err := catalog.Execute(ctx, "script:../secret.lua", nil); !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("Expected unknown script to be rejected, got %v", err)
+	}
+
+	if _, err := catalog.Execute(ctx, "obs:set_scene", nil); !errors.Is(err, ErrMissingParameter) {
+		t.Errorf("Expected missing scene parameter, got %v", err)
+	}
+
+	if _, err := catalog.Execute(ctx, "macro:go_live", nil); err != nil {
+		t.Fatalf("Macro failed: %v", err)
+	}
+	if len(obsExec.requests) != 2 || obsExec.requests[1].Action != "start_stream" {
+		t.Errorf("Expected macro steps to run in order, got %+v", obsExec.requests)
+	}
+
+	if _, err := catalog.Execute(ctx, "unknown", nil); !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
+
+func TestCatalog_MacroStopsOnFailure(t *testing.T) {
+	catalog, _, obsExec := newTestCatalog(t)
+	obsExec.err = errors.New("obs offline")
+
+	if _, err := catalog.Execute(context.Background(), "macro:go_live", nil); !errors.Is(err, obsExec.err) {
+		t.Errorf("Expected step error, got %v", err)
+	}
+	if len(obsExec.requests) != 1 {
+		t.Errorf("Expected macro to stop after the failed step, ran %d", len(obsExec.requests))
+	}
+}
@@ -0,0 +1,16 @@
+package commands
+
+import "errors"
+
+var (
+	// ErrCommandNotFound is returned when no command has the requested ID
+	ErrCommandNotFound = errors.New("command not found")
+
+	// ErrMissingParameter is returned when a required command parameter is
+	// not supplied
+	ErrMissingParameter = errors.New("missing required parameter")
+
+	// ErrUnavailable is returned when the component that runs a command is
+	// not enabled
+	ErrUnavailable = errors.New("command not available")
+)
@@ -16,27 +16,128 @@ type Config struct {
 	APIURL      string `mapstructure:"api-url"`
 	CommunityID string `mapstructure:"community-id"`
 	UserID      string `mapstructure:"user-id"`
+	APIToken    string `mapstructure:"api-token"` // static API token used instead of session tokens
+
+	// Task Routing
+	TaskTypes []string `mapstructure:"task-types"` // task types accepted, all when empty
+
+	// Additional Communities
+	Communities []CommunityConfig `mapstructure:"communities"`
+
+	// API TLS Configuration
+	APITLS APITLSConfig `mapstructure:"api-tls"`
+
+	// API HTTP Client Configuration
+	HTTP HTTPConfig `mapstructure:"http"`
+
+	// Upload Encoding Configuration
+	Upload UploadConfig `mapstructure:"upload"`
+
+	// Request Signing Configuration
+	Signing SigningConfig `mapstructure:"signing"`
 
 	// Polling Configuration
 	PollInterval int `mapstructure:"poll-interval"` // in seconds
 
+	// Heartbeat Configuration
+	Heartbeat HeartbeatConfig `mapstructure:"heartbeat"`
+
+	// Transport Configuration
+	Transport TransportConfig `mapstructure:"transport"`
+
+	// Outbox Configuration
+	Outbox OutboxConfig `mapstructure:"outbox"`
+
+	// Policy Configuration
+	Policy PolicyConfig `mapstructure:"policy"`
+
+	// Event Bus Configuration
+	Events EventsConfig `mapstructure:"events"`
+
+	// MQTT Configuration
+	MQTT MQTTConfig `mapstructure:"mqtt"`
+
+	// OSC Configuration
+	OSC OSCConfig `mapstructure:"osc"`
+
 	// Web Server Configuration
-	WebPort int    `mapstructure:"web-port"`
-	WebHost string `mapstructure:"web-host"`
+	WebPort int             `mapstructure:"web-port"`
+	WebHost string          `mapstructure:"web-host"`
+	WebTLS  ServerTLSConfig `mapstructure:"web-tls"`
+
+	// Tray Configuration
+	Tray TrayConfig `mapstructure:"tray"`
+
+	// Update Configuration
+	Update UpdateConfig `mapstructure:"update"`
+
+	// License Configuration
+	License LicenseConfig `mapstructure:"license"`
+
+	// Shutdown Configuration
+	Shutdown ShutdownConfig `mapstructure:"shutdown"`
+
+	// Power Configuration
+	Power PowerConfig `mapstructure:"power"`
+
+	// Stream Guardian Configuration
+	Guardian GuardianConfig `mapstructure:"guardian"`
+
+	// Scene Switching Rules Configuration
+	Rules RulesConfig `mapstructure:"rules"`
+
+	// Clip Upload Configuration
+	Clips ClipsConfig `mapstructure:"clips"`
+
+	// Text Source Configuration
+	TextSources TextSourcesConfig `mapstructure:"text-sources"`
+
+	// Timer Configuration
+	Timers TimersConfig `mapstructure:"timers"`
+
+	// Source Visibility Schedule Configuration
+	Schedules SchedulesConfig `mapstructure:"schedules"`
+
+	// Trigger Configuration
+	Triggers TriggersConfig `mapstructure:"triggers"`
+
+	// Community Calls Configuration
+	RTC RTCConfig `mapstructure:"rtc"`
 
 	// Storage Configuration
-	DataDir string `mapstructure:"data-dir"`
+	DataDir              string        `mapstructure:"data-dir"`
+	StorageBackend       string        `mapstructure:"storage-backend"`          // bolt or sqlite
+	StorageSweepInterval time.Duration `mapstructure:"storage-sweep-interval"`   // how often expired keys are purged
+	StorageCompactRatio  float64       `mapstructure:"storage-compact-ratio"`    // free share of the file that triggers compaction, 0 to disable
+	StorageMinFreeDiskMB int           `mapstructure:"storage-min-free-disk-mb"` // warn when less disk space is left in the data directory
+	Backup               BackupConfig  `mapstructure:"backup"`
 
 	// Logging Configuration
-	LogLevel string `mapstructure:"log-level"`
+	LogLevel    string            `mapstructure:"log-level"`
+	LogShipping LogShippingConfig `mapstructure:"log-shipping"`
+	Tracing     TracingConfig     `mapstructure:"tracing"`
+
+	// Reload Configuration
+	WatchConfig bool `mapstructure:"watch-config"` // reload when the config file is saved
+
+	// Profile Configuration
+	Profile  string                   `mapstructure:"profile"`  // active profile, empty for none
+	Profiles map[string]ProfileConfig `mapstructure:"profiles"` // named overrides of obs, scripting and gateway
 
 	// WebAuthn Configuration
-	WebAuthnDisplayName string `mapstructure:"webauthn-display-name"`
-	WebAuthnOrigin      string `mapstructure:"webauthn-origin"`
-	WebAuthnTimeout     int    `mapstructure:"webauthn-timeout"`
+	WebAuthnDisplayName string   `mapstructure:"webauthn-display-name"`
+	WebAuthnRPID        string   `mapstructure:"webauthn-rp-id"`
+	WebAuthnOrigin      string   `mapstructure:"webauthn-origin"`
+	WebAuthnOrigins     []string `mapstructure:"webauthn-origins"` // additional allowed origins
+	WebAuthnTimeout     int      `mapstructure:"webauthn-timeout"`
 
 	// Security Configuration
-	JWTSecret string `mapstructure:"jwt-secret"`
+	AuthMethod string        `mapstructure:"auth-method"` // webauthn or device
+	JWTSecret  string        `mapstructure:"jwt-secret"`
+	Sessions   SessionConfig `mapstructure:"sessions"`
+
+	// Account Linking Configuration
+	OAuth OAuthConfig `mapstructure:"oauth"`
 
 	// Module Configuration
 	ModulesDir         string `mapstructure:"modules-dir"`
@@ -51,31 +152,474 @@ type Config struct {
 
 	// Scripting Configuration
 	Scripting ScriptingConfig `mapstructure:"scripting"`
+
+	// Built-in Module Configuration
+	Builtin BuiltinModulesConfig `mapstructure:"builtin-modules"`
 }
 
-// OBSConfig holds OBS WebSocket connection configuration
-type OBSConfig struct {
-	Enabled              bool          `mapstructure:"enabled"`
-	Host                 string        `mapstructure:"host"`
-	Port                 int           `mapstructure:"port"`
-	Password             string        `mapstructure:"password"`
-	AutoReconnect        bool          `mapstructure:"auto-reconnect"`
+// CommunityConfig is an additional community served by the bridge. Each
+// community registers, heartbeats and polls for tasks separately with its
+// own credentials; empty fields fall back to the top-level settings.
+type CommunityConfig struct {
+	ID        string       `mapstructure:"id"`
+	UserID    string       `mapstructure:"user-id"`
+	APIToken  string       `mapstructure:"api-token"`
+	JWTSecret string       `mapstructure:"jwt-secret"`
+	TaskTypes []string     `mapstructure:"task-types"`
+	Policy    *PolicyRules `mapstructure:"policy"` // replaces policy.communities entry
+}
+
+// SessionConfig holds the lifetimes of WebAuthn sign-ins. Access tokens
+// are short-lived and renewed with a refresh token; each refresh extends
+// the session by RefreshTokenLifetime, up to MaxLifetime after sign in.
+// Without a jwt-secret, tokens are signed with a generated key replaced
+// every SigningKeyRotation; the replaced key keeps validating tokens for
+// SigningKeyGrace.
+type SessionConfig struct {
+	AccessTokenLifetime  time.Duration `mapstructure:"access-token-lifetime"`
+	RefreshTokenLifetime time.Duration `mapstructure:"refresh-token-lifetime"`
+	MaxLifetime          time.Duration `mapstructure:"max-lifetime"`
+	SigningKeyRotation   time.Duration `mapstructure:"signing-key-rotation"` // 0 disables
+	SigningKeyGrace      time.Duration `mapstructure:"signing-key-grace"`
+}
+
+// OAuthConfig identifies the bridge to the WaddleBot API's OAuth2 device
+// authorization flow, used by the login command to link the bridge to an
+// account
+type OAuthConfig struct {
+	ClientID string `mapstructure:"client-id"`
+	Scope    string `mapstructure:"scope"`
+}
+
+// APITLSConfig holds TLS settings for connections to the WaddleBot API.
+// The client certificate for mutual TLS comes from files, inline PEM or the
+// OS keystore, in that order of preference. Pins are checked against every
+// certificate in the server's chain.
+type APITLSConfig struct {
+	CAFile             string   `mapstructure:"ca-file"`
+	ClientCertFile     string   `mapstructure:"client-cert-file"`
+	ClientKeyFile      string   `mapstructure:"client-key-file"`
+	ClientCertPEM      string   `mapstructure:"client-cert-pem"`
+	ClientKeyPEM       string   `mapstructure:"client-key-pem"`
+	ClientCertKeystore string   `mapstructure:"client-cert-keystore"` // certificate common name
+	PinnedSPKI         []string `mapstructure:"pinned-spki-sha256"`   // base64 SHA-256 of the public key
+	PinnedCerts        []string `mapstructure:"pinned-cert-sha256"`   // hex SHA-256 of the certificate
+}
+
+// HTTPConfig holds settings for the HTTP client used to call the API.
+// Idempotent requests are retried with exponential backoff, and after
+// BreakerThreshold consecutive failures calls fail fast for BreakerCooldown.
+type HTTPConfig struct {
+	Proxy               string        `mapstructure:"proxy"` // overrides HTTP(S)_PROXY when set
+	Timeout             time.Duration `mapstructure:"timeout"`
+	MaxIdleConns        int           `mapstructure:"max-idle-conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max-idle-conns-per-host"`
+	MaxConnsPerHost     int           `mapstructure:"max-conns-per-host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle-conn-timeout"`
+	RetryMax            int           `mapstructure:"retry-max"`
+	RetryWait           time.Duration `mapstructure:"retry-wait"`
+	RetryMaxWait        time.Duration `mapstructure:"retry-max-wait"`
+	BreakerThreshold    int           `mapstructure:"breaker-threshold"` // 0 disables the breaker
+	BreakerCooldown     time.Duration `mapstructure:"breaker-cooldown"`
+}
+
+// UploadConfig controls how payloads sent to the API are encoded. Each
+// feature is offered at registration and only used once the server accepts
+// it; the server may also lower MaxPayloadBytes.
+type UploadConfig struct {
+	Compression      bool `mapstructure:"compression"`
+	CompressMinBytes int  `mapstructure:"compress-min-bytes"`
+	Batching         bool `mapstructure:"batching"`
+	BatchSize        int  `mapstructure:"batch-size"`
+	Chunking         bool `mapstructure:"chunking"`
+	MaxPayloadBytes  int  `mapstructure:"max-payload-bytes"`
+}
+
+// SigningConfig holds settings for signing requests to the WaddleBot API.
+// With ed25519 the key pair is generated on first use and the public key is
+// sent at registration; with hmac-sha256 the secret is shared with the API.
+type SigningConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Algorithm string `mapstructure:"algorithm"` // ed25519 or hmac-sha256
+	KeyFile   string `mapstructure:"key-file"`  // ed25519 private key, defaults to <data-dir>/signing.key
+	Secret    string `mapstructure:"secret"`    // hmac-sha256 shared secret
+	KeyID     string `mapstructure:"key-id"`
+}
+
+// HeartbeatConfig holds heartbeat timing. The API can change the interval
+// at registration or in heartbeat responses, within the min/max bounds.
+type HeartbeatConfig struct {
+	Interval    time.Duration `mapstructure:"interval"`
+	MinInterval time.Duration `mapstructure:"min-interval"`
+	MaxInterval time.Duration `mapstructure:"max-interval"`
+}
+
+// TransportConfig holds configuration for receiving tasks from the API
+type TransportConfig struct {
+	// Mode is "websocket" to receive tasks over a persistent connection,
+	// falling back to polling while it is unavailable, or "polling"
+	Mode                 string        `mapstructure:"mode"`
 	ReconnectInterval    time.Duration `mapstructure:"reconnect-interval"`
 	MaxReconnectInterval time.Duration `mapstructure:"max-reconnect-interval"`
-	Timeout              time.Duration `mapstructure:"timeout"`
+	PingInterval         time.Duration `mapstructure:"ping-interval"`
+}
+
+// OutboxConfig holds configuration for the durable queue of results,
+// heartbeats and events waiting to be delivered to the API
+type OutboxConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	MaxItems      int           `mapstructure:"max-items"`
+	MaxAge        time.Duration `mapstructure:"max-age"`
+	RetryInterval time.Duration `mapstructure:"retry-interval"`
+}
+
+// BackupConfig holds configuration for scheduled storage backups
+type BackupConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	Retain   int           `mapstructure:"retain"` // number of backups kept
+	Dir      string        `mapstructure:"dir"`    // defaults to <data-dir>/backups
+	Compress bool          `mapstructure:"compress"`
+}
+
+// TrayConfig configures the system tray icon shown while the bridge runs
+// in a desktop session
+type TrayConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// LogShippingConfig configures forwarding of the bridge's warnings and
+// errors to the WaddleBot API for premium support
+type LogShippingConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Level         string        `mapstructure:"level"`          // lowest level forwarded, warn or error
+	BatchSize     int           `mapstructure:"batch-size"`     // entries sent per request
+	FlushInterval time.Duration `mapstructure:"flush-interval"` // how long entries wait for a full batch
+	BufferSize    int           `mapstructure:"buffer-size"`    // entries kept while the API is unreachable
+}
+
+// TracingConfig configures OpenTelemetry tracing of tasks, exported to an
+// OTLP collector
+type TracingConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	Endpoint    string            `mapstructure:"endpoint"`     // collector host:port or URL
+	Protocol    string            `mapstructure:"protocol"`     // grpc or http
+	Insecure    bool              `mapstructure:"insecure"`     // export without TLS
+	Headers     map[string]string `mapstructure:"headers"`      // sent with every export, such as authorization
+	SampleRatio float64           `mapstructure:"sample-ratio"` // share of tasks traced unless the server already sampled them
+}
+
+// UpdateConfig configures updates of the bridge from WaddleBot's signed
+// releases
+type UpdateConfig struct {
+	Channel       string        `mapstructure:"channel"`        // stable or beta
+	AutoCheck     bool          `mapstructure:"auto-check"`     // check for updates while running
+	AutoInstall   bool          `mapstructure:"auto-install"`   // install updates found by auto-check
+	CheckInterval time.Duration `mapstructure:"check-interval"` // how often auto-check runs
+	PublicKey     string        `mapstructure:"public-key"`     // base64 ed25519 release key, defaults to the one built in
+}
+
+// LicenseConfig configures verification of the WaddleBot Premium
+// subscription the bridge runs under
+type LicenseConfig struct {
+	CheckInterval time.Duration `mapstructure:"check-interval"` // how often the subscription is verified online
+	PublicKey     string        `mapstructure:"public-key"`     // base64 ed25519 entitlement key, for builds without one
+}
+
+// ShutdownConfig bounds how long the bridge takes to stop
+type ShutdownConfig struct {
+	DrainTimeout time.Duration `mapstructure:"drain-timeout"` // wait for tasks in flight, 0 to cancel them right away
+	FlushTimeout time.Duration `mapstructure:"flush-timeout"` // deliver results and queued items
+}
+
+// PowerConfig configures how the bridge follows the computer's sleep and
+// presentation state
+type PowerConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	CheckInterval       time.Duration `mapstructure:"check-interval"`        // how often presentation mode and clock jumps are checked
+	PauseInPresentation bool          `mapstructure:"pause-in-presentation"` // hold tasks while presenting or in do not disturb
+}
+
+// GuardianConfig configures the stream health guardian, which watches the
+// stream's dropped frames, encoder lag and bitrate and acts when they stay
+// out of bounds
+type GuardianConfig struct {
+	Enabled            bool             `mapstructure:"enabled"`
+	Interval           time.Duration    `mapstructure:"interval"`              // how often OBS's stats are sampled
+	MaxDroppedRatio    float64          `mapstructure:"max-dropped-ratio"`     // share of frames dropped by the network, 0 to ignore
+	MaxEncoderLagRatio float64          `mapstructure:"max-encoder-lag-ratio"` // share of frames skipped by the encoder, 0 to ignore
+	MinBitrateKbps     int              `mapstructure:"min-bitrate-kbps"`      // 0 to ignore
+	TriggerSamples     int              `mapstructure:"trigger-samples"`       // bad samples in a row before the stream is unhealthy
+	RecoverSamples     int              `mapstructure:"recover-samples"`       // good samples in a row before it is healthy again
+	Cooldown           time.Duration    `mapstructure:"cooldown"`              // least time between two runs of an action
+	Actions            []GuardianAction `mapstructure:"actions"`
+}
+
+// GuardianAction is a remediation run while the stream is unhealthy
+type GuardianAction struct {
+	Type    string        `mapstructure:"type"`    // scene, warn or restart-stream
+	Scene   string        `mapstructure:"scene"`   // scene to switch to, for scene
+	Message string        `mapstructure:"message"` // chat message, for warn; {reason} is replaced
+	After   time.Duration `mapstructure:"after"`   // how long the stream must have been unhealthy first
+}
+
+// RulesConfig configures the scene switching rules engine. The rules
+// themselves are managed through the gateway.
+type RulesConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // how often the rules' conditions are checked
+}
+
+// TextSourcesConfig configures updating OBS text inputs from templates.
+// The sources and their variables are managed through the gateway.
+type TextSourcesConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // how often templates showing the time are refreshed
+}
+
+// TimersConfig configures the countdowns and stopwatches. The timers
+// themselves are managed through the gateway and scripts.
+type TimersConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// SchedulesConfig configures showing and hiding sources on a schedule.
+// The schedules themselves are managed through the gateway.
+type SchedulesConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // how often the schedules are checked
+}
+
+// TriggersConfig configures the trigger engine. The triggers themselves
+// are managed through the gateway.
+type TriggersConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // how often system metrics are sampled and triggers without an event checked
+}
+
+// RTCConfig configures reaching the community's calls through the RTC
+// module, and the composite view added to OBS as a browser source
+type RTCConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	URL        string `mapstructure:"url"`         // RTC module API, api-url when empty
+	LiveKitURL string `mapstructure:"livekit-url"` // LiveKit server the composite view connects to
+	ViewURL    string `mapstructure:"view-url"`    // composite view page, given url, token and layout
+	Layout     string `mapstructure:"layout"`      // default layout of the composite view
+}
+
+// ClipsConfig configures uploading replay buffer clips and recordings to
+// the WaddleBot API. Clips saved by a clip task are always uploaded; others
+// only when they are enabled here.
+type ClipsConfig struct {
+	Enabled          bool         `mapstructure:"enabled"`
+	UploadReplays    bool         `mapstructure:"upload-replays"`    // upload every replay saved in OBS
+	UploadRecordings bool         `mapstructure:"upload-recordings"` // upload recordings when they stop
+	MaxSizeMB        int          `mapstructure:"max-size-mb"`       // larger files are not uploaded
+	ChunkSizeMB      int          `mapstructure:"chunk-size-mb"`
+	FFmpeg           FFmpegConfig `mapstructure:"ffmpeg"`
+}
+
+// FFmpegConfig configures transcoding and trimming clips with ffmpeg
+// before they are uploaded
+type FFmpegConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`
+	Path        string   `mapstructure:"path"`
+	TrimSeconds int      `mapstructure:"trim-seconds"` // keep only the last seconds, 0 to keep all
+	Format      string   `mapstructure:"format"`       // container of the output, such as mp4; the input's when empty
+	Args        []string `mapstructure:"args"`         // output options; the streams are copied when empty
+}
+
+// EventsConfig holds configuration for the event bus that carries events
+// from modules, OBS and scripts to the API, local webhooks and WebSocket
+// clients. Each sink only receives events matching its filter.
+type EventsConfig struct {
+	Enabled    bool                  `mapstructure:"enabled"`
+	BufferSize int                   `mapstructure:"buffer-size"` // per-sink queue length
+	API        EventSinkConfig       `mapstructure:"api"`
+	WebSocket  EventSinkConfig       `mapstructure:"websocket"`
+	Webhooks   []WebhookSinkConfig   `mapstructure:"webhooks"`
+	Delivery   WebhookDeliveryConfig `mapstructure:"webhook-delivery"`
+}
+
+// WebhookDeliveryConfig controls how webhooks are delivered. Failed
+// deliveries are retried with exponential backoff, and the outcome of each
+// delivery is kept in a bounded history.
+type WebhookDeliveryConfig struct {
+	MaxAttempts    int           `mapstructure:"max-attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial-backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max-backoff"`
+	Timeout        time.Duration `mapstructure:"timeout"` // per attempt
+	HistorySize    int           `mapstructure:"history-size"`
+}
+
+// EventSinkConfig enables a built-in event sink and filters its events
+type EventSinkConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	EventFilter `mapstructure:",squash"`
+}
+
+// WebhookSinkConfig is a webhook that receives events from the bus
+type WebhookSinkConfig struct {
+	Name        string `mapstructure:"name"`
+	URL         string `mapstructure:"url"`
+	Secret      string `mapstructure:"secret"` // signs deliveries with X-Signature
+	EventFilter `mapstructure:",squash"`
+}
+
+// EventFilter selects events by glob patterns on their type and source.
+// Empty lists match everything; exclusions win.
+type EventFilter struct {
+	Types   []string `mapstructure:"types" json:"types,omitempty"`
+	Sources []string `mapstructure:"sources" json:"sources,omitempty"`
+	Exclude []string `mapstructure:"exclude" json:"exclude,omitempty"`
+}
+
+// MQTTConfig holds configuration for the MQTT bridge, which publishes bus
+// events under TopicPrefix and runs commands received on command topics.
+// Commands lists glob patterns of the command IDs that may be run over MQTT;
+// when it is empty no command topics are subscribed.
+type MQTTConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Broker         string        `mapstructure:"broker"` // such as tcp://localhost:1883 or ssl://broker:8883
+	ClientID       string        `mapstructure:"client-id"`
+	Username       string        `mapstructure:"username"`
+	Password       string        `mapstructure:"password"`
+	TopicPrefix    string        `mapstructure:"topic-prefix"`
+	QoS            int           `mapstructure:"qos"`
+	Retain         bool          `mapstructure:"retain"` // retain published events
+	ConnectTimeout time.Duration `mapstructure:"connect-timeout"`
+	Commands       []string      `mapstructure:"commands"`
+	EventFilter    `mapstructure:",squash"`
+}
+
+// OSCConfig holds configuration for Open Sound Control over UDP. Messages
+// received on Listen run the commands of matching mappings, and events on
+// the bus are sent to consoles as configured by the outputs.
+type OSCConfig struct {
+	Enabled  bool               `mapstructure:"enabled"`
+	Listen   string             `mapstructure:"listen"` // UDP address, empty to only send
+	Mappings []OSCMappingConfig `mapstructure:"mappings"`
+	Outputs  []OSCOutputConfig  `mapstructure:"outputs"`
+}
+
+// OSCMappingConfig runs a command palette command for messages whose
+// address matches Address, a glob pattern. Parameter values may contain
+// {0}, {1}... for the message arguments and {address} for its address.
+type OSCMappingConfig struct {
+	Address    string            `mapstructure:"address"`
+	Command    string            `mapstructure:"command"`
+	Parameters map[string]string `mapstructure:"parameters"`
+	IgnoreZero bool              `mapstructure:"ignore-zero"` // skip messages whose first argument is 0 or false, such as button releases
+}
+
+// OSCOutputConfig sends an OSC message to Target for every event whose type
+// matches Event, a glob pattern. Arguments may contain {field} for fields of
+// the event data; numbers and true/false are sent as such.
+type OSCOutputConfig struct {
+	Event   string   `mapstructure:"event"`
+	Target  string   `mapstructure:"target"` // host:port
+	Address string   `mapstructure:"address"`
+	Args    []string `mapstructure:"args"`
+}
+
+// PolicyConfig holds the local policy that remote tasks are checked against
+// before they are executed. Rules can be given inline or in a separate file,
+// which replaces the inline rules when set.
+type PolicyConfig struct {
+	Enabled         bool                   `mapstructure:"enabled" json:"enabled"`
+	File            string                 `mapstructure:"file" json:"file,omitempty"`
+	MaxAuditEntries int                    `mapstructure:"max-audit-entries" json:"max_audit_entries"`
+	Rules           PolicyRules            `mapstructure:",squash" json:"rules"`
+	Communities     map[string]PolicyRules `mapstructure:"communities" json:"communities,omitempty"`
+}
+
+// PolicyRules is a set of allow/deny rules. Default ("allow" or "deny")
+// applies to tasks not matched by any allow list.
+type PolicyRules struct {
+	Default string     `mapstructure:"default" json:"default"`
+	Modules PolicyList `mapstructure:"modules" json:"modules"`
+	Actions PolicyList `mapstructure:"actions" json:"actions"`
+	OBS     PolicyList `mapstructure:"obs" json:"obs"`
+	Scripts PolicyList `mapstructure:"scripts" json:"scripts"`
+}
+
+// PolicyList holds glob patterns for allowed and denied entries; deny
+// patterns take precedence
+type PolicyList struct {
+	Allow []string `mapstructure:"allow" json:"allow,omitempty"`
+	Deny  []string `mapstructure:"deny" json:"deny,omitempty"`
+}
+
+// OBSConfig holds OBS WebSocket connection configuration
+type OBSConfig struct {
+	Enabled              bool             `mapstructure:"enabled"`
+	Host                 string           `mapstructure:"host"`
+	Port                 int              `mapstructure:"port"`
+	Password             string           `mapstructure:"password"`
+	AutoReconnect        bool             `mapstructure:"auto-reconnect"`
+	ReconnectInterval    time.Duration    `mapstructure:"reconnect-interval"`
+	MaxReconnectInterval time.Duration    `mapstructure:"max-reconnect-interval"`
+	Timeout              time.Duration    `mapstructure:"timeout"`
+	Macros               []OBSMacroConfig `mapstructure:"macros"`
+	AudioMeters          AudioMeterConfig `mapstructure:"audio-meters"`
+	HistorySize          int              `mapstructure:"history-size"`      // connection history entries kept
+	HistoryRetention     time.Duration    `mapstructure:"history-retention"` // how long they are kept
+}
+
+// AudioMeterConfig controls the audio levels read from OBS's volume meters
+type AudioMeterConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Interval         time.Duration `mapstructure:"interval"`          // how often levels are reported
+	SilenceThreshold float64       `mapstructure:"silence-threshold"` // in dB
+	SilenceDelay     time.Duration `mapstructure:"silence-delay"`     // quiet this long counts as silent
+}
+
+// OBSMacroConfig is a named sequence of OBS actions run as one command
+type OBSMacroConfig struct {
+	Name        string         `mapstructure:"name"`
+	Description string         `mapstructure:"description"`
+	Steps       []OBSMacroStep `mapstructure:"steps"`
+}
+
+// OBSMacroStep is a single OBS action of a macro
+type OBSMacroStep struct {
+	Action     string            `mapstructure:"action"`
+	Parameters map[string]string `mapstructure:"parameters"`
 }
 
 // GatewayConfig holds local API gateway configuration
 type GatewayConfig struct {
-	Enabled        bool     `mapstructure:"enabled"`
-	Host           string   `mapstructure:"host"`
-	Port           int      `mapstructure:"port"`
-	EnableAuth     bool     `mapstructure:"enable-auth"`
-	APIKey         string   `mapstructure:"api-key"`
-	RateLimitRPS   int      `mapstructure:"rate-limit-rps"`
-	EnableCORS     bool     `mapstructure:"enable-cors"`
-	AllowedOrigins []string `mapstructure:"allowed-origins"`
-	WSPingInterval int      `mapstructure:"ws-ping-interval"`
+	Enabled        bool            `mapstructure:"enabled"`
+	Host           string          `mapstructure:"host"`
+	Port           int             `mapstructure:"port"`
+	EnableAuth     bool            `mapstructure:"enable-auth"`
+	APIKey         string          `mapstructure:"api-key"`
+	RateLimitRPS   int             `mapstructure:"rate-limit-rps"`
+	EnableCORS     bool            `mapstructure:"enable-cors"`
+	AllowedOrigins []string        `mapstructure:"allowed-origins"`
+	WSPingInterval int             `mapstructure:"ws-ping-interval"`
+	EnableMetrics  bool            `mapstructure:"enable-metrics"` // serve Prometheus metrics at /metrics
+	MaxBodySize    int64           `mapstructure:"max-body-size"`  // request body limit in bytes
+	TLS            ServerTLSConfig `mapstructure:"tls"`
+	Overlays       OverlaysConfig  `mapstructure:"overlays"`
+}
+
+// OverlaysConfig configures the overlay files the gateway serves at
+// /overlays/ for OBS browser sources
+type OverlaysConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Dir         string        `mapstructure:"dir"`           // defaults to <data-dir>/overlays
+	CacheMaxAge time.Duration `mapstructure:"cache-max-age"` // for non-HTML files; HTML is always revalidated
+}
+
+// ServerTLSConfig enables TLS on a local HTTP server. Without a certificate
+// and key, a self-signed certificate is generated and kept in the data
+// directory.
+type ServerTLSConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	CertFile string   `mapstructure:"cert-file"`
+	KeyFile  string   `mapstructure:"key-file"`
+	Hosts    []string `mapstructure:"hosts"` // extra DNS names and IPs for the self-signed certificate
 }
 
 // ScriptingConfig holds scripting engine configuration
@@ -95,6 +639,73 @@ type ScriptingConfig struct {
 	BashPath         string `mapstructure:"bash-path"`
 }
 
+// BuiltinModulesConfig holds configuration for first-party modules compiled
+// into the bridge
+type BuiltinModulesConfig struct {
+	FileOps     FileOpsConfig       `mapstructure:"file-ops"`
+	HTTPRequest HTTPRequestConfig   `mapstructure:"http-request"`
+	Soundboard  SoundboardConfig    `mapstructure:"soundboard"`
+	Hotkeys     HotkeysConfig       `mapstructure:"hotkeys"`
+	Overlays    OverlayAlertsConfig `mapstructure:"overlays"`
+}
+
+// FileOpsConfig holds configuration for the file operations module
+type FileOpsConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	AllowedRoots   []string `mapstructure:"allowed-roots"`
+	AllowWrite     bool     `mapstructure:"allow-write"`
+	MaxFileSizeKB  int      `mapstructure:"max-file-size-kb"`
+	MaxListEntries int      `mapstructure:"max-list-entries"`
+}
+
+// HTTPRequestConfig holds configuration for the outbound HTTP request module
+type HTTPRequestConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	AllowedHosts   []string `mapstructure:"allowed-hosts"`
+	AllowedHeaders []string `mapstructure:"allowed-headers"`
+	MaxResponseKB  int      `mapstructure:"max-response-kb"`
+	MaxRequestKB   int      `mapstructure:"max-request-kb"`
+	Timeout        int      `mapstructure:"timeout"`
+}
+
+// SoundboardConfig holds configuration for the soundboard module
+type SoundboardConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	SoundsDir     string `mapstructure:"sounds-dir"`
+	DefaultVolume int    `mapstructure:"default-volume"`
+	MaxConcurrent int    `mapstructure:"max-concurrent"`
+	PlayerPath    string `mapstructure:"player-path"`
+}
+
+// OverlayAlertsConfig holds configuration for the overlay alerts module, which
+// queues alerts and shows them one at a time on overlay pages
+type OverlayAlertsConfig struct {
+	Enabled         bool                  `mapstructure:"enabled"`
+	DefaultDuration time.Duration         `mapstructure:"default-duration"`
+	MaxQueue        int                   `mapstructure:"max-queue"`
+	Templates       []AlertTemplateConfig `mapstructure:"templates"`
+}
+
+// AlertTemplateConfig defines an overlay alert. Text may contain
+// {placeholders} filled from the alert's variables; Image and Sound are
+// paths or URLs the overlay page loads.
+type AlertTemplateConfig struct {
+	Name     string        `mapstructure:"name"`
+	Text     string        `mapstructure:"text"`
+	Image    string        `mapstructure:"image"`
+	Sound    string        `mapstructure:"sound"`
+	Duration time.Duration `mapstructure:"duration"`
+}
+
+// HotkeysConfig holds configuration for the keyboard emulation module.
+// Both Enabled and PermissionGranted must be set for keystrokes to be sent.
+type HotkeysConfig struct {
+	Enabled           bool              `mapstructure:"enabled"`
+	PermissionGranted bool              `mapstructure:"permission-granted"`
+	Mappings          map[string]string `mapstructure:"mappings"`
+	CooldownMs        int               `mapstructure:"cooldown-ms"`
+}
+
 // Load loads the configuration from various sources
 func Load() (*Config, error) {
 	// Set defaults
@@ -108,6 +719,13 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Apply the active profile before filling in derived settings
+	if cfg.Profile != "" {
+		if err := applyProfile(cfg, cfg.Profile); err != nil {
+			return nil, err
+		}
+	}
+
 	// Set default data directory if not specified
 	if cfg.DataDir == "" {
 		homeDir, err := os.UserHomeDir()
@@ -127,6 +745,21 @@ func Load() (*Config, error) {
 		cfg.Scripting.ScriptsDir = filepath.Join(cfg.DataDir, "scripts")
 	}
 
+	// Set default sounds directory
+	if cfg.Builtin.Soundboard.SoundsDir == "" {
+		cfg.Builtin.Soundboard.SoundsDir = filepath.Join(cfg.DataDir, "sounds")
+	}
+
+	// Set default overlays directory
+	if cfg.Gateway.Overlays.Dir == "" {
+		cfg.Gateway.Overlays.Dir = filepath.Join(cfg.DataDir, "overlays")
+	}
+
+	// Set default backups directory
+	if cfg.Backup.Dir == "" {
+		cfg.Backup.Dir = filepath.Join(cfg.DataDir, "backups")
+	}
+
 	// Ensure data directory exists
 	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
@@ -144,6 +777,25 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Ensure overlays directory exists if overlays are served
+	if cfg.Gateway.Enabled && cfg.Gateway.Overlays.Enabled {
+		if err := os.MkdirAll(cfg.Gateway.Overlays.Dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create overlays directory: %w", err)
+		}
+	}
+
+	// Per-community policies given with the community override
+	// policy.communities
+	for _, community := range cfg.Communities {
+		if community.Policy == nil {
+			continue
+		}
+		if cfg.Policy.Communities == nil {
+			cfg.Policy.Communities = make(map[string]PolicyRules)
+		}
+		cfg.Policy.Communities[community.ID] = *community.Policy
+	}
+
 	// Set platform-specific defaults
 	setPlatformDefaults(cfg)
 
@@ -157,12 +809,200 @@ func setDefaults() {
 	viper.SetDefault("web-port", 8080)
 	viper.SetDefault("web-host", "127.0.0.1")
 	viper.SetDefault("log-level", "info")
+	viper.SetDefault("watch-config", true)
+	viper.SetDefault("storage-backend", "bolt")
+	viper.SetDefault("storage-sweep-interval", 10*time.Minute)
+	viper.SetDefault("storage-compact-ratio", 0.5)
+	viper.SetDefault("storage-min-free-disk-mb", 500)
 	viper.SetDefault("webauthn-display-name", "WaddleBot Bridge")
+	viper.SetDefault("webauthn-rp-id", "localhost")
 	viper.SetDefault("webauthn-origin", "http://127.0.0.1:8080")
+	viper.SetDefault("webauthn-origins", []string{})
+	viper.SetDefault("web-tls.enabled", false)
 	viper.SetDefault("webauthn-timeout", 60)
+	viper.SetDefault("auth-method", "webauthn")
 	viper.SetDefault("module-timeout", 30)
 	viper.SetDefault("max-concurrent-tasks", 10)
 
+	// Session defaults
+	viper.SetDefault("sessions.access-token-lifetime", 15*time.Minute)
+	viper.SetDefault("sessions.refresh-token-lifetime", 24*time.Hour)
+	viper.SetDefault("sessions.max-lifetime", 30*24*time.Hour)
+	viper.SetDefault("sessions.signing-key-rotation", 30*24*time.Hour)
+	viper.SetDefault("sessions.signing-key-grace", time.Hour)
+
+	// OAuth defaults
+	viper.SetDefault("oauth.client-id", "waddlebot-bridge")
+	viper.SetDefault("oauth.scope", "bridge communities:read")
+
+	// Heartbeat defaults
+	viper.SetDefault("heartbeat.interval", 30*time.Second)
+	viper.SetDefault("heartbeat.min-interval", 5*time.Second)
+	viper.SetDefault("heartbeat.max-interval", 5*time.Minute)
+
+	// Transport defaults
+	viper.SetDefault("transport.mode", "websocket")
+	viper.SetDefault("transport.reconnect-interval", time.Second)
+	viper.SetDefault("transport.max-reconnect-interval", time.Minute)
+	viper.SetDefault("transport.ping-interval", 30*time.Second)
+
+	// Backup defaults
+	viper.SetDefault("backup.enabled", true)
+	viper.SetDefault("backup.interval", 24*time.Hour)
+	viper.SetDefault("backup.retain", 7)
+	viper.SetDefault("backup.compress", true)
+
+	// Outbox defaults
+	viper.SetDefault("outbox.enabled", true)
+	viper.SetDefault("outbox.max-items", 1000)
+	viper.SetDefault("outbox.max-age", 24*time.Hour)
+	viper.SetDefault("outbox.retry-interval", 30*time.Second)
+
+	// API HTTP client defaults
+	viper.SetDefault("http.proxy", "")
+	viper.SetDefault("http.timeout", 30*time.Second)
+	viper.SetDefault("http.max-idle-conns", 100)
+	viper.SetDefault("http.max-idle-conns-per-host", 10)
+	viper.SetDefault("http.max-conns-per-host", 0)
+	viper.SetDefault("http.idle-conn-timeout", 90*time.Second)
+	viper.SetDefault("http.retry-max", 3)
+	viper.SetDefault("http.retry-wait", 500*time.Millisecond)
+	viper.SetDefault("http.retry-max-wait", 10*time.Second)
+	viper.SetDefault("http.breaker-threshold", 5)
+	viper.SetDefault("http.breaker-cooldown", 30*time.Second)
+
+	// Upload encoding defaults
+	viper.SetDefault("upload.compression", true)
+	viper.SetDefault("upload.compress-min-bytes", 1024)
+	viper.SetDefault("upload.batching", true)
+	viper.SetDefault("upload.batch-size", 100)
+	viper.SetDefault("upload.chunking", true)
+	viper.SetDefault("upload.max-payload-bytes", 1<<20)
+
+	// Signing defaults
+	viper.SetDefault("signing.enabled", true)
+	viper.SetDefault("signing.algorithm", "ed25519")
+	viper.SetDefault("signing.key-file", "")
+	viper.SetDefault("signing.secret", "")
+	viper.SetDefault("signing.key-id", "")
+
+	// Event bus defaults
+	viper.SetDefault("events.enabled", true)
+	viper.SetDefault("events.buffer-size", 256)
+	viper.SetDefault("events.api.enabled", true)
+	viper.SetDefault("events.websocket.enabled", true)
+	viper.SetDefault("events.webhook-delivery.max-attempts", 5)
+	viper.SetDefault("events.webhook-delivery.initial-backoff", time.Second)
+	viper.SetDefault("events.webhook-delivery.max-backoff", time.Minute)
+	viper.SetDefault("events.webhook-delivery.timeout", 10*time.Second)
+	viper.SetDefault("events.webhook-delivery.history-size", 500)
+
+	// MQTT defaults
+	viper.SetDefault("mqtt.enabled", false)
+	viper.SetDefault("mqtt.broker", "tcp://localhost:1883")
+	viper.SetDefault("mqtt.client-id", "waddlebot-bridge")
+	viper.SetDefault("mqtt.username", "")
+	viper.SetDefault("mqtt.password", "")
+	viper.SetDefault("mqtt.topic-prefix", "waddlebot")
+	viper.SetDefault("mqtt.qos", 0)
+	viper.SetDefault("mqtt.retain", false)
+	viper.SetDefault("mqtt.connect-timeout", 10*time.Second)
+	viper.SetDefault("mqtt.commands", []string{"obs:*", "macro:*"})
+
+	// Tray defaults
+	viper.SetDefault("tray.enabled", false)
+
+	// Log shipping defaults
+	viper.SetDefault("log-shipping.enabled", false)
+	viper.SetDefault("log-shipping.level", "warn")
+	viper.SetDefault("log-shipping.batch-size", 50)
+	viper.SetDefault("log-shipping.flush-interval", 30*time.Second)
+	viper.SetDefault("log-shipping.buffer-size", 1000)
+
+	// Tracing defaults
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.endpoint", "localhost:4317")
+	viper.SetDefault("tracing.protocol", "grpc")
+	viper.SetDefault("tracing.insecure", false)
+	viper.SetDefault("tracing.sample-ratio", 1.0)
+
+	// Update defaults
+	viper.SetDefault("update.channel", "stable")
+	viper.SetDefault("update.auto-check", false)
+	viper.SetDefault("update.auto-install", false)
+	viper.SetDefault("update.check-interval", 24*time.Hour)
+	viper.SetDefault("update.public-key", "")
+
+	// License defaults
+	viper.SetDefault("license.check-interval", 12*time.Hour)
+	viper.SetDefault("license.public-key", "")
+
+	// Shutdown defaults
+	viper.SetDefault("shutdown.drain-timeout", 30*time.Second)
+	viper.SetDefault("shutdown.flush-timeout", 10*time.Second)
+
+	// Power defaults
+	viper.SetDefault("power.enabled", true)
+	viper.SetDefault("power.check-interval", 5*time.Second)
+	viper.SetDefault("power.pause-in-presentation", false)
+
+	// Stream guardian defaults
+	viper.SetDefault("guardian.enabled", false)
+	viper.SetDefault("guardian.interval", 5*time.Second)
+	viper.SetDefault("guardian.max-dropped-ratio", 0.05)
+	viper.SetDefault("guardian.max-encoder-lag-ratio", 0.05)
+	viper.SetDefault("guardian.min-bitrate-kbps", 0)
+	viper.SetDefault("guardian.trigger-samples", 3)
+	viper.SetDefault("guardian.recover-samples", 6)
+	viper.SetDefault("guardian.cooldown", 2*time.Minute)
+
+	// Scene switching rules defaults
+	viper.SetDefault("rules.enabled", true)
+	viper.SetDefault("rules.interval", time.Second)
+
+	// Clip upload defaults
+	viper.SetDefault("clips.enabled", false)
+	viper.SetDefault("clips.upload-replays", false)
+	viper.SetDefault("clips.upload-recordings", false)
+	viper.SetDefault("clips.max-size-mb", 500)
+	viper.SetDefault("clips.chunk-size-mb", 8)
+	viper.SetDefault("clips.ffmpeg.enabled", false)
+	viper.SetDefault("clips.ffmpeg.path", "ffmpeg")
+	viper.SetDefault("clips.ffmpeg.trim-seconds", 0)
+	viper.SetDefault("clips.ffmpeg.format", "mp4")
+
+	// Text source defaults
+	viper.SetDefault("text-sources.enabled", true)
+	viper.SetDefault("text-sources.interval", time.Second)
+
+	// Timer defaults
+	viper.SetDefault("timers.enabled", true)
+
+	// Source visibility schedule defaults
+	viper.SetDefault("schedules.enabled", true)
+	viper.SetDefault("schedules.interval", time.Second)
+
+	// Trigger defaults
+	viper.SetDefault("triggers.enabled", true)
+	viper.SetDefault("triggers.interval", 5*time.Second)
+
+	// Community call defaults
+	viper.SetDefault("rtc.enabled", false)
+	viper.SetDefault("rtc.url", "")
+	viper.SetDefault("rtc.livekit-url", "")
+	viper.SetDefault("rtc.view-url", "")
+	viper.SetDefault("rtc.layout", "grid")
+
+	// OSC defaults
+	viper.SetDefault("osc.enabled", false)
+	viper.SetDefault("osc.listen", "0.0.0.0:9000")
+
+	// Policy defaults
+	viper.SetDefault("policy.enabled", true)
+	viper.SetDefault("policy.file", "")
+	viper.SetDefault("policy.max-audit-entries", 1000)
+	viper.SetDefault("policy.default", "allow")
+
 	// OBS defaults
 	viper.SetDefault("obs.enabled", true)
 	viper.SetDefault("obs.host", "localhost")
@@ -172,6 +1012,12 @@ func setDefaults() {
 	viper.SetDefault("obs.reconnect-interval", time.Second)
 	viper.SetDefault("obs.max-reconnect-interval", 30*time.Second)
 	viper.SetDefault("obs.timeout", 10*time.Second)
+	viper.SetDefault("obs.audio-meters.enabled", false)
+	viper.SetDefault("obs.audio-meters.interval", 100*time.Millisecond)
+	viper.SetDefault("obs.audio-meters.silence-threshold", -60.0)
+	viper.SetDefault("obs.audio-meters.silence-delay", 10*time.Second)
+	viper.SetDefault("obs.history-size", 1000)
+	viper.SetDefault("obs.history-retention", 30*24*time.Hour)
 
 	// Gateway defaults
 	viper.SetDefault("gateway.enabled", true)
@@ -183,6 +1029,12 @@ func setDefaults() {
 	viper.SetDefault("gateway.enable-cors", false)
 	viper.SetDefault("gateway.allowed-origins", []string{})
 	viper.SetDefault("gateway.ws-ping-interval", 30)
+	viper.SetDefault("gateway.enable-metrics", true)
+	viper.SetDefault("gateway.max-body-size", 1<<20)
+	viper.SetDefault("gateway.overlays.enabled", true)
+	viper.SetDefault("gateway.overlays.dir", "")
+	viper.SetDefault("gateway.overlays.cache-max-age", "1h")
+	viper.SetDefault("gateway.tls.enabled", false)
 
 	// Scripting defaults
 	viper.SetDefault("scripting.enabled", true)
@@ -198,6 +1050,31 @@ func setDefaults() {
 	viper.SetDefault("scripting.python-path", "python3")
 	viper.SetDefault("scripting.powershell-path", "pwsh")
 	viper.SetDefault("scripting.bash-path", "bash")
+
+	// Built-in module defaults
+	viper.SetDefault("builtin-modules.file-ops.enabled", false)
+	viper.SetDefault("builtin-modules.file-ops.allowed-roots", []string{})
+	viper.SetDefault("builtin-modules.file-ops.allow-write", false)
+	viper.SetDefault("builtin-modules.file-ops.max-file-size-kb", 1024)
+	viper.SetDefault("builtin-modules.file-ops.max-list-entries", 500)
+	viper.SetDefault("builtin-modules.http-request.enabled", false)
+	viper.SetDefault("builtin-modules.http-request.allowed-hosts", []string{})
+	viper.SetDefault("builtin-modules.http-request.allowed-headers", []string{"Accept", "Content-Type", "User-Agent"})
+	viper.SetDefault("builtin-modules.http-request.max-response-kb", 256)
+	viper.SetDefault("builtin-modules.http-request.max-request-kb", 64)
+	viper.SetDefault("builtin-modules.http-request.timeout", 10)
+	viper.SetDefault("builtin-modules.soundboard.enabled", false)
+	viper.SetDefault("builtin-modules.soundboard.sounds-dir", "")
+	viper.SetDefault("builtin-modules.soundboard.default-volume", 80)
+	viper.SetDefault("builtin-modules.soundboard.max-concurrent", 3)
+	viper.SetDefault("builtin-modules.soundboard.player-path", "")
+	viper.SetDefault("builtin-modules.hotkeys.enabled", false)
+	viper.SetDefault("builtin-modules.hotkeys.permission-granted", false)
+	viper.SetDefault("builtin-modules.hotkeys.mappings", map[string]string{})
+	viper.SetDefault("builtin-modules.hotkeys.cooldown-ms", 500)
+	viper.SetDefault("builtin-modules.overlays.enabled", true)
+	viper.SetDefault("builtin-modules.overlays.default-duration", "5s")
+	viper.SetDefault("builtin-modules.overlays.max-queue", 50)
 }
 
 // setPlatformDefaults sets platform-specific default values
@@ -223,7 +1100,71 @@ func setPlatformDefaults(cfg *Config) {
 
 // GetWebAuthnURL returns the WebAuthn origin URL
 func (c *Config) GetWebAuthnURL() string {
-	return fmt.Sprintf("http://%s:%d", c.WebHost, c.WebPort)
+	scheme := "http"
+	if c.WebTLS.Enabled {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, c.WebHost, c.WebPort)
+}
+
+// GetWebAuthnOrigins returns the origins WebAuthn ceremonies are accepted
+// from: the web server's own URL and the configured origins
+func (c *Config) GetWebAuthnOrigins() []string {
+	origins := []string{c.GetWebAuthnURL()}
+	for _, origin := range append([]string{c.WebAuthnOrigin}, c.WebAuthnOrigins...) {
+		if origin != "" && !containsString(origins, origin) {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ForCommunities returns the configuration of every community served by the
+// bridge: this configuration first, then one copy per additional community
+// with its ID, credentials and task routing applied
+func (c *Config) ForCommunities() []*Config {
+	configs := []*Config{c}
+	for _, community := range c.Communities {
+		derived := *c
+		derived.CommunityID = community.ID
+		derived.APIToken = community.APIToken
+		derived.TaskTypes = community.TaskTypes
+		derived.Communities = nil
+		if community.UserID != "" {
+			derived.UserID = community.UserID
+		}
+		if community.JWTSecret != "" {
+			derived.JWTSecret = community.JWTSecret
+		}
+		configs = append(configs, &derived)
+	}
+	return configs
+}
+
+// ValidateCommunities checks that every additional community has a unique
+// ID distinct from the primary community
+func (c *Config) ValidateCommunities() error {
+	seen := map[string]bool{c.CommunityID: true}
+	for i, community := range c.Communities {
+		if community.ID == "" {
+			return fmt.Errorf("communities[%d]: id is required", i)
+		}
+		if seen[community.ID] {
+			return fmt.Errorf("communities[%d]: duplicate community %s", i, community.ID)
+		}
+		seen[community.ID] = true
+	}
+	return nil
 }
 
 // GetAPIEndpoint returns a formatted API endpoint URL
@@ -234,4 +1175,4 @@ func (c *Config) GetAPIEndpoint(path string) string {
 // GetUserAgent returns the user agent string for API requests
 func (c *Config) GetUserAgent() string {
 	return fmt.Sprintf("WaddleBot-Bridge/1.0.0 (%s %s)", runtime.GOOS, runtime.GOARCH)
-}
\ No newline at end of file
+}
@@ -392,6 +392,67 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+func TestConfig_ForCommunities(t *testing.T) {
+	cfg := &Config{
+		CommunityID: "primary",
+		UserID:      "user-1",
+		JWTSecret:   "primary-secret",
+		APIToken:    "primary-token",
+		Communities: []CommunityConfig{
+			{ID: "second", APIToken: "second-token", TaskTypes: []string{"obs"}},
+			{ID: "third", UserID: "user-3", JWTSecret: "third-secret"},
+		},
+	}
+
+	configs := cfg.ForCommunities()
+	if len(configs) != 3 || configs[0] != cfg {
+		t.Fatalf("Expected primary config followed by 2 communities, got %d", len(configs))
+	}
+
+	second := configs[1]
+	if second.CommunityID != "second" || second.UserID != "user-1" || second.APIToken != "second-token" {
+		t.Errorf("Unexpected second community config: %+v", second)
+	}
+	if len(second.TaskTypes) != 1 || second.TaskTypes[0] != "obs" || second.Communities != nil {
+		t.Error("Expected task routing applied and no nested communities")
+	}
+
+	third := configs[2]
+	if third.UserID != "user-3" || third.JWTSecret != "third-secret" || third.APIToken != "" {
+		t.Errorf("Expected third community credentials, got %+v", third)
+	}
+	if cfg.CommunityID != "primary" {
+		t.Error("Expected primary config to be unchanged")
+	}
+}
+
+func TestConfig_ValidateCommunities(t *testing.T) {
+	tests := []struct {
+		name        string
+		communities []CommunityConfig
+		expectErr   bool
+	}{
+		{"none", nil, false},
+		{"distinct", []CommunityConfig{{ID: "a"}, {ID: "b"}}, false},
+		{"missing id", []CommunityConfig{{UserID: "u"}}, true},
+		{"duplicate", []CommunityConfig{{ID: "a"}, {ID: "a"}}, true},
+		{"same as primary", []CommunityConfig{{ID: "primary"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{CommunityID: "primary", Communities: tt.communities}
+			err := cfg.ValidateCommunities()
+			if tt.expectErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[len(s)-len(substr):] == substr ||
@@ -412,3 +473,24 @@ func validateConfig(cfg *Config) error {
 	}
 	return nil
 }
+
+func TestConfig_GetWebAuthnOrigins(t *testing.T) {
+	cfg := &Config{
+		WebHost:         "bridge.lan",
+		WebPort:         8443,
+		WebTLS:          ServerTLSConfig{Enabled: true},
+		WebAuthnOrigin:  "https://bridge.lan:8443",
+		WebAuthnOrigins: []string{"https://studio.lan:8443"},
+	}
+
+	origins := cfg.GetWebAuthnOrigins()
+	expected := []string{"https://bridge.lan:8443", "https://studio.lan:8443"}
+	if len(origins) != len(expected) {
+		t.Fatalf("Expected origins %v, got %v", expected, origins)
+	}
+	for i := range expected {
+		if origins[i] != expected[i] {
+			t.Errorf("Expected origins %v, got %v", expected, origins)
+		}
+	}
+}
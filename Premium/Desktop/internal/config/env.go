@@ -0,0 +1,71 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix starts the environment variables that set configuration values
+const EnvPrefix = "WADDLEBOT"
+
+// envKeyReplacer turns a key such as gateway.api-key into the GATEWAY_API_KEY
+// part of its environment variable
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// BindEnv lets every setting be set from the environment, including nested
+// ones: gateway.api-key is read from WADDLEBOT_GATEWAY_API_KEY. Lists take
+// comma-separated values; maps and lists of objects can only be set in the
+// config file.
+func BindEnv() {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer)
+	viper.AutomaticEnv()
+
+	// AutomaticEnv only covers keys viper already knows from defaults or
+	// the config file, so bind the rest explicitly
+	for _, key := range EnvKeys() {
+		viper.BindEnv(key)
+	}
+}
+
+// EnvVar returns the environment variable that sets key
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// EnvKeys returns the keys of the settings that can be set from the
+// environment, sorted
+func EnvKeys() []string {
+	settings := make(map[string]interface{})
+	flatten("", reflect.ValueOf(Config{}), settings)
+
+	keys := make([]string, 0, len(settings))
+	for key, value := range settings {
+		if envSettable(reflect.TypeOf(value)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envSettable reports whether a setting of type t can be parsed from a
+// single environment variable
+func envSettable(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestBindEnv(t *testing.T) {
+	viper.Reset()
+	t.Setenv("WADDLEBOT_DATA_DIR", t.TempDir())
+	t.Setenv("WADDLEBOT_LOG_LEVEL", "debug")
+	t.Setenv("WADDLEBOT_OBS_PORT", "4460")
+	t.Setenv("WADDLEBOT_GATEWAY_API_KEY", "secret")
+	t.Setenv("WADDLEBOT_GATEWAY_ALLOWED_ORIGINS", "http://localhost,http://studio.lan")
+	t.Setenv("WADDLEBOT_GATEWAY_TLS_ENABLED", "true")
+	t.Setenv("WADDLEBOT_STORAGE_SWEEP_INTERVAL", "2m")
+	BindEnv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.LogLevel != "debug" {
+		t.Errorf("Expected log level from the environment, got %q", cfg.LogLevel)
+	}
+	if cfg.OBS.Port != 4460 {
+		t.Errorf("Expected OBS port from the environment, got %d", cfg.OBS.Port)
+	}
+	if cfg.Gateway.APIKey != "secret" || !cfg.Gateway.TLS.Enabled {
+		t.Errorf("Expected nested gateway settings from the environment, got %+v", cfg.Gateway)
+	}
+	if want := []string{"http://localhost", "http://studio.lan"}; !reflect.DeepEqual(cfg.Gateway.AllowedOrigins, want) {
+		t.Errorf("Expected origins %v, got %v", want, cfg.Gateway.AllowedOrigins)
+	}
+	if cfg.StorageSweepInterval.Minutes() != 2 {
+		t.Errorf("Expected sweep interval from the environment, got %v", cfg.StorageSweepInterval)
+	}
+}
+
+func TestEnvVar(t *testing.T) {
+	if got := EnvVar("gateway.tls.cert-file"); got != "WADDLEBOT_GATEWAY_TLS_CERT_FILE" {
+		t.Errorf("Expected WADDLEBOT_GATEWAY_TLS_CERT_FILE, got %s", got)
+	}
+	for _, key := range EnvKeys() {
+		if key == "profiles" || key == "communities" {
+			t.Errorf("Expected %s to be left to the config file", key)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// ErrUnknownProfile is returned when the selected profile is not defined
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// ProfileConfig is a named set of overrides, such as "streaming" or
+// "travel". Each section holds only the settings the profile changes, in
+// the same form as the top-level obs, scripting and gateway sections.
+type ProfileConfig struct {
+	Description string                 `mapstructure:"description"`
+	OBS         map[string]interface{} `mapstructure:"obs"`
+	Scripting   map[string]interface{} `mapstructure:"scripting"`
+	Gateway     map[string]interface{} `mapstructure:"gateway"`
+}
+
+// ProfileNames returns the names of the configured profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile overlays the named profile on cfg. Settings the profile
+// leaves out keep their values; lists and maps it sets replace the base
+// ones rather than merging with them.
+func applyProfile(cfg *Config, name string) error {
+	name = strings.ToLower(name)
+	if _, ok := cfg.Profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	cfg.Profile = name
+
+	sections := []struct {
+		key    string
+		target interface{}
+	}{
+		{"obs", &cfg.OBS},
+		{"scripting", &cfg.Scripting},
+		{"gateway", &cfg.Gateway},
+	}
+	for _, section := range sections {
+		sub := viper.Sub("profiles." + name + "." + section.key)
+		if sub == nil {
+			continue
+		}
+		if err := sub.Unmarshal(section.target, func(dc *mapstructure.DecoderConfig) {
+			dc.ZeroFields = true
+		}); err != nil {
+			return fmt.Errorf("failed to apply %s settings of profile %s: %w", section.key, name, err)
+		}
+	}
+	return nil
+}
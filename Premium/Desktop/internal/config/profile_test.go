@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const profilesConfig = `
+obs:
+  host: studio.local
+  port: 4455
+gateway:
+  port: 8081
+  allowed-origins: [http://localhost, http://127.0.0.1]
+scripting:
+  enabled: true
+  enable-lua: true
+  enable-bash: true
+profiles:
+  travel:
+    description: Laptop on the road
+    obs:
+      host: localhost
+    gateway:
+      allowed-origins: [http://localhost]
+    scripting:
+      enable-bash: false
+  recording-only:
+    gateway:
+      enabled: false
+`
+
+func loadProfilesConfig(t *testing.T, profile string) {
+	t.Helper()
+	viper.Reset()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, []byte("data-dir: "+dir+"\n"+profilesConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(file)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("profile", profile)
+}
+
+func TestLoad_Profile(t *testing.T) {
+	loadProfilesConfig(t, "Travel")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Profile != "travel" {
+		t.Errorf("Expected the travel profile to be active, got %q", cfg.Profile)
+	}
+	if cfg.OBS.Host != "localhost" || cfg.OBS.Port != 4455 {
+		t.Errorf("Expected the OBS host to be overridden and the port kept, got %s:%d", cfg.OBS.Host, cfg.OBS.Port)
+	}
+	if !reflect.DeepEqual(cfg.Gateway.AllowedOrigins, []string{"http://localhost"}) {
+		t.Errorf("Expected the profile's origins to replace the base ones, got %v", cfg.Gateway.AllowedOrigins)
+	}
+	if cfg.Gateway.Port != 8081 {
+		t.Errorf("Expected the gateway port to be kept, got %d", cfg.Gateway.Port)
+	}
+	if !cfg.Scripting.EnableLua || cfg.Scripting.EnableBash {
+		t.Errorf("Expected Lua to stay enabled and Bash to be disabled, got %+v", cfg.Scripting)
+	}
+	if names := cfg.ProfileNames(); !reflect.DeepEqual(names, []string{"recording-only", "travel"}) {
+		t.Errorf("Expected both profiles to be listed, got %v", names)
+	}
+}
+
+func TestLoad_UnknownProfile(t *testing.T) {
+	loadProfilesConfig(t, "streaming")
+
+	if _, err := Load(); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Expected ErrUnknownProfile, got %v", err)
+	}
+}
+
+func TestReloader_SwitchProfile(t *testing.T) {
+	loadProfilesConfig(t, "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	reloader := NewReloader(cfg, Load, logger)
+
+	var host string
+	reloader.Handle(func(_, updated *Config) {
+		host = updated.OBS.Host
+	}, "obs")
+
+	result, err := reloader.SwitchProfile("travel")
+	if err != nil {
+		t.Fatalf("SwitchProfile() failed: %v", err)
+	}
+	if reloader.ActiveProfile() != "travel" {
+		t.Errorf("Expected the travel profile to be active, got %q", reloader.ActiveProfile())
+	}
+	if host != "localhost" {
+		t.Errorf("Expected the OBS handler to get the profile's host, got %q", host)
+	}
+	if !reflect.DeepEqual(result.Applied, []string{"obs.host", "profile"}) {
+		t.Errorf("Expected obs.host and profile to be applied, got %v", result.Applied)
+	}
+	if !reflect.DeepEqual(result.RestartRequired, []string{"gateway.allowed-origins", "scripting.enable-bash"}) {
+		t.Errorf("Expected the unhandled settings to need a restart, got %v", result.RestartRequired)
+	}
+
+	if _, err := reloader.SwitchProfile("streaming"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Expected ErrUnknownProfile, got %v", err)
+	}
+	if reloader.ActiveProfile() != "travel" {
+		t.Errorf("Expected a failed switch to keep the active profile")
+	}
+
+	if _, err := reloader.SwitchProfile(""); err != nil {
+		t.Fatalf("SwitchProfile(\"\") failed: %v", err)
+	}
+	if reloader.ActiveProfile() != "" || reloader.Current().OBS.Host != "studio.local" {
+		t.Errorf("Expected the base configuration to be restored")
+	}
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// reloadDebounce groups the writes of one save into a single reload
+const reloadDebounce = 500 * time.Millisecond
+
+// ReloadHandler applies changed settings to a running subsystem
+type ReloadHandler func(old, updated *Config)
+
+// ReloadResult lists the settings a reload changed
+type ReloadResult struct {
+	Applied         []string
+	RestartRequired []string
+}
+
+// Reloader re-reads the configuration while the bridge runs. Settings with
+// a registered handler are applied live; changes to any other setting are
+// reported as needing a restart.
+type Reloader struct {
+	mu       sync.Mutex
+	current  *Config
+	load     func() (*Config, error)
+	handlers []reloadHandler
+	logger   *logrus.Logger
+
+	timerMu sync.Mutex
+	timer   *time.Timer
+}
+
+type reloadHandler struct {
+	keys  []string
+	apply ReloadHandler
+}
+
+// NewReloader creates a reloader starting from cfg. The load function
+// reads the configuration again, preparing it the way cfg was prepared at
+// startup.
+func NewReloader(cfg *Config, load func() (*Config, error), logger *logrus.Logger) *Reloader {
+	return &Reloader{
+		current: cfg,
+		load:    load,
+		logger:  logger,
+		// A profile takes effect through the settings it overrides, which
+		// are reported under their own keys
+		handlers: []reloadHandler{{
+			keys:  []string{"profile", "profiles"},
+			apply: func(_, _ *Config) {},
+		}},
+	}
+}
+
+// Handle registers apply to run when any of the settings under keys change.
+// A key covers the settings nested under it, so "obs" covers "obs.port".
+func (r *Reloader) Handle(apply ReloadHandler, keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, reloadHandler{keys: keys, apply: apply})
+}
+
+// Current returns the configuration of the last successful reload
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Profiles returns the names of the configured profiles, sorted
+func (r *Reloader) Profiles() []string {
+	return r.Current().ProfileNames()
+}
+
+// ActiveProfile returns the name of the active profile, empty for none
+func (r *Reloader) ActiveProfile() string {
+	return r.Current().Profile
+}
+
+// SwitchProfile activates the named profile, or the base configuration when
+// name is empty, and applies what changed. The choice overrides the config
+// file and --profile until the bridge restarts.
+func (r *Reloader) SwitchProfile(name string) (ReloadResult, error) {
+	name = strings.ToLower(name)
+	if name != "" {
+		if _, ok := r.Current().Profiles[name]; !ok {
+			return ReloadResult{}, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+		}
+	}
+
+	previous := viper.GetString("profile")
+	viper.Set("profile", name)
+	result, err := r.Reload()
+	if err != nil {
+		viper.Set("profile", previous)
+		return result, err
+	}
+
+	r.logger.WithField("profile", name).Info("Switched configuration profile")
+	return result, nil
+}
+
+// Reload reads the configuration file again and applies what changed
+func (r *Reloader) Reload() (ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return ReloadResult{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+	updated, err := r.load()
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	var result ReloadResult
+	changed := Changed(r.current, updated)
+	handled := make(map[string]bool)
+	for _, handler := range r.handlers {
+		var matched bool
+		for _, key := range changed {
+			if covers(handler.keys, key) {
+				handled[key] = true
+				matched = true
+			}
+		}
+		if matched {
+			handler.apply(r.current, updated)
+		}
+	}
+	for _, key := range changed {
+		if handled[key] {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+
+	r.current = updated
+
+	if len(result.Applied) > 0 {
+		r.logger.WithField("settings", result.Applied).Info("Applied configuration changes")
+	}
+	if len(result.RestartRequired) > 0 {
+		r.logger.WithField("settings", result.RestartRequired).Warn("Restart the bridge to apply these configuration changes")
+	}
+	return result, nil
+}
+
+// Watch reloads the configuration whenever the config file is saved
+func (r *Reloader) Watch() {
+	viper.OnConfigChange(func(fsnotify.Event) {
+		r.timerMu.Lock()
+		defer r.timerMu.Unlock()
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+		r.timer = time.AfterFunc(reloadDebounce, func() {
+			if _, err := r.Reload(); err != nil {
+				r.logger.WithError(err).Error("Failed to reload configuration; keeping the current settings")
+			}
+		})
+	})
+	viper.WatchConfig()
+}
+
+// covers reports whether key is one of keys or nested under one of them
+func covers(keys []string, key string) bool {
+	for _, k := range keys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Changed returns the settings that differ between two configurations,
+// such as "gateway.port", sorted
+func Changed(old, updated *Config) []string {
+	before := make(map[string]interface{})
+	after := make(map[string]interface{})
+	flatten("", reflect.ValueOf(*old), before)
+	flatten("", reflect.ValueOf(*updated), after)
+
+	var changed []string
+	for key, value := range after {
+		if !reflect.DeepEqual(before[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Settings returns every setting of c under its dotted key, such as
+// gateway.api-key
+func (c *Config) Settings() map[string]interface{} {
+	settings := make(map[string]interface{})
+	flatten("", reflect.ValueOf(*c), settings)
+	return settings
+}
+
+// flatten adds each setting of the struct v to out under its dotted key
+func flatten(prefix string, v reflect.Value, out map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("mapstructure"), ",")
+		value := v.Field(i)
+
+		key := tag[0]
+		if key == "" {
+			if len(tag) > 1 && tag[1] == "squash" && value.Kind() == reflect.Struct {
+				flatten(prefix, value, out)
+			}
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			flatten(key, value, out)
+		case value.Kind() == reflect.Ptr && value.Type().Elem().Kind() == reflect.Struct && !value.IsNil():
+			flatten(key, value.Elem(), out)
+		default:
+			out[key] = value.Interface()
+		}
+	}
+}
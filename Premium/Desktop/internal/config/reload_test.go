@@ -0,0 +1,80 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func TestChanged(t *testing.T) {
+	old := &Config{LogLevel: "info", Gateway: GatewayConfig{Port: 8081}}
+	updated := &Config{LogLevel: "debug", Gateway: GatewayConfig{Port: 8081, AllowedOrigins: []string{"http://localhost"}}}
+
+	want := []string{"gateway.allowed-origins", "log-level"}
+	if changed := Changed(old, updated); !reflect.DeepEqual(changed, want) {
+		t.Errorf("Expected %v, got %v", want, changed)
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	viper.Reset()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte("data-dir: "+dir+"\n"+content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("log-level: info\nweb-port: 8080\n")
+	viper.SetConfigFile(file)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	reloader := NewReloader(cfg, Load, logger)
+
+	var level string
+	reloader.Handle(func(_, updated *Config) {
+		level = updated.LogLevel
+	}, "log-level")
+
+	write("log-level: debug\nweb-port: 9090\n")
+	result, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+
+	if level != "debug" {
+		t.Errorf("Expected the handler to get the new log level, got %q", level)
+	}
+	if !reflect.DeepEqual(result.Applied, []string{"log-level"}) {
+		t.Errorf("Expected log-level to be applied, got %v", result.Applied)
+	}
+	if !reflect.DeepEqual(result.RestartRequired, []string{"web-port"}) {
+		t.Errorf("Expected web-port to need a restart, got %v", result.RestartRequired)
+	}
+	if reloader.Current().WebPort != 9090 {
+		t.Errorf("Expected the current config to be replaced")
+	}
+
+	// A broken file keeps the current settings
+	write("log-level: [\n")
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("Expected an error for an unreadable config file")
+	}
+	if reloader.Current().LogLevel != "debug" {
+		t.Errorf("Expected the current config to be kept")
+	}
+}
@@ -0,0 +1,429 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"waddlebot-bridge/internal/storage"
+)
+
+// Problem severities
+const (
+	SeverityError   = "error"   // the bridge cannot start with this setting
+	SeverityWarning = "warning" // the bridge starts, but the setting is adjusted or ignored
+)
+
+// Problem is one invalid setting found by Validate
+type Problem struct {
+	Key      string `json:"key"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Key, p.Message)
+}
+
+// Problems is the list of problems found in a configuration
+type Problems []Problem
+
+// Errors returns the problems the bridge cannot start with
+func (p Problems) Errors() Problems {
+	var errs Problems
+	for _, problem := range p {
+		if problem.Severity == SeverityError {
+			errs = append(errs, problem)
+		}
+	}
+	return errs
+}
+
+// Error lists the problems, one per line
+func (p Problems) Error() string {
+	lines := make([]string, len(p))
+	for i, problem := range p {
+		lines[i] = problem.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator collects the problems of a configuration
+type validator struct {
+	problems Problems
+}
+
+func (v *validator) errorf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Key: key, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Key: key, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the whole configuration and returns every problem found,
+// not only the first
+func (c *Config) Validate() Problems {
+	v := &validator{}
+
+	if c.CommunityID == "" {
+		v.errorf("community-id", "community ID is required; run login, use --community-id or set it in the config file")
+	}
+	if c.UserID == "" {
+		v.errorf("user-id", "user ID is required; run login, use --user-id or set it in the config file")
+	}
+	if err := c.ValidateCommunities(); err != nil {
+		v.errorf("communities", "%v", err)
+	}
+	v.url("api-url", c.APIURL, true, "http", "https")
+	if c.PollInterval < 5 {
+		v.warnf("poll-interval", "%d seconds is below the minimum; 5 seconds is used", c.PollInterval)
+	}
+
+	v.oneOf("log-level", c.LogLevel, "debug", "info", "warn", "warning", "error")
+	v.oneOf("auth-method", c.AuthMethod, "webauthn", "device")
+	v.oneOf("storage-backend", c.StorageBackend, storage.Backends()...)
+
+	v.port("web-port", c.WebPort)
+	v.tls("web-tls", c.WebTLS)
+	v.url("webauthn-origin", c.WebAuthnOrigin, false, "http", "https")
+	for i, origin := range c.WebAuthnOrigins {
+		v.url(fmt.Sprintf("webauthn-origins[%d]", i), origin, true, "http", "https")
+	}
+
+	if c.Gateway.Enabled {
+		v.port("gateway.port", c.Gateway.Port)
+		v.tls("gateway.tls", c.Gateway.TLS)
+		if c.Gateway.Port == c.WebPort && hostsOverlap(c.Gateway.Host, c.WebHost) {
+			v.errorf("gateway.port", "port %d is also used by the web interface (web-port); give the gateway another port", c.Gateway.Port)
+		}
+		for i, origin := range c.Gateway.AllowedOrigins {
+			if origin != "*" {
+				v.url(fmt.Sprintf("gateway.allowed-origins[%d]", i), origin, true, "http", "https")
+			}
+		}
+	}
+
+	if c.OBS.Enabled {
+		if c.OBS.Host == "" {
+			v.errorf("obs.host", "OBS host is required when OBS is enabled")
+		}
+		v.port("obs.port", c.OBS.Port)
+		if meters := c.OBS.AudioMeters; meters.Enabled {
+			if meters.Interval < 50*time.Millisecond {
+				v.errorf("obs.audio-meters.interval", "interval %s is shorter than the 50ms OBS updates its meters at", meters.Interval)
+			}
+			if meters.SilenceThreshold > 0 {
+				v.errorf("obs.audio-meters.silence-threshold", "silence threshold %.1f dB is above 0 dB", meters.SilenceThreshold)
+			}
+			if meters.SilenceDelay <= 0 {
+				v.errorf("obs.audio-meters.silence-delay", "silence delay must be positive")
+			}
+		}
+		if c.OBS.HistorySize < 0 {
+			v.errorf("obs.history-size", "history size must not be negative")
+		}
+		if c.OBS.HistoryRetention < 0 {
+			v.errorf("obs.history-retention", "history retention must not be negative")
+		}
+	}
+
+	if c.MQTT.Enabled {
+		v.url("mqtt.broker", c.MQTT.Broker, true, "tcp", "ssl", "tls", "ws", "wss", "mqtt", "mqtts")
+		if c.MQTT.QoS < 0 || c.MQTT.QoS > 2 {
+			v.errorf("mqtt.qos", "QoS %d is not 0, 1 or 2", c.MQTT.QoS)
+		}
+	}
+
+	if c.OSC.Enabled {
+		if c.OSC.Listen != "" {
+			v.address("osc.listen", c.OSC.Listen)
+		}
+		for i, output := range c.OSC.Outputs {
+			v.address(fmt.Sprintf("osc.outputs[%d].target", i), output.Target)
+		}
+	}
+
+	if c.Events.Enabled {
+		for i, webhook := range c.Events.Webhooks {
+			v.url(fmt.Sprintf("events.webhooks[%d].url", i), webhook.URL, true, "http", "https")
+		}
+	}
+
+	if c.LogShipping.Enabled {
+		v.oneOf("log-shipping.level", c.LogShipping.Level, "warn", "warning", "error")
+	}
+
+	if c.Tracing.Enabled {
+		v.oneOf("tracing.protocol", c.Tracing.Protocol, "grpc", "http")
+		if strings.Contains(c.Tracing.Endpoint, "://") {
+			v.url("tracing.endpoint", c.Tracing.Endpoint, true, "http", "https")
+		} else {
+			v.address("tracing.endpoint", c.Tracing.Endpoint)
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			v.errorf("tracing.sample-ratio", "%g is not between 0 and 1", c.Tracing.SampleRatio)
+		}
+	}
+
+	v.oneOf("update.channel", c.Update.Channel, "stable", "beta")
+	if c.Update.PublicKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.Update.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+			v.errorf("update.public-key", "not a base64 ed25519 public key")
+		}
+	}
+	if c.Update.AutoCheck && c.Update.CheckInterval < time.Hour {
+		v.warnf("update.check-interval", "%s is below the minimum; 1h is used", c.Update.CheckInterval)
+	}
+
+	if c.License.PublicKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.License.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+			v.errorf("license.public-key", "not a base64 ed25519 public key")
+		}
+	}
+	if c.License.CheckInterval < time.Hour {
+		v.warnf("license.check-interval", "%s is below the minimum; 1h is used", c.License.CheckInterval)
+	}
+
+	if c.Shutdown.DrainTimeout < 0 {
+		v.errorf("shutdown.drain-timeout", "must not be negative")
+	}
+	if c.Shutdown.FlushTimeout < 0 {
+		v.errorf("shutdown.flush-timeout", "must not be negative")
+	}
+
+	if c.Power.Enabled && c.Power.CheckInterval <= 0 {
+		v.errorf("power.check-interval", "must be positive")
+	}
+
+	if c.Guardian.Enabled {
+		v.guardian(c.Guardian, c.OBS.Enabled)
+	}
+
+	if c.Rules.Enabled && c.Rules.Interval <= 0 {
+		v.errorf("rules.interval", "must be positive")
+	}
+
+	if c.Clips.Enabled {
+		v.clips(c.Clips, c.OBS.Enabled)
+	}
+
+	if c.TextSources.Enabled && c.TextSources.Interval <= 0 {
+		v.errorf("text-sources.interval", "must be positive")
+	}
+
+	if c.Schedules.Enabled && c.Schedules.Interval <= 0 {
+		v.errorf("schedules.interval", "must be positive")
+	}
+
+	if c.Triggers.Enabled && c.Triggers.Interval < time.Second {
+		v.errorf("triggers.interval", "must be at least 1s")
+	}
+
+	if c.RTC.Enabled {
+		v.url("rtc.url", c.RTC.URL, false, "http", "https")
+		v.url("rtc.livekit-url", c.RTC.LiveKitURL, false, "ws", "wss", "http", "https")
+		v.url("rtc.view-url", c.RTC.ViewURL, false, "http", "https")
+		if c.RTC.ViewURL == "" || c.RTC.LiveKitURL == "" {
+			v.warnf("rtc.view-url", "rooms cannot be added to OBS without rtc.view-url and rtc.livekit-url")
+		}
+	}
+
+	if c.Policy.Enabled && c.Policy.File != "" {
+		v.file("policy.file", c.Policy.File)
+	}
+
+	if c.Scripting.Enabled {
+		v.dir("scripting.scripts-dir", c.Scripting.ScriptsDir)
+		if c.Scripting.EnablePython {
+			v.executable("scripting.python-path", c.Scripting.PythonPath)
+		}
+		if c.Scripting.EnablePowerShell {
+			v.executable("scripting.powershell-path", c.Scripting.PowerShellPath)
+		}
+		if c.Scripting.EnableBash {
+			v.executable("scripting.bash-path", c.Scripting.BashPath)
+		}
+	}
+
+	return v.problems
+}
+
+func (v *validator) clips(cfg ClipsConfig, obsEnabled bool) {
+	if !obsEnabled {
+		v.warnf("clips.enabled", "clips are saved by OBS, which is disabled")
+	}
+	if cfg.MaxSizeMB < 1 {
+		v.errorf("clips.max-size-mb", "must be at least 1")
+	}
+	if cfg.ChunkSizeMB < 1 {
+		v.errorf("clips.chunk-size-mb", "must be at least 1")
+	}
+	if cfg.FFmpeg.Enabled {
+		if _, err := exec.LookPath(cfg.FFmpeg.Path); err != nil {
+			v.warnf("clips.ffmpeg.path", "%s was not found; install ffmpeg, set its full path or disable clips.ffmpeg", cfg.FFmpeg.Path)
+		}
+		if cfg.FFmpeg.TrimSeconds < 0 {
+			v.errorf("clips.ffmpeg.trim-seconds", "must not be negative")
+		}
+	}
+}
+
+func (v *validator) guardian(cfg GuardianConfig, obsEnabled bool) {
+	if !obsEnabled {
+		v.warnf("guardian.enabled", "the stream guardian needs OBS, which is disabled")
+	}
+	if cfg.Interval <= 0 {
+		v.errorf("guardian.interval", "must be positive")
+	}
+	if cfg.MaxDroppedRatio < 0 || cfg.MaxDroppedRatio > 1 {
+		v.errorf("guardian.max-dropped-ratio", "%g is not between 0 and 1", cfg.MaxDroppedRatio)
+	}
+	if cfg.MaxEncoderLagRatio < 0 || cfg.MaxEncoderLagRatio > 1 {
+		v.errorf("guardian.max-encoder-lag-ratio", "%g is not between 0 and 1", cfg.MaxEncoderLagRatio)
+	}
+	if cfg.MinBitrateKbps < 0 {
+		v.errorf("guardian.min-bitrate-kbps", "must not be negative")
+	}
+	if cfg.TriggerSamples < 1 {
+		v.errorf("guardian.trigger-samples", "must be at least 1")
+	}
+	if cfg.RecoverSamples < 1 {
+		v.errorf("guardian.recover-samples", "must be at least 1")
+	}
+	if len(cfg.Actions) == 0 {
+		v.warnf("guardian.actions", "no actions are configured; unhealthy streams are only reported")
+	}
+	for i, action := range cfg.Actions {
+		key := fmt.Sprintf("guardian.actions[%d]", i)
+		v.oneOf(key+".type", action.Type, "scene", "warn", "restart-stream")
+		if action.Type == "scene" && action.Scene == "" {
+			v.errorf(key+".scene", "a scene is required to switch to")
+		}
+		if action.Type == "warn" && action.Message == "" {
+			v.errorf(key+".message", "a message is required to warn with")
+		}
+		if action.After < 0 {
+			v.errorf(key+".after", "must not be negative")
+		}
+	}
+}
+
+func (v *validator) port(key string, port int) {
+	if port < 1 || port > 65535 {
+		v.errorf(key, "port %d is out of range; use a port between 1 and 65535", port)
+	}
+}
+
+func (v *validator) url(key, value string, required bool, schemes ...string) {
+	if value == "" {
+		if required {
+			v.errorf(key, "a URL is required")
+		}
+		return
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		v.errorf(key, "%q is not a valid URL: %v", value, err)
+		return
+	}
+	if parsed.Host == "" {
+		v.errorf(key, "%q is not a full URL; use one such as %s://host", value, schemes[0])
+		return
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return
+		}
+	}
+	v.errorf(key, "%q must use one of the schemes %s", value, strings.Join(schemes, ", "))
+}
+
+func (v *validator) address(key, value string) {
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		v.errorf(key, "%q is not a host:port address: %v", value, err)
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		v.errorf(key, "port %q is out of range; use a port between 1 and 65535", port)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return
+		}
+	}
+	v.errorf(key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+}
+
+func (v *validator) tls(key string, cfg ServerTLSConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		v.errorf(key, "set both cert-file and key-file, or neither to use a self-signed certificate")
+		return
+	}
+	if cfg.CertFile != "" {
+		v.file(key+".cert-file", cfg.CertFile)
+		v.file(key+".key-file", cfg.KeyFile)
+	}
+}
+
+func (v *validator) file(key, path string) {
+	info, err := os.Stat(path)
+	switch {
+	case err != nil:
+		v.errorf(key, "cannot read %s: %v", path, err)
+	case info.IsDir():
+		v.errorf(key, "%s is a directory, not a file", path)
+	}
+}
+
+func (v *validator) dir(key, path string) {
+	if path == "" {
+		v.errorf(key, "a directory is required")
+		return
+	}
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		v.errorf(key, "%s does not exist; create it or point %s at your scripts", path, key)
+	case err != nil:
+		v.errorf(key, "cannot read %s: %v", path, err)
+	case !info.IsDir():
+		v.errorf(key, "%s is not a directory", path)
+	}
+}
+
+// executable checks an interpreter given as a path or a name on PATH.
+// Scripts of a type whose interpreter is missing fail when run, so this is
+// only a warning.
+func (v *validator) executable(key, path string) {
+	if path == "" {
+		v.warnf(key, "no interpreter is set; scripts of this type will fail")
+		return
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		v.warnf(key, "%s was not found; install it, set its full path or disable this script type", path)
+	}
+}
+
+// hostsOverlap reports whether servers listening on hosts a and b would
+// share addresses
+func hostsOverlap(a, b string) bool {
+	wildcard := func(host string) bool {
+		return host == "" || host == "0.0.0.0" || host == "::"
+	}
+	return a == b || wildcard(a) || wildcard(b)
+}
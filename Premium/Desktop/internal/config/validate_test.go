@@ -0,0 +1,77 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func validConfig(t *testing.T) *Config {
+	return &Config{
+		APIURL:         "https://api.waddlebot.io",
+		CommunityID:    "community",
+		UserID:         "user",
+		PollInterval:   30,
+		LogLevel:       "info",
+		AuthMethod:     "webauthn",
+		StorageBackend: "bolt",
+		WebHost:        "127.0.0.1",
+		WebPort:        8080,
+		WebAuthnOrigin: "http://127.0.0.1:8080",
+		Gateway:        GatewayConfig{Enabled: true, Host: "127.0.0.1", Port: 8090},
+		Scripting:      ScriptingConfig{Enabled: true, EnableLua: true, ScriptsDir: t.TempDir()},
+		Update:         UpdateConfig{Channel: "stable"},
+		License:        LicenseConfig{CheckInterval: 12 * time.Hour},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if problems := validConfig(t).Validate(); len(problems) != 0 {
+		t.Fatalf("Expected a valid config, got %v", problems)
+	}
+
+	cfg := validConfig(t)
+	cfg.UserID = ""
+	cfg.PollInterval = 1
+	cfg.WebPort = 70000
+	cfg.Gateway.Host = "0.0.0.0"
+	cfg.Gateway.Port = 70000
+	cfg.APIURL = "api.waddlebot.io"
+	cfg.MQTT = MQTTConfig{Enabled: true, Broker: "http://broker:1883"}
+	cfg.Scripting.ScriptsDir = filepath.Join(t.TempDir(), "missing")
+	cfg.Scripting.EnableBash = true
+	cfg.Scripting.BashPath = filepath.Join(t.TempDir(), "bash")
+	cfg.Tracing = TracingConfig{Enabled: true, Protocol: "zipkin", Endpoint: "collector", SampleRatio: 2}
+	cfg.Update.Channel = "nightly"
+	cfg.Update.PublicKey = "not a key"
+
+	problems := cfg.Validate()
+	var keys []string
+	for _, problem := range problems {
+		keys = append(keys, problem.Key)
+	}
+	want := []string{
+		"user-id",
+		"api-url",
+		"poll-interval",
+		"web-port",
+		"gateway.port",
+		"gateway.port",
+		"mqtt.broker",
+		"tracing.protocol",
+		"tracing.endpoint",
+		"tracing.sample-ratio",
+		"update.channel",
+		"update.public-key",
+		"scripting.scripts-dir",
+		"scripting.bash-path",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("Expected problems with %v, got %v", want, problems)
+	}
+
+	if errs := problems.Errors(); len(errs) != 12 {
+		t.Errorf("Expected 12 errors, got %v", errs)
+	}
+}
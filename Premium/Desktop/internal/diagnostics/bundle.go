@@ -0,0 +1,200 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/gateway/handlers"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/secrets"
+)
+
+// maxLogSize is how much of the end of each log file goes in a bundle
+const maxLogSize = 5 << 20
+
+// Gateway is the running bridge's API gateway
+type Gateway interface {
+	Status(ctx context.Context) (*handlers.BridgeStatus, error)
+	Modules(ctx context.Context) ([]models.ModuleInfo, error)
+	OBSStatus(ctx context.Context) (map[string]interface{}, error)
+}
+
+// Bundle collects what support needs to look into a problem: the bridge's
+// version and platform, its config, recent logs, modules and OBS
+// connection. Secrets are redacted from the config and the logs.
+type Bundle struct {
+	Version  string
+	Config   *config.Config // redacted in place by Write
+	LogFiles []string       // included when they exist
+	Gateway  Gateway        // nil when the gateway is disabled
+}
+
+// Write writes the bundle to w as a zip archive. Parts that cannot be
+// collected, such as the module list while the bridge is stopped, are
+// listed in errors.txt instead.
+func (b *Bundle) Write(ctx context.Context, w io.Writer) error {
+	archive := zip.NewWriter(w)
+	var problems []string
+	problem := func(part string, err error) {
+		problems = append(problems, fmt.Sprintf("%s: %v", part, err))
+	}
+
+	secretValues := secrets.Redact(b.Config)
+
+	system := map[string]interface{}{
+		"version":      b.Version,
+		"os":           runtime.GOOS,
+		"arch":         runtime.GOARCH,
+		"go_version":   runtime.Version(),
+		"generated_at": time.Now().UTC(),
+		"profile":      b.Config.Profile,
+	}
+	if err := writeJSON(archive, "system.json", system); err != nil {
+		return err
+	}
+
+	settings, err := redactSettings(b.Config.Settings())
+	if err != nil {
+		return fmt.Errorf("failed to redact config: %w", err)
+	}
+	if err := writeJSON(archive, "config.json", settings); err != nil {
+		return err
+	}
+
+	scrub := newScrubber(secretValues)
+	for _, path := range b.LogFiles {
+		if err := writeLog(archive, path, scrub); err != nil && !os.IsNotExist(err) {
+			problem("logs/"+filepath.Base(path), err)
+		}
+	}
+
+	if b.Gateway == nil {
+		problem("gateway", fmt.Errorf("the gateway is disabled; status, modules and OBS were not collected from the running bridge"))
+		if err := writeJSON(archive, "modules.json", map[string]interface{}{"files": moduleFiles(b.Config.ModulesDir)}); err != nil {
+			return err
+		}
+	} else {
+		if status, err := b.Gateway.Status(ctx); err != nil {
+			problem("status", err)
+		} else if err := writeJSON(archive, "status.json", status); err != nil {
+			return err
+		}
+
+		if modules, err := b.Gateway.Modules(ctx); err != nil {
+			problem("modules", err)
+			if err := writeJSON(archive, "modules.json", map[string]interface{}{"files": moduleFiles(b.Config.ModulesDir)}); err != nil {
+				return err
+			}
+		} else if err := writeJSON(archive, "modules.json", modules); err != nil {
+			return err
+		}
+
+		if b.Config.OBS.Enabled {
+			if status, err := b.Gateway.OBSStatus(ctx); err != nil {
+				problem("obs", err)
+			} else if err := writeJSON(archive, "obs.json", status); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		entry, err := create(archive, "errors.txt")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, strings.Join(problems, "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// create adds a compressed file to the archive dated now
+func create(archive *zip.Writer, name string) (io.Writer, error) {
+	return archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+}
+
+// writeJSON adds v to the archive as an indented JSON file
+func writeJSON(archive *zip.Writer, name string, v interface{}) error {
+	entry, err := create(archive, name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeLog adds the end of a log file to the archive with secrets scrubbed
+func writeLog(archive *zip.Writer, path string, scrub *strings.Replacer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil && info.Size() > maxLogSize {
+		if _, err := file.Seek(-maxLogSize, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	entry, err := create(archive, "logs/"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(entry, scrub.Replace(string(data)))
+	return err
+}
+
+// newScrubber replaces secret values with the redaction marker
+func newScrubber(values []string) *strings.Replacer {
+	var pairs []string
+	for _, value := range values {
+		pairs = append(pairs, value, secrets.Redacted)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// redactSettings returns settings as plain JSON values with every value
+// whose key looks secret replaced, including inside lists and profiles
+func redactSettings(settings map[string]interface{}) (interface{}, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var plain interface{}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return nil, err
+	}
+	return secrets.RedactValue("", plain), nil
+}
+
+// moduleFiles lists the modules directory, for when the running bridge
+// cannot be asked for its modules
+func moduleFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+	return files
+}
@@ -0,0 +1,174 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/gateway/handlers"
+	"waddlebot-bridge/internal/models"
+)
+
+type fakePoster struct {
+	mu       sync.Mutex
+	fail     bool
+	payloads []map[string]interface{}
+}
+
+func (p *fakePoster) PostJSON(ctx context.Context, path string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail {
+		return errors.New("API unavailable")
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(payload, &decoded)
+	p.payloads = append(p.payloads, decoded)
+	return nil
+}
+
+func (p *fakePoster) entries() []interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var entries []interface{}
+	for _, payload := range p.payloads {
+		entries = append(entries, payload["entries"].([]interface{})...)
+	}
+	return entries
+}
+
+func TestLogShipper(t *testing.T) {
+	poster := &fakePoster{fail: true}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	shipper := NewLogShipper(config.LogShippingConfig{Level: "warn", BatchSize: 2, BufferSize: 10, FlushInterval: time.Hour}, poster, logger)
+	logger.AddHook(shipper)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		shipper.Run(ctx)
+	}()
+
+	logger.Info("not forwarded")
+	logger.WithField("api_key", "hunter2").WithField("module", "obs").Warn("first")
+	logger.Error("second")
+	time.Sleep(100 * time.Millisecond)
+	if len(poster.entries()) != 0 {
+		t.Fatal("Expected nothing to be sent while the API fails")
+	}
+
+	poster.mu.Lock()
+	poster.fail = false
+	poster.mu.Unlock()
+	logger.Error("third")
+	cancel()
+	<-done
+
+	entries := poster.entries()
+	if len(entries) != 3 {
+		t.Fatalf("Expected the failed batch to be retried with the last entry, got %v", entries)
+	}
+	first := entries[0].(map[string]interface{})
+	fields := first["fields"].(map[string]interface{})
+	if first["message"] != "first" || first["level"] != "warning" || fields["module"] != "obs" || fields["api_key"] != nil {
+		t.Errorf("Unexpected entry %v", first)
+	}
+	if stats := shipper.GetStats(); stats["sent"] != int64(3) {
+		t.Errorf("Expected 3 sent entries, got %v", stats)
+	}
+}
+
+func TestLogShipper_ErrorLevel(t *testing.T) {
+	shipper := NewLogShipper(config.LogShippingConfig{Level: "error"}, &fakePoster{}, logrus.New())
+	for _, level := range shipper.Levels() {
+		if level == logrus.WarnLevel {
+			t.Error("Expected warnings not to be forwarded at error level")
+		}
+	}
+}
+
+type fakeGateway struct{}
+
+func (fakeGateway) Status(ctx context.Context) (*handlers.BridgeStatus, error) {
+	return &handlers.BridgeStatus{Status: "healthy", Version: "1.2.3"}, nil
+}
+
+func (fakeGateway) Modules(ctx context.Context) ([]models.ModuleInfo, error) {
+	return nil, errors.New("gateway returned 503")
+}
+
+func (fakeGateway) OBSStatus(ctx context.Context) (map[string]interface{}, error) {
+	return map[string]interface{}{"connected": true, "state": "connected"}, nil
+}
+
+func TestBundle(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "recent.log")
+	if err := os.WriteFile(logFile, []byte("level=info msg=\"Connecting to OBS\" password=obs-pass\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{CommunityID: "community", ModulesDir: dir}
+	cfg.OBS.Enabled = true
+	cfg.OBS.Password = "obs-pass"
+	cfg.Profiles = map[string]config.ProfileConfig{
+		"streaming": {Gateway: map[string]interface{}{"api-key": "profile-key"}},
+	}
+
+	bundle := &Bundle{
+		Version:  "1.2.3",
+		Config:   cfg,
+		LogFiles: []string{logFile, filepath.Join(dir, "missing.log")},
+		Gateway:  fakeGateway{},
+	}
+	var buf bytes.Buffer
+	if err := bundle.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Bundle is not a zip: %v", err)
+	}
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		r, _ := file.Open()
+		data, _ := io.ReadAll(r)
+		r.Close()
+		files[file.Name] = string(data)
+	}
+
+	for _, name := range []string{"system.json", "config.json", "logs/recent.log", "status.json", "modules.json", "obs.json", "errors.txt"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in the bundle, got %v", name, files)
+		}
+	}
+	for name, content := range files {
+		if strings.Contains(content, "obs-pass") || strings.Contains(content, "profile-key") {
+			t.Errorf("Expected secrets to be redacted from %s:\n%s", name, content)
+		}
+	}
+	if !strings.Contains(files["config.json"], `"community-id": "community"`) {
+		t.Errorf("Expected other settings in the config:\n%s", files["config.json"])
+	}
+	if !strings.Contains(files["errors.txt"], "modules: gateway returned 503") || strings.Contains(files["errors.txt"], "missing.log") {
+		t.Errorf("Unexpected errors:\n%s", files["errors.txt"])
+	}
+	if !strings.Contains(files["modules.json"], "recent.log") {
+		t.Errorf("Expected the modules directory listing as a fallback:\n%s", files["modules.json"])
+	}
+}
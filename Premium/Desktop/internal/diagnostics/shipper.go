@@ -0,0 +1,178 @@
+// Package diagnostics helps WaddleBot support look into problems: it
+// forwards warnings and errors to the API and collects a redacted bundle
+// for support tickets
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/secrets"
+)
+
+// logsPath is the API path log entries are posted to
+const logsPath = "/api/bridge/logs"
+
+// flushTimeout bounds the final flush when the shipper stops
+const flushTimeout = 5 * time.Second
+
+// Poster posts JSON to the WaddleBot API
+type Poster interface {
+	PostJSON(ctx context.Context, path string, payload []byte) error
+}
+
+// LogEntry is a log entry forwarded to the API
+type LogEntry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// LogShipper is a logrus hook that forwards warnings and errors to the
+// API in batches. Entries are dropped rather than block logging when the
+// API cannot keep up.
+type LogShipper struct {
+	cfg     config.LogShippingConfig
+	poster  Poster
+	logger  *logrus.Logger
+	levels  []logrus.Level
+	entries chan LogEntry
+	dropped atomic.Int64
+	sent    atomic.Int64
+}
+
+// NewLogShipper creates a shipper posting through poster. Add it to a
+// logger with AddHook and call Run to start sending.
+func NewLogShipper(cfg config.LogShippingConfig, poster Poster, logger *logrus.Logger) *LogShipper {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.BufferSize < cfg.BatchSize {
+		cfg.BufferSize = cfg.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 30 * time.Second
+	}
+
+	levels := []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+	if level := strings.ToLower(cfg.Level); level != "error" {
+		levels = append(levels, logrus.WarnLevel)
+	}
+
+	return &LogShipper{
+		cfg:     cfg,
+		poster:  poster,
+		logger:  logger,
+		levels:  levels,
+		entries: make(chan LogEntry, cfg.BufferSize),
+	}
+}
+
+// Levels returns the levels forwarded
+func (s *LogShipper) Levels() []logrus.Level {
+	return s.levels
+}
+
+// Fire queues an entry without blocking
+func (s *LogShipper) Fire(entry *logrus.Entry) error {
+	e := LogEntry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+	}
+	for key, value := range entry.Data {
+		if secrets.SecretField(key) {
+			continue
+		}
+		if e.Fields == nil {
+			e.Fields = make(map[string]string)
+		}
+		e.Fields[key] = fmt.Sprint(value)
+	}
+
+	select {
+	case s.entries <- e:
+	default:
+		s.dropped.Add(1)
+	}
+	return nil
+}
+
+// Run sends queued entries until ctx is cancelled, when what is left is
+// flushed. Failed batches are retried on the next flush while the buffer
+// has room for them.
+func (s *LogShipper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var pending []LogEntry
+	for {
+		select {
+		case <-ctx.Done():
+			for len(s.entries) > 0 {
+				pending = append(pending, <-s.entries)
+			}
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			s.flush(flushCtx, pending)
+			cancel()
+			return
+		case entry := <-s.entries:
+			pending = append(pending, entry)
+			if len(pending) >= s.cfg.BatchSize {
+				pending = s.flush(ctx, pending)
+			}
+		case <-ticker.C:
+			pending = s.flush(ctx, pending)
+		}
+	}
+}
+
+// flush sends pending in batches and returns the entries still unsent.
+// Failures are logged at debug level, which is never forwarded.
+func (s *LogShipper) flush(ctx context.Context, pending []LogEntry) []LogEntry {
+	for len(pending) > 0 {
+		n := len(pending)
+		if n > s.cfg.BatchSize {
+			n = s.cfg.BatchSize
+		}
+		if err := s.send(ctx, pending[:n]); err != nil {
+			s.logger.WithError(err).Debug("Failed to forward log entries")
+			if over := len(pending) - s.cfg.BufferSize; over > 0 {
+				s.dropped.Add(int64(over))
+				pending = pending[over:]
+			}
+			return pending
+		}
+		s.sent.Add(int64(n))
+		pending = pending[n:]
+	}
+	return pending[:0]
+}
+
+func (s *LogShipper) send(ctx context.Context, entries []LogEntry) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"entries": entries,
+		"dropped": s.dropped.Load(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.poster.PostJSON(ctx, logsPath, payload)
+}
+
+// GetStats returns shipping statistics
+func (s *LogShipper) GetStats() map[string]interface{} {
+	return map[string]interface{}{
+		"sent":    s.sent.Load(),
+		"dropped": s.dropped.Load(),
+		"queued":  len(s.entries),
+	}
+}
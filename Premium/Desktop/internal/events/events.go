@@ -0,0 +1,283 @@
+package events
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/config"
+)
+
+// Event sources
+const (
+	SourceBridge = "bridge"
+	SourceOBS    = "obs"
+	SourceScript = "script"
+)
+
+// ModuleSource returns the source name of events published by a module
+func ModuleSource(name string) string {
+	return "module:" + name
+}
+
+// defaultBufferSize is the per-sink queue length when none is configured
+const defaultBufferSize = 256
+
+// Event is a message published on the bus
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Source    string                 `json:"source"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Sink delivers events to a destination such as the WaddleBot API, a
+// webhook or the gateway's WebSocket clients
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, event Event) error
+}
+
+// BatchSink is a sink that can deliver several events at once. The bus
+// hands it whatever is queued, up to BatchSize events.
+type BatchSink interface {
+	Sink
+	BatchSize() int
+	DeliverBatch(ctx context.Context, events []Event) error
+}
+
+// Publisher publishes events to the bus
+type Publisher interface {
+	Publish(event Event)
+}
+
+// SinkStats holds delivery statistics for one sink
+type SinkStats struct {
+	Name      string             `json:"name"`
+	Filter    config.EventFilter `json:"filter"`
+	Delivered int64              `json:"delivered"`
+	Failed    int64              `json:"failed"`
+	Dropped   int64              `json:"dropped"`
+	Queued    int                `json:"queued"`
+}
+
+// Bus fans published events out to sinks. Every sink has its own queue and
+// worker, so a slow or failing sink never delays the others or the
+// publisher; events that do not fit in a full queue are dropped.
+type Bus struct {
+	logger     *logrus.Logger
+	bufferSize int
+
+	mu    sync.RWMutex
+	sinks map[string]*subscription
+	ctx   context.Context
+	stop  context.CancelFunc
+	wg    sync.WaitGroup
+}
+
+// subscription is a sink attached to the bus
+type subscription struct {
+	sink   Sink
+	filter config.EventFilter
+	queue  chan Event
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	delivered int64
+	failed    int64
+	dropped   int64
+}
+
+// NewBus creates an event bus
+func NewBus(cfg config.EventsConfig, logger *logrus.Logger) *Bus {
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	return &Bus{
+		logger:     logger,
+		bufferSize: bufferSize,
+		sinks:      make(map[string]*subscription),
+		ctx:        ctx,
+		stop:       stop,
+	}
+}
+
+// AddSink attaches a sink that receives events matching filter, replacing
+// any sink with the same name
+func (b *Bus) AddSink(sink Sink, filter config.EventFilter) {
+	ctx, cancel := context.WithCancel(b.ctx)
+	sub := &subscription{
+		sink:   sink,
+		filter: filter,
+		queue:  make(chan Event, b.bufferSize),
+		cancel: cancel,
+	}
+
+	b.mu.Lock()
+	if existing, exists := b.sinks[sink.Name()]; exists {
+		existing.cancel()
+	}
+	b.sinks[sink.Name()] = sub
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go b.deliver(ctx, sub)
+
+	b.logger.WithField("sink", sink.Name()).Debug("Event sink added")
+}
+
+// RemoveSink detaches a sink; events still queued for it are discarded
+func (b *Bus) RemoveSink(name string) {
+	b.mu.Lock()
+	sub, exists := b.sinks[name]
+	delete(b.sinks, name)
+	b.mu.Unlock()
+
+	if exists {
+		sub.cancel()
+		b.logger.WithField("sink", name).Debug("Event sink removed")
+	}
+}
+
+// Publish queues an event for every sink whose filter matches it. The ID
+// and timestamp are filled in when missing.
+func (b *Bus) Publish(event Event) {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for name, sub := range b.sinks {
+		if !Matches(sub.filter, event) {
+			continue
+		}
+		select {
+		case sub.queue <- event:
+		default:
+			sub.mu.Lock()
+			sub.dropped++
+			sub.mu.Unlock()
+			b.logger.WithFields(logrus.Fields{
+				"sink":  name,
+				"event": event.Type,
+			}).Warn("Event sink queue full, event dropped")
+		}
+	}
+}
+
+// Stats returns delivery statistics for every sink
+func (b *Bus) Stats() []SinkStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := make([]SinkStats, 0, len(b.sinks))
+	for name, sub := range b.sinks {
+		sub.mu.Lock()
+		stats = append(stats, SinkStats{
+			Name:      name,
+			Filter:    sub.filter,
+			Delivered: sub.delivered,
+			Failed:    sub.failed,
+			Dropped:   sub.dropped,
+			Queued:    len(sub.queue),
+		})
+		sub.mu.Unlock()
+	}
+	return stats
+}
+
+// Close stops all sink workers
+func (b *Bus) Close() {
+	b.stop()
+	b.wg.Wait()
+}
+
+// deliver feeds a sink from its queue until it is removed or the bus closes
+func (b *Bus) deliver(ctx context.Context, sub *subscription) {
+	defer b.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-sub.queue:
+			batch := []Event{event}
+			var err error
+			if batcher, ok := sub.sink.(BatchSink); ok {
+				batch = drain(sub.queue, batch, batcher.BatchSize())
+				err = batcher.DeliverBatch(ctx, batch)
+			} else {
+				err = sub.sink.Deliver(ctx, event)
+			}
+
+			sub.mu.Lock()
+			if err != nil {
+				sub.failed += int64(len(batch))
+			} else {
+				sub.delivered += int64(len(batch))
+			}
+			sub.mu.Unlock()
+
+			if err != nil && ctx.Err() == nil {
+				b.logger.WithFields(logrus.Fields{
+					"sink":   sub.sink.Name(),
+					"event":  event.Type,
+					"events": len(batch),
+					"error":  err.Error(),
+				}).Warn("Event delivery failed")
+			}
+		}
+	}
+}
+
+// drain appends already queued events to batch until it holds max events
+func drain(queue chan Event, batch []Event, max int) []Event {
+	for len(batch) < max {
+		select {
+		case event := <-queue:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// Matches reports whether an event passes a filter. Patterns use
+// path.Match syntax, so "obs.*" matches every OBS event type.
+func Matches(filter config.EventFilter, event Event) bool {
+	if matchAny(filter.Exclude, event.Type) {
+		return false
+	}
+	if len(filter.Types) > 0 && !matchAny(filter.Types, event.Type) {
+		return false
+	}
+	if len(filter.Sources) > 0 && !matchAny(filter.Sources, event.Source) {
+		return false
+	}
+	return true
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if pattern == value {
+			return true
+		}
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,360 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// channelSink forwards delivered events to a channel
+type channelSink struct {
+	name   string
+	events chan Event
+}
+
+func newChannelSink(name string) *channelSink {
+	return &channelSink{name: name, events: make(chan Event, 16)}
+}
+
+func (s *channelSink) Name() string {
+	return s.name
+}
+
+func (s *channelSink) Deliver(ctx context.Context, event Event) error {
+	s.events <- event
+	return nil
+}
+
+func (s *channelSink) next(t *testing.T) Event {
+	t.Helper()
+	select {
+	case event := <-s.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("sink %s received no event", s.name)
+		return Event{}
+	}
+}
+
+func (s *channelSink) none(t *testing.T) {
+	t.Helper()
+	select {
+	case event := <-s.events:
+		t.Fatalf("sink %s unexpectedly received %s", s.name, event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMatches(t *testing.T) {
+	event := Event{Type: "obs.SceneChanged", Source: SourceOBS}
+
+	tests := []struct {
+		name   string
+		filter config.EventFilter
+		want   bool
+	}{
+		{"empty filter", config.EventFilter{}, true},
+		{"exact type", config.EventFilter{Types: []string{"obs.SceneChanged"}}, true},
+		{"glob type", config.EventFilter{Types: []string{"obs.*"}}, true},
+		{"other type", config.EventFilter{Types: []string{"module.*"}}, false},
+		{"source", config.EventFilter{Sources: []string{"obs"}}, true},
+		{"other source", config.EventFilter{Sources: []string{"module:*"}}, false},
+		{"excluded", config.EventFilter{Types: []string{"obs.*"}, Exclude: []string{"obs.Scene*"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.filter, event); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBus_FanOutWithFilters(t *testing.T) {
+	bus := NewBus(config.EventsConfig{}, testLogger())
+	defer bus.Close()
+
+	all := newChannelSink("all")
+	obsOnly := newChannelSink("obs")
+	bus.AddSink(all, config.EventFilter{})
+	bus.AddSink(obsOnly, config.EventFilter{Sources: []string{SourceOBS}})
+
+	bus.Publish(Event{Type: "alert.shown", Source: ModuleSource("alerts")})
+	bus.Publish(Event{Type: "obs.SceneChanged", Source: SourceOBS})
+
+	first := all.next(t)
+	if first.ID == "" || first.Timestamp.IsZero() {
+		t.Error("Expected ID and timestamp to be filled in")
+	}
+	if first.Source != "module:alerts" {
+		t.Errorf("Expected module source, got %s", first.Source)
+	}
+	all.next(t)
+
+	if event := obsOnly.next(t); event.Type != "obs.SceneChanged" {
+		t.Errorf("Expected OBS event, got %s", event.Type)
+	}
+	obsOnly.none(t)
+}
+
+func TestBus_SlowSinkDoesNotBlockOthers(t *testing.T) {
+	bus := NewBus(config.EventsConfig{BufferSize: 1}, testLogger())
+	defer bus.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	bus.AddSink(SinkFunc{SinkName: "slow", Func: func(ctx context.Context, event Event) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}}, config.EventFilter{})
+
+	fast := newChannelSink("fast")
+	bus.AddSink(fast, config.EventFilter{})
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(Event{Type: "test"})
+		fast.next(t)
+	}
+
+	var dropped int64
+	for _, stats := range bus.Stats() {
+		if stats.Name == "slow" {
+			dropped = stats.Dropped
+		}
+	}
+	if dropped == 0 {
+		t.Error("Expected events for the slow sink to be dropped")
+	}
+}
+
+func TestBus_RemoveSink(t *testing.T) {
+	bus := NewBus(config.EventsConfig{}, testLogger())
+	defer bus.Close()
+
+	sink := newChannelSink("removed")
+	bus.AddSink(sink, config.EventFilter{})
+	bus.RemoveSink("removed")
+
+	bus.Publish(Event{Type: "test"})
+	sink.none(t)
+
+	if len(bus.Stats()) != 0 {
+		t.Error("Expected no sinks after removal")
+	}
+}
+
+func TestWebhookSink_Deliver(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-WaddleBot-Event") != "test.fired" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var event Event
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink("", server.URL, "", config.WebhookDeliveryConfig{})
+	if sink.Name() != server.URL {
+		t.Errorf("Expected URL as sink name, got %s", sink.Name())
+	}
+
+	err := sink.Deliver(context.Background(), Event{ID: "1", Type: "test.fired", Data: map[string]interface{}{"n": 1}})
+	if err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if event := <-received; event.ID != "1" {
+		t.Errorf("Expected event 1, got %s", event.ID)
+	}
+
+	failing := NewWebhookSink("failing", server.URL, "", config.WebhookDeliveryConfig{})
+	if err := failing.Deliver(context.Background(), Event{Type: "other"}); err == nil {
+		t.Error("Expected error for non-2xx response")
+	}
+}
+
+type deliveryLog struct {
+	deliveries []Delivery
+}
+
+func (l *deliveryLog) RecordDelivery(delivery Delivery) {
+	l.deliveries = append(l.deliveries, delivery)
+}
+
+func TestWebhookSink_SignsAndRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderSignature) != Sign([]byte("secret"), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	log := &deliveryLog{}
+	sink := NewWebhookSink("signed", server.URL, "secret", config.WebhookDeliveryConfig{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+	sink.SetRecorder(log)
+
+	if err := sink.Deliver(context.Background(), Event{ID: "1", Type: "test"}); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if len(log.deliveries) != 1 {
+		t.Fatalf("Expected one recorded delivery, got %d", len(log.deliveries))
+	}
+	if delivery := log.deliveries[0]; !delivery.Success || delivery.Attempts != 3 || delivery.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected delivery %+v", delivery)
+	}
+}
+
+func TestWebhookSink_NoRetryOnClientError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink("rejected", server.URL, "", config.WebhookDeliveryConfig{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+	})
+	delivery, err := sink.Send(context.Background(), Event{Type: "test"})
+	if err == nil || delivery.Success {
+		t.Fatal("Expected delivery to fail")
+	}
+	if atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("Expected a single attempt for a client error, got %d", attempts)
+	}
+}
+
+// batchRecorder is a batch sink that blocks on its first batch until released
+type batchRecorder struct {
+	release chan struct{}
+	batches chan []Event
+}
+
+func (s *batchRecorder) Name() string { return "batch" }
+
+func (s *batchRecorder) Deliver(ctx context.Context, event Event) error {
+	return s.DeliverBatch(ctx, []Event{event})
+}
+
+func (s *batchRecorder) BatchSize() int { return 3 }
+
+func (s *batchRecorder) DeliverBatch(ctx context.Context, events []Event) error {
+	<-s.release
+	s.batches <- events
+	return nil
+}
+
+func TestBus_BatchSink(t *testing.T) {
+	bus := NewBus(config.EventsConfig{}, testLogger())
+	defer bus.Close()
+
+	sink := &batchRecorder{release: make(chan struct{}), batches: make(chan []Event, 8)}
+	bus.AddSink(sink, config.EventFilter{})
+
+	for i := 0; i < 6; i++ {
+		bus.Publish(Event{Type: "test"})
+	}
+	close(sink.release)
+
+	sizes := []int{}
+	total := 0
+	for total < 6 {
+		select {
+		case batch := <-sink.batches:
+			sizes = append(sizes, len(batch))
+			total += len(batch)
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out after %d events", total)
+		}
+	}
+
+	// Events queued behind the blocked first batch are grouped, never
+	// beyond the sink's batch size
+	grouped := false
+	for _, size := range sizes {
+		if size > 3 {
+			t.Errorf("Batch of %d exceeds batch size", size)
+		}
+		if size > 1 {
+			grouped = true
+		}
+	}
+	if !grouped {
+		t.Errorf("Expected queued events to be batched, got sizes %v", sizes)
+	}
+}
+
+// batchingSender records NDJSON uploads
+type batchingSender struct {
+	batching bool
+	single   int
+	lines    [][]byte
+}
+
+func (s *batchingSender) PostJSON(ctx context.Context, path string, payload []byte) error {
+	s.single++
+	return nil
+}
+
+func (s *batchingSender) SupportsBatching() bool { return s.batching }
+
+func (s *batchingSender) PostNDJSON(ctx context.Context, path string, lines [][]byte) error {
+	s.lines = append(s.lines, lines...)
+	return nil
+}
+
+func TestAPISink_DeliverBatch(t *testing.T) {
+	events := []Event{{ID: "1", Type: "a"}, {ID: "2", Type: "b"}}
+
+	sender := &batchingSender{batching: true}
+	if err := NewAPISink(sender, nil, 10).DeliverBatch(context.Background(), events); err != nil {
+		t.Fatalf("DeliverBatch failed: %v", err)
+	}
+	if len(sender.lines) != 2 || sender.single != 0 {
+		t.Errorf("Expected one NDJSON upload of 2 lines, got %d lines and %d single posts", len(sender.lines), sender.single)
+	}
+
+	// Without server support events are posted one at a time
+	sender = &batchingSender{}
+	if err := NewAPISink(sender, nil, 10).DeliverBatch(context.Background(), events); err != nil {
+		t.Fatalf("DeliverBatch failed: %v", err)
+	}
+	if sender.single != 2 {
+		t.Errorf("Expected 2 single posts, got %d", sender.single)
+	}
+}
@@ -0,0 +1,25 @@
+package events
+
+import "waddlebot-bridge/internal/obs"
+
+// OBSSubscriber is the part of the OBS client used to forward its events
+type OBSSubscriber interface {
+	Subscribe(callback obs.EventCallback, eventTypes ...obs.EventType) obs.SubscriptionID
+}
+
+// ForwardOBS publishes every OBS event on the bus as "obs.<event type>",
+// except audio levels, which arrive several times a second and go straight
+// to the gateway's WebSocket clients
+func ForwardOBS(publisher Publisher, client OBSSubscriber) obs.SubscriptionID {
+	return client.Subscribe(func(event obs.Event) {
+		if event.Type == obs.EventAudioLevels {
+			return
+		}
+		publisher.Publish(Event{
+			Type:      "obs." + string(event.Type),
+			Source:    SourceOBS,
+			Timestamp: event.Timestamp,
+			Data:      event.Data,
+		})
+	})
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"waddlebot-bridge/internal/outbox"
+)
+
+// API paths events are posted to
+const (
+	APIEventsPath      = "/api/bridge/events"
+	APIEventsBatchPath = "/api/bridge/events/batch"
+)
+
+// BatchSender posts NDJSON batches once the server has agreed to accept them
+type BatchSender interface {
+	SupportsBatching() bool
+	PostNDJSON(ctx context.Context, path string, lines [][]byte) error
+}
+
+// APISink delivers events to the WaddleBot API. Events that cannot be
+// delivered are handed to the outbox, when one is set, and retried from
+// there.
+type APISink struct {
+	sender    outbox.Sender
+	outbox    *outbox.Outbox
+	batchSize int
+}
+
+// NewAPISink creates a sink posting events through sender. When sender
+// also implements BatchSender, up to batchSize queued events are uploaded
+// together as NDJSON.
+func NewAPISink(sender outbox.Sender, ob *outbox.Outbox, batchSize int) *APISink {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &APISink{sender: sender, outbox: ob, batchSize: batchSize}
+}
+
+// Name returns the sink name
+func (s *APISink) Name() string {
+	return "api"
+}
+
+// Deliver posts an event to the API
+func (s *APISink) Deliver(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if err := s.sender.PostJSON(ctx, APIEventsPath, payload); err != nil {
+		return s.queue(err, event)
+	}
+
+	return nil
+}
+
+// BatchSize returns the largest batch the sink uploads at once
+func (s *APISink) BatchSize() int {
+	return s.batchSize
+}
+
+// DeliverBatch uploads events as one NDJSON batch, or one at a time when
+// the server does not accept batches
+func (s *APISink) DeliverBatch(ctx context.Context, events []Event) error {
+	batcher, ok := s.sender.(BatchSender)
+	if !ok || !batcher.SupportsBatching() || len(events) == 1 {
+		var firstErr error
+		for _, event := range events {
+			if err := s.Deliver(ctx, event); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	lines := make([][]byte, 0, len(events))
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		lines = append(lines, line)
+	}
+
+	if err := batcher.PostNDJSON(ctx, APIEventsBatchPath, lines); err != nil {
+		return s.queue(err, events...)
+	}
+	return nil
+}
+
+// queue hands undelivered events to the outbox. It returns err when there
+// is no outbox or queueing fails.
+func (s *APISink) queue(err error, events ...Event) error {
+	if s.outbox == nil {
+		return err
+	}
+	for _, event := range events {
+		if qErr := s.outbox.Enqueue(outbox.KindEvent, event.ID, APIEventsPath, event); qErr != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SinkFunc adapts a function to the Sink interface
+type SinkFunc struct {
+	SinkName string
+	Func     func(ctx context.Context, event Event) error
+}
+
+// Name returns the sink name
+func (s SinkFunc) Name() string {
+	return s.SinkName
+}
+
+// Deliver calls the function
+func (s SinkFunc) Deliver(ctx context.Context, event Event) error {
+	return s.Func(ctx, event)
+}
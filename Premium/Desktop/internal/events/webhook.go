@@ -0,0 +1,196 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"waddlebot-bridge/internal/config"
+)
+
+// Webhook request headers
+const (
+	HeaderEvent      = "X-WaddleBot-Event"
+	HeaderEventID    = "X-WaddleBot-Event-ID"
+	HeaderDeliveryID = "X-WaddleBot-Delivery"
+	HeaderSignature  = "X-Signature"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// Delivery records the outcome of delivering an event to a webhook
+type Delivery struct {
+	ID         string    `json:"id"`
+	Webhook    string    `json:"webhook"`
+	URL        string    `json:"url"`
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// DeliveryRecorder keeps the outcome of webhook deliveries
+type DeliveryRecorder interface {
+	RecordDelivery(delivery Delivery)
+}
+
+// WebhookSink posts events as JSON to a URL. When a secret is set, each
+// request carries an X-Signature header of the form "sha256=<hex>" holding
+// the HMAC-SHA256 of the body. Failed deliveries are retried with
+// exponential backoff; client errors other than 408 and 429 are not
+// retried.
+type WebhookSink struct {
+	name     string
+	url      string
+	secret   []byte
+	config   config.WebhookDeliveryConfig
+	client   *http.Client
+	recorder DeliveryRecorder
+}
+
+// NewWebhookSink creates a sink posting events to url. The URL doubles as
+// the sink name when name is empty.
+func NewWebhookSink(name, url, secret string, cfg config.WebhookDeliveryConfig) *WebhookSink {
+	if name == "" {
+		name = url
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+
+	sink := &WebhookSink{
+		name:   name,
+		url:    url,
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+	if secret != "" {
+		sink.secret = []byte(secret)
+	}
+	return sink
+}
+
+// SetRecorder sets where the outcome of each delivery is recorded
+func (s *WebhookSink) SetRecorder(recorder DeliveryRecorder) {
+	s.recorder = recorder
+}
+
+// Name returns the sink name
+func (s *WebhookSink) Name() string {
+	return s.name
+}
+
+// Deliver posts an event to the webhook URL, treating any 2xx status as
+// success
+func (s *WebhookSink) Deliver(ctx context.Context, event Event) error {
+	delivery, err := s.Send(ctx, event)
+	if s.recorder != nil {
+		s.recorder.RecordDelivery(delivery)
+	}
+	return err
+}
+
+// Send delivers an event, retrying as configured, and returns the outcome
+// without recording it
+func (s *WebhookSink) Send(ctx context.Context, event Event) (Delivery, error) {
+	delivery := Delivery{
+		ID:        uuid.NewString(),
+		Webhook:   s.name,
+		URL:       s.url,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Time:      time.Now(),
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal event: %w", err)
+		delivery.Error = err.Error()
+		return delivery, err
+	}
+
+	backoff := s.config.InitialBackoff
+	for {
+		delivery.Attempts++
+		status, retry, err := s.post(ctx, delivery.ID, event, payload)
+		delivery.StatusCode = status
+		delivery.DurationMs = time.Since(delivery.Time).Milliseconds()
+
+		if err == nil {
+			delivery.Success = true
+			delivery.Error = ""
+			return delivery, nil
+		}
+		delivery.Error = err.Error()
+
+		if !retry || delivery.Attempts >= s.config.MaxAttempts {
+			return delivery, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return delivery, err
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// post makes a single delivery attempt. It returns the response status and
+// whether a failure is worth retrying.
+func (s *WebhookSink) post(ctx context.Context, deliveryID string, event Event, payload []byte) (int, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderEventID, event.ID)
+	req.Header.Set(HeaderDeliveryID, deliveryID)
+	if s.secret != nil {
+		req.Header.Set(HeaderSignature, Sign(s.secret, payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, ctx.Err() == nil, fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 ||
+			resp.StatusCode == http.StatusRequestTimeout ||
+			resp.StatusCode == http.StatusTooManyRequests
+		return resp.StatusCode, retry, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, false, nil
+}
+
+// Sign returns the X-Signature value for a webhook body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,102 @@
+// Package features decides which capabilities the bridge offers from the
+// tier of its verified subscription. Subsystems ask for their feature when
+// they start, and the gateway lists the features for UIs.
+package features
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/license"
+)
+
+// Feature is a capability granted by a subscription tier
+type Feature string
+
+// Features of the bridge
+const (
+	Macros            Feature = license.FeatureMacros
+	ScriptingLua      Feature = license.FeatureScriptingLua
+	ScriptingExternal Feature = license.FeatureScriptingExternal
+	MultiOBS          Feature = license.FeatureMultiOBS
+	GatewayExternal   Feature = license.FeatureGatewayExternal
+)
+
+// catalog describes every feature, in the order they are listed
+var catalog = []struct {
+	feature     Feature
+	description string
+}{
+	{Macros, "OBS macros in the command palette and over MQTT and WebSocket"},
+	{ScriptingLua, "Lua scripts"},
+	{ScriptingExternal, "Python, Bash and PowerShell scripts"},
+	{MultiOBS, "Connections to more than one OBS instance"},
+	{GatewayExternal, "Local API gateway reachable from other machines"},
+}
+
+// Info describes a feature and whether the subscription includes it
+type Info struct {
+	Name        Feature `json:"name"`
+	Description string  `json:"description"`
+	Enabled     bool    `json:"enabled"`
+}
+
+// Set is the features of the subscription the bridge started with.
+// Subsystems decide once, at startup, so a subscription verified or
+// upgraded while the bridge runs takes effect at the next restart.
+type Set struct {
+	tier    string
+	enabled map[Feature]bool
+	logger  *logrus.Logger
+}
+
+// New returns the features of entitlement, or none without one
+func New(entitlement *license.Entitlement, logger *logrus.Logger) *Set {
+	s := &Set{
+		enabled: make(map[Feature]bool),
+		logger:  logger,
+	}
+	if entitlement != nil {
+		s.tier = entitlement.Tier
+		for _, feature := range entitlement.AllFeatures() {
+			s.enabled[Feature(feature)] = true
+		}
+	}
+	return s
+}
+
+// Enabled reports whether the subscription includes feature
+func (s *Set) Enabled(feature Feature) bool {
+	return s.enabled[feature]
+}
+
+// Require reports whether the subscription includes feature, logging that
+// setting is ignored when it does not
+func (s *Set) Require(feature Feature, setting string) bool {
+	if s.Enabled(feature) {
+		return true
+	}
+	s.logger.WithFields(logrus.Fields{
+		"feature": feature,
+		"setting": setting,
+		"tier":    s.Tier(),
+	}).Warn("Setting ignored; the subscription does not include this feature")
+	return false
+}
+
+// Tier returns the subscription tier, empty when it was not verified
+func (s *Set) Tier() string {
+	return s.tier
+}
+
+// List describes every feature of the bridge
+func (s *Set) List() []Info {
+	infos := make([]Info, len(catalog))
+	for i, entry := range catalog {
+		infos[i] = Info{
+			Name:        entry.feature,
+			Description: entry.description,
+			Enabled:     s.Enabled(entry.feature),
+		}
+	}
+	return infos
+}
@@ -0,0 +1,65 @@
+package features
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/license"
+)
+
+func TestSet(t *testing.T) {
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+
+	set := New(&license.Entitlement{Tier: license.TierPremium, Features: []string{license.FeatureMultiOBS}}, logger)
+	if set.Tier() != license.TierPremium {
+		t.Errorf("Expected the premium tier, got %q", set.Tier())
+	}
+	for feature, want := range map[Feature]bool{
+		Macros:            true,
+		ScriptingLua:      true,
+		MultiOBS:          true,
+		ScriptingExternal: false,
+		GatewayExternal:   false,
+	} {
+		if got := set.Enabled(feature); got != want {
+			t.Errorf("Enabled(%s) = %v, want %v", feature, got, want)
+		}
+	}
+
+	if !set.Require(Macros, "obs.macros") || logs.Len() != 0 {
+		t.Errorf("Expected an included feature to pass quietly, logged %q", logs.String())
+	}
+	if set.Require(GatewayExternal, "gateway.host") || !strings.Contains(logs.String(), "gateway.host") {
+		t.Errorf("Expected the ignored setting to be logged, logged %q", logs.String())
+	}
+
+	list := set.List()
+	if len(list) != len(catalog) {
+		t.Fatalf("Expected every feature listed, got %v", list)
+	}
+	for _, info := range list {
+		if info.Description == "" || info.Enabled != set.Enabled(info.Name) {
+			t.Errorf("Unexpected feature info %+v", info)
+		}
+	}
+}
+
+func TestSet_Unverified(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(&bytes.Buffer{})
+
+	set := New(nil, logger)
+	if set.Tier() != "" {
+		t.Errorf("Expected no tier, got %q", set.Tier())
+	}
+	for _, info := range set.List() {
+		if info.Enabled {
+			t.Errorf("Expected no features without a subscription, got %s", info.Name)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package client talks to the local API gateway of a running bridge, for
+// the command line and automation
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"waddlebot-bridge/internal/commands"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/gateway/handlers"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/obs"
+)
+
+// defaultTimeout bounds each request; scripts that run longer are reported
+// as failed here even though the bridge finishes them
+const defaultTimeout = 60 * time.Second
+
+// Error is an error answered by the gateway
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("gateway returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("gateway returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the gateway's API
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// New creates a client for the gateway at baseURL, such as
+// http://127.0.0.1:8090. A nil tlsConfig uses the system roots.
+func New(baseURL, apiKey string, tlsConfig *tls.Config) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http: &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+}
+
+// FromConfig creates a client for the gateway configured in cfg. Without a
+// static API key it uses the admin key the bridge generated in the data
+// directory, and with TLS it trusts the gateway's certificate.
+func FromConfig(cfg *config.Config) (*Client, error) {
+	if !cfg.Gateway.Enabled {
+		return nil, fmt.Errorf("the gateway is disabled; set gateway.enabled to control the bridge")
+	}
+
+	apiKey := cfg.Gateway.APIKey
+	if apiKey == "" && cfg.Gateway.EnableAuth {
+		data, err := os.ReadFile(filepath.Join(cfg.DataDir, "gateway-admin.key"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read gateway admin key: %w", err)
+		}
+		apiKey = strings.TrimSpace(string(data))
+	}
+
+	scheme := "http"
+	var tlsConfig *tls.Config
+	if cfg.Gateway.TLS.Enabled {
+		scheme = "https"
+		certFile := cfg.Gateway.TLS.CertFile
+		if certFile == "" {
+			certFile = filepath.Join(cfg.DataDir, "tls", "gateway.crt")
+		}
+		pem, err := os.ReadFile(certFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gateway certificate: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		roots.AppendCertsFromPEM(pem)
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots}
+	}
+
+	host := cfg.Gateway.Host
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	baseURL := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(cfg.Gateway.Port))
+
+	return New(baseURL, apiKey, tlsConfig), nil
+}
+
+// Status returns the bridge's status
+func (c *Client) Status(ctx context.Context) (*handlers.BridgeStatus, error) {
+	var status handlers.BridgeStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/bridge/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// OBSStatus returns the state of the bridge's OBS connection
+func (c *Client) OBSStatus(ctx context.Context) (map[string]interface{}, error) {
+	var status map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/obs/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Scenes returns the OBS scenes
+func (c *Client) Scenes(ctx context.Context) ([]obs.SceneInfo, error) {
+	var response struct {
+		Scenes []obs.SceneInfo `json:"scenes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/obs/scenes", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Scenes, nil
+}
+
+// SwitchScene switches OBS to the named scene
+func (c *Client) SwitchScene(ctx context.Context, name string) error {
+	request := handlers.SwitchSceneRequest{SceneName: name}
+	return c.do(ctx, http.MethodPost, "/api/v1/obs/scenes/switch", request, nil)
+}
+
+// Modules returns the loaded modules
+func (c *Client) Modules(ctx context.Context) ([]models.ModuleInfo, error) {
+	var response struct {
+		Modules []models.ModuleInfo `json:"modules"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/modules", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Modules, nil
+}
+
+// Commands returns the command palette's commands of kind, or all of them
+// when kind is empty
+func (c *Client) Commands(ctx context.Context, kind commands.Kind) ([]commands.Command, error) {
+	path := "/api/v1/commands"
+	if kind != "" {
+		path += "?kind=" + url.QueryEscape(string(kind))
+	}
+	var response struct {
+		Commands []commands.Command `json:"commands"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Commands, nil
+}
+
+// Execute runs a command palette command, such as "script:intro.lua", and
+// returns its result
+func (c *Client) Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error) {
+	request := handlers.ExecuteCommandRequest{ID: id, Parameters: parameters}
+	var response struct {
+		Result map[string]interface{} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/commands/execute", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Result, nil
+}
+
+// do sends a request with body encoded as JSON and decodes the response
+// into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the gateway at %s (is the bridge running?): %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errResp handlers.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		message := errResp.Message
+		if message == "" {
+			message = errResp.Error
+		}
+		return &Error{StatusCode: resp.StatusCode, Code: errResp.Code, Message: message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode gateway response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/gateway/handlers"
+)
+
+func TestClient(t *testing.T) {
+	var switched string
+	var executed handlers.ExecuteCommandRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(handlers.ErrorResponse{Error: "invalid API key", Code: "unauthorized"})
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/bridge/status":
+			json.NewEncoder(w).Encode(handlers.BridgeStatus{Status: "healthy", Version: "1.2.3"})
+		case "POST /api/v1/obs/scenes/switch":
+			var req handlers.SwitchSceneRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			switched = req.SceneName
+			json.NewEncoder(w).Encode(handlers.SuccessResponse{Success: true})
+		case "POST /api/v1/commands/execute":
+			json.NewDecoder(r.Body).Decode(&executed)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"result":  map[string]interface{}{"output": "hello"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(handlers.ErrorResponse{Error: "not found", Code: "not_found"})
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := New(server.URL, "key", nil)
+
+	status, err := c.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "healthy" || status.Version != "1.2.3" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	if err := c.SwitchScene(ctx, "Live"); err != nil {
+		t.Fatalf("SwitchScene failed: %v", err)
+	}
+	if switched != "Live" {
+		t.Errorf("Expected the gateway to switch to Live, got %q", switched)
+	}
+
+	result, err := c.Execute(ctx, "script:intro.lua", map[string]string{"name": "penguin"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["output"] != "hello" || executed.ID != "script:intro.lua" || executed.Parameters["name"] != "penguin" {
+		t.Errorf("Unexpected execution: %+v -> %v", executed, result)
+	}
+
+	_, err = c.Modules(ctx)
+	var gatewayErr *Error
+	if !errors.As(err, &gatewayErr) || gatewayErr.StatusCode != http.StatusNotFound || gatewayErr.Code != "not_found" {
+		t.Errorf("Expected a not found gateway error, got %v", err)
+	}
+
+	if _, err := New(server.URL, "wrong", nil).Status(ctx); !errors.As(err, &gatewayErr) || gatewayErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthorized gateway error, got %v", err)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "gateway-admin.key"), []byte("admin\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		DataDir: dir,
+		Gateway: config.GatewayConfig{Enabled: true, Host: "0.0.0.0", Port: 8090, EnableAuth: true},
+	}
+	c, err := FromConfig(cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	if c.baseURL != "http://127.0.0.1:8090" {
+		t.Errorf("Expected the loopback address for a wildcard host, got %s", c.baseURL)
+	}
+	if c.apiKey != "admin" {
+		t.Errorf("Expected the generated admin key, got %q", c.apiKey)
+	}
+
+	cfg.Gateway.Enabled = false
+	if _, err := FromConfig(cfg); err == nil {
+		t.Error("Expected an error when the gateway is disabled")
+	}
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/timers"
+)
+
+// AudioLevelsTopic is the WebSocket topic of OBS audio levels
+const AudioLevelsTopic = "obs.audio_levels"
+
+// TimersTopic is the WebSocket topic of timer values
+const TimersTopic = "timers.tick"
+
+// TimerFeed reports timer values as they change
+type TimerFeed interface {
+	Subscribe(fn func([]timers.Status))
+}
+
+// EventSink returns an event bus sink that broadcasts events to all
+// WebSocket clients
+func (g *Gateway) EventSink() events.Sink {
+	return events.SinkFunc{
+		SinkName: "websocket",
+		Func: func(ctx context.Context, event events.Event) error {
+			g.wsHub.Broadcast(WSMessage{
+				Type:      event.Type,
+				Data:      event,
+				Timestamp: event.Timestamp.Unix(),
+			})
+			return nil
+		},
+	}
+}
+
+// forwardAudioLevels sends OBS audio levels to WebSocket clients
+// subscribed to AudioLevelsTopic. They arrive several times a second, so
+// they skip the event bus and only reach clients that ask for them.
+func (g *Gateway) forwardAudioLevels() {
+	g.obsClient.Subscribe(func(event obs.Event) {
+		g.wsHub.Broadcast(WSMessage{
+			Type:           AudioLevelsTopic,
+			Data:           event.Data,
+			Timestamp:      event.Timestamp.Unix(),
+			subscribedOnly: true,
+		})
+	}, obs.EventAudioLevels)
+}
+
+// forwardTimers sends timer values to WebSocket clients subscribed to
+// TimersTopic as the shown value of each changes, so overlays can show a
+// countdown without polling
+func (g *Gateway) forwardTimers(feed TimerFeed) {
+	feed.Subscribe(func(statuses []timers.Status) {
+		g.wsHub.Broadcast(WSMessage{
+			Type:           TimersTopic,
+			Data:           map[string]interface{}{"timers": statuses},
+			Timestamp:      time.Now().Unix(),
+			subscribedOnly: true,
+		})
+	})
+}
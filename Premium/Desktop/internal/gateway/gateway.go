@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"sync"
@@ -12,31 +13,144 @@ import (
 	"golang.org/x/time/rate"
 
 	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/gateway/handlers"
+	"waddlebot-bridge/internal/models"
 	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/supervisor"
 )
 
+// SessionValidator validates the session tokens of users signed in to the
+// bridge with WebAuthn
+type SessionValidator interface {
+	ValidateJWT(token string) (*models.AuthSession, error)
+}
+
 // Gateway represents the local API gateway server
 type Gateway struct {
-	config        config.GatewayConfig
-	server        *http.Server
-	router        *mux.Router
-	obsClient     *obs.Client
-	logger        *logrus.Logger
-	rateLimiters  map[string]*rate.Limiter
-	limiterMux    sync.RWMutex
-	wsHub         *WebSocketHub
-	running       bool
-	runningMux    sync.RWMutex
+	config         config.GatewayConfig
+	server         *http.Server
+	tlsConfig      *tls.Config
+	router         *mux.Router
+	obsClient      *obs.Client
+	moduleExecutor handlers.ModuleExecutor
+	outboxQueue    handlers.OutboxQueue
+	policy         handlers.PolicyProvider
+	webhooks       handlers.WebhookRegistry
+	commands       handlers.CommandCatalog
+	apiKeys        handlers.APIKeyStore
+	sessions       SessionValidator
+	signingKeys    handlers.SigningKeyRotator
+	profiles       handlers.ProfileSwitcher
+	features       handlers.FeatureProvider
+	rules          handlers.RuleStore
+	schedules      handlers.ScheduleStore
+	triggers       handlers.TriggerStore
+	textSources    handlers.TextSourceStore
+	timers         handlers.TimerStore
+	rtc            handlers.RTCClient
+	bridge         handlers.BridgeSources
+	adminKey       string
+	logger         *logrus.Logger
+	routeScopes    map[*mux.Route]string
+	rateLimiters   map[string]*rate.Limiter
+	limiterMux     sync.RWMutex
+	limitsMux      sync.RWMutex // guards the settings SetLimits changes
+	wsHub          *WebSocketHub
+	hubOnce        sync.Once // the hub runs once, across restarts of the gateway
+	running        bool
+	runningMux     sync.RWMutex
+}
+
+// Services holds the bridge components exposed through the gateway. Any
+// of them may be nil, in which case their endpoints report unavailable.
+type Services struct {
+	OBS      *obs.Client
+	Modules  handlers.ModuleExecutor
+	Outbox   handlers.OutboxQueue
+	Policy   handlers.PolicyProvider
+	Webhooks handlers.WebhookRegistry
+	Commands handlers.CommandCatalog
+	APIKeys  handlers.APIKeyStore
+	Bridge   handlers.BridgeClient
+	Poller   handlers.TaskPoller
+	Sessions SessionValidator
+	Storage  handlers.StorageMonitor
+	Profiles handlers.ProfileSwitcher
+	Features handlers.FeatureProvider
+	Rules    handlers.RuleStore
+
+	// Schedules manages the source visibility schedules
+	Schedules handlers.ScheduleStore
+
+	// Triggers manages the triggers and tries them against synthetic
+	// events
+	Triggers handlers.TriggerStore
+
+	// TextSources manages the text sources and their variables
+	TextSources handlers.TextSourceStore
+
+	// Timers manages the countdowns and stopwatches; values are sent to
+	// WebSocket clients when it is also a TimerFeed
+	Timers handlers.TimerStore
+
+	// RTC reaches the community's calls as the bridge's user
+	RTC handlers.RTCClient
+
+	// SigningKeys rotates the keys session tokens are signed with
+	SigningKeys handlers.SigningKeyRotator
+
+	// Version and StartedAt are reported by the bridge status endpoints
+	Version   string
+	StartedAt time.Time
 }
 
 // New creates a new Gateway instance
-func New(cfg config.GatewayConfig, obsClient *obs.Client, logger *logrus.Logger) *Gateway {
+func New(cfg config.GatewayConfig, services Services, logger *logrus.Logger) *Gateway {
 	g := &Gateway{
-		config:       cfg,
-		obsClient:    obsClient,
-		logger:       logger,
-		rateLimiters: make(map[string]*rate.Limiter),
-		wsHub:        NewWebSocketHub(logger),
+		config:         cfg,
+		obsClient:      services.OBS,
+		moduleExecutor: services.Modules,
+		outboxQueue:    services.Outbox,
+		policy:         services.Policy,
+		webhooks:       services.Webhooks,
+		commands:       services.Commands,
+		apiKeys:        services.APIKeys,
+		sessions:       services.Sessions,
+		signingKeys:    services.SigningKeys,
+		profiles:       services.Profiles,
+		features:       services.Features,
+		rules:          services.Rules,
+		schedules:      services.Schedules,
+		triggers:       services.Triggers,
+		textSources:    services.TextSources,
+		timers:         services.Timers,
+		rtc:            services.RTC,
+		adminKey:       cfg.APIKey,
+		logger:         logger,
+		routeScopes:    make(map[*mux.Route]string),
+		rateLimiters:   make(map[string]*rate.Limiter),
+		wsHub:          NewWebSocketHub(logger),
+	}
+
+	g.wsHub.commands = services.Commands
+
+	g.bridge = handlers.BridgeSources{
+		Version:   services.Version,
+		StartedAt: services.StartedAt,
+		Client:    services.Bridge,
+		Poller:    services.Poller,
+		Outbox:    services.Outbox,
+		Storage:   services.Storage,
+	}
+	if services.OBS != nil {
+		g.bridge.OBS = services.OBS
+		g.forwardAudioLevels()
+	}
+	if feed, ok := services.Timers.(TimerFeed); ok {
+		g.forwardTimers(feed)
+	}
+	if health, ok := services.Modules.(handlers.ModuleHealthSource); ok {
+		g.bridge.Modules = health
 	}
 
 	g.setupRouter()
@@ -49,18 +163,51 @@ func (g *Gateway) setupRouter() {
 
 	// Apply global middleware
 	g.router.Use(g.loggingMiddleware)
+	if g.config.EnableMetrics {
+		g.router.Use(g.metricsMiddleware)
+	}
 	if g.config.EnableAuth {
 		g.router.Use(g.authMiddleware)
 	}
 	g.router.Use(g.rateLimitMiddleware)
-	if g.config.EnableCORS {
-		g.router.Use(g.corsMiddleware)
-	}
+	g.router.Use(g.bodyLimitMiddleware)
+	g.router.Use(g.corsMiddleware)
 
 	// Register all routes
 	RegisterRoutes(g)
 }
 
+// SetLimits applies new CORS and rate limit settings to the running
+// gateway. Clients get a limiter with the new rate on their next request.
+func (g *Gateway) SetLimits(cfg config.GatewayConfig) {
+	g.limitsMux.Lock()
+	g.config.EnableCORS = cfg.EnableCORS
+	g.config.AllowedOrigins = cfg.AllowedOrigins
+	g.config.RateLimitRPS = cfg.RateLimitRPS
+	g.limitsMux.Unlock()
+
+	g.limiterMux.Lock()
+	g.rateLimiters = make(map[string]*rate.Limiter)
+	g.limiterMux.Unlock()
+
+	g.logger.WithFields(logrus.Fields{
+		"cors":           cfg.EnableCORS,
+		"rate_limit_rps": cfg.RateLimitRPS,
+	}).Info("Updated gateway limits")
+}
+
+// SetAdminKey sets the secret that authenticates as the admin key when no
+// static api-key is configured. It must be called before Start.
+func (g *Gateway) SetAdminKey(secret string) {
+	g.adminKey = secret
+}
+
+// SetTLSConfig serves the gateway over TLS with the given configuration.
+// It must be called before Start.
+func (g *Gateway) SetTLSConfig(tlsConfig *tls.Config) {
+	g.tlsConfig = tlsConfig
+}
+
 // Start starts the gateway server
 func (g *Gateway) Start(ctx context.Context) error {
 	g.runningMux.Lock()
@@ -71,8 +218,26 @@ func (g *Gateway) Start(ctx context.Context) error {
 	g.running = true
 	g.runningMux.Unlock()
 
+	// A panic leaves the gateway stopped, so it can be started again
+	defer func() {
+		if r := recover(); r != nil {
+			if g.server != nil {
+				g.server.Close()
+			}
+			g.runningMux.Lock()
+			g.running = false
+			g.runningMux.Unlock()
+			panic(r)
+		}
+	}()
+
 	// Start WebSocket hub
-	go g.wsHub.Run()
+	g.hubOnce.Do(func() {
+		go supervisor.Run(context.Background(), "gateway.websocket", supervisor.DefaultPolicy, func(context.Context) error {
+			g.wsHub.Run()
+			return nil
+		})
+	})
 
 	// Create HTTP server
 	addr := fmt.Sprintf("%s:%d", g.config.Host, g.config.Port)
@@ -82,6 +247,7 @@ func (g *Gateway) Start(ctx context.Context) error {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		TLSConfig:    g.tlsConfig,
 	}
 
 	g.logger.WithFields(logrus.Fields{
@@ -89,12 +255,19 @@ func (g *Gateway) Start(ctx context.Context) error {
 		"port": g.config.Port,
 		"auth": g.config.EnableAuth,
 		"cors": g.config.EnableCORS,
+		"tls":  g.tlsConfig != nil,
 	}).Info("Starting local API gateway")
 
 	// Start server in goroutine
 	errChan := make(chan error, 1)
 	go func() {
-		if err := g.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if g.tlsConfig != nil {
+			err = g.server.ListenAndServeTLS("", "")
+		} else {
+			err = g.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+)
+
+// APIKeyStore stores the gateway's API keys
+type APIKeyStore interface {
+	List() []apikeys.Key
+	Create(name string, scopes []string) (apikeys.Key, string, error)
+	Rotate(id string) (apikeys.Key, string, error)
+	Revoke(id string) error
+	Authenticate(secret string) (apikeys.Key, error)
+}
+
+// APIKeysHandler handles API key management endpoints
+type APIKeysHandler struct {
+	store  APIKeyStore
+	logger *logrus.Logger
+}
+
+// NewAPIKeysHandler creates a new API keys handler
+func NewAPIKeysHandler(store APIKeyStore, logger *logrus.Logger) *APIKeysHandler {
+	return &APIKeysHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// CreateKeyRequest represents an API key creation request
+type CreateKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// KeyWithSecret is a key returned with its secret, which is only shown
+// when the key is created or rotated
+type KeyWithSecret struct {
+	apikeys.Key
+	Secret string `json:"secret"`
+}
+
+// ListKeys returns all API keys without their secrets
+func (h *APIKeysHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "API keys not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	keys := h.store.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys":   keys,
+		"count":  len(keys),
+		"scopes": apikeys.Scopes,
+	})
+}
+
+// CreateKey creates an API key and returns its secret
+func (h *APIKeysHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "API keys not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req CreateKeyRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	if err := Required("name", req.Name); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	key, secret, err := h.store.Create(req.Name, req.Scopes)
+	if err != nil {
+		h.sendError(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":     key.ID,
+		"name":   key.Name,
+		"scopes": key.Scopes,
+	}).Info("API key created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(KeyWithSecret{Key: key, Secret: secret})
+}
+
+// RotateKey replaces the secret of an API key and returns the new one
+func (h *APIKeysHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "API keys not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	key, secret, err := h.store.Rotate(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendError(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	h.logger.WithField("id", key.ID).Info("API key rotated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(KeyWithSecret{Key: key, Secret: secret})
+}
+
+// RevokeKey deletes an API key
+func (h *APIKeysHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "API keys not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := h.store.Revoke(id); err != nil {
+		h.sendError(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	h.logger.WithField("id", id).Info("API key revoked")
+
+	h.sendSuccess(w, "API key revoked")
+}
+
+// apiKeyErrorStatus maps API key errors to HTTP status codes
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, apikeys.ErrKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apikeys.ErrInvalidScope):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Helper methods
+
+func (h *APIKeysHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *APIKeysHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *APIKeysHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
@@ -2,40 +2,108 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/modules"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/poller"
+	"waddlebot-bridge/internal/storage"
 )
 
+// staleIntervals is how many heartbeat or poll intervals may pass without
+// one succeeding before the bridge is reported degraded
+const staleIntervals = 3
+
+// BridgeClient reports and controls the bridge's connection to the API
+type BridgeClient interface {
+	IsAuthenticated() bool
+	IsRegistered() bool
+	LastHeartbeat() time.Time
+	HeartbeatInterval() time.Duration
+	Reconnect()
+}
+
+// TaskPoller reports how tasks are received from the API
+type TaskPoller interface {
+	LastPoll() time.Time
+	PollInterval() time.Duration
+	Transport() string
+	PendingResults() int
+}
+
+// OBSConnection reports the state of the OBS connection
+type OBSConnection interface {
+	GetState() obs.ConnectionState
+}
+
+// ModuleHealthSource reports the health of loaded modules
+type ModuleHealthSource interface {
+	GetModuleHealth() []modules.ModuleHealth
+}
+
+// StorageMonitor reports how much space storage takes
+type StorageMonitor interface {
+	Usage() (storage.Usage, error)
+	LowDisk() bool
+}
+
+// BridgeSources are the components the bridge status is read from. Any of
+// them may be nil, in which case they are left out of the status.
+type BridgeSources struct {
+	Version   string
+	StartedAt time.Time
+	Client    BridgeClient
+	Poller    TaskPoller
+	OBS       OBSConnection
+	Modules   ModuleHealthSource
+	Outbox    OutboxQueue
+	Storage   StorageMonitor
+}
+
 // BridgeHandler handles bridge-related endpoints
 type BridgeHandler struct {
-	logger *logrus.Logger
+	sources BridgeSources
+	logger  *logrus.Logger
 }
 
 // NewBridgeHandler creates a new bridge handler
-func NewBridgeHandler(logger *logrus.Logger) *BridgeHandler {
+func NewBridgeHandler(sources BridgeSources, logger *logrus.Logger) *BridgeHandler {
+	if sources.StartedAt.IsZero() {
+		sources.StartedAt = time.Now()
+	}
 	return &BridgeHandler{
-		logger: logger,
+		sources: sources,
+		logger:  logger,
 	}
 }
 
-// BridgeStatus represents bridge status information
+// BridgeStatus represents bridge status information. Status is "running",
+// or "degraded" when Issues lists a problem.
 type BridgeStatus struct {
-	Status    string `json:"status"`
-	Version   string `json:"version"`
-	Uptime    int64  `json:"uptime"`
-	Connected bool   `json:"connected"`
+	Status           string         `json:"status"`
+	Version          string         `json:"version"`
+	Uptime           int64          `json:"uptime"`
+	StartedAt        time.Time      `json:"started_at"`
+	Connected        bool           `json:"connected"`
+	Registered       bool           `json:"registered"`
+	LastHeartbeat    *time.Time     `json:"last_heartbeat,omitempty"`
+	LastPoll         *time.Time     `json:"last_poll,omitempty"`
+	Transport        string         `json:"transport,omitempty"`
+	OBS              string         `json:"obs,omitempty"`
+	UnhealthyModules []string       `json:"unhealthy_modules,omitempty"`
+	Queues           map[string]int `json:"queues"`
+	Issues           []string       `json:"issues,omitempty"`
 }
 
 // GetStatus returns the current bridge status
 func (h *BridgeHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
-	status := BridgeStatus{
-		Status:    "running",
-		Version:   "1.0.0", // TODO: Get from config
-		Uptime:    int64(time.Since(time.Now()).Seconds()),
-		Connected: true, // TODO: Get from bridge client
-	}
+	status, _ := h.check(time.Now())
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
@@ -46,20 +114,25 @@ type HealthResponse struct {
 	Healthy   bool              `json:"healthy"`
 	Timestamp int64             `json:"timestamp"`
 	Services  map[string]string `json:"services"`
+	Issues    []string          `json:"issues,omitempty"`
 }
 
-// GetHealth returns health check information
+// GetHealth returns the state of each component, answering 503 while the
+// bridge is degraded
 func (h *BridgeHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	status, services := h.check(now)
 	health := HealthResponse{
-		Healthy:   true,
-		Timestamp: time.Now().Unix(),
-		Services: map[string]string{
-			"gateway": "ok",
-			"bridge":  "ok",
-		},
+		Healthy:   len(status.Issues) == 0,
+		Timestamp: now.Unix(),
+		Services:  services,
+		Issues:    status.Issues,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if !health.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
 	json.NewEncoder(w).Encode(health)
 }
 
@@ -69,9 +142,15 @@ type ReconnectResponse struct {
 	Message string `json:"message"`
 }
 
-// Reconnect forces a bridge reconnection
+// Reconnect registers the bridge with the API again and sends a heartbeat
+// right away
 func (h *BridgeHandler) Reconnect(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement bridge reconnection logic
+	if h.sources.Client == nil {
+		h.sendError(w, "bridge client not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.sources.Client.Reconnect()
 
 	response := ReconnectResponse{
 		Success: true,
@@ -83,3 +162,142 @@ func (h *BridgeHandler) Reconnect(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Info("Bridge reconnection requested")
 }
+
+// GetStorage returns the size of storage, its buckets and the free disk
+// space in the data directory
+func (h *BridgeHandler) GetStorage(w http.ResponseWriter, r *http.Request) {
+	if h.sources.Storage == nil {
+		h.sendError(w, "storage monitor not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	usage, err := h.sources.Storage.Usage()
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// check reads the status of every component and the state of each service
+// for the health check
+func (h *BridgeHandler) check(now time.Time) (BridgeStatus, map[string]string) {
+	uptime := now.Sub(h.sources.StartedAt)
+	status := BridgeStatus{
+		Status:    "running",
+		Version:   h.sources.Version,
+		Uptime:    int64(uptime.Seconds()),
+		StartedAt: h.sources.StartedAt,
+		Queues:    map[string]int{},
+	}
+	services := map[string]string{"gateway": "ok"}
+
+	if client := h.sources.Client; client != nil {
+		status.Registered = client.IsRegistered()
+		last := client.LastHeartbeat()
+		if !last.IsZero() {
+			status.LastHeartbeat = &last
+		}
+
+		switch {
+		case !client.IsAuthenticated():
+			services["api"] = "unauthenticated"
+			status.Issues = append(status.Issues, "bridge is not authenticated")
+		case !status.Registered:
+			services["api"] = "unregistered"
+			status.Issues = append(status.Issues, "bridge is not registered with the API")
+		case stale(last, client.HeartbeatInterval(), uptime, now):
+			services["api"] = "stale"
+			status.Issues = append(status.Issues, fmt.Sprintf("no heartbeat accepted for %s", since(last, h.sources.StartedAt, now)))
+		default:
+			services["api"] = "ok"
+			status.Connected = true
+		}
+	}
+
+	if taskPoller := h.sources.Poller; taskPoller != nil {
+		status.Transport = taskPoller.Transport()
+		status.Queues["pending_results"] = taskPoller.PendingResults()
+		last := taskPoller.LastPoll()
+		if !last.IsZero() {
+			status.LastPoll = &last
+		}
+
+		// Tasks pushed over the stream need no polling
+		if status.Transport == poller.TransportPolling && stale(last, taskPoller.PollInterval(), uptime, now) {
+			services["tasks"] = "stale"
+			status.Issues = append(status.Issues, fmt.Sprintf("no successful poll for %s", since(last, h.sources.StartedAt, now)))
+		} else {
+			services["tasks"] = "ok"
+		}
+	}
+
+	if h.sources.Outbox != nil {
+		if stats, err := h.sources.Outbox.Stats(); err == nil {
+			status.Queues["outbox"] = stats.Total
+		}
+	}
+
+	if h.sources.OBS != nil {
+		state := h.sources.OBS.GetState()
+		status.OBS = state.String()
+		services["obs"] = state.String()
+		if state != obs.StateConnected {
+			status.Issues = append(status.Issues, "OBS is "+state.String())
+		}
+	}
+
+	if h.sources.Modules != nil {
+		for _, module := range h.sources.Modules.GetModuleHealth() {
+			if module.Enabled && !module.Healthy {
+				status.UnhealthyModules = append(status.UnhealthyModules, module.Name)
+			}
+		}
+		sort.Strings(status.UnhealthyModules)
+		services["modules"] = "ok"
+		if count := len(status.UnhealthyModules); count > 0 {
+			services["modules"] = fmt.Sprintf("%d unhealthy", count)
+			status.Issues = append(status.Issues, fmt.Sprintf("%d unhealthy modules", count))
+		}
+	}
+
+	if h.sources.Storage != nil {
+		services["storage"] = "ok"
+		if h.sources.Storage.LowDisk() {
+			services["storage"] = "low disk space"
+			status.Issues = append(status.Issues, "disk space is low in the data directory")
+		}
+	}
+
+	if len(status.Issues) > 0 {
+		status.Status = "degraded"
+	}
+	return status, services
+}
+
+// stale reports whether more than staleIntervals intervals have passed
+// since last. Before the first success the bridge gets the same grace
+// period from startup.
+func stale(last time.Time, interval, uptime time.Duration, now time.Time) bool {
+	limit := staleIntervals * interval
+	if last.IsZero() {
+		return uptime > limit
+	}
+	return now.Sub(last) > limit
+}
+
+// since returns how long ago last was, or startup if it never happened
+func since(last, startedAt time.Time, now time.Time) time.Duration {
+	if last.IsZero() {
+		last = startedAt
+	}
+	return now.Sub(last).Round(time.Second)
+}
+
+// Helper methods
+
+func (h *BridgeHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
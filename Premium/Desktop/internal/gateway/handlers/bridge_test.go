@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/modules"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/poller"
+	"waddlebot-bridge/internal/storage"
+)
+
+type fakeBridgeClient struct {
+	registered    bool
+	lastHeartbeat time.Time
+	reconnects    int
+}
+
+func (f *fakeBridgeClient) IsAuthenticated() bool            { return true }
+func (f *fakeBridgeClient) IsRegistered() bool               { return f.registered }
+func (f *fakeBridgeClient) LastHeartbeat() time.Time         { return f.lastHeartbeat }
+func (f *fakeBridgeClient) HeartbeatInterval() time.Duration { return 30 * time.Second }
+func (f *fakeBridgeClient) Reconnect()                       { f.reconnects++ }
+
+type fakePoller struct {
+	lastPoll  time.Time
+	transport string
+}
+
+func (f fakePoller) LastPoll() time.Time         { return f.lastPoll }
+func (f fakePoller) PollInterval() time.Duration { return 10 * time.Second }
+func (f fakePoller) Transport() string           { return f.transport }
+func (f fakePoller) PendingResults() int         { return 2 }
+
+type fakeOBSState obs.ConnectionState
+
+func (f fakeOBSState) GetState() obs.ConnectionState { return obs.ConnectionState(f) }
+
+type fakeModuleHealth []modules.ModuleHealth
+
+func (f fakeModuleHealth) GetModuleHealth() []modules.ModuleHealth { return f }
+
+type fakeStorage struct{ usage storage.Usage }
+
+func (f fakeStorage) Usage() (storage.Usage, error) { return f.usage, nil }
+func (f fakeStorage) LowDisk() bool                 { return f.usage.LowDisk }
+
+func newTestBridgeHandler(sources BridgeSources) *BridgeHandler {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewBridgeHandler(sources, logger)
+}
+
+func TestBridgeHandler_Status(t *testing.T) {
+	now := time.Now()
+	h := newTestBridgeHandler(BridgeSources{
+		Version:   "2.1.0",
+		StartedAt: now.Add(-time.Hour),
+		Client:    &fakeBridgeClient{registered: true, lastHeartbeat: now.Add(-10 * time.Second)},
+		Poller:    fakePoller{lastPoll: now.Add(-5 * time.Second), transport: poller.TransportPolling},
+		OBS:       fakeOBSState(obs.StateConnected),
+		Modules:   fakeModuleHealth{{Name: "soundboard", Enabled: true, Healthy: true}},
+	})
+
+	rec := httptest.NewRecorder()
+	h.GetStatus(rec, httptest.NewRequest(http.MethodGet, "/api/v1/bridge/status", nil))
+
+	var status BridgeStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if status.Status != "running" || !status.Connected || len(status.Issues) != 0 {
+		t.Errorf("Expected a healthy bridge, got %+v", status)
+	}
+	if status.Version != "2.1.0" || status.Uptime < 3600 {
+		t.Errorf("Expected version and uptime since start, got %s %d", status.Version, status.Uptime)
+	}
+	if status.LastHeartbeat == nil || status.LastPoll == nil || status.Queues["pending_results"] != 2 {
+		t.Errorf("Expected heartbeat, poll and queue details, got %+v", status)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetHealth(rec, httptest.NewRequest(http.MethodGet, "/api/v1/bridge/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 from a healthy bridge, got %d", rec.Code)
+	}
+}
+
+func TestBridgeHandler_Degraded(t *testing.T) {
+	now := time.Now()
+	h := newTestBridgeHandler(BridgeSources{
+		StartedAt: now.Add(-time.Hour),
+		Client:    &fakeBridgeClient{registered: true, lastHeartbeat: now.Add(-5 * time.Minute)},
+		Poller:    fakePoller{transport: poller.TransportPolling},
+		OBS:       fakeOBSState(obs.StateReconnecting),
+		Modules:   fakeModuleHealth{{Name: "soundboard", Enabled: true, Healthy: false}},
+	})
+
+	rec := httptest.NewRecorder()
+	h.GetHealth(rec, httptest.NewRequest(http.MethodGet, "/api/v1/bridge/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 from a degraded bridge, got %d", rec.Code)
+	}
+
+	var health HealthResponse
+	json.NewDecoder(rec.Body).Decode(&health)
+	want := map[string]string{
+		"gateway": "ok",
+		"api":     "stale",
+		"tasks":   "stale",
+		"obs":     obs.StateReconnecting.String(),
+		"modules": "1 unhealthy",
+	}
+	for service, state := range want {
+		if health.Services[service] != state {
+			t.Errorf("Expected %s to be %q, got %q", service, state, health.Services[service])
+		}
+	}
+	if len(health.Issues) != 4 {
+		t.Errorf("Expected 4 issues, got %v", health.Issues)
+	}
+}
+
+func TestBridgeHandler_StartupGrace(t *testing.T) {
+	h := newTestBridgeHandler(BridgeSources{
+		Client: &fakeBridgeClient{registered: true},
+		Poller: fakePoller{transport: poller.TransportPolling},
+	})
+
+	status, _ := h.check(time.Now())
+	if status.Status != "running" {
+		t.Errorf("Expected no issues before the first heartbeat is due, got %v", status.Issues)
+	}
+
+	streaming := newTestBridgeHandler(BridgeSources{
+		StartedAt: time.Now().Add(-time.Hour),
+		Poller:    fakePoller{transport: poller.TransportWebSocket},
+	})
+	if status, _ := streaming.check(time.Now()); status.Status != "running" {
+		t.Errorf("Expected no poll issue while tasks are streamed, got %v", status.Issues)
+	}
+}
+
+func TestBridgeHandler_Reconnect(t *testing.T) {
+	client := &fakeBridgeClient{}
+	h := newTestBridgeHandler(BridgeSources{Client: client})
+
+	rec := httptest.NewRecorder()
+	h.Reconnect(rec, httptest.NewRequest(http.MethodPost, "/api/v1/bridge/reconnect", nil))
+	if rec.Code != http.StatusOK || client.reconnects != 1 {
+		t.Errorf("Expected the client to reconnect, got %d after %d reconnects", rec.Code, client.reconnects)
+	}
+
+	rec = httptest.NewRecorder()
+	newTestBridgeHandler(BridgeSources{}).Reconnect(rec, httptest.NewRequest(http.MethodPost, "/api/v1/bridge/reconnect", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a bridge client, got %d", rec.Code)
+	}
+}
+
+func TestBridgeHandler_Storage(t *testing.T) {
+	h := newTestBridgeHandler(BridgeSources{
+		Storage: fakeStorage{storage.Usage{Backend: "bolt", SizeBytes: 1 << 20, LowDisk: true}},
+	})
+
+	rec := httptest.NewRecorder()
+	h.GetStorage(rec, httptest.NewRequest(http.MethodGet, "/api/v1/bridge/storage", nil))
+	var usage storage.Usage
+	json.NewDecoder(rec.Body).Decode(&usage)
+	if rec.Code != http.StatusOK || usage.SizeBytes != 1<<20 {
+		t.Errorf("Expected the storage usage, got %d %+v", rec.Code, usage)
+	}
+
+	status, services := h.check(time.Now())
+	if status.Status != "degraded" || services["storage"] != "low disk space" {
+		t.Errorf("Expected low disk space to degrade the bridge, got %v", status.Issues)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/commands"
+)
+
+// CommandCatalog lists and runs the commands of the command palette
+type CommandCatalog interface {
+	List() []commands.Command
+	Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error)
+}
+
+// CommandsHandler handles command palette endpoints
+type CommandsHandler struct {
+	catalog CommandCatalog
+	logger  *logrus.Logger
+}
+
+// NewCommandsHandler creates a new commands handler
+func NewCommandsHandler(catalog CommandCatalog, logger *logrus.Logger) *CommandsHandler {
+	return &CommandsHandler{
+		catalog: catalog,
+		logger:  logger,
+	}
+}
+
+// ExecuteCommandRequest represents a command execution request
+type ExecuteCommandRequest struct {
+	ID         string            `json:"id"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+// ListCommands returns every executable command, optionally filtered by
+// the "kind" query parameter
+func (h *CommandsHandler) ListCommands(w http.ResponseWriter, r *http.Request) {
+	if h.catalog == nil {
+		h.sendError(w, "command catalog not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	kind := r.URL.Query().Get("kind")
+	list := make([]commands.Command, 0)
+	for _, command := range h.catalog.List() {
+		if kind == "" || string(command.Kind) == kind {
+			list = append(list, command)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"commands": list,
+		"count":    len(list),
+	})
+}
+
+// ExecuteCommand runs a command by ID
+func (h *CommandsHandler) ExecuteCommand(w http.ResponseWriter, r *http.Request) {
+	if h.catalog == nil {
+		h.sendError(w, "command catalog not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req ExecuteCommandRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	if err := Required("id", req.ID); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	// The route needs scripts:run; OBS actions and macros also need
+	// obs:write
+	if scope := commands.Scope(req.ID); !hasScope(r, scope) {
+		h.sendError(w, fmt.Sprintf("API key lacks the %s scope", scope), http.StatusForbidden)
+		return
+	}
+
+	result, err := h.catalog.Execute(r.Context(), req.ID, req.Parameters)
+	if err != nil {
+		h.sendError(w, err.Error(), commandErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"id":      req.ID,
+		"result":  result,
+	})
+}
+
+// hasScope reports whether the key a request authenticated with grants a
+// scope. Requests without a key, when the gateway does not require
+// authentication, may do anything.
+func hasScope(r *http.Request, scope string) bool {
+	key, ok := apikeys.FromContext(r.Context())
+	return !ok || key.HasScope(scope)
+}
+
+// commandErrorStatus maps command errors to HTTP status codes
+func commandErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, commands.ErrCommandNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, commands.ErrMissingParameter):
+		return http.StatusBadRequest
+	case errors.Is(err, commands.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return moduleErrorStatus(err)
+	}
+}
+
+// Helper methods
+
+func (h *CommandsHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *CommandsHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+	h.logger.WithField("error", message).Warn("Command API error")
+}
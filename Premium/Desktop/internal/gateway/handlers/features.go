@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/features"
+)
+
+// FeatureProvider reports the features of the bridge's subscription
+type FeatureProvider interface {
+	Tier() string
+	List() []features.Info
+}
+
+// FeaturesHandler handles the subscription feature endpoint
+type FeaturesHandler struct {
+	features FeatureProvider
+	logger   *logrus.Logger
+}
+
+// NewFeaturesHandler creates a new features handler
+func NewFeaturesHandler(features FeatureProvider, logger *logrus.Logger) *FeaturesHandler {
+	return &FeaturesHandler{
+		features: features,
+		logger:   logger,
+	}
+}
+
+// ListFeatures returns the subscription tier and every feature, so UIs
+// can show what the subscription includes
+func (h *FeaturesHandler) ListFeatures(w http.ResponseWriter, r *http.Request) {
+	if h.features == nil {
+		h.sendError(w, "features not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tier":     h.features.Tier(),
+		"features": h.features.List(),
+	})
+}
+
+// Helper methods
+
+func (h *FeaturesHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
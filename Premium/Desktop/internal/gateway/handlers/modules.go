@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/modules"
+)
+
+// Names of built-in modules with their own endpoints
+const (
+	soundboardModule = "soundboard"
+	overlaysModule   = "overlays"
+)
+
+// ModuleExecutor executes module actions and lists loaded modules
+type ModuleExecutor interface {
+	ExecuteAction(ctx context.Context, moduleName, action string, parameters map[string]string) (map[string]interface{}, error)
+	GetModuleInfos() []models.ModuleInfo
+}
+
+// ModulesHandler handles module-related endpoints
+type ModulesHandler struct {
+	executor ModuleExecutor
+	logger   *logrus.Logger
+}
+
+// NewModulesHandler creates a new modules handler
+func NewModulesHandler(executor ModuleExecutor, logger *logrus.Logger) *ModulesHandler {
+	return &ModulesHandler{
+		executor: executor,
+		logger:   logger,
+	}
+}
+
+// ExecuteActionRequest represents a module action request
+type ExecuteActionRequest struct {
+	Parameters map[string]string `json:"parameters"`
+}
+
+// ListModules returns information about all loaded modules
+func (h *ModulesHandler) ListModules(w http.ResponseWriter, r *http.Request) {
+	if h.executor == nil {
+		h.sendError(w, "module manager not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"modules": h.executor.GetModuleInfos(),
+	})
+}
+
+// ExecuteAction executes an action on a module
+func (h *ModulesHandler) ExecuteAction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req ExecuteActionRequest
+	if r.ContentLength != 0 {
+		if err := DecodeJSON(r, &req); err != nil {
+			h.sendValidationError(w, err)
+			return
+		}
+	}
+
+	h.execute(w, r, vars["name"], vars["action"], req.Parameters)
+}
+
+// ListSounds returns the sounds available to the soundboard
+func (h *ModulesHandler) ListSounds(w http.ResponseWriter, r *http.Request) {
+	h.execute(w, r, soundboardModule, "list", nil)
+}
+
+// PlaySound plays a soundboard sound
+func (h *ModulesHandler) PlaySound(w http.ResponseWriter, r *http.Request) {
+	parameters := map[string]string{"sound": mux.Vars(r)["name"]}
+	if volume := r.URL.Query().Get("volume"); volume != "" {
+		parameters["volume"] = volume
+	}
+	h.execute(w, r, soundboardModule, "play", parameters)
+}
+
+// StopSounds stops all soundboard playback
+func (h *ModulesHandler) StopSounds(w http.ResponseWriter, r *http.Request) {
+	h.execute(w, r, soundboardModule, "stop", nil)
+}
+
+// SetSoundVolume sets the soundboard master volume
+func (h *ModulesHandler) SetSoundVolume(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Volume int `json:"volume"`
+	}
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	h.execute(w, r, soundboardModule, "set_volume", map[string]string{"volume": strconv.Itoa(req.Volume)})
+}
+
+// AlertRequest represents an overlay alert request. Variables fill
+// {placeholders} in the alert text.
+type AlertRequest struct {
+	Template  string            `json:"template,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Image     string            `json:"image,omitempty"`
+	Sound     string            `json:"sound,omitempty"`
+	Duration  string            `json:"duration,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// QueueAlert queues an overlay alert
+func (h *ModulesHandler) QueueAlert(w http.ResponseWriter, r *http.Request) {
+	var req AlertRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	if req.Template == "" && req.Text == "" && req.Image == "" && req.Sound == "" {
+		h.sendValidationError(w, Invalid("template", "template, text, image or sound is required"))
+		return
+	}
+
+	parameters := make(map[string]string, len(req.Variables)+5)
+	for name, value := range req.Variables {
+		parameters[name] = value
+	}
+	for name, value := range map[string]string{
+		"template": req.Template,
+		"text":     req.Text,
+		"image":    req.Image,
+		"sound":    req.Sound,
+		"duration": req.Duration,
+	} {
+		if value != "" {
+			parameters[name] = value
+		}
+	}
+	h.execute(w, r, overlaysModule, "alert", parameters)
+}
+
+// ListAlerts returns the overlay alert being shown and the queue
+func (h *ModulesHandler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	h.execute(w, r, overlaysModule, "queue", nil)
+}
+
+// SkipAlert ends the overlay alert being shown
+func (h *ModulesHandler) SkipAlert(w http.ResponseWriter, r *http.Request) {
+	h.execute(w, r, overlaysModule, "skip", nil)
+}
+
+// ClearAlerts drops queued overlay alerts and clears the overlays
+func (h *ModulesHandler) ClearAlerts(w http.ResponseWriter, r *http.Request) {
+	h.execute(w, r, overlaysModule, "clear", nil)
+}
+
+// ListAlertTemplates returns the overlay alert templates
+func (h *ModulesHandler) ListAlertTemplates(w http.ResponseWriter, r *http.Request) {
+	h.execute(w, r, overlaysModule, "templates", nil)
+}
+
+// execute runs a module action and writes the result
+func (h *ModulesHandler) execute(w http.ResponseWriter, r *http.Request, module, action string, parameters map[string]string) {
+	if h.executor == nil {
+		h.sendError(w, "module manager not available", http.StatusServiceUnavailable)
+		return
+	}
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+
+	result, err := h.executor.ExecuteAction(r.Context(), module, action, parameters)
+	if err != nil {
+		h.sendError(w, err.Error(), moduleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"module":  module,
+		"action":  action,
+		"result":  result,
+	})
+}
+
+// moduleErrorStatus maps module errors to HTTP status codes
+func moduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, modules.ErrModuleNotFound), errors.Is(err, modules.ErrActionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, modules.ErrInvalidParameters):
+		return http.StatusBadRequest
+	case errors.Is(err, modules.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, modules.ErrModuleDisabled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Helper methods
+
+func (h *ModulesHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *ModulesHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+	h.logger.WithField("error", message).Warn("Module API error")
+}
@@ -3,7 +3,10 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -25,9 +28,13 @@ func NewOBSHandler(obsClient *obs.Client, logger *logrus.Logger) *OBSHandler {
 	}
 }
 
-// ErrorResponse represents an error response
+// ErrorResponse represents an error response. Error and Message hold the
+// same text; Error is kept for existing clients.
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error   string `json:"error"`
+	Code    string `json:"code,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message,omitempty"`
 }
 
 // SuccessResponse represents a success response
@@ -47,6 +54,51 @@ func (h *OBSHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(status)
 }
 
+// GetHistory returns the timeline of the OBS connection, newest first, with
+// a summary of it (?since= as a duration or RFC 3339 time, default 24h;
+// ?limit=)
+func (h *OBSHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+	if value := r.URL.Query().Get("since"); value != "" {
+		if ago, err := time.ParseDuration(value); err == nil && ago > 0 {
+			since = now.Add(-ago)
+		} else if at, err := time.Parse(time.RFC3339, value); err == nil {
+			since = at
+		} else {
+			h.sendError(w, "since must be a positive duration or an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			h.sendError(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	// The summary covers the whole stretch even when limit cuts the events
+	events := h.obsClient.ConnectionHistory(since, 0)
+	summary := obs.Summarize(events, now)
+	total := len(events)
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"connection": h.obsClient.GetConnectionInfo(),
+		"since":      since,
+		"events":     events,
+		"total":      total,
+		"summary":    summary,
+	})
+}
+
 // Connect connects to OBS
 func (h *OBSHandler) Connect(w http.ResponseWriter, r *http.Request) {
 	if err := h.obsClient.Connect(context.Background()); err != nil {
@@ -101,13 +153,13 @@ type SwitchSceneRequest struct {
 // SwitchScene switches to a different scene
 func (h *OBSHandler) SwitchScene(w http.ResponseWriter, r *http.Request) {
 	var req SwitchSceneRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
-	if req.SceneName == "" {
-		h.sendError(w, "scene_name is required", http.StatusBadRequest)
+	if err := Required("scene_name", req.SceneName); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
@@ -148,13 +200,13 @@ func (h *OBSHandler) SetSourceVisibility(w http.ResponseWriter, r *http.Request)
 	sourceName := vars["name"]
 
 	var req SetSourceVisibilityRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
-	if req.SceneName == "" {
-		h.sendError(w, "scene_name is required", http.StatusBadRequest)
+	if err := Required("scene_name", req.SceneName); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
@@ -182,13 +234,13 @@ func (h *OBSHandler) SetSourceTransform(w http.ResponseWriter, r *http.Request)
 	sourceName := vars["name"]
 
 	var req SetSourceTransformRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
-	if req.SceneName == "" {
-		h.sendError(w, "scene_name is required", http.StatusBadRequest)
+	if err := Required("scene_name", req.SceneName); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
@@ -246,8 +298,8 @@ func (h *OBSHandler) UpdateFilter(w http.ResponseWriter, r *http.Request) {
 	filterName := vars["filter"]
 
 	var req UpdateFilterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
@@ -414,10 +466,12 @@ func (h *OBSHandler) ToggleRecording(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
+func (h *OBSHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
 func (h *OBSHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	writeError(w, errors.New(message), statusCode)
 	h.logger.WithField("error", message).Warn("OBS API error")
 }
 
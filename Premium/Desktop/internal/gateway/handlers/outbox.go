@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/outbox"
+)
+
+// OutboxQueue exposes the durable outbound queue for inspection
+type OutboxQueue interface {
+	List() ([]outbox.Item, error)
+	Stats() (outbox.Stats, error)
+	Purge(kind string) (int, error)
+	Delete(id string) error
+}
+
+// OutboxHandler handles outbox inspection endpoints
+type OutboxHandler struct {
+	queue  OutboxQueue
+	logger *logrus.Logger
+}
+
+// NewOutboxHandler creates a new outbox handler
+func NewOutboxHandler(queue OutboxQueue, logger *logrus.Logger) *OutboxHandler {
+	return &OutboxHandler{
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// ListItems returns queued items, optionally filtered by ?kind=
+func (h *OutboxHandler) ListItems(w http.ResponseWriter, r *http.Request) {
+	if h.queue == nil {
+		h.sendError(w, "outbox not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	items, err := h.queue.List()
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := h.queue.Stats()
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		filtered := make([]outbox.Item, 0, len(items))
+		for _, item := range items {
+			if item.Kind == kind {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items": items,
+		"stats": stats,
+	})
+}
+
+// PurgeItems deletes queued items, optionally only those of ?kind=
+func (h *OutboxHandler) PurgeItems(w http.ResponseWriter, r *http.Request) {
+	if h.queue == nil {
+		h.sendError(w, "outbox not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	purged, err := h.queue.Purge(r.URL.Query().Get("kind"))
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"purged":  purged,
+	})
+}
+
+// DeleteItem deletes a single queued item
+func (h *OutboxHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	if h.queue == nil {
+		h.sendError(w, "outbox not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := h.queue.Delete(id); err != nil {
+		if errors.Is(err, outbox.ErrItemNotFound) {
+			h.sendError(w, "outbox item not found", http.StatusNotFound)
+			return
+		}
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.WithField("id", id).Info("Outbox item deleted")
+	h.sendSuccess(w, "Outbox item deleted")
+}
+
+// Helper methods
+
+func (h *OutboxHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *OutboxHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/policy"
+)
+
+// PolicyProvider exposes the task policy and its audit log
+type PolicyProvider interface {
+	Policy() config.PolicyConfig
+	Reload() error
+	Denials(limit int) ([]policy.AuditEntry, error)
+}
+
+// PolicyHandler handles policy endpoints
+type PolicyHandler struct {
+	provider PolicyProvider
+	logger   *logrus.Logger
+}
+
+// NewPolicyHandler creates a new policy handler
+func NewPolicyHandler(provider PolicyProvider, logger *logrus.Logger) *PolicyHandler {
+	return &PolicyHandler{
+		provider: provider,
+		logger:   logger,
+	}
+}
+
+// GetPolicy returns the active policy
+func (h *PolicyHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		h.sendError(w, "policy engine not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.provider.Policy())
+}
+
+// ReloadPolicy re-reads the policy file
+func (h *PolicyHandler) ReloadPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		h.sendError(w, "policy engine not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := h.provider.Reload(); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.sendSuccess(w, "Policy reloaded")
+}
+
+// GetAuditLog returns recent policy denials, newest first (?limit=, default 100)
+func (h *PolicyHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		h.sendError(w, "policy engine not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			h.sendError(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := h.provider.Denials(limit)
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"denials": entries,
+	})
+}
+
+// Helper methods
+
+func (h *PolicyHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *PolicyHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+// ProfileSwitcher lists the configuration profiles and switches between
+// them
+type ProfileSwitcher interface {
+	Profiles() []string
+	ActiveProfile() string
+	SwitchProfile(name string) (config.ReloadResult, error)
+}
+
+// ProfilesHandler handles configuration profile endpoints
+type ProfilesHandler struct {
+	switcher ProfileSwitcher
+	logger   *logrus.Logger
+}
+
+// NewProfilesHandler creates a new profiles handler
+func NewProfilesHandler(switcher ProfileSwitcher, logger *logrus.Logger) *ProfilesHandler {
+	return &ProfilesHandler{
+		switcher: switcher,
+		logger:   logger,
+	}
+}
+
+// ListProfiles returns the configured profiles and the active one
+func (h *ProfilesHandler) ListProfiles(w http.ResponseWriter, r *http.Request) {
+	if h.switcher == nil {
+		h.sendError(w, "configuration profiles not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"profiles": h.switcher.Profiles(),
+		"active":   h.switcher.ActiveProfile(),
+	})
+}
+
+// ActivateProfile switches to the named profile
+func (h *ProfilesHandler) ActivateProfile(w http.ResponseWriter, r *http.Request) {
+	h.switchProfile(w, mux.Vars(r)["name"])
+}
+
+// DeactivateProfile returns to the configuration without a profile
+func (h *ProfilesHandler) DeactivateProfile(w http.ResponseWriter, r *http.Request) {
+	h.switchProfile(w, "")
+}
+
+func (h *ProfilesHandler) switchProfile(w http.ResponseWriter, name string) {
+	if h.switcher == nil {
+		h.sendError(w, "configuration profiles not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := h.switcher.SwitchProfile(name)
+	switch {
+	case errors.Is(err, config.ErrUnknownProfile):
+		h.sendError(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"active":           h.switcher.ActiveProfile(),
+		"applied":          result.Applied,
+		"restart_required": result.RestartRequired,
+	})
+}
+
+// Helper methods
+
+func (h *ProfilesHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
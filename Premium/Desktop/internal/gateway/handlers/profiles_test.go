@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+type fakeProfiles struct {
+	names  []string
+	active string
+}
+
+func (f *fakeProfiles) Profiles() []string    { return f.names }
+func (f *fakeProfiles) ActiveProfile() string { return f.active }
+
+func (f *fakeProfiles) SwitchProfile(name string) (config.ReloadResult, error) {
+	if name != "" && name != "travel" {
+		return config.ReloadResult{}, fmt.Errorf("%w: %s", config.ErrUnknownProfile, name)
+	}
+	f.active = name
+	return config.ReloadResult{Applied: []string{"obs.host"}, RestartRequired: []string{"gateway.port"}}, nil
+}
+
+func TestProfilesHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := NewProfilesHandler(&fakeProfiles{names: []string{"travel"}}, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/profiles", handler.ListProfiles).Methods("GET")
+	router.HandleFunc("/profiles/active", handler.DeactivateProfile).Methods("DELETE")
+	router.HandleFunc("/profiles/{name}/activate", handler.ActivateProfile).Methods("POST")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/profiles/travel/activate", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var switched struct {
+		Active          string   `json:"active"`
+		Applied         []string `json:"applied"`
+		RestartRequired []string `json:"restart_required"`
+	}
+	json.NewDecoder(rec.Body).Decode(&switched)
+	if switched.Active != "travel" || !reflect.DeepEqual(switched.RestartRequired, []string{"gateway.port"}) {
+		t.Errorf("Unexpected switch response: %+v", switched)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/profiles", nil))
+	var listed struct {
+		Profiles []string `json:"profiles"`
+		Active   string   `json:"active"`
+	}
+	json.NewDecoder(rec.Body).Decode(&listed)
+	if listed.Active != "travel" || !reflect.DeepEqual(listed.Profiles, []string{"travel"}) {
+		t.Errorf("Unexpected profile list: %+v", listed)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/profiles/streaming/activate", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown profile, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("DELETE", "/profiles/active", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 when deactivating, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	NewProfilesHandler(nil, logger).ListProfiles(rec, httptest.NewRequest("GET", "/profiles", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without profiles, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/rtc"
+)
+
+// RTCClient reaches the community's calls as the bridge's user
+type RTCClient interface {
+	ListRooms(ctx context.Context, filter rtc.RoomFilter) (*rtc.RoomPage, error)
+	JoinToken(ctx context.Context, room, role string) (*rtc.JoinToken, error)
+	Mute(ctx context.Context, room string) error
+	RaiseHand(ctx context.Context, room string) error
+	LowerHand(ctx context.Context, room string) error
+	AddBrowserSource(ctx context.Context, room string, source rtc.BrowserSource) (*rtc.BrowserSourceResult, error)
+	ViewerURL(ctx context.Context, room, layout string) (string, error)
+}
+
+// RTCHandler handles community call endpoints
+type RTCHandler struct {
+	client RTCClient
+	logger *logrus.Logger
+}
+
+// NewRTCHandler creates a new community calls handler
+func NewRTCHandler(client RTCClient, logger *logrus.Logger) *RTCHandler {
+	return &RTCHandler{
+		client: client,
+		logger: logger,
+	}
+}
+
+// BrowserSourceRequest asks for a room to be shown in OBS. APIKey is the
+// key the browser source loads the room's view with; it needs the
+// bridge:read scope.
+type BrowserSourceRequest struct {
+	rtc.BrowserSource
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// JoinTokenRequest asks for a token to join a room; an empty role joins
+// with the role last given in the room
+type JoinTokenRequest struct {
+	Role string `json:"role,omitempty"`
+}
+
+// ListRooms returns a page of the community's rooms. state may be given
+// more than once, or as a comma-separated list.
+func (h *RTCHandler) ListRooms(w http.ResponseWriter, r *http.Request) {
+	if h.client == nil {
+		h.sendError(w, "calls not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := rtc.RoomFilter{
+		Sort:  query.Get("sort"),
+		Order: query.Get("order"),
+	}
+	for _, states := range query["state"] {
+		for _, state := range strings.Split(states, ",") {
+			if state = strings.TrimSpace(state); state != "" {
+				filter.States = append(filter.States, state)
+			}
+		}
+	}
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			h.sendValidationError(w, Invalid(param.name, param.name+" must be a non-negative integer"))
+			return
+		}
+		*param.value = n
+	}
+
+	page, err := h.client.ListRooms(r.Context(), filter)
+	if err != nil {
+		h.sendRTCError(w, "Failed to list rooms", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetJoinToken fetches a token for the user to join a room with
+func (h *RTCHandler) GetJoinToken(w http.ResponseWriter, r *http.Request) {
+	if h.client == nil {
+		h.sendError(w, "calls not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req JoinTokenRequest
+	if r.ContentLength != 0 {
+		if err := DecodeJSON(r, &req); err != nil {
+			h.sendValidationError(w, err)
+			return
+		}
+	}
+
+	token, err := h.client.JoinToken(r.Context(), mux.Vars(r)["room"], req.Role)
+	if err != nil {
+		h.sendRTCError(w, "Failed to get join token", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+// Mute mutes the user's microphone in a room
+func (h *RTCHandler) Mute(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, "mute", "Muted in call", RTCClient.Mute)
+}
+
+// RaiseHand raises the user's hand in a room
+func (h *RTCHandler) RaiseHand(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, "raise hand", "Hand raised", RTCClient.RaiseHand)
+}
+
+// LowerHand lowers the user's hand in a room
+func (h *RTCHandler) LowerHand(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, "lower hand", "Hand lowered", RTCClient.LowerHand)
+}
+
+// act runs an action for the user in the room named in the path
+func (h *RTCHandler) act(w http.ResponseWriter, r *http.Request, action, done string, run func(RTCClient, context.Context, string) error) {
+	if h.client == nil {
+		h.sendError(w, "calls not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	room := mux.Vars(r)["room"]
+	if err := run(h.client, r.Context(), room); err != nil {
+		h.sendRTCError(w, "Failed to "+action, err)
+		return
+	}
+
+	h.logger.WithField("room", room).Info(done)
+
+	h.sendSuccess(w, done)
+}
+
+// AddBrowserSource shows a room's composite view in an OBS browser source
+func (h *RTCHandler) AddBrowserSource(w http.ResponseWriter, r *http.Request) {
+	if h.client == nil {
+		h.sendError(w, "calls not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req BrowserSourceRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	if err := Required("scene", req.Scene); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	if err := Required("name", req.Name); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	// The source loads the view through the gateway, at the address this
+	// request came to
+	page := url.URL{
+		Scheme: "http",
+		Host:   r.Host,
+		Path:   strings.TrimSuffix(r.URL.Path, "/obs-source") + "/view",
+	}
+	if r.TLS != nil {
+		page.Scheme = "https"
+	}
+	query := url.Values{}
+	if req.Layout != "" {
+		query.Set("layout", req.Layout)
+	}
+	if req.APIKey != "" {
+		query.Set("api_key", req.APIKey)
+	}
+	page.RawQuery = query.Encode()
+	req.PageURL = page.String()
+
+	result, err := h.client.AddBrowserSource(r.Context(), mux.Vars(r)["room"], req.BrowserSource)
+	if err != nil {
+		h.sendRTCError(w, "Failed to add call to OBS", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// ShowView sends a browser source on to a room's composite view, with a
+// token fetched for this load, so a source keeps joining after earlier
+// tokens expired
+func (h *RTCHandler) ShowView(w http.ResponseWriter, r *http.Request) {
+	if h.client == nil {
+		h.sendError(w, "calls not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	view, err := h.client.ViewerURL(r.Context(), mux.Vars(r)["room"], r.URL.Query().Get("layout"))
+	if err != nil {
+		h.sendRTCError(w, "Failed to show call", err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, view, http.StatusFound)
+}
+
+// sendRTCError answers with the status and code the RTC module gave for
+// client errors, and as a bad gateway when it failed, could not be reached
+// or did not accept the bridge's credentials
+func (h *RTCHandler) sendRTCError(w http.ResponseWriter, message string, err error) {
+	var apiErr *rtc.APIError
+	switch {
+	case errors.Is(err, rtc.ErrInvalidRequest):
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, rtc.ErrNotConfigured), errors.Is(err, rtc.ErrOBSUnavailable):
+		h.sendError(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.As(err, &apiErr) && apiErr.Status < 500 && apiErr.Status != http.StatusUnauthorized:
+		response := ErrorResponse{Error: apiErr.Message, Message: apiErr.Message, Code: apiErr.Code}
+		if response.Code == "" {
+			response.Code = statusErrorCode(apiErr.Status)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(apiErr.Status)
+		json.NewEncoder(w).Encode(response)
+	default:
+		h.logger.WithError(err).Error(message)
+		h.sendError(w, message+": "+err.Error(), http.StatusBadGateway)
+	}
+}
+
+// Helper methods
+
+func (h *RTCHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *RTCHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *RTCHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/rtc"
+)
+
+// fakeRTC answers as the RTC module would for community 7
+type fakeRTC struct {
+	filter     rtc.RoomFilter
+	muted      []string
+	configured bool
+	source     rtc.BrowserSource
+}
+
+func (f *fakeRTC) ListRooms(ctx context.Context, filter rtc.RoomFilter) (*rtc.RoomPage, error) {
+	f.filter = filter
+	return &rtc.RoomPage{Rooms: []rtc.Room{{RoomName: "community_7_lobby"}}, Total: 1}, nil
+}
+
+func (f *fakeRTC) JoinToken(ctx context.Context, room, role string) (*rtc.JoinToken, error) {
+	if room == "community_7_locked" {
+		return nil, &rtc.APIError{Status: http.StatusForbidden, Code: "room_locked", Message: "Room is locked"}
+	}
+	return &rtc.JoinToken{Token: "jwt", RoomName: room, Identity: "42"}, nil
+}
+
+func (f *fakeRTC) Mute(ctx context.Context, room string) error {
+	f.muted = append(f.muted, room)
+	return nil
+}
+
+func (f *fakeRTC) RaiseHand(ctx context.Context, room string) error {
+	return &rtc.APIError{Status: http.StatusInternalServerError, Message: "Failed to raise hand"}
+}
+
+func (f *fakeRTC) LowerHand(ctx context.Context, room string) error { return nil }
+
+func (f *fakeRTC) AddBrowserSource(ctx context.Context, room string, source rtc.BrowserSource) (*rtc.BrowserSourceResult, error) {
+	if !f.configured {
+		return nil, rtc.ErrNotConfigured
+	}
+	f.source = source
+	return &rtc.BrowserSourceResult{BrowserSource: source, RoomName: room, Created: true}, nil
+}
+
+func (f *fakeRTC) ViewerURL(ctx context.Context, room, layout string) (string, error) {
+	if _, err := f.JoinToken(ctx, room, "viewer"); err != nil {
+		return "", err
+	}
+	return "https://calls.example.com/view?token=jwt&layout=" + layout, nil
+}
+
+func TestRTCHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	client := &fakeRTC{}
+	handler := NewRTCHandler(client, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/rtc/rooms", handler.ListRooms).Methods("GET")
+	router.HandleFunc("/rtc/rooms/{room}/token", handler.GetJoinToken).Methods("POST")
+	router.HandleFunc("/rtc/rooms/{room}/mute", handler.Mute).Methods("POST")
+	router.HandleFunc("/rtc/rooms/{room}/raise-hand", handler.RaiseHand).Methods("POST")
+	router.HandleFunc("/rtc/rooms/{room}/obs-source", handler.AddBrowserSource).Methods("POST")
+	router.HandleFunc("/rtc/rooms/{room}/view", handler.ShowView).Methods("GET")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do("GET", "/rtc/rooms?state=active,locked&state=empty&limit=5", "")
+	if rec.Code != http.StatusOK || len(client.filter.States) != 3 || client.filter.Limit != 5 {
+		t.Errorf("Expected the filter passed on, got %d: %+v", rec.Code, client.filter)
+	}
+	if rec := do("GET", "/rtc/rooms?offset=-1", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative offset, got %d", rec.Code)
+	}
+
+	rec = do("POST", "/rtc/rooms/community_7_lobby/token", "")
+	var token rtc.JoinToken
+	json.NewDecoder(rec.Body).Decode(&token)
+	if rec.Code != http.StatusOK || token.Token != "jwt" {
+		t.Errorf("Expected a join token without a body, got %d: %+v", rec.Code, token)
+	}
+
+	// The RTC module's refusals keep their status and code
+	rec = do("POST", "/rtc/rooms/community_7_locked/token", `{"role":"viewer"}`)
+	var errResp ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&errResp)
+	if rec.Code != http.StatusForbidden || errResp.Code != "room_locked" {
+		t.Errorf("Expected 403 room_locked, got %d: %+v", rec.Code, errResp)
+	}
+	if rec := do("POST", "/rtc/rooms/community_7_lobby/raise-hand", ""); rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 when the RTC module fails, got %d", rec.Code)
+	}
+
+	if rec := do("POST", "/rtc/rooms/community_7_lobby/mute", ""); rec.Code != http.StatusOK || len(client.muted) != 1 {
+		t.Errorf("Expected the user muted, got %d", rec.Code)
+	}
+
+	if rec := do("POST", "/rtc/rooms/community_7_lobby/obs-source", `{"name":"Guests"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a scene, got %d", rec.Code)
+	}
+	if rec := do("POST", "/rtc/rooms/community_7_lobby/obs-source", `{"scene":"Call","name":"Guests"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without the composite view configured, got %d", rec.Code)
+	}
+
+	// The source loads the view through the gateway, with the key given
+	client.configured = true
+	rec = do("POST", "/rtc/rooms/community_7_lobby/obs-source", `{"scene":"Call","name":"Guests","layout":"speaker","api_key":"wbk_view"}`)
+	if rec.Code != http.StatusCreated || client.source.PageURL != "http://example.com/rtc/rooms/community_7_lobby/view?api_key=wbk_view&layout=speaker" {
+		t.Errorf("Expected the source to load the gateway's view page, got %d: %q", rec.Code, client.source.PageURL)
+	}
+	if strings.Contains(rec.Body.String(), "wbk_view") {
+		t.Errorf("Expected the key left out of the response, got %s", rec.Body.String())
+	}
+
+	rec = do("GET", "/rtc/rooms/community_7_lobby/view?layout=speaker", "")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://calls.example.com/view?token=jwt&layout=speaker" {
+		t.Errorf("Expected a redirect to the view with a new token, got %d: %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected the redirect not cached, got %q", rec.Header().Get("Cache-Control"))
+	}
+	if rec := do("GET", "/rtc/rooms/community_7_locked/view", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a room the view may not join, got %d", rec.Code)
+	}
+
+	unavailable := mux.NewRouter()
+	unavailable.HandleFunc("/rtc/rooms/{room}/mute", NewRTCHandler(nil, logger).Mute).Methods("POST")
+	rec = httptest.NewRecorder()
+	unavailable.ServeHTTP(rec, httptest.NewRequest("POST", "/rtc/rooms/community_7_lobby/mute", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with calls disabled, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/rules"
+)
+
+// RuleStore manages the scene switching rules
+type RuleStore interface {
+	List() []rules.Rule
+	Get(id string) (rules.Rule, error)
+	Create(rule rules.Rule) (rules.Rule, error)
+	Update(id string, rule rules.Rule) (rules.Rule, error)
+	Remove(id string) error
+}
+
+// RulesHandler handles scene switching rule endpoints
+type RulesHandler struct {
+	store  RuleStore
+	logger *logrus.Logger
+}
+
+// NewRulesHandler creates a new rules handler
+func NewRulesHandler(store RuleStore, logger *logrus.Logger) *RulesHandler {
+	return &RulesHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// RuleRequest creates or replaces a rule
+type RuleRequest struct {
+	Name       string          `json:"name"`
+	Enabled    *bool           `json:"enabled,omitempty"` // defaults to true
+	Condition  rules.Condition `json:"condition"`
+	ForSeconds int             `json:"for_seconds"`
+	Actions    []rules.Action  `json:"actions"`
+	Revert     bool            `json:"revert"`
+}
+
+// scope returns the scope a key needs for the rule's actions: obs:write
+// to switch scenes or toggle sources, scripts:run otherwise
+func (req RuleRequest) scope() string {
+	for _, action := range req.Actions {
+		if action.Type == rules.ActionSwitchScene || action.Type == rules.ActionToggleSource {
+			return apikeys.ScopeOBSWrite
+		}
+	}
+	return apikeys.ScopeScriptsRun
+}
+
+// rule converts the request to a rule
+func (req RuleRequest) rule() rules.Rule {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return rules.Rule{
+		Name:       req.Name,
+		Enabled:    enabled,
+		Condition:  req.Condition,
+		ForSeconds: req.ForSeconds,
+		Actions:    req.Actions,
+		Revert:     req.Revert,
+	}
+}
+
+// ListRules returns all rules
+func (h *RulesHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "rules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	list := h.store.List()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules": list,
+		"count": len(list),
+	})
+}
+
+// GetRule returns a rule
+func (h *RulesHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "rules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	rule, err := h.store.Get(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendError(w, err.Error(), ruleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// CreateRule creates a rule
+func (h *RulesHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "rules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req RuleRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	if scope := req.scope(); !hasScope(r, scope) {
+		h.sendError(w, fmt.Sprintf("API key lacks the %s scope", scope), http.StatusForbidden)
+		return
+	}
+
+	rule, err := h.store.Create(req.rule())
+	if err != nil {
+		h.sendError(w, err.Error(), ruleErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":        rule.ID,
+		"name":      rule.Name,
+		"condition": rule.Condition.Type,
+	}).Info("Rule created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateRule replaces a rule
+func (h *RulesHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "rules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req RuleRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	if scope := req.scope(); !hasScope(r, scope) {
+		h.sendError(w, fmt.Sprintf("API key lacks the %s scope", scope), http.StatusForbidden)
+		return
+	}
+
+	rule, err := h.store.Update(mux.Vars(r)["id"], req.rule())
+	if err != nil {
+		h.sendError(w, err.Error(), ruleErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":   rule.ID,
+		"name": rule.Name,
+	}).Info("Rule updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// RemoveRule deletes a rule
+func (h *RulesHandler) RemoveRule(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "rules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := h.store.Remove(id); err != nil {
+		h.sendError(w, err.Error(), ruleErrorStatus(err))
+		return
+	}
+
+	h.logger.WithField("id", id).Info("Rule removed")
+
+	h.sendSuccess(w, "Rule removed")
+}
+
+// ruleErrorStatus maps rule errors to HTTP status codes
+func ruleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, rules.ErrRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, rules.ErrInvalidRule):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Helper methods
+
+func (h *RulesHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *RulesHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *RulesHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/rules"
+)
+
+type fakeRules struct {
+	rules map[string]rules.Rule
+}
+
+func (f *fakeRules) List() []rules.Rule {
+	list := make([]rules.Rule, 0, len(f.rules))
+	for _, rule := range f.rules {
+		list = append(list, rule)
+	}
+	return list
+}
+
+func (f *fakeRules) Get(id string) (rules.Rule, error) {
+	rule, exists := f.rules[id]
+	if !exists {
+		return rules.Rule{}, fmt.Errorf("%w: %s", rules.ErrRuleNotFound, id)
+	}
+	return rule, nil
+}
+
+func (f *fakeRules) Create(rule rules.Rule) (rules.Rule, error) {
+	if err := rules.Validate(rule); err != nil {
+		return rules.Rule{}, err
+	}
+	rule.ID = "rule_1"
+	f.rules[rule.ID] = rule
+	return rule, nil
+}
+
+func (f *fakeRules) Update(id string, rule rules.Rule) (rules.Rule, error) {
+	if _, err := f.Get(id); err != nil {
+		return rules.Rule{}, err
+	}
+	rule.ID = id
+	f.rules[id] = rule
+	return rule, nil
+}
+
+func (f *fakeRules) Remove(id string) error {
+	if _, err := f.Get(id); err != nil {
+		return err
+	}
+	delete(f.rules, id)
+	return nil
+}
+
+func TestRulesHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := NewRulesHandler(&fakeRules{rules: make(map[string]rules.Rule)}, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/rules", handler.ListRules).Methods("GET")
+	router.HandleFunc("/rules", handler.CreateRule).Methods("POST")
+	router.HandleFunc("/rules/{id}", handler.GetRule).Methods("GET")
+	router.HandleFunc("/rules/{id}", handler.UpdateRule).Methods("PUT")
+	router.HandleFunc("/rules/{id}", handler.RemoveRule).Methods("DELETE")
+
+	body := `{"name":"BRB","condition":{"type":"process_not_running","process":"game.exe"},"actions":[{"type":"switch_scene","scene":"BRB"}],"revert":true}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/rules", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created rules.Rule
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.ID != "rule_1" || !created.Enabled || !created.Revert {
+		t.Errorf("Unexpected rule: %+v", created)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/rules", strings.NewReader(`{"name":"BRB","condition":{"type":"cpu_high"}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid rule, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/rules/rule_1", strings.NewReader(strings.Replace(body, `"revert":true`, `"enabled":false`, 1))))
+	var updated rules.Rule
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if rec.Code != http.StatusOK || updated.Enabled {
+		t.Errorf("Expected the rule to be disabled, got %d: %+v", rec.Code, updated)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("DELETE", "/rules/rule_1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 when removing, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/rules/rule_1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed rule, got %d", rec.Code)
+	}
+}
+
+func TestRulesHandler_Scopes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := NewRulesHandler(&fakeRules{rules: make(map[string]rules.Rule)}, logger)
+
+	scripts := apikeys.Key{Scopes: []string{apikeys.ScopeScriptsRun}}
+	obs := apikeys.Key{Scopes: []string{apikeys.ScopeScriptsRun, apikeys.ScopeOBSWrite}}
+	switchScene := `{"name":"BRB","condition":{"type":"process_not_running","process":"game.exe"},"actions":[{"type":"switch_scene","scene":"BRB"}]}`
+	runScript := `{"name":"Intro","condition":{"type":"process_not_running","process":"game.exe"},"actions":[{"type":"run_script","script":"intro.lua"}]}`
+
+	tests := []struct {
+		name string
+		key  apikeys.Key
+		body string
+		want int
+	}{
+		{"scene switch without obs:write", scripts, switchScene, http.StatusForbidden},
+		{"scene switch with obs:write", obs, switchScene, http.StatusCreated},
+		{"script", scripts, runScript, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/rules", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.CreateRule(rec, req.WithContext(apikeys.WithKey(req.Context(), tt.key)))
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRulesHandler_Unavailable(t *testing.T) {
+	handler := NewRulesHandler(nil, logrus.New())
+	rec := httptest.NewRecorder()
+	handler.ListRules(rec, httptest.NewRequest("GET", "/rules", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
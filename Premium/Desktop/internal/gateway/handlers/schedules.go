@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/schedules"
+)
+
+// ScheduleStore manages the source visibility schedules
+type ScheduleStore interface {
+	List() []schedules.Schedule
+	Get(id string) (schedules.Schedule, error)
+	Create(schedule schedules.Schedule) (schedules.Schedule, error)
+	Update(id string, schedule schedules.Schedule) (schedules.Schedule, error)
+	Remove(id string) error
+}
+
+// SchedulesHandler handles source visibility schedule endpoints
+type SchedulesHandler struct {
+	store  ScheduleStore
+	logger *logrus.Logger
+}
+
+// NewSchedulesHandler creates a new schedules handler
+func NewSchedulesHandler(store ScheduleStore, logger *logrus.Logger) *SchedulesHandler {
+	return &SchedulesHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// ScheduleRequest creates or replaces a schedule
+type ScheduleRequest struct {
+	Name         string `json:"name"`
+	Enabled      *bool  `json:"enabled,omitempty"` // defaults to true
+	Scene        string `json:"scene"`
+	Source       string `json:"source"`
+	ShowSeconds  int    `json:"show_seconds"`
+	EverySeconds int    `json:"every_seconds"`
+	StartSeconds int    `json:"start_seconds"`
+	While        string `json:"while,omitempty"` // defaults to streaming
+}
+
+// schedule converts the request to a schedule
+func (req ScheduleRequest) schedule() schedules.Schedule {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	while := req.While
+	if while == "" {
+		while = schedules.WhileStreaming
+	}
+	return schedules.Schedule{
+		Name:         req.Name,
+		Enabled:      enabled,
+		Scene:        req.Scene,
+		Source:       req.Source,
+		ShowSeconds:  req.ShowSeconds,
+		EverySeconds: req.EverySeconds,
+		StartSeconds: req.StartSeconds,
+		While:        while,
+	}
+}
+
+// ListSchedules returns all schedules
+func (h *SchedulesHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "schedules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	list := h.store.List()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"schedules": list,
+		"count":     len(list),
+	})
+}
+
+// GetSchedule returns a schedule
+func (h *SchedulesHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "schedules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	schedule, err := h.store.Get(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendError(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// CreateSchedule creates a schedule
+func (h *SchedulesHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "schedules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req ScheduleRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	schedule, err := h.store.Create(req.schedule())
+	if err != nil {
+		h.sendError(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":     schedule.ID,
+		"name":   schedule.Name,
+		"source": schedule.Source,
+	}).Info("Schedule created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// UpdateSchedule replaces a schedule
+func (h *SchedulesHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "schedules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req ScheduleRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	schedule, err := h.store.Update(mux.Vars(r)["id"], req.schedule())
+	if err != nil {
+		h.sendError(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":   schedule.ID,
+		"name": schedule.Name,
+	}).Info("Schedule updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// RemoveSchedule deletes a schedule
+func (h *SchedulesHandler) RemoveSchedule(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "schedules not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := h.store.Remove(id); err != nil {
+		h.sendError(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+
+	h.logger.WithField("id", id).Info("Schedule removed")
+
+	h.sendSuccess(w, "Schedule removed")
+}
+
+// scheduleErrorStatus maps schedule errors to HTTP status codes
+func scheduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, schedules.ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, schedules.ErrInvalidSchedule):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Helper methods
+
+func (h *SchedulesHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *SchedulesHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *SchedulesHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
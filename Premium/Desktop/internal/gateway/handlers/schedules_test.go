@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/schedules"
+)
+
+type fakeSchedules struct {
+	schedules map[string]schedules.Schedule
+}
+
+func (f *fakeSchedules) List() []schedules.Schedule {
+	list := make([]schedules.Schedule, 0, len(f.schedules))
+	for _, schedule := range f.schedules {
+		list = append(list, schedule)
+	}
+	return list
+}
+
+func (f *fakeSchedules) Get(id string) (schedules.Schedule, error) {
+	schedule, exists := f.schedules[id]
+	if !exists {
+		return schedules.Schedule{}, fmt.Errorf("%w: %s", schedules.ErrScheduleNotFound, id)
+	}
+	return schedule, nil
+}
+
+func (f *fakeSchedules) Create(schedule schedules.Schedule) (schedules.Schedule, error) {
+	if err := schedules.Validate(schedule); err != nil {
+		return schedules.Schedule{}, err
+	}
+	schedule.ID = "schedule_1"
+	f.schedules[schedule.ID] = schedule
+	return schedule, nil
+}
+
+func (f *fakeSchedules) Update(id string, schedule schedules.Schedule) (schedules.Schedule, error) {
+	if _, err := f.Get(id); err != nil {
+		return schedules.Schedule{}, err
+	}
+	schedule.ID = id
+	f.schedules[id] = schedule
+	return schedule, nil
+}
+
+func (f *fakeSchedules) Remove(id string) error {
+	if _, err := f.Get(id); err != nil {
+		return err
+	}
+	delete(f.schedules, id)
+	return nil
+}
+
+func TestSchedulesHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := NewSchedulesHandler(&fakeSchedules{schedules: make(map[string]schedules.Schedule)}, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/schedules", handler.ListSchedules).Methods("GET")
+	router.HandleFunc("/schedules", handler.CreateSchedule).Methods("POST")
+	router.HandleFunc("/schedules/{id}", handler.GetSchedule).Methods("GET")
+	router.HandleFunc("/schedules/{id}", handler.UpdateSchedule).Methods("PUT")
+	router.HandleFunc("/schedules/{id}", handler.RemoveSchedule).Methods("DELETE")
+
+	body := `{"name":"Ad","scene":"Gameplay","source":"Ad Banner","show_seconds":60,"every_seconds":1200}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/schedules", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created schedules.Schedule
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.ID != "schedule_1" || !created.Enabled || created.While != schedules.WhileStreaming {
+		t.Errorf("Expected an enabled schedule running while streaming, got %+v", created)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/schedules", strings.NewReader(`{"name":"Ad","scene":"Gameplay","source":"Ad Banner","show_seconds":60,"every_seconds":30}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid schedule, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/schedules/schedule_1", strings.NewReader(strings.Replace(body, `"name":"Ad"`, `"name":"Ad","enabled":false`, 1))))
+	var updated schedules.Schedule
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if rec.Code != http.StatusOK || updated.Enabled {
+		t.Errorf("Expected the schedule to be disabled, got %d: %+v", rec.Code, updated)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("DELETE", "/schedules/schedule_1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 when removing, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/schedules/schedule_1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed schedule, got %d", rec.Code)
+	}
+}
+
+func TestSchedulesHandler_Unavailable(t *testing.T) {
+	handler := NewSchedulesHandler(nil, logrus.New())
+	rec := httptest.NewRecorder()
+	handler.ListSchedules(rec, httptest.NewRequest("GET", "/schedules", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/auth"
+)
+
+// SigningKeyRotator manages the keys session tokens are signed with
+type SigningKeyRotator interface {
+	SigningKeys() []auth.SigningKeyInfo
+	RotateSigningKey() (auth.SigningKeyInfo, error)
+}
+
+// SigningKeysHandler handles JWT signing key endpoints
+type SigningKeysHandler struct {
+	keys   SigningKeyRotator
+	logger *logrus.Logger
+}
+
+// NewSigningKeysHandler creates a new signing keys handler
+func NewSigningKeysHandler(keys SigningKeyRotator, logger *logrus.Logger) *SigningKeysHandler {
+	return &SigningKeysHandler{
+		keys:   keys,
+		logger: logger,
+	}
+}
+
+// ListKeys returns the signing keys without their secrets
+func (h *SigningKeysHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	if h.keys == nil {
+		h.sendError(w, "signing keys not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	keys := h.keys.SigningKeys()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys":  keys,
+		"count": len(keys),
+	})
+}
+
+// RotateKey signs new tokens with a new key; tokens signed with the
+// previous key stay valid for the grace period
+func (h *SigningKeysHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	if h.keys == nil {
+		h.sendError(w, "signing keys not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	key, err := h.keys.RotateSigningKey()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrStaticSigningKey) {
+			status = http.StatusConflict
+		}
+		h.sendError(w, err.Error(), status)
+		return
+	}
+
+	h.logger.WithField("key_id", key.ID).Info("JWT signing key rotated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(key)
+}
+
+// Helper methods
+
+func (h *SigningKeysHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/textsources"
+)
+
+// TextSourceStore manages the text sources and the variables they show
+type TextSourceStore interface {
+	List() []textsources.Source
+	Get(id string) (textsources.Source, error)
+	Create(source textsources.Source) (textsources.Source, error)
+	Update(id string, source textsources.Source) (textsources.Source, error)
+	Remove(id string) error
+	Preview(template string) (string, error)
+
+	Variables() map[string]string
+	SetVariable(name, value string) error
+	Increment(name string, by int64) (int64, error)
+	DeleteVariable(name string) error
+}
+
+// TextSourcesHandler handles text source and variable endpoints
+type TextSourcesHandler struct {
+	store  TextSourceStore
+	logger *logrus.Logger
+}
+
+// NewTextSourcesHandler creates a new text sources handler
+func NewTextSourcesHandler(store TextSourceStore, logger *logrus.Logger) *TextSourcesHandler {
+	return &TextSourcesHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// TextSourceRequest creates or replaces a text source
+type TextSourceRequest struct {
+	Name     string `json:"name"`
+	Input    string `json:"input"`
+	Template string `json:"template"`
+	Enabled  *bool  `json:"enabled,omitempty"` // defaults to true
+}
+
+// source converts the request to a text source
+func (req TextSourceRequest) source() textsources.Source {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return textsources.Source{
+		Name:     req.Name,
+		Input:    req.Input,
+		Template: req.Template,
+		Enabled:  enabled,
+	}
+}
+
+// PreviewRequest renders a template without showing it
+type PreviewRequest struct {
+	Template string `json:"template"`
+}
+
+// VariableRequest sets a variable
+type VariableRequest struct {
+	Value string `json:"value"`
+}
+
+// IncrementRequest adds to a numeric variable
+type IncrementRequest struct {
+	By *int64 `json:"by,omitempty"` // defaults to 1
+}
+
+// ListSources returns all text sources
+func (h *TextSourcesHandler) ListSources(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	list := h.store.List()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sources": list,
+		"count":   len(list),
+	})
+}
+
+// GetSource returns a text source
+func (h *TextSourcesHandler) GetSource(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	source, err := h.store.Get(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendError(w, err.Error(), textSourceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(source)
+}
+
+// CreateSource creates a text source
+func (h *TextSourcesHandler) CreateSource(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req TextSourceRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	source, err := h.store.Create(req.source())
+	if err != nil {
+		h.sendError(w, err.Error(), textSourceErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":    source.ID,
+		"name":  source.Name,
+		"input": source.Input,
+	}).Info("Text source created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(source)
+}
+
+// UpdateSource replaces a text source
+func (h *TextSourcesHandler) UpdateSource(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req TextSourceRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	source, err := h.store.Update(mux.Vars(r)["id"], req.source())
+	if err != nil {
+		h.sendError(w, err.Error(), textSourceErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":   source.ID,
+		"name": source.Name,
+	}).Info("Text source updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(source)
+}
+
+// RemoveSource deletes a text source
+func (h *TextSourcesHandler) RemoveSource(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := h.store.Remove(id); err != nil {
+		h.sendError(w, err.Error(), textSourceErrorStatus(err))
+		return
+	}
+
+	h.logger.WithField("id", id).Info("Text source removed")
+
+	h.sendSuccess(w, "Text source removed")
+}
+
+// Preview renders a template against the current variables
+func (h *TextSourcesHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req PreviewRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	text, err := h.store.Preview(req.Template)
+	if err != nil {
+		h.sendError(w, err.Error(), textSourceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"text": text,
+	})
+}
+
+// ListVariables returns the variables and their values
+func (h *TextSourcesHandler) ListVariables(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"variables": h.store.Variables(),
+	})
+}
+
+// SetVariable sets a variable
+func (h *TextSourcesHandler) SetVariable(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req VariableRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if err := h.store.SetVariable(name, req.Value); err != nil {
+		h.sendError(w, err.Error(), textSourceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":  name,
+		"value": req.Value,
+	})
+}
+
+// IncrementVariable adds to a numeric variable
+func (h *TextSourcesHandler) IncrementVariable(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req IncrementRequest
+	if r.ContentLength != 0 {
+		if err := DecodeJSON(r, &req); err != nil {
+			h.sendValidationError(w, err)
+			return
+		}
+	}
+	by := int64(1)
+	if req.By != nil {
+		by = *req.By
+	}
+
+	name := mux.Vars(r)["name"]
+	value, err := h.store.Increment(name, by)
+	if err != nil {
+		h.sendError(w, err.Error(), textSourceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":  name,
+		"value": value,
+	})
+}
+
+// DeleteVariable removes a variable
+func (h *TextSourcesHandler) DeleteVariable(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "text sources not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := h.store.DeleteVariable(mux.Vars(r)["name"]); err != nil {
+		h.sendError(w, err.Error(), textSourceErrorStatus(err))
+		return
+	}
+
+	h.sendSuccess(w, "Variable removed")
+}
+
+// textSourceErrorStatus maps text source errors to HTTP status codes
+func textSourceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, textsources.ErrSourceNotFound), errors.Is(err, textsources.ErrVariableNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, textsources.ErrInvalidSource), errors.Is(err, textsources.ErrInvalidVariable):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Helper methods
+
+func (h *TextSourcesHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *TextSourcesHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *TextSourcesHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
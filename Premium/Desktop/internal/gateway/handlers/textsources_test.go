@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/testutils"
+	"waddlebot-bridge/internal/textsources"
+)
+
+type offlineOBS struct{}
+
+func (offlineOBS) IsConnected() bool { return false }
+
+func (offlineOBS) SetInputText(ctx context.Context, inputName, text string) error { return nil }
+
+func TestTextSourcesHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	manager, err := textsources.NewManager(testutils.NewMockStorage(), offlineOBS{}, config.TextSourcesConfig{Enabled: true, Interval: time.Second}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	handler := NewTextSourcesHandler(manager, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/text-sources", handler.CreateSource).Methods("POST")
+	router.HandleFunc("/text-sources/preview", handler.Preview).Methods("POST")
+	router.HandleFunc("/text-sources/variables", handler.ListVariables).Methods("GET")
+	router.HandleFunc("/text-sources/variables/{name}", handler.SetVariable).Methods("PUT")
+	router.HandleFunc("/text-sources/variables/{name}/increment", handler.IncrementVariable).Methods("POST")
+	router.HandleFunc("/text-sources/{id}", handler.GetSource).Methods("GET")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/text-sources", strings.NewReader(`{"name":"Deaths","input":"Deaths Text","template":"Deaths: {{.deaths}}"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created textsources.Source
+	json.NewDecoder(rec.Body).Decode(&created)
+	if !created.Enabled || created.ID == "" {
+		t.Errorf("Unexpected text source: %+v", created)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/text-sources", strings.NewReader(`{"name":"Deaths","input":"Deaths Text","template":"{{.deaths"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid template, got %d", rec.Code)
+	}
+
+	// Increment without a body counts up by one
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/text-sources/variables/deaths/increment", nil))
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/text-sources/variables/deaths/increment", strings.NewReader(`{"by":-5}`)))
+	var incremented struct {
+		Value int64 `json:"value"`
+	}
+	json.NewDecoder(rec.Body).Decode(&incremented)
+	if rec.Code != http.StatusOK || incremented.Value != -3 {
+		t.Errorf("Expected deaths to be -3, got %d: %+v", rec.Code, incremented)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/text-sources/variables/song", strings.NewReader(`{"value":"Blue Monday"}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 setting a variable, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/text-sources/variables/song/increment", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 incrementing text, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/text-sources/preview", strings.NewReader(`{"template":"{{.song}} ({{.deaths}})"}`)))
+	if !strings.Contains(rec.Body.String(), "Blue Monday (-3)") {
+		t.Errorf("Expected the preview to render the variables, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/text-sources/text_missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown source, got %d", rec.Code)
+	}
+}
+
+func TestTextSourcesHandler_Unavailable(t *testing.T) {
+	handler := NewTextSourcesHandler(nil, logrus.New())
+	rec := httptest.NewRecorder()
+	handler.ListVariables(rec, httptest.NewRequest("GET", "/text-sources/variables", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
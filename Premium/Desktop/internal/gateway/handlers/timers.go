@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/timers"
+)
+
+// TimerStore manages the countdowns and stopwatches
+type TimerStore interface {
+	List() []timers.Status
+	Get(name string) (timers.Status, error)
+	Create(timer timers.Timer) (timers.Status, error)
+	Remove(name string) error
+	Control(name, action string, amount time.Duration) (timers.Status, error)
+}
+
+// TimersHandler handles timer endpoints
+type TimersHandler struct {
+	store  TimerStore
+	logger *logrus.Logger
+}
+
+// NewTimersHandler creates a new timers handler
+func NewTimersHandler(store TimerStore, logger *logrus.Logger) *TimersHandler {
+	return &TimersHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// TimerRequest creates a timer
+type TimerRequest struct {
+	Name         string  `json:"name"`
+	Mode         string  `json:"mode"`
+	Duration     float64 `json:"duration_seconds,omitempty"`
+	FinishedText string  `json:"finished_text,omitempty"`
+	Start        bool    `json:"start,omitempty"` // start the timer once created
+}
+
+// TimerActionRequest is the body of a timer action; only add takes one
+type TimerActionRequest struct {
+	Seconds float64 `json:"seconds"`
+}
+
+// ListTimers returns all timers
+func (h *TimersHandler) ListTimers(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "timers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	list := h.store.List()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"timers": list,
+		"count":  len(list),
+	})
+}
+
+// GetTimer returns a timer
+func (h *TimersHandler) GetTimer(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "timers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	status, err := h.store.Get(mux.Vars(r)["name"])
+	if err != nil {
+		h.sendError(w, err.Error(), timerErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// CreateTimer creates a timer, starting it when asked to
+func (h *TimersHandler) CreateTimer(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "timers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req TimerRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	status, err := h.store.Create(timers.Timer{
+		Name:         req.Name,
+		Mode:         req.Mode,
+		Duration:     req.Duration,
+		FinishedText: req.FinishedText,
+	})
+	if err == nil && req.Start {
+		status, err = h.store.Control(req.Name, timers.ActionStart, 0)
+	}
+	if err != nil {
+		h.sendError(w, err.Error(), timerErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"name": status.Name,
+		"mode": status.Mode,
+	}).Info("Timer created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(status)
+}
+
+// ControlTimer starts, pauses, resets or adds time to a timer
+func (h *TimersHandler) ControlTimer(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "timers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	vars := mux.Vars(r)
+	var amount time.Duration
+	if vars["action"] == timers.ActionAdd {
+		var req TimerActionRequest
+		if err := DecodeJSON(r, &req); err != nil {
+			h.sendValidationError(w, err)
+			return
+		}
+		amount = time.Duration(req.Seconds * float64(time.Second))
+	}
+
+	status, err := h.store.Control(vars["name"], vars["action"], amount)
+	if err != nil {
+		h.sendError(w, err.Error(), timerErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// RemoveTimer deletes a timer
+func (h *TimersHandler) RemoveTimer(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "timers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if err := h.store.Remove(name); err != nil {
+		h.sendError(w, err.Error(), timerErrorStatus(err))
+		return
+	}
+
+	h.logger.WithField("name", name).Info("Timer removed")
+
+	h.sendSuccess(w, "Timer removed")
+}
+
+// timerErrorStatus maps timer errors to HTTP status codes
+func timerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, timers.ErrTimerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, timers.ErrTimerExists):
+		return http.StatusConflict
+	case errors.Is(err, timers.ErrInvalidTimer):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Helper methods
+
+func (h *TimersHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *TimersHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *TimersHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
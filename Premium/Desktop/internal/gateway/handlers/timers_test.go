@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/testutils"
+	"waddlebot-bridge/internal/timers"
+)
+
+func TestTimersHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	manager, err := timers.NewManager(testutils.NewMockStorage(), logger)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	handler := NewTimersHandler(manager, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/timers", handler.CreateTimer).Methods("POST")
+	router.HandleFunc("/timers/{name}", handler.GetTimer).Methods("GET")
+	router.HandleFunc("/timers/{name}", handler.RemoveTimer).Methods("DELETE")
+	router.HandleFunc("/timers/{name}/{action}", handler.ControlTimer).Methods("POST")
+
+	body := `{"name":"starting","mode":"countdown","duration_seconds":300,"start":true}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/timers", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var status timers.Status
+	json.NewDecoder(rec.Body).Decode(&status)
+	if !status.Running {
+		t.Errorf("Expected the timer to be started, got %+v", status)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/timers", strings.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a taken name, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/timers/starting/pause", nil))
+	json.NewDecoder(rec.Body).Decode(&status)
+	if rec.Code != http.StatusOK || status.Running {
+		t.Errorf("Expected the timer to be paused, got %d: %+v", rec.Code, status)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/timers/starting/add", strings.NewReader(`{"seconds":60}`)))
+	json.NewDecoder(rec.Body).Decode(&status)
+	if rec.Code != http.StatusOK || status.Duration != 360 {
+		t.Errorf("Expected a minute to be added, got %d: %+v", rec.Code, status)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/timers/starting/rewind", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown action, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("DELETE", "/timers/starting", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 when removing, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/timers/starting", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed timer, got %d", rec.Code)
+	}
+}
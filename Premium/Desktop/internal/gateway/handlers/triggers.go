@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/commands"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/triggers"
+)
+
+// TriggerStore manages the triggers
+type TriggerStore interface {
+	List() []triggers.Trigger
+	Get(id string) (triggers.Trigger, error)
+	Create(trigger triggers.Trigger) (triggers.Trigger, error)
+	Update(id string, trigger triggers.Trigger) (triggers.Trigger, error)
+	Remove(id string) error
+	Test(trigger triggers.Trigger, input triggers.Input) (triggers.Evaluation, error)
+}
+
+// TriggersHandler handles trigger endpoints
+type TriggersHandler struct {
+	store  TriggerStore
+	logger *logrus.Logger
+}
+
+// NewTriggersHandler creates a new triggers handler
+func NewTriggersHandler(store TriggerStore, logger *logrus.Logger) *TriggersHandler {
+	return &TriggersHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// TriggerRequest creates or replaces a trigger
+type TriggerRequest struct {
+	Name            string               `json:"name"`
+	Enabled         *bool                `json:"enabled,omitempty"` // defaults to true
+	Event           string               `json:"event,omitempty"`
+	Conditions      []triggers.Condition `json:"conditions,omitempty"`
+	Actions         []triggers.Action    `json:"actions"`
+	CooldownSeconds int                  `json:"cooldown_seconds"`
+}
+
+// scope returns the scope a key needs for the trigger's actions: obs:write
+// when one runs a macro, scripts:run otherwise
+func (req TriggerRequest) scope() string {
+	for _, action := range req.Actions {
+		if scope := commands.Scope(action.Command); scope != apikeys.ScopeScriptsRun {
+			return scope
+		}
+	}
+	return apikeys.ScopeScriptsRun
+}
+
+// trigger converts the request to a trigger
+func (req TriggerRequest) trigger() triggers.Trigger {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return triggers.Trigger{
+		Name:            req.Name,
+		Enabled:         enabled,
+		Event:           req.Event,
+		Conditions:      req.Conditions,
+		Actions:         req.Actions,
+		CooldownSeconds: req.CooldownSeconds,
+	}
+}
+
+// TriggerTestRequest evaluates a stored trigger, given by ID, or one given
+// inline against a synthetic event, metrics and time. Metrics left out are
+// the latest sampled, and the time defaults to now.
+type TriggerTestRequest struct {
+	ID      string             `json:"id,omitempty"`
+	Trigger *TriggerRequest    `json:"trigger,omitempty"`
+	Event   *events.Event      `json:"event,omitempty"`
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	Time    *time.Time         `json:"time,omitempty"`
+}
+
+// ListTriggers returns all triggers
+func (h *TriggersHandler) ListTriggers(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "triggers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	list := h.store.List()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"triggers": list,
+		"count":    len(list),
+	})
+}
+
+// GetTrigger returns a trigger
+func (h *TriggersHandler) GetTrigger(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "triggers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	trigger, err := h.store.Get(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendError(w, err.Error(), triggerErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trigger)
+}
+
+// CreateTrigger creates a trigger
+func (h *TriggersHandler) CreateTrigger(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "triggers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req TriggerRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	if scope := req.scope(); !hasScope(r, scope) {
+		h.sendError(w, fmt.Sprintf("API key lacks the %s scope", scope), http.StatusForbidden)
+		return
+	}
+
+	trigger, err := h.store.Create(req.trigger())
+	if err != nil {
+		h.sendError(w, err.Error(), triggerErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":    trigger.ID,
+		"name":  trigger.Name,
+		"event": trigger.Event,
+	}).Info("Trigger created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(trigger)
+}
+
+// UpdateTrigger replaces a trigger
+func (h *TriggersHandler) UpdateTrigger(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "triggers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req TriggerRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+	if scope := req.scope(); !hasScope(r, scope) {
+		h.sendError(w, fmt.Sprintf("API key lacks the %s scope", scope), http.StatusForbidden)
+		return
+	}
+
+	trigger, err := h.store.Update(mux.Vars(r)["id"], req.trigger())
+	if err != nil {
+		h.sendError(w, err.Error(), triggerErrorStatus(err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":   trigger.ID,
+		"name": trigger.Name,
+	}).Info("Trigger updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trigger)
+}
+
+// RemoveTrigger deletes a trigger
+func (h *TriggersHandler) RemoveTrigger(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "triggers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := h.store.Remove(id); err != nil {
+		h.sendError(w, err.Error(), triggerErrorStatus(err))
+		return
+	}
+
+	h.logger.WithField("id", id).Info("Trigger removed")
+
+	h.sendSuccess(w, "Trigger removed")
+}
+
+// TestTrigger evaluates a trigger against a synthetic event without
+// running its actions
+func (h *TriggersHandler) TestTrigger(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.sendError(w, "triggers not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req TriggerTestRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
+		return
+	}
+
+	var trigger triggers.Trigger
+	switch {
+	case req.ID != "" && req.Trigger != nil:
+		h.sendValidationError(w, Invalid("trigger", "give either id or trigger"))
+		return
+	case req.Trigger != nil:
+		trigger = req.Trigger.trigger()
+	case req.ID != "":
+		stored, err := h.store.Get(req.ID)
+		if err != nil {
+			h.sendError(w, err.Error(), triggerErrorStatus(err))
+			return
+		}
+		trigger = stored
+	default:
+		h.sendValidationError(w, &ValidationError{Code: CodeRequired, Field: "id", Message: "id or trigger is required"})
+		return
+	}
+	if req.Event != nil {
+		if err := Required("event.type", req.Event.Type); err != nil {
+			h.sendValidationError(w, err)
+			return
+		}
+	}
+
+	input := triggers.Input{Event: req.Event, Metrics: req.Metrics}
+	if req.Time != nil {
+		input.Time = *req.Time
+	}
+	evaluation, err := h.store.Test(trigger, input)
+	if err != nil {
+		h.sendError(w, err.Error(), triggerErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(evaluation)
+}
+
+// triggerErrorStatus maps trigger errors to HTTP status codes
+func triggerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, triggers.ErrTriggerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, triggers.ErrInvalidTrigger):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Helper methods
+
+func (h *TriggersHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
+func (h *TriggersHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, errors.New(message), statusCode)
+}
+
+func (h *TriggersHandler) sendSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
+}
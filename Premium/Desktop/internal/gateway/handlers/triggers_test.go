@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/triggers"
+)
+
+type fakeTriggers struct {
+	triggers map[string]triggers.Trigger
+	tested   triggers.Input
+}
+
+func (f *fakeTriggers) List() []triggers.Trigger {
+	list := make([]triggers.Trigger, 0, len(f.triggers))
+	for _, trigger := range f.triggers {
+		list = append(list, trigger)
+	}
+	return list
+}
+
+func (f *fakeTriggers) Get(id string) (triggers.Trigger, error) {
+	trigger, exists := f.triggers[id]
+	if !exists {
+		return triggers.Trigger{}, fmt.Errorf("%w: %s", triggers.ErrTriggerNotFound, id)
+	}
+	return trigger, nil
+}
+
+func (f *fakeTriggers) Create(trigger triggers.Trigger) (triggers.Trigger, error) {
+	if err := triggers.Validate(trigger); err != nil {
+		return triggers.Trigger{}, err
+	}
+	trigger.ID = "trigger_1"
+	f.triggers[trigger.ID] = trigger
+	return trigger, nil
+}
+
+func (f *fakeTriggers) Update(id string, trigger triggers.Trigger) (triggers.Trigger, error) {
+	if _, err := f.Get(id); err != nil {
+		return triggers.Trigger{}, err
+	}
+	trigger.ID = id
+	f.triggers[id] = trigger
+	return trigger, nil
+}
+
+func (f *fakeTriggers) Remove(id string) error {
+	if _, err := f.Get(id); err != nil {
+		return err
+	}
+	delete(f.triggers, id)
+	return nil
+}
+
+func (f *fakeTriggers) Test(trigger triggers.Trigger, input triggers.Input) (triggers.Evaluation, error) {
+	if err := triggers.Validate(trigger); err != nil {
+		return triggers.Evaluation{}, err
+	}
+	f.tested = input
+	return triggers.Evaluation{Fires: input.Event != nil && input.Event.Type == trigger.Event, EventMatched: true}, nil
+}
+
+func TestTriggersHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store := &fakeTriggers{triggers: make(map[string]triggers.Trigger)}
+	handler := NewTriggersHandler(store, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/triggers", handler.ListTriggers).Methods("GET")
+	router.HandleFunc("/triggers", handler.CreateTrigger).Methods("POST")
+	router.HandleFunc("/triggers/test", handler.TestTrigger).Methods("POST")
+	router.HandleFunc("/triggers/{id}", handler.GetTrigger).Methods("GET")
+	router.HandleFunc("/triggers/{id}", handler.UpdateTrigger).Methods("PUT")
+	router.HandleFunc("/triggers/{id}", handler.RemoveTrigger).Methods("DELETE")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	body := `{"name":"Go live","event":"obs.stream_started","conditions":[{"type":"metric","metric":"cpu_percent","op":"<","value":90}],"actions":[{"command":"macro:go_live"}]}`
+	rec := do("POST", "/triggers", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created triggers.Trigger
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.ID != "trigger_1" || !created.Enabled || len(created.Conditions) != 1 {
+		t.Errorf("Unexpected trigger: %+v", created)
+	}
+
+	if rec := do("POST", "/triggers", `{"name":"Go live","event":"obs.stream_started","actions":[{"command":"obs:start_stream"}]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid trigger, got %d", rec.Code)
+	}
+
+	rec = do("POST", "/triggers/test", `{"id":"trigger_1","event":{"type":"obs.stream_started","data":{"active":true}},"metrics":{"cpu_percent":95},"time":"2026-10-16T19:30:00Z"}`)
+	var evaluation triggers.Evaluation
+	json.NewDecoder(rec.Body).Decode(&evaluation)
+	if rec.Code != http.StatusOK || !evaluation.Fires {
+		t.Errorf("Expected the stored trigger evaluated, got %d: %+v", rec.Code, evaluation)
+	}
+	if store.tested.Metrics["cpu_percent"] != 95 || store.tested.Time.Hour() != 19 {
+		t.Errorf("Expected the synthetic metrics and time passed on, got %+v", store.tested)
+	}
+
+	if rec := do("POST", "/triggers/test", `{"trigger":`+body+`,"event":{"type":"obs.scene_changed"}}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected an inline trigger evaluated, got %d", rec.Code)
+	}
+	if rec := do("POST", "/triggers/test", `{"event":{"type":"obs.stream_started"}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a trigger, got %d", rec.Code)
+	}
+	if rec := do("POST", "/triggers/test", `{"id":"trigger_2"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown trigger, got %d", rec.Code)
+	}
+
+	rec = do("PUT", "/triggers/trigger_1", strings.Replace(body, `"name":"Go live"`, `"name":"Go live","enabled":false`, 1))
+	var updated triggers.Trigger
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if rec.Code != http.StatusOK || updated.Enabled {
+		t.Errorf("Expected the trigger to be disabled, got %d: %+v", rec.Code, updated)
+	}
+
+	if rec := do("DELETE", "/triggers/trigger_1", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 when removing, got %d", rec.Code)
+	}
+	if rec := do("GET", "/triggers/trigger_1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed trigger, got %d", rec.Code)
+	}
+}
+
+func TestTriggersHandler_Scopes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := NewTriggersHandler(&fakeTriggers{triggers: make(map[string]triggers.Trigger)}, logger)
+
+	scripts := apikeys.Key{Scopes: []string{apikeys.ScopeScriptsRun}}
+	obs := apikeys.Key{Scopes: []string{apikeys.ScopeScriptsRun, apikeys.ScopeOBSWrite}}
+	macro := `{"name":"Go live","event":"obs.stream_started","actions":[{"command":"script:intro.lua"},{"command":"macro:go_live"}]}`
+	script := `{"name":"Intro","event":"obs.stream_started","actions":[{"command":"script:intro.lua"}]}`
+
+	tests := []struct {
+		name string
+		key  apikeys.Key
+		body string
+		want int
+	}{
+		{"macro without obs:write", scripts, macro, http.StatusForbidden},
+		{"macro with obs:write", obs, macro, http.StatusCreated},
+		{"script", scripts, script, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/triggers", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.CreateTrigger(rec, req.WithContext(apikeys.WithKey(req.Context(), tt.key)))
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTriggersHandler_Unavailable(t *testing.T) {
+	handler := NewTriggersHandler(nil, logrus.New())
+	rec := httptest.NewRecorder()
+	handler.ListTriggers(rec, httptest.NewRequest("GET", "/triggers", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
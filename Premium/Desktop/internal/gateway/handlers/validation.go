@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error codes returned in ErrorResponse
+const (
+	CodeInvalidJSON  = "invalid_json"
+	CodeEmptyBody    = "empty_body"
+	CodeBodyTooLarge = "body_too_large"
+	CodeUnknownField = "unknown_field"
+	CodeInvalidType  = "invalid_type"
+	CodeRequired     = "required"
+	CodeInvalidValue = "invalid_value"
+)
+
+// ValidationError describes an invalid request. Field names the offending
+// JSON field when there is one.
+type ValidationError struct {
+	Code    string
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Status returns the HTTP status code for the error
+func (e *ValidationError) Status() int {
+	if e.Code == CodeBodyTooLarge {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// DecodeJSON strictly decodes a JSON request body into dst. Unknown fields,
+// trailing data and bodies over the gateway's size limit are rejected with
+// a *ValidationError.
+func DecodeJSON(r *http.Request, dst interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return &ValidationError{Code: CodeInvalidJSON, Message: "request body must contain a single JSON value"}
+	}
+	return nil
+}
+
+// decodeError converts a JSON decoding error into a *ValidationError
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return &ValidationError{Code: CodeEmptyBody, Message: "request body is required"}
+	case errors.As(err, &sizeErr):
+		return &ValidationError{Code: CodeBodyTooLarge, Message: fmt.Sprintf("request body exceeds %d bytes", sizeErr.Limit)}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &ValidationError{Code: CodeInvalidJSON, Message: "invalid JSON: " + err.Error()}
+	case errors.As(err, &typeErr):
+		return &ValidationError{
+			Code:    CodeInvalidType,
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type),
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &ValidationError{Code: CodeUnknownField, Field: field, Message: "unknown field " + field}
+	default:
+		return &ValidationError{Code: CodeInvalidJSON, Message: "invalid request body: " + err.Error()}
+	}
+}
+
+// Required returns a *ValidationError when a required field is empty
+func Required(field, value string) error {
+	if value == "" {
+		return &ValidationError{Code: CodeRequired, Field: field, Message: field + " is required"}
+	}
+	return nil
+}
+
+// Invalid returns a *ValidationError for a field with an unacceptable value
+func Invalid(field, message string) error {
+	return &ValidationError{Code: CodeInvalidValue, Field: field, Message: message}
+}
+
+// writeError writes an error response. Validation errors keep their code,
+// field and status; other errors get a code derived from statusCode.
+func writeError(w http.ResponseWriter, err error, statusCode int) {
+	response := ErrorResponse{Error: err.Error(), Message: err.Error(), Code: statusErrorCode(statusCode)}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		statusCode = validationErr.Status()
+		response.Code = validationErr.Code
+		response.Field = validationErr.Field
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// statusErrorCode returns the error code for an HTTP status code
+func statusErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusRequestEntityTooLarge:
+		return CodeBodyTooLarge
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	case http.StatusGatewayTimeout:
+		return "timeout"
+	case http.StatusBadGateway:
+		return "bad_gateway"
+	default:
+		if statusCode >= 500 {
+			return "internal_error"
+		}
+		return strings.ReplaceAll(strings.ToLower(http.StatusText(statusCode)), " ", "_")
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type request struct {
+		Name   string `json:"name"`
+		Volume int    `json:"volume"`
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		code  string
+		field string
+	}{
+		{"valid", `{"name":"intro","volume":50}`, "", ""},
+		{"empty", ``, CodeEmptyBody, ""},
+		{"syntax", `{"name":`, CodeInvalidJSON, ""},
+		{"unknown field", `{"name":"intro","volum":50}`, CodeUnknownField, "volum"},
+		{"wrong type", `{"volume":"loud"}`, CodeInvalidType, "volume"},
+		{"trailing data", `{"name":"a"}{"name":"b"}`, CodeInvalidJSON, ""},
+		{"too large", `{"name":"` + strings.Repeat("a", 64) + `"}`, CodeBodyTooLarge, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, 48)
+
+			var req request
+			err := DecodeJSON(r, &req)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected a ValidationError, got %v", err)
+			}
+			if validationErr.Code != tt.code || validationErr.Field != tt.field {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.code, tt.field, validationErr.Code, validationErr.Field)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeError(rec, Required("scene_name", ""), http.StatusInternalServerError)
+
+	var response ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a validation error, got %d", rec.Code)
+	}
+	if response.Code != CodeRequired || response.Field != "scene_name" || response.Message != "scene_name is required" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+
+	rec = httptest.NewRecorder()
+	writeError(rec, errors.New("module not found"), http.StatusNotFound)
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Code != "not_found" || response.Error != "module not found" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+}
@@ -1,52 +1,58 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"sync"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/webhooks"
 )
 
+// WebhookRegistry stores registered webhooks and their delivery history
+type WebhookRegistry interface {
+	List() []webhooks.Webhook
+	Register(url string, eventTypes []string, secret string) (webhooks.Webhook, error)
+	Remove(id string) error
+	Test(ctx context.Context, id string) (events.Delivery, error)
+	Deliveries(id string, limit int) ([]events.Delivery, error)
+}
+
 // WebhookHandler handles webhook-related endpoints
 type WebhookHandler struct {
+	registry WebhookRegistry
 	logger   *logrus.Logger
-	webhooks map[string]*Webhook
-	mu       sync.RWMutex
-}
-
-// Webhook represents a registered webhook
-type Webhook struct {
-	ID     string   `json:"id"`
-	URL    string   `json:"url"`
-	Events []string `json:"events"`
-	Secret string   `json:"secret,omitempty"`
 }
 
 // NewWebhookHandler creates a new webhook handler
-func NewWebhookHandler(logger *logrus.Logger) *WebhookHandler {
+func NewWebhookHandler(registry WebhookRegistry, logger *logrus.Logger) *WebhookHandler {
 	return &WebhookHandler{
+		registry: registry,
 		logger:   logger,
-		webhooks: make(map[string]*Webhook),
 	}
 }
 
 // ListWebhooks returns all registered webhooks
 func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	if h.registry == nil {
+		h.sendError(w, "webhooks not available", http.StatusServiceUnavailable)
+		return
+	}
 
-	webhooks := make([]*Webhook, 0, len(h.webhooks))
-	for _, wh := range h.webhooks {
+	list := h.registry.List()
+	for i := range list {
 		// Don't expose secrets
-		wh.Secret = ""
-		webhooks = append(webhooks, wh)
+		list[i].Secret = ""
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"webhooks": webhooks,
+		"webhooks": list,
 	})
 }
 
@@ -59,38 +65,37 @@ type RegisterWebhookRequest struct {
 
 // RegisterWebhook registers a new webhook
 func (h *WebhookHandler) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.registry == nil {
+		h.sendError(w, "webhooks not available", http.StatusServiceUnavailable)
+		return
+	}
+
 	var req RegisterWebhookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := DecodeJSON(r, &req); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
-	if req.URL == "" {
-		h.sendError(w, "url is required", http.StatusBadRequest)
+	if err := Required("url", req.URL); err != nil {
+		h.sendValidationError(w, err)
 		return
 	}
 
 	if len(req.Events) == 0 {
-		h.sendError(w, "at least one event is required", http.StatusBadRequest)
+		h.sendValidationError(w, Invalid("events", "at least one event is required"))
 		return
 	}
 
-	// Generate ID (simple implementation)
-	h.mu.Lock()
-	id := generateID()
-	webhook := &Webhook{
-		ID:     id,
-		URL:    req.URL,
-		Events: req.Events,
-		Secret: req.Secret,
+	webhook, err := h.registry.Register(req.URL, req.Events, req.Secret)
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	h.webhooks[id] = webhook
-	h.mu.Unlock()
 
 	h.logger.WithFields(logrus.Fields{
-		"id":     id,
-		"url":    req.URL,
-		"events": req.Events,
+		"id":     webhook.ID,
+		"url":    webhook.URL,
+		"events": webhook.Events,
 	}).Info("Webhook registered")
 
 	// Don't return secret in response
@@ -103,63 +108,102 @@ func (h *WebhookHandler) RegisterWebhook(w http.ResponseWriter, r *http.Request)
 
 // RemoveWebhook removes a registered webhook
 func (h *WebhookHandler) RemoveWebhook(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	h.mu.Lock()
-	_, exists := h.webhooks[id]
-	if !exists {
-		h.mu.Unlock()
-		h.sendError(w, "webhook not found", http.StatusNotFound)
+	if h.registry == nil {
+		h.sendError(w, "webhooks not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	delete(h.webhooks, id)
-	h.mu.Unlock()
+	id := mux.Vars(r)["id"]
+	if err := h.registry.Remove(id); err != nil {
+		h.sendError(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
 
 	h.logger.WithField("id", id).Info("Webhook removed")
 
 	h.sendSuccess(w, "Webhook removed")
 }
 
-// TestWebhook tests a webhook delivery
+// TestWebhook sends a test event to a webhook and returns the delivery
 func (h *WebhookHandler) TestWebhook(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	h.mu.RLock()
-	webhook, exists := h.webhooks[id]
-	h.mu.RUnlock()
+	if h.registry == nil {
+		h.sendError(w, "webhooks not available", http.StatusServiceUnavailable)
+		return
+	}
 
-	if !exists {
-		h.sendError(w, "webhook not found", http.StatusNotFound)
+	id := mux.Vars(r)["id"]
+	delivery, err := h.registry.Test(r.Context(), id)
+	if errors.Is(err, webhooks.ErrWebhookNotFound) {
+		h.sendError(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	// TODO: Implement actual webhook delivery test
 	h.logger.WithFields(logrus.Fields{
-		"id":  id,
-		"url": webhook.URL,
-	}).Info("Testing webhook delivery")
+		"id":       id,
+		"url":      delivery.URL,
+		"success":  delivery.Success,
+		"attempts": delivery.Attempts,
+	}).Info("Tested webhook delivery")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  err == nil,
+		"delivery": delivery,
+	})
+}
+
+// GetDeliveries returns the delivery history of a webhook, newest first
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	if h.registry == nil {
+		h.sendError(w, "webhooks not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			h.sendError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := h.registry.Deliveries(mux.Vars(r)["id"], limit)
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	h.sendSuccess(w, "Test webhook sent to "+webhook.URL)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// webhookErrorStatus maps webhook errors to HTTP status codes
+func webhookErrorStatus(err error) int {
+	if errors.Is(err, webhooks.ErrWebhookNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
 }
 
 // Helper methods
 
+func (h *WebhookHandler) sendValidationError(w http.ResponseWriter, err error) {
+	writeError(w, err, http.StatusBadRequest)
+}
+
 func (h *WebhookHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	writeError(w, errors.New(message), statusCode)
 }
 
 func (h *WebhookHandler) sendSuccess(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Message: message})
 }
-
-// generateID generates a simple webhook ID
-func generateID() string {
-	// TODO: Use proper UUID generation
-	return "webhook_" + string(rune(len("temp")))
-}
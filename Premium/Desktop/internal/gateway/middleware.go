@@ -1,12 +1,20 @@
 package gateway
 
 import (
+	"crypto/subtle"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/auth"
+	"waddlebot-bridge/internal/metrics"
 )
 
 // loggingMiddleware logs all HTTP requests
@@ -30,6 +38,27 @@ func (g *Gateway) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// metricsMiddleware records request counts and latencies per route. The
+// route template is used as the label so path parameters do not create a
+// series per value.
+func (g *Gateway) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(rw, r)
+
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		metrics.GatewayRequests.WithLabelValues(route, r.Method, strconv.Itoa(rw.statusCode)).Inc()
+		metrics.GatewayRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -44,8 +73,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 // authMiddleware validates API key authentication
 func (g *Gateway) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth for health check
-		if r.URL.Path == "/health" {
+		// Skip auth for health check and overlay files, which browser
+		// sources load without headers; overlays pass their key to the API
+		if r.URL.Path == "/health" || (g.config.Overlays.Enabled && strings.HasPrefix(r.URL.Path, overlaysPrefix)) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -57,8 +87,14 @@ func (g *Gateway) authMiddleware(next http.Handler) http.Handler {
 			apiKey = r.URL.Query().Get("api_key")
 		}
 
-		// Validate API key
-		if apiKey != g.config.APIKey {
+		var key apikeys.Key
+		var ok bool
+		if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); apiKey == "" && found {
+			key, ok = g.authenticateBearer(bearer)
+		} else {
+			key, ok = g.authenticate(apiKey)
+		}
+		if !ok {
 			g.logger.WithFields(logrus.Fields{
 				"path":        r.URL.Path,
 				"remote_addr": r.RemoteAddr,
@@ -68,6 +104,125 @@ func (g *Gateway) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if scope := g.requiredScope(r); !key.HasScope(scope) {
+			g.logger.WithFields(logrus.Fields{
+				"path":  r.URL.Path,
+				"key":   key.ID,
+				"scope": scope,
+			}).Warn("API key lacks required scope")
+
+			http.Error(w, fmt.Sprintf(`{"error":"forbidden","required_scope":%q}`, scope), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(apikeys.WithKey(r.Context(), key)))
+	})
+}
+
+// authenticate returns the key a secret belongs to. The static admin key
+// grants every scope; other secrets are looked up in the key store.
+func (g *Gateway) authenticate(secret string) (apikeys.Key, bool) {
+	if secret == "" {
+		return apikeys.Key{}, false
+	}
+	if g.adminKey != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(g.adminKey)) == 1 {
+		return apikeys.AdminKey, true
+	}
+	if g.apiKeys == nil {
+		return apikeys.Key{}, false
+	}
+	key, err := g.apiKeys.Authenticate(secret)
+	if err != nil {
+		return apikeys.Key{}, false
+	}
+	return key, true
+}
+
+// roleScopes are the scopes of each WebAuthn user role. Operators control
+// OBS and run commands but cannot change bridge settings.
+var roleScopes = map[auth.Role][]string{
+	auth.RoleOwner: {apikeys.ScopeAdmin},
+	auth.RoleOperator: {
+		apikeys.ScopeOBSRead,
+		apikeys.ScopeOBSWrite,
+		apikeys.ScopeScriptsRun,
+		apikeys.ScopeOverlaysWrite,
+		apikeys.ScopeEventsRead,
+		apikeys.ScopeBridgeRead,
+	},
+	auth.RoleViewer: {
+		apikeys.ScopeOBSRead,
+		apikeys.ScopeEventsRead,
+		apikeys.ScopeBridgeRead,
+	},
+}
+
+// authenticateBearer returns the key for a bearer token: an API key
+// secret, or the session token of a signed in user, who gets the scopes of
+// their role
+func (g *Gateway) authenticateBearer(token string) (apikeys.Key, bool) {
+	if strings.HasPrefix(token, apikeys.SecretPrefix) || g.sessions == nil {
+		return g.authenticate(token)
+	}
+
+	session, err := g.sessions.ValidateJWT(token)
+	if err != nil {
+		return apikeys.Key{}, false
+	}
+	scopes, ok := roleScopes[auth.Role(session.Role)]
+	if !ok {
+		return apikeys.Key{}, false
+	}
+	return apikeys.Key{
+		ID:     "user:" + session.UserID,
+		Name:   session.UserID,
+		Scopes: scopes,
+	}, true
+}
+
+// scopeRoutes tags every route of a router with the scope a key needs to
+// use it: readScope for GET requests and writeScope for the others
+func (g *Gateway) scopeRoutes(router *mux.Router, readScope, writeScope string) {
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		scope := writeScope
+		if methods, err := route.GetMethods(); err == nil && len(methods) == 1 && methods[0] == http.MethodGet {
+			scope = readScope
+		}
+		g.routeScopes[route] = scope
+		return nil
+	})
+}
+
+// requiredScope returns the scope needed for the matched route. Routes
+// without a scope require the admin scope.
+func (g *Gateway) requiredScope(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if scope, ok := g.routeScopes[route]; ok {
+			return scope
+		}
+	}
+	return apikeys.ScopeAdmin
+}
+
+// bodyLimitMiddleware rejects request bodies over the configured size.
+// Bodies without a declared length are cut off at the limit, which the
+// handlers report when decoding.
+func (g *Gateway) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := g.config.MaxBodySize
+		if limit <= 0 || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.ContentLength > limit {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			fmt.Fprintf(w, `{"error":"request body exceeds %d bytes","code":"body_too_large"}`+"\n", limit)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -123,22 +278,33 @@ func (g *Gateway) getRateLimiter(ip string) *rate.Limiter {
 	}
 
 	// Create limiter with configured RPS and burst of 2x
-	limiter = rate.NewLimiter(rate.Limit(g.config.RateLimitRPS), g.config.RateLimitRPS*2)
+	g.limitsMux.RLock()
+	rps := g.config.RateLimitRPS
+	g.limitsMux.RUnlock()
+	limiter = rate.NewLimiter(rate.Limit(rps), rps*2)
 	g.rateLimiters[ip] = limiter
 
 	return limiter
 }
 
-// corsMiddleware adds CORS headers
+// corsMiddleware adds CORS headers while CORS is enabled
 func (g *Gateway) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.limitsMux.RLock()
+		enabled := g.config.EnableCORS
+		g.limitsMux.RUnlock()
+		if !enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		origin := r.Header.Get("Origin")
 
 		// Check if origin is allowed
 		if origin != "" && g.isOriginAllowed(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, Authorization")
 			w.Header().Set("Access-Control-Max-Age", "86400")
 		}
 
@@ -154,6 +320,9 @@ func (g *Gateway) corsMiddleware(next http.Handler) http.Handler {
 
 // isOriginAllowed checks if an origin is in the allowed list
 func (g *Gateway) isOriginAllowed(origin string) bool {
+	g.limitsMux.RLock()
+	defer g.limitsMux.RUnlock()
+
 	// If no origins configured, allow all
 	if len(g.config.AllowedOrigins) == 0 {
 		return true
@@ -0,0 +1,263 @@
+package gateway
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/metrics"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/testutils"
+)
+
+func TestAuthMiddleware_Scopes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	keys, err := apikeys.NewStore(testutils.NewMockStorage(), logger)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	_, readOnly, _ := keys.Create("overlay", []string{apikeys.ScopeOBSRead, apikeys.ScopeBridgeRead})
+
+	g := New(config.GatewayConfig{
+		EnableAuth:   true,
+		APIKey:       "static-admin",
+		RateLimitRPS: 1000,
+	}, Services{APIKeys: keys}, logger)
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		want   int
+	}{
+		{"no key", "", "GET", "/api/v1/obs/status", http.StatusUnauthorized},
+		{"unknown key", "wbk_unknown", "GET", "/api/v1/obs/status", http.StatusUnauthorized},
+		{"read allowed", readOnly, "GET", "/api/v1/policy", http.StatusServiceUnavailable},
+		{"manage denied", readOnly, "POST", "/api/v1/policy/reload", http.StatusForbidden},
+		{"write denied", readOnly, "POST", "/api/v1/obs/recording/start", http.StatusForbidden},
+		{"scripts denied", readOnly, "POST", "/api/v1/commands/execute", http.StatusForbidden},
+		{"keys denied", readOnly, "GET", "/api/v1/keys", http.StatusForbidden},
+		{"admin allowed", "static-admin", "GET", "/api/v1/keys", http.StatusOK},
+		{"health open", "", "GET", "/health", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			g.router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_CommandScopes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	keys, err := apikeys.NewStore(testutils.NewMockStorage(), logger)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	_, scripts, _ := keys.Create("scripts", []string{apikeys.ScopeScriptsRun})
+	_, obs, _ := keys.Create("obs", []string{apikeys.ScopeScriptsRun, apikeys.ScopeOBSWrite})
+
+	catalog := &fakeCatalog{}
+	g := New(config.GatewayConfig{
+		EnableAuth:   true,
+		APIKey:       "static-admin",
+		RateLimitRPS: 1000,
+	}, Services{APIKeys: keys, Commands: catalog}, logger)
+
+	tests := []struct {
+		name    string
+		key     string
+		command string
+		want    int
+	}{
+		{"OBS action denied", scripts, "obs:set_scene", http.StatusForbidden},
+		{"macro denied", scripts, "macro:go_live", http.StatusForbidden},
+		{"script allowed", scripts, "script:intro.lua", http.StatusInternalServerError},
+		{"OBS action allowed", obs, "obs:set_scene", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog.executed = ""
+			req := httptest.NewRequest("POST", "/api/v1/commands/execute", strings.NewReader(`{"id":"`+tt.command+`","parameters":{"scene":"Gameplay"}}`))
+			req.Header.Set("X-API-Key", tt.key)
+			rec := httptest.NewRecorder()
+			g.router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if rec.Code == http.StatusForbidden && catalog.executed != "" {
+				t.Errorf("Expected nothing run without the scope, got %s", catalog.executed)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_OBSSourceScope(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	keys, err := apikeys.NewStore(testutils.NewMockStorage(), logger)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	_, manage, _ := keys.Create("manage", []string{apikeys.ScopeBridgeManage})
+	_, obs, _ := keys.Create("obs", []string{apikeys.ScopeOBSWrite})
+
+	g := New(config.GatewayConfig{
+		EnableAuth:   true,
+		APIKey:       "static-admin",
+		RateLimitRPS: 1000,
+	}, Services{APIKeys: keys}, logger)
+
+	// Past the scope check, the request finds calls disabled
+	for key, want := range map[string]int{manage: http.StatusForbidden, obs: http.StatusServiceUnavailable} {
+		req := httptest.NewRequest("POST", "/api/v1/rtc/rooms/community_7_lobby/obs-source", strings.NewReader(`{"scene":"Call","name":"Guests"}`))
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		g.router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Expected status %d, got %d: %s", want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+type fakeSessions map[string]string
+
+func (f fakeSessions) ValidateJWT(token string) (*models.AuthSession, error) {
+	role, ok := f[token]
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+	return &models.AuthSession{UserID: token, Role: role}, nil
+}
+
+func TestAuthMiddleware_UserRoles(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	g := New(config.GatewayConfig{
+		EnableAuth:   true,
+		APIKey:       "wbk_static-admin",
+		RateLimitRPS: 1000,
+	}, Services{Sessions: fakeSessions{
+		"streamer": "owner",
+		"cohost":   "operator",
+		"guest":    "viewer",
+	}}, logger)
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{"unknown session", "expired", "GET", "/api/v1/bridge/status", http.StatusUnauthorized},
+		{"viewer reads", "guest", "GET", "/api/v1/bridge/status", http.StatusOK},
+		{"viewer cannot control OBS", "guest", "POST", "/api/v1/obs/recording/start", http.StatusForbidden},
+		{"operator runs commands", "cohost", "POST", "/api/v1/commands/execute", http.StatusServiceUnavailable},
+		{"operator cannot change settings", "cohost", "POST", "/api/v1/policy/reload", http.StatusForbidden},
+		{"operator cannot manage keys", "cohost", "GET", "/api/v1/keys", http.StatusForbidden},
+		{"owner manages the bridge", "streamer", "POST", "/api/v1/policy/reload", http.StatusServiceUnavailable},
+		{"api key as bearer", "wbk_static-admin", "GET", "/api/v1/keys", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			g.router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMetricsMiddleware_RouteTemplate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	g := New(config.GatewayConfig{RateLimitRPS: 1000, EnableMetrics: true}, Services{OBS: obs.NewClient(obs.DefaultConfig(), logger)}, logger)
+	for _, scene := range []string{"Gameplay", "Just%20Chatting"} {
+		g.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/obs/scenes/"+scene+"/sources", nil))
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	series := 0
+	for _, line := range strings.Split(string(body), "\n") {
+		if !strings.HasPrefix(line, "waddlebot_bridge_gateway_requests_total{") {
+			continue
+		}
+		if strings.Contains(line, "Gameplay") || strings.Contains(line, "Chatting") {
+			t.Errorf("Expected no series per scene, got %s", line)
+		}
+		if strings.Contains(line, `method="GET",route="/api/v1/obs/scenes/{name}/sources"`) {
+			series++
+		}
+	}
+	if series != 1 {
+		t.Errorf("Expected both requests counted under the route template, got %d series", series)
+	}
+}
+
+func TestGateway_SetLimits(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	g := New(config.GatewayConfig{RateLimitRPS: 1000}, Services{}, logger)
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/policy", nil)
+		req.Header.Set("Origin", "http://overlay.local")
+		rec := httptest.NewRecorder()
+		g.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request(); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected no CORS headers while CORS is disabled")
+	}
+
+	g.SetLimits(config.GatewayConfig{
+		EnableCORS:     true,
+		AllowedOrigins: []string{"http://overlay.local"},
+		RateLimitRPS:   1,
+	})
+
+	if rec := request(); rec.Header().Get("Access-Control-Allow-Origin") != "http://overlay.local" {
+		t.Error("Expected CORS headers once CORS is enabled")
+	}
+	request()
+	if rec := request(); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the new rate limit to apply, got %d", rec.Code)
+	}
+}
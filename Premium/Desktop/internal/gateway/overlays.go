@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// overlaysPrefix is the path overlays are served under
+const overlaysPrefix = "/overlays/"
+
+// builtinOverlays are overlays shipped with the bridge, served under
+// /overlays/waddlebot/ unless the overlays directory has the same file
+//
+//go:embed overlay_assets
+var builtinOverlays embed.FS
+
+// OverlaySettings are the connection settings injected into overlay pages
+// as window.WaddleBot, so overlays can reach the gateway without
+// hard-coding its address
+type OverlaySettings struct {
+	WSURL  string `json:"wsUrl"`
+	APIURL string `json:"apiUrl"`
+	APIKey string `json:"apiKey,omitempty"`
+}
+
+// serveOverlay serves a file from the overlays directory. HTML pages get
+// the connection settings injected and are always revalidated; other files
+// are cached for the configured time.
+func (g *Gateway) serveOverlay(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + strings.TrimPrefix(r.URL.Path, overlaysPrefix))
+	if hiddenPath(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	assets, _ := fs.Sub(builtinOverlays, "overlay_assets")
+	var file http.File
+	var info fs.FileInfo
+	var opened string
+	var err error
+	for _, dir := range []http.FileSystem{http.Dir(g.config.Overlays.Dir), http.FS(assets)} {
+		if file, info, opened, err = openOverlay(dir, name); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	name = opened
+
+	ext := strings.ToLower(path.Ext(name))
+	if ext != ".html" && ext != ".htm" {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(g.config.Overlays.CacheMaxAge.Seconds())))
+		http.ServeContent(w, r, name, info.ModTime(), file)
+		return
+	}
+
+	page, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "failed to read overlay", http.StatusInternalServerError)
+		return
+	}
+	page, err = injectSettings(page, g.overlaySettings(r))
+	if err != nil {
+		http.Error(w, "failed to render overlay", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, name, info.ModTime(), bytes.NewReader(page))
+}
+
+// openOverlay opens a file in dir, or the index.html of a directory, and
+// returns it with the name of the opened file
+func openOverlay(dir http.FileSystem, name string) (http.File, fs.FileInfo, string, error) {
+	for _, candidate := range []string{name, path.Join(name, "index.html")} {
+		file, err := dir.Open(candidate)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		info, err := file.Stat()
+		if err == nil && !info.IsDir() {
+			return file, info, candidate, nil
+		}
+		file.Close()
+	}
+	return nil, nil, "", fs.ErrNotExist
+}
+
+// overlaySettings returns the connection settings for an overlay request.
+// The API key is passed through from the page URL, so a browser source can
+// be given a key with ?api_key=.
+func (g *Gateway) overlaySettings(r *http.Request) OverlaySettings {
+	httpScheme, wsScheme := "http", "ws"
+	if g.tlsConfig != nil {
+		httpScheme, wsScheme = "https", "wss"
+	}
+
+	return OverlaySettings{
+		WSURL:  fmt.Sprintf("%s://%s/ws", wsScheme, r.Host),
+		APIURL: fmt.Sprintf("%s://%s/api/v1", httpScheme, r.Host),
+		APIKey: r.URL.Query().Get("api_key"),
+	}
+}
+
+// injectSettings adds a script defining window.WaddleBot at the start of the
+// page's head, or of the page when it has none
+func injectSettings(page []byte, settings OverlaySettings) ([]byte, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	script := []byte("<script>window.WaddleBot = " + string(data) + ";</script>")
+
+	at := 0
+	if i := bytes.Index(bytes.ToLower(page), []byte("<head")); i >= 0 {
+		if end := bytes.IndexByte(page[i:], '>'); end >= 0 {
+			at = i + end + 1
+		}
+	}
+
+	injected := make([]byte, 0, len(page)+len(script))
+	injected = append(injected, page[:at]...)
+	injected = append(injected, script...)
+	return append(injected, page[at:]...), nil
+}
+
+// hiddenPath reports whether a path has a segment starting with a dot
+func hiddenPath(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+	return false
+}
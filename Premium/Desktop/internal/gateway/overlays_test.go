@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func TestServeOverlay(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "alerts"), 0755)
+	os.WriteFile(filepath.Join(dir, "alerts", "index.html"), []byte("<html><head><title>Alerts</title></head></html>"), 0644)
+	os.WriteFile(filepath.Join(dir, "alerts", "app.js"), []byte("console.log('alert')"), 0644)
+	os.WriteFile(filepath.Join(dir, ".secret"), []byte("hidden"), 0644)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	g := New(config.GatewayConfig{
+		EnableAuth:   true,
+		APIKey:       "static-admin",
+		RateLimitRPS: 1000,
+		Overlays:     config.OverlaysConfig{Enabled: true, Dir: dir, CacheMaxAge: time.Hour},
+	}, Services{}, logger)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		g.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/overlays/alerts/?api_key=wbk_overlay")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, `<html><head><script>window.WaddleBot = {"wsUrl":"ws://example.com/ws"`) {
+		t.Errorf("Expected settings injected in the head, got %s", body)
+	}
+	if !strings.Contains(body, `"apiKey":"wbk_overlay"`) {
+		t.Error("Expected the API key from the page URL")
+	}
+	if rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Expected HTML to be revalidated, got %q", rec.Header().Get("Cache-Control"))
+	}
+
+	rec = get("/overlays/alerts/app.js")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Errorf("Unexpected static response %d %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+
+	rec = get("/overlays/waddlebot/alerts.html")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "window.WaddleBot = ") {
+		t.Errorf("Expected the built-in alerts overlay, got %d", rec.Code)
+	}
+
+	for _, target := range []string{"/overlays/.secret", "/overlays/missing.html"} {
+		if rec := get(target); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s, got %d", target, rec.Code)
+		}
+	}
+}
@@ -3,15 +3,31 @@ package gateway
 import (
 	"net/http"
 
+	"waddlebot-bridge/internal/apikeys"
 	"waddlebot-bridge/internal/gateway/handlers"
+	"waddlebot-bridge/internal/metrics"
 )
 
 // RegisterRoutes registers all API routes with the gateway
 func RegisterRoutes(g *Gateway) {
 	// Create handler instances
-	bridgeHandler := handlers.NewBridgeHandler(g.logger)
+	bridgeHandler := handlers.NewBridgeHandler(g.bridge, g.logger)
 	obsHandler := handlers.NewOBSHandler(g.obsClient, g.logger)
-	webhookHandler := handlers.NewWebhookHandler(g.logger)
+	webhookHandler := handlers.NewWebhookHandler(g.webhooks, g.logger)
+	modulesHandler := handlers.NewModulesHandler(g.moduleExecutor, g.logger)
+	outboxHandler := handlers.NewOutboxHandler(g.outboxQueue, g.logger)
+	policyHandler := handlers.NewPolicyHandler(g.policy, g.logger)
+	commandsHandler := handlers.NewCommandsHandler(g.commands, g.logger)
+	apiKeysHandler := handlers.NewAPIKeysHandler(g.apiKeys, g.logger)
+	signingKeysHandler := handlers.NewSigningKeysHandler(g.signingKeys, g.logger)
+	profilesHandler := handlers.NewProfilesHandler(g.profiles, g.logger)
+	featuresHandler := handlers.NewFeaturesHandler(g.features, g.logger)
+	rulesHandler := handlers.NewRulesHandler(g.rules, g.logger)
+	schedulesHandler := handlers.NewSchedulesHandler(g.schedules, g.logger)
+	triggersHandler := handlers.NewTriggersHandler(g.triggers, g.logger)
+	textSourcesHandler := handlers.NewTextSourcesHandler(g.textSources, g.logger)
+	timersHandler := handlers.NewTimersHandler(g.timers, g.logger)
+	rtcHandler := handlers.NewRTCHandler(g.rtc, g.logger)
 
 	// Health check (no auth required)
 	g.router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -19,6 +35,12 @@ func RegisterRoutes(g *Gateway) {
 		w.Write([]byte(`{"status":"ok"}`))
 	}).Methods("GET")
 
+	// Prometheus metrics
+	if g.config.EnableMetrics {
+		route := g.router.Handle("/metrics", metrics.Handler()).Methods("GET")
+		g.routeScopes[route] = apikeys.ScopeBridgeRead
+	}
+
 	// API v1 routes
 	api := g.router.PathPrefix("/api/v1").Subrouter()
 
@@ -27,6 +49,8 @@ func RegisterRoutes(g *Gateway) {
 	bridge.HandleFunc("/status", bridgeHandler.GetStatus).Methods("GET")
 	bridge.HandleFunc("/health", bridgeHandler.GetHealth).Methods("GET")
 	bridge.HandleFunc("/reconnect", bridgeHandler.Reconnect).Methods("POST")
+	bridge.HandleFunc("/storage", bridgeHandler.GetStorage).Methods("GET")
+	g.scopeRoutes(bridge, apikeys.ScopeBridgeRead, apikeys.ScopeBridgeManage)
 
 	// OBS Control endpoints
 	obs := api.PathPrefix("/obs").Subrouter()
@@ -35,6 +59,7 @@ func RegisterRoutes(g *Gateway) {
 	obs.HandleFunc("/status", obsHandler.GetStatus).Methods("GET")
 	obs.HandleFunc("/connect", obsHandler.Connect).Methods("POST")
 	obs.HandleFunc("/disconnect", obsHandler.Disconnect).Methods("POST")
+	obs.HandleFunc("/history", obsHandler.GetHistory).Methods("GET")
 
 	// OBS Scenes
 	obs.HandleFunc("/scenes", obsHandler.GetScenes).Methods("GET")
@@ -63,6 +88,7 @@ func RegisterRoutes(g *Gateway) {
 	obs.HandleFunc("/recording/pause", obsHandler.PauseRecording).Methods("POST")
 	obs.HandleFunc("/recording/resume", obsHandler.ResumeRecording).Methods("POST")
 	obs.HandleFunc("/recording/toggle", obsHandler.ToggleRecording).Methods("POST")
+	g.scopeRoutes(obs, apikeys.ScopeOBSRead, apikeys.ScopeOBSWrite)
 
 	// Webhook endpoints
 	webhooks := api.PathPrefix("/webhooks").Subrouter()
@@ -70,9 +96,148 @@ func RegisterRoutes(g *Gateway) {
 	webhooks.HandleFunc("", webhookHandler.RegisterWebhook).Methods("POST")
 	webhooks.HandleFunc("/{id}", webhookHandler.RemoveWebhook).Methods("DELETE")
 	webhooks.HandleFunc("/{id}/test", webhookHandler.TestWebhook).Methods("POST")
+	webhooks.HandleFunc("/{id}/deliveries", webhookHandler.GetDeliveries).Methods("GET")
+	g.scopeRoutes(webhooks, apikeys.ScopeWebhooksManage, apikeys.ScopeWebhooksManage)
+
+	// Module endpoints
+	modules := api.PathPrefix("/modules").Subrouter()
+	modules.HandleFunc("", modulesHandler.ListModules).Methods("GET")
+	modules.HandleFunc("/{name}/actions/{action}", modulesHandler.ExecuteAction).Methods("POST")
+	g.scopeRoutes(modules, apikeys.ScopeScriptsRun, apikeys.ScopeScriptsRun)
+
+	// Soundboard endpoints
+	sounds := api.PathPrefix("/sounds").Subrouter()
+	sounds.HandleFunc("", modulesHandler.ListSounds).Methods("GET")
+	sounds.HandleFunc("/stop", modulesHandler.StopSounds).Methods("POST")
+	sounds.HandleFunc("/volume", modulesHandler.SetSoundVolume).Methods("PUT")
+	sounds.HandleFunc("/{name}/play", modulesHandler.PlaySound).Methods("POST")
+	g.scopeRoutes(sounds, apikeys.ScopeScriptsRun, apikeys.ScopeScriptsRun)
+
+	// Overlay alert endpoints
+	overlayAlerts := api.PathPrefix("/overlays").Subrouter()
+	overlayAlerts.HandleFunc("/alerts", modulesHandler.ListAlerts).Methods("GET")
+	overlayAlerts.HandleFunc("/alerts", modulesHandler.QueueAlert).Methods("POST")
+	overlayAlerts.HandleFunc("/alerts", modulesHandler.ClearAlerts).Methods("DELETE")
+	overlayAlerts.HandleFunc("/alerts/skip", modulesHandler.SkipAlert).Methods("POST")
+	overlayAlerts.HandleFunc("/templates", modulesHandler.ListAlertTemplates).Methods("GET")
+	g.scopeRoutes(overlayAlerts, apikeys.ScopeEventsRead, apikeys.ScopeOverlaysWrite)
+
+	// Outbox endpoints
+	outbox := api.PathPrefix("/outbox").Subrouter()
+	outbox.HandleFunc("", outboxHandler.ListItems).Methods("GET")
+	outbox.HandleFunc("", outboxHandler.PurgeItems).Methods("DELETE")
+	outbox.HandleFunc("/{id}", outboxHandler.DeleteItem).Methods("DELETE")
+	g.scopeRoutes(outbox, apikeys.ScopeBridgeRead, apikeys.ScopeBridgeManage)
+
+	// Policy endpoints
+	policy := api.PathPrefix("/policy").Subrouter()
+	policy.HandleFunc("", policyHandler.GetPolicy).Methods("GET")
+	policy.HandleFunc("/reload", policyHandler.ReloadPolicy).Methods("POST")
+	policy.HandleFunc("/audit", policyHandler.GetAuditLog).Methods("GET")
+	g.scopeRoutes(policy, apikeys.ScopeBridgeRead, apikeys.ScopeBridgeManage)
+
+	// Configuration profile endpoints
+	profiles := api.PathPrefix("/profiles").Subrouter()
+	profiles.HandleFunc("", profilesHandler.ListProfiles).Methods("GET")
+	profiles.HandleFunc("/active", profilesHandler.DeactivateProfile).Methods("DELETE")
+	profiles.HandleFunc("/{name}/activate", profilesHandler.ActivateProfile).Methods("POST")
+	g.scopeRoutes(profiles, apikeys.ScopeBridgeRead, apikeys.ScopeBridgeManage)
+
+	// Scene switching rule endpoints
+	rules := api.PathPrefix("/rules").Subrouter()
+	rules.HandleFunc("", rulesHandler.ListRules).Methods("GET")
+	rules.HandleFunc("", rulesHandler.CreateRule).Methods("POST")
+	rules.HandleFunc("/{id}", rulesHandler.GetRule).Methods("GET")
+	rules.HandleFunc("/{id}", rulesHandler.UpdateRule).Methods("PUT")
+	rules.HandleFunc("/{id}", rulesHandler.RemoveRule).Methods("DELETE")
+	g.scopeRoutes(rules, apikeys.ScopeOBSRead, apikeys.ScopeScriptsRun)
+
+	// Source visibility schedule endpoints
+	schedules := api.PathPrefix("/schedules").Subrouter()
+	schedules.HandleFunc("", schedulesHandler.ListSchedules).Methods("GET")
+	schedules.HandleFunc("", schedulesHandler.CreateSchedule).Methods("POST")
+	schedules.HandleFunc("/{id}", schedulesHandler.GetSchedule).Methods("GET")
+	schedules.HandleFunc("/{id}", schedulesHandler.UpdateSchedule).Methods("PUT")
+	schedules.HandleFunc("/{id}", schedulesHandler.RemoveSchedule).Methods("DELETE")
+	g.scopeRoutes(schedules, apikeys.ScopeOBSRead, apikeys.ScopeOBSWrite)
+
+	// Trigger endpoints
+	triggers := api.PathPrefix("/triggers").Subrouter()
+	triggers.HandleFunc("", triggersHandler.ListTriggers).Methods("GET")
+	triggers.HandleFunc("", triggersHandler.CreateTrigger).Methods("POST")
+	triggers.HandleFunc("/test", triggersHandler.TestTrigger).Methods("POST")
+	triggers.HandleFunc("/{id}", triggersHandler.GetTrigger).Methods("GET")
+	triggers.HandleFunc("/{id}", triggersHandler.UpdateTrigger).Methods("PUT")
+	triggers.HandleFunc("/{id}", triggersHandler.RemoveTrigger).Methods("DELETE")
+	g.scopeRoutes(triggers, apikeys.ScopeOBSRead, apikeys.ScopeScriptsRun)
+
+	// Text source endpoints
+	text := api.PathPrefix("/text-sources").Subrouter()
+	text.HandleFunc("", textSourcesHandler.ListSources).Methods("GET")
+	text.HandleFunc("", textSourcesHandler.CreateSource).Methods("POST")
+	text.HandleFunc("/preview", textSourcesHandler.Preview).Methods("POST")
+	text.HandleFunc("/variables", textSourcesHandler.ListVariables).Methods("GET")
+	text.HandleFunc("/variables/{name}", textSourcesHandler.SetVariable).Methods("PUT")
+	text.HandleFunc("/variables/{name}", textSourcesHandler.DeleteVariable).Methods("DELETE")
+	text.HandleFunc("/variables/{name}/increment", textSourcesHandler.IncrementVariable).Methods("POST")
+	text.HandleFunc("/{id}", textSourcesHandler.GetSource).Methods("GET")
+	text.HandleFunc("/{id}", textSourcesHandler.UpdateSource).Methods("PUT")
+	text.HandleFunc("/{id}", textSourcesHandler.RemoveSource).Methods("DELETE")
+	g.scopeRoutes(text, apikeys.ScopeOBSRead, apikeys.ScopeOBSWrite)
+
+	// Timer endpoints
+	timers := api.PathPrefix("/timers").Subrouter()
+	timers.HandleFunc("", timersHandler.ListTimers).Methods("GET")
+	timers.HandleFunc("", timersHandler.CreateTimer).Methods("POST")
+	timers.HandleFunc("/{name}", timersHandler.GetTimer).Methods("GET")
+	timers.HandleFunc("/{name}", timersHandler.RemoveTimer).Methods("DELETE")
+	timers.HandleFunc("/{name}/{action}", timersHandler.ControlTimer).Methods("POST")
+	g.scopeRoutes(timers, apikeys.ScopeEventsRead, apikeys.ScopeOverlaysWrite)
+
+	// Community call endpoints
+	calls := api.PathPrefix("/rtc/rooms").Subrouter()
+	calls.HandleFunc("", rtcHandler.ListRooms).Methods("GET")
+	calls.HandleFunc("/{room}/token", rtcHandler.GetJoinToken).Methods("POST")
+	calls.HandleFunc("/{room}/mute", rtcHandler.Mute).Methods("POST")
+	calls.HandleFunc("/{room}/raise-hand", rtcHandler.RaiseHand).Methods("POST")
+	calls.HandleFunc("/{room}/lower-hand", rtcHandler.LowerHand).Methods("POST")
+	calls.HandleFunc("/{room}/view", rtcHandler.ShowView).Methods("GET")
+	obsSource := calls.HandleFunc("/{room}/obs-source", rtcHandler.AddBrowserSource).Methods("POST")
+	g.scopeRoutes(calls, apikeys.ScopeBridgeRead, apikeys.ScopeBridgeManage)
+	g.routeScopes[obsSource] = apikeys.ScopeOBSWrite
+
+	// Subscription feature endpoint
+	route := api.HandleFunc("/features", featuresHandler.ListFeatures).Methods("GET")
+	g.routeScopes[route] = apikeys.ScopeBridgeRead
+
+	// Command palette endpoints
+	commands := api.PathPrefix("/commands").Subrouter()
+	commands.HandleFunc("", commandsHandler.ListCommands).Methods("GET")
+	commands.HandleFunc("/execute", commandsHandler.ExecuteCommand).Methods("POST")
+	g.scopeRoutes(commands, apikeys.ScopeScriptsRun, apikeys.ScopeScriptsRun)
+
+	// API key endpoints
+	keys := api.PathPrefix("/keys").Subrouter()
+	keys.HandleFunc("", apiKeysHandler.ListKeys).Methods("GET")
+	keys.HandleFunc("", apiKeysHandler.CreateKey).Methods("POST")
+	keys.HandleFunc("/{id}", apiKeysHandler.RevokeKey).Methods("DELETE")
+	keys.HandleFunc("/{id}/rotate", apiKeysHandler.RotateKey).Methods("POST")
+	g.scopeRoutes(keys, apikeys.ScopeAdmin, apikeys.ScopeAdmin)
+
+	// JWT signing key endpoints
+	signingKeys := api.PathPrefix("/auth/signing-keys").Subrouter()
+	signingKeys.HandleFunc("", signingKeysHandler.ListKeys).Methods("GET")
+	signingKeys.HandleFunc("/rotate", signingKeysHandler.RotateKey).Methods("POST")
+	g.scopeRoutes(signingKeys, apikeys.ScopeAdmin, apikeys.ScopeAdmin)
+
+	// Overlay files for OBS browser sources (no auth required)
+	if g.config.Overlays.Enabled {
+		g.router.PathPrefix(overlaysPrefix).HandlerFunc(g.serveOverlay).Methods("GET", "HEAD")
+	}
 
 	// WebSocket endpoint
-	g.router.HandleFunc("/ws", g.handleWebSocket).Methods("GET")
+	ws := g.router.HandleFunc("/ws", g.handleWebSocket).Methods("GET")
+	g.routeScopes[ws] = apikeys.ScopeEventsRead
 
 	g.logger.Info("Registered all gateway routes")
 }
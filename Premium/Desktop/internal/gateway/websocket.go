@@ -1,37 +1,108 @@
 package gateway
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/gateway/handlers"
+	"waddlebot-bridge/internal/metrics"
 )
 
 // WebSocketHub manages WebSocket connections and broadcasts
 type WebSocketHub struct {
-	clients    map[*WebSocketClient]bool
-	broadcast  chan WSMessage
-	register   chan *WebSocketClient
-	unregister chan *WebSocketClient
-	logger     *logrus.Logger
-	running    bool
-	runningMux sync.RWMutex
+	clients       map[*WebSocketClient]bool
+	broadcast     chan WSMessage
+	register      chan *WebSocketClient
+	unregister    chan *WebSocketClient
+	subscriptions chan subscriptionRequest
+	commands      handlers.CommandCatalog
+	direct        chan directMessage
+	last          map[string]WSMessage // last message per topic, for replay
+	logger        *logrus.Logger
+	running       bool
+	runningMux    sync.RWMutex
 }
 
-// WebSocketClient represents a connected WebSocket client
+// WebSocketClient represents a connected WebSocket client. A client that
+// never subscribed receives every message; once it subscribes it only
+// receives messages whose topic matches one of its topics.
 type WebSocketClient struct {
-	hub  *WebSocketHub
-	conn *websocket.Conn
-	send chan WSMessage
+	hub      *WebSocketHub
+	conn     *websocket.Conn
+	send     chan WSMessage
+	key      *apikeys.Key // nil when authentication is disabled
+	ctx      context.Context
+	cancel   context.CancelFunc
+	topics   map[string]bool // owned by the hub's Run loop
+	filtered bool
 }
 
-// WSMessage represents a WebSocket message
+// WSMessage represents a WebSocket message. Its topic defaults to its type;
+// replies to client messages carry the ID the client sent.
 type WSMessage struct {
 	Type      string      `json:"type"`
+	ID        string      `json:"id,omitempty"`
+	Topic     string      `json:"topic,omitempty"`
 	Data      interface{} `json:"data"`
 	Timestamp int64       `json:"timestamp"`
+
+	// subscribedOnly keeps frequent messages, such as audio levels, from
+	// clients that never subscribed and so receive everything else
+	subscribedOnly bool
+}
+
+// WebSocket client actions
+const (
+	WSActionSubscribe   = "subscribe"
+	WSActionUnsubscribe = "unsubscribe"
+)
+
+// Message types sent in reply to client messages
+const (
+	WSTypeSubscriptions = "subscriptions"
+	WSTypeResponse      = "response"
+	WSTypeError         = "error"
+)
+
+// WSClientMessage is a message sent by a WebSocket client. It either
+// changes subscriptions, with Action (or Type) set to subscribe or
+// unsubscribe, or runs a command: Type is a command palette ID with a dot
+// after the kind, such as "obs.set_scene", Data holds its parameters and
+// the response carries the same ID.
+//
+// Topics are patterns such as "obs.*"; with Replay, the last message of
+// every matching topic is sent right after subscribing.
+type WSClientMessage struct {
+	Action string                 `json:"action,omitempty"`
+	Type   string                 `json:"type,omitempty"`
+	ID     string                 `json:"id,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+	Topics []string               `json:"topics,omitempty"`
+	Replay bool                   `json:"replay,omitempty"`
+}
+
+// directMessage is a message for a single client
+type directMessage struct {
+	client  *WebSocketClient
+	message WSMessage
+}
+
+// subscriptionRequest changes the topics of a client
+type subscriptionRequest struct {
+	client    *WebSocketClient
+	topics    []string
+	subscribe bool
+	replay    bool
 }
 
 var upgrader = websocket.Upgrader{
@@ -46,11 +117,14 @@ var upgrader = websocket.Upgrader{
 // NewWebSocketHub creates a new WebSocket hub
 func NewWebSocketHub(logger *logrus.Logger) *WebSocketHub {
 	return &WebSocketHub{
-		clients:    make(map[*WebSocketClient]bool),
-		broadcast:  make(chan WSMessage, 256),
-		register:   make(chan *WebSocketClient),
-		unregister: make(chan *WebSocketClient),
-		logger:     logger,
+		clients:       make(map[*WebSocketClient]bool),
+		broadcast:     make(chan WSMessage, 256),
+		register:      make(chan *WebSocketClient),
+		unregister:    make(chan *WebSocketClient),
+		subscriptions: make(chan subscriptionRequest, 16),
+		direct:        make(chan directMessage, 16),
+		last:          make(map[string]WSMessage),
+		logger:        logger,
 	}
 }
 
@@ -66,23 +140,42 @@ func (h *WebSocketHub) Run() {
 		select {
 		case client := <-h.register:
 			h.clients[client] = true
+			metrics.WebSocketClients.Set(float64(len(h.clients)))
 			h.logger.WithField("client_count", len(h.clients)).Debug("WebSocket client registered")
 
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
 				close(client.send)
+				metrics.WebSocketClients.Set(float64(len(h.clients)))
 				h.logger.WithField("client_count", len(h.clients)).Debug("WebSocket client unregistered")
 			}
 
+		case req := <-h.subscriptions:
+			if _, ok := h.clients[req.client]; ok {
+				h.updateSubscriptions(req)
+			}
+
+		case direct := <-h.direct:
+			if _, ok := h.clients[direct.client]; ok {
+				direct.client.SendMessage(direct.message)
+			}
+
 		case message := <-h.broadcast:
-			// Add timestamp if not set
+			// Add timestamp and topic if not set
 			if message.Timestamp == 0 {
 				message.Timestamp = time.Now().Unix()
 			}
+			if message.Topic == "" {
+				message.Topic = message.Type
+			}
+			h.last[message.Topic] = message
 
-			// Broadcast to all clients
+			// Broadcast to subscribed clients
 			for client := range h.clients {
+				if !client.wants(message.Topic) || (message.subscribedOnly && !client.filtered) {
+					continue
+				}
 				select {
 				case client.send <- message:
 				default:
@@ -91,10 +184,101 @@ func (h *WebSocketHub) Run() {
 					delete(h.clients, client)
 				}
 			}
+			metrics.WebSocketClients.Set(float64(len(h.clients)))
 		}
 	}
 }
 
+// updateSubscriptions applies a subscription request, acknowledges the
+// client's topics and replays the last matching messages if asked to
+func (h *WebSocketHub) updateSubscriptions(req subscriptionRequest) {
+	client := req.client
+	if client.topics == nil {
+		client.topics = make(map[string]bool)
+	}
+	client.filtered = true
+	for _, topic := range req.topics {
+		if req.subscribe {
+			client.topics[topic] = true
+		} else {
+			delete(client.topics, topic)
+		}
+	}
+
+	topics := make([]string, 0, len(client.topics))
+	for topic := range client.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	client.SendMessage(WSMessage{
+		Type: WSTypeSubscriptions,
+		Data: map[string]interface{}{"topics": topics},
+	})
+
+	if !req.subscribe || !req.replay {
+		return
+	}
+	replayed := make([]WSMessage, 0)
+	for topic, message := range h.last {
+		if matchTopics(req.topics, topic) {
+			replayed = append(replayed, message)
+		}
+	}
+	sort.Slice(replayed, func(i, j int) bool { return replayed[i].Timestamp < replayed[j].Timestamp })
+	for _, message := range replayed {
+		client.SendMessage(message)
+	}
+}
+
+// wants reports whether a client receives messages on a topic
+func (c *WebSocketClient) wants(topic string) bool {
+	if !c.filtered {
+		return true
+	}
+	for pattern := range c.topics {
+		if matchTopic(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTopic reports whether a topic matches a pattern. Patterns use
+// path.Match syntax, so "obs.*" matches every OBS topic.
+func matchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	ok, err := path.Match(pattern, topic)
+	return err == nil && ok
+}
+
+func matchTopics(patterns []string, topic string) bool {
+	for _, pattern := range patterns {
+		if matchTopic(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe changes the topics a client receives. It is safe to call from
+// any goroutine.
+func (h *WebSocketHub) Subscribe(client *WebSocketClient, topics []string, replay bool) {
+	h.subscriptions <- subscriptionRequest{client: client, topics: topics, subscribe: true, replay: replay}
+}
+
+// Unsubscribe stops a client from receiving topics
+func (h *WebSocketHub) Unsubscribe(client *WebSocketClient, topics []string) {
+	h.subscriptions <- subscriptionRequest{client: client, topics: topics}
+}
+
+// SendTo sends a message to one client if it is still connected. Unlike
+// SendMessage it is safe to call from any goroutine.
+func (h *WebSocketHub) SendTo(client *WebSocketClient, message WSMessage) {
+	h.direct <- directMessage{client: client, message: message}
+}
+
 // Stop stops the WebSocket hub
 func (h *WebSocketHub) Stop() {
 	h.runningMux.Lock()
@@ -113,6 +297,7 @@ func (h *WebSocketHub) Stop() {
 	}
 
 	h.clients = make(map[*WebSocketClient]bool)
+	metrics.WebSocketClients.Set(0)
 	h.logger.Info("WebSocket hub stopped")
 }
 
@@ -141,15 +326,23 @@ func (g *Gateway) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create client
+	// Create client, keeping the key it authenticated with for commands
 	client := &WebSocketClient{
 		hub:  g.wsHub,
 		conn: conn,
 		send: make(chan WSMessage, 256),
 	}
+	client.ctx, client.cancel = context.WithCancel(context.Background())
+	if key, ok := apikeys.FromContext(r.Context()); ok {
+		client.key = &key
+	}
 
-	// Register client
+	// Register client, with the topics given when connecting
 	client.hub.register <- client
+	if topics := r.URL.Query().Get("topics"); topics != "" {
+		replay := r.URL.Query().Get("replay") == "true"
+		client.hub.Subscribe(client, strings.Split(topics, ","), replay)
+	}
 
 	// Start goroutines
 	go client.writePump()
@@ -175,6 +368,7 @@ const (
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *WebSocketClient) readPump() {
 	defer func() {
+		c.cancel()
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
@@ -195,11 +389,49 @@ func (c *WebSocketClient) readPump() {
 			break
 		}
 
-		// Log received message (clients typically only send pings)
 		c.hub.logger.WithField("message", string(message)).Debug("WebSocket message received")
+		c.handleMessage(message)
 	}
 }
 
+// handleMessage handles an action sent by the client
+func (c *WebSocketClient) handleMessage(message []byte) {
+	var msg WSClientMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.sendError("", "invalid message: "+err.Error())
+		return
+	}
+
+	action := msg.Action
+	if action == "" {
+		action = msg.Type
+	}
+
+	switch action {
+	case WSActionSubscribe:
+		if len(msg.Topics) == 0 {
+			c.sendError(msg.ID, "topics are required")
+			return
+		}
+		c.hub.Subscribe(c, msg.Topics, msg.Replay)
+	case WSActionUnsubscribe:
+		c.hub.Unsubscribe(c, msg.Topics)
+	case "":
+		c.sendError(msg.ID, "type is required")
+	default:
+		go c.runCommand(msg)
+	}
+}
+
+// sendError reports a problem with a client message to the client
+func (c *WebSocketClient) sendError(id, message string) {
+	c.hub.SendTo(c, WSMessage{
+		Type: WSTypeError,
+		ID:   id,
+		Data: map[string]interface{}{"error": message},
+	})
+}
+
 // writePump pumps messages from the hub to the WebSocket connection
 func (c *WebSocketClient) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apikeys"
+	"waddlebot-bridge/internal/commands"
+)
+
+func newTestHub() *WebSocketHub {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	hub := NewWebSocketHub(logger)
+	go hub.Run()
+	for {
+		hub.runningMux.RLock()
+		running := hub.running
+		hub.runningMux.RUnlock()
+		if running {
+			return hub
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newTestClient(hub *WebSocketHub) *WebSocketClient {
+	client := &WebSocketClient{hub: hub, send: make(chan WSMessage, 16), ctx: context.Background()}
+	hub.register <- client
+	return client
+}
+
+func receive(t *testing.T, client *WebSocketClient) WSMessage {
+	t.Helper()
+	select {
+	case message := <-client.send:
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a message")
+		return WSMessage{}
+	}
+}
+
+func expectNone(t *testing.T, client *WebSocketClient) {
+	t.Helper()
+	select {
+	case message := <-client.send:
+		t.Fatalf("Unexpected message %+v", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWebSocketHub_Topics(t *testing.T) {
+	hub := newTestHub()
+	all := newTestClient(hub)
+	obs := newTestClient(hub)
+
+	hub.Subscribe(obs, []string{"obs.*"}, false)
+	if ack := receive(t, obs); ack.Type != WSTypeSubscriptions {
+		t.Fatalf("Expected a subscriptions ack, got %+v", ack)
+	}
+
+	hub.Broadcast(WSMessage{Type: "obs.SceneChanged"})
+	hub.Broadcast(WSMessage{Type: "scripts.output"})
+
+	if message := receive(t, obs); message.Topic != "obs.SceneChanged" {
+		t.Errorf("Expected the OBS message, got %+v", message)
+	}
+	expectNone(t, obs)
+
+	if receive(t, all).Type != "obs.SceneChanged" || receive(t, all).Type != "scripts.output" {
+		t.Error("Expected an unsubscribed client to receive every message")
+	}
+
+	hub.Unsubscribe(obs, []string{"obs.*"})
+	receive(t, obs)
+	hub.Broadcast(WSMessage{Type: "obs.StreamStateChanged"})
+	receive(t, all)
+	expectNone(t, obs)
+}
+
+func TestWebSocketHub_Replay(t *testing.T) {
+	hub := newTestHub()
+	hub.Broadcast(WSMessage{Type: "obs.SceneChanged", Data: "first"})
+	hub.Broadcast(WSMessage{Type: "obs.SceneChanged", Data: "second"})
+	hub.Broadcast(WSMessage{Type: "bridge.tasks", Data: "task"})
+	time.Sleep(50 * time.Millisecond) // let the hub record the messages
+
+	client := newTestClient(hub)
+	hub.Subscribe(client, []string{"obs.*"}, true)
+	receive(t, client)
+
+	if message := receive(t, client); message.Data != "second" {
+		t.Errorf("Expected the last OBS message to be replayed, got %+v", message)
+	}
+	expectNone(t, client)
+}
+
+func TestWebSocketHub_SubscribedOnly(t *testing.T) {
+	hub := newTestHub()
+	all := newTestClient(hub)
+	meters := newTestClient(hub)
+	hub.Subscribe(meters, []string{"obs.*"}, false)
+	receive(t, meters)
+
+	hub.Broadcast(WSMessage{Type: AudioLevelsTopic, subscribedOnly: true})
+	if message := receive(t, meters); message.Type != AudioLevelsTopic {
+		t.Errorf("Expected the audio levels, got %+v", message)
+	}
+	expectNone(t, all)
+}
+
+type fakeCatalog struct {
+	executed   string
+	parameters map[string]string
+}
+
+func (f *fakeCatalog) List() []commands.Command { return nil }
+
+func (f *fakeCatalog) Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error) {
+	if id != "obs:set_scene" {
+		return nil, errors.New("unknown command")
+	}
+	f.executed, f.parameters = id, parameters
+	return map[string]interface{}{"scene": parameters["scene"]}, nil
+}
+
+func TestWebSocketClient_Commands(t *testing.T) {
+	hub := newTestHub()
+	catalog := &fakeCatalog{}
+	hub.commands = catalog
+	client := newTestClient(hub)
+
+	client.handleMessage([]byte(`{"type":"obs.set_scene","id":"123","data":{"scene":"Gameplay"}}`))
+	response := receive(t, client)
+	data, _ := response.Data.(map[string]interface{})
+	if response.Type != WSTypeResponse || response.ID != "123" || data["success"] != true {
+		t.Fatalf("Unexpected response %+v", response)
+	}
+	if catalog.parameters["scene"] != "Gameplay" {
+		t.Errorf("Expected the scene parameter, got %v", catalog.parameters)
+	}
+
+	client.handleMessage([]byte(`{"type":"obs.unknown","id":"124"}`))
+	if response := receive(t, client); response.ID != "124" || response.Data.(map[string]interface{})["success"] != false {
+		t.Errorf("Expected a failed response, got %+v", response)
+	}
+
+	key := apikeys.Key{Scopes: []string{apikeys.ScopeOBSRead}}
+	client.key = &key
+	client.handleMessage([]byte(`{"type":"obs.set_scene","id":"125","data":{"scene":"Gameplay"}}`))
+	if response := receive(t, client); response.Type != WSTypeError || response.ID != "125" {
+		t.Errorf("Expected a scope error, got %+v", response)
+	}
+}
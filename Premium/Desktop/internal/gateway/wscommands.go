@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/commands"
+)
+
+// wsCommandTimeout bounds a command run over the WebSocket
+const wsCommandTimeout = 2 * time.Minute
+
+// runCommand runs a command sent by the client and replies with a response
+// carrying the client's message ID
+func (c *WebSocketClient) runCommand(msg WSClientMessage) {
+	id := commandIDFromType(msg.Type)
+
+	if c.hub.commands == nil {
+		c.sendError(msg.ID, "commands not available")
+		return
+	}
+	if scope := commands.Scope(id); c.key != nil && !c.key.HasScope(scope) {
+		c.sendError(msg.ID, fmt.Sprintf("API key lacks the %s scope", scope))
+		return
+	}
+
+	parameters := make(map[string]string, len(msg.Data))
+	for name, value := range msg.Data {
+		parameters[name] = fmt.Sprint(value)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, wsCommandTimeout)
+	defer cancel()
+
+	result, err := c.hub.commands.Execute(ctx, id, parameters)
+	data := map[string]interface{}{
+		"success": err == nil,
+		"command": id,
+		"result":  result,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+		c.hub.logger.WithFields(logrus.Fields{
+			"command": id,
+			"error":   err,
+		}).Warn("WebSocket command failed")
+	}
+
+	c.hub.SendTo(c, WSMessage{
+		Type: WSTypeResponse,
+		ID:   msg.ID,
+		Data: data,
+	})
+}
+
+// commandIDFromType turns a message type such as "obs.set_scene" into the
+// command ID "obs:set_scene". Types already using a colon are kept.
+func commandIDFromType(messageType string) string {
+	if strings.Contains(messageType, ":") {
+		return messageType
+	}
+	kind, name, ok := strings.Cut(messageType, ".")
+	if !ok {
+		return messageType
+	}
+	return kind + ":" + name
+}
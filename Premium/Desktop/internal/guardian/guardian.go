@@ -0,0 +1,405 @@
+// Package guardian watches the health of the stream in OBS and acts when
+// it stays poor: switching to a lighter scene, warning the community's
+// chat or restarting the stream
+package guardian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/metrics"
+	"waddlebot-bridge/internal/obs"
+)
+
+// Action types
+const (
+	ActionScene         = "scene"
+	ActionWarn          = "warn"
+	ActionRestartStream = "restart-stream"
+)
+
+// Events published on the bus
+const (
+	EventUnhealthy = "stream_health.unhealthy"
+	EventRecovered = "stream_health.recovered"
+	EventAction    = "stream_health.action"
+)
+
+// ChatPath is the API path chat warnings are posted to
+const ChatPath = "/api/bridge/chat"
+
+// restartDelay is how long the stream stays stopped when it is restarted
+const restartDelay = 3 * time.Second
+
+// requestTimeout bounds each request made to OBS or the API
+const requestTimeout = 10 * time.Second
+
+// OBS is the part of the OBS client the guardian samples and controls
+type OBS interface {
+	GetStreamStatus(ctx context.Context) (*obs.StreamStatus, error)
+	GetStats(ctx context.Context) (*obs.OBSStats, error)
+	GetCurrentScene(ctx context.Context) (*obs.SceneInfo, error)
+	SetCurrentScene(ctx context.Context, sceneName string) error
+	StartStream(ctx context.Context) error
+	StopStream(ctx context.Context) error
+}
+
+// Chat is a community's WaddleBot API, which relays warnings to its chat
+type Chat interface {
+	PostJSON(ctx context.Context, path string, payload []byte) error
+}
+
+// Sample is the stream's health over one sampling interval
+type Sample struct {
+	DroppedRatio    float64 `json:"dropped_ratio"`     // frames dropped by the network
+	EncoderLagRatio float64 `json:"encoder_lag_ratio"` // frames skipped by the encoder
+	BitrateKbps     float64 `json:"bitrate_kbps"`
+}
+
+// counters are the running totals OBS reports, which samples are worked
+// out from
+type counters struct {
+	at             time.Time
+	bytes          int64
+	dropped        int64
+	frames         int64
+	encoderSkipped int64
+	encoderFrames  int64
+}
+
+// sampleBetween works out the health between two readings. It is false
+// when there is nothing to compare, such as after the stream restarted.
+func sampleBetween(prev, cur counters) (Sample, bool) {
+	elapsed := cur.at.Sub(prev.at).Seconds()
+	frames := cur.frames - prev.frames
+	if elapsed <= 0 || frames <= 0 || cur.bytes < prev.bytes || cur.dropped < prev.dropped {
+		return Sample{}, false
+	}
+
+	sample := Sample{
+		DroppedRatio: float64(cur.dropped-prev.dropped) / float64(frames),
+		BitrateKbps:  float64(cur.bytes-prev.bytes) * 8 / 1000 / elapsed,
+	}
+	if encoderFrames := cur.encoderFrames - prev.encoderFrames; encoderFrames > 0 && cur.encoderSkipped >= prev.encoderSkipped {
+		sample.EncoderLagRatio = float64(cur.encoderSkipped-prev.encoderSkipped) / float64(encoderFrames)
+	}
+	return sample, true
+}
+
+// Guardian samples the stream at an interval. Once enough samples in a row
+// are out of bounds the stream is unhealthy and the configured actions run,
+// each at most once per cooldown; once enough samples in a row are within
+// bounds it has recovered and the scene switched away from is restored.
+type Guardian struct {
+	cfg       config.GuardianConfig
+	obs       OBS
+	chats     []Chat
+	publisher events.Publisher // nil when the event bus is disabled
+	logger    *logrus.Logger
+
+	mu             sync.Mutex
+	prev           *counters
+	bad            int
+	good           int
+	unhealthySince time.Time         // zero while healthy
+	ran            map[int]time.Time // when each action last ran
+	restoreScene   string            // scene active before an action switched away
+	switchedTo     string            // scene an action switched to
+}
+
+// New creates a guardian. chats are the communities warned by warn
+// actions; publisher may be nil.
+func New(cfg config.GuardianConfig, obsClient OBS, chats []Chat, publisher events.Publisher, logger *logrus.Logger) *Guardian {
+	return &Guardian{
+		cfg:       cfg,
+		obs:       obsClient,
+		chats:     chats,
+		publisher: publisher,
+		logger:    logger,
+		ran:       make(map[int]time.Time),
+	}
+}
+
+// Run samples the stream until ctx is done
+func (g *Guardian) Run(ctx context.Context) error {
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			g.check(ctx)
+		}
+	}
+}
+
+// check takes a sample of the stream and acts on it
+func (g *Guardian) check(ctx context.Context) {
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	status, err := g.obs.GetStreamStatus(reqCtx)
+	if err != nil || !status.Active {
+		// Nothing to measure; the next stream starts from fresh totals
+		g.mu.Lock()
+		g.prev, g.bad, g.good = nil, 0, 0
+		g.mu.Unlock()
+		return
+	}
+	stats, err := g.obs.GetStats(reqCtx)
+	if err != nil {
+		g.logger.WithError(err).Debug("Failed to get OBS stats")
+		return
+	}
+
+	cur := counters{
+		at:             time.Now(),
+		bytes:          status.BytesSent,
+		dropped:        status.DroppedFrames,
+		frames:         status.TotalFrames,
+		encoderSkipped: stats.OutputSkippedFrames,
+		encoderFrames:  stats.OutputTotalFrames,
+	}
+	g.mu.Lock()
+	prev := g.prev
+	g.prev = &cur
+	g.mu.Unlock()
+	if prev == nil {
+		return
+	}
+
+	if sample, ok := sampleBetween(*prev, cur); ok {
+		g.evaluate(ctx, sample, cur.at)
+	}
+}
+
+// problems lists the ways a sample is out of bounds
+func (g *Guardian) problems(sample Sample) []string {
+	var reasons []string
+	if g.cfg.MaxDroppedRatio > 0 && sample.DroppedRatio > g.cfg.MaxDroppedRatio {
+		reasons = append(reasons, fmt.Sprintf("%.1f%% of frames dropped", sample.DroppedRatio*100))
+	}
+	if g.cfg.MaxEncoderLagRatio > 0 && sample.EncoderLagRatio > g.cfg.MaxEncoderLagRatio {
+		reasons = append(reasons, fmt.Sprintf("%.1f%% of frames skipped by the encoder", sample.EncoderLagRatio*100))
+	}
+	if g.cfg.MinBitrateKbps > 0 && sample.BitrateKbps < float64(g.cfg.MinBitrateKbps) {
+		reasons = append(reasons, fmt.Sprintf("bitrate down to %.0f kbps", sample.BitrateKbps))
+	}
+	return reasons
+}
+
+// evaluate updates the stream's health with a sample and runs the actions
+// that are due
+func (g *Guardian) evaluate(ctx context.Context, sample Sample, now time.Time) {
+	metrics.StreamDroppedRatio.Set(sample.DroppedRatio)
+	metrics.StreamEncoderLagRatio.Set(sample.EncoderLagRatio)
+	metrics.StreamBitrate.Set(sample.BitrateKbps)
+
+	reasons := g.problems(sample)
+
+	g.mu.Lock()
+	if len(reasons) > 0 {
+		g.bad, g.good = g.bad+1, 0
+	} else {
+		g.bad, g.good = 0, g.good+1
+	}
+
+	healthy := g.unhealthySince.IsZero()
+	switch {
+	case healthy && g.bad >= g.cfg.TriggerSamples:
+		g.unhealthySince = now
+		g.mu.Unlock()
+		metrics.StreamHealthy.Set(0)
+		g.logger.WithField("reasons", reasons).Warn("Stream is unhealthy")
+		g.publish(EventUnhealthy, map[string]interface{}{
+			"reasons": reasons,
+			"sample":  sample,
+		})
+	case !healthy && g.good >= g.cfg.RecoverSamples:
+		since := g.unhealthySince
+		restore, switchedTo := g.restoreScene, g.switchedTo
+		g.unhealthySince, g.restoreScene, g.switchedTo = time.Time{}, "", ""
+		g.mu.Unlock()
+		g.recovered(ctx, now.Sub(since), restore, switchedTo)
+		return
+	default:
+		g.mu.Unlock()
+	}
+
+	if len(reasons) > 0 {
+		for _, action := range g.due(now) {
+			g.run(ctx, action, reasons)
+		}
+	}
+}
+
+// due returns the actions to run while unhealthy, marking them as run
+func (g *Guardian) due(now time.Time) []config.GuardianAction {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.unhealthySince.IsZero() {
+		return nil
+	}
+	var actions []config.GuardianAction
+	for i, action := range g.cfg.Actions {
+		if now.Sub(g.unhealthySince) < action.After {
+			continue
+		}
+		if last, ok := g.ran[i]; ok && now.Sub(last) < g.cfg.Cooldown {
+			continue
+		}
+		g.ran[i] = now
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// run runs an action, logging rather than returning its failure
+func (g *Guardian) run(ctx context.Context, action config.GuardianAction, reasons []string) {
+	var err error
+	switch action.Type {
+	case ActionScene:
+		err = g.switchScene(ctx, action.Scene)
+	case ActionWarn:
+		err = g.warn(ctx, strings.ReplaceAll(action.Message, "{reason}", strings.Join(reasons, ", ")))
+	case ActionRestartStream:
+		err = g.restartStream(ctx)
+	default:
+		err = fmt.Errorf("unknown action type %q", action.Type)
+	}
+
+	log := g.logger.WithField("action", action.Type)
+	if err != nil {
+		log.WithError(err).Error("Stream guardian action failed")
+	} else {
+		log.Info("Stream guardian action ran")
+	}
+
+	data := map[string]interface{}{
+		"action":  action.Type,
+		"reasons": reasons,
+		"success": err == nil,
+	}
+	if action.Scene != "" {
+		data["scene"] = action.Scene
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	g.publish(EventAction, data)
+}
+
+// switchScene switches to scene, remembering the scene it replaced
+func (g *Guardian) switchScene(ctx context.Context, scene string) error {
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	current, err := g.obs.GetCurrentScene(reqCtx)
+	if err != nil {
+		return fmt.Errorf("failed to get the current scene: %w", err)
+	}
+	if current.Name == scene {
+		return nil
+	}
+	if err := g.obs.SetCurrentScene(reqCtx, scene); err != nil {
+		return fmt.Errorf("failed to switch to scene %s: %w", scene, err)
+	}
+
+	g.mu.Lock()
+	if g.restoreScene == "" {
+		g.restoreScene = current.Name
+	}
+	g.switchedTo = scene
+	g.mu.Unlock()
+	return nil
+}
+
+// warn posts a message to every community's chat
+func (g *Guardian) warn(ctx context.Context, message string) error {
+	payload, err := json.Marshal(map[string]string{
+		"message": message,
+		"source":  "stream_guardian",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal warning: %w", err)
+	}
+
+	var firstErr error
+	for _, chat := range g.chats {
+		reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		if err := chat.PostJSON(reqCtx, ChatPath, payload); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to post warning: %w", err)
+		}
+		cancel()
+	}
+	return firstErr
+}
+
+// restartStream stops the stream and starts it again
+func (g *Guardian) restartStream(ctx context.Context) error {
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	err := g.obs.StopStream(reqCtx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to stop the stream: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(restartDelay):
+	}
+
+	reqCtx, cancel = context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	if err := g.obs.StartStream(reqCtx); err != nil {
+		return fmt.Errorf("failed to start the stream: %w", err)
+	}
+	return nil
+}
+
+// recovered reports that the stream is healthy again and switches back to
+// the scene it was on, unless the scene was changed since
+func (g *Guardian) recovered(ctx context.Context, unhealthyFor time.Duration, restore, switchedTo string) {
+	metrics.StreamHealthy.Set(1)
+	g.logger.WithField("unhealthy_for", unhealthyFor.Round(time.Second)).Info("Stream has recovered")
+	g.publish(EventRecovered, map[string]interface{}{
+		"unhealthy_for": unhealthyFor.Seconds(),
+	})
+
+	if restore == "" {
+		return
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	current, err := g.obs.GetCurrentScene(reqCtx)
+	if err != nil || current.Name != switchedTo {
+		return
+	}
+	if err := g.obs.SetCurrentScene(reqCtx, restore); err != nil {
+		g.logger.WithError(err).WithField("scene", restore).Warn("Failed to switch back after the stream recovered")
+	}
+}
+
+// publish publishes an event on the bus, if there is one
+func (g *Guardian) publish(eventType string, data map[string]interface{}) {
+	if g.publisher == nil {
+		return
+	}
+	g.publisher.Publish(events.Event{
+		Type:   eventType,
+		Source: events.SourceBridge,
+		Data:   data,
+	})
+}
@@ -0,0 +1,166 @@
+package guardian
+
+import (
+	"context"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/obs"
+)
+
+type fakeOBS struct {
+	scene    string
+	switches []string
+	restarts int
+}
+
+func (f *fakeOBS) GetStreamStatus(ctx context.Context) (*obs.StreamStatus, error) {
+	return &obs.StreamStatus{Active: true}, nil
+}
+
+func (f *fakeOBS) GetStats(ctx context.Context) (*obs.OBSStats, error) {
+	return &obs.OBSStats{}, nil
+}
+
+func (f *fakeOBS) GetCurrentScene(ctx context.Context) (*obs.SceneInfo, error) {
+	return &obs.SceneInfo{Name: f.scene}, nil
+}
+
+func (f *fakeOBS) SetCurrentScene(ctx context.Context, sceneName string) error {
+	f.scene = sceneName
+	f.switches = append(f.switches, sceneName)
+	return nil
+}
+
+func (f *fakeOBS) StartStream(ctx context.Context) error { f.restarts++; return nil }
+func (f *fakeOBS) StopStream(ctx context.Context) error  { return nil }
+
+type fakeChat struct{ messages []string }
+
+func (f *fakeChat) PostJSON(ctx context.Context, path string, payload []byte) error {
+	f.messages = append(f.messages, string(payload))
+	return nil
+}
+
+type recorder struct{ types []string }
+
+func (r *recorder) Publish(event events.Event) { r.types = append(r.types, event.Type) }
+
+func newTestGuardian(actions ...config.GuardianAction) (*Guardian, *fakeOBS, *fakeChat, *recorder) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	obsClient := &fakeOBS{scene: "Gameplay"}
+	chat := &fakeChat{}
+	published := &recorder{}
+	cfg := config.GuardianConfig{
+		MaxDroppedRatio: 0.05,
+		MinBitrateKbps:  2000,
+		TriggerSamples:  2,
+		RecoverSamples:  2,
+		Cooldown:        time.Minute,
+		Actions:         actions,
+	}
+	return New(cfg, obsClient, []Chat{chat}, published, logger), obsClient, chat, published
+}
+
+var (
+	good = Sample{BitrateKbps: 6000}
+	bad  = Sample{DroppedRatio: 0.2, BitrateKbps: 6000}
+)
+
+func TestSampleBetween(t *testing.T) {
+	start := time.Now()
+	prev := counters{at: start, bytes: 1000, dropped: 10, frames: 100, encoderSkipped: 0, encoderFrames: 100}
+	cur := counters{at: start.Add(2 * time.Second), bytes: 1001000, dropped: 16, frames: 160, encoderSkipped: 3, encoderFrames: 160}
+
+	sample, ok := sampleBetween(prev, cur)
+	if !ok {
+		t.Fatal("Expected a sample")
+	}
+	if sample.DroppedRatio != 0.1 || sample.EncoderLagRatio != 0.05 || math.Abs(sample.BitrateKbps-4000) > 0.001 {
+		t.Errorf("Unexpected sample %+v", sample)
+	}
+
+	// Totals reset when the stream restarts
+	if _, ok := sampleBetween(cur, prev); ok {
+		t.Error("Expected no sample across a restart")
+	}
+}
+
+func TestGuardian_Hysteresis(t *testing.T) {
+	g, obsClient, chat, published := newTestGuardian(
+		config.GuardianAction{Type: ActionScene, Scene: "Low Bitrate"},
+		config.GuardianAction{Type: ActionWarn, Message: "Stream trouble: {reason}"},
+		config.GuardianAction{Type: ActionRestartStream, After: time.Hour},
+	)
+	ctx := context.Background()
+	now := time.Now()
+
+	g.evaluate(ctx, bad, now)
+	if len(obsClient.switches) != 0 {
+		t.Fatal("Expected no action before enough bad samples")
+	}
+
+	g.evaluate(ctx, bad, now.Add(time.Second))
+	if obsClient.scene != "Low Bitrate" {
+		t.Errorf("Expected a switch to the low bitrate scene, got %q", obsClient.scene)
+	}
+	if len(chat.messages) != 1 {
+		t.Fatalf("Expected one chat warning, got %v", chat.messages)
+	}
+	if obsClient.restarts != 0 {
+		t.Error("Expected the stream not to restart before its delay")
+	}
+
+	// Within the cooldown the actions do not run again
+	g.evaluate(ctx, bad, now.Add(2*time.Second))
+	if len(chat.messages) != 1 {
+		t.Errorf("Expected the warning to wait for the cooldown, got %v", chat.messages)
+	}
+
+	// One good sample is not enough to recover
+	g.evaluate(ctx, good, now.Add(3*time.Second))
+	if obsClient.scene != "Low Bitrate" {
+		t.Error("Expected the scene to stay until the stream recovers")
+	}
+
+	g.evaluate(ctx, good, now.Add(4*time.Second))
+	g.evaluate(ctx, good, now.Add(5*time.Second))
+	if obsClient.scene != "Gameplay" {
+		t.Errorf("Expected the original scene back after recovery, got %q", obsClient.scene)
+	}
+
+	want := []string{EventUnhealthy, EventAction, EventAction, EventRecovered}
+	if len(published.types) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, published.types)
+	}
+	for i := range want {
+		if published.types[i] != want[i] {
+			t.Errorf("Expected events %v, got %v", want, published.types)
+			break
+		}
+	}
+}
+
+func TestGuardian_KeepsSceneChangedByUser(t *testing.T) {
+	g, obsClient, _, _ := newTestGuardian(config.GuardianAction{Type: ActionScene, Scene: "Low Bitrate"})
+	ctx := context.Background()
+	now := time.Now()
+
+	g.evaluate(ctx, bad, now)
+	g.evaluate(ctx, bad, now.Add(time.Second))
+	obsClient.scene = "Just Chatting"
+
+	g.evaluate(ctx, good, now.Add(2*time.Second))
+	g.evaluate(ctx, good, now.Add(3*time.Second))
+	if obsClient.scene != "Just Chatting" {
+		t.Errorf("Expected the scene picked by the user to stay, got %q", obsClient.scene)
+	}
+}
@@ -0,0 +1,260 @@
+package keyboard
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Modifier is a key held down while the main key is pressed
+type Modifier string
+
+const (
+	ModCtrl  Modifier = "ctrl"
+	ModAlt   Modifier = "alt"
+	ModShift Modifier = "shift"
+	ModMeta  Modifier = "meta"
+)
+
+// modifierAliases maps accepted spellings onto modifiers
+var modifierAliases = map[string]Modifier{
+	"ctrl":    ModCtrl,
+	"control": ModCtrl,
+	"alt":     ModAlt,
+	"option":  ModAlt,
+	"shift":   ModShift,
+	"meta":    ModMeta,
+	"cmd":     ModMeta,
+	"command": ModMeta,
+	"super":   ModMeta,
+	"win":     ModMeta,
+}
+
+// namedKeys lists the supported non-character keys
+var namedKeys = map[string]bool{
+	"enter": true, "tab": true, "space": true, "escape": true, "backspace": true,
+	"delete": true, "insert": true, "home": true, "end": true, "pageup": true,
+	"pagedown": true, "up": true, "down": true, "left": true, "right": true,
+}
+
+// keyAliases maps alternative key names onto their canonical form
+var keyAliases = map[string]string{
+	"return": "enter",
+	"esc":    "escape",
+	"del":    "delete",
+	"ins":    "insert",
+	"pgup":   "pageup",
+	"pgdn":   "pagedown",
+}
+
+// Combo is a parsed key combination such as "ctrl+shift+m"
+type Combo struct {
+	Modifiers []Modifier
+	Key       string
+}
+
+// ParseCombo parses a "+"-separated key combination. The last element is the
+// key; all others must be modifiers.
+func ParseCombo(spec string) (Combo, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(spec)), "+")
+	if len(parts) == 0 || parts[len(parts)-1] == "" {
+		return Combo{}, fmt.Errorf("invalid key combination %q", spec)
+	}
+
+	var combo Combo
+	seen := make(map[Modifier]bool)
+	for _, part := range parts[:len(parts)-1] {
+		mod, ok := modifierAliases[strings.TrimSpace(part)]
+		if !ok {
+			return Combo{}, fmt.Errorf("unknown modifier %q in %q", part, spec)
+		}
+		if !seen[mod] {
+			seen[mod] = true
+			combo.Modifiers = append(combo.Modifiers, mod)
+		}
+	}
+
+	key := strings.TrimSpace(parts[len(parts)-1])
+	if alias, ok := keyAliases[key]; ok {
+		key = alias
+	}
+	if !validKey(key) {
+		return Combo{}, fmt.Errorf("unsupported key %q in %q", key, spec)
+	}
+	combo.Key = key
+
+	return combo, nil
+}
+
+// String returns the canonical form of the combination
+func (c Combo) String() string {
+	parts := make([]string, 0, len(c.Modifiers)+1)
+	for _, mod := range c.Modifiers {
+		parts = append(parts, string(mod))
+	}
+	return strings.Join(append(parts, c.Key), "+")
+}
+
+// validKey reports whether key is a single letter/digit, a function key or a
+// named key
+func validKey(key string) bool {
+	if len(key) == 1 {
+		c := key[0]
+		return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+	}
+	if functionKey(key) > 0 {
+		return true
+	}
+	return namedKeys[key]
+}
+
+// functionKey returns the number of an "f1".."f24" key, or 0
+func functionKey(key string) int {
+	var n int
+	if _, err := fmt.Sscanf(key, "f%d", &n); err != nil || fmt.Sprintf("f%d", n) != key {
+		return 0
+	}
+	if n < 1 || n > 24 {
+		return 0
+	}
+	return n
+}
+
+// Sender sends key combinations to the focused application
+type Sender interface {
+	Send(ctx context.Context, combo Combo) error
+}
+
+// NewSender returns the sender for the current platform
+func NewSender() Sender {
+	return &commandSender{goos: runtime.GOOS}
+}
+
+// commandSender sends keystrokes using the platform's automation tooling:
+// xdotool on Linux, System Events via osascript on macOS and SendKeys via
+// PowerShell on Windows
+type commandSender struct {
+	goos string
+}
+
+// Send sends the key combination
+func (s *commandSender) Send(ctx context.Context, combo Combo) error {
+	name, args, err := Command(s.goos, combo)
+	if err != nil {
+		return err
+	}
+
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to send keys: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Command returns the executable and arguments that send combo on goos
+func Command(goos string, combo Combo) (string, []string, error) {
+	switch goos {
+	case "linux", "freebsd", "openbsd":
+		return "xdotool", []string{"key", "--clearmodifiers", xdotoolCombo(combo)}, nil
+	case "darwin":
+		return "osascript", []string{"-e", appleScript(combo)}, nil
+	case "windows":
+		keys, err := sendKeysCombo(combo)
+		if err != nil {
+			return "", nil, err
+		}
+		script := fmt.Sprintf("Add-Type -AssemblyName System.Windows.Forms; [System.Windows.Forms.SendKeys]::SendWait('%s')", keys)
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, nil
+	default:
+		return "", nil, fmt.Errorf("keyboard emulation is not supported on %s", goos)
+	}
+}
+
+// xdotoolCombo formats a combination for xdotool
+func xdotoolCombo(combo Combo) string {
+	names := map[Modifier]string{ModCtrl: "ctrl", ModAlt: "alt", ModShift: "shift", ModMeta: "super"}
+	keys := map[string]string{
+		"enter": "Return", "tab": "Tab", "space": "space", "escape": "Escape",
+		"backspace": "BackSpace", "delete": "Delete", "insert": "Insert",
+		"home": "Home", "end": "End", "pageup": "Prior", "pagedown": "Next",
+		"up": "Up", "down": "Down", "left": "Left", "right": "Right",
+	}
+
+	parts := make([]string, 0, len(combo.Modifiers)+1)
+	for _, mod := range combo.Modifiers {
+		parts = append(parts, names[mod])
+	}
+
+	key := combo.Key
+	if n := functionKey(key); n > 0 {
+		key = fmt.Sprintf("F%d", n)
+	} else if mapped, ok := keys[key]; ok {
+		key = mapped
+	}
+
+	return strings.Join(append(parts, key), "+")
+}
+
+// appleScript builds a System Events script for a combination
+func appleScript(combo Combo) string {
+	names := map[Modifier]string{ModCtrl: "control down", ModAlt: "option down", ModShift: "shift down", ModMeta: "command down"}
+	keyCodes := map[string]int{
+		"enter": 36, "tab": 48, "space": 49, "escape": 53, "backspace": 51,
+		"delete": 117, "home": 115, "end": 119, "pageup": 116, "pagedown": 121,
+		"up": 126, "down": 125, "left": 123, "right": 124, "insert": 114,
+	}
+	fnCodes := []int{122, 120, 99, 118, 96, 97, 98, 100, 101, 109, 103, 111, 105, 107, 113, 106, 64, 79, 80, 90}
+
+	var action string
+	if n := functionKey(combo.Key); n > 0 && n <= len(fnCodes) {
+		action = fmt.Sprintf("key code %d", fnCodes[n-1])
+	} else if code, ok := keyCodes[combo.Key]; ok {
+		action = fmt.Sprintf("key code %d", code)
+	} else {
+		action = fmt.Sprintf("keystroke %q", combo.Key)
+	}
+
+	if len(combo.Modifiers) > 0 {
+		mods := make([]string, 0, len(combo.Modifiers))
+		for _, mod := range combo.Modifiers {
+			mods = append(mods, names[mod])
+		}
+		action += " using {" + strings.Join(mods, ", ") + "}"
+	}
+
+	return `tell application "System Events" to ` + action
+}
+
+// sendKeysCombo formats a combination for Windows SendKeys
+func sendKeysCombo(combo Combo) (string, error) {
+	prefixes := map[Modifier]string{ModCtrl: "^", ModAlt: "%", ModShift: "+"}
+	keys := map[string]string{
+		"enter": "{ENTER}", "tab": "{TAB}", "space": " ", "escape": "{ESC}",
+		"backspace": "{BACKSPACE}", "delete": "{DELETE}", "insert": "{INSERT}",
+		"home": "{HOME}", "end": "{END}", "pageup": "{PGUP}", "pagedown": "{PGDN}",
+		"up": "{UP}", "down": "{DOWN}", "left": "{LEFT}", "right": "{RIGHT}",
+	}
+
+	var prefix string
+	for _, mod := range combo.Modifiers {
+		p, ok := prefixes[mod]
+		if !ok {
+			return "", fmt.Errorf("modifier %s is not supported on windows", mod)
+		}
+		prefix += p
+	}
+
+	key := combo.Key
+	if n := functionKey(key); n > 0 {
+		if n > 16 {
+			return "", fmt.Errorf("key %s is not supported on windows", key)
+		}
+		key = fmt.Sprintf("{F%d}", n)
+	} else if mapped, ok := keys[key]; ok {
+		key = mapped
+	}
+
+	return prefix + key, nil
+}
@@ -0,0 +1,70 @@
+package keyboard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCombo(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{"ctrl+shift+m", "ctrl+shift+m", false},
+		{"Control + Alt + Delete", "ctrl+alt+delete", false},
+		{"cmd+esc", "meta+escape", false},
+		{"f13", "f13", false},
+		{"shift+shift+a", "shift+a", false},
+		{"ctrl+", "", true},
+		{"hyper+a", "", true},
+		{"ctrl+f25", "", true},
+		{"ctrl+@", "", true},
+		{"a+b", "", true},
+	}
+
+	for _, tt := range tests {
+		combo, err := ParseCombo(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCombo(%q): expected error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCombo(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if combo.String() != tt.want {
+			t.Errorf("ParseCombo(%q) = %s, want %s", tt.spec, combo, tt.want)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	combo, _ := ParseCombo("ctrl+shift+f5")
+
+	name, args, err := Command("linux", combo)
+	if err != nil || name != "xdotool" || args[len(args)-1] != "ctrl+shift+F5" {
+		t.Errorf("Unexpected linux command %s %v (%v)", name, args, err)
+	}
+
+	name, args, err = Command("darwin", combo)
+	if err != nil || name != "osascript" || !strings.Contains(args[1], "key code 96 using {control down, shift down}") {
+		t.Errorf("Unexpected darwin command %s %v (%v)", name, args, err)
+	}
+
+	name, args, err = Command("windows", combo)
+	if err != nil || name != "powershell" || !strings.Contains(args[len(args)-1], "SendWait('^+{F5}')") {
+		t.Errorf("Unexpected windows command %s %v (%v)", name, args, err)
+	}
+
+	meta, _ := ParseCombo("win+d")
+	if _, _, err := Command("windows", meta); err == nil {
+		t.Error("Expected error for meta modifier on windows")
+	}
+
+	if _, _, err := Command("plan9", combo); err == nil {
+		t.Error("Expected error for unsupported platform")
+	}
+}
@@ -0,0 +1,261 @@
+package keychain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned when the keychain holds no secret for an
+	// account
+	ErrNotFound = errors.New("secret not found in keychain")
+
+	// ErrUnsupported is returned when no OS keychain can be used on this
+	// platform
+	ErrUnsupported = errors.New("OS keychain is not supported on this platform")
+)
+
+// Service names the bridge's secrets in the OS keychain
+const Service = "waddlebot-bridge"
+
+// Keychain stores small secrets, such as tokens and keys, under an account
+// name
+type Keychain interface {
+	Get(account string) ([]byte, error)
+	Set(account string, secret []byte) error
+	Delete(account string) error
+}
+
+// New returns the OS keychain: the login keychain on macOS, the Secret
+// Service (through secret-tool) on Linux and files protected with the
+// user's Windows credentials (DPAPI) in dir on Windows. Secrets are kept
+// under service.
+func New(service, dir string) Keychain {
+	return &osKeychain{goos: runtime.GOOS, service: service, dir: dir}
+}
+
+// Supported reports whether the OS keychain can be used on this platform
+func Supported() bool {
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return true
+	case "linux":
+		_, err := exec.LookPath("secret-tool")
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// osKeychain runs the platform's keychain tools. Secrets are passed base64
+// encoded, through stdin or the environment where the tool allows it.
+type osKeychain struct {
+	goos    string
+	service string
+	dir     string
+}
+
+// Get returns the secret stored for account
+func (k *osKeychain) Get(account string) ([]byte, error) {
+	if k.goos == "windows" {
+		if _, err := os.Stat(k.protectedPath(account)); os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+	}
+
+	cmd, err := k.getCommand(account)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if k.notFound(err, stderr.String()) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("keychain read failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	encoded := strings.TrimSpace(stdout.String())
+	if encoded == "" {
+		return nil, ErrNotFound
+	}
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode keychain secret: %w", err)
+	}
+	return secret, nil
+}
+
+// Set stores secret for account, replacing any earlier secret
+func (k *osKeychain) Set(account string, secret []byte) error {
+	if k.goos == "windows" {
+		if err := os.MkdirAll(k.dir, 0700); err != nil {
+			return fmt.Errorf("failed to create keychain directory: %w", err)
+		}
+	}
+
+	cmd, err := k.setCommand(account, base64.StdEncoding.EncodeToString(secret))
+	if err != nil {
+		return err
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("keychain write failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Delete removes the secret stored for account
+func (k *osKeychain) Delete(account string) error {
+	if k.goos == "windows" {
+		if err := os.Remove(k.protectedPath(account)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete keychain secret: %w", err)
+		}
+		return nil
+	}
+
+	cmd, err := k.deleteCommand(account)
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil && !k.notFound(err, stderr.String()) {
+		return fmt.Errorf("keychain delete failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// getCommand returns the command that prints the secret of account
+func (k *osKeychain) getCommand(account string) (*exec.Cmd, error) {
+	switch k.goos {
+	case "darwin":
+		return exec.Command("security", "find-generic-password", "-s", k.service, "-a", account, "-w"), nil
+	case "linux":
+		return exec.Command("secret-tool", "lookup", "service", k.service, "account", account), nil
+	case "windows":
+		script := `Add-Type -AssemblyName System.Security; ` +
+			`$p = [IO.File]::ReadAllBytes($env:WADDLEBOT_SECRET_PATH); ` +
+			`$b = [Security.Cryptography.ProtectedData]::Unprotect($p, $null, 'CurrentUser'); ` +
+			`[Text.Encoding]::ASCII.GetString($b)`
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Env = append(os.Environ(), "WADDLEBOT_SECRET_PATH="+k.protectedPath(account))
+		return cmd, nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// setCommand returns the command that stores the encoded secret of account
+func (k *osKeychain) setCommand(account, encoded string) (*exec.Cmd, error) {
+	switch k.goos {
+	case "darwin":
+		// security only takes the secret as an argument
+		return exec.Command("security", "add-generic-password", "-U", "-s", k.service, "-a", account,
+			"-l", k.service+" "+account, "-w", encoded), nil
+	case "linux":
+		cmd := exec.Command("secret-tool", "store", "--label", k.service+" "+account,
+			"service", k.service, "account", account)
+		cmd.Stdin = strings.NewReader(encoded)
+		return cmd, nil
+	case "windows":
+		script := `Add-Type -AssemblyName System.Security; ` +
+			`$b = [Text.Encoding]::ASCII.GetBytes($env:WADDLEBOT_SECRET); ` +
+			`$p = [Security.Cryptography.ProtectedData]::Protect($b, $null, 'CurrentUser'); ` +
+			`[IO.File]::WriteAllBytes($env:WADDLEBOT_SECRET_PATH, $p)`
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Env = append(os.Environ(),
+			"WADDLEBOT_SECRET="+encoded,
+			"WADDLEBOT_SECRET_PATH="+k.protectedPath(account),
+		)
+		return cmd, nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// deleteCommand returns the command that removes the secret of account
+func (k *osKeychain) deleteCommand(account string) (*exec.Cmd, error) {
+	switch k.goos {
+	case "darwin":
+		return exec.Command("security", "delete-generic-password", "-s", k.service, "-a", account), nil
+	case "linux":
+		return exec.Command("secret-tool", "clear", "service", k.service, "account", account), nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// notFound reports whether a failed keychain command failed because the
+// secret does not exist
+func (k *osKeychain) notFound(err error, stderr string) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	switch k.goos {
+	case "darwin":
+		return exitErr.ExitCode() == 44 // errSecItemNotFound
+	case "linux":
+		return strings.TrimSpace(stderr) == ""
+	default:
+		return false
+	}
+}
+
+// protectedPath returns the file holding the DPAPI-protected secret of
+// account on Windows
+func (k *osKeychain) protectedPath(account string) string {
+	return filepath.Join(k.dir, k.service+"-"+account+".dpapi")
+}
+
+// Memory is a keychain that keeps secrets in memory, for tests and for
+// platforms without an OS keychain
+type Memory struct {
+	mu      sync.Mutex
+	secrets map[string][]byte
+}
+
+// NewMemory creates an empty in-memory keychain
+func NewMemory() *Memory {
+	return &Memory{secrets: make(map[string][]byte)}
+}
+
+// Get returns the secret stored for account
+func (m *Memory) Get(account string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	secret, exists := m.secrets[account]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), secret...), nil
+}
+
+// Set stores secret for account
+func (m *Memory) Set(account string, secret []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.secrets[account] = append([]byte(nil), secret...)
+	return nil
+}
+
+// Delete removes the secret stored for account
+func (m *Memory) Delete(account string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.secrets, account)
+	return nil
+}
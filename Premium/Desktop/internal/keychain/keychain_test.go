@@ -0,0 +1,74 @@
+package keychain
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	k := NewMemory()
+	if _, err := k.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	secret := []byte("secret")
+	if err := k.Set("token", secret); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	secret[0] = 'X'
+
+	got, err := k.Get("token")
+	if err != nil || string(got) != "secret" {
+		t.Errorf("expected the stored secret, got %q, %v", got, err)
+	}
+
+	k.Delete("token")
+	if _, err := k.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after Delete, got %v", err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	k := &osKeychain{goos: "linux", service: "waddlebot-bridge", dir: "keys"}
+	cmd, err := k.setCommand("device-token", "c2VjcmV0")
+	if err != nil {
+		t.Fatalf("setCommand failed: %v", err)
+	}
+	if strings.Contains(strings.Join(cmd.Args, " "), "c2VjcmV0") {
+		t.Error("expected secret-tool to read the secret from stdin")
+	}
+	if stdin, _ := io.ReadAll(cmd.Stdin); string(stdin) != "c2VjcmV0" {
+		t.Errorf("expected the secret on stdin, got %q", stdin)
+	}
+
+	k.goos = "windows"
+	cmd, err = k.setCommand("device-token", "c2VjcmV0")
+	if err != nil {
+		t.Fatalf("setCommand failed: %v", err)
+	}
+	if strings.Contains(strings.Join(cmd.Args, " "), "c2VjcmV0") {
+		t.Error("expected the secret to be passed through the environment")
+	}
+	if !containsEnv(cmd.Env, "WADDLEBOT_SECRET=c2VjcmV0") {
+		t.Error("expected WADDLEBOT_SECRET in the environment")
+	}
+
+	k.goos = "plan9"
+	if _, err := k.getCommand("device-token"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	if err := k.Set("device-token", []byte("secret")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func containsEnv(env []string, value string) bool {
+	for _, e := range env {
+		if e == value {
+			return true
+		}
+	}
+	return false
+}
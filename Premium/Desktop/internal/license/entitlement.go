@@ -0,0 +1,129 @@
+package license
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Subscription tiers
+const (
+	TierPremium    = "premium"
+	TierPro        = "pro"
+	TierEnterprise = "enterprise"
+)
+
+// Features a subscription can grant
+const (
+	FeatureMacros            = "macros"
+	FeatureScriptingLua      = "scripting-lua"
+	FeatureScriptingExternal = "scripting-external" // Python, Bash and PowerShell
+	FeatureMultiOBS          = "multi-obs"
+	FeatureGatewayExternal   = "gateway-external" // gateway reachable from other machines
+)
+
+// tierFeatures are the features each tier grants
+var tierFeatures = map[string][]string{
+	TierPremium: {FeatureMacros, FeatureScriptingLua},
+	TierPro: {FeatureMacros, FeatureScriptingLua, FeatureScriptingExternal,
+		FeatureMultiOBS, FeatureGatewayExternal},
+	TierEnterprise: {FeatureMacros, FeatureScriptingLua, FeatureScriptingExternal,
+		FeatureMultiOBS, FeatureGatewayExternal},
+}
+
+var (
+	// ErrNoSubscription is returned when the API reports no active
+	// subscription for the bridge's user
+	ErrNoSubscription = errors.New("no active WaddleBot Premium subscription")
+
+	// ErrNoSigningKey is returned when there is no key to verify
+	// entitlement tokens with
+	ErrNoSigningKey = errors.New("no license signing key; set license.public-key")
+
+	// ErrInvalidToken is returned for entitlement tokens that are malformed
+	// or whose signature does not verify
+	ErrInvalidToken = errors.New("entitlement token does not verify")
+
+	// ErrExpired is returned when an entitlement's offline grace period is
+	// over without the subscription being verified again
+	ErrExpired = errors.New("entitlement expired; the subscription could not be verified online")
+
+	// ErrUnverified is returned when a bridge without a kept entitlement
+	// could not verify its subscription in time
+	ErrUnverified = errors.New("the subscription could not be verified")
+)
+
+// Entitlement is what the user's subscription allows, as signed by the
+// WaddleBot API
+type Entitlement struct {
+	UserID     string    `json:"user_id"`
+	Tier       string    `json:"tier"`
+	Features   []string  `json:"features,omitempty"` // granted on top of the tier's
+	ExpiresAt  time.Time `json:"expires_at"`         // end of the paid subscription period
+	IssuedAt   time.Time `json:"issued_at"`
+	GraceUntil time.Time `json:"grace_until"` // the token is accepted offline until then
+}
+
+// Valid reports whether the entitlement may still be used at now
+func (e *Entitlement) Valid(now time.Time) bool {
+	return now.Before(e.GraceUntil)
+}
+
+// Has reports whether the entitlement grants feature
+func (e *Entitlement) Has(feature string) bool {
+	for _, f := range e.AllFeatures() {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// AllFeatures returns the features of the tier and those granted on top,
+// sorted
+func (e *Entitlement) AllFeatures() []string {
+	seen := make(map[string]bool)
+	var features []string
+	for _, list := range [][]string{tierFeatures[strings.ToLower(e.Tier)], e.Features} {
+		for _, feature := range list {
+			if !seen[feature] {
+				seen[feature] = true
+				features = append(features, feature)
+			}
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+// parseToken verifies an entitlement token, the base64url JSON entitlement
+// and its base64url ed25519 signature joined by a dot. The signature is not
+// checked when key is nil.
+func parseToken(token string, key ed25519.PublicKey) (*Entitlement, error) {
+	payloadText, signatureText, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return nil, fmt.Errorf("%w: not a signed token", ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadText)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(signatureText)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if key != nil && !ed25519.Verify(key, payload, signature) {
+		return nil, ErrInvalidToken
+	}
+
+	var entitlement Entitlement
+	if err := json.Unmarshal(payload, &entitlement); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return &entitlement, nil
+}
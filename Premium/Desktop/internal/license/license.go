@@ -7,7 +7,6 @@ import (
 	"os"
 	"runtime"
 	"strings"
-	"time"
 )
 
 const (
@@ -60,15 +59,14 @@ WaddleBot Premium Desktop Bridge v1.0.0
 	licenseAcceptanceFile = ".license-accepted"
 )
 
-// ValidateLicense checks if the user has accepted the premium license
+// ValidateLicense checks if the user has accepted the premium license. The
+// subscription itself is verified online by a Verifier.
 func ValidateLicense() bool {
 	// Check if license has been accepted
 	if !hasAcceptedLicense() {
 		return promptForLicenseAcceptance()
 	}
 
-	// TODO: In production, this should verify the user's premium subscription status
-	// For now, we'll assume the license is valid if accepted
 	return true
 }
 
@@ -98,7 +96,7 @@ func hasAcceptedLicense() bool {
 
 // promptForLicenseAcceptance displays the license and prompts for acceptance
 func promptForLicenseAcceptance() bool {
-	fmt.Println(LicenseText)
+	fmt.Print(LicenseText)
 	fmt.Println(strings.Repeat("=", 80))
 	fmt.Println("WaddleBot Premium Desktop Bridge License Agreement")
 	fmt.Println(strings.Repeat("=", 80))
@@ -153,14 +151,14 @@ func saveLicenseAcceptance() error {
 	return nil
 }
 
-// generateLicenseHash generates a hash of the license text and system info
+// generateLicenseHash generates a hash of the license text and system info,
+// so that acceptance is asked again when the license text changes
 func generateLicenseHash() string {
 	// Combine license text with system information for uniqueness
-	data := fmt.Sprintf("%s|%s|%s|%d",
+	data := fmt.Sprintf("%s|%s|%s",
 		LicenseText,
 		runtime.GOOS,
 		runtime.GOARCH,
-		time.Now().Unix()/86400, // Day-based timestamp
 	)
 
 	hash := sha256.Sum256([]byte(data))
@@ -0,0 +1,333 @@
+package license
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apitls"
+	"waddlebot-bridge/internal/config"
+)
+
+// SigningKey is the base64 ed25519 public key WaddleBot signs entitlement
+// tokens with. Release builds set it with
+// -ldflags "-X waddlebot-bridge/internal/license.SigningKey=...".
+var SigningKey = ""
+
+// entitlementPath is the API path of the user's entitlement
+const entitlementPath = "/api/bridge/entitlement"
+
+// tokenFile holds the last verified token in the data directory
+const tokenFile = "license.token"
+
+// minCheckInterval bounds how often the subscription is verified
+const minCheckInterval = time.Hour
+
+// graceWarning is how long before the grace period ends that failed
+// verifications are logged as warnings
+const graceWarning = 24 * time.Hour
+
+// pendingInterval is how often a bridge that could not verify its
+// subscription yet, such as before the user signs in, tries again
+const pendingInterval = time.Minute
+
+// unverifiedLimit is how long a bridge runs without a verified
+// subscription
+const unverifiedLimit = time.Hour
+
+// TokenSource provides authentication tokens for API requests
+type TokenSource interface {
+	GetAuthToken() (string, error)
+}
+
+// Verifier verifies the user's subscription with the WaddleBot API. Each
+// verification returns a signed token that is kept in the data directory,
+// so the bridge keeps running through API outages until the token's grace
+// period ends.
+type Verifier struct {
+	config *config.Config
+	key    ed25519.PublicKey
+	client *http.Client
+	logger *logrus.Logger
+	now    func() time.Time
+
+	mu              sync.RWMutex
+	entitlement     *Entitlement
+	unverifiedUntil time.Time // when a bridge that never verified stops
+}
+
+// NewVerifier creates a verifier for cfg's user
+func NewVerifier(cfg *config.Config, logger *logrus.Logger) (*Verifier, error) {
+	tlsConfig, err := apitls.NewConfig(cfg.APITLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure API TLS: %w", err)
+	}
+
+	// The built-in key always wins, so a user cannot sign their own token
+	// and run offline on it
+	keyText := SigningKey
+	if keyText == "" {
+		keyText = cfg.License.PublicKey
+	} else if cfg.License.PublicKey != "" && cfg.License.PublicKey != SigningKey {
+		logger.Warn("Ignoring license.public-key; this build has a license signing key built in")
+	}
+	var key ed25519.PublicKey
+	if keyText != "" {
+		decoded, err := base64.StdEncoding.DecodeString(keyText)
+		if err != nil || len(decoded) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("license signing key is not a base64 ed25519 public key")
+		}
+		key = decoded
+	}
+
+	return &Verifier{
+		config: cfg,
+		key:    key,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: apitls.NewTransport(tlsConfig),
+		},
+		logger: logger,
+		now:    time.Now,
+	}, nil
+}
+
+// Entitlement returns the current entitlement, or nil when there is none
+// or its grace period is over
+func (v *Verifier) Entitlement() *Entitlement {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.entitlement == nil || !v.entitlement.Valid(v.now()) {
+		return nil
+	}
+	return v.entitlement
+}
+
+// Check verifies the subscription at startup. When the API cannot be
+// reached, the token kept from the last verification is used instead
+// while it is in its grace period. Without one, Run keeps trying for a
+// while, so a user can still sign in; ErrNoSubscription and
+// ErrInvalidToken mean the bridge may not run.
+func (v *Verifier) Check(ctx context.Context, tokens TokenSource) (*Entitlement, error) {
+	cached, cacheErr := v.loadCached()
+
+	entitlement, err := v.Verify(ctx, tokens)
+	if err == nil {
+		return entitlement, nil
+	}
+	if errors.Is(err, ErrNoSubscription) || cached == nil {
+		if cacheErr != nil && !os.IsNotExist(cacheErr) {
+			v.logger.WithError(cacheErr).Debug("Ignoring the kept entitlement token")
+		}
+		v.mu.Lock()
+		v.unverifiedUntil = v.now().Add(unverifiedLimit)
+		v.mu.Unlock()
+		return nil, err
+	}
+
+	v.logger.WithError(err).WithField("grace_until", cached.GraceUntil).Warn("Failed to verify the subscription; using the last verification")
+	return cached, nil
+}
+
+// Verify asks the API for the user's entitlement and keeps the signed
+// token. A lapsed subscription returns ErrNoSubscription and forgets the
+// kept token; other failures leave the current entitlement in place.
+func (v *Verifier) Verify(ctx context.Context, tokens TokenSource) (*Entitlement, error) {
+	token, err := v.fetch(ctx, tokens)
+	if errors.Is(err, ErrNoSubscription) {
+		v.set(nil)
+		if err := os.Remove(v.tokenPath()); err != nil && !os.IsNotExist(err) {
+			v.logger.WithError(err).Warn("Failed to remove the kept entitlement token")
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a key the API's answer is trusted over its TLS connection,
+	// but is not kept for offline use
+	entitlement, err := parseToken(token, v.key)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkUser(entitlement); err != nil {
+		return nil, err
+	}
+	if !entitlement.Valid(v.now()) {
+		return nil, ErrExpired
+	}
+	v.set(entitlement)
+
+	if v.key == nil {
+		v.logger.Warn("No license signing key; the subscription is not kept for offline use")
+	} else if err := v.saveToken(token); err != nil {
+		v.logger.WithError(err).Warn("Failed to keep the entitlement token for offline use")
+	}
+	return entitlement, nil
+}
+
+// Run verifies the subscription every license.check-interval until ctx is
+// cancelled, and every minute while it has not been verified. It returns
+// an error when the subscription lapses, or when it could not be verified
+// in time.
+func (v *Verifier) Run(ctx context.Context, tokens TokenSource) error {
+	interval := v.config.License.CheckInterval
+	if interval < minCheckInterval {
+		interval = minCheckInterval
+	}
+
+	for {
+		wait := interval
+		if v.Entitlement() == nil {
+			wait = pendingInterval
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+			if err := v.recheck(ctx, tokens); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// recheck runs a periodic verification, returning an error only when the
+// bridge may no longer run
+func (v *Verifier) recheck(ctx context.Context, tokens TokenSource) error {
+	entitlement, err := v.Verify(ctx, tokens)
+	if err == nil {
+		v.logger.WithField("tier", entitlement.Tier).Debug("Subscription verified")
+		return nil
+	}
+	if errors.Is(err, ErrNoSubscription) || errors.Is(err, ErrInvalidToken) {
+		return err
+	}
+
+	v.mu.RLock()
+	current, unverifiedUntil := v.entitlement, v.unverifiedUntil
+	v.mu.RUnlock()
+	if current == nil {
+		if v.now().After(unverifiedUntil) {
+			return fmt.Errorf("%w: %v", ErrUnverified, err)
+		}
+		v.logger.WithError(err).Debug("Subscription not verified yet")
+		return nil
+	}
+	if !current.Valid(v.now()) {
+		return fmt.Errorf("%w: %v", ErrExpired, err)
+	}
+
+	entry := v.logger.WithError(err).WithField("grace_until", current.GraceUntil)
+	if current.GraceUntil.Sub(v.now()) < graceWarning {
+		entry.Warn("Failed to verify the subscription; the bridge stops when the grace period ends")
+	} else {
+		entry.Debug("Failed to verify the subscription")
+	}
+	return nil
+}
+
+// fetch requests a signed entitlement token from the API
+func (v *Verifier) fetch(ctx context.Context, tokens TokenSource) (string, error) {
+	authToken, err := tokens.GetAuthToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.config.GetAPIEndpoint(entitlementPath), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+authToken)
+	req.Header.Set("User-Agent", v.config.GetUserAgent())
+	req.Header.Set("X-Community-ID", v.config.CommunityID)
+	req.Header.Set("X-User-ID", v.config.UserID)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify subscription: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusPaymentRequired, http.StatusForbidden:
+		return "", ErrNoSubscription
+	default:
+		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	return response.Token, nil
+}
+
+// loadCached reads the token kept from the last verification and makes
+// it the current entitlement if it still verifies
+func (v *Verifier) loadCached() (*Entitlement, error) {
+	if v.key == nil {
+		return nil, ErrNoSigningKey
+	}
+	data, err := os.ReadFile(v.tokenPath())
+	if err != nil {
+		return nil, err
+	}
+	entitlement, err := parseToken(string(data), v.key)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkUser(entitlement); err != nil {
+		return nil, err
+	}
+	if !entitlement.Valid(v.now()) {
+		return nil, ErrExpired
+	}
+	v.set(entitlement)
+	return entitlement, nil
+}
+
+// checkUser rejects entitlements issued to another user
+func (v *Verifier) checkUser(entitlement *Entitlement) error {
+	if v.config.UserID != "" && entitlement.UserID != v.config.UserID {
+		return fmt.Errorf("%w: issued to another user", ErrInvalidToken)
+	}
+	return nil
+}
+
+func (v *Verifier) saveToken(token string) error {
+	if err := os.MkdirAll(v.config.DataDir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(v.tokenPath(), []byte(token), 0600)
+}
+
+func (v *Verifier) tokenPath() string {
+	return filepath.Join(v.config.DataDir, tokenFile)
+}
+
+func (v *Verifier) set(entitlement *Entitlement) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.entitlement = entitlement
+}
@@ -0,0 +1,233 @@
+package license
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+type staticTokens struct{}
+
+func (staticTokens) GetAuthToken() (string, error) {
+	return "auth-token", nil
+}
+
+func signToken(t *testing.T, key ed25519.PrivateKey, entitlement Entitlement) string {
+	payload, err := json.Marshal(entitlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, payload))
+}
+
+// testAPI serves the entitlement endpoint with status and token
+func testAPI(t *testing.T, status *atomic.Int32, token *atomic.Value) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != entitlementPath || r.Header.Get("Authorization") != "Bearer auth-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(int(status.Load()))
+		json.NewEncoder(w).Encode(map[string]string{"token": token.Load().(string)})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestVerifier(t *testing.T, apiURL string, public ed25519.PublicKey) *Verifier {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{
+		APIURL:  apiURL,
+		UserID:  "user",
+		DataDir: t.TempDir(),
+		License: config.LicenseConfig{PublicKey: base64.StdEncoding.EncodeToString(public)},
+	}
+	verifier, err := NewVerifier(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewVerifier failed: %v", err)
+	}
+	return verifier
+}
+
+func TestVerifier(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+	valid := Entitlement{UserID: "user", Tier: TierPremium, Features: []string{FeatureMultiOBS}, IssuedAt: now, GraceUntil: now.Add(72 * time.Hour)}
+
+	var status atomic.Int32
+	var token atomic.Value
+	status.Store(http.StatusOK)
+	token.Store(signToken(t, private, valid))
+	server := testAPI(t, &status, &token)
+	verifier := newTestVerifier(t, server.URL, public)
+	ctx := context.Background()
+
+	entitlement, err := verifier.Check(ctx, staticTokens{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !entitlement.Has(FeatureScriptingLua) || !entitlement.Has(FeatureMultiOBS) || entitlement.Has(FeatureGatewayExternal) {
+		t.Errorf("Expected the premium features and multi-obs, got %v", entitlement.AllFeatures())
+	}
+	if _, err := os.Stat(filepath.Join(verifier.config.DataDir, tokenFile)); err != nil {
+		t.Errorf("Expected the token to be kept: %v", err)
+	}
+
+	// The kept token carries a restarted bridge through an API outage
+	status.Store(http.StatusBadGateway)
+	restarted := newTestVerifier(t, server.URL, public)
+	restarted.config.DataDir = verifier.config.DataDir
+	if entitlement, err := restarted.Check(ctx, staticTokens{}); err != nil || entitlement.Tier != TierPremium {
+		t.Fatalf("Expected the kept entitlement offline, got %v, %v", entitlement, err)
+	}
+	if err := restarted.recheck(ctx, staticTokens{}); err != nil {
+		t.Errorf("Expected the grace period to cover the outage, got %v", err)
+	}
+
+	// Until the grace period ends
+	restarted.now = func() time.Time { return now.Add(73 * time.Hour) }
+	if err := restarted.recheck(ctx, staticTokens{}); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired after the grace period, got %v", err)
+	}
+	if restarted.Entitlement() != nil {
+		t.Error("Expected no entitlement after the grace period")
+	}
+
+	// A lapsed subscription forgets the kept token
+	status.Store(http.StatusPaymentRequired)
+	if err := verifier.recheck(ctx, staticTokens{}); !errors.Is(err, ErrNoSubscription) {
+		t.Errorf("Expected ErrNoSubscription, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(verifier.config.DataDir, tokenFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the kept token to be removed, got %v", err)
+	}
+	if _, err := verifier.Check(ctx, staticTokens{}); !errors.Is(err, ErrNoSubscription) {
+		t.Errorf("Expected the bridge not to start without a subscription, got %v", err)
+	}
+}
+
+func TestVerifier_RejectsTokens(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+	valid := Entitlement{UserID: "user", Tier: TierPro, GraceUntil: now.Add(time.Hour)}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"wrong key", signToken(t, otherKey, valid), ErrInvalidToken},
+		{"other user", signToken(t, private, Entitlement{UserID: "other", Tier: TierPro, GraceUntil: now.Add(time.Hour)}), ErrInvalidToken},
+		{"unsigned", base64.RawURLEncoding.EncodeToString([]byte(`{"user_id":"user"}`)), ErrInvalidToken},
+		{"expired", signToken(t, private, Entitlement{UserID: "user", Tier: TierPro, GraceUntil: now.Add(-time.Hour)}), ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status atomic.Int32
+			var token atomic.Value
+			status.Store(http.StatusOK)
+			token.Store(tt.token)
+			verifier := newTestVerifier(t, testAPI(t, &status, &token).URL, public)
+
+			if _, err := verifier.Check(context.Background(), staticTokens{}); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_BuiltInKeyWins(t *testing.T) {
+	builtIn, builtInPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	userKey, userPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	SigningKey = base64.StdEncoding.EncodeToString(builtIn)
+	t.Cleanup(func() { SigningKey = "" })
+
+	var status atomic.Int32
+	var token atomic.Value
+	status.Store(http.StatusServiceUnavailable)
+	token.Store("")
+	verifier := newTestVerifier(t, testAPI(t, &status, &token).URL, userKey)
+	ctx := context.Background()
+
+	// A token the user signed with their own key, kept for while the API
+	// is blocked
+	forever := Entitlement{UserID: "user", Tier: TierEnterprise, GraceUntil: time.Now().AddDate(100, 0, 0)}
+	if err := os.WriteFile(verifier.tokenPath(), []byte(signToken(t, userPrivate, forever)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if entitlement, err := verifier.Check(ctx, staticTokens{}); err == nil || entitlement != nil || verifier.Entitlement() != nil {
+		t.Fatalf("Expected a token signed with license.public-key rejected, got %+v", entitlement)
+	}
+
+	if err := os.WriteFile(verifier.tokenPath(), []byte(signToken(t, builtInPrivate, forever)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Check(ctx, staticTokens{}); err != nil || verifier.Entitlement() == nil {
+		t.Errorf("Expected a token signed with the built-in key accepted, got %v", err)
+	}
+}
+
+func TestVerifier_Unverified(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+
+	var status atomic.Int32
+	var token atomic.Value
+	status.Store(http.StatusServiceUnavailable)
+	token.Store("")
+	verifier := newTestVerifier(t, testAPI(t, &status, &token).URL, public)
+	ctx := context.Background()
+
+	// Not being able to verify yet, such as before signing in, is no
+	// reason to stop right away
+	if _, err := verifier.Check(ctx, staticTokens{}); err == nil || errors.Is(err, ErrNoSubscription) {
+		t.Fatalf("Expected a temporary failure, got %v", err)
+	}
+	if err := verifier.recheck(ctx, staticTokens{}); err != nil {
+		t.Errorf("Expected the bridge to keep trying, got %v", err)
+	}
+
+	status.Store(http.StatusOK)
+	token.Store(signToken(t, private, Entitlement{UserID: "user", Tier: TierPremium, GraceUntil: now.Add(72 * time.Hour)}))
+	if err := verifier.recheck(ctx, staticTokens{}); err != nil || verifier.Entitlement() == nil {
+		t.Fatalf("Expected the subscription to be verified, got %v", err)
+	}
+
+	unverified := newTestVerifier(t, verifier.config.APIURL, public)
+	status.Store(http.StatusServiceUnavailable)
+	unverified.Check(ctx, staticTokens{})
+	unverified.now = func() time.Time { return now.Add(2 * unverifiedLimit) }
+	if err := unverified.recheck(ctx, staticTokens{}); !errors.Is(err, ErrUnverified) {
+		t.Errorf("Expected ErrUnverified once the limit passed, got %v", err)
+	}
+}
+
+func TestEntitlement_TierFeatures(t *testing.T) {
+	pro := Entitlement{Tier: "Pro"}
+	if !pro.Has(FeatureScriptingExternal) || !pro.Has(FeatureGatewayExternal) {
+		t.Errorf("Expected pro features, got %v", pro.AllFeatures())
+	}
+	unknown := Entitlement{Tier: "trial", Features: []string{FeatureMacros}}
+	if features := unknown.AllFeatures(); len(features) != 1 || features[0] != FeatureMacros {
+		t.Errorf("Expected only the granted feature for an unknown tier, got %v", features)
+	}
+}
@@ -0,0 +1,163 @@
+// Package localtls provides TLS for the bridge's local HTTP servers, the
+// API gateway and the WebAuthn web server. A user-supplied certificate and
+// key are used when configured; otherwise a self-signed certificate is
+// generated once and kept in the data directory.
+package localtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+)
+
+// certValidity is the lifetime of generated certificates
+const certValidity = 2 * 365 * 24 * time.Hour
+
+// renewBefore is how long before expiry a generated certificate is replaced
+const renewBefore = 30 * 24 * time.Hour
+
+// defaultHosts are always included in generated certificates
+var defaultHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// NewServerConfig returns the TLS configuration for a local server. The
+// server name selects the generated certificate files, <dataDir>/tls/<name>.crt
+// and .key, and host is the address the server listens on, which is added
+// to the certificate unless it is a wildcard address.
+func NewServerConfig(cfg config.ServerTLSConfig, dataDir, name, host string) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+
+	switch {
+	case cfg.CertFile != "" || cfg.KeyFile != "":
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("both cert-file and key-file are required")
+		}
+		cert, err = tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+
+	default:
+		hosts := append([]string{}, defaultHosts...)
+		if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+			hosts = append(hosts, host)
+		}
+		hosts = append(hosts, cfg.Hosts...)
+
+		dir := filepath.Join(dataDir, "tls")
+		cert, err = LoadOrGenerate(filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key"), hosts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}, nil
+}
+
+// LoadOrGenerate loads a self-signed certificate from certFile and keyFile,
+// generating a new one when the files are missing, the certificate is
+// close to expiry or it does not cover every host
+func LoadOrGenerate(certFile, keyFile string, hosts []string) (tls.Certificate, error) {
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && usable(leaf, hosts) {
+			cert.Leaf = leaf
+			return cert, nil
+		}
+	}
+
+	certPEM, keyPEM, err := Generate(hosts)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create TLS directory: %w", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to write TLS key: %w", err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to write TLS certificate: %w", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load generated certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// Generate creates a self-signed ECDSA P-256 certificate for hosts and
+// returns it and its key PEM-encoded
+func Generate(hosts []string) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate TLS key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"WaddleBot Bridge"}, CommonName: "WaddleBot Bridge"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode TLS key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// usable reports whether a certificate is valid for a while longer and
+// covers every host
+func usable(cert *x509.Certificate, hosts []string) bool {
+	if time.Until(cert.NotAfter) < renewBefore {
+		return false
+	}
+	for _, host := range hosts {
+		if host != "" && cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package localtls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func TestNewServerConfig_GeneratesAndReuses(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.ServerTLSConfig{Enabled: true, Hosts: []string{"bridge.lan"}}
+
+	tlsConfig, err := NewServerConfig(cfg, dir, "gateway", "192.168.1.10")
+	if err != nil {
+		t.Fatalf("NewServerConfig failed: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("invalid certificate: %v", err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "192.168.1.10", "bridge.lan"} {
+		if err := leaf.VerifyHostname(host); err != nil {
+			t.Errorf("Expected certificate to cover %s: %v", host, err)
+		}
+	}
+
+	certFile := filepath.Join(dir, "tls", "gateway.crt")
+	first, _ := os.ReadFile(certFile)
+	if info, err := os.Stat(filepath.Join(dir, "tls", "gateway.key")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected key file with mode 0600, got %v", info)
+	}
+
+	if _, err := NewServerConfig(cfg, dir, "gateway", "0.0.0.0"); err != nil {
+		t.Fatalf("NewServerConfig failed: %v", err)
+	}
+	if second, _ := os.ReadFile(certFile); !bytes.Equal(first, second) {
+		t.Error("Expected the stored certificate to be reused")
+	}
+
+	cfg.Hosts = append(cfg.Hosts, "studio.lan")
+	if _, err := NewServerConfig(cfg, dir, "gateway", ""); err != nil {
+		t.Fatalf("NewServerConfig failed: %v", err)
+	}
+	if third, _ := os.ReadFile(certFile); bytes.Equal(first, third) {
+		t.Error("Expected a new certificate when a host is added")
+	}
+}
+
+func TestNewServerConfig_UserCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM, err := Generate([]string{"bridge.example.com"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, certPEM, 0644)
+	os.WriteFile(keyFile, keyPEM, 0600)
+
+	tlsConfig, err := NewServerConfig(config.ServerTLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile}, dir, "web", "")
+	if err != nil {
+		t.Fatalf("NewServerConfig failed: %v", err)
+	}
+	if len(tlsConfig.Certificates) != 1 {
+		t.Fatal("Expected the user certificate")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tls")); !os.IsNotExist(err) {
+		t.Error("Expected no certificate to be generated")
+	}
+
+	if _, err := NewServerConfig(config.ServerTLSConfig{Enabled: true, CertFile: certFile}, dir, "web", ""); err == nil {
+		t.Error("Expected an error without a key file")
+	}
+}
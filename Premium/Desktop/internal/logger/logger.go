@@ -1,7 +1,11 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -9,23 +13,15 @@ import (
 
 var logger *logrus.Logger
 
+// output is where log entries are written, stdout unless SetFile is called
+var output io.Writer = os.Stdout
+
 // Init initializes the logger with the specified level
 func Init(level string) {
 	logger = logrus.New()
 	
 	// Set log level
-	switch strings.ToLower(level) {
-	case "debug":
-		logger.SetLevel(logrus.DebugLevel)
-	case "info":
-		logger.SetLevel(logrus.InfoLevel)
-	case "warn", "warning":
-		logger.SetLevel(logrus.WarnLevel)
-	case "error":
-		logger.SetLevel(logrus.ErrorLevel)
-	default:
-		logger.SetLevel(logrus.InfoLevel)
-	}
+	logger.SetLevel(parseLevel(level))
 	
 	// Set output format
 	logger.SetFormatter(&logrus.TextFormatter{
@@ -34,7 +30,66 @@ func Init(level string) {
 	})
 	
 	// Set output
-	logger.SetOutput(os.Stdout)
+	logger.SetOutput(output)
+}
+
+// SetFile appends log entries to the file at path instead of stdout, for
+// when the bridge runs without a terminal. The file is readable by its
+// owner only, as logs may mention community and user details.
+func SetFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	output = file
+	if logger != nil {
+		logger.SetOutput(file)
+	}
+	return nil
+}
+
+// Output returns where log entries are written
+func Output() io.Writer {
+	return output
+}
+
+// DefaultFile returns the platform's log file for the bridge, or an empty
+// string where logs are left to the service manager, such as the systemd
+// journal on Linux
+func DefaultFile() string {
+	switch runtime.GOOS {
+	case "darwin":
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, "Library", "Logs", "WaddleBot", "bridge.log")
+		}
+	case "windows":
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, "WaddleBot", "bridge.log")
+		}
+	}
+	return ""
+}
+
+// SetLevel changes the level of the configured logger
+func SetLevel(level string) {
+	GetLogger().SetLevel(parseLevel(level))
+}
+
+// parseLevel returns the logrus level named by level, or info
+func parseLevel(level string) logrus.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return logrus.DebugLevel
+	case "warn", "warning":
+		return logrus.WarnLevel
+	case "error":
+		return logrus.ErrorLevel
+	default:
+		return logrus.InfoLevel
+	}
 }
 
 // GetLogger returns the configured logger instance
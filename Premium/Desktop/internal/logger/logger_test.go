@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSetFile(t *testing.T) {
+	previous := output
+	t.Cleanup(func() {
+		output = previous
+		GetLogger().SetOutput(previous)
+	})
+
+	path := filepath.Join(t.TempDir(), "logs", "bridge.log")
+	if err := SetFile(path); err != nil {
+		t.Fatalf("SetFile failed: %v", err)
+	}
+	GetLogger().Info("Written to the file")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the log file created: %v", err)
+	}
+	if !strings.Contains(string(data), "Written to the file") {
+		t.Errorf("Expected the entry in the log file, got %q", data)
+	}
+	if Output() == os.Stdout {
+		t.Error("Expected Output to return the log file")
+	}
+
+	if runtime.GOOS != "windows" {
+		info, _ := os.Stat(path)
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("Expected the log file readable by its owner only, got %v", perm)
+		}
+		dir, _ := os.Stat(filepath.Dir(path))
+		if perm := dir.Mode().Perm(); perm != 0700 {
+			t.Errorf("Expected the log directory private, got %v", perm)
+		}
+	}
+
+	// Entries are appended to a file that already exists
+	if err := SetFile(path); err != nil {
+		t.Fatalf("SetFile failed: %v", err)
+	}
+	GetLogger().Info("Appended")
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "Written to the file") || !strings.Contains(string(data), "Appended") {
+		t.Errorf("Expected entries appended, got %q", data)
+	}
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RecentFileSize is the size at which the recent log file is rotated; one
+// rotated file is kept
+const RecentFileSize = 5 << 20
+
+// RecentFiles returns the recent log files kept in dir, newest first
+func RecentFiles(dir string) []string {
+	path := filepath.Join(dir, "recent.log")
+	return []string{path, path + ".1"}
+}
+
+// recentHook copies log entries to a size-limited file, so that the
+// diagnostics command finds recent logs wherever the output goes
+type recentHook struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	size      int64
+	formatter logrus.Formatter
+}
+
+// KeepRecent copies every log entry to recent.log in dir as well as the
+// logger's output
+func KeepRecent(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	hook := &recentHook{
+		path:      RecentFiles(dir)[0],
+		formatter: &logrus.TextFormatter{FullTimestamp: true, DisableColors: true},
+	}
+	if err := hook.open(); err != nil {
+		return err
+	}
+	GetLogger().AddHook(hook)
+	return nil
+}
+
+func (h *recentHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *recentHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.size+int64(len(line)) > RecentFileSize {
+		if err := h.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := h.file.Write(line)
+	h.size += int64(n)
+	return err
+}
+
+// open opens the recent log file for appending
+func (h *recentHook) open() error {
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open recent log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	h.file, h.size = file, info.Size()
+	return nil
+}
+
+// rotate moves the full file aside, replacing the previous one
+func (h *recentHook) rotate() error {
+	h.file.Close()
+	if err := os.Rename(h.path, h.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return h.open()
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/secrets"
+)
+
+// tailSize is the number of log entries kept in memory
+const tailSize = 200
+
+// tailBuffer is how many entries a subscriber can fall behind before new
+// ones are dropped for it
+const tailBuffer = 64
+
+// Entry is a log entry kept for the web interface
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Tail keeps the latest log entries in memory and passes new ones to
+// subscribers, so the web interface can follow the log as it is written
+type Tail struct {
+	mu          sync.Mutex
+	entries     []Entry
+	subscribers map[chan Entry]struct{}
+}
+
+var (
+	tail     *Tail
+	tailOnce sync.Once
+)
+
+// KeepTail starts keeping the latest log entries in memory and returns
+// the tail holding them
+func KeepTail() *Tail {
+	tailOnce.Do(func() {
+		tail = &Tail{subscribers: make(map[chan Entry]struct{})}
+		GetLogger().AddHook(tail)
+	})
+	return tail
+}
+
+// Recent returns the entries kept, oldest first
+func (t *Tail) Recent() []Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Entry(nil), t.entries...)
+}
+
+// Subscribe returns a channel receiving new entries, and a function that
+// stops them and must be called when done. Entries are dropped for a
+// subscriber that falls behind rather than hold up logging.
+func (t *Tail) Subscribe() (<-chan Entry, func()) {
+	ch := make(chan Entry, tailBuffer)
+	t.mu.Lock()
+	t.subscribers[ch] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.subscribers, ch)
+			t.mu.Unlock()
+		})
+	}
+}
+
+func (t *Tail) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (t *Tail) Fire(entry *logrus.Entry) error {
+	kept := Entry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+	}
+	if len(entry.Data) > 0 {
+		kept.Fields = make(map[string]string, len(entry.Data))
+		for key, value := range entry.Data {
+			kept.Fields[key] = fieldText(key, value)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries = append(t.entries, kept)
+	if len(t.entries) > tailSize {
+		t.entries = append(t.entries[:0], t.entries[len(t.entries)-tailSize:]...)
+	}
+	for ch := range t.subscribers {
+		select {
+		case ch <- kept:
+		default:
+		}
+	}
+	return nil
+}
+
+// fieldText formats a log field for the web interface, with secrets
+// redacted the way the diagnostics bundle redacts them
+func fieldText(key string, value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return fmt.Sprint(secrets.RedactValue(key, value))
+	}
+	return fmt.Sprint(secrets.RedactValue(key, fmt.Sprint(value)))
+}
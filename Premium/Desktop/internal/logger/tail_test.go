@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/secrets"
+)
+
+func newTestTail() *Tail {
+	return &Tail{subscribers: make(map[chan Entry]struct{})}
+}
+
+func fire(t *testing.T, tail *Tail, message string, data logrus.Fields) {
+	t.Helper()
+	if err := tail.Fire(&logrus.Entry{Time: time.Now(), Level: logrus.InfoLevel, Message: message, Data: data}); err != nil {
+		t.Fatalf("Fire failed: %v", err)
+	}
+}
+
+func TestTail_Recent(t *testing.T) {
+	tail := newTestTail()
+	for i := 0; i < tailSize+50; i++ {
+		fire(t, tail, fmt.Sprintf("entry %d", i), nil)
+	}
+
+	recent := tail.Recent()
+	if len(recent) != tailSize {
+		t.Fatalf("Expected %d entries kept, got %d", tailSize, len(recent))
+	}
+	if recent[0].Message != "entry 50" || recent[tailSize-1].Message != fmt.Sprintf("entry %d", tailSize+49) {
+		t.Errorf("Expected the latest entries, oldest first, got %q to %q", recent[0].Message, recent[tailSize-1].Message)
+	}
+}
+
+func TestTail_Subscribe(t *testing.T) {
+	tail := newTestTail()
+	slow, stopSlow := tail.Subscribe()
+	defer stopSlow()
+	entries, stop := tail.Subscribe()
+
+	// A subscriber that falls behind loses entries instead of holding up
+	// logging
+	for i := 0; i < tailBuffer+10; i++ {
+		fire(t, tail, fmt.Sprintf("entry %d", i), nil)
+		<-entries
+	}
+	if len(slow) != tailBuffer {
+		t.Errorf("Expected %d entries buffered for the slow subscriber, got %d", tailBuffer, len(slow))
+	}
+	if first := <-slow; first.Message != "entry 0" {
+		t.Errorf("Expected the newest entries dropped, got %q first", first.Message)
+	}
+
+	stop()
+	stop()
+	fire(t, tail, "after", nil)
+	select {
+	case entry := <-entries:
+		t.Errorf("Expected nothing after unsubscribing, got %+v", entry)
+	default:
+	}
+}
+
+func TestTail_RedactsFields(t *testing.T) {
+	tail := newTestTail()
+	fire(t, tail, "Connected", logrus.Fields{
+		"api_key": "wbk_secret",
+		"error":   fmt.Errorf("refused"),
+		"request": map[string]interface{}{"url": "/api", "token": "abc"},
+	})
+
+	fields := tail.Recent()[0].Fields
+	if fields["api_key"] != secrets.Redacted {
+		t.Errorf("Expected the API key redacted, got %q", fields["api_key"])
+	}
+	if fields["error"] != "refused" {
+		t.Errorf("Expected other fields kept, got %q", fields["error"])
+	}
+	if fields["request"] != fmt.Sprint(map[string]interface{}{"url": "/api", "token": secrets.Redacted}) {
+		t.Errorf("Expected nested secrets redacted, got %q", fields["request"])
+	}
+}
@@ -0,0 +1,182 @@
+// Package metrics holds the Prometheus metrics of the bridge. Every
+// collector is registered with Registry, which the local gateway exposes at
+// /metrics; packages record into the collectors defined here.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "waddlebot_bridge"
+
+// Result label values
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Registry is the registry shared by all bridge metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// GatewayRequests counts gateway requests by route, method and status
+	GatewayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "requests_total",
+		Help:      "Local gateway requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// GatewayRequestDuration observes gateway request latency by route
+	GatewayRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "request_duration_seconds",
+		Help:      "Local gateway request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// WebSocketClients is the number of connected gateway WebSocket clients
+	WebSocketClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "websocket_clients",
+		Help:      "Connected local gateway WebSocket clients.",
+	})
+
+	// OBSConnectionState is 1 for the current OBS connection state and 0
+	// for the others
+	OBSConnectionState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "obs",
+		Name:      "connection_state",
+		Help:      "OBS WebSocket connection state; 1 for the current state.",
+	}, []string{"state"})
+
+	// ModuleExecutions counts module actions by module, action and result
+	ModuleExecutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "module",
+		Name:      "executions_total",
+		Help:      "Module action executions by module, action and result.",
+	}, []string{"module", "action", "result"})
+
+	// ModuleExecutionDuration observes module action run time
+	ModuleExecutionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "module",
+		Name:      "execution_duration_seconds",
+		Help:      "Module action run time by module and action.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"module", "action"})
+
+	// PollerTasks counts tasks run by the poller by type and result
+	PollerTasks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "poller",
+		Name:      "tasks_total",
+		Help:      "Tasks run by the poller by task type and result.",
+	}, []string{"type", "result"})
+
+	// PollerLag observes the time from a task's creation on the server to
+	// the start of its execution
+	PollerLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "poller",
+		Name:      "lag_seconds",
+		Help:      "Time from task creation to the start of its execution.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300},
+	})
+
+	// ScriptDuration observes script run time by script type and result
+	ScriptDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "script",
+		Name:      "run_duration_seconds",
+		Help:      "Script run time by script type and result.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60},
+	}, []string{"type", "result"})
+
+	// SubsystemCrashes counts panics and failures of bridge subsystems
+	SubsystemCrashes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "supervisor",
+		Name:      "crashes_total",
+		Help:      "Recovered panics and failures by subsystem.",
+	}, []string{"subsystem"})
+
+	// StreamDroppedRatio is the share of frames dropped by the network in
+	// the stream guardian's last sample
+	StreamDroppedRatio = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "dropped_frames_ratio",
+		Help:      "Share of stream frames dropped by the network in the last sample.",
+	})
+
+	// StreamEncoderLagRatio is the share of frames skipped by the encoder
+	// in the stream guardian's last sample
+	StreamEncoderLagRatio = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "encoder_lag_ratio",
+		Help:      "Share of frames skipped by the encoder in the last sample.",
+	})
+
+	// StreamBitrate is the stream's bitrate in the stream guardian's last
+	// sample
+	StreamBitrate = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "bitrate_kbps",
+		Help:      "Stream bitrate in kilobits per second in the last sample.",
+	})
+
+	// StreamHealthy is 0 while the stream guardian finds the stream
+	// unhealthy and 1 otherwise
+	StreamHealthy = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "healthy",
+		Help:      "Whether the stream guardian finds the stream healthy.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		GatewayRequests,
+		GatewayRequestDuration,
+		WebSocketClients,
+		OBSConnectionState,
+		ModuleExecutions,
+		ModuleExecutionDuration,
+		PollerTasks,
+		PollerLag,
+		ScriptDuration,
+		SubsystemCrashes,
+		StreamDroppedRatio,
+		StreamEncoderLagRatio,
+		StreamBitrate,
+		StreamHealthy,
+	)
+	StreamHealthy.Set(1)
+}
+
+// Handler serves the metrics in Registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Result returns the result label for an error
+func Result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
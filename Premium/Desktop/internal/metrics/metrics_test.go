@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_ExposesBridgeMetrics(t *testing.T) {
+	ModuleExecutions.WithLabelValues("soundboard", "play", Result(nil)).Inc()
+	ModuleExecutions.WithLabelValues("soundboard", "play", Result(errors.New("failed"))).Inc()
+	WebSocketClients.Set(2)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	for _, expected := range []string{
+		`waddlebot_bridge_module_executions_total{action="play",module="soundboard",result="success"} 1`,
+		`waddlebot_bridge_module_executions_total{action="play",module="soundboard",result="error"} 1`,
+		`waddlebot_bridge_gateway_websocket_clients 2`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("Expected metrics output to contain %q", expected)
+		}
+	}
+}
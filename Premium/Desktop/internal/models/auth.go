@@ -7,7 +7,13 @@ type AuthSession struct {
 	ID          string    `json:"id"`
 	UserID      string    `json:"user_id"`
 	CommunityID string    `json:"community_id"`
+	Role        string    `json:"role,omitempty"`
 	IssuedAt    time.Time `json:"issued_at"`
 	ExpiresAt   time.Time `json:"expires_at"`
 	Credential  []byte    `json:"credential"`
+
+	// RefreshHash is the hash of the current refresh token, and
+	// PreviousRefreshHash of the one it replaced, which must not be reused
+	RefreshHash         string `json:"refresh_hash,omitempty"`
+	PreviousRefreshHash string `json:"previous_refresh_hash,omitempty"`
 }
@@ -0,0 +1,513 @@
+package fileops
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/modules"
+)
+
+// ModuleName is the name the file operations module registers under
+const ModuleName = "file_operations"
+
+// FileOpsModule provides sandboxed file operations within admin-configured
+// root directories
+type FileOpsModule struct {
+	cfg          config.FileOpsConfig
+	roots        []string
+	configured   []string
+	maxFileSize  int64
+	maxListItems int
+}
+
+// New creates a new file operations module instance
+func New(cfg config.FileOpsConfig) *FileOpsModule {
+	return &FileOpsModule{cfg: cfg}
+}
+
+// Initialize resolves the allowed roots and applies size limits
+func (m *FileOpsModule) Initialize(cfg map[string]string) error {
+	if len(m.cfg.AllowedRoots) == 0 {
+		return fmt.Errorf("no allowed roots configured")
+	}
+
+	roots := make([]string, 0, len(m.cfg.AllowedRoots))
+	configured := make([]string, 0, len(m.cfg.AllowedRoots))
+	for _, root := range m.cfg.AllowedRoots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("root %s is not a directory", root)
+		}
+		roots = append(roots, resolved)
+		configured = append(configured, abs)
+	}
+	m.roots = roots
+	m.configured = configured
+
+	m.maxFileSize = int64(m.cfg.MaxFileSizeKB) * 1024
+	if m.maxFileSize <= 0 {
+		m.maxFileSize = 1024 * 1024
+	}
+	m.maxListItems = m.cfg.MaxListEntries
+	if m.maxListItems <= 0 {
+		m.maxListItems = 500
+	}
+
+	return nil
+}
+
+// GetInfo returns module information
+func (m *FileOpsModule) GetInfo() *modules.ModuleInfo {
+	return &modules.ModuleInfo{
+		Name:        ModuleName,
+		Version:     "1.0.0",
+		Description: "Sandboxed file operations within allowed root directories",
+		Author:      "WaddleBot",
+		Actions: []modules.ActionInfo{
+			{
+				Name:        "read_file",
+				Description: "Read a file",
+				Parameters: map[string]interface{}{
+					"path":     "string",
+					"encoding": "string",
+				},
+				ReturnType:  "object",
+				Timeout:     10,
+				Permissions: []string{"file.read"},
+			},
+			{
+				Name:        "write_file",
+				Description: "Write or append to a file",
+				Parameters: map[string]interface{}{
+					"path":     "string",
+					"content":  "string",
+					"encoding": "string",
+					"append":   "boolean",
+				},
+				ReturnType:  "object",
+				Timeout:     10,
+				Permissions: []string{"file.write"},
+			},
+			{
+				Name:        "list_dir",
+				Description: "List directory contents",
+				Parameters: map[string]interface{}{
+					"path": "string",
+				},
+				ReturnType:  "array",
+				Timeout:     10,
+				Permissions: []string{"file.read"},
+			},
+			{
+				Name:        "move",
+				Description: "Move or rename a file",
+				Parameters: map[string]interface{}{
+					"source":      "string",
+					"destination": "string",
+					"overwrite":   "boolean",
+				},
+				ReturnType:  "object",
+				Timeout:     10,
+				Permissions: []string{"file.write"},
+			},
+			{
+				Name:        "hash_file",
+				Description: "Compute a file checksum",
+				Parameters: map[string]interface{}{
+					"path":      "string",
+					"algorithm": "string",
+				},
+				ReturnType:  "object",
+				Timeout:     30,
+				Permissions: []string{"file.read"},
+			},
+		},
+		Dependencies: []string{},
+		Permissions:  []string{"file.read", "file.write"},
+		Config:       map[string]string{},
+		Enabled:      true,
+		LoadedAt:     time.Now(),
+	}
+}
+
+// ExecuteAction executes a specific action
+func (m *FileOpsModule) ExecuteAction(ctx context.Context, action string, parameters map[string]string) (map[string]interface{}, error) {
+	switch action {
+	case "read_file":
+		return m.readFile(parameters)
+	case "write_file":
+		return m.writeFile(parameters)
+	case "list_dir":
+		return m.listDir(parameters)
+	case "move":
+		return m.move(parameters)
+	case "hash_file":
+		return m.hashFile(ctx, parameters)
+	default:
+		return nil, fmt.Errorf("%w: %s", modules.ErrActionNotFound, action)
+	}
+}
+
+// GetActions returns available actions
+func (m *FileOpsModule) GetActions() []modules.ActionInfo {
+	return m.GetInfo().Actions
+}
+
+// Cleanup cleans up module resources
+func (m *FileOpsModule) Cleanup() error {
+	return nil
+}
+
+// resolvePath maps a requested path onto the filesystem and verifies it
+// stays inside an allowed root. Relative paths are resolved against the
+// first root. Symlinks are resolved so links pointing outside a root are
+// rejected; for paths that do not exist yet the parent directory is checked
+// and a dangling symlink is refused, since creating it would follow the link.
+func (m *FileOpsModule) resolvePath(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("%w: path is required", modules.ErrInvalidParameters)
+	}
+	if strings.ContainsRune(path, 0) {
+		return "", fmt.Errorf("%w: invalid path", modules.ErrInvalidParameters)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.roots[0], path)
+	}
+	path = filepath.Clean(path)
+
+	// Reject lexical escapes before touching the filesystem
+	if !m.inRoots(path, m.roots) && !m.inRoots(path, m.configured) {
+		return "", fmt.Errorf("%w: path %s is outside allowed roots", modules.ErrPermissionDenied, path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if _, err := os.Lstat(path); err == nil {
+			return "", fmt.Errorf("%w: %s is a dangling symlink", modules.ErrPermissionDenied, path)
+		}
+		parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			return "", fmt.Errorf("parent directory does not exist: %w", err)
+		}
+		resolved = filepath.Join(parent, filepath.Base(path))
+	}
+
+	if m.inRoots(resolved, m.roots) {
+		return resolved, nil
+	}
+
+	return "", fmt.Errorf("%w: path %s is outside allowed roots", modules.ErrPermissionDenied, path)
+}
+
+// inRoots reports whether path lies within any of the given roots
+func (m *FileOpsModule) inRoots(path string, roots []string) bool {
+	for _, root := range roots {
+		if withinRoot(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinRoot reports whether path is root or a descendant of root
+func withinRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// requireWrite returns an error when write operations are disabled
+func (m *FileOpsModule) requireWrite() error {
+	if !m.cfg.AllowWrite {
+		return fmt.Errorf("%w: write operations are disabled", modules.ErrPermissionDenied)
+	}
+	return nil
+}
+
+// readFile returns the contents of a file
+func (m *FileOpsModule) readFile(parameters map[string]string) (map[string]interface{}, error) {
+	path, err := m.resolvePath(parameters["path"])
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a directory", modules.ErrInvalidParameters, parameters["path"])
+	}
+	if info.Size() > m.maxFileSize {
+		return nil, fmt.Errorf("file exceeds maximum size of %d bytes", m.maxFileSize)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	// Guard against files growing between stat and read
+	data, err := io.ReadAll(io.LimitReader(file, m.maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > m.maxFileSize {
+		return nil, fmt.Errorf("file exceeds maximum size of %d bytes", m.maxFileSize)
+	}
+
+	encoding := parameters["encoding"]
+	var content string
+	switch encoding {
+	case "", "text":
+		encoding = "text"
+		content = string(data)
+	case "base64":
+		content = base64.StdEncoding.EncodeToString(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported encoding %s", modules.ErrInvalidParameters, encoding)
+	}
+
+	return map[string]interface{}{
+		"path":     path,
+		"size":     len(data),
+		"encoding": encoding,
+		"content":  content,
+		"modified": info.ModTime().Unix(),
+	}, nil
+}
+
+// writeFile writes or appends content to a file
+func (m *FileOpsModule) writeFile(parameters map[string]string) (map[string]interface{}, error) {
+	if err := m.requireWrite(); err != nil {
+		return nil, err
+	}
+
+	path, err := m.resolvePath(parameters["path"])
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	switch encoding := parameters["encoding"]; encoding {
+	case "", "text":
+		data = []byte(parameters["content"])
+	case "base64":
+		data, err = base64.StdEncoding.DecodeString(parameters["content"])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid base64 content", modules.ErrInvalidParameters)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported encoding %s", modules.ErrInvalidParameters, encoding)
+	}
+
+	appendMode, _ := strconv.ParseBool(parameters["append"])
+
+	size := int64(len(data))
+	if appendMode {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	if size > m.maxFileSize {
+		return nil, fmt.Errorf("file would exceed maximum size of %d bytes", m.maxFileSize)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendMode {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	// The resolved path has no links left; one appearing since would be
+	// followed by the open
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return nil, fmt.Errorf("%w: %s is a symlink", modules.ErrPermissionDenied, path)
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	written, err := file.Write(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return map[string]interface{}{
+		"path":    path,
+		"written": written,
+		"append":  appendMode,
+	}, nil
+}
+
+// listDir lists the entries of a directory
+func (m *FileOpsModule) listDir(parameters map[string]string) (map[string]interface{}, error) {
+	requested := parameters["path"]
+	if requested == "" {
+		requested = m.roots[0]
+	}
+
+	path, err := m.resolvePath(requested)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	items := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		if len(items) >= m.maxListItems {
+			break
+		}
+
+		item := map[string]interface{}{
+			"name":   entry.Name(),
+			"is_dir": entry.IsDir(),
+		}
+		if info, err := entry.Info(); err == nil {
+			item["size"] = info.Size()
+			item["modified"] = info.ModTime().Unix()
+		}
+		items = append(items, item)
+	}
+
+	return map[string]interface{}{
+		"path":      path,
+		"entries":   items,
+		"total":     len(entries),
+		"truncated": len(entries) > len(items),
+	}, nil
+}
+
+// move moves or renames a file within the allowed roots
+func (m *FileOpsModule) move(parameters map[string]string) (map[string]interface{}, error) {
+	if err := m.requireWrite(); err != nil {
+		return nil, err
+	}
+
+	source, err := m.resolvePath(parameters["source"])
+	if err != nil {
+		return nil, err
+	}
+	destination, err := m.resolvePath(parameters["destination"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Roots stay where the admin configured them
+	for _, root := range m.roots {
+		if withinRoot(source, root) || destination == root {
+			return nil, fmt.Errorf("%w: cannot move an allowed root", modules.ErrPermissionDenied)
+		}
+	}
+
+	if _, err := os.Lstat(source); err != nil {
+		return nil, fmt.Errorf("failed to stat source: %w", err)
+	}
+
+	overwrite, _ := strconv.ParseBool(parameters["overwrite"])
+	if _, err := os.Lstat(destination); err == nil && !overwrite {
+		return nil, fmt.Errorf("destination %s already exists", parameters["destination"])
+	}
+
+	if err := os.Rename(source, destination); err != nil {
+		return nil, fmt.Errorf("failed to move file: %w", err)
+	}
+
+	return map[string]interface{}{
+		"source":      source,
+		"destination": destination,
+	}, nil
+}
+
+// hashFile computes a checksum of a file
+func (m *FileOpsModule) hashFile(ctx context.Context, parameters map[string]string) (map[string]interface{}, error) {
+	path, err := m.resolvePath(parameters["path"])
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := parameters["algorithm"]
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha1":
+		h = sha1.New()
+	case "md5":
+		h = md5.New()
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %s", modules.ErrInvalidParameters, algorithm)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	size, err := io.Copy(h, &contextReader{ctx: ctx, r: file})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	return map[string]interface{}{
+		"path":      path,
+		"algorithm": algorithm,
+		"hash":      hex.EncodeToString(h.Sum(nil)),
+		"size":      size,
+	}, nil
+}
+
+// contextReader aborts reads once the context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
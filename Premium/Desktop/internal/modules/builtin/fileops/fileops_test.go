@@ -0,0 +1,229 @@
+package fileops
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/modules"
+)
+
+func newTestModule(t *testing.T, allowWrite bool) (*FileOpsModule, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	m := New(config.FileOpsConfig{
+		Enabled:        true,
+		AllowedRoots:   []string{root},
+		AllowWrite:     allowWrite,
+		MaxFileSizeKB:  1,
+		MaxListEntries: 10,
+	})
+	if err := m.Initialize(map[string]string{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	resolved, _ := filepath.EvalSymlinks(root)
+	return m, resolved
+}
+
+func TestFileOps_InitializeRequiresRoots(t *testing.T) {
+	m := New(config.FileOpsConfig{Enabled: true})
+	if err := m.Initialize(map[string]string{}); err == nil {
+		t.Error("Expected error without allowed roots")
+	}
+
+	m = New(config.FileOpsConfig{Enabled: true, AllowedRoots: []string{"/nonexistent/waddlebot"}})
+	if err := m.Initialize(map[string]string{}); err == nil {
+		t.Error("Expected error for missing root")
+	}
+}
+
+func TestFileOps_ReadWriteRoundTrip(t *testing.T) {
+	m, root := newTestModule(t, true)
+	ctx := context.Background()
+
+	_, err := m.ExecuteAction(ctx, "write_file", map[string]string{"path": "notes.txt", "content": "hello"})
+	if err != nil {
+		t.Fatalf("write_file failed: %v", err)
+	}
+	_, err = m.ExecuteAction(ctx, "write_file", map[string]string{"path": "notes.txt", "content": " world", "append": "true"})
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	result, err := m.ExecuteAction(ctx, "read_file", map[string]string{"path": filepath.Join(root, "notes.txt")})
+	if err != nil {
+		t.Fatalf("read_file failed: %v", err)
+	}
+	if result["content"] != "hello world" {
+		t.Errorf("Expected 'hello world', got %v", result["content"])
+	}
+
+	result, err = m.ExecuteAction(ctx, "read_file", map[string]string{"path": "notes.txt", "encoding": "base64"})
+	if err != nil {
+		t.Fatalf("read_file base64 failed: %v", err)
+	}
+	if result["content"] != "aGVsbG8gd29ybGQ=" {
+		t.Errorf("Unexpected base64 content %v", result["content"])
+	}
+}
+
+func TestFileOps_WriteDisabled(t *testing.T) {
+	m, _ := newTestModule(t, false)
+
+	_, err := m.ExecuteAction(context.Background(), "write_file", map[string]string{"path": "x.txt", "content": "x"})
+	if !errors.Is(err, modules.ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, got %v", err)
+	}
+
+	_, err = m.ExecuteAction(context.Background(), "move", map[string]string{"source": "a", "destination": "b"})
+	if !errors.Is(err, modules.ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for move, got %v", err)
+	}
+}
+
+func TestFileOps_PathTraversal(t *testing.T) {
+	m, root := newTestModule(t, true)
+	ctx := context.Background()
+
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	os.WriteFile(secret, []byte("secret"), 0644)
+	os.Symlink(outside, filepath.Join(root, "escape"))
+
+	paths := []string{
+		"../secret.txt",
+		"../../etc/passwd",
+		secret,
+		filepath.Join("escape", "secret.txt"),
+	}
+
+	for _, path := range paths {
+		_, err := m.ExecuteAction(ctx, "read_file", map[string]string{"path": path})
+		if !errors.Is(err, modules.ErrPermissionDenied) {
+			t.Errorf("Expected ErrPermissionDenied for %s, got %v", path, err)
+		}
+	}
+
+	_, err := m.ExecuteAction(ctx, "write_file", map[string]string{"path": filepath.Join("escape", "new.txt"), "content": "x"})
+	if !errors.Is(err, modules.ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied writing through symlink, got %v", err)
+	}
+}
+
+func TestFileOps_Symlinks(t *testing.T) {
+	m, root := newTestModule(t, true)
+	ctx := context.Background()
+
+	outside := t.TempDir()
+	os.Symlink(filepath.Join(outside, "pwned.txt"), filepath.Join(root, "dangling"))
+	os.Mkdir(filepath.Join(outside, "dir"), 0755)
+	os.Symlink(filepath.Join(outside, "dir"), filepath.Join(root, "parent"))
+
+	tests := []struct {
+		name   string
+		path   string
+		target string
+	}{
+		{"dangling symlink", "dangling", filepath.Join(outside, "pwned.txt")},
+		{"symlinked parent", filepath.Join("parent", "new.txt"), filepath.Join(outside, "dir", "new.txt")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.ExecuteAction(ctx, "write_file", map[string]string{"path": tt.path, "content": "x"})
+			if !errors.Is(err, modules.ErrPermissionDenied) {
+				t.Errorf("Expected ErrPermissionDenied, got %v", err)
+			}
+			if _, err := os.Lstat(tt.target); !os.IsNotExist(err) {
+				t.Errorf("Expected nothing created outside the root, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileOps_MoveRoot(t *testing.T) {
+	m, root := newTestModule(t, true)
+	ctx := context.Background()
+
+	os.Mkdir(filepath.Join(root, "sub"), 0755)
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("abc"), 0644)
+
+	moves := []map[string]string{
+		{"source": root, "destination": filepath.Join(root, "sub", "moved")},
+		{"source": ".", "destination": "renamed"},
+		{"source": "a.txt", "destination": root, "overwrite": "true"},
+	}
+
+	for _, params := range moves {
+		_, err := m.ExecuteAction(ctx, "move", params)
+		if !errors.Is(err, modules.ErrPermissionDenied) {
+			t.Errorf("Expected ErrPermissionDenied moving %s to %s, got %v", params["source"], params["destination"], err)
+		}
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		t.Errorf("Expected the root left in place, got %v", err)
+	}
+}
+
+func TestFileOps_SizeLimits(t *testing.T) {
+	m, root := newTestModule(t, true)
+	ctx := context.Background()
+
+	big := make([]byte, 2048)
+	os.WriteFile(filepath.Join(root, "big.bin"), big, 0644)
+
+	if _, err := m.ExecuteAction(ctx, "read_file", map[string]string{"path": "big.bin"}); err == nil {
+		t.Error("Expected error reading file over size limit")
+	}
+
+	if _, err := m.ExecuteAction(ctx, "write_file", map[string]string{"path": "big.txt", "content": string(big)}); err == nil {
+		t.Error("Expected error writing file over size limit")
+	}
+}
+
+func TestFileOps_ListMoveHash(t *testing.T) {
+	m, root := newTestModule(t, true)
+	ctx := context.Background()
+
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("abc"), 0644)
+	os.Mkdir(filepath.Join(root, "sub"), 0755)
+
+	result, err := m.ExecuteAction(ctx, "list_dir", map[string]string{})
+	if err != nil {
+		t.Fatalf("list_dir failed: %v", err)
+	}
+	if result["total"] != 2 {
+		t.Errorf("Expected 2 entries, got %v", result["total"])
+	}
+
+	_, err = m.ExecuteAction(ctx, "move", map[string]string{"source": "a.txt", "destination": "sub/b.txt"})
+	if err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "sub", "b.txt")); err != nil {
+		t.Errorf("Expected moved file to exist: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(root, "c.txt"), []byte("x"), 0644)
+	_, err = m.ExecuteAction(ctx, "move", map[string]string{"source": "c.txt", "destination": "sub/b.txt"})
+	if err == nil {
+		t.Error("Expected error moving onto existing file without overwrite")
+	}
+
+	result, err = m.ExecuteAction(ctx, "hash_file", map[string]string{"path": "sub/b.txt"})
+	if err != nil {
+		t.Fatalf("hash_file failed: %v", err)
+	}
+	if result["hash"] != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("Unexpected sha256 %v", result["hash"])
+	}
+
+	if _, err := m.ExecuteAction(ctx, "hash_file", map[string]string{"path": "sub/b.txt", "algorithm": "crc"}); !errors.Is(err, modules.ErrInvalidParameters) {
+		t.Errorf("Expected ErrInvalidParameters for unknown algorithm, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package hotkeys
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/keyboard"
+	"waddlebot-bridge/internal/modules"
+)
+
+// ModuleName is the name the hotkeys module registers under
+const ModuleName = "hotkeys"
+
+// HotkeysModule sends allowlisted key combinations to the focused application
+type HotkeysModule struct {
+	cfg      config.HotkeysConfig
+	sender   keyboard.Sender
+	mappings map[string]keyboard.Combo
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// New creates a new hotkeys module instance
+func New(cfg config.HotkeysConfig) *HotkeysModule {
+	return &HotkeysModule{
+		cfg:      cfg,
+		sender:   keyboard.NewSender(),
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// Initialize parses the configured mappings. Every mapping must be valid so
+// a typo cannot silently send a different key.
+func (m *HotkeysModule) Initialize(cfg map[string]string) error {
+	mappings := make(map[string]keyboard.Combo, len(m.cfg.Mappings))
+	for name, spec := range m.cfg.Mappings {
+		combo, err := keyboard.ParseCombo(spec)
+		if err != nil {
+			return fmt.Errorf("mapping %s: %w", name, err)
+		}
+		mappings[name] = combo
+	}
+	m.mappings = mappings
+
+	m.cooldown = time.Duration(m.cfg.CooldownMs) * time.Millisecond
+	return nil
+}
+
+// GetInfo returns module information
+func (m *HotkeysModule) GetInfo() *modules.ModuleInfo {
+	return &modules.ModuleInfo{
+		Name:        ModuleName,
+		Version:     "1.0.0",
+		Description: "Send allowlisted keyboard shortcuts to the focused application",
+		Author:      "WaddleBot",
+		Actions: []modules.ActionInfo{
+			{
+				Name:        "send",
+				Description: "Send a configured key mapping",
+				Parameters: map[string]interface{}{
+					"mapping": "string",
+				},
+				ReturnType:  "object",
+				Timeout:     5,
+				Permissions: []string{"keyboard.send"},
+			},
+			{
+				Name:        "list",
+				Description: "List configured key mappings",
+				Parameters:  map[string]interface{}{},
+				ReturnType:  "array",
+				Timeout:     5,
+				Permissions: []string{"keyboard.read"},
+			},
+		},
+		Dependencies: []string{},
+		Permissions:  []string{"keyboard.send", "keyboard.read"},
+		Config:       map[string]string{},
+		Enabled:      true,
+		LoadedAt:     time.Now(),
+	}
+}
+
+// ExecuteAction executes a specific action
+func (m *HotkeysModule) ExecuteAction(ctx context.Context, action string, parameters map[string]string) (map[string]interface{}, error) {
+	switch action {
+	case "send":
+		return m.send(ctx, parameters)
+	case "list":
+		return m.list(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", modules.ErrActionNotFound, action)
+	}
+}
+
+// GetActions returns available actions
+func (m *HotkeysModule) GetActions() []modules.ActionInfo {
+	return m.GetInfo().Actions
+}
+
+// Cleanup cleans up module resources
+func (m *HotkeysModule) Cleanup() error {
+	return nil
+}
+
+// send sends a configured mapping. Raw key combinations are never accepted.
+func (m *HotkeysModule) send(ctx context.Context, parameters map[string]string) (map[string]interface{}, error) {
+	if !m.cfg.PermissionGranted {
+		return nil, fmt.Errorf("%w: keyboard emulation has not been granted permission", modules.ErrPermissionDenied)
+	}
+
+	name := parameters["mapping"]
+	combo, ok := m.mappings[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: mapping %q is not in the allowlist", modules.ErrPermissionDenied, name)
+	}
+
+	m.mu.Lock()
+	if last, ok := m.lastSent[name]; ok && time.Since(last) < m.cooldown {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("mapping %s is cooling down", name)
+	}
+	m.lastSent[name] = time.Now()
+	m.mu.Unlock()
+
+	if err := m.sender.Send(ctx, combo); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"mapping": name,
+		"keys":    combo.String(),
+		"sent":    true,
+	}, nil
+}
+
+// list returns the configured mappings
+func (m *HotkeysModule) list() map[string]interface{} {
+	names := make([]string, 0, len(m.mappings))
+	for name := range m.mappings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mappings := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		mappings = append(mappings, map[string]interface{}{
+			"name": name,
+			"keys": m.mappings[name].String(),
+		})
+	}
+
+	return map[string]interface{}{
+		"mappings":           mappings,
+		"permission_granted": m.cfg.PermissionGranted,
+	}
+}
@@ -0,0 +1,79 @@
+package hotkeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/keyboard"
+	"waddlebot-bridge/internal/modules"
+)
+
+type recordingSender struct {
+	sent []string
+}
+
+func (s *recordingSender) Send(ctx context.Context, combo keyboard.Combo) error {
+	s.sent = append(s.sent, combo.String())
+	return nil
+}
+
+func newTestModule(t *testing.T, granted bool) (*HotkeysModule, *recordingSender) {
+	t.Helper()
+
+	m := New(config.HotkeysConfig{
+		Enabled:           true,
+		PermissionGranted: granted,
+		Mappings:          map[string]string{"mute": "ctrl+shift+m"},
+		CooldownMs:        60000,
+	})
+	sender := &recordingSender{}
+	m.sender = sender
+
+	if err := m.Initialize(map[string]string{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return m, sender
+}
+
+func TestHotkeys_InvalidMapping(t *testing.T) {
+	m := New(config.HotkeysConfig{Mappings: map[string]string{"bad": "ctrl+nope"}})
+	if err := m.Initialize(map[string]string{}); err == nil {
+		t.Error("Expected error for invalid mapping")
+	}
+}
+
+func TestHotkeys_RequiresPermission(t *testing.T) {
+	m, sender := newTestModule(t, false)
+
+	_, err := m.ExecuteAction(context.Background(), "send", map[string]string{"mapping": "mute"})
+	if !errors.Is(err, modules.ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, got %v", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Error("Expected no keys to be sent")
+	}
+}
+
+func TestHotkeys_Allowlist(t *testing.T) {
+	m, sender := newTestModule(t, true)
+	ctx := context.Background()
+
+	if _, err := m.ExecuteAction(ctx, "send", map[string]string{"mapping": "ctrl+alt+delete"}); !errors.Is(err, modules.ErrPermissionDenied) {
+		t.Errorf("Expected raw combo to be rejected, got %v", err)
+	}
+
+	result, err := m.ExecuteAction(ctx, "send", map[string]string{"mapping": "mute"})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if result["keys"] != "ctrl+shift+m" || len(sender.sent) != 1 {
+		t.Errorf("Unexpected send result %v, sent %v", result, sender.sent)
+	}
+
+	// Cooldown blocks immediate repeats
+	if _, err := m.ExecuteAction(ctx, "send", map[string]string{"mapping": "mute"}); err == nil {
+		t.Error("Expected cooldown error")
+	}
+}
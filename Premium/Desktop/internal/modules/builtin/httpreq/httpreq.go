@@ -0,0 +1,354 @@
+package httpreq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/modules"
+)
+
+// ModuleName is the name the HTTP request module registers under
+const ModuleName = "http_request"
+
+// reservedParameters are request parameters that are not exposed to templates
+var reservedParameters = map[string]bool{
+	"url":     true,
+	"method":  true,
+	"body":    true,
+	"headers": true,
+}
+
+// HTTPRequestModule performs outbound HTTP requests to allowlisted hosts
+type HTTPRequestModule struct {
+	cfg            config.HTTPRequestConfig
+	client         *http.Client
+	allowedHeaders map[string]bool
+	maxResponse    int64
+	maxRequest     int
+}
+
+// New creates a new HTTP request module instance
+func New(cfg config.HTTPRequestConfig) *HTTPRequestModule {
+	return &HTTPRequestModule{cfg: cfg}
+}
+
+// Initialize prepares the HTTP client and applies limits
+func (m *HTTPRequestModule) Initialize(cfg map[string]string) error {
+	if len(m.cfg.AllowedHosts) == 0 {
+		return fmt.Errorf("no allowed hosts configured")
+	}
+
+	m.allowedHeaders = make(map[string]bool, len(m.cfg.AllowedHeaders))
+	for _, header := range m.cfg.AllowedHeaders {
+		m.allowedHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	m.maxResponse = int64(m.cfg.MaxResponseKB) * 1024
+	if m.maxResponse <= 0 {
+		m.maxResponse = 256 * 1024
+	}
+	m.maxRequest = m.cfg.MaxRequestKB * 1024
+	if m.maxRequest <= 0 {
+		m.maxRequest = 64 * 1024
+	}
+
+	timeout := time.Duration(m.cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	m.client = &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			return m.checkURL(req.URL)
+		},
+	}
+
+	return nil
+}
+
+// GetInfo returns module information
+func (m *HTTPRequestModule) GetInfo() *modules.ModuleInfo {
+	params := map[string]interface{}{
+		"url":     "string",
+		"headers": "object",
+	}
+	bodyParams := map[string]interface{}{
+		"url":     "string",
+		"body":    "string",
+		"headers": "object",
+	}
+	return &modules.ModuleInfo{
+		Name:        ModuleName,
+		Version:     "1.0.0",
+		Description: "Outbound HTTP requests to allowlisted hosts",
+		Author:      "WaddleBot",
+		Actions: []modules.ActionInfo{
+			{
+				Name:        "get",
+				Description: "Perform an HTTP GET request",
+				Parameters:  params,
+				ReturnType:  "object",
+				Timeout:     15,
+				Permissions: []string{"network.request"},
+			},
+			{
+				Name:        "post",
+				Description: "Perform an HTTP POST request",
+				Parameters:  bodyParams,
+				ReturnType:  "object",
+				Timeout:     15,
+				Permissions: []string{"network.request"},
+			},
+			{
+				Name:        "request",
+				Description: "Perform an HTTP request with an explicit method",
+				Parameters: map[string]interface{}{
+					"method":  "string",
+					"url":     "string",
+					"body":    "string",
+					"headers": "object",
+				},
+				ReturnType:  "object",
+				Timeout:     15,
+				Permissions: []string{"network.request"},
+			},
+		},
+		Dependencies: []string{},
+		Permissions:  []string{"network.request"},
+		Config:       map[string]string{},
+		Enabled:      true,
+		LoadedAt:     time.Now(),
+	}
+}
+
+// ExecuteAction executes a specific action
+func (m *HTTPRequestModule) ExecuteAction(ctx context.Context, action string, parameters map[string]string) (map[string]interface{}, error) {
+	switch action {
+	case "get":
+		return m.doRequest(ctx, http.MethodGet, parameters)
+	case "post":
+		return m.doRequest(ctx, http.MethodPost, parameters)
+	case "request":
+		method := strings.ToUpper(parameters["method"])
+		switch method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead:
+		case "":
+			method = http.MethodGet
+		default:
+			return nil, fmt.Errorf("%w: unsupported method %s", modules.ErrInvalidParameters, method)
+		}
+		return m.doRequest(ctx, method, parameters)
+	default:
+		return nil, fmt.Errorf("%w: %s", modules.ErrActionNotFound, action)
+	}
+}
+
+// GetActions returns available actions
+func (m *HTTPRequestModule) GetActions() []modules.ActionInfo {
+	return m.GetInfo().Actions
+}
+
+// Cleanup cleans up module resources
+func (m *HTTPRequestModule) Cleanup() error {
+	if m.client != nil {
+		m.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// doRequest renders the request templates, validates the target and
+// performs the request
+func (m *HTTPRequestModule) doRequest(ctx context.Context, method string, parameters map[string]string) (map[string]interface{}, error) {
+	vars := templateVars(parameters)
+
+	rawURL, err := render("url", parameters["url"], vars, url.QueryEscape)
+	if err != nil {
+		return nil, err
+	}
+	if rawURL == "" {
+		return nil, fmt.Errorf("%w: url is required", modules.ErrInvalidParameters)
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid url", modules.ErrInvalidParameters)
+	}
+	if err := m.checkURL(target); err != nil {
+		return nil, err
+	}
+
+	body, err := render("body", parameters["body"], vars, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > m.maxRequest {
+		return nil, fmt.Errorf("%w: request body exceeds %d bytes", modules.ErrInvalidParameters, m.maxRequest)
+	}
+
+	headers, err := m.parseHeaders(parameters["headers"])
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	start := time.Now()
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, m.maxResponse+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	truncated := int64(len(data)) > m.maxResponse
+	if truncated {
+		data = data[:m.maxResponse]
+	}
+
+	responseHeaders := make(map[string]string, len(resp.Header))
+	for key := range resp.Header {
+		responseHeaders[key] = resp.Header.Get(key)
+	}
+
+	result := map[string]interface{}{
+		"status":      resp.StatusCode,
+		"headers":     responseHeaders,
+		"body":        string(data),
+		"truncated":   truncated,
+		"url":         resp.Request.URL.String(),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+
+	// Decode JSON responses for convenience
+	if !truncated && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err == nil {
+			result["json"] = decoded
+		}
+	}
+
+	return result, nil
+}
+
+// checkURL verifies the scheme and host of a request target against the
+// allowlist
+func (m *HTTPRequestModule) checkURL(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %s", modules.ErrPermissionDenied, target.Scheme)
+	}
+	if target.User != nil {
+		return fmt.Errorf("%w: credentials in url are not allowed", modules.ErrPermissionDenied)
+	}
+
+	host := strings.ToLower(target.Hostname())
+	for _, allowed := range m.cfg.AllowedHosts {
+		if hostMatches(strings.ToLower(allowed), host) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: host %s is not allowed", modules.ErrPermissionDenied, host)
+}
+
+// hostMatches matches a host against an allowlist pattern. Patterns may be
+// exact host names or "*.example.com" to allow any subdomain.
+func hostMatches(pattern, host string) bool {
+	if host == "" {
+		return false
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		suffix := pattern[1:]
+		return strings.HasSuffix(host, suffix) && net.ParseIP(host) == nil
+	}
+	return pattern == host
+}
+
+// parseHeaders decodes the headers parameter and rejects headers outside
+// the allowlist
+func (m *HTTPRequestModule) parseHeaders(raw string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if raw == "" {
+		return headers, nil
+	}
+
+	var decoded map[string]string
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+		return nil, fmt.Errorf("%w: headers must be a JSON object of strings", modules.ErrInvalidParameters)
+	}
+
+	for key, value := range decoded {
+		canonical := http.CanonicalHeaderKey(key)
+		if !m.allowedHeaders[canonical] {
+			return nil, fmt.Errorf("%w: header %s is not allowed", modules.ErrPermissionDenied, canonical)
+		}
+		headers[canonical] = value
+	}
+
+	return headers, nil
+}
+
+// templateVars returns the non-reserved parameters available to templates
+func templateVars(parameters map[string]string) map[string]string {
+	vars := make(map[string]string, len(parameters))
+	for key, value := range parameters {
+		if !reservedParameters[key] {
+			vars[key] = value
+		}
+	}
+	return vars
+}
+
+// render executes a text template against the given variables. When escape
+// is set every variable is escaped before substitution.
+func render(name, text string, vars map[string]string, escape func(string) string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	data := vars
+	if escape != nil {
+		data = make(map[string]string, len(vars))
+		for key, value := range vars {
+			data[key] = escape(value)
+		}
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid %s template: %v", modules.ErrInvalidParameters, name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w: failed to render %s: %v", modules.ErrInvalidParameters, name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,404 @@
+package overlays
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/modules"
+)
+
+// ModuleName is the name the overlays module registers under
+const ModuleName = "overlays"
+
+// Event types pushed to overlay pages over the gateway WebSocket
+const (
+	EventAlert    = "overlay.alert"
+	EventAlertEnd = "overlay.alert_end"
+	EventClear    = "overlay.clear"
+)
+
+// Alert is a queued or showing alert
+type Alert struct {
+	ID       string        `json:"id"`
+	Template string        `json:"template,omitempty"`
+	Text     string        `json:"text,omitempty"`
+	Image    string        `json:"image,omitempty"`
+	Sound    string        `json:"sound,omitempty"`
+	Duration time.Duration `json:"-"`
+	QueuedAt time.Time     `json:"queued_at"`
+}
+
+// data returns the alert as event data
+func (a Alert) data() map[string]interface{} {
+	return map[string]interface{}{
+		"id":          a.ID,
+		"template":    a.Template,
+		"text":        a.Text,
+		"image":       a.Image,
+		"sound":       a.Sound,
+		"duration_ms": a.Duration.Milliseconds(),
+	}
+}
+
+// OverlaysModule queues overlay alerts and shows them one at a time by
+// publishing render events, which the gateway pushes to overlay pages
+type OverlaysModule struct {
+	cfg       config.OverlayAlertsConfig
+	templates map[string]config.AlertTemplateConfig
+	publisher events.Publisher
+
+	mu      sync.Mutex
+	queue   []Alert
+	current *Alert
+	wake    chan struct{}
+	skip    chan struct{}
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates a new overlays module instance
+func New(cfg config.OverlayAlertsConfig) *OverlaysModule {
+	m := &OverlaysModule{
+		cfg:       cfg,
+		templates: make(map[string]config.AlertTemplateConfig),
+		wake:      make(chan struct{}, 1),
+		skip:      make(chan struct{}, 1),
+	}
+	for _, template := range cfg.Templates {
+		m.templates[template.Name] = template
+	}
+	return m
+}
+
+// SetEventPublisher sets the publisher render events are sent through
+func (m *OverlaysModule) SetEventPublisher(publisher events.Publisher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publisher = publisher
+}
+
+// Initialize starts showing queued alerts
+func (m *OverlaysModule) Initialize(cfg map[string]string) error {
+	for name, template := range m.templates {
+		if name == "" {
+			return fmt.Errorf("%w: alert template without a name", modules.ErrInvalidParameters)
+		}
+		if template.Text == "" && template.Image == "" && template.Sound == "" {
+			return fmt.Errorf("%w: alert template %s shows nothing", modules.ErrInvalidParameters, name)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stop = cancel
+	m.wg.Add(1)
+	go m.run(ctx)
+	return nil
+}
+
+// GetInfo returns module information
+func (m *OverlaysModule) GetInfo() *modules.ModuleInfo {
+	return &modules.ModuleInfo{
+		Name:        ModuleName,
+		Version:     "1.0.0",
+		Description: "Queue alerts and show them on overlay pages served by the gateway",
+		Author:      "WaddleBot",
+		Actions: []modules.ActionInfo{
+			{
+				Name:        "alert",
+				Description: "Queue an alert from a template or with its own text, image and sound; other parameters fill {placeholders}",
+				Parameters: map[string]interface{}{
+					"template": "string",
+					"text":     "string",
+					"image":    "string",
+					"sound":    "string",
+					"duration": "string",
+				},
+				ReturnType:  "object",
+				Timeout:     5,
+				Permissions: []string{"overlay.write"},
+			},
+			{
+				Name:        "skip",
+				Description: "End the alert being shown",
+				Parameters:  map[string]interface{}{},
+				ReturnType:  "object",
+				Timeout:     5,
+				Permissions: []string{"overlay.write"},
+			},
+			{
+				Name:        "clear",
+				Description: "Drop queued alerts and clear the overlays",
+				Parameters:  map[string]interface{}{},
+				ReturnType:  "object",
+				Timeout:     5,
+				Permissions: []string{"overlay.write"},
+			},
+			{
+				Name:        "queue",
+				Description: "List the alert being shown and queued alerts",
+				Parameters:  map[string]interface{}{},
+				ReturnType:  "object",
+				Timeout:     5,
+				Permissions: []string{"overlay.read"},
+			},
+			{
+				Name:        "templates",
+				Description: "List alert templates",
+				Parameters:  map[string]interface{}{},
+				ReturnType:  "array",
+				Timeout:     5,
+				Permissions: []string{"overlay.read"},
+			},
+		},
+		Dependencies: []string{},
+		Permissions:  []string{"overlay.read", "overlay.write"},
+		Config:       map[string]string{},
+		Enabled:      true,
+		LoadedAt:     time.Now(),
+	}
+}
+
+// ExecuteAction executes a specific action
+func (m *OverlaysModule) ExecuteAction(ctx context.Context, action string, parameters map[string]string) (map[string]interface{}, error) {
+	switch action {
+	case "alert":
+		return m.enqueue(parameters)
+	case "skip":
+		select {
+		case m.skip <- struct{}{}:
+		default:
+		}
+		return map[string]interface{}{"skipped": true}, nil
+	case "clear":
+		return map[string]interface{}{"cleared": m.clear()}, nil
+	case "queue":
+		return m.list(), nil
+	case "templates":
+		return m.listTemplates(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", modules.ErrActionNotFound, action)
+	}
+}
+
+// GetActions returns available actions
+func (m *OverlaysModule) GetActions() []modules.ActionInfo {
+	return m.GetInfo().Actions
+}
+
+// Cleanup stops showing alerts
+func (m *OverlaysModule) Cleanup() error {
+	if m.stop != nil {
+		m.stop()
+	}
+	m.wg.Wait()
+	return nil
+}
+
+// enqueue builds an alert from the parameters and adds it to the queue
+func (m *OverlaysModule) enqueue(parameters map[string]string) (map[string]interface{}, error) {
+	var template config.AlertTemplateConfig
+	if name := parameters["template"]; name != "" {
+		var ok bool
+		if template, ok = m.templates[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown alert template %s", modules.ErrInvalidParameters, name)
+		}
+	}
+
+	alert := Alert{
+		ID:       uuid.NewString(),
+		Template: template.Name,
+		Text:     firstNonEmpty(parameters["text"], template.Text),
+		Image:    firstNonEmpty(parameters["image"], template.Image),
+		Sound:    firstNonEmpty(parameters["sound"], template.Sound),
+		Duration: template.Duration,
+		QueuedAt: time.Now(),
+	}
+	if raw := parameters["duration"]; raw != "" {
+		duration, err := parseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid duration %s", modules.ErrInvalidParameters, raw)
+		}
+		alert.Duration = duration
+	}
+	if alert.Duration <= 0 {
+		alert.Duration = m.cfg.DefaultDuration
+	}
+	if alert.Duration <= 0 {
+		alert.Duration = 5 * time.Second
+	}
+	if alert.Text == "" && alert.Image == "" && alert.Sound == "" {
+		return nil, fmt.Errorf("%w: an alert needs a template, text, image or sound", modules.ErrInvalidParameters)
+	}
+	alert.Text = fillPlaceholders(alert.Text, parameters)
+
+	m.mu.Lock()
+	limit := m.cfg.MaxQueue
+	if limit <= 0 {
+		limit = 50
+	}
+	if len(m.queue) >= limit {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("alert queue is full (%d alerts)", limit)
+	}
+	m.queue = append(m.queue, alert)
+	position := len(m.queue)
+	m.mu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+
+	return map[string]interface{}{
+		"id":       alert.ID,
+		"position": position,
+	}, nil
+}
+
+// run shows queued alerts one at a time until ctx is cancelled
+func (m *OverlaysModule) run(ctx context.Context) {
+	defer m.wg.Done()
+
+	for {
+		m.mu.Lock()
+		if len(m.queue) == 0 {
+			m.mu.Unlock()
+			select {
+			case <-m.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		alert := m.queue[0]
+		m.queue = m.queue[1:]
+		m.current = &alert
+		m.mu.Unlock()
+
+		// A skip requested before this alert started does not apply to it
+		select {
+		case <-m.skip:
+		default:
+		}
+
+		m.publish(EventAlert, alert.data())
+
+		timer := time.NewTimer(alert.Duration)
+		select {
+		case <-timer.C:
+		case <-m.skip:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		m.mu.Lock()
+		m.current = nil
+		m.mu.Unlock()
+		m.publish(EventAlertEnd, map[string]interface{}{"id": alert.ID})
+	}
+}
+
+// clear drops queued alerts, ends the one being shown and tells overlays to
+// clear. It returns the number of alerts dropped.
+func (m *OverlaysModule) clear() int {
+	m.mu.Lock()
+	dropped := len(m.queue)
+	m.queue = nil
+	showing := m.current != nil
+	m.mu.Unlock()
+
+	if showing {
+		select {
+		case m.skip <- struct{}{}:
+		default:
+		}
+	}
+	m.publish(EventClear, map[string]interface{}{})
+	return dropped
+}
+
+// list returns the alert being shown and the queue
+func (m *OverlaysModule) list() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued := make([]Alert, len(m.queue))
+	copy(queued, m.queue)
+	result := map[string]interface{}{
+		"queued": queued,
+		"count":  len(queued),
+	}
+	if m.current != nil {
+		result["current"] = *m.current
+	}
+	return result
+}
+
+// listTemplates returns the configured templates sorted by name
+func (m *OverlaysModule) listTemplates() map[string]interface{} {
+	templates := make([]map[string]interface{}, 0, len(m.templates))
+	for _, template := range m.templates {
+		templates = append(templates, map[string]interface{}{
+			"name":        template.Name,
+			"text":        template.Text,
+			"image":       template.Image,
+			"sound":       template.Sound,
+			"duration_ms": template.Duration.Milliseconds(),
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i]["name"].(string) < templates[j]["name"].(string)
+	})
+	return map[string]interface{}{"templates": templates}
+}
+
+// publish sends a render event if a publisher is set
+func (m *OverlaysModule) publish(eventType string, data map[string]interface{}) {
+	m.mu.Lock()
+	publisher := m.publisher
+	m.mu.Unlock()
+
+	if publisher != nil {
+		publisher.Publish(events.Event{Type: eventType, Data: data})
+	}
+}
+
+// fillPlaceholders replaces {name} in text with the parameter of that name
+func fillPlaceholders(text string, parameters map[string]string) string {
+	if !strings.Contains(text, "{") {
+		return text
+	}
+	pairs := make([]string, 0, len(parameters)*2)
+	for name, value := range parameters {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// parseDuration accepts Go durations such as "8s" and plain milliseconds
+func parseDuration(raw string) (time.Duration, error) {
+	if ms, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	return time.ParseDuration(raw)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package overlays
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/modules"
+)
+
+type recorder chan events.Event
+
+func (r recorder) Publish(event events.Event) { r <- event }
+
+func (r recorder) next(t *testing.T) events.Event {
+	t.Helper()
+	select {
+	case event := <-r:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+		return events.Event{}
+	}
+}
+
+func newTestModule(t *testing.T) (*OverlaysModule, recorder) {
+	t.Helper()
+
+	m := New(config.OverlayAlertsConfig{
+		Enabled:         true,
+		DefaultDuration: 50 * time.Millisecond,
+		MaxQueue:        2,
+		Templates: []config.AlertTemplateConfig{
+			{Name: "follow", Text: "{user} just followed!", Image: "images/follow.gif", Sound: "sounds/follow.mp3", Duration: 30 * time.Millisecond},
+		},
+	})
+	published := make(recorder, 16)
+	m.SetEventPublisher(published)
+	if err := m.Initialize(map[string]string{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { m.Cleanup() })
+	return m, published
+}
+
+func TestOverlays_AlertsShowInOrder(t *testing.T) {
+	m, events := newTestModule(t)
+	ctx := context.Background()
+
+	if _, err := m.ExecuteAction(ctx, "alert", map[string]string{"template": "follow", "user": "penguin"}); err != nil {
+		t.Fatalf("alert failed: %v", err)
+	}
+	if _, err := m.ExecuteAction(ctx, "alert", map[string]string{"text": "Raid incoming", "duration": "20"}); err != nil {
+		t.Fatalf("alert failed: %v", err)
+	}
+
+	first := events.next(t)
+	if first.Type != EventAlert || first.Data["text"] != "penguin just followed!" || first.Data["sound"] != "sounds/follow.mp3" {
+		t.Errorf("Unexpected first alert %+v", first)
+	}
+	if first.Data["duration_ms"] != int64(30) {
+		t.Errorf("Expected the template duration, got %v", first.Data["duration_ms"])
+	}
+	if end := events.next(t); end.Type != EventAlertEnd || end.Data["id"] != first.Data["id"] {
+		t.Errorf("Unexpected end event %+v", end)
+	}
+	if second := events.next(t); second.Data["text"] != "Raid incoming" || second.Data["duration_ms"] != int64(20) {
+		t.Errorf("Unexpected second alert %+v", second)
+	}
+}
+
+func TestOverlays_InvalidAlerts(t *testing.T) {
+	m, _ := newTestModule(t)
+	ctx := context.Background()
+
+	for _, parameters := range []map[string]string{
+		{},
+		{"template": "raid"},
+		{"text": "hi", "duration": "soon"},
+	} {
+		if _, err := m.ExecuteAction(ctx, "alert", parameters); !errors.Is(err, modules.ErrInvalidParameters) {
+			t.Errorf("Expected ErrInvalidParameters for %v, got %v", parameters, err)
+		}
+	}
+}
+
+func TestOverlays_QueueLimitAndClear(t *testing.T) {
+	m, events := newTestModule(t)
+	ctx := context.Background()
+
+	parameters := map[string]string{"text": "hello", "duration": "1m"}
+	m.ExecuteAction(ctx, "alert", parameters)
+	events.next(t) // the first alert is showing, leaving the queue empty
+
+	m.ExecuteAction(ctx, "alert", parameters)
+	m.ExecuteAction(ctx, "alert", parameters)
+	if _, err := m.ExecuteAction(ctx, "alert", parameters); err == nil {
+		t.Error("Expected the queue to be full")
+	}
+
+	queue, _ := m.ExecuteAction(ctx, "queue", nil)
+	if queue["count"] != 2 || queue["current"] == nil {
+		t.Errorf("Unexpected queue %v", queue)
+	}
+
+	result, _ := m.ExecuteAction(ctx, "clear", nil)
+	if result["cleared"] != 2 {
+		t.Errorf("Expected 2 alerts dropped, got %v", result["cleared"])
+	}
+	seen := map[string]bool{}
+	seen[events.next(t).Type] = true
+	seen[events.next(t).Type] = true
+	if !seen[EventClear] || !seen[EventAlertEnd] {
+		t.Errorf("Expected clear and alert end events, got %v", seen)
+	}
+}
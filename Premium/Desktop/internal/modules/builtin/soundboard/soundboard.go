@@ -0,0 +1,454 @@
+package soundboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/modules"
+)
+
+// ModuleName is the name the soundboard module registers under
+const ModuleName = "soundboard"
+
+// ManifestFile is the name of the sound manifest inside the sounds directory
+const ManifestFile = "manifest.json"
+
+// audioExtensions are the file types discovered when no manifest exists
+var audioExtensions = map[string]bool{
+	".wav":  true,
+	".mp3":  true,
+	".ogg":  true,
+	".aiff": true,
+	".m4a":  true,
+	".flac": true,
+}
+
+// Sound is a single manifest entry
+type Sound struct {
+	File        string `json:"file"`
+	Volume      int    `json:"volume,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Manifest describes the sounds available to the soundboard
+type Manifest struct {
+	Sounds map[string]Sound `json:"sounds"`
+}
+
+// commandFunc builds the command used to play a file at a volume (0-100)
+type commandFunc func(ctx context.Context, file string, volume int) (*exec.Cmd, error)
+
+// SoundboardModule plays local audio files through the default output device
+type SoundboardModule struct {
+	cfg        config.SoundboardConfig
+	newCommand commandFunc
+
+	mu     sync.Mutex
+	sounds map[string]Sound
+	volume int
+	active map[int]context.CancelFunc
+	nextID int
+	wg     sync.WaitGroup
+}
+
+// New creates a new soundboard module instance
+func New(cfg config.SoundboardConfig) *SoundboardModule {
+	m := &SoundboardModule{
+		cfg:    cfg,
+		sounds: make(map[string]Sound),
+		active: make(map[int]context.CancelFunc),
+	}
+	m.newCommand = m.playerCommand
+	return m
+}
+
+// Initialize loads the sound manifest
+func (m *SoundboardModule) Initialize(cfg map[string]string) error {
+	if m.cfg.SoundsDir == "" {
+		return fmt.Errorf("sounds directory is not configured")
+	}
+	if err := os.MkdirAll(m.cfg.SoundsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create sounds directory: %w", err)
+	}
+
+	m.mu.Lock()
+	m.volume = clampVolume(m.cfg.DefaultVolume, 80)
+	m.mu.Unlock()
+
+	return m.loadManifest()
+}
+
+// GetInfo returns module information
+func (m *SoundboardModule) GetInfo() *modules.ModuleInfo {
+	return &modules.ModuleInfo{
+		Name:        ModuleName,
+		Version:     "1.0.0",
+		Description: "Play local sound alerts through the default output device",
+		Author:      "WaddleBot",
+		Actions: []modules.ActionInfo{
+			{
+				Name:        "play",
+				Description: "Play a sound from the manifest",
+				Parameters: map[string]interface{}{
+					"sound":  "string",
+					"volume": "number",
+					"wait":   "boolean",
+				},
+				ReturnType:  "object",
+				Timeout:     30,
+				Permissions: []string{"audio.play"},
+			},
+			{
+				Name:        "stop",
+				Description: "Stop all playing sounds",
+				Parameters:  map[string]interface{}{},
+				ReturnType:  "object",
+				Timeout:     5,
+				Permissions: []string{"audio.play"},
+			},
+			{
+				Name:        "list",
+				Description: "List available sounds",
+				Parameters:  map[string]interface{}{},
+				ReturnType:  "array",
+				Timeout:     5,
+				Permissions: []string{"audio.read"},
+			},
+			{
+				Name:        "set_volume",
+				Description: "Set the master volume (0-100)",
+				Parameters: map[string]interface{}{
+					"volume": "number",
+				},
+				ReturnType:  "object",
+				Timeout:     5,
+				Permissions: []string{"audio.play"},
+			},
+			{
+				Name:        "reload",
+				Description: "Reload the sound manifest",
+				Parameters:  map[string]interface{}{},
+				ReturnType:  "object",
+				Timeout:     5,
+				Permissions: []string{"audio.read"},
+			},
+		},
+		Dependencies: []string{},
+		Permissions:  []string{"audio.play", "audio.read"},
+		Config:       map[string]string{},
+		Enabled:      true,
+		LoadedAt:     time.Now(),
+	}
+}
+
+// ExecuteAction executes a specific action
+func (m *SoundboardModule) ExecuteAction(ctx context.Context, action string, parameters map[string]string) (map[string]interface{}, error) {
+	switch action {
+	case "play":
+		return m.play(ctx, parameters)
+	case "stop":
+		return map[string]interface{}{"stopped": m.StopAll()}, nil
+	case "list":
+		return m.list(), nil
+	case "set_volume":
+		return m.setVolume(parameters)
+	case "reload":
+		if err := m.loadManifest(); err != nil {
+			return nil, err
+		}
+		return m.list(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", modules.ErrActionNotFound, action)
+	}
+}
+
+// GetActions returns available actions
+func (m *SoundboardModule) GetActions() []modules.ActionInfo {
+	return m.GetInfo().Actions
+}
+
+// Cleanup stops all playback and waits for players to exit
+func (m *SoundboardModule) Cleanup() error {
+	m.StopAll()
+	m.wg.Wait()
+	return nil
+}
+
+// StopAll stops every active playback and returns how many were stopped
+func (m *SoundboardModule) StopAll() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stopped := len(m.active)
+	for _, cancel := range m.active {
+		cancel()
+	}
+	return stopped
+}
+
+// loadManifest reads the manifest file, falling back to discovering audio
+// files in the sounds directory
+func (m *SoundboardModule) loadManifest() error {
+	sounds := make(map[string]Sound)
+
+	data, err := os.ReadFile(filepath.Join(m.cfg.SoundsDir, ManifestFile))
+	switch {
+	case err == nil:
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Errorf("invalid sound manifest: %w", err)
+		}
+		for name, sound := range manifest.Sounds {
+			if _, err := m.soundPath(sound.File); err != nil {
+				return fmt.Errorf("sound %s: %w", name, err)
+			}
+			sounds[name] = sound
+		}
+	case os.IsNotExist(err):
+		entries, err := os.ReadDir(m.cfg.SoundsDir)
+		if err != nil {
+			return fmt.Errorf("failed to read sounds directory: %w", err)
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || !audioExtensions[ext] {
+				continue
+			}
+			sounds[strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))] = Sound{File: entry.Name()}
+		}
+	default:
+		return fmt.Errorf("failed to read sound manifest: %w", err)
+	}
+
+	m.mu.Lock()
+	m.sounds = sounds
+	m.mu.Unlock()
+
+	return nil
+}
+
+// soundPath resolves a manifest file entry inside the sounds directory
+func (m *SoundboardModule) soundPath(file string) (string, error) {
+	if file == "" {
+		return "", fmt.Errorf("%w: file is required", modules.ErrInvalidParameters)
+	}
+
+	path := filepath.Clean(filepath.Join(m.cfg.SoundsDir, file))
+	rel, err := filepath.Rel(filepath.Clean(m.cfg.SoundsDir), path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s is outside the sounds directory", modules.ErrPermissionDenied, file)
+	}
+	return path, nil
+}
+
+// play starts playback of a sound, optionally waiting for it to finish
+func (m *SoundboardModule) play(ctx context.Context, parameters map[string]string) (map[string]interface{}, error) {
+	name := parameters["sound"]
+	if name == "" {
+		return nil, fmt.Errorf("%w: sound is required", modules.ErrInvalidParameters)
+	}
+
+	m.mu.Lock()
+	sound, ok := m.sounds[name]
+	master := m.volume
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sound %s", modules.ErrInvalidParameters, name)
+	}
+
+	path, err := m.soundPath(sound.File)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("sound file unavailable: %w", err)
+	}
+
+	volume := clampVolume(sound.Volume, 100)
+	if raw := parameters["volume"]; raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid volume", modules.ErrInvalidParameters)
+		}
+		volume = boundVolume(v)
+	}
+	volume = volume * master / 100
+
+	wait, _ := strconv.ParseBool(parameters["wait"])
+
+	// Waiting playback follows the caller's context, background playback
+	// lives until it finishes or is stopped
+	parent := context.Background()
+	if wait {
+		parent = ctx
+	}
+	playCtx, cancel := context.WithCancel(parent)
+
+	cmd, err := m.newCommand(playCtx, path, volume)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	m.mu.Lock()
+	limit := m.cfg.MaxConcurrent
+	if limit <= 0 {
+		limit = 3
+	}
+	if len(m.active) >= limit {
+		m.mu.Unlock()
+		cancel()
+		return nil, fmt.Errorf("playback limit of %d concurrent sounds reached", limit)
+	}
+	m.nextID++
+	id := m.nextID
+	m.active[id] = cancel
+	m.mu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		m.finish(id)
+		return nil, fmt.Errorf("failed to start player: %w", err)
+	}
+
+	done := make(chan error, 1)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		err := cmd.Wait()
+		m.finish(id)
+		done <- err
+	}()
+
+	result := map[string]interface{}{
+		"playback_id": id,
+		"sound":       name,
+		"volume":      volume,
+	}
+
+	if wait {
+		if err := <-done; err != nil && playCtx.Err() == nil {
+			return nil, fmt.Errorf("playback failed: %w", err)
+		}
+		result["finished"] = true
+	}
+
+	return result, nil
+}
+
+// finish removes a playback from the active set
+func (m *SoundboardModule) finish(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cancel, ok := m.active[id]; ok {
+		cancel()
+		delete(m.active, id)
+	}
+}
+
+// list returns the available sounds and playback state
+func (m *SoundboardModule) list() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.sounds))
+	for name := range m.sounds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sounds := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		sound := m.sounds[name]
+		sounds = append(sounds, map[string]interface{}{
+			"name":        name,
+			"file":        sound.File,
+			"volume":      clampVolume(sound.Volume, 100),
+			"description": sound.Description,
+		})
+	}
+
+	return map[string]interface{}{
+		"sounds":        sounds,
+		"master_volume": m.volume,
+		"playing":       len(m.active),
+	}
+}
+
+// setVolume sets the master volume
+func (m *SoundboardModule) setVolume(parameters map[string]string) (map[string]interface{}, error) {
+	v, err := strconv.Atoi(parameters["volume"])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid volume", modules.ErrInvalidParameters)
+	}
+
+	m.mu.Lock()
+	m.volume = boundVolume(v)
+	volume := m.volume
+	m.mu.Unlock()
+
+	return map[string]interface{}{"master_volume": volume}, nil
+}
+
+// playerCommand builds the platform-specific playback command
+func (m *SoundboardModule) playerCommand(ctx context.Context, file string, volume int) (*exec.Cmd, error) {
+	if m.cfg.PlayerPath != "" {
+		return exec.CommandContext(ctx, m.cfg.PlayerPath, file), nil
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.CommandContext(ctx, "afplay", "-v", fmt.Sprintf("%.2f", float64(volume)/100), file), nil
+	case "windows":
+		script := fmt.Sprintf(`Add-Type -AssemblyName PresentationCore
+$p = New-Object System.Windows.Media.MediaPlayer
+$p.Open([uri]'%s')
+$p.Volume = %.2f
+$p.Play()
+while (-not $p.NaturalDuration.HasTimeSpan) { Start-Sleep -Milliseconds 50 }
+Start-Sleep -Milliseconds ([int]$p.NaturalDuration.TimeSpan.TotalMilliseconds)`,
+			strings.ReplaceAll(file, "'", "''"), float64(volume)/100)
+		return exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script), nil
+	default:
+		if path, err := exec.LookPath("paplay"); err == nil {
+			return exec.CommandContext(ctx, path, fmt.Sprintf("--volume=%d", volume*65536/100), file), nil
+		}
+		if path, err := exec.LookPath("ffplay"); err == nil {
+			return exec.CommandContext(ctx, path, "-nodisp", "-autoexit", "-loglevel", "quiet", "-volume", strconv.Itoa(volume), file), nil
+		}
+		if path, err := exec.LookPath("aplay"); err == nil {
+			return exec.CommandContext(ctx, path, "-q", file), nil
+		}
+		return nil, fmt.Errorf("no audio player found (install paplay, ffplay or aplay, or set player-path)")
+	}
+}
+
+// clampVolume limits a configured volume to 1-100, using def for unset values
+func clampVolume(volume, def int) int {
+	if volume <= 0 {
+		return def
+	}
+	return boundVolume(volume)
+}
+
+// boundVolume limits an explicit volume to 0-100
+func boundVolume(volume int) int {
+	if volume < 0 {
+		return 0
+	}
+	if volume > 100 {
+		return 100
+	}
+	return volume
+}
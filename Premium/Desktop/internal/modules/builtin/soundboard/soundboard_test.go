@@ -0,0 +1,127 @@
+package soundboard
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/modules"
+)
+
+func newTestModule(t *testing.T, manifest string) (*SoundboardModule, *[]int) {
+	t.Helper()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "follow.wav"), []byte("RIFF"), 0644)
+	os.WriteFile(filepath.Join(dir, "donation.mp3"), []byte("ID3"), 0644)
+	if manifest != "" {
+		os.WriteFile(filepath.Join(dir, ManifestFile), []byte(manifest), 0644)
+	}
+
+	m := New(config.SoundboardConfig{
+		Enabled:       true,
+		SoundsDir:     dir,
+		DefaultVolume: 50,
+		MaxConcurrent: 1,
+	})
+
+	volumes := &[]int{}
+	m.newCommand = func(ctx context.Context, file string, volume int) (*exec.Cmd, error) {
+		*volumes = append(*volumes, volume)
+		return exec.CommandContext(ctx, "sleep", "0.2"), nil
+	}
+
+	if err := m.Initialize(map[string]string{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return m, volumes
+}
+
+func TestSoundboard_DiscoversSounds(t *testing.T) {
+	m, _ := newTestModule(t, "")
+
+	result, err := m.ExecuteAction(context.Background(), "list", map[string]string{})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	sounds := result["sounds"].([]map[string]interface{})
+	if len(sounds) != 2 || sounds[0]["name"] != "donation" || sounds[1]["name"] != "follow" {
+		t.Errorf("Unexpected sounds %v", sounds)
+	}
+}
+
+func TestSoundboard_Manifest(t *testing.T) {
+	m, volumes := newTestModule(t, `{"sounds":{"alert":{"file":"follow.wav","volume":60}}}`)
+
+	_, err := m.ExecuteAction(context.Background(), "play", map[string]string{"sound": "alert", "wait": "true"})
+	if err != nil {
+		t.Fatalf("play failed: %v", err)
+	}
+	// Sound volume 60 scaled by master volume 50
+	if len(*volumes) != 1 || (*volumes)[0] != 30 {
+		t.Errorf("Expected volume 30, got %v", *volumes)
+	}
+
+	if _, err := m.ExecuteAction(context.Background(), "play", map[string]string{"sound": "follow"}); !errors.Is(err, modules.ErrInvalidParameters) {
+		t.Errorf("Expected unknown sound error, got %v", err)
+	}
+}
+
+func TestSoundboard_ManifestTraversal(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"sounds":{"x":{"file":"../secret.wav"}}}`), 0644)
+
+	m := New(config.SoundboardConfig{Enabled: true, SoundsDir: dir})
+	if err := m.Initialize(map[string]string{}); !errors.Is(err, modules.ErrPermissionDenied) {
+		t.Errorf("Expected traversal to be rejected, got %v", err)
+	}
+}
+
+func TestSoundboard_ConcurrencyLimitAndStop(t *testing.T) {
+	m, _ := newTestModule(t, "")
+	ctx := context.Background()
+
+	if _, err := m.ExecuteAction(ctx, "play", map[string]string{"sound": "follow"}); err != nil {
+		t.Fatalf("play failed: %v", err)
+	}
+	if _, err := m.ExecuteAction(ctx, "play", map[string]string{"sound": "donation"}); err == nil {
+		t.Error("Expected playback limit error")
+	}
+
+	result, _ := m.ExecuteAction(ctx, "stop", map[string]string{})
+	if result["stopped"] != 1 {
+		t.Errorf("Expected 1 stopped playback, got %v", result["stopped"])
+	}
+
+	m.Cleanup()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if list := m.list(); list["playing"] == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected no active playback after stop")
+}
+
+func TestSoundboard_SetVolume(t *testing.T) {
+	m, volumes := newTestModule(t, "")
+
+	result, err := m.ExecuteAction(context.Background(), "set_volume", map[string]string{"volume": "150"})
+	if err != nil {
+		t.Fatalf("set_volume failed: %v", err)
+	}
+	if result["master_volume"] != 100 {
+		t.Errorf("Expected volume clamped to 100, got %v", result["master_volume"])
+	}
+
+	m.ExecuteAction(context.Background(), "play", map[string]string{"sound": "follow", "volume": "40", "wait": "true"})
+	if (*volumes)[0] != 40 {
+		t.Errorf("Expected volume 40, got %v", *volumes)
+	}
+}
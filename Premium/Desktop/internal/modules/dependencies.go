@@ -0,0 +1,240 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dependency represents a parsed module dependency such as "system" or
+// "system>=1.2.0".
+type Dependency struct {
+	Name     string
+	Operator string
+	Version  string
+}
+
+// dependencyOperators lists supported constraint operators, longest first so
+// that ">=" is matched before ">".
+var dependencyOperators = []string{">=", "<=", "==", "!=", ">", "<", "="}
+
+// ParseDependency parses a dependency string into its name and optional
+// version constraint
+func ParseDependency(spec string) (Dependency, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return Dependency{}, fmt.Errorf("empty dependency")
+	}
+
+	for _, op := range dependencyOperators {
+		idx := strings.Index(spec, op)
+		if idx < 0 {
+			continue
+		}
+
+		name := strings.TrimSpace(spec[:idx])
+		version := strings.TrimSpace(spec[idx+len(op):])
+		if name == "" || version == "" {
+			return Dependency{}, fmt.Errorf("invalid dependency %q", spec)
+		}
+		if _, err := parseVersion(version); err != nil {
+			return Dependency{}, fmt.Errorf("invalid dependency %q: %w", spec, err)
+		}
+		if op == "==" {
+			op = "="
+		}
+
+		return Dependency{Name: name, Operator: op, Version: version}, nil
+	}
+
+	return Dependency{Name: spec}, nil
+}
+
+// Satisfies reports whether the given module version satisfies the constraint
+func (d Dependency) Satisfies(version string) bool {
+	if d.Operator == "" {
+		return true
+	}
+
+	have, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+	want, _ := parseVersion(d.Version)
+
+	cmp := compareVersions(have, want)
+	switch d.Operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// String returns the dependency in its textual form
+func (d Dependency) String() string {
+	return d.Name + d.Operator + d.Version
+}
+
+// parseVersion parses a dotted version string ("1.2.3", "v1.2") into its
+// numeric components. Pre-release and build suffixes are ignored.
+func parseVersion(version string) ([]int, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if idx := strings.IndexAny(version, "-+"); idx >= 0 {
+		version = version[:idx]
+	}
+	if version == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+
+	parts := strings.Split(version, ".")
+	result := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		result[i] = n
+	}
+	return result, nil
+}
+
+// compareVersions compares two parsed versions, treating missing components
+// as zero
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// resolveLoadOrder orders modules so that every module comes after its
+// dependencies. Modules whose dependencies are missing, fail their version
+// constraint or form a cycle are returned in the failed map together with
+// the reason and are excluded from the order. Modules already present in
+// loaded are treated as satisfied dependencies.
+func resolveLoadOrder(candidates map[string]*ModuleInfo, loaded map[string]*ModuleInfo) ([]string, map[string]error) {
+	failed := make(map[string]error)
+	deps := make(map[string][]Dependency, len(candidates))
+
+	// Parse dependency declarations
+	for name, info := range candidates {
+		for _, spec := range info.Dependencies {
+			dep, err := ParseDependency(spec)
+			if err != nil {
+				failed[name] = fmt.Errorf("%w: %v", ErrDependencyInvalid, err)
+				break
+			}
+			deps[name] = append(deps[name], dep)
+		}
+	}
+
+	lookup := func(name string) (*ModuleInfo, bool) {
+		if info, ok := candidates[name]; ok {
+			return info, true
+		}
+		info, ok := loaded[name]
+		return info, ok
+	}
+
+	// Validate existence and version constraints
+	for name := range candidates {
+		if failed[name] != nil {
+			continue
+		}
+		for _, dep := range deps[name] {
+			info, ok := lookup(dep.Name)
+			if !ok {
+				failed[name] = fmt.Errorf("%w: %s requires %s", ErrDependencyMissing, name, dep.Name)
+				break
+			}
+			if !dep.Satisfies(info.Version) {
+				failed[name] = fmt.Errorf("%w: %s requires %s, found %s", ErrDependencyVersion, name, dep, info.Version)
+				break
+			}
+		}
+	}
+
+	// Depth-first topological sort over candidates in name order so the
+	// result is deterministic
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(candidates))
+	order := make([]string, 0, len(candidates))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return failed[name]
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path, name), " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		err := failed[name]
+		if err == nil {
+			for _, dep := range deps[name] {
+				if _, ok := candidates[dep.Name]; !ok {
+					continue
+				}
+				if depErr := visit(dep.Name, path); depErr != nil {
+					if errors.Is(depErr, ErrDependencyCycle) {
+						err = depErr
+					} else {
+						err = fmt.Errorf("%w: %s requires %s which failed to load", ErrDependencyMissing, name, dep.Name)
+					}
+					break
+				}
+			}
+		}
+
+		state[name] = done
+		if err != nil {
+			failed[name] = err
+			return err
+		}
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		visit(name, nil)
+	}
+
+	return order, failed
+}
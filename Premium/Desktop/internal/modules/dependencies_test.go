@@ -0,0 +1,180 @@
+package modules
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/testutils"
+)
+
+func TestParseDependency(t *testing.T) {
+	tests := []struct {
+		spec     string
+		name     string
+		operator string
+		version  string
+		wantErr  bool
+	}{
+		{"system", "system", "", "", false},
+		{" system ", "system", "", "", false},
+		{"system>=1.2.0", "system", ">=", "1.2.0", false},
+		{"system == 1.0", "system", "=", "1.0", false},
+		{"system<2", "system", "<", "2", false},
+		{"", "", "", "", true},
+		{">=1.0.0", "", "", "", true},
+		{"system>=", "", "", "", true},
+		{"system>=abc", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		dep, err := ParseDependency(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDependency(%q): expected error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDependency(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if dep.Name != tt.name || dep.Operator != tt.operator || dep.Version != tt.version {
+			t.Errorf("ParseDependency(%q) = %+v", tt.spec, dep)
+		}
+	}
+}
+
+func TestDependency_Satisfies(t *testing.T) {
+	tests := []struct {
+		spec    string
+		version string
+		want    bool
+	}{
+		{"system", "0.0.1", true},
+		{"system>=1.2.0", "1.2.0", true},
+		{"system>=1.2.0", "1.10.0", true},
+		{"system>=1.2.0", "1.1.9", false},
+		{"system<2.0", "1.9.9", true},
+		{"system<2.0", "2.0.0", false},
+		{"system=1.0", "v1.0.0", true},
+		{"system!=1.0", "1.0.0", false},
+		{"system>=1.0.0", "not-a-version", false},
+	}
+
+	for _, tt := range tests {
+		dep, err := ParseDependency(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseDependency(%q) failed: %v", tt.spec, err)
+		}
+		if got := dep.Satisfies(tt.version); got != tt.want {
+			t.Errorf("%q satisfied by %q = %v, want %v", tt.spec, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestResolveLoadOrder(t *testing.T) {
+	candidates := map[string]*ModuleInfo{
+		"app":    {Name: "app", Version: "1.0.0", Dependencies: []string{"obs", "base>=1.0"}},
+		"obs":    {Name: "obs", Version: "2.1.0", Dependencies: []string{"base"}},
+		"base":   {Name: "base", Version: "1.3.0"},
+		"orphan": {Name: "orphan", Version: "1.0.0", Dependencies: []string{"missing"}},
+		"old":    {Name: "old", Version: "1.0.0", Dependencies: []string{"obs>=3.0"}},
+		"child":  {Name: "child", Version: "1.0.0", Dependencies: []string{"orphan"}},
+		"a":      {Name: "a", Version: "1.0.0", Dependencies: []string{"b"}},
+		"b":      {Name: "b", Version: "1.0.0", Dependencies: []string{"a"}},
+	}
+
+	order, failed := resolveLoadOrder(candidates, nil)
+
+	position := make(map[string]int)
+	for i, name := range order {
+		position[name] = i
+	}
+
+	for _, name := range []string{"app", "obs", "base"} {
+		if _, ok := position[name]; !ok {
+			t.Errorf("Expected %s in load order, got %v", name, order)
+		}
+	}
+	if position["base"] > position["obs"] || position["obs"] > position["app"] {
+		t.Errorf("Expected dependencies before dependents, got %v", order)
+	}
+
+	expected := map[string]error{
+		"orphan": ErrDependencyMissing,
+		"child":  ErrDependencyMissing,
+		"old":    ErrDependencyVersion,
+		"a":      ErrDependencyCycle,
+		"b":      ErrDependencyCycle,
+	}
+	for name, want := range expected {
+		if !errors.Is(failed[name], want) {
+			t.Errorf("Expected %s to fail with %v, got %v", name, want, failed[name])
+		}
+		if _, ok := position[name]; ok {
+			t.Errorf("Expected %s to be excluded from load order", name)
+		}
+	}
+}
+
+func TestResolveLoadOrder_LoadedDependencies(t *testing.T) {
+	loaded := map[string]*ModuleInfo{
+		"base": {Name: "base", Version: "1.0.0"},
+	}
+	candidates := map[string]*ModuleInfo{
+		"app": {Name: "app", Version: "1.0.0", Dependencies: []string{"base"}},
+		"new": {Name: "new", Version: "1.0.0", Dependencies: []string{"base>=2.0"}},
+	}
+
+	order, failed := resolveLoadOrder(candidates, loaded)
+	if len(order) != 1 || order[0] != "app" {
+		t.Errorf("Expected [app], got %v", order)
+	}
+	if !errors.Is(failed["new"], ErrDependencyVersion) {
+		t.Errorf("Expected version error for new, got %v", failed["new"])
+	}
+}
+
+func TestManager_EnableModule_DependencyDisabled(t *testing.T) {
+	cfg := testutils.TestConfig()
+	storage := testutils.NewMockStorage()
+	manager := NewManager(cfg, storage)
+
+	base := testutils.TestModule("base")
+	app := testutils.TestModule("app")
+	appInfo := app.GetInfo()
+	appInfo.Dependencies = []string{"base"}
+
+	manager.modules["base"] = &Module{
+		Info:     base.GetInfo(),
+		Instance: base,
+		Enabled:  false,
+		LoadedAt: time.Now(),
+	}
+	manager.modules["app"] = &Module{
+		Info:     appInfo,
+		Instance: app,
+		Enabled:  false,
+		LoadedAt: time.Now(),
+	}
+
+	err := manager.EnableModule("app")
+	if !errors.Is(err, ErrDependencyDisabled) {
+		t.Fatalf("Expected ErrDependencyDisabled, got %v", err)
+	}
+
+	if err := manager.EnableModule("base"); err != nil {
+		t.Fatalf("EnableModule(base) failed: %v", err)
+	}
+	if err := manager.EnableModule("app"); err != nil {
+		t.Fatalf("EnableModule(app) failed: %v", err)
+	}
+
+	// Missing dependency is also refused
+	delete(manager.modules, "base")
+	manager.modules["app"].Enabled = false
+	if err := manager.EnableModule("app"); !errors.Is(err, ErrDependencyMissing) {
+		t.Errorf("Expected ErrDependencyMissing, got %v", err)
+	}
+}
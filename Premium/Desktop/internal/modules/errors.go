@@ -13,4 +13,9 @@ var (
 	ErrModuleLoadFailed   = fmt.Errorf("module load failed")
 	ErrPermissionDenied   = fmt.Errorf("permission denied")
 	ErrTimeout            = fmt.Errorf("operation timeout")
+	ErrDependencyMissing  = fmt.Errorf("missing module dependency")
+	ErrDependencyVersion  = fmt.Errorf("module dependency version mismatch")
+	ErrDependencyCycle    = fmt.Errorf("circular module dependency")
+	ErrDependencyInvalid  = fmt.Errorf("invalid module dependency")
+	ErrDependencyDisabled = fmt.Errorf("module dependency is disabled")
 )
\ No newline at end of file
@@ -0,0 +1,44 @@
+package modules
+
+import "waddlebot-bridge/internal/events"
+
+// EventEmitter is implemented by modules that publish events on the bridge
+// event bus. The publisher is set before the module is initialized.
+type EventEmitter interface {
+	SetEventPublisher(publisher events.Publisher)
+}
+
+// modulePublisher stamps events published by a module with its source
+type modulePublisher struct {
+	publisher events.Publisher
+	module    string
+}
+
+// Publish publishes an event as coming from the module
+func (p modulePublisher) Publish(event events.Event) {
+	if event.Source == "" {
+		event.Source = events.ModuleSource(p.module)
+	}
+	p.publisher.Publish(event)
+}
+
+// SetEventPublisher gives modules that implement EventEmitter access to the
+// event bus, including modules that are already loaded
+func (m *Manager) SetEventPublisher(publisher events.Publisher) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.publisher = publisher
+	for name, module := range m.modules {
+		m.attachPublisher(name, module.Instance)
+	}
+}
+
+// attachPublisher hands the event publisher to a module if it wants one
+func (m *Manager) attachPublisher(name string, instance ModuleInterface) {
+	emitter, ok := instance.(EventEmitter)
+	if !ok || m.publisher == nil {
+		return
+	}
+	emitter.SetEventPublisher(modulePublisher{publisher: m.publisher, module: name})
+}
@@ -0,0 +1,108 @@
+package modules
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ModuleHealth summarizes the recent behavior of a module's actions
+type ModuleHealth struct {
+	Name        string     `json:"name"`
+	Enabled     bool       `json:"enabled"`
+	Healthy     bool       `json:"healthy"`
+	Calls       int64      `json:"calls"`
+	Failures    int64      `json:"failures"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastUsed    time.Time  `json:"last_used"`
+}
+
+// actionHealth holds the action counters for one module
+type actionHealth struct {
+	calls       int64
+	failures    int64
+	lastError   string
+	lastFailure time.Time
+	lastSuccess time.Time
+}
+
+// healthTracker records action outcomes per module
+type healthTracker struct {
+	mu      sync.Mutex
+	modules map[string]*actionHealth
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{modules: make(map[string]*actionHealth)}
+}
+
+// record counts an action call and its outcome
+func (h *healthTracker) record(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry, exists := h.modules[name]
+	if !exists {
+		entry = &actionHealth{}
+		h.modules[name] = entry
+	}
+
+	entry.calls++
+	if err != nil {
+		entry.failures++
+		entry.lastError = err.Error()
+		entry.lastFailure = time.Now()
+	} else {
+		entry.lastSuccess = time.Now()
+	}
+}
+
+// forget drops the counters of an unloaded module
+func (h *healthTracker) forget(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.modules, name)
+}
+
+// GetModuleHealth returns the health of every loaded module, sorted by
+// name. A module is healthy when it is enabled and its most recent action
+// did not fail.
+func (m *Manager) GetModuleHealth() []ModuleHealth {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	health := make([]ModuleHealth, 0, len(m.modules))
+	for name, module := range m.modules {
+		entry := ModuleHealth{
+			Name:     name,
+			Enabled:  module.Enabled,
+			Healthy:  module.Enabled,
+			LastUsed: module.Info.LastUsed,
+		}
+
+		if counters, exists := m.health.modules[name]; exists {
+			entry.Calls = counters.calls
+			entry.Failures = counters.failures
+			entry.LastError = counters.lastError
+			if !counters.lastFailure.IsZero() {
+				lastFailure := counters.lastFailure
+				entry.LastFailure = &lastFailure
+				if counters.lastFailure.After(counters.lastSuccess) {
+					entry.Healthy = false
+				}
+			}
+		}
+
+		health = append(health, entry)
+	}
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Name < health[j].Name
+	})
+
+	return health
+}
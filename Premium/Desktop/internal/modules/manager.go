@@ -13,19 +13,26 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
 	"waddlebot-bridge/internal/logger"
+	"waddlebot-bridge/internal/metrics"
 	"waddlebot-bridge/internal/models"
 	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/supervisor"
+	"waddlebot-bridge/internal/tracing"
 )
 
 // Manager handles module loading and execution
 type Manager struct {
 	config      *config.Config
-	storage     storage.Storage
+	storage     *storage.ModuleRepo
 	logger      *logrus.Logger
 	modules     map[string]*Module
 	moduleInfos map[string]*models.ModuleInfo
+	health      *healthTracker
+	publisher   events.Publisher
 	mutex       sync.RWMutex
 }
 
@@ -65,22 +72,41 @@ type ModuleInterface interface {
 
 // NewManager creates a new module manager
 func NewManager(cfg *config.Config, store storage.Storage) *Manager {
+	log := logger.GetLogger()
+	repo, err := storage.NewModuleRepo(store)
+	if err != nil {
+		log.WithError(err).Warn("Failed to prepare module storage")
+	}
+
 	return &Manager{
 		config:      cfg,
-		storage:     store,
-		logger:      logger.GetLogger(),
+		storage:     repo,
+		logger:      log,
 		modules:     make(map[string]*Module),
 		moduleInfos: make(map[string]*ModuleInfo),
+		health:      newHealthTracker(),
 	}
 }
 
-// LoadModules loads all modules from the modules directory
+// pendingModule is a plugin that has been opened but not yet initialized
+type pendingModule struct {
+	path     string
+	plugin   *plugin.Plugin
+	instance ModuleInterface
+	info     *ModuleInfo
+}
+
+// LoadModules loads all modules from the modules directory. Modules are
+// initialized in dependency order; modules whose dependencies cannot be
+// satisfied are skipped.
 func (m *Manager) LoadModules() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	m.logger.WithField("modules_dir", m.config.ModulesDir).Info("Loading modules")
 
+	pending := make(map[string]*pendingModule)
+
 	// Walk through modules directory
 	err := filepath.WalkDir(m.config.ModulesDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -94,10 +120,21 @@ func (m *Manager) LoadModules() error {
 
 		// Check if it's a .so file (plugin)
 		if strings.HasSuffix(path, ".so") {
-			if err := m.loadModule(path); err != nil {
+			pm, err := m.openModule(path)
+			if err != nil {
 				m.logger.WithError(err).WithField("path", path).Error("Failed to load module")
 				// Continue loading other modules
+				return nil
+			}
+			if existing, ok := pending[pm.info.Name]; ok {
+				m.logger.WithFields(logrus.Fields{
+					"module": pm.info.Name,
+					"path":   path,
+					"first":  existing.path,
+				}).Error("Duplicate module name, skipping")
+				return nil
 			}
+			pending[pm.info.Name] = pm
 		}
 
 		return nil
@@ -107,41 +144,103 @@ func (m *Manager) LoadModules() error {
 		return fmt.Errorf("failed to walk modules directory: %w", err)
 	}
 
+	if err := m.initializeModules(pending); err != nil {
+		return err
+	}
+
 	m.logger.WithField("loaded_modules", len(m.modules)).Info("Finished loading modules")
 	return nil
 }
 
+// initializeModules resolves dependencies between pending modules and
+// initializes them in load order
+func (m *Manager) initializeModules(pending map[string]*pendingModule) error {
+	candidates := make(map[string]*ModuleInfo, len(pending))
+	for name, pm := range pending {
+		candidates[name] = pm.info
+	}
+
+	order, failed := resolveLoadOrder(candidates, m.moduleInfos)
+	for name, err := range failed {
+		m.logger.WithError(err).WithFields(logrus.Fields{
+			"module": name,
+			"path":   pending[name].path,
+		}).Error("Failed to resolve module dependencies")
+	}
+
+	for _, name := range order {
+		pm := pending[name]
+		if err := m.checkDependenciesLoaded(pm.info); err != nil {
+			m.logger.WithError(err).WithField("path", pm.path).Error("Failed to load module")
+			continue
+		}
+		if err := m.initModule(pm); err != nil {
+			m.logger.WithError(err).WithField("path", pm.path).Error("Failed to load module")
+		}
+	}
+
+	return nil
+}
+
 // loadModule loads a single module from a plugin file
 func (m *Manager) loadModule(path string) error {
+	pm, err := m.openModule(path)
+	if err != nil {
+		return err
+	}
+
+	if _, failed := resolveLoadOrder(map[string]*ModuleInfo{pm.info.Name: pm.info}, m.moduleInfos); failed[pm.info.Name] != nil {
+		return failed[pm.info.Name]
+	}
+
+	return m.initModule(pm)
+}
+
+// openModule opens a plugin file and instantiates its module without
+// initializing it
+func (m *Manager) openModule(path string) (*pendingModule, error) {
 	m.logger.WithField("path", path).Debug("Loading module")
 
 	// Load plugin
 	plug, err := plugin.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open plugin: %w", err)
+		return nil, fmt.Errorf("failed to open plugin: %w", err)
 	}
 
 	// Look for the required symbol
 	symbol, err := plug.Lookup("NewModule")
 	if err != nil {
-		return fmt.Errorf("failed to find NewModule symbol: %w", err)
+		return nil, fmt.Errorf("failed to find NewModule symbol: %w", err)
 	}
 
 	// Assert the symbol is the correct type
 	newModuleFunc, ok := symbol.(func() ModuleInterface)
 	if !ok {
-		return fmt.Errorf("NewModule is not of type func() ModuleInterface")
+		return nil, fmt.Errorf("NewModule is not of type func() ModuleInterface")
 	}
 
 	// Create module instance
 	instance := newModuleFunc()
-	
+
 	// Get module info
 	info := instance.GetInfo()
 	if info == nil {
-		return fmt.Errorf("module returned nil info")
+		return nil, fmt.Errorf("module returned nil info")
 	}
 
+	return &pendingModule{
+		path:     path,
+		plugin:   plug,
+		instance: instance,
+		info:     info,
+	}, nil
+}
+
+// initModule initializes an opened module and registers it with the manager
+func (m *Manager) initModule(pm *pendingModule) error {
+	info := pm.info
+	instance := pm.instance
+
 	// Load module configuration
 	config, err := m.loadModuleConfig(info.Name)
 	if err != nil {
@@ -149,18 +248,27 @@ func (m *Manager) loadModule(path string) error {
 		config = make(map[string]string)
 	}
 
+	m.attachPublisher(info.Name, instance)
+
 	// Initialize module
 	if err := instance.Initialize(config); err != nil {
 		return fmt.Errorf("failed to initialize module: %w", err)
 	}
 
+	// A module cannot start enabled if any of its dependencies is disabled
+	enabled := m.checkDependenciesEnabled(info) == nil
+	if !enabled {
+		m.logger.WithField("module", info.Name).Warn("Module dependency is disabled, loading module as disabled")
+	}
+	info.Enabled = enabled
+
 	// Create module wrapper
 	module := &Module{
 		Info:     info,
-		Plugin:   plug,
+		Plugin:   pm.plugin,
 		Instance: instance,
 		Config:   config,
-		Enabled:  true,
+		Enabled:  enabled,
 		LoadedAt: time.Now(),
 	}
 
@@ -174,14 +282,73 @@ func (m *Manager) loadModule(path string) error {
 	}
 
 	m.logger.WithFields(logrus.Fields{
-		"module":  info.Name,
-		"version": info.Version,
-		"actions": len(info.Actions),
+		"module":       info.Name,
+		"version":      info.Version,
+		"actions":      len(info.Actions),
+		"dependencies": len(info.Dependencies),
 	}).Info("Module loaded successfully")
 
 	return nil
 }
 
+// RegisterBuiltin registers a module compiled into the bridge. Built-in
+// modules go through the same dependency checks and initialization as
+// plugin modules but have no backing plugin file.
+func (m *Manager) RegisterBuiltin(instance ModuleInterface) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	info := instance.GetInfo()
+	if info == nil {
+		return fmt.Errorf("module returned nil info")
+	}
+	if _, exists := m.modules[info.Name]; exists {
+		return fmt.Errorf("module %s already loaded", info.Name)
+	}
+
+	pm := &pendingModule{
+		path:     "builtin:" + info.Name,
+		instance: instance,
+		info:     info,
+	}
+
+	if _, failed := resolveLoadOrder(map[string]*ModuleInfo{info.Name: info}, m.moduleInfos); failed[info.Name] != nil {
+		return failed[info.Name]
+	}
+
+	return m.initModule(pm)
+}
+
+// checkDependenciesLoaded verifies that every dependency of a module has
+// been loaded
+func (m *Manager) checkDependenciesLoaded(info *ModuleInfo) error {
+	for _, spec := range info.Dependencies {
+		dep, err := ParseDependency(spec)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDependencyInvalid, err)
+		}
+		if _, ok := m.modules[dep.Name]; !ok {
+			return fmt.Errorf("%w: %s requires %s", ErrDependencyMissing, info.Name, dep.Name)
+		}
+	}
+	return nil
+}
+
+// checkDependenciesEnabled verifies that every dependency of a module is
+// loaded and enabled
+func (m *Manager) checkDependenciesEnabled(info *ModuleInfo) error {
+	if err := m.checkDependenciesLoaded(info); err != nil {
+		return err
+	}
+	for _, spec := range info.Dependencies {
+		dep, _ := ParseDependency(spec)
+		if !m.modules[dep.Name].Enabled {
+			return fmt.Errorf("%w: %s requires %s", ErrDependencyDisabled, info.Name, dep.Name)
+		}
+	}
+	return nil
+}
+
 // loadModuleConfig loads configuration for a module
 func (m *Manager) loadModuleConfig(moduleName string) (map[string]string, error) {
 	configKey := fmt.Sprintf("module_config_%s", moduleName)
@@ -218,7 +385,7 @@ func (m *Manager) ExecuteAction(ctx context.Context, moduleName, action string,
 	// Find module
 	module, exists := m.modules[moduleName]
 	if !exists {
-		return nil, fmt.Errorf("module %s not found", moduleName)
+		return nil, fmt.Errorf("%w: %s", ErrModuleNotFound, moduleName)
 	}
 
 	// Check if module is enabled
@@ -239,7 +406,16 @@ func (m *Manager) ExecuteAction(ctx context.Context, moduleName, action string,
 	defer cancel()
 
 	// Execute action
-	result, err := module.Instance.ExecuteAction(actionCtx, action, parameters)
+	actionCtx, span := tracing.Start(actionCtx, "module.execute",
+		attribute.String("module.name", moduleName),
+		attribute.String("module.action", action),
+	)
+	start := time.Now()
+	result, err := runAction(actionCtx, module.Instance, moduleName, action, parameters)
+	tracing.End(span, err)
+	metrics.ModuleExecutionDuration.WithLabelValues(moduleName, action).Observe(time.Since(start).Seconds())
+	metrics.ModuleExecutions.WithLabelValues(moduleName, action, metrics.Result(err)).Inc()
+	m.health.record(moduleName, err)
 	if err != nil {
 		return nil, fmt.Errorf("action execution failed: %w", err)
 	}
@@ -250,6 +426,13 @@ func (m *Manager) ExecuteAction(ctx context.Context, moduleName, action string,
 	return result, nil
 }
 
+// runAction runs a module's action, turning a panic in the module into an
+// error so a faulty module cannot stop the bridge
+func runAction(ctx context.Context, module ModuleInterface, moduleName, action string, parameters map[string]string) (_ map[string]interface{}, err error) {
+	defer supervisor.Recover("module."+moduleName, &err)
+	return module.ExecuteAction(ctx, action, parameters)
+}
+
 // GetModule returns a module by name
 func (m *Manager) GetModule(name string) (*Module, bool) {
 	m.mutex.RLock()
@@ -291,6 +474,11 @@ func (m *Manager) EnableModule(name string) error {
 		return fmt.Errorf("module %s not found", name)
 	}
 
+	// Refuse to enable a module whose dependencies are disabled
+	if err := m.checkDependenciesEnabled(module.Info); err != nil {
+		return fmt.Errorf("cannot enable module %s: %w", name, err)
+	}
+
 	module.Enabled = true
 	module.Info.Enabled = true
 
@@ -341,6 +529,16 @@ func (m *Manager) ReloadModule(name string) error {
 		m.logger.WithError(err).WithField("module", name).Warn("Failed to cleanup module")
 	}
 
+	// Built-in modules have no plugin file, re-initialize them in place
+	if module.Plugin == nil {
+		if err := module.Instance.Initialize(module.Config); err != nil {
+			return fmt.Errorf("failed to reload module: %w", err)
+		}
+		module.LoadedAt = time.Now()
+		m.logger.WithField("module", name).Info("Module reloaded")
+		return nil
+	}
+
 	// Remove from maps
 	delete(m.modules, name)
 	delete(m.moduleInfos, name)
@@ -378,6 +576,7 @@ func (m *Manager) UnloadModule(name string) error {
 	// Remove from maps
 	delete(m.modules, name)
 	delete(m.moduleInfos, name)
+	m.health.forget(name)
 
 	m.logger.WithField("module", name).Info("Module unloaded")
 	return nil
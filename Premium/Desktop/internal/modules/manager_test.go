@@ -4,12 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"testing"
 	"time"
 
-	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
 	"waddlebot-bridge/internal/testutils"
 )
 
@@ -27,7 +25,7 @@ func TestNewManager(t *testing.T) {
 		t.Error("Expected config to be set")
 	}
 	
-	if manager.storage != storage {
+	if manager.storage.Storage() != storage {
 		t.Error("Expected storage to be set")
 	}
 	
@@ -488,7 +486,7 @@ func TestManager_LoadModuleConfig(t *testing.T) {
 	
 	// Save config to storage
 	configData, _ := json.Marshal(expectedConfig)
-	storage.Set(fmt.Sprintf("module_config_%s", moduleName), configData)
+	manager.storage.Set(fmt.Sprintf("module_config_%s", moduleName), configData)
 	
 	// Test loading config
 	config, err := manager.loadModuleConfig(moduleName)
@@ -523,7 +521,7 @@ func TestManager_SaveModuleInfo(t *testing.T) {
 	
 	// Verify info was saved
 	key := fmt.Sprintf("module_info_%s", info.Name)
-	data, err := storage.Get(key)
+	data, err := manager.storage.Get(key)
 	if err != nil {
 		t.Fatalf("Failed to get saved module info: %v", err)
 	}
@@ -624,3 +622,118 @@ func TestManager_ConcurrentExecution(t *testing.T) {
 	}
 }
 
+
+func TestManager_RegisterBuiltin(t *testing.T) {
+	cfg := testutils.TestConfig()
+	storage := testutils.NewMockStorage()
+	manager := NewManager(cfg, storage)
+
+	testModule := testutils.TestModule("builtin-module")
+	if err := manager.RegisterBuiltin(testModule); err != nil {
+		t.Fatalf("RegisterBuiltin failed: %v", err)
+	}
+
+	module, exists := manager.GetModule("builtin-module")
+	if !exists {
+		t.Fatal("Expected built-in module to be registered")
+	}
+	if !module.Enabled || module.Plugin != nil {
+		t.Error("Expected enabled built-in module without plugin")
+	}
+
+	// Registering the same name twice fails
+	if err := manager.RegisterBuiltin(testModule); err == nil {
+		t.Error("Expected error registering duplicate module")
+	}
+
+	// Built-in modules reload in place
+	if err := manager.ReloadModule("builtin-module"); err != nil {
+		t.Errorf("ReloadModule failed: %v", err)
+	}
+}
+
+func TestManager_GetModuleHealth(t *testing.T) {
+	cfg := testutils.TestConfig()
+	storage := testutils.NewMockStorage()
+	manager := NewManager(cfg, storage)
+
+	testModule := testutils.TestModule("test-module")
+	manager.modules["test-module"] = &Module{
+		Info:     testModule.GetInfo(),
+		Instance: testModule,
+		Config:   make(map[string]string),
+		Enabled:  true,
+		LoadedAt: time.Now(),
+	}
+	manager.moduleInfos["test-module"] = testModule.GetInfo()
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	manager.ExecuteAction(ctx, "test-module", "ping", map[string]string{})
+	manager.ExecuteAction(ctx, "test-module", "fail", map[string]string{})
+
+	health := manager.GetModuleHealth()
+	if len(health) != 1 {
+		t.Fatalf("Expected 1 module, got %d", len(health))
+	}
+	if health[0].Calls != 2 || health[0].Failures != 1 {
+		t.Errorf("Expected 2 calls and 1 failure, got %d and %d", health[0].Calls, health[0].Failures)
+	}
+	if health[0].Healthy {
+		t.Error("Expected module to be unhealthy after a failed action")
+	}
+	if health[0].LastError == "" || health[0].LastFailure == nil {
+		t.Error("Expected last failure to be recorded")
+	}
+
+	// A later success makes the module healthy again
+	manager.ExecuteAction(ctx, "test-module", "ping", map[string]string{})
+	if health := manager.GetModuleHealth(); !health[0].Healthy {
+		t.Error("Expected module to be healthy after a successful action")
+	}
+}
+
+// emittingModule is a test module that publishes events
+type emittingModule struct {
+	*testutils.MockModule
+	publisher events.Publisher
+}
+
+func (m *emittingModule) SetEventPublisher(publisher events.Publisher) {
+	m.publisher = publisher
+}
+
+// recordingPublisher records published events
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(event events.Event) {
+	p.events = append(p.events, event)
+}
+
+func TestManager_SetEventPublisher(t *testing.T) {
+	cfg := testutils.TestConfig()
+	storage := testutils.NewMockStorage()
+	manager := NewManager(cfg, storage)
+
+	publisher := &recordingPublisher{}
+	manager.SetEventPublisher(publisher)
+
+	module := &emittingModule{MockModule: testutils.TestModule("emitter")}
+	if err := manager.RegisterBuiltin(module); err != nil {
+		t.Fatalf("RegisterBuiltin failed: %v", err)
+	}
+	if module.publisher == nil {
+		t.Fatal("Expected module to receive an event publisher")
+	}
+
+	module.publisher.Publish(events.Event{Type: "emitter.fired"})
+	if len(publisher.events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(publisher.events))
+	}
+	if publisher.events[0].Source != "module:emitter" {
+		t.Errorf("Expected source module:emitter, got %s", publisher.events[0].Source)
+	}
+}
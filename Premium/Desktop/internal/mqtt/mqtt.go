@@ -0,0 +1,330 @@
+// Package mqtt bridges the bridge to an MQTT broker: events from the bus are
+// published under a topic prefix, and messages on command topics run
+// command palette commands, so home automation such as Home Assistant can
+// react to stream events and control OBS.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+)
+
+// Topics under the configured prefix. Events are published to
+// <prefix>/events/<type with dots as slashes>, command results to
+// <prefix>/results/<command topic> and the connection state, retained, to
+// <prefix>/status.
+const (
+	StatusTopic  = "status"
+	EventsTopic  = "events"
+	CommandTopic = "command"
+	ResultsTopic = "results"
+)
+
+// Connection states published to the status topic
+const (
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+)
+
+// commandTimeout bounds a command run from a command topic
+const commandTimeout = 2 * time.Minute
+
+var (
+	// ErrUnknownTopic is returned for messages on topics that are not
+	// command topics
+	ErrUnknownTopic = errors.New("unknown command topic")
+
+	// ErrCommandNotAllowed is returned for commands not matching the
+	// configured command patterns
+	ErrCommandNotAllowed = errors.New("command not allowed over MQTT")
+
+	// ErrNotConnected is returned when an event is delivered while the
+	// broker is unreachable
+	ErrNotConnected = errors.New("not connected to MQTT broker")
+)
+
+// Executor runs command palette commands
+type Executor interface {
+	Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error)
+}
+
+// topicCommand is the command run by a fixed command topic. A payload that
+// is not a JSON object is passed as the parameter named by param.
+type topicCommand struct {
+	id    string
+	param string
+}
+
+// obsTopics are command topics for common OBS actions, such as
+// <prefix>/obs/scene/set with the scene name as payload
+var obsTopics = map[string]topicCommand{
+	"obs/scene/set":             {id: "obs:set_scene", param: "scene"},
+	"obs/source/visibility/set": {id: "obs:set_source_visibility"},
+	"obs/filter/toggle":         {id: "obs:toggle_filter"},
+	"obs/stream/start":          {id: "obs:start_stream"},
+	"obs/stream/stop":           {id: "obs:stop_stream"},
+	"obs/stream/toggle":         {id: "obs:toggle_stream"},
+	"obs/recording/start":       {id: "obs:start_recording"},
+	"obs/recording/stop":        {id: "obs:stop_recording"},
+	"obs/recording/toggle":      {id: "obs:toggle_recording"},
+}
+
+// Result is published to the results topic after a command runs
+type Result struct {
+	Success bool                   `json:"success"`
+	Command string                 `json:"command,omitempty"`
+	Result  map[string]interface{} `json:"result,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// Client publishes events to an MQTT broker and runs commands received on
+// command topics. It is an event bus sink.
+type Client struct {
+	cfg      config.MQTTConfig
+	executor Executor
+	client   paho.Client
+	logger   *logrus.Logger
+}
+
+// New creates an MQTT client. The executor may be nil, in which case no
+// command topics are subscribed.
+func New(cfg config.MQTTConfig, executor Executor, logger *logrus.Logger) *Client {
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = "waddlebot"
+	}
+	cfg.TopicPrefix = strings.TrimSuffix(cfg.TopicPrefix, "/")
+	if cfg.QoS < 0 || cfg.QoS > 2 {
+		cfg.QoS = 0
+	}
+
+	return &Client{
+		cfg:      cfg,
+		executor: executor,
+		logger:   logger,
+	}
+}
+
+// Start connects to the broker in the background. The connection is retried
+// until it succeeds and re-established when lost; command topics are
+// subscribed on every connect.
+func (c *Client) Start() {
+	opts := paho.NewClientOptions().
+		AddBroker(c.cfg.Broker).
+		SetClientID(c.cfg.ClientID).
+		SetUsername(c.cfg.Username).
+		SetPassword(c.cfg.Password).
+		SetConnectTimeout(c.cfg.ConnectTimeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(c.topic(StatusTopic), StatusOffline, c.qos(), true).
+		SetOnConnectHandler(c.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			c.logger.WithError(err).Warn("Lost connection to MQTT broker")
+		})
+
+	c.client = paho.NewClient(opts)
+	token := c.client.Connect()
+	go func() {
+		if token.Wait(); token.Error() != nil {
+			c.logger.WithError(token.Error()).Error("Failed to connect to MQTT broker")
+		}
+	}()
+}
+
+// Stop marks the bridge offline and disconnects from the broker
+func (c *Client) Stop() {
+	if c.client == nil {
+		return
+	}
+	if c.client.IsConnectionOpen() {
+		c.client.Publish(c.topic(StatusTopic), c.qos(), true, StatusOffline).WaitTimeout(time.Second)
+	}
+	c.client.Disconnect(250)
+}
+
+// Name returns the sink name
+func (c *Client) Name() string {
+	return "mqtt"
+}
+
+// Deliver publishes an event as JSON to its event topic
+func (c *Client) Deliver(ctx context.Context, event events.Event) error {
+	if c.client == nil || !c.client.IsConnectionOpen() {
+		return ErrNotConnected
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	token := c.client.Publish(c.EventTopic(event.Type), c.qos(), c.cfg.Retain, payload)
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// EventTopic returns the topic events of a type are published to
+func (c *Client) EventTopic(eventType string) string {
+	name := strings.NewReplacer(".", "/", "+", "_", "#", "_").Replace(eventType)
+	return c.topic(EventsTopic + "/" + name)
+}
+
+// onConnect announces the bridge and subscribes the command topics
+func (c *Client) onConnect(client paho.Client) {
+	c.logger.WithField("broker", c.cfg.Broker).Info("Connected to MQTT broker")
+	client.Publish(c.topic(StatusTopic), c.qos(), true, StatusOnline)
+
+	if c.executor == nil || len(c.cfg.Commands) == 0 {
+		return
+	}
+	filters := map[string]byte{
+		c.topic("obs/#"):             c.qos(),
+		c.topic(CommandTopic + "/#"): c.qos(),
+	}
+	token := client.SubscribeMultiple(filters, c.handleMessage)
+	go func() {
+		if token.Wait(); token.Error() != nil {
+			c.logger.WithError(token.Error()).Error("Failed to subscribe to MQTT command topics")
+		}
+	}()
+}
+
+// handleMessage runs the command of a command topic message and publishes
+// its result. Commands run outside the client's message goroutine so a slow
+// command does not hold up others. Retained messages are ignored so a
+// reconnect does not replay an old command.
+func (c *Client) handleMessage(client paho.Client, msg paho.Message) {
+	if msg.Retained() {
+		return
+	}
+	topic := msg.Topic()
+	payload := msg.Payload()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+
+		resultTopic, result := c.runCommand(ctx, topic, payload)
+		data, err := json.Marshal(result)
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to marshal MQTT command result")
+			return
+		}
+		client.Publish(resultTopic, c.qos(), false, data)
+	}()
+}
+
+// runCommand runs the command a message on topic asks for and returns the
+// topic its result is published to
+func (c *Client) runCommand(ctx context.Context, topic string, payload []byte) (string, Result) {
+	suffix := strings.TrimPrefix(topic, c.cfg.TopicPrefix+"/")
+	resultTopic := c.topic(ResultsTopic + "/" + suffix)
+
+	id, parameters, err := c.parseCommand(suffix, payload)
+	if err != nil {
+		c.logger.WithFields(logrus.Fields{
+			"topic": topic,
+			"error": err,
+		}).Warn("Rejected MQTT command")
+		return resultTopic, Result{Command: id, Error: err.Error()}
+	}
+
+	result, err := c.executor.Execute(ctx, id, parameters)
+	if err != nil {
+		c.logger.WithFields(logrus.Fields{
+			"command": id,
+			"error":   err,
+		}).Warn("MQTT command failed")
+		return resultTopic, Result{Command: id, Error: err.Error()}
+	}
+
+	return resultTopic, Result{Success: true, Command: id, Result: result}
+}
+
+// parseCommand returns the command ID and parameters of a message on a
+// command topic relative to the prefix. Besides the fixed OBS topics,
+// command/<kind>/<name> runs the command <kind>:<name>, such as
+// command/module/soundboard/play for "module:soundboard/play".
+func (c *Client) parseCommand(suffix string, payload []byte) (string, map[string]string, error) {
+	command, ok := obsTopics[suffix]
+	if !ok {
+		rest, found := strings.CutPrefix(suffix, CommandTopic+"/")
+		kind, name, valid := strings.Cut(rest, "/")
+		if !found || !valid || kind == "" || name == "" {
+			return "", nil, fmt.Errorf("%w: %s", ErrUnknownTopic, suffix)
+		}
+		command = topicCommand{id: kind + ":" + name}
+	}
+
+	if !c.allowed(command.id) {
+		return command.id, nil, fmt.Errorf("%w: %s", ErrCommandNotAllowed, command.id)
+	}
+
+	parameters, err := parsePayload(payload, command.param)
+	if err != nil {
+		return command.id, nil, err
+	}
+	return command.id, parameters, nil
+}
+
+// allowed reports whether a command ID matches one of the command patterns
+func (c *Client) allowed(id string) bool {
+	for _, pattern := range c.cfg.Commands {
+		if matched, _ := path.Match(pattern, id); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePayload returns command parameters from a JSON object payload. Any
+// other payload is passed as param, when the topic has one.
+func parsePayload(payload []byte, param string) (map[string]string, error) {
+	text := strings.TrimSpace(string(payload))
+	parameters := map[string]string{}
+	if text == "" {
+		return parameters, nil
+	}
+
+	if strings.HasPrefix(text, "{") {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &values); err != nil {
+			return nil, fmt.Errorf("invalid JSON payload: %w", err)
+		}
+		for name, value := range values {
+			parameters[name] = fmt.Sprint(value)
+		}
+		return parameters, nil
+	}
+
+	if param == "" {
+		return nil, errors.New("payload must be a JSON object")
+	}
+	parameters[param] = text
+	return parameters, nil
+}
+
+// topic returns a topic under the prefix
+func (c *Client) topic(name string) string {
+	return c.cfg.TopicPrefix + "/" + name
+}
+
+// qos returns the configured quality of service level
+func (c *Client) qos() byte {
+	return byte(c.cfg.QoS)
+}
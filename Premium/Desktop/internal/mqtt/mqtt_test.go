@@ -0,0 +1,100 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+type fakeExecutor struct {
+	id         string
+	parameters map[string]string
+	err        error
+}
+
+func (f *fakeExecutor) Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error) {
+	f.id = id
+	f.parameters = parameters
+	if f.err != nil {
+		return nil, f.err
+	}
+	return map[string]interface{}{"ok": true}, nil
+}
+
+func newTestClient(executor Executor) *Client {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return New(config.MQTTConfig{
+		TopicPrefix: "home/waddlebot/",
+		Commands:    []string{"obs:*", "module:soundboard/*"},
+	}, executor, logger)
+}
+
+func TestEventTopic(t *testing.T) {
+	c := newTestClient(nil)
+
+	if topic := c.EventTopic("obs.scene_changed"); topic != "home/waddlebot/events/obs/scene_changed" {
+		t.Errorf("Unexpected event topic %s", topic)
+	}
+	if topic := c.EventTopic("custom.a+b#"); topic != "home/waddlebot/events/custom/a_b_" {
+		t.Errorf("Expected wildcards to be replaced, got %s", topic)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	executor := &fakeExecutor{}
+	c := newTestClient(executor)
+	ctx := context.Background()
+
+	topic, result := c.runCommand(ctx, "home/waddlebot/obs/scene/set", []byte("Starting Soon\n"))
+	if topic != "home/waddlebot/results/obs/scene/set" || !result.Success {
+		t.Fatalf("Unexpected result %s %+v", topic, result)
+	}
+	if executor.id != "obs:set_scene" || executor.parameters["scene"] != "Starting Soon" {
+		t.Errorf("Unexpected command %s %v", executor.id, executor.parameters)
+	}
+
+	_, result = c.runCommand(ctx, "home/waddlebot/command/module/soundboard/play", []byte(`{"sound":"airhorn","volume":50}`))
+	if !result.Success || executor.id != "module:soundboard/play" || executor.parameters["volume"] != "50" {
+		t.Errorf("Unexpected generic command %+v %s %v", result, executor.id, executor.parameters)
+	}
+
+	executor.err = errors.New("obs not connected")
+	if _, result = c.runCommand(ctx, "home/waddlebot/obs/stream/start", nil); result.Success || result.Error != "obs not connected" {
+		t.Errorf("Expected the command error, got %+v", result)
+	}
+}
+
+func TestRunCommand_Rejected(t *testing.T) {
+	executor := &fakeExecutor{}
+	c := newTestClient(executor)
+
+	for _, tt := range []struct {
+		topic   string
+		payload string
+		err     error
+	}{
+		{"home/waddlebot/obs/unknown", "", ErrUnknownTopic},
+		{"home/waddlebot/command/script", "", ErrUnknownTopic},
+		{"home/waddlebot/command/script/intro.lua", "", ErrCommandNotAllowed},
+		{"home/waddlebot/obs/source/visibility/set", "Camera", nil},
+		{"home/waddlebot/command/module/soundboard/play", "{broken", nil},
+	} {
+		executor.id = ""
+		_, result := c.runCommand(context.Background(), tt.topic, []byte(tt.payload))
+		if result.Success || executor.id != "" {
+			t.Errorf("Expected %s to be rejected", tt.topic)
+		}
+		if tt.err != nil {
+			_, _, err := c.parseCommand(tt.topic[len("home/waddlebot/"):], []byte(tt.payload))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v for %s, got %v", tt.err, tt.topic, err)
+			}
+		}
+	}
+}
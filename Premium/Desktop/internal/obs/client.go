@@ -7,27 +7,36 @@ import (
 	"time"
 
 	"github.com/andreykaipov/goobs"
+	"github.com/andreykaipov/goobs/api/events/subscriptions"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/metrics"
+	"waddlebot-bridge/internal/supervisor"
 )
 
 // Client manages the OBS WebSocket connection
 type Client struct {
 	config     Config
+	configMux  sync.RWMutex
 	client     *goobs.Client
 	logger     *logrus.Logger
 	state      ConnectionState
 	stateMux   sync.RWMutex
 	connInfo   ConnectionInfo
 	connInfoMux sync.RWMutex
+	history    *History // guarded by connInfoMux
 
 	// Event handling
 	eventCallbacks map[SubscriptionID]eventSubscription
 	callbackMux    sync.RWMutex
+	meter          *audioMeter
 
 	// Reconnection
 	reconnectChan chan struct{}
 	stopReconnect chan struct{}
+	suspended     bool // disconnected for sleep, reconnected on Wake
+	suspendMux    sync.Mutex
 
 	// Lifecycle
 	ctx    context.Context
@@ -48,12 +57,17 @@ func NewClient(cfg Config, logger *logrus.Logger) *Client {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	metrics.OBSConnectionState.WithLabelValues(StateDisconnected.String()).Set(1)
+
+	// Kept in memory until SetHistory gives one persisted in storage
+	history, _ := NewHistory(nil, 0, 0)
 
 	return &Client{
 		config:         cfg,
 		logger:         logger,
 		state:          StateDisconnected,
 		eventCallbacks: make(map[SubscriptionID]eventSubscription),
+		meter:          newAudioMeter(),
 		reconnectChan:  make(chan struct{}, 1),
 		stopReconnect:  make(chan struct{}),
 		ctx:            ctx,
@@ -61,34 +75,36 @@ func NewClient(cfg Config, logger *logrus.Logger) *Client {
 		connInfo: ConnectionInfo{
 			State: StateDisconnected,
 		},
+		history: history,
 	}
 }
 
 // Connect establishes a connection to OBS
 func (c *Client) Connect(ctx context.Context) error {
-	c.stateMux.Lock()
-	if c.state == StateConnected {
-		c.stateMux.Unlock()
+	if c.GetState() == StateConnected {
 		return nil
 	}
 	c.setState(StateConnecting)
-	c.stateMux.Unlock()
 
+	cfg := c.settings()
 	c.logger.WithFields(logrus.Fields{
-		"host": c.config.Host,
-		"port": c.config.Port,
+		"host": cfg.Host,
+		"port": cfg.Port,
 	}).Info("Connecting to OBS")
 
 	// Build connection options
-	addr := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
+	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	opts := []goobs.Option{}
 
-	if c.config.Password != "" {
-		opts = append(opts, goobs.WithPassword(c.config.Password))
+	if cfg.Password != "" {
+		opts = append(opts, goobs.WithPassword(cfg.Password))
+	}
+	if cfg.AudioMeters {
+		opts = append(opts, goobs.WithEventSubscriptions(subscriptions.All|subscriptions.InputVolumeMeters))
 	}
 
 	// Create connection with timeout
-	connectCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	connectCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
 	defer cancel()
 
 	// Channel to receive connection result
@@ -106,10 +122,12 @@ func (c *Client) Connect(ctx context.Context) error {
 	select {
 	case <-connectCtx.Done():
 		c.setStateAndError(StateDisconnected, "connection timeout")
+		c.recordHistory(ConnectionEvent{Type: HistoryFailed, Error: "connection timeout"})
 		return ErrTimeout
 	case result := <-resultCh:
 		if result.err != nil {
 			c.setStateAndError(StateDisconnected, result.err.Error())
+			c.recordHistory(ConnectionEvent{Type: HistoryFailed, Error: result.err.Error()})
 			return NewOBSError(ErrConnectionFailed, result.err.Error())
 		}
 		c.client = result.client
@@ -130,12 +148,20 @@ func (c *Client) Connect(ctx context.Context) error {
 	// Update connection state
 	now := time.Now()
 	c.connInfoMux.Lock()
+	attempts := c.connInfo.ReconnectAttempts
+	obsVersion := c.connInfo.OBSVersion
 	c.connInfo.ConnectedAt = &now
 	c.connInfo.DisconnectedAt = nil
 	c.connInfo.ReconnectAttempts = 0
 	c.connInfo.LastError = ""
 	c.connInfoMux.Unlock()
 
+	if attempts > 0 {
+		c.recordHistory(ConnectionEvent{Type: HistoryReconnected, Attempts: attempts, OBSVersion: obsVersion})
+	} else {
+		c.recordHistory(ConnectionEvent{Type: HistoryConnected, OBSVersion: obsVersion})
+	}
+
 	c.setState(StateConnected)
 	c.logger.WithFields(logrus.Fields{
 		"obs_version": c.connInfo.OBSVersion,
@@ -143,9 +169,12 @@ func (c *Client) Connect(ctx context.Context) error {
 	}).Info("Connected to OBS")
 
 	// Start event listener if auto-reconnect is enabled
-	if c.config.AutoReconnect {
+	if cfg.AutoReconnect {
 		c.wg.Add(1)
-		go c.monitorConnection()
+		go func() {
+			defer c.wg.Done()
+			supervisor.Run(c.ctx, "obs.monitor", supervisor.DefaultPolicy, c.monitorConnection)
+		}()
 	}
 
 	// Emit connected event
@@ -163,6 +192,12 @@ func (c *Client) Connect(ctx context.Context) error {
 
 // Disconnect closes the connection to OBS
 func (c *Client) Disconnect() error {
+	return c.disconnect("manual_disconnect")
+}
+
+// disconnect closes the connection to OBS, giving reason in the
+// disconnected event
+func (c *Client) disconnect(reason string) error {
 	c.stateMux.Lock()
 	if c.state == StateDisconnected {
 		c.stateMux.Unlock()
@@ -187,11 +222,9 @@ func (c *Client) Disconnect() error {
 	}
 
 	// Update state
-	now := time.Now()
-	c.connInfoMux.Lock()
-	c.connInfo.DisconnectedAt = &now
-	c.connInfoMux.Unlock()
+	c.recordHistory(ConnectionEvent{Type: HistoryDisconnected, Reason: reason, Uptime: c.markDisconnected()})
 
+	c.meter.reset()
 	c.setState(StateDisconnected)
 
 	// Emit disconnected event
@@ -199,13 +232,72 @@ func (c *Client) Disconnect() error {
 		Type:      EventType("disconnected"),
 		Timestamp: time.Now(),
 		Data: map[string]interface{}{
-			"reason": "manual_disconnect",
+			"reason": reason,
 		},
 	})
 
 	return nil
 }
 
+// Suspend disconnects from OBS before the computer sleeps, so the dead
+// connection is not found and retried after it wakes. Wake connects again.
+func (c *Client) Suspend() {
+	if c.GetState() == StateDisconnected {
+		return
+	}
+
+	c.suspendMux.Lock()
+	c.suspended = true
+	c.suspendMux.Unlock()
+
+	c.disconnect("sleep")
+}
+
+// Wake reconnects to OBS after the computer wakes, right away rather than
+// once the old connection times out or the next retry is due
+func (c *Client) Wake() {
+	c.suspendMux.Lock()
+	suspended := c.suspended
+	c.suspended = false
+	c.suspendMux.Unlock()
+
+	switch c.GetState() {
+	case StateReconnecting:
+		select {
+		case c.reconnectChan <- struct{}{}:
+		default:
+		}
+		return
+	case StateConnecting:
+		return
+	case StateConnected:
+		// The connection most likely died while the computer slept
+		c.disconnect("wake")
+	case StateDisconnected:
+		if !suspended {
+			return
+		}
+	}
+
+	c.logger.Info("Reconnecting to OBS after wake")
+	c.reconnect()
+}
+
+// reconnect connects again in the background, retrying with backoff when
+// auto-reconnect is enabled
+func (c *Client) reconnect() {
+	if c.settings().AutoReconnect {
+		c.setState(StateReconnecting)
+		go c.attemptReconnect()
+		return
+	}
+	go func() {
+		if err := c.Connect(c.ctx); err != nil {
+			c.logger.WithError(err).Warn("Failed to reconnect to OBS")
+		}
+	}()
+}
+
 // Close shuts down the client completely
 func (c *Client) Close() error {
 	c.cancel()
@@ -216,6 +308,37 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// Reconfigure applies new connection settings. If the address or password
+// changed while connected, the client reconnects with them.
+func (c *Client) Reconfigure(cfg Config) {
+	c.configMux.Lock()
+	old := c.config
+	c.config = cfg
+	c.configMux.Unlock()
+
+	if old.Host == cfg.Host && old.Port == cfg.Port && old.Password == cfg.Password {
+		return
+	}
+	if c.GetState() != StateConnected {
+		// The next connection attempt uses the new settings
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"host": cfg.Host,
+		"port": cfg.Port,
+	}).Info("OBS connection settings changed, reconnecting")
+	c.Disconnect()
+	c.reconnect()
+}
+
+// settings returns the current connection settings
+func (c *Client) settings() Config {
+	c.configMux.RLock()
+	defer c.configMux.RUnlock()
+	return c.config
+}
+
 // GetState returns the current connection state
 func (c *Client) GetState() ConnectionState {
 	c.stateMux.RLock()
@@ -286,6 +409,8 @@ func (c *Client) setState(state ConnectionState) {
 	c.connInfoMux.Unlock()
 
 	if oldState != state {
+		metrics.OBSConnectionState.WithLabelValues(oldState.String()).Set(0)
+		metrics.OBSConnectionState.WithLabelValues(state.String()).Set(1)
 		c.logger.WithFields(logrus.Fields{
 			"old_state": oldState.String(),
 			"new_state": state.String(),
@@ -302,25 +427,23 @@ func (c *Client) setStateAndError(state ConnectionState, errMsg string) {
 }
 
 // monitorConnection monitors the connection and triggers reconnection
-func (c *Client) monitorConnection() {
-	defer c.wg.Done()
-
+func (c *Client) monitorConnection(ctx context.Context) error {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-c.ctx.Done():
-			return
+		case <-ctx.Done():
+			return nil
 		case <-c.stopReconnect:
-			return
+			return nil
 		case <-ticker.C:
 			if c.GetState() == StateConnected && c.client != nil {
 				// Ping to check connection
 				_, err := c.client.General.GetVersion()
 				if err != nil {
 					c.logger.WithError(err).Warn("Connection lost, attempting reconnect")
-					c.handleDisconnect()
+					c.handleDisconnect(err)
 				}
 			}
 		}
@@ -328,12 +451,15 @@ func (c *Client) monitorConnection() {
 }
 
 // handleDisconnect handles unexpected disconnection
-func (c *Client) handleDisconnect() {
-	now := time.Now()
-	c.connInfoMux.Lock()
-	c.connInfo.DisconnectedAt = &now
-	c.connInfoMux.Unlock()
+func (c *Client) handleDisconnect(err error) {
+	c.recordHistory(ConnectionEvent{
+		Type:   HistoryDisconnected,
+		Reason: "connection_lost",
+		Error:  err.Error(),
+		Uptime: c.markDisconnected(),
+	})
 
+	c.meter.reset()
 	c.setState(StateReconnecting)
 
 	// Emit disconnected event
@@ -351,7 +477,7 @@ func (c *Client) handleDisconnect() {
 
 // attemptReconnect tries to reconnect with exponential backoff
 func (c *Client) attemptReconnect() {
-	interval := c.config.ReconnectInterval
+	interval := c.settings().ReconnectInterval
 	attempts := 0
 
 	for {
@@ -374,7 +500,7 @@ func (c *Client) attemptReconnect() {
 		}).Info("Attempting to reconnect to OBS")
 
 		// Try to connect
-		ctx, cancel := context.WithTimeout(c.ctx, c.config.Timeout)
+		ctx, cancel := context.WithTimeout(c.ctx, c.settings().Timeout)
 		err := c.Connect(ctx)
 		cancel()
 
@@ -392,23 +518,34 @@ func (c *Client) attemptReconnect() {
 
 		c.logger.WithError(err).WithField("attempt", attempts).Warn("Reconnection failed")
 
-		// Wait before next attempt with exponential backoff
+		// Wait before next attempt with exponential backoff, or retry
+		// right away when asked to
 		select {
 		case <-c.ctx.Done():
 			return
 		case <-c.stopReconnect:
 			return
+		case <-c.reconnectChan:
+			interval = c.settings().ReconnectInterval
+			continue
 		case <-time.After(interval):
 		}
 
 		// Exponential backoff
 		interval = interval * 2
-		if interval > c.config.MaxReconnectInterval {
-			interval = c.config.MaxReconnectInterval
+		if max := c.settings().MaxReconnectInterval; interval > max {
+			interval = max
 		}
 	}
 }
 
+// runCallback runs an event callback, recovering from a panic in it
+func runCallback(callback EventCallback, event Event) {
+	var err error
+	defer supervisor.Recover("obs.callback", &err)
+	callback(event)
+}
+
 // emitEvent sends an event to all registered callbacks
 func (c *Client) emitEvent(event Event) {
 	c.callbackMux.RLock()
@@ -417,11 +554,11 @@ func (c *Client) emitEvent(event Event) {
 	for _, sub := range c.eventCallbacks {
 		// Check if subscription is for all events or specific event types
 		if len(sub.eventTypes) == 0 {
-			go sub.callback(event)
+			go runCallback(sub.callback, event)
 		} else {
 			for _, et := range sub.eventTypes {
 				if et == event.Type {
-					go sub.callback(event)
+					go runCallback(sub.callback, event)
 					break
 				}
 			}
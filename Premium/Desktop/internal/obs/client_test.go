@@ -0,0 +1,70 @@
+package obs
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/metrics"
+)
+
+// stateGauges returns the connection state gauges as exposed at /metrics
+func stateGauges(t *testing.T) map[string]string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	gauges := make(map[string]string)
+	for _, line := range strings.Split(string(body), "\n") {
+		if rest, found := strings.CutPrefix(line, `waddlebot_bridge_obs_connection_state{state="`); found {
+			state, value, _ := strings.Cut(rest, `"} `)
+			gauges[state] = value
+		}
+	}
+	return gauges
+}
+
+func TestClient_ConnectionStateMetric(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	c := NewClient(DefaultConfig(), logger)
+
+	if gauges := stateGauges(t); gauges["disconnected"] != "1" {
+		t.Fatalf("Expected a new client to be disconnected, got %v", gauges)
+	}
+
+	steps := []ConnectionState{StateConnecting, StateConnected, StateReconnecting, StateConnected, StateDisconnected}
+	for _, state := range steps {
+		c.setState(state)
+		gauges := stateGauges(t)
+		for _, other := range []ConnectionState{StateDisconnected, StateConnecting, StateConnected, StateReconnecting} {
+			want := "0"
+			if other == state {
+				want = "1"
+			}
+			if value, ok := gauges[other.String()]; ok && value != want {
+				t.Errorf("After %s, expected %s to be %s, got %s", state, other, want, value)
+			}
+		}
+		if gauges[state.String()] != "1" {
+			t.Errorf("Expected %s to be 1, got %v", state, gauges)
+		}
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	missing := lookupError(ErrSourceNotFound, errors.New("request GetInputSettings: ResourceNotFound (600): No source was found by the name of `Guests`."))
+	if !errors.Is(missing, ErrSourceNotFound) {
+		t.Errorf("Expected a missing input to be not found, got %v", missing)
+	}
+
+	timeout := lookupError(ErrSourceNotFound, errors.New("request GetInputSettings: timeout waiting for response from server"))
+	if errors.Is(timeout, ErrSourceNotFound) || !errors.Is(timeout, ErrOperationFailed) {
+		t.Errorf("Expected a timeout to be a failure, got %v", timeout)
+	}
+}
@@ -142,6 +142,11 @@ func (c *Client) handleOBSEvent(event interface{}) {
 		ev.Data["state"] = e.OutputState
 		ev.Data["output_path"] = e.OutputPath
 
+	// Replay buffer events
+	case *events.ReplayBufferSaved:
+		ev.Type = EventReplayBufferSaved
+		ev.Data["output_path"] = e.SavedReplayPath
+
 	// General events
 	case *events.ExitStarted:
 		ev.Type = EventExiting
@@ -149,6 +154,11 @@ func (c *Client) handleOBSEvent(event interface{}) {
 		ev.Type = EventStudioModeChanged
 		ev.Data["enabled"] = e.StudioModeEnabled
 
+	// Audio events
+	case *events.InputVolumeMeters:
+		c.handleVolumeMeters(e.Inputs)
+		return
+
 	default:
 		// Unknown event type, skip
 		return
@@ -198,9 +208,17 @@ func GetAvailableEventTypes() []EventType {
 		EventRecordingPaused,
 		EventRecordingResumed,
 
+		// Replay buffer events
+		EventReplayBufferSaved,
+
 		// General events
 		EventExiting,
 		EventStudioModeChanged,
+
+		// Audio events
+		EventAudioLevels,
+		EventAudioSilent,
+		EventAudioResumed,
 	}
 }
 
@@ -255,6 +273,15 @@ func (c *Client) SubscribeStreamEvents(callback EventCallback) SubscriptionID {
 	)
 }
 
+// SubscribeAudioEvents subscribes to audio level and silence events
+func (c *Client) SubscribeAudioEvents(callback EventCallback) SubscriptionID {
+	return c.Subscribe(callback,
+		EventAudioLevels,
+		EventAudioSilent,
+		EventAudioResumed,
+	)
+}
+
 // SubscribeRecordingEvents subscribes to recording-related events
 func (c *Client) SubscribeRecordingEvents(callback EventCallback) SubscriptionID {
 	return c.Subscribe(callback,
@@ -0,0 +1,278 @@
+package obs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Connection history entry types
+const (
+	HistoryConnected    = "connected"
+	HistoryReconnected  = "reconnected"
+	HistoryDisconnected = "disconnected"
+	HistoryFailed       = "connect_failed"
+)
+
+// defaultHistorySize is how many entries a history keeps when no size is
+// given
+const defaultHistorySize = 1000
+
+// ConnectionEvent is an entry in the timeline of the OBS connection
+type ConnectionEvent struct {
+	Type string    `json:"type"`
+	At   time.Time `json:"at"`
+	// Reason is why the connection ended, for disconnected
+	Reason string `json:"reason,omitempty"`
+	// Error is why the connection or attempt failed
+	Error string `json:"error,omitempty"`
+	// Attempts is how many attempts reconnecting took, for reconnected
+	Attempts int `json:"attempts,omitempty"`
+	// Count is how many attempts in a row failed with Error, the last at
+	// LastAt, for connect_failed
+	Count  int        `json:"count,omitempty"`
+	LastAt *time.Time `json:"last_at,omitempty"`
+	// Uptime is how long the connection lasted, for disconnected
+	Uptime float64 `json:"uptime_seconds,omitempty"`
+	// OBSVersion is the version of OBS connected to
+	OBSVersion string `json:"obs_version,omitempty"`
+}
+
+// HistorySummary adds up a stretch of the timeline
+type HistorySummary struct {
+	Connects    int            `json:"connects"`
+	Disconnects int            `json:"disconnects"`
+	Failures    int            `json:"failed_attempts"`
+	Reasons     map[string]int `json:"disconnect_reasons"`
+	// Downtime is the time spent disconnected between a disconnect and the
+	// next connection, in seconds
+	Downtime float64 `json:"downtime_seconds"`
+	// LongestOutage is the longest of those stretches, in seconds
+	LongestOutage float64 `json:"longest_outage_seconds"`
+}
+
+// HistoryStore persists the history. It is satisfied by storage.Repo.
+type HistoryStore interface {
+	SetWithTTL(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+	All() (map[string][]byte, error)
+}
+
+// History is the timeline of connects, disconnects and failed attempts,
+// kept so a connection that keeps dropping can be looked into over days
+type History struct {
+	store     HistoryStore // nil keeps the history in memory
+	size      int
+	retention time.Duration // 0 keeps entries until size is reached
+
+	mu      sync.Mutex
+	entries []historyEntry // oldest first
+	seq     int
+}
+
+// historyEntry is a timeline entry and its storage key
+type historyEntry struct {
+	key   string
+	event ConnectionEvent
+}
+
+// NewHistory creates a history of at most size entries, each kept for
+// retention, and loads the entries persisted in store. store may be nil.
+func NewHistory(store HistoryStore, size int, retention time.Duration) (*History, error) {
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	h := &History{store: store, size: size, retention: retention}
+	if store == nil {
+		return h, nil
+	}
+
+	stored, err := store.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load connection history: %w", err)
+	}
+	for key, data := range stored {
+		var event ConnectionEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			store.Delete(key)
+			continue
+		}
+		h.entries = append(h.entries, historyEntry{key: key, event: event})
+	}
+	sort.Slice(h.entries, func(i, j int) bool { return h.entries[i].key < h.entries[j].key })
+	h.prune(time.Now())
+	return h, nil
+}
+
+// record adds an entry. A failed attempt with the same error as the entry
+// before it is counted in that entry, so a long outage is one entry.
+func (h *History) record(event ConnectionEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if event.Type == HistoryFailed && len(h.entries) > 0 {
+		last := &h.entries[len(h.entries)-1]
+		if last.event.Type == HistoryFailed && last.event.Error == event.Error {
+			last.event.Count++
+			at := event.At
+			last.event.LastAt = &at
+			h.persist(*last)
+			return
+		}
+	}
+	if event.Type == HistoryFailed {
+		event.Count = 1
+	}
+
+	// Keys sort in time order; the sequence number keeps entries recorded
+	// in the same instant apart
+	h.seq = (h.seq + 1) % 1000000
+	entry := historyEntry{
+		key:   fmt.Sprintf("%020d-%06d", event.At.UnixNano(), h.seq),
+		event: event,
+	}
+	h.entries = append(h.entries, entry)
+	h.persist(entry)
+	h.prune(event.At)
+}
+
+// persist stores an entry, if the history is persisted. The caller holds
+// h.mu.
+func (h *History) persist(entry historyEntry) {
+	if h.store == nil {
+		return
+	}
+	data, err := json.Marshal(entry.event)
+	if err != nil {
+		return
+	}
+	// A failed write loses one entry, which is not worth failing over
+	h.store.SetWithTTL(entry.key, data, h.retention)
+}
+
+// prune drops the entries past the size or retention. The caller holds
+// h.mu.
+func (h *History) prune(now time.Time) {
+	drop := 0
+	if len(h.entries) > h.size {
+		drop = len(h.entries) - h.size
+	}
+	if h.retention > 0 {
+		for drop < len(h.entries) && now.Sub(h.entries[drop].event.At) > h.retention {
+			drop++
+		}
+	}
+	if drop == 0 {
+		return
+	}
+
+	if h.store != nil {
+		for _, entry := range h.entries[:drop] {
+			h.store.Delete(entry.key)
+		}
+	}
+	h.entries = append([]historyEntry(nil), h.entries[drop:]...)
+}
+
+// Events returns the entries since a time, newest first, at most limit of
+// them when limit is positive
+func (h *History) Events(since time.Time, limit int) []ConnectionEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make([]ConnectionEvent, 0)
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(events) == limit {
+			break
+		}
+		event := h.entries[i].event
+		// A run of failures counts while any of it is in range
+		last := event.At
+		if event.LastAt != nil {
+			last = *event.LastAt
+		}
+		if last.Before(since) {
+			break
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// Summarize adds up a timeline given newest first, as Events returns it.
+// An outage still going on counts up to now.
+func Summarize(events []ConnectionEvent, now time.Time) HistorySummary {
+	summary := HistorySummary{Reasons: make(map[string]int)}
+
+	// Going from newest to oldest, reconnectedAt is when the connection came
+	// back after the disconnect at hand
+	var reconnectedAt *time.Time
+	for _, event := range events {
+		switch event.Type {
+		case HistoryConnected, HistoryReconnected:
+			summary.Connects++
+			at := event.At
+			reconnectedAt = &at
+		case HistoryFailed:
+			summary.Failures += event.Count
+		case HistoryDisconnected:
+			summary.Disconnects++
+			summary.Reasons[event.Reason]++
+
+			end := now
+			if reconnectedAt != nil {
+				end = *reconnectedAt
+			}
+			outage := end.Sub(event.At).Seconds()
+			summary.Downtime += outage
+			if outage > summary.LongestOutage {
+				summary.LongestOutage = outage
+			}
+			reconnectedAt = nil
+		}
+	}
+	return summary
+}
+
+// SetHistory replaces the client's connection history, such as with one
+// persisted in storage
+func (c *Client) SetHistory(history *History) {
+	c.connInfoMux.Lock()
+	defer c.connInfoMux.Unlock()
+	c.history = history
+}
+
+// ConnectionHistory returns the connection timeline since a time, newest
+// first, at most limit entries when limit is positive
+func (c *Client) ConnectionHistory(since time.Time, limit int) []ConnectionEvent {
+	c.connInfoMux.RLock()
+	history := c.history
+	c.connInfoMux.RUnlock()
+	return history.Events(since, limit)
+}
+
+// recordHistory adds an entry to the connection timeline
+func (c *Client) recordHistory(event ConnectionEvent) {
+	c.connInfoMux.RLock()
+	history := c.history
+	c.connInfoMux.RUnlock()
+
+	event.At = time.Now()
+	history.record(event)
+}
+
+// markDisconnected notes the time the connection ended and returns how long
+// it lasted in seconds
+func (c *Client) markDisconnected() float64 {
+	now := time.Now()
+	c.connInfoMux.Lock()
+	defer c.connInfoMux.Unlock()
+
+	c.connInfo.DisconnectedAt = &now
+	if c.connInfo.ConnectedAt == nil {
+		return 0
+	}
+	return now.Sub(*c.connInfo.ConnectedAt).Seconds()
+}
@@ -0,0 +1,110 @@
+package obs
+
+import (
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/testutils"
+)
+
+func TestHistory_CoalescesFailures(t *testing.T) {
+	history, _ := NewHistory(nil, 0, 0)
+	start := time.Now()
+
+	history.record(ConnectionEvent{Type: HistoryFailed, Error: "connection refused", At: start})
+	history.record(ConnectionEvent{Type: HistoryFailed, Error: "connection refused", At: start.Add(time.Second)})
+	history.record(ConnectionEvent{Type: HistoryFailed, Error: "connection refused", At: start.Add(2 * time.Second)})
+	history.record(ConnectionEvent{Type: HistoryFailed, Error: "connection timeout", At: start.Add(3 * time.Second)})
+
+	events := history.Events(time.Time{}, 0)
+	if len(events) != 2 {
+		t.Fatalf("Expected the failures to make 2 entries, got %+v", events)
+	}
+	if events[0].Error != "connection timeout" || events[0].Count != 1 {
+		t.Errorf("Expected the newest entry to be the timeout, got %+v", events[0])
+	}
+	if events[1].Count != 3 || events[1].LastAt == nil || !events[1].LastAt.Equal(start.Add(2*time.Second)) {
+		t.Errorf("Expected 3 refused attempts ending at the third, got %+v", events[1])
+	}
+}
+
+func TestHistory_Prune(t *testing.T) {
+	history, _ := NewHistory(nil, 3, time.Hour)
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+		history.record(ConnectionEvent{Type: HistoryConnected, At: start.Add(time.Duration(i) * time.Minute)})
+	}
+	if events := history.Events(time.Time{}, 0); len(events) != 3 || !events[2].At.Equal(start.Add(2*time.Minute)) {
+		t.Fatalf("Expected the 3 newest entries, got %+v", events)
+	}
+
+	// Entries older than the retention are dropped as new ones come in
+	history.record(ConnectionEvent{Type: HistoryDisconnected, At: start.Add(63*time.Minute + 30*time.Second)})
+	events := history.Events(time.Time{}, 0)
+	if len(events) != 2 || events[0].Type != HistoryDisconnected {
+		t.Fatalf("Expected the disconnect and the entry within the hour before it, got %+v", events)
+	}
+
+	if events := history.Events(start.Add(63*time.Minute), 0); len(events) != 1 {
+		t.Errorf("Expected 1 entry since the given time, got %+v", events)
+	}
+	if events := history.Events(time.Time{}, 1); len(events) != 1 || events[0].Type != HistoryDisconnected {
+		t.Errorf("Expected the limit to keep the newest entry, got %+v", events)
+	}
+}
+
+func TestHistory_Persisted(t *testing.T) {
+	store := testutils.NewMockStorage()
+	repo, err := storage.NewOBSHistoryRepo(store)
+	if err != nil {
+		t.Fatalf("Failed to open repo: %v", err)
+	}
+
+	history, err := NewHistory(repo, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to create history: %v", err)
+	}
+	start := time.Now()
+	history.record(ConnectionEvent{Type: HistoryConnected, At: start, OBSVersion: "30.1.0"})
+	history.record(ConnectionEvent{Type: HistoryDisconnected, Reason: "connection_lost", At: start.Add(time.Minute)})
+	history.record(ConnectionEvent{Type: HistoryReconnected, Attempts: 2, At: start.Add(2 * time.Minute)})
+
+	stored, _ := repo.All()
+	if len(stored) != 2 {
+		t.Errorf("Expected the pruned entry to be deleted from storage, got %d entries", len(stored))
+	}
+
+	reloaded, err := NewHistory(repo, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to reload history: %v", err)
+	}
+	events := reloaded.Events(time.Time{}, 0)
+	if len(events) != 2 || events[0].Type != HistoryReconnected || events[0].Attempts != 2 || events[1].Reason != "connection_lost" {
+		t.Fatalf("Expected the stored entries newest first, got %+v", events)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	start := time.Now()
+	events := []ConnectionEvent{
+		{Type: HistoryDisconnected, Reason: "manual_disconnect", At: start.Add(10 * time.Minute)},
+		{Type: HistoryReconnected, Attempts: 3, At: start.Add(5 * time.Minute)},
+		{Type: HistoryFailed, Error: "connection refused", Count: 3, At: start.Add(3 * time.Minute)},
+		{Type: HistoryDisconnected, Reason: "connection_lost", At: start.Add(2 * time.Minute)},
+		{Type: HistoryConnected, At: start},
+	}
+
+	summary := Summarize(events, start.Add(11*time.Minute))
+	if summary.Connects != 2 || summary.Disconnects != 2 || summary.Failures != 3 {
+		t.Errorf("Expected 2 connects, 2 disconnects and 3 failures, got %+v", summary)
+	}
+	if summary.Reasons["connection_lost"] != 1 || summary.Reasons["manual_disconnect"] != 1 {
+		t.Errorf("Expected a disconnect for each reason, got %v", summary.Reasons)
+	}
+	// 3 minutes before reconnecting, and 1 minute of the current outage
+	if summary.Downtime != 240 || summary.LongestOutage != 180 {
+		t.Errorf("Expected 240s down with the longest outage 180s, got %+v", summary)
+	}
+}
@@ -0,0 +1,78 @@
+package obs
+
+import (
+	"context"
+
+	"github.com/andreykaipov/goobs/api/requests/inputs"
+)
+
+// SetInputSettings updates the settings of an input, keeping those not given
+func (c *Client) SetInputSettings(ctx context.Context, inputName string, settings map[string]interface{}) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	overlay := true
+	_, err := c.client.Inputs.SetInputSettings(&inputs.SetInputSettingsParams{
+		InputName:     &inputName,
+		InputSettings: settings,
+		Overlay:       &overlay,
+	})
+	if err != nil {
+		return NewOBSError(ErrOperationFailed, err.Error())
+	}
+
+	c.logger.WithFields(map[string]interface{}{
+		"input":    inputName,
+		"settings": settings,
+	}).Debug("Updated input settings")
+
+	return nil
+}
+
+// SetInputText sets the text shown by a text input (GDI+ or FreeType 2)
+func (c *Client) SetInputText(ctx context.Context, inputName, text string) error {
+	return c.SetInputSettings(ctx, inputName, map[string]interface{}{"text": text})
+}
+
+// GetInputSettings returns the kind and settings of an input
+func (c *Client) GetInputSettings(ctx context.Context, inputName string) (string, map[string]interface{}, error) {
+	if !c.IsConnected() {
+		return "", nil, ErrNotConnected
+	}
+
+	resp, err := c.client.Inputs.GetInputSettings(&inputs.GetInputSettingsParams{
+		InputName: &inputName,
+	})
+	if err != nil {
+		return "", nil, lookupError(ErrSourceNotFound, err)
+	}
+	return resp.InputKind, resp.InputSettings, nil
+}
+
+// CreateInput creates an input of the given kind and adds it to a scene
+func (c *Client) CreateInput(ctx context.Context, sceneName, inputName, inputKind string, settings map[string]interface{}) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	enabled := true
+	_, err := c.client.Inputs.CreateInput(&inputs.CreateInputParams{
+		SceneName:        &sceneName,
+		InputName:        &inputName,
+		InputKind:        &inputKind,
+		InputSettings:    settings,
+		SceneItemEnabled: &enabled,
+	})
+	if err != nil {
+		return NewOBSError(ErrOperationFailed, err.Error())
+	}
+
+	c.logger.WithFields(map[string]interface{}{
+		"scene": sceneName,
+		"input": inputName,
+		"kind":  inputKind,
+	}).Info("Created input")
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package obs
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andreykaipov/goobs/api/typedefs"
+)
+
+const (
+	// minLevel is the level reported for silence, in dB
+	minLevel = -100.0
+
+	// meterRelease is how fast a smoothed level falls, in dB per second.
+	// Levels rise at once, like a peak meter.
+	meterRelease = 20.0
+
+	// peakHold is how long a peak is held before it falls
+	peakHold = 1500 * time.Millisecond
+)
+
+// AudioLevel is the smoothed volume of an OBS input
+type AudioLevel struct {
+	// Input is the name of the input
+	Input string `json:"input"`
+	// Level is the smoothed level of the loudest channel, in dB
+	Level float64 `json:"level_db"`
+	// Peak is the held peak of the loudest channel, in dB
+	Peak float64 `json:"peak_db"`
+	// Silent indicates the input has been below the silence threshold for
+	// the silence delay
+	Silent bool `json:"silent"`
+}
+
+// inputMeter tracks the levels of one input
+type inputMeter struct {
+	level      float64
+	peak       float64
+	peakAt     time.Time
+	updated    time.Time
+	quietSince time.Time // zero while the input is above the threshold
+	silent     bool
+}
+
+// audioMeter smooths the volume meters OBS sends every 50ms, reports them
+// at the configured interval and notices inputs that go silent
+type audioMeter struct {
+	mu       sync.Mutex
+	inputs   map[string]*inputMeter
+	lastEmit time.Time
+}
+
+func newAudioMeter() *audioMeter {
+	return &audioMeter{inputs: make(map[string]*inputMeter)}
+}
+
+// toDB converts an amplitude multiplier to dB
+func toDB(mul float64) float64 {
+	if mul <= 0 {
+		return minLevel
+	}
+	return math.Max(20*math.Log10(mul), minLevel)
+}
+
+// loudest returns the highest magnitude and peak across an input's
+// channels, in dB
+func loudest(channels [][3]float64) (level, peak float64) {
+	level, peak = minLevel, minLevel
+	for _, channel := range channels {
+		level = math.Max(level, toDB(channel[0]))
+		peak = math.Max(peak, toDB(channel[1]))
+	}
+	return level, peak
+}
+
+// update applies a volume meter reading. It returns the events to emit:
+// silence changes, and the levels of every input once interval has passed
+// since they were last reported.
+func (m *audioMeter) update(inputs []*typedefs.InputVolumeMeter, cfg Config, now time.Time) []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var emitted []Event
+	seen := make(map[string]bool, len(inputs))
+	for _, input := range inputs {
+		if input == nil {
+			continue
+		}
+		seen[input.Name] = true
+		level, peak := loudest(input.Levels)
+
+		meter, exists := m.inputs[input.Name]
+		if !exists {
+			meter = &inputMeter{level: level, peak: peak, peakAt: now}
+			m.inputs[input.Name] = meter
+		} else {
+			elapsed := now.Sub(meter.updated).Seconds()
+			meter.level = math.Max(level, meter.level-meterRelease*elapsed)
+			if peak >= meter.peak {
+				meter.peak, meter.peakAt = peak, now
+			} else if now.Sub(meter.peakAt) > peakHold {
+				meter.peak = math.Max(peak, meter.peak-meterRelease*elapsed)
+			}
+		}
+		meter.updated = now
+
+		if event, changed := meter.checkSilence(input.Name, level, cfg, now); changed {
+			emitted = append(emitted, event)
+		}
+	}
+
+	// OBS only reports active inputs; one that is gone is neither loud nor
+	// silent
+	for name := range m.inputs {
+		if !seen[name] {
+			delete(m.inputs, name)
+		}
+	}
+
+	if now.Sub(m.lastEmit) >= cfg.AudioMeterInterval {
+		m.lastEmit = now
+		emitted = append(emitted, Event{
+			Type:      EventAudioLevels,
+			Timestamp: now,
+			Data: map[string]interface{}{
+				"inputs": m.levels(),
+			},
+		})
+	}
+	return emitted
+}
+
+// checkSilence tracks how long an input has been below the silence
+// threshold, returning an event when it goes silent or sounds again
+func (meter *inputMeter) checkSilence(name string, level float64, cfg Config, now time.Time) (Event, bool) {
+	if level >= cfg.SilenceThreshold {
+		meter.quietSince = time.Time{}
+		if !meter.silent {
+			return Event{}, false
+		}
+		meter.silent = false
+		return Event{
+			Type:      EventAudioResumed,
+			Timestamp: now,
+			Data: map[string]interface{}{
+				"input_name": name,
+				"level_db":   level,
+			},
+		}, true
+	}
+
+	if meter.quietSince.IsZero() {
+		meter.quietSince = now
+	}
+	if meter.silent || now.Sub(meter.quietSince) < cfg.SilenceDelay {
+		return Event{}, false
+	}
+	meter.silent = true
+	return Event{
+		Type:      EventAudioSilent,
+		Timestamp: now,
+		Data: map[string]interface{}{
+			"input_name": name,
+			"silent_for": now.Sub(meter.quietSince).Seconds(),
+		},
+	}, true
+}
+
+// levels returns the levels of every input, sorted by name. The caller
+// holds m.mu.
+func (m *audioMeter) levels() []AudioLevel {
+	levels := make([]AudioLevel, 0, len(m.inputs))
+	for name, meter := range m.inputs {
+		levels = append(levels, AudioLevel{
+			Input:  name,
+			Level:  math.Round(meter.level*10) / 10,
+			Peak:   math.Round(meter.peak*10) / 10,
+			Silent: meter.silent,
+		})
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Input < levels[j].Input })
+	return levels
+}
+
+// reset forgets every input, when the connection to OBS is lost
+func (m *audioMeter) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = make(map[string]*inputMeter)
+}
+
+// quietSince returns when an input went below the silence threshold, and
+// whether it is below it
+func (m *audioMeter) quietSince(name string) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meter, exists := m.inputs[name]
+	if !exists || meter.quietSince.IsZero() {
+		return time.Time{}, false
+	}
+	return meter.quietSince, true
+}
+
+// handleVolumeMeters applies a volume meter reading from OBS
+func (c *Client) handleVolumeMeters(inputs []*typedefs.InputVolumeMeter) {
+	for _, event := range c.meter.update(inputs, c.settings(), time.Now()) {
+		c.emitEvent(event)
+	}
+}
+
+// AudioLevels returns the smoothed levels of the active inputs. It is
+// empty unless audio meters are enabled.
+func (c *Client) AudioLevels() []AudioLevel {
+	c.meter.mu.Lock()
+	defer c.meter.mu.Unlock()
+	return c.meter.levels()
+}
+
+// QuietSince returns when an input went below the silence threshold, and
+// false when it is above it or not active. It needs audio meters enabled.
+func (c *Client) QuietSince(input string) (time.Time, bool) {
+	return c.meter.quietSince(input)
+}
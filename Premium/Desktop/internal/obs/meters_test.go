@@ -0,0 +1,81 @@
+package obs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreykaipov/goobs/api/typedefs"
+)
+
+func reading(name string, mul float64) []*typedefs.InputVolumeMeter {
+	return []*typedefs.InputVolumeMeter{{Name: name, Levels: [][3]float64{{mul, mul, mul}, {mul / 2, mul / 2, mul / 2}}}}
+}
+
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func TestAudioMeter_Levels(t *testing.T) {
+	meter := newAudioMeter()
+	cfg := DefaultConfig()
+	start := time.Now()
+
+	events := meter.update(reading("Mic", 0.5), cfg, start)
+	if len(events) != 1 || events[0].Type != EventAudioLevels {
+		t.Fatalf("Expected the first reading to report levels, got %v", eventTypes(events))
+	}
+	levels := events[0].Data["inputs"].([]AudioLevel)
+	if len(levels) != 1 || levels[0].Level != -6 {
+		t.Fatalf("Expected Mic at -6 dB from its loudest channel, got %+v", levels)
+	}
+
+	// Within the interval nothing is reported, and the level falls slowly
+	if events := meter.update(reading("Mic", 0), cfg, start.Add(50*time.Millisecond)); len(events) != 0 {
+		t.Errorf("Expected no report within the interval, got %v", eventTypes(events))
+	}
+	events = meter.update(reading("Mic", 0), cfg, start.Add(100*time.Millisecond))
+	levels = events[0].Data["inputs"].([]AudioLevel)
+	if levels[0].Level != -8 || levels[0].Peak != -6 {
+		t.Errorf("Expected the level to fall to -8 dB and the peak to hold, got %+v", levels[0])
+	}
+
+	// Inputs OBS stops reporting are forgotten
+	meter.update(reading("Desktop", 1), cfg, start.Add(200*time.Millisecond))
+	if _, exists := meter.inputs["Mic"]; exists {
+		t.Error("Expected an inactive input to be forgotten")
+	}
+}
+
+func TestAudioMeter_Silence(t *testing.T) {
+	meter := newAudioMeter()
+	cfg := DefaultConfig()
+	start := time.Now()
+
+	meter.update(reading("Mic", 0), cfg, start)
+	if since, quiet := meter.quietSince("Mic"); !quiet || !since.Equal(start) {
+		t.Errorf("Expected Mic quiet since the first reading, got %v %v", since, quiet)
+	}
+	if events := meter.update(reading("Mic", 0), cfg, start.Add(cfg.SilenceDelay/2)); len(events) != 1 {
+		t.Errorf("Expected no silence before the delay, got %v", eventTypes(events))
+	}
+
+	events := meter.update(reading("Mic", 0), cfg, start.Add(cfg.SilenceDelay))
+	if len(events) != 2 || events[0].Type != EventAudioSilent || events[0].Data["input_name"] != "Mic" {
+		t.Fatalf("Expected Mic to go silent after the delay, got %v", eventTypes(events))
+	}
+	if events := meter.update(reading("Mic", 0), cfg, start.Add(2*cfg.SilenceDelay)); len(events) != 1 {
+		t.Errorf("Expected silence to be reported once, got %v", eventTypes(events))
+	}
+
+	events = meter.update(reading("Mic", 0.1), cfg, start.Add(3*cfg.SilenceDelay))
+	if len(events) != 2 || events[0].Type != EventAudioResumed {
+		t.Fatalf("Expected Mic to resume, got %v", eventTypes(events))
+	}
+	if _, quiet := meter.quietSince("Mic"); quiet {
+		t.Error("Expected Mic not to be quiet after it resumed")
+	}
+}
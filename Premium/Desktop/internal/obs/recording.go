@@ -189,3 +189,34 @@ func (c *Client) GetRecordDirectory(ctx context.Context) (string, error) {
 
 	return resp.RecordDirectory, nil
 }
+
+// SaveReplay saves the replay buffer and returns the path of the saved
+// file, once OBS has written it
+func (c *Client) SaveReplay(ctx context.Context) (string, error) {
+	if !c.IsConnected() {
+		return "", ErrNotConnected
+	}
+
+	// Subscribe first, so the event cannot arrive before it is awaited
+	saved := make(chan string, 1)
+	id := c.Subscribe(func(event Event) {
+		path, _ := event.Data["output_path"].(string)
+		select {
+		case saved <- path:
+		default:
+		}
+	}, EventReplayBufferSaved)
+	defer c.Unsubscribe(id)
+
+	if _, err := c.client.Outputs.SaveReplayBuffer(); err != nil {
+		return "", NewOBSError(ErrOperationFailed, err.Error())
+	}
+
+	select {
+	case path := <-saved:
+		c.logger.WithField("output_path", path).Info("Saved replay buffer")
+		return path, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
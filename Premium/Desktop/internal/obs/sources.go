@@ -266,7 +266,7 @@ func (c *Client) getSceneItemID(sceneName, sourceName string) (int, error) {
 		SourceName: &sourceName,
 	})
 	if err != nil {
-		return 0, NewOBSError(ErrSourceNotFound, err.Error())
+		return 0, lookupError(ErrSourceNotFound, err)
 	}
 	return resp.SceneItemId, nil
 }
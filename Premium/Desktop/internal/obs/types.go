@@ -3,6 +3,7 @@
 package obs
 
 import (
+	"strings"
 	"time"
 )
 
@@ -54,6 +55,15 @@ type Config struct {
 	Timeout time.Duration `mapstructure:"obs-timeout"`
 	// Enabled controls whether OBS integration is active
 	Enabled bool `mapstructure:"obs-enabled"`
+	// AudioMeters subscribes to the input volume meters, a high-volume
+	// event OBS sends every 50ms
+	AudioMeters bool `mapstructure:"obs-audio-meters"`
+	// AudioMeterInterval is how often audio levels are reported
+	AudioMeterInterval time.Duration `mapstructure:"obs-audio-meter-interval"`
+	// SilenceThreshold is the level in dB below which an input is quiet
+	SilenceThreshold float64 `mapstructure:"obs-silence-threshold"`
+	// SilenceDelay is how long an input must be quiet to be reported silent
+	SilenceDelay time.Duration `mapstructure:"obs-silence-delay"`
 }
 
 // DefaultConfig returns the default OBS configuration
@@ -67,6 +77,9 @@ func DefaultConfig() Config {
 		MaxReconnectInterval: 30 * time.Second,
 		Timeout:              10 * time.Second,
 		Enabled:              true,
+		AudioMeterInterval:   100 * time.Millisecond,
+		SilenceThreshold:     -60,
+		SilenceDelay:         10 * time.Second,
 	}
 }
 
@@ -261,9 +274,17 @@ const (
 	EventRecordingPaused   EventType = "recording_paused"
 	EventRecordingResumed  EventType = "recording_resumed"
 
+	// Replay buffer events
+	EventReplayBufferSaved EventType = "replay_buffer_saved"
+
 	// General events
 	EventExiting         EventType = "exiting"
 	EventStudioModeChanged EventType = "studio_mode_changed"
+
+	// Audio events, sent when audio meters are enabled
+	EventAudioLevels  EventType = "audio_levels"
+	EventAudioSilent  EventType = "audio_silent"
+	EventAudioResumed EventType = "audio_resumed"
 )
 
 // Event represents an OBS event
@@ -332,6 +353,13 @@ func (e *OBSError) Error() string {
 	return e.Code + ": " + e.Message
 }
 
+// Is matches errors with the same code, so an error made with NewOBSError
+// matches the one it was made from
+func (e *OBSError) Is(target error) bool {
+	t, ok := target.(*OBSError)
+	return ok && t.Code == e.Code
+}
+
 // NewOBSError creates a new OBS error with details
 func NewOBSError(base *OBSError, details string) *OBSError {
 	return &OBSError{
@@ -340,3 +368,14 @@ func NewOBSError(base *OBSError, details string) *OBSError {
 		Details: details,
 	}
 }
+
+// lookupError wraps the error of a request looking something up as
+// notFound when OBS has no such resource, and as ErrOperationFailed when
+// the request failed otherwise, such as by timing out. goobs only reports
+// OBS's request status in the message.
+func lookupError(notFound *OBSError, err error) *OBSError {
+	if strings.Contains(err.Error(), "ResourceNotFound") {
+		return NewOBSError(notFound, err.Error())
+	}
+	return NewOBSError(ErrOperationFailed, err.Error())
+}
@@ -0,0 +1,224 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// bundleTag starts an OSC bundle
+const bundleTag = "#bundle"
+
+// ErrMalformed is returned for packets that are not valid OSC
+var ErrMalformed = errors.New("malformed OSC packet")
+
+// Message is an OSC message. Arguments are int32, int64, float32, float64,
+// string, []byte (blob), bool or nil.
+type Message struct {
+	Address string
+	Args    []interface{}
+}
+
+// MarshalBinary encodes the message as an OSC packet
+func (m Message) MarshalBinary() ([]byte, error) {
+	if !strings.HasPrefix(m.Address, "/") {
+		return nil, fmt.Errorf("%w: address %q must start with /", ErrMalformed, m.Address)
+	}
+
+	var buf bytes.Buffer
+	writeString(&buf, m.Address)
+
+	tags := []byte{','}
+	var data bytes.Buffer
+	for _, arg := range m.Args {
+		switch v := arg.(type) {
+		case int32:
+			tags = append(tags, 'i')
+			binary.Write(&data, binary.BigEndian, v)
+		case int:
+			tags = append(tags, 'i')
+			binary.Write(&data, binary.BigEndian, int32(v))
+		case int64:
+			tags = append(tags, 'h')
+			binary.Write(&data, binary.BigEndian, v)
+		case float32:
+			tags = append(tags, 'f')
+			binary.Write(&data, binary.BigEndian, math.Float32bits(v))
+		case float64:
+			tags = append(tags, 'd')
+			binary.Write(&data, binary.BigEndian, math.Float64bits(v))
+		case string:
+			tags = append(tags, 's')
+			writeString(&data, v)
+		case []byte:
+			tags = append(tags, 'b')
+			binary.Write(&data, binary.BigEndian, int32(len(v)))
+			data.Write(v)
+			data.Write(make([]byte, padding(len(v))))
+		case bool:
+			if v {
+				tags = append(tags, 'T')
+			} else {
+				tags = append(tags, 'F')
+			}
+		case nil:
+			tags = append(tags, 'N')
+		default:
+			return nil, fmt.Errorf("unsupported OSC argument type %T", arg)
+		}
+	}
+
+	writeString(&buf, string(tags))
+	buf.Write(data.Bytes())
+	return buf.Bytes(), nil
+}
+
+// Parse decodes an OSC packet into its messages. A bundle yields the
+// messages it contains, in order; time tags are ignored.
+func Parse(packet []byte) ([]Message, error) {
+	if bytes.HasPrefix(packet, []byte(bundleTag+"\x00")) {
+		return parseBundle(packet)
+	}
+	msg, err := parseMessage(packet)
+	if err != nil {
+		return nil, err
+	}
+	return []Message{msg}, nil
+}
+
+// parseBundle decodes the elements of a bundle
+func parseBundle(packet []byte) ([]Message, error) {
+	// "#bundle\0" and an 8 byte time tag
+	if len(packet) < 16 {
+		return nil, fmt.Errorf("%w: short bundle", ErrMalformed)
+	}
+	rest := packet[16:]
+
+	var messages []Message
+	for len(rest) > 0 {
+		if len(rest) < 4 {
+			return nil, fmt.Errorf("%w: truncated bundle element", ErrMalformed)
+		}
+		size := int(int32(binary.BigEndian.Uint32(rest)))
+		rest = rest[4:]
+		if size < 0 || size > len(rest) {
+			return nil, fmt.Errorf("%w: bundle element size %d", ErrMalformed, size)
+		}
+		elements, err := Parse(rest[:size])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, elements...)
+		rest = rest[size:]
+	}
+	return messages, nil
+}
+
+// parseMessage decodes a single message
+func parseMessage(packet []byte) (Message, error) {
+	address, rest, err := readString(packet)
+	if err != nil {
+		return Message{}, err
+	}
+	if !strings.HasPrefix(address, "/") {
+		return Message{}, fmt.Errorf("%w: address %q", ErrMalformed, address)
+	}
+
+	msg := Message{Address: address}
+	if len(rest) == 0 {
+		// Old implementations omit the type tags of messages without arguments
+		return msg, nil
+	}
+
+	tags, rest, err := readString(rest)
+	if err != nil {
+		return Message{}, err
+	}
+	if !strings.HasPrefix(tags, ",") {
+		return Message{}, fmt.Errorf("%w: type tags %q", ErrMalformed, tags)
+	}
+
+	for _, tag := range tags[1:] {
+		var arg interface{}
+		switch tag {
+		case 'i':
+			if len(rest) < 4 {
+				return Message{}, fmt.Errorf("%w: truncated int32", ErrMalformed)
+			}
+			arg = int32(binary.BigEndian.Uint32(rest))
+			rest = rest[4:]
+		case 'f':
+			if len(rest) < 4 {
+				return Message{}, fmt.Errorf("%w: truncated float32", ErrMalformed)
+			}
+			arg = math.Float32frombits(binary.BigEndian.Uint32(rest))
+			rest = rest[4:]
+		case 'h':
+			if len(rest) < 8 {
+				return Message{}, fmt.Errorf("%w: truncated int64", ErrMalformed)
+			}
+			arg = int64(binary.BigEndian.Uint64(rest))
+			rest = rest[8:]
+		case 'd':
+			if len(rest) < 8 {
+				return Message{}, fmt.Errorf("%w: truncated float64", ErrMalformed)
+			}
+			arg = math.Float64frombits(binary.BigEndian.Uint64(rest))
+			rest = rest[8:]
+		case 's', 'S':
+			var s string
+			if s, rest, err = readString(rest); err != nil {
+				return Message{}, err
+			}
+			arg = s
+		case 'b':
+			if len(rest) < 4 {
+				return Message{}, fmt.Errorf("%w: truncated blob", ErrMalformed)
+			}
+			size := int(int32(binary.BigEndian.Uint32(rest)))
+			rest = rest[4:]
+			if size < 0 || size+padding(size) > len(rest) {
+				return Message{}, fmt.Errorf("%w: blob size %d", ErrMalformed, size)
+			}
+			arg = append([]byte(nil), rest[:size]...)
+			rest = rest[size+padding(size):]
+		case 'T':
+			arg = true
+		case 'F':
+			arg = false
+		case 'N', 'I':
+			arg = nil
+		default:
+			return Message{}, fmt.Errorf("%w: unsupported type tag %q", ErrMalformed, tag)
+		}
+		msg.Args = append(msg.Args, arg)
+	}
+	return msg, nil
+}
+
+// writeString writes a null terminated string padded to a multiple of 4
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	buf.Write(make([]byte, 4-len(s)%4))
+}
+
+// readString reads a padded OSC string and returns it with the rest of data
+func readString(data []byte) (string, []byte, error) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("%w: unterminated string", ErrMalformed)
+	}
+	next := end + 4 - end%4
+	if next > len(data) {
+		return "", nil, fmt.Errorf("%w: unpadded string", ErrMalformed)
+	}
+	return string(data[:end]), data[next:], nil
+}
+
+// padding returns the bytes needed to pad n bytes to a multiple of 4
+func padding(n int) int {
+	return (4 - n%4) % 4
+}
@@ -0,0 +1,279 @@
+// Package osc speaks Open Sound Control over UDP, so audio and lighting
+// consoles such as a Behringer X32 or a lighting desk can run bridge
+// commands and react to stream state. Received messages run the command
+// palette commands of matching mappings; bus events are sent to consoles as
+// configured by the outputs.
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+)
+
+// commandTimeout bounds a command run from an OSC message
+const commandTimeout = 2 * time.Minute
+
+// maxPacketSize is the largest UDP datagram read
+const maxPacketSize = 65535
+
+// ErrNotStarted is returned when an event is delivered before Start
+var ErrNotStarted = errors.New("OSC server not started")
+
+// Executor runs command palette commands
+type Executor interface {
+	Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error)
+}
+
+// Server receives OSC messages and sends events as OSC messages. It is an
+// event bus sink.
+type Server struct {
+	cfg      config.OSCConfig
+	executor Executor
+	logger   *logrus.Logger
+
+	conn   *net.UDPConn
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates an OSC server
+func New(cfg config.OSCConfig, executor Executor, logger *logrus.Logger) *Server {
+	return &Server{
+		cfg:      cfg,
+		executor: executor,
+		logger:   logger,
+	}
+}
+
+// Start opens the UDP socket and, when a listen address is set, starts
+// handling received messages. Outputs are sent from the same socket, so
+// consoles that reply to the sender's port reach the bridge.
+func (s *Server) Start() error {
+	var addr *net.UDPAddr
+	if s.cfg.Listen != "" {
+		var err error
+		if addr, err = net.ResolveUDPAddr("udp", s.cfg.Listen); err != nil {
+			return fmt.Errorf("invalid OSC listen address: %w", err)
+		}
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to open OSC socket: %w", err)
+	}
+	s.conn = conn
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	if s.cfg.Listen != "" {
+		s.wg.Add(1)
+		go s.serve()
+		s.logger.WithField("address", conn.LocalAddr().String()).Info("Listening for OSC messages")
+	}
+	return nil
+}
+
+// Stop closes the socket and waits for running commands
+func (s *Server) Stop() error {
+	if s.conn == nil {
+		return nil
+	}
+	s.cancel()
+	err := s.conn.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Addr returns the address the server receives messages on
+func (s *Server) Addr() net.Addr {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+// Name returns the sink name
+func (s *Server) Name() string {
+	return "osc"
+}
+
+// Deliver sends the OSC messages of every output matching the event
+func (s *Server) Deliver(ctx context.Context, event events.Event) error {
+	if s.conn == nil {
+		return ErrNotStarted
+	}
+
+	var firstErr error
+	for _, output := range s.cfg.Outputs {
+		if matched, _ := path.Match(output.Event, event.Type); !matched {
+			continue
+		}
+		if err := s.Send(output.Target, outputMessage(output, event)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Send sends a message to target, a host:port address
+func (s *Server) Send(target string, msg Message) error {
+	if s.conn == nil {
+		return ErrNotStarted
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		return fmt.Errorf("invalid OSC target %s: %w", target, err)
+	}
+	packet, err := msg.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if _, err := s.conn.WriteToUDP(packet, addr); err != nil {
+		return fmt.Errorf("failed to send OSC message to %s: %w", target, err)
+	}
+	return nil
+}
+
+// serve reads packets until the socket is closed
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.logger.WithError(err).Error("Failed to read OSC packet")
+			}
+			return
+		}
+
+		messages, err := Parse(buf[:n])
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"from":  from.String(),
+				"error": err,
+			}).Debug("Ignored invalid OSC packet")
+			continue
+		}
+		for _, msg := range messages {
+			s.wg.Add(1)
+			go func(msg Message) {
+				defer s.wg.Done()
+				s.dispatch(msg)
+			}(msg)
+		}
+	}
+}
+
+// dispatch runs the command of every mapping matching the message
+func (s *Server) dispatch(msg Message) {
+	if s.executor == nil {
+		return
+	}
+
+	for _, mapping := range s.cfg.Mappings {
+		if matched, _ := path.Match(mapping.Address, msg.Address); !matched {
+			continue
+		}
+		if mapping.IgnoreZero && len(msg.Args) > 0 && isZero(msg.Args[0]) {
+			continue
+		}
+
+		parameters := make(map[string]string, len(mapping.Parameters))
+		for name, value := range mapping.Parameters {
+			parameters[name] = fillArguments(value, msg)
+		}
+
+		ctx, cancel := context.WithTimeout(s.ctx, commandTimeout)
+		_, err := s.executor.Execute(ctx, mapping.Command, parameters)
+		cancel()
+
+		fields := logrus.Fields{
+			"address": msg.Address,
+			"command": mapping.Command,
+		}
+		if err != nil {
+			fields["error"] = err
+			s.logger.WithFields(fields).Warn("OSC command failed")
+		} else {
+			s.logger.WithFields(fields).Debug("OSC command executed")
+		}
+	}
+}
+
+// outputMessage builds the message an output sends for an event
+func outputMessage(output config.OSCOutputConfig, event events.Event) Message {
+	pairs := []string{"{type}", event.Type, "{source}", event.Source}
+	for name, value := range event.Data {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	msg := Message{Address: replacer.Replace(output.Address)}
+	for _, arg := range output.Args {
+		msg.Args = append(msg.Args, typedArgument(replacer.Replace(arg)))
+	}
+	return msg
+}
+
+// typedArgument sends integers as int32, other numbers as float32 and
+// true/false as booleans; anything else is a string
+func typedArgument(value string) interface{} {
+	if i, err := strconv.ParseInt(value, 10, 32); err == nil {
+		return int32(i)
+	}
+	if f, err := strconv.ParseFloat(value, 32); err == nil {
+		return float32(f)
+	}
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+	return value
+}
+
+// fillArguments replaces {n} with the nth argument of msg and {address}
+// with its address
+func fillArguments(value string, msg Message) string {
+	if !strings.Contains(value, "{") {
+		return value
+	}
+	pairs := []string{"{address}", msg.Address}
+	for i, arg := range msg.Args {
+		pairs = append(pairs, "{"+strconv.Itoa(i)+"}", fmt.Sprint(arg))
+	}
+	return strings.NewReplacer(pairs...).Replace(value)
+}
+
+// isZero reports whether an argument is a zero number, false or nil
+func isZero(arg interface{}) bool {
+	switch v := arg.(type) {
+	case int32:
+		return v == 0
+	case int64:
+		return v == 0
+	case float32:
+		return v == 0
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case nil:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,172 @@
+package osc
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+)
+
+type execution struct {
+	id         string
+	parameters map[string]string
+}
+
+type fakeExecutor chan execution
+
+func (f fakeExecutor) Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error) {
+	f <- execution{id: id, parameters: parameters}
+	return nil, nil
+}
+
+func TestMessage_RoundTrip(t *testing.T) {
+	msg := Message{
+		Address: "/ch/01/mix/on",
+		Args:    []interface{}{int32(1), float32(0.75), "Main", []byte{1, 2, 3}, true, false, nil, int64(-5), 2.5},
+	}
+	packet, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	if len(packet)%4 != 0 {
+		t.Errorf("Expected a packet padded to 4 bytes, got %d", len(packet))
+	}
+
+	parsed, err := Parse(packet)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(parsed) != 1 || !reflect.DeepEqual(parsed[0], msg) {
+		t.Errorf("Expected %+v, got %+v", msg, parsed)
+	}
+
+	for _, broken := range [][]byte{packet[:len(packet)-3], []byte("/no-terminator"), []byte("#bundle\x00\x00")} {
+		if _, err := Parse(broken); err == nil {
+			t.Errorf("Expected an error for %q", broken)
+		}
+	}
+}
+
+func TestParse_Bundle(t *testing.T) {
+	first, _ := Message{Address: "/a", Args: []interface{}{int32(1)}}.MarshalBinary()
+	second, _ := Message{Address: "/b"}.MarshalBinary()
+
+	bundle := append([]byte("#bundle\x00"), make([]byte, 8)...)
+	for _, element := range [][]byte{first, second} {
+		bundle = append(bundle, 0, 0, 0, byte(len(element)))
+		bundle = append(bundle, element...)
+	}
+
+	messages, err := Parse(bundle)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Address != "/a" || messages[1].Address != "/b" {
+		t.Errorf("Unexpected bundle messages %+v", messages)
+	}
+}
+
+func TestServer_Mappings(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	executed := make(fakeExecutor, 4)
+	s := New(config.OSCConfig{
+		Listen: "127.0.0.1:0",
+		Mappings: []config.OSCMappingConfig{
+			{Address: "/waddlebot/scene", Command: "obs:set_scene", Parameters: map[string]string{"scene": "{0}"}},
+			{Address: "/x32/button/*", Command: "macro:go_live", IgnoreZero: true},
+		},
+	}, executed, logger)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Stop()
+
+	console, err := net.DialUDP("udp", nil, s.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer console.Close()
+
+	send := func(msg Message) {
+		packet, _ := msg.MarshalBinary()
+		console.Write(packet)
+	}
+	send(Message{Address: "/x32/button/5", Args: []interface{}{float32(0)}})
+	send(Message{Address: "/waddlebot/scene", Args: []interface{}{"Gameplay"}})
+	send(Message{Address: "/x32/button/5", Args: []interface{}{float32(1)}})
+
+	seen := map[string]map[string]string{}
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-executed:
+			seen[e.id] = e.parameters
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for commands")
+		}
+	}
+	if seen["obs:set_scene"]["scene"] != "Gameplay" {
+		t.Errorf("Expected the scene from the first argument, got %v", seen)
+	}
+	if _, ok := seen["macro:go_live"]; !ok {
+		t.Errorf("Expected the button press to run the macro, got %v", seen)
+	}
+	select {
+	case e := <-executed:
+		t.Errorf("Expected the button release to be ignored, got %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestServer_Outputs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	console, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer console.Close()
+
+	s := New(config.OSCConfig{
+		Outputs: []config.OSCOutputConfig{
+			{Event: "obs.StreamStateChanged", Target: console.LocalAddr().String(), Address: "/waddlebot/live", Args: []string{"{outputActive}", "{outputState}"}},
+			{Event: "overlay.*", Target: console.LocalAddr().String(), Address: "/unused"},
+		},
+	}, nil, logger)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Stop()
+
+	err = s.Deliver(context.Background(), events.Event{
+		Type: "obs.StreamStateChanged",
+		Data: map[string]interface{}{"outputActive": true, "outputState": "OBS_WEBSOCKET_OUTPUT_STARTED"},
+	})
+	if err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	console.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := console.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("Expected an OSC message: %v", err)
+	}
+	messages, err := Parse(buf[:n])
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := Message{Address: "/waddlebot/live", Args: []interface{}{true, "OBS_WEBSOCKET_OUTPUT_STARTED"}}
+	if !reflect.DeepEqual(messages[0], want) {
+		t.Errorf("Expected %+v, got %+v", want, messages[0])
+	}
+}
@@ -0,0 +1,385 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/storage"
+)
+
+// BucketName is the storage bucket holding queued items
+const BucketName = "outbox"
+
+// Item kinds
+const (
+	KindResult    = "result"
+	KindHeartbeat = "heartbeat"
+	KindEvent     = "event"
+)
+
+const (
+	defaultMaxItems      = 1000
+	defaultMaxAge        = 24 * time.Hour
+	defaultRetryInterval = 30 * time.Second
+)
+
+var (
+	// ErrItemNotFound is returned when a queued item does not exist
+	ErrItemNotFound = errors.New("outbox item not found")
+)
+
+// Sender delivers a queued payload to an API path
+type Sender interface {
+	PostJSON(ctx context.Context, path string, payload []byte) error
+}
+
+// Item is an outbound message waiting for delivery
+type Item struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Key         string          `json:"key"`
+	Path        string          `json:"path"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// Stats summarizes the queue
+type Stats struct {
+	Total    int            `json:"total"`
+	ByKind   map[string]int `json:"by_kind"`
+	Oldest   *time.Time     `json:"oldest,omitempty"`
+	Dropped  int            `json:"dropped"`
+	Sent     int            `json:"sent"`
+	MaxItems int            `json:"max_items"`
+	MaxAge   string         `json:"max_age"`
+}
+
+// Outbox is a durable queue of outbound messages delivered at least once.
+// Items survive restarts, are retried until delivered and are dropped,
+// oldest first, once the queue exceeds its size or age cap.
+type Outbox struct {
+	store   storage.Storage
+	bucket  string
+	config  config.OutboxConfig
+	logger  *logrus.Logger
+	mu      sync.Mutex
+	dropped int
+	sent    int
+}
+
+// New creates an outbox backed by the given storage
+func New(store storage.Storage, cfg config.OutboxConfig, logger *logrus.Logger) *Outbox {
+	if cfg.MaxItems <= 0 {
+		cfg.MaxItems = defaultMaxItems
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = defaultMaxAge
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+
+	return &Outbox{
+		store:  store,
+		bucket: BucketName,
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// NewForCommunity creates an outbox for an additional community, kept in
+// its own bucket so its items are delivered with that community's
+// credentials
+func NewForCommunity(store storage.Storage, communityID string, cfg config.OutboxConfig, logger *logrus.Logger) (*Outbox, error) {
+	o := New(store, cfg, logger)
+	o.bucket = BucketName + ":" + communityID
+	if err := store.EnsureBucket(o.bucket); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Enqueue stores a payload for delivery to path. Enqueueing an item with
+// the same kind and key replaces the earlier item. The first delivery
+// attempt by Flush happens after the retry interval, giving the caller time
+// to deliver the item itself and Remove it.
+func (o *Outbox) Enqueue(kind, key, path string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	now := time.Now()
+	item := Item{
+		ID:          itemID(kind, key),
+		Kind:        kind,
+		Key:         key,
+		Path:        path,
+		Payload:     data,
+		CreatedAt:   now,
+		NextAttempt: now.Add(o.config.RetryInterval),
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.put(item); err != nil {
+		return err
+	}
+
+	return o.enforceCaps(now)
+}
+
+// Remove deletes the item with the given kind and key, e.g. once the
+// server has acknowledged it
+func (o *Outbox) Remove(kind, key string) error {
+	return o.Delete(itemID(kind, key))
+}
+
+// Delete deletes the item with the given ID
+func (o *Outbox) Delete(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, err := o.store.GetWithBucket(o.bucket, id); err != nil {
+		return ErrItemNotFound
+	}
+	return o.store.DeleteWithBucket(o.bucket, id)
+}
+
+// List returns queued items, oldest first
+func (o *Outbox) List() ([]Item, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.items()
+}
+
+// Purge deletes all queued items of a kind, or every item when kind is
+// empty, and returns the number deleted
+func (o *Outbox) Purge(kind string) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	items, err := o.items()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, item := range items {
+		if kind != "" && item.Kind != kind {
+			continue
+		}
+		if err := o.store.DeleteWithBucket(o.bucket, item.ID); err != nil {
+			return purged, fmt.Errorf("failed to delete outbox item: %w", err)
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		o.logger.WithFields(logrus.Fields{
+			"kind":   kind,
+			"purged": purged,
+		}).Info("Purged outbox items")
+	}
+
+	return purged, nil
+}
+
+// Flush delivers due items in order. It stops at the first failed delivery,
+// since a failure usually means the API is unreachable, and returns the
+// number of items delivered.
+func (o *Outbox) Flush(ctx context.Context, sender Sender) (int, error) {
+	o.mu.Lock()
+	now := time.Now()
+	if err := o.enforceCaps(now); err != nil {
+		o.mu.Unlock()
+		return 0, err
+	}
+	items, err := o.items()
+	o.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		if item.NextAttempt.After(now) {
+			continue
+		}
+
+		if err := sender.PostJSON(ctx, item.Path, item.Payload); err != nil {
+			o.recordFailure(item, err)
+			return delivered, fmt.Errorf("failed to deliver outbox item %s: %w", item.ID, err)
+		}
+
+		o.mu.Lock()
+		o.store.DeleteWithBucket(o.bucket, item.ID)
+		o.sent++
+		o.mu.Unlock()
+		delivered++
+	}
+
+	if delivered > 0 {
+		o.logger.WithField("delivered", delivered).Info("Delivered queued outbox items")
+	}
+
+	return delivered, nil
+}
+
+// Run flushes the outbox every retry interval until ctx is cancelled
+func (o *Outbox) Run(ctx context.Context, sender Sender) {
+	ticker := time.NewTicker(o.config.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := o.Flush(ctx, sender); err != nil && ctx.Err() == nil {
+				o.logger.WithError(err).Debug("Outbox flush incomplete")
+			}
+		}
+	}
+}
+
+// Stats returns a summary of the queue
+func (o *Outbox) Stats() (Stats, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := Stats{
+		ByKind:   make(map[string]int),
+		Dropped:  o.dropped,
+		Sent:     o.sent,
+		MaxItems: o.config.MaxItems,
+		MaxAge:   o.config.MaxAge.String(),
+	}
+
+	items, err := o.items()
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Total = len(items)
+	for _, item := range items {
+		stats.ByKind[item.Kind]++
+	}
+	if len(items) > 0 {
+		oldest := items[0].CreatedAt
+		stats.Oldest = &oldest
+	}
+
+	return stats, nil
+}
+
+// recordFailure schedules the next attempt for an item
+func (o *Outbox) recordFailure(item Item, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// The item may have been removed while it was being delivered
+	if _, getErr := o.store.GetWithBucket(o.bucket, item.ID); getErr != nil {
+		return
+	}
+
+	item.Attempts++
+	item.LastError = err.Error()
+	item.NextAttempt = time.Now().Add(o.config.RetryInterval)
+	if putErr := o.put(item); putErr != nil {
+		o.logger.WithError(putErr).WithField("id", item.ID).Warn("Failed to update outbox item")
+	}
+}
+
+// enforceCaps drops items older than the age cap and the oldest items
+// beyond the size cap; the caller must hold the lock
+func (o *Outbox) enforceCaps(now time.Time) error {
+	items, err := o.items()
+	if err != nil {
+		return err
+	}
+
+	excess := len(items) - o.config.MaxItems
+	for i, item := range items {
+		expired := now.Sub(item.CreatedAt) > o.config.MaxAge
+		if !expired && i >= excess {
+			break
+		}
+
+		if err := o.store.DeleteWithBucket(o.bucket, item.ID); err != nil {
+			return fmt.Errorf("failed to drop outbox item: %w", err)
+		}
+		o.dropped++
+
+		o.logger.WithFields(logrus.Fields{
+			"id":      item.ID,
+			"expired": expired,
+		}).Warn("Dropped undelivered outbox item")
+	}
+
+	return nil
+}
+
+// items loads all items sorted by creation time; the caller must hold the lock
+func (o *Outbox) items() ([]Item, error) {
+	data, err := o.store.GetAllFromBucket(o.bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	items := make([]Item, 0, len(data))
+	for id, raw := range data {
+		var item Item
+		if err := json.Unmarshal(raw, &item); err != nil {
+			o.logger.WithError(err).WithField("id", id).Warn("Discarding corrupt outbox item")
+			o.store.DeleteWithBucket(o.bucket, id)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].ID < items[j].ID
+		}
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+
+	return items, nil
+}
+
+// put writes an item; the caller must hold the lock. Items dropped by
+// the age cap are normally removed by enforceCaps; the storage TTL, one
+// retry interval later, removes those of outboxes no longer in use.
+func (o *Outbox) put(item Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox item: %w", err)
+	}
+	ttl := max(time.Until(item.CreatedAt.Add(o.config.MaxAge)), 0) + o.config.RetryInterval
+	if err := o.store.SetWithBucketTTL(o.bucket, item.ID, data, ttl); err != nil {
+		return fmt.Errorf("failed to store outbox item: %w", err)
+	}
+	return nil
+}
+
+// itemID builds the storage key for an item
+func itemID(kind, key string) string {
+	return kind + ":" + strings.ReplaceAll(key, ":", "_")
+}
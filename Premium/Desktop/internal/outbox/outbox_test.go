@@ -0,0 +1,231 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/testutils"
+)
+
+type mockSender struct {
+	mu    sync.Mutex
+	fail  bool
+	paths []string
+}
+
+func (s *mockSender) PostJSON(ctx context.Context, path string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("network unreachable")
+	}
+	s.paths = append(s.paths, path)
+	return nil
+}
+
+func newTestOutbox(cfg config.OutboxConfig) *Outbox {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return New(testutils.NewMockStorage(), cfg, logger)
+}
+
+// makeDue marks every queued item as due for delivery
+func makeDue(t *testing.T, o *Outbox) {
+	t.Helper()
+	items, err := o.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, item := range items {
+		item.NextAttempt = time.Time{}
+		if err := o.put(item); err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+}
+
+func TestOutbox_EnqueueAndList(t *testing.T) {
+	o := newTestOutbox(config.OutboxConfig{})
+
+	if err := o.Enqueue(KindResult, "task-1", "/api/bridge/response", map[string]string{"id": "task-1"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := o.Enqueue(KindHeartbeat, "hb-1", "/api/bridge/heartbeat", map[string]string{"status": "active"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	// Re-enqueueing the same key replaces the item
+	if err := o.Enqueue(KindResult, "task-1", "/api/bridge/response", map[string]string{"id": "task-1", "v": "2"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	items, err := o.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(items))
+	}
+	if items[0].Kind != KindHeartbeat || items[1].Kind != KindResult {
+		t.Errorf("Expected items oldest first, got %s then %s", items[0].Kind, items[1].Kind)
+	}
+
+	stats, err := o.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Total != 2 || stats.ByKind[KindResult] != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestOutbox_SizeCap(t *testing.T) {
+	o := newTestOutbox(config.OutboxConfig{MaxItems: 2})
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := o.Enqueue(KindEvent, key, "/api/bridge/events", key); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	items, _ := o.List()
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(items))
+	}
+	if items[0].Key != "b" || items[1].Key != "c" {
+		t.Errorf("Expected oldest item to be dropped, got %s, %s", items[0].Key, items[1].Key)
+	}
+
+	stats, _ := o.Stats()
+	if stats.Dropped != 1 {
+		t.Errorf("Expected 1 dropped item, got %d", stats.Dropped)
+	}
+}
+
+func TestOutbox_AgeCap(t *testing.T) {
+	o := newTestOutbox(config.OutboxConfig{MaxAge: 50 * time.Millisecond})
+
+	o.Enqueue(KindEvent, "old", "/api/bridge/events", "old")
+	time.Sleep(100 * time.Millisecond)
+	o.Enqueue(KindEvent, "new", "/api/bridge/events", "new")
+
+	items, _ := o.List()
+	if len(items) != 1 || items[0].Key != "new" {
+		t.Errorf("Expected only the new item to remain, got %+v", items)
+	}
+}
+
+func TestOutbox_Flush(t *testing.T) {
+	o := newTestOutbox(config.OutboxConfig{})
+	sender := &mockSender{fail: true}
+
+	o.Enqueue(KindResult, "task-1", "/api/bridge/response", "r1")
+	o.Enqueue(KindHeartbeat, "hb-1", "/api/bridge/heartbeat", "h1")
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	// Items are not due until the retry interval has passed
+	delivered, err := o.Flush(ctx, sender)
+	if err != nil || delivered != 0 {
+		t.Fatalf("Expected nothing to be delivered yet, got %d, %v", delivered, err)
+	}
+
+	makeDue(t, o)
+
+	// Failed deliveries keep the items and record the attempt
+	if _, err := o.Flush(ctx, sender); err == nil {
+		t.Fatal("Expected flush error while offline")
+	}
+	items, _ := o.List()
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items to remain, got %d", len(items))
+	}
+	if items[0].Attempts != 1 || items[0].LastError == "" {
+		t.Errorf("Expected failed attempt to be recorded, got %+v", items[0])
+	}
+
+	makeDue(t, o)
+	sender.fail = false
+
+	delivered, err = o.Flush(ctx, sender)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if delivered != 2 {
+		t.Errorf("Expected 2 items delivered, got %d", delivered)
+	}
+	if items, _ := o.List(); len(items) != 0 {
+		t.Errorf("Expected empty outbox, got %d items", len(items))
+	}
+}
+
+func TestOutbox_PurgeAndDelete(t *testing.T) {
+	o := newTestOutbox(config.OutboxConfig{})
+
+	o.Enqueue(KindResult, "task-1", "/api/bridge/response", "r1")
+	o.Enqueue(KindHeartbeat, "hb-1", "/api/bridge/heartbeat", "h1")
+	o.Enqueue(KindHeartbeat, "hb-2", "/api/bridge/heartbeat", "h2")
+
+	purged, err := o.Purge(KindHeartbeat)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("Expected 2 purged, got %d", purged)
+	}
+
+	if err := o.Remove(KindResult, "task-1"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := o.Remove(KindResult, "task-1"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("Expected ErrItemNotFound, got %v", err)
+	}
+}
+
+func TestOutbox_NewForCommunity_Separate(t *testing.T) {
+	store, err := storage.NewBoltStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	primary := New(store, config.OutboxConfig{}, logger)
+	second, err := NewForCommunity(store, "second", config.OutboxConfig{}, logger)
+	if err != nil {
+		t.Fatalf("NewForCommunity failed: %v", err)
+	}
+
+	if err := second.Enqueue(KindResult, "task-1", "/api/bridge/response", "result"); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	items, err := primary.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("Expected primary outbox to be empty, got %d items", len(items))
+	}
+
+	items, err = second.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Errorf("Expected 1 item in community outbox, got %d", len(items))
+	}
+}
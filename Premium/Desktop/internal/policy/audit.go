@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"waddlebot-bridge/internal/storage"
+)
+
+// AuditBucket is the storage bucket holding policy denials
+const AuditBucket = "policy_audit"
+
+const defaultMaxAuditEntries = 1000
+
+// AuditEntry records a task denied by the policy
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Request Request   `json:"request"`
+	Reason  string    `json:"reason"`
+}
+
+// AuditLog persists policy denials, keeping the most recent entries
+type AuditLog struct {
+	store      storage.Storage
+	maxEntries int
+	mu         sync.Mutex
+	lastKey    int64
+}
+
+// NewAuditLog creates an audit log backed by the given storage
+func NewAuditLog(store storage.Storage, maxEntries int) *AuditLog {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxAuditEntries
+	}
+
+	return &AuditLog{
+		store:      store,
+		maxEntries: maxEntries,
+	}
+}
+
+// Record appends an entry, dropping the oldest entries beyond the limit
+func (a *AuditLog) Record(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Keys sort chronologically; bump the key if two entries share a timestamp
+	key := entry.Time.UnixNano()
+	if key <= a.lastKey {
+		key = a.lastKey + 1
+	}
+	a.lastKey = key
+
+	if err := a.store.SetWithBucket(AuditBucket, fmt.Sprintf("%020d", key), data); err != nil {
+		return fmt.Errorf("failed to store audit entry: %w", err)
+	}
+
+	keys, err := a.keys()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(keys)-a.maxEntries; i++ {
+		a.store.DeleteWithBucket(AuditBucket, keys[i])
+	}
+
+	return nil
+}
+
+// Entries returns up to limit entries, newest first; limit <= 0 returns all
+func (a *AuditLog) Entries(limit int) ([]AuditEntry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	data, err := a.store.GetAllFromBucket(AuditBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	entries := make([]AuditEntry, 0, len(keys))
+	for _, key := range keys {
+		var entry AuditEntry
+		if err := json.Unmarshal(data[key], &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// keys returns the stored keys in chronological order; the caller must
+// hold the lock
+func (a *AuditLog) keys() ([]string, error) {
+	data, err := a.store.GetAllFromBucket(AuditBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
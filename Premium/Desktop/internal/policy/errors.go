@@ -0,0 +1,11 @@
+package policy
+
+import "errors"
+
+var (
+	// ErrDenied is returned when a task is rejected by the policy
+	ErrDenied = errors.New("denied by policy")
+
+	// ErrInvalidPolicy is returned when a policy cannot be loaded
+	ErrInvalidPolicy = errors.New("invalid policy")
+)
@@ -0,0 +1,273 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/storage"
+)
+
+// Task categories the policy distinguishes
+const (
+	CategoryModule = "module"
+	CategoryOBS    = "obs"
+	CategoryScript = "script"
+)
+
+// Default verdicts
+const (
+	DefaultAllow = "allow"
+	DefaultDeny  = "deny"
+)
+
+// Request describes a remote task to be checked against the policy
+type Request struct {
+	TaskID      string `json:"task_id"`
+	CommunityID string `json:"community_id"`
+	UserID      string `json:"user_id"`
+	Category    string `json:"category"`
+	Module      string `json:"module,omitempty"`
+	Action      string `json:"action"`
+}
+
+// Decision is the outcome of a policy evaluation
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// verdict is the result of checking a task against one rule list
+type verdict int
+
+const (
+	undecided verdict = iota
+	allowed
+	denied
+)
+
+// Engine evaluates remote tasks against the local policy and audits denials
+type Engine struct {
+	mu     sync.RWMutex
+	config config.PolicyConfig
+	audit  *AuditLog
+	logger *logrus.Logger
+}
+
+// New creates a policy engine, loading rules from the policy file if one is
+// configured
+func New(cfg config.PolicyConfig, store storage.Storage, logger *logrus.Logger) (*Engine, error) {
+	e := &Engine{
+		audit:  NewAuditLog(store, cfg.MaxAuditEntries),
+		logger: logger,
+	}
+
+	loaded, err := load(cfg)
+	if err != nil {
+		return nil, err
+	}
+	e.config = loaded
+
+	return e, nil
+}
+
+// Reload re-reads the policy file, keeping the current policy if it is invalid
+func (e *Engine) Reload() error {
+	e.mu.RLock()
+	current := e.config
+	e.mu.RUnlock()
+
+	loaded, err := load(current)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.config = loaded
+	e.mu.Unlock()
+
+	e.logger.Info("Policy reloaded")
+	return nil
+}
+
+// Policy returns the active policy
+func (e *Engine) Policy() config.PolicyConfig {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.config
+}
+
+// Evaluate checks a request against the global rules and the rules of the
+// request's community; both must allow it
+func (e *Engine) Evaluate(req Request) Decision {
+	e.mu.RLock()
+	cfg := e.config
+	e.mu.RUnlock()
+
+	if !cfg.Enabled {
+		return Decision{Allowed: true}
+	}
+
+	if decision := evaluateRules(cfg.Rules, req); !decision.Allowed {
+		return decision
+	}
+
+	if rules, exists := cfg.Communities[req.CommunityID]; exists {
+		decision := evaluateRules(rules, req)
+		if !decision.Allowed {
+			decision.Reason = fmt.Sprintf("community %s: %s", req.CommunityID, decision.Reason)
+		}
+		return decision
+	}
+
+	return Decision{Allowed: true}
+}
+
+// Authorize evaluates a request and records it in the audit log if denied
+func (e *Engine) Authorize(req Request) error {
+	decision := e.Evaluate(req)
+	if decision.Allowed {
+		return nil
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"task_id":      req.TaskID,
+		"community_id": req.CommunityID,
+		"category":     req.Category,
+		"module":       req.Module,
+		"action":       req.Action,
+		"reason":       decision.Reason,
+	}).Warn("Task denied by policy")
+
+	if err := e.audit.Record(AuditEntry{
+		Time:    time.Now(),
+		Request: req,
+		Reason:  decision.Reason,
+	}); err != nil {
+		e.logger.WithError(err).Warn("Failed to record policy denial")
+	}
+
+	return fmt.Errorf("%w: %s", ErrDenied, decision.Reason)
+}
+
+// Denials returns the most recent audited denials, newest first
+func (e *Engine) Denials(limit int) ([]AuditEntry, error) {
+	return e.audit.Entries(limit)
+}
+
+// evaluateRules checks a request against one rule set
+func evaluateRules(rules config.PolicyRules, req Request) Decision {
+	var verdicts []verdict
+	var reasons []string
+
+	switch req.Category {
+	case CategoryModule:
+		v, reason := check(rules.Modules, req.Module, "module "+req.Module)
+		verdicts, reasons = append(verdicts, v), append(reasons, reason)
+
+		action := req.Module + "." + req.Action
+		v, reason = check(rules.Actions, action, "action "+action)
+		verdicts, reasons = append(verdicts, v), append(reasons, reason)
+	case CategoryOBS:
+		v, reason := check(rules.OBS, req.Action, "OBS operation "+req.Action)
+		verdicts, reasons = append(verdicts, v), append(reasons, reason)
+	case CategoryScript:
+		v, reason := check(rules.Scripts, req.Action, "script type "+req.Action)
+		verdicts, reasons = append(verdicts, v), append(reasons, reason)
+	}
+
+	anyAllowed := false
+	for i, v := range verdicts {
+		if v == denied {
+			return Decision{Allowed: false, Reason: reasons[i]}
+		}
+		if v == allowed {
+			anyAllowed = true
+		}
+	}
+
+	if anyAllowed || !strings.EqualFold(rules.Default, DefaultDeny) {
+		return Decision{Allowed: true}
+	}
+
+	return Decision{Allowed: false, Reason: fmt.Sprintf("%s task not allowed by default", req.Category)}
+}
+
+// check matches a value against a rule list
+func check(list config.PolicyList, value, subject string) (verdict, string) {
+	if matchAny(list.Deny, value) {
+		return denied, subject + " is denied"
+	}
+	if len(list.Allow) == 0 {
+		return undecided, ""
+	}
+	if matchAny(list.Allow, value) {
+		return allowed, ""
+	}
+	return denied, subject + " is not allowed"
+}
+
+// matchAny reports whether value matches any of the glob patterns
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// load returns cfg with its rules replaced by the policy file, if set, and
+// validates the result
+func load(cfg config.PolicyConfig) (config.PolicyConfig, error) {
+	if cfg.File != "" {
+		v := viper.New()
+		v.SetConfigFile(cfg.File)
+		if err := v.ReadInConfig(); err != nil {
+			return cfg, fmt.Errorf("%w: failed to read %s: %v", ErrInvalidPolicy, cfg.File, err)
+		}
+
+		var fromFile config.PolicyConfig
+		if err := v.Unmarshal(&fromFile); err != nil {
+			return cfg, fmt.Errorf("%w: failed to parse %s: %v", ErrInvalidPolicy, cfg.File, err)
+		}
+
+		cfg.Rules = fromFile.Rules
+		cfg.Communities = fromFile.Communities
+	}
+
+	if err := validateRules(cfg.Rules); err != nil {
+		return cfg, err
+	}
+	for community, rules := range cfg.Communities {
+		if err := validateRules(rules); err != nil {
+			return cfg, fmt.Errorf("community %s: %w", community, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// validateRules checks the default verdict and glob patterns of a rule set
+func validateRules(rules config.PolicyRules) error {
+	switch strings.ToLower(rules.Default) {
+	case "", DefaultAllow, DefaultDeny:
+	default:
+		return fmt.Errorf("%w: default must be %q or %q, got %q", ErrInvalidPolicy, DefaultAllow, DefaultDeny, rules.Default)
+	}
+
+	for _, list := range []config.PolicyList{rules.Modules, rules.Actions, rules.OBS, rules.Scripts} {
+		for _, pattern := range append(append([]string{}, list.Allow...), list.Deny...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%w: bad pattern %q", ErrInvalidPolicy, pattern)
+			}
+		}
+	}
+
+	return nil
+}
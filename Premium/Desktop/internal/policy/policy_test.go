@@ -0,0 +1,175 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/testutils"
+)
+
+func newTestEngine(t *testing.T, cfg config.PolicyConfig) *Engine {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	engine, err := New(cfg, testutils.NewMockStorage(), logger)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return engine
+}
+
+func moduleRequest(module, action string) Request {
+	return Request{TaskID: "task", CommunityID: "test-community", Category: CategoryModule, Module: module, Action: action}
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	engine := newTestEngine(t, config.PolicyConfig{
+		Enabled: true,
+		Rules: config.PolicyRules{
+			Default: DefaultDeny,
+			Modules: config.PolicyList{Allow: []string{"system", "soundboard"}},
+			Actions: config.PolicyList{Deny: []string{"system.execute_command"}},
+			OBS:     config.PolicyList{Allow: []string{"set_scene", "toggle_*"}},
+		},
+		Communities: map[string]config.PolicyRules{
+			"strict-community": {
+				Modules: config.PolicyList{Deny: []string{"soundboard"}},
+			},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		req     Request
+		allowed bool
+	}{
+		{"allowed module", moduleRequest("system", "get_info"), true},
+		{"denied action", moduleRequest("system", "execute_command"), false},
+		{"module not in allowlist", moduleRequest("file_operations", "read_file"), false},
+		{"allowed OBS operation", Request{Category: CategoryOBS, Action: "set_scene"}, true},
+		{"allowed OBS glob", Request{Category: CategoryOBS, Action: "toggle_stream"}, true},
+		{"OBS operation not in allowlist", Request{Category: CategoryOBS, Action: "stop_stream"}, false},
+		{"script denied by default", Request{Category: CategoryScript, Action: "lua"}, false},
+		{"community rule denies", Request{CommunityID: "strict-community", Category: CategoryModule, Module: "soundboard", Action: "play"}, false},
+		{"community without rules", Request{CommunityID: "other", Category: CategoryModule, Module: "soundboard", Action: "play"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(tt.req)
+			if decision.Allowed != tt.allowed {
+				t.Errorf("Expected allowed=%v, got %v (%s)", tt.allowed, decision.Allowed, decision.Reason)
+			}
+			if !decision.Allowed && decision.Reason == "" {
+				t.Error("Expected a reason for denial")
+			}
+		})
+	}
+}
+
+func TestEngine_DefaultAllowAndDisabled(t *testing.T) {
+	engine := newTestEngine(t, config.PolicyConfig{Enabled: true})
+	if !engine.Evaluate(Request{Category: CategoryScript, Action: "bash"}).Allowed {
+		t.Error("Expected empty policy to allow tasks")
+	}
+
+	engine = newTestEngine(t, config.PolicyConfig{
+		Enabled: false,
+		Rules:   config.PolicyRules{Default: DefaultDeny},
+	})
+	if !engine.Evaluate(Request{Category: CategoryScript, Action: "bash"}).Allowed {
+		t.Error("Expected disabled policy to allow tasks")
+	}
+}
+
+func TestEngine_AuthorizeAuditsDenials(t *testing.T) {
+	engine := newTestEngine(t, config.PolicyConfig{
+		Enabled: true,
+		Rules:   config.PolicyRules{Modules: config.PolicyList{Deny: []string{"hotkeys"}}},
+	})
+
+	if err := engine.Authorize(moduleRequest("system", "get_info")); err != nil {
+		t.Fatalf("Expected task to be allowed, got %v", err)
+	}
+
+	err := engine.Authorize(moduleRequest("hotkeys", "send"))
+	if !errors.Is(err, ErrDenied) {
+		t.Fatalf("Expected ErrDenied, got %v", err)
+	}
+
+	denials, err := engine.Denials(10)
+	if err != nil {
+		t.Fatalf("Denials failed: %v", err)
+	}
+	if len(denials) != 1 {
+		t.Fatalf("Expected 1 denial, got %d", len(denials))
+	}
+	if denials[0].Request.Module != "hotkeys" || denials[0].Reason == "" {
+		t.Errorf("Unexpected audit entry: %+v", denials[0])
+	}
+}
+
+func TestAuditLog_Limit(t *testing.T) {
+	audit := NewAuditLog(testutils.NewMockStorage(), 3)
+
+	for i := 0; i < 5; i++ {
+		if err := audit.Record(AuditEntry{Request: Request{TaskID: string(rune('a' + i))}}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	entries, err := audit.Entries(0)
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].Request.TaskID != "e" || entries[2].Request.TaskID != "c" {
+		t.Errorf("Expected newest entries first, got %s..%s", entries[0].Request.TaskID, entries[2].Request.TaskID)
+	}
+}
+
+func TestEngine_LoadFromFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "policy.yaml")
+	content := `default: deny
+modules:
+  allow: ["system"]
+communities:
+  test-community:
+    actions:
+      deny: ["system.get_processes"]
+`
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write policy file: %v", err)
+	}
+
+	engine := newTestEngine(t, config.PolicyConfig{Enabled: true, File: file})
+
+	if !engine.Evaluate(moduleRequest("system", "get_info")).Allowed {
+		t.Error("Expected system.get_info to be allowed")
+	}
+	if engine.Evaluate(moduleRequest("system", "get_processes")).Allowed {
+		t.Error("Expected community rule to deny system.get_processes")
+	}
+	if engine.Evaluate(moduleRequest("soundboard", "play")).Allowed {
+		t.Error("Expected soundboard to be denied")
+	}
+
+	// An invalid file is rejected and the previous policy is kept
+	if err := os.WriteFile(file, []byte("default: maybe\n"), 0600); err != nil {
+		t.Fatalf("Failed to write policy file: %v", err)
+	}
+	if err := engine.Reload(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("Expected ErrInvalidPolicy, got %v", err)
+	}
+	if !engine.Evaluate(moduleRequest("system", "get_info")).Allowed {
+		t.Error("Expected previous policy to remain active")
+	}
+}
@@ -0,0 +1,25 @@
+package poller
+
+import "errors"
+
+var (
+	// ErrUnsupportedTaskType is returned when no executor is registered for a task type
+	ErrUnsupportedTaskType = errors.New("unsupported task type")
+
+	// ErrTaskTimeout is returned when a task does not finish within its timeout
+	ErrTaskTimeout = errors.New("task timed out")
+
+	// ErrMissingParameter is returned when a task lacks a required parameter
+	ErrMissingParameter = errors.New("missing required parameter")
+
+	// ErrUnsupportedAction is returned when an executor does not support a task action
+	ErrUnsupportedAction = errors.New("unsupported action")
+
+	// ErrWrongCommunity is returned for a task addressed to a community the
+	// poller does not serve
+	ErrWrongCommunity = errors.New("task belongs to another community")
+
+	// ErrTaskTypeNotRouted is returned for a task type the community does
+	// not accept
+	ErrTaskTypeNotRouted = errors.New("task type not accepted for this community")
+)
@@ -0,0 +1,235 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"waddlebot-bridge/internal/bridge"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/scripting"
+	"waddlebot-bridge/internal/tracing"
+)
+
+// Task types understood by the poller
+const (
+	TaskTypeModule = "module_action"
+	TaskTypeScript = "script"
+	TaskTypeOBS    = "obs"
+)
+
+// TaskExecutor executes tasks of a single type
+type TaskExecutor interface {
+	Execute(ctx context.Context, task ActionRequest) (map[string]interface{}, error)
+}
+
+// ModuleExecutor runs module actions through the module manager
+type ModuleExecutor struct {
+	manager ModuleManager
+}
+
+// NewModuleExecutor creates an executor for module tasks
+func NewModuleExecutor(manager ModuleManager) *ModuleExecutor {
+	return &ModuleExecutor{manager: manager}
+}
+
+// Execute runs the task's module action
+func (e *ModuleExecutor) Execute(ctx context.Context, task ActionRequest) (map[string]interface{}, error) {
+	return e.manager.ExecuteAction(ctx, task.ModuleName, task.Action, task.Parameters)
+}
+
+// ScriptExecutor runs script tasks through the scripting manager. The task
+// action names the script type and the "source" parameter holds the script;
+// remaining parameters are passed to the script as environment variables.
+type ScriptExecutor struct {
+	manager *scripting.Manager
+	config  config.ScriptingConfig
+}
+
+// NewScriptExecutor creates an executor for script tasks
+func NewScriptExecutor(manager *scripting.Manager, cfg config.ScriptingConfig) *ScriptExecutor {
+	return &ScriptExecutor{
+		manager: manager,
+		config:  cfg,
+	}
+}
+
+// Execute runs the task's script
+func (e *ScriptExecutor) Execute(ctx context.Context, task ActionRequest) (map[string]interface{}, error) {
+	source := task.Parameters["source"]
+	if source == "" {
+		return nil, fmt.Errorf("%w: source", ErrMissingParameter)
+	}
+
+	env := make(map[string]string, len(task.Parameters))
+	for key, value := range task.Parameters {
+		if key != "source" {
+			env[key] = value
+		}
+	}
+
+	timeout := time.Duration(e.config.DefaultTimeout) * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	result, err := e.manager.Execute(ctx, scripting.ScriptConfig{
+		Type:            scripting.ScriptType(task.Action),
+		Source:          source,
+		Timeout:         timeout,
+		MaxMemoryMB:     e.config.MaxMemoryMB,
+		AllowNetwork:    e.config.AllowNetwork,
+		AllowFileSystem: e.config.AllowFileSystem,
+		Environment:     env,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := map[string]interface{}{
+		"output":      result.Output,
+		"exit_code":   result.ExitCode,
+		"duration_ms": result.Duration.Milliseconds(),
+	}
+
+	if result.Error != "" {
+		return output, fmt.Errorf("script failed: %s", result.Error)
+	}
+	if result.ExitCode != 0 {
+		return output, fmt.Errorf("script exited with code %d", result.ExitCode)
+	}
+
+	return output, nil
+}
+
+// Clipper saves the replay buffer and uploads the clip
+type Clipper interface {
+	Clip(ctx context.Context, taskID string) (*bridge.Clip, error)
+}
+
+// OBSExecutor runs OBS tasks against the OBS WebSocket client
+type OBSExecutor struct {
+	client *obs.Client
+	clips  Clipper // nil when clip uploads are disabled
+}
+
+// NewOBSExecutor creates an executor for OBS tasks
+func NewOBSExecutor(client *obs.Client) *OBSExecutor {
+	return &OBSExecutor{client: client}
+}
+
+// SetClipper enables the clip action, which uploads a replay and returns
+// its link
+func (e *OBSExecutor) SetClipper(clips Clipper) {
+	e.clips = clips
+}
+
+// Execute runs the task's OBS action
+func (e *OBSExecutor) Execute(ctx context.Context, task ActionRequest) (_ map[string]interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "obs."+task.Action)
+	defer func() { tracing.End(span, err) }()
+
+	params := task.Parameters
+
+	switch task.Action {
+	case "set_scene":
+		scene, err := requireParam(params, "scene")
+		if err != nil {
+			return nil, err
+		}
+		if err := e.client.SetCurrentScene(ctx, scene); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"scene": scene}, nil
+
+	case "start_stream":
+		return nil, e.client.StartStream(ctx)
+
+	case "stop_stream":
+		return nil, e.client.StopStream(ctx)
+
+	case "toggle_stream":
+		active, err := e.client.ToggleStream(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"streaming": active}, nil
+
+	case "start_recording":
+		return nil, e.client.StartRecording(ctx)
+
+	case "stop_recording":
+		path, err := e.client.StopRecording(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"output_path": path}, nil
+
+	case "toggle_recording":
+		return nil, e.client.ToggleRecording(ctx)
+
+	case "save_replay":
+		path, err := e.client.SaveReplay(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"output_path": path}, nil
+
+	case "clip":
+		if e.clips == nil {
+			return nil, fmt.Errorf("%w: clip uploads are disabled", ErrUnsupportedAction)
+		}
+		clip, err := e.clips.Clip(ctx, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"clip_id": clip.ID, "url": clip.URL}, nil
+
+	case "set_source_visibility":
+		scene, err := requireParam(params, "scene")
+		if err != nil {
+			return nil, err
+		}
+		source, err := requireParam(params, "source")
+		if err != nil {
+			return nil, err
+		}
+		visible, err := strconv.ParseBool(params["visible"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid visible parameter: %w", err)
+		}
+		if err := e.client.SetSourceVisibility(ctx, scene, source, visible); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"scene": scene, "source": source, "visible": visible}, nil
+
+	case "toggle_filter":
+		source, err := requireParam(params, "source")
+		if err != nil {
+			return nil, err
+		}
+		filter, err := requireParam(params, "filter")
+		if err != nil {
+			return nil, err
+		}
+		enabled, err := e.client.ToggleFilter(ctx, source, filter)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"source": source, "filter": filter, "enabled": enabled}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: obs %s", ErrUnsupportedAction, task.Action)
+	}
+}
+
+// requireParam returns a non-empty task parameter
+func requireParam(params map[string]string, name string) (string, error) {
+	value := params[name]
+	if value == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingParameter, name)
+	}
+	return value, nil
+}
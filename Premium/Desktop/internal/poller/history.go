@@ -0,0 +1,68 @@
+package poller
+
+import (
+	"sync"
+	"time"
+)
+
+// recentTaskLimit is the number of finished tasks kept for the dashboard
+const recentTaskLimit = 50
+
+// TaskSummary describes a finished task
+type TaskSummary struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Module     string    `json:"module,omitempty"`
+	Action     string    `json:"action,omitempty"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Duration   int64     `json:"duration"` // in milliseconds
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// taskHistory keeps the most recently finished tasks
+type taskHistory struct {
+	mu    sync.Mutex
+	tasks []TaskSummary
+}
+
+// add records a finished task, forgetting the oldest beyond the limit
+func (h *taskHistory) add(task ActionRequest, response ActionResponse) {
+	taskType := task.Type
+	if taskType == "" {
+		taskType = TaskTypeModule
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tasks = append(h.tasks, TaskSummary{
+		ID:         task.ID,
+		Type:       taskType,
+		Module:     task.ModuleName,
+		Action:     task.Action,
+		Success:    response.Success,
+		Error:      response.Error,
+		Duration:   response.Duration,
+		FinishedAt: response.Timestamp,
+	})
+	if len(h.tasks) > recentTaskLimit {
+		h.tasks = append(h.tasks[:0], h.tasks[len(h.tasks)-recentTaskLimit:]...)
+	}
+}
+
+// recent returns the finished tasks, newest first
+func (h *taskHistory) recent() []TaskSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	tasks := make([]TaskSummary, len(h.tasks))
+	for i, task := range h.tasks {
+		tasks[len(h.tasks)-1-i] = task
+	}
+	return tasks
+}
+
+// RecentTasks returns the most recently finished tasks, newest first
+func (p *Poller) RecentTasks() []TaskSummary {
+	return p.history.recent()
+}
@@ -1,32 +1,102 @@
 package poller
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"waddlebot-bridge/internal/bridge"
 	"waddlebot-bridge/internal/config"
 	"waddlebot-bridge/internal/logger"
-	"waddlebot-bridge/internal/modules"
+	"waddlebot-bridge/internal/metrics"
+	"waddlebot-bridge/internal/outbox"
+	"waddlebot-bridge/internal/policy"
+	"waddlebot-bridge/internal/resilience"
+	"waddlebot-bridge/internal/supervisor"
+	"waddlebot-bridge/internal/tracing"
+	"waddlebot-bridge/internal/upload"
 )
 
+// BridgeClient provides authentication tokens for API requests
+type BridgeClient interface {
+	GetAuthToken() (string, error)
+}
+
+// ModuleManager executes module actions
+type ModuleManager interface {
+	ExecuteAction(ctx context.Context, moduleName, action string, parameters map[string]string) (map[string]interface{}, error)
+}
+
+// HTTPClientProvider is implemented by bridge clients that carry their own
+// HTTP client, such as one configured for mutual TLS and certificate pinning
+type HTTPClientProvider interface {
+	HTTPClient() *http.Client
+}
+
+// RequestSigner is implemented by bridge clients that sign API requests so
+// the server can verify results came from the registered bridge
+type RequestSigner interface {
+	SignRequest(req *http.Request, body []byte) error
+}
+
+// UploadNegotiator is implemented by bridge clients that negotiate upload
+// compression and chunking with the server
+type UploadNegotiator interface {
+	UploadOptions() upload.Options
+}
+
+// RegistrationTracker is implemented by bridge clients that maintain the
+// bridge's registration, so requests carry the bridge ID and 401/410
+// responses trigger re-registration
+type RegistrationTracker interface {
+	BridgeID() string
+	Deregistered(statusCode int)
+}
+
 // Poller handles polling the WaddleBot API for actions to execute
 type Poller struct {
 	config        *config.Config
-	bridgeClient  *bridge.Client
-	moduleManager *modules.Manager
+	bridgeClient  BridgeClient
+	moduleManager ModuleManager
 	logger        *logrus.Logger
 	httpClient    *http.Client
 	ticker        *time.Ticker
 	lastPoll      time.Time
+	polledAt      time.Time // last successful poll, zero before the first
+	startedAt     time.Time
+
+	mu        sync.RWMutex
+	executors map[string]TaskExecutor
+	tracker   *taskTracker
+	history   taskHistory
+	stream    *bridge.TaskStream
+	outbox    *outbox.Outbox
+	policy    TaskAuthorizer
+	paused    bool
+	resumed   chan struct{} // closed by Resume
+	draining  bool
+	inflight  int
+	drained   chan struct{} // closed when the last task in flight ends
 }
 
+// TaskAuthorizer decides whether a remote task may be executed
+type TaskAuthorizer interface {
+	Authorize(req policy.Request) error
+}
+
+// resultPath is the API path task results are posted to
+const resultPath = "/api/bridge/response"
+
 // ActionRequest represents an action request from the server
 type ActionRequest struct {
 	ID          string            `json:"id"`
@@ -40,6 +110,7 @@ type ActionRequest struct {
 	Timeout     int               `json:"timeout"`
 	CreatedAt   time.Time         `json:"created_at"`
 	ExpiresAt   time.Time         `json:"expires_at"`
+	TraceParent string            `json:"traceparent,omitempty"` // W3C trace context of the server's trace
 }
 
 // ActionResponse represents the response to an action request
@@ -50,6 +121,7 @@ type ActionResponse struct {
 	Error     string                 `json:"error,omitempty"`
 	Duration  int64                  `json:"duration"` // in milliseconds
 	Timestamp time.Time              `json:"timestamp"`
+	TraceID   string                 `json:"trace_id,omitempty"` // set when the task was traced
 }
 
 // PollResponse represents the response from the polling endpoint
@@ -71,9 +143,11 @@ type ClientInfo struct {
 	Uptime         int64     `json:"uptime"`
 }
 
-// NewPoller creates a new poller instance
-func NewPoller(cfg *config.Config, bridgeClient *bridge.Client, moduleManager *modules.Manager) *Poller {
-	return &Poller{
+// NewPoller creates a new poller instance. Module tasks are dispatched to
+// the module manager; other task types need an executor registered with
+// RegisterExecutor.
+func NewPoller(cfg *config.Config, bridgeClient BridgeClient, moduleManager ModuleManager) *Poller {
+	p := &Poller{
 		config:        cfg,
 		bridgeClient:  bridgeClient,
 		moduleManager: moduleManager,
@@ -81,8 +155,31 @@ func NewPoller(cfg *config.Config, bridgeClient *bridge.Client, moduleManager *m
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		lastPoll: time.Now(),
+		lastPoll:  time.Now(),
+		startedAt: time.Now(),
+		executors: make(map[string]TaskExecutor),
+		tracker:   newTaskTracker(),
+	}
+
+	if provider, ok := bridgeClient.(HTTPClientProvider); ok {
+		if client := provider.HTTPClient(); client != nil {
+			p.httpClient = client
+		}
 	}
+
+	p.executors[TaskTypeModule] = NewModuleExecutor(moduleManager)
+
+	return p
+}
+
+// RegisterExecutor registers the executor for a task type, replacing any
+// existing executor for that type
+func (p *Poller) RegisterExecutor(taskType string, executor TaskExecutor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.executors[taskType] = executor
+	p.logger.WithField("type", taskType).Debug("Registered task executor")
 }
 
 // Start starts the polling process
@@ -93,6 +190,18 @@ func (p *Poller) Start(ctx context.Context) error {
 		"user_id":      p.config.UserID,
 	}).Info("Starting action poller")
 
+	// Receive tasks over a push stream when possible; polling below only
+	// runs while the stream is disconnected
+	streamDone := make(chan struct{})
+	if streamer, ok := p.bridgeClient.(TaskStreamer); ok && p.config.Transport.Mode == TransportWebSocket {
+		go func() {
+			defer close(streamDone)
+			p.runStream(ctx, streamer)
+		}()
+	} else {
+		close(streamDone)
+	}
+
 	// Create ticker for polling interval
 	p.ticker = time.NewTicker(time.Duration(p.config.PollInterval) * time.Second)
 	defer p.ticker.Stop()
@@ -106,17 +215,25 @@ func (p *Poller) Start(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
+			<-streamDone
 			p.logger.Info("Stopping action poller")
 			return nil
 		case <-p.ticker.C:
-			if err := p.pollForActions(ctx); err != nil {
+			if p.activeStream() != nil || p.Paused() || p.Draining() {
+				continue
+			}
+			if err := p.pollForActions(ctx); errors.Is(err, resilience.ErrCircuitOpen) {
+				p.logger.Debug("Skipping poll while API calls are paused")
+			} else if err != nil {
 				p.logger.WithError(err).Error("Poll failed")
 			}
 		}
 	}
 }
 
-// pollForActions polls the server for actions to execute
+// pollForActions fetches pending tasks from the server and executes them.
+// Results the server has not acknowledged yet are resent first, and tasks
+// that were already handled are skipped.
 func (p *Poller) pollForActions(ctx context.Context) error {
 	startTime := time.Now()
 	
@@ -126,8 +243,12 @@ func (p *Poller) pollForActions(ctx context.Context) error {
 		return fmt.Errorf("failed to get auth token: %w", err)
 	}
 
+	// Retry results that were not acknowledged on a previous poll
+	p.retryPendingResults(ctx)
+	p.tracker.prune(time.Now())
+
 	// Build poll URL
-	pollURL := p.config.GetAPIEndpoint("/api/bridge/poll")
+	pollURL := p.config.GetAPIEndpoint("/api/bridge/tasks")
 	
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "GET", pollURL, nil)
@@ -141,7 +262,11 @@ func (p *Poller) pollForActions(ctx context.Context) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Community-ID", p.config.CommunityID)
 	req.Header.Set("X-User-ID", p.config.UserID)
-	req.Header.Set("X-Last-Poll", p.lastPoll.Format(time.RFC3339))
+	p.mu.RLock()
+	lastPoll := p.lastPoll
+	p.mu.RUnlock()
+	req.Header.Set("X-Last-Poll", lastPoll.Format(time.RFC3339))
+	p.setBridgeHeader(req)
 
 	// Make request
 	resp, err := p.httpClient.Do(req)
@@ -158,6 +283,7 @@ func (p *Poller) pollForActions(ctx context.Context) error {
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
+		p.reportStatus(resp.StatusCode)
 		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
@@ -168,7 +294,10 @@ func (p *Poller) pollForActions(ctx context.Context) error {
 	}
 
 	// Update last poll time
+	p.mu.Lock()
 	p.lastPoll = time.Now()
+	p.polledAt = p.lastPoll
+	p.mu.Unlock()
 
 	// Process actions
 	if len(pollResponse.Actions) > 0 {
@@ -179,6 +308,11 @@ func (p *Poller) pollForActions(ctx context.Context) error {
 
 		// Process each action
 		for _, action := range pollResponse.Actions {
+			if !p.tracker.begin(action.ID, action.ExpiresAt) {
+				p.logger.WithField("action_id", action.ID).Debug("Skipping duplicate task")
+				continue
+			}
+
 			if err := p.processAction(ctx, action); err != nil {
 				p.logger.WithError(err).WithField("action_id", action.ID).Error("Failed to process action")
 			}
@@ -200,7 +334,22 @@ func (p *Poller) pollForActions(ctx context.Context) error {
 
 // processAction processes a single action request
 func (p *Poller) processAction(ctx context.Context, action ActionRequest) error {
+	if !p.beginTask() {
+		// The server sends the task again once the bridge is back
+		p.logger.WithField("action_id", action.ID).Debug("Not running task while shutting down")
+		return nil
+	}
+	defer p.endTask()
+
 	startTime := time.Now()
+
+	ctx, span := tracing.StartRemote(ctx, action.TraceParent, "task",
+		attribute.String("task.id", action.ID),
+		attribute.String("task.module", action.ModuleName),
+		attribute.String("task.action", action.Action),
+	)
+	var taskErr error
+	defer func() { tracing.End(span, taskErr) }()
 	
 	p.logger.WithFields(logrus.Fields{
 		"action_id":   action.ID,
@@ -213,21 +362,22 @@ func (p *Poller) processAction(ctx context.Context, action ActionRequest) error
 	// Check if action has expired
 	if time.Now().After(action.ExpiresAt) {
 		p.logger.WithField("action_id", action.ID).Warn("Action expired, skipping")
-		return p.sendActionResponse(ctx, ActionResponse{
+		taskErr = errors.New("action expired")
+		response := ActionResponse{
 			ID:        action.ID,
 			Success:   false,
 			Error:     "Action expired",
 			Duration:  time.Since(startTime).Milliseconds(),
 			Timestamp: time.Now(),
-		})
+			TraceID:   tracing.TraceID(ctx),
+		}
+		p.history.add(action, response)
+		return p.submitResult(ctx, response)
 	}
 
-	// Create context with timeout
-	actionCtx, cancel := context.WithTimeout(ctx, time.Duration(action.Timeout)*time.Second)
-	defer cancel()
-
-	// Execute action through module manager
-	result, err := p.moduleManager.ExecuteAction(actionCtx, action.ModuleName, action.Action, action.Parameters)
+	// Execute the task with its own timeout
+	result, err := p.executeTask(ctx, action)
+	taskErr = err
 	
 	// Calculate duration
 	duration := time.Since(startTime)
@@ -238,6 +388,7 @@ func (p *Poller) processAction(ctx context.Context, action ActionRequest) error
 		Success:   err == nil,
 		Duration:  duration.Milliseconds(),
 		Timestamp: time.Now(),
+		TraceID:   tracing.TraceID(ctx),
 	}
 
 	if err != nil {
@@ -252,7 +403,280 @@ func (p *Poller) processAction(ctx context.Context, action ActionRequest) error
 	}
 
 	// Send response back to server
-	return p.sendActionResponse(ctx, response)
+	p.history.add(action, response)
+	return p.submitResult(ctx, response)
+}
+
+// submitResult records a task result and sends it to the server, over the
+// task stream when connected. Results that are not acknowledged stay pending
+// and are retried on the next poll or reconnect; with an outbox they are
+// persisted first and redelivered by the outbox instead.
+func (p *Poller) submitResult(ctx context.Context, response ActionResponse) error {
+	p.tracker.complete(response)
+
+	if ob := p.resultOutbox(); ob != nil {
+		if err := ob.Enqueue(outbox.KindResult, response.ID, resultPath, response); err != nil {
+			p.logger.WithError(err).WithField("action_id", response.ID).Warn("Failed to persist task result")
+		} else {
+			p.tracker.handOff(response.ID)
+		}
+	}
+
+	if stream := p.activeStream(); stream != nil && p.sendStreamResult(stream, response) {
+		// Acknowledged asynchronously by an ack message on the stream
+		return nil
+	}
+
+	if err := p.sendActionResponse(ctx, response); err != nil {
+		return err
+	}
+	p.acknowledgeResult(response.ID)
+
+	return nil
+}
+
+// acknowledgeResult marks a result as accepted by the server
+func (p *Poller) acknowledgeResult(id string) {
+	p.tracker.acknowledge(id)
+
+	if ob := p.resultOutbox(); ob != nil {
+		if err := ob.Remove(outbox.KindResult, id); err != nil && !errors.Is(err, outbox.ErrItemNotFound) {
+			p.logger.WithError(err).WithField("action_id", id).Warn("Failed to remove delivered result from outbox")
+		}
+	}
+}
+
+// Drain stops the poller taking new tasks and waits until the tasks in
+// flight finish or ctx is done. Tasks are cancelled only when the context
+// passed to Start is, so the caller cancels it after draining.
+func (p *Poller) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	p.draining = true
+	if p.inflight > 0 && p.drained == nil {
+		p.drained = make(chan struct{})
+	}
+	drained := p.drained
+	p.mu.Unlock()
+
+	if drained == nil {
+		return nil
+	}
+
+	p.logger.WithField("tasks", p.InFlight()).Info("Waiting for tasks in flight to finish")
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d tasks still running: %w", p.InFlight(), ctx.Err())
+	}
+}
+
+// Draining reports whether the poller stopped taking new tasks
+func (p *Poller) Draining() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.draining
+}
+
+// InFlight returns the number of tasks running
+func (p *Poller) InFlight() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.inflight
+}
+
+// FlushResults resends the results the server has not acknowledged, and
+// returns the number still undelivered. Results kept in an outbox are
+// delivered by flushing the outbox instead.
+func (p *Poller) FlushResults(ctx context.Context) int {
+	p.retryPendingResults(ctx)
+	return p.tracker.stats().pending
+}
+
+// beginTask counts a task as in flight, unless the poller is draining
+func (p *Poller) beginTask() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.draining {
+		return false
+	}
+	p.inflight++
+	return true
+}
+
+// endTask ends a task begun with beginTask
+func (p *Poller) endTask() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inflight--
+	if p.inflight == 0 && p.drained != nil {
+		close(p.drained)
+		p.drained = nil
+	}
+}
+
+// SetOutbox persists task results in a durable queue until the server
+// acknowledges them, so results survive restarts and connectivity loss
+func (p *Poller) SetOutbox(ob *outbox.Outbox) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.outbox = ob
+}
+
+// SetPolicy sets the policy every task is checked against before execution
+func (p *Poller) SetPolicy(authorizer TaskAuthorizer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.policy = authorizer
+}
+
+// policyRequest describes a task for policy evaluation
+func (p *Poller) policyRequest(task ActionRequest, taskType string) policy.Request {
+	category := taskType
+	switch taskType {
+	case TaskTypeModule:
+		category = policy.CategoryModule
+	case TaskTypeOBS:
+		category = policy.CategoryOBS
+	case TaskTypeScript:
+		category = policy.CategoryScript
+	}
+
+	communityID := task.CommunityID
+	if communityID == "" {
+		communityID = p.config.CommunityID
+	}
+
+	return policy.Request{
+		TaskID:      task.ID,
+		CommunityID: communityID,
+		UserID:      task.UserID,
+		Category:    category,
+		Module:      task.ModuleName,
+		Action:      task.Action,
+	}
+}
+
+// resultOutbox returns the outbox used for results, if any
+func (p *Poller) resultOutbox() *outbox.Outbox {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.outbox
+}
+
+// executeTask dispatches a task to the executor for its type and enforces
+// the task timeout even if the executor ignores context cancellation
+func (p *Poller) executeTask(ctx context.Context, task ActionRequest) (_ map[string]interface{}, err error) {
+	taskType := task.Type
+	if taskType == "" {
+		taskType = TaskTypeModule
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("task.type", taskType))
+
+	if !task.CreatedAt.IsZero() {
+		metrics.PollerLag.Observe(math.Max(time.Since(task.CreatedAt).Seconds(), 0))
+	}
+	defer func() {
+		metrics.PollerTasks.WithLabelValues(taskType, metrics.Result(err)).Inc()
+	}()
+
+	p.mu.RLock()
+	executor, exists := p.executors[taskType]
+	authorizer := p.policy
+	p.mu.RUnlock()
+
+	// Only run tasks routed to this poller's community
+	if err := p.checkRoute(task, taskType); err != nil {
+		return nil, err
+	}
+
+	// Check the local policy before anything runs
+	if authorizer != nil {
+		_, span := tracing.Start(ctx, "policy.authorize")
+		err := authorizer.Authorize(p.policyRequest(task, taskType))
+		tracing.End(span, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTaskType, taskType)
+	}
+
+	timeout := p.taskTimeout(task)
+	taskCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type taskResult struct {
+		result map[string]interface{}
+		err    error
+	}
+
+	done := make(chan taskResult, 1)
+	go func() {
+		var res taskResult
+		defer func() { done <- res }()
+		defer supervisor.Recover("task."+taskType, &res.err)
+		res.result, res.err = executor.Execute(taskCtx, task)
+	}()
+
+	select {
+	case res := <-done:
+		return res.result, res.err
+	case <-taskCtx.Done():
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w after %s", ErrTaskTimeout, timeout)
+	}
+}
+
+// checkRoute verifies that a task is addressed to the poller's community
+// and that the community accepts its type
+func (p *Poller) checkRoute(task ActionRequest, taskType string) error {
+	if task.CommunityID != "" && task.CommunityID != p.config.CommunityID {
+		return fmt.Errorf("%w: %s", ErrWrongCommunity, task.CommunityID)
+	}
+
+	if len(p.config.TaskTypes) == 0 {
+		return nil
+	}
+	for _, allowed := range p.config.TaskTypes {
+		if allowed == taskType {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrTaskTypeNotRouted, taskType)
+}
+
+// taskTimeout returns the execution timeout for a task, falling back to the
+// configured module timeout
+func (p *Poller) taskTimeout(task ActionRequest) time.Duration {
+	if task.Timeout > 0 {
+		return time.Duration(task.Timeout) * time.Second
+	}
+	if p.config.ModuleTimeout > 0 {
+		return time.Duration(p.config.ModuleTimeout) * time.Second
+	}
+	return defaultTaskTimeout
+}
+
+// retryPendingResults resends results the server has not acknowledged
+func (p *Poller) retryPendingResults(ctx context.Context) {
+	for _, response := range p.tracker.pending() {
+		if err := p.sendActionResponse(ctx, response); err != nil {
+			if p.tracker.retryFailed(response.ID) {
+				p.logger.WithError(err).WithField("action_id", response.ID).Error("Dropping task result after repeated delivery failures")
+			} else {
+				p.logger.WithError(err).WithField("action_id", response.ID).Warn("Failed to resend task result")
+			}
+			continue
+		}
+
+		p.acknowledgeResult(response.ID)
+		p.logger.WithField("action_id", response.ID).Info("Task result delivered on retry")
+	}
 }
 
 // sendActionResponse sends the action response back to the server
@@ -263,28 +687,62 @@ func (p *Poller) sendActionResponse(ctx context.Context, response ActionResponse
 		return fmt.Errorf("failed to get auth token: %w", err)
 	}
 
-	// Build response URL
-	responseURL := p.config.GetAPIEndpoint("/api/bridge/response")
-
 	// Marshal response
 	responseData, err := json.Marshal(response)
 	if err != nil {
 		return fmt.Errorf("failed to marshal response: %w", err)
 	}
 
+	// Large results are compressed and chunked when the server allows it
+	var opts upload.Options
+	if negotiator, ok := p.bridgeClient.(UploadNegotiator); ok {
+		opts = negotiator.UploadOptions()
+	}
+
+	_, err = upload.Send(ctx, opts, resultPath, upload.ContentTypeJSON, responseData, func(ctx context.Context, part upload.Part) ([]byte, error) {
+		return nil, p.sendPart(ctx, token, response.ID, part)
+	})
+	if err != nil {
+		return err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"action_id": response.ID,
+		"success":   response.Success,
+		"duration":  response.Duration,
+	}).Debug("Action response sent")
+
+	return nil
+}
+
+// sendPart sends one encoded part of an action response
+func (p *Poller) sendPart(ctx context.Context, token, responseID string, part upload.Part) error {
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", responseURL, 
-		strings.NewReader(string(responseData)))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.GetAPIEndpoint(part.Path),
+		bytes.NewReader(part.Body))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add headers
+	for key, values := range part.Header {
+		req.Header[key] = values
+	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("User-Agent", p.config.GetUserAgent())
-	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Community-ID", p.config.CommunityID)
 	req.Header.Set("X-User-ID", p.config.UserID)
+	idempotencyKey := responseID
+	if index := part.Header.Get(upload.HeaderChunkIndex); index != "" {
+		idempotencyKey += "/" + index
+	}
+	req.Header.Set(resilience.HeaderIdempotencyKey, idempotencyKey)
+	p.setBridgeHeader(req)
+	if signer, ok := p.bridgeClient.(RequestSigner); ok {
+		if err := signer.SignRequest(req, part.Body); err != nil {
+			return err
+		}
+	}
 
 	// Make request
 	resp, err := p.httpClient.Do(req)
@@ -293,18 +751,13 @@ func (p *Poller) sendActionResponse(ctx context.Context, response ActionResponse
 	}
 	defer resp.Body.Close()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
+	// Any 2xx status acknowledges the result
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		p.reportStatus(resp.StatusCode)
 		body, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	p.logger.WithFields(logrus.Fields{
-		"action_id": response.ID,
-		"success":   response.Success,
-		"duration":  response.Duration,
-	}).Debug("Action response sent")
-
 	return nil
 }
 
@@ -324,13 +777,147 @@ func (p *Poller) UpdatePollInterval(seconds int) {
 	p.logger.WithField("interval", seconds).Info("Updated poll interval")
 }
 
+// Pause stops fetching tasks until Resume is called. Tasks already
+// received still run and their results are still sent.
+func (p *Poller) Pause() {
+	p.mu.Lock()
+	if p.paused {
+		p.mu.Unlock()
+		return
+	}
+	p.paused = true
+	p.resumed = make(chan struct{})
+	stream := p.stream
+	p.mu.Unlock()
+
+	// The stream would keep delivering tasks, so disconnect it
+	if stream != nil {
+		stream.Close()
+	}
+	p.logger.Info("Paused task processing")
+}
+
+// Resume fetches tasks again after Pause
+func (p *Poller) Resume() {
+	p.mu.Lock()
+	if !p.paused {
+		p.mu.Unlock()
+		return
+	}
+	p.paused = false
+	close(p.resumed)
+	p.mu.Unlock()
+
+	p.logger.Info("Resumed task processing")
+}
+
+// Reconnect drops the task stream so it connects again, for when its
+// connection died without the poller noticing, as after the computer
+// sleeps
+func (p *Poller) Reconnect() {
+	p.mu.RLock()
+	stream := p.stream
+	p.mu.RUnlock()
+
+	if stream != nil {
+		stream.Close()
+	}
+}
+
+// Paused reports whether task processing is paused
+func (p *Poller) Paused() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.paused
+}
+
+// waitResumed blocks while the poller is paused. It returns false if ctx
+// is cancelled first.
+func (p *Poller) waitResumed(ctx context.Context) bool {
+	p.mu.RLock()
+	paused, resumed := p.paused, p.resumed
+	p.mu.RUnlock()
+	if !paused {
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-resumed:
+		return true
+	}
+}
+
+// setBridgeHeader adds the bridge ID to a request when the bridge client
+// tracks registration
+func (p *Poller) setBridgeHeader(req *http.Request) {
+	if tracker, ok := p.bridgeClient.(RegistrationTracker); ok {
+		if id := tracker.BridgeID(); id != "" {
+			req.Header.Set("X-Bridge-ID", id)
+		}
+	}
+}
+
+// reportStatus tells the bridge client about 401 and 410 responses, which
+// mean the API no longer recognizes the bridge
+func (p *Poller) reportStatus(statusCode int) {
+	if statusCode != http.StatusUnauthorized && statusCode != http.StatusGone {
+		return
+	}
+	if tracker, ok := p.bridgeClient.(RegistrationTracker); ok {
+		tracker.Deregistered(statusCode)
+	}
+}
+
+// PendingResults returns the number of results awaiting acknowledgement
+func (p *Poller) PendingResults() int {
+	return p.tracker.stats().pending
+}
+
+// LastPoll returns when tasks were last fetched by polling, or the zero
+// time if no poll has succeeded yet
+func (p *Poller) LastPoll() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.polledAt
+}
+
+// PollInterval returns the current polling interval
+func (p *Poller) PollInterval() time.Duration {
+	return time.Duration(p.config.PollInterval) * time.Second
+}
+
+// Transport returns how tasks are currently received: over the push
+// stream or by polling
+func (p *Poller) Transport() string {
+	if p.activeStream() != nil {
+		return TransportWebSocket
+	}
+	return TransportPolling
+}
+
 // GetStats returns polling statistics
 func (p *Poller) GetStats() map[string]interface{} {
+	trackerStats := p.tracker.stats()
+
+	p.mu.RLock()
+	lastPoll := p.lastPoll
+	p.mu.RUnlock()
+
+	transport := p.Transport()
+
 	return map[string]interface{}{
-		"poll_interval": p.config.PollInterval,
-		"last_poll":     p.lastPoll,
-		"uptime":        time.Since(p.lastPoll).Seconds(),
-		"community_id":  p.config.CommunityID,
-		"user_id":       p.config.UserID,
+		"poll_interval":   p.config.PollInterval,
+		"last_poll":       lastPoll,
+		"uptime":          time.Since(p.startedAt).Seconds(),
+		"community_id":    p.config.CommunityID,
+		"user_id":         p.config.UserID,
+		"tasks_processed": trackerStats.processed,
+		"tasks_duplicate": trackerStats.duplicates,
+		"results_pending": trackerStats.pending,
+		"results_dropped": trackerStats.dropped,
+		"transport":       transport,
+		"paused":          p.Paused(),
 	}
 }
\ No newline at end of file
@@ -1,15 +1,35 @@
 package poller
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"waddlebot-bridge/internal/bridge"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/outbox"
+	"waddlebot-bridge/internal/policy"
+	"waddlebot-bridge/internal/resilience"
+	"waddlebot-bridge/internal/signing"
 	"waddlebot-bridge/internal/testutils"
+	"waddlebot-bridge/internal/upload"
 )
 
 func TestNewPoller(t *testing.T) {
@@ -52,8 +72,8 @@ func TestPoller_PollForActions_Success(t *testing.T) {
 	// Create test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Verify request
-		if r.URL.Path != "/api/bridge/poll" {
-			t.Errorf("Expected path '/api/bridge/poll', got %s", r.URL.Path)
+		if r.URL.Path != "/api/bridge/tasks" {
+			t.Errorf("Expected path '/api/bridge/tasks', got %s", r.URL.Path)
 		}
 
 		if r.Method != "GET" {
@@ -131,7 +151,7 @@ func TestPoller_PollForActions_Success(t *testing.T) {
 	// Update config to use response server
 	cfg.APIURL = responseServer.URL
 	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/api/bridge/poll" {
+		if r.URL.Path == "/api/bridge/tasks" {
 			response := PollResponse{
 				Actions: []ActionRequest{
 					{
@@ -655,6 +675,64 @@ func TestPoller_Start_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestPoller_Pause(t *testing.T) {
+	cfg := testutils.TestConfig()
+	bridgeClient := testutils.NewMockBridgeClient(cfg)
+	moduleManager := testutils.NewMockModuleManager()
+
+	var mu sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(PollResponse{})
+	}))
+	defer server.Close()
+
+	cfg.APIURL = server.URL
+	cfg.PollInterval = 1
+	poller := NewPoller(cfg, bridgeClient, moduleManager)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		poller.Start(ctx)
+	}()
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return polls
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	poller.Pause()
+	poller.Pause()
+	if !poller.Paused() || poller.GetStats()["paused"] != true {
+		t.Fatal("Expected the poller to be paused")
+	}
+	before := count()
+	time.Sleep(2500 * time.Millisecond)
+	if count() != before {
+		t.Errorf("Expected no polls while paused, got %d", count()-before)
+	}
+
+	poller.Resume()
+	if poller.Paused() {
+		t.Fatal("Expected the poller to be resumed")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if count() == before {
+		t.Error("Expected polling to resume")
+	}
+
+	cancel()
+	<-done
+}
+
 func TestPoller_Start_PollError(t *testing.T) {
 	cfg := testutils.TestConfig()
 	bridgeClient := testutils.NewMockBridgeClient(cfg)
@@ -749,4 +827,740 @@ func TestPoller_ProcessAction_Timeout(t *testing.T) {
 	if err != nil {
 		t.Fatalf("processAction failed: %v", err)
 	}
-}
\ No newline at end of file
+}
+func TestPoller_PollForActions_DeduplicatesTasks(t *testing.T) {
+	var executions, responses int32
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bridge/tasks":
+			response := PollResponse{
+				Actions: []ActionRequest{
+					{
+						ID:         "dup-task",
+						Type:       TaskTypeModule,
+						ModuleName: "counter",
+						Action:     "count",
+						Timeout:    5,
+						ExpiresAt:  time.Now().Add(5 * time.Minute),
+					},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+		case "/api/bridge/response":
+			mu.Lock()
+			responses++
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	cfg := testutils.TestConfig()
+	cfg.APIURL = server.URL
+	moduleManager := testutils.NewMockModuleManager()
+	counter := testutils.NewMockModule("counter")
+	counter.AddAction("count", func(ctx context.Context, parameters map[string]string) (map[string]interface{}, error) {
+		mu.Lock()
+		executions++
+		mu.Unlock()
+		return map[string]interface{}{}, nil
+	})
+	moduleManager.AddModule("counter", counter)
+
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), moduleManager)
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		if err := poller.pollForActions(ctx); err != nil {
+			t.Fatalf("pollForActions failed: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if executions != 1 {
+		t.Errorf("Expected task to execute once, got %d", executions)
+	}
+	if responses != 1 {
+		t.Errorf("Expected one result to be sent, got %d", responses)
+	}
+
+	stats := poller.GetStats()
+	if stats["tasks_duplicate"] != 2 {
+		t.Errorf("Expected 2 duplicate tasks, got %v", stats["tasks_duplicate"])
+	}
+}
+
+func TestPoller_RetriesUnacknowledgedResults(t *testing.T) {
+	var mu sync.Mutex
+	ack := false
+	var delivered []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bridge/tasks":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(PollResponse{})
+		case "/api/bridge/response":
+			var response ActionResponse
+			json.NewDecoder(r.Body).Decode(&response)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !ack {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			delivered = append(delivered, response.ID)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	cfg := testutils.TestConfig()
+	cfg.APIURL = server.URL
+	moduleManager := testutils.NewMockModuleManager()
+	moduleManager.AddModule("test-module", testutils.TestModule("test-module"))
+
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), moduleManager)
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	action := ActionRequest{
+		ID:         "retry-task",
+		Type:       TaskTypeModule,
+		ModuleName: "test-module",
+		Action:     "ping",
+		Timeout:    5,
+		ExpiresAt:  time.Now().Add(5 * time.Minute),
+	}
+
+	if err := poller.processAction(ctx, action); err == nil {
+		t.Fatal("Expected error when result is not acknowledged")
+	}
+
+	if pending := poller.GetStats()["results_pending"]; pending != 1 {
+		t.Fatalf("Expected 1 pending result, got %v", pending)
+	}
+
+	mu.Lock()
+	ack = true
+	mu.Unlock()
+
+	if err := poller.pollForActions(ctx); err != nil {
+		t.Fatalf("pollForActions failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(delivered) != 1 || delivered[0] != "retry-task" {
+		t.Errorf("Expected retry-task to be delivered, got %v", delivered)
+	}
+
+	if pending := poller.GetStats()["results_pending"]; pending != 0 {
+		t.Errorf("Expected no pending results, got %v", pending)
+	}
+}
+
+func TestPoller_ExecuteTask_UnsupportedType(t *testing.T) {
+	cfg := testutils.TestConfig()
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), testutils.NewMockModuleManager())
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	_, err := poller.executeTask(ctx, ActionRequest{ID: "task", Type: "unknown"})
+	if !errors.Is(err, ErrUnsupportedTaskType) {
+		t.Errorf("Expected ErrUnsupportedTaskType, got %v", err)
+	}
+}
+
+type blockingExecutor struct {
+	release chan struct{}
+}
+
+func (e *blockingExecutor) Execute(ctx context.Context, task ActionRequest) (map[string]interface{}, error) {
+	<-e.release
+	return map[string]interface{}{}, nil
+}
+
+func TestPoller_ExecuteTask_TimeoutIgnoringContext(t *testing.T) {
+	cfg := testutils.TestConfig()
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), testutils.NewMockModuleManager())
+
+	executor := &blockingExecutor{release: make(chan struct{})}
+	defer close(executor.release)
+	poller.RegisterExecutor("blocking", executor)
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	start := time.Now()
+	_, err := poller.executeTask(ctx, ActionRequest{ID: "task", Type: "blocking", Timeout: 1})
+	if !errors.Is(err, ErrTaskTimeout) {
+		t.Errorf("Expected ErrTaskTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected timeout after ~1s, took %v", elapsed)
+	}
+}
+
+func TestOBSExecutor_MissingParameter(t *testing.T) {
+	executor := NewOBSExecutor(nil)
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	_, err := executor.Execute(ctx, ActionRequest{Type: TaskTypeOBS, Action: "set_scene"})
+	if !errors.Is(err, ErrMissingParameter) {
+		t.Errorf("Expected ErrMissingParameter, got %v", err)
+	}
+
+	_, err = executor.Execute(ctx, ActionRequest{Type: TaskTypeOBS, Action: "explode"})
+	if !errors.Is(err, ErrUnsupportedAction) {
+		t.Errorf("Expected ErrUnsupportedAction, got %v", err)
+	}
+}
+
+type testStreamer struct {
+	*testutils.MockBridgeClient
+	url string
+}
+
+func (s *testStreamer) OpenTaskStream(ctx context.Context) (*bridge.TaskStream, error) {
+	return bridge.DialTaskStream(ctx, s.url, nil, time.Second, nil)
+}
+
+func TestPoller_Start_StreamsTasks(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	acked := make(chan ActionResponse, 1)
+	var polls int32
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case bridge.TaskStreamPath:
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Errorf("upgrade failed: %v", err)
+				return
+			}
+			defer conn.Close()
+
+			task, _ := json.Marshal(ActionRequest{
+				ID:         "stream-task",
+				Type:       TaskTypeModule,
+				ModuleName: "test-module",
+				Action:     "ping",
+				Timeout:    5,
+				ExpiresAt:  time.Now().Add(5 * time.Minute),
+			})
+			conn.WriteJSON(bridge.StreamMessage{Type: bridge.StreamMessageTask, ID: "stream-task", Data: task})
+
+			var msg bridge.StreamMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			var response ActionResponse
+			json.Unmarshal(msg.Data, &response)
+			conn.WriteJSON(bridge.StreamMessage{Type: bridge.StreamMessageAck, ID: response.ID})
+			acked <- response
+
+			// Hold the connection open until the client goes away
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		case "/api/bridge/tasks":
+			mu.Lock()
+			polls++
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(PollResponse{})
+		}
+	}))
+	defer server.Close()
+
+	cfg := testutils.TestConfig()
+	cfg.APIURL = server.URL
+	cfg.PollInterval = 1
+	cfg.Transport.Mode = TransportWebSocket
+
+	moduleManager := testutils.NewMockModuleManager()
+	moduleManager.AddModule("test-module", testutils.TestModule("test-module"))
+
+	streamer := &testStreamer{
+		MockBridgeClient: testutils.NewMockBridgeClient(cfg),
+		url:              server.URL + bridge.TaskStreamPath,
+	}
+	poller := NewPoller(cfg, streamer, moduleManager)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- poller.Start(ctx) }()
+
+	select {
+	case response := <-acked:
+		if response.ID != "stream-task" || !response.Success {
+			t.Errorf("Unexpected streamed result: %+v", response)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for streamed result")
+	}
+
+	// Give the ack time to arrive before checking pending results
+	time.Sleep(100 * time.Millisecond)
+	if pending := poller.GetStats()["results_pending"]; pending != 0 {
+		t.Errorf("Expected no pending results, got %v", pending)
+	}
+	if transport := poller.GetStats()["transport"]; transport != TransportWebSocket {
+		t.Errorf("Expected websocket transport, got %v", transport)
+	}
+
+	// Polling pauses while the stream is connected
+	mu.Lock()
+	pollsWhileStreaming := polls
+	mu.Unlock()
+	time.Sleep(1500 * time.Millisecond)
+	mu.Lock()
+	if polls > pollsWhileStreaming {
+		t.Errorf("Expected no polls while streaming, got %d more", polls-pollsWhileStreaming)
+	}
+	mu.Unlock()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	min := 100 * time.Millisecond
+	max := time.Second
+
+	for attempt := 0; attempt < 10; attempt++ {
+		ceiling := min << attempt
+		if ceiling > max {
+			ceiling = max
+		}
+
+		delay := backoffDelay(attempt, min, max)
+		if delay < ceiling/2 || delay > ceiling {
+			t.Errorf("attempt %d: delay %v outside [%v, %v]", attempt, delay, ceiling/2, ceiling)
+		}
+	}
+}
+
+func TestPoller_SubmitResult_PersistsToOutbox(t *testing.T) {
+	var mu sync.Mutex
+	online := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !online {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := testutils.TestConfig()
+	cfg.APIURL = server.URL
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), testutils.NewMockModuleManager())
+
+	ob := outbox.New(testutils.NewMockStorage(), config.OutboxConfig{}, logrus.New())
+	poller.SetOutbox(ob)
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	response := ActionResponse{ID: "queued-task", Success: true, Timestamp: time.Now()}
+	if err := poller.submitResult(ctx, response); err == nil {
+		t.Fatal("Expected error while server is unavailable")
+	}
+
+	items, err := ob.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Kind != outbox.KindResult || items[0].Key != "queued-task" {
+		t.Fatalf("Expected result to be queued, got %+v", items)
+	}
+
+	// The outbox owns redelivery, so the poller does not retry it itself
+	if pending := poller.GetStats()["results_pending"]; pending != 0 {
+		t.Errorf("Expected no in-memory pending results, got %v", pending)
+	}
+
+	mu.Lock()
+	online = true
+	mu.Unlock()
+
+	if err := poller.submitResult(ctx, response); err != nil {
+		t.Fatalf("submitResult failed: %v", err)
+	}
+	if items, _ := ob.List(); len(items) != 0 {
+		t.Errorf("Expected delivered result to be removed from outbox, got %d items", len(items))
+	}
+}
+
+type denyAuthorizer struct {
+	requests []policy.Request
+}
+
+func (a *denyAuthorizer) Authorize(req policy.Request) error {
+	a.requests = append(a.requests, req)
+	return fmt.Errorf("%w: module %s is denied", policy.ErrDenied, req.Module)
+}
+
+func TestPoller_ExecuteTask_PolicyDenied(t *testing.T) {
+	cfg := testutils.TestConfig()
+	moduleManager := testutils.NewMockModuleManager()
+
+	executed := false
+	module := testutils.NewMockModule("test-module")
+	module.AddAction("ping", func(ctx context.Context, parameters map[string]string) (map[string]interface{}, error) {
+		executed = true
+		return nil, nil
+	})
+	moduleManager.AddModule("test-module", module)
+
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), moduleManager)
+	authorizer := &denyAuthorizer{}
+	poller.SetPolicy(authorizer)
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	_, err := poller.executeTask(ctx, ActionRequest{ID: "task", ModuleName: "test-module", Action: "ping"})
+	if !errors.Is(err, policy.ErrDenied) {
+		t.Fatalf("Expected policy.ErrDenied, got %v", err)
+	}
+	if executed {
+		t.Error("Expected denied task not to execute")
+	}
+
+	if len(authorizer.requests) != 1 {
+		t.Fatalf("Expected 1 policy check, got %d", len(authorizer.requests))
+	}
+	req := authorizer.requests[0]
+	if req.Category != policy.CategoryModule || req.CommunityID != cfg.CommunityID {
+		t.Errorf("Unexpected policy request: %+v", req)
+	}
+}
+
+func TestPoller_ProcessAction_Traced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(previous)
+
+	cfg := testutils.TestConfig()
+	moduleManager := testutils.NewMockModuleManager()
+	moduleManager.AddModule("test-module", testutils.TestModule("test-module"))
+
+	var response ActionResponse
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&response)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg.APIURL = server.URL
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), moduleManager)
+	poller.SetPolicy(&denyAuthorizer{})
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	err := poller.processAction(ctx, ActionRequest{
+		ID:          "traced",
+		ModuleName:  "test-module",
+		Action:      "ping",
+		ExpiresAt:   time.Now().Add(time.Minute),
+		TraceParent: "00-" + traceID + "-00f067aa0ba902b7-01",
+	})
+	if err != nil {
+		t.Fatalf("processAction failed: %v", err)
+	}
+
+	if response.TraceID != traceID {
+		t.Errorf("Expected the result to carry trace %s, got %q", traceID, response.TraceID)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "policy.authorize" || spans[1].Name != "task" {
+		t.Fatalf("Expected policy and task spans, got %v", spans)
+	}
+	for _, span := range spans {
+		if span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("Expected %s to continue the server's trace", span.Name)
+		}
+	}
+	if spans[1].Status.Description == "" {
+		t.Error("Expected the denied task's span to be marked failed")
+	}
+}
+
+// signingBridgeClient adds HMAC request signing to the mock bridge client
+type signingBridgeClient struct {
+	*testutils.MockBridgeClient
+	signer *signing.Signer
+}
+
+func (c *signingBridgeClient) SignRequest(req *http.Request, body []byte) error {
+	return c.signer.SignRequest(req, body)
+}
+
+func TestPoller_SendActionResponse_Signed(t *testing.T) {
+	signer, err := signing.New(config.SigningConfig{Algorithm: signing.AlgorithmHMAC, Secret: "shared"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	verified := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified <- signing.Verify(r.Header.Get(signing.HeaderSignature), r.Method, r.URL.Path, body,
+			[]byte("shared"), time.Minute, time.Now())
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := testutils.TestConfig()
+	cfg.APIURL = server.URL
+	client := &signingBridgeClient{MockBridgeClient: testutils.NewMockBridgeClient(cfg), signer: signer}
+	poller := NewPoller(cfg, client, testutils.NewMockModuleManager())
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	if err := poller.sendActionResponse(ctx, ActionResponse{ID: "task-1", Success: true}); err != nil {
+		t.Fatalf("sendActionResponse failed: %v", err)
+	}
+	if err := <-verified; err != nil {
+		t.Errorf("Expected signature to verify, got %v", err)
+	}
+}
+
+// registeringBridgeClient records deregistrations reported by the poller
+type registeringBridgeClient struct {
+	*testutils.MockBridgeClient
+	mu       sync.Mutex
+	statuses []int
+}
+
+func (c *registeringBridgeClient) BridgeID() string {
+	return "bridge-123"
+}
+
+func (c *registeringBridgeClient) Deregistered(statusCode int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statuses = append(c.statuses, statusCode)
+}
+
+func TestPoller_PollForActions_Deregistered(t *testing.T) {
+	status := http.StatusGone
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Bridge-ID"); got != "bridge-123" {
+			t.Errorf("Expected X-Bridge-ID bridge-123, got %q", got)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	cfg := testutils.TestConfig()
+	cfg.APIURL = server.URL
+	client := &registeringBridgeClient{MockBridgeClient: testutils.NewMockBridgeClient(cfg)}
+	poller := NewPoller(cfg, client, testutils.NewMockModuleManager())
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	if err := poller.pollForActions(ctx); err == nil {
+		t.Fatal("Expected error for 410 response")
+	}
+
+	status = http.StatusInternalServerError
+	if err := poller.pollForActions(ctx); err == nil {
+		t.Fatal("Expected error for 500 response")
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if len(client.statuses) != 1 || client.statuses[0] != http.StatusGone {
+		t.Errorf("Expected a single 410 deregistration, got %v", client.statuses)
+	}
+}
+
+// uploadingBridgeClient negotiates gzip and chunking with the server
+type uploadingBridgeClient struct {
+	*testutils.MockBridgeClient
+	opts upload.Options
+}
+
+func (c *uploadingBridgeClient) UploadOptions() upload.Options {
+	return c.opts
+}
+
+func TestPoller_SendActionResponse_Chunked(t *testing.T) {
+	var mu sync.Mutex
+	chunks := make(map[string][]byte)
+	keys := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != upload.ChunkPath {
+			t.Errorf("Expected chunk upload, got %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		chunks[r.Header.Get(upload.HeaderChunkIndex)] = body
+		keys[r.Header.Get(resilience.HeaderIdempotencyKey)] = true
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := testutils.TestConfig()
+	cfg.APIURL = server.URL
+	client := &uploadingBridgeClient{
+		MockBridgeClient: testutils.NewMockBridgeClient(cfg),
+		opts:             upload.Options{Gzip: true, Chunking: true, MaxPayloadBytes: 64},
+	}
+	poller := NewPoller(cfg, client, testutils.NewMockModuleManager())
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	// Random data does not compress, so the result needs several chunks
+	data := make([]byte, 256)
+	rand.Read(data)
+	response := ActionResponse{ID: "task-1", Success: true, Result: map[string]interface{}{"blob": data}}
+	if err := poller.sendActionResponse(ctx, response); err != nil {
+		t.Fatalf("sendActionResponse failed: %v", err)
+	}
+
+	var compressed []byte
+	for i := 0; i < len(chunks); i++ {
+		compressed = append(compressed, chunks[strconv.Itoa(i)]...)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Chunks are not a gzip stream: %v", err)
+	}
+	var decoded ActionResponse
+	if err := json.NewDecoder(reader).Decode(&decoded); err != nil {
+		t.Fatalf("Failed to decode reassembled result: %v", err)
+	}
+	if decoded.ID != "task-1" {
+		t.Errorf("Expected task-1, got %s", decoded.ID)
+	}
+	if len(chunks) < 2 || len(keys) != len(chunks) {
+		t.Errorf("Expected several chunks with distinct idempotency keys, got %d chunks and %d keys", len(chunks), len(keys))
+	}
+}
+
+func TestPoller_ExecuteTask_Routing(t *testing.T) {
+	cfg := testutils.TestConfig()
+	cfg.TaskTypes = []string{TaskTypeModule}
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), testutils.NewMockModuleManager())
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	_, err := poller.executeTask(ctx, ActionRequest{ID: "1", Type: TaskTypeOBS, CommunityID: cfg.CommunityID})
+	if !errors.Is(err, ErrTaskTypeNotRouted) {
+		t.Errorf("Expected ErrTaskTypeNotRouted, got %v", err)
+	}
+
+	_, err = poller.executeTask(ctx, ActionRequest{ID: "2", ModuleName: "test-module", Action: "ping", CommunityID: "other-community"})
+	if !errors.Is(err, ErrWrongCommunity) {
+		t.Errorf("Expected ErrWrongCommunity, got %v", err)
+	}
+}
+
+func TestPoller_Drain(t *testing.T) {
+	cfg := testutils.TestConfig()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	cfg.APIURL = server.URL
+
+	poller := NewPoller(cfg, testutils.NewMockBridgeClient(cfg), testutils.NewMockModuleManager())
+	executor := &blockingExecutor{release: make(chan struct{})}
+	poller.RegisterExecutor("blocking", executor)
+
+	ctx, cancel := testutils.TestContext()
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- poller.processAction(ctx, ActionRequest{ID: "running", Type: "blocking", ExpiresAt: time.Now().Add(time.Minute)})
+	}()
+	for poller.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The task in flight keeps the poller from draining in time
+	timeout, cancelTimeout := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelTimeout()
+	if err := poller.Drain(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the drain to time out, got %v", err)
+	}
+	if !poller.Draining() {
+		t.Error("Expected the poller to be draining")
+	}
+
+	// New tasks are left for the server to send again
+	if err := poller.processAction(ctx, ActionRequest{ID: "late", Type: "blocking", ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Errorf("Expected the late task to be skipped, got %v", err)
+	}
+	if _, pending := poller.tracker.result("late"); pending {
+		t.Error("Expected no result for a task received while draining")
+	}
+
+	close(executor.release)
+	if err := poller.Drain(ctx); err != nil {
+		t.Errorf("Expected the drain to finish, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Expected the running task to finish, got %v", err)
+	}
+	if poller.InFlight() != 0 {
+		t.Errorf("Expected no tasks in flight, got %d", poller.InFlight())
+	}
+}
+
+func TestTaskHistory(t *testing.T) {
+	var history taskHistory
+	for i := 0; i < recentTaskLimit+5; i++ {
+		history.add(
+			ActionRequest{ID: fmt.Sprintf("task-%d", i), ModuleName: "soundboard", Action: "play"},
+			ActionResponse{ID: fmt.Sprintf("task-%d", i), Success: i%2 == 0, Duration: int64(i)},
+		)
+	}
+
+	tasks := history.recent()
+	if len(tasks) != recentTaskLimit {
+		t.Fatalf("Expected %d tasks, got %d", recentTaskLimit, len(tasks))
+	}
+	newest, oldest := tasks[0], tasks[len(tasks)-1]
+	if newest.ID != fmt.Sprintf("task-%d", recentTaskLimit+4) || oldest.ID != "task-5" {
+		t.Errorf("Expected tasks newest first, got %s to %s", newest.ID, oldest.ID)
+	}
+	if newest.Type != TaskTypeModule || newest.Module != "soundboard" || !newest.Success {
+		t.Errorf("Unexpected summary %+v", newest)
+	}
+}
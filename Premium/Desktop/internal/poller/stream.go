@@ -0,0 +1,201 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/bridge"
+)
+
+// Transport modes
+const (
+	TransportWebSocket = "websocket"
+	TransportPolling   = "polling"
+)
+
+const (
+	// streamTaskBuffer is the number of streamed tasks queued for execution
+	// before the stream stops reading
+	streamTaskBuffer = 64
+
+	defaultReconnectInterval    = time.Second
+	defaultMaxReconnectInterval = time.Minute
+)
+
+// TaskStreamer opens push connections that deliver tasks as they are created
+type TaskStreamer interface {
+	OpenTaskStream(ctx context.Context) (*bridge.TaskStream, error)
+}
+
+// runStream keeps a task stream connected until ctx is cancelled. While the
+// stream is down the regular poll loop takes over, and reconnects are
+// attempted with exponential backoff and jitter.
+func (p *Poller) runStream(ctx context.Context, streamer TaskStreamer) {
+	attempt := 0
+
+	for {
+		if !p.waitResumed(ctx) {
+			return
+		}
+
+		connected, err := p.streamTasks(ctx, streamer)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if connected {
+			attempt = 0
+		}
+		if p.Paused() {
+			continue
+		}
+
+		delay := backoffDelay(attempt, p.config.Transport.ReconnectInterval, p.config.Transport.MaxReconnectInterval)
+		attempt++
+
+		p.logger.WithFields(logrus.Fields{
+			"error":    err,
+			"retry_in": delay,
+		}).Warn("Task stream unavailable, falling back to polling")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// streamTasks connects a task stream and executes the tasks it delivers
+// until the connection drops. It reports whether the connection was
+// established.
+func (p *Poller) streamTasks(ctx context.Context, streamer TaskStreamer) (bool, error) {
+	stream, err := streamer.OpenTaskStream(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer stream.Close()
+
+	// Unblock Receive when the poller stops
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-stop:
+		}
+	}()
+
+	p.setStream(stream)
+	defer p.setStream(nil)
+
+	p.logger.Info("Task stream connected")
+
+	// Results that were not acknowledged before the reconnect are resent
+	for _, response := range p.tracker.pending() {
+		p.sendStreamResult(stream, response)
+	}
+
+	tasks := make(chan ActionRequest, streamTaskBuffer)
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		for task := range tasks {
+			if err := p.processAction(ctx, task); err != nil {
+				p.logger.WithError(err).WithField("action_id", task.ID).Error("Failed to process action")
+			}
+		}
+	}()
+	defer func() {
+		close(tasks)
+		<-workerDone
+	}()
+
+	for {
+		msg, err := stream.Receive()
+		if err != nil {
+			return true, err
+		}
+
+		switch msg.Type {
+		case bridge.StreamMessageTask:
+			var task ActionRequest
+			if err := json.Unmarshal(msg.Data, &task); err != nil {
+				p.logger.WithError(err).Warn("Ignoring malformed task from stream")
+				continue
+			}
+
+			if !p.tracker.begin(task.ID, task.ExpiresAt) {
+				p.logger.WithField("action_id", task.ID).Debug("Skipping duplicate task")
+				if response, pending := p.tracker.result(task.ID); pending {
+					p.sendStreamResult(stream, response)
+				}
+				continue
+			}
+
+			p.logger.WithField("action_id", task.ID).Debug("Received task from stream")
+			tasks <- task
+
+		case bridge.StreamMessageAck:
+			p.acknowledgeResult(msg.ID)
+			p.logger.WithField("action_id", msg.ID).Debug("Task result acknowledged")
+
+		default:
+			p.logger.WithField("type", msg.Type).Debug("Ignoring unknown stream message")
+		}
+	}
+}
+
+// sendStreamResult writes a result to the stream, logging failures; the
+// result stays pending until acknowledged either way
+func (p *Poller) sendStreamResult(stream *bridge.TaskStream, response ActionResponse) bool {
+	if err := stream.Send(bridge.StreamMessageResult, response.ID, response); err != nil {
+		p.logger.WithError(err).WithField("action_id", response.ID).Warn("Failed to send task result over stream")
+		return false
+	}
+	return true
+}
+
+// setStream records the active task stream
+func (p *Poller) setStream(stream *bridge.TaskStream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stream = stream
+}
+
+// activeStream returns the connected task stream, or nil while polling
+func (p *Poller) activeStream() *bridge.TaskStream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.stream
+}
+
+// backoffDelay returns the reconnect delay for an attempt: the interval
+// doubles per attempt up to max, and the result is jittered between half
+// and the full delay
+func backoffDelay(attempt int, min, max time.Duration) time.Duration {
+	if min <= 0 {
+		min = defaultReconnectInterval
+	}
+	if max <= 0 {
+		max = defaultMaxReconnectInterval
+	}
+	if max < min {
+		max = min
+	}
+
+	delay := min
+	for i := 0; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
@@ -0,0 +1,192 @@
+package poller
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// seenTaskTTL is how long a task ID is remembered for deduplication
+	// when the task carries no later expiry
+	seenTaskTTL = time.Hour
+
+	// maxResultAttempts is the number of delivery attempts made for a task
+	// result before it is dropped
+	maxResultAttempts = 10
+
+	// defaultTaskTimeout is used when neither the task nor the config
+	// specifies a timeout
+	defaultTaskTimeout = 30 * time.Second
+)
+
+// pendingResult is a task result awaiting acknowledgement by the server
+type pendingResult struct {
+	response ActionResponse
+	attempts int
+}
+
+// trackerStats is a snapshot of task tracking counters
+type trackerStats struct {
+	processed  int
+	duplicates int
+	pending    int
+	dropped    int
+}
+
+// taskTracker deduplicates tasks by ID across polls and holds results until
+// the server acknowledges them
+type taskTracker struct {
+	mu         sync.Mutex
+	seen       map[string]time.Time
+	results    map[string]*pendingResult
+	order      []string
+	processed  int
+	duplicates int
+	dropped    int
+}
+
+// newTaskTracker creates an empty task tracker
+func newTaskTracker() *taskTracker {
+	return &taskTracker{
+		seen:    make(map[string]time.Time),
+		results: make(map[string]*pendingResult),
+	}
+}
+
+// begin marks a task as seen and reports whether it should be executed.
+// Tasks already seen are not executed again; if their result is still
+// unacknowledged it is resent with the other pending results.
+func (t *taskTracker) begin(id string, expiresAt time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.seen[id]; exists {
+		t.duplicates++
+		return false
+	}
+
+	forgetAt := time.Now().Add(seenTaskTTL)
+	if expiresAt.After(forgetAt) {
+		forgetAt = expiresAt
+	}
+	t.seen[id] = forgetAt
+
+	return true
+}
+
+// complete records the result of a task as pending acknowledgement
+func (t *taskTracker) complete(response ActionResponse) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.seen[response.ID]; !exists {
+		t.seen[response.ID] = time.Now().Add(seenTaskTTL)
+	}
+
+	t.processed++
+	if _, exists := t.results[response.ID]; !exists {
+		t.order = append(t.order, response.ID)
+	}
+	t.results[response.ID] = &pendingResult{response: response, attempts: 1}
+}
+
+// acknowledge removes a result the server has accepted
+func (t *taskTracker) acknowledge(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.remove(id)
+}
+
+// handOff stops tracking a result whose redelivery is owned by the outbox
+func (t *taskTracker) handOff(id string) {
+	t.acknowledge(id)
+}
+
+// retryFailed records a failed delivery attempt and reports whether the
+// result was dropped because it ran out of attempts
+func (t *taskTracker) retryFailed(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result, exists := t.results[id]
+	if !exists {
+		return false
+	}
+
+	result.attempts++
+	if result.attempts < maxResultAttempts {
+		return false
+	}
+
+	t.remove(id)
+	t.dropped++
+	return true
+}
+
+// result returns the unacknowledged result of a task, if any
+func (t *taskTracker) result(id string) (ActionResponse, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending, exists := t.results[id]
+	if !exists {
+		return ActionResponse{}, false
+	}
+	return pending.response, true
+}
+
+// pending returns unacknowledged results in completion order
+func (t *taskTracker) pending() []ActionResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	responses := make([]ActionResponse, 0, len(t.order))
+	for _, id := range t.order {
+		responses = append(responses, t.results[id].response)
+	}
+	return responses
+}
+
+// prune forgets seen task IDs whose deduplication window has passed
+func (t *taskTracker) prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, forgetAt := range t.seen {
+		if _, pending := t.results[id]; pending {
+			continue
+		}
+		if now.After(forgetAt) {
+			delete(t.seen, id)
+		}
+	}
+}
+
+// stats returns a snapshot of the tracker counters
+func (t *taskTracker) stats() trackerStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return trackerStats{
+		processed:  t.processed,
+		duplicates: t.duplicates,
+		pending:    len(t.results),
+		dropped:    t.dropped,
+	}
+}
+
+// remove deletes a pending result; the caller must hold the lock
+func (t *taskTracker) remove(id string) {
+	if _, exists := t.results[id]; !exists {
+		return
+	}
+
+	delete(t.results, id)
+	for i, pendingID := range t.order {
+		if pendingID == id {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package power follows the computer's sleep and presentation state. Task
+// processing and OBS are paused before the computer sleeps, and on wake
+// the bridge registers again and reconnects to OBS instead of waiting for
+// dead connections to time out. Task processing can also be held while the
+// user is presenting or has turned on do not disturb.
+package power
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+// errUnsupported is returned by platform checks the platform lacks
+var errUnsupported = errors.New("not supported on this platform")
+
+// wakeThreshold is how far the wall clock must jump past a check for the
+// computer to be taken to have slept
+const wakeThreshold = 30 * time.Second
+
+// Event is a sleep notification from the system
+type Event int
+
+const (
+	// EventSleep is sent before the computer sleeps
+	EventSleep Event = iota
+	// EventWake is sent after the computer wakes
+	EventWake
+)
+
+// OBS is the OBS connection suspended while the computer sleeps
+type OBS interface {
+	Suspend()
+	Wake()
+}
+
+// Bridge is a connection to a WaddleBot community, registered again on
+// wake
+type Bridge interface {
+	Reconnect()
+}
+
+// Tasks receives and runs a community's tasks
+type Tasks interface {
+	Pause()
+	Resume()
+	Paused() bool
+	Reconnect()
+}
+
+// Options are the parts of the bridge that follow the power state
+type Options struct {
+	OBS     OBS      // nil when OBS is disabled
+	Bridges []Bridge // one per community
+	Tasks   []Tasks  // one per community
+}
+
+// Watcher follows the computer's sleep and presentation state
+type Watcher struct {
+	cfg    config.PowerConfig
+	opts   Options
+	logger *logrus.Logger
+
+	mu         sync.Mutex
+	asleep     bool
+	presenting bool
+	paused     []Tasks // tasks paused by the watcher, not by the user
+}
+
+// New creates a power watcher
+func New(cfg config.PowerConfig, opts Options, logger *logrus.Logger) *Watcher {
+	return &Watcher{
+		cfg:    cfg,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+// Run follows the power state until ctx is done. Where the system sends
+// no sleep notifications, waking is detected from jumps of the wall clock,
+// which keeps running while the computer sleeps.
+func (w *Watcher) Run(ctx context.Context) {
+	events := make(chan Event, 4)
+	native := notifySleep(ctx, events, w.logger)
+
+	ticker := time.NewTicker(w.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	checkPresentation := true
+	last := time.Now().Round(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			switch event {
+			case EventSleep:
+				w.sleep()
+			case EventWake:
+				w.wake(0)
+			}
+		case <-ticker.C:
+			now := time.Now().Round(0)
+			if gap := now.Sub(last) - w.cfg.CheckInterval; !native && gap > wakeThreshold {
+				w.wake(gap)
+			}
+			last = now
+
+			if checkPresentation {
+				presenting, err := presentationMode(ctx)
+				switch {
+				case errors.Is(err, errUnsupported):
+					checkPresentation = false
+				case err != nil:
+					w.logger.WithError(err).Debug("Failed to check presentation mode")
+				default:
+					w.setPresenting(presenting)
+				}
+			}
+		}
+	}
+}
+
+// Presenting reports whether the user is presenting or in do not disturb
+func (w *Watcher) Presenting() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.presenting
+}
+
+// sleep pauses task processing and disconnects OBS before the computer
+// sleeps
+func (w *Watcher) sleep() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.asleep {
+		return
+	}
+
+	w.logger.Info("Computer is going to sleep; pausing tasks and OBS")
+	w.asleep = true
+	w.updatePause()
+	if w.opts.OBS != nil {
+		w.opts.OBS.Suspend()
+	}
+}
+
+// wake resumes task processing after the computer wakes, registering the
+// bridge again and reconnecting the task stream and OBS right away. slept
+// is how long the computer slept, when known.
+func (w *Watcher) wake(slept time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry := w.logger.WithFields(logrus.Fields{})
+	if slept > 0 {
+		entry = entry.WithField("slept", slept.Round(time.Second))
+	}
+	entry.Info("Computer woke up; reconnecting")
+	w.asleep = false
+	w.updatePause()
+
+	for _, bridge := range w.opts.Bridges {
+		bridge.Reconnect()
+	}
+	for _, tasks := range w.opts.Tasks {
+		tasks.Reconnect()
+	}
+	if w.opts.OBS != nil {
+		w.opts.OBS.Wake()
+	}
+}
+
+// setPresenting records whether the user is presenting
+func (w *Watcher) setPresenting(presenting bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if presenting == w.presenting {
+		return
+	}
+
+	w.presenting = presenting
+	if presenting {
+		w.logger.Info("Presentation mode or do not disturb turned on")
+	} else {
+		w.logger.Info("Presentation mode or do not disturb turned off")
+	}
+	w.updatePause()
+}
+
+// updatePause pauses task processing while the computer sleeps, or while
+// the user presents if configured to, and resumes the tasks it paused
+// otherwise. Tasks the user paused are left paused. The caller must hold
+// mu.
+func (w *Watcher) updatePause() {
+	hold := w.asleep || (w.presenting && w.cfg.PauseInPresentation)
+	switch {
+	case hold && w.paused == nil:
+		w.paused = []Tasks{}
+		for _, tasks := range w.opts.Tasks {
+			if !tasks.Paused() {
+				tasks.Pause()
+				w.paused = append(w.paused, tasks)
+			}
+		}
+	case !hold && w.paused != nil:
+		for _, tasks := range w.paused {
+			tasks.Resume()
+		}
+		w.paused = nil
+	}
+}
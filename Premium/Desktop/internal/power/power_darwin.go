@@ -0,0 +1,38 @@
+package power
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// checkTimeout bounds the command reading the Focus state
+const checkTimeout = 2 * time.Second
+
+// notifySleep reports that sleep notifications are not received; they need
+// IOKit, which builds without cgo lack
+func notifySleep(ctx context.Context, events chan<- Event, logger *logrus.Logger) bool {
+	return false
+}
+
+// presentationMode reports whether a Focus mode, such as Do Not Disturb,
+// is on. Control Center shows the Focus menu bar item while one is.
+func presentationMode(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "defaults", "read", "com.apple.controlcenter", "NSStatusItem Visible FocusModes").Output()
+	if err != nil {
+		// The setting is missing until Focus is first used
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, nil
+		}
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) == "1", nil
+}
@@ -0,0 +1,19 @@
+//go:build !windows && !darwin
+
+package power
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// notifySleep reports that sleep notifications are not received
+func notifySleep(ctx context.Context, events chan<- Event, logger *logrus.Logger) bool {
+	return false
+}
+
+// presentationMode is not detected on this platform
+func presentationMode(ctx context.Context) (bool, error) {
+	return false, errUnsupported
+}
@@ -0,0 +1,91 @@
+package power
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+type fakeOBS struct{ suspended, woken int }
+
+func (f *fakeOBS) Suspend() { f.suspended++ }
+func (f *fakeOBS) Wake()    { f.woken++ }
+
+type fakeBridge struct{ reconnected int }
+
+func (f *fakeBridge) Reconnect() { f.reconnected++ }
+
+type fakeTasks struct {
+	paused      bool
+	reconnected int
+}
+
+func (f *fakeTasks) Pause()       { f.paused = true }
+func (f *fakeTasks) Resume()      { f.paused = false }
+func (f *fakeTasks) Paused() bool { return f.paused }
+func (f *fakeTasks) Reconnect()   { f.reconnected++ }
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestWatcher_SleepAndWake(t *testing.T) {
+	obsClient := &fakeOBS{}
+	bridge := &fakeBridge{}
+	running, userPaused := &fakeTasks{}, &fakeTasks{paused: true}
+	watcher := New(config.PowerConfig{CheckInterval: time.Second}, Options{
+		OBS:     obsClient,
+		Bridges: []Bridge{bridge},
+		Tasks:   []Tasks{running, userPaused},
+	}, quietLogger())
+
+	watcher.sleep()
+	watcher.sleep()
+	if !running.paused || obsClient.suspended != 1 {
+		t.Fatalf("Expected tasks paused and OBS suspended once, got %+v %+v", running, obsClient)
+	}
+
+	watcher.wake(time.Hour)
+	if running.paused {
+		t.Error("Expected tasks paused for sleep to resume on wake")
+	}
+	if !userPaused.paused {
+		t.Error("Expected tasks paused by the user to stay paused")
+	}
+	if bridge.reconnected != 1 || running.reconnected != 1 || obsClient.woken != 1 {
+		t.Errorf("Expected everything to reconnect on wake, got %+v %+v %+v", bridge, running, obsClient)
+	}
+}
+
+func TestWatcher_Presentation(t *testing.T) {
+	tasks := &fakeTasks{}
+	watcher := New(config.PowerConfig{CheckInterval: time.Second}, Options{Tasks: []Tasks{tasks}}, quietLogger())
+
+	watcher.setPresenting(true)
+	if !watcher.Presenting() || tasks.paused {
+		t.Error("Expected presentation mode to leave tasks running by default")
+	}
+
+	watcher = New(config.PowerConfig{CheckInterval: time.Second, PauseInPresentation: true}, Options{Tasks: []Tasks{tasks}}, quietLogger())
+	watcher.setPresenting(true)
+	if !tasks.paused {
+		t.Fatal("Expected tasks paused while presenting")
+	}
+
+	// Waking while presenting keeps tasks paused until the presentation ends
+	watcher.sleep()
+	watcher.wake(0)
+	if !tasks.paused {
+		t.Error("Expected tasks to stay paused while presenting")
+	}
+	watcher.setPresenting(false)
+	if tasks.paused {
+		t.Error("Expected tasks to resume after the presentation")
+	}
+}
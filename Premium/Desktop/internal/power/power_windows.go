@@ -0,0 +1,109 @@
+package power
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	powrprof = syscall.NewLazyDLL("powrprof.dll")
+	shell32  = syscall.NewLazyDLL("shell32.dll")
+
+	procRegisterSuspendResume   = powrprof.NewProc("PowerRegisterSuspendResumeNotification")
+	procUnregisterSuspendResume = powrprof.NewProc("PowerUnregisterSuspendResumeNotification")
+	procQueryNotificationState  = shell32.NewProc("SHQueryUserNotificationState")
+)
+
+const (
+	deviceNotifyCallback = 2 // DEVICE_NOTIFY_CALLBACK
+
+	pbtAPMSuspend         = 0x4  // PBT_APMSUSPEND
+	pbtAPMResumeAutomatic = 0x12 // PBT_APMRESUMEAUTOMATIC, sent on every wake
+
+	qunsPresentationMode = 4 // QUNS_PRESENTATION_MODE
+)
+
+// deviceNotifySubscribeParameters is DEVICE_NOTIFY_SUBSCRIBE_PARAMETERS
+type deviceNotifySubscribeParameters struct {
+	callback uintptr
+	context  uintptr
+}
+
+var (
+	// Callbacks are never freed, so one is created for the process and
+	// delivers to the watcher registered last
+	callbackOnce sync.Once
+	callback     uintptr
+	sleepMu      sync.Mutex
+	sleepEvents  chan<- Event
+)
+
+// notifySleep registers for the system's suspend and resume notifications
+func notifySleep(ctx context.Context, events chan<- Event, logger *logrus.Logger) bool {
+	if procRegisterSuspendResume.Find() != nil {
+		return false
+	}
+
+	callbackOnce.Do(func() {
+		callback = syscall.NewCallback(func(_, eventType, _ uintptr) uintptr {
+			var event Event
+			switch eventType {
+			case pbtAPMSuspend:
+				event = EventSleep
+			case pbtAPMResumeAutomatic:
+				event = EventWake
+			default:
+				return 0
+			}
+			sleepMu.Lock()
+			defer sleepMu.Unlock()
+			if sleepEvents != nil {
+				select {
+				case sleepEvents <- event:
+				default:
+				}
+			}
+			return 0
+		})
+	})
+
+	sleepMu.Lock()
+	sleepEvents = events
+	sleepMu.Unlock()
+
+	params := &deviceNotifySubscribeParameters{callback: callback}
+	var handle uintptr
+	r, _, _ := procRegisterSuspendResume.Call(deviceNotifyCallback, uintptr(unsafe.Pointer(params)), uintptr(unsafe.Pointer(&handle)))
+	if r != 0 {
+		logger.WithError(syscall.Errno(r)).Warn("Failed to register for sleep notifications")
+		return false
+	}
+
+	go func() {
+		<-ctx.Done()
+		procUnregisterSuspendResume.Call(handle)
+		sleepMu.Lock()
+		sleepEvents = nil
+		sleepMu.Unlock()
+	}()
+	return true
+}
+
+// presentationMode reports whether Windows is in presentation mode
+func presentationMode(ctx context.Context) (bool, error) {
+	if procQueryNotificationState.Find() != nil {
+		return false, errUnsupported
+	}
+
+	var state int32
+	r, _, _ := procQueryNotificationState.Call(uintptr(unsafe.Pointer(&state)))
+	if r != 0 {
+		return false, fmt.Errorf("SHQueryUserNotificationState failed: 0x%x", r)
+	}
+	return state == qunsPresentationMode, nil
+}
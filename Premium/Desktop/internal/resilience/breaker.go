@@ -0,0 +1,145 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// Breaker stops calls to a failing API. After threshold consecutive
+// failures it opens and rejects calls for the cooldown period, then lets a
+// single trial call through; the trial's outcome closes or reopens it.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	trial     bool
+	opens     int
+	rejected  int
+	now       func() time.Time
+}
+
+// NewBreaker creates a circuit breaker. A threshold of zero or less
+// disables it.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     StateClosed,
+		now:       time.Now,
+	}
+}
+
+// Allow returns ErrCircuitOpen if a call may not be made now
+func (b *Breaker) Allow() error {
+	if b == nil || b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.state = StateHalfOpen
+		b.trial = true
+		return nil
+	case StateHalfOpen:
+		if b.trial {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.trial = true
+		return nil
+	}
+
+	return nil
+}
+
+// Success records a successful call
+func (b *Breaker) Success() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+	b.state = StateClosed
+}
+
+// Failure records a failed call
+func (b *Breaker) Failure() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		if b.state != StateOpen {
+			b.opens++
+		}
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// release ends a half-open trial without an outcome, letting the next call
+// be the trial instead
+func (b *Breaker) release() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// State returns the current breaker state
+func (b *Breaker) State() string {
+	if b == nil || b.threshold <= 0 {
+		return StateClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Stats returns breaker statistics
+func (b *Breaker) Stats() map[string]interface{} {
+	if b == nil || b.threshold <= 0 {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return map[string]interface{}{
+		"enabled":              true,
+		"state":                b.state,
+		"consecutive_failures": b.failures,
+		"opens":                b.opens,
+		"rejected":             b.rejected,
+	}
+}
@@ -0,0 +1,218 @@
+package resilience
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func testHTTPConfig() config.HTTPConfig {
+	return config.HTTPConfig{
+		RetryMax:         3,
+		RetryWait:        time.Millisecond,
+		RetryMaxWait:     5 * time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	}
+}
+
+func TestTransport_RetriesIdempotentRequests(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport, testHTTPConfig(), nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 after retries, got %d", resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestTransport_RetriesPostWithIdempotencyKey(t *testing.T) {
+	var calls int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := NewTransport(http.DefaultTransport, testHTTPConfig(), nil)
+	resigned := 0
+	transport.Resign = func(req *http.Request, body []byte) error {
+		resigned++
+		req.Header.Set(headerSignature, "resigned:"+string(body))
+		return nil
+	}
+	client := &http.Client{Transport: transport}
+
+	// Without an idempotency key a POST is not retried
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader(`{"n":1}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || calls != 1 {
+		t.Fatalf("expected a single attempt returning 502, got %d after %d calls", resp.StatusCode, calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	bodies = nil
+	req, _ = http.NewRequest("POST", server.URL, strings.NewReader(`{"n":2}`))
+	req.Header.Set(HeaderIdempotencyKey, "task-1")
+	req.Header.Set(headerSignature, "original")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Errorf("expected success on the second attempt, got %d after %d calls", resp.StatusCode, calls)
+	}
+	if len(bodies) != 2 || bodies[1] != `{"n":2}` {
+		t.Errorf("expected body to be resent, got %q", bodies)
+	}
+	if resigned != 1 {
+		t.Errorf("expected retried request to be re-signed once, got %d", resigned)
+	}
+}
+
+func TestTransport_CircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := testHTTPConfig()
+	cfg.RetryMax = 0
+	transport := NewTransport(http.DefaultTransport, cfg, nil)
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected open breaker to stop calls, server saw %d", calls)
+	}
+	if transport.Breaker.State() != StateOpen {
+		t.Errorf("expected breaker open, got %s", transport.Breaker.State())
+	}
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	breaker := NewBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open breaker, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("expected trial call after cooldown, got %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("expected only one trial call while half-open")
+	}
+
+	breaker.Failure()
+	if breaker.State() != StateOpen {
+		t.Errorf("expected failed trial to reopen breaker, got %s", breaker.State())
+	}
+
+	now = now.Add(2 * time.Minute)
+	breaker.Allow()
+	breaker.Success()
+	if breaker.State() != StateClosed {
+		t.Errorf("expected successful trial to close breaker, got %s", breaker.State())
+	}
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	breaker := NewBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		breaker.Failure()
+	}
+	if err := breaker.Allow(); err != nil {
+		t.Errorf("expected disabled breaker to allow calls, got %v", err)
+	}
+}
+
+func TestConfigureTransport(t *testing.T) {
+	transport := &http.Transport{}
+	cfg := config.HTTPConfig{
+		Proxy:               "http://proxy.local:3128",
+		MaxIdleConnsPerHost: 20,
+		IdleConnTimeout:     time.Minute,
+	}
+	if err := ConfigureTransport(transport, cfg); err != nil {
+		t.Fatalf("ConfigureTransport failed: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "https://api.waddlebot.io", nil)
+	proxyURL, err := transport.Proxy(req)
+	if err != nil || proxyURL == nil || proxyURL.Host != "proxy.local:3128" {
+		t.Errorf("expected proxy.local:3128, got %v (%v)", proxyURL, err)
+	}
+	if transport.MaxIdleConnsPerHost != 20 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("expected pool settings to be applied, got %d and %s", transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+
+	if err := ConfigureTransport(transport, config.HTTPConfig{Proxy: "::bad"}); err == nil {
+		t.Error("expected error for invalid proxy URL")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Now()
+	if wait, ok := retryAfter("3", now); !ok || wait != 3*time.Second {
+		t.Errorf("expected 3s, got %s (%v)", wait, ok)
+	}
+	date := now.Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if wait, ok := retryAfter(date, now); !ok || wait < 8*time.Second || wait > 11*time.Second {
+		t.Errorf("expected about 10s, got %s (%v)", wait, ok)
+	}
+	if _, ok := retryAfter("soon", now); ok {
+		t.Error("expected invalid value to be ignored")
+	}
+}
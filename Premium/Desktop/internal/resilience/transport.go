@@ -0,0 +1,267 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"waddlebot-bridge/internal/config"
+)
+
+// ErrCircuitOpen is returned while the circuit breaker is rejecting calls
+var ErrCircuitOpen = errors.New("API calls paused after repeated failures")
+
+// HeaderIdempotencyKey marks a non-GET request as safe to retry
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// headerSignature is re-signed on retries; it matches signing.HeaderSignature
+const headerSignature = "X-WaddleBot-Signature"
+
+// Transport wraps an http.RoundTripper with retries and a circuit breaker.
+// Idempotent requests, and requests carrying an Idempotency-Key header, are
+// retried on network errors and 429, 502, 503 and 504 responses with
+// exponential backoff, honoring Retry-After. Network errors and 5xx
+// responses count as failures for the breaker.
+type Transport struct {
+	Base         http.RoundTripper
+	Breaker      *Breaker
+	RetryMax     int
+	RetryWait    time.Duration
+	RetryMaxWait time.Duration
+
+	// Resign re-signs a retried request, since signatures carry a
+	// timestamp and nonce and must not be replayed
+	Resign func(req *http.Request, body []byte) error
+
+	logger *logrus.Logger
+}
+
+// NewTransport wraps base with retries and a circuit breaker configured
+// from cfg
+func NewTransport(base http.RoundTripper, cfg config.HTTPConfig, logger *logrus.Logger) *Transport {
+	return &Transport{
+		Base:         base,
+		Breaker:      NewBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		RetryMax:     cfg.RetryMax,
+		RetryWait:    cfg.RetryWait,
+		RetryMaxWait: cfg.RetryMaxWait,
+		logger:       logger,
+	}
+}
+
+// ConfigureTransport applies proxy and connection pool settings. Without
+// an explicit proxy, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored.
+func ConfigureTransport(t *http.Transport, cfg config.HTTPConfig) error {
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q", cfg.Proxy)
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+	} else {
+		t.Proxy = http.ProxyFromEnvironment
+	}
+
+	if cfg.MaxIdleConns > 0 {
+		t.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.Breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	retryable := isIdempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+
+	attemptReq := req
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base().RoundTrip(attemptReq)
+
+		if !retryable || attempt >= t.RetryMax || !shouldRetry(req.Context(), resp, err) {
+			t.record(req, resp, err)
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		if t.logger != nil {
+			fields := logrus.Fields{
+				"method":  req.Method,
+				"path":    req.URL.Path,
+				"attempt": attempt + 1,
+				"wait":    wait,
+			}
+			if err != nil {
+				fields["error"] = err.Error()
+			} else {
+				fields["status"] = resp.StatusCode
+			}
+			t.logger.WithFields(fields).Debug("Retrying API request")
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			t.Breaker.release()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		attemptReq, err = t.rewind(req)
+		if err != nil {
+			t.Breaker.Failure()
+			return nil, err
+		}
+	}
+}
+
+// Stats returns breaker statistics
+func (t *Transport) Stats() map[string]interface{} {
+	return t.Breaker.Stats()
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// record feeds a call's final outcome to the breaker. Calls cancelled by
+// the caller say nothing about the API's health.
+func (t *Transport) record(req *http.Request, resp *http.Response, err error) {
+	if err != nil && req.Context().Err() != nil {
+		t.Breaker.release()
+		return
+	}
+	if err != nil || resp.StatusCode >= 500 {
+		t.Breaker.Failure()
+	} else {
+		t.Breaker.Success()
+	}
+}
+
+// rewind builds a fresh copy of req for another attempt
+func (t *Transport) rewind(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		clone.Body, _ = req.GetBody()
+	}
+
+	if t.Resign != nil && clone.Header.Get(headerSignature) != "" {
+		if err := t.Resign(clone, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return clone, nil
+}
+
+// backoff returns how long to wait before the next attempt
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	maxWait := t.RetryMaxWait
+	if maxWait <= 0 {
+		maxWait = 10 * time.Second
+	}
+
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if wait > maxWait {
+				wait = maxWait
+			}
+			return wait
+		}
+	}
+
+	wait := t.RetryWait
+	if wait <= 0 {
+		wait = 500 * time.Millisecond
+	}
+	for i := 0; i < attempt && wait < maxWait; i++ {
+		wait *= 2
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(wait-half)+1))
+}
+
+// isIdempotent reports whether a request is safe to send more than once
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(HeaderIdempotencyKey) != ""
+}
+
+// shouldRetry reports whether an attempt's outcome is worth retrying
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if wait := when.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
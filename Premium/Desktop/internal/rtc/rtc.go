@@ -0,0 +1,440 @@
+// Package rtc links the bridge to the community's calls. It lists the
+// community's rooms, fetches join tokens and mutes or raises the hand of the
+// bridge's user through the API of WaddleBot's RTC module, and adds a
+// room's composite view to OBS as a browser source, so a call can be shown
+// on stream. The source loads a page of the bridge's that sends it on to the
+// view with a new token each time, since join tokens expire long before a
+// source is removed.
+package rtc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/obs"
+)
+
+// BrowserSourceKind is the OBS input kind of browser sources
+const BrowserSourceKind = "browser_source"
+
+// viewerSuffix is appended to the user's ID for the identity the composite
+// view joins as, so it does not take the user's own place in the call
+const viewerSuffix = "-obs"
+
+// Default size of a composite view browser source
+const (
+	defaultWidth  = 1920
+	defaultHeight = 1080
+)
+
+// maxResponseSize bounds the RTC module's responses
+const maxResponseSize = 1 << 20
+
+var (
+	// ErrNotConfigured is returned for adding a room to OBS without the
+	// composite view's page and LiveKit server configured
+	ErrNotConfigured = errors.New("rtc.view-url and rtc.livekit-url are required to add a room to OBS")
+
+	// ErrInvalidRequest is returned for a request that cannot be sent, such
+	// as one naming no room
+	ErrInvalidRequest = errors.New("invalid RTC request")
+
+	// ErrOBSUnavailable is returned for adding a room to OBS while the bridge
+	// has no OBS connection
+	ErrOBSUnavailable = errors.New("OBS is not available")
+)
+
+// roomName is what the RTC module's room names may contain
+var roomName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,254}$`)
+
+// APIError is an error answered by the RTC module
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"error"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("RTC module returned status %d", e.Status)
+	}
+	return fmt.Sprintf("RTC module returned status %d: %s", e.Status, e.Message)
+}
+
+// TokenSource provides authentication tokens for API requests
+type TokenSource interface {
+	GetAuthToken() (string, error)
+}
+
+// OBS is the part of the OBS client browser sources are added with
+type OBS interface {
+	IsConnected() bool
+	GetInputSettings(ctx context.Context, inputName string) (string, map[string]interface{}, error)
+	CreateInput(ctx context.Context, sceneName, inputName, inputKind string, settings map[string]interface{}) error
+	SetInputSettings(ctx context.Context, inputName string, settings map[string]interface{}) error
+}
+
+// Room is one of the community's rooms
+type Room struct {
+	RoomName     string    `json:"room_name"`
+	CommunityID  int       `json:"community_id"`
+	Participants int       `json:"participants"`
+	CreatedAt    time.Time `json:"created_at"`
+	IsLocked     bool      `json:"is_locked"`
+	Template     string    `json:"template,omitempty"`
+	Lobby        bool      `json:"lobby"`
+	Active       bool      `json:"active"`
+	Recording    bool      `json:"recording"`
+}
+
+// RoomPage is a page of the community's rooms
+type RoomPage struct {
+	Rooms  []Room `json:"rooms"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// RoomFilter selects and orders the rooms listed. Empty fields are left to
+// the RTC module's defaults.
+type RoomFilter struct {
+	States []string
+	Sort   string
+	Order  string // asc or desc
+	Limit  int
+	Offset int
+}
+
+// JoinToken is a token to join a room with. URL is the LiveKit server to
+// connect to, when configured.
+type JoinToken struct {
+	Token     string    `json:"token"`
+	RoomName  string    `json:"room_name"`
+	Identity  string    `json:"identity"`
+	Lobby     bool      `json:"lobby"`
+	ExpiresAt time.Time `json:"expires_at"`
+	URL       string    `json:"url,omitempty"`
+}
+
+// BrowserSource describes the browser source a room's composite view is
+// shown in. An input with the name already in OBS is pointed at the room
+// instead of created. PageURL is the page the source loads, which redirects
+// to ViewerURL, so the view joins with a fresh token whenever OBS loads it.
+type BrowserSource struct {
+	Scene   string `json:"scene"`
+	Name    string `json:"name"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Layout  string `json:"layout,omitempty"`
+	PageURL string `json:"-"`
+}
+
+// BrowserSourceResult is the browser source showing a room
+type BrowserSourceResult struct {
+	BrowserSource
+	RoomName string `json:"room_name"`
+	Created  bool   `json:"created"`
+}
+
+// Client talks to the RTC module as the bridge's user
+type Client struct {
+	cfg         config.RTCConfig
+	baseURL     string
+	communityID string
+	userID      string
+	userAgent   string
+	tokens      TokenSource
+	httpClient  *http.Client
+	obs         OBS // nil without OBS
+	logger      *logrus.Logger
+}
+
+// NewClient creates a client for the community and user of cfg. Requests
+// are authenticated with tokens and sent with httpClient, so they use the
+// API's TLS settings.
+func NewClient(cfg *config.Config, tokens TokenSource, httpClient *http.Client, obsClient OBS, logger *logrus.Logger) *Client {
+	baseURL := cfg.RTC.URL
+	if baseURL == "" {
+		baseURL = cfg.APIURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		cfg:         cfg.RTC,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		communityID: cfg.CommunityID,
+		userID:      cfg.UserID,
+		userAgent:   cfg.GetUserAgent(),
+		tokens:      tokens,
+		httpClient:  httpClient,
+		obs:         obsClient,
+		logger:      logger,
+	}
+}
+
+// ListRooms returns a page of the community's rooms
+func (c *Client) ListRooms(ctx context.Context, filter RoomFilter) (*RoomPage, error) {
+	query := url.Values{}
+	for _, state := range filter.States {
+		query.Add("state", state)
+	}
+	if filter.Sort != "" {
+		query.Set("sort", filter.Sort)
+	}
+	if filter.Order != "" {
+		query.Set("order", filter.Order)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+
+	path := "/api/v1/communities/" + url.PathEscape(c.communityID) + "/rooms"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var page RoomPage
+	if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	if page.Rooms == nil {
+		page.Rooms = []Room{}
+	}
+	return &page, nil
+}
+
+// JoinToken fetches a token for the user to join a room with. An empty
+// role joins with the role the user was last given in the room.
+func (c *Client) JoinToken(ctx context.Context, room, role string) (*JoinToken, error) {
+	return c.joinToken(ctx, room, map[string]string{"role": role})
+}
+
+func (c *Client) joinToken(ctx context.Context, room string, request map[string]string) (*JoinToken, error) {
+	if err := checkRoom(room); err != nil {
+		return nil, err
+	}
+
+	var token JoinToken
+	if err := c.do(ctx, http.MethodPost, roomPath(room, "join"), request, &token); err != nil {
+		return nil, err
+	}
+	token.URL = c.cfg.LiveKitURL
+	return &token, nil
+}
+
+// Mute mutes the user's microphone in a room
+func (c *Client) Mute(ctx context.Context, room string) error {
+	if err := checkRoom(room); err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, roomPath(room, "mute/"+url.PathEscape(c.userID)), struct{}{}, nil)
+}
+
+// RaiseHand raises the user's hand in a room
+func (c *Client) RaiseHand(ctx context.Context, room string) error {
+	if err := checkRoom(room); err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, roomPath(room, "raise-hand"), struct{}{}, nil)
+}
+
+// LowerHand lowers the user's hand in a room
+func (c *Client) LowerHand(ctx context.Context, room string) error {
+	if err := checkRoom(room); err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, roomPath(room, "lower-hand"), map[string]string{"user_id": c.userID}, nil)
+}
+
+// ViewURL returns the address of a room's composite view, joining with
+// token in the given layout, or the configured one when empty
+func (c *Client) ViewURL(token *JoinToken, layout string) (string, error) {
+	if c.cfg.ViewURL == "" || c.cfg.LiveKitURL == "" {
+		return "", ErrNotConfigured
+	}
+	if layout == "" {
+		layout = c.cfg.Layout
+	}
+
+	view, err := url.Parse(c.cfg.ViewURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid rtc.view-url: %w", err)
+	}
+	query := view.Query()
+	query.Set("url", c.cfg.LiveKitURL)
+	query.Set("token", token.Token)
+	if layout != "" {
+		query.Set("layout", layout)
+	}
+	view.RawQuery = query.Encode()
+	return view.String(), nil
+}
+
+// ViewerURL returns the address of a room's composite view in the given
+// layout, joining with a new token. The view joins as a viewer under its own
+// identity, the user's ID with "-obs" added, so the user can stay in the
+// call; asking for a token for another identity takes a community moderator.
+func (c *Client) ViewerURL(ctx context.Context, room, layout string) (string, error) {
+	if c.cfg.ViewURL == "" || c.cfg.LiveKitURL == "" {
+		return "", ErrNotConfigured
+	}
+	token, err := c.joinToken(ctx, room, map[string]string{
+		"user_id": c.userID + viewerSuffix,
+		"role":    "viewer",
+	})
+	if err != nil {
+		return "", err
+	}
+	return c.ViewURL(token, layout)
+}
+
+// AddBrowserSource shows a room's composite view in OBS, in a browser source
+// loading source.PageURL. A view is fetched first, so a room the view may
+// not join is refused now rather than when OBS loads it.
+func (c *Client) AddBrowserSource(ctx context.Context, room string, source BrowserSource) (*BrowserSourceResult, error) {
+	if source.Scene == "" || source.Name == "" {
+		return nil, fmt.Errorf("%w: scene and name are required", ErrInvalidRequest)
+	}
+	if source.PageURL == "" {
+		return nil, fmt.Errorf("%w: page URL is required", ErrInvalidRequest)
+	}
+	if source.Width < 0 || source.Height < 0 {
+		return nil, fmt.Errorf("%w: width and height must not be negative", ErrInvalidRequest)
+	}
+	if source.Width == 0 {
+		source.Width = defaultWidth
+	}
+	if source.Height == 0 {
+		source.Height = defaultHeight
+	}
+	if c.cfg.ViewURL == "" || c.cfg.LiveKitURL == "" {
+		return nil, ErrNotConfigured
+	}
+	if c.obs == nil || !c.obs.IsConnected() {
+		return nil, ErrOBSUnavailable
+	}
+
+	if _, err := c.ViewerURL(ctx, room, source.Layout); err != nil {
+		return nil, err
+	}
+
+	// The call's audio is played through OBS, so it can be mixed
+	settings := map[string]interface{}{
+		"url":           source.PageURL,
+		"width":         source.Width,
+		"height":        source.Height,
+		"reroute_audio": true,
+	}
+	result := &BrowserSourceResult{BrowserSource: source, RoomName: room}
+
+	kind, _, err := c.obs.GetInputSettings(ctx, source.Name)
+	switch {
+	case errors.Is(err, obs.ErrSourceNotFound):
+		if err := c.obs.CreateInput(ctx, source.Scene, source.Name, BrowserSourceKind, settings); err != nil {
+			return nil, err
+		}
+		result.Created = true
+	case err != nil:
+		return nil, err
+	case kind != BrowserSourceKind:
+		return nil, fmt.Errorf("%w: input %q is a %s, not a browser source", ErrInvalidRequest, source.Name, kind)
+	default:
+		if err := c.obs.SetInputSettings(ctx, source.Name, settings); err != nil {
+			return nil, err
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"room":    room,
+		"scene":   source.Scene,
+		"source":  source.Name,
+		"created": result.Created,
+	}).Info("Showing call in OBS browser source")
+
+	return result, nil
+}
+
+// do sends a request to the RTC module, decoding the response into out
+// when it is not nil
+func (c *Client) do(ctx context.Context, method, path string, payload, out interface{}) error {
+	authToken, err := c.tokens.GetAuthToken()
+	if err != nil {
+		return fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+authToken)
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach RTC module: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{Status: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// checkRoom refuses room names the RTC module would not know
+func checkRoom(room string) error {
+	if !roomName.MatchString(room) {
+		return fmt.Errorf("%w: invalid room name %q", ErrInvalidRequest, room)
+	}
+	return nil
+}
+
+// roomPath returns the API path of an action on a room
+func roomPath(room, action string) string {
+	return "/api/v1/rooms/" + url.PathEscape(room) + "/" + action
+}
@@ -0,0 +1,235 @@
+package rtc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/obs"
+)
+
+type staticToken string
+
+func (t staticToken) GetAuthToken() (string, error) { return string(t), nil }
+
+// fakeOBS records the inputs created and updated. Looking inputs up fails
+// with err when set.
+type fakeOBS struct {
+	kinds    map[string]string
+	settings map[string]map[string]interface{}
+	scenes   map[string]string
+	err      error
+}
+
+func newFakeOBS() *fakeOBS {
+	return &fakeOBS{
+		kinds:    make(map[string]string),
+		settings: make(map[string]map[string]interface{}),
+		scenes:   make(map[string]string),
+	}
+}
+
+func (f *fakeOBS) IsConnected() bool { return true }
+
+func (f *fakeOBS) GetInputSettings(ctx context.Context, inputName string) (string, map[string]interface{}, error) {
+	if f.err != nil {
+		return "", nil, f.err
+	}
+	kind, ok := f.kinds[inputName]
+	if !ok {
+		return "", nil, obs.ErrSourceNotFound
+	}
+	return kind, f.settings[inputName], nil
+}
+
+func (f *fakeOBS) CreateInput(ctx context.Context, sceneName, inputName, inputKind string, settings map[string]interface{}) error {
+	f.kinds[inputName] = inputKind
+	f.settings[inputName] = settings
+	f.scenes[inputName] = sceneName
+	return nil
+}
+
+func (f *fakeOBS) SetInputSettings(ctx context.Context, inputName string, settings map[string]interface{}) error {
+	f.settings[inputName] = settings
+	return nil
+}
+
+// rtcRequest is a request the fake RTC module received
+type rtcRequest struct {
+	method, path, query, auth string
+	body                      map[string]string
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *fakeOBS, *[]rtcRequest) {
+	t.Helper()
+	var requests []rtcRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := rtcRequest{method: r.Method, path: r.URL.EscapedPath(), query: r.URL.RawQuery, auth: r.Header.Get("Authorization")}
+		json.NewDecoder(r.Body).Decode(&req.body)
+		requests = append(requests, req)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{
+		APIURL:      server.URL + "/",
+		CommunityID: "7",
+		UserID:      "42",
+		RTC: config.RTCConfig{
+			Enabled:    true,
+			LiveKitURL: "wss://livekit.example.com",
+			ViewURL:    "https://calls.example.com/composite",
+			Layout:     "grid",
+		},
+	}
+	fake := newFakeOBS()
+	return NewClient(cfg, staticToken("token"), server.Client(), fake, logger), fake, &requests
+}
+
+func TestClient_ListRooms(t *testing.T) {
+	client, _, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rooms":[{"room_name":"community_7_lobby","participants":3,"active":true}],"total":1,"limit":10}`))
+	})
+
+	page, err := client.ListRooms(context.Background(), RoomFilter{States: []string{"active", "locked"}, Sort: "name", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to list rooms: %v", err)
+	}
+	if len(page.Rooms) != 1 || page.Rooms[0].RoomName != "community_7_lobby" || page.Rooms[0].Participants != 3 || page.Total != 1 {
+		t.Errorf("Expected the community's room, got %+v", page)
+	}
+
+	req := (*requests)[0]
+	query, _ := url.ParseQuery(req.query)
+	if req.method != http.MethodGet || req.path != "/api/v1/communities/7/rooms" || req.auth != "Bearer token" {
+		t.Errorf("Expected the community's rooms asked for with the bridge's token, got %+v", req)
+	}
+	if len(query["state"]) != 2 || query.Get("sort") != "name" || query.Get("limit") != "10" || query.Has("offset") {
+		t.Errorf("Expected the filter sent, got %q", req.query)
+	}
+}
+
+func TestClient_JoinTokenAndActions(t *testing.T) {
+	client, _, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/rooms/community_7_lobby/join":
+			w.Write([]byte(`{"token":"jwt","room_name":"community_7_lobby","identity":"42","expires_at":"2030-01-01T00:00:00Z"}`))
+		case "/api/v1/rooms/community_7_locked/join":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"Room is locked","code":"room_locked"}`))
+		default:
+			w.Write([]byte(`{"success":true}`))
+		}
+	})
+	ctx := context.Background()
+
+	token, err := client.JoinToken(ctx, "community_7_lobby", "speaker")
+	if err != nil {
+		t.Fatalf("Failed to get join token: %v", err)
+	}
+	if token.Token != "jwt" || token.Identity != "42" || token.URL != "wss://livekit.example.com" || token.ExpiresAt.IsZero() {
+		t.Errorf("Expected the token with the LiveKit server, got %+v", token)
+	}
+	if body := (*requests)[0].body; body["role"] != "speaker" {
+		t.Errorf("Expected the role asked for, got %v", body)
+	}
+
+	var apiErr *APIError
+	if _, err := client.JoinToken(ctx, "community_7_locked", ""); !errors.As(err, &apiErr) || apiErr.Status != http.StatusForbidden || apiErr.Code != "room_locked" {
+		t.Errorf("Expected the RTC module's error, got %v", err)
+	}
+	if _, err := client.JoinToken(ctx, "../bridge", ""); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected a bad room name refused, got %v", err)
+	}
+
+	*requests = nil
+	client.Mute(ctx, "community_7_lobby")
+	client.RaiseHand(ctx, "community_7_lobby")
+	client.LowerHand(ctx, "community_7_lobby")
+	paths := []string{"/api/v1/rooms/community_7_lobby/mute/42", "/api/v1/rooms/community_7_lobby/raise-hand", "/api/v1/rooms/community_7_lobby/lower-hand"}
+	if len(*requests) != len(paths) {
+		t.Fatalf("Expected %d requests, got %+v", len(paths), *requests)
+	}
+	for i, path := range paths {
+		if req := (*requests)[i]; req.method != http.MethodPost || req.path != path {
+			t.Errorf("Expected POST %s, got %s %s", path, req.method, req.path)
+		}
+	}
+	if body := (*requests)[2].body; body["user_id"] != "42" {
+		t.Errorf("Expected the user's hand lowered, got %v", body)
+	}
+}
+
+func TestClient_AddBrowserSource(t *testing.T) {
+	client, fake, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"view jwt","room_name":"community_7_lobby","identity":"42-obs","expires_at":"2030-01-01T00:00:00Z"}`))
+	})
+	ctx := context.Background()
+
+	view, err := client.ViewerURL(ctx, "community_7_lobby", "")
+	if err != nil {
+		t.Fatalf("Failed to get the view: %v", err)
+	}
+	if body := (*requests)[0].body; body["user_id"] != "42-obs" || body["role"] != "viewer" {
+		t.Errorf("Expected the view to join as a viewer under its own identity, got %v", body)
+	}
+	viewURL, _ := url.Parse(view)
+	query := viewURL.Query()
+	if viewURL.Host != "calls.example.com" || query.Get("url") != "wss://livekit.example.com" || query.Get("token") != "view jwt" || query.Get("layout") != "grid" {
+		t.Errorf("Expected the composite view of the room, got %s", view)
+	}
+
+	// The source loads the page given, which fetches the view on each load
+	page := "http://127.0.0.1:8090/api/v1/rtc/rooms/community_7_lobby/view"
+	result, err := client.AddBrowserSource(ctx, "community_7_lobby", BrowserSource{Scene: "Call", Name: "Guests", PageURL: page})
+	if err != nil {
+		t.Fatalf("Failed to add browser source: %v", err)
+	}
+	if !result.Created || result.Width != defaultWidth || result.Height != defaultHeight {
+		t.Errorf("Expected a full HD source created, got %+v", result)
+	}
+	if fake.kinds["Guests"] != BrowserSourceKind || fake.scenes["Guests"] != "Call" || fake.settings["Guests"]["url"] != page {
+		t.Fatalf("Expected a browser source in the scene, got %v %v %v", fake.kinds, fake.scenes, fake.settings)
+	}
+
+	result, err = client.AddBrowserSource(ctx, "community_7_lobby", BrowserSource{Scene: "Call", Name: "Guests", PageURL: page + "?layout=speaker"})
+	if err != nil || result.Created {
+		t.Errorf("Expected the source updated, got %+v, %v", result, err)
+	}
+	if fake.settings["Guests"]["url"] != page+"?layout=speaker" {
+		t.Errorf("Expected the source pointed at the new page, got %v", fake.settings["Guests"]["url"])
+	}
+
+	// Only an input OBS does not have is created
+	fake.err = obs.NewOBSError(obs.ErrOperationFailed, "request GetInputSettings: timeout waiting for response from server")
+	if _, err := client.AddBrowserSource(ctx, "community_7_lobby", BrowserSource{Scene: "Call", Name: "Stage", PageURL: page}); !errors.Is(err, obs.ErrOperationFailed) {
+		t.Errorf("Expected the lookup failure returned, got %v", err)
+	}
+	if _, ok := fake.kinds["Stage"]; ok {
+		t.Error("Expected no input created when looking it up failed")
+	}
+	fake.err = nil
+
+	fake.kinds["Webcam"] = "dshow_input"
+	if _, err := client.AddBrowserSource(ctx, "community_7_lobby", BrowserSource{Scene: "Call", Name: "Webcam", PageURL: page}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected another kind of input left alone, got %v", err)
+	}
+	if _, err := client.AddBrowserSource(ctx, "community_7_lobby", BrowserSource{Name: "Guests", PageURL: page}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected a source without a scene refused, got %v", err)
+	}
+
+	client.cfg.ViewURL = ""
+	if _, err := client.AddBrowserSource(ctx, "community_7_lobby", BrowserSource{Scene: "Call", Name: "Guests", PageURL: page}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Expected no view without rtc.view-url, got %v", err)
+	}
+}
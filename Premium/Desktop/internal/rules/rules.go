@@ -0,0 +1,610 @@
+// Package rules switches scenes automatically. A rule watches one
+// condition, such as a source going hidden, a mic going silent or a game
+// exiting, and runs its actions once the condition has held for a while;
+// when the condition clears the actions can be undone, so a BRB scene is
+// only shown while it is needed. Rules are managed through the local
+// gateway and persisted in storage.
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/storage"
+)
+
+// Condition types
+const (
+	ConditionSourceHidden      = "source_hidden"
+	ConditionAudioSilent       = "audio_silent"
+	ConditionProcessNotRunning = "process_not_running"
+	ConditionWindowTitle       = "window_title"
+)
+
+// Action types
+const (
+	ActionSwitchScene  = "switch_scene"
+	ActionToggleSource = "toggle_source"
+	ActionRunScript    = "run_script"
+)
+
+// Events published on the bus
+const (
+	EventTriggered = "rules.triggered"
+	EventCleared   = "rules.cleared"
+)
+
+// actionTimeout bounds each action and the OBS requests of a check
+const actionTimeout = 10 * time.Second
+
+var (
+	// ErrRuleNotFound is returned for an unknown rule ID
+	ErrRuleNotFound = errors.New("rule not found")
+
+	// ErrInvalidRule is returned for a rule that cannot be run
+	ErrInvalidRule = errors.New("invalid rule")
+)
+
+// Condition is what a rule watches
+type Condition struct {
+	Type    string `json:"type"`
+	Scene   string `json:"scene,omitempty"`   // scene holding the source; the current scene when empty
+	Source  string `json:"source,omitempty"`  // source for source_hidden, input for audio_silent
+	Process string `json:"process,omitempty"` // executable name for process_not_running
+	Pattern string `json:"pattern,omitempty"` // regular expression for window_title
+}
+
+// Action is run when a rule triggers
+type Action struct {
+	Type       string            `json:"type"`
+	Scene      string            `json:"scene,omitempty"`      // scene to switch to, or holding the source; the current scene when empty
+	Source     string            `json:"source,omitempty"`     // source for toggle_source
+	Visible    *bool             `json:"visible,omitempty"`    // visibility for toggle_source; flipped when unset
+	Script     string            `json:"script,omitempty"`     // script path for run_script, as in the command palette
+	Parameters map[string]string `json:"parameters,omitempty"` // script parameters
+}
+
+// Rule runs its actions once its condition has held for ForSeconds
+type Rule struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Enabled    bool      `json:"enabled"`
+	Condition  Condition `json:"condition"`
+	ForSeconds int       `json:"for_seconds"`
+	Actions    []Action  `json:"actions"`
+	Revert     bool      `json:"revert"` // undo scene and source changes once the condition clears
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// OBS is the part of the OBS client rules watch and control
+type OBS interface {
+	IsConnected() bool
+	GetCurrentScene(ctx context.Context) (*obs.SceneInfo, error)
+	SetCurrentScene(ctx context.Context, sceneName string) error
+	GetSourceInfo(ctx context.Context, sceneName, sourceName string) (*obs.SourceInfo, error)
+	SetSourceVisibility(ctx context.Context, sceneName, sourceName string, visible bool) error
+	QuietSince(input string) (time.Time, bool)
+}
+
+// Commands runs command palette commands, which is how scripts are run
+type Commands interface {
+	Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error)
+}
+
+// System reports what runs on the computer
+type System interface {
+	Processes(ctx context.Context) (map[string]bool, error)
+	ForegroundWindow(ctx context.Context) (string, error)
+}
+
+// ruleState is how far a rule has got
+type ruleState struct {
+	since time.Time // when the condition started holding, zero while it does not
+	fired bool
+	undo  []func(ctx context.Context) error
+}
+
+// Engine checks the enabled rules at an interval and runs their actions
+type Engine struct {
+	store     *storage.RuleRepo
+	obs       OBS
+	commands  Commands // nil when scripting is disabled
+	system    System
+	publisher events.Publisher // nil when the event bus is disabled
+	cfg       config.RulesConfig
+	logger    *logrus.Logger
+
+	mu     sync.RWMutex
+	rules  map[string]*Rule
+	states map[string]*ruleState
+}
+
+// NewEngine creates an engine with the rules persisted in store. commands
+// and publisher may be nil.
+func NewEngine(store storage.Storage, obsClient OBS, commands Commands, publisher events.Publisher, cfg config.RulesConfig, logger *logrus.Logger) (*Engine, error) {
+	repo, err := storage.NewRuleRepo(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rules bucket: %w", err)
+	}
+
+	e := &Engine{
+		store:     repo,
+		obs:       obsClient,
+		commands:  commands,
+		system:    localSystem{},
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+		rules:     make(map[string]*Rule),
+		states:    make(map[string]*ruleState),
+	}
+
+	stored, err := repo.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+	for id, data := range stored {
+		var rule Rule
+		if err := json.Unmarshal(data, &rule); err != nil {
+			logger.WithError(err).WithField("id", id).Warn("Skipping unreadable rule")
+			continue
+		}
+		e.rules[rule.ID] = &rule
+	}
+
+	if len(e.rules) > 0 {
+		logger.WithField("count", len(e.rules)).Info("Loaded scene switching rules")
+	}
+	return e, nil
+}
+
+// List returns the rules ordered by creation time
+func (e *Engine) List() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	list := make([]Rule, 0, len(e.rules))
+	for _, rule := range e.rules {
+		list = append(list, *rule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Get returns a rule
+func (e *Engine) Get(id string) (Rule, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rule, exists := e.rules[id]
+	if !exists {
+		return Rule{}, fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+	}
+	return *rule, nil
+}
+
+// Create validates and persists a new rule
+func (e *Engine) Create(rule Rule) (Rule, error) {
+	if err := Validate(rule); err != nil {
+		return Rule{}, err
+	}
+
+	rule.ID = "rule_" + uuid.NewString()
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+	if err := e.save(&rule); err != nil {
+		return Rule{}, err
+	}
+
+	e.mu.Lock()
+	e.rules[rule.ID] = &rule
+	e.mu.Unlock()
+	return rule, nil
+}
+
+// Update replaces a rule. It starts over, as if its condition had just
+// been checked for the first time.
+func (e *Engine) Update(id string, rule Rule) (Rule, error) {
+	existing, err := e.Get(id)
+	if err != nil {
+		return Rule{}, err
+	}
+	if err := Validate(rule); err != nil {
+		return Rule{}, err
+	}
+
+	rule.ID = id
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+	if err := e.save(&rule); err != nil {
+		return Rule{}, err
+	}
+
+	e.mu.Lock()
+	e.rules[id] = &rule
+	delete(e.states, id)
+	e.mu.Unlock()
+	return rule, nil
+}
+
+// Remove deletes a rule
+func (e *Engine) Remove(id string) error {
+	e.mu.Lock()
+	if _, exists := e.rules[id]; !exists {
+		e.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+	}
+	delete(e.rules, id)
+	delete(e.states, id)
+	e.mu.Unlock()
+
+	if err := e.store.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+	return nil
+}
+
+// save persists a rule
+func (e *Engine) save(rule *Rule) error {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rule: %w", err)
+	}
+	if err := e.store.Set(rule.ID, data); err != nil {
+		return fmt.Errorf("failed to store rule: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that a rule can be run
+func Validate(rule Rule) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(rule.Name) == "" {
+		return invalid("name is required")
+	}
+	if rule.ForSeconds < 0 {
+		return invalid("for_seconds must not be negative")
+	}
+
+	condition := rule.Condition
+	switch condition.Type {
+	case ConditionSourceHidden, ConditionAudioSilent:
+		if condition.Source == "" {
+			return invalid("condition source is required for %s", condition.Type)
+		}
+	case ConditionProcessNotRunning:
+		if condition.Process == "" {
+			return invalid("condition process is required for %s", condition.Type)
+		}
+	case ConditionWindowTitle:
+		if _, err := regexp.Compile(condition.Pattern); err != nil || condition.Pattern == "" {
+			return invalid("condition pattern must be a regular expression")
+		}
+	default:
+		return invalid("unknown condition type %q", condition.Type)
+	}
+
+	if len(rule.Actions) == 0 {
+		return invalid("at least one action is required")
+	}
+	for i, action := range rule.Actions {
+		switch action.Type {
+		case ActionSwitchScene:
+			if action.Scene == "" {
+				return invalid("actions[%d]: scene is required for %s", i, action.Type)
+			}
+		case ActionToggleSource:
+			if action.Source == "" {
+				return invalid("actions[%d]: source is required for %s", i, action.Type)
+			}
+		case ActionRunScript:
+			if action.Script == "" {
+				return invalid("actions[%d]: script is required for %s", i, action.Type)
+			}
+		default:
+			return invalid("actions[%d]: unknown action type %q", i, action.Type)
+		}
+	}
+	return nil
+}
+
+// Run checks the rules until ctx is done
+func (e *Engine) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			e.check(ctx, time.Now())
+		}
+	}
+}
+
+// check evaluates every enabled rule and runs or undoes their actions
+func (e *Engine) check(ctx context.Context, now time.Time) {
+	e.mu.RLock()
+	rules := make([]Rule, 0, len(e.rules))
+	for _, rule := range e.rules {
+		if rule.Enabled {
+			rules = append(rules, *rule)
+		}
+	}
+	e.mu.RUnlock()
+	if len(rules) == 0 {
+		return
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+
+	probe := &probe{engine: e}
+	for _, rule := range rules {
+		holds, since, err := probe.holds(ctx, rule.Condition)
+		if err != nil {
+			e.logger.WithError(err).WithField("rule", rule.Name).Debug("Failed to check rule condition")
+			continue
+		}
+		e.step(ctx, rule, holds, since, now)
+	}
+}
+
+// step advances a rule given whether its condition holds, and since when
+// if the condition knows
+func (e *Engine) step(ctx context.Context, rule Rule, holds bool, since, now time.Time) {
+	e.mu.Lock()
+	state, exists := e.states[rule.ID]
+	if !exists {
+		state = &ruleState{}
+		e.states[rule.ID] = state
+	}
+
+	if !holds {
+		fired, undo := state.fired, state.undo
+		*state = ruleState{}
+		e.mu.Unlock()
+		if fired {
+			e.clear(ctx, rule, undo)
+		}
+		return
+	}
+
+	if state.since.IsZero() {
+		state.since = now
+		if !since.IsZero() {
+			state.since = since
+		}
+	}
+	if state.fired || now.Sub(state.since) < time.Duration(rule.ForSeconds)*time.Second {
+		e.mu.Unlock()
+		return
+	}
+	state.fired = true
+	e.mu.Unlock()
+
+	undo := e.trigger(ctx, rule)
+
+	e.mu.Lock()
+	if current, exists := e.states[rule.ID]; exists && current == state {
+		state.undo = undo
+	}
+	e.mu.Unlock()
+}
+
+// trigger runs a rule's actions in order, returning how to undo them
+func (e *Engine) trigger(ctx context.Context, rule Rule) []func(ctx context.Context) error {
+	log := e.logger.WithField("rule", rule.Name)
+	log.Info("Rule triggered")
+
+	var undo []func(ctx context.Context) error
+	failed := 0
+	for _, action := range rule.Actions {
+		actionCtx, cancel := context.WithTimeout(ctx, actionTimeout)
+		revert, err := e.run(actionCtx, action)
+		cancel()
+		if err != nil {
+			failed++
+			log.WithError(err).WithField("action", action.Type).Warn("Rule action failed")
+			continue
+		}
+		if revert != nil {
+			undo = append(undo, revert)
+		}
+	}
+
+	e.publish(EventTriggered, rule, map[string]interface{}{"failed_actions": failed})
+	return undo
+}
+
+// clear undoes a rule's actions, newest first, if the rule reverts
+func (e *Engine) clear(ctx context.Context, rule Rule, undo []func(ctx context.Context) error) {
+	e.logger.WithField("rule", rule.Name).Info("Rule condition cleared")
+	if rule.Revert {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undoCtx, cancel := context.WithTimeout(ctx, actionTimeout)
+			if err := undo[i](undoCtx); err != nil {
+				e.logger.WithError(err).WithField("rule", rule.Name).Warn("Failed to revert rule action")
+			}
+			cancel()
+		}
+	}
+	e.publish(EventCleared, rule, map[string]interface{}{"reverted": rule.Revert})
+}
+
+// run runs an action. Scene and source changes return how to undo them,
+// unless they changed nothing.
+func (e *Engine) run(ctx context.Context, action Action) (func(ctx context.Context) error, error) {
+	switch action.Type {
+	case ActionSwitchScene:
+		current, err := e.obs.GetCurrentScene(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if current.Name == action.Scene {
+			return nil, nil
+		}
+		if err := e.obs.SetCurrentScene(ctx, action.Scene); err != nil {
+			return nil, err
+		}
+		previous := current.Name
+		return func(ctx context.Context) error {
+			// Leave a scene someone has switched to since alone
+			now, err := e.obs.GetCurrentScene(ctx)
+			if err != nil || now.Name != action.Scene {
+				return err
+			}
+			return e.obs.SetCurrentScene(ctx, previous)
+		}, nil
+
+	case ActionToggleSource:
+		scene := action.Scene
+		if scene == "" {
+			current, err := e.obs.GetCurrentScene(ctx)
+			if err != nil {
+				return nil, err
+			}
+			scene = current.Name
+		}
+		source, err := e.obs.GetSourceInfo(ctx, scene, action.Source)
+		if err != nil {
+			return nil, err
+		}
+		visible := !source.Visible
+		if action.Visible != nil {
+			visible = *action.Visible
+		}
+		if visible == source.Visible {
+			return nil, nil
+		}
+		if err := e.obs.SetSourceVisibility(ctx, scene, action.Source, visible); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) error {
+			return e.obs.SetSourceVisibility(ctx, scene, action.Source, !visible)
+		}, nil
+
+	case ActionRunScript:
+		if e.commands == nil {
+			return nil, errors.New("scripting is not enabled")
+		}
+		_, err := e.commands.Execute(ctx, "script:"+action.Script, action.Parameters)
+		return nil, err
+	}
+	return nil, fmt.Errorf("unknown action type %q", action.Type)
+}
+
+// publish publishes a rule event on the bus, if there is one
+func (e *Engine) publish(eventType string, rule Rule, data map[string]interface{}) {
+	if e.publisher == nil {
+		return
+	}
+	data["rule_id"] = rule.ID
+	data["rule"] = rule.Name
+	e.publisher.Publish(events.Event{
+		Type:   eventType,
+		Source: events.SourceBridge,
+		Data:   data,
+	})
+}
+
+// probe checks conditions for one pass over the rules, reading the
+// process list and foreground window at most once
+type probe struct {
+	engine *Engine
+
+	processes    map[string]bool
+	processesErr error
+	window       *string
+	windowErr    error
+	scene        string
+}
+
+// holds reports whether a condition holds. For conditions that know when
+// they started holding, since is that time.
+func (p *probe) holds(ctx context.Context, condition Condition) (bool, time.Time, error) {
+	e := p.engine
+	switch condition.Type {
+	case ConditionSourceHidden:
+		if !e.obs.IsConnected() {
+			return false, time.Time{}, obs.ErrNotConnected
+		}
+		scene := condition.Scene
+		if scene == "" {
+			if p.scene == "" {
+				ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+				defer cancel()
+				current, err := e.obs.GetCurrentScene(ctx)
+				if err != nil {
+					return false, time.Time{}, err
+				}
+				p.scene = current.Name
+			}
+			scene = p.scene
+		}
+		ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+		defer cancel()
+		source, err := e.obs.GetSourceInfo(ctx, scene, condition.Source)
+		if errors.Is(err, obs.ErrSourceNotFound) {
+			// A source removed from the scene is as gone as a hidden one
+			return true, time.Time{}, nil
+		}
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		return !source.Visible, time.Time{}, nil
+
+	case ConditionAudioSilent:
+		if !e.obs.IsConnected() {
+			return false, time.Time{}, obs.ErrNotConnected
+		}
+		since, quiet := e.obs.QuietSince(condition.Source)
+		return quiet, since, nil
+
+	case ConditionProcessNotRunning:
+		if p.processes == nil && p.processesErr == nil {
+			p.processes, p.processesErr = e.system.Processes(ctx)
+		}
+		if p.processesErr != nil {
+			return false, time.Time{}, p.processesErr
+		}
+		return !p.processes[processName(condition.Process)], time.Time{}, nil
+
+	case ConditionWindowTitle:
+		if p.window == nil && p.windowErr == nil {
+			title, err := e.system.ForegroundWindow(ctx)
+			p.window, p.windowErr = &title, err
+		}
+		if p.windowErr != nil {
+			return false, time.Time{}, p.windowErr
+		}
+		pattern, err := regexp.Compile(condition.Pattern)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		return pattern.MatchString(*p.window), time.Time{}, nil
+	}
+	return false, time.Time{}, fmt.Errorf("unknown condition type %q", condition.Type)
+}
+
+// processName normalizes an executable name for comparison, so "Game",
+// "game.exe" and "GAME.EXE" are the same process
+func processName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
@@ -0,0 +1,267 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/testutils"
+)
+
+type fakeOBS struct {
+	scene   string
+	visible map[string]bool
+	quiet   map[string]time.Time
+}
+
+func (f *fakeOBS) IsConnected() bool { return true }
+
+func (f *fakeOBS) GetCurrentScene(ctx context.Context) (*obs.SceneInfo, error) {
+	return &obs.SceneInfo{Name: f.scene}, nil
+}
+
+func (f *fakeOBS) SetCurrentScene(ctx context.Context, sceneName string) error {
+	f.scene = sceneName
+	return nil
+}
+
+func (f *fakeOBS) GetSourceInfo(ctx context.Context, sceneName, sourceName string) (*obs.SourceInfo, error) {
+	visible, exists := f.visible[sourceName]
+	if !exists {
+		return nil, obs.ErrSourceNotFound
+	}
+	return &obs.SourceInfo{Name: sourceName, Visible: visible}, nil
+}
+
+func (f *fakeOBS) SetSourceVisibility(ctx context.Context, sceneName, sourceName string, visible bool) error {
+	f.visible[sourceName] = visible
+	return nil
+}
+
+func (f *fakeOBS) QuietSince(input string) (time.Time, bool) {
+	since, quiet := f.quiet[input]
+	return since, quiet
+}
+
+type fakeSystem struct {
+	processes map[string]bool
+	window    string
+}
+
+func (f *fakeSystem) Processes(ctx context.Context) (map[string]bool, error) {
+	return f.processes, nil
+}
+
+func (f *fakeSystem) ForegroundWindow(ctx context.Context) (string, error) {
+	return f.window, nil
+}
+
+type recorder struct{ types []string }
+
+func (r *recorder) Publish(event events.Event) { r.types = append(r.types, event.Type) }
+
+func newTestEngine(t *testing.T) (*Engine, *fakeOBS, *fakeSystem, *recorder) {
+	t.Helper()
+	return newTestEngineWith(t, testutils.NewMockStorage())
+}
+
+func newTestEngineWith(t *testing.T, store storage.Storage) (*Engine, *fakeOBS, *fakeSystem, *recorder) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	obsClient := &fakeOBS{scene: "Gameplay", visible: map[string]bool{"Game Capture": true}, quiet: map[string]time.Time{}}
+	published := &recorder{}
+	e, err := NewEngine(store, obsClient, nil, published, config.RulesConfig{Enabled: true, Interval: time.Second}, logger)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	system := &fakeSystem{processes: map[string]bool{"game": true}}
+	e.system = system
+	return e, obsClient, system, published
+}
+
+func brbRule(condition Condition) Rule {
+	return Rule{
+		Name:       "BRB",
+		Enabled:    true,
+		Condition:  condition,
+		ForSeconds: 5,
+		Actions:    []Action{{Type: ActionSwitchScene, Scene: "BRB"}},
+		Revert:     true,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		ok   bool
+	}{
+		{"valid", brbRule(Condition{Type: ConditionSourceHidden, Source: "Game Capture"}), true},
+		{"no name", Rule{Condition: Condition{Type: ConditionProcessNotRunning, Process: "game"}, Actions: []Action{{Type: ActionSwitchScene, Scene: "BRB"}}}, false},
+		{"unknown condition", brbRule(Condition{Type: "cpu_high"}), false},
+		{"bad pattern", brbRule(Condition{Type: ConditionWindowTitle, Pattern: "("}), false},
+		{"no actions", Rule{Name: "BRB", Condition: Condition{Type: ConditionProcessNotRunning, Process: "game"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.rule)
+			if (err == nil) != tt.ok {
+				t.Errorf("Validate() error = %v, want ok %v", err, tt.ok)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("Expected ErrInvalidRule, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEngine_CRUD(t *testing.T) {
+	store := testutils.NewMockStorage()
+	e, _, _, _ := newTestEngineWith(t, store)
+
+	created, err := e.Create(brbRule(Condition{Type: ConditionProcessNotRunning, Process: "game.exe"}))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Rules survive a restart
+	reloaded, err := NewEngine(store, e.obs, nil, nil, e.cfg, e.logger)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	if rule, err := reloaded.Get(created.ID); err != nil || rule.Name != "BRB" {
+		t.Fatalf("Expected the rule to be reloaded, got %+v, %v", rule, err)
+	}
+
+	created.Name = "Be right back"
+	updated, err := e.Update(created.ID, created)
+	if err != nil || updated.Name != "Be right back" || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("Unexpected update %+v, %v", updated, err)
+	}
+
+	if err := e.Remove(created.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := e.Get(created.ID); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound, got %v", err)
+	}
+}
+
+func TestEngine_SwitchesAndReverts(t *testing.T) {
+	e, obsClient, _, published := newTestEngine(t)
+	ctx := context.Background()
+	if _, err := e.Create(brbRule(Condition{Type: ConditionSourceHidden, Source: "Game Capture"})); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	obsClient.visible["Game Capture"] = false
+	e.check(ctx, now)
+	if obsClient.scene != "Gameplay" {
+		t.Fatal("Expected no switch before the condition held long enough")
+	}
+
+	e.check(ctx, now.Add(5*time.Second))
+	if obsClient.scene != "BRB" {
+		t.Fatalf("Expected a switch to BRB, got %q", obsClient.scene)
+	}
+
+	// The actions run once while the condition holds
+	obsClient.scene = "Gameplay"
+	e.check(ctx, now.Add(6*time.Second))
+	if obsClient.scene != "Gameplay" {
+		t.Error("Expected the rule not to trigger again")
+	}
+	obsClient.scene = "BRB"
+
+	obsClient.visible["Game Capture"] = true
+	e.check(ctx, now.Add(7*time.Second))
+	if obsClient.scene != "Gameplay" {
+		t.Errorf("Expected the previous scene back, got %q", obsClient.scene)
+	}
+
+	want := []string{EventTriggered, EventCleared}
+	if len(published.types) != 2 || published.types[0] != want[0] || published.types[1] != want[1] {
+		t.Errorf("Expected events %v, got %v", want, published.types)
+	}
+}
+
+func TestEngine_Conditions(t *testing.T) {
+	e, obsClient, system, _ := newTestEngine(t)
+	ctx := context.Background()
+	now := time.Now()
+	probe := &probe{engine: e}
+
+	obsClient.quiet["Mic"] = now.Add(-time.Minute)
+	system.window = "Game - Paused"
+
+	tests := []struct {
+		condition Condition
+		holds     bool
+	}{
+		{Condition{Type: ConditionSourceHidden, Source: "Game Capture"}, false},
+		{Condition{Type: ConditionSourceHidden, Source: "Removed"}, true},
+		{Condition{Type: ConditionAudioSilent, Source: "Mic"}, true},
+		{Condition{Type: ConditionAudioSilent, Source: "Desktop Audio"}, false},
+		{Condition{Type: ConditionProcessNotRunning, Process: "Game.exe"}, false},
+		{Condition{Type: ConditionProcessNotRunning, Process: "other"}, true},
+		{Condition{Type: ConditionWindowTitle, Pattern: `(?i)paused$`}, true},
+		{Condition{Type: ConditionWindowTitle, Pattern: `^Loading`}, false},
+	}
+
+	for _, tt := range tests {
+		holds, _, err := probe.holds(ctx, tt.condition)
+		if err != nil || holds != tt.holds {
+			t.Errorf("holds(%+v) = %v, %v, want %v", tt.condition, holds, err, tt.holds)
+		}
+	}
+
+	// Silence counts from when the input went quiet, not from the first check
+	if _, err := e.Create(brbRule(Condition{Type: ConditionAudioSilent, Source: "Mic"})); err != nil {
+		t.Fatal(err)
+	}
+	e.check(ctx, now)
+	if obsClient.scene != "BRB" {
+		t.Errorf("Expected a mic quiet for a minute to trigger at once, got %q", obsClient.scene)
+	}
+}
+
+func TestEngine_ToggleSource(t *testing.T) {
+	e, obsClient, system, _ := newTestEngine(t)
+	ctx := context.Background()
+	hidden := false
+	rule := Rule{
+		Name:      "Hide webcam",
+		Enabled:   true,
+		Condition: Condition{Type: ConditionProcessNotRunning, Process: "game"},
+		Actions:   []Action{{Type: ActionToggleSource, Source: "Game Capture", Visible: &hidden}},
+		Revert:    true,
+	}
+	if _, err := e.Create(rule); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	delete(system.processes, "game")
+	e.check(ctx, now)
+	if obsClient.visible["Game Capture"] {
+		t.Fatal("Expected the source to be hidden")
+	}
+
+	system.processes["game"] = true
+	e.check(ctx, now.Add(time.Second))
+	if !obsClient.visible["Game Capture"] {
+		t.Error("Expected the source to be shown again")
+	}
+}
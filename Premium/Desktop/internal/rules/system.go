@@ -0,0 +1,48 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// systemTimeout bounds listing the processes or reading the foreground
+// window
+const systemTimeout = 2 * time.Second
+
+// errUnsupported is returned where the foreground window cannot be read
+var errUnsupported = errors.New("not supported on this platform")
+
+// localSystem reads the processes and foreground window of this computer
+type localSystem struct{}
+
+// Processes returns the normalized names of the running processes
+func (localSystem) Processes(ctx context.Context) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, systemTimeout)
+	defer cancel()
+
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(procs))
+	for _, proc := range procs {
+		// Processes can exit while they are listed
+		name, err := proc.NameWithContext(ctx)
+		if err != nil || name == "" {
+			continue
+		}
+		names[processName(name)] = true
+	}
+	return names, nil
+}
+
+// ForegroundWindow returns the title of the window that has focus
+func (localSystem) ForegroundWindow(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, systemTimeout)
+	defer cancel()
+	return foregroundWindow(ctx)
+}
@@ -0,0 +1,24 @@
+package rules
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// frontWindowScript names the front window of the frontmost application.
+// System Events needs the accessibility permission to read it.
+const frontWindowScript = `tell application "System Events"
+	set frontApp to first application process whose frontmost is true
+	if (count of windows of frontApp) is 0 then return ""
+	return name of front window of frontApp
+end tell`
+
+// foregroundWindow reads the title of the front window
+func foregroundWindow(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "osascript", "-e", frontWindowScript).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,27 @@
+//go:build !windows && !darwin
+
+package rules
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// foregroundWindow reads the title of the active window with xdotool,
+// which needs an X11 session
+func foregroundWindow(ctx context.Context) (string, error) {
+	if _, err := exec.LookPath("xdotool"); err != nil {
+		return "", errUnsupported
+	}
+
+	out, err := exec.CommandContext(ctx, "xdotool", "getactivewindow", "getwindowname").Output()
+	if err != nil {
+		// xdotool fails when no window has focus
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
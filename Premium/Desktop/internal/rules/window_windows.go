@@ -0,0 +1,33 @@
+package rules
+
+import (
+	"context"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+
+	procGetForegroundWindow  = user32.NewProc("GetForegroundWindow")
+	procGetWindowTextLengthW = user32.NewProc("GetWindowTextLengthW")
+	procGetWindowTextW       = user32.NewProc("GetWindowTextW")
+)
+
+// foregroundWindow reads the title of the foreground window
+func foregroundWindow(ctx context.Context) (string, error) {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	if hwnd == 0 {
+		// Nothing has focus, such as while the desktop is locked
+		return "", nil
+	}
+
+	length, _, _ := procGetWindowTextLengthW.Call(hwnd)
+	if length == 0 {
+		return "", nil
+	}
+
+	buf := make([]uint16, length+1)
+	procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return syscall.UTF16ToString(buf), nil
+}
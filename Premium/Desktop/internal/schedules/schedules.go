@@ -0,0 +1,467 @@
+// Package schedules shows and hides OBS sources on a schedule, such as
+// showing an ad banner for a minute every twenty minutes while streaming.
+// A schedule counts from the start of the stream or recording, or from
+// midnight, so it picks up where it was across restarts of the bridge.
+// Schedules are managed through the local gateway and persisted in
+// storage.
+package schedules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/storage"
+)
+
+// What a schedule runs while, and counts from
+const (
+	WhileStreaming = "streaming"
+	WhileRecording = "recording"
+	WhileAlways    = "always"
+)
+
+// Events published on the bus
+const (
+	EventShown  = "schedules.shown"
+	EventHidden = "schedules.hidden"
+)
+
+// requestTimeout bounds each OBS request of a check
+const requestTimeout = 10 * time.Second
+
+var (
+	// ErrScheduleNotFound is returned for an unknown schedule ID
+	ErrScheduleNotFound = errors.New("schedule not found")
+
+	// ErrInvalidSchedule is returned for a schedule that cannot be run
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// Schedule shows a source for ShowSeconds every EverySeconds while its
+// condition holds. The first showing is StartSeconds into the stream or
+// recording, or after midnight for schedules that always run.
+type Schedule struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Enabled      bool      `json:"enabled"`
+	Scene        string    `json:"scene"`
+	Source       string    `json:"source"`
+	ShowSeconds  int       `json:"show_seconds"`
+	EverySeconds int       `json:"every_seconds"`
+	StartSeconds int       `json:"start_seconds"`
+	While        string    `json:"while"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// showing reports whether the source is shown at elapsed, the time into
+// the stream, recording or day
+func (s *Schedule) showing(elapsed time.Duration) bool {
+	start := seconds(s.StartSeconds)
+	if elapsed < start {
+		return false
+	}
+	return (elapsed-start)%seconds(s.EverySeconds) < seconds(s.ShowSeconds)
+}
+
+// seconds converts seconds to a duration
+func seconds(s int) time.Duration {
+	return time.Duration(s) * time.Second
+}
+
+// OBS is the part of the OBS client schedules watch and control
+type OBS interface {
+	IsConnected() bool
+	GetStreamStatus(ctx context.Context) (*obs.StreamStatus, error)
+	GetRecordingStatus(ctx context.Context) (*obs.RecordingStatus, error)
+	SetSourceVisibility(ctx context.Context, sceneName, sourceName string, visible bool) error
+}
+
+// target is a source a schedule has set, and whether it was shown
+type target struct {
+	scene   string
+	source  string
+	visible bool
+}
+
+// Scheduler checks the schedules at an interval and shows or hides their
+// sources
+type Scheduler struct {
+	store     *storage.ScheduleRepo
+	obs       OBS
+	publisher events.Publisher // nil when the event bus is disabled
+	cfg       config.SchedulesConfig
+	logger    *logrus.Logger
+
+	mu        sync.RWMutex
+	schedules map[string]*Schedule
+	set       map[string]target // what each schedule last set, while its condition holds
+	stale     []target          // sources shown by schedules since changed or removed
+}
+
+// NewScheduler creates a scheduler with the schedules persisted in store.
+// publisher may be nil.
+func NewScheduler(store storage.Storage, obsClient OBS, publisher events.Publisher, cfg config.SchedulesConfig, logger *logrus.Logger) (*Scheduler, error) {
+	repo, err := storage.NewScheduleRepo(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schedules bucket: %w", err)
+	}
+
+	s := &Scheduler{
+		store:     repo,
+		obs:       obsClient,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+		schedules: make(map[string]*Schedule),
+		set:       make(map[string]target),
+	}
+
+	stored, err := repo.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedules: %w", err)
+	}
+	for id, data := range stored {
+		var schedule Schedule
+		if err := json.Unmarshal(data, &schedule); err != nil {
+			logger.WithError(err).WithField("id", id).Warn("Skipping unreadable schedule")
+			continue
+		}
+		s.schedules[schedule.ID] = &schedule
+	}
+
+	if len(s.schedules) > 0 {
+		logger.WithField("count", len(s.schedules)).Info("Loaded source visibility schedules")
+	}
+	return s, nil
+}
+
+// List returns the schedules ordered by creation time
+func (s *Scheduler) List() []Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		list = append(list, *schedule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Get returns a schedule
+func (s *Scheduler) Get(id string) (Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, exists := s.schedules[id]
+	if !exists {
+		return Schedule{}, fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	return *schedule, nil
+}
+
+// Create validates and persists a new schedule
+func (s *Scheduler) Create(schedule Schedule) (Schedule, error) {
+	if err := Validate(schedule); err != nil {
+		return Schedule{}, err
+	}
+
+	schedule.ID = "schedule_" + uuid.NewString()
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = schedule.CreatedAt
+	if err := s.save(&schedule); err != nil {
+		return Schedule{}, err
+	}
+
+	s.mu.Lock()
+	s.schedules[schedule.ID] = &schedule
+	s.mu.Unlock()
+	return schedule, nil
+}
+
+// Update replaces a schedule. A source the schedule showed is hidden if
+// the schedule now shows another one.
+func (s *Scheduler) Update(id string, schedule Schedule) (Schedule, error) {
+	existing, err := s.Get(id)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if err := Validate(schedule); err != nil {
+		return Schedule{}, err
+	}
+
+	schedule.ID = id
+	schedule.CreatedAt = existing.CreatedAt
+	schedule.UpdatedAt = time.Now()
+	if err := s.save(&schedule); err != nil {
+		return Schedule{}, err
+	}
+
+	s.mu.Lock()
+	s.schedules[id] = &schedule
+	if set, exists := s.set[id]; exists && (set.scene != schedule.Scene || set.source != schedule.Source) {
+		s.forget(id)
+	}
+	s.mu.Unlock()
+	return schedule, nil
+}
+
+// Remove deletes a schedule. A source it showed is hidden.
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	if _, exists := s.schedules[id]; !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	delete(s.schedules, id)
+	s.forget(id)
+	s.mu.Unlock()
+
+	if err := s.store.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	return nil
+}
+
+// forget drops what a schedule set, leaving a source it showed to be
+// hidden by the next check. s.mu must be held.
+func (s *Scheduler) forget(id string) {
+	if set, exists := s.set[id]; exists && set.visible {
+		s.stale = append(s.stale, set)
+	}
+	delete(s.set, id)
+}
+
+// save persists a schedule
+func (s *Scheduler) save(schedule *Schedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+	if err := s.store.Set(schedule.ID, data); err != nil {
+		return fmt.Errorf("failed to store schedule: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that a schedule can be run
+func Validate(schedule Schedule) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidSchedule, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(schedule.Name) == "" {
+		return invalid("name is required")
+	}
+	if schedule.Scene == "" {
+		return invalid("scene is required")
+	}
+	if schedule.Source == "" {
+		return invalid("source is required")
+	}
+	if schedule.ShowSeconds <= 0 {
+		return invalid("show_seconds must be positive")
+	}
+	if schedule.EverySeconds <= schedule.ShowSeconds {
+		return invalid("every_seconds must be longer than show_seconds")
+	}
+	if schedule.StartSeconds < 0 {
+		return invalid("start_seconds must not be negative")
+	}
+	switch schedule.While {
+	case WhileStreaming, WhileRecording, WhileAlways:
+	default:
+		return invalid("unknown while %q", schedule.While)
+	}
+	return nil
+}
+
+// Run checks the schedules until ctx is done
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.check(ctx, time.Now())
+		}
+	}
+}
+
+// check shows or hides the sources of the schedules as of now
+func (s *Scheduler) check(ctx context.Context, now time.Time) {
+	if !s.obs.IsConnected() {
+		// OBS may come back with other visibilities, so the sources are
+		// all set again once it is connected
+		s.mu.Lock()
+		s.set = make(map[string]target)
+		s.stale = nil
+		s.mu.Unlock()
+		return
+	}
+
+	s.mu.Lock()
+	stale := s.stale
+	s.stale = nil
+	schedules := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedules = append(schedules, *schedule)
+	}
+	s.mu.Unlock()
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].CreatedAt.Before(schedules[j].CreatedAt) })
+
+	for _, set := range stale {
+		if err := s.setVisibility(ctx, set.scene, set.source, false); err != nil {
+			s.logger.WithError(err).WithField("source", set.source).Warn("Failed to hide source of removed schedule")
+		}
+	}
+
+	clock := &clock{obs: s.obs, now: now}
+	for _, schedule := range schedules {
+		// A disabled schedule hides what it showed, as if its condition
+		// had stopped holding
+		active, elapsed := false, time.Duration(0)
+		if schedule.Enabled {
+			var err error
+			active, elapsed, err = clock.elapsed(ctx, schedule.While)
+			if err != nil {
+				s.logger.WithError(err).WithField("schedule", schedule.Name).Debug("Failed to check schedule")
+				continue
+			}
+		}
+		s.step(ctx, schedule, active, schedule.showing(elapsed))
+	}
+}
+
+// step sets a schedule's source given whether its condition holds and
+// whether the source is due to be shown. Sources are only set when that
+// changes, so they can be shown or hidden by hand in between.
+func (s *Scheduler) step(ctx context.Context, schedule Schedule, active, show bool) {
+	s.mu.RLock()
+	set, exists := s.set[schedule.ID]
+	s.mu.RUnlock()
+
+	log := s.logger.WithFields(logrus.Fields{"schedule": schedule.Name, "source": schedule.Source})
+	if !active {
+		if exists && set.visible {
+			if err := s.setVisibility(ctx, schedule.Scene, schedule.Source, false); err != nil {
+				log.WithError(err).Warn("Failed to hide scheduled source")
+				return
+			}
+			log.Info("Scheduled source hidden")
+			s.publish(EventHidden, schedule)
+		}
+		s.mu.Lock()
+		delete(s.set, schedule.ID)
+		s.mu.Unlock()
+		return
+	}
+
+	if exists && set.visible == show {
+		return
+	}
+	if err := s.setVisibility(ctx, schedule.Scene, schedule.Source, show); err != nil {
+		log.WithError(err).Warn("Failed to set scheduled source visibility")
+		return
+	}
+
+	s.mu.Lock()
+	if _, exists := s.schedules[schedule.ID]; exists {
+		s.set[schedule.ID] = target{scene: schedule.Scene, source: schedule.Source, visible: show}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case show:
+		log.Info("Scheduled source shown")
+		s.publish(EventShown, schedule)
+	case exists:
+		log.Info("Scheduled source hidden")
+		s.publish(EventHidden, schedule)
+	}
+}
+
+// setVisibility shows or hides a source
+func (s *Scheduler) setVisibility(ctx context.Context, scene, source string, visible bool) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	return s.obs.SetSourceVisibility(ctx, scene, source, visible)
+}
+
+// publish publishes a schedule event on the bus, if there is one
+func (s *Scheduler) publish(eventType string, schedule Schedule) {
+	if s.publisher == nil {
+		return
+	}
+	s.publisher.Publish(events.Event{
+		Type:   eventType,
+		Source: events.SourceBridge,
+		Data: map[string]interface{}{
+			"schedule_id": schedule.ID,
+			"schedule":    schedule.Name,
+			"scene":       schedule.Scene,
+			"source":      schedule.Source,
+		},
+	})
+}
+
+// clock tells the schedules of one check how far they are, asking OBS for
+// the stream and recording status at most once
+type clock struct {
+	obs OBS
+	now time.Time
+
+	stream    *obs.StreamStatus
+	streamErr error
+	record    *obs.RecordingStatus
+	recordErr error
+}
+
+// elapsed reports whether a schedule's condition holds and how long it
+// has held: the time into the stream or recording, or since midnight
+func (c *clock) elapsed(ctx context.Context, while string) (bool, time.Duration, error) {
+	switch while {
+	case WhileStreaming:
+		if c.stream == nil && c.streamErr == nil {
+			ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+			c.stream, c.streamErr = c.obs.GetStreamStatus(ctx)
+			cancel()
+		}
+		if c.streamErr != nil {
+			return false, 0, c.streamErr
+		}
+		return c.stream.Active, c.stream.Duration, nil
+
+	case WhileRecording:
+		if c.record == nil && c.recordErr == nil {
+			ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+			c.record, c.recordErr = c.obs.GetRecordingStatus(ctx)
+			cancel()
+		}
+		if c.recordErr != nil {
+			return false, 0, c.recordErr
+		}
+		return c.record.Active, c.record.Duration, nil
+
+	case WhileAlways:
+		midnight := time.Date(c.now.Year(), c.now.Month(), c.now.Day(), 0, 0, 0, 0, c.now.Location())
+		return true, c.now.Sub(midnight), nil
+	}
+	return false, 0, fmt.Errorf("unknown while %q", while)
+}
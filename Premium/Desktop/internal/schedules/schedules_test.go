@@ -0,0 +1,222 @@
+package schedules
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/testutils"
+)
+
+type fakeOBS struct {
+	connected bool
+	stream    obs.StreamStatus
+	visible   map[string]bool
+	sets      int
+}
+
+func (f *fakeOBS) IsConnected() bool { return f.connected }
+
+func (f *fakeOBS) GetStreamStatus(ctx context.Context) (*obs.StreamStatus, error) {
+	stream := f.stream
+	return &stream, nil
+}
+
+func (f *fakeOBS) GetRecordingStatus(ctx context.Context) (*obs.RecordingStatus, error) {
+	return &obs.RecordingStatus{}, nil
+}
+
+func (f *fakeOBS) SetSourceVisibility(ctx context.Context, sceneName, sourceName string, visible bool) error {
+	f.visible[sceneName+"/"+sourceName] = visible
+	f.sets++
+	return nil
+}
+
+type recorder struct{ types []string }
+
+func (r *recorder) Publish(event events.Event) { r.types = append(r.types, event.Type) }
+
+func newTestScheduler(t *testing.T, store storage.Storage) (*Scheduler, *fakeOBS, *recorder) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	obsClient := &fakeOBS{connected: true, visible: map[string]bool{}}
+	published := &recorder{}
+	s, err := NewScheduler(store, obsClient, published, config.SchedulesConfig{Enabled: true, Interval: time.Second}, logger)
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	return s, obsClient, published
+}
+
+// adBanner shows the banner for a minute every twenty minutes of the stream
+func adBanner() Schedule {
+	return Schedule{
+		Name:         "Ad",
+		Enabled:      true,
+		Scene:        "Gameplay",
+		Source:       "Ad Banner",
+		ShowSeconds:  60,
+		EverySeconds: 1200,
+		StartSeconds: 300,
+		While:        WhileStreaming,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	noSource := adBanner()
+	noSource.Source = ""
+	tooLong := adBanner()
+	tooLong.ShowSeconds = 1200
+	unknown := adBanner()
+	unknown.While = "live"
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		ok       bool
+	}{
+		{"valid", adBanner(), true},
+		{"no source", noSource, false},
+		{"shown all the time", tooLong, false},
+		{"unknown while", unknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.schedule)
+			if (err == nil) != tt.ok {
+				t.Errorf("Validate() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSchedule_Showing(t *testing.T) {
+	schedule := adBanner()
+	tests := []struct {
+		elapsed time.Duration
+		shown   bool
+	}{
+		{0, false},
+		{299 * time.Second, false},
+		{300 * time.Second, true},
+		{359 * time.Second, true},
+		{360 * time.Second, false},
+		{1500 * time.Second, true},
+		{1560 * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		if shown := schedule.showing(tt.elapsed); shown != tt.shown {
+			t.Errorf("showing(%s) = %v, want %v", tt.elapsed, shown, tt.shown)
+		}
+	}
+}
+
+func TestScheduler_Streaming(t *testing.T) {
+	s, obsClient, published := newTestScheduler(t, testutils.NewMockStorage())
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := s.Create(adBanner()); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Offline, the source is left as it is
+	s.check(ctx, now)
+	if obsClient.sets != 0 {
+		t.Fatalf("Expected nothing set while offline, got %v", obsClient.visible)
+	}
+
+	obsClient.stream = obs.StreamStatus{Active: true, Duration: 10 * time.Second}
+	s.check(ctx, now)
+	if shown, set := obsClient.visible["Gameplay/Ad Banner"]; !set || shown {
+		t.Fatalf("Expected the banner hidden until it is due, got %v", obsClient.visible)
+	}
+
+	obsClient.stream.Duration = 310 * time.Second
+	s.check(ctx, now)
+	if !obsClient.visible["Gameplay/Ad Banner"] {
+		t.Fatal("Expected the banner shown five minutes in")
+	}
+
+	// Hiding it by hand is left alone until the next change
+	obsClient.visible["Gameplay/Ad Banner"] = false
+	obsClient.stream.Duration = 320 * time.Second
+	s.check(ctx, now)
+	if obsClient.visible["Gameplay/Ad Banner"] {
+		t.Error("Expected the banner hidden by hand to stay hidden")
+	}
+
+	obsClient.stream.Duration = 400 * time.Second
+	s.check(ctx, now)
+
+	obsClient.stream.Duration = 1510 * time.Second
+	s.check(ctx, now)
+	if !obsClient.visible["Gameplay/Ad Banner"] {
+		t.Fatal("Expected the banner shown again twenty minutes later")
+	}
+
+	obsClient.stream = obs.StreamStatus{}
+	s.check(ctx, now)
+	if obsClient.visible["Gameplay/Ad Banner"] {
+		t.Error("Expected the banner hidden once the stream ends")
+	}
+
+	want := []string{EventShown, EventHidden, EventShown, EventHidden}
+	if len(published.types) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, published.types)
+	}
+	for i := range want {
+		if published.types[i] != want[i] {
+			t.Errorf("Expected events %v, got %v", want, published.types)
+			break
+		}
+	}
+}
+
+func TestScheduler_RemoveAndPersist(t *testing.T) {
+	store := testutils.NewMockStorage()
+	s, obsClient, _ := newTestScheduler(t, store)
+	ctx := context.Background()
+
+	schedule := adBanner()
+	schedule.While = WhileAlways
+	schedule.StartSeconds = 0
+	schedule.ShowSeconds = 86399
+	schedule.EverySeconds = 86400
+	created, err := s.Create(schedule)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	reloaded, _, _ := newTestScheduler(t, store)
+	if got, err := reloaded.Get(created.ID); err != nil || got.Source != "Ad Banner" {
+		t.Errorf("Expected the schedule persisted, got %+v, %v", got, err)
+	}
+
+	s.check(ctx, time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local))
+	if !obsClient.visible["Gameplay/Ad Banner"] {
+		t.Fatal("Expected the banner shown")
+	}
+
+	if err := s.Remove(created.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	s.check(ctx, time.Date(2026, 1, 1, 12, 0, 1, 0, time.Local))
+	if obsClient.visible["Gameplay/Ad Banner"] {
+		t.Error("Expected the banner hidden once its schedule is removed")
+	}
+	if _, err := s.Get(created.ID); err == nil {
+		t.Error("Expected the schedule gone")
+	}
+}
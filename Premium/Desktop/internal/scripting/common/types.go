@@ -3,6 +3,9 @@ package common
 import (
 	"context"
 	"time"
+
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/timers"
 )
 
 // ScriptType represents the type of script
@@ -34,6 +37,29 @@ type ScriptResult struct {
 	Duration time.Duration
 }
 
+// ActionExecutor executes module actions on behalf of scripts
+type ActionExecutor interface {
+	ExecuteAction(ctx context.Context, moduleName, action string, parameters map[string]string) (map[string]interface{}, error)
+}
+
+// EventPublisher publishes script events on the bridge event bus
+type EventPublisher interface {
+	Publish(event events.Event)
+}
+
+// TextVariables holds the variables text sources show
+type TextVariables interface {
+	Variable(name string) (string, bool)
+	SetVariable(name, value string) error
+	Increment(name string, by int64) (int64, error)
+}
+
+// Timers runs the countdowns and stopwatches
+type Timers interface {
+	Get(name string) (timers.Status, error)
+	Control(name, action string, amount time.Duration) (timers.Status, error)
+}
+
 // ScriptEngine defines the interface for script execution
 type ScriptEngine interface {
 	Execute(ctx context.Context, config ScriptConfig) (*ScriptResult, error)
@@ -4,20 +4,28 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 
 	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/metrics"
 	"waddlebot-bridge/internal/scripting/external"
 	"waddlebot-bridge/internal/scripting/lua"
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/supervisor"
+	"waddlebot-bridge/internal/tracing"
 )
 
 // Manager manages script execution across different engines
 type Manager struct {
-	config  config.ScriptingConfig
-	engines map[ScriptType]ScriptEngine
-	logger  *logrus.Logger
-	mu      sync.RWMutex
+	config    config.ScriptingConfig
+	engines   map[ScriptType]ScriptEngine
+	available map[ScriptType]ScriptEngine // engines created at startup
+	luaEngine *lua.Engine
+	logger    *logrus.Logger
+	mu        sync.RWMutex
 }
 
 // NewManager creates a new script manager
@@ -32,6 +40,7 @@ func NewManager(cfg config.ScriptingConfig, logger *logrus.Logger) (*Manager, er
 	if cfg.EnableLua {
 		luaEngine := lua.NewEngine(cfg, logger)
 		m.engines[ScriptTypeLua] = luaEngine
+		m.luaEngine = luaEngine
 		logger.Info("Lua scripting engine enabled")
 	}
 
@@ -60,9 +69,99 @@ func NewManager(cfg config.ScriptingConfig, logger *logrus.Logger) (*Manager, er
 		return nil, fmt.Errorf("no scripting engines enabled")
 	}
 
+	m.available = make(map[ScriptType]ScriptEngine, len(m.engines))
+	for t, engine := range m.engines {
+		m.available[t] = engine
+	}
+
 	return m, nil
 }
 
+// SetPermissions enables and disables script types while the bridge runs.
+// Only the engines created at startup can be enabled again; enabling any
+// other type takes a restart.
+func (m *Manager) SetPermissions(cfg config.ScriptingConfig) {
+	allowed := map[ScriptType]bool{
+		ScriptTypeLua:        cfg.Enabled && cfg.EnableLua,
+		ScriptTypePython:     cfg.Enabled && cfg.EnablePython,
+		ScriptTypePowerShell: cfg.Enabled && cfg.EnablePowerShell,
+		ScriptTypeBash:       cfg.Enabled && cfg.EnableBash,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.engines = make(map[ScriptType]ScriptEngine)
+	for t, enabled := range allowed {
+		if !enabled {
+			continue
+		}
+		engine, ok := m.available[t]
+		if !ok {
+			m.logger.WithField("type", t).Warn("Restart the bridge to enable this script type")
+			continue
+		}
+		m.engines[t] = engine
+	}
+}
+
+// SetActionExecutor gives scripts access to module actions
+func (m *Manager) SetActionExecutor(executor ActionExecutor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.luaEngine != nil {
+		m.luaEngine.SetActionExecutor(executor)
+	}
+}
+
+// SetEventPublisher lets scripts publish events with bridge.emit
+func (m *Manager) SetEventPublisher(publisher EventPublisher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.luaEngine != nil {
+		m.luaEngine.SetEventPublisher(publisher)
+	}
+}
+
+// SetTextVariables lets scripts set the variables text sources show
+func (m *Manager) SetTextVariables(vars TextVariables) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.luaEngine != nil {
+		m.luaEngine.SetTextVariables(vars)
+	}
+}
+
+// SetTimers lets scripts run the countdowns and stopwatches
+func (m *Manager) SetTimers(timers Timers) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.luaEngine != nil {
+		m.luaEngine.SetTimers(timers)
+	}
+}
+
+// SetStorage lets scripts keep values with storage.set in the scripts
+// bucket of store
+func (m *Manager) SetStorage(store storage.Storage) error {
+	repo, err := storage.NewScriptRepo(store)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.luaEngine != nil {
+		m.luaEngine.SetStorage(repo)
+	}
+	return nil
+}
+
 // Execute executes a script with the appropriate engine
 func (m *Manager) Execute(ctx context.Context, config ScriptConfig) (*ScriptResult, error) {
 	m.mu.RLock()
@@ -79,7 +178,18 @@ func (m *Manager) Execute(ctx context.Context, config ScriptConfig) (*ScriptResu
 	}
 
 	// Execute script
-	result, err := engine.Execute(ctx, config)
+	ctx, span := tracing.Start(ctx, "script.execute", attribute.String("script.type", string(config.Type)))
+	start := time.Now()
+	result, err := runScript(ctx, engine, config)
+	outcome := metrics.ResultSuccess
+	if err != nil || result.Error != "" || result.ExitCode != 0 {
+		outcome = metrics.ResultError
+	}
+	if result != nil {
+		span.SetAttributes(attribute.Int("script.exit_code", result.ExitCode))
+	}
+	tracing.End(span, err)
+	metrics.ScriptDuration.WithLabelValues(string(config.Type), outcome).Observe(time.Since(start).Seconds())
 	if err != nil {
 		m.logger.WithFields(logrus.Fields{
 			"type":  config.Type,
@@ -97,6 +207,13 @@ func (m *Manager) Execute(ctx context.Context, config ScriptConfig) (*ScriptResu
 	return result, nil
 }
 
+// runScript runs a script, turning a panic in the engine into an error so a
+// faulty engine cannot stop the bridge
+func runScript(ctx context.Context, engine ScriptEngine, config ScriptConfig) (_ *ScriptResult, err error) {
+	defer supervisor.Recover("script."+string(config.Type), &err)
+	return engine.Execute(ctx, config)
+}
+
 // Validate validates a script configuration
 func (m *Manager) Validate(config ScriptConfig) error {
 	m.mu.RLock()
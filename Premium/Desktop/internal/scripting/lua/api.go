@@ -1,9 +1,16 @@
 package lua
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
 	"time"
 
 	lua "github.com/yuin/gopher-lua"
+
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/timers"
 )
 
 // loadWaddleBotAPI loads WaddleBot-specific API functions into Lua
@@ -48,8 +55,38 @@ func (e *Engine) loadWaddleBotAPI(L *lua.LState) {
 	L.SetFuncs(bridgeModule, map[string]lua.LGFunction{
 		"send_response": e.luaBridgeSendResponse,
 		"trigger":       e.luaBridgeTrigger,
+		"emit":          e.luaBridgeEmit,
 	})
 	L.SetGlobal("bridge", bridgeModule)
+
+	// Create sound module backed by the soundboard module
+	soundModule := L.NewTable()
+	L.SetFuncs(soundModule, map[string]lua.LGFunction{
+		"play":       e.luaSoundPlay,
+		"stop":       e.luaSoundStop,
+		"set_volume": e.luaSoundSetVolume,
+	})
+	L.SetGlobal("sound", soundModule)
+
+	// Create text module for the variables text sources show
+	textModule := L.NewTable()
+	L.SetFuncs(textModule, map[string]lua.LGFunction{
+		"get":       e.luaTextGet,
+		"set":       e.luaTextSet,
+		"increment": e.luaTextIncrement,
+	})
+	L.SetGlobal("text", textModule)
+
+	// Create timer module for the countdowns and stopwatches
+	timerModule := L.NewTable()
+	L.SetFuncs(timerModule, map[string]lua.LGFunction{
+		"get":   e.luaTimerGet,
+		"start": e.luaTimerAction(timers.ActionStart),
+		"pause": e.luaTimerAction(timers.ActionPause),
+		"reset": e.luaTimerAction(timers.ActionReset),
+		"add":   e.luaTimerAction(timers.ActionAdd),
+	})
+	L.SetGlobal("timer", timerModule)
 }
 
 // Logging functions
@@ -78,13 +115,29 @@ func (e *Engine) luaLogDebug(L *lua.LState) int {
 	return 0
 }
 
-// Storage functions (in-memory for now)
+// Storage functions, kept in the scripts bucket or in memory when the
+// engine has no storage
 
-var scriptStorage = make(map[string]string)
+var (
+	scriptStorage   = make(map[string]string)
+	scriptStorageMu sync.Mutex
+)
 
 func (e *Engine) luaStorageGet(L *lua.LState) int {
 	key := L.ToString(1)
+	if e.store != nil {
+		value, err := e.store.Get(key)
+		if err != nil {
+			L.Push(lua.LNil)
+			return 1
+		}
+		L.Push(lua.LString(value))
+		return 1
+	}
+
+	scriptStorageMu.Lock()
 	value, exists := scriptStorage[key]
+	scriptStorageMu.Unlock()
 	if !exists {
 		L.Push(lua.LNil)
 		return 1
@@ -96,7 +149,16 @@ func (e *Engine) luaStorageGet(L *lua.LState) int {
 func (e *Engine) luaStorageSet(L *lua.LState) int {
 	key := L.ToString(1)
 	value := L.ToString(2)
+	if e.store != nil {
+		if err := e.store.Set(key, []byte(value)); err != nil {
+			L.RaiseError("storage.set failed: %v", err)
+		}
+		return 0
+	}
+
+	scriptStorageMu.Lock()
 	scriptStorage[key] = value
+	scriptStorageMu.Unlock()
 	return 0
 }
 
@@ -181,15 +243,313 @@ func (e *Engine) luaBridgeSendResponse(L *lua.LState) int {
 	return 1
 }
 
+// luaBridgeTrigger runs a module action. Parameters may be given as a table
+// or a JSON object string. Returns the result table, or nil and an error.
 func (e *Engine) luaBridgeTrigger(L *lua.LState) int {
-	module := L.ToString(1)
-	action := L.ToString(2)
-	params := L.ToString(3)
-	// TODO: Trigger via bridge client
+	module := L.CheckString(1)
+	action := L.CheckString(2)
+
+	params, err := luaParams(L.Get(3))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
 	e.logger.WithField("module", module).
 		WithField("action", action).
-		WithField("params", params).
 		Debug("[Lua] Bridge trigger called")
-	L.Push(lua.LBool(true))
+
+	return e.executeAction(L, module, action, params)
+}
+
+// luaBridgeEmit publishes an event on the bridge event bus. Data may be a
+// table or a JSON string; other values are wrapped as {value = ...}.
+// Returns true, or false and an error.
+func (e *Engine) luaBridgeEmit(L *lua.LState) int {
+	eventType := L.CheckString(1)
+
+	if e.publisher == nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("event bus is not available"))
+		return 2
+	}
+
+	var value interface{}
+	switch v := L.Get(2).(type) {
+	case lua.LString:
+		if err := json.Unmarshal([]byte(v), &value); err != nil {
+			value = string(v)
+		}
+	default:
+		value = fromLuaValue(v)
+	}
+
+	data, ok := value.(map[string]interface{})
+	if !ok && value != nil {
+		data = map[string]interface{}{"value": value}
+	}
+
+	e.publisher.Publish(events.Event{
+		Type:   eventType,
+		Source: events.SourceScript,
+		Data:   data,
+	})
+
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// Sound functions (backed by the soundboard module)
+
+func (e *Engine) luaSoundPlay(L *lua.LState) int {
+	params := map[string]string{"sound": L.CheckString(1)}
+	if L.GetTop() >= 2 {
+		params["volume"] = fmt.Sprintf("%d", L.CheckInt(2))
+	}
+	return e.executeAction(L, "soundboard", "play", params)
+}
+
+func (e *Engine) luaSoundStop(L *lua.LState) int {
+	return e.executeAction(L, "soundboard", "stop", map[string]string{})
+}
+
+func (e *Engine) luaSoundSetVolume(L *lua.LState) int {
+	params := map[string]string{"volume": fmt.Sprintf("%d", L.CheckInt(1))}
+	return e.executeAction(L, "soundboard", "set_volume", params)
+}
+
+// Text functions (backed by the text sources)
+
+// luaTextGet returns a variable, or nil when it is not set
+func (e *Engine) luaTextGet(L *lua.LState) int {
+	name := L.CheckString(1)
+	if e.textVars == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	value, exists := e.textVars.Variable(name)
+	if !exists {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(lua.LString(value))
 	return 1
 }
+
+// luaTextSet sets a variable. Returns true, or false and an error.
+func (e *Engine) luaTextSet(L *lua.LState) int {
+	name := L.CheckString(1)
+	value := L.ToString(2)
+	if e.textVars == nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("text sources are not available"))
+		return 2
+	}
+
+	if err := e.textVars.SetVariable(name, value); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// luaTextIncrement adds to a numeric variable, 1 by default. Returns the
+// new value, or nil and an error.
+func (e *Engine) luaTextIncrement(L *lua.LState) int {
+	name := L.CheckString(1)
+	by := int64(L.OptInt(2, 1))
+	if e.textVars == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("text sources are not available"))
+		return 2
+	}
+
+	value, err := e.textVars.Increment(name, by)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LNumber(value))
+	return 1
+}
+
+// Timer functions (backed by the timers)
+
+// luaTimerGet returns a timer's status table, or nil and an error
+func (e *Engine) luaTimerGet(L *lua.LState) int {
+	name := L.CheckString(1)
+	if e.timers == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("timers are not available"))
+		return 2
+	}
+
+	status, err := e.timers.Get(name)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(toLuaValue(L, status))
+	return 1
+}
+
+// luaTimerAction returns a function taking a timer action. add takes the
+// seconds to add after the name. Each returns the timer's status table, or
+// nil and an error.
+func (e *Engine) luaTimerAction(action string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		name := L.CheckString(1)
+		var amount time.Duration
+		if action == timers.ActionAdd {
+			amount = time.Duration(float64(L.CheckNumber(2)) * float64(time.Second))
+		}
+		if e.timers == nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString("timers are not available"))
+			return 2
+		}
+
+		status, err := e.timers.Control(name, action, amount)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(toLuaValue(L, status))
+		return 1
+	}
+}
+
+// executeAction runs a module action and pushes the result table, or nil
+// and an error message
+func (e *Engine) executeAction(L *lua.LState, module, action string, params map[string]string) int {
+	if e.executor == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("module actions are not available"))
+		return 2
+	}
+
+	ctx := L.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	result, err := e.executor.ExecuteAction(ctx, module, action, params)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(toLuaValue(L, result))
+	return 1
+}
+
+// luaParams converts a Lua table or JSON string into action parameters
+func luaParams(value lua.LValue) (map[string]string, error) {
+	params := make(map[string]string)
+
+	switch v := value.(type) {
+	case *lua.LNilType:
+	case lua.LString:
+		if v == "" {
+			break
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &decoded); err != nil {
+			return nil, fmt.Errorf("invalid parameters: %w", err)
+		}
+		for key, val := range decoded {
+			if s, ok := val.(string); ok {
+				params[key] = s
+			} else {
+				params[key] = fmt.Sprint(val)
+			}
+		}
+	case *lua.LTable:
+		v.ForEach(func(key, val lua.LValue) {
+			params[key.String()] = val.String()
+		})
+	default:
+		return nil, fmt.Errorf("parameters must be a table or JSON string")
+	}
+
+	return params, nil
+}
+
+// fromLuaValue converts a Lua value into a Go value. Tables with only
+// sequential integer keys become slices, other tables become maps.
+func fromLuaValue(value lua.LValue) interface{} {
+	switch v := value.(type) {
+	case *lua.LNilType:
+		return nil
+	case lua.LBool:
+		return bool(v)
+	case lua.LNumber:
+		return float64(v)
+	case lua.LString:
+		return string(v)
+	case *lua.LTable:
+		if n := v.Len(); n > 0 {
+			list := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				list = append(list, fromLuaValue(v.RawGetInt(i)))
+			}
+			return list
+		}
+		m := make(map[string]interface{})
+		v.ForEach(func(key, val lua.LValue) {
+			m[key.String()] = fromLuaValue(val)
+		})
+		return m
+	default:
+		return v.String()
+	}
+}
+
+// toLuaValue converts a Go value returned by a module into a Lua value
+func toLuaValue(L *lua.LState, value interface{}) lua.LValue {
+	switch v := value.(type) {
+	case nil:
+		return lua.LNil
+	case string:
+		return lua.LString(v)
+	case bool:
+		return lua.LBool(v)
+	case int:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case float64:
+		return lua.LNumber(v)
+	case map[string]interface{}:
+		table := L.NewTable()
+		for key, val := range v {
+			table.RawSetString(key, toLuaValue(L, val))
+		}
+		return table
+	case []interface{}:
+		table := L.NewTable()
+		for _, val := range v {
+			table.Append(toLuaValue(L, val))
+		}
+		return table
+	default:
+		// Round-trip other types through JSON
+		data, err := json.Marshal(v)
+		if err != nil {
+			return lua.LString(fmt.Sprint(v))
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return lua.LString(string(data))
+		}
+		return toLuaValue(L, decoded)
+	}
+}
@@ -10,12 +10,18 @@ import (
 
 	"waddlebot-bridge/internal/config"
 	"waddlebot-bridge/internal/scripting/common"
+	"waddlebot-bridge/internal/storage"
 )
 
 // Engine implements ScriptEngine for Lua
 type Engine struct {
-	config config.ScriptingConfig
-	logger *logrus.Logger
+	config   config.ScriptingConfig
+	logger   *logrus.Logger
+	executor  common.ActionExecutor
+	publisher common.EventPublisher
+	store     *storage.ScriptRepo
+	textVars  common.TextVariables
+	timers    common.Timers
 }
 
 // NewEngine creates a new Lua engine
@@ -26,6 +32,33 @@ func NewEngine(cfg config.ScriptingConfig, logger *logrus.Logger) *Engine {
 	}
 }
 
+// SetActionExecutor sets the executor used by bridge.trigger and the sound
+// API to run module actions
+func (e *Engine) SetActionExecutor(executor common.ActionExecutor) {
+	e.executor = executor
+}
+
+// SetEventPublisher sets the publisher used by bridge.emit
+func (e *Engine) SetEventPublisher(publisher common.EventPublisher) {
+	e.publisher = publisher
+}
+
+// SetTextVariables sets the variables the text API reads and changes
+func (e *Engine) SetTextVariables(vars common.TextVariables) {
+	e.textVars = vars
+}
+
+// SetTimers sets the timers the timer API runs
+func (e *Engine) SetTimers(timers common.Timers) {
+	e.timers = timers
+}
+
+// SetStorage sets where storage.get and storage.set keep values. Without
+// it values are kept in memory until the bridge stops.
+func (e *Engine) SetStorage(store *storage.ScriptRepo) {
+	e.store = store
+}
+
 // Execute executes a Lua script
 func (e *Engine) Execute(ctx context.Context, config common.ScriptConfig) (*common.ScriptResult, error) {
 	start := time.Now()
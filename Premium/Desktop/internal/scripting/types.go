@@ -10,6 +10,11 @@ type (
 	ScriptConfig = common.ScriptConfig
 	ScriptResult = common.ScriptResult
 	ScriptEngine = common.ScriptEngine
+
+	ActionExecutor = common.ActionExecutor
+	EventPublisher = common.EventPublisher
+	TextVariables  = common.TextVariables
+	Timers         = common.Timers
 )
 
 // Re-export constants
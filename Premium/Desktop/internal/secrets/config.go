@@ -0,0 +1,227 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"waddlebot-bridge/internal/config"
+)
+
+// RefPrefix marks a config value naming a secret in the store, as in
+// "secret:obs.password"
+const RefPrefix = "secret:"
+
+// configSecret is a secret setting of the config
+type configSecret struct {
+	name  string        // name of the secret in the store
+	path  []interface{} // keys and list indexes of the setting in the config file
+	value *string
+}
+
+// configSecrets lists the secret settings of cfg
+func configSecrets(cfg *config.Config) []configSecret {
+	secrets := []configSecret{
+		{"api-token", []interface{}{"api-token"}, &cfg.APIToken},
+		{"jwt-secret", []interface{}{"jwt-secret"}, &cfg.JWTSecret},
+		{"signing.secret", []interface{}{"signing", "secret"}, &cfg.Signing.Secret},
+		{"obs.password", []interface{}{"obs", "password"}, &cfg.OBS.Password},
+		{"mqtt.password", []interface{}{"mqtt", "password"}, &cfg.MQTT.Password},
+		{"gateway.api-key", []interface{}{"gateway", "api-key"}, &cfg.Gateway.APIKey},
+	}
+	for i := range cfg.Communities {
+		community := &cfg.Communities[i]
+		id := listName(community.ID, i)
+		secrets = append(secrets,
+			configSecret{"communities." + id + ".api-token", []interface{}{"communities", i, "api-token"}, &community.APIToken},
+			configSecret{"communities." + id + ".jwt-secret", []interface{}{"communities", i, "jwt-secret"}, &community.JWTSecret},
+		)
+	}
+	for i := range cfg.Events.Webhooks {
+		webhook := &cfg.Events.Webhooks[i]
+		secrets = append(secrets, configSecret{
+			"events.webhooks." + listName(webhook.Name, i) + ".secret",
+			[]interface{}{"events", "webhooks", i, "secret"},
+			&webhook.Secret,
+		})
+	}
+	return secrets
+}
+
+// listName names a list entry by its ID, or its index if it has none
+func listName(id string, index int) string {
+	if id != "" {
+		return id
+	}
+	return strconv.Itoa(index)
+}
+
+// ResolveConfig replaces references to secrets in cfg with their values.
+// Secrets still written in plaintext in configFile are moved into the
+// store and replaced there with references, keeping the rest of the file
+// as it is.
+func (s *Store) ResolveConfig(cfg *config.Config, configFile string) error {
+	var plaintext []configSecret
+	for _, secret := range configSecrets(cfg) {
+		value := *secret.value
+		switch {
+		case strings.HasPrefix(value, RefPrefix):
+			resolved, err := s.Get(strings.TrimPrefix(value, RefPrefix))
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", secret.name, err)
+			}
+			*secret.value = resolved
+		case value != "":
+			plaintext = append(plaintext, secret)
+		}
+	}
+
+	if configFile == "" || len(plaintext) == 0 {
+		return nil
+	}
+	return s.migrateConfigFile(configFile, plaintext)
+}
+
+// Redacted replaces secret settings in redacted copies of a config
+const Redacted = "[redacted]"
+
+// Redact replaces the secret settings of cfg with Redacted and returns the
+// values it replaced, other than references to the store, so they can be
+// scrubbed from logs as well
+func Redact(cfg *config.Config) []string {
+	var values []string
+	for _, secret := range configSecrets(cfg) {
+		value := *secret.value
+		if value == "" {
+			continue
+		}
+		if !strings.HasPrefix(value, RefPrefix) {
+			values = append(values, value)
+		}
+		*secret.value = Redacted
+	}
+	return values
+}
+
+// SecretField reports whether a log field or setting may hold a secret,
+// judged by its name, such as api_key or gateway.api-key
+func SecretField(key string) bool {
+	key = strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	key = strings.ReplaceAll(key, "-", "_")
+	for _, word := range []string{"password", "secret", "token", "api_key", "apikey", "authorization"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactValue returns a copy of a plain JSON value, named key, with every
+// string whose key looks secret replaced with Redacted, including inside
+// objects and lists
+func RedactValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			redacted[k] = RedactValue(k, item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = RedactValue(key, item)
+		}
+		return redacted
+	case string:
+		if v != "" && SecretField(key) {
+			return Redacted
+		}
+	}
+	return value
+}
+
+// migrateConfigFile moves the plaintext secrets found in configFile into
+// the store. Secrets set by flags or the environment are left alone.
+func (s *Store) migrateConfigFile(configFile string, plaintext []configSecret) error {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// Only YAML config files are migrated
+		s.logger.WithError(err).Debug("Not moving secrets out of the config file")
+		return nil
+	}
+
+	migrated := 0
+	for _, secret := range plaintext {
+		node := lookupNode(&doc, secret.path)
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value != *secret.value {
+			continue
+		}
+		if err := s.Set(secret.name, *secret.value); err != nil {
+			return fmt.Errorf("failed to store %s: %w", secret.name, err)
+		}
+		node.Value = RefPrefix + secret.name
+		node.Style = 0
+		migrated++
+	}
+	if migrated == 0 {
+		return nil
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := os.WriteFile(configFile, out.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	s.logger.WithField("count", migrated).Info("Moved plaintext secrets from the config file into the encrypted store")
+	return nil
+}
+
+// lookupNode returns the node at path in a YAML document, or nil
+func lookupNode(node *yaml.Node, path []interface{}) *yaml.Node {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+
+	for _, step := range path {
+		var next *yaml.Node
+		switch step := step.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return nil
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == step {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind != yaml.SequenceNode || step >= len(node.Content) {
+				return nil
+			}
+			next = node.Content[step]
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
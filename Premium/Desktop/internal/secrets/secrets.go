@@ -0,0 +1,244 @@
+// Package secrets encrypts secrets at rest. Values are sealed with
+// AES-256-GCM under a key kept in the OS keychain, or in a file readable
+// only by the user where no keychain is available. Secrets can be stored by
+// name, whole storage buckets can be encrypted transparently, and
+// plaintext secrets in the config file can be moved into the store.
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/keychain"
+	"waddlebot-bridge/internal/storage"
+)
+
+// Bucket holds the named secrets
+const Bucket = "secrets"
+
+const (
+	// keychainAccount is the keychain account holding the encryption key
+	keychainAccount = "secrets-key"
+
+	// keyFile holds the encryption key in the data directory where no
+	// keychain is available
+	keyFile = "secrets.key"
+)
+
+// magic starts every sealed value, telling it apart from plaintext
+var magic = []byte("wbs1")
+
+var (
+	// ErrNotFound is returned for an unknown secret
+	ErrNotFound = errors.New("secret not found")
+
+	// ErrCorrupt is returned for a sealed value that cannot be opened
+	ErrCorrupt = errors.New("encrypted value is corrupt or was sealed with another key")
+)
+
+// Cipher seals and opens values. Each value is bound to a label, such as
+// its storage location, so it cannot be moved elsewhere.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher with a 32 byte key
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts plain for label
+func (c *Cipher) Seal(plain []byte, label string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := append(append([]byte{}, magic...), nonce...)
+	return c.aead.Seal(sealed, nonce, plain, []byte(label)), nil
+}
+
+// Open decrypts a value sealed for label
+func (c *Cipher) Open(data []byte, label string) ([]byte, error) {
+	if !IsSealed(data) || len(data) < len(magic)+c.aead.NonceSize() {
+		return nil, ErrCorrupt
+	}
+	data = data[len(magic):]
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, []byte(label))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return plain, nil
+}
+
+// IsSealed reports whether data was sealed by a Cipher
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// LoadKey returns the encryption key from the OS keychain, creating it on
+// first use. Without a usable keychain the key is kept in dataDir instead;
+// a key found there is moved into the keychain once it is available.
+func LoadKey(k keychain.Keychain, dataDir string, logger *logrus.Logger) ([]byte, error) {
+	path := filepath.Join(dataDir, keyFile)
+	fileKey, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if k != nil {
+		key, err := k.Get(keychainAccount)
+		switch {
+		case err == nil && len(key) == 32:
+			if fileKey != nil {
+				removeKeyFile(path, logger)
+			}
+			return key, nil
+		case errors.Is(err, keychain.ErrNotFound):
+			if fileKey == nil {
+				if fileKey, err = newKey(); err != nil {
+					return nil, err
+				}
+			}
+			if err := k.Set(keychainAccount, fileKey); err == nil {
+				removeKeyFile(path, logger)
+				logger.Info("Stored the secrets encryption key in the OS keychain")
+				return fileKey, nil
+			} else {
+				logger.WithError(err).Warn("OS keychain unavailable, keeping the secrets encryption key in the data directory")
+			}
+		case err == nil:
+			return nil, fmt.Errorf("invalid secrets key in the OS keychain")
+		default:
+			logger.WithError(err).Warn("OS keychain unavailable, keeping the secrets encryption key in the data directory")
+		}
+	}
+
+	if fileKey != nil {
+		return fileKey, nil
+	}
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write secrets key: %w", err)
+	}
+	return key, nil
+}
+
+// readKeyFile returns the key kept in the data directory, or nil if there
+// is none
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid secrets key in %s", path)
+	}
+	return key, nil
+}
+
+func removeKeyFile(path string, logger *logrus.Logger) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.WithError(err).Warn("Failed to remove the secrets key file")
+	}
+}
+
+func newKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secrets key: %w", err)
+	}
+	return key, nil
+}
+
+// Store keeps named secrets encrypted in storage
+type Store struct {
+	cipher  *Cipher
+	storage *EncryptedStorage
+	logger  *logrus.Logger
+}
+
+// Open creates the secret store, with the encryption key from the
+// keychain k. Values in the given buckets of store, and the named secrets,
+// are encrypted; see Storage.
+func Open(store storage.Storage, k keychain.Keychain, dataDir string, logger *logrus.Logger, buckets ...string) (*Store, error) {
+	key, err := LoadKey(k, dataDir, logger)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(store, c, logger, buckets...)
+}
+
+// NewStore creates a secret store sealing values with c
+func NewStore(store storage.Storage, c *Cipher, logger *logrus.Logger, buckets ...string) (*Store, error) {
+	if err := store.EnsureBucket(Bucket); err != nil {
+		return nil, fmt.Errorf("failed to create secrets bucket: %w", err)
+	}
+	return &Store{
+		cipher:  c,
+		storage: Wrap(store, c, logger, append([]string{Bucket}, buckets...)...),
+		logger:  logger,
+	}, nil
+}
+
+// Storage returns the storage that encrypts the store's buckets
+func (s *Store) Storage() storage.Storage {
+	return s.storage
+}
+
+// Get returns a named secret
+func (s *Store) Get(name string) (string, error) {
+	value, err := s.storage.GetWithBucket(Bucket, name)
+	if errors.Is(err, ErrCorrupt) {
+		return "", err
+	}
+	if err != nil {
+		// Storage reports missing keys with plain errors
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return string(value), nil
+}
+
+// Set stores a named secret
+func (s *Store) Set(name, value string) error {
+	return s.storage.SetWithBucket(Bucket, name, []byte(value))
+}
+
+// Delete removes a named secret
+func (s *Store) Delete(name string) error {
+	return s.storage.DeleteWithBucket(Bucket, name)
+}
+
+// Names lists the named secrets
+func (s *Store) Names() ([]string, error) {
+	return s.storage.ListWithBucket(Bucket, "")
+}
@@ -0,0 +1,193 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/keychain"
+	"waddlebot-bridge/internal/testutils"
+)
+
+func newTestCipher(t *testing.T) *Cipher {
+	t.Helper()
+	c, err := NewCipher(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+	return c
+}
+
+func TestCipher(t *testing.T) {
+	c := newTestCipher(t)
+
+	sealed, err := c.Seal([]byte("hunter2"), "obs/password")
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("hunter2")) {
+		t.Fatalf("expected an encrypted value, got %q", sealed)
+	}
+
+	plain, err := c.Open(sealed, "obs/password")
+	if err != nil || string(plain) != "hunter2" {
+		t.Errorf("expected the value back, got %q, %v", plain, err)
+	}
+
+	// A value cannot be opened for another label
+	if _, err := c.Open(sealed, "mqtt/password"); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt for another label, got %v", err)
+	}
+}
+
+func TestLoadKeyMovesFileKeyIntoKeychain(t *testing.T) {
+	dir := t.TempDir()
+	logger := logrus.New()
+
+	// Without a keychain the key is kept in the data directory
+	fileKey, err := LoadKey(nil, dir, logger)
+	if err != nil {
+		t.Fatalf("LoadKey failed: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, keyFile))
+	if err != nil {
+		t.Fatalf("expected a key file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the key file to be private, got %v", info.Mode().Perm())
+	}
+
+	k := keychain.NewMemory()
+	key, err := LoadKey(k, dir, logger)
+	if err != nil || !bytes.Equal(key, fileKey) {
+		t.Fatalf("expected the file key, got %v", err)
+	}
+	if stored, _ := k.Get(keychainAccount); !bytes.Equal(stored, fileKey) {
+		t.Error("expected the key to be moved into the keychain")
+	}
+	if _, err := os.Stat(filepath.Join(dir, keyFile)); !os.IsNotExist(err) {
+		t.Error("expected the key file to be removed")
+	}
+
+	if key, _ := LoadKey(k, dir, logger); !bytes.Equal(key, fileKey) {
+		t.Error("expected the same key from the keychain")
+	}
+}
+
+func TestEncryptedStorageMigratesPlaintext(t *testing.T) {
+	inner := testutils.NewMockStorage()
+	inner.SetWithBucket("webhooks", "legacy", []byte(`{"secret":"s3cret"}`))
+	inner.SetWithBucket("other", "key", []byte("plain"))
+
+	s := Wrap(inner, newTestCipher(t), logrus.New(), "webhooks")
+
+	all, err := s.GetAllFromBucket("webhooks")
+	if err != nil || string(all["legacy"]) != `{"secret":"s3cret"}` {
+		t.Fatalf("expected the plaintext value, got %q, %v", all["legacy"], err)
+	}
+	if raw, _ := inner.GetWithBucket("webhooks", "legacy"); !IsSealed(raw) {
+		t.Error("expected the plaintext value to be encrypted in place")
+	}
+	if value, _ := s.GetWithBucket("webhooks", "legacy"); string(value) != `{"secret":"s3cret"}` {
+		t.Errorf("expected the decrypted value, got %q", value)
+	}
+
+	if err := s.SetWithBucket("other", "key", []byte("still plain")); err != nil {
+		t.Fatalf("SetWithBucket failed: %v", err)
+	}
+	if raw, _ := inner.GetWithBucket("other", "key"); string(raw) != "still plain" {
+		t.Errorf("expected other buckets to be left alone, got %q", raw)
+	}
+}
+
+func TestResolveConfig(t *testing.T) {
+	store, err := NewStore(testutils.NewMockStorage(), newTestCipher(t), logrus.New())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	if err := store.Set("mqtt.password", "from-store"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `# bridge settings
+obs:
+  enabled: true
+  password: "obs-pass" # from the OBS dialog
+mqtt:
+  password: secret:mqtt.password
+communities:
+  - id: penguins
+    api-token: community-token
+`
+	if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{JWTSecret: "from-flag"}
+	cfg.OBS.Password = "obs-pass"
+	cfg.MQTT.Password = "secret:mqtt.password"
+	cfg.Communities = []config.CommunityConfig{{ID: "penguins", APIToken: "community-token"}}
+
+	if err := store.ResolveConfig(cfg, configFile); err != nil {
+		t.Fatalf("ResolveConfig failed: %v", err)
+	}
+	if cfg.MQTT.Password != "from-store" || cfg.OBS.Password != "obs-pass" || cfg.JWTSecret != "from-flag" {
+		t.Errorf("unexpected resolved config %+v", cfg)
+	}
+
+	if value, err := store.Get("obs.password"); err != nil || value != "obs-pass" {
+		t.Errorf("expected the OBS password in the store, got %q, %v", value, err)
+	}
+	if value, err := store.Get("communities.penguins.api-token"); err != nil || value != "community-token" {
+		t.Errorf("expected the community token in the store, got %q, %v", value, err)
+	}
+	if _, err := store.Get("jwt-secret"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected secrets outside the config file to stay out of the store, got %v", err)
+	}
+
+	data, _ := os.ReadFile(configFile)
+	rewritten := string(data)
+	for _, expected := range []string{
+		"# bridge settings",
+		"password: secret:obs.password # from the OBS dialog",
+		"api-token: secret:communities.penguins.api-token",
+	} {
+		if !strings.Contains(rewritten, expected) {
+			t.Errorf("expected %q in the rewritten config:\n%s", expected, rewritten)
+		}
+	}
+	if strings.Contains(rewritten, "obs-pass") || strings.Contains(rewritten, "community-token") {
+		t.Errorf("expected no plaintext secrets in the rewritten config:\n%s", rewritten)
+	}
+
+	// A reference to a missing secret is an error
+	cfg.Gateway.APIKey = "secret:missing"
+	if err := store.ResolveConfig(cfg, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing secret, got %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "jwt", CommunityID: "community"}
+	cfg.OBS.Password = "obs-pass"
+	cfg.MQTT.Password = "secret:mqtt.password"
+	cfg.Events.Webhooks = []config.WebhookSinkConfig{{Name: "alerts", Secret: "hook-secret"}}
+
+	values := Redact(cfg)
+	if cfg.JWTSecret != Redacted || cfg.OBS.Password != Redacted || cfg.MQTT.Password != Redacted || cfg.Events.Webhooks[0].Secret != Redacted {
+		t.Errorf("expected every secret to be redacted, got %+v", cfg)
+	}
+	if cfg.CommunityID != "community" || cfg.APIToken != "" {
+		t.Errorf("expected other settings to be left alone, got %+v", cfg)
+	}
+	if strings.Join(values, ",") != "jwt,obs-pass,hook-secret" {
+		t.Errorf("expected the plaintext values, got %v", values)
+	}
+}
@@ -0,0 +1,125 @@
+package secrets
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/storage"
+)
+
+// EncryptedStorage encrypts the values of some buckets of the storage it
+// wraps and passes everything else through. Plaintext values written
+// before a bucket was encrypted are still read, and are encrypted in place
+// the first time they are.
+type EncryptedStorage struct {
+	storage.Storage
+	cipher  *Cipher
+	logger  *logrus.Logger
+	buckets map[string]bool
+}
+
+// Wrap returns storage that encrypts the values of buckets in inner
+func Wrap(inner storage.Storage, c *Cipher, logger *logrus.Logger, buckets ...string) *EncryptedStorage {
+	s := &EncryptedStorage{
+		Storage: inner,
+		cipher:  c,
+		logger:  logger,
+		buckets: make(map[string]bool),
+	}
+	for _, bucket := range buckets {
+		s.buckets[bucket] = true
+	}
+	return s
+}
+
+// SetWithBucket stores a value, encrypted if the bucket is
+func (s *EncryptedStorage) SetWithBucket(bucketName, key string, value []byte) error {
+	if !s.buckets[bucketName] {
+		return s.Storage.SetWithBucket(bucketName, key, value)
+	}
+	sealed, err := s.cipher.Seal(value, label(bucketName, key))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SetWithBucket(bucketName, key, sealed)
+}
+
+// SetWithBucketTTL stores an expiring value, encrypted if the bucket is
+func (s *EncryptedStorage) SetWithBucketTTL(bucketName, key string, value []byte, ttl time.Duration) error {
+	if !s.buckets[bucketName] {
+		return s.Storage.SetWithBucketTTL(bucketName, key, value, ttl)
+	}
+	sealed, err := s.cipher.Seal(value, label(bucketName, key))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SetWithBucketTTL(bucketName, key, sealed, ttl)
+}
+
+// GetWithBucket returns a value, decrypted if the bucket is encrypted
+func (s *EncryptedStorage) GetWithBucket(bucketName, key string) ([]byte, error) {
+	data, err := s.Storage.GetWithBucket(bucketName, key)
+	if err != nil || !s.buckets[bucketName] {
+		return data, err
+	}
+	return s.open(bucketName, key, data)
+}
+
+// GetAllFromBucket returns a bucket's values, decrypted if it is
+// encrypted. Values that cannot be decrypted are left out.
+func (s *EncryptedStorage) GetAllFromBucket(bucketName string) (map[string][]byte, error) {
+	all, err := s.Storage.GetAllFromBucket(bucketName)
+	if err != nil || !s.buckets[bucketName] {
+		return all, err
+	}
+
+	for key, data := range all {
+		plain, err := s.open(bucketName, key, data)
+		if err != nil {
+			s.logger.WithError(err).WithField("bucket", bucketName).WithField("key", key).Warn("Skipping unreadable encrypted value")
+			delete(all, key)
+			continue
+		}
+		all[key] = plain
+	}
+	return all, nil
+}
+
+// Buckets returns the names of all buckets of the wrapped storage
+func (s *EncryptedStorage) Buckets() ([]string, error) {
+	lister, ok := s.Storage.(storage.BucketLister)
+	if !ok {
+		return nil, fmt.Errorf("storage cannot list its buckets")
+	}
+	return lister.Buckets()
+}
+
+// ExpiresAt returns when a key of the wrapped storage expires
+func (s *EncryptedStorage) ExpiresAt(bucketName, key string) (time.Time, bool) {
+	if expiring, ok := s.Storage.(storage.Expiring); ok {
+		return expiring.ExpiresAt(bucketName, key)
+	}
+	return time.Time{}, false
+}
+
+// open decrypts a stored value, encrypting it in place if it was stored
+// as plaintext
+func (s *EncryptedStorage) open(bucketName, key string, data []byte) ([]byte, error) {
+	if IsSealed(data) {
+		return s.cipher.Open(data, label(bucketName, key))
+	}
+
+	if err := s.SetWithBucket(bucketName, key, data); err != nil {
+		s.logger.WithError(err).WithField("bucket", bucketName).Warn("Failed to encrypt stored value")
+	} else {
+		s.logger.WithField("bucket", bucketName).WithField("key", key).Debug("Encrypted plaintext value")
+	}
+	return data, nil
+}
+
+// label binds a sealed value to where it is stored
+func label(bucketName, key string) string {
+	return bucketName + "/" + key
+}
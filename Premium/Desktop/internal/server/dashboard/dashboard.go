@@ -0,0 +1,437 @@
+// Package dashboard serves the activity dashboard of the web interface:
+// recent tasks, OBS state, module health and the live log
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/auth"
+	"waddlebot-bridge/internal/logger"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/modules"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/poller"
+)
+
+// logAuthTimeout is how long a log stream waits for the caller's token
+const logAuthTimeout = 10 * time.Second
+
+// sessionCheckInterval is how often a log stream checks that the session
+// it was opened with is still valid, so a revoked session stops following
+// the log
+const sessionCheckInterval = 30 * time.Second
+
+// Sessions validates the access tokens of signed in users
+type Sessions interface {
+	ValidateJWT(token string) (*models.AuthSession, error)
+}
+
+// OBSStatus reports the OBS connection
+type OBSStatus interface {
+	GetConnectionInfo() obs.ConnectionInfo
+}
+
+// ModuleHealthSource reports the health of loaded modules
+type ModuleHealthSource interface {
+	GetModuleHealth() []modules.ModuleHealth
+}
+
+// TaskHistory lists a community's recently finished tasks
+type TaskHistory interface {
+	RecentTasks() []poller.TaskSummary
+}
+
+// LogTail holds the latest log entries and follows new ones
+type LogTail interface {
+	Recent() []logger.Entry
+	Subscribe() (<-chan logger.Entry, func())
+}
+
+// Sources are the parts of the bridge shown on the activity dashboard.
+// Any of them may be nil, in which case they are left out.
+type Sources struct {
+	OBS     OBSStatus
+	Modules ModuleHealthSource
+	Tasks   map[string]TaskHistory // by community ID
+	Logs    LogTail
+}
+
+// dashboardTask is a finished task and the community it came from
+type dashboardTask struct {
+	Community string `json:"community"`
+	poller.TaskSummary
+}
+
+// logUpgrader accepts log streams from the dashboard page only, which is
+// served from the same origin
+var logUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// Dashboard serves the activity dashboard
+type Dashboard struct {
+	sources      Sources
+	sessions     Sessions
+	logger       *logrus.Logger
+	sessionCheck time.Duration
+}
+
+// New creates the activity dashboard showing sources to users signed in
+// with sessions
+func New(sources Sources, sessions Sessions, logger *logrus.Logger) *Dashboard {
+	return &Dashboard{
+		sources:      sources,
+		sessions:     sessions,
+		logger:       logger,
+		sessionCheck: sessionCheckInterval,
+	}
+}
+
+// Register adds the dashboard routes to router
+func (d *Dashboard) Register(router *mux.Router) {
+	router.HandleFunc("/dashboard", d.handlePage).Methods("GET")
+	router.HandleFunc("/dashboard/data", d.handleData).Methods("GET")
+	router.HandleFunc("/dashboard/logs", d.handleLogs).Methods("GET")
+}
+
+// handlePage serves the activity dashboard page. The page signs in with
+// the token kept by the main page and loads its data from the endpoints
+// below.
+func (d *Dashboard) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(dashboardPage))
+}
+
+// handleData returns the OBS state, module health and recent tasks
+func (d *Dashboard) handleData(w http.ResponseWriter, r *http.Request) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if _, err := d.sessions.ValidateJWT(token); err != nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	data := map[string]interface{}{
+		"generated_at": time.Now(),
+	}
+
+	if d.sources.OBS != nil {
+		info := d.sources.OBS.GetConnectionInfo()
+		data["obs"] = map[string]interface{}{
+			"state":             info.State.String(),
+			"obs_version":       info.OBSVersion,
+			"websocket_version": info.WebSocketVersion,
+			"connected_at":      info.ConnectedAt,
+			"last_error":        info.LastError,
+		}
+	}
+
+	moduleHealth := []modules.ModuleHealth{}
+	if d.sources.Modules != nil {
+		moduleHealth = d.sources.Modules.GetModuleHealth()
+	}
+	data["modules"] = moduleHealth
+
+	tasks := []dashboardTask{}
+	for community, history := range d.sources.Tasks {
+		for _, task := range history.RecentTasks() {
+			tasks = append(tasks, dashboardTask{Community: community, TaskSummary: task})
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].FinishedAt.After(tasks[j].FinishedAt)
+	})
+	data["tasks"] = tasks
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// handleLogs streams the log over a WebSocket to owners. Browsers cannot
+// set headers on WebSockets, so the caller sends its access token as the
+// first message, {"token": "..."}; the latest entries follow, then new
+// ones as they are logged. The stream is closed once the session is
+// revoked or expires.
+func (d *Dashboard) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if d.sources.Logs == nil {
+		http.Error(w, "Log tail unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := logUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		d.logger.WithError(err).Debug("Failed to upgrade log stream")
+		return
+	}
+	defer conn.Close()
+
+	var hello struct {
+		Token string `json:"token"`
+	}
+	conn.SetReadDeadline(time.Now().Add(logAuthTimeout))
+	if err := conn.ReadJSON(&hello); err != nil {
+		return
+	}
+	if reason := d.checkOwner(hello.Token); reason != "" {
+		closeStream(conn, reason)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	entries, stop := d.sources.Logs.Subscribe()
+	defer stop()
+
+	for _, entry := range d.sources.Logs.Recent() {
+		if err := conn.WriteJSON(entry); err != nil {
+			return
+		}
+	}
+
+	// The page sends nothing more; reading notices when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(d.sessionCheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			if reason := d.checkOwner(hello.Token); reason != "" {
+				closeStream(conn, reason)
+				return
+			}
+		case entry := <-entries:
+			conn.SetWriteDeadline(time.Now().Add(logAuthTimeout))
+			if err := conn.WriteJSON(entry); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// checkOwner returns why a token may not follow the log, or "" when it
+// belongs to an owner's valid session. The log may hold anything the
+// bridge does, so it is shown to owners only, like the bridge's settings.
+func (d *Dashboard) checkOwner(token string) string {
+	session, err := d.sessions.ValidateJWT(token)
+	if err != nil {
+		return "authentication required"
+	}
+	if auth.Role(session.Role) != auth.RoleOwner {
+		return "owner role required"
+	}
+	return ""
+}
+
+// closeStream closes a log stream as a policy violation, giving reason
+func closeStream(conn *websocket.Conn, reason string) {
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(time.Second))
+}
+
+// dashboardPage is the activity dashboard
+const dashboardPage = `<!DOCTYPE html>
+<html>
+<head>
+    <title>WaddleBot Bridge Activity</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 0; padding: 20px; background-color: #f5f5f5; }
+        .container { max-width: 1100px; margin: 0 auto; background: white; padding: 20px; border-radius: 10px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); }
+        .header { display: flex; justify-content: space-between; align-items: baseline; }
+        .logo { font-size: 1.6em; color: #4CAF50; }
+        .notice { margin: 20px 0; padding: 15px; border-radius: 5px; background-color: #f8d7da; border: 1px solid #f5c6cb; color: #721c24; display: none; }
+        .grid { display: grid; grid-template-columns: 1fr 2fr; gap: 20px; }
+        .panel { margin: 10px 0; padding: 15px; background-color: #f8f9fa; border-radius: 5px; }
+        .panel h3 { margin-top: 0; }
+        table { width: 100%; border-collapse: collapse; font-size: 0.9em; }
+        th, td { text-align: left; padding: 4px 6px; border-bottom: 1px solid #e0e0e0; }
+        .ok { color: #155724; }
+        .failed { color: #721c24; }
+        #logs { height: 320px; overflow-y: auto; background: #1e1e1e; color: #d4d4d4; font-family: monospace; font-size: 0.85em; padding: 10px; border-radius: 5px; white-space: pre-wrap; }
+        .level-warning { color: #dcdcaa; }
+        .level-error, .level-fatal, .level-panic { color: #f48771; }
+        .level-debug, .level-trace { color: #808080; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <div class="logo">WaddleBot Bridge Activity</div>
+            <a href="/">Back to the bridge</a>
+        </div>
+
+        <div id="notice" class="notice">
+            Sign in on the <a href="/">main page</a> to see the bridge's activity.
+        </div>
+
+        <div class="grid">
+            <div>
+                <div class="panel">
+                    <h3>OBS</h3>
+                    <div id="obs">Not enabled</div>
+                </div>
+                <div class="panel">
+                    <h3>Modules</h3>
+                    <table>
+                        <thead><tr><th>Module</th><th>Calls</th><th>Status</th></tr></thead>
+                        <tbody id="modules"></tbody>
+                    </table>
+                </div>
+            </div>
+            <div class="panel">
+                <h3>Recent Tasks</h3>
+                <table>
+                    <thead><tr><th>Finished</th><th>Community</th><th>Task</th><th>Duration</th><th>Result</th></tr></thead>
+                    <tbody id="tasks"></tbody>
+                </table>
+            </div>
+        </div>
+
+        <div class="panel">
+            <h3>Log</h3>
+            <div id="logs"></div>
+        </div>
+    </div>
+
+    <script>
+        const maxLogLines = 500;
+
+        function token() {
+            return localStorage.getItem('waddlebot-token');
+        }
+
+        function showNotice() {
+            document.getElementById('notice').style.display = 'block';
+        }
+
+        // cell adds a table cell holding text
+        function cell(row, text, className) {
+            const td = row.insertCell();
+            td.textContent = text;
+            if (className) {
+                td.className = className;
+            }
+        }
+
+        async function refreshData() {
+            if (!token()) {
+                showNotice();
+                return;
+            }
+            try {
+                const response = await fetch('/dashboard/data', { headers: { 'Authorization': 'Bearer ' + token() } });
+                if (response.status === 401) {
+                    showNotice();
+                    return;
+                }
+                render(await response.json());
+            } catch (error) {
+                console.error('Error loading activity:', error);
+            }
+        }
+
+        function render(data) {
+            const obs = document.getElementById('obs');
+            if (data.obs) {
+                obs.textContent = data.obs.state
+                    + (data.obs.obs_version ? ' (OBS ' + data.obs.obs_version + ')' : '')
+                    + (data.obs.last_error ? ': ' + data.obs.last_error : '');
+                obs.className = data.obs.state === 'connected' ? 'ok' : 'failed';
+            }
+
+            const modules = document.getElementById('modules');
+            modules.innerHTML = '';
+            for (const module of data.modules) {
+                const row = modules.insertRow();
+                cell(row, module.name);
+                cell(row, module.calls);
+                if (!module.enabled) {
+                    cell(row, 'disabled');
+                } else if (module.healthy) {
+                    cell(row, 'healthy', 'ok');
+                } else {
+                    cell(row, module.last_error || 'failing', 'failed');
+                }
+            }
+
+            const tasks = document.getElementById('tasks');
+            tasks.innerHTML = '';
+            for (const task of data.tasks) {
+                const row = tasks.insertRow();
+                cell(row, new Date(task.finished_at).toLocaleTimeString());
+                cell(row, task.community);
+                cell(row, task.module ? task.module + '.' + task.action : task.type);
+                cell(row, task.duration + ' ms');
+                cell(row, task.success ? 'ok' : task.error, task.success ? 'ok' : 'failed');
+            }
+        }
+
+        function followLogs() {
+            if (!token()) {
+                return;
+            }
+            const scheme = location.protocol === 'https:' ? 'wss://' : 'ws://';
+            const socket = new WebSocket(scheme + location.host + '/dashboard/logs');
+            socket.onopen = () => socket.send(JSON.stringify({ token: token() }));
+            socket.onmessage = (message) => {
+                const entry = JSON.parse(message.data);
+                const logs = document.getElementById('logs');
+                const atBottom = logs.scrollTop + logs.clientHeight >= logs.scrollHeight - 5;
+
+                const line = document.createElement('div');
+                line.className = 'level-' + entry.level;
+                const fields = Object.entries(entry.fields || {}).map(([key, value]) => key + '=' + value).join(' ');
+                line.textContent = new Date(entry.time).toLocaleTimeString() + ' ' + entry.level.toUpperCase() + ' ' + entry.message + (fields ? ' ' + fields : '');
+                logs.appendChild(line);
+                while (logs.childElementCount > maxLogLines) {
+                    logs.removeChild(logs.firstChild);
+                }
+                if (atBottom) {
+                    logs.scrollTop = logs.scrollHeight;
+                }
+            };
+            socket.onclose = (event) => {
+                if (event.code === 1008) {
+                    if (event.reason === 'owner role required') {
+                        document.getElementById('logs').textContent = 'Only the bridge owner can follow the log.';
+                    } else {
+                        showNotice();
+                    }
+                    return;
+                }
+                setTimeout(followLogs, 5000);
+            };
+        }
+
+        window.onload = function() {
+            refreshData();
+            setInterval(refreshData, 5000);
+            followLogs();
+        };
+    </script>
+</body>
+</html>
+`
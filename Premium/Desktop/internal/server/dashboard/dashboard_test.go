@@ -0,0 +1,154 @@
+package dashboard
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/logger"
+	"waddlebot-bridge/internal/models"
+)
+
+// fakeSessions maps access tokens to the role of their session
+type fakeSessions struct {
+	mu    sync.Mutex
+	roles map[string]string
+}
+
+func (f *fakeSessions) ValidateJWT(token string) (*models.AuthSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	role, ok := f.roles[token]
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+	return &models.AuthSession{ID: token, UserID: token, Role: role}, nil
+}
+
+func (f *fakeSessions) revoke(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.roles, token)
+}
+
+type fakeTail struct {
+	recent []logger.Entry
+	live   chan logger.Entry
+}
+
+func (f *fakeTail) Recent() []logger.Entry { return f.recent }
+
+func (f *fakeTail) Subscribe() (<-chan logger.Entry, func()) { return f.live, func() {} }
+
+func newTestDashboard(t *testing.T) (*httptest.Server, *fakeSessions, *fakeTail) {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	sessions := &fakeSessions{roles: map[string]string{"streamer": "owner", "guest": "viewer"}}
+	tail := &fakeTail{
+		recent: []logger.Entry{{Level: "info", Message: "Bridge started"}},
+		live:   make(chan logger.Entry, 1),
+	}
+	d := New(Sources{Logs: tail}, sessions, log)
+	d.sessionCheck = 20 * time.Millisecond
+
+	router := mux.NewRouter()
+	d.Register(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, sessions, tail
+}
+
+// openLogs opens the log stream and sends token
+func openLogs(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/dashboard/logs", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.WriteJSON(map[string]string{"token": token}); err != nil {
+		t.Fatalf("Sending the token failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn
+}
+
+// expectClose reads until the stream is closed as a policy violation
+// giving reason
+func expectClose(t *testing.T, conn *websocket.Conn, reason string) {
+	t.Helper()
+	for {
+		var entry logger.Entry
+		err := conn.ReadJSON(&entry)
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != reason {
+			t.Fatalf("Expected a policy violation close (%s), got %v", reason, err)
+		}
+		return
+	}
+}
+
+func TestDashboard_Data(t *testing.T) {
+	server, _, _ := newTestDashboard(t)
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"no session", "", http.StatusUnauthorized},
+		{"unknown session", "expired", http.StatusUnauthorized},
+		{"viewer", "guest", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", server.URL+"/dashboard/data", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestDashboard_Logs(t *testing.T) {
+	server, sessions, tail := newTestDashboard(t)
+
+	expectClose(t, openLogs(t, server, "expired"), "authentication required")
+	expectClose(t, openLogs(t, server, "guest"), "owner role required")
+
+	conn := openLogs(t, server, "streamer")
+	var entry logger.Entry
+	if err := conn.ReadJSON(&entry); err != nil || entry.Message != "Bridge started" {
+		t.Fatalf("Expected the recent entries first, got %+v (%v)", entry, err)
+	}
+	tail.live <- logger.Entry{Level: "warning", Message: "OBS disconnected"}
+	if err := conn.ReadJSON(&entry); err != nil || entry.Message != "OBS disconnected" {
+		t.Fatalf("Expected the live entry, got %+v (%v)", entry, err)
+	}
+
+	sessions.revoke("streamer")
+	expectClose(t, conn, "authentication required")
+}
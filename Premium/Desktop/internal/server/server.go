@@ -2,10 +2,13 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -14,6 +17,8 @@ import (
 	"waddlebot-bridge/internal/bridge"
 	"waddlebot-bridge/internal/config"
 	"waddlebot-bridge/internal/logger"
+	"waddlebot-bridge/internal/models"
+	"waddlebot-bridge/internal/server/dashboard"
 )
 
 // WebServer handles the web interface for authentication
@@ -23,6 +28,8 @@ type WebServer struct {
 	bridgeClient *bridge.Client
 	logger       *logrus.Logger
 	server       *http.Server
+	tlsConfig    *tls.Config
+	dashboard    dashboard.Sources
 }
 
 // NewWebServer creates a new web server
@@ -35,6 +42,18 @@ func NewWebServer(cfg *config.Config, authenticator *auth.WebAuthnManager, bridg
 	}
 }
 
+// SetDashboard sets what the activity dashboard shows. It must be called
+// before Start.
+func (s *WebServer) SetDashboard(sources dashboard.Sources) {
+	s.dashboard = sources
+}
+
+// SetTLSConfig serves the web interface over TLS with the given
+// configuration. It must be called before Start.
+func (s *WebServer) SetTLSConfig(tlsConfig *tls.Config) {
+	s.tlsConfig = tlsConfig
+}
+
 // Start starts the web server
 func (s *WebServer) Start(ctx context.Context) error {
 	router := mux.NewRouter()
@@ -48,26 +67,55 @@ func (s *WebServer) Start(ctx context.Context) error {
 	router.HandleFunc("/auth/register/complete", s.handleRegisterComplete).Methods("POST")
 	router.HandleFunc("/auth/login/start", s.handleLoginStart).Methods("POST")
 	router.HandleFunc("/auth/login/complete", s.handleLoginComplete).Methods("POST")
+	router.HandleFunc("/auth/refresh", s.handleRefresh).Methods("POST")
 	router.HandleFunc("/auth/logout", s.handleLogout).Methods("POST")
 
+	// Device token routes, for machines without a browser
+	router.HandleFunc("/auth/device", s.handleDeviceLogin).Methods("POST")
+	router.HandleFunc("/auth/device/reset", s.handleDeviceReset).Methods("POST")
+
+	// Credential routes, for the signed in user's own passkeys
+	router.HandleFunc("/auth/credentials", s.handleListCredentials).Methods("GET")
+	router.HandleFunc("/auth/credentials/start", s.handleCredentialStart).Methods("POST")
+	router.HandleFunc("/auth/credentials/complete", s.handleCredentialComplete).Methods("POST")
+	router.HandleFunc("/auth/credentials/{credential}", s.handleRevokeCredential).Methods("DELETE")
+
+	// User management routes, for owners
+	router.HandleFunc("/auth/users", s.handleListUsers).Methods("GET")
+	router.HandleFunc("/auth/users/{id}/role", s.handleSetUserRole).Methods("PUT")
+	router.HandleFunc("/auth/users/{id}/credentials", s.handleListCredentials).Methods("GET")
+	router.HandleFunc("/auth/users/{id}/credentials/{credential}", s.handleRevokeCredential).Methods("DELETE")
+	router.HandleFunc("/auth/users/{id}", s.handleDeleteUser).Methods("DELETE")
+
 	// Status routes
 	router.HandleFunc("/status", s.handleStatus).Methods("GET")
 	router.HandleFunc("/health", s.handleHealth).Methods("GET")
 
+	// Activity dashboard
+	dashboard.New(s.dashboard, s.authenticator, s.logger).Register(router)
+
 	// Create server
 	s.server = &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", s.config.WebHost, s.config.WebPort),
-		Handler: router,
+		Addr:      fmt.Sprintf("%s:%d", s.config.WebHost, s.config.WebPort),
+		Handler:   router,
+		TLSConfig: s.tlsConfig,
 	}
 
 	s.logger.WithFields(logrus.Fields{
 		"host": s.config.WebHost,
 		"port": s.config.WebPort,
+		"tls":  s.tlsConfig != nil,
 	}).Info("Starting web server")
 
 	// Start server in goroutine
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.tlsConfig != nil {
+			err = s.server.ListenAndServeTLS("", "")
+		} else {
+			err = s.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.logger.WithError(err).Error("Web server error")
 		}
 	}()
@@ -105,7 +153,7 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
         .btn-danger { background-color: #dc3545; color: white; }
         .form-group { margin: 15px 0; }
         .form-group label { display: block; margin-bottom: 5px; font-weight: bold; }
-        .form-group input { width: 100%; padding: 10px; border: 1px solid #ddd; border-radius: 5px; }
+        .form-group input, .form-group select { width: 100%; padding: 10px; border: 1px solid #ddd; border-radius: 5px; }
         .info { margin: 20px 0; padding: 15px; background-color: #f8f9fa; border-radius: 5px; }
         .footer { margin-top: 30px; text-align: center; color: #666; }
     </style>
@@ -135,6 +183,15 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
                     <label for="community-id">Community ID:</label>
                     <input type="text" id="community-id" placeholder="Enter your community ID">
                 </div>
+
+                <div class="form-group">
+                    <label for="role">Role (when an owner registers another user):</label>
+                    <select id="role">
+                        <option value="viewer">Viewer - watch status only</option>
+                        <option value="operator">Operator - control OBS and run commands</option>
+                        <option value="owner">Owner - manage the bridge and its users</option>
+                    </select>
+                </div>
                 
                 <button class="btn btn-primary" onclick="register()">Register New Device</button>
                 <button class="btn btn-success" onclick="login()">Login</button>
@@ -143,6 +200,8 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
             <div id="authenticated-section" style="display: none;">
                 <h3>Bridge Connected</h3>
                 <p>Your bridge is successfully connected to WaddleBot.</p>
+                <p id="caller-info"></p>
+                <p><a href="/dashboard">View activity</a></p>
                 <button class="btn btn-warning" onclick="logout()">Logout</button>
             </div>
         </div>
@@ -165,11 +224,61 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
 
     <script>
         // Check authentication status on load
-        window.onload = checkAuthStatus;
+        window.onload = async function() {
+            await refreshSession();
+            checkAuthStatus();
+        };
+
+        // authHeaders sends the token of the signed in user
+        function authHeaders() {
+            const headers = { 'Content-Type': 'application/json' };
+            const token = localStorage.getItem('waddlebot-token');
+            if (token) {
+                headers['Authorization'] = 'Bearer ' + token;
+            }
+            return headers;
+        }
+
+        // saveSession keeps the tokens of a sign in and renews the access
+        // token a minute before it expires
+        function saveSession(session) {
+            localStorage.setItem('waddlebot-token', session.token);
+            localStorage.setItem('waddlebot-refresh-token', session.refresh_token);
+            clearTimeout(window.refreshTimer);
+            window.refreshTimer = setTimeout(refreshSession, Math.max(session.expires_in - 60, 10) * 1000);
+        }
+
+        function clearSession() {
+            clearTimeout(window.refreshTimer);
+            localStorage.removeItem('waddlebot-token');
+            localStorage.removeItem('waddlebot-refresh-token');
+        }
+
+        async function refreshSession() {
+            const refreshToken = localStorage.getItem('waddlebot-refresh-token');
+            if (!refreshToken) {
+                return;
+            }
+            try {
+                const response = await fetch('/auth/refresh', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ refresh_token: refreshToken })
+                });
+                if (response.ok) {
+                    saveSession(await response.json());
+                } else if (response.status === 401) {
+                    clearSession();
+                    checkAuthStatus();
+                }
+            } catch (error) {
+                console.error('Error refreshing session:', error);
+            }
+        }
 
         async function checkAuthStatus() {
             try {
-                const response = await fetch('/status');
+                const response = await fetch('/status', { headers: authHeaders() });
                 const data = await response.json();
                 
                 if (data.authenticated) {
@@ -177,6 +286,9 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
                     document.getElementById('status').innerHTML = '<strong>Status:</strong> Connected and authenticated';
                     document.getElementById('auth-section').style.display = 'none';
                     document.getElementById('authenticated-section').style.display = 'block';
+                    document.getElementById('caller-info').textContent = data.caller
+                        ? 'Signed in as ' + data.caller.user_id + ' (' + data.caller.role + ')'
+                        : '';
                 } else {
                     document.getElementById('status').className = 'status disconnected';
                     document.getElementById('status').innerHTML = '<strong>Status:</strong> Not authenticated';
@@ -202,8 +314,12 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
             try {
                 const response = await fetch('/auth/register/start', {
                     method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify({ user_id: userId, community_id: communityId })
+                    headers: authHeaders(),
+                    body: JSON.stringify({
+                        user_id: userId,
+                        community_id: communityId,
+                        role: document.getElementById('role').value
+                    })
                 });
                 
                 const data = await response.json();
@@ -223,6 +339,11 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
                     });
                     
                     if (completeResponse.ok) {
+                        // An owner registering someone else stays signed in
+                        const session = await completeResponse.json();
+                        if (!localStorage.getItem('waddlebot-token')) {
+                            saveSession(session);
+                        }
                         alert('Registration successful!');
                         checkAuthStatus();
                     } else {
@@ -270,6 +391,7 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
                     });
                     
                     if (completeResponse.ok) {
+                        saveSession(await completeResponse.json());
                         alert('Login successful!');
                         checkAuthStatus();
                     } else {
@@ -288,10 +410,12 @@ func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
         async function logout() {
             try {
                 const response = await fetch('/auth/logout', {
-                    method: 'POST'
+                    method: 'POST',
+                    headers: authHeaders()
                 });
                 
                 if (response.ok) {
+                    clearSession();
                     alert('Logged out successfully');
                     checkAuthStatus();
                 } else {
@@ -334,6 +458,7 @@ func (s *WebServer) handleRegisterStart(w http.ResponseWriter, r *http.Request)
 	var req struct {
 		UserID      string `json:"user_id"`
 		CommunityID string `json:"community_id"`
+		Role        string `json:"role"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -341,7 +466,34 @@ func (s *WebServer) handleRegisterStart(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	creation, err := s.authenticator.StartRegistration(req.UserID, req.CommunityID)
+	// The first user registers freely and owns the bridge. After that,
+	// users add devices to their own account and owners add new users.
+	var creation interface{}
+	var err error
+	if !s.authenticator.HasUsers() {
+		creation, err = s.authenticator.StartRegistration(req.UserID, req.CommunityID)
+	} else {
+		caller, ok := s.bearerSession(r)
+		switch {
+		case !ok:
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		case caller.UserID == req.UserID:
+			creation, err = s.authenticator.StartCredentialRegistration(req.UserID)
+		case auth.Role(caller.Role) != auth.RoleOwner:
+			http.Error(w, "Only owners can register users", http.StatusForbidden)
+			return
+		default:
+			var role auth.Role
+			if req.Role != "" {
+				if role, err = auth.ParseRole(req.Role); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			creation, err = s.authenticator.StartRegistrationWithRole(req.UserID, req.CommunityID, role)
+		}
+	}
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to start registration")
 		http.Error(w, fmt.Sprintf("Registration failed: %v", err), http.StatusInternalServerError)
@@ -379,11 +531,7 @@ func (s *WebServer) handleRegisterComplete(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":    true,
-		"session_id": session.ID,
-	})
+	s.sendSession(w, session)
 }
 
 // handleLoginStart handles the start of WebAuthn login
@@ -435,16 +583,120 @@ func (s *WebServer) handleLoginComplete(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	s.sendSession(w, session)
+}
+
+// handleRefresh exchanges a refresh token for a new access token and
+// refresh token, extending the session
+func (s *WebServer) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	tokens, session, err := s.authenticator.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		s.logger.WithError(err).Error("Failed to refresh session")
+		http.Error(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+
+	s.sendTokens(w, session, tokens)
+}
+
+// handleDeviceLogin signs in with the device token kept in the OS keychain
+func (s *WebServer) handleDeviceLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	session, err := s.authenticator.DeviceLogin(req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrDeviceAuthDisabled):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, auth.ErrInvalidToken):
+			http.Error(w, "Invalid device token", http.StatusUnauthorized)
+		default:
+			s.logger.WithError(err).Error("Failed to sign in with device token")
+			http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.sendSession(w, session)
+}
+
+// handleDeviceReset replaces the device token, signing out everyone who
+// signed in with the old one
+func (s *WebServer) handleDeviceReset(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireOwner(w, r); !ok {
+		return
+	}
+
+	if err := s.authenticator.ResetDeviceToken(); err != nil {
+		if errors.Is(err, auth.ErrDeviceAuthDisabled) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		s.logger.WithError(err).Error("Failed to reset device token")
+		http.Error(w, "Failed to reset device token", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":    true,
-		"session_id": session.ID,
+		"success": true,
 	})
 }
 
-// handleLogout handles user logout
+// sendSession answers a completed registration or login with the session
+// and its tokens. The page sends the access token as a bearer token and
+// renews it with the refresh token before it expires.
+func (s *WebServer) sendSession(w http.ResponseWriter, session *models.AuthSession) {
+	tokens, err := s.authenticator.IssueTokens(session)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to generate session tokens")
+		http.Error(w, "Failed to generate session tokens", http.StatusInternalServerError)
+		return
+	}
+
+	s.sendTokens(w, session, tokens)
+}
+
+func (s *WebServer) sendTokens(w http.ResponseWriter, session *models.AuthSession, tokens auth.TokenPair) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":         true,
+		"session_id":      session.ID,
+		"role":            session.Role,
+		"token":           tokens.AccessToken,
+		"refresh_token":   tokens.RefreshToken,
+		"expires_in":      tokens.ExpiresIn,
+		"session_expires": tokens.SessionExpires,
+	})
+}
+
+// handleLogout ends the caller's session, or the bridge's current session
+// when no token is sent
 func (s *WebServer) handleLogout(w http.ResponseWriter, r *http.Request) {
-	session := s.authenticator.GetCurrentSession()
+	session, ok := s.bearerSession(r)
+	if !ok {
+		session = s.authenticator.GetCurrentSession()
+	}
 	if session != nil {
 		s.authenticator.RevokeSession(session.ID)
 	}
@@ -463,6 +715,7 @@ func (s *WebServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	status := map[string]interface{}{
 		"authenticated": authenticated,
 		"bridge_status": "running",
+		"device_auth":   s.authenticator.DeviceAuthEnabled(),
 	}
 
 	if authenticated {
@@ -471,6 +724,14 @@ func (s *WebServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 		status["session_expires"] = session.ExpiresAt
 	}
 
+	// A caller with a token also learns who they are signed in as
+	if caller, ok := s.bearerSession(r); ok {
+		status["caller"] = map[string]interface{}{
+			"user_id": caller.UserID,
+			"role":    caller.Role,
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
@@ -485,4 +746,236 @@ func (s *WebServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(health)
-}
\ No newline at end of file
+}
+
+// handleListUsers returns the registered users
+func (s *WebServer) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireOwner(w, r); !ok {
+		return
+	}
+
+	users, err := s.authenticator.ListUsers()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list users")
+		http.Error(w, "Failed to list users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"users": users,
+		"roles": auth.Roles,
+	})
+}
+
+// handleSetUserRole changes the role of a user
+func (s *WebServer) handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireOwner(w, r); !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := mux.Vars(r)["id"]
+	if err := s.authenticator.SetUserRole(userID, role); err != nil {
+		s.sendUserError(w, err)
+		return
+	}
+
+	user, _ := s.authenticator.GetUser(userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// handleDeleteUser removes a user and ends its sessions
+func (s *WebServer) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireOwner(w, r); !ok {
+		return
+	}
+
+	if err := s.authenticator.DeleteUser(mux.Vars(r)["id"]); err != nil {
+		s.sendUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// handleListCredentials returns the passkeys of the caller, or of any user
+// for owners
+func (s *WebServer) handleListCredentials(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.credentialOwner(w, r)
+	if !ok {
+		return
+	}
+
+	credentials, err := s.authenticator.ListCredentials(userID)
+	if err != nil {
+		s.sendUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":     userID,
+		"credentials": credentials,
+	})
+}
+
+// handleCredentialStart starts enrolling another authenticator for the
+// caller
+func (s *WebServer) handleCredentialStart(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	creation, err := s.authenticator.StartCredentialRegistration(session.UserID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to start credential registration")
+		http.Error(w, fmt.Sprintf("Registration failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"credentialCreationOptions": creation,
+	})
+}
+
+// handleCredentialComplete completes enrolling another authenticator for
+// the caller
+func (s *WebServer) handleCredentialComplete(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Credential interface{} `json:"credential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	credentialData, err := json.Marshal(req.Credential)
+	if err != nil {
+		http.Error(w, "Invalid credential format", http.StatusBadRequest)
+		return
+	}
+
+	credential, err := s.authenticator.CompleteCredentialRegistration(session.UserID, credentialData)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to complete credential registration")
+		http.Error(w, fmt.Sprintf("Registration failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credential)
+}
+
+// handleRevokeCredential removes a passkey of the caller, or of any user
+// for owners
+func (s *WebServer) handleRevokeCredential(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.credentialOwner(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.authenticator.RevokeCredential(userID, mux.Vars(r)["credential"]); err != nil {
+		s.sendUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// credentialOwner returns whose credentials a request is about: the user
+// in the path, which needs an owner unless it is the caller, or else the
+// caller
+func (s *WebServer) credentialOwner(w http.ResponseWriter, r *http.Request) (string, bool) {
+	session, ok := s.requireSession(w, r)
+	if !ok {
+		return "", false
+	}
+
+	userID, found := mux.Vars(r)["id"]
+	if !found || userID == session.UserID {
+		return session.UserID, true
+	}
+	if auth.Role(session.Role) != auth.RoleOwner {
+		http.Error(w, "Owner role required", http.StatusForbidden)
+		return "", false
+	}
+	return userID, true
+}
+
+// sendUserError reports a failed user change
+func (s *WebServer) sendUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound), errors.Is(err, auth.ErrCredentialNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, auth.ErrLastOwner), errors.Is(err, auth.ErrInvalidRole), errors.Is(err, auth.ErrLastCredential):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		s.logger.WithError(err).Error("Failed to change user")
+		http.Error(w, "Failed to change user", http.StatusInternalServerError)
+	}
+}
+
+// bearerSession returns the session of the request's bearer token
+func (s *WebServer) bearerSession(r *http.Request) (*models.AuthSession, bool) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return nil, false
+	}
+	session, err := s.authenticator.ValidateJWT(token)
+	if err != nil {
+		return nil, false
+	}
+	return session, true
+}
+
+// requireSession returns the caller's session and answers the request if
+// there is none
+func (s *WebServer) requireSession(w http.ResponseWriter, r *http.Request) (*models.AuthSession, bool) {
+	session, ok := s.bearerSession(r)
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return nil, false
+	}
+	return session, true
+}
+
+// requireOwner returns the caller's session if they own the bridge and
+// answers the request otherwise
+func (s *WebServer) requireOwner(w http.ResponseWriter, r *http.Request) (*models.AuthSession, bool) {
+	session, ok := s.requireSession(w, r)
+	if !ok {
+		return nil, false
+	}
+	if auth.Role(session.Role) != auth.RoleOwner {
+		http.Error(w, "Owner role required", http.StatusForbidden)
+		return nil, false
+	}
+	return session, true
+}
@@ -0,0 +1,86 @@
+// Package shutdown stops the bridge in stages: it stops taking tasks and
+// lets those in flight finish, stops the components, delivers what is left
+// to send, tells the API and closes storage. Every stage has its own
+// timeout, so a stuck component cannot keep the bridge from exiting.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAbandoned is returned for stages that did not return in time
+var ErrAbandoned = errors.New("stage did not return in time")
+
+// stage is a named step of the shutdown
+type stage struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context) error
+}
+
+// Coordinator runs the stages of the shutdown in order
+type Coordinator struct {
+	logger *logrus.Logger
+	stages []stage
+}
+
+// New creates a coordinator without stages
+func New(logger *logrus.Logger) *Coordinator {
+	return &Coordinator{logger: logger}
+}
+
+// Add appends a stage. run is given a context that is done after timeout;
+// a stage that has not returned shortly after is abandoned.
+func (c *Coordinator) Add(name string, timeout time.Duration, run func(ctx context.Context) error) {
+	c.stages = append(c.stages, stage{name: name, timeout: timeout, run: run})
+}
+
+// Run runs every stage in order, carrying on after failed ones, and
+// reports whether all of them succeeded
+func (c *Coordinator) Run() bool {
+	clean := true
+	for _, s := range c.stages {
+		started := time.Now()
+		if err := c.runStage(s); err != nil {
+			clean = false
+			c.logger.WithError(err).WithField("stage", s.name).Warn("Shutdown stage failed")
+			continue
+		}
+		c.logger.WithFields(logrus.Fields{
+			"stage":    s.name,
+			"duration": time.Since(started),
+		}).Debug("Shutdown stage finished")
+	}
+	return clean
+}
+
+// abandonGrace is how long a stage may run past its timeout to notice
+// that its context is done
+const abandonGrace = time.Second
+
+func (c *Coordinator) runStage(s stage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("stage panicked: %v", r)
+			}
+		}()
+		done <- s.run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(s.timeout + abandonGrace):
+		return ErrAbandoned
+	}
+}
@@ -0,0 +1,80 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCoordinator(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	coordinator := New(logger)
+
+	var ran []string
+	coordinator.Add("first", time.Second, func(ctx context.Context) error {
+		ran = append(ran, "first")
+		return nil
+	})
+	coordinator.Add("failing", time.Second, func(ctx context.Context) error {
+		ran = append(ran, "failing")
+		return errors.New("failed")
+	})
+	coordinator.Add("last", time.Second, func(ctx context.Context) error {
+		ran = append(ran, "last")
+		return nil
+	})
+
+	if coordinator.Run() {
+		t.Error("Expected an unclean shutdown after a failed stage")
+	}
+	if want := []string{"first", "failing", "last"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("Stages ran %v, want %v", ran, want)
+	}
+}
+
+func TestCoordinator_Timeouts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	coordinator := New(logger)
+
+	// A stage that respects its context ends at the timeout
+	coordinator.Add("bounded", 50*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	// One that does not is abandoned
+	stuck := make(chan struct{})
+	defer close(stuck)
+	coordinator.Add("stuck", 10*time.Millisecond, func(ctx context.Context) error {
+		<-stuck
+		return nil
+	})
+	coordinator.Add("panicking", time.Second, func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	started := time.Now()
+	if coordinator.Run() {
+		t.Error("Expected an unclean shutdown")
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("Expected stuck stages to be abandoned, took %v", elapsed)
+	}
+}
+
+func TestCoordinator_Clean(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	coordinator := New(logger)
+	coordinator.Add("quick", time.Second, func(ctx context.Context) error { return nil })
+
+	if !coordinator.Run() {
+		t.Error("Expected a clean shutdown")
+	}
+}
@@ -0,0 +1,296 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+)
+
+// HeaderSignature is the request header carrying the signature
+const HeaderSignature = "X-WaddleBot-Signature"
+
+// Supported signing algorithms
+const (
+	AlgorithmEd25519 = "ed25519"
+	AlgorithmHMAC    = "hmac-sha256"
+)
+
+// signatureVersion prefixes the signed string so the format can evolve
+const signatureVersion = "v1"
+
+var (
+	// ErrInvalidSignature is returned when a signature does not verify
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrSignatureExpired is returned when a signature's timestamp is outside
+	// the allowed clock skew
+	ErrSignatureExpired = errors.New("signature timestamp outside allowed window")
+)
+
+// Signer signs API requests so the server can verify they came from the
+// registered bridge. Each signature covers the method, path, a timestamp, a
+// random nonce and the SHA-256 digest of the body, so a captured token or
+// request cannot be replayed against a different payload or later on.
+type Signer struct {
+	algorithm  string
+	keyID      string
+	secret     []byte
+	privateKey ed25519.PrivateKey
+	now        func() time.Time
+}
+
+// New creates a signer from configuration. For ed25519 the private key is
+// read from cfg.KeyFile, or <dataDir>/signing.key, and generated if missing.
+func New(cfg config.SigningConfig, dataDir string) (*Signer, error) {
+	s := &Signer{
+		algorithm: strings.ToLower(cfg.Algorithm),
+		keyID:     cfg.KeyID,
+		now:       time.Now,
+	}
+
+	switch s.algorithm {
+	case AlgorithmHMAC:
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("signing secret is required for %s", AlgorithmHMAC)
+		}
+		s.secret = []byte(cfg.Secret)
+
+	case AlgorithmEd25519, "":
+		s.algorithm = AlgorithmEd25519
+		keyFile := cfg.KeyFile
+		if keyFile == "" {
+			keyFile = filepath.Join(dataDir, "signing.key")
+		}
+		key, err := loadOrCreateKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.privateKey = key
+		if s.keyID == "" {
+			sum := sha256.Sum256(key.Public().(ed25519.PublicKey))
+			s.keyID = hex.EncodeToString(sum[:8])
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", cfg.Algorithm)
+	}
+
+	return s, nil
+}
+
+// Algorithm returns the signing algorithm
+func (s *Signer) Algorithm() string {
+	return s.algorithm
+}
+
+// KeyID returns the identifier sent with each signature
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the base64 ed25519 public key the server verifies
+// signatures with, or an empty string for HMAC
+func (s *Signer) PublicKey() string {
+	if s.privateKey == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(s.privateKey.Public().(ed25519.PublicKey))
+}
+
+// Sign returns the signature header value for a request
+func (s *Signer) Sign(method, path string, body []byte) (string, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	nonceStr := base64.RawURLEncoding.EncodeToString(nonce)
+	digest := bodyDigest(body)
+
+	payload := signedString(timestamp, nonceStr, method, path, digest)
+
+	var sig []byte
+	switch s.algorithm {
+	case AlgorithmHMAC:
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(payload)
+		sig = mac.Sum(nil)
+	default:
+		sig = ed25519.Sign(s.privateKey, payload)
+	}
+
+	fields := []string{
+		signatureVersion,
+		"alg=" + s.algorithm,
+		"t=" + timestamp,
+		"n=" + nonceStr,
+		"d=" + digest,
+	}
+	if s.keyID != "" {
+		fields = append(fields, "kid="+s.keyID)
+	}
+	fields = append(fields, "sig="+base64.StdEncoding.EncodeToString(sig))
+
+	return strings.Join(fields, ","), nil
+}
+
+// SignRequest sets the signature header on req. body must be the exact
+// request body; the path is taken from the request URL.
+func (s *Signer) SignRequest(req *http.Request, body []byte) error {
+	header, err := s.Sign(req.Method, req.URL.Path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderSignature, header)
+	return nil
+}
+
+// Verify checks a signature header against a request. key is the HMAC
+// secret or the ed25519 public key, depending on the algorithm in the
+// header. Nonce tracking, for rejecting replays inside the window, is left
+// to the caller.
+func Verify(header, method, path string, body []byte, key []byte, maxSkew time.Duration, now time.Time) error {
+	fields, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+
+	digest := bodyDigest(body)
+	if !hmac.Equal([]byte(fields["d"]), []byte(digest)) {
+		return fmt.Errorf("%w: body digest mismatch", ErrInvalidSignature)
+	}
+
+	ts, err := strconv.ParseInt(fields["t"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew < -maxSkew || skew > maxSkew {
+		return ErrSignatureExpired
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(fields["sig"])
+	if err != nil {
+		return fmt.Errorf("%w: bad encoding", ErrInvalidSignature)
+	}
+
+	payload := signedString(fields["t"], fields["n"], method, path, digest)
+
+	switch fields["alg"] {
+	case AlgorithmHMAC:
+		mac := hmac.New(sha256.New, key)
+		mac.Write(payload)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+	case AlgorithmEd25519:
+		if len(key) != ed25519.PublicKeySize || !ed25519.Verify(ed25519.PublicKey(key), payload, sig) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, fields["alg"])
+	}
+
+	return nil
+}
+
+// parseHeader splits a signature header into its fields
+func parseHeader(header string) (map[string]string, error) {
+	parts := strings.Split(header, ",")
+	if len(parts) == 0 || parts[0] != signatureVersion {
+		return nil, fmt.Errorf("%w: unsupported version", ErrInvalidSignature)
+	}
+
+	fields := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed field %q", ErrInvalidSignature, part)
+		}
+		fields[name] = value
+	}
+
+	for _, name := range []string{"alg", "t", "n", "d", "sig"} {
+		if fields[name] == "" {
+			return nil, fmt.Errorf("%w: missing %s", ErrInvalidSignature, name)
+		}
+	}
+
+	return fields, nil
+}
+
+// signedString builds the canonical string covered by the signature
+func signedString(timestamp, nonce, method, path, digest string) []byte {
+	return []byte(strings.Join([]string{
+		signatureVersion,
+		timestamp,
+		nonce,
+		strings.ToUpper(method),
+		path,
+		digest,
+	}, "\n"))
+}
+
+// bodyDigest returns the hex SHA-256 digest of a request body
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadOrCreateKey reads a PKCS#8 PEM ed25519 private key, generating and
+// saving a new one if the file does not exist
+func loadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM data in signing key file %s", path)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key: %w", err)
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("signing key in %s is not an ed25519 key", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create signing key directory: %w", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save signing key: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,127 @@
+package signing
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func TestSigner_Ed25519(t *testing.T) {
+	dir := t.TempDir()
+
+	signer, err := New(config.SigningConfig{Algorithm: AlgorithmEd25519}, dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "signing.key")); err != nil {
+		t.Fatalf("expected key file to be created: %v", err)
+	}
+
+	body := []byte(`{"status":"active"}`)
+	header, err := signer.Sign("POST", "/api/bridge/heartbeat", body)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if !strings.Contains(header, "kid="+signer.KeyID()) {
+		t.Errorf("expected key id in header, got %s", header)
+	}
+
+	publicKey, _ := base64.StdEncoding.DecodeString(signer.PublicKey())
+	if err := Verify(header, "POST", "/api/bridge/heartbeat", body, publicKey, time.Minute, time.Now()); err != nil {
+		t.Errorf("expected signature to verify: %v", err)
+	}
+
+	// A second signer on the same data dir reuses the key
+	again, err := New(config.SigningConfig{Algorithm: AlgorithmEd25519}, dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if again.PublicKey() != signer.PublicKey() {
+		t.Error("expected persisted key to be reused")
+	}
+}
+
+func TestSigner_HMAC(t *testing.T) {
+	if _, err := New(config.SigningConfig{Algorithm: AlgorithmHMAC}, t.TempDir()); err == nil {
+		t.Error("expected error without secret")
+	}
+
+	signer, err := New(config.SigningConfig{Algorithm: AlgorithmHMAC, Secret: "shared", KeyID: "k1"}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if signer.PublicKey() != "" {
+		t.Error("expected no public key for HMAC")
+	}
+
+	req, _ := http.NewRequest("POST", "https://api.test/api/bridge/response", nil)
+	body := []byte(`{"id":"task-1"}`)
+	if err := signer.SignRequest(req, body); err != nil {
+		t.Fatalf("SignRequest failed: %v", err)
+	}
+
+	header := req.Header.Get(HeaderSignature)
+	if err := Verify(header, "POST", "/api/bridge/response", body, []byte("shared"), time.Minute, time.Now()); err != nil {
+		t.Errorf("expected signature to verify: %v", err)
+	}
+	if err := Verify(header, "POST", "/api/bridge/response", body, []byte("other"), time.Minute, time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature with wrong secret, got %v", err)
+	}
+}
+
+func TestVerify_Tampering(t *testing.T) {
+	signer, err := New(config.SigningConfig{Algorithm: AlgorithmHMAC, Secret: "shared"}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	signedAt := time.Unix(1700000000, 0)
+	signer.now = func() time.Time { return signedAt }
+
+	body := []byte(`{"success":true}`)
+	header, _ := signer.Sign("POST", "/api/bridge/response", body)
+	key := []byte("shared")
+
+	tests := []struct {
+		name   string
+		header string
+		method string
+		path   string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"valid", header, "POST", "/api/bridge/response", body, signedAt, nil},
+		{"changed body", header, "POST", "/api/bridge/response", []byte(`{"success":false}`), signedAt, ErrInvalidSignature},
+		{"changed path", header, "POST", "/api/bridge/register", body, signedAt, ErrInvalidSignature},
+		{"changed method", header, "PUT", "/api/bridge/response", body, signedAt, ErrInvalidSignature},
+		{"expired", header, "POST", "/api/bridge/response", body, signedAt.Add(10 * time.Minute), ErrSignatureExpired},
+		{"changed nonce", strings.Replace(header, ",n=", ",n=x", 1), "POST", "/api/bridge/response", body, signedAt, ErrInvalidSignature},
+		{"malformed", "v1,garbage", "POST", "/api/bridge/response", body, signedAt, ErrInvalidSignature},
+		{"wrong version", "v0" + header[2:], "POST", "/api/bridge/response", body, signedAt, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.header, tt.method, tt.path, tt.body, key, 5*time.Minute, tt.now)
+			if tt.want == nil && err != nil {
+				t.Errorf("expected success, got %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNew_UnsupportedAlgorithm(t *testing.T) {
+	if _, err := New(config.SigningConfig{Algorithm: "rsa"}, t.TempDir()); err == nil {
+		t.Error("expected error for unsupported algorithm")
+	}
+}
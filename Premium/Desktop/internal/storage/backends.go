@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Storage backends selected with storage-backend
+const (
+	BackendBolt   = "bolt"
+	BackendSQLite = "sqlite"
+)
+
+// Factory opens a storage backend keeping its files in dataDir
+type Factory func(dataDir string) (Storage, error)
+
+var backends = map[string]Factory{
+	BackendBolt: func(dataDir string) (Storage, error) {
+		return NewBoltStorage(dataDir)
+	},
+	BackendSQLite: func(dataDir string) (Storage, error) {
+		return NewSQLiteStorage(dataDir)
+	},
+}
+
+// Open opens the named storage backend
+func Open(backend, dataDir string) (Storage, error) {
+	if backend == "" {
+		backend = BackendBolt
+	}
+	factory, exists := backends[backend]
+	if !exists {
+		return nil, fmt.Errorf("unknown storage backend %q (available: %v)", backend, Backends())
+	}
+	return factory(dataDir)
+}
+
+// Backends lists the available storage backends
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BucketLister is implemented by storage that can list its buckets
+type BucketLister interface {
+	Buckets() ([]string, error)
+}
+
+// Expiring is implemented by storage that can tell when a key stored with
+// a TTL expires
+type Expiring interface {
+	ExpiresAt(bucketName, key string) (time.Time, bool)
+}
+
+// Migrate copies every bucket of src into dst, replacing keys dst already
+// holds, and returns the number of keys copied. Keys keep their TTL.
+func Migrate(dst, src Storage) (int, error) {
+	lister, ok := src.(BucketLister)
+	if !ok {
+		return 0, fmt.Errorf("source storage cannot list its buckets")
+	}
+	buckets, err := lister.Buckets()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list buckets: %w", err)
+	}
+
+	copied := 0
+	for _, bucket := range buckets {
+		if err := dst.EnsureBucket(bucket); err != nil {
+			return copied, err
+		}
+		data, err := src.GetAllFromBucket(bucket)
+		if err != nil {
+			return copied, fmt.Errorf("failed to read bucket %s: %w", bucket, err)
+		}
+		for key, value := range data {
+			var ttl time.Duration
+			if expiring, ok := src.(Expiring); ok {
+				if expiresAt, ok := expiring.ExpiresAt(bucket, key); ok {
+					if ttl = time.Until(expiresAt); ttl <= 0 {
+						continue
+					}
+				}
+			}
+			if err := dst.SetWithBucketTTL(bucket, key, value, ttl); err != nil {
+				return copied, fmt.Errorf("failed to copy %s/%s: %w", bucket, key, err)
+			}
+			copied++
+		}
+	}
+	return copied, nil
+}
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"go.etcd.io/bbolt"
@@ -16,11 +19,19 @@ const (
 	sessionsBucket = "sessions"
 	modulesBucket  = "modules"
 	configBucket   = "config"
+	outboxBucket   = "outbox"
+	auditBucket    = "policy_audit"
+	
+	// expiryBucket holds when keys stored with a TTL expire
+	expiryBucket = "expiry"
 )
 
 // BoltStorage implements the Storage interface using BoltDB
 type BoltStorage struct {
 	db *bbolt.DB
+
+	// mu is held for writing while Compact replaces db
+	mu sync.RWMutex
 }
 
 // NewBoltStorage creates a new BoltDB storage instance
@@ -49,8 +60,8 @@ func NewBoltStorage(dataDir string) (*BoltStorage, error) {
 
 // initBuckets creates the required buckets if they don't exist
 func (s *BoltStorage) initBuckets() error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
-		buckets := []string{defaultBucket, sessionsBucket, modulesBucket, configBucket}
+	return s.update(func(tx *bbolt.Tx) error {
+		buckets := []string{defaultBucket, sessionsBucket, modulesBucket, configBucket, outboxBucket, auditBucket, expiryBucket}
 		
 		for _, bucket := range buckets {
 			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
@@ -64,108 +75,72 @@ func (s *BoltStorage) initBuckets() error {
 
 // Set stores a key-value pair
 func (s *BoltStorage) Set(key string, value []byte) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte(defaultBucket))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", defaultBucket)
-		}
-		
-		return bucket.Put([]byte(key), value)
-	})
+	return s.SetWithBucket(defaultBucket, key, value)
+}
+
+// SetWithTTL stores a key-value pair that expires after ttl
+func (s *BoltStorage) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return s.SetWithBucketTTL(defaultBucket, key, value, ttl)
 }
 
 // Get retrieves a value by key
 func (s *BoltStorage) Get(key string) ([]byte, error) {
-	var value []byte
-	
-	err := s.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte(defaultBucket))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", defaultBucket)
-		}
-		
-		data := bucket.Get([]byte(key))
-		if data == nil {
-			return fmt.Errorf("key %s not found", key)
-		}
-		
-		// Make a copy of the data since it's only valid during the transaction
-		value = make([]byte, len(data))
-		copy(value, data)
-		
-		return nil
-	})
-	
-	return value, err
+	return s.GetWithBucket(defaultBucket, key)
 }
 
 // Delete removes a key
 func (s *BoltStorage) Delete(key string) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte(defaultBucket))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", defaultBucket)
-		}
-		
-		return bucket.Delete([]byte(key))
-	})
+	return s.DeleteWithBucket(defaultBucket, key)
 }
 
 // Exists checks if a key exists
 func (s *BoltStorage) Exists(key string) bool {
-	err := s.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte(defaultBucket))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", defaultBucket)
-		}
-		
-		data := bucket.Get([]byte(key))
-		if data == nil {
-			return fmt.Errorf("key not found")
-		}
-		
-		return nil
-	})
-	
+	_, err := s.Get(key)
 	return err == nil
 }
 
 // List returns all keys with a given prefix
 func (s *BoltStorage) List(prefix string) ([]string, error) {
-	var keys []string
-	
-	err := s.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte(defaultBucket))
+	return s.ListWithBucket(defaultBucket, prefix)
+}
+
+// SetWithBucket stores a key-value pair in a specific bucket
+func (s *BoltStorage) SetWithBucket(bucketName, key string, value []byte) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", defaultBucket)
+			return fmt.Errorf("bucket %s not found", bucketName)
 		}
 		
-		cursor := bucket.Cursor()
-		prefixBytes := []byte(prefix)
-		
-		for k, _ := cursor.Seek(prefixBytes); k != nil && len(k) >= len(prefixBytes); k, _ = cursor.Next() {
-			if len(k) >= len(prefixBytes) && string(k[:len(prefixBytes)]) == prefix {
-				keys = append(keys, string(k))
-			} else {
-				break
-			}
+		if err := bucket.Put([]byte(key), value); err != nil {
+			return err
 		}
 		
-		return nil
+		// A value stored without a TTL no longer expires
+		return tx.Bucket([]byte(expiryBucket)).Delete(expiryKey(bucketName, key))
 	})
-	
-	return keys, err
 }
 
-// SetWithBucket stores a key-value pair in a specific bucket
-func (s *BoltStorage) SetWithBucket(bucketName, key string, value []byte) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
+// SetWithBucketTTL stores a key-value pair in a specific bucket that
+// expires after ttl; a ttl of zero or less never expires
+func (s *BoltStorage) SetWithBucketTTL(bucketName, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return s.SetWithBucket(bucketName, key, value)
+	}
+	
+	return s.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
 			return fmt.Errorf("bucket %s not found", bucketName)
 		}
 		
-		return bucket.Put([]byte(key), value)
+		if err := bucket.Put([]byte(key), value); err != nil {
+			return err
+		}
+		
+		expiresAt := make([]byte, 8)
+		binary.BigEndian.PutUint64(expiresAt, uint64(time.Now().Add(ttl).UnixNano()))
+		return tx.Bucket([]byte(expiryBucket)).Put(expiryKey(bucketName, key), expiresAt)
 	})
 }
 
@@ -173,14 +148,14 @@ func (s *BoltStorage) SetWithBucket(bucketName, key string, value []byte) error
 func (s *BoltStorage) GetWithBucket(bucketName, key string) ([]byte, error) {
 	var value []byte
 	
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
 			return fmt.Errorf("bucket %s not found", bucketName)
 		}
 		
 		data := bucket.Get([]byte(key))
-		if data == nil {
+		if data == nil || expired(tx, bucketName, []byte(key), time.Now()) {
 			return fmt.Errorf("key %s not found", key)
 		}
 		
@@ -195,13 +170,17 @@ func (s *BoltStorage) GetWithBucket(bucketName, key string) ([]byte, error) {
 
 // DeleteWithBucket removes a key from a specific bucket
 func (s *BoltStorage) DeleteWithBucket(bucketName, key string) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
 			return fmt.Errorf("bucket %s not found", bucketName)
 		}
 		
-		return bucket.Delete([]byte(key))
+		if err := bucket.Delete([]byte(key)); err != nil {
+			return err
+		}
+		
+		return tx.Bucket([]byte(expiryBucket)).Delete(expiryKey(bucketName, key))
 	})
 }
 
@@ -209,7 +188,7 @@ func (s *BoltStorage) DeleteWithBucket(bucketName, key string) error {
 func (s *BoltStorage) ListWithBucket(bucketName, prefix string) ([]string, error) {
 	var keys []string
 	
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
 			return fmt.Errorf("bucket %s not found", bucketName)
@@ -217,10 +196,13 @@ func (s *BoltStorage) ListWithBucket(bucketName, prefix string) ([]string, error
 		
 		cursor := bucket.Cursor()
 		prefixBytes := []byte(prefix)
+		now := time.Now()
 		
 		for k, _ := cursor.Seek(prefixBytes); k != nil && len(k) >= len(prefixBytes); k, _ = cursor.Next() {
 			if len(k) >= len(prefixBytes) && string(k[:len(prefixBytes)]) == prefix {
-				keys = append(keys, string(k))
+				if !expired(tx, bucketName, k, now) {
+					keys = append(keys, string(k))
+				}
 			} else {
 				break
 			}
@@ -236,13 +218,18 @@ func (s *BoltStorage) ListWithBucket(bucketName, prefix string) ([]string, error
 func (s *BoltStorage) GetAllFromBucket(bucketName string) (map[string][]byte, error) {
 	data := make(map[string][]byte)
 	
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
 			return fmt.Errorf("bucket %s not found", bucketName)
 		}
 		
+		now := time.Now()
 		return bucket.ForEach(func(k, v []byte) error {
+			if expired(tx, bucketName, k, now) {
+				return nil
+			}
+			
 			// Make copies of the key and value
 			key := make([]byte, len(k))
 			value := make([]byte, len(v))
@@ -259,7 +246,7 @@ func (s *BoltStorage) GetAllFromBucket(bucketName string) (map[string][]byte, er
 
 // ClearBucket removes all data from a specific bucket
 func (s *BoltStorage) ClearBucket(bucketName string) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *bbolt.Tx) error {
 		// Delete the bucket
 		if err := tx.DeleteBucket([]byte(bucketName)); err != nil {
 			return fmt.Errorf("failed to delete bucket %s: %w", bucketName, err)
@@ -274,21 +261,126 @@ func (s *BoltStorage) ClearBucket(bucketName string) error {
 	})
 }
 
+// EnsureBucket creates a bucket if it does not exist yet
+func (s *BoltStorage) EnsureBucket(bucketName string) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketName)); err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", bucketName, err)
+		}
+		return nil
+	})
+}
+
+// Buckets returns the names of all buckets
+func (s *BoltStorage) Buckets() ([]string, error) {
+	var buckets []string
+	err := s.view(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			if string(name) != expiryBucket {
+				buckets = append(buckets, string(name))
+			}
+			return nil
+		})
+	})
+	return buckets, err
+}
+
+// BucketStats returns the number of keys and bytes in each bucket
+func (s *BoltStorage) BucketStats() ([]BucketUsage, error) {
+	var stats []BucketUsage
+	err := s.view(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+			if string(name) == expiryBucket {
+				return nil
+			}
+			usage := BucketUsage{Name: string(name)}
+			err := bucket.ForEach(func(k, v []byte) error {
+				usage.Keys++
+				usage.Bytes += int64(len(k) + len(v))
+				return nil
+			})
+			stats = append(stats, usage)
+			return err
+		})
+	})
+	return stats, err
+}
+
+// ExpiresAt returns when a key stored with a TTL expires
+func (s *BoltStorage) ExpiresAt(bucketName, key string) (time.Time, bool) {
+	var expiresAt time.Time
+	s.view(func(tx *bbolt.Tx) error {
+		if data := tx.Bucket([]byte(expiryBucket)).Get(expiryKey(bucketName, key)); len(data) == 8 {
+			expiresAt = time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+		}
+		return nil
+	})
+	return expiresAt, !expiresAt.IsZero()
+}
+
+// PurgeExpired deletes the keys whose TTL has passed and returns how many
+// were deleted
+func (s *BoltStorage) PurgeExpired() (int, error) {
+	purged := 0
+	err := s.update(func(tx *bbolt.Tx) error {
+		expiry := tx.Bucket([]byte(expiryBucket))
+		now := time.Now().UnixNano()
+		
+		var due [][]byte
+		expiry.ForEach(func(k, v []byte) error {
+			if len(v) == 8 && now >= int64(binary.BigEndian.Uint64(v)) {
+				due = append(due, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		
+		for _, k := range due {
+			bucketName, key, _ := bytes.Cut(k, []byte{0})
+			if bucket := tx.Bucket(bucketName); bucket != nil && bucket.Get(key) != nil {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+				purged++
+			}
+			if err := expiry.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// expiryKey is the key of a bucket's key in the expiry bucket
+func expiryKey(bucketName, key string) []byte {
+	return []byte(bucketName + "\x00" + key)
+}
+
+// expired reports whether a key stored with a TTL has expired
+func expired(tx *bbolt.Tx, bucketName string, key []byte, now time.Time) bool {
+	data := tx.Bucket([]byte(expiryBucket)).Get(expiryKey(bucketName, string(key)))
+	return len(data) == 8 && now.UnixNano() >= int64(binary.BigEndian.Uint64(data))
+}
+
 // Close closes the database connection
 func (s *BoltStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.db.Close()
 }
 
 // Backup creates a backup of the database
 func (s *BoltStorage) Backup(backupPath string) error {
-	return s.db.View(func(tx *bbolt.Tx) error {
+	return s.view(func(tx *bbolt.Tx) error {
 		return tx.CopyFile(backupPath, 0600)
 	})
 }
 
 // Stats returns database statistics
 func (s *BoltStorage) Stats() map[string]interface{} {
+	s.mu.RLock()
 	stats := s.db.Stats()
+	s.mu.RUnlock()
 	
 	return map[string]interface{}{
 		"free_page_n":       stats.FreePageN,
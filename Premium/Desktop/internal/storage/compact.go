@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// compactTxSize is how many bytes Compact copies per transaction
+const compactTxSize = 4 << 20
+
+// openCompacted opens the database file Compact swapped in; tests replace
+// it to fail the reopen
+var openCompacted = bbolt.Open
+
+// Compactor is implemented by storage that can reclaim the space freed by
+// deleted keys
+type Compactor interface {
+	// FreeRatio returns the share of the file that compaction would reclaim
+	FreeRatio() float64
+
+	// Compact rewrites the file and returns its size before and after
+	Compact() (before, after int64, err error)
+}
+
+// view runs fn in a read-only transaction
+func (s *BoltStorage) view(fn func(*bbolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.View(fn)
+}
+
+// update runs fn in a read-write transaction
+func (s *BoltStorage) update(fn func(*bbolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.Update(fn)
+}
+
+// FreeRatio returns the share of the database file taken by free pages and
+// by space Bolt has allocated but not used yet
+func (s *BoltStorage) FreeRatio() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, err := os.Stat(s.db.Path())
+	if err != nil || info.Size() == 0 {
+		return 0
+	}
+
+	var used int64
+	s.db.View(func(tx *bbolt.Tx) error {
+		used = tx.Size()
+		return nil
+	})
+	stats := s.db.Stats()
+	used -= int64(stats.FreePageN+stats.PendingPageN) * int64(s.db.Info().PageSize)
+
+	if used <= 0 {
+		return 0
+	}
+	return 1 - float64(used)/float64(info.Size())
+}
+
+// Compact copies the database into a new file without its free pages and
+// replaces the old file with it. Other operations wait until it is done.
+func (s *BoltStorage) Compact() (before, after int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.db.Path()
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	before = info.Size()
+
+	tmpPath := path + ".compact"
+	os.Remove(tmpPath)
+	dst, err := bbolt.Open(tmpPath, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return before, 0, fmt.Errorf("failed to create compacted database: %w", err)
+	}
+	if err := bbolt.Compact(dst, s.db, compactTxSize); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return before, 0, fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return before, 0, fmt.Errorf("failed to write compacted database: %w", err)
+	}
+
+	// The original file is kept aside until the compacted one opens, so
+	// that a failed swap leaves the database as it was rather than closed
+	if err := s.db.Close(); err != nil {
+		os.Remove(tmpPath)
+		return before, 0, fmt.Errorf("failed to close database: %w", err)
+	}
+	oldPath := path + ".precompact"
+	os.Remove(oldPath)
+	if err := os.Rename(path, oldPath); err != nil {
+		os.Remove(tmpPath)
+		return before, before, s.restore(path, "", fmt.Errorf("failed to replace database: %w", err))
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return before, before, s.restore(path, oldPath, fmt.Errorf("failed to replace database: %w", err))
+	}
+
+	db, err := openCompacted(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		os.Remove(path)
+		return before, before, s.restore(path, oldPath, fmt.Errorf("failed to open compacted database: %w", err))
+	}
+	s.db = db
+	os.Remove(oldPath)
+
+	if info, err := os.Stat(path); err == nil {
+		after = info.Size()
+	}
+	return before, after, nil
+}
+
+// restore puts the original database file back at path, moving it from
+// oldPath unless that is empty, and reopens it after a failed compaction.
+// It returns cause, or cause joined with the reason the original could not
+// be reopened.
+func (s *BoltStorage) restore(path, oldPath string, cause error) error {
+	if oldPath != "" {
+		if err := os.Rename(oldPath, path); err != nil {
+			return errors.Join(cause, fmt.Errorf("failed to restore database from %s: %w", oldPath, err))
+		}
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("failed to reopen database: %w", err))
+	}
+	s.db = db
+	return cause
+}
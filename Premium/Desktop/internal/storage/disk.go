@@ -0,0 +1,13 @@
+package storage
+
+import "github.com/shirou/gopsutil/disk"
+
+// diskSpace returns the bytes available to the bridge and the total size of
+// the disk holding dir
+func diskSpace(dir string) (free, total uint64, err error) {
+	usage, err := disk.Usage(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	return usage.Free, usage.Total, nil
+}
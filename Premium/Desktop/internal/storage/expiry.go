@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSweepInterval is how often expired keys are purged by default
+const DefaultSweepInterval = 10 * time.Minute
+
+// RunExpirySweeps purges expired keys from s at start and then every
+// interval until ctx is cancelled
+func RunExpirySweeps(ctx context.Context, s Storage, interval time.Duration, logger *logrus.Logger) {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeExpired(); err != nil {
+			logger.WithError(err).Warn("Failed to purge expired storage keys")
+		} else if purged > 0 {
+			logger.WithField("count", purged).Debug("Purged expired storage keys")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestExpiringKeys(t *testing.T) {
+	for _, backend := range Backends() {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(backend, t.TempDir())
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer s.Close()
+
+			s.SetWithTTL("temp_user_1", []byte("temp"), 20*time.Millisecond)
+			s.SetWithTTL("kept", []byte("kept"), time.Hour)
+			s.Set("permanent", []byte("permanent"))
+
+			// Overwriting without a TTL makes a key permanent
+			s.SetWithTTL("renewed", []byte("old"), 20*time.Millisecond)
+			s.Set("renewed", []byte("new"))
+
+			if value, err := s.Get("temp_user_1"); err != nil || string(value) != "temp" {
+				t.Fatalf("expected the key before it expires, got %q, %v", value, err)
+			}
+
+			time.Sleep(40 * time.Millisecond)
+
+			if _, err := s.Get("temp_user_1"); err == nil {
+				t.Error("expected an expired key not to be found")
+			}
+			if s.Exists("temp_user_1") {
+				t.Error("expected an expired key not to exist")
+			}
+			if keys, _ := s.List(""); len(keys) != 3 {
+				t.Errorf("expected 3 live keys, got %v", keys)
+			}
+
+			purged, err := s.PurgeExpired()
+			if err != nil || purged != 1 {
+				t.Errorf("expected 1 key purged, got %d, %v", purged, err)
+			}
+			for _, key := range []string{"kept", "permanent", "renewed"} {
+				if !s.Exists(key) {
+					t.Errorf("expected %s to be kept", key)
+				}
+			}
+
+			if expiresAt, ok := s.(Expiring).ExpiresAt(defaultBucket, "kept"); !ok || time.Until(expiresAt) < 59*time.Minute {
+				t.Errorf("expected kept to expire in an hour, got %v, %v", expiresAt, ok)
+			}
+		})
+	}
+}
+
+func TestMigrateKeepsTTL(t *testing.T) {
+	dir := t.TempDir()
+	src, _ := NewBoltStorage(dir)
+	defer src.Close()
+	dst, _ := NewSQLiteStorage(dir)
+	defer dst.Close()
+
+	src.SetWithTTL("kept", []byte("kept"), time.Hour)
+	src.SetWithTTL("expired", []byte("expired"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := Migrate(dst, src); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if _, ok := dst.ExpiresAt(defaultBucket, "kept"); !ok {
+		t.Error("expected the TTL to be copied")
+	}
+	if dst.Exists("expired") {
+		t.Error("expected expired keys not to be copied")
+	}
+}
+
+func TestRunExpirySweeps(t *testing.T) {
+	s, _ := NewSQLiteStorage(t.TempDir())
+	defer s.Close()
+	s.SetWithTTL("temp", []byte("temp"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunExpirySweeps(ctx, s, time.Hour, logrus.New())
+		close(done)
+	}()
+
+	// The first sweep runs at start
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := s.ExpiresAt(defaultBucket, "temp"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the expired key to be purged")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// File returns the database file of a storage backend in dataDir
+func File(backend, dataDir string) (string, error) {
+	switch backend {
+	case BackendBolt, "":
+		return filepath.Join(dataDir, "waddlebot-bridge.db"), nil
+	case BackendSQLite:
+		return filepath.Join(dataDir, "waddlebot-bridge.sqlite"), nil
+	default:
+		return "", fmt.Errorf("unknown storage backend %q (available: %v)", backend, Backends())
+	}
+}
+
+// Verify checks the integrity of a database file of a storage backend,
+// such as a backup
+func Verify(backend, path string) error {
+	switch backend {
+	case BackendBolt, "":
+		return verifyBolt(path)
+	case BackendSQLite:
+		return verifySQLite(path)
+	default:
+		return fmt.Errorf("unknown storage backend %q (available: %v)", backend, Backends())
+	}
+}
+
+func verifyBolt(path string) error {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to open bolt database: %w", err)
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bbolt.Tx) error {
+		// Drain every error so the check finishes before the transaction ends
+		var corrupt error
+		for err := range tx.Check() {
+			if corrupt == nil {
+				corrupt = err
+			}
+		}
+		if corrupt != nil {
+			return fmt.Errorf("bolt database is corrupt: %w", corrupt)
+		}
+		if tx.Bucket([]byte(defaultBucket)) == nil {
+			return fmt.Errorf("not a bridge database: bucket %s not found", defaultBucket)
+		}
+		return nil
+	})
+}
+
+func verifySQLite(path string) error {
+	db, err := sql.Open("sqlite", path+"?_pragma=query_only(1)")
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("failed to check sqlite database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("sqlite database is corrupt: %s", result)
+	}
+
+	var buckets int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM buckets WHERE name = ?`, defaultBucket).Scan(&buckets); err != nil || buckets == 0 {
+		return fmt.Errorf("not a bridge database: bucket %s not found", defaultBucket)
+	}
+	return nil
+}
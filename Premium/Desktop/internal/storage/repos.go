@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Buckets owned by the subsystem repositories
+const (
+	AuthBucket       = "auth"
+	ModulesBucket    = modulesBucket
+	ScriptsBucket    = "scripts"
+	RulesBucket      = "rules"
+	ClipsBucket      = "clips"
+	OBSHistoryBucket = "obs_history"
+	TextSourceBucket = "text_sources"
+	TimersBucket     = "timers"
+	SchedulesBucket  = "schedules"
+	TriggersBucket   = "triggers"
+)
+
+// KeyValue is the key space of a single subsystem. It is satisfied by
+// Storage, which uses the default bucket, and by Repo.
+type KeyValue interface {
+	Set(key string, value []byte) error
+	SetWithTTL(key string, value []byte, ttl time.Duration) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	Exists(key string) bool
+	List(prefix string) ([]string, error)
+}
+
+// Repo keeps the data of one subsystem in its own bucket, so it can be
+// listed and purged without touching anything else
+type Repo struct {
+	store  Storage
+	bucket string
+}
+
+// newRepo creates the bucket and moves keys with the legacy prefixes out of
+// the default bucket, where they were kept before subsystems had buckets.
+// The repo is returned even if moving keys fails.
+func newRepo(store Storage, bucket string, legacyPrefixes ...string) (*Repo, error) {
+	r := &Repo{store: store, bucket: bucket}
+	if err := store.EnsureBucket(bucket); err != nil {
+		return r, fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+	}
+	for _, prefix := range legacyPrefixes {
+		if err := r.adopt(prefix); err != nil {
+			return r, fmt.Errorf("failed to move %s keys to bucket %s: %w", prefix, bucket, err)
+		}
+	}
+	return r, nil
+}
+
+// adopt moves the default bucket keys starting with prefix into the repo,
+// keeping when they expire
+func (r *Repo) adopt(prefix string) error {
+	keys, err := r.store.List(prefix)
+	if err != nil {
+		return err
+	}
+	expiring, _ := r.store.(Expiring)
+
+	for _, key := range keys {
+		value, err := r.store.Get(key)
+		if err != nil {
+			continue
+		}
+		var ttl time.Duration
+		if expiring != nil {
+			if expiresAt, ok := expiring.ExpiresAt(defaultBucket, key); ok {
+				if ttl = time.Until(expiresAt); ttl <= 0 {
+					r.store.Delete(key)
+					continue
+				}
+			}
+		}
+		if err := r.store.SetWithBucketTTL(r.bucket, key, value, ttl); err != nil {
+			return err
+		}
+		if err := r.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bucket returns the name of the repo's bucket
+func (r *Repo) Bucket() string {
+	return r.bucket
+}
+
+// Storage returns the storage the repo is kept in
+func (r *Repo) Storage() Storage {
+	return r.store
+}
+
+// Set stores a value
+func (r *Repo) Set(key string, value []byte) error {
+	return r.store.SetWithBucket(r.bucket, key, value)
+}
+
+// SetWithTTL stores a value that expires after ttl
+func (r *Repo) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return r.store.SetWithBucketTTL(r.bucket, key, value, ttl)
+}
+
+// Get retrieves a value
+func (r *Repo) Get(key string) ([]byte, error) {
+	return r.store.GetWithBucket(r.bucket, key)
+}
+
+// Delete removes a key
+func (r *Repo) Delete(key string) error {
+	return r.store.DeleteWithBucket(r.bucket, key)
+}
+
+// Exists checks if a key exists
+func (r *Repo) Exists(key string) bool {
+	_, err := r.Get(key)
+	return err == nil
+}
+
+// List returns the keys starting with prefix
+func (r *Repo) List(prefix string) ([]string, error) {
+	return r.store.ListWithBucket(r.bucket, prefix)
+}
+
+// All returns every key and value
+func (r *Repo) All() (map[string][]byte, error) {
+	return r.store.GetAllFromBucket(r.bucket)
+}
+
+// Purge removes every key
+func (r *Repo) Purge() error {
+	return r.store.ClearBucket(r.bucket)
+}
+
+// AuthRepo holds users, signing keys, sessions and sign-in ceremonies
+type AuthRepo struct {
+	*Repo
+}
+
+// NewAuthRepo returns the auth repository of store
+func NewAuthRepo(store Storage) (*AuthRepo, error) {
+	repo, err := newRepo(store, AuthBucket, "user_", "registration_session_", "temp_user_", "auth_", "jwt_signing_keys")
+	return &AuthRepo{repo}, err
+}
+
+// ModuleRepo holds module information and configuration
+type ModuleRepo struct {
+	*Repo
+}
+
+// NewModuleRepo returns the module repository of store
+func NewModuleRepo(store Storage) (*ModuleRepo, error) {
+	repo, err := newRepo(store, ModulesBucket, "module_info_", "module_config_")
+	return &ModuleRepo{repo}, err
+}
+
+// ScriptRepo holds the values scripts keep with storage.set
+type ScriptRepo struct {
+	*Repo
+}
+
+// NewScriptRepo returns the script repository of store
+func NewScriptRepo(store Storage) (*ScriptRepo, error) {
+	repo, err := newRepo(store, ScriptsBucket)
+	return &ScriptRepo{repo}, err
+}
+
+// RuleRepo holds the scene switching rules, as JSON by rule ID
+type RuleRepo struct {
+	*Repo
+}
+
+// NewRuleRepo returns the rule repository of store
+func NewRuleRepo(store Storage) (*RuleRepo, error) {
+	repo, err := newRepo(store, RulesBucket)
+	return &RuleRepo{repo}, err
+}
+
+// ClipRepo holds the clip uploads that have not finished, by file hash
+type ClipRepo struct {
+	*Repo
+}
+
+// NewClipRepo returns the clip repository of store
+func NewClipRepo(store Storage) (*ClipRepo, error) {
+	repo, err := newRepo(store, ClipsBucket)
+	return &ClipRepo{repo}, err
+}
+
+// OBSHistoryRepo holds the timeline of the OBS connection
+type OBSHistoryRepo struct {
+	*Repo
+}
+
+// NewOBSHistoryRepo returns the OBS connection history repository of store
+func NewOBSHistoryRepo(store Storage) (*OBSHistoryRepo, error) {
+	repo, err := newRepo(store, OBSHistoryBucket)
+	return &OBSHistoryRepo{repo}, err
+}
+
+// TextSourceRepo holds the text sources and the variables they show
+type TextSourceRepo struct {
+	*Repo
+}
+
+// NewTextSourceRepo returns the text source repository of store
+func NewTextSourceRepo(store Storage) (*TextSourceRepo, error) {
+	repo, err := newRepo(store, TextSourceBucket)
+	return &TextSourceRepo{repo}, err
+}
+
+// TimerRepo holds the countdowns and stopwatches, by name
+type TimerRepo struct {
+	*Repo
+}
+
+// NewTimerRepo returns the timer repository of store
+func NewTimerRepo(store Storage) (*TimerRepo, error) {
+	repo, err := newRepo(store, TimersBucket)
+	return &TimerRepo{repo}, err
+}
+
+// ScheduleRepo holds the source visibility schedules, as JSON by
+// schedule ID
+type ScheduleRepo struct {
+	*Repo
+}
+
+// NewScheduleRepo returns the schedule repository of store
+func NewScheduleRepo(store Storage) (*ScheduleRepo, error) {
+	repo, err := newRepo(store, SchedulesBucket)
+	return &ScheduleRepo{repo}, err
+}
+
+// TriggerRepo holds the triggers, as JSON by trigger ID
+type TriggerRepo struct {
+	*Repo
+}
+
+// NewTriggerRepo returns the trigger repository of store
+func NewTriggerRepo(store Storage) (*TriggerRepo, error) {
+	repo, err := newRepo(store, TriggersBucket)
+	return &TriggerRepo{repo}, err
+}
+
+// WebhookRepo holds webhook registrations and their delivery history
+type WebhookRepo struct {
+	Registrations *Repo
+	Deliveries    *Repo
+}
+
+// NewWebhookRepo returns the webhook repository of store, using the given
+// buckets for registrations and deliveries
+func NewWebhookRepo(store Storage, registrationsBucket, deliveriesBucket string) (*WebhookRepo, error) {
+	registrations, err := newRepo(store, registrationsBucket)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := newRepo(store, deliveriesBucket)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookRepo{Registrations: registrations, Deliveries: deliveries}, nil
+}
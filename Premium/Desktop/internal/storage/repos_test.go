@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRepoMovesLegacyKeys(t *testing.T) {
+	for _, backend := range Backends() {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(backend, t.TempDir())
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer s.Close()
+
+			s.Set("user_alice", []byte("alice"))
+			s.SetWithTTL("auth_session_alice", []byte("ceremony"), time.Hour)
+			s.Set("bridge_id", []byte("bridge"))
+
+			repo, err := NewAuthRepo(s)
+			if err != nil {
+				t.Fatalf("NewAuthRepo failed: %v", err)
+			}
+
+			if value, err := repo.Get("user_alice"); err != nil || string(value) != "alice" {
+				t.Errorf("expected the user in the auth bucket, got %q, %v", value, err)
+			}
+			if s.Exists("user_alice") {
+				t.Error("expected the user to be removed from the default bucket")
+			}
+			if expiresAt, ok := s.(Expiring).ExpiresAt(AuthBucket, "auth_session_alice"); !ok || time.Until(expiresAt) <= 0 {
+				t.Error("expected the moved ceremony to keep its expiry")
+			}
+			if !s.Exists("bridge_id") {
+				t.Error("expected keys of other subsystems to stay in the default bucket")
+			}
+		})
+	}
+}
+
+func TestRepoIsolatesSubsystems(t *testing.T) {
+	s, err := Open(BackendBolt, t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	scripts, err := NewScriptRepo(s)
+	if err != nil {
+		t.Fatalf("NewScriptRepo failed: %v", err)
+	}
+	modules, err := NewModuleRepo(s)
+	if err != nil {
+		t.Fatalf("NewModuleRepo failed: %v", err)
+	}
+
+	scripts.Set("counter", []byte("1"))
+	modules.Set("counter", []byte("module"))
+
+	if value, _ := scripts.Get("counter"); string(value) != "1" {
+		t.Errorf("expected the script value, got %q", value)
+	}
+	if keys, _ := scripts.List(""); len(keys) != 1 {
+		t.Errorf("expected 1 script key, got %v", keys)
+	}
+
+	if err := scripts.Purge(); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if scripts.Exists("counter") {
+		t.Error("expected the script values to be purged")
+	}
+	if !modules.Exists("counter") {
+		t.Error("expected purging scripts to leave modules alone")
+	}
+}
@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// live matches entries that have not expired, given the current time in
+// unix nanoseconds
+const live = `(expires_at IS NULL OR expires_at > ?)`
+
+// SQLiteStorage implements the Storage interface using SQLite. Buckets are
+// rows of one table, so the database can be inspected with the sqlite3
+// shell, and readers do not wait for writers.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// NewSQLiteStorage creates a new SQLite storage instance
+func NewSQLiteStorage(dataDir string) (*SQLiteStorage, error) {
+	dbPath := filepath.Join(dataDir, "waddlebot-bridge.sqlite")
+
+	// A plain path rather than a file: URI, which would misread # and %
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	storage := &SQLiteStorage{db: db}
+
+	if err := storage.initSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// initSchema creates the tables and the required buckets if they don't
+// exist
+func (s *SQLiteStorage) initSchema() error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS buckets (
+			name TEXT PRIMARY KEY
+		)`,
+		`CREATE TABLE IF NOT EXISTS entries (
+			bucket TEXT NOT NULL REFERENCES buckets(name) ON DELETE CASCADE,
+			key    TEXT NOT NULL,
+			value  BLOB NOT NULL,
+			expires_at INTEGER, -- unix nanoseconds, NULL never expires
+			PRIMARY KEY (bucket, key)
+		) WITHOUT ROWID`,
+		`CREATE INDEX IF NOT EXISTS entries_expires_at ON entries (expires_at) WHERE expires_at IS NOT NULL`,
+	}
+	for _, statement := range statements {
+		if _, err := s.db.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	for _, bucket := range []string{defaultBucket, sessionsBucket, modulesBucket, configBucket, outboxBucket, auditBucket} {
+		if err := s.EnsureBucket(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set stores a key-value pair
+func (s *SQLiteStorage) Set(key string, value []byte) error {
+	return s.SetWithBucket(defaultBucket, key, value)
+}
+
+// SetWithTTL stores a key-value pair that expires after ttl
+func (s *SQLiteStorage) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return s.SetWithBucketTTL(defaultBucket, key, value, ttl)
+}
+
+// Get retrieves a value by key
+func (s *SQLiteStorage) Get(key string) ([]byte, error) {
+	return s.GetWithBucket(defaultBucket, key)
+}
+
+// Delete removes a key
+func (s *SQLiteStorage) Delete(key string) error {
+	return s.DeleteWithBucket(defaultBucket, key)
+}
+
+// Exists checks if a key exists
+func (s *SQLiteStorage) Exists(key string) bool {
+	_, err := s.Get(key)
+	return err == nil
+}
+
+// List returns all keys with a given prefix
+func (s *SQLiteStorage) List(prefix string) ([]string, error) {
+	return s.ListWithBucket(defaultBucket, prefix)
+}
+
+// SetWithBucket stores a key-value pair in a specific bucket
+func (s *SQLiteStorage) SetWithBucket(bucketName, key string, value []byte) error {
+	return s.set(bucketName, key, value, nil)
+}
+
+// SetWithBucketTTL stores a key-value pair in a specific bucket that
+// expires after ttl; a ttl of zero or less never expires
+func (s *SQLiteStorage) SetWithBucketTTL(bucketName, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return s.set(bucketName, key, value, nil)
+	}
+	return s.set(bucketName, key, value, time.Now().Add(ttl).UnixNano())
+}
+
+// set stores a key-value pair with its expiry, or nil to never expire
+func (s *SQLiteStorage) set(bucketName, key string, value []byte, expiresAt interface{}) error {
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := s.db.Exec(`INSERT INTO entries (bucket, key, value, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (bucket, key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`,
+		bucketName, key, value, expiresAt)
+	if err != nil && strings.Contains(err.Error(), "FOREIGN KEY") {
+		return fmt.Errorf("bucket %s not found", bucketName)
+	}
+	return err
+}
+
+// GetWithBucket retrieves a value by key from a specific bucket
+func (s *SQLiteStorage) GetWithBucket(bucketName, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRow(`SELECT value FROM entries WHERE bucket = ? AND key = ? AND `+live, bucketName, key, time.Now().UnixNano()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+	}
+	return value, err
+}
+
+// DeleteWithBucket removes a key from a specific bucket
+func (s *SQLiteStorage) DeleteWithBucket(bucketName, key string) error {
+	if err := s.checkBucket(bucketName); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM entries WHERE bucket = ? AND key = ?`, bucketName, key)
+	return err
+}
+
+// ListWithBucket returns all keys with a given prefix from a specific bucket
+func (s *SQLiteStorage) ListWithBucket(bucketName, prefix string) ([]string, error) {
+	if err := s.checkBucket(bucketName); err != nil {
+		return nil, err
+	}
+
+	// substr keeps the match byte-wise and free of LIKE wildcards
+	rows, err := s.db.Query(`SELECT key FROM entries WHERE bucket = ? AND substr(key, 1, length(?)) = ? AND `+live+` ORDER BY key`,
+		bucketName, prefix, prefix, time.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetAllFromBucket returns all key-value pairs from a specific bucket
+func (s *SQLiteStorage) GetAllFromBucket(bucketName string) (map[string][]byte, error) {
+	if err := s.checkBucket(bucketName); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT key, value FROM entries WHERE bucket = ? AND `+live, bucketName, time.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := make(map[string][]byte)
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		data[key] = value
+	}
+	return data, rows.Err()
+}
+
+// ClearBucket removes all data from a specific bucket
+func (s *SQLiteStorage) ClearBucket(bucketName string) error {
+	if err := s.checkBucket(bucketName); err != nil {
+		return fmt.Errorf("failed to delete bucket %s: %w", bucketName, err)
+	}
+	_, err := s.db.Exec(`DELETE FROM entries WHERE bucket = ?`, bucketName)
+	return err
+}
+
+// EnsureBucket creates a bucket if it does not exist yet
+func (s *SQLiteStorage) EnsureBucket(bucketName string) error {
+	if _, err := s.db.Exec(`INSERT OR IGNORE INTO buckets (name) VALUES (?)`, bucketName); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", bucketName, err)
+	}
+	return nil
+}
+
+// Buckets returns the names of all buckets
+func (s *SQLiteStorage) Buckets() ([]string, error) {
+	rows, err := s.db.Query(`SELECT name FROM buckets ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, name)
+	}
+	return buckets, rows.Err()
+}
+
+// BucketStats returns the number of keys and bytes in each bucket
+func (s *SQLiteStorage) BucketStats() ([]BucketUsage, error) {
+	rows, err := s.db.Query(`SELECT b.name, COUNT(e.key), COALESCE(SUM(LENGTH(e.key) + LENGTH(e.value)), 0)
+		FROM buckets b LEFT JOIN entries e ON e.bucket = b.name GROUP BY b.name ORDER BY b.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []BucketUsage
+	for rows.Next() {
+		var usage BucketUsage
+		if err := rows.Scan(&usage.Name, &usage.Keys, &usage.Bytes); err != nil {
+			return nil, err
+		}
+		stats = append(stats, usage)
+	}
+	return stats, rows.Err()
+}
+
+// FreeRatio returns the share of database pages on the freelist
+func (s *SQLiteStorage) FreeRatio() float64 {
+	var free, total int64
+	s.db.QueryRow(`PRAGMA freelist_count`).Scan(&free)
+	s.db.QueryRow(`PRAGMA page_count`).Scan(&total)
+	if total == 0 {
+		return 0
+	}
+	return float64(free) / float64(total)
+}
+
+// Compact rebuilds the database with VACUUM and returns its size before and
+// after
+func (s *SQLiteStorage) Compact() (before, after int64, err error) {
+	before = s.size()
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		return before, before, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	s.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+	return before, s.size(), nil
+}
+
+// size returns the size of the database file in bytes
+func (s *SQLiteStorage) size() int64 {
+	var pages, pageSize int64
+	s.db.QueryRow(`PRAGMA page_count`).Scan(&pages)
+	s.db.QueryRow(`PRAGMA page_size`).Scan(&pageSize)
+	return pages * pageSize
+}
+
+// ExpiresAt returns when a key stored with a TTL expires
+func (s *SQLiteStorage) ExpiresAt(bucketName, key string) (time.Time, bool) {
+	var expiresAt sql.NullInt64
+	err := s.db.QueryRow(`SELECT expires_at FROM entries WHERE bucket = ? AND key = ?`, bucketName, key).Scan(&expiresAt)
+	if err != nil || !expiresAt.Valid {
+		return time.Time{}, false
+	}
+	return time.Unix(0, expiresAt.Int64), true
+}
+
+// PurgeExpired deletes the keys whose TTL has passed and returns how many
+// were deleted
+func (s *SQLiteStorage) PurgeExpired() (int, error) {
+	result, err := s.db.Exec(`DELETE FROM entries WHERE expires_at IS NOT NULL AND expires_at <= ?`, time.Now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// checkBucket returns an error if a bucket does not exist
+func (s *SQLiteStorage) checkBucket(bucketName string) error {
+	var exists int
+	err := s.db.QueryRow(`SELECT 1 FROM buckets WHERE name = ?`, bucketName).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("bucket %s not found", bucketName)
+	}
+	return err
+}
+
+// Close closes the database connection
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// Backup creates a backup of the database
+func (s *SQLiteStorage) Backup(backupPath string) error {
+	_, err := s.db.Exec(`VACUUM INTO ?`, backupPath)
+	return err
+}
+
+// Stats returns database statistics
+func (s *SQLiteStorage) Stats() map[string]interface{} {
+	stats := s.db.Stats()
+
+	var buckets, keys int
+	s.db.QueryRow(`SELECT COUNT(*) FROM buckets`).Scan(&buckets)
+	s.db.QueryRow(`SELECT COUNT(*) FROM entries`).Scan(&keys)
+
+	return map[string]interface{}{
+		"buckets":          buckets,
+		"keys":             keys,
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"wait_count":       stats.WaitCount,
+	}
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func newTestSQLiteStorage(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	storage, err := NewSQLiteStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func TestSQLiteStorage_Set_Get(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+
+	if err := storage.Set("test-key", []byte("test-value")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	value, err := storage.Get("test-key")
+	if err != nil || string(value) != "test-value" {
+		t.Errorf("expected test-value, got %q, %v", value, err)
+	}
+	if !storage.Exists("test-key") {
+		t.Error("expected the key to exist")
+	}
+
+	if _, err := storage.Get("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	if err := storage.Delete("test-key"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if storage.Exists("test-key") {
+		t.Error("expected the key to be deleted")
+	}
+}
+
+func TestSQLiteStorage_BucketOperations(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+
+	if err := storage.SetWithBucket("test-bucket", "key", []byte("value")); err == nil {
+		t.Error("expected an error for a missing bucket")
+	}
+	if err := storage.EnsureBucket("test-bucket"); err != nil {
+		t.Fatalf("EnsureBucket failed: %v", err)
+	}
+
+	for _, key := range []string{"a_1", "a_2", "ab", "b"} {
+		if err := storage.SetWithBucket("test-bucket", key, []byte(key)); err != nil {
+			t.Fatalf("SetWithBucket failed: %v", err)
+		}
+	}
+
+	// _ is not a wildcard
+	keys, err := storage.ListWithBucket("test-bucket", "a_")
+	if err != nil || len(keys) != 2 || keys[0] != "a_1" || keys[1] != "a_2" {
+		t.Errorf("expected a_1 and a_2, got %v, %v", keys, err)
+	}
+
+	all, err := storage.GetAllFromBucket("test-bucket")
+	if err != nil || len(all) != 4 || string(all["ab"]) != "ab" {
+		t.Errorf("unexpected bucket contents %v, %v", all, err)
+	}
+
+	if err := storage.ClearBucket("test-bucket"); err != nil {
+		t.Fatalf("ClearBucket failed: %v", err)
+	}
+	if all, _ := storage.GetAllFromBucket("test-bucket"); len(all) != 0 {
+		t.Errorf("expected an empty bucket, got %v", all)
+	}
+}
+
+func TestSQLiteStorage_ConcurrentAccess(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := string(rune('a' + i))
+			if err := storage.Set(key, []byte(key)); err != nil {
+				t.Errorf("Set failed: %v", err)
+			}
+			if _, err := storage.Get(key); err != nil {
+				t.Errorf("Get failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if keys, _ := storage.List(""); len(keys) != 10 {
+		t.Errorf("expected 10 keys, got %d", len(keys))
+	}
+}
+
+func TestSQLiteStorage_Backup(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+	storage.Set("key", []byte("value"))
+
+	backupDir := t.TempDir()
+	if err := storage.Backup(filepath.Join(backupDir, "waddlebot-bridge.sqlite")); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	backup, err := NewSQLiteStorage(backupDir)
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer backup.Close()
+	if value, err := backup.Get("key"); err != nil || string(value) != "value" {
+		t.Errorf("expected the backed up value, got %q, %v", value, err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	src, err := Open(BackendBolt, dir)
+	if err != nil {
+		t.Fatalf("Open bolt failed: %v", err)
+	}
+	defer src.Close()
+
+	src.Set("key", []byte("value"))
+	src.EnsureBucket("webhooks")
+	src.SetWithBucket("webhooks", "hook", []byte("{}"))
+
+	dst, err := Open(BackendSQLite, dir)
+	if err != nil {
+		t.Fatalf("Open sqlite failed: %v", err)
+	}
+	defer dst.Close()
+
+	copied, err := Migrate(dst, src)
+	if err != nil || copied != 2 {
+		t.Fatalf("expected 2 keys copied, got %d, %v", copied, err)
+	}
+	if value, err := dst.Get("key"); err != nil || string(value) != "value" {
+		t.Errorf("expected the copied value, got %q, %v", value, err)
+	}
+	if value, err := dst.GetWithBucket("webhooks", "hook"); err != nil || string(value) != "{}" {
+		t.Errorf("expected the copied bucket, got %q, %v", value, err)
+	}
+
+	buckets, _ := dst.(BucketLister).Buckets()
+	if len(buckets) < 7 {
+		t.Errorf("expected the default buckets and webhooks, got %v", buckets)
+	}
+
+	if _, err := Open("leveldb", dir); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
@@ -1,5 +1,7 @@
 package storage
 
+import "time"
+
 // Storage defines the interface for data storage operations
 type Storage interface {
 	// Basic operations
@@ -16,6 +18,12 @@ type Storage interface {
 	ListWithBucket(bucketName, prefix string) ([]string, error)
 	GetAllFromBucket(bucketName string) (map[string][]byte, error)
 	ClearBucket(bucketName string) error
+	EnsureBucket(bucketName string) error
+	
+	// Expiring keys, for transient data such as sign-in ceremonies
+	SetWithTTL(key string, value []byte, ttl time.Duration) error
+	SetWithBucketTTL(bucketName, key string, value []byte, ttl time.Duration) error
+	PurgeExpired() (int, error)
 	
 	// Utility operations
 	Close() error
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// compactMinSize is the smallest file worth compacting; small databases
+// are mostly preallocated space
+const compactMinSize = 1 << 20
+
+// BucketStatter is implemented by storage that can report the size of its
+// buckets
+type BucketStatter interface {
+	BucketStats() ([]BucketUsage, error)
+}
+
+// BucketUsage is how much data one bucket holds
+type BucketUsage struct {
+	Name  string `json:"name"`
+	Keys  int    `json:"keys"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Usage is how much space storage takes and how much is left on the disk
+// holding it
+type Usage struct {
+	Backend        string        `json:"backend"`
+	Path           string        `json:"path"`
+	SizeBytes      int64         `json:"size_bytes"`
+	FreeRatio      float64       `json:"free_ratio"`
+	Buckets        []BucketUsage `json:"buckets,omitempty"`
+	DiskFreeBytes  uint64        `json:"disk_free_bytes,omitempty"`
+	DiskTotalBytes uint64        `json:"disk_total_bytes,omitempty"`
+	LowDisk        bool          `json:"low_disk"`
+	LastCompacted  *time.Time    `json:"last_compacted,omitempty"`
+}
+
+// MonitorOptions control when Monitor compacts storage and warns about disk
+// space
+type MonitorOptions struct {
+	// CompactRatio is the free ratio above which storage is compacted; 0
+	// disables compaction
+	CompactRatio float64
+
+	// MinFreeDisk is the free disk space in bytes below which the data
+	// directory is reported low; 0 disables the check
+	MinFreeDisk uint64
+}
+
+// Monitor watches the size of storage, compacting it when much of its file
+// is free space and warning when the data directory's disk runs low
+type Monitor struct {
+	store   Storage
+	backend string
+	dataDir string
+	options MonitorOptions
+	logger  *logrus.Logger
+
+	mu            sync.RWMutex
+	lowDisk       bool
+	lastCompacted time.Time
+}
+
+// NewMonitor creates a monitor for store, kept in dataDir with backend
+func NewMonitor(store Storage, backend, dataDir string, options MonitorOptions, logger *logrus.Logger) *Monitor {
+	return &Monitor{
+		store:   store,
+		backend: backend,
+		dataDir: dataDir,
+		options: options,
+		logger:  logger,
+	}
+}
+
+// Usage returns the current size of storage and its buckets
+func (m *Monitor) Usage() (Usage, error) {
+	usage := Usage{Backend: m.backend}
+
+	path, err := File(m.backend, m.dataDir)
+	if err != nil {
+		return usage, err
+	}
+	usage.Path = path
+	if info, err := os.Stat(path); err == nil {
+		usage.SizeBytes = info.Size()
+	}
+
+	if compactor, ok := m.store.(Compactor); ok {
+		usage.FreeRatio = compactor.FreeRatio()
+	}
+	if statter, ok := m.store.(BucketStatter); ok {
+		if usage.Buckets, err = statter.BucketStats(); err != nil {
+			return usage, err
+		}
+	}
+	if free, total, err := diskSpace(m.dataDir); err == nil {
+		usage.DiskFreeBytes = free
+		usage.DiskTotalBytes = total
+		usage.LowDisk = m.options.MinFreeDisk > 0 && free < m.options.MinFreeDisk
+	}
+
+	m.mu.RLock()
+	if !m.lastCompacted.IsZero() {
+		lastCompacted := m.lastCompacted
+		usage.LastCompacted = &lastCompacted
+	}
+	m.mu.RUnlock()
+
+	return usage, nil
+}
+
+// LowDisk reports whether the last check found the data directory's disk
+// low on space
+func (m *Monitor) LowDisk() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lowDisk
+}
+
+// Run checks storage at start and then every interval until ctx is
+// cancelled
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.Check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check warns if the disk is low on space and compacts storage when its
+// free ratio exceeds the threshold
+func (m *Monitor) Check() {
+	if m.options.MinFreeDisk > 0 {
+		if free, total, err := diskSpace(m.dataDir); err != nil {
+			m.logger.WithError(err).Debug("Failed to read free disk space")
+		} else {
+			low := free < m.options.MinFreeDisk
+			if low {
+				m.logger.WithFields(logrus.Fields{
+					"data_dir":   m.dataDir,
+					"free_bytes": free,
+					"disk_bytes": total,
+				}).Warn("Disk space is low in the data directory")
+			}
+			m.mu.Lock()
+			m.lowDisk = low
+			m.mu.Unlock()
+		}
+	}
+
+	compactor, ok := m.store.(Compactor)
+	if !ok || m.options.CompactRatio <= 0 {
+		return
+	}
+	path, err := File(m.backend, m.dataDir)
+	if err != nil {
+		return
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() < compactMinSize {
+		return
+	}
+	ratio := compactor.FreeRatio()
+	if ratio < m.options.CompactRatio {
+		return
+	}
+
+	before, after, err := compactor.Compact()
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to compact storage")
+		return
+	}
+	m.mu.Lock()
+	m.lastCompacted = time.Now()
+	m.mu.Unlock()
+	m.logger.WithFields(logrus.Fields{
+		"free_ratio":   ratio,
+		"before_bytes": before,
+		"after_bytes":  after,
+	}).Info("Compacted storage")
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+func TestMonitorCompactsFreeSpace(t *testing.T) {
+	for _, backend := range Backends() {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := Open(backend, dir)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer s.Close()
+
+			// Fill a few megabytes and delete most of it
+			value := make([]byte, 4096)
+			for i := 0; i < 1000; i++ {
+				s.SetWithBucket("outbox", fmt.Sprintf("item_%04d", i), value)
+			}
+			for i := 0; i < 990; i++ {
+				s.DeleteWithBucket("outbox", fmt.Sprintf("item_%04d", i))
+			}
+			s.Set("kept", []byte("kept"))
+
+			if ratio := s.(Compactor).FreeRatio(); ratio < 0.5 {
+				t.Fatalf("expected most of the file to be free, got %.2f", ratio)
+			}
+
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			monitor := NewMonitor(s, backend, dir, MonitorOptions{CompactRatio: 0.5}, logger)
+
+			before, _ := monitor.Usage()
+			monitor.Check()
+			after, err := monitor.Usage()
+			if err != nil {
+				t.Fatalf("Usage failed: %v", err)
+			}
+
+			if after.LastCompacted == nil {
+				t.Fatal("expected storage to be compacted")
+			}
+			if after.SizeBytes >= before.SizeBytes {
+				t.Errorf("expected compaction to shrink the file, %d -> %d bytes", before.SizeBytes, after.SizeBytes)
+			}
+			if value, err := s.Get("kept"); err != nil || string(value) != "kept" {
+				t.Errorf("expected data to survive compaction, got %q, %v", value, err)
+			}
+
+			for _, bucket := range after.Buckets {
+				if bucket.Name == "outbox" && bucket.Keys != 10 {
+					t.Errorf("expected 10 outbox keys, got %d", bucket.Keys)
+				}
+			}
+		})
+	}
+}
+
+func TestMonitorLowDisk(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(BackendBolt, dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// No disk has this much space left
+	monitor := NewMonitor(s, BackendBolt, dir, MonitorOptions{MinFreeDisk: 1 << 62}, logger)
+	monitor.Check()
+	if !monitor.LowDisk() {
+		t.Error("expected the disk to be reported low")
+	}
+
+	monitor = NewMonitor(s, BackendBolt, dir, MonitorOptions{MinFreeDisk: 1}, logger)
+	monitor.Check()
+	if monitor.LowDisk() {
+		t.Error("expected the disk not to be reported low")
+	}
+}
+
+func TestCompactKeepsDatabaseOpenOnFailure(t *testing.T) {
+	s, err := NewBoltStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBoltStorage failed: %v", err)
+	}
+	defer s.Close()
+	s.Set("kept", []byte("kept"))
+
+	openCompacted = func(string, os.FileMode, *bbolt.Options) (*bbolt.DB, error) {
+		return nil, errors.New("open failed")
+	}
+	defer func() { openCompacted = bbolt.Open }()
+
+	if _, _, err := s.Compact(); err == nil {
+		t.Fatal("expected Compact to fail")
+	}
+	if value, err := s.Get("kept"); err != nil || string(value) != "kept" {
+		t.Errorf("expected the original database to be reopened, got %q, %v", value, err)
+	}
+	if err := s.Set("after", []byte("after")); err != nil {
+		t.Errorf("expected the database to stay writable, got %v", err)
+	}
+
+	openCompacted = bbolt.Open
+	if _, _, err := s.Compact(); err != nil {
+		t.Errorf("expected a later compaction to succeed, got %v", err)
+	}
+	if value, err := s.Get("after"); err != nil || string(value) != "after" {
+		t.Errorf("expected data to survive compaction, got %q, %v", value, err)
+	}
+}
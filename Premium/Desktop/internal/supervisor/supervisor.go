@@ -0,0 +1,216 @@
+// Package supervisor keeps a panic in one subsystem from stopping the
+// bridge. Calls into modules and script engines recover panics as errors,
+// and long-running subsystems such as the poller, gateway and OBS monitor
+// are restarted with a backoff. Crashes are counted per subsystem, and a
+// subsystem that keeps crashing is reported in heartbeats as crash looping.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/logger"
+	"waddlebot-bridge/internal/metrics"
+)
+
+// ErrPanic is wrapped by the errors panics are turned into
+var ErrPanic = errors.New("panic")
+
+// Policy decides how a subsystem run by Run is restarted
+type Policy struct {
+	RestartOnError bool          // restart when run returns an error, not only after a panic
+	Backoff        time.Duration // wait before the first restart, doubled per crash in a row
+	MaxBackoff     time.Duration // longest wait between restarts
+	LoopThreshold  int           // crashes within LoopWindow that make a crash loop
+	LoopWindow     time.Duration // how long crashes count towards a crash loop
+}
+
+// DefaultPolicy restarts a subsystem after it panics, waiting from one
+// second up to a minute, and reports a crash loop after five crashes in ten
+// minutes
+var DefaultPolicy = Policy{
+	Backoff:       time.Second,
+	MaxBackoff:    time.Minute,
+	LoopThreshold: 5,
+	LoopWindow:    10 * time.Minute,
+}
+
+// Status describes the crashes of a subsystem
+type Status struct {
+	Name      string    `json:"name"`
+	Crashes   int       `json:"crashes"`
+	Restarts  int       `json:"restarts"`
+	LastCrash time.Time `json:"last_crash"`
+	LastError string    `json:"last_error"`
+	CrashLoop bool      `json:"crash_loop"`
+}
+
+// subsystem tracks the crashes of one subsystem
+type subsystem struct {
+	status Status
+	recent []time.Time // crashes within the loop window
+	policy Policy
+}
+
+var (
+	mu         sync.Mutex
+	subsystems = make(map[string]*subsystem)
+	now        = time.Now
+)
+
+// Recover turns a panic in the calling function into an error wrapping
+// ErrPanic, returned through err, and counts it as a crash of name. It must
+// be deferred directly:
+//
+//	defer supervisor.Recover("scripts", &err)
+func Recover(name string, err *error) {
+	if r := recover(); r != nil {
+		*err = crashed(name, r)
+	}
+}
+
+// Run runs a long-running subsystem until ctx is done, restarting it after
+// it panics, and after it returns an error if policy says so. Restarts wait
+// for a backoff that doubles while the subsystem keeps crashing.
+func Run(ctx context.Context, name string, policy Policy, run func(ctx context.Context) error) {
+	backoff := policy.Backoff
+	for {
+		started := now()
+		err := runOnce(ctx, name, run)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case errors.Is(err, ErrPanic):
+		case err != nil && policy.RestartOnError:
+			record(name, err)
+		default:
+			if err != nil {
+				logger.GetLogger().WithError(err).WithField("subsystem", name).Error("Subsystem stopped")
+			}
+			return
+		}
+
+		// A subsystem that ran for a while before crashing starts over
+		// from the shortest wait
+		if now().Sub(started) > policy.MaxBackoff {
+			backoff = policy.Backoff
+		}
+		status := restarting(name, policy)
+		entry := logger.GetLogger().WithFields(logrus.Fields{
+			"subsystem": name,
+			"crashes":   status.Crashes,
+			"retry_in":  backoff,
+		})
+		if status.CrashLoop {
+			entry.Error("Subsystem is crash looping")
+		} else {
+			entry.Warn("Restarting subsystem")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// runOnce runs a subsystem, turning a panic into an error. Its context is
+// cancelled when it returns, stopping whatever it started before crashing.
+func runOnce(ctx context.Context, name string, run func(ctx context.Context) error) (err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer Recover(name, &err)
+	return run(ctx)
+}
+
+// Statuses describes the subsystems that crashed, sorted by name
+func Statuses() []Status {
+	mu.Lock()
+	defer mu.Unlock()
+
+	statuses := make([]Status, 0, len(subsystems))
+	for _, s := range subsystems {
+		s.prune()
+		statuses = append(statuses, s.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// crashed logs a panic with its stack and records the crash
+func crashed(name string, r interface{}) error {
+	err := fmt.Errorf("%w: %v", ErrPanic, r)
+	logger.GetLogger().WithFields(logrus.Fields{
+		"subsystem": name,
+		"panic":     fmt.Sprint(r),
+		"stack":     string(debug.Stack()),
+	}).Error("Recovered from panic")
+	record(name, err)
+	return err
+}
+
+// record counts a crash of name
+func record(name string, err error) {
+	metrics.SubsystemCrashes.WithLabelValues(name).Inc()
+
+	mu.Lock()
+	defer mu.Unlock()
+	s := get(name)
+	at := now()
+	s.status.Crashes++
+	s.status.LastCrash = at
+	s.status.LastError = err.Error()
+	s.recent = append(s.recent, at)
+	s.prune()
+}
+
+// restarting counts a restart of name and returns its status
+func restarting(name string, policy Policy) Status {
+	mu.Lock()
+	defer mu.Unlock()
+	s := get(name)
+	s.policy = policy
+	s.status.Restarts++
+	s.prune()
+	return s.status
+}
+
+// get returns the subsystem called name, adding it with the default
+// policy; the caller must hold mu
+func get(name string) *subsystem {
+	s, ok := subsystems[name]
+	if !ok {
+		s = &subsystem{status: Status{Name: name}, policy: DefaultPolicy}
+		subsystems[name] = s
+	}
+	return s
+}
+
+// prune forgets crashes outside the loop window and updates whether the
+// subsystem is crash looping; the caller must hold mu
+func (s *subsystem) prune() {
+	cutoff := now().Add(-s.policy.LoopWindow)
+	kept := s.recent[:0]
+	for _, at := range s.recent {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	s.recent = kept
+	s.status.CrashLoop = s.policy.LoopThreshold > 0 && len(s.recent) >= s.policy.LoopThreshold
+}
@@ -0,0 +1,156 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testPolicy restarts quickly and reports a crash loop after three crashes
+var testPolicy = Policy{
+	Backoff:       time.Millisecond,
+	MaxBackoff:    10 * time.Millisecond,
+	LoopThreshold: 3,
+	LoopWindow:    time.Minute,
+}
+
+// reset forgets the crashes recorded by earlier tests
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	subsystems = make(map[string]*subsystem)
+}
+
+func status(t *testing.T, name string) Status {
+	t.Helper()
+	for _, s := range Statuses() {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("No status for %s", name)
+	return Status{}
+}
+
+func TestRecover(t *testing.T) {
+	reset()
+
+	call := func() (err error) {
+		defer Recover("test.recover", &err)
+		panic("boom")
+	}
+
+	err := call()
+	if !errors.Is(err, ErrPanic) {
+		t.Fatalf("Expected a panic error, got %v", err)
+	}
+	if s := status(t, "test.recover"); s.Crashes != 1 || s.LastError != err.Error() {
+		t.Errorf("Unexpected status %+v", s)
+	}
+}
+
+func TestRun_RestartsAfterPanic(t *testing.T) {
+	reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	runs := 0
+	Run(ctx, "test.restart", testPolicy, func(ctx context.Context) error {
+		runs++
+		if runs < 3 {
+			panic("boom")
+		}
+		return nil
+	})
+
+	if runs != 3 {
+		t.Errorf("Expected 3 runs, got %d", runs)
+	}
+	s := status(t, "test.restart")
+	if s.Crashes != 2 || s.Restarts != 2 || s.CrashLoop {
+		t.Errorf("Unexpected status %+v", s)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	runs := 0
+	Run(ctx, "test.error", testPolicy, func(ctx context.Context) error {
+		runs++
+		return errors.New("failed")
+	})
+	if runs != 1 {
+		t.Errorf("Expected an error to stop the subsystem, got %d runs", runs)
+	}
+
+	policy := testPolicy
+	policy.RestartOnError = true
+	runs = 0
+	Run(ctx, "test.error_restart", policy, func(ctx context.Context) error {
+		runs++
+		if runs < 2 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	if runs != 2 {
+		t.Errorf("Expected an error to restart the subsystem, got %d runs", runs)
+	}
+}
+
+func TestRun_CrashLoop(t *testing.T) {
+	reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	runs := 0
+	Run(ctx, "test.loop", testPolicy, func(ctx context.Context) error {
+		runs++
+		if runs == 4 {
+			cancel()
+			<-ctx.Done()
+			return nil
+		}
+		panic("boom")
+	})
+
+	if s := status(t, "test.loop"); s.Crashes != 3 || !s.CrashLoop {
+		t.Errorf("Expected a crash loop, got %+v", s)
+	}
+
+	// Crashes outside the loop window no longer count
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Now().Add(2 * testPolicy.LoopWindow) }
+	if s := status(t, "test.loop"); s.CrashLoop {
+		t.Errorf("Expected the crash loop to end, got %+v", s)
+	}
+}
+
+func TestRun_CancelsRunContext(t *testing.T) {
+	reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var first context.Context
+	runs := 0
+	Run(ctx, "test.context", testPolicy, func(ctx context.Context) error {
+		runs++
+		if runs == 1 {
+			first = ctx
+			panic("boom")
+		}
+		return nil
+	})
+
+	if first.Err() == nil {
+		t.Error("Expected the crashed run's context to be cancelled")
+	}
+}
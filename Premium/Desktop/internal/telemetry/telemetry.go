@@ -0,0 +1,180 @@
+package telemetry
+
+import (
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+	"waddlebot-bridge/internal/modules"
+	"waddlebot-bridge/internal/obs"
+	"waddlebot-bridge/internal/supervisor"
+)
+
+// ProcessStats describes resource usage of the bridge process
+type ProcessStats struct {
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryRSS     uint64  `json:"memory_rss"`
+	HeapAlloc     uint64  `json:"heap_alloc"`
+	Goroutines    int     `json:"goroutines"`
+	UptimeSeconds int64   `json:"uptime_seconds"`
+}
+
+// OBSStatus describes the bridge's connection to OBS Studio
+type OBSStatus struct {
+	State      string `json:"state"`
+	OBSVersion string `json:"obs_version,omitempty"`
+}
+
+// ModuleSummary summarizes the health of loaded modules
+type ModuleSummary struct {
+	Total     int                    `json:"total"`
+	Enabled   int                    `json:"enabled"`
+	Unhealthy int                    `json:"unhealthy"`
+	Modules   []modules.ModuleHealth `json:"modules,omitempty"`
+}
+
+// Snapshot is the telemetry sent with each heartbeat
+type Snapshot struct {
+	Process ProcessStats        `json:"process"`
+	OBS     *OBSStatus          `json:"obs,omitempty"`
+	Modules ModuleSummary       `json:"modules"`
+	Queues  map[string]int      `json:"queues"`
+	Crashes []supervisor.Status `json:"crashes,omitempty"` // subsystems that crashed since startup
+}
+
+// OBSSource reports the OBS connection state
+type OBSSource interface {
+	GetConnectionInfo() obs.ConnectionInfo
+}
+
+// ModuleSource reports module health
+type ModuleSource interface {
+	GetModuleHealth() []modules.ModuleHealth
+}
+
+// Collector gathers telemetry from the bridge's components. Sources are
+// optional; missing ones are left out of the snapshot.
+type Collector struct {
+	mu        sync.RWMutex
+	startedAt time.Time
+	proc      *process.Process
+	obs       OBSSource
+	modules   ModuleSource
+	queues    map[string]func() int
+}
+
+// NewCollector creates a telemetry collector for the current process
+func NewCollector() *Collector {
+	c := &Collector{
+		startedAt: time.Now(),
+		queues:    make(map[string]func() int),
+	}
+
+	if proc, err := process.NewProcess(int32(os.Getpid())); err == nil {
+		c.proc = proc
+		// Prime the CPU counter so the first snapshot measures from here
+		proc.Percent(0)
+	}
+
+	return c
+}
+
+// SetOBS sets the OBS connection source
+func (c *Collector) SetOBS(source OBSSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.obs = source
+}
+
+// SetModules sets the module health source
+func (c *Collector) SetModules(source ModuleSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.modules = source
+}
+
+// AddQueue registers a named queue whose depth is reported
+func (c *Collector) AddQueue(name string, depth func() int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queues[name] = depth
+}
+
+// Collect takes a telemetry snapshot. CPU usage is averaged over the time
+// since the previous snapshot.
+func (c *Collector) Collect() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Process: c.processStats(),
+		Queues:  make(map[string]int, len(c.queues)),
+	}
+
+	if c.obs != nil {
+		info := c.obs.GetConnectionInfo()
+		snapshot.OBS = &OBSStatus{
+			State:      info.State.String(),
+			OBSVersion: info.OBSVersion,
+		}
+	}
+
+	if c.modules != nil {
+		snapshot.Modules = summarizeModules(c.modules.GetModuleHealth())
+	}
+
+	for name, depth := range c.queues {
+		snapshot.Queues[name] = depth()
+	}
+
+	snapshot.Crashes = supervisor.Statuses()
+
+	return snapshot
+}
+
+// processStats reads resource usage of the bridge process
+func (c *Collector) processStats() ProcessStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := ProcessStats{
+		HeapAlloc:     mem.HeapAlloc,
+		Goroutines:    runtime.NumGoroutine(),
+		UptimeSeconds: int64(time.Since(c.startedAt).Seconds()),
+	}
+
+	if c.proc != nil {
+		if percent, err := c.proc.Percent(0); err == nil {
+			stats.CPUPercent = percent
+		}
+		if info, err := c.proc.MemoryInfo(); err == nil {
+			stats.MemoryRSS = info.RSS
+		}
+	}
+
+	return stats
+}
+
+// summarizeModules counts enabled and unhealthy modules. Only unhealthy
+// modules are listed individually to keep heartbeats small.
+func summarizeModules(health []modules.ModuleHealth) ModuleSummary {
+	summary := ModuleSummary{Total: len(health)}
+	for _, module := range health {
+		if module.Enabled {
+			summary.Enabled++
+		}
+		if !module.Healthy {
+			summary.Unhealthy++
+			summary.Modules = append(summary.Modules, module)
+		}
+	}
+
+	sort.Slice(summary.Modules, func(i, j int) bool {
+		return summary.Modules[i].Name < summary.Modules[j].Name
+	})
+
+	return summary
+}
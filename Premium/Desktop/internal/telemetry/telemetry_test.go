@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"testing"
+
+	"waddlebot-bridge/internal/modules"
+	"waddlebot-bridge/internal/obs"
+)
+
+type fakeOBS struct{ state obs.ConnectionState }
+
+func (f fakeOBS) GetConnectionInfo() obs.ConnectionInfo {
+	return obs.ConnectionInfo{State: f.state, OBSVersion: "30.0.0"}
+}
+
+type fakeModules []modules.ModuleHealth
+
+func (f fakeModules) GetModuleHealth() []modules.ModuleHealth {
+	return f
+}
+
+func TestCollector_Collect(t *testing.T) {
+	collector := NewCollector()
+	collector.SetOBS(fakeOBS{state: obs.StateConnected})
+	collector.SetModules(fakeModules{
+		{Name: "soundboard", Enabled: true, Healthy: true},
+		{Name: "hotkeys", Enabled: true, Healthy: false, Failures: 2},
+		{Name: "fileops", Enabled: false, Healthy: false},
+	})
+	collector.AddQueue("outbox", func() int { return 3 })
+
+	snapshot := collector.Collect()
+
+	if snapshot.OBS == nil || snapshot.OBS.State != obs.StateConnected.String() {
+		t.Errorf("Expected OBS state %s, got %+v", obs.StateConnected, snapshot.OBS)
+	}
+	if snapshot.Modules.Total != 3 || snapshot.Modules.Enabled != 2 || snapshot.Modules.Unhealthy != 2 {
+		t.Errorf("Unexpected module summary: %+v", snapshot.Modules)
+	}
+	if len(snapshot.Modules.Modules) != 2 || snapshot.Modules.Modules[0].Name != "fileops" {
+		t.Errorf("Expected unhealthy modules sorted by name, got %+v", snapshot.Modules.Modules)
+	}
+	if snapshot.Queues["outbox"] != 3 {
+		t.Errorf("Expected outbox depth 3, got %d", snapshot.Queues["outbox"])
+	}
+	if snapshot.Process.Goroutines == 0 || snapshot.Process.HeapAlloc == 0 {
+		t.Errorf("Expected process stats, got %+v", snapshot.Process)
+	}
+}
+
+func TestCollector_OptionalSources(t *testing.T) {
+	snapshot := NewCollector().Collect()
+
+	if snapshot.OBS != nil {
+		t.Error("Expected no OBS status without a source")
+	}
+	if snapshot.Modules.Total != 0 {
+		t.Errorf("Expected empty module summary, got %+v", snapshot.Modules)
+	}
+	if snapshot.Queues == nil {
+		t.Error("Expected non-nil queue map")
+	}
+}
@@ -3,6 +3,7 @@ package testutils
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"waddlebot-bridge/internal/config"
@@ -12,25 +13,37 @@ import (
 
 // MockStorage implements the storage interface for testing
 type MockStorage struct {
-	data map[string][]byte
+	data    map[string][]byte
+	expires map[string]time.Time
 }
 
 // NewMockStorage creates a new mock storage instance
 func NewMockStorage() *MockStorage {
 	return &MockStorage{
-		data: make(map[string][]byte),
+		data:    make(map[string][]byte),
+		expires: make(map[string]time.Time),
 	}
 }
 
 // Set stores a value in mock storage
 func (m *MockStorage) Set(key string, value []byte) error {
 	m.data[key] = value
+	delete(m.expires, key)
+	return nil
+}
+
+// SetWithTTL stores a value that expires after ttl
+func (m *MockStorage) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	m.Set(key, value)
+	if ttl > 0 {
+		m.expires[key] = time.Now().Add(ttl)
+	}
 	return nil
 }
 
 // Get retrieves a value from mock storage
 func (m *MockStorage) Get(key string) ([]byte, error) {
-	if value, exists := m.data[key]; exists {
+	if value, exists := m.data[key]; exists && !m.expired(key) {
 		return value, nil
 	}
 	return nil, storage.ErrKeyNotFound
@@ -39,20 +52,21 @@ func (m *MockStorage) Get(key string) ([]byte, error) {
 // Delete removes a key from mock storage
 func (m *MockStorage) Delete(key string) error {
 	delete(m.data, key)
+	delete(m.expires, key)
 	return nil
 }
 
 // Exists checks if a key exists in mock storage
 func (m *MockStorage) Exists(key string) bool {
 	_, exists := m.data[key]
-	return exists
+	return exists && !m.expired(key)
 }
 
 // List returns all keys with a given prefix in mock storage
 func (m *MockStorage) List(prefix string) ([]string, error) {
 	var keys []string
 	for key := range m.data {
-		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix && !m.expired(key) {
 			keys = append(keys, key)
 		}
 	}
@@ -64,6 +78,11 @@ func (m *MockStorage) SetWithBucket(bucketName, key string, value []byte) error
 	return m.Set(bucketName+":"+key, value)
 }
 
+// SetWithBucketTTL stores a value in a named bucket that expires after ttl
+func (m *MockStorage) SetWithBucketTTL(bucketName, key string, value []byte, ttl time.Duration) error {
+	return m.SetWithTTL(bucketName+":"+key, value, ttl)
+}
+
 // GetWithBucket retrieves a value from a named bucket
 func (m *MockStorage) GetWithBucket(bucketName, key string) ([]byte, error) {
 	return m.Get(bucketName + ":" + key)
@@ -76,7 +95,11 @@ func (m *MockStorage) DeleteWithBucket(bucketName, key string) error {
 
 // ListWithBucket returns all keys in a bucket with a given prefix
 func (m *MockStorage) ListWithBucket(bucketName, prefix string) ([]string, error) {
-	return m.List(bucketName + ":" + prefix)
+	keys, err := m.List(bucketName + ":" + prefix)
+	for i, key := range keys {
+		keys[i] = key[len(bucketName)+1:]
+	}
+	return keys, err
 }
 
 // GetAllFromBucket retrieves all key-value pairs from a named bucket
@@ -84,7 +107,7 @@ func (m *MockStorage) GetAllFromBucket(bucketName string) (map[string][]byte, er
 	result := make(map[string][]byte)
 	bucketPrefix := bucketName + ":"
 	for key, value := range m.data {
-		if len(key) >= len(bucketPrefix) && key[:len(bucketPrefix)] == bucketPrefix {
+		if len(key) >= len(bucketPrefix) && key[:len(bucketPrefix)] == bucketPrefix && !m.expired(key) {
 			cleanKey := key[len(bucketPrefix):]
 			result[cleanKey] = value
 		}
@@ -92,6 +115,11 @@ func (m *MockStorage) GetAllFromBucket(bucketName string) (map[string][]byte, er
 	return result, nil
 }
 
+// EnsureBucket is a no-op; mock buckets exist implicitly
+func (m *MockStorage) EnsureBucket(bucketName string) error {
+	return nil
+}
+
 // ClearBucket removes all keys from a named bucket
 func (m *MockStorage) ClearBucket(bucketName string) error {
 	bucketPrefix := bucketName + ":"
@@ -103,6 +131,24 @@ func (m *MockStorage) ClearBucket(bucketName string) error {
 	return nil
 }
 
+// PurgeExpired removes expired keys from mock storage
+func (m *MockStorage) PurgeExpired() (int, error) {
+	purged := 0
+	for key := range m.expires {
+		if m.expired(key) {
+			m.Delete(key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// expired reports whether a key stored with a TTL has expired
+func (m *MockStorage) expired(key string) bool {
+	expiresAt, exists := m.expires[key]
+	return exists && !time.Now().Before(expiresAt)
+}
+
 // Close closes the mock storage
 func (m *MockStorage) Close() error {
 	return nil
@@ -291,6 +337,68 @@ func (m *MockWebAuthnManager) GetCurrentSession() *models.AuthSession {
 	return nil
 }
 
+// MockBridgeClient implements the bridge client token provider for testing
+type MockBridgeClient struct {
+	config    *config.Config
+	mu        sync.Mutex
+	authError bool
+}
+
+// NewMockBridgeClient creates a new mock bridge client
+func NewMockBridgeClient(cfg *config.Config) *MockBridgeClient {
+	return &MockBridgeClient{config: cfg}
+}
+
+// GetAuthToken returns a test token, or an error when auth errors are enabled
+func (m *MockBridgeClient) GetAuthToken() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.authError {
+		return "", fmt.Errorf("no authenticated session found")
+	}
+	return "test-token", nil
+}
+
+// SetAuthError makes GetAuthToken fail when enabled
+func (m *MockBridgeClient) SetAuthError(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.authError = enabled
+}
+
+// MockModuleManager implements module action execution for testing
+type MockModuleManager struct {
+	mu      sync.RWMutex
+	modules map[string]*MockModule
+}
+
+// NewMockModuleManager creates a new mock module manager
+func NewMockModuleManager() *MockModuleManager {
+	return &MockModuleManager{
+		modules: make(map[string]*MockModule),
+	}
+}
+
+// AddModule registers a mock module under the given name
+func (m *MockModuleManager) AddModule(name string, module *MockModule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.modules[name] = module
+}
+
+// ExecuteAction executes an action on a registered mock module
+func (m *MockModuleManager) ExecuteAction(ctx context.Context, moduleName, action string, parameters map[string]string) (map[string]interface{}, error) {
+	m.mu.RLock()
+	module, exists := m.modules[moduleName]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("module not found: %s", moduleName)
+	}
+	return module.ExecuteAction(ctx, action, parameters)
+}
+
 // TestConfig creates a test configuration
 func TestConfig() *config.Config {
 	return &config.Config{
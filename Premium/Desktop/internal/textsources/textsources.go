@@ -0,0 +1,576 @@
+// Package textsources keeps OBS text inputs up to date from templates. A
+// text source binds a text input to a template such as
+// "Deaths: {{.deaths}}" or "Starting in {{until .start}}", rendered against
+// variables that are set through the local gateway and scripts. Sources
+// are updated whenever a variable changes, and templates that show the time
+// are refreshed at an interval. Sources and variables are persisted in
+// storage.
+package textsources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/storage"
+)
+
+// Storage key prefixes of sources and variables
+const (
+	sourcePrefix   = "source:"
+	variablePrefix = "var:"
+)
+
+// updateTimeout bounds setting the text of an input
+const updateTimeout = 5 * time.Second
+
+var (
+	// ErrSourceNotFound is returned for an unknown text source ID
+	ErrSourceNotFound = errors.New("text source not found")
+
+	// ErrInvalidSource is returned for a text source that cannot be shown
+	ErrInvalidSource = errors.New("invalid text source")
+
+	// ErrVariableNotFound is returned for an unset variable
+	ErrVariableNotFound = errors.New("variable not found")
+
+	// ErrInvalidVariable is returned for a bad variable name, or for
+	// incrementing a variable that is not a number
+	ErrInvalidVariable = errors.New("invalid variable")
+)
+
+// variableName is what variable names may contain
+var variableName = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Source binds an OBS text input to a template
+type Source struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Input     string    `json:"input"`    // OBS text input (GDI+ or FreeType 2) to update
+	Template  string    `json:"template"` // Go template rendered against the variables
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OBS is the part of the OBS client text sources update
+type OBS interface {
+	IsConnected() bool
+	SetInputText(ctx context.Context, inputName, text string) error
+}
+
+// Timers formats the timers shown with the timer template function
+type Timers interface {
+	Format(name string) (string, error)
+}
+
+// shownText is what was last done with a source
+type shownText struct {
+	text  string // text the input was set to
+	timed bool   // the template shows the time, so is refreshed at the interval
+	err   string // last error rendering or setting the text, logged once
+}
+
+// Manager renders the enabled text sources into their OBS inputs
+type Manager struct {
+	store  *storage.TextSourceRepo
+	obs    OBS
+	cfg    config.TextSourcesConfig
+	logger *logrus.Logger
+
+	mu      sync.RWMutex
+	timers  Timers // nil until SetTimers
+	sources map[string]*Source
+	vars    map[string]string
+	shown   map[string]*shownText
+
+	changed chan struct{} // wakes Run after a change
+}
+
+// NewManager creates a manager with the sources and variables persisted in
+// store
+func NewManager(store storage.Storage, obsClient OBS, cfg config.TextSourcesConfig, logger *logrus.Logger) (*Manager, error) {
+	repo, err := storage.NewTextSourceRepo(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create text sources bucket: %w", err)
+	}
+
+	m := &Manager{
+		store:   repo,
+		obs:     obsClient,
+		cfg:     cfg,
+		logger:  logger,
+		sources: make(map[string]*Source),
+		vars:    make(map[string]string),
+		shown:   make(map[string]*shownText),
+		changed: make(chan struct{}, 1),
+	}
+
+	stored, err := repo.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load text sources: %w", err)
+	}
+	for key, data := range stored {
+		switch {
+		case strings.HasPrefix(key, sourcePrefix):
+			var source Source
+			if err := json.Unmarshal(data, &source); err != nil {
+				logger.WithError(err).WithField("key", key).Warn("Skipping unreadable text source")
+				continue
+			}
+			m.sources[source.ID] = &source
+		case strings.HasPrefix(key, variablePrefix):
+			m.vars[strings.TrimPrefix(key, variablePrefix)] = string(data)
+		}
+	}
+
+	if len(m.sources) > 0 {
+		logger.WithField("count", len(m.sources)).Info("Loaded text sources")
+	}
+	return m, nil
+}
+
+// SetTimers lets templates show timers with the timer function
+func (m *Manager) SetTimers(timers Timers) {
+	m.mu.Lock()
+	m.timers = timers
+	m.mu.Unlock()
+	m.notify()
+}
+
+// List returns the text sources ordered by creation time
+func (m *Manager) List() []Source {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Source, 0, len(m.sources))
+	for _, source := range m.sources {
+		list = append(list, *source)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Get returns a text source
+func (m *Manager) Get(id string) (Source, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	source, exists := m.sources[id]
+	if !exists {
+		return Source{}, fmt.Errorf("%w: %s", ErrSourceNotFound, id)
+	}
+	return *source, nil
+}
+
+// Create validates and persists a new text source
+func (m *Manager) Create(source Source) (Source, error) {
+	if err := Validate(source); err != nil {
+		return Source{}, err
+	}
+
+	source.ID = "text_" + uuid.NewString()
+	source.CreatedAt = time.Now()
+	source.UpdatedAt = source.CreatedAt
+	if err := m.save(&source); err != nil {
+		return Source{}, err
+	}
+
+	m.mu.Lock()
+	m.sources[source.ID] = &source
+	m.mu.Unlock()
+	m.notify()
+	return source, nil
+}
+
+// Update replaces a text source
+func (m *Manager) Update(id string, source Source) (Source, error) {
+	existing, err := m.Get(id)
+	if err != nil {
+		return Source{}, err
+	}
+	if err := Validate(source); err != nil {
+		return Source{}, err
+	}
+
+	source.ID = id
+	source.CreatedAt = existing.CreatedAt
+	source.UpdatedAt = time.Now()
+	if err := m.save(&source); err != nil {
+		return Source{}, err
+	}
+
+	m.mu.Lock()
+	m.sources[id] = &source
+	delete(m.shown, id)
+	m.mu.Unlock()
+	m.notify()
+	return source, nil
+}
+
+// Remove deletes a text source. The input keeps the text it last showed.
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	if _, exists := m.sources[id]; !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrSourceNotFound, id)
+	}
+	delete(m.sources, id)
+	delete(m.shown, id)
+	m.mu.Unlock()
+
+	if err := m.store.Delete(sourcePrefix + id); err != nil {
+		return fmt.Errorf("failed to delete text source: %w", err)
+	}
+	return nil
+}
+
+// save persists a text source
+func (m *Manager) save(source *Source) error {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to marshal text source: %w", err)
+	}
+	if err := m.store.Set(sourcePrefix+source.ID, data); err != nil {
+		return fmt.Errorf("failed to store text source: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that a text source can be shown
+func Validate(source Source) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidSource, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(source.Name) == "" {
+		return invalid("name is required")
+	}
+	if source.Input == "" {
+		return invalid("input is required")
+	}
+	if _, err := check(source.Template); err != nil {
+		return invalid("%v", err)
+	}
+	return nil
+}
+
+// Variables returns the variables and their values
+func (m *Manager) Variables() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	vars := make(map[string]string, len(m.vars))
+	for name, value := range m.vars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// Variable returns the value of a variable
+func (m *Manager) Variable(name string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, exists := m.vars[name]
+	return value, exists
+}
+
+// SetVariable sets and persists a variable, updating the sources that
+// show it
+func (m *Manager) SetVariable(name, value string) error {
+	if !variableName.MatchString(name) {
+		return fmt.Errorf("%w: name %q must be 1 to 64 letters, digits, '_', '.' or '-'", ErrInvalidVariable, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.setVariable(name, value)
+}
+
+// Increment adds by to a numeric variable and returns its new value. An
+// unset variable counts from 0.
+func (m *Manager) Increment(name string, by int64) (int64, error) {
+	if !variableName.MatchString(name) {
+		return 0, fmt.Errorf("%w: name %q must be 1 to 64 letters, digits, '_', '.' or '-'", ErrInvalidVariable, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var current int64
+	if value, exists := m.vars[name]; exists && value != "" {
+		parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s is %q, not a whole number", ErrInvalidVariable, name, value)
+		}
+		current = parsed
+	}
+
+	current += by
+	if err := m.setVariable(name, strconv.FormatInt(current, 10)); err != nil {
+		return 0, err
+	}
+	return current, nil
+}
+
+// setVariable stores a variable. The caller holds m.mu.
+func (m *Manager) setVariable(name, value string) error {
+	if err := m.store.Set(variablePrefix+name, []byte(value)); err != nil {
+		return fmt.Errorf("failed to store variable: %w", err)
+	}
+	m.vars[name] = value
+	m.notify()
+	return nil
+}
+
+// DeleteVariable removes a variable
+func (m *Manager) DeleteVariable(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.vars[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrVariableNotFound, name)
+	}
+	if err := m.store.Delete(variablePrefix + name); err != nil {
+		return fmt.Errorf("failed to delete variable: %w", err)
+	}
+	delete(m.vars, name)
+	m.notify()
+	return nil
+}
+
+// Preview renders a template against the current variables
+func (m *Manager) Preview(text string) (string, error) {
+	m.mu.RLock()
+	timers := m.timers
+	m.mu.RUnlock()
+
+	rendered, _, err := render(text, m.Variables(), timers, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	return rendered, nil
+}
+
+// notify wakes Run to update the sources
+func (m *Manager) notify() {
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run keeps the text inputs up to date until ctx is done
+func (m *Manager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	m.update(ctx, false)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.changed:
+			m.update(ctx, false)
+		case <-ticker.C:
+			m.update(ctx, true)
+		}
+	}
+}
+
+// update renders the enabled sources and sets the inputs whose text
+// changed. With timedOnly only the templates that show the time are
+// rendered.
+func (m *Manager) update(ctx context.Context, timedOnly bool) {
+	if !m.obs.IsConnected() {
+		// OBS may have restarted without the text; set it all again once
+		// it is back
+		m.mu.Lock()
+		m.shown = make(map[string]*shownText)
+		m.mu.Unlock()
+		return
+	}
+
+	m.mu.RLock()
+	var sources []Source
+	for _, source := range m.sources {
+		if !source.Enabled {
+			continue
+		}
+		if shown, ok := m.shown[source.ID]; timedOnly && (!ok || !shown.timed) {
+			continue
+		}
+		sources = append(sources, *source)
+	}
+	vars := make(map[string]string, len(m.vars))
+	for name, value := range m.vars {
+		vars[name] = value
+	}
+	timers := m.timers
+	m.mu.RUnlock()
+
+	now := time.Now()
+	for _, source := range sources {
+		if ctx.Err() != nil {
+			return
+		}
+		m.show(ctx, source, vars, timers, now)
+	}
+}
+
+// show renders a source and sets its input when the text changed
+func (m *Manager) show(ctx context.Context, source Source, vars map[string]string, timers Timers, now time.Time) {
+	text, timed, err := render(source.Template, vars, timers, now)
+
+	m.mu.Lock()
+	shown, ok := m.shown[source.ID]
+	if !ok {
+		shown = &shownText{}
+		m.shown[source.ID] = shown
+	}
+	shown.timed = timed
+	unchanged := err == nil && ok && shown.text == text && shown.err == ""
+	m.mu.Unlock()
+	if unchanged {
+		return
+	}
+
+	if err == nil {
+		setCtx, cancel := context.WithTimeout(ctx, updateTimeout)
+		err = m.obs.SetInputText(setCtx, source.Input, text)
+		cancel()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		if shown.err != err.Error() {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"source": source.Name,
+				"input":  source.Input,
+			}).Warn("Failed to update text source")
+		}
+		shown.err = err.Error()
+		return
+	}
+	shown.text = text
+	shown.err = ""
+}
+
+// render executes a template against the variables at now, reporting
+// whether it used the time. timers may be nil.
+func render(text string, vars map[string]string, timers Timers, now time.Time) (string, bool, error) {
+	timed := false
+	tmpl, err := parse(text, templateFuncs(vars, timers, now, &timed))
+	if err != nil {
+		return "", false, err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", timed, err
+	}
+	return out.String(), timed, nil
+}
+
+// check parses a template to find errors in it
+func check(text string) (*template.Template, error) {
+	return parse(text, templateFuncs(nil, nil, time.Now(), new(bool)))
+}
+
+// parse parses a template calling funcs
+func parse(text string, funcs template.FuncMap) (*template.Template, error) {
+	return template.New("text").Option("missingkey=zero").Funcs(funcs).Parse(text)
+}
+
+// templateFuncs returns the functions templates can call. Those showing
+// the time set timed.
+func templateFuncs(vars map[string]string, timers Timers, now time.Time, timed *bool) template.FuncMap {
+	return template.FuncMap{
+		// var returns a variable whose name is not a valid field name
+		"var": func(name string) string {
+			return vars[name]
+		},
+		// now formats the current time, as 15:04 by default
+		"now": func(layout ...string) string {
+			*timed = true
+			if len(layout) == 0 {
+				return now.Format("15:04")
+			}
+			return now.Format(layout[0])
+		},
+		// until counts down to a time, stopping at 0:00
+		"until": func(at string) (string, error) {
+			*timed = true
+			target, err := parseTime(at, now)
+			if err != nil {
+				return "", err
+			}
+			return FormatDuration(target.Sub(now)), nil
+		},
+		// since counts up from a time
+		"since": func(at string) (string, error) {
+			*timed = true
+			start, err := parseTime(at, now)
+			if err != nil {
+				return "", err
+			}
+			return FormatDuration(now.Sub(start)), nil
+		},
+		// timer shows a countdown or stopwatch
+		"timer": func(name string) (string, error) {
+			*timed = true
+			if timers == nil {
+				return "", errors.New("timers are not available")
+			}
+			return timers.Format(name)
+		},
+	}
+}
+
+// parseTime parses an RFC 3339 time, or a clock time such as 20:30. A
+// clock time is today's, or tomorrow's once today's is more than 12 hours
+// past, so a countdown to it rests at 0:00 for the evening.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	clock, err := time.ParseInLocation("15:04", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a clock time such as 20:30", value)
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if now.Sub(at) > 12*time.Hour {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, nil
+}
+
+// FormatDuration formats a duration as a clock, such as 4:05 or 1:04:05.
+// Negative durations show as 0:00.
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	seconds := int64(d.Round(time.Second) / time.Second)
+	hours, minutes := seconds/3600, seconds/60%60
+	seconds %= 60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
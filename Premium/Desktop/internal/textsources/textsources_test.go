@@ -0,0 +1,200 @@
+package textsources
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/testutils"
+)
+
+type fakeOBS struct {
+	connected bool
+	texts     map[string]string
+	sets      int
+}
+
+func (f *fakeOBS) IsConnected() bool { return f.connected }
+
+func (f *fakeOBS) SetInputText(ctx context.Context, inputName, text string) error {
+	f.texts[inputName] = text
+	f.sets++
+	return nil
+}
+
+func newTestManager(t *testing.T, store storage.Storage) (*Manager, *fakeOBS) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	obsClient := &fakeOBS{connected: true, texts: make(map[string]string)}
+	m, err := NewManager(store, obsClient, config.TextSourcesConfig{Enabled: true, Interval: time.Second}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	return m, obsClient
+}
+
+func TestRender(t *testing.T) {
+	now := time.Date(2026, 3, 2, 19, 45, 0, 0, time.UTC)
+	vars := map[string]string{"song": "Blue Monday", "last-follower": "penguin"}
+
+	tests := []struct {
+		template string
+		want     string
+		timed    bool
+	}{
+		{"Now playing: {{.song}}", "Now playing: Blue Monday", false},
+		{"{{or .artist \"Unknown\"}}", "Unknown", false},
+		{"Thanks {{var \"last-follower\"}}!", "Thanks penguin!", false},
+		{"It is {{now}}", "It is 19:45", true},
+		{"Starting in {{until \"2026-03-02T20:00:00Z\"}}", "Starting in 15:00", true},
+		{"{{until \"20:00\"}}", "15:00", true},
+		{"{{until \"19:00\"}}", "0:00", true},
+		{"Live for {{since \"2026-03-02T18:40:30Z\"}}", "Live for 1:04:30", true},
+	}
+	for _, tt := range tests {
+		got, timed, err := render(tt.template, vars, nil, now)
+		if err != nil {
+			t.Errorf("render(%q) failed: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want || timed != tt.timed {
+			t.Errorf("render(%q) = %q, timed %v; want %q, timed %v", tt.template, got, timed, tt.want, tt.timed)
+		}
+	}
+
+	if _, _, err := render("{{until \"soon\"}}", vars, nil, now); err == nil {
+		t.Error("Expected an unparseable time to fail")
+	}
+}
+
+type fakeTimers map[string]string
+
+func (f fakeTimers) Format(name string) (string, error) {
+	text, exists := f[name]
+	if !exists {
+		return "", errors.New("timer not found")
+	}
+	return text, nil
+}
+
+func TestRender_Timer(t *testing.T) {
+	timers := fakeTimers{"starting": "4:59"}
+
+	text, timed, err := render("Starting in {{timer \"starting\"}}", nil, timers, time.Now())
+	if err != nil || text != "Starting in 4:59" || !timed {
+		t.Errorf("Expected the timer to be shown and refreshed, got %q, timed %v, %v", text, timed, err)
+	}
+	if _, _, err := render("{{timer \"missing\"}}", nil, timers, time.Now()); err == nil {
+		t.Error("Expected an unknown timer to fail")
+	}
+	if _, _, err := render("{{timer \"starting\"}}", nil, nil, time.Now()); err == nil {
+		t.Error("Expected the timer function to fail without timers")
+	}
+}
+
+func TestManager_Update(t *testing.T) {
+	m, obsClient := newTestManager(t, testutils.NewMockStorage())
+	ctx := context.Background()
+
+	if _, err := m.Create(Source{Name: "Deaths", Input: "Deaths Text", Template: "Deaths: {{or .deaths \"0\"}}", Enabled: true}); err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	if _, err := m.Create(Source{Name: "Clock", Input: "Clock Text", Template: "{{now \"15:04:05\"}}", Enabled: true}); err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+
+	m.update(ctx, false)
+	if obsClient.texts["Deaths Text"] != "Deaths: 0" || obsClient.texts["Clock Text"] == "" || obsClient.sets != 2 {
+		t.Fatalf("Expected both inputs to be set, got %v", obsClient.texts)
+	}
+
+	// Unchanged text is not sent again; only the clock may have ticked
+	m.update(ctx, false)
+	if obsClient.sets > 3 {
+		t.Errorf("Expected unchanged text not to be sent again, got %d sets", obsClient.sets)
+	}
+
+	value, err := m.Increment("deaths", 2)
+	if err != nil || value != 2 {
+		t.Fatalf("Expected deaths to be 2, got %d, %v", value, err)
+	}
+	m.update(ctx, true)
+	if obsClient.texts["Deaths Text"] != "Deaths: 0" {
+		t.Errorf("Expected the interval to leave the deaths counter, got %q", obsClient.texts["Deaths Text"])
+	}
+	m.update(ctx, false)
+	if obsClient.texts["Deaths Text"] != "Deaths: 2" {
+		t.Errorf("Expected the deaths counter to be updated, got %q", obsClient.texts["Deaths Text"])
+	}
+
+	// Once OBS is back the text is set again
+	obsClient.connected = false
+	m.update(ctx, false)
+	obsClient.connected = true
+	obsClient.texts = make(map[string]string)
+	m.update(ctx, true)
+	m.update(ctx, false)
+	if obsClient.texts["Deaths Text"] != "Deaths: 2" {
+		t.Errorf("Expected the text to be set again after reconnecting, got %v", obsClient.texts)
+	}
+}
+
+func TestManager_Variables(t *testing.T) {
+	store := testutils.NewMockStorage()
+	m, _ := newTestManager(t, store)
+
+	if err := m.SetVariable("song", "Blue Monday"); err != nil {
+		t.Fatalf("Failed to set variable: %v", err)
+	}
+	if err := m.SetVariable("bad name", "x"); !errors.Is(err, ErrInvalidVariable) {
+		t.Errorf("Expected ErrInvalidVariable for a name with a space, got %v", err)
+	}
+	if _, err := m.Increment("song", 1); !errors.Is(err, ErrInvalidVariable) {
+		t.Errorf("Expected ErrInvalidVariable incrementing text, got %v", err)
+	}
+	if err := m.DeleteVariable("missing"); !errors.Is(err, ErrVariableNotFound) {
+		t.Errorf("Expected ErrVariableNotFound, got %v", err)
+	}
+
+	if _, err := m.Create(Source{Name: "Bad", Input: "Text", Template: "{{.song"}); !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("Expected ErrInvalidSource for an unclosed action, got %v", err)
+	}
+	source, err := m.Create(Source{Name: "Song", Input: "Song Text", Template: "{{.song}}", Enabled: true})
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+
+	reloaded, _ := newTestManager(t, store)
+	if value, _ := reloaded.Variable("song"); value != "Blue Monday" {
+		t.Errorf("Expected the variable to be persisted, got %q", value)
+	}
+	if _, err := reloaded.Get(source.ID); err != nil {
+		t.Errorf("Expected the source to be persisted: %v", err)
+	}
+	if text, err := reloaded.Preview("♪ {{.song}}"); err != nil || text != "♪ Blue Monday" {
+		t.Errorf("Expected the preview to render, got %q, %v", text, err)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Second:                  "0:00",
+		59 * time.Second:              "0:59",
+		5*time.Minute + 4*time.Second: "5:04",
+		2*time.Hour + 3*time.Second:   "2:00:03",
+		1500 * time.Millisecond:       "0:02",
+	}
+	for d, want := range tests {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
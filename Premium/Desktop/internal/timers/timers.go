@@ -0,0 +1,417 @@
+// Package timers keeps countdowns and stopwatches, such as the "starting
+// soon" countdown, that can be started, paused and given more time from
+// the local gateway and scripts. Their formatted value drives OBS text
+// sources through the timer template function and is sent to WebSocket
+// overlays as it changes. Timers are persisted in storage, so a running
+// timer keeps counting across restarts.
+package timers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/textsources"
+)
+
+// Timer modes
+const (
+	ModeCountdown = "countdown"
+	ModeStopwatch = "stopwatch"
+)
+
+// Timer actions, as taken by Control
+const (
+	ActionStart = "start"
+	ActionPause = "pause"
+	ActionReset = "reset"
+	ActionAdd   = "add"
+)
+
+// EventFinished is published on the bus when a countdown reaches zero
+const EventFinished = "timers.finished"
+
+// tickInterval is how often running timers are checked. It is well under
+// a second so the shown value never skips a second.
+const tickInterval = 200 * time.Millisecond
+
+var (
+	// ErrTimerNotFound is returned for an unknown timer name
+	ErrTimerNotFound = errors.New("timer not found")
+
+	// ErrTimerExists is returned creating a timer whose name is taken
+	ErrTimerExists = errors.New("timer already exists")
+
+	// ErrInvalidTimer is returned for a timer or action that cannot be run
+	ErrInvalidTimer = errors.New("invalid timer")
+)
+
+// timerName is what timer names may contain
+var timerName = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Timer is a countdown or stopwatch
+type Timer struct {
+	Name     string  `json:"name"`
+	Mode     string  `json:"mode"`
+	Duration float64 `json:"duration_seconds,omitempty"` // length of a countdown
+	// FinishedText is shown instead of 0:00 once a countdown is done
+	FinishedText string `json:"finished_text,omitempty"`
+
+	// Elapsed is the time counted before StartedAt, which is set while
+	// the timer runs
+	Elapsed   float64    `json:"elapsed_seconds"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Finished  bool       `json:"finished"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Status is a timer and its value at a moment
+type Status struct {
+	Timer
+	Running bool    `json:"running"`
+	Value   float64 `json:"value_seconds"` // time left of a countdown, or counted by a stopwatch
+	Text    string  `json:"text"`          // value as a clock, such as 4:05
+}
+
+// elapsed returns the time the timer has counted at now
+func (t *Timer) elapsed(now time.Time) time.Duration {
+	elapsed := seconds(t.Elapsed)
+	if t.StartedAt != nil {
+		elapsed += now.Sub(*t.StartedAt)
+	}
+	return elapsed
+}
+
+// status returns the timer's value at now
+func (t *Timer) status(now time.Time) Status {
+	value := t.elapsed(now)
+	if t.Mode == ModeCountdown {
+		value = seconds(t.Duration) - value
+		if value < 0 {
+			value = 0
+		}
+	}
+
+	text := textsources.FormatDuration(value)
+	if t.Mode == ModeCountdown && t.Finished && t.FinishedText != "" {
+		text = t.FinishedText
+	}
+	return Status{
+		Timer:   *t,
+		Running: t.StartedAt != nil,
+		Value:   value.Seconds(),
+		Text:    text,
+	}
+}
+
+// seconds converts seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Manager keeps the timers and tells subscribers as their values change
+type Manager struct {
+	store     *storage.TimerRepo
+	publisher events.Publisher // nil when the event bus is disabled
+	logger    *logrus.Logger
+
+	mu          sync.RWMutex
+	timers      map[string]*Timer
+	shown       map[string]string // text last sent to subscribers
+	subscribers []func([]Status)
+}
+
+// NewManager creates a manager with the timers persisted in store
+func NewManager(store storage.Storage, logger *logrus.Logger) (*Manager, error) {
+	repo, err := storage.NewTimerRepo(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timers bucket: %w", err)
+	}
+
+	m := &Manager{
+		store:  repo,
+		logger: logger,
+		timers: make(map[string]*Timer),
+		shown:  make(map[string]string),
+	}
+
+	stored, err := repo.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timers: %w", err)
+	}
+	for name, data := range stored {
+		var timer Timer
+		if err := json.Unmarshal(data, &timer); err != nil {
+			logger.WithError(err).WithField("name", name).Warn("Skipping unreadable timer")
+			continue
+		}
+		m.timers[timer.Name] = &timer
+	}
+
+	if len(m.timers) > 0 {
+		logger.WithField("count", len(m.timers)).Info("Loaded timers")
+	}
+	return m, nil
+}
+
+// SetEventPublisher sets where timers.finished is published
+func (m *Manager) SetEventPublisher(publisher events.Publisher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publisher = publisher
+}
+
+// Subscribe calls fn with the timers whose shown value changed, as they
+// change
+func (m *Manager) Subscribe(fn func([]Status)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// List returns the timers ordered by creation time
+func (m *Manager) List() []Status {
+	now := time.Now()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Status, 0, len(m.timers))
+	for _, timer := range m.timers {
+		list = append(list, timer.status(now))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Get returns a timer
+func (m *Manager) Get(name string) (Status, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	timer, exists := m.timers[name]
+	if !exists {
+		return Status{}, fmt.Errorf("%w: %s", ErrTimerNotFound, name)
+	}
+	return timer.status(time.Now()), nil
+}
+
+// Format returns a timer's value as shown, for the timer template function
+func (m *Manager) Format(name string) (string, error) {
+	status, err := m.Get(name)
+	if err != nil {
+		return "", err
+	}
+	return status.Text, nil
+}
+
+// Create validates and persists a new, stopped timer
+func (m *Manager) Create(timer Timer) (Status, error) {
+	if !timerName.MatchString(timer.Name) {
+		return Status{}, fmt.Errorf("%w: name %q must be 1 to 64 letters, digits, '_', '.' or '-'", ErrInvalidTimer, timer.Name)
+	}
+	switch timer.Mode {
+	case ModeCountdown:
+		if timer.Duration <= 0 {
+			return Status{}, fmt.Errorf("%w: a countdown needs a positive duration_seconds", ErrInvalidTimer)
+		}
+	case ModeStopwatch:
+		timer.Duration = 0
+		timer.FinishedText = ""
+	default:
+		return Status{}, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidTimer, ModeCountdown, ModeStopwatch)
+	}
+
+	timer.Elapsed = 0
+	timer.StartedAt = nil
+	timer.Finished = false
+	timer.CreatedAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.timers[timer.Name]; exists {
+		return Status{}, fmt.Errorf("%w: %s", ErrTimerExists, timer.Name)
+	}
+	if err := m.save(&timer); err != nil {
+		return Status{}, err
+	}
+	m.timers[timer.Name] = &timer
+	return m.changed(&timer, time.Now()), nil
+}
+
+// Remove deletes a timer
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.timers[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrTimerNotFound, name)
+	}
+	if err := m.store.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete timer: %w", err)
+	}
+	delete(m.timers, name)
+	delete(m.shown, name)
+	return nil
+}
+
+// Control starts, pauses, resets or adds time to a timer. amount is the
+// time added by ActionAdd, which may be negative. Adding time to a
+// countdown lengthens it; a finished countdown waits to be started again.
+func (m *Manager) Control(name, action string, amount time.Duration) (Status, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	timer, exists := m.timers[name]
+	if !exists {
+		return Status{}, fmt.Errorf("%w: %s", ErrTimerNotFound, name)
+	}
+
+	updated := *timer
+	switch action {
+	case ActionStart:
+		if updated.StartedAt != nil {
+			return timer.status(now), nil
+		}
+		if updated.Finished || (updated.Mode == ModeCountdown && updated.Elapsed >= updated.Duration) {
+			updated.Elapsed = 0
+			updated.Finished = false
+		}
+		updated.StartedAt = &now
+	case ActionPause:
+		if updated.StartedAt == nil {
+			return timer.status(now), nil
+		}
+		updated.Elapsed = updated.elapsed(now).Seconds()
+		updated.StartedAt = nil
+	case ActionReset:
+		updated.Elapsed = 0
+		updated.StartedAt = nil
+		updated.Finished = false
+	case ActionAdd:
+		if updated.Mode == ModeCountdown {
+			updated.Duration += amount.Seconds()
+			if updated.Duration < 0 {
+				updated.Duration = 0
+			}
+			if updated.Finished && amount > 0 {
+				// The added time is left, once started again
+				updated.Finished = false
+			}
+		} else {
+			updated.Elapsed += amount.Seconds()
+			if elapsed := updated.elapsed(now); elapsed < 0 {
+				updated.Elapsed -= elapsed.Seconds()
+			}
+		}
+	default:
+		return Status{}, fmt.Errorf("%w: unknown action %q", ErrInvalidTimer, action)
+	}
+
+	if err := m.save(&updated); err != nil {
+		return Status{}, err
+	}
+	*timer = updated
+	return m.changed(timer, now), nil
+}
+
+// save persists a timer. The caller holds m.mu.
+func (m *Manager) save(timer *Timer) error {
+	data, err := json.Marshal(timer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal timer: %w", err)
+	}
+	if err := m.store.Set(timer.Name, data); err != nil {
+		return fmt.Errorf("failed to store timer: %w", err)
+	}
+	return nil
+}
+
+// changed tells the subscribers about a timer that was changed, and
+// returns its status. The caller holds m.mu.
+func (m *Manager) changed(timer *Timer, now time.Time) Status {
+	status := timer.status(now)
+	m.shown[timer.Name] = status.Text
+	m.notify([]Status{status})
+	return status
+}
+
+// notify calls the subscribers. The caller holds m.mu, so they must not
+// call back into the manager.
+func (m *Manager) notify(statuses []Status) {
+	for _, fn := range m.subscribers {
+		fn(statuses)
+	}
+}
+
+// Run finishes countdowns that reach zero and sends the running timers'
+// values to subscribers as they change, until ctx is done
+func (m *Manager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			m.tick(now)
+		}
+	}
+}
+
+// tick checks the running timers at now
+func (m *Manager) tick(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var changed, finished []Status
+	for _, timer := range m.timers {
+		if timer.StartedAt == nil {
+			continue
+		}
+
+		if timer.Mode == ModeCountdown && timer.elapsed(now) >= seconds(timer.Duration) {
+			timer.Elapsed = timer.Duration
+			timer.StartedAt = nil
+			timer.Finished = true
+			if err := m.save(timer); err != nil {
+				m.logger.WithError(err).WithField("timer", timer.Name).Warn("Failed to store finished timer")
+			}
+			finished = append(finished, timer.status(now))
+		}
+
+		status := timer.status(now)
+		if m.shown[timer.Name] == status.Text && !status.Finished {
+			continue
+		}
+		m.shown[timer.Name] = status.Text
+		changed = append(changed, status)
+	}
+
+	if len(changed) > 0 {
+		m.notify(changed)
+	}
+	for _, status := range finished {
+		m.logger.WithField("timer", status.Name).Info("Countdown finished")
+		if m.publisher != nil {
+			m.publisher.Publish(events.Event{
+				Type:   EventFinished,
+				Source: events.SourceBridge,
+				Data: map[string]interface{}{
+					"name":             status.Name,
+					"duration_seconds": status.Duration,
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package timers
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/storage"
+	"waddlebot-bridge/internal/testutils"
+)
+
+type recorder struct{ events []events.Event }
+
+func (r *recorder) Publish(event events.Event) { r.events = append(r.events, event) }
+
+func newTestManager(t *testing.T, store storage.Storage) *Manager {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	m, err := NewManager(store, logger)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	return m
+}
+
+// rewind moves a running timer's start back, as if it had run for d
+func rewind(m *Manager, name string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	started := m.timers[name].StartedAt.Add(-d)
+	m.timers[name].StartedAt = &started
+}
+
+func TestManager_Countdown(t *testing.T) {
+	m := newTestManager(t, testutils.NewMockStorage())
+	bus := &recorder{}
+	m.SetEventPublisher(bus)
+	var ticks []Status
+	m.Subscribe(func(statuses []Status) { ticks = append(ticks, statuses...) })
+
+	status, err := m.Create(Timer{Name: "starting", Mode: ModeCountdown, Duration: 300, FinishedText: "Starting now!"})
+	if err != nil {
+		t.Fatalf("Failed to create timer: %v", err)
+	}
+	if status.Text != "5:00" || status.Running {
+		t.Errorf("Expected a stopped 5:00 countdown, got %+v", status)
+	}
+	if _, err := m.Create(Timer{Name: "starting", Mode: ModeCountdown, Duration: 60}); !errors.Is(err, ErrTimerExists) {
+		t.Errorf("Expected ErrTimerExists, got %v", err)
+	}
+
+	m.Control("starting", ActionStart, 0)
+	rewind(m, "starting", 90*time.Second)
+	status, _ = m.Control("starting", ActionPause, 0)
+	if status.Running || status.Text != "3:30" {
+		t.Errorf("Expected a paused 3:30 countdown, got %+v", status)
+	}
+
+	status, _ = m.Control("starting", ActionAdd, time.Minute)
+	if status.Text != "4:30" || status.Duration != 360 {
+		t.Errorf("Expected a minute to be added, got %+v", status)
+	}
+
+	ticks = nil
+	m.Control("starting", ActionStart, 0)
+	rewind(m, "starting", 271*time.Second)
+	m.tick(time.Now())
+	status, _ = m.Get("starting")
+	if !status.Finished || status.Running || status.Text != "Starting now!" {
+		t.Errorf("Expected the countdown to finish, got %+v", status)
+	}
+	if len(bus.events) != 1 || bus.events[0].Type != EventFinished {
+		t.Errorf("Expected a %s event, got %+v", EventFinished, bus.events)
+	}
+	if len(ticks) < 2 || ticks[len(ticks)-1].Text != "Starting now!" {
+		t.Errorf("Expected subscribers to be told of the start and finish, got %+v", ticks)
+	}
+
+	// Starting a finished countdown starts it over
+	status, _ = m.Control("starting", ActionStart, 0)
+	if status.Finished || !status.Running || status.Text != "6:00" {
+		t.Errorf("Expected the countdown to start over, got %+v", status)
+	}
+}
+
+func TestManager_Stopwatch(t *testing.T) {
+	store := testutils.NewMockStorage()
+	m := newTestManager(t, store)
+
+	if _, err := m.Create(Timer{Name: "bad name", Mode: ModeStopwatch}); !errors.Is(err, ErrInvalidTimer) {
+		t.Errorf("Expected ErrInvalidTimer for a name with a space, got %v", err)
+	}
+	if _, err := m.Create(Timer{Name: "egg", Mode: ModeCountdown}); !errors.Is(err, ErrInvalidTimer) {
+		t.Errorf("Expected ErrInvalidTimer for a countdown without a duration, got %v", err)
+	}
+
+	m.Create(Timer{Name: "uptime", Mode: ModeStopwatch})
+	m.Control("uptime", ActionStart, 0)
+	rewind(m, "uptime", time.Hour)
+	status, _ := m.Control("uptime", ActionAdd, -2*time.Hour)
+	if status.Text != "0:00" || !status.Running {
+		t.Errorf("Expected the stopwatch to stop at 0:00 going back, got %+v", status)
+	}
+	m.Control("uptime", ActionAdd, time.Hour+5*time.Second)
+	if _, err := m.Control("uptime", "rewind", 0); !errors.Is(err, ErrInvalidTimer) {
+		t.Errorf("Expected ErrInvalidTimer for an unknown action, got %v", err)
+	}
+
+	// A running timer keeps counting across a restart
+	reloaded := newTestManager(t, store)
+	text, err := reloaded.Format("uptime")
+	if err != nil || text != "1:00:05" {
+		t.Errorf("Expected the stored stopwatch at 1:00:05, got %q, %v", text, err)
+	}
+
+	status, _ = reloaded.Control("uptime", ActionReset, 0)
+	if status.Running || status.Value != 0 {
+		t.Errorf("Expected reset to stop at zero, got %+v", status)
+	}
+	if err := reloaded.Remove("uptime"); err != nil {
+		t.Errorf("Failed to remove timer: %v", err)
+	}
+	if _, err := reloaded.Get("uptime"); !errors.Is(err, ErrTimerNotFound) {
+		t.Errorf("Expected ErrTimerNotFound, got %v", err)
+	}
+}
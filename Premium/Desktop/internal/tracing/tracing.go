@@ -0,0 +1,135 @@
+// Package tracing traces remote tasks through the bridge with
+// OpenTelemetry, from receipt through the policy check to the module,
+// script or OBS call that runs them. Spans are exported to an OTLP
+// collector when tracing is enabled and cost next to nothing otherwise.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"waddlebot-bridge/internal/config"
+)
+
+const serviceName = "waddlebot-bridge"
+
+// tracer follows the global provider, so spans are only recorded once
+// Setup has installed an exporting one
+var tracer = otel.Tracer(serviceName)
+
+// propagator carries trace context in W3C traceparent headers
+var propagator = propagation.TraceContext{}
+
+// Setup exports spans as configured and returns a function that flushes
+// and stops the exporter. Nothing is exported when tracing is disabled.
+func Setup(ctx context.Context, cfg config.TracingConfig, version string, logger *logrus.Logger) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.WithError(err).Warn("Tracing error")
+	}))
+
+	logger.WithFields(logrus.Fields{
+		"endpoint": cfg.Endpoint,
+		"protocol": cfg.Protocol,
+	}).Info("Exporting traces")
+	return provider.Shutdown, nil
+}
+
+// newExporter creates an OTLP exporter for the configured protocol. The
+// endpoint is a host:port, or a URL whose scheme decides TLS.
+func newExporter(ctx context.Context, cfg config.TracingConfig) (*otlptrace.Exporter, error) {
+	isURL := strings.Contains(cfg.Endpoint, "://")
+
+	if strings.EqualFold(cfg.Protocol, "http") {
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.Headers)}
+		if isURL {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		} else {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.Headers)}
+	if isURL {
+		opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+	} else {
+		opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+// Start starts a span as a child of the span in ctx, if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartRemote starts the span of a task received from the server,
+// continuing the server's trace when traceparent carries one
+func StartRemote(ctx context.Context, traceparent, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if traceparent != "" {
+		ctx = propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+	}
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the recorded trace in ctx, or an empty string
+// when the task is not traced
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
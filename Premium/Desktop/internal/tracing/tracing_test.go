@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func withExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestStartRemote(t *testing.T) {
+	exporter := withExporter(t)
+
+	ctx, task := StartRemote(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "task")
+	_, child := Start(ctx, "module.execute")
+	End(child, errors.New("module failed"))
+	End(task, nil)
+
+	if id := TraceID(ctx); id != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the server's trace, got %q", id)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Error("Expected the module span to be a child of the task span")
+	}
+	if spans[1].Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the task span to continue the server's span, got parent %s", spans[1].Parent.SpanID())
+	}
+	if spans[0].Status.Code != codes.Error || spans[1].Status.Code == codes.Error {
+		t.Errorf("Expected only the failed span to be marked failed, got %v and %v", spans[0].Status, spans[1].Status)
+	}
+}
+
+func TestStartRemote_NewTrace(t *testing.T) {
+	withExporter(t)
+
+	ctx, span := StartRemote(context.Background(), "", "task")
+	defer span.End()
+
+	if TraceID(ctx) == "" {
+		t.Error("Expected a new trace without a traceparent")
+	}
+}
+
+func TestTraceID_NotTraced(t *testing.T) {
+	if id := TraceID(context.Background()); id != "" {
+		t.Errorf("Expected no trace ID, got %q", id)
+	}
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{}, "1.0.0", logrus.New())
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}
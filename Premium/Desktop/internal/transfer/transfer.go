@@ -0,0 +1,377 @@
+// Package transfer exports the bridge's state to a passphrase-encrypted
+// archive and imports it on another machine. The archive holds the stored
+// users, credentials, module settings, webhooks, secrets and bridge IDs,
+// the config file, scripts and the keys in the data directory, so the
+// bridge keeps its identity. Sessions, queued items, delivery history and
+// other transient data are left out, as are secrets kept in the OS
+// keychain, such as a linked account's tokens.
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"waddlebot-bridge/internal/storage"
+)
+
+// archiveVersion is the version of the archive contents
+const archiveVersion = 1
+
+// magic starts every archive
+var magic = []byte("WBX1")
+
+// Key derivation parameters of argon2id
+const (
+	saltSize      = 16
+	argonTime     = 3
+	argonMemory   = 64 * 1024
+	argonThreads  = 4
+	minPassphrase = 8
+)
+
+// transientBuckets hold data that is not carried to another machine
+var transientBuckets = []string{"sessions", "outbox", "webhook_deliveries"}
+
+// transientKeys are auth keys that are not carried to another machine
+var transientKeys = []string{"auth_sessions", "auth_revoked_sessions"}
+
+// dataFiles are the files of the data directory that are carried along:
+// the keys the bridge signs requests and tokens with
+var dataFiles = []string{"jwt-signing.key", "signing.key", "gateway-admin.key"}
+
+var (
+	// ErrWrongPassphrase is returned when an archive cannot be decrypted
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupt archive")
+
+	// ErrNotFresh is returned when importing into a bridge that already
+	// has users or an identity
+	ErrNotFresh = errors.New("the bridge already has users or an identity")
+
+	// ErrWeakPassphrase is returned for passphrases that are too short
+	ErrWeakPassphrase = fmt.Errorf("passphrase must be at least %d characters", minPassphrase)
+)
+
+// Paths locates the files carried in an archive
+type Paths struct {
+	ConfigFile string // empty when there is none
+	DataDir    string
+	ScriptsDir string
+}
+
+// Archive is the decrypted contents of an export
+type Archive struct {
+	Version    int                          `json:"version"`
+	ExportedAt time.Time                    `json:"exported_at"`
+	Buckets    map[string]map[string][]byte `json:"buckets"`
+	Config     []byte                       `json:"config,omitempty"`
+	DataFiles  map[string][]byte            `json:"data_files,omitempty"`
+	Scripts    map[string][]byte            `json:"scripts,omitempty"` // relative to the scripts directory
+}
+
+// Summary counts what an archive holds
+type Summary struct {
+	Keys      int `json:"keys"`
+	DataFiles int `json:"data_files"`
+	Scripts   int `json:"scripts"`
+}
+
+// Export writes the bridge's state to w, encrypted with passphrase. store
+// must return secrets decrypted, so they can be encrypted again with the
+// key of the importing machine.
+func Export(w io.Writer, store storage.Storage, paths Paths, passphrase string) (Summary, error) {
+	if len(passphrase) < minPassphrase {
+		return Summary{}, ErrWeakPassphrase
+	}
+
+	archive, err := collect(store, paths)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if err := json.NewEncoder(zw).Encode(archive); err != nil {
+		return Summary{}, fmt.Errorf("failed to encode archive: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return Summary{}, fmt.Errorf("failed to compress archive: %w", err)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return Summary{}, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return Summary{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return Summary{}, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	header := append(append(append([]byte{}, magic...), salt...), nonce...)
+	if _, err := w.Write(aead.Seal(header, nonce, plain.Bytes(), magic)); err != nil {
+		return Summary{}, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return archive.summary(), nil
+}
+
+// Read decrypts an archive
+func Read(r io.Reader, passphrase string) (*Archive, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if !bytes.HasPrefix(data, magic) || len(data) < len(magic)+saltSize {
+		return nil, fmt.Errorf("not a bridge export")
+	}
+	data = data[len(magic):]
+
+	aead, err := newAEAD(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], magic)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	var archive Archive
+	if err := json.NewDecoder(zr).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("failed to decode archive: %w", err)
+	}
+	if archive.Version > archiveVersion {
+		return nil, fmt.Errorf("archive version %d is newer than this bridge supports", archive.Version)
+	}
+	return &archive, nil
+}
+
+// Import restores an archive into store and the files at paths. Unless
+// force is set, the bridge must have no users or identity yet, and
+// existing files are not replaced. store must encrypt secrets, as the
+// archive holds them decrypted.
+func Import(archive *Archive, store storage.Storage, paths Paths, force bool) (Summary, error) {
+	if !force {
+		if err := checkFresh(store, paths); err != nil {
+			return Summary{}, err
+		}
+	}
+
+	for bucket, data := range archive.Buckets {
+		if err := store.EnsureBucket(bucket); err != nil {
+			return Summary{}, err
+		}
+		for key, value := range data {
+			if err := store.SetWithBucket(bucket, key, value); err != nil {
+				return Summary{}, fmt.Errorf("failed to import %s/%s: %w", bucket, key, err)
+			}
+		}
+	}
+
+	if len(archive.Config) > 0 && paths.ConfigFile != "" {
+		if err := writeFile(paths.ConfigFile, archive.Config, force); err != nil {
+			return Summary{}, err
+		}
+	}
+	for name, data := range archive.DataFiles {
+		if err := writeFile(filepath.Join(paths.DataDir, filepath.Base(name)), data, force); err != nil {
+			return Summary{}, err
+		}
+	}
+	for name, data := range archive.Scripts {
+		path, err := scriptPath(paths.ScriptsDir, name)
+		if err != nil {
+			return Summary{}, err
+		}
+		if err := writeFile(path, data, force); err != nil {
+			return Summary{}, err
+		}
+	}
+
+	return archive.summary(), nil
+}
+
+// collect reads the state to export
+func collect(store storage.Storage, paths Paths) (*Archive, error) {
+	lister, ok := store.(storage.BucketLister)
+	if !ok {
+		return nil, fmt.Errorf("storage cannot list its buckets")
+	}
+	buckets, err := lister.Buckets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list buckets: %w", err)
+	}
+	expiring, _ := store.(storage.Expiring)
+
+	archive := &Archive{
+		Version:    archiveVersion,
+		ExportedAt: time.Now(),
+		Buckets:    make(map[string]map[string][]byte),
+		DataFiles:  make(map[string][]byte),
+		Scripts:    make(map[string][]byte),
+	}
+
+	for _, bucket := range buckets {
+		if isTransientBucket(bucket) {
+			continue
+		}
+		data, err := store.GetAllFromBucket(bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bucket %s: %w", bucket, err)
+		}
+		for key := range data {
+			if contains(transientKeys, key) {
+				delete(data, key)
+			} else if expiring != nil {
+				if _, ok := expiring.ExpiresAt(bucket, key); ok {
+					delete(data, key)
+				}
+			}
+		}
+		if len(data) > 0 {
+			archive.Buckets[bucket] = data
+		}
+	}
+
+	if paths.ConfigFile != "" {
+		data, err := os.ReadFile(paths.ConfigFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		archive.Config = data
+	}
+
+	for _, name := range dataFiles {
+		data, err := os.ReadFile(filepath.Join(paths.DataDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		archive.DataFiles[name] = data
+	}
+
+	if paths.ScriptsDir != "" {
+		err := filepath.WalkDir(paths.ScriptsDir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(paths.ScriptsDir, path)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			archive.Scripts[filepath.ToSlash(rel)] = data
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read scripts: %w", err)
+		}
+	}
+
+	return archive, nil
+}
+
+// checkFresh returns ErrNotFresh if the bridge already has users, a bridge
+// ID or keys
+func checkFresh(store storage.Storage, paths Paths) error {
+	for _, prefix := range []string{"user_", "bridge_id"} {
+		if keys, err := store.List(prefix); err == nil && len(keys) > 0 {
+			return ErrNotFresh
+		}
+	}
+	if keys, err := store.ListWithBucket(storage.AuthBucket, "user_"); err == nil && len(keys) > 0 {
+		return ErrNotFresh
+	}
+	for _, name := range dataFiles {
+		if _, err := os.Stat(filepath.Join(paths.DataDir, name)); err == nil {
+			return fmt.Errorf("%w: %s exists", ErrNotFresh, name)
+		}
+	}
+	return nil
+}
+
+// scriptPath returns where a script of the archive goes, refusing names
+// that leave the scripts directory
+func scriptPath(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid script name %q in archive", name)
+	}
+	return path, nil
+}
+
+// writeFile writes an imported file, leaving an existing one unless force
+// is set
+func writeFile(path string, data []byte, force bool) error {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (a *Archive) summary() Summary {
+	summary := Summary{DataFiles: len(a.DataFiles), Scripts: len(a.Scripts)}
+	for _, data := range a.Buckets {
+		summary.Keys += len(data)
+	}
+	return summary
+}
+
+func isTransientBucket(bucket string) bool {
+	for _, transient := range transientBuckets {
+		if bucket == transient || strings.HasPrefix(bucket, transient+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
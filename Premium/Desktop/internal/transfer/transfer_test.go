@@ -0,0 +1,121 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"waddlebot-bridge/internal/storage"
+)
+
+const testPassphrase = "correct horse battery"
+
+// newTestBridge returns storage and paths of a bridge in a temporary
+// directory
+func newTestBridge(t *testing.T) (storage.Storage, Paths) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := storage.NewSQLiteStorage(dir)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, Paths{
+		ConfigFile: filepath.Join(dir, "config.yaml"),
+		DataDir:    dir,
+		ScriptsDir: filepath.Join(dir, "scripts"),
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src, srcPaths := newTestBridge(t)
+	src.Set("user_streamer", []byte(`{"id":"streamer"}`))
+	src.Set("bridge_id:penguins", []byte("bridge-1"))
+	src.Set("auth_sessions", []byte("{}"))
+	src.SetWithTTL("temp_user_visitor", []byte("{}"), time.Hour)
+	src.EnsureBucket("webhooks")
+	src.SetWithBucket("webhooks", "hook-1", []byte(`{"secret":"s3cret"}`))
+	src.SetWithBucket("outbox", "result:1", []byte("{}"))
+
+	os.WriteFile(srcPaths.ConfigFile, []byte("community-id: penguins\n"), 0600)
+	os.WriteFile(filepath.Join(srcPaths.DataDir, "signing.key"), []byte("key"), 0600)
+	os.MkdirAll(filepath.Join(srcPaths.ScriptsDir, "alerts"), 0700)
+	os.WriteFile(filepath.Join(srcPaths.ScriptsDir, "alerts", "follow.js"), []byte("// follow"), 0600)
+
+	var archive bytes.Buffer
+	exported, err := Export(&archive, src, srcPaths, testPassphrase)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if exported.Keys != 3 || exported.DataFiles != 1 || exported.Scripts != 1 {
+		t.Errorf("unexpected export summary %+v", exported)
+	}
+	if bytes.Contains(archive.Bytes(), []byte("s3cret")) {
+		t.Error("expected the archive to be encrypted")
+	}
+
+	if _, err := Read(bytes.NewReader(archive.Bytes()), "wrong passphrase"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+
+	read, err := Read(bytes.NewReader(archive.Bytes()), testPassphrase)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	dst, dstPaths := newTestBridge(t)
+	if _, err := Import(read, dst, dstPaths, false); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if value, err := dst.Get("bridge_id:penguins"); err != nil || string(value) != "bridge-1" {
+		t.Errorf("expected the bridge identity to be kept, got %q, %v", value, err)
+	}
+	if value, err := dst.GetWithBucket("webhooks", "hook-1"); err != nil || string(value) != `{"secret":"s3cret"}` {
+		t.Errorf("expected the webhook, got %q, %v", value, err)
+	}
+	for _, key := range []string{"auth_sessions", "temp_user_visitor"} {
+		if dst.Exists(key) {
+			t.Errorf("expected transient %s not to be imported", key)
+		}
+	}
+	if data, _ := dst.GetAllFromBucket("outbox"); len(data) != 0 {
+		t.Errorf("expected queued items not to be imported, got %v", data)
+	}
+
+	if data, _ := os.ReadFile(dstPaths.ConfigFile); string(data) != "community-id: penguins\n" {
+		t.Errorf("expected the config file, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dstPaths.DataDir, "signing.key")); string(data) != "key" {
+		t.Errorf("expected the signing key, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dstPaths.ScriptsDir, "alerts", "follow.js")); string(data) != "// follow" {
+		t.Errorf("expected the script, got %q", data)
+	}
+
+	// A second import needs force
+	if _, err := Import(read, dst, dstPaths, false); !errors.Is(err, ErrNotFresh) {
+		t.Errorf("expected ErrNotFresh, got %v", err)
+	}
+	if _, err := Import(read, dst, dstPaths, true); err != nil {
+		t.Errorf("expected a forced import to succeed, got %v", err)
+	}
+}
+
+func TestExportRejectsWeakPassphrase(t *testing.T) {
+	store, paths := newTestBridge(t)
+	if _, err := Export(&bytes.Buffer{}, store, paths, "short"); !errors.Is(err, ErrWeakPassphrase) {
+		t.Errorf("expected ErrWeakPassphrase, got %v", err)
+	}
+}
+
+func TestImportRejectsEscapingScripts(t *testing.T) {
+	store, paths := newTestBridge(t)
+	archive := &Archive{Version: archiveVersion, Scripts: map[string][]byte{"../../evil.js": []byte("evil")}}
+	if _, err := Import(archive, store, paths, false); err == nil {
+		t.Error("expected a script outside the scripts directory to be refused")
+	}
+}
@@ -0,0 +1,18 @@
+//go:build linux && !android
+
+package tray
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// available checks for the desktop session's bus, which the tray is shown
+// through; without one the tray library fails on shutdown
+func available() error {
+	if _, err := dbus.SessionBus(); err != nil {
+		return fmt.Errorf("no desktop session bus: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package tray
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"runtime"
+)
+
+// iconSize is the width and height of the tray icon in pixels
+const iconSize = 32
+
+// levelColors are the icon colors of each level
+var levelColors = map[Level]color.NRGBA{
+	LevelOK:       {R: 0x2e, G: 0xcc, B: 0x71, A: 0xff},
+	LevelDegraded: {R: 0xf3, G: 0x9c, B: 0x12, A: 0xff},
+	LevelDown:     {R: 0xe7, G: 0x4c, B: 0x3c, A: 0xff},
+	LevelPaused:   {R: 0x95, G: 0xa5, B: 0xa6, A: 0xff},
+}
+
+// icon returns the tray icon for level: a filled circle in the level's
+// color, as ICO on Windows and PNG elsewhere
+func icon(level Level) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, iconSize, iconSize))
+	c := levelColors[level]
+	center := float64(iconSize-1) / 2
+	radius := float64(iconSize)/2 - 2
+	for y := 0; y < iconSize; y++ {
+		for x := 0; x < iconSize; x++ {
+			dx, dy := float64(x)-center, float64(y)-center
+			if dx*dx+dy*dy <= radius*radius {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	if runtime.GOOS == "windows" {
+		return wrapICO(buf.Bytes())
+	}
+	return buf.Bytes()
+}
+
+// wrapICO wraps a PNG image in an ICO file with a single entry, which
+// Windows Vista and later accept
+func wrapICO(pngData []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Reserved, Type, Count uint16
+	}{0, 1, 1})
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Width, Height, Colors, Reserved uint8
+		Planes, BitCount                uint16
+		Size, Offset                    uint32
+	}{iconSize, iconSize, 0, 0, 1, 32, uint32(len(pngData)), 22})
+	buf.Write(pngData)
+	return buf.Bytes()
+}
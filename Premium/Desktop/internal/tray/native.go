@@ -0,0 +1,8 @@
+//go:build windows || (darwin && cgo)
+
+package tray
+
+// available reports no error; the tray is part of the desktop here
+func available() error {
+	return nil
+}
@@ -0,0 +1,96 @@
+//go:build windows || (linux && !android) || (darwin && cgo)
+
+package tray
+
+import (
+	"context"
+	"time"
+
+	"fyne.io/systray"
+
+	"waddlebot-bridge/internal/obs"
+)
+
+// Run shows the tray icon until ctx is cancelled. It must be called from
+// the main goroutine, which macOS requires for its event loop.
+func (t *Tray) Run(ctx context.Context) error {
+	if err := available(); err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		systray.Quit()
+	}()
+	systray.Run(func() { t.show(ctx) }, nil)
+	return nil
+}
+
+// show builds the menu and keeps it up to date until ctx is cancelled
+func (t *Tray) show(ctx context.Context) {
+	systray.SetTitle("WaddleBot")
+
+	summary := systray.AddMenuItem("", "")
+	summary.Disable()
+	obsSummary := systray.AddMenuItem("", "")
+	obsSummary.Disable()
+	systray.AddSeparator()
+
+	stream := systray.AddMenuItem("Start Streaming", "Start or stop streaming in OBS")
+	reconnect := systray.AddMenuItem("Reconnect", "Reconnect to WaddleBot and OBS")
+	webUI := systray.AddMenuItem("Open Web UI", "Open the bridge's web interface")
+	pause := systray.AddMenuItemCheckbox("Pause Tasks", "Stop running tasks from WaddleBot", false)
+	systray.AddSeparator()
+	quit := systray.AddMenuItem("Quit", "Stop the bridge")
+
+	if t.opts.OBS == nil {
+		obsSummary.Hide()
+		stream.Hide()
+	}
+
+	refresh := func() {
+		status := t.Status(ctx)
+		systray.SetIcon(icon(status.Level()))
+		systray.SetTooltip(status.Tooltip())
+		summary.SetTitle(status.Summary())
+		obsSummary.SetTitle(status.OBSSummary())
+		stream.SetTitle(status.StreamLabel())
+		if status.OBS != nil && *status.OBS == obs.StateConnected {
+			stream.Enable()
+		} else {
+			stream.Disable()
+		}
+		if status.Paused {
+			pause.Check()
+		} else {
+			pause.Uncheck()
+		}
+	}
+	refresh()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-stream.ClickedCh:
+			if err := t.ToggleStream(ctx); err != nil {
+				t.logger.WithError(err).Error("Failed to toggle stream from the tray")
+			}
+		case <-reconnect.ClickedCh:
+			t.Reconnect(ctx)
+		case <-webUI.ClickedCh:
+			if err := t.OpenWebUI(); err != nil {
+				t.logger.WithError(err).Error("Failed to open the web interface")
+			}
+		case <-pause.ClickedCh:
+			t.TogglePause()
+		case <-quit.ClickedCh:
+			t.quit()
+		}
+		refresh()
+	}
+}
@@ -0,0 +1,237 @@
+// Package tray shows the bridge's state in the system tray, with a menu of
+// quick controls for streaming, reconnecting and pausing tasks
+package tray
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/obs"
+)
+
+// ErrUnsupported is returned by Run on platforms without a tray, such as
+// macOS builds without cgo
+var ErrUnsupported = errors.New("the system tray is not supported by this build")
+
+// refreshInterval is how often the tray's icon and menu are updated
+const refreshInterval = 2 * time.Second
+
+// actionTimeout bounds the OBS requests made from the menu
+const actionTimeout = 10 * time.Second
+
+// OBS is the OBS connection shown and controlled from the tray
+type OBS interface {
+	GetState() obs.ConnectionState
+	Connect(ctx context.Context) error
+	StartEventListener() error
+	IsStreaming(ctx context.Context) (bool, error)
+	ToggleStream(ctx context.Context) (bool, error)
+}
+
+// Bridge is a connection to a WaddleBot community
+type Bridge interface {
+	IsRegistered() bool
+	Reconnect()
+}
+
+// Tasks receives and runs a community's tasks
+type Tasks interface {
+	Pause()
+	Resume()
+	Paused() bool
+}
+
+// Options are the parts of the bridge the tray controls
+type Options struct {
+	OBS     OBS      // nil when OBS is disabled
+	Bridges []Bridge // one per community
+	Tasks   []Tasks  // one per community
+	WebURL  string   // opened by Open Web UI
+	Quit    func()   // stops the bridge
+}
+
+// Level is the overall health shown by the tray icon
+type Level int
+
+const (
+	LevelOK       Level = iota // connected, with OBS if enabled
+	LevelDegraded              // connected, but OBS is not
+	LevelDown                  // not connected to WaddleBot
+	LevelPaused                // tasks are paused
+)
+
+// Status is the state shown in the tray
+type Status struct {
+	Connected bool                 // every community is registered
+	OBS       *obs.ConnectionState // nil when OBS is disabled
+	Streaming bool
+	Paused    bool
+}
+
+// Level returns the health shown by the icon
+func (s Status) Level() Level {
+	switch {
+	case s.Paused:
+		return LevelPaused
+	case !s.Connected:
+		return LevelDown
+	case s.OBS != nil && *s.OBS != obs.StateConnected:
+		return LevelDegraded
+	default:
+		return LevelOK
+	}
+}
+
+// Summary describes the WaddleBot connection
+func (s Status) Summary() string {
+	switch {
+	case s.Paused:
+		return "WaddleBot: tasks paused"
+	case s.Connected:
+		return "WaddleBot: connected"
+	default:
+		return "WaddleBot: disconnected"
+	}
+}
+
+// OBSSummary describes the OBS connection, or is empty when OBS is disabled
+func (s Status) OBSSummary() string {
+	if s.OBS == nil {
+		return ""
+	}
+	if *s.OBS == obs.StateConnected && s.Streaming {
+		return "OBS: live"
+	}
+	return "OBS: " + s.OBS.String()
+}
+
+// Tooltip is shown when pointing at the icon
+func (s Status) Tooltip() string {
+	lines := []string{"WaddleBot Bridge", s.Summary()}
+	if summary := s.OBSSummary(); summary != "" {
+		lines = append(lines, summary)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// StreamLabel is the title of the stream menu item
+func (s Status) StreamLabel() string {
+	if s.Streaming {
+		return "Stop Streaming"
+	}
+	return "Start Streaming"
+}
+
+// Tray is the bridge's system tray icon
+type Tray struct {
+	opts   Options
+	logger *logrus.Logger
+}
+
+// New creates the tray for the bridge parts in opts
+func New(opts Options, logger *logrus.Logger) *Tray {
+	return &Tray{opts: opts, logger: logger}
+}
+
+// Status returns the bridge's current state
+func (t *Tray) Status(ctx context.Context) Status {
+	status := Status{Connected: len(t.opts.Bridges) > 0}
+	for _, bridge := range t.opts.Bridges {
+		if !bridge.IsRegistered() {
+			status.Connected = false
+		}
+	}
+	for _, tasks := range t.opts.Tasks {
+		if tasks.Paused() {
+			status.Paused = true
+		}
+	}
+
+	if t.opts.OBS != nil {
+		state := t.opts.OBS.GetState()
+		status.OBS = &state
+		if state == obs.StateConnected {
+			ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+			status.Streaming, _ = t.opts.OBS.IsStreaming(ctx)
+			cancel()
+		}
+	}
+	return status
+}
+
+// ToggleStream starts or stops streaming in OBS
+func (t *Tray) ToggleStream(ctx context.Context) error {
+	if t.opts.OBS == nil {
+		return fmt.Errorf("OBS is disabled")
+	}
+	ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+	defer cancel()
+
+	active, err := t.opts.OBS.ToggleStream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to toggle stream: %w", err)
+	}
+	t.logger.WithField("streaming", active).Info("Toggled stream from the tray")
+	return nil
+}
+
+// Reconnect registers every community again and connects to OBS if it is
+// disconnected
+func (t *Tray) Reconnect(ctx context.Context) {
+	t.logger.Info("Reconnecting from the tray")
+	for _, bridge := range t.opts.Bridges {
+		bridge.Reconnect()
+	}
+
+	if t.opts.OBS != nil && t.opts.OBS.GetState() == obs.StateDisconnected {
+		go func() {
+			if err := t.opts.OBS.Connect(ctx); err != nil {
+				t.logger.WithError(err).Warn("Failed to reconnect to OBS")
+				return
+			}
+			if err := t.opts.OBS.StartEventListener(); err != nil {
+				t.logger.WithError(err).Error("Failed to start OBS event listener")
+			}
+		}()
+	}
+}
+
+// TogglePause pauses every community's tasks, or resumes them if any are
+// paused, and reports whether they are now paused
+func (t *Tray) TogglePause() bool {
+	paused := false
+	for _, tasks := range t.opts.Tasks {
+		if tasks.Paused() {
+			paused = true
+		}
+	}
+
+	for _, tasks := range t.opts.Tasks {
+		if paused {
+			tasks.Resume()
+		} else {
+			tasks.Pause()
+		}
+	}
+	return !paused
+}
+
+// OpenWebUI opens the bridge's web interface in the default browser
+func (t *Tray) OpenWebUI() error {
+	if t.opts.WebURL == "" {
+		return fmt.Errorf("no web interface URL")
+	}
+	return openURL(t.opts.WebURL)
+}
+
+// quit stops the bridge
+func (t *Tray) quit() {
+	if t.opts.Quit != nil {
+		t.opts.Quit()
+	}
+}
@@ -0,0 +1,136 @@
+package tray
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/obs"
+)
+
+type fakeOBS struct {
+	state     obs.ConnectionState
+	streaming bool
+}
+
+func (f *fakeOBS) GetState() obs.ConnectionState             { return f.state }
+func (f *fakeOBS) Connect(ctx context.Context) error         { f.state = obs.StateConnected; return nil }
+func (f *fakeOBS) StartEventListener() error                 { return nil }
+func (f *fakeOBS) IsStreaming(context.Context) (bool, error) { return f.streaming, nil }
+func (f *fakeOBS) ToggleStream(context.Context) (bool, error) {
+	f.streaming = !f.streaming
+	return f.streaming, nil
+}
+
+type fakeBridge struct {
+	registered  bool
+	reconnected int
+}
+
+func (f *fakeBridge) IsRegistered() bool { return f.registered }
+func (f *fakeBridge) Reconnect()         { f.reconnected++ }
+
+type fakeTasks struct{ paused bool }
+
+func (f *fakeTasks) Pause()       { f.paused = true }
+func (f *fakeTasks) Resume()      { f.paused = false }
+func (f *fakeTasks) Paused() bool { return f.paused }
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestTray_Status(t *testing.T) {
+	ctx := context.Background()
+	obsClient := &fakeOBS{state: obs.StateConnected}
+	first, second := &fakeBridge{registered: true}, &fakeBridge{registered: true}
+	tasks := []Tasks{&fakeTasks{}, &fakeTasks{}}
+	tray := New(Options{OBS: obsClient, Bridges: []Bridge{first, second}, Tasks: tasks}, quietLogger())
+
+	status := tray.Status(ctx)
+	if status.Level() != LevelOK || status.Summary() != "WaddleBot: connected" || status.OBSSummary() != "OBS: connected" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	if err := tray.ToggleStream(ctx); err != nil {
+		t.Fatalf("ToggleStream failed: %v", err)
+	}
+	status = tray.Status(ctx)
+	if !status.Streaming || status.StreamLabel() != "Stop Streaming" || status.OBSSummary() != "OBS: live" {
+		t.Errorf("Expected the stream to be live, got %+v", status)
+	}
+
+	obsClient.state = obs.StateReconnecting
+	if level := tray.Status(ctx).Level(); level != LevelDegraded {
+		t.Errorf("Expected a degraded level while OBS reconnects, got %d", level)
+	}
+
+	second.registered = false
+	if level := tray.Status(ctx).Level(); level != LevelDown {
+		t.Errorf("Expected a down level with a community disconnected, got %d", level)
+	}
+
+	if !tray.TogglePause() {
+		t.Error("Expected the first toggle to pause tasks")
+	}
+	status = tray.Status(ctx)
+	if status.Level() != LevelPaused || status.Summary() != "WaddleBot: tasks paused" {
+		t.Errorf("Expected paused tasks, got %+v", status)
+	}
+	if tray.TogglePause() || tray.Status(ctx).Paused {
+		t.Error("Expected the second toggle to resume tasks")
+	}
+
+	tray.Reconnect(ctx)
+	if first.reconnected != 1 || second.reconnected != 1 {
+		t.Errorf("Expected every community to reconnect, got %d and %d", first.reconnected, second.reconnected)
+	}
+}
+
+func TestTray_WithoutOBS(t *testing.T) {
+	tray := New(Options{Bridges: []Bridge{&fakeBridge{registered: true}}}, quietLogger())
+
+	status := tray.Status(context.Background())
+	if status.OBS != nil || status.OBSSummary() != "" || status.Level() != LevelOK {
+		t.Errorf("Expected no OBS state, got %+v", status)
+	}
+	if status.Tooltip() != "WaddleBot Bridge\nWaddleBot: connected" {
+		t.Errorf("Unexpected tooltip %q", status.Tooltip())
+	}
+	if err := tray.ToggleStream(context.Background()); err == nil {
+		t.Error("Expected an error toggling the stream without OBS")
+	}
+}
+
+func TestIcon(t *testing.T) {
+	for level := range levelColors {
+		img, err := png.Decode(bytes.NewReader(iconPNG(t, icon(level))))
+		if err != nil {
+			t.Fatalf("Icon for level %d is not a PNG: %v", level, err)
+		}
+		if img.Bounds().Dx() != iconSize {
+			t.Errorf("Expected a %dpx icon, got %dpx", iconSize, img.Bounds().Dx())
+		}
+	}
+
+	ico := wrapICO([]byte("png"))
+	if binary.LittleEndian.Uint16(ico[2:]) != 1 || binary.LittleEndian.Uint32(ico[18:]) != 22 || string(ico[22:]) != "png" {
+		t.Errorf("Unexpected ICO header % x", ico[:22])
+	}
+}
+
+// iconPNG returns the PNG inside an icon, which is wrapped in ICO on Windows
+func iconPNG(t *testing.T, data []byte) []byte {
+	t.Helper()
+	if bytes.HasPrefix(data, []byte{0, 0, 1, 0}) {
+		return data[22:]
+	}
+	return data
+}
@@ -0,0 +1,10 @@
+//go:build !(windows || (linux && !android) || (darwin && cgo))
+
+package tray
+
+import "context"
+
+// Run returns ErrUnsupported; this build has no system tray
+func (t *Tray) Run(ctx context.Context) error {
+	return ErrUnsupported
+}
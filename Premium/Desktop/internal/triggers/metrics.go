@@ -0,0 +1,108 @@
+package triggers
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// systemModuleName is the module system metrics are read from
+const systemModuleName = "system"
+
+// sampleTimeout bounds reading the metrics. Reading the CPU usage takes a
+// second, as it is measured over one.
+const sampleTimeout = 10 * time.Second
+
+// ModuleExecutor runs module actions
+type ModuleExecutor interface {
+	ExecuteAction(ctx context.Context, moduleName, action string, parameters map[string]string) (map[string]interface{}, error)
+}
+
+// Metrics reads the system metrics
+type Metrics interface {
+	Sample(ctx context.Context) (Sample, error)
+}
+
+// Sample is the system metrics at a moment. A metric that could not be
+// read is missing.
+type Sample struct {
+	Values map[string]float64
+	At     time.Time
+}
+
+// metric returns a metric of the sample
+func (s Sample) metric(name string) (float64, error) {
+	value, ok := s.Values[name]
+	if !ok {
+		return 0, fmt.Errorf("%s not sampled; the %s module must be loaded", name, systemModuleName)
+	}
+	return value, nil
+}
+
+// systemModule reads the metrics from the system module
+type systemModule struct {
+	modules ModuleExecutor
+}
+
+// Sample reads the CPU and memory usage
+func (m systemModule) Sample(ctx context.Context) (Sample, error) {
+	sample := Sample{Values: make(map[string]float64), At: time.Now()}
+	if m.modules == nil {
+		return sample, fmt.Errorf("%s module not available", systemModuleName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sampleTimeout)
+	defer cancel()
+
+	var firstErr error
+	for _, read := range []struct {
+		metric, action string
+		value          func(map[string]interface{}) interface{}
+	}{
+		{MetricCPUPercent, "get_cpu_info", func(result map[string]interface{}) interface{} {
+			return result["percent"]
+		}},
+		{MetricMemoryPercent, "get_memory_info", func(result map[string]interface{}) interface{} {
+			virtual, _ := result["virtual"].(map[string]interface{})
+			return virtual["used_percent"]
+		}},
+	} {
+		result, err := m.modules.ExecuteAction(ctx, systemModuleName, read.action, nil)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to read %s: %w", read.metric, err)
+			}
+			continue
+		}
+		if value, ok := number(read.value(result)); ok {
+			sample.Values[read.metric] = value
+		}
+	}
+	return sample, firstErr
+}
+
+// number converts a JSON or module value to a number. The CPU usage comes
+// as a list holding the usage of all CPUs.
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case []float64:
+		if len(v) == 1 {
+			return v[0], true
+		}
+	case []interface{}:
+		if len(v) == 1 {
+			return number(v[0])
+		}
+	}
+	return 0, false
+}
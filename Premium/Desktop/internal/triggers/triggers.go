@@ -0,0 +1,719 @@
+// Package triggers runs command palette commands when conditions mixing
+// events, system load and the time of day are met, such as running a
+// script and a macro when the stream starts on a weekday evening, or
+// switching to a lighter scene when the CPU goes above 90%. A trigger
+// either fires on bus events of a type, or, without one, whenever its
+// conditions start to hold. Triggers are managed through the local gateway,
+// persisted in storage, and can be tried against synthetic events without
+// running anything.
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/commands"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/storage"
+)
+
+// Condition types
+const (
+	ConditionEvent      = "event"
+	ConditionMetric     = "metric"
+	ConditionTimeWindow = "time_window"
+)
+
+// Metrics read from the system module
+const (
+	MetricCPUPercent    = "cpu_percent"
+	MetricMemoryPercent = "memory_percent"
+)
+
+// EventFired is published on the bus when a trigger runs its actions.
+// Triggers never fire on it, so they cannot set each other off in a loop.
+const EventFired = "triggers.fired"
+
+// commandTimeout bounds each action
+const commandTimeout = 2 * time.Minute
+
+var (
+	// ErrTriggerNotFound is returned for an unknown trigger ID
+	ErrTriggerNotFound = errors.New("trigger not found")
+
+	// ErrInvalidTrigger is returned for a trigger that cannot be run
+	ErrInvalidTrigger = errors.New("invalid trigger")
+)
+
+// operators are the comparisons conditions can make
+var operators = map[string]bool{"==": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true}
+
+// weekdays are the day names time windows are limited to
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// actionKinds are the command kinds triggers may run
+var actionKinds = map[commands.Kind]bool{commands.KindModule: true, commands.KindScript: true, commands.KindMacro: true}
+
+// Condition must hold for a trigger to fire
+type Condition struct {
+	Type   string      `json:"type"`
+	Field  string      `json:"field,omitempty"`  // event data field for event; nested fields are joined with dots
+	Metric string      `json:"metric,omitempty"` // cpu_percent or memory_percent for metric
+	Op     string      `json:"op,omitempty"`     // comparison for event and metric; == when empty
+	Value  interface{} `json:"value,omitempty"`  // compared against
+	From   string      `json:"from,omitempty"`   // start of a time_window, as 15:04 local time
+	To     string      `json:"to,omitempty"`     // end of a time_window; before From for windows past midnight
+	Days   []string    `json:"days,omitempty"`   // days of a time_window, such as "mon"; every day when empty
+}
+
+// Action runs a command palette command
+type Action struct {
+	Command string `json:"command"` // module:, script: or macro: command ID
+	// Parameters are passed to the command. {type}, {source} and {field}
+	// are replaced with the type, source and data fields of the event the
+	// trigger fired on.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// Trigger runs its actions in order when an event matching Event arrives
+// while all its conditions hold, or, without Event, when the conditions
+// start to hold. It fires at most once every CooldownSeconds.
+type Trigger struct {
+	ID              string      `json:"id"`
+	Name            string      `json:"name"`
+	Enabled         bool        `json:"enabled"`
+	Event           string      `json:"event,omitempty"` // event type pattern, such as "obs.stream_started" or "obs.*"
+	Conditions      []Condition `json:"conditions,omitempty"`
+	Actions         []Action    `json:"actions"`
+	CooldownSeconds int         `json:"cooldown_seconds"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// usesMetrics reports whether a trigger reads system metrics
+func (t *Trigger) usesMetrics() bool {
+	for _, condition := range t.Conditions {
+		if condition.Type == ConditionMetric {
+			return true
+		}
+	}
+	return false
+}
+
+// Commands runs command palette commands
+type Commands interface {
+	Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error)
+}
+
+// Input is what a trigger is evaluated against. Metrics missing from a
+// test input are taken from the latest sample.
+type Input struct {
+	Event   *events.Event      `json:"event,omitempty"`
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	Time    time.Time          `json:"time"`
+}
+
+// ConditionResult tells whether a condition held, and what it saw
+type ConditionResult struct {
+	Condition Condition   `json:"condition"`
+	Holds     bool        `json:"holds"`
+	Actual    interface{} `json:"actual,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Evaluation tells whether a trigger would fire on an input
+type Evaluation struct {
+	Fires        bool              `json:"fires"`
+	EventMatched bool              `json:"event_matched"`
+	Conditions   []ConditionResult `json:"conditions"`
+	Actions      []Action          `json:"actions"` // with the event's fields filled in
+}
+
+// triggerState is what a trigger last did
+type triggerState struct {
+	holding bool // whether the conditions of a trigger without an event held at the last check
+	fired   time.Time
+}
+
+// Engine fires triggers on the events delivered to it as a bus sink, and
+// checks triggers without an event at an interval
+type Engine struct {
+	store     *storage.TriggerRepo
+	commands  Commands
+	metrics   Metrics
+	publisher events.Publisher // nil when the event bus is disabled
+	cfg       config.TriggersConfig
+	logger    *logrus.Logger
+
+	mu       sync.RWMutex
+	triggers map[string]*Trigger
+	states   map[string]*triggerState
+	sample   Sample
+}
+
+// NewEngine creates an engine with the triggers persisted in store, reading
+// system metrics from the system module through modules. publisher may be
+// nil.
+func NewEngine(store storage.Storage, catalog Commands, modules ModuleExecutor, publisher events.Publisher, cfg config.TriggersConfig, logger *logrus.Logger) (*Engine, error) {
+	repo, err := storage.NewTriggerRepo(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create triggers bucket: %w", err)
+	}
+
+	e := &Engine{
+		store:     repo,
+		commands:  catalog,
+		metrics:   systemModule{modules: modules},
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+		triggers:  make(map[string]*Trigger),
+		states:    make(map[string]*triggerState),
+	}
+
+	stored, err := repo.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load triggers: %w", err)
+	}
+	for id, data := range stored {
+		var trigger Trigger
+		if err := json.Unmarshal(data, &trigger); err != nil {
+			logger.WithError(err).WithField("id", id).Warn("Skipping unreadable trigger")
+			continue
+		}
+		e.triggers[trigger.ID] = &trigger
+	}
+
+	if len(e.triggers) > 0 {
+		logger.WithField("count", len(e.triggers)).Info("Loaded triggers")
+	}
+	return e, nil
+}
+
+// List returns the triggers ordered by creation time
+func (e *Engine) List() []Trigger {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	list := make([]Trigger, 0, len(e.triggers))
+	for _, trigger := range e.triggers {
+		list = append(list, *trigger)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Get returns a trigger
+func (e *Engine) Get(id string) (Trigger, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	trigger, exists := e.triggers[id]
+	if !exists {
+		return Trigger{}, fmt.Errorf("%w: %s", ErrTriggerNotFound, id)
+	}
+	return *trigger, nil
+}
+
+// Create validates and persists a new trigger
+func (e *Engine) Create(trigger Trigger) (Trigger, error) {
+	if err := Validate(trigger); err != nil {
+		return Trigger{}, err
+	}
+
+	trigger.ID = "trigger_" + uuid.NewString()
+	trigger.CreatedAt = time.Now()
+	trigger.UpdatedAt = trigger.CreatedAt
+	if err := e.save(&trigger); err != nil {
+		return Trigger{}, err
+	}
+
+	e.mu.Lock()
+	e.triggers[trigger.ID] = &trigger
+	e.mu.Unlock()
+	return trigger, nil
+}
+
+// Update replaces a trigger. It starts over, as if it had never fired.
+func (e *Engine) Update(id string, trigger Trigger) (Trigger, error) {
+	existing, err := e.Get(id)
+	if err != nil {
+		return Trigger{}, err
+	}
+	if err := Validate(trigger); err != nil {
+		return Trigger{}, err
+	}
+
+	trigger.ID = id
+	trigger.CreatedAt = existing.CreatedAt
+	trigger.UpdatedAt = time.Now()
+	if err := e.save(&trigger); err != nil {
+		return Trigger{}, err
+	}
+
+	e.mu.Lock()
+	e.triggers[id] = &trigger
+	delete(e.states, id)
+	e.mu.Unlock()
+	return trigger, nil
+}
+
+// Remove deletes a trigger
+func (e *Engine) Remove(id string) error {
+	e.mu.Lock()
+	if _, exists := e.triggers[id]; !exists {
+		e.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrTriggerNotFound, id)
+	}
+	delete(e.triggers, id)
+	delete(e.states, id)
+	e.mu.Unlock()
+
+	if err := e.store.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete trigger: %w", err)
+	}
+	return nil
+}
+
+// save persists a trigger
+func (e *Engine) save(trigger *Trigger) error {
+	data, err := json.Marshal(trigger)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trigger: %w", err)
+	}
+	if err := e.store.Set(trigger.ID, data); err != nil {
+		return fmt.Errorf("failed to store trigger: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that a trigger can be run
+func Validate(trigger Trigger) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidTrigger, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(trigger.Name) == "" {
+		return invalid("name is required")
+	}
+	if trigger.Event != "" {
+		if _, err := path.Match(trigger.Event, ""); err != nil {
+			return invalid("event must be a valid pattern")
+		}
+	} else if len(trigger.Conditions) == 0 {
+		return invalid("an event or at least one condition is required")
+	}
+	if trigger.CooldownSeconds < 0 {
+		return invalid("cooldown_seconds must not be negative")
+	}
+
+	for i, condition := range trigger.Conditions {
+		if condition.Op != "" && !operators[condition.Op] {
+			return invalid("conditions[%d]: unknown op %q", i, condition.Op)
+		}
+		switch condition.Type {
+		case ConditionEvent:
+			if trigger.Event == "" {
+				return invalid("conditions[%d]: %s needs the trigger to have an event", i, condition.Type)
+			}
+			if condition.Field == "" {
+				return invalid("conditions[%d]: field is required for %s", i, condition.Type)
+			}
+		case ConditionMetric:
+			if condition.Metric != MetricCPUPercent && condition.Metric != MetricMemoryPercent {
+				return invalid("conditions[%d]: unknown metric %q", i, condition.Metric)
+			}
+			if _, ok := number(condition.Value); !ok {
+				return invalid("conditions[%d]: value must be a number for %s", i, condition.Type)
+			}
+		case ConditionTimeWindow:
+			if _, err := clock(condition.From); err != nil {
+				return invalid("conditions[%d]: from must be a time such as 18:30", i)
+			}
+			to, err := clock(condition.To)
+			if err != nil {
+				return invalid("conditions[%d]: to must be a time such as 23:00", i)
+			}
+			if from, _ := clock(condition.From); from == to {
+				return invalid("conditions[%d]: from and to must differ", i)
+			}
+			for _, day := range condition.Days {
+				if _, ok := weekdays[strings.ToLower(day)]; !ok {
+					return invalid("conditions[%d]: unknown day %q", i, day)
+				}
+			}
+		default:
+			return invalid("conditions[%d]: unknown condition type %q", i, condition.Type)
+		}
+	}
+
+	if len(trigger.Actions) == 0 {
+		return invalid("at least one action is required")
+	}
+	for i, action := range trigger.Actions {
+		kind, name, _ := strings.Cut(action.Command, ":")
+		if !actionKinds[commands.Kind(kind)] || name == "" {
+			return invalid("actions[%d]: command must be a module:, script: or macro: command", i)
+		}
+	}
+	return nil
+}
+
+// Name returns the sink name
+func (e *Engine) Name() string {
+	return "triggers"
+}
+
+// Deliver fires the enabled triggers whose event pattern matches the event
+// and whose conditions hold
+func (e *Engine) Deliver(ctx context.Context, event events.Event) error {
+	if event.Type == EventFired {
+		return nil
+	}
+
+	now := time.Now()
+	for _, trigger := range e.enabled() {
+		if trigger.Event == "" {
+			continue
+		}
+		evaluation := e.evaluate(trigger, Input{Event: &event, Time: now})
+		if evaluation.Fires && e.claim(trigger, now) {
+			e.fire(ctx, trigger, evaluation.Actions, event.Type)
+		}
+	}
+	return nil
+}
+
+// Run samples system metrics and checks the triggers without an event
+// until ctx is done
+func (e *Engine) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			e.check(ctx, time.Now())
+		}
+	}
+}
+
+// check samples the metrics if a trigger reads them, and fires the
+// triggers without an event whose conditions have started to hold
+func (e *Engine) check(ctx context.Context, now time.Time) {
+	triggers := e.enabled()
+
+	for _, trigger := range triggers {
+		if trigger.usesMetrics() {
+			sample, err := e.metrics.Sample(ctx)
+			if err != nil {
+				e.logger.WithError(err).Debug("Failed to sample system metrics")
+			}
+			e.mu.Lock()
+			e.sample = sample
+			e.mu.Unlock()
+			break
+		}
+	}
+
+	for _, trigger := range triggers {
+		if trigger.Event != "" {
+			continue
+		}
+		evaluation := e.evaluate(trigger, Input{Time: now})
+
+		e.mu.Lock()
+		state := e.state(trigger.ID)
+		started := evaluation.Fires && !state.holding
+		state.holding = evaluation.Fires
+		e.mu.Unlock()
+
+		if started && e.claim(trigger, now) {
+			e.fire(ctx, trigger, evaluation.Actions, "")
+		}
+	}
+}
+
+// Test evaluates a trigger against a synthetic input without running its
+// actions. Cooldowns are ignored.
+func (e *Engine) Test(trigger Trigger, input Input) (Evaluation, error) {
+	if err := Validate(trigger); err != nil {
+		return Evaluation{}, err
+	}
+	if input.Time.IsZero() {
+		input.Time = time.Now()
+	}
+	if input.Event != nil && input.Event.Timestamp.IsZero() {
+		input.Event.Timestamp = input.Time
+	}
+	return e.evaluate(trigger, input), nil
+}
+
+// enabled returns the enabled triggers ordered by creation time
+func (e *Engine) enabled() []Trigger {
+	e.mu.RLock()
+	triggers := make([]Trigger, 0, len(e.triggers))
+	for _, trigger := range e.triggers {
+		if trigger.Enabled {
+			triggers = append(triggers, *trigger)
+		}
+	}
+	e.mu.RUnlock()
+	sort.Slice(triggers, func(i, j int) bool { return triggers[i].CreatedAt.Before(triggers[j].CreatedAt) })
+	return triggers
+}
+
+// state returns a trigger's state, creating it. e.mu must be held.
+func (e *Engine) state(id string) *triggerState {
+	state, exists := e.states[id]
+	if !exists {
+		state = &triggerState{}
+		e.states[id] = state
+	}
+	return state
+}
+
+// claim records a trigger as fired at now, unless it is cooling down
+func (e *Engine) claim(trigger Trigger, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.triggers[trigger.ID]; !exists {
+		return false
+	}
+	state := e.state(trigger.ID)
+	if !state.fired.IsZero() && now.Sub(state.fired) < time.Duration(trigger.CooldownSeconds)*time.Second {
+		return false
+	}
+	state.fired = now
+	return true
+}
+
+// fire runs a trigger's actions in order
+func (e *Engine) fire(ctx context.Context, trigger Trigger, actions []Action, eventType string) {
+	log := e.logger.WithField("trigger", trigger.Name)
+	log.Info("Trigger fired")
+
+	failed := 0
+	for _, action := range actions {
+		actionCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+		_, err := e.commands.Execute(actionCtx, action.Command, action.Parameters)
+		cancel()
+		if err != nil {
+			failed++
+			log.WithError(err).WithField("command", action.Command).Warn("Trigger action failed")
+		}
+	}
+
+	if e.publisher == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"trigger_id":     trigger.ID,
+		"trigger":        trigger.Name,
+		"failed_actions": failed,
+	}
+	if eventType != "" {
+		data["event"] = eventType
+	}
+	e.publisher.Publish(events.Event{
+		Type:   EventFired,
+		Source: events.SourceBridge,
+		Data:   data,
+	})
+}
+
+// evaluate tells whether a trigger fires on an input. Every condition is
+// evaluated, so a test shows all of them.
+func (e *Engine) evaluate(trigger Trigger, input Input) Evaluation {
+	evaluation := Evaluation{Conditions: make([]ConditionResult, 0, len(trigger.Conditions))}
+	if trigger.Event == "" {
+		evaluation.EventMatched = true
+	} else if input.Event != nil {
+		evaluation.EventMatched, _ = path.Match(trigger.Event, input.Event.Type)
+	}
+
+	e.mu.RLock()
+	sample := e.sample
+	e.mu.RUnlock()
+
+	holds := true
+	for _, condition := range trigger.Conditions {
+		result := ConditionResult{Condition: condition}
+		actual, err := observe(condition, input, sample)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Actual = actual
+			result.Holds = satisfied(condition, actual, input.Time)
+		}
+		holds = holds && result.Holds
+		evaluation.Conditions = append(evaluation.Conditions, result)
+	}
+
+	evaluation.Fires = evaluation.EventMatched && holds
+	evaluation.Actions = make([]Action, 0, len(trigger.Actions))
+	for _, action := range trigger.Actions {
+		evaluation.Actions = append(evaluation.Actions, fill(action, input.Event))
+	}
+	return evaluation
+}
+
+// observe returns what a condition looks at: an event field, a metric or
+// the time
+func observe(condition Condition, input Input, sample Sample) (interface{}, error) {
+	switch condition.Type {
+	case ConditionEvent:
+		if input.Event == nil {
+			return nil, errors.New("no event")
+		}
+		value, ok := field(input.Event.Data, condition.Field)
+		if !ok {
+			return nil, fmt.Errorf("event has no field %q", condition.Field)
+		}
+		return value, nil
+
+	case ConditionMetric:
+		if value, ok := input.Metrics[condition.Metric]; ok {
+			return value, nil
+		}
+		return sample.metric(condition.Metric)
+
+	case ConditionTimeWindow:
+		return input.Time.Format("Mon 15:04"), nil
+	}
+	return nil, fmt.Errorf("unknown condition type %q", condition.Type)
+}
+
+// satisfied reports whether what a condition saw meets it
+func satisfied(condition Condition, actual interface{}, now time.Time) bool {
+	if condition.Type == ConditionTimeWindow {
+		return inWindow(condition, now)
+	}
+
+	op := condition.Op
+	if op == "" {
+		op = "=="
+	}
+	a, aNumber := number(actual)
+	b, bNumber := number(condition.Value)
+	if aNumber && bNumber {
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		case ">":
+			return a > b
+		case ">=":
+			return a >= b
+		case "<":
+			return a < b
+		case "<=":
+			return a <= b
+		}
+		return false
+	}
+
+	// Anything else only compares equal or not, as text
+	equal := fmt.Sprint(actual) == fmt.Sprint(condition.Value)
+	switch op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	}
+	return false
+}
+
+// inWindow reports whether now is within a time window. A window whose end
+// is before its start runs past midnight, and belongs to the day it
+// started on.
+func inWindow(condition Condition, now time.Time) bool {
+	from, _ := clock(condition.From)
+	to, _ := clock(condition.To)
+	minute := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+
+	day := now.Weekday()
+	switch {
+	case from <= to:
+		if minute < from || minute >= to {
+			return false
+		}
+	case minute >= from:
+	case minute < to:
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+
+	if len(condition.Days) == 0 {
+		return true
+	}
+	for _, name := range condition.Days {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// clock parses a time of day such as 18:30
+func clock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// field returns a field of event data, following dots into nested objects
+func field(data map[string]interface{}, name string) (interface{}, bool) {
+	var value interface{} = data
+	for _, part := range strings.Split(name, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// fill replaces the placeholders in an action's parameters with the event's
+// type, source and data fields
+func fill(action Action, event *events.Event) Action {
+	if event == nil || len(action.Parameters) == 0 {
+		return action
+	}
+
+	pairs := []string{"{type}", event.Type, "{source}", event.Source}
+	for name, value := range event.Data {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	parameters := make(map[string]string, len(action.Parameters))
+	for name, value := range action.Parameters {
+		parameters[name] = replacer.Replace(value)
+	}
+	return Action{Command: action.Command, Parameters: parameters}
+}
@@ -0,0 +1,215 @@
+package triggers
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/testutils"
+)
+
+// execution is a command the engine ran
+type execution struct {
+	id         string
+	parameters map[string]string
+}
+
+type fakeCommands struct{ executed []execution }
+
+func (f *fakeCommands) Execute(ctx context.Context, id string, parameters map[string]string) (map[string]interface{}, error) {
+	f.executed = append(f.executed, execution{id, parameters})
+	return nil, nil
+}
+
+type fakeMetrics struct{ cpu float64 }
+
+func (f *fakeMetrics) Sample(ctx context.Context) (Sample, error) {
+	return Sample{Values: map[string]float64{MetricCPUPercent: f.cpu}, At: time.Now()}, nil
+}
+
+type recorder struct{ types []string }
+
+func (r *recorder) Publish(event events.Event) { r.types = append(r.types, event.Type) }
+
+func newTestEngine(t *testing.T) (*Engine, *fakeCommands, *fakeMetrics, *recorder) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	executed := &fakeCommands{}
+	published := &recorder{}
+	e, err := NewEngine(testutils.NewMockStorage(), executed, nil, published, config.TriggersConfig{Enabled: true, Interval: 5 * time.Second}, logger)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	e.metrics = metrics
+	return e, executed, metrics, published
+}
+
+// goLive runs the intro when the stream starts while the CPU has room
+func goLive() Trigger {
+	return Trigger{
+		Name:    "Go live",
+		Enabled: true,
+		Event:   "obs.stream_started",
+		Conditions: []Condition{
+			{Type: ConditionEvent, Field: "active", Value: true},
+			{Type: ConditionMetric, Metric: MetricCPUPercent, Op: "<", Value: 90.0},
+		},
+		Actions: []Action{
+			{Command: "script:intro.lua", Parameters: map[string]string{"state": "{state}"}},
+			{Command: "macro:go_live"},
+		},
+		CooldownSeconds: 60,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	noEvent := goLive()
+	noEvent.Event = ""
+	badMetric := goLive()
+	badMetric.Conditions = []Condition{{Type: ConditionMetric, Metric: "gpu_percent", Value: 90.0}}
+	badWindow := goLive()
+	badWindow.Conditions = []Condition{{Type: ConditionTimeWindow, From: "18:00", To: "25:00"}}
+	obsAction := goLive()
+	obsAction.Actions = []Action{{Command: "obs:start_stream"}}
+
+	tests := []struct {
+		name    string
+		trigger Trigger
+		ok      bool
+	}{
+		{"valid", goLive(), true},
+		{"event condition without event", noEvent, false},
+		{"unknown metric", badMetric, false},
+		{"bad time", badWindow, false},
+		{"OBS action", obsAction, false},
+		{"nothing to wait for", Trigger{Name: "Empty", Actions: []Action{{Command: "macro:go_live"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.trigger)
+			if (err == nil) != tt.ok {
+				t.Errorf("Validate() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEngine_Deliver(t *testing.T) {
+	e, executed, metrics, published := newTestEngine(t)
+	ctx := context.Background()
+
+	if _, err := e.Create(goLive()); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	metrics.cpu = 40
+	e.check(ctx, time.Now())
+
+	started := events.Event{Type: "obs.stream_started", Source: events.SourceOBS, Data: map[string]interface{}{"active": true, "state": "OBS_WEBSOCKET_OUTPUT_STARTED"}}
+	e.Deliver(ctx, events.Event{Type: "obs.scene_changed", Data: map[string]interface{}{"active": true}})
+	if len(executed.executed) != 0 {
+		t.Fatalf("Expected other events ignored, got %v", executed.executed)
+	}
+
+	e.Deliver(ctx, started)
+	if len(executed.executed) != 2 || executed.executed[0].id != "script:intro.lua" || executed.executed[1].id != "macro:go_live" {
+		t.Fatalf("Expected the script then the macro, got %v", executed.executed)
+	}
+	if state := executed.executed[0].parameters["state"]; state != "OBS_WEBSOCKET_OUTPUT_STARTED" {
+		t.Errorf("Expected the event's state passed to the script, got %q", state)
+	}
+
+	// Cooling down
+	e.Deliver(ctx, started)
+	if len(executed.executed) != 2 {
+		t.Errorf("Expected no second run within the cooldown, got %v", executed.executed)
+	}
+
+	if len(published.types) != 1 || published.types[0] != EventFired {
+		t.Errorf("Expected %s published, got %v", EventFired, published.types)
+	}
+}
+
+func TestEngine_Check(t *testing.T) {
+	e, executed, metrics, _ := newTestEngine(t)
+	ctx := context.Background()
+
+	_, err := e.Create(Trigger{
+		Name:       "CPU busy",
+		Enabled:    true,
+		Conditions: []Condition{{Type: ConditionMetric, Metric: MetricCPUPercent, Op: ">", Value: 90.0}},
+		Actions:    []Action{{Command: "macro:light_scene"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	for _, cpu := range []float64{50, 95, 97, 60, 92} {
+		metrics.cpu = cpu
+		e.check(ctx, time.Now())
+	}
+	if len(executed.executed) != 2 {
+		t.Errorf("Expected the trigger fired each time the CPU went above 90%%, got %d runs", len(executed.executed))
+	}
+}
+
+func TestEngine_Test(t *testing.T) {
+	e, executed, _, _ := newTestEngine(t)
+
+	trigger := goLive()
+	trigger.Conditions = append(trigger.Conditions, Condition{Type: ConditionTimeWindow, From: "18:00", To: "23:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}})
+	event := &events.Event{Type: "obs.stream_started", Data: map[string]interface{}{"active": true, "state": "started"}}
+	friday := time.Date(2026, 10, 16, 19, 30, 0, 0, time.Local)
+
+	evaluation, err := e.Test(trigger, Input{Event: event, Metrics: map[string]float64{MetricCPUPercent: 35}, Time: friday})
+	if err != nil {
+		t.Fatalf("Test failed: %v", err)
+	}
+	if !evaluation.Fires || !evaluation.EventMatched || len(evaluation.Conditions) != 3 {
+		t.Errorf("Expected the trigger to fire, got %+v", evaluation)
+	}
+	if evaluation.Actions[0].Parameters["state"] != "started" {
+		t.Errorf("Expected the actions filled in from the event, got %+v", evaluation.Actions)
+	}
+
+	saturday := friday.AddDate(0, 0, 1)
+	evaluation, _ = e.Test(trigger, Input{Event: event, Metrics: map[string]float64{MetricCPUPercent: 95}, Time: saturday})
+	if evaluation.Fires || evaluation.Conditions[1].Holds || evaluation.Conditions[2].Holds {
+		t.Errorf("Expected a busy CPU and the weekend to keep it from firing, got %+v", evaluation)
+	}
+
+	if len(executed.executed) != 0 {
+		t.Errorf("Expected nothing run by a test, got %v", executed.executed)
+	}
+}
+
+func TestInWindow(t *testing.T) {
+	lateNight := Condition{Type: ConditionTimeWindow, From: "22:00", To: "02:00", Days: []string{"fri"}}
+	friday := func(day, hour int) time.Time { return time.Date(2026, 10, 16+day, hour, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		name string
+		at   time.Time
+		in   bool
+	}{
+		{"friday night", friday(0, 23), true},
+		{"early saturday", friday(1, 1), true},
+		{"saturday night", friday(1, 23), false},
+		{"early friday", friday(0, 1), false},
+		{"friday afternoon", friday(0, 15), false},
+	}
+
+	for _, tt := range tests {
+		if in := inWindow(lateNight, tt.at); in != tt.in {
+			t.Errorf("%s: inWindow = %v, want %v", tt.name, in, tt.in)
+		}
+	}
+}
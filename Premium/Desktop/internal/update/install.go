@@ -0,0 +1,159 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxBinarySize bounds the size of a downloaded release
+const maxBinarySize = 512 << 20
+
+// Install downloads release, verifies it against the release key and
+// replaces the bridge's executable with it. The running bridge keeps its
+// current version until it restarts.
+func (u *Updater) Install(ctx context.Context, release *Release) error {
+	if u.publicKey == nil {
+		return ErrNoReleaseKey
+	}
+	if !Newer(release.Version, u.version) {
+		return fmt.Errorf("%w: %s is not newer than %s", ErrNotNewer, release.Version, u.version)
+	}
+
+	binary, err := u.fetch(ctx, release.URL)
+	if err != nil {
+		return err
+	}
+	if err := verify(binary, release, u.publicKey); err != nil {
+		return err
+	}
+
+	if err := replaceExecutable(u.executable, binary); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", u.executable, err)
+	}
+	return nil
+}
+
+// fetch downloads a release binary
+func (u *Updater) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", u.config.GetUserAgent())
+
+	resp, err := u.download.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download update: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download returned %d", resp.StatusCode)
+	}
+
+	binary, err := io.ReadAll(io.LimitReader(resp.Body, maxBinarySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download update: %w", err)
+	}
+	if len(binary) > maxBinarySize {
+		return nil, fmt.Errorf("update is larger than %d MB", maxBinarySize>>20)
+	}
+	return binary, nil
+}
+
+// Manifest returns the bytes a release's signature covers: its version,
+// channel and the SHA-256 of its binary. Signing these rather than the
+// binary alone stops an old or other-channel binary from being served as a
+// newer release.
+func Manifest(version, channel string, sum [sha256.Size]byte) []byte {
+	return []byte(fmt.Sprintf("waddlebot-bridge release\nversion: %s\nchannel: %s\nsha256: %s\n",
+		version, channel, hex.EncodeToString(sum[:])))
+}
+
+// verify checks a downloaded binary against its release's checksum and
+// signature
+func verify(binary []byte, release *Release, publicKey ed25519.PublicKey) error {
+	sum := sha256.Sum256(binary)
+	if release.SHA256 != "" && !strings.EqualFold(hex.EncodeToString(sum[:]), release.SHA256) {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidSignature)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(release.Signature)
+	if err != nil || !ed25519.Verify(publicKey, Manifest(release.Version, release.Channel, sum), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// replaceExecutable swaps binary in at path. The old executable is moved
+// aside first, since Windows cannot overwrite a running program, and is
+// restored if the new one cannot be put in place.
+func replaceExecutable(path string, binary []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0111); err != nil {
+		return err
+	}
+
+	old := oldPath(path)
+	os.Remove(old)
+	if err := os.Rename(path, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Rename(old, path)
+		return err
+	}
+
+	// Windows keeps the old file until the bridge exits; RemoveOld cleans
+	// it up on the next start
+	os.Remove(old)
+	return nil
+}
+
+// RemoveOld deletes the executable left behind by an update that could not
+// remove it while it was running
+func RemoveOld() {
+	if path, err := currentExecutable(); err == nil {
+		os.Remove(oldPath(path))
+	}
+}
+
+// oldPath is where the previous executable is kept during an update
+func oldPath(path string) string {
+	return path + ".old"
+}
+
+// currentExecutable returns the path of the running executable with
+// symlinks resolved, so that a linked install is updated in place
+func currentExecutable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
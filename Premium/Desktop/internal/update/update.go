@@ -0,0 +1,291 @@
+// Package update checks WaddleBot's release API for new versions of the
+// bridge and installs them after verifying their signature
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/apitls"
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/logger"
+)
+
+// ReleaseKey is the base64 ed25519 public key WaddleBot signs releases
+// with. Release builds set it with
+// -ldflags "-X waddlebot-bridge/internal/update.ReleaseKey=...".
+var ReleaseKey = ""
+
+// releasePath is the API path of the latest release on a channel
+const releasePath = "/api/bridge/releases/latest"
+
+// minCheckInterval bounds how often auto-check asks the API
+const minCheckInterval = time.Hour
+
+var (
+	// ErrUpToDate is returned by Check when no newer release exists
+	ErrUpToDate = errors.New("already up to date")
+
+	// ErrNoReleaseKey is returned when there is no key to verify releases
+	// with
+	ErrNoReleaseKey = errors.New("no release signing key; set update.public-key")
+
+	// ErrInvalidSignature is returned when a downloaded binary does not
+	// match its release's signature or checksum
+	ErrInvalidSignature = errors.New("release signature does not verify")
+
+	// ErrNotNewer is returned by Install for a release that is not newer
+	// than the running bridge
+	ErrNotNewer = errors.New("release is not newer than the running bridge")
+)
+
+// Release is a bridge release offered by the release API
+type Release struct {
+	Version     string    `json:"version"`
+	Channel     string    `json:"channel"`
+	URL         string    `json:"url"`              // binary for this OS and architecture
+	SHA256      string    `json:"sha256,omitempty"` // hex checksum of the binary
+	Signature   string    `json:"signature"`        // base64 ed25519 signature over the release's Manifest
+	Notes       string    `json:"notes,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Updater checks for and installs releases of the bridge
+type Updater struct {
+	config     *config.Config
+	version    string
+	publicKey  ed25519.PublicKey
+	api        *http.Client // release API, with the API's TLS settings
+	download   *http.Client // binaries, which may be served from anywhere
+	executable string       // binary replaced by Install
+	logger     *logrus.Logger
+}
+
+// New creates an updater for the running bridge, which is at version
+func New(cfg *config.Config, version string) (*Updater, error) {
+	tlsConfig, err := apitls.NewConfig(cfg.APITLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure API TLS: %w", err)
+	}
+
+	keyText := cfg.Update.PublicKey
+	if keyText == "" {
+		keyText = ReleaseKey
+	}
+	var publicKey ed25519.PublicKey
+	if keyText != "" {
+		key, err := base64.StdEncoding.DecodeString(keyText)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("release key is not a base64 ed25519 public key")
+		}
+		publicKey = key
+	}
+
+	executable, err := currentExecutable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the bridge's executable: %w", err)
+	}
+
+	return &Updater{
+		config:    cfg,
+		version:   version,
+		publicKey: publicKey,
+		api: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: apitls.NewTransport(tlsConfig),
+		},
+		download: &http.Client{
+			Timeout: 10 * time.Minute,
+		},
+		executable: executable,
+		logger:     logger.GetLogger(),
+	}, nil
+}
+
+// Check returns the latest release on channel, or the configured channel
+// when empty. It returns ErrUpToDate if that release is not newer than the
+// running bridge.
+func (u *Updater) Check(ctx context.Context, channel string) (*Release, error) {
+	if channel == "" {
+		channel = u.config.Update.Channel
+	}
+
+	query := url.Values{}
+	query.Set("channel", channel)
+	query.Set("os", runtime.GOOS)
+	query.Set("arch", runtime.GOARCH)
+	query.Set("version", u.version)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.config.GetAPIEndpoint(releasePath)+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", u.config.GetUserAgent())
+
+	resp, err := u.api.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotFound:
+		return nil, ErrUpToDate
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("release API returned %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+	if !Newer(release.Version, u.version) {
+		return nil, ErrUpToDate
+	}
+	if release.Channel != channel {
+		return nil, fmt.Errorf("release API returned a %q release for channel %q", release.Channel, channel)
+	}
+	if release.URL == "" {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s", release.Version, runtime.GOOS, runtime.GOARCH)
+	}
+	return &release, nil
+}
+
+// Run checks for updates now and then on the configured interval until ctx
+// is cancelled, logging releases it finds and installing them when
+// auto-install is set
+func (u *Updater) Run(ctx context.Context) {
+	interval := u.config.Update.CheckInterval
+	if interval < minCheckInterval {
+		interval = minCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		u.runOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce checks for an update and installs it if auto-install is set
+func (u *Updater) runOnce(ctx context.Context) {
+	release, err := u.Check(ctx, "")
+	if errors.Is(err, ErrUpToDate) {
+		u.logger.Debug("Bridge is up to date")
+		return
+	}
+	if err != nil {
+		u.logger.WithError(err).Warn("Failed to check for updates")
+		return
+	}
+
+	log := u.logger.WithFields(logrus.Fields{
+		"version": release.Version,
+		"channel": release.Channel,
+	})
+	if !u.config.Update.AutoInstall {
+		log.Info("A bridge update is available; run waddlebot-bridge update to install it")
+		return
+	}
+	if err := u.Install(ctx, release); err != nil {
+		log.WithError(err).Error("Failed to install bridge update")
+		return
+	}
+	log.Info("Installed bridge update; it is used when the bridge restarts")
+}
+
+// Newer reports whether version a is newer than version b. Versions are
+// dotted numbers with an optional pre-release suffix, such as 1.2.0-beta.1,
+// which is older than the release without one.
+func Newer(a, b string) bool {
+	return compareVersions(a, b) > 0
+}
+
+// compareVersions compares two versions following semantic versioning's
+// precedence rules, ignoring build metadata
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			return compareInts(x, y)
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+
+	aIDs, bIDs := strings.Split(aPre, "."), strings.Split(bPre, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		x, xErr := strconv.Atoi(aIDs[i])
+		y, yErr := strconv.Atoi(bIDs[i])
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				return compareInts(x, y)
+			}
+		case xErr == nil:
+			return -1 // numeric identifiers sort before alphanumeric ones
+		case yErr == nil:
+			return 1
+		case aIDs[i] != bIDs[i]:
+			return strings.Compare(aIDs[i], bIDs[i])
+		}
+	}
+	return compareInts(len(aIDs), len(bIDs))
+}
+
+// splitVersion splits a version into its dotted numbers and pre-release
+// suffix
+func splitVersion(version string) (core, pre string) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if idx := strings.IndexByte(version, '+'); idx >= 0 {
+		version = version[:idx]
+	}
+	if idx := strings.IndexByte(version, '-'); idx >= 0 {
+		return version[:idx], version[idx+1:]
+	}
+	return version, ""
+}
+
+func compareInts(x, y int) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,218 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.1.0", "1.0.0", true},
+		{"v1.0.10", "1.0.9", true},
+		{"1.0", "1.0.0", false},
+		{"1.0.0", "1.0.0-beta.1", true},
+		{"1.0.0-beta.1", "1.0.0", false},
+		{"1.0.0-beta.2", "1.0.0-beta.1", true},
+		{"1.0.0-beta.10", "1.0.0-beta.9", true},
+		{"1.0.0-rc.1", "1.0.0-beta.3", true},
+		{"1.0.0-beta.1", "1.0.0-beta", true},
+		{"1.0.0+build.5", "1.0.0", false},
+		{"0.9.0", "1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.a, tt.b); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// testUpdater returns an updater at version 1.0.0 for the release API at
+// apiURL, replacing a fake executable in a temporary directory
+func testUpdater(t *testing.T, apiURL string, publicKey ed25519.PublicKey) *Updater {
+	t.Helper()
+	executable := filepath.Join(t.TempDir(), "waddlebot-bridge")
+	if err := os.WriteFile(executable, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return &Updater{
+		config: &config.Config{
+			APIURL: apiURL,
+			Update: config.UpdateConfig{Channel: "stable"},
+		},
+		version:    "1.0.0",
+		publicKey:  publicKey,
+		api:        http.DefaultClient,
+		download:   http.DefaultClient,
+		executable: executable,
+		logger:     logger,
+	}
+}
+
+func TestUpdater_CheckAndInstall(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("new bridge binary")
+	sum := sha256.Sum256(binary)
+
+	var channel string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case releasePath:
+			channel = r.URL.Query().Get("channel")
+			json.NewEncoder(w).Encode(Release{
+				Version:   "1.1.0-beta.1",
+				Channel:   channel,
+				URL:       server.URL + "/download",
+				SHA256:    hex.EncodeToString(sum[:]),
+				Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, Manifest("1.1.0-beta.1", channel, sum))),
+			})
+		case "/download":
+			w.Write(binary)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	u := testUpdater(t, server.URL, publicKey)
+
+	release, err := u.Check(ctx, "beta")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if channel != "beta" || release.Version != "1.1.0-beta.1" {
+		t.Errorf("Unexpected release %+v from channel %q", release, channel)
+	}
+
+	if err := u.Install(ctx, release); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	installed, err := os.ReadFile(u.executable)
+	if err != nil || string(installed) != string(binary) {
+		t.Errorf("Expected the new binary to be installed, got %q (%v)", installed, err)
+	}
+	if _, err := os.Stat(oldPath(u.executable)); !os.IsNotExist(err) {
+		t.Errorf("Expected the old executable to be removed, got %v", err)
+	}
+
+	u.version = "1.1.0"
+	if err := u.Install(ctx, release); !errors.Is(err, ErrNotNewer) {
+		t.Errorf("Expected ErrNotNewer installing an older release, got %v", err)
+	}
+	if _, err := u.Check(ctx, ""); !errors.Is(err, ErrUpToDate) {
+		t.Errorf("Expected ErrUpToDate for an older release, got %v", err)
+	}
+	if channel != "stable" {
+		t.Errorf("Expected the configured channel, got %q", channel)
+	}
+}
+
+func TestUpdater_InstallRejectsUnsignedBinary(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tampered binary"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	release := &Release{
+		Version:   "1.1.0",
+		Channel:   "stable",
+		URL:       server.URL,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, Manifest("1.1.0", "stable", sha256.Sum256([]byte("signed binary"))))),
+	}
+
+	u := testUpdater(t, server.URL, publicKey)
+	if err := u.Install(ctx, release); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+	if current, _ := os.ReadFile(u.executable); string(current) != "old" {
+		t.Errorf("Expected the executable to be left alone, got %q", current)
+	}
+
+	release.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, Manifest("1.1.0", "stable", sha256.Sum256([]byte("tampered binary")))))
+	release.SHA256 = hex.EncodeToString(make([]byte, sha256.Size))
+	if err := u.Install(ctx, release); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+
+	if err := testUpdater(t, server.URL, nil).Install(ctx, release); !errors.Is(err, ErrNoReleaseKey) {
+		t.Errorf("Expected ErrNoReleaseKey without a key, got %v", err)
+	}
+}
+
+func TestUpdater_InstallRejectsReplayedRelease(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("old bridge binary")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	u := testUpdater(t, server.URL, publicKey)
+	signed := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, Manifest("0.9.0", "beta", sha256.Sum256(binary))))
+
+	tests := []struct {
+		name    string
+		release Release
+		want    error
+	}{
+		{"older version", Release{Version: "0.9.0", Channel: "beta", Signature: signed}, ErrNotNewer},
+		{"relabelled version", Release{Version: "1.2.0", Channel: "beta", Signature: signed}, ErrInvalidSignature},
+		{"relabelled channel", Release{Version: "0.9.0", Channel: "stable", Signature: signed}, ErrNotNewer},
+		{"relabelled version and channel", Release{Version: "1.2.0", Channel: "stable", Signature: signed}, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		tt.release.URL = server.URL
+		if err := u.Install(ctx, &tt.release); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+	if current, _ := os.ReadFile(u.executable); string(current) != "old" {
+		t.Errorf("Expected the executable to be left alone, got %q", current)
+	}
+}
+
+func TestUpdater_CheckRejectsOtherChannel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Release{Version: "1.1.0-beta.1", Channel: "beta", URL: "http://example.invalid"})
+	}))
+	defer server.Close()
+
+	if _, err := testUpdater(t, server.URL, nil).Check(context.Background(), "stable"); err == nil {
+		t.Error("Expected a beta release offered on the stable channel to be rejected")
+	}
+}
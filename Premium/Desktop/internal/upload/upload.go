@@ -0,0 +1,225 @@
+// Package upload encodes payloads sent to the WaddleBot API. Payloads can be
+// gzip-compressed, events can be batched as NDJSON, and payloads larger than
+// the server's limit are split into chunks that the server reassembles.
+// Which of these are used is negotiated at registration.
+package upload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"waddlebot-bridge/internal/config"
+)
+
+// EncodingGzip is the gzip content encoding
+const EncodingGzip = "gzip"
+
+// Content types of encoded payloads
+const (
+	ContentTypeJSON   = "application/json"
+	ContentTypeNDJSON = "application/x-ndjson"
+	ContentTypeChunk  = "application/octet-stream"
+)
+
+// ChunkPath is the API path chunks of large payloads are posted to
+const ChunkPath = "/api/bridge/uploads"
+
+// Headers describing a chunk. The server reassembles the chunks of an
+// upload in index order and handles the result as a request to the upload
+// path with the given content type and encoding.
+const (
+	HeaderUploadID              = "X-Upload-ID"
+	HeaderUploadPath            = "X-Upload-Path"
+	HeaderUploadContentType     = "X-Upload-Content-Type"
+	HeaderUploadContentEncoding = "X-Upload-Content-Encoding"
+	HeaderChunkIndex            = "X-Chunk-Index"
+	HeaderChunkCount            = "X-Chunk-Count"
+)
+
+// Capabilities are the upload features offered by the bridge at
+// registration, and those accepted by the server in its response
+type Capabilities struct {
+	Encodings       []string `json:"encodings,omitempty"`
+	Batching        bool     `json:"ndjson_batching"`
+	Chunking        bool     `json:"chunking"`
+	MaxPayloadBytes int      `json:"max_payload_bytes,omitempty"`
+}
+
+// Options are the negotiated upload features. The zero value sends every
+// payload as plain JSON in a single request.
+type Options struct {
+	Gzip             bool
+	CompressMinBytes int
+	Batching         bool
+	BatchSize        int
+	Chunking         bool
+	MaxPayloadBytes  int
+}
+
+// Offer returns the capabilities the bridge offers at registration
+func Offer(cfg config.UploadConfig) Capabilities {
+	offer := Capabilities{
+		Batching:        cfg.Batching,
+		Chunking:        cfg.Chunking,
+		MaxPayloadBytes: cfg.MaxPayloadBytes,
+	}
+	if cfg.Compression {
+		offer.Encodings = []string{EncodingGzip}
+	}
+	return offer
+}
+
+// Negotiate combines the local configuration with the capabilities the
+// server accepted. A nil server response disables every feature.
+func Negotiate(cfg config.UploadConfig, server *Capabilities) Options {
+	if server == nil {
+		return Options{}
+	}
+
+	opts := Options{
+		CompressMinBytes: cfg.CompressMinBytes,
+		Batching:         cfg.Batching && server.Batching,
+		BatchSize:        cfg.BatchSize,
+		Chunking:         cfg.Chunking && server.Chunking,
+		MaxPayloadBytes:  cfg.MaxPayloadBytes,
+	}
+	if cfg.Compression {
+		for _, encoding := range server.Encodings {
+			if encoding == EncodingGzip {
+				opts.Gzip = true
+			}
+		}
+	}
+	if server.MaxPayloadBytes > 0 && (opts.MaxPayloadBytes <= 0 || server.MaxPayloadBytes < opts.MaxPayloadBytes) {
+		opts.MaxPayloadBytes = server.MaxPayloadBytes
+	}
+	if opts.BatchSize <= 0 {
+		opts.Batching = false
+	}
+	return opts
+}
+
+// Part is a single request that carries all or part of a payload. Callers
+// add authentication, signing and idempotency headers before sending it.
+type Part struct {
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// SendFunc sends one part and returns the response body
+type SendFunc func(ctx context.Context, part Part) ([]byte, error)
+
+// Send encodes a payload according to opts and sends it with send. Payloads
+// of at least CompressMinBytes are gzip-compressed; when the encoded payload
+// exceeds MaxPayloadBytes and chunking is negotiated it is sent as chunks to
+// ChunkPath, and the response to the last chunk is returned.
+func Send(ctx context.Context, opts Options, path, contentType string, payload []byte, send SendFunc) ([]byte, error) {
+	body := payload
+	encoding := ""
+	if opts.Gzip && len(payload) >= opts.CompressMinBytes {
+		compressed, err := Compress(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = compressed
+		encoding = EncodingGzip
+	}
+
+	if !opts.Chunking || opts.MaxPayloadBytes <= 0 || len(body) <= opts.MaxPayloadBytes {
+		header := http.Header{}
+		header.Set("Content-Type", contentType)
+		if encoding != "" {
+			header.Set("Content-Encoding", encoding)
+		}
+		return send(ctx, Part{Path: path, Header: header, Body: body})
+	}
+
+	chunks := Split(body, opts.MaxPayloadBytes)
+	id := UploadID(path, payload)
+
+	var response []byte
+	for i, chunk := range chunks {
+		header := http.Header{}
+		header.Set("Content-Type", ContentTypeChunk)
+		header.Set(HeaderUploadID, id)
+		header.Set(HeaderUploadPath, path)
+		header.Set(HeaderUploadContentType, contentType)
+		if encoding != "" {
+			header.Set(HeaderUploadContentEncoding, encoding)
+		}
+		header.Set(HeaderChunkIndex, strconv.Itoa(i))
+		header.Set(HeaderChunkCount, strconv.Itoa(len(chunks)))
+
+		var err error
+		response, err = send(ctx, Part{Path: ChunkPath, Header: header, Body: chunk})
+		if err != nil {
+			return nil, fmt.Errorf("failed to send chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+	}
+	return response, nil
+}
+
+// Compress gzip-compresses a payload
+func Compress(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(payload); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Split cuts data into pieces of at most size bytes
+func Split(data []byte, size int) [][]byte {
+	chunks := make([][]byte, 0, (len(data)+size-1)/size)
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+// UploadID identifies a chunked upload. It is derived from the path and
+// payload so a resent payload reuses the ID and the server can discard
+// duplicate chunks.
+func UploadID(path string, payload []byte) string {
+	sum := sha256.Sum256(append([]byte(path+"\n"), payload...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Batches groups JSON documents into NDJSON bodies of at most batchSize
+// lines and, when maxBytes is positive, at most maxBytes bytes. A single
+// document larger than maxBytes gets a batch of its own.
+func Batches(lines [][]byte, batchSize, maxBytes int) [][]byte {
+	var batches [][]byte
+	var current bytes.Buffer
+	count := 0
+
+	for _, line := range lines {
+		size := len(line) + 1
+		full := batchSize > 0 && count >= batchSize
+		tooLarge := maxBytes > 0 && count > 0 && current.Len()+size > maxBytes
+		if full || tooLarge {
+			batches = append(batches, append([]byte(nil), current.Bytes()...))
+			current.Reset()
+			count = 0
+		}
+		current.Write(line)
+		current.WriteByte('\n')
+		count++
+	}
+	if count > 0 {
+		batches = append(batches, append([]byte(nil), current.Bytes()...))
+	}
+	return batches
+}
@@ -0,0 +1,169 @@
+package upload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"waddlebot-bridge/internal/config"
+)
+
+func testUploadConfig() config.UploadConfig {
+	return config.UploadConfig{
+		Compression:      true,
+		CompressMinBytes: 16,
+		Batching:         true,
+		BatchSize:        10,
+		Chunking:         true,
+		MaxPayloadBytes:  1 << 20,
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cfg := testUploadConfig()
+
+	if opts := Negotiate(cfg, nil); opts != (Options{}) {
+		t.Errorf("Expected no features without a server response, got %+v", opts)
+	}
+
+	opts := Negotiate(cfg, &Capabilities{
+		Encodings:       []string{"br", EncodingGzip},
+		Batching:        true,
+		MaxPayloadBytes: 4096,
+	})
+	if !opts.Gzip || !opts.Batching || opts.Chunking {
+		t.Errorf("Unexpected negotiated features: %+v", opts)
+	}
+	if opts.MaxPayloadBytes != 4096 {
+		t.Errorf("Expected server limit 4096, got %d", opts.MaxPayloadBytes)
+	}
+
+	cfg.Compression = false
+	if opts := Negotiate(cfg, &Capabilities{Encodings: []string{EncodingGzip}}); opts.Gzip {
+		t.Error("Expected gzip to stay off when disabled locally")
+	}
+}
+
+func TestSend_CompressesLargePayloads(t *testing.T) {
+	opts := Options{Gzip: true, CompressMinBytes: 16}
+
+	var parts []Part
+	send := func(ctx context.Context, part Part) ([]byte, error) {
+		parts = append(parts, part)
+		return []byte("ok"), nil
+	}
+
+	if _, err := Send(context.Background(), opts, "/small", ContentTypeJSON, []byte(`{}`), send); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	payload := []byte(`{"data":"` + strings.Repeat("a", 100) + `"}`)
+	if _, err := Send(context.Background(), opts, "/large", ContentTypeJSON, payload, send); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if parts[0].Header.Get("Content-Encoding") != "" || string(parts[0].Body) != `{}` {
+		t.Error("Expected small payload to be sent uncompressed")
+	}
+	if parts[1].Header.Get("Content-Encoding") != EncodingGzip {
+		t.Fatal("Expected large payload to be gzip-compressed")
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(parts[1].Body))
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(reader)
+	if !bytes.Equal(decoded, payload) {
+		t.Error("Decompressed body does not match payload")
+	}
+}
+
+func TestSend_ChunksOversizedPayloads(t *testing.T) {
+	opts := Options{Chunking: true, MaxPayloadBytes: 10}
+	payload := []byte(strings.Repeat("x", 25))
+
+	var parts []Part
+	response, err := Send(context.Background(), opts, "/api/bridge/result", ContentTypeJSON, payload, func(ctx context.Context, part Part) ([]byte, error) {
+		parts = append(parts, part)
+		return []byte(part.Header.Get(HeaderChunkIndex)), nil
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if len(parts) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(parts))
+	}
+	if string(response) != "2" {
+		t.Errorf("Expected response of the last chunk, got %q", response)
+	}
+
+	var joined []byte
+	id := parts[0].Header.Get(HeaderUploadID)
+	for _, part := range parts {
+		if part.Path != ChunkPath {
+			t.Errorf("Expected chunk path, got %s", part.Path)
+		}
+		if part.Header.Get(HeaderUploadID) != id || part.Header.Get(HeaderChunkCount) != "3" {
+			t.Error("Expected every chunk to carry the upload ID and count")
+		}
+		if part.Header.Get(HeaderUploadPath) != "/api/bridge/result" {
+			t.Errorf("Unexpected upload path %s", part.Header.Get(HeaderUploadPath))
+		}
+		joined = append(joined, part.Body...)
+	}
+	if !bytes.Equal(joined, payload) {
+		t.Error("Chunks do not reassemble to the payload")
+	}
+}
+
+func TestSend_ChunkFailure(t *testing.T) {
+	opts := Options{Chunking: true, MaxPayloadBytes: 4}
+	sendErr := errors.New("unavailable")
+
+	_, err := Send(context.Background(), opts, "/path", ContentTypeJSON, []byte("0123456789"), func(ctx context.Context, part Part) ([]byte, error) {
+		if part.Header.Get(HeaderChunkIndex) == "1" {
+			return nil, sendErr
+		}
+		return nil, nil
+	})
+	if !errors.Is(err, sendErr) {
+		t.Errorf("Expected chunk error, got %v", err)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	lines := [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`), []byte(`{"c":3}`)}
+
+	batches := Batches(lines, 2, 0)
+	if len(batches) != 2 {
+		t.Fatalf("Expected 2 batches, got %d", len(batches))
+	}
+	if string(batches[0]) != "{\"a\":1}\n{\"b\":2}\n" {
+		t.Errorf("Unexpected first batch %q", batches[0])
+	}
+
+	// Each line is 8 bytes with its newline, so two fit in 16 bytes
+	batches = Batches(lines, 10, 16)
+	if len(batches) != 2 {
+		t.Errorf("Expected byte limit to split into 2 batches, got %d", len(batches))
+	}
+
+	// A line larger than the limit still gets sent on its own
+	batches = Batches([][]byte{[]byte(strings.Repeat("z", 32))}, 10, 16)
+	if len(batches) != 1 {
+		t.Errorf("Expected oversized line in its own batch, got %d", len(batches))
+	}
+}
+
+func TestUploadID_Stable(t *testing.T) {
+	if UploadID("/p", []byte("x")) != UploadID("/p", []byte("x")) {
+		t.Error("Expected the same payload to get the same upload ID")
+	}
+	if UploadID("/p", []byte("x")) == UploadID("/q", []byte("x")) {
+		t.Error("Expected different paths to get different upload IDs")
+	}
+}
@@ -0,0 +1,286 @@
+// Package webhooks manages webhooks registered through the local gateway.
+// Registrations are persisted so they survive restarts, each webhook is
+// attached to the event bus as a sink, and the outcome of every delivery is
+// kept in a bounded history.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/storage"
+)
+
+// Storage buckets
+const (
+	RegistrationsBucket = "webhooks"
+	DeliveriesBucket    = "webhook_deliveries"
+)
+
+const defaultHistorySize = 500
+
+// TestEventType is the type of the event sent by Test
+const TestEventType = "webhook.test"
+
+// ErrWebhookNotFound is returned for an unknown webhook ID
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Bus is the event bus webhooks are attached to
+type Bus interface {
+	AddSink(sink events.Sink, filter config.EventFilter)
+	RemoveSink(name string)
+}
+
+// Webhook is a registered webhook
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Registry holds registered webhooks and their delivery history
+type Registry struct {
+	store    *storage.WebhookRepo
+	bus      Bus
+	config   config.WebhookDeliveryConfig
+	logger   *logrus.Logger
+	webhooks map[string]*Webhook
+	mu       sync.RWMutex
+
+	historyMu sync.Mutex
+	lastKey   int64
+}
+
+// NewRegistry creates a registry backed by store and attaches every
+// persisted webhook to bus. The bus may be nil, in which case webhooks only
+// receive test deliveries.
+func NewRegistry(store storage.Storage, bus Bus, cfg config.WebhookDeliveryConfig, logger *logrus.Logger) (*Registry, error) {
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = defaultHistorySize
+	}
+
+	repo, err := storage.NewWebhookRepo(store, RegistrationsBucket, DeliveriesBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook bucket: %w", err)
+	}
+
+	r := &Registry{
+		store:    repo,
+		bus:      bus,
+		config:   cfg,
+		logger:   logger,
+		webhooks: make(map[string]*Webhook),
+	}
+
+	stored, err := repo.Registrations.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	for id, data := range stored {
+		var webhook Webhook
+		if err := json.Unmarshal(data, &webhook); err != nil {
+			logger.WithError(err).WithField("id", id).Warn("Skipping unreadable webhook")
+			continue
+		}
+		r.webhooks[webhook.ID] = &webhook
+		r.attach(&webhook)
+	}
+
+	if len(r.webhooks) > 0 {
+		logger.WithField("count", len(r.webhooks)).Info("Loaded registered webhooks")
+	}
+	return r, nil
+}
+
+// List returns the registered webhooks ordered by creation time
+func (r *Registry) List() []Webhook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Webhook, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		list = append(list, *webhook)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Get returns a registered webhook
+func (r *Registry) Get(id string) (Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhook, exists := r.webhooks[id]
+	if !exists {
+		return Webhook{}, fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+	}
+	return *webhook, nil
+}
+
+// Register persists a new webhook and attaches it to the bus
+func (r *Registry) Register(url string, eventTypes []string, secret string) (Webhook, error) {
+	webhook := &Webhook{
+		ID:        "webhook_" + uuid.NewString(),
+		URL:       url,
+		Events:    eventTypes,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("failed to marshal webhook: %w", err)
+	}
+	if err := r.store.Registrations.Set(webhook.ID, data); err != nil {
+		return Webhook{}, fmt.Errorf("failed to store webhook: %w", err)
+	}
+
+	r.mu.Lock()
+	r.webhooks[webhook.ID] = webhook
+	r.mu.Unlock()
+
+	r.attach(webhook)
+	return *webhook, nil
+}
+
+// Remove detaches and deletes a webhook. Its delivery history is kept
+// until it ages out.
+func (r *Registry) Remove(id string) error {
+	r.mu.Lock()
+	if _, exists := r.webhooks[id]; !exists {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+	}
+	delete(r.webhooks, id)
+	r.mu.Unlock()
+
+	if r.bus != nil {
+		r.bus.RemoveSink(id)
+	}
+	if err := r.store.Registrations.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// Test sends a test event to a webhook, with the configured retries, and
+// returns the recorded delivery
+func (r *Registry) Test(ctx context.Context, id string) (events.Delivery, error) {
+	webhook, err := r.Get(id)
+	if err != nil {
+		return events.Delivery{}, err
+	}
+
+	delivery, err := r.sink(&webhook).Send(ctx, events.Event{
+		ID:        uuid.NewString(),
+		Type:      TestEventType,
+		Source:    events.SourceBridge,
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"webhook_id": id},
+	})
+	r.RecordDelivery(delivery)
+	return delivery, err
+}
+
+// RecordDelivery appends a delivery to the history, dropping the oldest
+// entries beyond the configured size
+func (r *Registry) RecordDelivery(delivery events.Delivery) {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return
+	}
+
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	// Keys sort chronologically; bump the key if two deliveries share a timestamp
+	key := delivery.Time.UnixNano()
+	if key <= r.lastKey {
+		key = r.lastKey + 1
+	}
+	r.lastKey = key
+
+	if err := r.store.Deliveries.Set(fmt.Sprintf("%020d", key), data); err != nil {
+		r.logger.WithError(err).Warn("Failed to record webhook delivery")
+		return
+	}
+
+	stored, err := r.store.Deliveries.All()
+	if err != nil {
+		return
+	}
+	keys := make([]string, 0, len(stored))
+	for key := range stored {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i := 0; i < len(keys)-r.config.HistorySize; i++ {
+		r.store.Deliveries.Delete(keys[i])
+	}
+}
+
+// Deliveries returns up to limit deliveries, newest first. An empty id
+// returns the deliveries of every webhook; limit <= 0 returns all.
+func (r *Registry) Deliveries(id string, limit int) ([]events.Delivery, error) {
+	r.historyMu.Lock()
+	data, err := r.store.Deliveries.All()
+	r.historyMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook deliveries: %w", err)
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	deliveries := make([]events.Delivery, 0)
+	for _, key := range keys {
+		var delivery events.Delivery
+		if err := json.Unmarshal(data[key], &delivery); err != nil {
+			continue
+		}
+		if id != "" && delivery.Webhook != id {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+		if limit > 0 && len(deliveries) >= limit {
+			break
+		}
+	}
+	return deliveries, nil
+}
+
+// NewSink creates a webhook sink that records its deliveries in the
+// registry's history, for webhooks configured outside the registry
+func (r *Registry) NewSink(name, url, secret string) *events.WebhookSink {
+	sink := events.NewWebhookSink(name, url, secret, r.config)
+	sink.SetRecorder(r)
+	return sink
+}
+
+// attach adds a webhook to the bus, filtered by its event types
+func (r *Registry) attach(webhook *Webhook) {
+	if r.bus == nil {
+		return
+	}
+	r.bus.AddSink(r.sink(webhook), config.EventFilter{Types: webhook.Events})
+}
+
+// sink creates the sink delivering to a webhook
+func (r *Registry) sink(webhook *Webhook) *events.WebhookSink {
+	return r.NewSink(webhook.ID, webhook.URL, webhook.Secret)
+}
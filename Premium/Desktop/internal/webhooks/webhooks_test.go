@@ -0,0 +1,114 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"waddlebot-bridge/internal/config"
+	"waddlebot-bridge/internal/events"
+	"waddlebot-bridge/internal/testutils"
+)
+
+type recordingBus struct {
+	sinks map[string]config.EventFilter
+}
+
+func (b *recordingBus) AddSink(sink events.Sink, filter config.EventFilter) {
+	b.sinks[sink.Name()] = filter
+}
+
+func (b *recordingBus) RemoveSink(name string) {
+	delete(b.sinks, name)
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestRegistry_PersistsRegistrations(t *testing.T) {
+	store := testutils.NewMockStorage()
+	bus := &recordingBus{sinks: make(map[string]config.EventFilter)}
+
+	registry, err := NewRegistry(store, bus, config.WebhookDeliveryConfig{}, testLogger())
+	if err != nil {
+		t.Fatalf("NewRegistry failed: %v", err)
+	}
+	webhook, err := registry.Register("http://example.invalid/hook", []string{"obs.*"}, "secret")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if filter, ok := bus.sinks[webhook.ID]; !ok || filter.Types[0] != "obs.*" {
+		t.Errorf("Expected webhook to be attached filtered by its events, got %v", bus.sinks)
+	}
+
+	// A new registry over the same storage restores and reattaches it
+	restoredBus := &recordingBus{sinks: make(map[string]config.EventFilter)}
+	restored, err := NewRegistry(store, restoredBus, config.WebhookDeliveryConfig{}, testLogger())
+	if err != nil {
+		t.Fatalf("NewRegistry failed: %v", err)
+	}
+	loaded, err := restored.Get(webhook.ID)
+	if err != nil || loaded.Secret != "secret" {
+		t.Errorf("Expected persisted webhook, got %+v: %v", loaded, err)
+	}
+	if _, ok := restoredBus.sinks[webhook.ID]; !ok {
+		t.Error("Expected restored webhook to be attached")
+	}
+
+	if err := restored.Remove(webhook.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, ok := restoredBus.sinks[webhook.ID]; ok {
+		t.Error("Expected removed webhook to be detached")
+	}
+	if err := restored.Remove(webhook.ID); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+	}
+	if again, _ := NewRegistry(store, nil, config.WebhookDeliveryConfig{}, testLogger()); len(again.List()) != 0 {
+		t.Error("Expected removal to be persisted")
+	}
+}
+
+func TestRegistry_TestRecordsHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(events.HeaderEvent) != TestEventType || r.Header.Get(events.HeaderSignature) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry, err := NewRegistry(testutils.NewMockStorage(), nil, config.WebhookDeliveryConfig{HistorySize: 2}, testLogger())
+	if err != nil {
+		t.Fatalf("NewRegistry failed: %v", err)
+	}
+	webhook, _ := registry.Register(server.URL, []string{"*"}, "secret")
+
+	for i := 0; i < 3; i++ {
+		if _, err := registry.Test(context.Background(), webhook.ID); err != nil {
+			t.Fatalf("Test delivery failed: %v", err)
+		}
+	}
+	registry.RecordDelivery(events.Delivery{Webhook: "other", Time: time.Now()})
+
+	deliveries, err := registry.Deliveries(webhook.ID, 0)
+	if err != nil {
+		t.Fatalf("Deliveries failed: %v", err)
+	}
+	if len(deliveries) != 1 || !deliveries[0].Success {
+		t.Errorf("Expected history trimmed to one successful delivery for the webhook, got %+v", deliveries)
+	}
+	if all, _ := registry.Deliveries("", 0); len(all) != 2 {
+		t.Errorf("Expected history of 2 deliveries, got %d", len(all))
+	}
+}
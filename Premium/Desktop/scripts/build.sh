@@ -17,6 +17,11 @@ NC='\033[0m' # No Color
 # Configuration
 APP_NAME="waddlebot-bridge"
 VERSION="1.0.0"
+# Base64 ed25519 key that verifies releases installed by the update command
+RELEASE_PUBLIC_KEY="${RELEASE_PUBLIC_KEY:-}"
+# Base64 ed25519 key that verifies subscription entitlement tokens
+LICENSE_PUBLIC_KEY="${LICENSE_PUBLIC_KEY:-}"
+LDFLAGS="-s -w -X main.version=$VERSION -X waddlebot-bridge/internal/update.ReleaseKey=$RELEASE_PUBLIC_KEY -X waddlebot-bridge/internal/license.SigningKey=$LICENSE_PUBLIC_KEY"
 BUILD_DIR="build"
 DIST_DIR="dist"
 
@@ -57,10 +62,10 @@ cd ../../../../
 # Build macOS Universal Binary
 print_status "Building macOS Universal Binary..."
 print_status "Building for macOS arm64..."
-CGO_ENABLED=0 GOOS=darwin GOARCH=arm64 go build -ldflags="-s -w -X main.version=$VERSION" -o $BUILD_DIR/${APP_NAME}-darwin-arm64 cmd/main.go
+CGO_ENABLED=0 GOOS=darwin GOARCH=arm64 go build -ldflags="$LDFLAGS" -o $BUILD_DIR/${APP_NAME}-darwin-arm64 ./cmd
 
 print_status "Building for macOS amd64..."
-CGO_ENABLED=0 GOOS=darwin GOARCH=amd64 go build -ldflags="-s -w -X main.version=$VERSION" -o $BUILD_DIR/${APP_NAME}-darwin-amd64 cmd/main.go
+CGO_ENABLED=0 GOOS=darwin GOARCH=amd64 go build -ldflags="$LDFLAGS" -o $BUILD_DIR/${APP_NAME}-darwin-amd64 ./cmd
 
 # Create Universal Binary
 print_status "Creating Universal Binary..."
@@ -74,11 +79,11 @@ fi
 
 # Build Windows 11 Binary
 print_status "Building Windows 11 Binary..."
-CGO_ENABLED=0 GOOS=windows GOARCH=amd64 go build -ldflags="-s -w -X main.version=$VERSION" -o $BUILD_DIR/${APP_NAME}-windows-amd64.exe cmd/main.go
+CGO_ENABLED=0 GOOS=windows GOARCH=amd64 go build -ldflags="$LDFLAGS" -o $BUILD_DIR/${APP_NAME}-windows-amd64.exe ./cmd
 
 # Build Linux Binary (for completeness)
 print_status "Building Linux Binary..."
-CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags="-s -w -X main.version=$VERSION" -o $BUILD_DIR/${APP_NAME}-linux-amd64 cmd/main.go
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags="$LDFLAGS" -o $BUILD_DIR/${APP_NAME}-linux-amd64 ./cmd
 
 # Create distribution packages
 print_status "Creating distribution packages..."
@@ -0,0 +1,99 @@
+/**
+ * Call Recordings Controller - Recordings of community call rooms
+ * Recordings are made by module_rtc, which reports them here once they end
+ */
+import { query } from '../config/database.js';
+import { errors } from '../middleware/errorHandler.js';
+import { logger } from '../utils/logger.js';
+
+/**
+ * Record a finished call recording
+ * Called by module_rtc when a LiveKit egress ends
+ */
+export async function recordCallRecording(req, res, next) {
+  try {
+    const {
+      communityId,
+      roomName,
+      egressId,
+      kind,
+      trackId,
+      status,
+      destination,
+      location,
+      filename,
+      sizeBytes,
+      durationSeconds,
+      error,
+      startedBy,
+      startedAt,
+      endedAt,
+    } = req.body;
+
+    if (!communityId || !roomName || !egressId || !kind || !status || !destination || !startedAt) {
+      return next(errors.badRequest('Missing required fields: communityId, roomName, egressId, kind, status, destination, startedAt'));
+    }
+
+    if (!['room', 'track'].includes(kind)) {
+      return next(errors.badRequest('kind must be room or track'));
+    }
+
+    await query(
+      `INSERT INTO call_recordings
+       (community_id, room_name, egress_id, kind, track_id, status, destination, location,
+        filename, size_bytes, duration_seconds, error, started_by, started_at, ended_at)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+       ON CONFLICT (egress_id) DO UPDATE SET
+         status = EXCLUDED.status,
+         location = EXCLUDED.location,
+         filename = EXCLUDED.filename,
+         size_bytes = EXCLUDED.size_bytes,
+         duration_seconds = EXCLUDED.duration_seconds,
+         error = EXCLUDED.error,
+         ended_at = EXCLUDED.ended_at`,
+      [
+        communityId, roomName, egressId, kind, trackId || null, status, destination,
+        location || null, filename || null, sizeBytes || 0, durationSeconds || 0,
+        error || null, startedBy || null, startedAt, endedAt || null,
+      ]
+    );
+
+    res.json({ success: true });
+  } catch (err) {
+    logger.error('Error recording call recording', { error: err.message });
+    next(err);
+  }
+}
+
+/**
+ * Get a community's call recordings, newest first
+ */
+export async function getCallRecordings(req, res, next) {
+  try {
+    const { communityId } = req.params;
+    const limit = Math.min(Math.max(parseInt(req.query.limit, 10) || 50, 1), 200);
+    const offset = Math.max(parseInt(req.query.offset, 10) || 0, 0);
+
+    const params = [communityId];
+    let roomFilter = '';
+    if (req.query.room_name) {
+      params.push(req.query.room_name);
+      roomFilter = `AND room_name = $${params.length}`;
+    }
+
+    const result = await query(
+      `SELECT id, room_name, egress_id, kind, track_id, status, destination, location,
+              filename, size_bytes, duration_seconds, error, started_by, started_at, ended_at
+       FROM call_recordings
+       WHERE community_id = $1 ${roomFilter}
+       ORDER BY started_at DESC
+       LIMIT ${limit} OFFSET ${offset}`,
+      params
+    );
+
+    res.json({ success: true, recordings: result.rows });
+  } catch (err) {
+    logger.error('Error getting call recordings', { error: err.message });
+    next(err);
+  }
+}
@@ -8,21 +8,27 @@ import logger from '../utils/logger.js';
 const MODULE_RTC_URL = process.env.MODULE_RTC_URL || 'http://core-module-rtc:8093';
 
 /**
- * Get all call rooms for a community
+ * Get a page of a community's call rooms
+ * Passes state, sort, order, limit and offset through to module_rtc
  */
 export async function getCallRooms(req, res) {
   try {
     const { communityId } = req.params;
-    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/rooms`, {
-      params: { community_id: communityId },
+    const { state, sort, order, limit, offset } = req.query;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/communities/${encodeURIComponent(communityId)}/rooms`, {
+      // Repeated states arrive as an array, which axios would send as state[]
+      params: { state: [].concat(state || []).join(',') || undefined, sort, order, limit, offset },
       headers: { Authorization: req.headers.authorization }
     });
-    res.json({ success: true, rooms: response.data.rooms || [] });
+    res.json({
+      success: true,
+      rooms: response.data.rooms || [],
+      total: response.data.total || 0,
+      limit: response.data.limit,
+      offset: response.data.offset
+    });
   } catch (error) {
     logger.error('Failed to get call rooms:', error.message);
-    if (error.response?.status === 404) {
-      return res.json({ success: true, rooms: [] });
-    }
     res.status(error.response?.status || 500).json({
       success: false,
       error: error.response?.data?.error || 'Failed to get call rooms'
@@ -56,11 +62,12 @@ export async function getCallRoom(req, res) {
 export async function createCallRoom(req, res) {
   try {
     const { communityId } = req.params;
-    const { room_name, max_participants } = req.body;
+    const { room_name, template, max_participants } = req.body;
     const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms`, {
       community_id: communityId,
       room_name,
-      max_participants: max_participants || 100
+      template,
+      max_participants
     }, {
       headers: { Authorization: req.headers.authorization }
     });
@@ -160,19 +167,24 @@ export async function getCallParticipants(req, res) {
 }
 
 /**
- * Kick a participant from a room
+ * Kick a participant from a room, optionally banning them from rejoining
  */
 export async function kickCallParticipant(req, res) {
   try {
-    const { communityId, roomName } = req.params;
-    const { identity } = req.body;
-    await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/kick`, {
-      community_id: communityId,
-      identity
+    const { roomName } = req.params;
+    const { identity, ban, reason, duration_minutes } = req.body;
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/kick/${encodeURIComponent(identity)}`, {
+      ban: Boolean(ban),
+      reason,
+      duration_minutes
     }, {
       headers: { Authorization: req.headers.authorization }
     });
-    res.json({ success: true, message: 'Participant removed' });
+    res.json({
+      success: true,
+      ban: response.data.ban,
+      message: ban ? 'Participant banned' : 'Participant removed'
+    });
   } catch (error) {
     logger.error('Failed to kick participant:', error.message);
     res.status(error.response?.status || 500).json({
@@ -182,6 +194,335 @@ export async function kickCallParticipant(req, res) {
   }
 }
 
+/**
+ * Get the bans in force in a room
+ */
+export async function getCallBans(req, res) {
+  try {
+    const { roomName } = req.params;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/bans`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, bans: response.data.bans || [] });
+  } catch (error) {
+    logger.error('Failed to get call bans:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get call bans'
+    });
+  }
+}
+
+/**
+ * Lift a user's ban from a room
+ */
+export async function liftCallBan(req, res) {
+  try {
+    const { roomName, userId } = req.params;
+    await axios.delete(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/bans/${encodeURIComponent(userId)}`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Ban lifted' });
+  } catch (error) {
+    logger.error('Failed to lift call ban:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to lift call ban'
+    });
+  }
+}
+
+/**
+ * Get the join tokens issued for a room, optionally for one user
+ */
+export async function getCallTokens(req, res) {
+  try {
+    const { roomName } = req.params;
+    const { user_id, limit } = req.query;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/tokens`, {
+      params: { user_id, limit },
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, tokens: response.data.tokens || [] });
+  } catch (error) {
+    logger.error('Failed to get call tokens:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get call tokens'
+    });
+  }
+}
+
+/**
+ * Revoke a user's join tokens for a room and remove them from it
+ */
+export async function revokeCallTokens(req, res) {
+  try {
+    const { roomName, userId } = req.params;
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/revoke-tokens/${encodeURIComponent(userId)}`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, revoked: response.data.revoked });
+  } catch (error) {
+    logger.error('Failed to revoke call tokens:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to revoke call tokens'
+    });
+  }
+}
+
+/**
+ * Set how long join tokens for a room last
+ */
+export async function setCallTokenTTL(req, res) {
+  try {
+    const { roomName } = req.params;
+    const seconds = parseInt(req.body.seconds, 10);
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/token-ttl`, {
+      seconds
+    }, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, token_ttl_seconds: response.data.token_ttl_seconds });
+  } catch (error) {
+    logger.error('Failed to set call token lifetime:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to set call token lifetime'
+    });
+  }
+}
+
+/**
+ * Set how many may be in a room at once
+ */
+export async function setCallMaxParticipants(req, res) {
+  try {
+    const { roomName } = req.params;
+    const maxParticipants = parseInt(req.body.max_participants, 10);
+    await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/max-participants`, {
+      max_participants: maxParticipants
+    }, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, max_participants: maxParticipants });
+  } catch (error) {
+    logger.error('Failed to set max participants:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to set max participants'
+    });
+  }
+}
+
+/**
+ * Set how clients in a room process the audio they publish
+ */
+export async function setCallAudioSettings(req, res) {
+  try {
+    const { roomName } = req.params;
+    const settings = {};
+    for (const key of ['noise_suppression', 'echo_cancellation', 'auto_gain_control', 'red', 'dtx']) {
+      if (req.body[key] !== undefined) {
+        settings[key] = req.body[key];
+      }
+    }
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/audio`, settings, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, audio: response.data.audio });
+  } catch (error) {
+    logger.error('Failed to set audio settings:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to set audio settings'
+    });
+  }
+}
+
+/**
+ * Get what a room is watching together and where playback is
+ */
+export async function getCallWatchParty(req, res) {
+  try {
+    const { roomName } = req.params;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/watch`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, watch: response.data });
+  } catch (error) {
+    logger.error('Failed to get watch party:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get watch party'
+    });
+  }
+}
+
+/**
+ * Start a room watching media together
+ */
+export async function startCallWatchParty(req, res) {
+  try {
+    const { roomName } = req.params;
+    const { media_url } = req.body;
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/watch`, {
+      media_url
+    }, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.status(201).json({ success: true, watch: response.data });
+  } catch (error) {
+    logger.error('Failed to start watch party:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to start watch party'
+    });
+  }
+}
+
+/**
+ * Play, pause or seek what a room is watching
+ */
+export async function controlCallWatchParty(req, res) {
+  try {
+    const { roomName, action } = req.params;
+    const control = {};
+    if (req.body.position !== undefined) {
+      control.position = parseFloat(req.body.position);
+    }
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/watch/${encodeURIComponent(action)}`, control, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, watch: response.data });
+  } catch (error) {
+    logger.error('Failed to control watch party:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to control watch party'
+    });
+  }
+}
+
+/**
+ * Stop what a room is watching
+ */
+export async function stopCallWatchParty(req, res) {
+  try {
+    const { roomName } = req.params;
+    await axios.delete(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/watch`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Watch party stopped' });
+  } catch (error) {
+    logger.error('Failed to stop watch party:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to stop watch party'
+    });
+  }
+}
+
+/**
+ * Set who may share their screen in a room
+ */
+export async function setCallScreenShare(req, res) {
+  try {
+    const { communityId, roomName } = req.params;
+    const { screen_share } = req.body;
+    await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/screen-share`, {
+      community_id: communityId,
+      screen_share
+    }, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, screen_share });
+  } catch (error) {
+    logger.error('Failed to set screen share:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to set screen share'
+    });
+  }
+}
+
+/**
+ * Stop a participant's screen share
+ */
+export async function stopCallScreenShare(req, res) {
+  try {
+    const { roomName, userId } = req.params;
+    await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/stop-screen-share/${encodeURIComponent(userId)}`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Screen share stopped' });
+  } catch (error) {
+    logger.error('Failed to stop screen share:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to stop screen share'
+    });
+  }
+}
+
+/**
+ * Stop a participant publishing their camera and microphone
+ */
+export async function revokeCallMedia(req, res) {
+  try {
+    const { roomName, userId } = req.params;
+    await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/revoke-media/${encodeURIComponent(userId)}`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Media revoked' });
+  } catch (error) {
+    logger.error('Failed to revoke media:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to revoke media'
+    });
+  }
+}
+
+/**
+ * Let a participant waiting in the lobby into the room
+ */
+export async function admitCallParticipant(req, res) {
+  try {
+    const { roomName, userId } = req.params;
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/admit/${encodeURIComponent(userId)}`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, ...response.data });
+  } catch (error) {
+    logger.error('Failed to admit participant:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to admit participant'
+    });
+  }
+}
+
+/**
+ * Let a participant publish their camera and microphone again
+ */
+export async function restoreCallMedia(req, res) {
+  try {
+    const { roomName, userId } = req.params;
+    await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/restore-media/${encodeURIComponent(userId)}`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Media restored' });
+  } catch (error) {
+    logger.error('Failed to restore media:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to restore media'
+    });
+  }
+}
+
 /**
  * Mute all participants in a room
  */
@@ -248,3 +589,344 @@ export async function acknowledgeHand(req, res) {
     });
   }
 }
+
+/**
+ * Unmute the first hand in the queue
+ */
+export async function nextCallSpeaker(req, res) {
+  try {
+    const { roomName } = req.params;
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/next-speaker`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, speaker: response.data });
+  } catch (error) {
+    logger.error('Failed to pick next speaker:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to pick next speaker'
+    });
+  }
+}
+
+/**
+ * Set how long raised hands stay up
+ */
+export async function setCallHandExpiry(req, res) {
+  try {
+    const { roomName } = req.params;
+    const minutes = parseInt(req.body.minutes, 10);
+    await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/hand-expiry`, {
+      minutes
+    }, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, hand_expiry_minutes: minutes });
+  } catch (error) {
+    logger.error('Failed to set hand expiry:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to set hand expiry'
+    });
+  }
+}
+
+/**
+ * Get speaking time, sessions and peak concurrency for a room
+ */
+export async function getCallRoomAnalytics(req, res) {
+  try {
+    const { communityId, roomName } = req.params;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/analytics`, {
+      params: { community_id: communityId },
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, analytics: response.data });
+  } catch (error) {
+    logger.error('Failed to get room analytics:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get room analytics'
+    });
+  }
+}
+
+/**
+ * Get the community's call usage over the last days
+ */
+export async function getCallUsage(req, res) {
+  try {
+    const { communityId } = req.params;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/communities/${encodeURIComponent(communityId)}/usage`, {
+      params: { days: req.query.days },
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, usage: response.data });
+  } catch (error) {
+    logger.error('Failed to get call usage:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get call usage'
+    });
+  }
+}
+
+/**
+ * Get a room's guest invites
+ */
+export async function getCallInvites(req, res) {
+  try {
+    const { roomName } = req.params;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/invites`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, invites: response.data.invites || [] });
+  } catch (error) {
+    logger.error('Failed to get call invites:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get call invites'
+    });
+  }
+}
+
+/**
+ * Create a single-use guest invite link to a room
+ */
+export async function createCallInvite(req, res) {
+  try {
+    const { roomName } = req.params;
+    const { role, expires_in_minutes } = req.body;
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/invites`, {
+      role,
+      expires_in_minutes
+    }, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.status(201).json({ success: true, invite: response.data });
+  } catch (error) {
+    logger.error('Failed to create call invite:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to create call invite'
+    });
+  }
+}
+
+/**
+ * Revoke a guest invite
+ */
+export async function revokeCallInvite(req, res) {
+  try {
+    const { roomName, inviteId } = req.params;
+    await axios.delete(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/invites/${encodeURIComponent(inviteId)}`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Invite revoked' });
+  } catch (error) {
+    logger.error('Failed to revoke call invite:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to revoke call invite'
+    });
+  }
+}
+
+/**
+ * Get a room's dial-in PIN and the numbers to call
+ */
+export async function getCallDialIn(req, res) {
+  try {
+    const { roomName } = req.params;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/dial-in`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, dialIn: response.data });
+  } catch (error) {
+    logger.error('Failed to get call dial-in:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get call dial-in'
+    });
+  }
+}
+
+/**
+ * Enable phone dial-in for a room, or give it a new PIN
+ */
+export async function enableCallDialIn(req, res) {
+  try {
+    const { roomName } = req.params;
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/dial-in`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.status(201).json({ success: true, dialIn: response.data });
+  } catch (error) {
+    logger.error('Failed to enable call dial-in:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to enable call dial-in'
+    });
+  }
+}
+
+/**
+ * Disable phone dial-in for a room
+ */
+export async function disableCallDialIn(req, res) {
+  try {
+    const { roomName } = req.params;
+    await axios.delete(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/dial-in`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Dial-in disabled' });
+  } catch (error) {
+    logger.error('Failed to disable call dial-in:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to disable call dial-in'
+    });
+  }
+}
+
+/**
+ * Start live captions in a room
+ */
+export async function startCallTranscription(req, res) {
+  try {
+    const { roomName } = req.params;
+    const response = await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/transcription/start`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, tracks: response.data.tracks });
+  } catch (error) {
+    logger.error('Failed to start call transcription:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to start call transcription'
+    });
+  }
+}
+
+/**
+ * Stop live captions in a room
+ */
+export async function stopCallTranscription(req, res) {
+  try {
+    const { roomName } = req.params;
+    await axios.post(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/transcription/stop`, {}, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Transcription stopped' });
+  } catch (error) {
+    logger.error('Failed to stop call transcription:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to stop call transcription'
+    });
+  }
+}
+
+/**
+ * Get a room's transcript
+ */
+export async function getCallTranscript(req, res) {
+  try {
+    const { roomName } = req.params;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/transcript`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, segments: response.data.segments });
+  } catch (error) {
+    logger.error('Failed to get call transcript:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get call transcript'
+    });
+  }
+}
+
+/**
+ * Delete a room's transcript
+ */
+export async function deleteCallTranscript(req, res) {
+  try {
+    const { roomName } = req.params;
+    await axios.delete(`${MODULE_RTC_URL}/api/v1/rooms/${encodeURIComponent(roomName)}/transcript`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Transcript deleted' });
+  } catch (error) {
+    logger.error('Failed to delete call transcript:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to delete call transcript'
+    });
+  }
+}
+
+/**
+ * Get the community's room templates
+ */
+export async function getCallRoomTemplates(req, res) {
+  try {
+    const { communityId } = req.params;
+    const response = await axios.get(`${MODULE_RTC_URL}/api/v1/communities/${encodeURIComponent(communityId)}/room-templates`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, templates: response.data.templates || [] });
+  } catch (error) {
+    logger.error('Failed to get room templates:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to get room templates'
+    });
+  }
+}
+
+/**
+ * Create or replace a room template
+ */
+export async function saveCallRoomTemplate(req, res) {
+  try {
+    const { communityId, name } = req.params;
+    const { max_participants, empty_timeout_seconds, default_role, lobby, recording, codecs, is_default } = req.body;
+    const response = await axios.put(`${MODULE_RTC_URL}/api/v1/communities/${encodeURIComponent(communityId)}/room-templates/${encodeURIComponent(name)}`, {
+      max_participants,
+      empty_timeout_seconds,
+      default_role,
+      lobby,
+      recording,
+      codecs,
+      is_default
+    }, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, template: response.data });
+  } catch (error) {
+    logger.error('Failed to save room template:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to save room template'
+    });
+  }
+}
+
+/**
+ * Delete a room template
+ */
+export async function deleteCallRoomTemplate(req, res) {
+  try {
+    const { communityId, name } = req.params;
+    await axios.delete(`${MODULE_RTC_URL}/api/v1/communities/${encodeURIComponent(communityId)}/room-templates/${encodeURIComponent(name)}`, {
+      headers: { Authorization: req.headers.authorization }
+    });
+    res.json({ success: true, message: 'Room template deleted' });
+  } catch (error) {
+    logger.error('Failed to delete room template:', error.message);
+    res.status(error.response?.status || 500).json({
+      success: false,
+      error: error.response?.data?.error || 'Failed to delete room template'
+    });
+  }
+}
@@ -0,0 +1,136 @@
+/**
+ * Service Registry Controller - Modules registering in the service catalog
+ * Modules register on startup, send heartbeats while running and deregister
+ * on shutdown, so the catalog reflects which services are available
+ */
+import { query } from '../config/database.js';
+import { errors } from '../middleware/errorHandler.js';
+import { logger } from '../utils/logger.js';
+
+/**
+ * Record an event in a service's history
+ */
+async function recordServiceEvent(serviceId, eventType, message, metadata = {}) {
+  await query(
+    `INSERT INTO service_events (service_id, event_type, message, metadata)
+     VALUES ($1, $2, $3, $4)`,
+    [serviceId, eventType, message, JSON.stringify(metadata)]
+  );
+}
+
+/**
+ * Register a service, or update its entry, and mark it healthy
+ */
+export async function registerService(req, res, next) {
+  try {
+    const {
+      name,
+      version,
+      category,
+      description,
+      url,
+      port,
+      healthEndpoint,
+      endpoints,
+      capabilities,
+      heartbeatInterval,
+    } = req.body;
+
+    if (!name || !url) {
+      return next(errors.badRequest('Missing required fields: name, url'));
+    }
+
+    const result = await query(
+      `INSERT INTO services
+       (name, description, category, url, port, health_endpoint, status, version,
+        endpoints, capabilities, heartbeat_interval, last_heartbeat, last_checked, is_active)
+       VALUES ($1, $2, $3, $4, $5, $6, 'healthy', $7, $8, $9, $10, NOW(), NOW(), true)
+       ON CONFLICT (name) DO UPDATE SET
+         description = COALESCE(EXCLUDED.description, services.description),
+         category = COALESCE(EXCLUDED.category, services.category),
+         url = EXCLUDED.url,
+         port = EXCLUDED.port,
+         health_endpoint = COALESCE(EXCLUDED.health_endpoint, services.health_endpoint),
+         status = 'healthy',
+         version = EXCLUDED.version,
+         endpoints = EXCLUDED.endpoints,
+         capabilities = EXCLUDED.capabilities,
+         heartbeat_interval = EXCLUDED.heartbeat_interval,
+         last_heartbeat = NOW(),
+         last_checked = NOW(),
+         is_active = true
+       RETURNING id`,
+      [
+        name, description || null, category || null, url, port || null,
+        healthEndpoint || null, version || null,
+        JSON.stringify(endpoints || {}), JSON.stringify(capabilities || []),
+        heartbeatInterval || null,
+      ]
+    );
+
+    await recordServiceEvent(result.rows[0].id, 'status_change',
+      `${name} registered${version ? ` (v${version})` : ''}`, { url, capabilities: capabilities || [] });
+
+    logger.info('Service registered', { name, version, url });
+    res.json({ success: true });
+  } catch (err) {
+    logger.error('Error registering service', { error: err.message });
+    next(err);
+  }
+}
+
+/**
+ * Record a heartbeat from a registered service
+ * Unknown services get a 404 so they register again
+ */
+export async function recordHeartbeat(req, res, next) {
+  try {
+    const { name } = req.params;
+
+    const result = await query(
+      `UPDATE services
+       SET last_heartbeat = NOW(), last_checked = NOW(), status = 'healthy'
+       WHERE name = $1 AND is_active = true
+       RETURNING id`,
+      [name]
+    );
+
+    if (result.rows.length === 0) {
+      return next(errors.notFound('Service is not registered'));
+    }
+
+    res.json({ success: true });
+  } catch (err) {
+    logger.error('Error recording service heartbeat', { error: err.message });
+    next(err);
+  }
+}
+
+/**
+ * Mark a service stopped when it shuts down
+ */
+export async function deregisterService(req, res, next) {
+  try {
+    const { name } = req.params;
+
+    const result = await query(
+      `UPDATE services
+       SET status = 'stopped', last_checked = NOW()
+       WHERE name = $1
+       RETURNING id`,
+      [name]
+    );
+
+    if (result.rows.length === 0) {
+      return next(errors.notFound('Service is not registered'));
+    }
+
+    await recordServiceEvent(result.rows[0].id, 'status_change', `${name} deregistered`);
+
+    logger.info('Service deregistered', { name });
+    res.json({ success: true });
+  } catch (err) {
+    logger.error('Error deregistering service', { error: err.message });
+    next(err);
+  }
+}
@@ -3,6 +3,7 @@
  */
 import { Router } from 'express';
 import * as callsController from '../controllers/callsController.js';
+import * as callRecordingsController from '../controllers/callRecordingsController.js';
 import { requireAuth, requireCommunityAdmin } from '../middleware/auth.js';
 import { validators, validateRequest } from '../middleware/validation.js';
 
@@ -34,6 +35,7 @@ router.post(
   '/:communityId/calls/rooms',
   requireCommunityAdmin,
   validators.text('room_name', { min: 1, max: 100 }),
+  validators.text('template', { max: 64, optional: true }),
   validators.integer('max_participants', { min: 2, max: 1000, optional: true }),
   validateRequest,
   callsController.createCallRoom
@@ -64,6 +66,95 @@ router.post(
   callsController.unlockCallRoom
 );
 
+// Set how many may be in a room at once
+router.post(
+  '/:communityId/calls/rooms/:roomName/max-participants',
+  requireCommunityAdmin,
+  validators.integer('max_participants', { min: 1, max: 1000 }),
+  validateRequest,
+  callsController.setCallMaxParticipants
+);
+
+// Set how clients process the audio they publish
+router.post(
+  '/:communityId/calls/rooms/:roomName/audio',
+  requireCommunityAdmin,
+  validators.text('noise_suppression', { pattern: /^(krisp|rnnoise|browser|off)$/, optional: true }),
+  validators.boolean('echo_cancellation', { optional: true }),
+  validators.boolean('auto_gain_control', { optional: true }),
+  validators.boolean('red', { optional: true }),
+  validators.boolean('dtx', { optional: true }),
+  validateRequest,
+  callsController.setCallAudioSettings
+);
+
+// Get what the room is watching together
+router.get(
+  '/:communityId/calls/rooms/:roomName/watch',
+  requireCommunityAdmin,
+  callsController.getCallWatchParty
+);
+
+// Start the room watching media together
+router.post(
+  '/:communityId/calls/rooms/:roomName/watch',
+  requireCommunityAdmin,
+  validators.text('media_url', { pattern: /^https?:\/\/\S+$/ }),
+  validateRequest,
+  callsController.startCallWatchParty
+);
+
+// Play, pause or seek what the room is watching
+router.post(
+  '/:communityId/calls/rooms/:roomName/watch/:action',
+  requireCommunityAdmin,
+  callsController.controlCallWatchParty
+);
+
+// Stop watching
+router.delete(
+  '/:communityId/calls/rooms/:roomName/watch',
+  requireCommunityAdmin,
+  callsController.stopCallWatchParty
+);
+
+// Set who may share their screen
+router.post(
+  '/:communityId/calls/rooms/:roomName/screen-share',
+  requireCommunityAdmin,
+  validators.text('screen_share', { pattern: /^(hosts|speakers|everyone)$/ }),
+  validateRequest,
+  callsController.setCallScreenShare
+);
+
+// Stop a participant's screen share
+router.post(
+  '/:communityId/calls/rooms/:roomName/participants/:userId/stop-screen-share',
+  requireCommunityAdmin,
+  callsController.stopCallScreenShare
+);
+
+// Stop a participant publishing their camera and microphone
+router.post(
+  '/:communityId/calls/rooms/:roomName/participants/:userId/revoke-media',
+  requireCommunityAdmin,
+  callsController.revokeCallMedia
+);
+
+// Let a participant waiting in the lobby into the room
+router.post(
+  '/:communityId/calls/rooms/:roomName/participants/:userId/admit',
+  requireCommunityAdmin,
+  callsController.admitCallParticipant
+);
+
+// Let a participant publish their camera and microphone again
+router.post(
+  '/:communityId/calls/rooms/:roomName/participants/:userId/restore-media',
+  requireCommunityAdmin,
+  callsController.restoreCallMedia
+);
+
 /**
  * Participant Management
  */
@@ -75,15 +166,56 @@ router.get(
   callsController.getCallParticipants
 );
 
-// Kick a participant
+// Kick a participant, banning them when ban is set; a duration of 0 bans
+// until lifted
 router.post(
   '/:communityId/calls/rooms/:roomName/kick',
   requireCommunityAdmin,
   validators.text('identity', { min: 1, max: 255 }),
+  validators.boolean('ban', { optional: true }),
+  validators.text('reason', { max: 500, optional: true }),
+  validators.integer('duration_minutes', { min: 0, optional: true }),
   validateRequest,
   callsController.kickCallParticipant
 );
 
+// Get a room's bans
+router.get(
+  '/:communityId/calls/rooms/:roomName/bans',
+  requireCommunityAdmin,
+  callsController.getCallBans
+);
+
+// Lift a ban
+router.delete(
+  '/:communityId/calls/rooms/:roomName/bans/:userId',
+  requireCommunityAdmin,
+  callsController.liftCallBan
+);
+
+// Get the join tokens issued for a room
+router.get(
+  '/:communityId/calls/rooms/:roomName/tokens',
+  requireCommunityAdmin,
+  callsController.getCallTokens
+);
+
+// Revoke a user's join tokens and remove them from the room
+router.post(
+  '/:communityId/calls/rooms/:roomName/participants/:userId/revoke-tokens',
+  requireCommunityAdmin,
+  callsController.revokeCallTokens
+);
+
+// Set how long join tokens for a room last
+router.post(
+  '/:communityId/calls/rooms/:roomName/token-ttl',
+  requireCommunityAdmin,
+  validators.integer('seconds', { min: 0, max: 86400 }),
+  validateRequest,
+  callsController.setCallTokenTTL
+);
+
 // Mute all participants
 router.post(
   '/:communityId/calls/rooms/:roomName/mute-all',
@@ -111,4 +243,167 @@ router.post(
   callsController.acknowledgeHand
 );
 
+// Unmute the first hand in the queue
+router.post(
+  '/:communityId/calls/rooms/:roomName/next-speaker',
+  requireCommunityAdmin,
+  callsController.nextCallSpeaker
+);
+
+// Lower unacknowledged hands after a number of minutes
+router.post(
+  '/:communityId/calls/rooms/:roomName/hand-expiry',
+  requireCommunityAdmin,
+  validators.integer('minutes', { min: 0, max: 1440 }),
+  validateRequest,
+  callsController.setCallHandExpiry
+);
+
+/**
+ * Guest Invites
+ */
+
+// Get a room's guest invites
+router.get(
+  '/:communityId/calls/rooms/:roomName/invites',
+  requireCommunityAdmin,
+  callsController.getCallInvites
+);
+
+// Create a single-use guest invite link
+router.post(
+  '/:communityId/calls/rooms/:roomName/invites',
+  requireCommunityAdmin,
+  validators.text('role', { max: 20, optional: true }),
+  validators.integer('expires_in_minutes', { min: 0, max: 10080, optional: true }),
+  validateRequest,
+  callsController.createCallInvite
+);
+
+// Revoke a guest invite
+router.delete(
+  '/:communityId/calls/rooms/:roomName/invites/:inviteId',
+  requireCommunityAdmin,
+  callsController.revokeCallInvite
+);
+
+/**
+ * Phone Dial-In
+ */
+
+// Get a room's dial-in PIN and numbers
+router.get(
+  '/:communityId/calls/rooms/:roomName/dial-in',
+  requireCommunityAdmin,
+  callsController.getCallDialIn
+);
+
+// Enable dial-in, or change the room's PIN
+router.post(
+  '/:communityId/calls/rooms/:roomName/dial-in',
+  requireCommunityAdmin,
+  callsController.enableCallDialIn
+);
+
+// Disable dial-in
+router.delete(
+  '/:communityId/calls/rooms/:roomName/dial-in',
+  requireCommunityAdmin,
+  callsController.disableCallDialIn
+);
+
+/**
+ * Transcription
+ */
+
+// Start live captions
+router.post(
+  '/:communityId/calls/rooms/:roomName/transcription/start',
+  requireCommunityAdmin,
+  callsController.startCallTranscription
+);
+
+// Stop live captions
+router.post(
+  '/:communityId/calls/rooms/:roomName/transcription/stop',
+  requireCommunityAdmin,
+  callsController.stopCallTranscription
+);
+
+// Get a room's transcript
+router.get(
+  '/:communityId/calls/rooms/:roomName/transcript',
+  requireCommunityAdmin,
+  callsController.getCallTranscript
+);
+
+// Delete a room's transcript
+router.delete(
+  '/:communityId/calls/rooms/:roomName/transcript',
+  requireCommunityAdmin,
+  callsController.deleteCallTranscript
+);
+
+/**
+ * Room Templates
+ */
+
+// Get the community's room templates
+router.get(
+  '/:communityId/calls/room-templates',
+  requireCommunityAdmin,
+  callsController.getCallRoomTemplates
+);
+
+// Create or replace a room template
+router.put(
+  '/:communityId/calls/room-templates/:name',
+  requireCommunityAdmin,
+  validators.integer('max_participants', { min: 0, max: 1000, optional: true }),
+  validators.integer('empty_timeout_seconds', { min: 0, max: 86400, optional: true }),
+  validators.text('default_role', { max: 20, optional: true }),
+  validators.boolean('lobby', { optional: true }),
+  validators.boolean('recording', { optional: true }),
+  validators.array('codecs', { max: 6, optional: true }),
+  validators.boolean('is_default', { optional: true }),
+  validateRequest,
+  callsController.saveCallRoomTemplate
+);
+
+// Delete a room template
+router.delete(
+  '/:communityId/calls/room-templates/:name',
+  requireCommunityAdmin,
+  callsController.deleteCallRoomTemplate
+);
+
+/**
+ * Analytics
+ */
+
+// Get speaking time and concurrency for a room
+router.get(
+  '/:communityId/calls/rooms/:roomName/analytics',
+  requireCommunityAdmin,
+  callsController.getCallRoomAnalytics
+);
+
+// Get the community's call usage
+router.get(
+  '/:communityId/calls/usage',
+  requireCommunityAdmin,
+  callsController.getCallUsage
+);
+
+/**
+ * Recordings
+ */
+
+// Get the community's call recordings
+router.get(
+  '/:communityId/calls/recordings',
+  requireCommunityAdmin,
+  callRecordingsController.getCallRecordings
+);
+
 export default router;
@@ -4,6 +4,8 @@
  */
 import { Router } from 'express';
 import * as activityController from '../controllers/activityController.js';
+import * as callRecordingsController from '../controllers/callRecordingsController.js';
+import * as serviceRegistryController from '../controllers/serviceRegistryController.js';
 import { requireServiceAuth } from '../middleware/auth.js';
 
 const router = Router();
@@ -16,6 +18,14 @@ router.post('/activity/watch-session', activityController.recordWatchSession);
 router.post('/activity/message', activityController.recordMessage);
 router.post('/activity/batch', activityController.recordActivityBatch);
 
+// Call recordings (called by module_rtc when a recording ends)
+router.post('/rtc/recordings', callRecordingsController.recordCallRecording);
+
+// Service catalog (modules register on startup and send heartbeats)
+router.post('/services/register', serviceRegistryController.registerService);
+router.post('/services/:name/heartbeat', serviceRegistryController.recordHeartbeat);
+router.post('/services/:name/deregister', serviceRegistryController.deregisterService);
+
 // Background job endpoints
 router.post('/activity/close-stale-sessions', activityController.closeStaleWatchSessions);
 
@@ -0,0 +1,42 @@
+-- Migration 029: Add Call Recordings
+-- Description: Stores recordings of community call rooms reported by module_rtc
+-- Author: WaddleBot Engineering
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- Call recordings table, one row per LiveKit egress
+CREATE TABLE IF NOT EXISTS call_recordings (
+    id SERIAL PRIMARY KEY,
+    community_id INTEGER NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    room_name VARCHAR(255) NOT NULL,
+    egress_id VARCHAR(255) UNIQUE NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    track_id VARCHAR(255),
+    status VARCHAR(20) NOT NULL,
+    destination VARCHAR(20) NOT NULL,
+    location TEXT,
+    filename TEXT,
+    size_bytes BIGINT DEFAULT 0,
+    duration_seconds BIGINT DEFAULT 0,
+    error TEXT,
+    started_by VARCHAR(255),
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT chk_call_recordings_kind CHECK (kind IN ('room', 'track'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_call_recordings_community_id ON call_recordings(community_id);
+CREATE INDEX IF NOT EXISTS idx_call_recordings_room_name ON call_recordings(room_name);
+CREATE INDEX IF NOT EXISTS idx_call_recordings_started_at ON call_recordings(started_at);
+
+-- Trigger for updated_at on call_recordings
+DROP TRIGGER IF EXISTS trigger_update_call_recordings_timestamp ON call_recordings;
+CREATE TRIGGER trigger_update_call_recordings_timestamp
+    BEFORE UPDATE ON call_recordings
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+COMMIT;
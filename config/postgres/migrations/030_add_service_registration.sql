@@ -0,0 +1,23 @@
+-- Migration 030: Add Service Registration
+-- Description: Lets modules register themselves in the service catalog and send heartbeats
+-- Author: WaddleBot Engineering
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- What a module serves and can do, as it reported when registering
+ALTER TABLE services ADD COLUMN IF NOT EXISTS endpoints JSONB DEFAULT '{}';
+ALTER TABLE services ADD COLUMN IF NOT EXISTS capabilities JSONB DEFAULT '[]';
+
+-- Heartbeats; a module is stale once it misses a few intervals
+ALTER TABLE services ADD COLUMN IF NOT EXISTS last_heartbeat TIMESTAMPTZ;
+ALTER TABLE services ADD COLUMN IF NOT EXISTS heartbeat_interval INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_services_last_heartbeat ON services(last_heartbeat);
+
+COMMENT ON COLUMN services.endpoints IS 'JSON object of endpoint URLs by kind (rest, grpc, websocket, ...)';
+COMMENT ON COLUMN services.capabilities IS 'JSON array of features the running module supports';
+COMMENT ON COLUMN services.last_heartbeat IS 'When the module last reported it was available';
+COMMENT ON COLUMN services.heartbeat_interval IS 'Seconds between the module''s heartbeats';
+
+COMMIT;
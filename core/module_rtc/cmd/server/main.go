@@ -4,16 +4,30 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/penguintech/waddlebot/module_rtc/internal/api"
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
+	"github.com/penguintech/waddlebot/module_rtc/internal/calendar"
 	"github.com/penguintech/waddlebot/module_rtc/internal/config"
+	"github.com/penguintech/waddlebot/module_rtc/internal/grpcapi"
+	"github.com/penguintech/waddlebot/module_rtc/internal/hub"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
 	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+	"github.com/penguintech/waddlebot/module_rtc/internal/sip"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+	"github.com/penguintech/waddlebot/module_rtc/internal/stt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
@@ -25,21 +39,108 @@ func main() {
 		log.Println("WARNING: LiveKit API credentials not configured")
 	}
 
-	roomService := services.NewRoomService(cfg.LiveKitHost, cfg.LiveKitAPIKey, cfg.LiveKitAPISecret)
-	featuresService := services.NewCallFeaturesService(roomService)
+	var store storage.Store
+	if cfg.DatabaseURL == "" {
+		log.Println("WARNING: DATABASE_URL not configured, room state will not survive restarts")
+		store = storage.NewMemoryStore()
+	} else {
+		connectCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		pgStore, err := storage.NewPostgresStore(connectCtx, cfg.DatabaseURL)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to open room state database: %v", err)
+		}
+		store = pgStore
+	}
+	store = storage.NewCachedStore(store, cfg.StateCacheTTL)
+	defer store.Close()
+
+	events := services.NewEventBus()
+	roomService := services.NewRoomService(cfg.LiveKitHost, cfg.LiveKitAPIKey, cfg.LiveKitAPISecret, store, events)
+	analyticsService := services.NewAnalyticsService(store)
+	featuresService := services.NewCallFeaturesService(roomService, store, analyticsService, events)
+
+	hubClient := hub.NewClient(cfg.HubAPIURL, cfg.ServiceAPIKey)
+	if !hubClient.Enabled() {
+		log.Println("WARNING: SERVICE_API_KEY not configured, participant activity will not be sent to the hub")
+	}
+
+	if cfg.RecordingOutput == services.RecordingDestinationS3 && cfg.RecordingS3Bucket == "" {
+		log.Fatal("RECORDING_OUTPUT is s3 but RECORDING_S3_BUCKET is not configured")
+	}
+	recordingService := services.NewRecordingService(cfg.LiveKitHost, cfg.LiveKitAPIKey, cfg.LiveKitAPISecret, services.RecordingOutput{
+		Destination: cfg.RecordingOutput,
+		LocalDir:    cfg.RecordingLocalDir,
+		S3Bucket:    cfg.RecordingS3Bucket,
+		S3Region:    cfg.RecordingS3Region,
+		S3Endpoint:  cfg.RecordingS3Endpoint,
+		S3AccessKey: cfg.RecordingS3AccessKey,
+		S3Secret:    cfg.RecordingS3Secret,
+	}, store, hubClient, events)
+
+	breakoutService := services.NewBreakoutService(roomService, store, events)
+	broadcastService := services.NewBroadcastService(cfg.LiveKitHost, cfg.LiveKitAPIKey, cfg.LiveKitAPISecret, store, events)
+	scheduleService := services.NewScheduleService(roomService, store, calendar.NewClient(cfg.CalendarAPIURL))
+	chatService := services.NewChatService(roomService, store, events,
+		services.NewBlocklistFilter(strings.Split(cfg.ChatBlocklist, ","), cfg.ChatBlocklistAction == "reject"))
 
-	handlers := api.NewHandlers(roomService, featuresService)
+	inviteService := services.NewInviteService(roomService, featuresService, store, cfg.InviteSecret, cfg.InviteBaseURL, events)
+	sipService := services.NewSIPService(sip.NewClient(cfg.LiveKitHost, cfg.LiveKitAPIKey, cfg.LiveKitAPISecret), services.SIPTrunk{
+		Name:             cfg.SIPTrunkName,
+		Numbers:          splitList(cfg.SIPNumbers),
+		AllowedAddresses: splitList(cfg.SIPAllowedAddresses),
+		AuthUsername:     cfg.SIPAuthUsername,
+		AuthPassword:     cfg.SIPAuthPassword,
+	}, store, events)
+	if sipService.Enabled() {
+		provisionCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		if _, err := sipService.ProvisionTrunk(provisionCtx); err != nil {
+			log.Printf("WARNING: failed to provision the SIP trunk, retrying when dial-in is next enabled: %v", err)
+		}
+		cancel()
+	}
+	var transcriber services.Transcriber
+	if cfg.TranscriptionSTTURL != "" {
+		transcriber = stt.NewClient(cfg.TranscriptionSTTURL, cfg.TranscriptionSTTAPIKey, cfg.TranscriptionSTTModel)
+	}
+	transcriptionService := services.NewTranscriptionService(cfg.LiveKitHost, cfg.LiveKitAPIKey, cfg.LiveKitAPISecret, roomService, transcriber, services.TranscriptionOptions{
+		StreamURL: cfg.TranscriptionStreamURL,
+		Secret:    cfg.TranscriptionSecret,
+		Language:  cfg.TranscriptionLanguage,
+		Chunk:     cfg.TranscriptionChunk,
+	}, store, events)
+	if cfg.TranscriptionSTTURL != "" && cfg.TranscriptionStreamURL == "" {
+		log.Println("WARNING: TRANSCRIPTION_STT_URL is set but TRANSCRIPTION_STREAM_URL is not, transcription is disabled")
+	}
+	webhookService := services.NewWebhookService(cfg.LiveKitAPIKey, cfg.LiveKitAPISecret, store, hubClient, roomService, recordingService, broadcastService, transcriptionService, analyticsService, events)
+
+	authenticator := auth.NewAuthenticator(cfg.JWTSecret, cfg.ServiceAPIKey)
+	if !authenticator.Enabled() {
+		log.Println("WARNING: neither JWT_SECRET nor SERVICE_API_KEY configured, all API requests will be rejected")
+	}
+
+	trustedProxies, err := api.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	handlers := api.NewHandlers(roomService, featuresService, recordingService, broadcastService, breakoutService, scheduleService, chatService, inviteService, sipService, transcriptionService, analyticsService, webhookService, authenticator, api.TokenLimits{
+		PerUser:        cfg.TokenRateLimitUser,
+		PerIP:          cfg.TokenRateLimitIP,
+		Window:         cfg.TokenRateWindow,
+		TrustedProxies: trustedProxies,
+	})
 
 	r := mux.NewRouter()
 
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `{"status":"healthy","module":"%s","version":"%s","timestamp":"%s"}`,
-			cfg.ModuleName, cfg.ModuleVersion, time.Now().UTC().Format(time.RFC3339))
-	}).Methods("GET")
+	api.NewHealth(cfg.ModuleName, cfg.ModuleVersion,
+		api.HealthCheck{Name: "livekit", Check: roomService.Ping},
+		api.HealthCheck{Name: "database", Check: store.Ping},
+	).RegisterRoutes(r)
+	r.Handle("/metrics", metrics.Handler(store)).Methods("GET")
 
 	handlers.RegisterRoutes(r)
+	api.NewRoomSocket(roomService, featuresService, events, authenticator).RegisterRoutes(r)
+	api.NewTranscriptionSocket(transcriptionService).RegisterRoutes(r)
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.ModulePort),
@@ -56,11 +157,45 @@ func main() {
 		}
 	}()
 
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go scheduleService.Run(schedulerCtx, cfg.SchedulerInterval)
+	go featuresService.RunHandExpiry(schedulerCtx, cfg.SchedulerInterval)
+
+	unaryAuth, streamAuth := grpcapi.ServiceKeyInterceptors(authenticator)
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(unaryAuth), grpc.StreamInterceptor(streamAuth))
+	grpcapi.NewServer(roomService, featuresService, recordingService, broadcastService, breakoutService, scheduleService, chatService, inviteService, sipService, transcriptionService, events).Register(grpcServer)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GrpcPort))
+	if err != nil {
+		log.Fatalf("gRPC listener failed: %v", err)
+	}
+	go func() {
+		log.Printf("gRPC server starting on port %d", cfg.GrpcPort)
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("gRPC server failed: %v", err)
+		}
+	}()
+
+	// Register once both servers are listening, so the hub lists the module
+	// only while it can be reached
+	registrationCtx, stopRegistration := context.WithCancel(context.Background())
+	deregistered := make(chan struct{})
+	go func() {
+		hubClient.RunRegistration(registrationCtx, hubRegistration(cfg, sipService, transcriptionService), cfg.HubHeartbeat)
+		close(deregistered)
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopRegistration()
+	<-deregistered
+	stopScheduler()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -69,5 +204,77 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Event streams only end when their clients go, so stop waiting for them
+	// when the shutdown timeout runs out
+	healthServer.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+
 	log.Println("Server stopped")
 }
+
+// hubRegistration describes the module for the hub's service catalog, with
+// the optional features this deployment has configured.
+func hubRegistration(cfg *config.Config, sipService *services.SIPService, transcriptionService *services.TranscriptionService) *hub.Registration {
+	moduleURL := strings.TrimRight(cfg.ModuleURL, "/")
+	if moduleURL == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "localhost"
+		}
+		moduleURL = fmt.Sprintf("http://%s:%d", hostname, cfg.ModulePort)
+	}
+	host := moduleURL
+	if u, err := url.Parse(moduleURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+
+	capabilities := []string{
+		"rooms", "raised_hands", "moderation", "bans", "lobby", "chat", "breakouts",
+		"recording", "broadcasts", "invites", "room_templates", "scheduled_rooms", "analytics",
+	}
+	if sipService.Enabled() {
+		capabilities = append(capabilities, "dial_in")
+	}
+	if transcriptionService.Enabled() {
+		capabilities = append(capabilities, "transcription")
+	}
+
+	return &hub.Registration{
+		Name:           cfg.ModuleName,
+		Version:        cfg.ModuleVersion,
+		Category:       "core",
+		Description:    "WebRTC community calls on LiveKit",
+		URL:            moduleURL,
+		Port:           cfg.ModulePort,
+		HealthEndpoint: "/health",
+		Endpoints: map[string]string{
+			"rest":      moduleURL + "/api/v1",
+			"grpc":      fmt.Sprintf("%s:%d", host, cfg.GrpcPort),
+			"websocket": strings.Replace(moduleURL, "http", "ws", 1) + "/ws/rooms",
+			"webhook":   moduleURL + "/webhooks/livekit",
+			"ready":     moduleURL + "/ready",
+			"metrics":   moduleURL + "/metrics",
+		},
+		Capabilities: capabilities,
+	}
+}
+
+// splitList splits a comma-separated setting, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
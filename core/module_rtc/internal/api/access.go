@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+)
+
+// roomAccess is how much of a room a caller may see.
+type roomAccess int
+
+const (
+	// accessNone sees nothing of the room.
+	accessNone roomAccess = iota
+	// accessMember sees the room's state, events and chat: members of its
+	// community, and whoever is in the call, such as invited guests.
+	accessMember
+	// accessModerator also sees what only moderators are shown, through
+	// their community role or a role given to them in the room.
+	accessModerator
+)
+
+// checkRoomAccess works out what the principal may see of the room. Users
+// banned from the room see nothing.
+func checkRoomAccess(ctx context.Context, roomService *services.RoomService, featuresService *services.CallFeaturesService, principal *auth.Principal, roomName string) (roomAccess, error) {
+	communityID, err := roomService.CommunityID(ctx, roomName)
+	if err != nil {
+		return accessNone, fmt.Errorf("failed to look up community: %w", err)
+	}
+	if principal.CanModerate(communityID) {
+		return accessModerator, nil
+	}
+
+	var banned *services.BanError
+	if err := featuresService.CheckBan(ctx, roomName, principal.UserID); errors.As(err, &banned) {
+		return accessNone, nil
+	} else if err != nil {
+		return accessNone, fmt.Errorf("failed to check bans: %w", err)
+	}
+
+	role, err := featuresService.AssignedRole(ctx, roomName, principal.UserID)
+	if err != nil {
+		return accessNone, fmt.Errorf("failed to get role: %w", err)
+	}
+	if services.RoleRank(role) >= services.RoleRank(services.RoleModerator) {
+		return accessModerator, nil
+	}
+	if principal.IsMember(communityID) {
+		return accessMember, nil
+	}
+
+	inRoom, err := featuresService.InRoom(ctx, roomName, principal.UserID)
+	if err != nil {
+		return accessNone, fmt.Errorf("failed to list participants: %w", err)
+	}
+	if inRoom {
+		return accessMember, nil
+	}
+	return accessNone, nil
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+	"github.com/twitchtv/twirp"
+)
+
+// Error codes sent with every error response, so clients can tell errors
+// apart without matching on messages. Most follow from the status; the rest
+// mark refusals a client is likely to handle differently.
+const (
+	CodeInvalidRequest    = "invalid_request"
+	CodeRequestTooLarge   = "request_too_large"
+	CodeUnauthorized      = "unauthorized"
+	CodeForbidden         = "forbidden"
+	CodeNotFound          = "not_found"
+	CodeConflict          = "conflict"
+	CodeGone              = "gone"
+	CodeRateLimited       = "rate_limited"
+	CodeInternal          = "internal_error"
+	CodeUnavailable       = "unavailable"
+	CodeModeratorRequired = "moderator_required"
+	CodeHostRequired      = "host_required"
+	CodeRoomLocked        = "room_locked"
+	CodeBanned            = "banned"
+	CodeNotConfigured     = "not_configured"
+)
+
+// ErrorResponse is the body of every error response. Field names the request
+// field that failed validation, if one did.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	Field string `json:"field,omitempty"`
+}
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeInvalidRequest,
+	http.StatusRequestEntityTooLarge: CodeRequestTooLarge,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusGone:                  CodeGone,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+}
+
+// jsonError sends an error with the code its status implies.
+func jsonError(w http.ResponseWriter, message string, status int) {
+	code, ok := statusCodes[status]
+	if !ok {
+		code = CodeInternal
+	}
+	jsonErrorCode(w, code, message, status)
+}
+
+func jsonErrorCode(w http.ResponseWriter, code, message string, status int) {
+	jsonResponse(w, ErrorResponse{Error: message, Code: code}, status)
+}
+
+// fieldError is a request field that failed validation.
+type fieldError struct {
+	field   string
+	message string
+}
+
+func (e *fieldError) Error() string {
+	return e.field + " " + e.message
+}
+
+func invalidField(w http.ResponseWriter, field, message string) {
+	jsonResponse(w, ErrorResponse{Error: field + " " + message, Code: CodeInvalidRequest, Field: field}, http.StatusBadRequest)
+}
+
+// liveKitStatuses are the statuses LiveKit's errors answer with, for those
+// the caller rather than the module is to blame for.
+var liveKitStatuses = map[twirp.ErrorCode]int{
+	twirp.InvalidArgument:    http.StatusBadRequest,
+	twirp.OutOfRange:         http.StatusBadRequest,
+	twirp.Malformed:          http.StatusBadRequest,
+	twirp.NotFound:           http.StatusNotFound,
+	twirp.PermissionDenied:   http.StatusForbidden,
+	twirp.Unauthenticated:    http.StatusForbidden,
+	twirp.AlreadyExists:      http.StatusConflict,
+	twirp.FailedPrecondition: http.StatusConflict,
+	twirp.ResourceExhausted:  http.StatusTooManyRequests,
+	twirp.Unavailable:        http.StatusServiceUnavailable,
+}
+
+// serverError answers for an error no handler expected. Rooms and
+// participants that are gone, and requests LiveKit refuses, get the matching
+// 4xx; anything else is logged and answered with a 500.
+func serverError(w http.ResponseWriter, message string, err error) {
+	var twirpErr twirp.Error
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound):
+		jsonError(w, "Room not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrParticipantNotFound):
+		jsonError(w, "Participant not found", http.StatusNotFound)
+	case errors.As(err, &twirpErr) && liveKitStatuses[twirpErr.Code()] != 0:
+		jsonError(w, message+": "+twirpErr.Msg(), liveKitStatuses[twirpErr.Code()])
+	default:
+		log.Printf("%s: %v", message, err)
+		jsonError(w, message, http.StatusInternalServerError)
+	}
+}
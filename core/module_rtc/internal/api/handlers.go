@@ -1,29 +1,65 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
 	"github.com/penguintech/waddlebot/module_rtc/internal/services"
 )
 
 type Handlers struct {
-	roomService     *services.RoomService
-	featuresService *services.CallFeaturesService
+	roomService          *services.RoomService
+	featuresService      *services.CallFeaturesService
+	recordingService     *services.RecordingService
+	broadcastService     *services.BroadcastService
+	breakoutService      *services.BreakoutService
+	scheduleService      *services.ScheduleService
+	chatService          *services.ChatService
+	inviteService        *services.InviteService
+	sipService           *services.SIPService
+	transcriptionService *services.TranscriptionService
+	analyticsService     *services.AnalyticsService
+	webhookService       *services.WebhookService
+	authenticator        *auth.Authenticator
+	tokenLimiter         *tokenLimiter
 }
 
-func NewHandlers(roomService *services.RoomService, featuresService *services.CallFeaturesService) *Handlers {
+func NewHandlers(roomService *services.RoomService, featuresService *services.CallFeaturesService, recordingService *services.RecordingService, broadcastService *services.BroadcastService, breakoutService *services.BreakoutService, scheduleService *services.ScheduleService, chatService *services.ChatService, inviteService *services.InviteService, sipService *services.SIPService, transcriptionService *services.TranscriptionService, analyticsService *services.AnalyticsService, webhookService *services.WebhookService, authenticator *auth.Authenticator, tokenLimits TokenLimits) *Handlers {
 	return &Handlers{
-		roomService:     roomService,
-		featuresService: featuresService,
+		roomService:          roomService,
+		featuresService:      featuresService,
+		recordingService:     recordingService,
+		broadcastService:     broadcastService,
+		breakoutService:      breakoutService,
+		scheduleService:      scheduleService,
+		chatService:          chatService,
+		inviteService:        inviteService,
+		sipService:           sipService,
+		transcriptionService: transcriptionService,
+		analyticsService:     analyticsService,
+		webhookService:       webhookService,
+		authenticator:        authenticator,
+		tokenLimiter:         newTokenLimiter(tokenLimits),
 	}
 }
 
 func (h *Handlers) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/webhooks/livekit", h.LiveKitWebhook).Methods("POST")
+	// Guests redeeming invites have no credentials; the invite is theirs
+	r.HandleFunc("/api/v1/invites/redeem", h.RedeemInvite).Methods("POST")
+
 	api := r.PathPrefix("/api/v1").Subrouter()
+	api.Use(h.authenticator.Middleware, checkPathParams)
 
 	api.HandleFunc("/rooms", h.CreateRoom).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}", h.GetRoom).Methods("GET")
@@ -31,24 +67,99 @@ func (h *Handlers) RegisterRoutes(r *mux.Router) {
 	api.HandleFunc("/rooms/{roomName}/join", h.JoinRoom).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}/leave", h.LeaveRoom).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}/participants", h.ListParticipants).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/participants/{userId}/promote", h.PromoteParticipant).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/participants/{userId}/demote", h.DemoteParticipant).Methods("POST")
 
 	api.HandleFunc("/rooms/{roomName}/raise-hand", h.RaiseHand).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}/lower-hand", h.LowerHand).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}/raised-hands", h.GetRaisedHands).Methods("GET")
 	api.HandleFunc("/rooms/{roomName}/acknowledge-hand/{userId}", h.AcknowledgeHand).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/next-speaker", h.NextSpeaker).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/hand-expiry", h.SetHandExpiry).Methods("POST")
 
 	api.HandleFunc("/rooms/{roomName}/mute/{userId}", h.MuteParticipant).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}/unmute/{userId}", h.UnmuteParticipant).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}/mute-all", h.MuteAll).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/revoke-media/{userId}", h.RevokeMedia).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/restore-media/{userId}", h.RestoreMedia).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}/kick/{userId}", h.KickParticipant).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/bans", h.ListBans).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/bans/{userId}", h.LiftBan).Methods("DELETE")
+	api.HandleFunc("/rooms/{roomName}/admit/{userId}", h.AdmitParticipant).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/tokens", h.ListIssuedTokens).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/revoke-tokens/{userId}", h.RevokeTokens).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/token-ttl", h.SetTokenTTL).Methods("POST")
+
+	api.HandleFunc("/rooms/{roomName}/invites", h.ListInvites).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/invites", h.CreateInvite).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/invites/{inviteId}", h.RevokeInvite).Methods("DELETE")
+
+	api.HandleFunc("/rooms/{roomName}/dial-in", h.GetDialIn).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/dial-in", h.EnableDialIn).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/dial-in", h.DisableDialIn).Methods("DELETE")
 
 	api.HandleFunc("/rooms/{roomName}/lock", h.LockRoom).Methods("POST")
 	api.HandleFunc("/rooms/{roomName}/unlock", h.UnlockRoom).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/max-participants", h.SetMaxParticipants).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/audio", h.SetAudioSettings).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/screen-share", h.SetScreenShare).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/stop-screen-share/{userId}", h.StopScreenShare).Methods("POST")
+
+	api.HandleFunc("/rooms/{roomName}/recordings", h.ListRecordings).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/recordings", h.StartRecording).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/recordings/{egressId}/stop", h.StopRecording).Methods("POST")
+
+	api.HandleFunc("/rooms/{roomName}/transcription/start", h.StartTranscription).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/transcription/stop", h.StopTranscription).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/transcript", h.GetTranscript).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/transcript", h.DeleteTranscript).Methods("DELETE")
+
+	api.HandleFunc("/rooms/{roomName}/breakouts", h.GetBreakouts).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/breakouts", h.CreateBreakouts).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/breakouts", h.CloseBreakouts).Methods("DELETE")
+	api.HandleFunc("/rooms/{roomName}/breakouts/assign", h.AssignBreakouts).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/breakouts/auto-assign", h.AutoAssignBreakouts).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/breakouts/broadcast", h.BroadcastToBreakouts).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/breakouts/return", h.ReturnFromBreakouts).Methods("POST")
+
+	api.HandleFunc("/communities/{communityId}/rooms", h.ListCommunityRooms).Methods("GET")
+
+	api.HandleFunc("/communities/{communityId}/stream-destinations", h.ListStreamDestinations).Methods("GET")
+	api.HandleFunc("/communities/{communityId}/stream-destinations", h.AddStreamDestination).Methods("POST")
+	api.HandleFunc("/communities/{communityId}/stream-destinations/{destinationId}", h.DeleteStreamDestination).Methods("DELETE")
+
+	api.HandleFunc("/rooms/{roomName}/broadcasts", h.ListBroadcasts).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/broadcasts", h.StartBroadcast).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/broadcasts/{egressId}/stop", h.StopBroadcast).Methods("POST")
+
+	api.HandleFunc("/rooms/{roomName}/messages", h.ListChatMessages).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/messages", h.PostChatMessage).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/messages/{messageId}", h.DeleteChatMessage).Methods("DELETE")
+	api.HandleFunc("/rooms/{roomName}/slow-mode", h.SetSlowMode).Methods("POST")
+
+	api.HandleFunc("/rooms/{roomName}/watch", h.GetWatchParty).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/watch", h.StartWatchParty).Methods("POST")
+	api.HandleFunc("/rooms/{roomName}/watch", h.StopWatchParty).Methods("DELETE")
+	api.HandleFunc("/rooms/{roomName}/watch/{action:play|pause|seek}", h.ControlWatchParty).Methods("POST")
+
+	api.HandleFunc("/rooms/{roomName}/analytics", h.GetRoomAnalytics).Methods("GET")
+	api.HandleFunc("/rooms/{roomName}/active-speakers", h.ReportActiveSpeakers).Methods("POST")
+	api.HandleFunc("/communities/{communityId}/usage", h.GetCommunityUsage).Methods("GET")
+
+	api.HandleFunc("/communities/{communityId}/room-templates", h.ListRoomTemplates).Methods("GET")
+	api.HandleFunc("/communities/{communityId}/room-templates/{name}", h.SaveRoomTemplate).Methods("PUT")
+	api.HandleFunc("/communities/{communityId}/room-templates/{name}", h.DeleteRoomTemplate).Methods("DELETE")
+
+	api.HandleFunc("/communities/{communityId}/scheduled-rooms", h.ListScheduledRooms).Methods("GET")
+	api.HandleFunc("/communities/{communityId}/scheduled-rooms", h.ScheduleRoom).Methods("POST")
+	api.HandleFunc("/communities/{communityId}/scheduled-rooms/{scheduleId}", h.DeleteScheduledRoom).Methods("DELETE")
+	api.HandleFunc("/communities/{communityId}/upcoming-rooms", h.ListUpcomingRooms).Methods("GET")
 }
 
 type CreateRoomRequest struct {
 	CommunityID     int    `json:"community_id"`
 	RoomName        string `json:"room_name"`
+	Template        string `json:"template"`
 	MaxParticipants uint32 `json:"max_participants"`
 }
 
@@ -67,21 +178,172 @@ type ModeratorRequest struct {
 	ModeratorID string `json:"moderator_id"`
 }
 
+// KickRequest bans the user as well when Ban is set, for DurationMinutes or
+// until lifted if zero.
+type KickRequest struct {
+	AdminID         string `json:"admin_id"`
+	Ban             bool   `json:"ban"`
+	Reason          string `json:"reason"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+type InviteRequest struct {
+	Role             string `json:"role"`
+	ExpiresInMinutes int    `json:"expires_in_minutes"`
+	ModeratorID      string `json:"moderator_id"`
+}
+
+type RedeemInviteRequest struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
+type StartRecordingRequest struct {
+	TrackID     string `json:"track_id"`
+	ModeratorID string `json:"moderator_id"`
+}
+
+type CreateBreakoutsRequest struct {
+	Count       int      `json:"count"`
+	Names       []string `json:"names"`
+	ModeratorID string   `json:"moderator_id"`
+}
+
+type AssignBreakoutsRequest struct {
+	Assignments map[string]string `json:"assignments"` // user ID -> room name
+	ModeratorID string            `json:"moderator_id"`
+}
+
+type AutoAssignBreakoutsRequest struct {
+	UserIDs     []string `json:"user_ids"`
+	ModeratorID string   `json:"moderator_id"`
+}
+
+type BreakoutMessageRequest struct {
+	Message     string `json:"message"`
+	ModeratorID string `json:"moderator_id"`
+}
+
+type RoomTemplateRequest struct {
+	MaxParticipants     uint32   `json:"max_participants"`
+	EmptyTimeoutSeconds uint32   `json:"empty_timeout_seconds"`
+	DefaultRole         string   `json:"default_role"`
+	Lobby               bool     `json:"lobby"`
+	Recording           *bool    `json:"recording"`
+	Codecs              []string `json:"codecs"`
+	IsDefault           bool     `json:"is_default"`
+	ModeratorID         string   `json:"moderator_id"`
+}
+
+type StreamDestinationRequest struct {
+	Name        string `json:"name"`
+	Platform    string `json:"platform"`
+	RTMPURL     string `json:"rtmp_url"`
+	StreamKey   string `json:"stream_key"`
+	ModeratorID string `json:"moderator_id"`
+}
+
+type StartBroadcastRequest struct {
+	DestinationIDs []string `json:"destination_ids"`
+	ModeratorID    string   `json:"moderator_id"`
+}
+
+type ChatMessageRequest struct {
+	UserID   string `json:"user_id"`
+	UserName string `json:"user_name"`
+	Message  string `json:"message"`
+}
+
+// TokenTTLRequest sets how long join tokens for a room last, or back to the
+// default if Seconds is zero.
+type TokenTTLRequest struct {
+	Seconds     int    `json:"seconds"`
+	ModeratorID string `json:"moderator_id"`
+}
+
+type HandExpiryRequest struct {
+	Minutes     int    `json:"minutes"`
+	ModeratorID string `json:"moderator_id"`
+}
+
+type MaxParticipantsRequest struct {
+	MaxParticipants uint32 `json:"max_participants"`
+	ModeratorID     string `json:"moderator_id"`
+}
+
+// AudioRequest changes the audio settings it sets, leaving the rest.
+type AudioRequest struct {
+	services.AudioUpdate
+	ModeratorID string `json:"moderator_id"`
+}
+
+type ScreenShareRequest struct {
+	ScreenShare string `json:"screen_share"`
+	ModeratorID string `json:"moderator_id"`
+}
+
+type SlowModeRequest struct {
+	Seconds     int    `json:"seconds"`
+	ModeratorID string `json:"moderator_id"`
+}
+
+type WatchPartyRequest struct {
+	MediaURL string `json:"media_url"`
+	HostID   string `json:"host_id"`
+}
+
+// WatchControlRequest plays or pauses at Position, or where playback is if
+// it is left out; seeking needs it.
+type WatchControlRequest struct {
+	Position *float64 `json:"position"`
+	HostID   string   `json:"host_id"`
+}
+
+type ActiveSpeakersRequest struct {
+	Speakers []string `json:"speakers"`
+}
+
+type ScheduleRoomRequest struct {
+	RoomName        string     `json:"room_name"`
+	Title           string     `json:"title"`
+	StartsAt        time.Time  `json:"starts_at"`
+	EndsAt          time.Time  `json:"ends_at"`
+	Recurrence      string     `json:"recurrence"`
+	RecurUntil      *time.Time `json:"recur_until"`
+	AutoOpen        *bool      `json:"auto_open"`
+	AutoClose       *bool      `json:"auto_close"`
+	MaxParticipants uint32     `json:"max_participants"`
+	CalendarEventID int        `json:"calendar_event_id"`
+	ModeratorID     string     `json:"moderator_id"`
+}
+
+type RoleRequest struct {
+	Role        string `json:"role"`
+	ModeratorID string `json:"moderator_id"`
+}
+
 func (h *Handlers) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	var req CreateRoomRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
-	if req.MaxParticipants == 0 {
-		req.MaxParticipants = 100
+	if !auth.FromContext(r.Context()).CanModerate(req.CommunityID) {
+		jsonErrorCode(w, CodeModeratorRequired, "Moderator role required", http.StatusForbidden)
+		return
 	}
 
-	room, err := h.roomService.CreateRoom(r.Context(), req.CommunityID, req.RoomName, req.MaxParticipants)
+	room, err := h.roomService.CreateRoom(r.Context(), req.CommunityID, req.RoomName, req.Template, req.MaxParticipants)
+	if errors.Is(err, services.ErrInvalidRoom) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrRoomTemplateNotFound) {
+		jsonError(w, "Room template not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to create room: %v", err)
-		jsonError(w, "Failed to create room", http.StatusInternalServerError)
+		serverError(w, "Failed to create room", err)
 		return
 	}
 
@@ -93,20 +355,65 @@ func (h *Handlers) GetRoom(w http.ResponseWriter, r *http.Request) {
 
 	room, err := h.roomService.GetRoomInfo(r.Context(), roomName)
 	if err != nil {
-		jsonError(w, "Room not found", http.StatusNotFound)
+		serverError(w, "Failed to get room", err)
 		return
 	}
 
 	jsonResponse(w, room, http.StatusOK)
 }
 
+// ListCommunityRooms returns a page of the community's rooms. state may be
+// given more than once, or as a comma-separated list.
+func (h *Handlers) ListCommunityRooms(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.RoomFilter{
+		Sort:   query.Get("sort"),
+		Limit:  getIntParam(r, "limit", 0),
+		Offset: getIntParam(r, "offset", 0),
+	}
+	for _, states := range query["state"] {
+		filter.States = append(filter.States, splitParam(states)...)
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		jsonError(w, "order must be asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.roomService.ListRooms(r.Context(), communityID, filter)
+	if errors.Is(err, services.ErrInvalidRoomFilter) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to list rooms of community %d: %v", communityID, err)
+		jsonError(w, "Failed to list rooms", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, page, http.StatusOK)
+}
+
 func (h *Handlers) DeleteRoom(w http.ResponseWriter, r *http.Request) {
 	roomName := mux.Vars(r)["roomName"]
 
+	if _, ok := h.authorizeCommunityModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
 	if err := h.roomService.DeleteRoom(r.Context(), roomName); err != nil {
-		jsonError(w, "Failed to delete room", http.StatusInternalServerError)
+		serverError(w, "Failed to delete room", err)
 		return
 	}
+	h.sipService.RoomDeleted(r.Context(), roomName)
 
 	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
 }
@@ -114,28 +421,88 @@ func (h *Handlers) DeleteRoom(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) JoinRoom(w http.ResponseWriter, r *http.Request) {
 	roomName := mux.Vars(r)["roomName"]
 
-	if h.featuresService.IsRoomLocked(r.Context(), roomName) {
-		jsonError(w, "Room is locked", http.StatusForbidden)
+	locked, err := h.featuresService.IsRoomLocked(r.Context(), roomName)
+	if err != nil {
+		log.Printf("Failed to check room lock: %v", err)
+		jsonError(w, "Failed to join room", http.StatusInternalServerError)
+		return
+	}
+	if locked {
+		jsonErrorCode(w, CodeRoomLocked, "Room is locked", http.StatusForbidden)
 		return
 	}
 
 	var req JoinRoomRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	principal := auth.FromContext(r.Context())
+	if !principal.Service {
+		if req.UserID == "" {
+			req.UserID = principal.UserID
+		}
+		if req.UserName == "" {
+			req.UserName = principal.Username
+		}
+	}
+	if !h.authorizeSelf(w, r, roomName, req.UserID) {
+		return
+	}
+	if err := h.featuresService.CheckBan(r.Context(), roomName, req.UserID); err != nil {
+		banError(w, "Failed to join room", err)
 		return
 	}
 
+	// Participants rejoin with the role they were last given in the room
+	assigned, err := h.featuresService.AssignedRole(r.Context(), roomName, req.UserID)
+	if err != nil {
+		log.Printf("Failed to get assigned role: %v", err)
+		jsonError(w, "Failed to join room", http.StatusInternalServerError)
+		return
+	}
 	if req.Role == "" {
-		req.Role = "viewer"
+		req.Role = assigned
+	}
+	if services.RoleRank(req.Role) < 0 {
+		jsonError(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	if services.RoleRank(req.Role) > services.RoleRank(assigned) {
+		if _, ok := h.authorizeCommunityModerator(w, r, roomName, ""); !ok {
+			return
+		}
 	}
 
-	token, err := h.roomService.JoinRoom(r.Context(), roomName, req.UserID, req.UserName, req.Role)
+	// Rooms with a lobby keep those not let in yet waiting there, unless
+	// they were given a higher role just now or may moderate the room
+	waiting, err := h.featuresService.InLobby(r.Context(), roomName, req.UserID)
+	if err == nil && waiting && !principal.Service {
+		var moderator bool
+		moderator, err = h.canModerate(r, roomName, false)
+		waiting = !moderator
+	}
 	if err != nil {
-		log.Printf("Failed to join room: %v", err)
+		log.Printf("Failed to check the lobby: %v", err)
 		jsonError(w, "Failed to join room", http.StatusInternalServerError)
 		return
 	}
 
+	ctx, ok := h.limitTokens(w, r, principal)
+	if !ok {
+		return
+	}
+	var token *services.JoinToken
+	if waiting && services.RoleRank(req.Role) <= services.RoleRank(assigned) {
+		token, err = h.roomService.JoinLobby(ctx, roomName, req.UserID, req.UserName)
+	} else {
+		token, err = h.roomService.JoinRoom(ctx, roomName, req.UserID, req.UserName, req.Role)
+	}
+	if err != nil {
+		serverError(w, "Failed to join room", err)
+		return
+	}
+
 	jsonResponse(w, token, http.StatusOK)
 }
 
@@ -145,8 +512,11 @@ func (h *Handlers) LeaveRoom(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID string `json:"user_id"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if !h.authorizeSelf(w, r, roomName, req.UserID) {
 		return
 	}
 
@@ -164,7 +534,7 @@ func (h *Handlers) ListParticipants(w http.ResponseWriter, r *http.Request) {
 
 	participants, err := h.roomService.ListParticipants(r.Context(), roomName)
 	if err != nil {
-		jsonError(w, "Failed to list participants", http.StatusInternalServerError)
+		serverError(w, "Failed to list participants", err)
 		return
 	}
 
@@ -174,17 +544,64 @@ func (h *Handlers) ListParticipants(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
+func (h *Handlers) PromoteParticipant(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, h.featuresService.PromoteParticipant)
+}
+
+func (h *Handlers) DemoteParticipant(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, h.featuresService.DemoteParticipant)
+}
+
+type roleChangeFunc func(ctx context.Context, roomName, userID, role, moderatorID string) (*services.RoleChange, error)
+
+func (h *Handlers) changeRole(w http.ResponseWriter, r *http.Request, change roleChangeFunc) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+	userID := vars["userId"]
+
+	var req RoleRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	// Roles given in the room do not count, so room moderators cannot make
+	// more moderators
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	result, err := change(r.Context(), roomName, userID, req.Role, moderatorID)
+	if errors.Is(err, services.ErrInvalidRole) || errors.Is(err, services.ErrInvalidRoleChange) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to change role", err)
+		return
+	}
+
+	jsonResponse(w, result, http.StatusOK)
+}
+
 func (h *Handlers) RaiseHand(w http.ResponseWriter, r *http.Request) {
 	roomName := mux.Vars(r)["roomName"]
 
 	var req RaiseHandRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if principal := auth.FromContext(r.Context()); !principal.Service && req.UserID == "" {
+		req.UserID = principal.UserID
+		req.UserName = principal.Username
+	}
+	if !h.authorizeSelf(w, r, roomName, req.UserID) {
 		return
 	}
 
 	if err := h.featuresService.RaiseHand(r.Context(), roomName, req.UserID, req.UserName); err != nil {
-		jsonError(w, "Failed to raise hand", http.StatusInternalServerError)
+		serverError(w, "Failed to raise hand", err)
 		return
 	}
 
@@ -197,13 +614,16 @@ func (h *Handlers) LowerHand(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID string `json:"user_id"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if !h.authorizeSelf(w, r, roomName, req.UserID) {
 		return
 	}
 
 	if err := h.featuresService.LowerHand(r.Context(), roomName, req.UserID); err != nil {
-		jsonError(w, "Failed to lower hand", http.StatusInternalServerError)
+		serverError(w, "Failed to lower hand", err)
 		return
 	}
 
@@ -215,7 +635,7 @@ func (h *Handlers) GetRaisedHands(w http.ResponseWriter, r *http.Request) {
 
 	hands, err := h.featuresService.GetRaisedHands(r.Context(), roomName)
 	if err != nil {
-		jsonError(w, "Failed to get raised hands", http.StatusInternalServerError)
+		serverError(w, "Failed to get raised hands", err)
 		return
 	}
 
@@ -231,29 +651,100 @@ func (h *Handlers) AcknowledgeHand(w http.ResponseWriter, r *http.Request) {
 	userID := vars["userId"]
 
 	var req ModeratorRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
 		return
 	}
 
-	if err := h.featuresService.AcknowledgeHand(r.Context(), roomName, userID, req.ModeratorID); err != nil {
-		jsonError(w, "Failed to acknowledge hand", http.StatusInternalServerError)
+	if err := h.featuresService.AcknowledgeHand(r.Context(), roomName, userID, moderatorID); err != nil {
+		serverError(w, "Failed to acknowledge hand", err)
 		return
 	}
 
 	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
 }
 
+// NextSpeaker takes the first hand off the queue and unmutes its owner.
+func (h *Handlers) NextSpeaker(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	hand, err := h.featuresService.NextSpeaker(r.Context(), roomName, moderatorID)
+	if errors.Is(err, services.ErrNoRaisedHands) {
+		jsonError(w, "No hands are raised", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to pick the next speaker", err)
+		return
+	}
+
+	jsonResponse(w, hand, http.StatusOK)
+}
+
+func (h *Handlers) SetHandExpiry(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req HandExpiryRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	err := h.featuresService.SetHandExpiry(r.Context(), roomName, req.Minutes, moderatorID)
+	if errors.Is(err, services.ErrInvalidHandExpiry) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to set hand expiry of %s: %v", roomName, err)
+		jsonError(w, "Failed to set hand expiry", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"success": true, "hand_expiry_minutes": req.Minutes}, http.StatusOK)
+}
+
+// MuteParticipant mutes a participant's microphone. Participants may mute
+// themselves, such as from the desktop bridge; muting others takes a
+// moderator.
 func (h *Handlers) MuteParticipant(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomName := vars["roomName"]
 	userID := vars["userId"]
 
 	var req ModeratorRequest
-	json.NewDecoder(r.Body).Decode(&req)
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID := userID
+	if principal := auth.FromContext(r.Context()); principal.Service || principal.UserID != userID || req.ModeratorID != "" {
+		var ok bool
+		if moderatorID, ok = h.authorizeModerator(w, r, roomName, req.ModeratorID); !ok {
+			return
+		}
+	}
 
-	if err := h.featuresService.MuteParticipant(r.Context(), roomName, userID, req.ModeratorID); err != nil {
-		jsonError(w, "Failed to mute participant", http.StatusInternalServerError)
+	if err := h.featuresService.MuteParticipant(r.Context(), roomName, userID, moderatorID); err != nil {
+		serverError(w, "Failed to mute participant", err)
 		return
 	}
 
@@ -266,10 +757,17 @@ func (h *Handlers) UnmuteParticipant(w http.ResponseWriter, r *http.Request) {
 	userID := vars["userId"]
 
 	var req ModeratorRequest
-	json.NewDecoder(r.Body).Decode(&req)
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
 
-	if err := h.featuresService.UnmuteParticipant(r.Context(), roomName, userID, req.ModeratorID); err != nil {
-		jsonError(w, "Failed to unmute participant", http.StatusInternalServerError)
+	if err := h.featuresService.UnmuteParticipant(r.Context(), roomName, userID, moderatorID); err != nil {
+		serverError(w, "Failed to unmute participant", err)
 		return
 	}
 
@@ -280,74 +778,1806 @@ func (h *Handlers) MuteAll(w http.ResponseWriter, r *http.Request) {
 	roomName := mux.Vars(r)["roomName"]
 
 	var req ModeratorRequest
-	json.NewDecoder(r.Body).Decode(&req)
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
 
-	if err := h.featuresService.MuteAll(r.Context(), roomName, req.ModeratorID); err != nil {
-		jsonError(w, "Failed to mute all", http.StatusInternalServerError)
+	if err := h.featuresService.MuteAll(r.Context(), roomName, moderatorID); err != nil {
+		serverError(w, "Failed to mute all", err)
 		return
 	}
 
 	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
 }
 
-func (h *Handlers) KickParticipant(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) RevokeMedia(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomName := vars["roomName"]
 	userID := vars["userId"]
 
-	var req struct {
-		AdminID string `json:"admin_id"`
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
 	}
-	json.NewDecoder(r.Body).Decode(&req)
 
-	if err := h.featuresService.KickParticipant(r.Context(), roomName, userID, req.AdminID); err != nil {
-		jsonError(w, "Failed to kick participant", http.StatusInternalServerError)
+	if err := h.featuresService.RevokeMedia(r.Context(), roomName, userID, moderatorID); err != nil {
+		serverError(w, "Failed to revoke media", err)
 		return
 	}
 
 	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
 }
 
-func (h *Handlers) LockRoom(w http.ResponseWriter, r *http.Request) {
-	roomName := mux.Vars(r)["roomName"]
+func (h *Handlers) RestoreMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+	userID := vars["userId"]
 
-	var req struct {
-		AdminID string `json:"admin_id"`
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
 	}
-	json.NewDecoder(r.Body).Decode(&req)
 
-	if err := h.featuresService.LockRoom(r.Context(), roomName, req.AdminID); err != nil {
-		jsonError(w, "Failed to lock room", http.StatusInternalServerError)
+	if err := h.featuresService.RestoreMedia(r.Context(), roomName, userID, moderatorID); err != nil {
+		serverError(w, "Failed to restore media", err)
 		return
 	}
 
 	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
 }
 
-func (h *Handlers) UnlockRoom(w http.ResponseWriter, r *http.Request) {
-	roomName := mux.Vars(r)["roomName"]
+func (h *Handlers) KickParticipant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+	userID := vars["userId"]
 
-	var req struct {
-		AdminID string `json:"admin_id"`
+	var req KickRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	adminID, ok := h.authorizeModerator(w, r, roomName, req.AdminID)
+	if !ok {
+		return
+	}
+
+	if req.Ban {
+		ban, err := h.featuresService.BanParticipant(r.Context(), roomName, userID, req.Reason, time.Duration(req.DurationMinutes)*time.Minute, adminID)
+		if err != nil {
+			banError(w, "Failed to ban participant", err)
+			return
+		}
+		jsonResponse(w, map[string]interface{}{"success": true, "ban": ban}, http.StatusOK)
+		return
 	}
-	json.NewDecoder(r.Body).Decode(&req)
 
-	if err := h.featuresService.UnlockRoom(r.Context(), roomName, req.AdminID); err != nil {
-		jsonError(w, "Failed to unlock room", http.StatusInternalServerError)
+	if err := h.featuresService.KickParticipant(r.Context(), roomName, userID, adminID); err != nil {
+		serverError(w, "Failed to kick participant", err)
 		return
 	}
 
 	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
 }
 
-func jsonResponse(w http.ResponseWriter, data interface{}, status int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+// ListIssuedTokens lists the join tokens issued for the room, newest first,
+// or those of the user_id given.
+func (h *Handlers) ListIssuedTokens(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if _, ok := h.authorizeModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if len(userID) > maxIDLength {
+		invalidField(w, "user_id", "is too long")
+		return
+	}
+	tokens, err := h.roomService.ListIssuedTokens(r.Context(), roomName, userID, getIntParam(r, "limit", 0))
+	if err != nil {
+		serverError(w, "Failed to list tokens", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"tokens": tokens,
+		"count":  len(tokens),
+	}, http.StatusOK)
 }
 
-func jsonError(w http.ResponseWriter, message string, status int) {
-	jsonResponse(w, map[string]string{"error": message}, status)
+// RevokeTokens revokes the user's join tokens for the room and removes them
+// from it.
+func (h *Handlers) RevokeTokens(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+	userID := vars["userId"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.roomService.RevokeTokens(r.Context(), roomName, userID, moderatorID)
+	if err != nil {
+		serverError(w, "Failed to revoke tokens", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"success": true, "revoked": revoked}, http.StatusOK)
+}
+
+func (h *Handlers) SetTokenTTL(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req TokenTTLRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	err := h.roomService.SetTokenTTL(r.Context(), roomName, time.Duration(req.Seconds)*time.Second, moderatorID)
+	if errors.Is(err, services.ErrInvalidTokenTTL) {
+		invalidField(w, "seconds", "must be 0 or between 60 and 86400")
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to set token lifetime", err)
+		return
+	}
+
+	ttl, err := h.roomService.TokenTTL(r.Context(), roomName)
+	if err != nil {
+		serverError(w, "Failed to set token lifetime", err)
+		return
+	}
+	jsonResponse(w, map[string]interface{}{"success": true, "token_ttl_seconds": int(ttl.Seconds())}, http.StatusOK)
+}
+
+func (h *Handlers) ListBans(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if _, ok := h.authorizeModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	bans, err := h.featuresService.ListBans(r.Context(), roomName)
+	if err != nil {
+		serverError(w, "Failed to list bans", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"bans":  bans,
+		"count": len(bans),
+	}, http.StatusOK)
+}
+
+func (h *Handlers) LiftBan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	if err := h.featuresService.LiftBan(r.Context(), roomName, vars["userId"], moderatorID); err != nil {
+		banError(w, "Failed to lift ban", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) AdmitParticipant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+	userID := vars["userId"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	result, err := h.featuresService.AdmitParticipant(r.Context(), roomName, userID, moderatorID)
+	if errors.Is(err, services.ErrAlreadyAdmitted) {
+		jsonError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to admit participant", err)
+		return
+	}
+
+	jsonResponse(w, result, http.StatusOK)
+}
+
+func (h *Handlers) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req InviteRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	// Guests are let in without an account, so room moderators may not
+	// invite them
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	invite, err := h.inviteService.CreateInvite(r.Context(), roomName, req.Role, time.Duration(req.ExpiresInMinutes)*time.Minute, moderatorID)
+	if err != nil {
+		inviteError(w, "Failed to create invite", err)
+		return
+	}
+
+	jsonResponse(w, invite, http.StatusCreated)
+}
+
+func (h *Handlers) ListInvites(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if _, ok := h.authorizeCommunityModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	invites, err := h.inviteService.ListInvites(r.Context(), roomName)
+	if err != nil {
+		serverError(w, "Failed to list invites", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"invites": invites,
+		"count":   len(invites),
+	}, http.StatusOK)
+}
+
+func (h *Handlers) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+
+	if _, ok := h.authorizeCommunityModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	if err := h.inviteService.RevokeInvite(r.Context(), roomName, vars["inviteId"]); err != nil {
+		inviteError(w, "Failed to revoke invite", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) RedeemInvite(w http.ResponseWriter, r *http.Request) {
+	var req RedeemInviteRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	// Signed in users join as themselves, so their bans from the room apply
+	var userID string
+	principal, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		principal = nil
+	} else if !principal.Service {
+		userID = principal.UserID
+		if req.Name == "" {
+			req.Name = principal.Username
+		}
+	}
+
+	ctx, ok := h.limitTokens(w, r, principal)
+	if !ok {
+		return
+	}
+	token, err := h.inviteService.RedeemInvite(ctx, req.Token, req.Name, userID)
+	if err != nil {
+		inviteError(w, "Failed to redeem invite", err)
+		return
+	}
+
+	jsonResponse(w, token, http.StatusOK)
+}
+
+// GetDialIn returns the room's PIN and the numbers to call. Phone callers
+// need no account and skip the lobby, so like guest invites, dial-in is
+// managed by community moderators.
+func (h *Handlers) GetDialIn(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if _, ok := h.authorizeCommunityModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	dialIn, err := h.sipService.DialIn(r.Context(), roomName)
+	if err != nil {
+		dialInError(w, "Failed to get dial-in", err)
+		return
+	}
+
+	jsonResponse(w, dialIn, http.StatusOK)
+}
+
+func (h *Handlers) EnableDialIn(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	dialIn, err := h.sipService.EnableDialIn(r.Context(), roomName, moderatorID)
+	if err != nil {
+		dialInError(w, "Failed to enable dial-in", err)
+		return
+	}
+
+	jsonResponse(w, dialIn, http.StatusCreated)
+}
+
+func (h *Handlers) DisableDialIn(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	if err := h.sipService.DisableDialIn(r.Context(), roomName, moderatorID); err != nil {
+		dialInError(w, "Failed to disable dial-in", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) LockRoom(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req struct {
+		AdminID string `json:"admin_id"`
+	}
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	adminID, ok := h.authorizeModerator(w, r, roomName, req.AdminID)
+	if !ok {
+		return
+	}
+
+	if err := h.featuresService.LockRoom(r.Context(), roomName, adminID); err != nil {
+		serverError(w, "Failed to lock room", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) UnlockRoom(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req struct {
+		AdminID string `json:"admin_id"`
+	}
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	adminID, ok := h.authorizeModerator(w, r, roomName, req.AdminID)
+	if !ok {
+		return
+	}
+
+	if err := h.featuresService.UnlockRoom(r.Context(), roomName, adminID); err != nil {
+		serverError(w, "Failed to unlock room", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// SetMaxParticipants changes how many may be in the room at once, telling
+// those already in it.
+func (h *Handlers) SetMaxParticipants(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req MaxParticipantsRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	err := h.roomService.SetMaxParticipants(r.Context(), roomName, req.MaxParticipants, moderatorID)
+	if errors.Is(err, services.ErrInvalidRoom) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to set max participants", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"success": true, "max_participants": req.MaxParticipants}, http.StatusOK)
+}
+
+// SetAudioSettings changes how clients in the room should process the
+// audio they publish.
+func (h *Handlers) SetAudioSettings(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req AudioRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	audio, err := h.featuresService.SetAudioSettings(r.Context(), roomName, &req.AudioUpdate, moderatorID)
+	if err != nil {
+		serverError(w, "Failed to set audio settings", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"success": true, "audio": audio}, http.StatusOK)
+}
+
+// SetScreenShare changes who may share their screen in the room: hosts,
+// speakers or everyone.
+func (h *Handlers) SetScreenShare(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ScreenShareRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	if err := h.featuresService.SetScreenShare(r.Context(), roomName, req.ScreenShare, moderatorID); err != nil {
+		screenShareError(w, "Failed to set screen share", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"success": true, "screen_share": req.ScreenShare}, http.StatusOK)
+}
+
+func (h *Handlers) StopScreenShare(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+	userID := vars["userId"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	stopped, err := h.featuresService.StopScreenShare(r.Context(), roomName, userID, moderatorID)
+	if err != nil {
+		screenShareError(w, "Failed to stop screen share", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"success": true, "tracks_stopped": stopped}, http.StatusOK)
+}
+
+func (h *Handlers) ListRecordings(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if _, ok := h.authorizeModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	recordings, err := h.recordingService.ListRecordings(r.Context(), roomName)
+	if err != nil {
+		serverError(w, "Failed to list recordings", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"recordings": recordings,
+		"count":      len(recordings),
+	}, http.StatusOK)
+}
+
+// StartRecording records the whole room, or only the track given.
+func (h *Handlers) StartRecording(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req StartRecordingRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	var recording *services.Recording
+	var err error
+	if req.TrackID != "" {
+		recording, err = h.recordingService.StartTrackRecording(r.Context(), roomName, req.TrackID, moderatorID)
+	} else {
+		recording, err = h.recordingService.StartRoomRecording(r.Context(), roomName, moderatorID)
+	}
+	if errors.Is(err, services.ErrAlreadyRecording) {
+		jsonError(w, "Already being recorded", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, services.ErrRecordingDisabled) {
+		jsonError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to start recording", err)
+		return
+	}
+
+	jsonResponse(w, recording, http.StatusCreated)
+}
+
+func (h *Handlers) StopRecording(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+	egressID := vars["egressId"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	recording, err := h.recordingService.StopRecording(r.Context(), roomName, egressID, moderatorID)
+	if errors.Is(err, services.ErrRecordingNotFound) {
+		jsonError(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrRecordingNotActive) {
+		jsonError(w, "Recording has already ended", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to stop recording", err)
+		return
+	}
+
+	jsonResponse(w, recording, http.StatusOK)
+}
+
+// StartTranscription captions the room live, for everyone in it.
+func (h *Handlers) StartTranscription(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	tracks, err := h.transcriptionService.StartTranscription(r.Context(), roomName, moderatorID)
+	if err != nil {
+		transcriptionError(w, "Failed to start transcription", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"success": true, "tracks": tracks}, http.StatusOK)
+}
+
+func (h *Handlers) StopTranscription(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	if err := h.transcriptionService.StopTranscription(r.Context(), roomName, moderatorID); err != nil {
+		transcriptionError(w, "Failed to stop transcription", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// GetTranscript returns what was said in the room while it was being
+// transcribed, kept after the call for the hub.
+func (h *Handlers) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if _, ok := h.authorizeModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	segments, err := h.transcriptionService.Transcript(r.Context(), roomName)
+	if err != nil {
+		serverError(w, "Failed to get transcript", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"room_name": roomName, "segments": segments}, http.StatusOK)
+}
+
+func (h *Handlers) DeleteTranscript(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	if err := h.transcriptionService.DeleteTranscript(r.Context(), roomName, moderatorID); err != nil {
+		serverError(w, "Failed to delete transcript", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) GetBreakouts(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	breakouts, err := h.breakoutService.GetBreakouts(r.Context(), roomName)
+	if err != nil {
+		serverError(w, "Failed to get breakout rooms", err)
+		return
+	}
+
+	jsonResponse(w, breakouts, http.StatusOK)
+}
+
+func (h *Handlers) CreateBreakouts(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req CreateBreakoutsRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	rooms, err := h.breakoutService.CreateBreakouts(r.Context(), roomName, req.Count, req.Names, moderatorID)
+	if err != nil {
+		breakoutError(w, "Failed to create breakout rooms", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"rooms": rooms,
+		"count": len(rooms),
+	}, http.StatusCreated)
+}
+
+// CloseBreakouts returns everyone to the room and deletes its breakout rooms.
+func (h *Handlers) CloseBreakouts(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	if err := h.breakoutService.CloseBreakouts(r.Context(), roomName, moderatorID); err != nil {
+		breakoutError(w, "Failed to close breakout rooms", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) AssignBreakouts(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req AssignBreakoutsRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	moves, err := h.breakoutService.AssignParticipants(r.Context(), roomName, req.Assignments, moderatorID)
+	if err != nil {
+		breakoutError(w, "Failed to assign breakout rooms", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"moves": moves}, http.StatusOK)
+}
+
+// AutoAssignBreakouts spreads the users given, or everyone in the room not
+// yet in a breakout room, evenly across the breakout rooms.
+func (h *Handlers) AutoAssignBreakouts(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req AutoAssignBreakoutsRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	moves, err := h.breakoutService.AutoAssign(r.Context(), roomName, req.UserIDs, moderatorID)
+	if err != nil {
+		breakoutError(w, "Failed to assign breakout rooms", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"moves": moves}, http.StatusOK)
+}
+
+func (h *Handlers) BroadcastToBreakouts(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req BreakoutMessageRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	sent, err := h.breakoutService.BroadcastMessage(r.Context(), roomName, req.Message, moderatorID)
+	if err != nil {
+		breakoutError(w, "Failed to send message", err)
+		return
+	}
+
+	jsonResponse(w, map[string]int{"rooms": sent}, http.StatusOK)
+}
+
+func (h *Handlers) ReturnFromBreakouts(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	moves, err := h.breakoutService.ReturnAll(r.Context(), roomName, moderatorID)
+	if err != nil {
+		breakoutError(w, "Failed to return participants", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"moves": moves}, http.StatusOK)
+}
+
+func (h *Handlers) ListStreamDestinations(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeCommunity(w, r, communityID, ""); !ok {
+		return
+	}
+
+	destinations, err := h.broadcastService.ListDestinations(r.Context(), communityID)
+	if err != nil {
+		serverError(w, "Failed to list stream destinations", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"destinations": destinations,
+		"count":        len(destinations),
+	}, http.StatusOK)
+}
+
+func (h *Handlers) AddStreamDestination(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req StreamDestinationRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunity(w, r, communityID, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	destination, err := h.broadcastService.AddDestination(r.Context(), communityID, req.Name, req.Platform, req.RTMPURL, req.StreamKey, moderatorID)
+	if errors.Is(err, services.ErrInvalidStreamDestination) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to add stream destination", err)
+		return
+	}
+
+	jsonResponse(w, destination, http.StatusCreated)
+}
+
+func (h *Handlers) DeleteStreamDestination(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeCommunity(w, r, communityID, ""); !ok {
+		return
+	}
+
+	err := h.broadcastService.DeleteDestination(r.Context(), communityID, mux.Vars(r)["destinationId"])
+	if errors.Is(err, services.ErrStreamDestinationNotFound) {
+		jsonError(w, "Stream destination not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to delete stream destination", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) ListRoomTemplates(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeCommunity(w, r, communityID, ""); !ok {
+		return
+	}
+
+	templates, err := h.roomService.ListTemplates(r.Context(), communityID)
+	if err != nil {
+		serverError(w, "Failed to list room templates", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"templates": templates,
+		"count":     len(templates),
+	}, http.StatusOK)
+}
+
+func (h *Handlers) SaveRoomTemplate(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req RoomTemplateRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunity(w, r, communityID, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	// Templates record rooms unless told not to
+	recording := req.Recording == nil || *req.Recording
+	template, err := h.roomService.SaveTemplate(r.Context(), &services.RoomTemplate{
+		CommunityID:         communityID,
+		Name:                mux.Vars(r)["name"],
+		MaxParticipants:     req.MaxParticipants,
+		EmptyTimeoutSeconds: req.EmptyTimeoutSeconds,
+		DefaultRole:         req.DefaultRole,
+		Lobby:               req.Lobby,
+		Recording:           recording,
+		Codecs:              req.Codecs,
+		IsDefault:           req.IsDefault,
+		UpdatedBy:           moderatorID,
+	})
+	if errors.Is(err, services.ErrInvalidRoomTemplate) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to save room template", err)
+		return
+	}
+
+	jsonResponse(w, template, http.StatusOK)
+}
+
+func (h *Handlers) DeleteRoomTemplate(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeCommunity(w, r, communityID, ""); !ok {
+		return
+	}
+
+	err := h.roomService.DeleteTemplate(r.Context(), communityID, mux.Vars(r)["name"])
+	if errors.Is(err, services.ErrRoomTemplateNotFound) {
+		jsonError(w, "Room template not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to delete room template", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) ListBroadcasts(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if _, ok := h.authorizeModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	broadcasts, err := h.broadcastService.ListBroadcasts(r.Context(), roomName)
+	if err != nil {
+		serverError(w, "Failed to list broadcasts", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"broadcasts": broadcasts,
+		"count":      len(broadcasts),
+	}, http.StatusOK)
+}
+
+// StartBroadcast streams the room to the community's stream destinations
+// given.
+func (h *Handlers) StartBroadcast(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req StartBroadcastRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	broadcast, err := h.broadcastService.StartBroadcast(r.Context(), roomName, req.DestinationIDs, moderatorID)
+	if errors.Is(err, services.ErrInvalidStreamDestination) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrStreamDestinationNotFound) {
+		jsonError(w, "Stream destination not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrAlreadyBroadcasting) {
+		jsonError(w, "Already being broadcast", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to start broadcast", err)
+		return
+	}
+
+	jsonResponse(w, broadcast, http.StatusCreated)
+}
+
+func (h *Handlers) StopBroadcast(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+	egressID := vars["egressId"]
+
+	var req ModeratorRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunityModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	broadcast, err := h.broadcastService.StopBroadcast(r.Context(), roomName, egressID, moderatorID)
+	if errors.Is(err, services.ErrBroadcastNotFound) {
+		jsonError(w, "Broadcast not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrBroadcastNotActive) {
+		jsonError(w, "Broadcast has already ended", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to stop broadcast", err)
+		return
+	}
+
+	jsonResponse(w, broadcast, http.StatusOK)
+}
+
+// ListChatMessages pages through the room's chat, the last messages by
+// default, before a message ID with before, or after one with after. Only
+// the service key sees the text of deleted messages.
+func (h *Handlers) ListChatMessages(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if !h.authorizeViewer(w, r, roomName) {
+		return
+	}
+
+	afterID, err1 := getInt64Param(r, "after")
+	beforeID, err2 := getInt64Param(r, "before")
+	if err1 != nil || err2 != nil {
+		jsonError(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	principal := auth.FromContext(r.Context())
+	messages, err := h.chatService.GetMessages(r.Context(), roomName, afterID, beforeID, getIntParam(r, "limit", 0), principal.Service)
+	if err != nil {
+		serverError(w, "Failed to list chat messages", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"messages": messages,
+		"count":    len(messages),
+	}, http.StatusOK)
+}
+
+// PostChatMessage posts as the authenticated user; only the service key may
+// post for another user. Moderators are not held to slow mode.
+func (h *Handlers) PostChatMessage(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ChatMessageRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	principal := auth.FromContext(r.Context())
+	exempt := principal.Service
+	if !principal.Service {
+		req.UserID = principal.UserID
+		req.UserName = principal.Username
+
+		var err error
+		if exempt, err = h.canModerate(r, roomName, true); err != nil {
+			log.Printf("Failed to check permissions in %s: %v", roomName, err)
+			jsonError(w, "Failed to check permissions", http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.UserID == "" {
+		jsonError(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.chatService.PostMessage(r.Context(), roomName, req.UserID, req.UserName, req.Message, exempt)
+	if err != nil {
+		chatError(w, "Failed to post chat message", err)
+		return
+	}
+
+	jsonResponse(w, message, http.StatusCreated)
+}
+
+func (h *Handlers) DeleteChatMessage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+
+	messageID, err := strconv.ParseInt(vars["messageId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, "")
+	if !ok {
+		return
+	}
+
+	if err := h.chatService.DeleteMessage(r.Context(), roomName, messageID, moderatorID); err != nil {
+		chatError(w, "Failed to delete chat message", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) SetSlowMode(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req SlowModeRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeModerator(w, r, roomName, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	if err := h.chatService.SetSlowMode(r.Context(), roomName, req.Seconds, moderatorID); err != nil {
+		chatError(w, "Failed to set slow mode", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"success": true, "slow_mode_seconds": req.Seconds}, http.StatusOK)
+}
+
+func (h *Handlers) GetWatchParty(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	sync, err := h.featuresService.GetWatchParty(r.Context(), roomName)
+	if err != nil {
+		watchError(w, "Failed to get watch party", err)
+		return
+	}
+
+	jsonResponse(w, sync, http.StatusOK)
+}
+
+// StartWatchParty has the room watch media together, replacing anything it
+// was watching. Only hosts control what the room watches.
+func (h *Handlers) StartWatchParty(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req WatchPartyRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	hostID, ok := h.authorizeHost(w, r, roomName, req.HostID)
+	if !ok {
+		return
+	}
+
+	sync, err := h.featuresService.StartWatchParty(r.Context(), roomName, req.MediaURL, hostID)
+	if err != nil {
+		watchError(w, "Failed to start watch party", err)
+		return
+	}
+
+	jsonResponse(w, sync, http.StatusCreated)
+}
+
+func (h *Handlers) ControlWatchParty(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomName := vars["roomName"]
+
+	var req WatchControlRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	hostID, ok := h.authorizeHost(w, r, roomName, req.HostID)
+	if !ok {
+		return
+	}
+
+	sync, err := h.featuresService.ControlWatchParty(r.Context(), roomName, vars["action"], req.Position, hostID)
+	if err != nil {
+		watchError(w, "Failed to control watch party", err)
+		return
+	}
+
+	jsonResponse(w, sync, http.StatusOK)
+}
+
+func (h *Handlers) StopWatchParty(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req struct {
+		HostID string `json:"host_id"`
+	}
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	hostID, ok := h.authorizeHost(w, r, roomName, req.HostID)
+	if !ok {
+		return
+	}
+
+	if err := h.featuresService.StopWatchParty(r.Context(), roomName, hostID); err != nil {
+		watchError(w, "Failed to stop watch party", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func (h *Handlers) GetRoomAnalytics(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	if _, ok := h.authorizeModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	analytics, err := h.analyticsService.RoomAnalytics(r.Context(), roomName, time.Now())
+	if errors.Is(err, services.ErrNoAnalytics) {
+		jsonError(w, "No analytics for room", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get analytics of %s: %v", roomName, err)
+		jsonError(w, "Failed to get room analytics", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, analytics, http.StatusOK)
+}
+
+// ReportActiveSpeakers takes the room's active speakers from a moderator's
+// client or a service, since LiveKit only tells clients who is speaking.
+func (h *Handlers) ReportActiveSpeakers(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	var req ActiveSpeakersRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if _, ok := h.authorizeModerator(w, r, roomName, ""); !ok {
+		return
+	}
+
+	if err := h.analyticsService.ActiveSpeakers(r.Context(), roomName, req.Speakers, time.Now()); err != nil {
+		log.Printf("Failed to record active speakers in %s: %v", roomName, err)
+		jsonError(w, "Failed to record active speakers", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// GetCommunityUsage sums the community's room usage over the last days
+// (default 30, at most 365).
+func (h *Handlers) GetCommunityUsage(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeCommunity(w, r, communityID, ""); !ok {
+		return
+	}
+
+	days := getIntParam(r, "days", 30)
+	if days < 1 || days > 365 {
+		jsonError(w, "days must be between 1 and 365", http.StatusBadRequest)
+		return
+	}
+
+	usage, err := h.analyticsService.CommunityUsage(r.Context(), communityID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("Failed to get usage of community %d: %v", communityID, err)
+		jsonError(w, "Failed to get community usage", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, usage, http.StatusOK)
+}
+
+func (h *Handlers) ListScheduledRooms(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeCommunity(w, r, communityID, ""); !ok {
+		return
+	}
+
+	schedules, err := h.scheduleService.ListSchedules(r.Context(), communityID)
+	if err != nil {
+		serverError(w, "Failed to list scheduled rooms", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"schedules": schedules,
+		"count":     len(schedules),
+	}, http.StatusOK)
+}
+
+func (h *Handlers) ScheduleRoom(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req ScheduleRoomRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	moderatorID, ok := h.authorizeCommunity(w, r, communityID, req.ModeratorID)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.scheduleService.CreateSchedule(r.Context(), &services.RoomSchedule{
+		CommunityID:     communityID,
+		RoomName:        req.RoomName,
+		Title:           req.Title,
+		StartsAt:        req.StartsAt,
+		EndsAt:          req.EndsAt,
+		Recurrence:      req.Recurrence,
+		RecurUntil:      req.RecurUntil,
+		AutoOpen:        req.AutoOpen == nil || *req.AutoOpen,
+		AutoClose:       req.AutoClose == nil || *req.AutoClose,
+		MaxParticipants: req.MaxParticipants,
+		CalendarEventID: req.CalendarEventID,
+		CreatedBy:       moderatorID,
+	})
+	if errors.Is(err, services.ErrInvalidSchedule) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to schedule room", err)
+		return
+	}
+
+	jsonResponse(w, schedule, http.StatusCreated)
+}
+
+func (h *Handlers) DeleteScheduledRoom(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeCommunity(w, r, communityID, ""); !ok {
+		return
+	}
+
+	err := h.scheduleService.DeleteSchedule(r.Context(), communityID, mux.Vars(r)["scheduleId"])
+	if errors.Is(err, services.ErrScheduleNotFound) {
+		jsonError(w, "Scheduled room not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, "Failed to delete scheduled room", err)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// ListUpcomingRooms lists the community's scheduled room occurrences over
+// the next days (default 7, at most 90).
+func (h *Handlers) ListUpcomingRooms(w http.ResponseWriter, r *http.Request) {
+	communityID, ok := h.communityParam(w, r)
+	if !ok {
+		return
+	}
+
+	days := getIntParam(r, "days", 7)
+	if days < 1 || days > 90 {
+		jsonError(w, "days must be between 1 and 90", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	rooms, err := h.scheduleService.UpcomingRooms(r.Context(), communityID, now, now.AddDate(0, 0, days))
+	if err != nil {
+		serverError(w, "Failed to list upcoming rooms", err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"rooms": rooms,
+		"count": len(rooms),
+	}, http.StatusOK)
+}
+
+func (h *Handlers) LiveKitWebhook(w http.ResponseWriter, r *http.Request) {
+	event, err := h.webhookService.Receive(r)
+	if err != nil {
+		log.Printf("Rejected LiveKit webhook: %v", err)
+		jsonError(w, "Invalid webhook signature", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.webhookService.HandleEvent(r.Context(), event); err != nil {
+		log.Printf("Failed to handle LiveKit %s webhook: %v", event.Event, err)
+		jsonError(w, "Failed to handle webhook", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// authorizeModerator checks the caller may moderate the room, through their
+// community role or a role given to them in the room, and returns the ID to
+// act as. Users may only act as themselves; a service may name any moderator.
+func (h *Handlers) authorizeModerator(w http.ResponseWriter, r *http.Request, roomName, claimedID string) (string, bool) {
+	return h.authorize(w, r, roomName, claimedID, true)
+}
+
+// authorizeCommunityModerator is authorizeModerator ignoring roles given in
+// the room.
+func (h *Handlers) authorizeCommunityModerator(w http.ResponseWriter, r *http.Request, roomName, claimedID string) (string, bool) {
+	return h.authorize(w, r, roomName, claimedID, false)
+}
+
+func (h *Handlers) authorize(w http.ResponseWriter, r *http.Request, roomName, claimedID string, roomRoles bool) (string, bool) {
+	principal := auth.FromContext(r.Context())
+	if !checkClaimedID(w, principal, "Moderator ID", claimedID) {
+		return "", false
+	}
+
+	allowed, err := h.canModerate(r, roomName, roomRoles)
+	if err != nil {
+		log.Printf("Failed to check permissions in %s: %v", roomName, err)
+		jsonError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return "", false
+	}
+	if !allowed {
+		jsonErrorCode(w, CodeModeratorRequired, "Moderator role required", http.StatusForbidden)
+		return "", false
+	}
+
+	if claimedID == "" {
+		claimedID = principal.UserID
+	}
+	return claimedID, true
+}
+
+// canModerate reports whether the caller may moderate the room through
+// their community role, or with roomRoles a role given to them in the room.
+func (h *Handlers) canModerate(r *http.Request, roomName string, roomRoles bool) (bool, error) {
+	principal := auth.FromContext(r.Context())
+	communityID, err := h.roomService.CommunityID(r.Context(), roomName)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up community: %w", err)
+	}
+	if principal.CanModerate(communityID) {
+		return true, nil
+	}
+	if !roomRoles {
+		return false, nil
+	}
+
+	role, err := h.featuresService.AssignedRole(r.Context(), roomName, principal.UserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get role: %w", err)
+	}
+	return services.RoleRank(role) >= services.RoleRank(services.RoleModerator), nil
+}
+
+// authorizeCommunity checks the caller may moderate the community, and
+// returns the ID to act as, as authorizeModerator does for rooms.
+func (h *Handlers) authorizeCommunity(w http.ResponseWriter, r *http.Request, communityID int, claimedID string) (string, bool) {
+	principal := auth.FromContext(r.Context())
+	if !checkClaimedID(w, principal, "Moderator ID", claimedID) {
+		return "", false
+	}
+	if !principal.CanModerate(communityID) {
+		jsonErrorCode(w, CodeModeratorRequired, "Moderator role required", http.StatusForbidden)
+		return "", false
+	}
+
+	if claimedID == "" {
+		claimedID = principal.UserID
+	}
+	return claimedID, true
+}
+
+// authorizeHost checks the caller may control what the room watches
+// together: a host in the room, or a community moderator, who may join as
+// one. It returns the acting user as authorize does.
+func (h *Handlers) authorizeHost(w http.ResponseWriter, r *http.Request, roomName, claimedID string) (string, bool) {
+	principal := auth.FromContext(r.Context())
+	if !checkClaimedID(w, principal, "Host ID", claimedID) {
+		return "", false
+	}
+
+	allowed, err := h.canModerate(r, roomName, false)
+	if err == nil && !allowed {
+		var role string
+		role, _, err = h.roomService.ParticipantRole(r.Context(), roomName, principal.UserID)
+		allowed = role == services.RoleHost
+	}
+	if err != nil {
+		log.Printf("Failed to check permissions in %s: %v", roomName, err)
+		jsonError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return "", false
+	}
+	if !allowed {
+		jsonErrorCode(w, CodeHostRequired, "Host role required", http.StatusForbidden)
+		return "", false
+	}
+
+	if claimedID == "" {
+		claimedID = principal.UserID
+	}
+	return claimedID, true
+}
+
+// checkClaimedID checks the ID of the user acting, sent in a request, names
+// the caller unless they are the service. label names the ID in errors.
+func checkClaimedID(w http.ResponseWriter, principal *auth.Principal, label, claimedID string) bool {
+	if len(claimedID) > maxIDLength {
+		jsonError(w, label+" is too long", http.StatusBadRequest)
+		return false
+	}
+	if claimedID != "" && !principal.Service && claimedID != principal.UserID {
+		jsonError(w, label+" does not match the authenticated user", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (h *Handlers) communityParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	communityID, err := strconv.Atoi(mux.Vars(r)["communityId"])
+	if err != nil {
+		jsonError(w, "Invalid community ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return communityID, true
+}
+
+// authorizeSelf checks the caller is the user acted on, or may moderate the
+// room.
+func (h *Handlers) authorizeSelf(w http.ResponseWriter, r *http.Request, roomName, userID string) bool {
+	if userID == "" {
+		jsonError(w, "user_id is required", http.StatusBadRequest)
+		return false
+	}
+
+	principal := auth.FromContext(r.Context())
+	if principal.Service || principal.UserID == userID {
+		return true
+	}
+	_, ok := h.authorizeModerator(w, r, roomName, "")
+	return ok
+}
+
+// authorizeViewer checks the caller may see the room, as the room events
+// WebSocket does: a member of its community, someone in the call, or a
+// moderator, unless banned from the room.
+func (h *Handlers) authorizeViewer(w http.ResponseWriter, r *http.Request, roomName string) bool {
+	access, err := checkRoomAccess(r.Context(), h.roomService, h.featuresService, auth.FromContext(r.Context()), roomName)
+	if err != nil {
+		log.Printf("Failed to check access to %s: %v", roomName, err)
+		jsonError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return false
+	}
+	if access == accessNone {
+		jsonError(w, "Not a member of the room's community", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func breakoutError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidBreakout):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNoBreakouts), errors.Is(err, services.ErrBreakoutNotFound):
+		jsonError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrBreakoutsExist):
+		jsonError(w, err.Error(), http.StatusConflict)
+	default:
+		serverError(w, message, err)
+	}
+}
+
+func inviteError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInvite):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrRoomNotFound):
+		jsonError(w, "Room not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrInviteNotFound):
+		jsonError(w, "Invite not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrInviteExpired), errors.Is(err, services.ErrInviteRedeemed):
+		jsonError(w, err.Error(), http.StatusGone)
+	case errors.Is(err, services.ErrRoomLocked):
+		jsonErrorCode(w, CodeRoomLocked, "Room is locked", http.StatusForbidden)
+	case errors.Is(err, services.ErrBanned):
+		jsonErrorCode(w, CodeBanned, err.Error(), http.StatusForbidden)
+	default:
+		serverError(w, message, err)
+	}
+}
+
+func dialInError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrDialInDisabled):
+		jsonErrorCode(w, CodeNotConfigured, "Phone dial-in is not configured", http.StatusServiceUnavailable)
+	case errors.Is(err, services.ErrRoomNotFound):
+		jsonError(w, "Room not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrDialInNotEnabled):
+		jsonError(w, err.Error(), http.StatusNotFound)
+	default:
+		serverError(w, message, err)
+	}
+}
+
+func transcriptionError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrTranscriptionDisabled):
+		jsonErrorCode(w, CodeNotConfigured, "Transcription is not configured", http.StatusServiceUnavailable)
+	case errors.Is(err, services.ErrRoomNotFound):
+		jsonError(w, "Room not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrAlreadyTranscribing), errors.Is(err, services.ErrNotTranscribing):
+		jsonError(w, err.Error(), http.StatusConflict)
+	default:
+		serverError(w, message, err)
+	}
+}
+
+func banError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrBanned):
+		jsonErrorCode(w, CodeBanned, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrInvalidBan):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrBanNotFound):
+		jsonError(w, "Ban not found", http.StatusNotFound)
+	default:
+		serverError(w, message, err)
+	}
+}
+
+func screenShareError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidScreenShare):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrParticipantNotFound):
+		jsonError(w, "Participant not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrNotSharingScreen):
+		jsonError(w, err.Error(), http.StatusConflict)
+	default:
+		serverError(w, message, err)
+	}
+}
+
+func chatError(w http.ResponseWriter, message string, err error) {
+	var slowMode *services.SlowModeError
+	switch {
+	case errors.As(err, &slowMode):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(slowMode.RetryAfter.Seconds()))))
+		jsonError(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrInvalidChatMessage), errors.Is(err, services.ErrChatMessageRejected):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrChatMessageNotFound):
+		jsonError(w, "Chat message not found", http.StatusNotFound)
+	default:
+		serverError(w, message, err)
+	}
+}
+
+func watchError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWatchParty):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNoWatchParty):
+		jsonError(w, err.Error(), http.StatusNotFound)
+	default:
+		serverError(w, message, err)
+	}
+}
+
+func jsonResponse(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// getInt64Param returns zero if the parameter is missing.
+func getInt64Param(r *http.Request, key string) (int64, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+// splitParam splits a comma-separated query parameter, dropping empty
+// entries.
+func splitParam(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
 }
 
 func getIntParam(r *http.Request, key string, defaultVal int) int {
@@ -0,0 +1,787 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/gorilla/mux"
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+	"github.com/penguintech/waddlebot/module_rtc/internal/sip"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+	"github.com/twitchtv/twirp"
+)
+
+const testJWTSecret = "jwt-secret"
+
+func testToken(t *testing.T, userID string, communityRoles map[string]string) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(testJWTSecret)}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	token, err := jwt.Signed(signer).
+		Claims(jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}).
+		Claims(map[string]interface{}{"userId": userID, "username": "user " + userID, "communityRoles": communityRoles}).
+		CompactSerialize()
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+type testAPI struct {
+	t        *testing.T
+	router   *mux.Router
+	store    storage.Store
+	features *services.CallFeaturesService
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	store := storage.NewMemoryStore()
+	roomService := services.NewRoomService("http://localhost:7880", "key", "secret", store, nil)
+	analytics := services.NewAnalyticsService(store)
+	features := services.NewCallFeaturesService(roomService, store, analytics, nil)
+	broadcasts := services.NewBroadcastService("http://localhost:7880", "key", "secret", store, nil)
+	schedules := services.NewScheduleService(roomService, store, nil)
+	chat := services.NewChatService(roomService, store, nil, services.NewBlocklistFilter([]string{"darn"}, false))
+	invites := services.NewInviteService(roomService, features, store, "invite-secret", "https://waddlebot.test/calls/invite", nil)
+	// No numbers are configured, so dial-in is off
+	dialIn := services.NewSIPService(sip.NewClient("http://localhost:7880", "key", "secret"), services.SIPTrunk{}, store, nil)
+	// No speech-to-text backend is configured, so transcription is off
+	transcription := services.NewTranscriptionService("http://localhost:7880", "key", "secret", roomService, nil, services.TranscriptionOptions{}, store, nil)
+	h := NewHandlers(roomService, features, nil, broadcasts, nil, schedules, chat, invites, dialIn, transcription, analytics, nil, auth.NewAuthenticator(testJWTSecret, "service-key"), TokenLimits{PerUser: 3, PerIP: 5, Window: time.Minute})
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	return &testAPI{t: t, router: router, store: store, features: features}
+}
+
+func (a *testAPI) do(method, path, authorization, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if strings.HasPrefix(authorization, "Bearer ") {
+		req.Header.Set("Authorization", authorization)
+	} else if authorization != "" {
+		req.Header.Set("X-Service-Key", authorization)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlers_RequireAuthentication(t *testing.T) {
+	a := newTestAPI(t)
+
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/raised-hands", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", rec.Code)
+	}
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/raised-hands", "wrong-key", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong service key, got %d", rec.Code)
+	}
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/raised-hands", "service-key", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with the service key, got %d", rec.Code)
+	}
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/raised-hands", testToken(t, "1", nil), ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with a hub token, got %d", rec.Code)
+	}
+}
+
+// errorBody decodes an error response.
+func errorBody(t *testing.T, rec *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var body ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected a JSON error, got %s", rec.Body.String())
+	}
+	return body
+}
+
+func TestHandlers_Validation(t *testing.T) {
+	a := newTestAPI(t)
+	viewer := testToken(t, "1", nil)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	longID := strings.Repeat("x", maxIDLength+1)
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		code, field        string
+	}{
+		{"POST", "/api/v1/rooms", `{"community_id":7}`, http.StatusBadRequest, CodeInvalidRequest, "room_name"},
+		{"POST", "/api/v1/rooms", `{"community_id":"7","room_name":"stage"}`, http.StatusBadRequest, CodeInvalidRequest, "community_id"},
+		{"POST", "/api/v1/rooms", `{"community_id":7,"room_name":"main stage!"}`, http.StatusBadRequest, CodeInvalidRequest, ""},
+		{"POST", "/api/v1/rooms", `{"community_id":7,"room_name":"stage","max_participants":5000}`, http.StatusBadRequest, CodeInvalidRequest, ""},
+		{"POST", "/api/v1/rooms", `{"community_id":7`, http.StatusBadRequest, CodeInvalidRequest, ""},
+		{"GET", "/api/v1/rooms/community%207/raised-hands", "", http.StatusBadRequest, CodeInvalidRequest, "room_name"},
+		{"GET", "/api/v1/rooms/" + strings.Repeat("a", 256) + "/raised-hands", "", http.StatusBadRequest, CodeInvalidRequest, "room_name"},
+		{"POST", "/api/v1/rooms/community_7_lobby/mute/" + longID, "", http.StatusBadRequest, CodeInvalidRequest, "user_id"},
+		{"POST", "/api/v1/rooms/community_7_lobby/raise-hand", `{"user_name":"` + strings.Repeat("n", 200) + `"}`, http.StatusBadRequest, CodeInvalidRequest, "user_name"},
+		{"POST", "/api/v1/rooms/community_7_lobby/lock", `{"admin_id":"` + longID + `"}`, http.StatusBadRequest, CodeInvalidRequest, ""},
+		{"POST", "/api/v1/rooms/community_7_lobby/max-participants", `{"max_participants":0}`, http.StatusBadRequest, CodeInvalidRequest, ""},
+		{"POST", "/api/v1/rooms/community_7_lobby/max-participants", `{"max_participants":-1}`, http.StatusBadRequest, CodeInvalidRequest, "max_participants"},
+		{"POST", "/api/v1/rooms/community_7_lobby/audio", `{"noise_suppression":"loud"}`, http.StatusBadRequest, CodeInvalidRequest, "noise_suppression"},
+		{"POST", "/api/v1/rooms/community_7_lobby/audio", `{"dtx":"yes"}`, http.StatusBadRequest, CodeInvalidRequest, "dtx"},
+		{"POST", "/api/v1/rooms/community_7_lobby/watch", `{}`, http.StatusBadRequest, CodeInvalidRequest, "media_url"},
+		{"POST", "/api/v1/rooms/community_7_lobby/token-ttl", `{"seconds":30}`, http.StatusBadRequest, CodeInvalidRequest, "seconds"},
+		{"GET", "/api/v1/rooms/community_7_lobby/tokens?user_id=" + longID, "", http.StatusBadRequest, CodeInvalidRequest, "user_id"},
+		{"POST", "/api/v1/rooms/community_7_lobby/watch", `{"media_url":"file:///etc/passwd"}`, http.StatusBadRequest, CodeInvalidRequest, ""},
+		{"POST", "/api/v1/rooms/community_7_lobby/watch/seek", `{"position":"1:30"}`, http.StatusBadRequest, CodeInvalidRequest, "position"},
+		// Bodies that may be left out must still be JSON when sent
+		{"POST", "/api/v1/rooms/community_7_lobby/unlock", `not json`, http.StatusBadRequest, CodeInvalidRequest, ""},
+		{"POST", "/api/v1/rooms/community_7_lobby/active-speakers", `{"speakers":["` + strings.Repeat("s", maxRequestBytes) + `"]}`, http.StatusRequestEntityTooLarge, CodeRequestTooLarge, ""},
+		{"POST", "/api/v1/invites/redeem", `{}`, http.StatusBadRequest, CodeInvalidRequest, "token"},
+	} {
+		rec := a.do(tc.method, tc.path, moderator, tc.body)
+		if rec.Code != tc.status {
+			t.Errorf("%s %.60s: expected %d, got %d: %.200s", tc.method, tc.path, tc.status, rec.Code, rec.Body.String())
+			continue
+		}
+		if body := errorBody(t, rec); body.Code != tc.code || body.Field != tc.field || body.Error == "" {
+			t.Errorf("%s %.60s: expected code %q on %q, got %+v", tc.method, tc.path, tc.code, tc.field, body)
+		}
+	}
+
+	// Empty bodies are fine where they may be left out
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/unlock", moderator, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected an empty body to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := a.do("POST", "/api/v1/rooms/community_7_lobby/lock", viewer, `{}`)
+	if body := errorBody(t, rec); rec.Code != http.StatusForbidden || body.Code != CodeModeratorRequired {
+		t.Errorf("Expected a viewer to need the moderator role, got %d %+v", rec.Code, body)
+	}
+	rec = a.do("GET", "/api/v1/rooms/community_7_lobby/raised-hands", "", "")
+	if body := errorBody(t, rec); rec.Code != http.StatusUnauthorized || body.Code != CodeUnauthorized {
+		t.Errorf("Expected an unauthorized code without credentials, got %d %+v", rec.Code, body)
+	}
+}
+
+func TestServerError(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("failed to mute: %w", twirp.NewError(twirp.NotFound, "participant does not exist")), http.StatusNotFound, CodeNotFound},
+		{twirp.NewError(twirp.PermissionDenied, "permissions denied"), http.StatusForbidden, CodeForbidden},
+		{twirp.NewError(twirp.Unavailable, "no nodes"), http.StatusServiceUnavailable, CodeUnavailable},
+		{twirp.NewError(twirp.Internal, "boom"), http.StatusInternalServerError, CodeInternal},
+		{fmt.Errorf("lookup: %w", services.ErrRoomNotFound), http.StatusNotFound, CodeNotFound},
+		{errors.New("database is down"), http.StatusInternalServerError, CodeInternal},
+	} {
+		rec := httptest.NewRecorder()
+		serverError(rec, "Failed to mute participant", tc.err)
+		if body := errorBody(t, rec); rec.Code != tc.status || body.Code != tc.code {
+			t.Errorf("Expected %v to answer %d %s, got %d %+v", tc.err, tc.status, tc.code, rec.Code, body)
+		}
+	}
+}
+
+func TestHandlers_Moderation(t *testing.T) {
+	a := newTestAPI(t)
+	ctx := context.Background()
+	viewer := testToken(t, "1", nil)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	otherModerator := testToken(t, "3", map[string]string{"8": "community-admin"})
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/lock", viewer, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a viewer to be refused, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/lock", otherModerator, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected another community's moderator to be refused, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/breakouts", viewer, `{"count":2}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a viewer not to create breakout rooms, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/lock", moderator, `{"admin_id":"1"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected acting as someone else to be refused, got %d", rec.Code)
+	}
+
+	// Participants may mute themselves, but nobody else
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/mute/5", viewer, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a viewer not to mute others, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/mute/1", viewer, `{}`); rec.Code == http.StatusForbidden {
+		t.Errorf("Expected the viewer allowed to mute themselves, got %d", rec.Code)
+	}
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/lock", moderator, `{}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the moderator to lock the room, got %d: %s", rec.Code, rec.Body.String())
+	}
+	state, _ := a.store.GetRoomState(ctx, "community_7_lobby")
+	if !state.IsLocked || state.LockedBy != "2" {
+		t.Errorf("Expected the room to be locked by the moderator, got %+v", state)
+	}
+
+	// A service may act for any moderator
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/unlock", "service-key", `{"admin_id":"99"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected the service to unlock the room, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_RaisedHands(t *testing.T) {
+	a := newTestAPI(t)
+	ctx := context.Background()
+	viewer := testToken(t, "1", nil)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+
+	// The user raises their own hand, and may not raise anyone else's
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/raise-hand", viewer, `{}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the viewer to raise their hand, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/raise-hand", viewer, `{"user_id":"5"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected raising another user's hand to be refused, got %d", rec.Code)
+	}
+	hands, _ := a.features.GetRaisedHands(ctx, "community_7_lobby")
+	if len(hands) != 1 || hands[0].UserID != "1" || hands[0].UserName != "user 1" {
+		t.Fatalf("Expected the viewer's hand from their token, got %+v", hands)
+	}
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/acknowledge-hand/1", viewer, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a viewer not to acknowledge hands, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/acknowledge-hand/1", moderator, `{}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected the moderator to acknowledge the hand, got %d", rec.Code)
+	}
+
+	// A moderator may lower someone else's hand
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/lower-hand", moderator, `{"user_id":"1"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected the moderator to lower the hand, got %d", rec.Code)
+	}
+	if hands, _ := a.features.GetRaisedHands(ctx, "community_7_lobby"); len(hands) != 0 {
+		t.Errorf("Expected no hands left, got %+v", hands)
+	}
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/hand-expiry", viewer, `{"minutes":5}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a viewer not to set hand expiry, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/hand-expiry", moderator, `{"minutes":-1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a negative expiry refused, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/hand-expiry", moderator, `{"minutes":5}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected the moderator to set hand expiry, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/next-speaker", viewer, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a viewer not to pick the next speaker, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/next-speaker", moderator, `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected no next speaker with an empty queue, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_RoomModerator(t *testing.T) {
+	a := newTestAPI(t)
+	ctx := context.Background()
+	a.store.SetParticipantRole(ctx, "community_7_lobby", &storage.RoleAssignment{UserID: "4", Role: services.RoleModerator})
+	roomModerator := testToken(t, "4", nil)
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/lock", roomModerator, `{}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected a room moderator to lock the room, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_other/lock", roomModerator, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the role not to count in other rooms, got %d", rec.Code)
+	}
+
+	// Only community moderators change roles
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/participants/1/promote", roomModerator, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a room moderator not to promote, got %d", rec.Code)
+	}
+	if rec := a.do("DELETE", "/api/v1/rooms/community_7_lobby", roomModerator, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a room moderator not to delete the room, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_JoinRoles(t *testing.T) {
+	a := newTestAPI(t)
+	viewer := testToken(t, "1", nil)
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", viewer, `{"role":"admin"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown role to be refused, got %d", rec.Code)
+	}
+
+	// A participant promoted in the room may rejoin with that role
+	a.store.SetParticipantRole(context.Background(), "community_7_lobby", &storage.RoleAssignment{UserID: "1", Role: services.RoleSpeaker})
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", viewer, `{"role":"speaker"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected the speaker to join as one, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", viewer, `{"role":"moderator"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the speaker not to join as moderator, got %d", rec.Code)
+	}
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", viewer, `{"role":"host"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a viewer not to join as host, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", viewer, `{"user_id":"5"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected joining as someone else to be refused, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_StreamDestinations(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	otherModerator := testToken(t, "3", map[string]string{"8": "community-admin"})
+	body := `{"name":"Twitch","platform":"twitch","stream_key":"live_123"}`
+
+	if rec := a.do("POST", "/api/v1/communities/7/stream-destinations", otherModerator, body); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected another community's moderator to be refused, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/communities/7/stream-destinations", moderator, `{"name":"Twitch","platform":"twitch"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a destination without a stream key to be refused, got %d", rec.Code)
+	}
+	rec := a.do("POST", "/api/v1/communities/7/stream-destinations", moderator, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the moderator to add a destination, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "live_123") {
+		t.Errorf("Expected the stream key not to be returned, got %s", rec.Body.String())
+	}
+
+	rec = a.do("GET", "/api/v1/communities/7/stream-destinations", moderator, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"count":1`) || strings.Contains(rec.Body.String(), "live_123") {
+		t.Errorf("Expected one destination without its key, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("DELETE", "/api/v1/communities/7/stream-destinations/missing", moderator, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected deleting a missing destination to 404, got %d", rec.Code)
+	}
+
+	// Only community moderators start broadcasts
+	a.store.SetParticipantRole(context.Background(), "community_7_lobby", &storage.RoleAssignment{UserID: "4", Role: services.RoleModerator})
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/broadcasts", testToken(t, "4", nil), `{"destination_ids":["x"]}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a room moderator not to broadcast, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_RoomTemplates(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	otherModerator := testToken(t, "3", map[string]string{"8": "community-admin"})
+	body := `{"max_participants":20,"lobby":true,"recording":false,"codecs":["audio/opus","video/vp8"],"is_default":true}`
+
+	if rec := a.do("PUT", "/api/v1/communities/7/room-templates/stage", otherModerator, body); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected another community's moderator to be refused, got %d", rec.Code)
+	}
+	if rec := a.do("PUT", "/api/v1/communities/7/room-templates/stage", moderator, `{"default_role":"host"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a host default role to be refused, got %d", rec.Code)
+	}
+	rec := a.do("PUT", "/api/v1/communities/7/room-templates/stage", moderator, body)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"updated_by":"2"`) {
+		t.Fatalf("Expected the moderator to save a template, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("GET", "/api/v1/communities/7/room-templates", moderator, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"count":1`) {
+		t.Errorf("Expected one template, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("DELETE", "/api/v1/communities/7/room-templates/missing", moderator, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected deleting a missing template to 404, got %d", rec.Code)
+	}
+
+	// Rooms with a lobby hold viewers there, but not moderators
+	a.store.SaveRoomState(context.Background(), &storage.RoomState{RoomName: "community_7_stage", Lobby: true})
+	rec = a.do("POST", "/api/v1/rooms/community_7_stage/join", testToken(t, "1", nil), `{}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"lobby":true`) {
+		t.Errorf("Expected the viewer to wait in the lobby, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = a.do("POST", "/api/v1/rooms/community_7_stage/join", moderator, `{}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"lobby":false`) {
+		t.Errorf("Expected the moderator to skip the lobby, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlers_ListCommunityRooms(t *testing.T) {
+	a := newTestAPI(t)
+	viewer := testToken(t, "1", nil)
+
+	for _, query := range []string{"?state=active,open", "?sort=size", "?order=up", "?limit=500"} {
+		if rec := a.do("GET", "/api/v1/communities/7/rooms"+query, viewer, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected %s to be refused, got %d", query, rec.Code)
+		}
+	}
+	rec := a.do("GET", "/api/v1/communities/7/rooms?state=active&state=locked&sort=name&order=desc&limit=10", viewer, "")
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"rooms":[],"total":0,"limit":10,"offset":0}`+"\n" {
+		t.Errorf("Expected an empty page, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlers_Invites(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	a.store.SaveRoom(context.Background(), &storage.Room{RoomName: "community_7_stage", CommunityID: 7})
+
+	// Room moderators may not let guests in
+	a.store.SetParticipantRole(context.Background(), "community_7_stage", &storage.RoleAssignment{UserID: "4", Role: services.RoleModerator})
+	if rec := a.do("POST", "/api/v1/rooms/community_7_stage/invites", testToken(t, "4", nil), `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a room moderator not to invite guests, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_stage/invites", moderator, `{"role":"host"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a host invite to be refused, got %d", rec.Code)
+	}
+	rec := a.do("POST", "/api/v1/rooms/community_7_stage/invites", moderator, `{"role":"speaker","expires_in_minutes":30}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the moderator to create an invite, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var invite struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &invite)
+	if invite.Token == "" || !strings.Contains(invite.URL, "invite=") {
+		t.Fatalf("Expected a token and link, got %s", rec.Body.String())
+	}
+
+	// Guests redeem without credentials, once
+	rec = a.do("POST", "/api/v1/invites/redeem", "", `{"token":"`+invite.Token+`","name":"Visitor"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"identity":"guest_`) {
+		t.Errorf("Expected the guest to get a join token, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("POST", "/api/v1/invites/redeem", "", `{"token":"`+invite.Token+`"}`); rec.Code != http.StatusGone {
+		t.Errorf("Expected a used invite to be gone, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/invites/redeem", "", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected redeeming without a token to be refused, got %d", rec.Code)
+	}
+
+	rec = a.do("GET", "/api/v1/rooms/community_7_stage/invites", moderator, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"count":1`) || strings.Contains(rec.Body.String(), invite.Token) {
+		t.Errorf("Expected one invite listed without its token, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("DELETE", "/api/v1/rooms/community_7_stage/invites/"+invite.ID, moderator, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the invite to be revoked, got %d", rec.Code)
+	}
+	if rec := a.do("DELETE", "/api/v1/rooms/community_7_stage/invites/"+invite.ID, moderator, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected revoking twice to 404, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_Bans(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	banned := testToken(t, "8", nil)
+	a.store.SaveRoom(context.Background(), &storage.Room{RoomName: "community_7_stage", CommunityID: 7})
+	a.store.SaveBan(context.Background(), &storage.Ban{RoomName: "community_7_stage", UserID: "8", Reason: "spam", BannedBy: "2", CreatedAt: time.Now()})
+
+	rec := a.do("POST", "/api/v1/rooms/community_7_stage/join", banned, `{}`)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "banned") {
+		t.Errorf("Expected the banned user not to rejoin, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Nor through an invite while signed in
+	rec = a.do("POST", "/api/v1/rooms/community_7_stage/invites", moderator, `{}`)
+	var invite struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &invite)
+	if rec := a.do("POST", "/api/v1/invites/redeem", banned, `{"token":"`+invite.Token+`"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the banned user's invite to be refused, got %d", rec.Code)
+	}
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_stage/kick/9", moderator, `{"ban":true,"duration_minutes":-5}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a negative ban to be refused, got %d", rec.Code)
+	}
+	if rec := a.do("GET", "/api/v1/rooms/community_7_stage/bans", banned, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to list bans, got %d", rec.Code)
+	}
+	rec = a.do("GET", "/api/v1/rooms/community_7_stage/bans", moderator, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"count":1`) || !strings.Contains(rec.Body.String(), `"reason":"spam"`) {
+		t.Errorf("Expected the ban listed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := a.do("DELETE", "/api/v1/rooms/community_7_stage/bans/8", moderator, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the ban to be lifted, got %d", rec.Code)
+	}
+	if rec := a.do("DELETE", "/api/v1/rooms/community_7_stage/bans/8", moderator, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected lifting twice to 404, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_stage/join", banned, `{}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected the user to rejoin once the ban is lifted, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_DialInNotConfigured(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	a.store.SaveRoom(context.Background(), &storage.Room{RoomName: "community_7_stage", CommunityID: 7})
+
+	if rec := a.do("GET", "/api/v1/rooms/community_7_stage/dial-in", testToken(t, "5", nil), ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to see the PIN, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_stage/dial-in", moderator, `{}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected dial-in to be unavailable without numbers, got %d", rec.Code)
+	}
+	if rec := a.do("DELETE", "/api/v1/rooms/community_7_stage/dial-in", moderator, ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected disabling dial-in to be unavailable, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_Transcript(t *testing.T) {
+	a := newTestAPI(t)
+	ctx := context.Background()
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	a.store.SaveRoom(ctx, &storage.Room{RoomName: "community_7_stage", CommunityID: 7})
+	spoken := time.Now().Add(-time.Minute)
+	a.store.AddTranscriptSegment(ctx, &storage.TranscriptSegment{RoomName: "community_7_stage", UserID: "4", Text: "second", StartedAt: spoken.Add(5 * time.Second)})
+	a.store.AddTranscriptSegment(ctx, &storage.TranscriptSegment{RoomName: "community_7_stage", UserID: "3", Text: "first", StartedAt: spoken})
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_stage/transcription/start", moderator, `{}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected transcription to be unavailable without a backend, got %d", rec.Code)
+	}
+	if rec := a.do("GET", "/api/v1/rooms/community_7_stage/transcript", testToken(t, "5", nil), ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to read the transcript, got %d", rec.Code)
+	}
+
+	rec := a.do("GET", "/api/v1/rooms/community_7_stage/transcript", "service-key", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the hub to read the transcript, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var transcript struct {
+		Segments []*storage.TranscriptSegment `json:"segments"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &transcript)
+	if len(transcript.Segments) != 2 || transcript.Segments[0].Text != "first" || transcript.Segments[1].Text != "second" {
+		t.Errorf("Expected the transcript in the order it was spoken, got %+v", transcript.Segments)
+	}
+
+	if rec := a.do("DELETE", "/api/v1/rooms/community_7_stage/transcript", moderator, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the moderator to delete the transcript, got %d", rec.Code)
+	}
+	if segments, _ := a.store.ListTranscript(ctx, "community_7_stage"); len(segments) != 0 {
+		t.Errorf("Expected the transcript to be deleted, got %d segments", len(segments))
+	}
+}
+
+func TestHandlers_ScheduledRooms(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	member := testToken(t, "5", nil)
+	start := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := `{"room_name":"standup","starts_at":"` + start + `","recurrence":"daily"}`
+
+	if rec := a.do("POST", "/api/v1/communities/7/scheduled-rooms", member, body); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to schedule rooms, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/communities/7/scheduled-rooms", moderator, `{"room_name":"standup"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a schedule without a start to be refused, got %d", rec.Code)
+	}
+	rec := a.do("POST", "/api/v1/communities/7/scheduled-rooms", moderator, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the moderator to schedule a room, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var schedule services.RoomSchedule
+	json.Unmarshal(rec.Body.Bytes(), &schedule)
+	if schedule.RoomName != "community_7_standup" || !schedule.AutoOpen || !schedule.AutoClose {
+		t.Errorf("Unexpected schedule: %+v", schedule)
+	}
+
+	// Members see each occurrence
+	rec = a.do("GET", "/api/v1/communities/7/upcoming-rooms?days=3", member, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"count":3`) {
+		t.Errorf("Expected three daily occurrences, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("GET", "/api/v1/communities/7/upcoming-rooms?days=365", member, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a year ahead to be refused, got %d", rec.Code)
+	}
+
+	if rec := a.do("DELETE", "/api/v1/communities/8/scheduled-rooms/"+schedule.ID, testToken(t, "3", map[string]string{"8": "moderator"}), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected another community's schedule to be hidden, got %d", rec.Code)
+	}
+	if rec := a.do("DELETE", "/api/v1/communities/7/scheduled-rooms/"+schedule.ID, moderator, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the schedule deleted, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_Chat(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	member := testToken(t, "5", map[string]string{"7": "member"})
+
+	// Members post as themselves
+	rec := a.do("POST", "/api/v1/rooms/community_7_lobby/messages", member, `{"user_id":"2","message":"darn it"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the member to post, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var message services.ChatMessage
+	json.Unmarshal(rec.Body.Bytes(), &message)
+	if message.UserID != "5" || message.Message != "**** it" {
+		t.Errorf("Expected a filtered message from the member, got %+v", message)
+	}
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/slow-mode", member, `{"seconds":60}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to set slow mode, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/slow-mode", moderator, `{"seconds":60}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the moderator to set slow mode, got %d", rec.Code)
+	}
+	rec = a.do("POST", "/api/v1/rooms/community_7_lobby/messages", member, `{"message":"again"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected slow mode to hold the member, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/messages", moderator, `{"message":"one"}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected the moderator to skip slow mode, got %d", rec.Code)
+	}
+
+	path := "/api/v1/rooms/community_7_lobby/messages/" + strconv.FormatInt(message.ID, 10)
+	if rec := a.do("DELETE", path, member, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to delete messages, got %d", rec.Code)
+	}
+	if rec := a.do("DELETE", path, moderator, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the moderator to delete the message, got %d", rec.Code)
+	}
+
+	rec = a.do("GET", "/api/v1/rooms/community_7_lobby/messages", member, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"count":2`) || strings.Contains(rec.Body.String(), "**** it") {
+		t.Errorf("Expected two messages with the deleted one blanked, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/messages", testToken(t, "6", map[string]string{"8": "member"}), ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected another community's member not to read the chat, got %d", rec.Code)
+	}
+	a.store.AddParticipant(context.Background(), "community_7_lobby", &storage.Participant{Identity: "guest-1"})
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/messages", testToken(t, "guest-1", nil), ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected a guest in the call to read the chat, got %d", rec.Code)
+	}
+	rec = a.do("GET", "/api/v1/rooms/community_7_lobby/messages?after="+strconv.FormatInt(message.ID-1, 10), "service-key", "")
+	if !strings.Contains(rec.Body.String(), "**** it") {
+		t.Errorf("Expected the service to archive the deleted text, got %s", rec.Body.String())
+	}
+}
+
+func TestHandlers_Analytics(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	member := testToken(t, "5", nil)
+
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/analytics", moderator, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected no analytics for an unused room, got %d", rec.Code)
+	}
+
+	a.do("POST", "/api/v1/rooms/community_7_lobby/raise-hand", member, `{}`)
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/active-speakers", member, `{"speakers":["5"]}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to report speakers, got %d", rec.Code)
+	}
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/analytics", member, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to see analytics, got %d", rec.Code)
+	}
+	rec := a.do("GET", "/api/v1/rooms/community_7_lobby/analytics", moderator, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"hand_raises":1`) {
+		t.Errorf("Expected the raised hand counted, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = a.do("GET", "/api/v1/communities/7/usage?days=7", moderator, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"rooms":1`) {
+		t.Errorf("Expected the community's usage, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("GET", "/api/v1/communities/7/usage", member, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to see community usage, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_WatchParty(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+
+	rec := a.do("GET", "/api/v1/rooms/community_7_lobby/watch", moderator, "")
+	if body := errorBody(t, rec); rec.Code != http.StatusNotFound || body.Code != CodeNotFound {
+		t.Errorf("Expected no watch party, got %d %+v", rec.Code, body)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/watch", moderator, `{"media_url":"https://example.com/film.mp4"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the watch party started, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/watch/play", moderator, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected playback started, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/watch/seek", moderator, `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a seek without a position refused, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/watch/rewind", moderator, ""); rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected an unknown action not routed, got %d", rec.Code)
+	}
+
+	rec = a.do("POST", "/api/v1/rooms/community_7_lobby/watch/seek", moderator, `{"position":90}`)
+	var sync services.WatchSync
+	if err := json.Unmarshal(rec.Body.Bytes(), &sync); err != nil || rec.Code != http.StatusOK || !sync.Playing || sync.Position != 90 || sync.ServerTime == 0 {
+		t.Errorf("Expected playback at 90 seconds, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := a.do("DELETE", "/api/v1/rooms/community_7_lobby/watch", moderator, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the watch party stopped, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/watch/pause", moderator, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected nothing to pause once stopped, got %d", rec.Code)
+	}
+}
+
+func TestHandlers_TokenLimits(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+
+	// Users are held to 3 tokens a minute, and each address to 5
+	for i := 0; i < 3; i++ {
+		if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", testToken(t, "1", nil), `{}`); rec.Code != http.StatusOK {
+			t.Fatalf("Expected join %d to be allowed, got %d: %s", i+1, rec.Code, rec.Body.String())
+		}
+	}
+	rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", testToken(t, "1", nil), `{}`)
+	if body := errorBody(t, rec); rec.Code != http.StatusTooManyRequests || body.Code != CodeRateLimited || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected a user's fourth token refused, got %d %+v", rec.Code, body)
+	}
+	for i := 0; i < 2; i++ {
+		if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", testToken(t, "3", nil), `{}`); rec.Code != http.StatusOK {
+			t.Fatalf("Expected another user to join, got %d", rec.Code)
+		}
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", testToken(t, "4", nil), `{}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the address's sixth token refused, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/invites/redeem", "", `{"token":"bogus"}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected guests from the address held to its limit, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/join", "service-key", `{"user_id":"6"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected the service key not to be limited, got %d", rec.Code)
+	}
+
+	rec = a.do("GET", "/api/v1/rooms/community_7_lobby/tokens?user_id=1", moderator, "")
+	var listed struct {
+		Tokens []services.IssuedToken `json:"tokens"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || rec.Code != http.StatusOK || len(listed.Tokens) != 3 ||
+		listed.Tokens[0].RequestedBy != "1" || listed.Tokens[0].ClientIP != "192.0.2.1" {
+		t.Errorf("Expected user 1's three tokens with who asked for them, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := a.do("GET", "/api/v1/rooms/community_7_lobby/tokens", testToken(t, "1", nil), ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a viewer not to list tokens, got %d", rec.Code)
+	}
+
+	rec = a.do("POST", "/api/v1/rooms/community_7_lobby/token-ttl", moderator, `{"seconds":300}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"token_ttl_seconds":300`) {
+		t.Errorf("Expected the token lifetime set, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = a.do("POST", "/api/v1/rooms/community_7_lobby/join", "service-key", `{"user_id":"6"}`)
+	var token services.JoinToken
+	if err := json.Unmarshal(rec.Body.Bytes(), &token); err != nil || time.Until(token.ExpiresAt) > 5*time.Minute {
+		t.Errorf("Expected a token lasting the room's lifetime, got %s", rec.Body.String())
+	}
+}
+
+func TestHandlers_ScreenShare(t *testing.T) {
+	a := newTestAPI(t)
+	moderator := testToken(t, "2", map[string]string{"7": "moderator"})
+	member := testToken(t, "5", nil)
+
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/screen-share", member, `{"screen_share":"everyone"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to change screen share, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/screen-share", moderator, `{"screen_share":"nobody"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown setting refused, got %d", rec.Code)
+	}
+	if rec := a.do("POST", "/api/v1/rooms/community_7_lobby/stop-screen-share/3", member, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a member not to stop a share, got %d", rec.Code)
+	}
+}
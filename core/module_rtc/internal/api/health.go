@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// healthCheckTimeout bounds each readiness check.
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck is a dependency the module needs to serve requests.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Health serves the liveness and readiness probes. /health only says the
+// process is up, so an outage elsewhere does not get it restarted; /ready
+// runs the checks and fails while any of them does.
+type Health struct {
+	module    string
+	version   string
+	checks    []HealthCheck
+	startedAt time.Time
+}
+
+func NewHealth(module, version string, checks ...HealthCheck) *Health {
+	return &Health{
+		module:    module,
+		version:   version,
+		checks:    checks,
+		startedAt: time.Now(),
+	}
+}
+
+func (h *Health) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/health", h.Live).Methods("GET")
+	r.HandleFunc("/ready", h.Ready).Methods("GET")
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status        string                  `json:"status"`
+	Module        string                  `json:"module"`
+	Version       string                  `json:"version"`
+	Timestamp     string                  `json:"timestamp"`
+	UptimeSeconds float64                 `json:"uptime_seconds"`
+	Checks        map[string]*CheckResult `json:"checks,omitempty"`
+}
+
+func (h *Health) response(status string) *HealthResponse {
+	now := time.Now()
+	return &HealthResponse{
+		Status:        status,
+		Module:        h.module,
+		Version:       h.version,
+		Timestamp:     now.UTC().Format(time.RFC3339),
+		UptimeSeconds: now.Sub(h.startedAt).Seconds(),
+	}
+}
+
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, h.response("healthy"), http.StatusOK)
+}
+
+// Ready runs every check at once, answering 503 if any fails.
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	results := make([]*CheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			results[i] = &CheckResult{Status: "ok", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				results[i].Status = "error"
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	resp := h.response("ready")
+	resp.Checks = make(map[string]*CheckResult, len(h.checks))
+	code := http.StatusOK
+	for i, check := range h.checks {
+		resp.Checks[check.Name] = results[i]
+		if results[i].Status != "ok" {
+			resp.Status = "not_ready"
+			code = http.StatusServiceUnavailable
+		}
+	}
+	jsonResponse(w, resp, code)
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestHealth(t *testing.T) {
+	livekitErr := errors.New("connection refused")
+	health := NewHealth("rtc", "1.0.0",
+		HealthCheck{Name: "livekit", Check: func(ctx context.Context) error { return livekitErr }},
+		HealthCheck{Name: "database", Check: func(ctx context.Context) error { return nil }},
+	)
+	router := mux.NewRouter()
+	health.RegisterRoutes(router)
+
+	get := func(path string) (int, *HealthResponse) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		var resp HealthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode %s: %v", path, err)
+		}
+		return rec.Code, &resp
+	}
+
+	// Liveness does not depend on LiveKit
+	if code, resp := get("/health"); code != http.StatusOK || resp.Status != "healthy" || resp.Module != "rtc" || resp.Checks != nil {
+		t.Errorf("Expected the module live, got %d: %+v", code, resp)
+	}
+
+	code, resp := get("/ready")
+	if code != http.StatusServiceUnavailable || resp.Status != "not_ready" {
+		t.Errorf("Expected the module not ready, got %d: %+v", code, resp)
+	}
+	if c := resp.Checks["livekit"]; c == nil || c.Status != "error" || c.Error != "connection refused" {
+		t.Errorf("Expected the LiveKit check failed, got %+v", c)
+	}
+	if c := resp.Checks["database"]; c == nil || c.Status != "ok" {
+		t.Errorf("Expected the database check passed, got %+v", c)
+	}
+
+	livekitErr = nil
+	if code, resp := get("/ready"); code != http.StatusOK || resp.Status != "ready" {
+		t.Errorf("Expected the module ready, got %d: %+v", code, resp)
+	}
+}
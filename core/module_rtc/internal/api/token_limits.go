@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+)
+
+// TokenLimits caps how many join tokens each user, and each client address,
+// may be issued over the API within Window; a zero limit leaves it off. The
+// service key asks for tokens on behalf of others, so it is not limited.
+// Each replica counts the requests it serves on its own.
+//
+// Client addresses are those requests come from, or the last address in
+// X-Forwarded-For not in TrustedProxies when they come from one.
+type TokenLimits struct {
+	PerUser        int
+	PerIP          int
+	Window         time.Duration
+	TrustedProxies []*net.IPNet
+}
+
+// ParseTrustedProxies parses a comma-separated list of CIDR ranges and
+// addresses.
+func ParseTrustedProxies(list string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil && ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+type tokenWindow struct {
+	start time.Time
+	count int
+}
+
+type tokenLimiter struct {
+	limits  TokenLimits
+	windows map[string]*tokenWindow // "user:" or "ip:" and the key
+	swept   time.Time
+	mu      sync.Mutex
+}
+
+func newTokenLimiter(limits TokenLimits) *tokenLimiter {
+	if limits.Window <= 0 {
+		limits.Window = time.Minute
+	}
+	return &tokenLimiter{limits: limits, windows: make(map[string]*tokenWindow)}
+}
+
+// allow counts a token for the user and address, unless either has been
+// issued its limit in the current window, in which case it returns how long
+// until both may be issued another.
+func (l *tokenLimiter) allow(userID, ip string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= l.limits.Window {
+		for key, window := range l.windows {
+			if now.Sub(window.start) >= l.limits.Window {
+				delete(l.windows, key)
+			}
+		}
+		l.swept = now
+	}
+
+	type counted struct {
+		window *tokenWindow
+		limit  int
+	}
+	var keys []counted
+	if userID != "" && l.limits.PerUser > 0 {
+		keys = append(keys, counted{l.window("user:"+userID, now), l.limits.PerUser})
+	}
+	if ip != "" && l.limits.PerIP > 0 {
+		keys = append(keys, counted{l.window("ip:"+ip, now), l.limits.PerIP})
+	}
+
+	var wait time.Duration
+	for _, key := range keys {
+		if key.window.count >= key.limit {
+			if until := key.window.start.Add(l.limits.Window).Sub(now); until > wait {
+				wait = until
+			}
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	for _, key := range keys {
+		key.window.count++
+	}
+	return 0
+}
+
+func (l *tokenLimiter) window(key string, now time.Time) *tokenWindow {
+	window, ok := l.windows[key]
+	if !ok || now.Sub(window.start) >= l.limits.Window {
+		window = &tokenWindow{start: now}
+		l.windows[key] = window
+	}
+	return window
+}
+
+// clientIP returns the address the request came from, looking through
+// trusted proxies.
+func (l *tokenLimiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !l.trusted(host) {
+		return host
+	}
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		host = hop
+		if !l.trusted(hop) {
+			break
+		}
+	}
+	return host
+}
+
+func (l *tokenLimiter) trusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.limits.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitTokens counts a join token for the caller, who may not be signed in,
+// answering 429 if they have been issued too many. It returns the context
+// to issue the token with, recording who asked for it.
+func (h *Handlers) limitTokens(w http.ResponseWriter, r *http.Request, principal *auth.Principal) (context.Context, bool) {
+	requester := services.TokenRequester{ClientIP: h.tokenLimiter.clientIP(r)}
+	if principal != nil {
+		requester.UserID = principal.UserID
+		if principal.Service {
+			return services.WithTokenRequester(r.Context(), requester), true
+		}
+	}
+
+	if wait := h.tokenLimiter.allow(requester.UserID, requester.ClientIP, time.Now()); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		jsonError(w, "Too many join tokens requested, try again later", http.StatusTooManyRequests)
+		return nil, false
+	}
+	return services.WithTokenRequester(r.Context(), requester), true
+}
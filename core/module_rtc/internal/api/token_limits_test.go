@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenLimiter_Allow(t *testing.T) {
+	l := newTokenLimiter(TokenLimits{PerUser: 2, PerIP: 3, Window: time.Minute})
+	now := time.Now()
+
+	if l.allow("u1", "10.0.0.1", now) != 0 || l.allow("u1", "10.0.0.1", now) != 0 {
+		t.Fatal("Expected the first two tokens allowed")
+	}
+	if wait := l.allow("u1", "10.0.0.2", now.Add(20*time.Second)); wait != 40*time.Second {
+		t.Errorf("Expected the user to wait out the window from another address, got %s", wait)
+	}
+	// A refused token is not counted against the address
+	if l.allow("u2", "10.0.0.1", now) != 0 {
+		t.Error("Expected another user allowed from the address")
+	}
+	if wait := l.allow("u3", "10.0.0.1", now); wait == 0 {
+		t.Error("Expected the address's fourth token refused")
+	}
+	if l.allow("u1", "10.0.0.1", now.Add(time.Minute)) != 0 {
+		t.Error("Expected tokens allowed again in the next window")
+	}
+
+	unlimited := newTokenLimiter(TokenLimits{})
+	for i := 0; i < 100; i++ {
+		if unlimited.allow("u1", "10.0.0.1", now) != 0 {
+			t.Fatal("Expected no limits when none are set")
+		}
+	}
+}
+
+func TestTokenLimiter_ClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatalf("Failed to parse trusted proxies: %v", err)
+	}
+	l := newTokenLimiter(TokenLimits{TrustedProxies: proxies})
+
+	for _, tc := range []struct {
+		remoteAddr, forwarded, want string
+	}{
+		{"198.51.100.4:5000", "203.0.113.9", "198.51.100.4"},
+		{"192.0.2.1:5000", "", "192.0.2.1"},
+		{"192.0.2.1:5000", "203.0.113.9", "203.0.113.9"},
+		{"192.0.2.1:5000", "198.51.100.8, 203.0.113.9, 10.1.2.3", "203.0.113.9"},
+		{"192.0.2.1:5000", "10.1.2.3", "10.1.2.3"},
+	} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		if tc.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		if got := l.clientIP(r); got != tc.want {
+			t.Errorf("%s via %q: expected %s, got %s", tc.remoteAddr, tc.forwarded, tc.want, got)
+		}
+	}
+
+	if _, err := ParseTrustedProxies("not-an-address"); err == nil {
+		t.Error("Expected an invalid proxy refused")
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+)
+
+// TranscriptionSocket receives the audio LiveKit egress streams from rooms
+// being transcribed. Egress has no module credentials; each stream's URL
+// carries a token saying whose track it is.
+type TranscriptionSocket struct {
+	transcriptionService *services.TranscriptionService
+	upgrader             websocket.Upgrader
+}
+
+func NewTranscriptionSocket(transcriptionService *services.TranscriptionService) *TranscriptionSocket {
+	return &TranscriptionSocket{
+		transcriptionService: transcriptionService,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func (s *TranscriptionSocket) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/transcription/ws", s.ServeStream).Methods("GET")
+}
+
+// muteMessage is the text message egress sends when the track is muted or
+// unmuted.
+type muteMessage struct {
+	Muted bool `json:"muted"`
+}
+
+// ServeStream transcribes a track's raw audio until egress disconnects.
+func (s *TranscriptionSocket) ServeStream(w http.ResponseWriter, r *http.Request) {
+	stream, err := s.transcriptionService.OpenStream(r.Context(), r.URL.Query().Get("token"),
+		services.ParseAudioFormat(r.Header.Get("Content-Type")))
+	switch {
+	case errors.Is(err, services.ErrTranscriptionDisabled):
+		jsonErrorCode(w, CodeNotConfigured, "Transcription is not configured", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, services.ErrInvalidStream):
+		jsonError(w, err.Error(), http.StatusUnauthorized)
+		return
+	case errors.Is(err, services.ErrNotTranscribing):
+		jsonError(w, err.Error(), http.StatusGone)
+		return
+	case err != nil:
+		log.Printf("Failed to open transcription stream: %v", err)
+		jsonError(w, "Failed to open transcription stream", http.StatusInternalServerError)
+		return
+	}
+	defer stream.Close()
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// Muted tracks send nothing for as long as they stay muted, so the
+	// stream only ends when egress closes it
+	conn.SetReadDeadline(time.Time{})
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		switch messageType {
+		case websocket.BinaryMessage:
+			stream.Write(data)
+		case websocket.TextMessage:
+			var message muteMessage
+			if json.Unmarshal(data, &message) == nil && message.Muted {
+				stream.Flush()
+			}
+		}
+	}
+}
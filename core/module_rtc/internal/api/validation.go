@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+)
+
+const (
+	maxRequestBytes = 1 << 20
+	maxIDLength     = 255
+	maxNameLength   = 128
+)
+
+// validator is a request body that checks its own fields once decoded.
+type validator interface {
+	validate() error
+}
+
+// decodeBody reads a JSON request body, answering with a 4xx and reporting
+// false if it is missing, malformed, too large or invalid.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	return decode(w, r, v, false)
+}
+
+// decodeOptionalBody is decodeBody for requests that may leave the body
+// out, as moderators acting as themselves do.
+func decodeOptionalBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	return decode(w, r, v, true)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}, optional bool) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	var wrongType *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF) && optional:
+	case errors.As(err, &tooLarge):
+		jsonError(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+		return false
+	case errors.As(err, &wrongType) && wrongType.Field != "":
+		invalidField(w, wrongType.Field, "must be a "+jsonType(wrongType.Type.Kind().String()))
+		return false
+	case err != nil:
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+
+	if v, ok := v.(validator); ok {
+		if err := v.validate(); err != nil {
+			var field *fieldError
+			if errors.As(err, &field) {
+				invalidField(w, field.field, field.message)
+			} else {
+				jsonError(w, err.Error(), http.StatusBadRequest)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// jsonType names a Go kind as JSON does.
+func jsonType(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "bool":
+		return "boolean"
+	case kind == "slice", kind == "array":
+		return "list"
+	case kind == "map", kind == "struct":
+		return "object"
+	}
+	return kind
+}
+
+// checkPathParams refuses room names and user IDs in the path that could
+// not be valid, before any handler looks them up.
+func checkPathParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		if roomName, ok := vars["roomName"]; ok && !services.ValidRoomName(roomName) {
+			invalidField(w, "room_name", "is not a valid room name")
+			return
+		}
+		if userID, ok := vars["userId"]; ok && len(userID) > maxIDLength {
+			invalidField(w, "user_id", "is too long")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkLength checks an optional field is no longer than max bytes.
+func checkLength(field, value string, max int) error {
+	if len(value) > max {
+		return &fieldError{field: field, message: "is too long"}
+	}
+	return nil
+}
+
+func (req *CreateRoomRequest) validate() error {
+	switch {
+	case req.CommunityID <= 0:
+		return &fieldError{field: "community_id", message: "is required"}
+	case req.RoomName == "":
+		return &fieldError{field: "room_name", message: "is required"}
+	}
+	return nil
+}
+
+func (req *JoinRoomRequest) validate() error {
+	if err := checkLength("user_id", req.UserID, maxIDLength); err != nil {
+		return err
+	}
+	return checkLength("user_name", req.UserName, maxNameLength)
+}
+
+func (req *RaiseHandRequest) validate() error {
+	if err := checkLength("user_id", req.UserID, maxIDLength); err != nil {
+		return err
+	}
+	return checkLength("user_name", req.UserName, maxNameLength)
+}
+
+func (req *ChatMessageRequest) validate() error {
+	if err := checkLength("user_id", req.UserID, maxIDLength); err != nil {
+		return err
+	}
+	return checkLength("user_name", req.UserName, maxNameLength)
+}
+
+func (req *AudioRequest) validate() error {
+	if req.NoiseSuppression != nil && !services.ValidNoiseSuppression(*req.NoiseSuppression) {
+		return &fieldError{field: "noise_suppression", message: "must be krisp, rnnoise, browser or off"}
+	}
+	return nil
+}
+
+func (req *WatchPartyRequest) validate() error {
+	if req.MediaURL == "" {
+		return &fieldError{field: "media_url", message: "is required"}
+	}
+	return nil
+}
+
+func (req *RedeemInviteRequest) validate() error {
+	if req.Token == "" {
+		return &fieldError{field: "token", message: "is required"}
+	}
+	return nil
+}
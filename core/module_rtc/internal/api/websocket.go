@@ -0,0 +1,210 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+)
+
+const (
+	socketWriteWait    = 10 * time.Second
+	socketPongWait     = 60 * time.Second
+	socketPingInterval = socketPongWait * 9 / 10
+)
+
+// moderatorEvents are only sent to those who may moderate the room. They say
+// who was banned and why, and which invites and tokens were used.
+var moderatorEvents = map[string]bool{
+	services.EventParticipantBanned: true,
+	services.EventBanLifted:         true,
+	services.EventInviteRedeemed:    true,
+	services.EventTokensRevoked:     true,
+}
+
+// RoomSocket pushes room events to clients over WebSockets, so they need not
+// poll for raised hands, participants, mutes and the room lock.
+type RoomSocket struct {
+	roomService     *services.RoomService
+	featuresService *services.CallFeaturesService
+	events          *services.EventBus
+	authenticator   *auth.Authenticator
+	upgrader        websocket.Upgrader
+}
+
+func NewRoomSocket(roomService *services.RoomService, featuresService *services.CallFeaturesService, events *services.EventBus, authenticator *auth.Authenticator) *RoomSocket {
+	return &RoomSocket{
+		roomService:     roomService,
+		featuresService: featuresService,
+		events:          events,
+		authenticator:   authenticator,
+		upgrader: websocket.Upgrader{
+			// Credentials come from the token, never cookies, so any origin
+			// may connect.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func (s *RoomSocket) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/ws/rooms/{roomName}", s.ServeRoom).Methods("GET")
+}
+
+type snapshotMessage struct {
+	Type string `json:"type"`
+	*services.RoomSnapshot
+}
+
+type raisedHandsMessage struct {
+	Type        string                 `json:"type"`
+	RoomName    string                 `json:"room_name"`
+	RaisedHands []*services.RaisedHand `json:"raised_hands"`
+}
+
+// ServeRoom sends the room's snapshot, then each of its events as it
+// happens, to members of the room's community and those in the call. After
+// a raised hand changes, the whole queue follows the event. A user banned
+// from the room is disconnected.
+func (s *RoomSocket) ServeRoom(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["roomName"]
+
+	principal, err := s.authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="module_rtc"`)
+		jsonError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !services.ValidRoomName(roomName) {
+		invalidField(w, "room_name", "is not a valid room name")
+		return
+	}
+
+	access, err := checkRoomAccess(r.Context(), s.roomService, s.featuresService, principal, roomName)
+	if err != nil {
+		log.Printf("Failed to check access to %s: %v", roomName, err)
+		jsonError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if access == accessNone {
+		jsonError(w, "Not a member of the room's community", http.StatusForbidden)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Subscribe before reading the snapshot so no event falls between them
+	events, unsubscribe := s.events.Subscribe(roomName)
+	defer unsubscribe()
+
+	snapshot, err := s.featuresService.RoomSnapshot(r.Context(), roomName)
+	if err != nil {
+		log.Printf("Failed to get snapshot of %s: %v", roomName, err)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to get room state"),
+			time.Now().Add(socketWriteWait))
+		return
+	}
+	if err := s.write(conn, snapshotMessage{Type: "snapshot", RoomSnapshot: snapshot}); err != nil {
+		return
+	}
+
+	closed := make(chan struct{})
+	go s.readUntilClosed(conn, closed)
+
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if moderatorEvents[event.Type] && access < accessModerator {
+				if event.Type == services.EventParticipantBanned && event.UserID == principal.UserID {
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "banned from the room"),
+						time.Now().Add(socketWriteWait))
+					return
+				}
+				continue
+			}
+			// Captions already reach those in the call through LiveKit; the
+			// rest of the community does not hear the call, so it does not
+			// read it either
+			if event.Type == services.EventCaption && access < accessModerator {
+				inRoom, err := s.featuresService.InRoom(r.Context(), roomName, principal.UserID)
+				if err != nil {
+					log.Printf("Failed to list participants of %s: %v", roomName, err)
+				}
+				if !inRoom {
+					continue
+				}
+			}
+			if err := s.write(conn, event); err != nil {
+				return
+			}
+			switch event.Type {
+			case services.EventHandRaised, services.EventHandLowered, services.EventHandAcknowledged, services.EventHandExpiryChanged:
+				hands, err := s.featuresService.GetRaisedHands(r.Context(), roomName)
+				if err != nil {
+					log.Printf("Failed to get raised hands of %s: %v", roomName, err)
+					continue
+				}
+				if err := s.write(conn, raisedHandsMessage{Type: "raised_hands", RoomName: roomName, RaisedHands: hands}); err != nil {
+					return
+				}
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// authenticate accepts the API's credentials, or a hub token in the token
+// query parameter since browsers cannot set headers on WebSockets.
+func (s *RoomSocket) authenticate(r *http.Request) (*auth.Principal, error) {
+	principal, err := s.authenticator.Authenticate(r)
+	if errors.Is(err, auth.ErrNoCredentials) {
+		if token := r.URL.Query().Get("token"); token != "" {
+			return s.authenticator.AuthenticateToken(token)
+		}
+	}
+	return principal, err
+}
+
+func (s *RoomSocket) write(conn *websocket.Conn, message interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return conn.WriteJSON(message)
+}
+
+// readUntilClosed discards client messages, keeping the read deadline
+// extended by pongs, and closes closed when the connection ends.
+func (s *RoomSocket) readUntilClosed(conn *websocket.Conn, closed chan<- struct{}) {
+	defer close(closed)
+
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
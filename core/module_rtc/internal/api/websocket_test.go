@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestRoomSocket(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	events := services.NewEventBus()
+	roomService := services.NewRoomService("http://localhost:7880", "key", "secret", store, events)
+	features := services.NewCallFeaturesService(roomService, store, nil, events)
+
+	router := mux.NewRouter()
+	NewRoomSocket(roomService, features, events, auth.NewAuthenticator(testJWTSecret, "service-key")).RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/rooms/community_7_lobby"
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected a connection without credentials to be refused, got %v", err)
+	}
+
+	features.LockRoom(ctx, "community_7_lobby", "2")
+	features.RaiseHand(ctx, "community_7_lobby", "1", "One")
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token="+strings.TrimPrefix(testToken(t, "1", map[string]string{"7": "member"}), "Bearer "), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var snapshot struct {
+		Type        string                 `json:"type"`
+		IsLocked    bool                   `json:"is_locked"`
+		RaisedHands []*services.RaisedHand `json:"raised_hands"`
+	}
+	if err := conn.ReadJSON(&snapshot); err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if snapshot.Type != "snapshot" || !snapshot.IsLocked || len(snapshot.RaisedHands) != 1 {
+		t.Errorf("Expected the locked room with one hand, got %+v", snapshot)
+	}
+
+	features.RaiseHand(ctx, "community_7_lobby", "2", "Two")
+	features.RaiseHand(ctx, "community_7_other", "3", "Three")
+	features.UnlockRoom(ctx, "community_7_lobby", "2")
+
+	var event services.RoomEvent
+	if err := conn.ReadJSON(&event); err != nil || event.Type != services.EventHandRaised || event.UserID != "2" {
+		t.Fatalf("Expected the raised hand, got %+v, %v", event, err)
+	}
+	var hands raisedHandsMessage
+	if err := conn.ReadJSON(&hands); err != nil || hands.Type != "raised_hands" || len(hands.RaisedHands) != 2 {
+		t.Fatalf("Expected the queue of two hands, got %+v, %v", hands, err)
+	}
+	if err := conn.ReadJSON(&event); err != nil || event.Type != services.EventRoomUnlocked {
+		t.Errorf("Expected only this room's unlock next, got %+v, %v", event, err)
+	}
+
+	// Bans are for moderators' eyes, unless it is this user being banned
+	events.Publish(services.RoomEvent{Type: services.EventParticipantBanned, RoomName: "community_7_lobby", UserID: "4", Data: map[string]string{"reason": "spam"}})
+	events.Publish(services.RoomEvent{Type: services.EventRoomStarted, RoomName: "community_7_lobby"})
+	for event.Type != services.EventRoomStarted {
+		if err := conn.ReadJSON(&event); err != nil || event.Type == services.EventParticipantBanned {
+			t.Fatalf("Expected another user's ban left out, got %+v, %v", event, err)
+		}
+	}
+	events.Publish(services.RoomEvent{Type: services.EventParticipantBanned, RoomName: "community_7_lobby", UserID: "1"})
+	var closeErr *websocket.CloseError
+	if err := conn.ReadJSON(&event); !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation {
+		t.Errorf("Expected the banned user disconnected, got %+v, %v", event, err)
+	}
+}
+
+func TestRoomSocket_Access(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	events := services.NewEventBus()
+	roomService := services.NewRoomService("http://localhost:7880", "key", "secret", store, events)
+	features := services.NewCallFeaturesService(roomService, store, nil, events)
+
+	router := mux.NewRouter()
+	NewRoomSocket(roomService, features, events, auth.NewAuthenticator(testJWTSecret, "service-key")).RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/rooms/community_7_lobby?token="
+	dial := func(userID string, communityRoles map[string]string) (*websocket.Conn, int) {
+		conn, resp, err := websocket.DefaultDialer.Dial(url+strings.TrimPrefix(testToken(t, userID, communityRoles), "Bearer "), nil)
+		if err != nil {
+			return nil, resp.StatusCode
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var snapshot snapshotMessage
+		conn.ReadJSON(&snapshot)
+		return conn, http.StatusSwitchingProtocols
+	}
+
+	store.AddParticipant(ctx, "community_7_lobby", &storage.Participant{Identity: "guest-1", Name: "Guest"})
+	for _, tc := range []struct {
+		name           string
+		userID         string
+		communityRoles map[string]string
+		status         int
+	}{
+		{"another community", "3", map[string]string{"8": "member"}, http.StatusForbidden},
+		{"no community", "3", nil, http.StatusForbidden},
+		{"guest in the call", "guest-1", nil, http.StatusSwitchingProtocols},
+		{"member", "5", map[string]string{"7": "member"}, http.StatusSwitchingProtocols},
+	} {
+		conn, status := dial(tc.userID, tc.communityRoles)
+		if status != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, status)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+
+	features.BanParticipant(ctx, "community_7_lobby", "6", "spam", 0, "2")
+	if _, status := dial("6", map[string]string{"7": "member"}); status != http.StatusForbidden {
+		t.Errorf("Expected a banned member refused, got %d", status)
+	}
+
+	// Moderators see the events only they are shown
+	moderator, _ := dial("2", map[string]string{"7": "moderator"})
+	defer moderator.Close()
+	events.Publish(services.RoomEvent{Type: services.EventInviteRedeemed, RoomName: "community_7_lobby", UserID: "guest-2"})
+	var event services.RoomEvent
+	if err := moderator.ReadJSON(&event); err != nil || event.Type != services.EventInviteRedeemed {
+		t.Errorf("Expected the moderator to see the invite used, got %+v, %v", event, err)
+	}
+
+	// Captions go to those in the call, not the rest of the community
+	guest, _ := dial("guest-1", nil)
+	defer guest.Close()
+	member, _ := dial("5", map[string]string{"7": "member"})
+	defer member.Close()
+	caption := services.RoomEvent{Type: services.EventCaption, RoomName: "community_7_lobby", UserID: "guest-1", Data: map[string]string{"text": "hello"}}
+	events.Publish(caption)
+	events.Publish(services.RoomEvent{Type: services.EventRoomStarted, RoomName: "community_7_lobby"})
+	for name, conn := range map[string]*websocket.Conn{"guest": guest, "moderator": moderator} {
+		if err := conn.ReadJSON(&event); err != nil || event.Type != services.EventCaption {
+			t.Errorf("Expected the %s to get the caption, got %+v, %v", name, event, err)
+		}
+	}
+	if err := member.ReadJSON(&event); err != nil || event.Type != services.EventRoomStarted {
+		t.Errorf("Expected the caption kept from a member outside the call, got %+v, %v", event, err)
+	}
+}
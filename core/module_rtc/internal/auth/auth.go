@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+var (
+	ErrNoCredentials      = errors.New("authentication required")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Community roles from the hub that may moderate the community's rooms.
+var moderatorCommunityRoles = map[string]bool{
+	"community-owner": true,
+	"community-admin": true,
+	"moderator":       true,
+}
+
+// Global roles from the hub that may moderate any room.
+var adminRoles = map[string]bool{
+	"admin":          true,
+	"super_admin":    true,
+	"platform-admin": true,
+}
+
+// Principal is who a request was made by: a hub user, or another module
+// calling with the service API key.
+type Principal struct {
+	UserID         string
+	Username       string
+	Roles          []string
+	IsSuperAdmin   bool
+	CommunityRoles map[string]string // community ID -> role
+	Service        bool
+}
+
+// IsAdmin reports whether the principal may moderate every room.
+func (p *Principal) IsAdmin() bool {
+	if p.Service || p.IsSuperAdmin {
+		return true
+	}
+	for _, role := range p.Roles {
+		if adminRoles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// CanModerate reports whether the principal's claims allow moderating rooms
+// of the community.
+func (p *Principal) CanModerate(communityID int) bool {
+	if p.IsAdmin() {
+		return true
+	}
+	return communityID > 0 && moderatorCommunityRoles[p.CommunityRoles[strconv.Itoa(communityID)]]
+}
+
+// IsMember reports whether the principal belongs to the community, in any
+// role, or may moderate every room.
+func (p *Principal) IsMember(communityID int) bool {
+	if p.IsAdmin() {
+		return true
+	}
+	return communityID > 0 && p.CommunityRoles[strconv.Itoa(communityID)] != ""
+}
+
+// hubClaims are the claims of a hub session token.
+type hubClaims struct {
+	UserID         userID            `json:"userId"`
+	Username       string            `json:"username"`
+	Roles          []string          `json:"roles"`
+	IsSuperAdmin   bool              `json:"isSuperAdmin"`
+	CommunityRoles map[string]string `json:"communityRoles"`
+}
+
+// userID accepts the hub's numeric user IDs as well as strings.
+type userID string
+
+func (u *userID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*u = userID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*u = userID(n.String())
+	return nil
+}
+
+type Authenticator struct {
+	jwtSecret  []byte
+	serviceKey string
+}
+
+func NewAuthenticator(jwtSecret, serviceKey string) *Authenticator {
+	return &Authenticator{
+		jwtSecret:  []byte(jwtSecret),
+		serviceKey: serviceKey,
+	}
+}
+
+// Enabled reports whether any credentials can be accepted.
+func (a *Authenticator) Enabled() bool {
+	return len(a.jwtSecret) > 0 || a.serviceKey != ""
+}
+
+// Authenticate accepts a service API key in X-Service-Key or X-API-Key, or a
+// hub-issued JWT as a bearer token.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get("X-Service-Key"); key != "" {
+		return a.AuthenticateServiceKey(key)
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return a.AuthenticateServiceKey(key)
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrNoCredentials
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return a.AuthenticateToken(token)
+}
+
+func (a *Authenticator) AuthenticateServiceKey(key string) (*Principal, error) {
+	if a.serviceKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(a.serviceKey)) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{UserID: "service", Service: true}, nil
+}
+
+func (a *Authenticator) AuthenticateToken(token string) (*Principal, error) {
+	if len(a.jwtSecret) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var registered jwt.Claims
+	var claims hubClaims
+	if err := parsed.Claims(a.jwtSecret, &registered, &claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := registered.ValidateWithLeeway(jwt.Expected{Time: time.Now()}, time.Minute); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if claims.UserID == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{
+		UserID:         string(claims.UserID),
+		Username:       claims.Username,
+		Roles:          claims.Roles,
+		IsSuperAdmin:   claims.IsSuperAdmin,
+		CommunityRoles: claims.CommunityRoles,
+	}, nil
+}
+
+type contextKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the request's principal, or nil outside Middleware.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
+
+// Middleware rejects requests without valid credentials and adds the
+// principal to the context of those with them.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			if !errors.Is(err, ErrNoCredentials) {
+				log.Printf("Rejected credentials for %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="module_rtc"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error(), "code": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func hubToken(t *testing.T, secret string, expiry time.Time, claims map[string]interface{}) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(secret)}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	token, err := jwt.Signed(signer).Claims(jwt.Claims{Expiry: jwt.NewNumericDate(expiry)}).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestAuthenticator(t *testing.T) {
+	a := NewAuthenticator("jwt-secret", "service-key")
+	hour := time.Now().Add(time.Hour)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+hubToken(t, "jwt-secret", hour, map[string]interface{}{
+		"userId":         42,
+		"username":       "alice",
+		"communityRoles": map[string]string{"7": "moderator", "8": "member"},
+	}))
+	p, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Failed to authenticate hub token: %v", err)
+	}
+	if p.UserID != "42" || p.Username != "alice" || p.Service {
+		t.Errorf("Unexpected principal: %+v", p)
+	}
+	if !p.CanModerate(7) || p.CanModerate(8) || p.CanModerate(0) {
+		t.Errorf("Expected alice to moderate only community 7, got %+v", p.CommunityRoles)
+	}
+	if !p.IsMember(7) || !p.IsMember(8) || p.IsMember(9) || p.IsMember(0) {
+		t.Errorf("Expected alice to belong to communities 7 and 8, got %+v", p.CommunityRoles)
+	}
+
+	for name, header := range map[string]string{
+		"wrong secret": "Bearer " + hubToken(t, "other", hour, map[string]interface{}{"userId": "42"}),
+		"expired":      "Bearer " + hubToken(t, "jwt-secret", time.Now().Add(-time.Hour), map[string]interface{}{"userId": "42"}),
+		"no user":      "Bearer " + hubToken(t, "jwt-secret", hour, map[string]interface{}{"username": "bob"}),
+		"not bearer":   "Basic abc",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", header)
+		if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected ErrInvalidCredentials, got %v", name, err)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Service-Key", "service-key")
+	if p, err := a.Authenticate(req); err != nil || !p.Service || !p.CanModerate(0) {
+		t.Errorf("Expected the service key to be accepted as an admin, got %+v, %v", p, err)
+	}
+	req.Header.Set("X-Service-Key", "guess")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected a wrong service key to be rejected, got %v", err)
+	}
+
+	if _, err := a.Authenticate(httptest.NewRequest("GET", "/", nil)); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials, got %v", err)
+	}
+}
+
+func TestAuthenticator_NothingConfigured(t *testing.T) {
+	a := NewAuthenticator("", "")
+	if a.Enabled() {
+		t.Error("Expected no credentials to be accepted")
+	}
+	if _, err := a.AuthenticateServiceKey(""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected an empty service key to be rejected, got %v", err)
+	}
+}
+
+func TestPrincipal_IsAdmin(t *testing.T) {
+	if !(&Principal{Roles: []string{"vendor", "platform-admin"}}).IsAdmin() {
+		t.Error("Expected a platform admin to be an admin")
+	}
+	if !(&Principal{IsSuperAdmin: true}).CanModerate(3) {
+		t.Error("Expected a super admin to moderate any community")
+	}
+	if (&Principal{Roles: []string{"vendor"}}).IsAdmin() {
+		t.Error("Expected a vendor not to be an admin")
+	}
+}
@@ -0,0 +1,112 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrEventNotFound = errors.New("calendar event not found")
+
+// Client reads community events from the calendar module.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether the calendar URL is configured.
+func (c *Client) Enabled() bool {
+	return c != nil && c.baseURL != ""
+}
+
+// Event is a community calendar event. EndsAt is zero if the event has no
+// end.
+type Event struct {
+	ID          int
+	CommunityID int
+	Title       string
+	Status      string
+	StartsAt    time.Time
+	EndsAt      time.Time
+}
+
+type eventResponse struct {
+	Data struct {
+		ID          int     `json:"id"`
+		CommunityID int     `json:"community_id"`
+		Title       string  `json:"title"`
+		Status      string  `json:"status"`
+		EventDate   string  `json:"event_date"`
+		EndDate     *string `json:"end_date"`
+	} `json:"data"`
+}
+
+func (c *Client) GetEvent(ctx context.Context, communityID, eventID int) (*Event, error) {
+	if !c.Enabled() {
+		return nil, fmt.Errorf("calendar module is not configured")
+	}
+
+	url := fmt.Sprintf("%s/api/v1/calendar/%d/events/%d", c.baseURL, communityID, eventID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calendar request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calendar request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrEventNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("calendar request failed: %s", resp.Status)
+	}
+
+	var body eventResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode calendar event: %w", err)
+	}
+	// The calendar looks events up by ID alone
+	if body.Data.CommunityID != communityID {
+		return nil, ErrEventNotFound
+	}
+
+	event := &Event{
+		ID:          body.Data.ID,
+		CommunityID: body.Data.CommunityID,
+		Title:       body.Data.Title,
+		Status:      body.Data.Status,
+	}
+	if event.StartsAt, err = parseTime(body.Data.EventDate); err != nil {
+		return nil, fmt.Errorf("failed to decode calendar event: %w", err)
+	}
+	if body.Data.EndDate != nil {
+		if event.EndsAt, err = parseTime(*body.Data.EndDate); err != nil {
+			return nil, fmt.Errorf("failed to decode calendar event: %w", err)
+		}
+	}
+	return event, nil
+}
+
+// parseTime reads Python's isoformat, which has no zone for naive times;
+// those are taken as UTC.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05.999999", value, time.UTC)
+}
@@ -0,0 +1,55 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// ServiceKeyInterceptors only let through calls with the service API key in
+// their x-service-key metadata, as the gRPC API is for other core modules.
+// Health checks need no key.
+func ServiceKeyInterceptors(authenticator *auth.Authenticator) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	check := func(ctx context.Context) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		keys := md.Get("x-service-key")
+		if len(keys) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "service API key required")
+		}
+		principal, err := authenticator.AuthenticateServiceKey(keys[0])
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid service API key")
+		}
+		return auth.WithPrincipal(ctx, principal), nil
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(ctx, req)
+		}
+		ctx, err := check(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(srv, ss)
+		}
+		if _, err := check(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+
+	return unary, stream
+}
@@ -0,0 +1,66 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/auth"
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+	rtcpb "github.com/penguintech/waddlebot/module_rtc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServiceKeyInterceptors(t *testing.T) {
+	events := services.NewEventBus()
+	features := services.NewCallFeaturesService(nil, storage.NewMemoryStore(), nil, events)
+
+	unary, stream := ServiceKeyInterceptors(auth.NewAuthenticator("jwt-secret", "service-key"))
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer(grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	NewServer(nil, features, nil, nil, nil, nil, nil, nil, nil, nil, events).Register(g)
+	healthpb.RegisterHealthServer(g, health.NewServer())
+	go g.Serve(lis)
+	defer g.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	client := rtcpb.NewRTCServiceClient(conn)
+	ctx := context.Background()
+	req := &rtcpb.RoomRequest{RoomName: "lobby"}
+
+	if _, err := client.GetRaisedHands(ctx, req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a key, got %v", err)
+	}
+	wrong := metadata.AppendToOutgoingContext(ctx, "x-service-key", "guess")
+	if _, err := client.GetRaisedHands(wrong, req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated with a wrong key, got %v", err)
+	}
+	if stream, err := client.StreamRoomEvents(ctx, req); err == nil {
+		if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated streaming without a key, got %v", err)
+		}
+	}
+
+	keyed := metadata.AppendToOutgoingContext(ctx, "x-service-key", "service-key")
+	if _, err := client.GetRaisedHands(keyed, req); err != nil {
+		t.Errorf("Expected the service key to be accepted, got %v", err)
+	}
+
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("Expected health checks without a key, got %v", err)
+	}
+}
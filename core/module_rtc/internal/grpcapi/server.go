@@ -0,0 +1,1303 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+	rtcpb "github.com/penguintech/waddlebot/module_rtc/proto"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Server serves the room management and call features over gRPC, for other
+// core modules.
+type Server struct {
+	rtcpb.UnimplementedRTCServiceServer
+	roomService          *services.RoomService
+	featuresService      *services.CallFeaturesService
+	recordingService     *services.RecordingService
+	broadcastService     *services.BroadcastService
+	breakoutService      *services.BreakoutService
+	scheduleService      *services.ScheduleService
+	chatService          *services.ChatService
+	inviteService        *services.InviteService
+	sipService           *services.SIPService
+	transcriptionService *services.TranscriptionService
+	events               *services.EventBus
+}
+
+func NewServer(roomService *services.RoomService, featuresService *services.CallFeaturesService, recordingService *services.RecordingService, broadcastService *services.BroadcastService, breakoutService *services.BreakoutService, scheduleService *services.ScheduleService, chatService *services.ChatService, inviteService *services.InviteService, sipService *services.SIPService, transcriptionService *services.TranscriptionService, events *services.EventBus) *Server {
+	return &Server{
+		roomService:          roomService,
+		featuresService:      featuresService,
+		recordingService:     recordingService,
+		broadcastService:     broadcastService,
+		breakoutService:      breakoutService,
+		scheduleService:      scheduleService,
+		chatService:          chatService,
+		inviteService:        inviteService,
+		sipService:           sipService,
+		transcriptionService: transcriptionService,
+		events:               events,
+	}
+}
+
+func (s *Server) Register(g *grpc.Server) {
+	rtcpb.RegisterRTCServiceServer(g, s)
+}
+
+func (s *Server) CreateRoom(ctx context.Context, req *rtcpb.CreateRoomRequest) (*rtcpb.Room, error) {
+	if req.CommunityId <= 0 || req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "community_id and room_name are required")
+	}
+
+	room, err := s.roomService.CreateRoom(ctx, int(req.CommunityId), req.RoomName, req.Template, req.MaxParticipants)
+	if errors.Is(err, services.ErrInvalidRoom) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, services.ErrRoomTemplateNotFound) {
+		return nil, status.Error(codes.NotFound, "room template not found")
+	}
+	if err != nil {
+		return nil, internalError("create room", err)
+	}
+	return roomToProto(room), nil
+}
+
+func (s *Server) GetRoom(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.Room, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	room, err := s.roomService.GetRoomInfo(ctx, req.RoomName)
+	if errors.Is(err, services.ErrRoomNotFound) {
+		return nil, status.Error(codes.NotFound, "room not found")
+	}
+	if err != nil {
+		return nil, internalError("get room", err)
+	}
+	return roomToProto(room), nil
+}
+
+func (s *Server) DeleteRoom(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.roomService.DeleteRoom(ctx, req.RoomName); err != nil {
+		return nil, internalError("delete room", err)
+	}
+	s.sipService.RoomDeleted(ctx, req.RoomName)
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) JoinRoom(ctx context.Context, req *rtcpb.JoinRoomRequest) (*rtcpb.JoinToken, error) {
+	if req.RoomName == "" || req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name and user_id are required")
+	}
+
+	locked, err := s.featuresService.IsRoomLocked(ctx, req.RoomName)
+	if err != nil {
+		return nil, internalError("check room lock", err)
+	}
+	if locked {
+		return nil, status.Error(codes.PermissionDenied, "room is locked")
+	}
+	if err := s.featuresService.CheckBan(ctx, req.RoomName, req.UserId); err != nil {
+		return nil, banError("check ban", err)
+	}
+
+	role := req.Role
+	if role == "" {
+		assigned, err := s.featuresService.AssignedRole(ctx, req.RoomName, req.UserId)
+		if err != nil {
+			return nil, internalError("get assigned role", err)
+		}
+		role = assigned
+	}
+	if services.RoleRank(role) < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid role")
+	}
+
+	// Callers naming a role have let the user in themselves
+	waiting := false
+	if req.Role == "" {
+		if waiting, err = s.featuresService.InLobby(ctx, req.RoomName, req.UserId); err != nil {
+			return nil, internalError("check lobby", err)
+		}
+	}
+
+	var token *services.JoinToken
+	if waiting {
+		token, err = s.roomService.JoinLobby(tokenContext(ctx), req.RoomName, req.UserId, req.UserName)
+	} else {
+		token, err = s.roomService.JoinRoom(tokenContext(ctx), req.RoomName, req.UserId, req.UserName, role)
+	}
+	if err != nil {
+		return nil, internalError("join room", err)
+	}
+	return joinTokenToProto(token), nil
+}
+
+func (s *Server) LeaveRoom(ctx context.Context, req *rtcpb.UserRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	s.featuresService.LowerHand(ctx, req.RoomName, req.UserId)
+
+	if err := s.roomService.LeaveRoom(ctx, req.RoomName, req.UserId); err != nil {
+		log.Printf("Failed to leave room: %v", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) ListParticipants(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.ListParticipantsResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	participants, err := s.roomService.ListParticipants(ctx, req.RoomName)
+	if err != nil {
+		return nil, internalError("list participants", err)
+	}
+
+	resp := &rtcpb.ListParticipantsResponse{Count: int32(len(participants))}
+	for _, p := range participants {
+		participant := &rtcpb.Participant{
+			UserId:       p.UserID,
+			Identity:     p.Identity,
+			Role:         p.Role,
+			JoinedAt:     p.JoinedAt,
+			IsMuted:      p.IsMuted,
+			MediaRevoked: p.MediaRevoked,
+			InLobby:      p.InLobby,
+		}
+		for _, t := range p.Tracks {
+			participant.Tracks = append(participant.Tracks, &rtcpb.Track{
+				Sid:    t.Sid,
+				Type:   t.Type,
+				Source: t.Source,
+				Muted:  t.Muted,
+			})
+		}
+		resp.Participants = append(resp.Participants, participant)
+	}
+	return resp, nil
+}
+
+func (s *Server) PromoteParticipant(ctx context.Context, req *rtcpb.RoleRequest) (*rtcpb.RoleChange, error) {
+	return s.changeRole(ctx, req, s.featuresService.PromoteParticipant)
+}
+
+func (s *Server) DemoteParticipant(ctx context.Context, req *rtcpb.RoleRequest) (*rtcpb.RoleChange, error) {
+	return s.changeRole(ctx, req, s.featuresService.DemoteParticipant)
+}
+
+func (s *Server) changeRole(ctx context.Context, req *rtcpb.RoleRequest, change func(ctx context.Context, roomName, userID, role, moderatorID string) (*services.RoleChange, error)) (*rtcpb.RoleChange, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	result, err := change(ctx, req.RoomName, req.UserId, req.Role, req.ModeratorId)
+	if errors.Is(err, services.ErrInvalidRole) || errors.Is(err, services.ErrInvalidRoleChange) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("change role", err)
+	}
+	return &rtcpb.RoleChange{
+		UserId:       result.UserID,
+		Role:         result.Role,
+		PreviousRole: result.PreviousRole,
+		InRoom:       result.InRoom,
+	}, nil
+}
+
+func (s *Server) RaiseHand(ctx context.Context, req *rtcpb.RaiseHandRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.RaiseHand(ctx, req.RoomName, req.UserId, req.UserName); err != nil {
+		return nil, internalError("raise hand", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) LowerHand(ctx context.Context, req *rtcpb.UserRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.LowerHand(ctx, req.RoomName, req.UserId); err != nil {
+		return nil, internalError("lower hand", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) GetRaisedHands(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.RaisedHandsResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	hands, err := s.featuresService.GetRaisedHands(ctx, req.RoomName)
+	if err != nil {
+		return nil, internalError("get raised hands", err)
+	}
+
+	resp := &rtcpb.RaisedHandsResponse{Count: int32(len(hands))}
+	for _, h := range hands {
+		resp.RaisedHands = append(resp.RaisedHands, raisedHandToProto(h))
+	}
+	return resp, nil
+}
+
+func (s *Server) NextSpeaker(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.RaisedHand, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	hand, err := s.featuresService.NextSpeaker(ctx, req.RoomName, req.ModeratorId)
+	if errors.Is(err, services.ErrNoRaisedHands) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("pick next speaker", err)
+	}
+	return raisedHandToProto(hand), nil
+}
+
+func (s *Server) SetHandExpiry(ctx context.Context, req *rtcpb.HandExpiryRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	err := s.featuresService.SetHandExpiry(ctx, req.RoomName, int(req.Minutes), req.ModeratorId)
+	if errors.Is(err, services.ErrInvalidHandExpiry) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("set hand expiry", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) AcknowledgeHand(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.AcknowledgeHand(ctx, req.RoomName, req.UserId, req.ModeratorId); err != nil {
+		return nil, internalError("acknowledge hand", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) MuteParticipant(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.MuteParticipant(ctx, req.RoomName, req.UserId, req.ModeratorId); err != nil {
+		return nil, participantError("mute participant", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) UnmuteParticipant(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.UnmuteParticipant(ctx, req.RoomName, req.UserId, req.ModeratorId); err != nil {
+		return nil, participantError("unmute participant", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) RevokeMedia(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.RevokeMedia(ctx, req.RoomName, req.UserId, req.ModeratorId); err != nil {
+		return nil, participantError("revoke media", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) RestoreMedia(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.RestoreMedia(ctx, req.RoomName, req.UserId, req.ModeratorId); err != nil {
+		return nil, participantError("restore media", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) MuteAll(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.featuresService.MuteAll(ctx, req.RoomName, req.ModeratorId); err != nil {
+		return nil, internalError("mute all", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) KickParticipant(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.KickParticipant(ctx, req.RoomName, req.UserId, req.ModeratorId); err != nil {
+		return nil, internalError("kick participant", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) BanParticipant(ctx context.Context, req *rtcpb.BanRequest) (*rtcpb.Ban, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	ban, err := s.featuresService.BanParticipant(ctx, req.RoomName, req.UserId, req.Reason, time.Duration(req.DurationMinutes)*time.Minute, req.ModeratorId)
+	if err != nil {
+		return nil, banError("ban participant", err)
+	}
+	return banToProto(ban), nil
+}
+
+func (s *Server) ListBans(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.ListBansResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	bans, err := s.featuresService.ListBans(ctx, req.RoomName)
+	if err != nil {
+		return nil, internalError("list bans", err)
+	}
+	resp := &rtcpb.ListBansResponse{Bans: make([]*rtcpb.Ban, 0, len(bans))}
+	for _, ban := range bans {
+		resp.Bans = append(resp.Bans, banToProto(ban))
+	}
+	return resp, nil
+}
+
+func (s *Server) LiftBan(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.featuresService.LiftBan(ctx, req.RoomName, req.UserId, req.ModeratorId); err != nil {
+		return nil, banError("lift ban", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) RevokeTokens(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.RevokeTokensResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.roomService.RevokeTokens(ctx, req.RoomName, req.UserId, req.ModeratorId)
+	if err != nil {
+		return nil, internalError("revoke tokens", err)
+	}
+	return &rtcpb.RevokeTokensResponse{Revoked: int32(revoked)}, nil
+}
+
+func (s *Server) AdmitParticipant(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.RoleChange, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	result, err := s.featuresService.AdmitParticipant(ctx, req.RoomName, req.UserId, req.ModeratorId)
+	if errors.Is(err, services.ErrAlreadyAdmitted) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("admit participant", err)
+	}
+	return &rtcpb.RoleChange{
+		UserId:       result.UserID,
+		Role:         result.Role,
+		PreviousRole: result.PreviousRole,
+		InRoom:       result.InRoom,
+	}, nil
+}
+
+func (s *Server) LockRoom(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.featuresService.LockRoom(ctx, req.RoomName, req.ModeratorId); err != nil {
+		return nil, internalError("lock room", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) UnlockRoom(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.featuresService.UnlockRoom(ctx, req.RoomName, req.ModeratorId); err != nil {
+		return nil, internalError("unlock room", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) SetMaxParticipants(ctx context.Context, req *rtcpb.MaxParticipantsRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	err := s.roomService.SetMaxParticipants(ctx, req.RoomName, req.MaxParticipants, req.ModeratorId)
+	if errors.Is(err, services.ErrInvalidRoom) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("set max participants", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) SetAudioSettings(ctx context.Context, req *rtcpb.AudioSettingsRequest) (*rtcpb.AudioSettings, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	audio, err := s.featuresService.SetAudioSettings(ctx, req.RoomName, &services.AudioUpdate{
+		NoiseSuppression: req.NoiseSuppression,
+		EchoCancellation: req.EchoCancellation,
+		AutoGainControl:  req.AutoGainControl,
+		RED:              req.Red,
+		DTX:              req.Dtx,
+	}, req.ModeratorId)
+	if errors.Is(err, services.ErrInvalidAudioSettings) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("set audio settings", err)
+	}
+	return audioToProto(audio), nil
+}
+
+func (s *Server) SetScreenShare(ctx context.Context, req *rtcpb.ScreenShareRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.featuresService.SetScreenShare(ctx, req.RoomName, req.ScreenShare, req.ModeratorId); err != nil {
+		return nil, screenShareError("set screen share", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) StopScreenShare(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if err := requireUser(req.RoomName, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.featuresService.StopScreenShare(ctx, req.RoomName, req.UserId, req.ModeratorId); err != nil {
+		return nil, screenShareError("stop screen share", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) CreateInvite(ctx context.Context, req *rtcpb.CreateInviteRequest) (*rtcpb.Invite, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	invite, err := s.inviteService.CreateInvite(ctx, req.RoomName, req.Role, time.Duration(req.ExpiresInMinutes)*time.Minute, req.ModeratorId)
+	if err != nil {
+		return nil, inviteError("create invite", err)
+	}
+	return &rtcpb.Invite{
+		Id:        invite.ID,
+		RoomName:  invite.RoomName,
+		Role:      invite.Role,
+		ExpiresAt: invite.ExpiresAt.Unix(),
+		Token:     invite.Token,
+		Url:       invite.URL,
+	}, nil
+}
+
+func (s *Server) RedeemInvite(ctx context.Context, req *rtcpb.RedeemInviteRequest) (*rtcpb.JoinToken, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	token, err := s.inviteService.RedeemInvite(tokenContext(ctx), req.Token, req.Name, req.UserId)
+	if err != nil {
+		return nil, inviteError("redeem invite", err)
+	}
+	return joinTokenToProto(token), nil
+}
+
+func (s *Server) GetDialIn(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.DialIn, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	dialIn, err := s.sipService.DialIn(ctx, req.RoomName)
+	if err != nil {
+		return nil, dialInError("get dial-in", err)
+	}
+	return dialInToProto(dialIn), nil
+}
+
+func (s *Server) EnableDialIn(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.DialIn, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	dialIn, err := s.sipService.EnableDialIn(ctx, req.RoomName, req.ModeratorId)
+	if err != nil {
+		return nil, dialInError("enable dial-in", err)
+	}
+	return dialInToProto(dialIn), nil
+}
+
+func (s *Server) DisableDialIn(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.sipService.DisableDialIn(ctx, req.RoomName, req.ModeratorId); err != nil {
+		return nil, dialInError("disable dial-in", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) StartRecording(ctx context.Context, req *rtcpb.StartRecordingRequest) (*rtcpb.Recording, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	var recording *services.Recording
+	var err error
+	if req.TrackId != "" {
+		recording, err = s.recordingService.StartTrackRecording(ctx, req.RoomName, req.TrackId, req.ModeratorId)
+	} else {
+		recording, err = s.recordingService.StartRoomRecording(ctx, req.RoomName, req.ModeratorId)
+	}
+	if errors.Is(err, services.ErrAlreadyRecording) {
+		return nil, status.Error(codes.AlreadyExists, "already being recorded")
+	}
+	if errors.Is(err, services.ErrRecordingDisabled) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("start recording", err)
+	}
+	return recordingToProto(recording), nil
+}
+
+func (s *Server) StopRecording(ctx context.Context, req *rtcpb.StopRecordingRequest) (*rtcpb.Recording, error) {
+	if req.RoomName == "" || req.EgressId == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name and egress_id are required")
+	}
+
+	recording, err := s.recordingService.StopRecording(ctx, req.RoomName, req.EgressId, req.ModeratorId)
+	if errors.Is(err, services.ErrRecordingNotFound) {
+		return nil, status.Error(codes.NotFound, "recording not found")
+	}
+	if errors.Is(err, services.ErrRecordingNotActive) {
+		return nil, status.Error(codes.FailedPrecondition, "recording has already ended")
+	}
+	if err != nil {
+		return nil, internalError("stop recording", err)
+	}
+	return recordingToProto(recording), nil
+}
+
+func (s *Server) ListRecordings(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.ListRecordingsResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	recordings, err := s.recordingService.ListRecordings(ctx, req.RoomName)
+	if err != nil {
+		return nil, internalError("list recordings", err)
+	}
+
+	resp := &rtcpb.ListRecordingsResponse{Count: int32(len(recordings))}
+	for _, r := range recordings {
+		resp.Recordings = append(resp.Recordings, recordingToProto(r))
+	}
+	return resp, nil
+}
+
+func (s *Server) StartTranscription(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	tracks, err := s.transcriptionService.StartTranscription(ctx, req.RoomName, req.ModeratorId)
+	if err != nil {
+		return nil, transcriptionError("start transcription", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true, Message: fmt.Sprintf("Transcribing %d tracks", tracks)}, nil
+}
+
+func (s *Server) StopTranscription(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.transcriptionService.StopTranscription(ctx, req.RoomName, req.ModeratorId); err != nil {
+		return nil, transcriptionError("stop transcription", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) GetTranscript(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.Transcript, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	segments, err := s.transcriptionService.Transcript(ctx, req.RoomName)
+	if err != nil {
+		return nil, internalError("get transcript", err)
+	}
+
+	resp := &rtcpb.Transcript{RoomName: req.RoomName}
+	for _, segment := range segments {
+		resp.Segments = append(resp.Segments, &rtcpb.TranscriptSegment{
+			Id:        segment.ID,
+			UserId:    segment.UserID,
+			UserName:  segment.UserName,
+			TrackId:   segment.TrackID,
+			Text:      segment.Text,
+			StartedAt: segment.StartedAt.Unix(),
+			EndedAt:   segment.EndedAt.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) StartBroadcast(ctx context.Context, req *rtcpb.StartBroadcastRequest) (*rtcpb.Broadcast, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	broadcast, err := s.broadcastService.StartBroadcast(ctx, req.RoomName, req.DestinationIds, req.ModeratorId)
+	if errors.Is(err, services.ErrInvalidStreamDestination) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, services.ErrStreamDestinationNotFound) {
+		return nil, status.Error(codes.NotFound, "stream destination not found")
+	}
+	if errors.Is(err, services.ErrAlreadyBroadcasting) {
+		return nil, status.Error(codes.AlreadyExists, "already being broadcast")
+	}
+	if err != nil {
+		return nil, internalError("start broadcast", err)
+	}
+	return broadcastToProto(broadcast), nil
+}
+
+func (s *Server) StopBroadcast(ctx context.Context, req *rtcpb.StopBroadcastRequest) (*rtcpb.Broadcast, error) {
+	if req.RoomName == "" || req.EgressId == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name and egress_id are required")
+	}
+
+	broadcast, err := s.broadcastService.StopBroadcast(ctx, req.RoomName, req.EgressId, req.ModeratorId)
+	if errors.Is(err, services.ErrBroadcastNotFound) {
+		return nil, status.Error(codes.NotFound, "broadcast not found")
+	}
+	if errors.Is(err, services.ErrBroadcastNotActive) {
+		return nil, status.Error(codes.FailedPrecondition, "broadcast has already ended")
+	}
+	if err != nil {
+		return nil, internalError("stop broadcast", err)
+	}
+	return broadcastToProto(broadcast), nil
+}
+
+func (s *Server) ListBroadcasts(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.ListBroadcastsResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	broadcasts, err := s.broadcastService.ListBroadcasts(ctx, req.RoomName)
+	if err != nil {
+		return nil, internalError("list broadcasts", err)
+	}
+
+	resp := &rtcpb.ListBroadcastsResponse{Count: int32(len(broadcasts))}
+	for _, b := range broadcasts {
+		resp.Broadcasts = append(resp.Broadcasts, broadcastToProto(b))
+	}
+	return resp, nil
+}
+
+func (s *Server) PostChatMessage(ctx context.Context, req *rtcpb.PostChatMessageRequest) (*rtcpb.ChatMessage, error) {
+	if req.RoomName == "" || req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name and user_id are required")
+	}
+
+	message, err := s.chatService.PostMessage(ctx, req.RoomName, req.UserId, req.UserName, req.Message, true)
+	if err != nil {
+		return nil, chatError("post chat message", err)
+	}
+	return chatMessageToProto(message), nil
+}
+
+func (s *Server) ListChatMessages(ctx context.Context, req *rtcpb.ListChatMessagesRequest) (*rtcpb.ListChatMessagesResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	messages, err := s.chatService.GetMessages(ctx, req.RoomName, req.AfterId, req.BeforeId, int(req.Limit), true)
+	if err != nil {
+		return nil, internalError("list chat messages", err)
+	}
+
+	resp := &rtcpb.ListChatMessagesResponse{Count: int32(len(messages))}
+	for _, m := range messages {
+		resp.Messages = append(resp.Messages, chatMessageToProto(m))
+	}
+	return resp, nil
+}
+
+func (s *Server) DeleteChatMessage(ctx context.Context, req *rtcpb.DeleteChatMessageRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" || req.MessageId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "room_name and message_id are required")
+	}
+
+	if err := s.chatService.DeleteMessage(ctx, req.RoomName, req.MessageId, req.ModeratorId); err != nil {
+		return nil, chatError("delete chat message", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) StartWatchParty(ctx context.Context, req *rtcpb.StartWatchPartyRequest) (*rtcpb.WatchSync, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	sync, err := s.featuresService.StartWatchParty(ctx, req.RoomName, req.MediaUrl, req.HostId)
+	if err != nil {
+		return nil, watchError("start watch party", err)
+	}
+	return watchSyncToProto(sync), nil
+}
+
+func (s *Server) ControlWatchParty(ctx context.Context, req *rtcpb.WatchControlRequest) (*rtcpb.WatchSync, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	sync, err := s.featuresService.ControlWatchParty(ctx, req.RoomName, req.Action, req.Position, req.HostId)
+	if err != nil {
+		return nil, watchError("control watch party", err)
+	}
+	return watchSyncToProto(sync), nil
+}
+
+func (s *Server) GetWatchParty(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.WatchSync, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	sync, err := s.featuresService.GetWatchParty(ctx, req.RoomName)
+	if err != nil {
+		return nil, watchError("get watch party", err)
+	}
+	return watchSyncToProto(sync), nil
+}
+
+func (s *Server) StopWatchParty(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.featuresService.StopWatchParty(ctx, req.RoomName, req.ModeratorId); err != nil {
+		return nil, watchError("stop watch party", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) ListUpcomingRooms(ctx context.Context, req *rtcpb.UpcomingRoomsRequest) (*rtcpb.UpcomingRoomsResponse, error) {
+	if req.CommunityId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "community_id is required")
+	}
+	days := int(req.Days)
+	if days == 0 {
+		days = 7
+	}
+	if days < 1 || days > 90 {
+		return nil, status.Error(codes.InvalidArgument, "days must be between 1 and 90")
+	}
+
+	now := time.Now()
+	rooms, err := s.scheduleService.UpcomingRooms(ctx, int(req.CommunityId), now, now.AddDate(0, 0, days))
+	if err != nil {
+		return nil, internalError("list upcoming rooms", err)
+	}
+
+	resp := &rtcpb.UpcomingRoomsResponse{Count: int32(len(rooms))}
+	for _, room := range rooms {
+		resp.Rooms = append(resp.Rooms, &rtcpb.UpcomingRoom{
+			ScheduleId:      room.ScheduleID,
+			CommunityId:     int32(room.CommunityID),
+			RoomName:        room.RoomName,
+			Title:           room.Title,
+			StartsAt:        room.StartsAt.Unix(),
+			EndsAt:          room.EndsAt.Unix(),
+			IsOpen:          room.IsOpen,
+			CalendarEventId: int32(room.CalendarEventID),
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) CreateBreakouts(ctx context.Context, req *rtcpb.CreateBreakoutsRequest) (*rtcpb.Breakouts, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	rooms, err := s.breakoutService.CreateBreakouts(ctx, req.RoomName, int(req.Count), req.Names, req.ModeratorId)
+	if err != nil {
+		return nil, breakoutError("create breakout rooms", err)
+	}
+	return breakoutsToProto(&services.Breakouts{ParentRoom: req.RoomName, Rooms: rooms}), nil
+}
+
+func (s *Server) GetBreakouts(ctx context.Context, req *rtcpb.RoomRequest) (*rtcpb.Breakouts, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	breakouts, err := s.breakoutService.GetBreakouts(ctx, req.RoomName)
+	if err != nil {
+		return nil, internalError("get breakout rooms", err)
+	}
+	return breakoutsToProto(breakouts), nil
+}
+
+func (s *Server) AssignBreakouts(ctx context.Context, req *rtcpb.AssignBreakoutsRequest) (*rtcpb.BreakoutMovesResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	moves, err := s.breakoutService.AssignParticipants(ctx, req.RoomName, req.Assignments, req.ModeratorId)
+	if err != nil {
+		return nil, breakoutError("assign breakout rooms", err)
+	}
+	return movesToProto(moves), nil
+}
+
+func (s *Server) AutoAssignBreakouts(ctx context.Context, req *rtcpb.AutoAssignBreakoutsRequest) (*rtcpb.BreakoutMovesResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	moves, err := s.breakoutService.AutoAssign(ctx, req.RoomName, req.UserIds, req.ModeratorId)
+	if err != nil {
+		return nil, breakoutError("assign breakout rooms", err)
+	}
+	return movesToProto(moves), nil
+}
+
+func (s *Server) BroadcastToBreakouts(ctx context.Context, req *rtcpb.BreakoutMessageRequest) (*rtcpb.BreakoutMessageResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	sent, err := s.breakoutService.BroadcastMessage(ctx, req.RoomName, req.Message, req.ModeratorId)
+	if err != nil {
+		return nil, breakoutError("send breakout message", err)
+	}
+	return &rtcpb.BreakoutMessageResponse{Rooms: int32(sent)}, nil
+}
+
+func (s *Server) ReturnFromBreakouts(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.BreakoutMovesResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	moves, err := s.breakoutService.ReturnAll(ctx, req.RoomName, req.ModeratorId)
+	if err != nil {
+		return nil, breakoutError("return participants", err)
+	}
+	return movesToProto(moves), nil
+}
+
+func (s *Server) CloseBreakouts(ctx context.Context, req *rtcpb.ModerationRequest) (*rtcpb.SuccessResponse, error) {
+	if req.RoomName == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_name is required")
+	}
+
+	if err := s.breakoutService.CloseBreakouts(ctx, req.RoomName, req.ModeratorId); err != nil {
+		return nil, breakoutError("close breakout rooms", err)
+	}
+	return &rtcpb.SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) StreamRoomEvents(req *rtcpb.RoomRequest, stream rtcpb.RTCService_StreamRoomEventsServer) error {
+	events, unsubscribe := s.events.Subscribe(req.RoomName)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&rtcpb.RoomEvent{
+				Type:      event.Type,
+				RoomName:  event.RoomName,
+				UserId:    event.UserID,
+				ActorId:   event.ActorID,
+				Data:      event.Data,
+				Timestamp: event.Timestamp.Unix(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func joinTokenToProto(token *services.JoinToken) *rtcpb.JoinToken {
+	return &rtcpb.JoinToken{
+		Token:     token.Token,
+		RoomName:  token.RoomName,
+		Identity:  token.Identity,
+		Lobby:     token.Lobby,
+		Audio:     audioToProto(token.Audio),
+		ExpiresAt: token.ExpiresAt.Unix(),
+	}
+}
+
+// tokenContext records tokens issued with the returned context as requested
+// by the service calling from the peer's address.
+func tokenContext(ctx context.Context) context.Context {
+	requester := services.TokenRequester{UserID: "service"}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		requester.ClientIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(requester.ClientIP); err == nil {
+			requester.ClientIP = host
+		}
+	}
+	return services.WithTokenRequester(ctx, requester)
+}
+
+func audioToProto(audio services.AudioSettings) *rtcpb.AudioSettings {
+	return &rtcpb.AudioSettings{
+		NoiseSuppression: audio.NoiseSuppression,
+		EchoCancellation: audio.EchoCancellation,
+		AutoGainControl:  audio.AutoGainControl,
+		Red:              audio.RED,
+		Dtx:              audio.DTX,
+	}
+}
+
+func watchSyncToProto(sync *services.WatchSync) *rtcpb.WatchSync {
+	return &rtcpb.WatchSync{
+		MediaUrl:   sync.MediaURL,
+		Playing:    sync.Playing,
+		Position:   sync.Position,
+		ServerTime: sync.ServerTime,
+		UpdatedBy:  sync.UpdatedBy,
+	}
+}
+
+func roomToProto(room *services.RoomInfo) *rtcpb.Room {
+	return &rtcpb.Room{
+		RoomId:       room.RoomID,
+		RoomName:     room.RoomName,
+		CommunityId:  int32(room.CommunityID),
+		Participants: int32(room.Participants),
+		CreatedAt:    room.CreatedAt.Unix(),
+		IsLocked:     room.IsLocked,
+		ScreenShare:  room.ScreenShare,
+		Template:     room.Template,
+		Lobby:        room.Lobby,
+	}
+}
+
+func raisedHandToProto(h *services.RaisedHand) *rtcpb.RaisedHand {
+	hand := &rtcpb.RaisedHand{
+		UserId:         h.UserID,
+		UserName:       h.UserName,
+		RaisedAt:       h.RaisedAt.Unix(),
+		AcknowledgedBy: h.AcknowledgedBy,
+		Position:       int32(h.Position),
+	}
+	if h.AcknowledgedAt != nil {
+		hand.AcknowledgedAt = h.AcknowledgedAt.Unix()
+	}
+	if h.ExpiresAt != nil {
+		hand.ExpiresAt = h.ExpiresAt.Unix()
+	}
+	return hand
+}
+
+func dialInToProto(d *services.DialInInfo) *rtcpb.DialIn {
+	return &rtcpb.DialIn{
+		RoomName:  d.RoomName,
+		Pin:       d.PIN,
+		Numbers:   d.Numbers,
+		CreatedBy: d.CreatedBy,
+		CreatedAt: d.CreatedAt.Unix(),
+	}
+}
+
+func banToProto(b *services.Ban) *rtcpb.Ban {
+	ban := &rtcpb.Ban{
+		RoomName:  b.RoomName,
+		UserId:    b.UserID,
+		Reason:    b.Reason,
+		BannedBy:  b.BannedBy,
+		CreatedAt: b.CreatedAt.Unix(),
+	}
+	if b.ExpiresAt != nil {
+		ban.ExpiresAt = b.ExpiresAt.Unix()
+	}
+	return ban
+}
+
+func recordingToProto(r *services.Recording) *rtcpb.Recording {
+	recording := &rtcpb.Recording{
+		EgressId:        r.EgressID,
+		RoomName:        r.RoomName,
+		Kind:            r.Kind,
+		TrackId:         r.TrackID,
+		Status:          r.Status,
+		Destination:     r.Destination,
+		Location:        r.Location,
+		Filename:        r.Filename,
+		Size:            r.Size,
+		DurationSeconds: r.DurationSeconds,
+		Error:           r.Error,
+		StartedBy:       r.StartedBy,
+		StartedAt:       r.StartedAt.Unix(),
+	}
+	if r.EndedAt != nil {
+		recording.EndedAt = r.EndedAt.Unix()
+	}
+	return recording
+}
+
+func requireUser(roomName, userID string) error {
+	if roomName == "" || userID == "" {
+		return status.Error(codes.InvalidArgument, "room_name and user_id are required")
+	}
+	return nil
+}
+
+// liveKitCodes are the codes LiveKit's errors answer with, for those the
+// caller rather than the module is to blame for.
+var liveKitCodes = map[twirp.ErrorCode]codes.Code{
+	twirp.InvalidArgument:    codes.InvalidArgument,
+	twirp.OutOfRange:         codes.OutOfRange,
+	twirp.Malformed:          codes.InvalidArgument,
+	twirp.NotFound:           codes.NotFound,
+	twirp.PermissionDenied:   codes.PermissionDenied,
+	twirp.Unauthenticated:    codes.PermissionDenied,
+	twirp.AlreadyExists:      codes.AlreadyExists,
+	twirp.FailedPrecondition: codes.FailedPrecondition,
+	twirp.ResourceExhausted:  codes.ResourceExhausted,
+	twirp.Unavailable:        codes.Unavailable,
+}
+
+// internalError answers for an error no method expected. Rooms and
+// participants that are gone, and requests LiveKit refuses, keep their code;
+// anything else is logged and answered as internal.
+func internalError(action string, err error) error {
+	var twirpErr twirp.Error
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound):
+		return status.Error(codes.NotFound, "room not found")
+	case errors.Is(err, services.ErrParticipantNotFound):
+		return status.Error(codes.NotFound, "participant not found")
+	case errors.As(err, &twirpErr):
+		if code, ok := liveKitCodes[twirpErr.Code()]; ok {
+			return status.Errorf(code, "failed to %s: %s", action, twirpErr.Msg())
+		}
+	}
+	log.Printf("Failed to %s: %v", action, err)
+	return status.Errorf(codes.Internal, "failed to %s", action)
+}
+
+func breakoutError(action string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidBreakout):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrNoBreakouts), errors.Is(err, services.ErrBreakoutNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrBreakoutsExist):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func inviteError(action string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidInvite):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrInviteNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrInviteExpired), errors.Is(err, services.ErrInviteRedeemed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, services.ErrRoomLocked), errors.Is(err, services.ErrBanned):
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func dialInError(action string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrDialInDisabled):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrDialInNotEnabled):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func transcriptionError(action string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrTranscriptionDisabled):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, services.ErrRoomNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrAlreadyTranscribing), errors.Is(err, services.ErrNotTranscribing):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func banError(action string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrBanned):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, services.ErrInvalidBan):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrBanNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func participantError(action string, err error) error {
+	if errors.Is(err, services.ErrParticipantNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func screenShareError(action string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidScreenShare):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrParticipantNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrNotSharingScreen):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func watchError(action string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidWatchParty):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrNoWatchParty):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func chatError(action string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrSlowMode):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, services.ErrInvalidChatMessage), errors.Is(err, services.ErrChatMessageRejected):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrChatMessageNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return internalError(action, err)
+}
+
+func chatMessageToProto(m *services.ChatMessage) *rtcpb.ChatMessage {
+	message := &rtcpb.ChatMessage{
+		Id:        m.ID,
+		RoomName:  m.RoomName,
+		UserId:    m.UserID,
+		UserName:  m.UserName,
+		Message:   m.Message,
+		Filtered:  m.Filtered,
+		CreatedAt: m.CreatedAt.Unix(),
+		DeletedBy: m.DeletedBy,
+	}
+	if m.DeletedAt != nil {
+		message.DeletedAt = m.DeletedAt.Unix()
+	}
+	return message
+}
+
+func breakoutsToProto(b *services.Breakouts) *rtcpb.Breakouts {
+	breakouts := &rtcpb.Breakouts{ParentRoom: b.ParentRoom}
+	for _, room := range b.Rooms {
+		breakouts.Rooms = append(breakouts.Rooms, &rtcpb.BreakoutRoom{
+			RoomName: room.RoomName,
+			Name:     room.Name,
+			Position: int32(room.Position),
+		})
+	}
+	for _, a := range b.Assignments {
+		breakouts.Assignments = append(breakouts.Assignments, &rtcpb.BreakoutAssignment{
+			UserId:     a.UserID,
+			RoomName:   a.RoomName,
+			AssignedBy: a.AssignedBy,
+			AssignedAt: a.AssignedAt.Unix(),
+		})
+	}
+	return breakouts
+}
+
+func movesToProto(moves []*services.BreakoutMove) *rtcpb.BreakoutMovesResponse {
+	resp := &rtcpb.BreakoutMovesResponse{}
+	for _, m := range moves {
+		resp.Moves = append(resp.Moves, &rtcpb.BreakoutMove{UserId: m.UserID, RoomName: m.RoomName, Notified: m.Notified})
+	}
+	return resp
+}
+
+func broadcastToProto(b *services.Broadcast) *rtcpb.Broadcast {
+	broadcast := &rtcpb.Broadcast{
+		EgressId:       b.EgressID,
+		RoomName:       b.RoomName,
+		DestinationIds: b.DestinationIDs,
+		Status:         b.Status,
+		Error:          b.Error,
+		StartedBy:      b.StartedBy,
+		StartedAt:      b.StartedAt.Unix(),
+	}
+	if b.EndedAt != nil {
+		broadcast.EndedAt = b.EndedAt.Unix()
+	}
+	return broadcast
+}
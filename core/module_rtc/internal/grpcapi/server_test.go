@@ -0,0 +1,95 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/services"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+	rtcpb "github.com/penguintech/waddlebot/module_rtc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T) rtcpb.RTCServiceClient {
+	t.Helper()
+	events := services.NewEventBus()
+	features := services.NewCallFeaturesService(nil, storage.NewMemoryStore(), nil, events)
+
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	NewServer(nil, features, nil, nil, nil, nil, nil, nil, nil, nil, events).Register(g)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return rtcpb.NewRTCServiceClient(conn)
+}
+
+func TestServer_RaisedHandsAndLocks(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.RaiseHand(ctx, &rtcpb.RaiseHandRequest{RoomName: "lobby"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without a user, got %v", err)
+	}
+
+	client.RaiseHand(ctx, &rtcpb.RaiseHandRequest{RoomName: "lobby", UserId: "u1", UserName: "One"})
+	client.RaiseHand(ctx, &rtcpb.RaiseHandRequest{RoomName: "lobby", UserId: "u2", UserName: "Two"})
+	client.AcknowledgeHand(ctx, &rtcpb.ModerationRequest{RoomName: "lobby", UserId: "u1", ModeratorId: "mod"})
+
+	hands, err := client.GetRaisedHands(ctx, &rtcpb.RoomRequest{RoomName: "lobby"})
+	if err != nil || hands.Count != 2 || hands.RaisedHands[0].AcknowledgedBy != "mod" || hands.RaisedHands[0].AcknowledgedAt == 0 {
+		t.Fatalf("Expected two hands with the first acknowledged, got %+v, %v", hands, err)
+	}
+
+	client.LockRoom(ctx, &rtcpb.ModerationRequest{RoomName: "lobby", ModeratorId: "mod"})
+	_, err = client.JoinRoom(ctx, &rtcpb.JoinRoomRequest{RoomName: "lobby", UserId: "u3"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied joining a locked room, got %v", err)
+	}
+}
+
+func TestServer_StreamRoomEvents(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamRoomEvents(ctx, &rtcpb.RoomRequest{RoomName: "lobby"})
+	if err != nil {
+		t.Fatalf("Failed to stream events: %v", err)
+	}
+
+	// The subscription starts once the server has the call, so keep raising
+	// hands in another room and then this one until the first event arrives
+	go func() {
+		for i := 0; ctx.Err() == nil; i++ {
+			client.RaiseHand(ctx, &rtcpb.RaiseHandRequest{RoomName: "stage", UserId: "u1"})
+			client.LowerHand(ctx, &rtcpb.UserRequest{RoomName: "lobby", UserId: "u1"})
+			client.RaiseHand(ctx, &rtcpb.RaiseHandRequest{RoomName: "lobby", UserId: "u1", UserName: "One"})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive event: %v", err)
+	}
+	if event.RoomName != "lobby" || event.UserId != "u1" {
+		t.Errorf("Expected an event for u1 in the lobby, got %+v", event)
+	}
+	if event.Type == services.EventHandRaised && event.Data["user_name"] != "One" {
+		t.Errorf("Expected the user name with the raised hand, got %+v", event.Data)
+	}
+}
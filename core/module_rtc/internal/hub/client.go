@@ -0,0 +1,222 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const Platform = "rtc"
+
+const (
+	EventJoin  = "join"
+	EventLeave = "leave"
+)
+
+// ErrNotRegistered is returned for heartbeats the hub has no registration
+// for, such as after its database was reset.
+var ErrNotRegistered = errors.New("module is not registered with the hub")
+
+// Client sends activity to the hub's internal API.
+type Client struct {
+	baseURL    string
+	serviceKey string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL, serviceKey string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		serviceKey: serviceKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether the hub URL and service key are configured.
+func (c *Client) Enabled() bool {
+	return c != nil && c.baseURL != "" && c.serviceKey != ""
+}
+
+type watchSessionRequest struct {
+	EventType        string `json:"eventType"`
+	CommunityID      int    `json:"communityId"`
+	Platform         string `json:"platform"`
+	PlatformUserID   string `json:"platformUserId"`
+	PlatformUsername string `json:"platformUsername,omitempty"`
+	ChannelID        string `json:"channelId"`
+}
+
+// RecordParticipant records a participant joining or leaving a room as a
+// watch session, with the room as the channel.
+func (c *Client) RecordParticipant(ctx context.Context, eventType string, communityID int, roomName, identity, name string) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	return c.post(ctx, "/api/v1/internal/activity/watch-session", watchSessionRequest{
+		EventType:        eventType,
+		CommunityID:      communityID,
+		Platform:         Platform,
+		PlatformUserID:   identity,
+		PlatformUsername: name,
+		ChannelID:        roomName,
+	})
+}
+
+// Recording is a finished room recording, reported so the hub can list it
+// for the community.
+type Recording struct {
+	CommunityID     int        `json:"communityId"`
+	RoomName        string     `json:"roomName"`
+	EgressID        string     `json:"egressId"`
+	Kind            string     `json:"kind"`
+	TrackID         string     `json:"trackId,omitempty"`
+	Status          string     `json:"status"`
+	Destination     string     `json:"destination"`
+	Location        string     `json:"location,omitempty"`
+	Filename        string     `json:"filename,omitempty"`
+	SizeBytes       int64      `json:"sizeBytes"`
+	DurationSeconds int64      `json:"durationSeconds"`
+	Error           string     `json:"error,omitempty"`
+	StartedBy       string     `json:"startedBy,omitempty"`
+	StartedAt       time.Time  `json:"startedAt"`
+	EndedAt         *time.Time `json:"endedAt,omitempty"`
+}
+
+func (c *Client) ReportRecording(ctx context.Context, recording *Recording) error {
+	if !c.Enabled() {
+		return nil
+	}
+	return c.post(ctx, "/api/v1/internal/rtc/recordings", recording)
+}
+
+// Registration is the module's entry in the hub's service catalog.
+type Registration struct {
+	Name              string            `json:"name"`
+	Version           string            `json:"version"`
+	Category          string            `json:"category"`
+	Description       string            `json:"description,omitempty"`
+	URL               string            `json:"url"`
+	Port              int               `json:"port"`
+	HealthEndpoint    string            `json:"healthEndpoint"`
+	Endpoints         map[string]string `json:"endpoints"`
+	Capabilities      []string          `json:"capabilities"`
+	HeartbeatInterval int               `json:"heartbeatInterval"` // seconds
+}
+
+// Register adds the module to the hub's service catalog, or updates its
+// entry, marking it available.
+func (c *Client) Register(ctx context.Context, registration *Registration) error {
+	if !c.Enabled() {
+		return nil
+	}
+	return c.post(ctx, "/api/v1/internal/services/register", registration)
+}
+
+// Heartbeat tells the hub the module is still available.
+func (c *Client) Heartbeat(ctx context.Context, name string) error {
+	if !c.Enabled() {
+		return nil
+	}
+	return c.post(ctx, "/api/v1/internal/services/"+url.PathEscape(name)+"/heartbeat", struct{}{})
+}
+
+// Deregister marks the module stopped in the hub's service catalog.
+func (c *Client) Deregister(ctx context.Context, name string) error {
+	if !c.Enabled() {
+		return nil
+	}
+	return c.post(ctx, "/api/v1/internal/services/"+url.PathEscape(name)+"/deregister", struct{}{})
+}
+
+// RunRegistration registers the module, then sends a heartbeat every
+// interval until the context is cancelled, when it deregisters. Failed
+// registrations are retried on the next beat, and the module registers again
+// if the hub has forgotten it.
+func (c *Client) RunRegistration(ctx context.Context, registration *Registration, interval time.Duration) {
+	if !c.Enabled() {
+		return
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	registration.HeartbeatInterval = int(interval.Seconds())
+
+	registered := false
+	beat := func() {
+		callCtx, cancel := context.WithTimeout(ctx, interval)
+		defer cancel()
+		if registered {
+			err := c.Heartbeat(callCtx, registration.Name)
+			if err == nil {
+				return
+			}
+			if !errors.Is(err, ErrNotRegistered) {
+				log.Printf("Failed to send heartbeat to hub: %v", err)
+				return
+			}
+			registered = false
+		}
+		if err := c.Register(callCtx, registration); err != nil {
+			log.Printf("Failed to register with hub, retrying in %s: %v", interval, err)
+			return
+		}
+		registered = true
+		log.Printf("Registered %s v%s with hub", registration.Name, registration.Version)
+	}
+
+	beat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if registered {
+				// The context is done, so deregister on a fresh one
+				stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := c.Deregister(stopCtx, registration.Name); err != nil {
+					log.Printf("Failed to deregister from hub: %v", err)
+				}
+				cancel()
+			}
+			return
+		case <-ticker.C:
+			beat()
+		}
+	}
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode hub request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create hub request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Service-Key", c.serviceKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("hub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && strings.HasSuffix(path, "/heartbeat") {
+		return ErrNotRegistered
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("hub request failed: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package hub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_RunRegistration(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	heartbeats := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("X-Service-Key") != "key" {
+			t.Errorf("Expected the service key on %s", r.URL.Path)
+		}
+		calls = append(calls, r.URL.Path)
+		// The hub forgets the module after its first heartbeat
+		if r.URL.Path == "/api/v1/internal/services/module-rtc/heartbeat" {
+			heartbeats++
+			if heartbeats == 2 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		}
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	registration := &Registration{Name: "module-rtc", Version: "1.0.0", URL: "http://rtc:8093"}
+	go func() {
+		NewClient(server.URL, "key").RunRegistration(ctx, registration, 10*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		mu.Lock()
+		n := heartbeats
+		mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("Timed out waiting for heartbeats")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"/api/v1/internal/services/register",
+		"/api/v1/internal/services/module-rtc/heartbeat",
+		"/api/v1/internal/services/module-rtc/heartbeat",
+		"/api/v1/internal/services/register",
+		"/api/v1/internal/services/module-rtc/heartbeat",
+	}
+	for i, path := range want {
+		if i >= len(calls) || calls[i] != path {
+			t.Fatalf("Expected calls to start %v, got %v", want, calls)
+		}
+	}
+	if last := calls[len(calls)-1]; last != "/api/v1/internal/services/module-rtc/deregister" {
+		t.Errorf("Expected to deregister on shutdown, got %v", calls)
+	}
+}
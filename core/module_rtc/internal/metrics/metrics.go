@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/twitchtv/twirp"
+)
+
+const namespace = "waddlebot_rtc"
+
+// countTimeout bounds counting rooms in the store on each scrape.
+const countTimeout = 5 * time.Second
+
+var (
+	tokensIssued = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_issued_total",
+		Help:      "Room join tokens issued, by role.",
+	}, []string{"role"})
+
+	livekitRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "livekit_requests_total",
+		Help:      "Requests made to the LiveKit API, by operation.",
+	}, []string{"operation"})
+
+	livekitErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "livekit_errors_total",
+		Help:      "Failed LiveKit API requests, by operation and Twirp error code.",
+	}, []string{"operation", "code"})
+)
+
+// TokenIssued counts a join token issued for the role.
+func TokenIssued(role string) {
+	tokensIssued.WithLabelValues(role).Inc()
+}
+
+// ObserveLiveKit counts a LiveKit API request and, if err is set, its
+// failure.
+func ObserveLiveKit(operation string, err error) {
+	livekitRequests.WithLabelValues(operation).Inc()
+	if err != nil {
+		code := "unknown"
+		var twerr twirp.Error
+		if errors.As(err, &twerr) {
+			code = string(twerr.Code())
+		}
+		livekitErrors.WithLabelValues(operation, code).Inc()
+	}
+}
+
+// roomCollector reports the rooms and participants in the store as of each
+// scrape, so every replica sharing a database reports the same totals.
+type roomCollector struct {
+	store        storage.Store
+	rooms        *prometheus.Desc
+	participants *prometheus.Desc
+}
+
+func (c *roomCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rooms
+	ch <- c.participants
+}
+
+func (c *roomCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countTimeout)
+	defer cancel()
+
+	rooms, participants, err := c.store.CountRooms(ctx)
+	if err != nil {
+		log.Printf("Failed to count rooms for metrics: %v", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.rooms, prometheus.GaugeValue, float64(rooms))
+	ch <- prometheus.MustNewConstMetric(c.participants, prometheus.GaugeValue, float64(participants))
+}
+
+// Handler serves the module's metrics in the Prometheus format, along with
+// the Go runtime and process metrics.
+func Handler(store storage.Store) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		tokensIssued,
+		livekitRequests,
+		livekitErrors,
+		&roomCollector{
+			store:        store,
+			rooms:        prometheus.NewDesc(namespace+"_rooms", "Rooms created through the API that have not been deleted.", nil, nil),
+			participants: prometheus.NewDesc(namespace+"_participants", "Participants connected to rooms.", nil, nil),
+		},
+	)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+	"github.com/twitchtv/twirp"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	store.SaveRoom(ctx, &storage.Room{RoomName: "community_7_lobby", CommunityID: 7})
+	store.AddParticipant(ctx, "community_7_lobby", &storage.Participant{Identity: "1", JoinedAt: time.Now()})
+	store.AddParticipant(ctx, "community_7_lobby", &storage.Participant{Identity: "2", JoinedAt: time.Now()})
+
+	TokenIssued("speaker")
+	ObserveLiveKit("CreateRoom", nil)
+	ObserveLiveKit("CreateRoom", twirp.NewError(twirp.Unavailable, "down"))
+	ObserveLiveKit("SendData", errors.New("dial tcp: connection refused"))
+
+	rec := httptest.NewRecorder()
+	Handler(store).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"waddlebot_rtc_rooms 1",
+		"waddlebot_rtc_participants 2",
+		`waddlebot_rtc_tokens_issued_total{role="speaker"} 1`,
+		`waddlebot_rtc_livekit_requests_total{operation="CreateRoom"} 2`,
+		`waddlebot_rtc_livekit_errors_total{code="unavailable",operation="CreateRoom"} 1`,
+		`waddlebot_rtc_livekit_errors_total{code="unknown",operation="SendData"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+var ErrNoAnalytics = errors.New("no analytics for room")
+
+type ParticipantStats = storage.ParticipantStats
+type RoomStats = storage.RoomStats
+
+// RoomAnalytics is a room's usage and each participant's share of it, with
+// sessions and speaking turns still running counted up to now.
+type RoomAnalytics struct {
+	*RoomStats
+	CurrentParticipants int                 `json:"current_participants"`
+	ParticipantStats    []*ParticipantStats `json:"participant_stats"`
+}
+
+// CommunityUsage sums the usage of a community's rooms active in a period.
+type CommunityUsage struct {
+	CommunityID      int          `json:"community_id"`
+	Since            time.Time    `json:"since"`
+	Rooms            int          `json:"rooms"`
+	Participants     int          `json:"participants"`
+	PeakParticipants int          `json:"peak_participants"`
+	ConnectedSeconds float64      `json:"connected_seconds"`
+	SpeakingSeconds  float64      `json:"speaking_seconds"`
+	HandRaises       int          `json:"hand_raises"`
+	RoomStats        []*RoomStats `json:"room_stats"`
+}
+
+// AnalyticsService tracks how long participants are connected and speak in
+// each room, raised hands, and how many are in a room at once. Participants
+// come from LiveKit webhooks; LiveKit only tells clients who is speaking, so
+// active speakers are reported to it. A nil AnalyticsService tracks nothing.
+type AnalyticsService struct {
+	store storage.Store
+	mu    sync.Mutex
+}
+
+func NewAnalyticsService(store storage.Store) *AnalyticsService {
+	return &AnalyticsService{store: store}
+}
+
+// ParticipantJoined starts a session. A session already running, as from a
+// retried webhook, is left as it is.
+func (s *AnalyticsService) ParticipantJoined(ctx context.Context, roomName, userID, userName string, at time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.participantJoined(ctx, roomName, userID, userName, at); err != nil {
+		log.Printf("Failed to record %s joining %s: %v", userID, roomName, err)
+	}
+}
+
+func (s *AnalyticsService) participantJoined(ctx context.Context, roomName, userID, userName string, at time.Time) error {
+	participant, err := s.participantStats(ctx, roomName, userID, at)
+	if err != nil {
+		return err
+	}
+	if participant.ConnectedSince != nil {
+		return nil
+	}
+	participant.Sessions++
+	participant.ConnectedSince = &at
+	if userName != "" {
+		participant.UserName = userName
+	}
+	if err := s.store.SaveParticipantStats(ctx, participant); err != nil {
+		return err
+	}
+
+	room, err := s.roomStats(ctx, roomName, at)
+	if err != nil {
+		return err
+	}
+	if participant.Sessions == 1 {
+		room.Participants++
+	}
+	participants, err := s.store.ListParticipantStats(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	current := connectedCount(participants)
+	if current > room.PeakParticipants {
+		room.PeakParticipants = current
+		room.PeakAt = &at
+	}
+	room.LastActiveAt = at
+	return s.store.SaveRoomStats(ctx, room)
+}
+
+// ParticipantLeft ends the participant's session and any speaking turn.
+func (s *AnalyticsService) ParticipantLeft(ctx context.Context, roomName, userID string, at time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	participant, err := s.store.GetParticipantStats(ctx, roomName, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return
+	}
+	if err == nil {
+		err = s.endSessions(ctx, roomName, []*ParticipantStats{participant}, at)
+	}
+	if err != nil {
+		log.Printf("Failed to record %s leaving %s: %v", userID, roomName, err)
+	}
+}
+
+// RoomFinished ends every session still running in the room.
+func (s *AnalyticsService) RoomFinished(ctx context.Context, roomName string, at time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	participants, err := s.store.ListParticipantStats(ctx, roomName)
+	if err == nil {
+		err = s.endSessions(ctx, roomName, participants, at)
+	}
+	if err != nil {
+		log.Printf("Failed to record %s finishing: %v", roomName, err)
+	}
+}
+
+func (s *AnalyticsService) endSessions(ctx context.Context, roomName string, participants []*ParticipantStats, at time.Time) error {
+	var connected, speaking float64
+	ended := false
+	for _, p := range participants {
+		if p.ConnectedSince == nil {
+			continue
+		}
+		speaking += stopSpeaking(p, at)
+		seconds := max(at.Sub(*p.ConnectedSince).Seconds(), 0)
+		p.ConnectedSeconds += seconds
+		p.ConnectedSince = nil
+		connected += seconds
+		ended = true
+		if err := s.store.SaveParticipantStats(ctx, p); err != nil {
+			return err
+		}
+	}
+	if !ended {
+		return nil
+	}
+
+	room, err := s.roomStats(ctx, roomName, at)
+	if err != nil {
+		return err
+	}
+	room.ConnectedSeconds += connected
+	room.SpeakingSeconds += speaking
+	room.LastActiveAt = at
+	return s.store.SaveRoomStats(ctx, room)
+}
+
+// ActiveSpeakers sets who in the room is speaking, as LiveKit reports to
+// clients. Reports are the whole set, so a repeated report changes nothing.
+func (s *AnalyticsService) ActiveSpeakers(ctx context.Context, roomName string, speakerIDs []string, at time.Time) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	participants, err := s.store.ListParticipantStats(ctx, roomName)
+	if err != nil {
+		return err
+	}
+
+	var spoken float64
+	changed := false
+	for _, p := range participants {
+		speaking := p.ConnectedSince != nil && slices.Contains(speakerIDs, p.UserID)
+		switch {
+		case speaking && p.SpeakingSince == nil:
+			p.SpeakingSince = &at
+		case !speaking && p.SpeakingSince != nil:
+			spoken += stopSpeaking(p, at)
+		default:
+			continue
+		}
+		changed = true
+		if err := s.store.SaveParticipantStats(ctx, p); err != nil {
+			return err
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	room, err := s.roomStats(ctx, roomName, at)
+	if err != nil {
+		return err
+	}
+	room.SpeakingSeconds += spoken
+	room.LastActiveAt = at
+	return s.store.SaveRoomStats(ctx, room)
+}
+
+// HandRaised counts a raised hand for the participant and the room.
+func (s *AnalyticsService) HandRaised(ctx context.Context, roomName, userID, userName string, at time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.handRaised(ctx, roomName, userID, userName, at); err != nil {
+		log.Printf("Failed to record hand raised by %s in %s: %v", userID, roomName, err)
+	}
+}
+
+func (s *AnalyticsService) handRaised(ctx context.Context, roomName, userID, userName string, at time.Time) error {
+	participant, err := s.participantStats(ctx, roomName, userID, at)
+	if err != nil {
+		return err
+	}
+	participant.HandRaises++
+	if participant.UserName == "" {
+		participant.UserName = userName
+	}
+	if err := s.store.SaveParticipantStats(ctx, participant); err != nil {
+		return err
+	}
+
+	room, err := s.roomStats(ctx, roomName, at)
+	if err != nil {
+		return err
+	}
+	room.HandRaises++
+	room.LastActiveAt = at
+	return s.store.SaveRoomStats(ctx, room)
+}
+
+// RoomAnalytics returns the room's usage, participants by speaking time.
+func (s *AnalyticsService) RoomAnalytics(ctx context.Context, roomName string, now time.Time) (*RoomAnalytics, error) {
+	room, err := s.store.GetRoomStats(ctx, roomName)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNoAnalytics
+	}
+	if err != nil {
+		return nil, err
+	}
+	participants, err := s.store.ListParticipantStats(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count what is still running without ending it
+	for _, p := range participants {
+		if p.SpeakingSince != nil {
+			seconds := max(now.Sub(*p.SpeakingSince).Seconds(), 0)
+			p.SpeakingSeconds += seconds
+			room.SpeakingSeconds += seconds
+		}
+		if p.ConnectedSince != nil {
+			seconds := max(now.Sub(*p.ConnectedSince).Seconds(), 0)
+			p.ConnectedSeconds += seconds
+			room.ConnectedSeconds += seconds
+		}
+	}
+	sort.SliceStable(participants, func(i, j int) bool {
+		return participants[i].SpeakingSeconds > participants[j].SpeakingSeconds
+	})
+
+	return &RoomAnalytics{
+		RoomStats:           room,
+		CurrentParticipants: connectedCount(participants),
+		ParticipantStats:    participants,
+	}, nil
+}
+
+// CommunityUsage sums the usage of the community's rooms active since the
+// given time. Participants counts each room's participants, so someone in
+// two rooms counts twice.
+func (s *AnalyticsService) CommunityUsage(ctx context.Context, communityID int, since time.Time) (*CommunityUsage, error) {
+	rooms, err := s.store.ListRoomStats(ctx, communityID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &CommunityUsage{
+		CommunityID: communityID,
+		Since:       since,
+		Rooms:       len(rooms),
+		RoomStats:   rooms,
+	}
+	for _, room := range rooms {
+		usage.Participants += room.Participants
+		usage.PeakParticipants = max(usage.PeakParticipants, room.PeakParticipants)
+		usage.ConnectedSeconds += room.ConnectedSeconds
+		usage.SpeakingSeconds += room.SpeakingSeconds
+		usage.HandRaises += room.HandRaises
+	}
+	return usage, nil
+}
+
+func (s *AnalyticsService) participantStats(ctx context.Context, roomName, userID string, at time.Time) (*ParticipantStats, error) {
+	participant, err := s.store.GetParticipantStats(ctx, roomName, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &ParticipantStats{RoomName: roomName, UserID: userID, FirstJoinedAt: at}, nil
+	}
+	return participant, err
+}
+
+func (s *AnalyticsService) roomStats(ctx context.Context, roomName string, at time.Time) (*RoomStats, error) {
+	room, err := s.store.GetRoomStats(ctx, roomName)
+	if !errors.Is(err, storage.ErrNotFound) {
+		return room, err
+	}
+
+	communityID, err := lookupCommunityID(ctx, s.store, roomName)
+	if err != nil {
+		return nil, err
+	}
+	return &RoomStats{RoomName: roomName, CommunityID: communityID, FirstActiveAt: at, LastActiveAt: at}, nil
+}
+
+// stopSpeaking ends the participant's speaking turn, returning its length.
+func stopSpeaking(p *ParticipantStats, at time.Time) float64 {
+	if p.SpeakingSince == nil {
+		return 0
+	}
+	seconds := max(at.Sub(*p.SpeakingSince).Seconds(), 0)
+	p.SpeakingSeconds += seconds
+	p.SpeakingSince = nil
+	return seconds
+}
+
+func connectedCount(participants []*ParticipantStats) int {
+	count := 0
+	for _, p := range participants {
+		if p.ConnectedSince != nil {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestAnalyticsService(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	s := NewAnalyticsService(store)
+	const room = "community_7_stage"
+	start := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	if _, err := s.RoomAnalytics(ctx, room, start); !errors.Is(err, ErrNoAnalytics) {
+		t.Errorf("Expected no analytics before anyone joined, got %v", err)
+	}
+
+	s.ParticipantJoined(ctx, room, "u1", "One", at(0))
+	s.ParticipantJoined(ctx, room, "u2", "Two", at(10))
+	s.ParticipantJoined(ctx, room, "u2", "Two", at(10)) // retried webhook
+	s.ActiveSpeakers(ctx, room, []string{"u1", "ghost"}, at(20))
+	s.ActiveSpeakers(ctx, room, []string{"u1"}, at(25))
+	s.ActiveSpeakers(ctx, room, []string{"u2"}, at(50))
+	s.HandRaised(ctx, room, "u2", "Two", at(55))
+	s.ParticipantLeft(ctx, room, "u1", at(60))
+
+	// u2 is still connected and speaking
+	analytics, err := s.RoomAnalytics(ctx, room, at(70))
+	if err != nil {
+		t.Fatalf("Failed to get analytics: %v", err)
+	}
+	if analytics.PeakParticipants != 2 || analytics.CurrentParticipants != 1 || analytics.HandRaises != 1 || analytics.Participants != 2 {
+		t.Errorf("Unexpected room stats: %+v", analytics.RoomStats)
+	}
+	u1, u2 := analytics.ParticipantStats[0], analytics.ParticipantStats[1]
+	if u1.UserID != "u1" || u1.SpeakingSeconds != 30 || u1.ConnectedSeconds != 60 {
+		t.Errorf("Expected u1 to have spoken 30s of 60s, got %+v", u1)
+	}
+	if u2.SpeakingSeconds != 20 || u2.ConnectedSeconds != 60 || u2.HandRaises != 1 {
+		t.Errorf("Expected u2's running turn and session counted, got %+v", u2)
+	}
+	if analytics.SpeakingSeconds != 50 || analytics.ConnectedSeconds != 120 {
+		t.Errorf("Expected the room totals to include running time, got %+v", analytics.RoomStats)
+	}
+
+	// Rejoining is a new session for the same participant
+	s.ParticipantJoined(ctx, room, "u1", "One", at(80))
+	s.RoomFinished(ctx, room, at(100))
+	analytics, _ = s.RoomAnalytics(ctx, room, at(200))
+	if analytics.CurrentParticipants != 0 || analytics.Participants != 2 || analytics.ConnectedSeconds != 80+90 || analytics.SpeakingSeconds != 30+50 {
+		t.Errorf("Expected every session ended with the room, got %+v", analytics.RoomStats)
+	}
+
+	s.ParticipantJoined(ctx, "community_7_other", "u3", "Three", at(300))
+	s.ParticipantJoined(ctx, "community_8_elsewhere", "u4", "Four", at(300))
+	usage, err := s.CommunityUsage(ctx, 7, start)
+	if err != nil || usage.Rooms != 2 || usage.Participants != 3 || usage.PeakParticipants != 2 || usage.HandRaises != 1 {
+		t.Errorf("Unexpected community usage: %+v, %v", usage, err)
+	}
+	if usage, _ := s.CommunityUsage(ctx, 7, at(200)); usage.Rooms != 1 {
+		t.Errorf("Expected only the room active since then, got %+v", usage)
+	}
+}
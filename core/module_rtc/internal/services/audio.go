@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+type AudioSettings = storage.AudioSettings
+
+// How clients suppress background noise in the audio they publish. Krisp
+// needs LiveKit Cloud, and browser leaves it to the browser's own filter.
+const (
+	NoiseSuppressionKrisp   = "krisp"
+	NoiseSuppressionRNNoise = "rnnoise"
+	NoiseSuppressionBrowser = "browser"
+	NoiseSuppressionOff     = "off"
+)
+
+var ErrInvalidAudioSettings = errors.New("noise_suppression must be krisp, rnnoise, browser or off")
+
+// DefaultAudioSettings are the audio settings of rooms no moderator has
+// changed them in, which are what LiveKit's clients do by default.
+func DefaultAudioSettings() AudioSettings {
+	return AudioSettings{
+		NoiseSuppression: NoiseSuppressionBrowser,
+		EchoCancellation: true,
+		AutoGainControl:  true,
+		RED:              true,
+		DTX:              true,
+	}
+}
+
+func audioOrDefault(audio AudioSettings) AudioSettings {
+	if audio.NoiseSuppression == "" {
+		return DefaultAudioSettings()
+	}
+	return audio
+}
+
+// AudioUpdate changes some of a room's audio settings, leaving those it
+// does not set.
+type AudioUpdate struct {
+	NoiseSuppression *string `json:"noise_suppression,omitempty"`
+	EchoCancellation *bool   `json:"echo_cancellation,omitempty"`
+	AutoGainControl  *bool   `json:"auto_gain_control,omitempty"`
+	RED              *bool   `json:"red,omitempty"`
+	DTX              *bool   `json:"dtx,omitempty"`
+}
+
+func (u *AudioUpdate) apply(audio *AudioSettings) {
+	if u.NoiseSuppression != nil {
+		audio.NoiseSuppression = *u.NoiseSuppression
+	}
+	if u.EchoCancellation != nil {
+		audio.EchoCancellation = *u.EchoCancellation
+	}
+	if u.AutoGainControl != nil {
+		audio.AutoGainControl = *u.AutoGainControl
+	}
+	if u.RED != nil {
+		audio.RED = *u.RED
+	}
+	if u.DTX != nil {
+		audio.DTX = *u.DTX
+	}
+}
+
+// ValidNoiseSuppression reports whether clients know the noise suppression
+// mode.
+func ValidNoiseSuppression(mode string) bool {
+	switch mode {
+	case NoiseSuppressionKrisp, NoiseSuppressionRNNoise, NoiseSuppressionBrowser, NoiseSuppressionOff:
+		return true
+	}
+	return false
+}
+
+// AudioSettings returns how clients in the room should process their audio.
+func (s *RoomService) AudioSettings(ctx context.Context, roomName string) (AudioSettings, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return AudioSettings{}, err
+	}
+	return audioOrDefault(state.Audio), nil
+}
+
+// SetAudioSettings changes how clients in the room should process their
+// audio, returning the room's settings after the change. Clients already in
+// the room learn of it from the room's metadata.
+func (s *CallFeaturesService) SetAudioSettings(ctx context.Context, roomName string, update *AudioUpdate, moderatorID string) (AudioSettings, error) {
+	if update.NoiseSuppression != nil && !ValidNoiseSuppression(*update.NoiseSuppression) {
+		return AudioSettings{}, ErrInvalidAudioSettings
+	}
+
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return AudioSettings{}, err
+	}
+	audio := audioOrDefault(state.Audio)
+	update.apply(&audio)
+	state.Audio = audio
+	state.UpdatedAt = time.Now()
+	if err := s.store.SaveRoomState(ctx, state); err != nil {
+		return AudioSettings{}, err
+	}
+
+	s.roomService.PublishRoomConfig(ctx, roomName, ConfigAudio, moderatorID)
+	return audio, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestCallFeaturesService_SetAudioSettings(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	rooms := NewRoomService(url, "key", "secret", store, events)
+	s := NewCallFeaturesService(rooms, store, nil, events)
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+
+	token, err := rooms.JoinRoom(ctx, "room", "u1", "One", RoleSpeaker)
+	if err != nil {
+		t.Fatalf("Failed to join room: %v", err)
+	}
+	if token.Audio != DefaultAudioSettings() {
+		t.Errorf("Expected the default audio settings, got %+v", token.Audio)
+	}
+
+	lk.rooms["room"] = &livekit.Room{Sid: "RM_room", Name: "room", Metadata: `{"codecs":["audio/opus","video/VP8"]}`}
+	off, krisp := false, NoiseSuppressionKrisp
+	audio, err := s.SetAudioSettings(ctx, "room", &AudioUpdate{NoiseSuppression: &krisp, DTX: &off}, "mod")
+	if err != nil {
+		t.Fatalf("Failed to set audio settings: %v", err)
+	}
+	want := AudioSettings{NoiseSuppression: NoiseSuppressionKrisp, EchoCancellation: true, AutoGainControl: true, RED: true}
+	if audio != want {
+		t.Errorf("Expected only the settings given changed, got %+v", audio)
+	}
+
+	var metadata roomMetadata
+	json.Unmarshal([]byte(lk.rooms["room"].Metadata), &metadata)
+	if metadata.Config == nil || metadata.Config.Audio != want || len(metadata.Codecs) != 2 {
+		t.Errorf("Expected the audio settings in the room's metadata, got %s", lk.rooms["room"].Metadata)
+	}
+	if event := <-roomEvents; event.Type != EventRoomConfigChanged || event.Data["changed"] != ConfigAudio || event.Data["noise_suppression"] != NoiseSuppressionKrisp || event.Data["dtx"] != "false" {
+		t.Errorf("Expected a room_config_changed event for the audio, got %+v", event)
+	}
+
+	// Tokens, including for the lobby, carry the room's settings
+	if token, _ := rooms.JoinRoom(ctx, "room", "u2", "Two", RoleViewer); token.Audio != want {
+		t.Errorf("Expected the room's audio settings with the token, got %+v", token.Audio)
+	}
+	if token, _ := rooms.JoinLobby(ctx, "room", "u3", "Three"); token.Audio != want {
+		t.Errorf("Expected the room's audio settings with a lobby token, got %+v", token.Audio)
+	}
+
+	loud := "loud"
+	if _, err := s.SetAudioSettings(ctx, "room", &AudioUpdate{NoiseSuppression: &loud}, "mod"); !errors.Is(err, ErrInvalidAudioSettings) {
+		t.Errorf("Expected an unknown noise suppression refused, got %v", err)
+	}
+	if audio, _ := rooms.AudioSettings(ctx, "room"); audio != want {
+		t.Errorf("Expected a refused change to leave the settings, got %+v", audio)
+	}
+}
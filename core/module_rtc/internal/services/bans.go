@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+const maxBanReasonSize = 500
+
+var (
+	ErrBanned      = errors.New("banned from this room")
+	ErrBanNotFound = errors.New("ban not found")
+	ErrInvalidBan  = errors.New("invalid ban")
+)
+
+type Ban = storage.Ban
+
+// BanError is returned when a banned user tries to join the room.
+type BanError struct {
+	Ban *Ban
+}
+
+func (e *BanError) Error() string {
+	if e.Ban.ExpiresAt == nil {
+		return ErrBanned.Error()
+	}
+	return fmt.Sprintf("%v until %s", ErrBanned, e.Ban.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+func (e *BanError) Unwrap() error {
+	return ErrBanned
+}
+
+func banActive(ban *Ban, now time.Time) bool {
+	return ban.ExpiresAt == nil || now.Before(*ban.ExpiresAt)
+}
+
+// activeBan returns the user's ban from the room if it is still in force,
+// or nil.
+func activeBan(ctx context.Context, store storage.Store, roomName, userID string) (*Ban, error) {
+	ban, err := store.GetBan(ctx, roomName, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !banActive(ban, time.Now()) {
+		return nil, nil
+	}
+	return ban, nil
+}
+
+// CheckBan returns a BanError if the user is banned from the room.
+func (s *CallFeaturesService) CheckBan(ctx context.Context, roomName, userID string) error {
+	ban, err := activeBan(ctx, s.store, roomName, userID)
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return &BanError{Ban: ban}
+	}
+	return nil
+}
+
+// BanParticipant removes the user from the room if they are in it and keeps
+// them out for duration, or until lifted if zero. Banning them again
+// replaces the ban.
+func (s *CallFeaturesService) BanParticipant(ctx context.Context, roomName, userID, reason string, duration time.Duration, moderatorID string) (*Ban, error) {
+	reason = strings.TrimSpace(reason)
+	switch {
+	case duration < 0:
+		return nil, fmt.Errorf("%w: duration cannot be negative", ErrInvalidBan)
+	case len([]rune(reason)) > maxBanReasonSize:
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidBan, maxBanReasonSize)
+	}
+
+	now := time.Now()
+	ban := &Ban{
+		RoomName:  roomName,
+		UserID:    userID,
+		Reason:    reason,
+		BannedBy:  moderatorID,
+		CreatedAt: now,
+	}
+	if duration > 0 {
+		expiresAt := now.Add(duration)
+		ban.ExpiresAt = &expiresAt
+	}
+	// Saved first, so they cannot slip back in between the kick and the ban
+	if err := s.store.SaveBan(ctx, ban); err != nil {
+		return nil, err
+	}
+
+	s.LowerHand(ctx, roomName, userID)
+	if err := s.roomService.KickParticipant(ctx, roomName, userID); err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	data := map[string]string{"reason": ban.Reason}
+	if ban.ExpiresAt != nil {
+		data["expires_at"] = ban.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	s.events.Publish(RoomEvent{Type: EventParticipantBanned, RoomName: roomName, UserID: userID, ActorID: moderatorID, Data: data})
+	log.Printf("%s banned from %s by %s", userID, roomName, moderatorID)
+	return ban, nil
+}
+
+// ListBans returns the bans in force in the room, newest first.
+func (s *CallFeaturesService) ListBans(ctx context.Context, roomName string) ([]*Ban, error) {
+	bans, err := s.store.ListBans(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]*Ban, 0, len(bans))
+	for _, ban := range bans {
+		if banActive(ban, now) {
+			active = append(active, ban)
+		}
+	}
+	return active, nil
+}
+
+// LiftBan lets the user join the room again.
+func (s *CallFeaturesService) LiftBan(ctx context.Context, roomName, userID, moderatorID string) error {
+	ban, err := activeBan(ctx, s.store, roomName, userID)
+	if err != nil {
+		return err
+	}
+	if ban == nil {
+		return ErrBanNotFound
+	}
+	if err := s.store.DeleteBan(ctx, roomName, userID); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventBanLifted, RoomName: roomName, UserID: userID, ActorID: moderatorID})
+	return nil
+}
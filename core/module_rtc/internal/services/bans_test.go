@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestCallFeaturesService_Bans(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+	roomService := NewRoomService(url, "key", "secret", store, events)
+	s := NewCallFeaturesService(roomService, store, nil, events)
+
+	lk.participants["troll"] = &livekit.ParticipantInfo{Identity: "troll"}
+	s.RaiseHand(ctx, "room", "troll", "Troll")
+	<-roomEvents
+
+	if _, err := s.BanParticipant(ctx, "room", "troll", "", -time.Minute, "mod"); !errors.Is(err, ErrInvalidBan) {
+		t.Errorf("Expected a negative duration to be refused, got %v", err)
+	}
+
+	ban, err := s.BanParticipant(ctx, "room", "troll", "  spamming  ", time.Hour, "mod")
+	if err != nil {
+		t.Fatalf("Failed to ban: %v", err)
+	}
+	if ban.Reason != "spamming" || ban.BannedBy != "mod" || ban.ExpiresAt == nil || ban.ExpiresAt.Sub(ban.CreatedAt) != time.Hour {
+		t.Errorf("Expected an hour's ban, got %+v", ban)
+	}
+	if _, ok := lk.participants["troll"]; ok {
+		t.Error("Expected the banned user to be removed from the room")
+	}
+	if hands, _ := s.GetRaisedHands(ctx, "room"); len(hands) != 0 {
+		t.Errorf("Expected the banned user's hand to be lowered, got %+v", hands)
+	}
+	<-roomEvents // hand_lowered
+	if event := <-roomEvents; event.Type != EventParticipantBanned || event.UserID != "troll" || event.Data["reason"] != "spamming" || event.Data["expires_at"] == "" {
+		t.Errorf("Expected a participant_banned event, got %+v", event)
+	}
+
+	var banErr *BanError
+	if err := s.CheckBan(ctx, "room", "troll"); !errors.As(err, &banErr) || !errors.Is(err, ErrBanned) || banErr.Ban.Reason != "spamming" {
+		t.Errorf("Expected the user to be banned, got %v", err)
+	}
+	if err := s.CheckBan(ctx, "other", "troll"); err != nil {
+		t.Errorf("Expected the ban to apply to its room only, got %v", err)
+	}
+
+	// Users banned while away are banned all the same
+	if _, err := s.BanParticipant(ctx, "room", "absent", "", 0, "mod"); err != nil {
+		t.Fatalf("Failed to ban an absent user: %v", err)
+	}
+	<-roomEvents
+	if err := s.CheckBan(ctx, "room", "absent"); err == nil || err.Error() != "banned from this room" {
+		t.Errorf("Expected a ban until lifted, got %v", err)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	store.SaveBan(ctx, &storage.Ban{RoomName: "room", UserID: "forgiven", CreatedAt: expired.Add(-time.Hour), ExpiresAt: &expired})
+	if err := s.CheckBan(ctx, "room", "forgiven"); err != nil {
+		t.Errorf("Expected an expired ban to be ignored, got %v", err)
+	}
+	bans, err := s.ListBans(ctx, "room")
+	if err != nil || len(bans) != 2 || bans[0].UserID != "absent" || bans[1].UserID != "troll" {
+		t.Errorf("Expected the bans in force, newest first, got %+v, %v", bans, err)
+	}
+
+	if err := s.LiftBan(ctx, "room", "forgiven", "mod"); !errors.Is(err, ErrBanNotFound) {
+		t.Errorf("Expected lifting an expired ban to find none, got %v", err)
+	}
+	if err := s.LiftBan(ctx, "room", "troll", "mod"); err != nil {
+		t.Fatalf("Failed to lift ban: %v", err)
+	}
+	if event := <-roomEvents; event.Type != EventBanLifted || event.UserID != "troll" || event.ActorID != "mod" {
+		t.Errorf("Expected a ban_lifted event, got %+v", event)
+	}
+	if err := s.CheckBan(ctx, "room", "troll"); err != nil {
+		t.Errorf("Expected the lifted ban to let the user back in, got %v", err)
+	}
+}
+
+func TestWebhookService_RemovesBannedParticipants(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	rooms := NewRoomService(url, "key", "secret", store, nil)
+	s := NewWebhookService("key", "secret", store, nil, rooms, nil, nil, nil, nil, nil)
+
+	store.SaveBan(ctx, &storage.Ban{RoomName: "community_42_lobby", UserID: "troll", CreatedAt: time.Now()})
+	// Joining with a token handed out before the ban
+	lk.participants["troll"] = &livekit.ParticipantInfo{Identity: "troll"}
+
+	event, err := s.Receive(signedWebhook(t, "secret", `{"event":"participant_joined","room":{"name":"community_42_lobby"},"participant":{"identity":"troll","name":"Troll"}}`))
+	if err != nil {
+		t.Fatalf("Failed to receive webhook: %v", err)
+	}
+	if err := s.HandleEvent(ctx, event); err != nil {
+		t.Fatalf("Failed to handle webhook: %v", err)
+	}
+	if _, ok := lk.participants["troll"]; ok {
+		t.Error("Expected the banned user to be removed on joining")
+	}
+	if participants, _ := store.ListParticipants(ctx, "community_42_lobby"); len(participants) != 0 {
+		t.Errorf("Expected the banned user not to be recorded, got %+v", participants)
+	}
+}
+
+func TestInviteService_SignedInRedemption(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	rooms := NewRoomService("http://localhost:7880", "key", "secret", store, nil)
+	features := NewCallFeaturesService(rooms, store, nil, nil)
+	s := NewInviteService(rooms, features, store, "invite-secret", "", nil)
+	store.SaveRoom(ctx, &storage.Room{RoomName: "community_7_stage", CommunityID: 7})
+	store.SetParticipantRole(ctx, "community_7_stage", &storage.RoleAssignment{UserID: "mod", Role: RoleModerator})
+	store.SaveBan(ctx, &storage.Ban{RoomName: "community_7_stage", UserID: "troll", CreatedAt: time.Now()})
+
+	invite, err := s.CreateInvite(ctx, "community_7_stage", RoleSpeaker, 0, "host")
+	if err != nil {
+		t.Fatalf("Failed to create invite: %v", err)
+	}
+	if _, err := s.RedeemInvite(ctx, invite.Token, "", "troll"); !errors.Is(err, ErrBanned) {
+		t.Errorf("Expected a banned user to be refused, got %v", err)
+	}
+
+	// The refused redemption left the invite unused
+	token, err := s.RedeemInvite(ctx, invite.Token, "Mod", "mod")
+	if err != nil {
+		t.Fatalf("Failed to redeem invite: %v", err)
+	}
+	if token.Identity != "mod" {
+		t.Errorf("Expected a signed in user to join as themselves, got %s", token.Identity)
+	}
+	if role, _ := features.AssignedRole(ctx, "community_7_stage", "mod"); role != RoleModerator {
+		t.Errorf("Expected the user to keep their higher role, got %s", role)
+	}
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+var (
+	ErrInvalidBreakout  = errors.New("invalid breakout request")
+	ErrBreakoutsExist   = errors.New("room already has breakout rooms")
+	ErrNoBreakouts      = errors.New("room has no breakout rooms")
+	ErrBreakoutNotFound = errors.New("breakout room not found")
+)
+
+// BreakoutNoticeTopic is the data topic participants are sent their
+// breakout room and breakout messages on.
+const BreakoutNoticeTopic = "waddlebot.breakout"
+
+const maxBreakoutRooms = 50
+
+type BreakoutRoom = storage.BreakoutRoom
+type BreakoutAssignment = storage.BreakoutAssignment
+
+// Breakouts are a room's breakout rooms and who was sent to each.
+type Breakouts struct {
+	ParentRoom  string                `json:"parent_room"`
+	Rooms       []*BreakoutRoom       `json:"rooms"`
+	Assignments []*BreakoutAssignment `json:"assignments"`
+}
+
+// BreakoutMove is a participant sent to a breakout room, or back to the
+// parent room. Notified is false if they were not in a call to be told.
+type BreakoutMove struct {
+	UserID   string `json:"user_id"`
+	RoomName string `json:"room_name"`
+	Notified bool   `json:"notified"`
+}
+
+// BreakoutService splits rooms into breakout rooms. LiveKit cannot move a
+// participant between rooms, so each is sent a token for their new room on
+// BreakoutNoticeTopic and their client reconnects with it.
+type BreakoutService struct {
+	roomService *RoomService
+	store       storage.Store
+	events      *EventBus
+}
+
+func NewBreakoutService(roomService *RoomService, store storage.Store, events *EventBus) *BreakoutService {
+	return &BreakoutService{
+		roomService: roomService,
+		store:       store,
+		events:      events,
+	}
+}
+
+// CreateBreakouts creates count breakout rooms for the parent room, named by
+// names when given.
+func (s *BreakoutService) CreateBreakouts(ctx context.Context, parentRoom string, count int, names []string, createdBy string) ([]*BreakoutRoom, error) {
+	if len(names) > 0 {
+		count = len(names)
+	}
+	if count < 1 || count > maxBreakoutRooms {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidBreakout, maxBreakoutRooms)
+	}
+
+	existing, err := s.store.ListBreakoutRooms(ctx, parentRoom)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, ErrBreakoutsExist
+	}
+
+	communityID, err := lookupCommunityID(ctx, s.store, parentRoom)
+	if err != nil {
+		return nil, err
+	}
+	maxParticipants := uint32(DefaultMaxParticipants)
+	if parent, err := s.store.GetRoom(ctx, parentRoom); err == nil && parent.MaxParticipants > 0 {
+		maxParticipants = parent.MaxParticipants
+	}
+
+	rooms := make([]*BreakoutRoom, 0, count)
+	for i := 1; i <= count; i++ {
+		name := "Breakout " + strconv.Itoa(i)
+		if len(names) > 0 && names[i-1] != "" {
+			name = names[i-1]
+		}
+
+		// Breakouts take no template, so nobody sent there waits in a lobby
+		info, err := s.roomService.createRoom(ctx, communityID, fmt.Sprintf("%s_breakout_%d", parentRoom, i), maxParticipants, nil)
+		if err != nil {
+			return nil, err
+		}
+		room := &BreakoutRoom{
+			RoomName:   info.RoomName,
+			ParentRoom: parentRoom,
+			Name:       name,
+			Position:   i,
+			CreatedBy:  createdBy,
+			CreatedAt:  info.CreatedAt,
+		}
+		if err := s.store.SaveBreakoutRoom(ctx, room); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+
+	s.events.Publish(RoomEvent{
+		Type:     EventBreakoutsCreated,
+		RoomName: parentRoom,
+		ActorID:  createdBy,
+		Data:     map[string]string{"count": strconv.Itoa(count)},
+	})
+	return rooms, nil
+}
+
+func (s *BreakoutService) GetBreakouts(ctx context.Context, parentRoom string) (*Breakouts, error) {
+	rooms, err := s.store.ListBreakoutRooms(ctx, parentRoom)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.store.ListBreakoutAssignments(ctx, parentRoom)
+	if err != nil {
+		return nil, err
+	}
+	return &Breakouts{ParentRoom: parentRoom, Rooms: rooms, Assignments: assignments}, nil
+}
+
+// AssignParticipants sends each user to the breakout room they are mapped
+// to, or back to the parent room if mapped to it.
+func (s *BreakoutService) AssignParticipants(ctx context.Context, parentRoom string, assignments map[string]string, assignedBy string) ([]*BreakoutMove, error) {
+	rooms, current, err := s.breakouts(ctx, parentRoom)
+	if err != nil {
+		return nil, err
+	}
+	for userID, roomName := range assignments {
+		if userID == "" || (roomName != parentRoom && rooms[roomName] == nil) {
+			return nil, ErrBreakoutNotFound
+		}
+	}
+
+	moves := make([]*BreakoutMove, 0, len(assignments))
+	for userID, roomName := range assignments {
+		move, err := s.move(ctx, parentRoom, rooms[roomName], current[userID], userID, assignedBy)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+// AutoAssign spreads the users, or everyone in the parent room who is not
+// yet in a breakout room apart from the moderator, evenly across the
+// breakout rooms.
+func (s *BreakoutService) AutoAssign(ctx context.Context, parentRoom string, userIDs []string, assignedBy string) ([]*BreakoutMove, error) {
+	rooms, current, err := s.breakouts(ctx, parentRoom)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(userIDs) == 0 {
+		participants, err := s.store.ListParticipants(ctx, parentRoom)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range participants {
+			if current[p.Identity] == "" && p.Identity != assignedBy {
+				userIDs = append(userIDs, p.Identity)
+			}
+		}
+	}
+
+	ordered, err := s.store.ListBreakoutRooms(ctx, parentRoom)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int, len(ordered))
+	for userID, roomName := range current {
+		if !slices.Contains(userIDs, userID) {
+			sizes[roomName]++
+		}
+	}
+
+	moves := make([]*BreakoutMove, 0, len(userIDs))
+	for _, userID := range userIDs {
+		smallest := ordered[0]
+		for _, room := range ordered[1:] {
+			if sizes[room.RoomName] < sizes[smallest.RoomName] {
+				smallest = room
+			}
+		}
+		sizes[smallest.RoomName]++
+
+		move, err := s.move(ctx, parentRoom, rooms[smallest.RoomName], current[userID], userID, assignedBy)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+// BroadcastMessage sends a message to everyone in every breakout room,
+// returning how many rooms it was sent to.
+func (s *BreakoutService) BroadcastMessage(ctx context.Context, parentRoom, message, sentBy string) (int, error) {
+	if message == "" {
+		return 0, fmt.Errorf("%w: message is required", ErrInvalidBreakout)
+	}
+	rooms, err := s.store.ListBreakoutRooms(ctx, parentRoom)
+	if err != nil {
+		return 0, err
+	}
+	if len(rooms) == 0 {
+		return 0, ErrNoBreakouts
+	}
+
+	sent := 0
+	for _, room := range rooms {
+		err := s.roomService.NotifyRoom(ctx, room.RoomName, BreakoutNoticeTopic, map[string]string{
+			"type":        EventBreakoutMessage,
+			"parent_room": parentRoom,
+			"message":     message,
+			"sent_by":     sentBy,
+		})
+		if err != nil && !isNotFound(err) {
+			log.Printf("Failed to send breakout message to %s: %v", room.RoomName, err)
+			continue
+		}
+		s.events.Publish(RoomEvent{
+			Type:     EventBreakoutMessage,
+			RoomName: room.RoomName,
+			ActorID:  sentBy,
+			Data:     map[string]string{"message": message, "parent_room": parentRoom},
+		})
+		sent++
+	}
+	return sent, nil
+}
+
+// ReturnAll sends everyone in a breakout room back to the parent room,
+// leaving the breakout rooms open for another round.
+func (s *BreakoutService) ReturnAll(ctx context.Context, parentRoom, returnedBy string) ([]*BreakoutMove, error) {
+	rooms, current, err := s.breakouts(ctx, parentRoom)
+	if err != nil {
+		return nil, err
+	}
+
+	moves := make([]*BreakoutMove, 0, len(current))
+	for userID, roomName := range current {
+		if rooms[roomName] == nil {
+			continue
+		}
+		move, err := s.move(ctx, parentRoom, nil, roomName, userID, returnedBy)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+	}
+
+	s.events.Publish(RoomEvent{Type: EventBreakoutsReturned, RoomName: parentRoom, ActorID: returnedBy})
+	return moves, nil
+}
+
+// CloseBreakouts returns everyone to the parent room and deletes the
+// breakout rooms.
+func (s *BreakoutService) CloseBreakouts(ctx context.Context, parentRoom, closedBy string) error {
+	if _, err := s.ReturnAll(ctx, parentRoom, closedBy); err != nil {
+		return err
+	}
+
+	rooms, err := s.store.ListBreakoutRooms(ctx, parentRoom)
+	if err != nil {
+		return err
+	}
+	for _, room := range rooms {
+		if err := s.roomService.DeleteRoom(ctx, room.RoomName); err != nil && !isNotFound(err) {
+			log.Printf("Failed to delete breakout room %s: %v", room.RoomName, err)
+		}
+	}
+	if err := s.store.DeleteBreakoutRooms(ctx, parentRoom); err != nil {
+		return err
+	}
+
+	s.events.Publish(RoomEvent{Type: EventBreakoutsClosed, RoomName: parentRoom, ActorID: closedBy})
+	return nil
+}
+
+// breakouts returns the parent room's breakout rooms by name and the room
+// each assigned user is in, refusing rooms without breakouts.
+func (s *BreakoutService) breakouts(ctx context.Context, parentRoom string) (map[string]*BreakoutRoom, map[string]string, error) {
+	list, err := s.store.ListBreakoutRooms(ctx, parentRoom)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil, ErrNoBreakouts
+	}
+	rooms := make(map[string]*BreakoutRoom, len(list))
+	for _, room := range list {
+		rooms[room.RoomName] = room
+	}
+
+	assignments, err := s.store.ListBreakoutAssignments(ctx, parentRoom)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := make(map[string]string, len(assignments))
+	for _, a := range assignments {
+		current[a.UserID] = a.RoomName
+	}
+	return rooms, current, nil
+}
+
+// move sends the user a token for the breakout room, or for the parent room
+// when to is nil, in the room they are in now.
+func (s *BreakoutService) move(ctx context.Context, parentRoom string, to *BreakoutRoom, from, userID, actorID string) (*BreakoutMove, error) {
+	if from == "" {
+		from = parentRoom
+	}
+	roomName, noticeType, name := parentRoom, EventBreakoutReturned, ""
+	if to != nil {
+		roomName, noticeType, name = to.RoomName, EventBreakoutAssigned, to.Name
+	}
+
+	role, err := s.breakoutRole(ctx, parentRoom, userID, to != nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.roomService.JoinRoom(ctx, roomName, userID, s.participantName(ctx, from, userID), role)
+	if err != nil {
+		return nil, err
+	}
+
+	notified := false
+	if from != roomName {
+		notified, err = s.roomService.NotifyParticipant(ctx, from, userID, BreakoutNoticeTopic, map[string]string{
+			"type":        noticeType,
+			"parent_room": parentRoom,
+			"room_name":   roomName,
+			"name":        name,
+			"token":       token.Token,
+			"moved_by":    actorID,
+		})
+		if err != nil {
+			log.Printf("Failed to send %s to %s: %v", userID, roomName, err)
+		}
+	}
+
+	if to != nil {
+		err = s.store.SetBreakoutAssignment(ctx, parentRoom, &BreakoutAssignment{
+			UserID:     userID,
+			RoomName:   roomName,
+			AssignedBy: actorID,
+			AssignedAt: time.Now(),
+		})
+	} else {
+		err = s.store.DeleteBreakoutAssignment(ctx, parentRoom, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(RoomEvent{
+		Type:     noticeType,
+		RoomName: parentRoom,
+		UserID:   userID,
+		ActorID:  actorID,
+		Data:     map[string]string{"room_name": roomName},
+	})
+	return &BreakoutMove{UserID: userID, RoomName: roomName, Notified: notified}, nil
+}
+
+// breakoutRole is the user's role in the parent room, raised to speaker in
+// breakout rooms so everyone can talk.
+func (s *BreakoutService) breakoutRole(ctx context.Context, parentRoom, userID string, inBreakout bool) (string, error) {
+	role := RoleViewer
+	assignment, err := s.store.GetParticipantRole(ctx, parentRoom, userID)
+	if err == nil {
+		role = assignment.Role
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+	if inBreakout && RoleRank(role) < RoleRank(RoleSpeaker) {
+		role = RoleSpeaker
+	}
+	return role, nil
+}
+
+// participantName is the user's display name in the room, or their ID if
+// the room's webhooks have not reported them.
+func (s *BreakoutService) participantName(ctx context.Context, roomName, userID string) string {
+	participants, err := s.store.ListParticipants(ctx, roomName)
+	if err != nil {
+		return userID
+	}
+	for _, p := range participants {
+		if p.Identity == userID && p.Name != "" {
+			return p.Name
+		}
+	}
+	return userID
+}
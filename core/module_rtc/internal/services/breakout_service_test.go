@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestBreakoutService(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	s := NewBreakoutService(NewRoomService(url, "key", "secret", store, nil), store, nil)
+
+	const parent = "community_7_stage"
+	for i, id := range []string{"mod", "u1", "u2", "u3"} {
+		lk.participants[id] = &livekit.ParticipantInfo{Sid: "PA_" + id, Identity: id}
+		store.AddParticipant(ctx, parent, &storage.Participant{Identity: id, Name: "User " + id, JoinedAt: time.Unix(int64(i), 0)})
+	}
+
+	if _, err := s.AutoAssign(ctx, parent, nil, "mod"); !errors.Is(err, ErrNoBreakouts) {
+		t.Errorf("Expected assigning without breakout rooms to fail, got %v", err)
+	}
+	if _, err := s.CreateBreakouts(ctx, parent, 0, nil, "mod"); !errors.Is(err, ErrInvalidBreakout) {
+		t.Errorf("Expected zero breakout rooms to be refused, got %v", err)
+	}
+
+	rooms, err := s.CreateBreakouts(ctx, parent, 0, []string{"Red", ""}, "mod")
+	if err != nil {
+		t.Fatalf("Failed to create breakout rooms: %v", err)
+	}
+	if len(rooms) != 2 || rooms[0].Name != "Red" || rooms[1].Name != "Breakout 2" || rooms[1].RoomName != parent+"_breakout_2" {
+		t.Fatalf("Unexpected breakout rooms: %+v", rooms)
+	}
+	if lk.rooms[parent+"_breakout_1"] == nil {
+		t.Error("Expected the breakout rooms created in LiveKit")
+	}
+	if _, err := s.CreateBreakouts(ctx, parent, 2, nil, "mod"); !errors.Is(err, ErrBreakoutsExist) {
+		t.Errorf("Expected a second set of breakout rooms to be refused, got %v", err)
+	}
+
+	// Everyone but the moderator is spread across the rooms
+	moves, err := s.AutoAssign(ctx, parent, nil, "mod")
+	if err != nil || len(moves) != 3 {
+		t.Fatalf("Expected three participants assigned, got %+v, %v", moves, err)
+	}
+	breakouts, _ := s.GetBreakouts(ctx, parent)
+	sizes := map[string]int{}
+	for _, a := range breakouts.Assignments {
+		sizes[a.RoomName]++
+	}
+	if sizes[rooms[0].RoomName] != 2 || sizes[rooms[1].RoomName] != 1 {
+		t.Errorf("Expected the participants spread evenly, got %v", sizes)
+	}
+
+	// Each is sent a token for their breakout room in the parent room
+	var notice map[string]string
+	json.Unmarshal(lk.sent[0].Data, &notice)
+	if lk.sent[0].Room != parent || notice["type"] != EventBreakoutAssigned || notice["room_name"] != rooms[0].RoomName || notice["token"] == "" {
+		t.Errorf("Unexpected notice: %+v", notice)
+	}
+
+	// A targeted move is told in the breakout room they are in
+	sent := len(lk.sent)
+	if _, err := s.AssignParticipants(ctx, parent, map[string]string{"u1": "elsewhere"}, "mod"); !errors.Is(err, ErrBreakoutNotFound) {
+		t.Errorf("Expected an unknown room to be refused, got %v", err)
+	}
+	moves, err = s.AssignParticipants(ctx, parent, map[string]string{"u1": rooms[1].RoomName}, "mod")
+	if err != nil || !moves[0].Notified {
+		t.Fatalf("Expected u1 moved, got %+v, %v", moves, err)
+	}
+	if lk.sent[sent].Room != rooms[0].RoomName {
+		t.Errorf("Expected u1 told in their old breakout room, got %s", lk.sent[sent].Room)
+	}
+
+	sent = len(lk.sent)
+	if n, err := s.BroadcastMessage(ctx, parent, "Two minutes left", "mod"); err != nil || n != 2 {
+		t.Errorf("Expected the message sent to both rooms, got %d, %v", n, err)
+	}
+	if len(lk.sent) != sent+2 || lk.sent[sent].DestinationSids != nil {
+		t.Errorf("Expected the message sent to everyone in each room, got %+v", lk.sent[sent:])
+	}
+
+	moves, err = s.ReturnAll(ctx, parent, "mod")
+	if err != nil || len(moves) != 3 || moves[0].RoomName != parent {
+		t.Fatalf("Expected everyone returned, got %+v, %v", moves, err)
+	}
+	if breakouts, _ := s.GetBreakouts(ctx, parent); len(breakouts.Assignments) != 0 || len(breakouts.Rooms) != 2 {
+		t.Errorf("Expected the rooms kept without assignments, got %+v", breakouts)
+	}
+
+	if err := s.CloseBreakouts(ctx, parent, "mod"); err != nil {
+		t.Fatalf("Failed to close breakout rooms: %v", err)
+	}
+	if breakouts, _ := s.GetBreakouts(ctx, parent); len(breakouts.Rooms) != 0 || len(lk.rooms) != 0 {
+		t.Errorf("Expected the breakout rooms deleted, got %+v", breakouts)
+	}
+}
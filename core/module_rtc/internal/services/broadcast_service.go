@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+var (
+	ErrInvalidStreamDestination  = errors.New("invalid stream destination")
+	ErrStreamDestinationNotFound = errors.New("stream destination not found")
+	ErrAlreadyBroadcasting       = errors.New("already being broadcast")
+	ErrBroadcastNotFound         = errors.New("broadcast not found")
+	ErrBroadcastNotActive        = errors.New("broadcast has already ended")
+)
+
+const (
+	PlatformTwitch  = "twitch"
+	PlatformYouTube = "youtube"
+	PlatformCustom  = "custom"
+)
+
+// platformIngestURLs are the RTMP ingest URLs used when a destination on a
+// known platform does not give its own.
+var platformIngestURLs = map[string]string{
+	PlatformTwitch:  "rtmp://live.twitch.tv/app",
+	PlatformYouTube: "rtmp://a.rtmp.youtube.com/live2",
+}
+
+type StreamDestination = storage.StreamDestination
+type Broadcast = storage.Broadcast
+
+// BroadcastService streams rooms to external platforms over RTMP with
+// LiveKit egress, to destinations each community configures.
+type BroadcastService struct {
+	client *lksdk.EgressClient
+	store  storage.Store
+	events *EventBus
+}
+
+func NewBroadcastService(host, apiKey, apiSecret string, store storage.Store, events *EventBus) *BroadcastService {
+	return &BroadcastService{
+		client: lksdk.NewEgressClient(host, apiKey, apiSecret),
+		store:  store,
+		events: events,
+	}
+}
+
+// AddDestination stores an RTMP destination for the community. Twitch and
+// YouTube destinations default to the platform's ingest URL.
+func (s *BroadcastService) AddDestination(ctx context.Context, communityID int, name, platform, rtmpURL, streamKey, createdBy string) (*StreamDestination, error) {
+	if platform == "" {
+		platform = PlatformCustom
+	}
+	if rtmpURL == "" {
+		rtmpURL = platformIngestURLs[platform]
+	}
+
+	switch {
+	case name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidStreamDestination)
+	case platform != PlatformCustom && platformIngestURLs[platform] == "":
+		return nil, fmt.Errorf("%w: unknown platform %q", ErrInvalidStreamDestination, platform)
+	case !strings.HasPrefix(rtmpURL, "rtmp://") && !strings.HasPrefix(rtmpURL, "rtmps://"):
+		return nil, fmt.Errorf("%w: rtmp_url must be an rtmp:// or rtmps:// URL", ErrInvalidStreamDestination)
+	case streamKey == "":
+		return nil, fmt.Errorf("%w: stream_key is required", ErrInvalidStreamDestination)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	destination := &StreamDestination{
+		ID:          id,
+		CommunityID: communityID,
+		Name:        name,
+		Platform:    platform,
+		RTMPURL:     strings.TrimRight(rtmpURL, "/"),
+		StreamKey:   streamKey,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.store.SaveStreamDestination(ctx, destination); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Stream destination %s (%s) added to community %d by %s", destination.ID, platform, communityID, createdBy)
+	return destination, nil
+}
+
+func (s *BroadcastService) ListDestinations(ctx context.Context, communityID int) ([]*StreamDestination, error) {
+	return s.store.ListStreamDestinations(ctx, communityID)
+}
+
+// DeleteDestination removes one of the community's destinations. Broadcasts
+// already streaming to it carry on until stopped.
+func (s *BroadcastService) DeleteDestination(ctx context.Context, communityID int, id string) error {
+	if _, err := s.destination(ctx, communityID, id); err != nil {
+		return err
+	}
+	return s.store.DeleteStreamDestination(ctx, id)
+}
+
+// StartBroadcast streams the room's composite to each of the given
+// destinations, which must belong to the room's community. A room has at
+// most one broadcast at a time.
+func (s *BroadcastService) StartBroadcast(ctx context.Context, roomName string, destinationIDs []string, startedBy string) (*Broadcast, error) {
+	if len(destinationIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one destination is required", ErrInvalidStreamDestination)
+	}
+
+	communityID, err := lookupCommunityID(ctx, s.store, roomName)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(destinationIDs))
+	for _, id := range destinationIDs {
+		destination, err := s.destination(ctx, communityID, id)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, destination.RTMPURL+"/"+destination.StreamKey)
+	}
+
+	broadcasts, err := s.store.ListBroadcasts(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range broadcasts {
+		if !recordingEnded(b.Status) {
+			return nil, ErrAlreadyBroadcasting
+		}
+	}
+
+	info, err := s.client.StartRoomCompositeEgress(ctx, &livekit.RoomCompositeEgressRequest{
+		RoomName: roomName,
+		Layout:   "speaker",
+		StreamOutputs: []*livekit.StreamOutput{{
+			Protocol: livekit.StreamProtocol_RTMP,
+			Urls:     urls,
+		}},
+	})
+	metrics.ObserveLiveKit("StartRoomCompositeEgress", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start broadcast: %w", err)
+	}
+
+	broadcast := &Broadcast{
+		EgressID:       info.EgressId,
+		RoomName:       roomName,
+		DestinationIDs: destinationIDs,
+		StartedBy:      startedBy,
+		StartedAt:      time.Now(),
+	}
+	applyBroadcastInfo(broadcast, info)
+	if err := s.store.SaveBroadcast(ctx, broadcast); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(RoomEvent{
+		Type:     EventBroadcastStarted,
+		RoomName: roomName,
+		ActorID:  startedBy,
+		Data:     broadcastEventData(broadcast),
+	})
+	return broadcast, nil
+}
+
+// StopBroadcast ends one of the room's broadcasts on every destination.
+func (s *BroadcastService) StopBroadcast(ctx context.Context, roomName, egressID, stoppedBy string) (*Broadcast, error) {
+	broadcast, err := s.store.GetBroadcast(ctx, egressID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && broadcast.RoomName != roomName) {
+		return nil, ErrBroadcastNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if recordingEnded(broadcast.Status) {
+		return nil, ErrBroadcastNotActive
+	}
+
+	info, err := s.client.StopEgress(ctx, &livekit.StopEgressRequest{EgressId: egressID})
+	metrics.ObserveLiveKit("StopEgress", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop broadcast: %w", err)
+	}
+	log.Printf("Broadcast %s of %s stopped by %s", egressID, roomName, stoppedBy)
+	return s.HandleEgressUpdate(ctx, info)
+}
+
+func (s *BroadcastService) ListBroadcasts(ctx context.Context, roomName string) ([]*Broadcast, error) {
+	return s.store.ListBroadcasts(ctx, roomName)
+}
+
+// HandleEgressUpdate applies an egress's status from LiveKit to its
+// broadcast. Egresses this service did not start are ignored.
+func (s *BroadcastService) HandleEgressUpdate(ctx context.Context, info *livekit.EgressInfo) (*Broadcast, error) {
+	broadcast, err := s.store.GetBroadcast(ctx, info.EgressId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	wasEnded := recordingEnded(broadcast.Status)
+	applyBroadcastInfo(broadcast, info)
+	if err := s.store.SaveBroadcast(ctx, broadcast); err != nil {
+		return nil, err
+	}
+
+	if recordingEnded(broadcast.Status) && !wasEnded {
+		s.events.Publish(RoomEvent{
+			Type:     EventBroadcastEnded,
+			RoomName: broadcast.RoomName,
+			Data:     broadcastEventData(broadcast),
+		})
+	}
+	return broadcast, nil
+}
+
+// destination gets a destination, treating other communities' as missing.
+func (s *BroadcastService) destination(ctx context.Context, communityID int, id string) (*StreamDestination, error) {
+	destination, err := s.store.GetStreamDestination(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && destination.CommunityID != communityID) {
+		return nil, ErrStreamDestinationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return destination, nil
+}
+
+func applyBroadcastInfo(broadcast *Broadcast, info *livekit.EgressInfo) {
+	broadcast.Status = strings.ToLower(strings.TrimPrefix(info.Status.String(), "EGRESS_"))
+	broadcast.Error = info.Error
+	if info.EndedAt > 0 {
+		endedAt := time.Unix(0, info.EndedAt)
+		broadcast.EndedAt = &endedAt
+	}
+}
+
+func broadcastEventData(broadcast *Broadcast) map[string]string {
+	return map[string]string{
+		"egress_id":    broadcast.EgressID,
+		"status":       broadcast.Status,
+		"destinations": strings.Join(broadcast.DestinationIDs, ","),
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
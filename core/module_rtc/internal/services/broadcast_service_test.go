@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestBroadcastService_Destinations(t *testing.T) {
+	ctx := context.Background()
+	s := NewBroadcastService("http://localhost:7880", "key", "secret", storage.NewMemoryStore(), nil)
+
+	twitch, err := s.AddDestination(ctx, 7, "Twitch", PlatformTwitch, "", "live_123", "mod")
+	if err != nil {
+		t.Fatalf("Failed to add destination: %v", err)
+	}
+	if twitch.RTMPURL != "rtmp://live.twitch.tv/app" || twitch.ID == "" {
+		t.Errorf("Expected the Twitch ingest URL by default, got %+v", twitch)
+	}
+
+	invalid := []struct{ name, platform, url, key string }{
+		{"", PlatformTwitch, "", "key"},
+		{"Kick", "kick", "", "key"},
+		{"Custom", PlatformCustom, "", "key"},
+		{"Custom", PlatformCustom, "https://example.com/live", "key"},
+		{"YouTube", PlatformYouTube, "", ""},
+	}
+	for _, d := range invalid {
+		if _, err := s.AddDestination(ctx, 7, d.name, d.platform, d.url, d.key, "mod"); !errors.Is(err, ErrInvalidStreamDestination) {
+			t.Errorf("Expected %+v to be refused, got %v", d, err)
+		}
+	}
+
+	if _, err := s.AddDestination(ctx, 8, "Own server", "", "rtmps://ingest.example.com/live/", "abc", "mod"); err != nil {
+		t.Fatalf("Failed to add custom destination: %v", err)
+	}
+	destinations, _ := s.ListDestinations(ctx, 7)
+	if len(destinations) != 1 || destinations[0].ID != twitch.ID {
+		t.Fatalf("Expected only community 7's destination, got %+v", destinations)
+	}
+
+	if err := s.DeleteDestination(ctx, 8, twitch.ID); !errors.Is(err, ErrStreamDestinationNotFound) {
+		t.Errorf("Expected another community's destination to be hidden, got %v", err)
+	}
+	if err := s.DeleteDestination(ctx, 7, twitch.ID); err != nil {
+		t.Fatalf("Failed to delete destination: %v", err)
+	}
+	if destinations, _ := s.ListDestinations(ctx, 7); len(destinations) != 0 {
+		t.Errorf("Expected no destinations left, got %+v", destinations)
+	}
+}
+
+func TestBroadcastService(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("community_7_stage")
+	defer unsubscribe()
+	s := NewBroadcastService(url, "key", "secret", storage.NewMemoryStore(), events)
+
+	twitch, _ := s.AddDestination(ctx, 7, "Twitch", PlatformTwitch, "", "live_123", "mod")
+	youtube, _ := s.AddDestination(ctx, 7, "YouTube", PlatformYouTube, "", "yt-key", "mod")
+	other, _ := s.AddDestination(ctx, 8, "Twitch", PlatformTwitch, "", "live_456", "mod")
+
+	if _, err := s.StartBroadcast(ctx, "community_7_stage", nil, "mod"); !errors.Is(err, ErrInvalidStreamDestination) {
+		t.Errorf("Expected a broadcast without destinations to be refused, got %v", err)
+	}
+	if _, err := s.StartBroadcast(ctx, "community_7_stage", []string{twitch.ID, other.ID}, "mod"); !errors.Is(err, ErrStreamDestinationNotFound) {
+		t.Errorf("Expected another community's destination to be refused, got %v", err)
+	}
+	if len(lk.egressCalls) != 0 {
+		t.Fatal("Expected no egress started for refused broadcasts")
+	}
+
+	broadcast, err := s.StartBroadcast(ctx, "community_7_stage", []string{twitch.ID, youtube.ID}, "mod")
+	if err != nil {
+		t.Fatalf("Failed to start broadcast: %v", err)
+	}
+	if broadcast.EgressID != "EG_1" || broadcast.Status != RecordingStarting || len(broadcast.DestinationIDs) != 2 {
+		t.Errorf("Unexpected broadcast: %+v", broadcast)
+	}
+	req := lk.egressCalls[0].(*livekit.RoomCompositeEgressRequest)
+	if len(req.StreamOutputs) != 1 || req.StreamOutputs[0].Protocol != livekit.StreamProtocol_RTMP {
+		t.Fatalf("Expected one RTMP stream output, got %+v", req.StreamOutputs)
+	}
+	urls := req.StreamOutputs[0].Urls
+	if len(urls) != 2 || urls[0] != "rtmp://live.twitch.tv/app/live_123" || urls[1] != "rtmp://a.rtmp.youtube.com/live2/yt-key" {
+		t.Errorf("Expected the stream keys appended to the ingest URLs, got %v", urls)
+	}
+	if event := <-roomEvents; event.Type != EventBroadcastStarted || event.Data["egress_id"] != "EG_1" || event.ActorID != "mod" {
+		t.Errorf("Expected a broadcast_started event, got %+v", event)
+	}
+
+	if _, err := s.StartBroadcast(ctx, "community_7_stage", []string{twitch.ID}, "mod"); !errors.Is(err, ErrAlreadyBroadcasting) {
+		t.Errorf("Expected a second broadcast to be refused, got %v", err)
+	}
+
+	if _, err := s.StopBroadcast(ctx, "community_7_other", "EG_1", "mod"); !errors.Is(err, ErrBroadcastNotFound) {
+		t.Errorf("Expected stopping another room's broadcast to fail, got %v", err)
+	}
+	stopped, err := s.StopBroadcast(ctx, "community_7_stage", "EG_1", "mod")
+	if err != nil || stopped.Status != RecordingEnding {
+		t.Fatalf("Expected the broadcast to be ending, got %+v, %v", stopped, err)
+	}
+
+	// LiveKit's egress_ended webhook finishes the broadcast
+	s.HandleEgressUpdate(ctx, &livekit.EgressInfo{EgressId: "EG_1", Status: livekit.EgressStatus_EGRESS_COMPLETE, EndedAt: 1})
+	if event := <-roomEvents; event.Type != EventBroadcastEnded || event.Data["status"] != RecordingComplete {
+		t.Errorf("Expected a broadcast_ended event, got %+v", event)
+	}
+	broadcasts, _ := s.ListBroadcasts(ctx, "community_7_stage")
+	if len(broadcasts) != 1 || broadcasts[0].EndedAt == nil {
+		t.Fatalf("Expected the ended broadcast, got %+v", broadcasts)
+	}
+	if _, err := s.StopBroadcast(ctx, "community_7_stage", "EG_1", "mod"); !errors.Is(err, ErrBroadcastNotActive) {
+		t.Errorf("Expected stopping an ended broadcast to fail, got %v", err)
+	}
+	if _, err := s.StartBroadcast(ctx, "community_7_stage", []string{twitch.ID}, "mod"); err != nil {
+		t.Errorf("Expected a new broadcast after the last ended, got %v", err)
+	}
+
+	if b, err := s.HandleEgressUpdate(ctx, &livekit.EgressInfo{EgressId: "EG_unknown"}); b != nil || err != nil {
+		t.Errorf("Expected egress started elsewhere to be ignored, got %+v, %v", b, err)
+	}
+}
@@ -2,154 +2,368 @@ package services
 
 import (
 	"context"
-	"sync"
+	"errors"
+	"log"
+	"strconv"
 	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+type RaisedHand = storage.RaisedHand
+
+var (
+	ErrInvalidHandExpiry = errors.New("hand expiry must be between 0 and 1440 minutes")
+	ErrNoRaisedHands     = errors.New("no hands are raised")
+)
+
+const maxHandExpiryMinutes = 24 * 60
+
+// Why a hand was lowered, in the reason of hand_lowered events not caused by
+// its owner.
+const (
+	HandLoweredExpired     = "expired"
+	HandLoweredLeft        = "left"
+	HandLoweredNextSpeaker = "next_speaker"
 )
 
-type RaisedHand struct {
-	UserID         string    `json:"user_id"`
-	UserName       string    `json:"user_name"`
-	RaisedAt       time.Time `json:"raised_at"`
-	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
-	AcknowledgedBy string    `json:"acknowledged_by,omitempty"`
+// RoomSnapshot is a room's call state at one moment: its lock, chat slow
+// mode, who may share their screen, raised hand queue and the participants
+// LiveKit's webhooks reported.
+type RoomSnapshot struct {
+	RoomName          string                 `json:"room_name"`
+	IsLocked          bool                   `json:"is_locked"`
+	LockedBy          string                 `json:"locked_by,omitempty"`
+	SlowModeSeconds   int                    `json:"slow_mode_seconds"`
+	ScreenShare       string                 `json:"screen_share"`
+	HandExpiryMinutes int                    `json:"hand_expiry_minutes"`
+	RaisedHands       []*RaisedHand          `json:"raised_hands"`
+	Participants      []*storage.Participant `json:"participants"`
 }
 
 type CallFeaturesService struct {
-	roomService  *RoomService
-	raisedHands  map[string][]*RaisedHand // roomName -> hands
-	lockedRooms  map[string]bool
-	mu           sync.RWMutex
+	roomService *RoomService
+	store       storage.Store
+	analytics   *AnalyticsService
+	events      *EventBus
 }
 
-func NewCallFeaturesService(roomService *RoomService) *CallFeaturesService {
+func NewCallFeaturesService(roomService *RoomService, store storage.Store, analytics *AnalyticsService, events *EventBus) *CallFeaturesService {
 	return &CallFeaturesService{
-		roomService:  roomService,
-		raisedHands:  make(map[string][]*RaisedHand),
-		lockedRooms:  make(map[string]bool),
+		roomService: roomService,
+		store:       store,
+		analytics:   analytics,
+		events:      events,
 	}
 }
 
 func (s *CallFeaturesService) RaiseHand(ctx context.Context, roomName, userID, userName string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	hands := s.raisedHands[roomName]
-	for _, h := range hands {
-		if h.UserID == userID {
-			return nil // Already raised
-		}
-	}
-
-	s.raisedHands[roomName] = append(hands, &RaisedHand{
+	now := time.Now()
+	added, err := s.store.AddRaisedHand(ctx, roomName, &RaisedHand{
 		UserID:   userID,
 		UserName: userName,
-		RaisedAt: time.Now(),
+		RaisedAt: now,
 	})
-
+	if err != nil {
+		return err
+	}
+	if added {
+		s.analytics.HandRaised(ctx, roomName, userID, userName, now)
+		s.events.Publish(RoomEvent{
+			Type:     EventHandRaised,
+			RoomName: roomName,
+			UserID:   userID,
+			Data:     map[string]string{"user_name": userName},
+		})
+	}
 	return nil
 }
 
 func (s *CallFeaturesService) LowerHand(ctx context.Context, roomName, userID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	hands := s.raisedHands[roomName]
-	for i, h := range hands {
-		if h.UserID == userID {
-			s.raisedHands[roomName] = append(hands[:i], hands[i+1:]...)
-			return nil
+	_, err := s.lowerHand(ctx, roomName, userID, "", "")
+	return err
+}
+
+// lowerHand takes the user's hand off the queue, reporting false if it was
+// not raised. The reason is given when someone else lowered it.
+func (s *CallFeaturesService) lowerHand(ctx context.Context, roomName, userID, actorID, reason string) (bool, error) {
+	lowered, err := s.store.RemoveRaisedHand(ctx, roomName, userID)
+	if err != nil {
+		return false, err
+	}
+	if lowered {
+		event := RoomEvent{Type: EventHandLowered, RoomName: roomName, UserID: userID, ActorID: actorID}
+		if reason != "" {
+			event.Data = map[string]string{"reason": reason}
 		}
+		s.events.Publish(event)
 	}
-
-	return nil
+	return lowered, nil
 }
 
 func (s *CallFeaturesService) AcknowledgeHand(ctx context.Context, roomName, userID, moderatorID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	hands := s.raisedHands[roomName]
-	for _, h := range hands {
-		if h.UserID == userID {
-			now := time.Now()
-			h.AcknowledgedAt = &now
-			h.AcknowledgedBy = moderatorID
-			return nil
-		}
+	if err := s.store.AcknowledgeHand(ctx, roomName, userID, moderatorID, time.Now()); err != nil {
+		return err
 	}
-
+	s.events.Publish(RoomEvent{Type: EventHandAcknowledged, RoomName: roomName, UserID: userID, ActorID: moderatorID})
 	return nil
 }
 
+// GetRaisedHands returns the room's queue in the order hands were raised,
+// numbered from 1, lowering any that have expired.
 func (s *CallFeaturesService) GetRaisedHands(ctx context.Context, roomName string) ([]*RaisedHand, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	return s.queue(ctx, state)
+}
 
-	hands := s.raisedHands[roomName]
-	if hands == nil {
-		return []*RaisedHand{}, nil
+func (s *CallFeaturesService) queue(ctx context.Context, state *storage.RoomState) ([]*RaisedHand, error) {
+	hands, err := s.store.ListRaisedHands(ctx, state.RoomName)
+	if err != nil {
+		return nil, err
 	}
 
-	result := make([]*RaisedHand, len(hands))
-	copy(result, hands)
-	return result, nil
+	now := time.Now()
+	expiry := time.Duration(state.HandExpiryMinutes) * time.Minute
+	queue := make([]*RaisedHand, 0, len(hands))
+	for _, hand := range hands {
+		if expiry > 0 && hand.AcknowledgedAt == nil {
+			expiresAt := hand.RaisedAt.Add(expiry)
+			if !expiresAt.After(now) {
+				if _, err := s.lowerHand(ctx, state.RoomName, hand.UserID, "", HandLoweredExpired); err != nil {
+					log.Printf("Failed to lower expired hand of %s in %s: %v", hand.UserID, state.RoomName, err)
+				}
+				continue
+			}
+			hand.ExpiresAt = &expiresAt
+		}
+		hand.Position = len(queue) + 1
+		queue = append(queue, hand)
+	}
+	return queue, nil
 }
 
-func (s *CallFeaturesService) ClearRaisedHands(ctx context.Context, roomName string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// SetHandExpiry lowers hands left unacknowledged for minutes; zero keeps
+// them up until lowered.
+func (s *CallFeaturesService) SetHandExpiry(ctx context.Context, roomName string, minutes int, moderatorID string) error {
+	if minutes < 0 || minutes > maxHandExpiryMinutes {
+		return ErrInvalidHandExpiry
+	}
+
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	state.HandExpiryMinutes = minutes
+	state.UpdatedAt = time.Now()
+	if err := s.store.SaveRoomState(ctx, state); err != nil {
+		return err
+	}
 
-	delete(s.raisedHands, roomName)
+	s.events.Publish(RoomEvent{Type: EventHandExpiryChanged, RoomName: roomName, ActorID: moderatorID, Data: map[string]string{
+		"hand_expiry_minutes": strconv.Itoa(minutes),
+	}})
+	s.roomService.PublishRoomConfig(ctx, roomName, ConfigHandExpiry, moderatorID)
 	return nil
 }
 
-func (s *CallFeaturesService) MuteParticipant(ctx context.Context, roomName, userID, moderatorID string) error {
-	return s.roomService.MuteParticipant(ctx, roomName, userID, true)
+// NextSpeaker takes the first hand off the room's queue and unmutes its
+// owner. Anyone ahead of them who has left the room is dropped on the way.
+func (s *CallFeaturesService) NextSpeaker(ctx context.Context, roomName, moderatorID string) (*RaisedHand, error) {
+	hands, err := s.GetRaisedHands(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hand := range hands {
+		err := s.giveFloor(ctx, roomName, hand.UserID)
+		if errors.Is(err, ErrParticipantNotFound) {
+			if _, err := s.lowerHand(ctx, roomName, hand.UserID, moderatorID, HandLoweredLeft); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := s.lowerHand(ctx, roomName, hand.UserID, moderatorID, HandLoweredNextSpeaker); err != nil {
+			return nil, err
+		}
+		s.events.Publish(RoomEvent{Type: EventParticipantUnmuted, RoomName: roomName, UserID: hand.UserID, ActorID: moderatorID})
+		return hand, nil
+	}
+	return nil, ErrNoRaisedHands
 }
 
-func (s *CallFeaturesService) UnmuteParticipant(ctx context.Context, roomName, userID, moderatorID string) error {
+// giveFloor lets the user publish their camera and microphone and unmutes
+// the latter.
+func (s *CallFeaturesService) giveFloor(ctx context.Context, roomName, userID string) error {
+	if err := s.roomService.RevokeMedia(ctx, roomName, userID, false); err != nil {
+		return err
+	}
 	return s.roomService.MuteParticipant(ctx, roomName, userID, false)
 }
 
-func (s *CallFeaturesService) MuteAll(ctx context.Context, roomName, moderatorID string) error {
-	participants, err := s.roomService.ListParticipants(ctx, roomName)
+// RunHandExpiry lowers expired hands every interval until ctx is done, so
+// rooms nobody is reading still see them go.
+func (s *CallFeaturesService) RunHandExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ExpireRaisedHands(ctx, time.Now()); err != nil {
+			log.Printf("Failed to lower expired hands: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireRaisedHands lowers every hand past its room's expiry, returning how
+// many it lowered.
+func (s *CallFeaturesService) ExpireRaisedHands(ctx context.Context, now time.Time) (int, error) {
+	expired, err := s.store.ListExpiredRaisedHands(ctx, now)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	count := 0
+	for roomName, hands := range expired {
+		for _, hand := range hands {
+			lowered, err := s.lowerHand(ctx, roomName, hand.UserID, "", HandLoweredExpired)
+			if err != nil {
+				return count, err
+			}
+			if lowered {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+func (s *CallFeaturesService) RoomSnapshot(ctx context.Context, roomName string) (*RoomSnapshot, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	hands, err := s.queue(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	participants, err := s.store.ListParticipants(ctx, roomName)
+	if err != nil {
+		return nil, err
 	}
+	return &RoomSnapshot{
+		RoomName:          roomName,
+		IsLocked:          state.IsLocked,
+		LockedBy:          state.LockedBy,
+		SlowModeSeconds:   state.SlowModeSeconds,
+		ScreenShare:       screenShareOrDefault(state.ScreenShare),
+		HandExpiryMinutes: state.HandExpiryMinutes,
+		RaisedHands:       hands,
+		Participants:      participants,
+	}, nil
+}
 
+// InRoom reports whether the user is in the room, as LiveKit's webhooks
+// last reported.
+func (s *CallFeaturesService) InRoom(ctx context.Context, roomName, userID string) (bool, error) {
+	participants, err := s.store.ListParticipants(ctx, roomName)
+	if err != nil {
+		return false, err
+	}
 	for _, p := range participants {
-		if p.Identity != moderatorID {
-			_ = s.roomService.MuteParticipant(ctx, roomName, p.Identity, true)
+		if p.Identity == userID {
+			return true, nil
 		}
 	}
+	return false, nil
+}
+
+func (s *CallFeaturesService) ClearRaisedHands(ctx context.Context, roomName string) error {
+	return s.store.ClearRaisedHands(ctx, roomName)
+}
 
+// MuteParticipant mutes the user's microphone, which they may unmute again.
+func (s *CallFeaturesService) MuteParticipant(ctx context.Context, roomName, userID, moderatorID string) error {
+	if err := s.roomService.MuteParticipant(ctx, roomName, userID, true); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventParticipantMuted, RoomName: roomName, UserID: userID, ActorID: moderatorID})
+	return nil
+}
+
+func (s *CallFeaturesService) UnmuteParticipant(ctx context.Context, roomName, userID, moderatorID string) error {
+	if err := s.roomService.MuteParticipant(ctx, roomName, userID, false); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventParticipantUnmuted, RoomName: roomName, UserID: userID, ActorID: moderatorID})
+	return nil
+}
+
+func (s *CallFeaturesService) MuteAll(ctx context.Context, roomName, moderatorID string) error {
+	if _, err := s.roomService.MuteAll(ctx, roomName, moderatorID); err != nil {
+		return err
+	}
+
+	s.events.Publish(RoomEvent{Type: EventAllMuted, RoomName: roomName, ActorID: moderatorID})
 	return nil
 }
 
 func (s *CallFeaturesService) KickParticipant(ctx context.Context, roomName, userID, adminID string) error {
 	s.LowerHand(ctx, roomName, userID)
-	return s.roomService.KickParticipant(ctx, roomName, userID)
+	if err := s.roomService.KickParticipant(ctx, roomName, userID); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventParticipantKicked, RoomName: roomName, UserID: userID, ActorID: adminID})
+	return nil
 }
 
 func (s *CallFeaturesService) LockRoom(ctx context.Context, roomName, adminID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.lockedRooms[roomName] = true
+	if err := s.setLocked(ctx, roomName, adminID, true); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventRoomLocked, RoomName: roomName, ActorID: adminID})
+	s.roomService.PublishRoomConfig(ctx, roomName, ConfigLocked, adminID)
 	return nil
 }
 
 func (s *CallFeaturesService) UnlockRoom(ctx context.Context, roomName, adminID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	delete(s.lockedRooms, roomName)
+	if err := s.setLocked(ctx, roomName, adminID, false); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventRoomUnlocked, RoomName: roomName, ActorID: adminID})
+	s.roomService.PublishRoomConfig(ctx, roomName, ConfigLocked, adminID)
 	return nil
 }
 
-func (s *CallFeaturesService) IsRoomLocked(ctx context.Context, roomName string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *CallFeaturesService) setLocked(ctx context.Context, roomName, adminID string, locked bool) error {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return err
+	}
+
+	state.IsLocked = locked
+	state.LockedBy = ""
+	if locked {
+		state.LockedBy = adminID
+	}
+	state.UpdatedAt = time.Now()
+	return s.store.SaveRoomState(ctx, state)
+}
 
-	return s.lockedRooms[roomName]
+func (s *CallFeaturesService) IsRoomLocked(ctx context.Context, roomName string) (bool, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return false, err
+	}
+	return state.IsLocked, nil
 }
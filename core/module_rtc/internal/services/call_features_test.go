@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestCallFeaturesService_StateSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+
+	s := NewCallFeaturesService(nil, store, nil, nil)
+	s.RaiseHand(ctx, "room", "user1", "One")
+	s.RaiseHand(ctx, "room", "user2", "Two")
+	s.RaiseHand(ctx, "room", "user1", "One")
+	s.LockRoom(ctx, "room", "admin")
+
+	// A new instance over the same store sees the same state
+	restarted := NewCallFeaturesService(nil, store, nil, nil)
+	hands, err := restarted.GetRaisedHands(ctx, "room")
+	if err != nil || len(hands) != 2 || hands[0].UserID != "user1" {
+		t.Fatalf("Expected two hands with user1 first, got %+v, %v", hands, err)
+	}
+	if locked, err := restarted.IsRoomLocked(ctx, "room"); err != nil || !locked {
+		t.Errorf("Expected the room to stay locked, got %v, %v", locked, err)
+	}
+
+	restarted.LowerHand(ctx, "room", "user1")
+	restarted.UnlockRoom(ctx, "room", "admin")
+	if hands, _ := s.GetRaisedHands(ctx, "room"); len(hands) != 1 || hands[0].UserID != "user2" {
+		t.Errorf("Expected only user2 left, got %+v", hands)
+	}
+	if locked, _ := s.IsRoomLocked(ctx, "room"); locked {
+		t.Error("Expected the room to be unlocked")
+	}
+}
+
+func TestCallFeaturesService_HandQueue(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+	s := NewCallFeaturesService(NewRoomService(url, "key", "secret", store, events), store, nil, events)
+
+	lk.participants["u2"] = &livekit.ParticipantInfo{Sid: "PA_2", Identity: "u2", Metadata: `{"role":"speaker"}`}
+	lk.participants["u3"] = &livekit.ParticipantInfo{Sid: "PA_3", Identity: "u3", Metadata: `{"role":"speaker"}`}
+	now := time.Now()
+	for i, id := range []string{"gone", "u2", "u3"} {
+		store.AddRaisedHand(ctx, "room", &RaisedHand{UserID: id, UserName: id, RaisedAt: now.Add(time.Duration(i-10) * time.Minute)})
+	}
+	store.AddRaisedHand(ctx, "room", &RaisedHand{UserID: "u4", UserName: "Four", RaisedAt: now})
+	s.AcknowledgeHand(ctx, "room", "u3", "mod")
+	<-roomEvents
+
+	if err := s.SetHandExpiry(ctx, "room", -1, "mod"); !errors.Is(err, ErrInvalidHandExpiry) {
+		t.Errorf("Expected a negative expiry refused, got %v", err)
+	}
+	if err := s.SetHandExpiry(ctx, "room", 5, "mod"); err != nil {
+		t.Fatalf("Failed to set hand expiry: %v", err)
+	}
+	if event := <-roomEvents; event.Type != EventHandExpiryChanged || event.Data["hand_expiry_minutes"] != "5" {
+		t.Errorf("Expected a hand_expiry_changed event, got %+v", event)
+	}
+	if event := <-roomEvents; event.Type != EventRoomConfigChanged || event.Data["changed"] != ConfigHandExpiry {
+		t.Errorf("Expected a room_config_changed event, got %+v", event)
+	}
+
+	// Hands older than five minutes go, unless acknowledged
+	if n, err := s.ExpireRaisedHands(ctx, now); err != nil || n != 2 {
+		t.Fatalf("Expected two hands expired, got %d, %v", n, err)
+	}
+	if event := <-roomEvents; event.Type != EventHandLowered || event.Data["reason"] != HandLoweredExpired {
+		t.Errorf("Expected an expired hand_lowered event, got %+v", event)
+	}
+	<-roomEvents
+
+	hands, err := s.GetRaisedHands(ctx, "room")
+	if err != nil || len(hands) != 2 || hands[0].UserID != "u3" || hands[0].Position != 1 || hands[1].Position != 2 {
+		t.Fatalf("Expected u3 then u4 in the queue, got %+v, %v", hands, err)
+	}
+	if hands[0].ExpiresAt != nil || hands[1].ExpiresAt == nil || !hands[1].ExpiresAt.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("Expected only the unacknowledged hand to expire, got %v, %v", hands[0].ExpiresAt, hands[1].ExpiresAt)
+	}
+
+	// The next speaker is unmuted and leaves the queue; those who left are
+	// skipped
+	store.AddRaisedHand(ctx, "room", &RaisedHand{UserID: "gone", UserName: "Gone", RaisedAt: now.Add(-time.Hour)})
+	s.AcknowledgeHand(ctx, "room", "gone", "mod")
+	<-roomEvents
+	hand, err := s.NextSpeaker(ctx, "room", "mod")
+	if err != nil || hand.UserID != "u3" {
+		t.Fatalf("Expected u3 to speak next, got %+v, %v", hand, err)
+	}
+	if p := lk.participants["u3"].Permission; p == nil || !p.CanPublish {
+		t.Errorf("Expected u3 unmuted, got %+v", p)
+	}
+	if event := <-roomEvents; event.UserID != "gone" || event.Data["reason"] != HandLoweredLeft {
+		t.Errorf("Expected the hand of someone who left dropped, got %+v", event)
+	}
+	if event := <-roomEvents; event.UserID != "u3" || event.Data["reason"] != HandLoweredNextSpeaker || event.ActorID != "mod" {
+		t.Errorf("Expected u3's hand lowered as next speaker, got %+v", event)
+	}
+	if event := <-roomEvents; event.Type != EventParticipantUnmuted || event.UserID != "u3" {
+		t.Errorf("Expected u3 unmuted, got %+v", event)
+	}
+
+	if hands, _ := s.GetRaisedHands(ctx, "room"); len(hands) != 1 || hands[0].UserID != "u4" || hands[0].Position != 1 {
+		t.Errorf("Expected u4 first now, got %+v", hands)
+	}
+	store.RemoveRaisedHand(ctx, "room", "u4")
+	if _, err := s.NextSpeaker(ctx, "room", "mod"); !errors.Is(err, ErrNoRaisedHands) {
+		t.Errorf("Expected no next speaker, got %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+var (
+	ErrInvalidChatMessage  = errors.New("invalid chat message")
+	ErrChatMessageRejected = errors.New("chat message rejected")
+	ErrChatMessageNotFound = errors.New("chat message not found")
+	ErrSlowMode            = errors.New("slow mode is on")
+)
+
+// ChatNoticeTopic is the data topic chat messages, deletions and slow mode
+// changes are sent to participants on.
+const ChatNoticeTopic = "waddlebot.chat"
+
+const (
+	maxChatMessageLength = 2000
+	defaultChatPage      = 50
+	maxChatPage          = 200
+	maxSlowModeSeconds   = 3600
+)
+
+type ChatMessage = storage.ChatMessage
+
+// SlowModeError is returned when a user posts again sooner than the room's
+// slow mode allows.
+type SlowModeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("%v: wait %d seconds", ErrSlowMode, int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+func (e *SlowModeError) Unwrap() error {
+	return ErrSlowMode
+}
+
+// ChatFilter checks a message before it is posted. It returns the text to
+// post, which may be changed, or an error wrapping ErrChatMessageRejected.
+type ChatFilter interface {
+	FilterMessage(ctx context.Context, roomName, userID, message string) (string, error)
+}
+
+// ChatService keeps each room's chat history and relays messages to the
+// room's participants on ChatNoticeTopic.
+type ChatService struct {
+	roomService *RoomService
+	store       storage.Store
+	events      *EventBus
+	filters     []ChatFilter
+}
+
+func NewChatService(roomService *RoomService, store storage.Store, events *EventBus, filters ...ChatFilter) *ChatService {
+	return &ChatService{
+		roomService: roomService,
+		store:       store,
+		events:      events,
+		filters:     filters,
+	}
+}
+
+type chatNotice struct {
+	Type            string       `json:"type"`
+	Message         *ChatMessage `json:"message,omitempty"`
+	MessageID       int64        `json:"message_id,omitempty"`
+	SlowModeSeconds *int         `json:"slow_mode_seconds,omitempty"`
+}
+
+// PostMessage filters and stores a message and sends it to the room.
+// Moderators pass exempt to skip slow mode.
+func (s *ChatService) PostMessage(ctx context.Context, roomName, userID, userName, text string, exempt bool) (*ChatMessage, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: message is required", ErrInvalidChatMessage)
+	}
+	if utf8.RuneCountInString(text) > maxChatMessageLength {
+		return nil, fmt.Errorf("%w: message is longer than %d characters", ErrInvalidChatMessage, maxChatMessageLength)
+	}
+
+	now := time.Now()
+	if !exempt {
+		state, err := s.store.GetRoomState(ctx, roomName)
+		if err != nil {
+			return nil, err
+		}
+		if state.SlowModeSeconds > 0 {
+			last, err := s.store.LastChatMessageAt(ctx, roomName, userID)
+			if err != nil {
+				return nil, err
+			}
+			if wait := last.Add(time.Duration(state.SlowModeSeconds) * time.Second).Sub(now); wait > 0 {
+				return nil, &SlowModeError{RetryAfter: wait}
+			}
+		}
+	}
+
+	message := &ChatMessage{
+		RoomName:  roomName,
+		UserID:    userID,
+		UserName:  userName,
+		Message:   text,
+		CreatedAt: now,
+	}
+	for _, filter := range s.filters {
+		filtered, err := filter.FilterMessage(ctx, roomName, userID, message.Message)
+		if err != nil {
+			return nil, err
+		}
+		if filtered != message.Message {
+			message.Message = filtered
+			message.Filtered = true
+		}
+	}
+
+	if err := s.store.AddChatMessage(ctx, message); err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, roomName, chatNotice{Type: EventChatMessage, Message: message})
+	s.events.Publish(RoomEvent{Type: EventChatMessage, RoomName: roomName, UserID: userID, Data: map[string]string{
+		"message_id": strconv.FormatInt(message.ID, 10),
+		"user_name":  message.UserName,
+		"message":    message.Message,
+	}})
+	return message, nil
+}
+
+// GetMessages returns up to limit messages after afterID, or if it is zero
+// the last before beforeID, oldest first. Deleted messages keep their text
+// only with withDeleted.
+func (s *ChatService) GetMessages(ctx context.Context, roomName string, afterID, beforeID int64, limit int, withDeleted bool) ([]*ChatMessage, error) {
+	if limit <= 0 {
+		limit = defaultChatPage
+	}
+	limit = min(limit, maxChatPage)
+
+	var messages []*ChatMessage
+	var err error
+	if afterID > 0 {
+		messages, err = s.store.ListChatMessages(ctx, roomName, afterID, limit)
+	} else {
+		messages, err = s.store.ListRecentChatMessages(ctx, roomName, beforeID, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !withDeleted {
+		for _, m := range messages {
+			if m.DeletedAt != nil {
+				m.Message = ""
+			}
+		}
+	}
+	return messages, nil
+}
+
+// DeleteMessage hides a message from the room. It is kept, with who deleted
+// it, for the archive.
+func (s *ChatService) DeleteMessage(ctx context.Context, roomName string, id int64, moderatorID string) error {
+	message, err := s.store.GetChatMessage(ctx, roomName, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrChatMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if message.DeletedAt != nil {
+		return nil
+	}
+
+	if err := s.store.DeleteChatMessage(ctx, roomName, id, moderatorID, time.Now()); err != nil {
+		return err
+	}
+
+	s.notify(ctx, roomName, chatNotice{Type: EventChatMessageDeleted, MessageID: id})
+	s.events.Publish(RoomEvent{Type: EventChatMessageDeleted, RoomName: roomName, UserID: message.UserID, ActorID: moderatorID, Data: map[string]string{
+		"message_id": strconv.FormatInt(id, 10),
+	}})
+	return nil
+}
+
+// SetSlowMode makes participants wait seconds between messages; zero turns
+// slow mode off.
+func (s *ChatService) SetSlowMode(ctx context.Context, roomName string, seconds int, moderatorID string) error {
+	if seconds < 0 || seconds > maxSlowModeSeconds {
+		return fmt.Errorf("%w: slow mode must be between 0 and %d seconds", ErrInvalidChatMessage, maxSlowModeSeconds)
+	}
+
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	state.SlowModeSeconds = seconds
+	state.UpdatedAt = time.Now()
+	if err := s.store.SaveRoomState(ctx, state); err != nil {
+		return err
+	}
+
+	s.notify(ctx, roomName, chatNotice{Type: EventChatSlowMode, SlowModeSeconds: &seconds})
+	s.events.Publish(RoomEvent{Type: EventChatSlowMode, RoomName: roomName, ActorID: moderatorID, Data: map[string]string{
+		"seconds": strconv.Itoa(seconds),
+	}})
+	s.roomService.PublishRoomConfig(ctx, roomName, ConfigSlowMode, moderatorID)
+	return nil
+}
+
+// notify sends a notice to everyone in the room. The history is kept whether
+// or not the room is live, so a failure is only logged.
+func (s *ChatService) notify(ctx context.Context, roomName string, notice chatNotice) {
+	if err := s.roomService.NotifyRoom(ctx, roomName, ChatNoticeTopic, notice); err != nil && !isNotFound(err) {
+		log.Printf("Failed to send %s to %s: %v", notice.Type, roomName, err)
+	}
+}
+
+// BlocklistFilter masks blocked words with asterisks, or rejects messages
+// containing them. Words match whole and case-insensitively.
+type BlocklistFilter struct {
+	pattern *regexp.Regexp
+	reject  bool
+}
+
+func NewBlocklistFilter(words []string, reject bool) *BlocklistFilter {
+	var quoted []string
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+
+	f := &BlocklistFilter{reject: reject}
+	if len(quoted) > 0 {
+		f.pattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	}
+	return f
+}
+
+func (f *BlocklistFilter) FilterMessage(ctx context.Context, roomName, userID, message string) (string, error) {
+	if f.pattern == nil || !f.pattern.MatchString(message) {
+		return message, nil
+	}
+	if f.reject {
+		return "", fmt.Errorf("%w: message contains a blocked word", ErrChatMessageRejected)
+	}
+	return f.pattern.ReplaceAllStringFunc(message, func(word string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	}), nil
+}
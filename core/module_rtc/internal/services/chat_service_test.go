@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestChatService(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	s := NewChatService(NewRoomService(url, "key", "secret", store, nil), store, nil, NewBlocklistFilter([]string{"darn", " "}, false))
+
+	const room = "community_7_lobby"
+	if _, err := s.PostMessage(ctx, room, "u1", "One", "   ", false); !errors.Is(err, ErrInvalidChatMessage) {
+		t.Errorf("Expected an empty message to be refused, got %v", err)
+	}
+
+	message, err := s.PostMessage(ctx, room, "u1", "One", "Darn, the darned mic", false)
+	if err != nil {
+		t.Fatalf("Failed to post message: %v", err)
+	}
+	if message.Message != "****, the darned mic" || !message.Filtered {
+		t.Errorf("Expected only the whole blocked word masked, got %+v", message)
+	}
+
+	// The message is relayed to the room
+	var notice struct {
+		Type    string       `json:"type"`
+		Message *ChatMessage `json:"message"`
+	}
+	json.Unmarshal(lk.sent[0].Data, &notice)
+	if lk.sent[0].Room != room || *lk.sent[0].Topic != ChatNoticeTopic || notice.Type != EventChatMessage || notice.Message.ID != message.ID {
+		t.Errorf("Unexpected notice: %+v", notice)
+	}
+
+	if err := s.SetSlowMode(ctx, room, 30, "mod"); err != nil {
+		t.Fatalf("Failed to set slow mode: %v", err)
+	}
+	var slowMode *SlowModeError
+	if _, err := s.PostMessage(ctx, room, "u1", "One", "again", false); !errors.As(err, &slowMode) || slowMode.RetryAfter <= 0 {
+		t.Errorf("Expected slow mode to hold the second message, got %v", err)
+	}
+	if _, err := s.PostMessage(ctx, room, "u2", "Two", "hello", false); err != nil {
+		t.Errorf("Expected another user to post, got %v", err)
+	}
+	if _, err := s.PostMessage(ctx, room, "u1", "One", "moderator says", true); err != nil {
+		t.Errorf("Expected an exempt user to skip slow mode, got %v", err)
+	}
+
+	if err := s.DeleteMessage(ctx, room, 99, "mod"); !errors.Is(err, ErrChatMessageNotFound) {
+		t.Errorf("Expected a missing message to 404, got %v", err)
+	}
+	if err := s.DeleteMessage(ctx, room, message.ID, "mod"); err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+
+	messages, _ := s.GetMessages(ctx, room, 0, 0, 0, false)
+	if len(messages) != 3 || messages[0].Message != "" || messages[0].DeletedBy != "mod" {
+		t.Errorf("Expected the deleted message without its text, got %+v", messages[0])
+	}
+	messages, _ = s.GetMessages(ctx, room, 0, 0, 0, true)
+	if messages[0].Message == "" {
+		t.Error("Expected the archive to keep the deleted message's text")
+	}
+
+	// Paging forwards for the archive, and backwards for history
+	if messages, _ := s.GetMessages(ctx, room, message.ID, 0, 1, true); len(messages) != 1 || messages[0].UserID != "u2" {
+		t.Errorf("Expected the message after the first, got %+v", messages)
+	}
+	if messages, _ := s.GetMessages(ctx, room, 0, messages[2].ID, 1, true); len(messages) != 1 || messages[0].UserID != "u2" {
+		t.Errorf("Expected the message before the last, got %+v", messages)
+	}
+}
+
+func TestBlocklistFilter_Reject(t *testing.T) {
+	f := NewBlocklistFilter([]string{"spam"}, true)
+	if _, err := f.FilterMessage(context.Background(), "room", "u1", "buy SPAM now"); !errors.Is(err, ErrChatMessageRejected) {
+		t.Errorf("Expected the message rejected, got %v", err)
+	}
+	if message, err := f.FilterMessage(context.Background(), "room", "u1", "spammer"); err != nil || message != "spammer" {
+		t.Errorf("Expected a longer word to pass, got %q, %v", message, err)
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventRoomCreated          = "room_created"
+	EventRoomDeleted          = "room_deleted"
+	EventRoomStarted          = "room_started"
+	EventRoomFinished         = "room_finished"
+	EventRoomLocked           = "room_locked"
+	EventRoomUnlocked         = "room_unlocked"
+	EventRoomConfigChanged    = "room_config_changed"
+	EventParticipantJoined    = "participant_joined"
+	EventParticipantLeft      = "participant_left"
+	EventParticipantMuted     = "participant_muted"
+	EventParticipantUnmuted   = "participant_unmuted"
+	EventParticipantKicked    = "participant_kicked"
+	EventParticipantBanned    = "participant_banned"
+	EventBanLifted            = "ban_lifted"
+	EventDialInEnabled        = "dial_in_enabled"
+	EventDialInDisabled       = "dial_in_disabled"
+	EventParticipantAdmitted  = "participant_admitted"
+	EventMediaRevoked         = "media_revoked"
+	EventMediaRestored        = "media_restored"
+	EventAllMuted             = "all_muted"
+	EventHandRaised           = "hand_raised"
+	EventHandLowered          = "hand_lowered"
+	EventHandAcknowledged     = "hand_acknowledged"
+	EventHandExpiryChanged    = "hand_expiry_changed"
+	EventRoleChanged          = "role_changed"
+	EventRecordingStarted     = "recording_started"
+	EventRecordingEnded       = "recording_ended"
+	EventBroadcastStarted     = "broadcast_started"
+	EventBroadcastEnded       = "broadcast_ended"
+	EventBreakoutsCreated     = "breakouts_created"
+	EventBreakoutAssigned     = "breakout_assigned"
+	EventBreakoutReturned     = "breakout_returned"
+	EventBreakoutMessage      = "breakout_message"
+	EventBreakoutsReturned    = "breakouts_returned"
+	EventBreakoutsClosed      = "breakouts_closed"
+	EventChatMessage          = "chat_message"
+	EventChatMessageDeleted   = "chat_message_deleted"
+	EventChatSlowMode         = "chat_slow_mode"
+	EventScreenShareChanged   = "screen_share_changed"
+	EventScreenShareStopped   = "screen_share_stopped"
+	EventCodecRejected        = "codec_rejected"
+	EventInviteRedeemed       = "invite_redeemed"
+	EventTranscriptionStarted = "transcription_started"
+	EventTranscriptionStopped = "transcription_stopped"
+	EventCaption              = "caption"
+	EventWatchStarted         = "watch_started"
+	EventWatchSynced          = "watch_synced"
+	EventWatchEnded           = "watch_ended"
+	EventTokensRevoked        = "tokens_revoked"
+)
+
+const eventSubscriberQueueSize = 64
+
+type RoomEvent struct {
+	Type      string            `json:"type"`
+	RoomName  string            `json:"room_name"`
+	UserID    string            `json:"user_id,omitempty"`
+	ActorID   string            `json:"actor_id,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+type eventSubscription struct {
+	roomName string
+	events   chan RoomEvent
+}
+
+// EventBus fans room events out to subscribers in this process. A subscriber
+// that falls behind misses events rather than holding up the publisher.
+type EventBus struct {
+	subscribers map[*eventSubscription]struct{}
+	mu          sync.RWMutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[*eventSubscription]struct{}),
+	}
+}
+
+// Subscribe returns the events for a room, or for all rooms when roomName is
+// empty, and a function that ends the subscription and closes the channel.
+func (b *EventBus) Subscribe(roomName string) (<-chan RoomEvent, func()) {
+	sub := &eventSubscription{
+		roomName: roomName,
+		events:   make(chan RoomEvent, eventSubscriberQueueSize),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, sub)
+			b.mu.Unlock()
+			close(sub.events)
+		})
+	}
+}
+
+func (b *EventBus) Publish(event RoomEvent) {
+	if b == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if sub.roomName != "" && sub.roomName != event.RoomName {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	lobby, unsubscribeLobby := bus.Subscribe("lobby")
+	all, unsubscribeAll := bus.Subscribe("")
+	defer unsubscribeAll()
+
+	bus.Publish(RoomEvent{Type: EventHandRaised, RoomName: "lobby", UserID: "u1"})
+	bus.Publish(RoomEvent{Type: EventRoomLocked, RoomName: "stage"})
+
+	if event := <-lobby; event.Type != EventHandRaised || event.Timestamp.IsZero() {
+		t.Errorf("Expected a timestamped hand_raised event, got %+v", event)
+	}
+	select {
+	case event := <-lobby:
+		t.Errorf("Expected no events from other rooms, got %+v", event)
+	default:
+	}
+	if len(all) != 2 {
+		t.Errorf("Expected both events for the all-rooms subscriber, got %d", len(all))
+	}
+
+	unsubscribeLobby()
+	unsubscribeLobby()
+	if _, ok := <-lobby; ok {
+		t.Error("Expected the channel to be closed after unsubscribing")
+	}
+
+	// A subscriber that is not reading does not block publishing
+	for i := 0; i < eventSubscriberQueueSize*2; i++ {
+		bus.Publish(RoomEvent{Type: EventHandLowered, RoomName: "lobby"})
+	}
+	if len(all) != eventSubscriberQueueSize {
+		t.Errorf("Expected the queue to fill at %d, got %d", eventSubscriberQueueSize, len(all))
+	}
+
+	var nilBus *EventBus
+	nilBus.Publish(RoomEvent{Type: EventRoomStarted})
+}
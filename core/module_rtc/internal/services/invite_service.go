@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+// How long invites last unless asked otherwise, and at most.
+const (
+	DefaultInviteTTL = 24 * time.Hour
+	MaxInviteTTL     = 7 * 24 * time.Hour
+)
+
+const (
+	inviteIssuer     = "module_rtc"
+	inviteSubject    = "room_invite"
+	maxGuestNameSize = 64
+)
+
+var (
+	ErrInvalidInvite  = errors.New("invalid invite")
+	ErrInviteExpired  = errors.New("invite has expired")
+	ErrInviteNotFound = errors.New("invite not found")
+	ErrInviteRedeemed = errors.New("invite has already been used")
+	ErrRoomLocked     = errors.New("room is locked")
+)
+
+type Invite = storage.Invite
+
+// InviteLink is a new invite with the token and link to hand the guest,
+// which cannot be looked up again.
+type InviteLink struct {
+	*Invite
+	Token string `json:"token"`
+	URL   string `json:"url,omitempty"`
+}
+
+// inviteClaims are the claims an invite token carries besides its ID and
+// expiry.
+type inviteClaims struct {
+	Room string `json:"room"`
+	Role string `json:"role"`
+}
+
+// InviteService issues single-use invite links and exchanges them for join
+// tokens, so guests without WaddleBot accounts can join rooms.
+type InviteService struct {
+	rooms    *RoomService
+	features *CallFeaturesService
+	store    storage.Store
+	secret   []byte
+	baseURL  string
+	events   *EventBus
+}
+
+// NewInviteService signs invites with secret and links them to baseURL,
+// the page guests redeem them on.
+func NewInviteService(rooms *RoomService, features *CallFeaturesService, store storage.Store, secret, baseURL string, events *EventBus) *InviteService {
+	return &InviteService{
+		rooms:    rooms,
+		features: features,
+		store:    store,
+		secret:   []byte(secret),
+		baseURL:  baseURL,
+		events:   events,
+	}
+}
+
+// CreateInvite issues an invite to the room with the role, viewer if empty,
+// lasting ttl or DefaultInviteTTL if zero.
+func (s *InviteService) CreateInvite(ctx context.Context, roomName, role string, ttl time.Duration, createdBy string) (*InviteLink, error) {
+	if role == "" {
+		role = RoleViewer
+	}
+	if ttl == 0 {
+		ttl = DefaultInviteTTL
+	}
+	switch {
+	case role != RoleViewer && role != RoleSpeaker:
+		return nil, fmt.Errorf("%w: role must be viewer or speaker", ErrInvalidInvite)
+	case ttl < time.Minute || ttl > MaxInviteTTL:
+		return nil, fmt.Errorf("%w: invites last from a minute to %d days", ErrInvalidInvite, int(MaxInviteTTL.Hours()/24))
+	}
+	if _, err := s.store.GetRoom(ctx, roomName); errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrRoomNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	invite := &Invite{
+		ID:        id,
+		RoomName:  roomName,
+		Role:      role,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	token, err := s.sign(invite)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Invite %s to %s created by %s", invite.ID, roomName, createdBy)
+	return &InviteLink{Invite: invite, Token: token, URL: s.link(token)}, nil
+}
+
+func (s *InviteService) sign(invite *Invite) (string, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: s.secret}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign invite: %w", err)
+	}
+	token, err := jwt.Signed(signer).
+		Claims(jwt.Claims{
+			ID:       invite.ID,
+			Issuer:   inviteIssuer,
+			Subject:  inviteSubject,
+			IssuedAt: jwt.NewNumericDate(invite.CreatedAt),
+			Expiry:   jwt.NewNumericDate(invite.ExpiresAt),
+		}).
+		Claims(inviteClaims{Room: invite.RoomName, Role: invite.Role}).
+		CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign invite: %w", err)
+	}
+	return token, nil
+}
+
+func (s *InviteService) link(token string) string {
+	u, err := url.Parse(s.baseURL)
+	if err != nil || s.baseURL == "" {
+		return ""
+	}
+	query := u.Query()
+	query.Set("invite", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// RedeemInvite uses up the invite and returns a token joining the room as a
+// new guest, named guestName or Guest, or as userID if they are signed in
+// and not banned from the room. Either way they skip the room's lobby.
+func (s *InviteService) RedeemInvite(ctx context.Context, token, guestName, userID string) (*JoinToken, error) {
+	invite, err := s.verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if userID != "" {
+		if err := s.features.CheckBan(ctx, invite.RoomName, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	locked, err := s.features.IsRoomLocked(ctx, invite.RoomName)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return nil, ErrRoomLocked
+	}
+
+	guestName = strings.TrimSpace(guestName)
+	if guestName == "" {
+		guestName = "Guest"
+	}
+	if len([]rune(guestName)) > maxGuestNameSize {
+		guestName = string([]rune(guestName)[:maxGuestNameSize])
+	}
+	guestID := userID
+	if guestID == "" {
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		guestID = "guest_" + id
+	}
+
+	now := time.Now()
+	redeemed, err := s.store.RedeemInvite(ctx, invite.ID, guestID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !redeemed {
+		return nil, ErrInviteRedeemed
+	}
+
+	// The invite is what lets them in, so they get its role and skip the
+	// lobby. Users already given a higher role in the room keep it.
+	role := invite.Role
+	assignment, err := s.store.GetParticipantRole(ctx, invite.RoomName, guestID)
+	switch {
+	case err == nil && RoleRank(assignment.Role) >= RoleRank(role):
+		role = assignment.Role
+	case err == nil || errors.Is(err, storage.ErrNotFound):
+		err = s.store.SetParticipantRole(ctx, invite.RoomName, &storage.RoleAssignment{
+			UserID:     guestID,
+			Role:       role,
+			AssignedBy: invite.CreatedBy,
+			UpdatedAt:  now,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	joinToken, err := s.rooms.JoinRoom(ctx, invite.RoomName, guestID, guestName, role)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(RoomEvent{
+		Type:     EventInviteRedeemed,
+		RoomName: invite.RoomName,
+		UserID:   guestID,
+		ActorID:  invite.CreatedBy,
+		Data:     map[string]string{"invite_id": invite.ID, "name": guestName, "role": role},
+	})
+	return joinToken, nil
+}
+
+// verify checks the token's signature and expiry and returns its stored
+// invite, which is gone if it was revoked.
+func (s *InviteService) verify(ctx context.Context, token string) (*Invite, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, ErrInvalidInvite
+	}
+	var registered jwt.Claims
+	var claims inviteClaims
+	if err := parsed.Claims(s.secret, &registered, &claims); err != nil {
+		return nil, ErrInvalidInvite
+	}
+	err = registered.ValidateWithLeeway(jwt.Expected{Issuer: inviteIssuer, Subject: inviteSubject, Time: time.Now()}, time.Minute)
+	if errors.Is(err, jwt.ErrExpired) {
+		return nil, ErrInviteExpired
+	}
+	if err != nil || registered.ID == "" {
+		return nil, ErrInvalidInvite
+	}
+
+	invite, err := s.store.GetInvite(ctx, registered.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invite.RoomName != claims.Room || invite.Role != claims.Role {
+		return nil, ErrInvalidInvite
+	}
+	return invite, nil
+}
+
+// ListInvites returns the room's invites, newest first, without their
+// tokens.
+func (s *InviteService) ListInvites(ctx context.Context, roomName string) ([]*Invite, error) {
+	return s.store.ListInvites(ctx, roomName)
+}
+
+// RevokeInvite deletes one of the room's invites, so its link no longer
+// works. Guests who already joined with it stay.
+func (s *InviteService) RevokeInvite(ctx context.Context, roomName, id string) error {
+	invite, err := s.store.GetInvite(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && invite.RoomName != roomName) {
+		return ErrInviteNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteInvite(ctx, id)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestInviteService_CreateAndRedeem(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("community_7_stage")
+	defer unsubscribe()
+	rooms := NewRoomService("http://localhost:7880", "key", "secret", store, events)
+	features := NewCallFeaturesService(rooms, store, nil, events)
+	s := NewInviteService(rooms, features, store, "invite-secret", "https://waddlebot.test/calls/invite", events)
+
+	if _, err := s.CreateInvite(ctx, "community_7_stage", RoleViewer, 0, "mod"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected an invite to a missing room to fail, got %v", err)
+	}
+	store.SaveRoom(ctx, &storage.Room{RoomName: "community_7_stage", CommunityID: 7})
+	store.SaveRoomState(ctx, &storage.RoomState{RoomName: "community_7_stage", Lobby: true})
+
+	if _, err := s.CreateInvite(ctx, "community_7_stage", RoleModerator, 0, "mod"); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("Expected a moderator invite to be refused, got %v", err)
+	}
+	if _, err := s.CreateInvite(ctx, "community_7_stage", RoleViewer, MaxInviteTTL+time.Hour, "mod"); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("Expected an invite past the longest expiry to be refused, got %v", err)
+	}
+
+	invite, err := s.CreateInvite(ctx, "community_7_stage", RoleSpeaker, 0, "mod")
+	if err != nil {
+		t.Fatalf("Failed to create invite: %v", err)
+	}
+	if invite.ExpiresAt.Sub(invite.CreatedAt) != DefaultInviteTTL || invite.Token == "" {
+		t.Errorf("Expected a token lasting a day, got %+v", invite)
+	}
+	link, err := url.Parse(invite.URL)
+	if err != nil || !strings.HasPrefix(invite.URL, "https://waddlebot.test/calls/invite?") || link.Query().Get("invite") != invite.Token {
+		t.Errorf("Expected the link to carry the token, got %s", invite.URL)
+	}
+
+	token, err := s.RedeemInvite(ctx, invite.Token, "  Visitor  ", "")
+	if err != nil {
+		t.Fatalf("Failed to redeem invite: %v", err)
+	}
+	if !strings.HasPrefix(token.Identity, "guest_") || token.Lobby || token.Token == "" {
+		t.Errorf("Expected a new guest let into the room, got %+v", token)
+	}
+	if role, _ := features.AssignedRole(ctx, "community_7_stage", token.Identity); role != RoleSpeaker {
+		t.Errorf("Expected the guest to keep the invite's role, got %s", role)
+	}
+	if waiting, _ := features.InLobby(ctx, "community_7_stage", token.Identity); waiting {
+		t.Error("Expected the guest to skip the lobby")
+	}
+	if event := <-roomEvents; event.Type != EventInviteRedeemed || event.Data["name"] != "Visitor" || event.ActorID != "mod" {
+		t.Errorf("Expected an invite_redeemed event, got %+v", event)
+	}
+
+	if _, err := s.RedeemInvite(ctx, invite.Token, "", ""); !errors.Is(err, ErrInviteRedeemed) {
+		t.Errorf("Expected the invite to work once, got %v", err)
+	}
+	invites, _ := s.ListInvites(ctx, "community_7_stage")
+	if len(invites) != 1 || invites[0].RedeemedBy != token.Identity || invites[0].RedeemedAt == nil {
+		t.Errorf("Expected the invite marked redeemed, got %+v", invites)
+	}
+}
+
+func TestInviteService_RejectedInvites(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	rooms := NewRoomService("http://localhost:7880", "key", "secret", store, nil)
+	features := NewCallFeaturesService(rooms, store, nil, nil)
+	s := NewInviteService(rooms, features, store, "invite-secret", "", nil)
+	store.SaveRoom(ctx, &storage.Room{RoomName: "room", CommunityID: 7})
+
+	invite, err := s.CreateInvite(ctx, "room", "", time.Hour, "mod")
+	if err != nil {
+		t.Fatalf("Failed to create invite: %v", err)
+	}
+	if invite.Role != RoleViewer || invite.URL != "" {
+		t.Errorf("Expected a viewer invite without a link, got %+v", invite)
+	}
+
+	forged := NewInviteService(rooms, features, store, "other-secret", "", nil)
+	if _, err := forged.RedeemInvite(ctx, invite.Token, "", ""); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("Expected a token signed with another secret to be refused, got %v", err)
+	}
+	if _, err := s.RedeemInvite(ctx, "not-a-token", "", ""); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("Expected garbage to be refused, got %v", err)
+	}
+
+	expired := *invite.Invite
+	expired.ExpiresAt = time.Now().Add(-time.Hour)
+	token, _ := s.sign(&expired)
+	if _, err := s.RedeemInvite(ctx, token, "", ""); !errors.Is(err, ErrInviteExpired) {
+		t.Errorf("Expected an expired invite to be refused, got %v", err)
+	}
+
+	features.LockRoom(ctx, "room", "mod")
+	if _, err := s.RedeemInvite(ctx, invite.Token, "", ""); !errors.Is(err, ErrRoomLocked) {
+		t.Errorf("Expected a locked room to refuse guests, got %v", err)
+	}
+	features.UnlockRoom(ctx, "room", "mod")
+
+	if err := s.RevokeInvite(ctx, "other", invite.ID); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("Expected revoking from another room to fail, got %v", err)
+	}
+	if err := s.RevokeInvite(ctx, "room", invite.ID); err != nil {
+		t.Fatalf("Failed to revoke invite: %v", err)
+	}
+	if _, err := s.RedeemInvite(ctx, invite.Token, "", ""); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("Expected a revoked invite to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeLiveKit answers the LiveKit room service and egress calls the
+// services make.
+type fakeLiveKit struct {
+	participants map[string]*livekit.ParticipantInfo
+	rooms        map[string]*livekit.Room
+	sent         []*livekit.SendDataRequest
+	mutedTracks  []*livekit.MuteRoomTrackRequest
+	egress       map[string]*livekit.EgressInfo
+	egressCalls  []proto.Message
+	sipTrunks    []map[string]interface{}
+	sipRules     map[string]map[string]interface{}
+	sipRuleSeq   int
+	mu           sync.Mutex
+}
+
+func newFakeLiveKit(t *testing.T) (*fakeLiveKit, string) {
+	f := &fakeLiveKit{
+		participants: make(map[string]*livekit.ParticipantInfo),
+		rooms:        make(map[string]*livekit.Room),
+		egress:       make(map[string]*livekit.EgressInfo),
+		sipRules:     make(map[string]map[string]interface{}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	return f, server.URL
+}
+
+func (f *fakeLiveKit) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if strings.HasPrefix(r.URL.Path, "/twirp/livekit.SIP/") {
+		f.serveSIP(w, r, body)
+		return
+	}
+	var resp proto.Message
+	switch path.Base(r.URL.Path) {
+	case "GetParticipant":
+		var req livekit.RoomParticipantIdentity
+		proto.Unmarshal(body, &req)
+		p, ok := f.participants[req.Identity]
+		if !ok {
+			notFound(w)
+			return
+		}
+		resp = p
+	case "UpdateParticipant":
+		var req livekit.UpdateParticipantRequest
+		proto.Unmarshal(body, &req)
+		p, ok := f.participants[req.Identity]
+		if !ok {
+			notFound(w)
+			return
+		}
+		// Like LiveKit, only what the request sets is changed
+		if req.Metadata != "" {
+			p.Metadata = req.Metadata
+		}
+		if req.Permission != nil {
+			p.Permission = req.Permission
+		}
+		resp = p
+	case "RemoveParticipant":
+		var req livekit.RoomParticipantIdentity
+		proto.Unmarshal(body, &req)
+		if _, ok := f.participants[req.Identity]; !ok {
+			notFound(w)
+			return
+		}
+		delete(f.participants, req.Identity)
+		resp = &livekit.RemoveParticipantResponse{}
+	case "ListParticipants":
+		list := &livekit.ListParticipantsResponse{}
+		for _, p := range f.participants {
+			list.Participants = append(list.Participants, p)
+		}
+		resp = list
+	case "MutePublishedTrack":
+		var req livekit.MuteRoomTrackRequest
+		proto.Unmarshal(body, &req)
+		f.mutedTracks = append(f.mutedTracks, &req)
+		var track *livekit.TrackInfo
+		if p, ok := f.participants[req.Identity]; ok {
+			for _, t := range p.Tracks {
+				if t.Sid == req.TrackSid {
+					track = t
+				}
+			}
+		}
+		if track == nil {
+			notFound(w)
+			return
+		}
+		track.Muted = req.Muted
+		resp = &livekit.MuteRoomTrackResponse{Track: track}
+	case "SendData":
+		var req livekit.SendDataRequest
+		proto.Unmarshal(body, &req)
+		f.sent = append(f.sent, &req)
+		resp = &livekit.SendDataResponse{}
+	case "CreateRoom":
+		var req livekit.CreateRoomRequest
+		proto.Unmarshal(body, &req)
+		// Like LiveKit, creating a room that is open updates what is set
+		if room, ok := f.rooms[req.Name]; ok {
+			if req.MaxParticipants > 0 {
+				room.MaxParticipants = req.MaxParticipants
+			}
+			if req.Metadata != "" {
+				room.Metadata = req.Metadata
+			}
+			resp = room
+			break
+		}
+		room := &livekit.Room{
+			Sid:             "RM_" + req.Name,
+			Name:            req.Name,
+			MaxParticipants: req.MaxParticipants,
+			EmptyTimeout:    req.EmptyTimeout,
+			Metadata:        req.Metadata,
+		}
+		f.rooms[req.Name] = room
+		resp = room
+	case "UpdateRoomMetadata":
+		var req livekit.UpdateRoomMetadataRequest
+		proto.Unmarshal(body, &req)
+		room, ok := f.rooms[req.Room]
+		if !ok {
+			notFound(w)
+			return
+		}
+		room.Metadata = req.Metadata
+		resp = room
+	case "ListRooms":
+		var req livekit.ListRoomsRequest
+		proto.Unmarshal(body, &req)
+		list := &livekit.ListRoomsResponse{}
+		for _, name := range req.Names {
+			if room, ok := f.rooms[name]; ok {
+				list.Rooms = append(list.Rooms, room)
+			}
+		}
+		resp = list
+	case "DeleteRoom":
+		var req livekit.DeleteRoomRequest
+		proto.Unmarshal(body, &req)
+		delete(f.rooms, req.Room)
+		resp = &livekit.DeleteRoomResponse{}
+	case "StartRoomCompositeEgress":
+		var req livekit.RoomCompositeEgressRequest
+		proto.Unmarshal(body, &req)
+		resp = f.startEgress(req.RoomName, &req)
+	case "StartTrackEgress":
+		var req livekit.TrackEgressRequest
+		proto.Unmarshal(body, &req)
+		resp = f.startEgress(req.RoomName, &req)
+	case "StopEgress":
+		var req livekit.StopEgressRequest
+		proto.Unmarshal(body, &req)
+		info, ok := f.egress[req.EgressId]
+		if !ok {
+			notFound(w)
+			return
+		}
+		info.Status = livekit.EgressStatus_EGRESS_ENDING
+		resp = info
+	case "ListEgress":
+		var req livekit.ListEgressRequest
+		proto.Unmarshal(body, &req)
+		list := &livekit.ListEgressResponse{}
+		for _, info := range f.egress {
+			active := info.Status == livekit.EgressStatus_EGRESS_STARTING || info.Status == livekit.EgressStatus_EGRESS_ACTIVE
+			if info.RoomName == req.RoomName && (active || !req.Active) {
+				list.Items = append(list.Items, info)
+			}
+		}
+		resp = list
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	data, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/protobuf")
+	w.Write(data)
+}
+
+// serveSIP answers the SIP API, which the module calls with JSON.
+func (f *fakeLiveKit) serveSIP(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var req map[string]interface{}
+	json.Unmarshal(body, &req)
+
+	var resp interface{}
+	switch path.Base(r.URL.Path) {
+	case "ListSIPInboundTrunk":
+		resp = map[string]interface{}{"items": f.sipTrunks}
+	case "CreateSIPInboundTrunk":
+		trunk := req["trunk"].(map[string]interface{})
+		trunk["sip_trunk_id"] = fmt.Sprintf("ST_%d", len(f.sipTrunks)+1)
+		f.sipTrunks = append(f.sipTrunks, trunk)
+		resp = trunk
+	case "CreateSIPDispatchRule":
+		f.sipRuleSeq++
+		req["sip_dispatch_rule_id"] = fmt.Sprintf("SDR_%d", f.sipRuleSeq)
+		f.sipRules[req["sip_dispatch_rule_id"].(string)] = req
+		resp = req
+	case "DeleteSIPDispatchRule":
+		id, _ := req["sip_dispatch_rule_id"].(string)
+		rule, ok := f.sipRules[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"code": "not_found", "msg": "dispatch rule not found"})
+			return
+		}
+		delete(f.sipRules, id)
+		resp = rule
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeLiveKit) startEgress(roomName string, req proto.Message) *livekit.EgressInfo {
+	f.egressCalls = append(f.egressCalls, req)
+	info := &livekit.EgressInfo{
+		EgressId: "EG_" + strconv.Itoa(len(f.egressCalls)),
+		RoomName: roomName,
+		Status:   livekit.EgressStatus_EGRESS_STARTING,
+	}
+	if track, ok := req.(*livekit.TrackEgressRequest); ok {
+		info.Request = &livekit.EgressInfo_Track{Track: track}
+	}
+	f.egress[info.EgressId] = info
+	return info
+}
+
+func notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte(`{"code":"not_found","msg":"participant not found"}`))
+}
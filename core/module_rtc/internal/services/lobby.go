@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+// LobbyTopic is the data topic participants waiting in a room's lobby are
+// told on that they were let in.
+const LobbyTopic = "waddlebot.lobby"
+
+var ErrAlreadyAdmitted = errors.New("participant was already let into the room")
+
+// lobbyMetadata marks a participant as waiting in the lobby, so moderators
+// can tell them from viewers.
+func lobbyMetadata() string {
+	metadata, _ := json.Marshal(map[string]interface{}{"role": RoleViewer, "lobby": true})
+	return string(metadata)
+}
+
+// metadataLobby reports whether participant metadata marks them as waiting
+// in the lobby.
+func metadataLobby(metadata string) bool {
+	var m struct {
+		Lobby bool `json:"lobby"`
+	}
+	return json.Unmarshal([]byte(metadata), &m) == nil && m.Lobby
+}
+
+// JoinLobby returns a token that puts the user in the room's lobby: in the
+// room, where moderators see them, but unable to see, hear or publish
+// anything until admitted.
+func (s *RoomService) JoinLobby(ctx context.Context, roomName, userID, userName string) (*JoinToken, error) {
+	token, err := s.joinToken(ctx, roomName, userID, userName, RoleViewer, &livekit.ParticipantPermission{}, lobbyMetadata())
+	if err != nil {
+		return nil, err
+	}
+	token.Lobby = true
+	return token, nil
+}
+
+// InLobby reports whether the user has to wait in the room's lobby, which
+// they do until given a role in it.
+func (s *CallFeaturesService) InLobby(ctx context.Context, roomName, userID string) (bool, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil || !state.Lobby {
+		return false, err
+	}
+	_, err = s.store.GetParticipantRole(ctx, roomName, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return true, nil
+	}
+	return false, err
+}
+
+// AdmitParticipant lets the user into the room with its default role. Users
+// not in the room yet may be admitted ahead, and then skip the lobby.
+func (s *CallFeaturesService) AdmitParticipant(ctx context.Context, roomName, userID, moderatorID string) (*RoleChange, error) {
+	if _, err := s.store.GetParticipantRole(ctx, roomName, userID); err == nil {
+		return nil, ErrAlreadyAdmitted
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	role, err := s.defaultRole(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.roomService.grantRole(ctx, roomName, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetParticipantRole(ctx, roomName, &storage.RoleAssignment{
+		UserID:     userID,
+		Role:       role,
+		AssignedBy: moderatorID,
+		UpdatedAt:  time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	if p != nil {
+		err := s.roomService.sendData(ctx, roomName, LobbyTopic, map[string]string{
+			"type":        EventParticipantAdmitted,
+			"role":        role,
+			"admitted_by": moderatorID,
+		}, []string{p.Sid})
+		if err != nil {
+			log.Printf("Failed to tell %s they were let into %s: %v", userID, roomName, err)
+		}
+	}
+	s.events.Publish(RoomEvent{
+		Type:     EventParticipantAdmitted,
+		RoomName: roomName,
+		UserID:   userID,
+		ActorID:  moderatorID,
+		Data:     map[string]string{"role": role},
+	})
+	return &RoleChange{UserID: userID, Role: role, PreviousRole: RoleViewer, InRoom: p != nil}, nil
+}
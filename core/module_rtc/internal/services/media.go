@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+)
+
+// TrackState is one of a participant's published tracks and whether it is
+// muted, by them or the server.
+type TrackState struct {
+	Sid    string `json:"sid"`
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Muted  bool   `json:"muted"`
+}
+
+func trackStates(tracks []*livekit.TrackInfo) []*TrackState {
+	states := make([]*TrackState, 0, len(tracks))
+	for _, track := range tracks {
+		states = append(states, &TrackState{
+			Sid:    track.Sid,
+			Type:   strings.ToLower(track.Type.String()),
+			Source: strings.ToLower(track.Source.String()),
+			Muted:  track.Muted,
+		})
+	}
+	return states
+}
+
+// isMicrophone reports whether the track is the participant's voice. Audio
+// published without a source counts, screen share audio does not.
+func isMicrophone(track *livekit.TrackInfo) bool {
+	return track.Source == livekit.TrackSource_MICROPHONE ||
+		(track.Type == livekit.TrackType_AUDIO && !isScreenSource(track.Source))
+}
+
+// microphoneMuted reports whether the participant publishes no unmuted
+// microphone.
+func microphoneMuted(p *livekit.ParticipantInfo) bool {
+	for _, track := range p.Tracks {
+		if isMicrophone(track) && !track.Muted {
+			return false
+		}
+	}
+	return true
+}
+
+// canPublishMedia reports whether the permission lets the participant
+// publish their camera or microphone, as opposed to only their screen.
+func canPublishMedia(permission *livekit.ParticipantPermission) bool {
+	if permission == nil || !permission.CanPublish {
+		return false
+	}
+	if len(permission.CanPublishSources) == 0 {
+		return true
+	}
+	for _, source := range permission.CanPublishSources {
+		if !isScreenSource(source) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaRevoked reports whether a moderator has taken away the camera and
+// microphone the participant's role would give them.
+func mediaRevoked(p *livekit.ParticipantInfo) bool {
+	return RoleRank(metadataRole(p.Metadata)) >= RoleRank(RoleSpeaker) && !canPublishMedia(p.Permission)
+}
+
+func (s *RoomService) participant(ctx context.Context, roomName, userID string) (*livekit.ParticipantInfo, error) {
+	p, err := s.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{
+		Room:     roomName,
+		Identity: userID,
+	})
+	metrics.ObserveLiveKit("GetParticipant", err)
+	if isNotFound(err) {
+		return nil, ErrParticipantNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participant: %w", err)
+	}
+	return p, nil
+}
+
+// MuteParticipant mutes or unmutes the user's microphone tracks, leaving
+// their camera and screen alone. The participant may still unmute
+// themselves; RevokeMedia is what stops them. Unmuting needs
+// room.enable_remote_unmute in LiveKit's config.
+func (s *RoomService) MuteParticipant(ctx context.Context, roomName, userID string, muted bool) error {
+	p, err := s.participant(ctx, roomName, userID)
+	if err != nil {
+		return err
+	}
+	return s.muteMicrophone(ctx, roomName, p, muted)
+}
+
+// MuteAll mutes the microphone of everyone in the room but exceptID,
+// returning how many were muted.
+func (s *RoomService) MuteAll(ctx context.Context, roomName, exceptID string) (int, error) {
+	resp, err := s.client.ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: roomName})
+	metrics.ObserveLiveKit("ListParticipants", err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list participants: %w", err)
+	}
+
+	muted := 0
+	for _, p := range resp.Participants {
+		if p.Identity == exceptID || microphoneMuted(p) {
+			continue
+		}
+		if err := s.muteMicrophone(ctx, roomName, p, true); err != nil {
+			return muted, err
+		}
+		muted++
+	}
+	return muted, nil
+}
+
+func (s *RoomService) muteMicrophone(ctx context.Context, roomName string, p *livekit.ParticipantInfo, muted bool) error {
+	for _, track := range p.Tracks {
+		if !isMicrophone(track) || track.Muted == muted {
+			continue
+		}
+		_, err := s.client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+			Room:     roomName,
+			Identity: p.Identity,
+			TrackSid: track.Sid,
+			Muted:    muted,
+		})
+		metrics.ObserveLiveKit("MutePublishedTrack", err)
+		// The track may have been unpublished since it was listed
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to mute microphone: %w", err)
+		}
+	}
+	return nil
+}
+
+// RevokeMedia takes away or gives back the user's permission to publish
+// their camera and microphone, which LiveKit enforces by unpublishing them.
+// Their screen stays as the room allows. Restoring gives media to any role,
+// so a viewer given the floor can talk.
+func (s *RoomService) RevokeMedia(ctx context.Context, roomName, userID string, revoked bool) error {
+	p, err := s.participant(ctx, roomName, userID)
+	if err != nil {
+		return err
+	}
+	screenShare, err := s.ScreenShare(ctx, roomName)
+	if err != nil {
+		return err
+	}
+
+	sources := publishSources(!revoked, CanShareScreen(metadataRole(p.Metadata), screenShare))
+	permission := &livekit.ParticipantPermission{
+		CanPublish:        len(sources) > 0,
+		CanPublishSources: sources,
+		CanSubscribe:      true,
+	}
+	if p.Permission != nil {
+		permission.CanPublishData = p.Permission.CanPublishData
+	}
+
+	_, err = s.client.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room:       roomName,
+		Identity:   userID,
+		Permission: permission,
+	})
+	metrics.ObserveLiveKit("UpdateParticipant", err)
+	if isNotFound(err) {
+		return ErrParticipantNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update participant: %w", err)
+	}
+	return nil
+}
+
+// RevokeMedia stops the user publishing their camera and microphone until
+// restored, unlike a mute they cannot undo themselves.
+func (s *CallFeaturesService) RevokeMedia(ctx context.Context, roomName, userID, moderatorID string) error {
+	if err := s.roomService.RevokeMedia(ctx, roomName, userID, true); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventMediaRevoked, RoomName: roomName, UserID: userID, ActorID: moderatorID})
+	return nil
+}
+
+func (s *CallFeaturesService) RestoreMedia(ctx context.Context, roomName, userID, moderatorID string) error {
+	if err := s.roomService.RevokeMedia(ctx, roomName, userID, false); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventMediaRestored, RoomName: roomName, UserID: userID, ActorID: moderatorID})
+	return nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestCallFeaturesService_MuteAndRevokeMedia(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+	roomService := NewRoomService(url, "key", "secret", store, events)
+	s := NewCallFeaturesService(roomService, store, nil, events)
+
+	publishing := func(identity, role string) *livekit.ParticipantInfo {
+		return &livekit.ParticipantInfo{
+			Sid:        "PA_" + identity,
+			Identity:   identity,
+			Metadata:   roleMetadata(role),
+			Permission: rolePermission(role, ScreenShareSpeakers),
+			Tracks: []*livekit.TrackInfo{
+				{Sid: "TR_cam_" + identity, Type: livekit.TrackType_VIDEO, Source: livekit.TrackSource_CAMERA},
+				{Sid: "TR_mic_" + identity, Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_MICROPHONE},
+				{Sid: "TR_screen_audio_" + identity, Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_SCREEN_SHARE_AUDIO},
+			},
+		}
+	}
+	lk.participants["mod"] = publishing("mod", RoleModerator)
+	lk.participants["speaker"] = publishing("speaker", RoleSpeaker)
+
+	// Muting takes only the microphone, and leaves the permissions alone
+	if err := s.MuteParticipant(ctx, "room", "speaker", "mod"); err != nil {
+		t.Fatalf("Failed to mute: %v", err)
+	}
+	if len(lk.mutedTracks) != 1 || lk.mutedTracks[0].TrackSid != "TR_mic_speaker" || !lk.mutedTracks[0].Muted {
+		t.Fatalf("Expected only the microphone muted, got %+v", lk.mutedTracks)
+	}
+	if !canPublishMedia(lk.participants["speaker"].Permission) {
+		t.Error("Expected the speaker still allowed to publish")
+	}
+	if event := <-roomEvents; event.Type != EventParticipantMuted || event.UserID != "speaker" {
+		t.Errorf("Expected a participant_muted event, got %+v", event)
+	}
+
+	participants, err := roomService.ListParticipants(ctx, "room")
+	if err != nil {
+		t.Fatalf("Failed to list participants: %v", err)
+	}
+	for _, p := range participants {
+		if p.Identity != "speaker" {
+			continue
+		}
+		if !p.IsMuted || p.MediaRevoked || len(p.Tracks) != 3 {
+			t.Errorf("Expected the speaker muted with media, got %+v", p)
+		}
+		if p.Tracks[0].Source != "camera" || p.Tracks[0].Muted || p.Tracks[1].Type != "audio" || !p.Tracks[1].Muted {
+			t.Errorf("Expected only the microphone track muted, got %+v, %+v", p.Tracks[0], p.Tracks[1])
+		}
+	}
+
+	// Muting again or everyone else touches nothing already muted
+	if err := s.MuteAll(ctx, "room", "mod"); err != nil {
+		t.Fatalf("Failed to mute all: %v", err)
+	}
+	<-roomEvents
+	if len(lk.mutedTracks) != 1 {
+		t.Errorf("Expected the muted speaker and moderator left alone, got %+v", lk.mutedTracks)
+	}
+
+	if err := s.UnmuteParticipant(ctx, "room", "speaker", "mod"); err != nil {
+		t.Fatalf("Failed to unmute: %v", err)
+	}
+	if len(lk.mutedTracks) != 2 || lk.mutedTracks[1].Muted {
+		t.Errorf("Expected the microphone unmuted, got %+v", lk.mutedTracks)
+	}
+	<-roomEvents
+
+	// Revoking takes the camera and microphone, but not an allowed screen
+	if err := s.RevokeMedia(ctx, "room", "speaker", "mod"); err != nil {
+		t.Fatalf("Failed to revoke media: %v", err)
+	}
+	permission := lk.participants["speaker"].Permission
+	if sources := permission.CanPublishSources; len(sources) != 2 || sources[0] != livekit.TrackSource_SCREEN_SHARE {
+		t.Errorf("Expected only the screen left, got %v", sources)
+	}
+	if !mediaRevoked(lk.participants["speaker"]) {
+		t.Error("Expected the speaker's media reported revoked")
+	}
+	if event := <-roomEvents; event.Type != EventMediaRevoked || event.ActorID != "mod" {
+		t.Errorf("Expected a media_revoked event, got %+v", event)
+	}
+
+	if err := s.RestoreMedia(ctx, "room", "speaker", "mod"); err != nil {
+		t.Fatalf("Failed to restore media: %v", err)
+	}
+	if mediaRevoked(lk.participants["speaker"]) {
+		t.Error("Expected the speaker's media restored")
+	}
+	if event := <-roomEvents; event.Type != EventMediaRestored {
+		t.Errorf("Expected a media_restored event, got %+v", event)
+	}
+
+	if err := s.MuteParticipant(ctx, "room", "nobody", "mod"); !errors.Is(err, ErrParticipantNotFound) {
+		t.Errorf("Expected muting someone not in the room to fail, got %v", err)
+	}
+	if err := s.RevokeMedia(ctx, "room", "nobody", "mod"); !errors.Is(err, ErrParticipantNotFound) {
+		t.Errorf("Expected revoking someone not in the room to fail, got %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+	"github.com/penguintech/waddlebot/module_rtc/internal/hub"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+var (
+	ErrAlreadyRecording   = errors.New("already being recorded")
+	ErrRecordingNotFound  = errors.New("recording not found")
+	ErrRecordingNotActive = errors.New("recording has already ended")
+	ErrRecordingDisabled  = errors.New("recording is disabled in this room")
+)
+
+const (
+	RecordingKindRoom  = "room"
+	RecordingKindTrack = "track"
+
+	RecordingDestinationLocal = "local"
+	RecordingDestinationS3    = "s3"
+)
+
+// Recording statuses follow LiveKit's egress statuses.
+const (
+	RecordingStarting     = "starting"
+	RecordingActive       = "active"
+	RecordingEnding       = "ending"
+	RecordingComplete     = "complete"
+	RecordingFailed       = "failed"
+	RecordingAborted      = "aborted"
+	RecordingLimitReached = "limit_reached"
+)
+
+type Recording = storage.Recording
+
+// RecordingOutput is where egress writes recordings: a directory on the
+// egress service's disk, or an S3 bucket.
+type RecordingOutput struct {
+	Destination string
+	LocalDir    string
+	S3Bucket    string
+	S3Region    string
+	S3Endpoint  string
+	S3AccessKey string
+	S3Secret    string
+}
+
+// RecordingService records rooms and tracks with LiveKit egress and tracks
+// their status from egress webhooks.
+type RecordingService struct {
+	client *lksdk.EgressClient
+	output RecordingOutput
+	store  storage.Store
+	hub    *hub.Client
+	events *EventBus
+}
+
+func NewRecordingService(host, apiKey, apiSecret string, output RecordingOutput, store storage.Store, hubClient *hub.Client, events *EventBus) *RecordingService {
+	if output.Destination == "" {
+		output.Destination = RecordingDestinationLocal
+	}
+	return &RecordingService{
+		client: lksdk.NewEgressClient(host, apiKey, apiSecret),
+		output: output,
+		store:  store,
+		hub:    hubClient,
+		events: events,
+	}
+}
+
+// StartRoomRecording records the room's composite of all participants to
+// one file.
+func (s *RecordingService) StartRoomRecording(ctx context.Context, roomName, startedBy string) (*Recording, error) {
+	if err := s.checkCanRecord(ctx, roomName, ""); err != nil {
+		return nil, err
+	}
+
+	file := &livekit.EncodedFileOutput{
+		FileType: livekit.EncodedFileType_MP4,
+		Filepath: s.filepath("{room_name}-{time}"),
+	}
+	if s3 := s.s3Upload(); s3 != nil {
+		file.Output = &livekit.EncodedFileOutput_S3{S3: s3}
+	}
+
+	info, err := s.client.StartRoomCompositeEgress(ctx, &livekit.RoomCompositeEgressRequest{
+		RoomName:    roomName,
+		Layout:      "speaker",
+		FileOutputs: []*livekit.EncodedFileOutput{file},
+	})
+	metrics.ObserveLiveKit("StartRoomCompositeEgress", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start room recording: %w", err)
+	}
+	return s.started(ctx, info, RecordingKindRoom, "", startedBy)
+}
+
+// StartTrackRecording records one published track to its own file, without
+// transcoding.
+func (s *RecordingService) StartTrackRecording(ctx context.Context, roomName, trackID, startedBy string) (*Recording, error) {
+	if err := s.checkCanRecord(ctx, roomName, trackID); err != nil {
+		return nil, err
+	}
+
+	file := &livekit.DirectFileOutput{Filepath: s.filepath("{room_name}-{track_id}-{time}")}
+	if s3 := s.s3Upload(); s3 != nil {
+		file.Output = &livekit.DirectFileOutput_S3{S3: s3}
+	}
+
+	info, err := s.client.StartTrackEgress(ctx, &livekit.TrackEgressRequest{
+		RoomName: roomName,
+		TrackId:  trackID,
+		Output:   &livekit.TrackEgressRequest_File{File: file},
+	})
+	metrics.ObserveLiveKit("StartTrackEgress", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start track recording: %w", err)
+	}
+	return s.started(ctx, info, RecordingKindTrack, trackID, startedBy)
+}
+
+// StopRecording ends one of the room's recordings. Its file is finished and
+// reported once LiveKit says the egress has ended.
+func (s *RecordingService) StopRecording(ctx context.Context, roomName, egressID, stoppedBy string) (*Recording, error) {
+	recording, err := s.store.GetRecording(ctx, egressID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && recording.RoomName != roomName) {
+		return nil, ErrRecordingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if recordingEnded(recording.Status) {
+		return nil, ErrRecordingNotActive
+	}
+
+	info, err := s.client.StopEgress(ctx, &livekit.StopEgressRequest{EgressId: egressID})
+	metrics.ObserveLiveKit("StopEgress", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop recording: %w", err)
+	}
+	log.Printf("Recording %s of %s stopped by %s", egressID, roomName, stoppedBy)
+	return s.HandleEgressUpdate(ctx, info)
+}
+
+func (s *RecordingService) ListRecordings(ctx context.Context, roomName string) ([]*Recording, error) {
+	return s.store.ListRecordings(ctx, roomName)
+}
+
+// HandleEgressUpdate applies an egress's status from LiveKit to its
+// recording, reporting it to the hub once it ends. Egresses this service did
+// not start are ignored.
+func (s *RecordingService) HandleEgressUpdate(ctx context.Context, info *livekit.EgressInfo) (*Recording, error) {
+	recording, err := s.store.GetRecording(ctx, info.EgressId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	wasEnded := recordingEnded(recording.Status)
+	applyEgressInfo(recording, info)
+	if err := s.store.SaveRecording(ctx, recording); err != nil {
+		return nil, err
+	}
+
+	if recordingEnded(recording.Status) && !wasEnded {
+		s.events.Publish(RoomEvent{
+			Type:     EventRecordingEnded,
+			RoomName: recording.RoomName,
+			Data:     recordingEventData(recording),
+		})
+		s.report(ctx, recording)
+	}
+	return recording, nil
+}
+
+func (s *RecordingService) started(ctx context.Context, info *livekit.EgressInfo, kind, trackID, startedBy string) (*Recording, error) {
+	recording := &Recording{
+		EgressID:    info.EgressId,
+		RoomName:    info.RoomName,
+		Kind:        kind,
+		TrackID:     trackID,
+		Destination: s.output.Destination,
+		StartedBy:   startedBy,
+		StartedAt:   time.Now(),
+	}
+	applyEgressInfo(recording, info)
+	if err := s.store.SaveRecording(ctx, recording); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(RoomEvent{
+		Type:     EventRecordingStarted,
+		RoomName: recording.RoomName,
+		ActorID:  startedBy,
+		Data:     recordingEventData(recording),
+	})
+	return recording, nil
+}
+
+// checkCanRecord refuses recording a room whose template disabled it, and a
+// second recording of the room, or of the track if trackID is set.
+func (s *RecordingService) checkCanRecord(ctx context.Context, roomName, trackID string) error {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	if state.RecordingDisabled {
+		return ErrRecordingDisabled
+	}
+
+	recordings, err := s.store.ListRecordings(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	kind := RecordingKindRoom
+	if trackID != "" {
+		kind = RecordingKindTrack
+	}
+	for _, r := range recordings {
+		if r.Kind == kind && r.TrackID == trackID && !recordingEnded(r.Status) {
+			return ErrAlreadyRecording
+		}
+	}
+	return nil
+}
+
+func (s *RecordingService) filepath(name string) string {
+	if s.output.Destination == RecordingDestinationS3 {
+		return "recordings/" + name
+	}
+	return path.Join(s.output.LocalDir, name)
+}
+
+func (s *RecordingService) s3Upload() *livekit.S3Upload {
+	if s.output.Destination != RecordingDestinationS3 {
+		return nil
+	}
+	return &livekit.S3Upload{
+		AccessKey:      s.output.S3AccessKey,
+		Secret:         s.output.S3Secret,
+		Region:         s.output.S3Region,
+		Endpoint:       s.output.S3Endpoint,
+		Bucket:         s.output.S3Bucket,
+		ForcePathStyle: s.output.S3Endpoint != "",
+	}
+}
+
+// report sends an ended recording to the hub. Failures are logged so the
+// webhook that ended it is not retried.
+func (s *RecordingService) report(ctx context.Context, recording *Recording) {
+	if !s.hub.Enabled() {
+		return
+	}
+
+	communityID, err := lookupCommunityID(ctx, s.store, recording.RoomName)
+	if err != nil || communityID == 0 {
+		return
+	}
+
+	err = s.hub.ReportRecording(ctx, &hub.Recording{
+		CommunityID:     communityID,
+		RoomName:        recording.RoomName,
+		EgressID:        recording.EgressID,
+		Kind:            recording.Kind,
+		TrackID:         recording.TrackID,
+		Status:          recording.Status,
+		Destination:     recording.Destination,
+		Location:        recording.Location,
+		Filename:        recording.Filename,
+		SizeBytes:       recording.Size,
+		DurationSeconds: recording.DurationSeconds,
+		Error:           recording.Error,
+		StartedBy:       recording.StartedBy,
+		StartedAt:       recording.StartedAt,
+		EndedAt:         recording.EndedAt,
+	})
+	if err != nil {
+		log.Printf("Failed to report recording %s of %s to hub: %v", recording.EgressID, recording.RoomName, err)
+	}
+}
+
+func applyEgressInfo(recording *Recording, info *livekit.EgressInfo) {
+	recording.Status = strings.ToLower(strings.TrimPrefix(info.Status.String(), "EGRESS_"))
+	recording.Error = info.Error
+	if info.EndedAt > 0 {
+		endedAt := time.Unix(0, info.EndedAt)
+		recording.EndedAt = &endedAt
+	}
+
+	file := info.GetFile()
+	if len(info.FileResults) > 0 {
+		file = info.FileResults[0]
+	}
+	if file != nil {
+		recording.Filename = file.Filename
+		recording.Location = file.Location
+		recording.Size = file.Size
+		recording.DurationSeconds = file.Duration / int64(time.Second)
+	}
+}
+
+func recordingEnded(status string) bool {
+	switch status {
+	case RecordingComplete, RecordingFailed, RecordingAborted, RecordingLimitReached:
+		return true
+	}
+	return false
+}
+
+func recordingEventData(recording *Recording) map[string]string {
+	data := map[string]string{
+		"egress_id": recording.EgressID,
+		"kind":      recording.Kind,
+		"status":    recording.Status,
+	}
+	if recording.TrackID != "" {
+		data["track_id"] = recording.TrackID
+	}
+	return data
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/hub"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestRecordingService(t *testing.T) {
+	var reports []map[string]interface{}
+	hubServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/internal/rtc/recordings" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var report map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&report)
+		reports = append(reports, report)
+	}))
+	defer hubServer.Close()
+
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("community_7_stage")
+	defer unsubscribe()
+	s := NewRecordingService(url, "key", "secret", RecordingOutput{
+		Destination: RecordingDestinationS3,
+		S3Bucket:    "recordings",
+		S3Region:    "us-east-1",
+	}, store, hub.NewClient(hubServer.URL, "service"), events)
+
+	recording, err := s.StartRoomRecording(ctx, "community_7_stage", "mod")
+	if err != nil {
+		t.Fatalf("Failed to start recording: %v", err)
+	}
+	if recording.EgressID != "EG_1" || recording.Status != RecordingStarting || recording.Kind != RecordingKindRoom || recording.Destination != RecordingDestinationS3 {
+		t.Errorf("Unexpected recording: %+v", recording)
+	}
+	req := lk.egressCalls[0].(*livekit.RoomCompositeEgressRequest)
+	if s3 := req.FileOutputs[0].GetS3(); s3 == nil || s3.Bucket != "recordings" {
+		t.Errorf("Expected the recording to upload to S3, got %+v", req.FileOutputs[0])
+	}
+	if event := <-roomEvents; event.Type != EventRecordingStarted || event.Data["egress_id"] != "EG_1" || event.ActorID != "mod" {
+		t.Errorf("Expected a recording_started event, got %+v", event)
+	}
+
+	if _, err := s.StartRoomRecording(ctx, "community_7_stage", "mod"); !errors.Is(err, ErrAlreadyRecording) {
+		t.Errorf("Expected a second room recording to be refused, got %v", err)
+	}
+	track, err := s.StartTrackRecording(ctx, "community_7_stage", "TR_1", "mod")
+	if err != nil || track.Kind != RecordingKindTrack || track.TrackID != "TR_1" {
+		t.Fatalf("Expected a track recording alongside, got %+v, %v", track, err)
+	}
+	<-roomEvents
+
+	if _, err := s.StopRecording(ctx, "community_7_other", "EG_1", "mod"); !errors.Is(err, ErrRecordingNotFound) {
+		t.Errorf("Expected stopping another room's recording to fail, got %v", err)
+	}
+	stopped, err := s.StopRecording(ctx, "community_7_stage", "EG_1", "mod")
+	if err != nil || stopped.Status != RecordingEnding {
+		t.Fatalf("Expected the recording to be ending, got %+v, %v", stopped, err)
+	}
+	if len(reports) != 0 {
+		t.Error("Expected nothing reported before the egress ends")
+	}
+
+	// LiveKit's egress_ended webhook finishes the recording
+	endedAt := time.Now()
+	s.HandleEgressUpdate(ctx, &livekit.EgressInfo{
+		EgressId: "EG_1",
+		RoomName: "community_7_stage",
+		Status:   livekit.EgressStatus_EGRESS_COMPLETE,
+		EndedAt:  endedAt.UnixNano(),
+		FileResults: []*livekit.FileInfo{{
+			Filename: "recordings/community_7_stage-1.mp4",
+			Location: "https://recordings.s3.amazonaws.com/recordings/community_7_stage-1.mp4",
+			Size:     1024,
+			Duration: int64(90 * time.Second),
+		}},
+	})
+	recordings, _ := s.ListRecordings(ctx, "community_7_stage")
+	if len(recordings) != 2 {
+		t.Fatalf("Expected two recordings, got %d", len(recordings))
+	}
+	var done *Recording
+	for _, r := range recordings {
+		if r.EgressID == "EG_1" {
+			done = r
+		}
+	}
+	if done.Status != RecordingComplete || done.Size != 1024 || done.DurationSeconds != 90 || done.EndedAt == nil {
+		t.Errorf("Expected the finished file on the recording, got %+v", done)
+	}
+	if event := <-roomEvents; event.Type != EventRecordingEnded || event.Data["status"] != RecordingComplete {
+		t.Errorf("Expected a recording_ended event, got %+v", event)
+	}
+	if len(reports) != 1 || reports[0]["communityId"] != float64(7) || reports[0]["egressId"] != "EG_1" || reports[0]["sizeBytes"] != float64(1024) {
+		t.Fatalf("Expected the recording to be reported to the hub, got %+v", reports)
+	}
+
+	// A repeated webhook is not reported twice, and the room can be recorded again
+	s.HandleEgressUpdate(ctx, &livekit.EgressInfo{EgressId: "EG_1", Status: livekit.EgressStatus_EGRESS_COMPLETE})
+	if len(reports) != 1 {
+		t.Errorf("Expected one report, got %d", len(reports))
+	}
+	if _, err := s.StopRecording(ctx, "community_7_stage", "EG_1", "mod"); !errors.Is(err, ErrRecordingNotActive) {
+		t.Errorf("Expected stopping an ended recording to fail, got %v", err)
+	}
+	if _, err := s.StartRoomRecording(ctx, "community_7_stage", "mod"); err != nil {
+		t.Errorf("Expected a new room recording after the last ended, got %v", err)
+	}
+
+	if r, err := s.HandleEgressUpdate(ctx, &livekit.EgressInfo{EgressId: "EG_unknown"}); r != nil || err != nil {
+		t.Errorf("Expected egress started elsewhere to be ignored, got %+v, %v", r, err)
+	}
+}
+
+func TestRecordingService_LocalOutput(t *testing.T) {
+	lk, url := newFakeLiveKit(t)
+	s := NewRecordingService(url, "key", "secret", RecordingOutput{LocalDir: "/out/recordings"}, storage.NewMemoryStore(), nil, nil)
+
+	if _, err := s.StartTrackRecording(context.Background(), "stage", "TR_1", "mod"); err != nil {
+		t.Fatalf("Failed to start recording: %v", err)
+	}
+	file := lk.egressCalls[0].(*livekit.TrackEgressRequest).GetFile()
+	if file.Filepath != "/out/recordings/{room_name}-{track_id}-{time}" || file.GetS3() != nil {
+		t.Errorf("Expected a local file, got %+v", file)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+const (
+	RoleViewer    = "viewer"
+	RoleSpeaker   = "speaker"
+	RoleModerator = "moderator"
+	RoleHost      = "host"
+)
+
+var (
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidRoleChange = errors.New("role change is not a promotion or demotion")
+)
+
+// Participants are promoted and demoted along this ladder. Hosts only come
+// from joining as one.
+var roleLadder = []string{RoleViewer, RoleSpeaker, RoleModerator}
+
+// RoleRank orders roles from viewer up to host, returning -1 for unknown ones.
+func RoleRank(role string) int {
+	if role == RoleHost {
+		return len(roleLadder)
+	}
+	for i, r := range roleLadder {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+type RoleChange struct {
+	UserID       string `json:"user_id"`
+	Role         string `json:"role"`
+	PreviousRole string `json:"previous_role"`
+	InRoom       bool   `json:"in_room"`
+}
+
+// AssignedRole returns the role the user was promoted or demoted to in the
+// room, or the room's default role if they never were.
+func (s *CallFeaturesService) AssignedRole(ctx context.Context, roomName, userID string) (string, error) {
+	assignment, err := s.store.GetParticipantRole(ctx, roomName, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return s.defaultRole(ctx, roomName)
+	}
+	if err != nil {
+		return "", err
+	}
+	return assignment.Role, nil
+}
+
+// defaultRole is the role the room's template gives participants, viewer
+// unless it says otherwise.
+func (s *CallFeaturesService) defaultRole(ctx context.Context, roomName string) (string, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return "", err
+	}
+	if state.DefaultRole == "" {
+		return RoleViewer, nil
+	}
+	return state.DefaultRole, nil
+}
+
+// PromoteParticipant raises the user's role in the room to role, or one step
+// up the ladder if role is empty.
+func (s *CallFeaturesService) PromoteParticipant(ctx context.Context, roomName, userID, role, moderatorID string) (*RoleChange, error) {
+	return s.changeRole(ctx, roomName, userID, role, moderatorID, 1)
+}
+
+// DemoteParticipant lowers the user's role in the room to role, or one step
+// down the ladder if role is empty.
+func (s *CallFeaturesService) DemoteParticipant(ctx context.Context, roomName, userID, role, moderatorID string) (*RoleChange, error) {
+	return s.changeRole(ctx, roomName, userID, role, moderatorID, -1)
+}
+
+func (s *CallFeaturesService) changeRole(ctx context.Context, roomName, userID, role, moderatorID string, direction int) (*RoleChange, error) {
+	previous, err := s.AssignedRole(ctx, roomName, userID)
+	if err != nil {
+		return nil, err
+	}
+	// The role they joined with counts if they are in the room
+	if current, inRoom, err := s.roomService.ParticipantRole(ctx, roomName, userID); err != nil {
+		return nil, err
+	} else if inRoom {
+		previous = current
+	}
+	if previous == RolePhone {
+		return nil, ErrInvalidRoleChange
+	}
+
+	if role == "" {
+		next := RoleRank(previous) + direction
+		if next < 0 || next >= len(roleLadder) {
+			return nil, ErrInvalidRoleChange
+		}
+		role = roleLadder[next]
+	}
+	if role == RoleHost || RoleRank(role) < 0 {
+		return nil, ErrInvalidRole
+	}
+	if (RoleRank(role)-RoleRank(previous))*direction <= 0 {
+		return nil, ErrInvalidRoleChange
+	}
+
+	inRoom, err := s.roomService.UpdateParticipantRole(ctx, roomName, userID, role, previous, moderatorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetParticipantRole(ctx, roomName, &storage.RoleAssignment{
+		UserID:     userID,
+		Role:       role,
+		AssignedBy: moderatorID,
+		UpdatedAt:  time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(RoomEvent{
+		Type:     EventRoleChanged,
+		RoomName: roomName,
+		UserID:   userID,
+		ActorID:  moderatorID,
+		Data:     map[string]string{"role": role, "previous_role": previous},
+	})
+	return &RoleChange{UserID: userID, Role: role, PreviousRole: previous, InRoom: inRoom}, nil
+}
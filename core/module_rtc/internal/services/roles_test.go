@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestCallFeaturesService_ChangeRole(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	lk.participants["u1"] = &livekit.ParticipantInfo{Sid: "PA_1", Identity: "u1", Metadata: `{"role":"viewer"}`}
+
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+	s := NewCallFeaturesService(NewRoomService(url, "key", "secret", store, events), store, nil, events)
+
+	change, err := s.PromoteParticipant(ctx, "room", "u1", "", "mod")
+	if err != nil {
+		t.Fatalf("Failed to promote: %v", err)
+	}
+	if change.Role != RoleSpeaker || change.PreviousRole != RoleViewer || !change.InRoom {
+		t.Errorf("Expected a viewer promoted to speaker in the room, got %+v", change)
+	}
+	if p := lk.participants["u1"]; !p.Permission.CanPublish || p.Permission.CanPublishData || p.Metadata != `{"role":"speaker"}` {
+		t.Errorf("Expected speaker permissions in LiveKit, got %+v, %s", p.Permission, p.Metadata)
+	}
+	if len(lk.sent) != 1 || lk.sent[0].DestinationSids[0] != "PA_1" || lk.sent[0].GetTopic() != RoleNoticeTopic {
+		t.Fatalf("Expected the participant to be notified, got %+v", lk.sent)
+	}
+	var notice map[string]string
+	json.Unmarshal(lk.sent[0].Data, &notice)
+	if notice["role"] != RoleSpeaker || notice["changed_by"] != "mod" {
+		t.Errorf("Unexpected notice: %+v", notice)
+	}
+	if event := <-roomEvents; event.Type != EventRoleChanged || event.Data["role"] != RoleSpeaker || event.ActorID != "mod" {
+		t.Errorf("Expected a role_changed event, got %+v", event)
+	}
+
+	if change, _ := s.PromoteParticipant(ctx, "room", "u1", "", "mod"); change == nil || change.Role != RoleModerator {
+		t.Errorf("Expected a promotion to moderator, got %+v", change)
+	}
+	if _, err := s.PromoteParticipant(ctx, "room", "u1", "", "mod"); !errors.Is(err, ErrInvalidRoleChange) {
+		t.Errorf("Expected no promotion past moderator, got %v", err)
+	}
+	if _, err := s.PromoteParticipant(ctx, "room", "u1", RoleSpeaker, "mod"); !errors.Is(err, ErrInvalidRoleChange) {
+		t.Errorf("Expected promoting to a lower role to be refused, got %v", err)
+	}
+
+	if change, err := s.DemoteParticipant(ctx, "room", "u1", RoleViewer, "mod"); err != nil || change.PreviousRole != RoleModerator {
+		t.Errorf("Expected a demotion from moderator, got %+v, %v", change, err)
+	}
+	if lk.participants["u1"].Permission.CanPublish {
+		t.Error("Expected a viewer not to publish")
+	}
+	if _, err := s.DemoteParticipant(ctx, "room", "u1", "", "mod"); !errors.Is(err, ErrInvalidRoleChange) {
+		t.Errorf("Expected no demotion below viewer, got %v", err)
+	}
+	if role, _ := s.AssignedRole(ctx, "room", "u1"); role != RoleViewer {
+		t.Errorf("Expected viewer to be stored, got %s", role)
+	}
+}
+
+func TestCallFeaturesService_ChangeRoleOutsideRoom(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	s := NewCallFeaturesService(NewRoomService(url, "key", "secret", store, nil), store, nil, nil)
+
+	if _, err := s.PromoteParticipant(ctx, "room", "u2", RoleHost, "mod"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("Expected promoting to host to be refused, got %v", err)
+	}
+
+	change, err := s.PromoteParticipant(ctx, "room", "u2", RoleModerator, "mod")
+	if err != nil || change.InRoom {
+		t.Fatalf("Expected the role to be stored for later, got %+v, %v", change, err)
+	}
+	if len(lk.sent) != 0 {
+		t.Error("Expected nobody to be notified")
+	}
+
+	// The role is kept for when they join
+	if role, _ := s.AssignedRole(ctx, "room", "u2"); role != RoleModerator {
+		t.Errorf("Expected moderator to be stored, got %s", role)
+	}
+	if role, _ := NewCallFeaturesService(nil, store, nil, nil).AssignedRole(ctx, "other", "u2"); role != RoleViewer {
+		t.Errorf("Expected roles to be per room, got %s", role)
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+// Settings of a room's config, named in room_config_changed events as the
+// one that changed.
+const (
+	ConfigLocked          = "locked"
+	ConfigMaxParticipants = "max_participants"
+	ConfigScreenShare     = "screen_share"
+	ConfigSlowMode        = "slow_mode_seconds"
+	ConfigHandExpiry      = "hand_expiry_minutes"
+	ConfigAudio           = "audio"
+)
+
+// RoomConfig is how moderators have set a room up, which clients already in
+// it need to follow as it changes. It is kept in the room's LiveKit
+// metadata, so clients learn of changes from LiveKit's room metadata
+// updates.
+type RoomConfig struct {
+	Locked            bool          `json:"locked"`
+	MaxParticipants   uint32        `json:"max_participants"`
+	ScreenShare       string        `json:"screen_share"`
+	Lobby             bool          `json:"lobby"`
+	SlowModeSeconds   int           `json:"slow_mode_seconds"`
+	HandExpiryMinutes int           `json:"hand_expiry_minutes"`
+	Audio             AudioSettings `json:"audio"`
+}
+
+// RoomConfig returns the room's config as stored.
+func (s *RoomService) RoomConfig(ctx context.Context, roomName string) (*RoomConfig, error) {
+	config := &RoomConfig{}
+	record, err := s.store.GetRoom(ctx, roomName)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if record != nil {
+		config.MaxParticipants = record.MaxParticipants
+	}
+
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	config.Locked = state.IsLocked
+	config.ScreenShare = screenShareOrDefault(state.ScreenShare)
+	config.Lobby = state.Lobby
+	config.SlowModeSeconds = state.SlowModeSeconds
+	config.HandExpiryMinutes = state.HandExpiryMinutes
+	config.Audio = audioOrDefault(state.Audio)
+	return config, nil
+}
+
+// SetMaxParticipants changes how many may be in the room at once. Those
+// already in it stay even if there are more of them than the new limit, but
+// no one else can join until enough have left.
+func (s *RoomService) SetMaxParticipants(ctx context.Context, roomName string, maxParticipants uint32, moderatorID string) error {
+	if maxParticipants < 1 || maxParticipants > maxRoomParticipants {
+		return fmt.Errorf("%w: max_participants must be between 1 and %d", ErrInvalidRoom, maxRoomParticipants)
+	}
+	record, err := s.store.GetRoom(ctx, roomName)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrRoomNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// LiveKit takes a new limit for a room that is already open when it is
+	// created again
+	open, err := s.openRoom(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	if open != nil {
+		_, err := s.client.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: roomName, MaxParticipants: maxParticipants})
+		metrics.ObserveLiveKit("CreateRoom", err)
+		if err != nil {
+			return fmt.Errorf("failed to update room: %w", err)
+		}
+	}
+
+	record.MaxParticipants = maxParticipants
+	if err := s.store.SaveRoom(ctx, record); err != nil {
+		return err
+	}
+	s.PublishRoomConfig(ctx, roomName, ConfigMaxParticipants, moderatorID)
+	return nil
+}
+
+// PublishRoomConfig puts the room's config in its LiveKit metadata and
+// publishes a room_config_changed event naming the setting that changed.
+// The setting is already saved by then, so failures are only logged. A nil
+// RoomService, as services without LiveKit have, publishes nothing.
+func (s *RoomService) PublishRoomConfig(ctx context.Context, roomName, changed, actorID string) {
+	if s == nil {
+		return
+	}
+	config, err := s.RoomConfig(ctx, roomName)
+	if err != nil {
+		log.Printf("Failed to get config of %s: %v", roomName, err)
+		return
+	}
+	if err := s.updateRoomConfig(ctx, roomName, config); err != nil {
+		log.Printf("Failed to update metadata of %s: %v", roomName, err)
+	}
+
+	s.events.Publish(RoomEvent{Type: EventRoomConfigChanged, RoomName: roomName, ActorID: actorID, Data: map[string]string{
+		"changed":             changed,
+		"locked":              strconv.FormatBool(config.Locked),
+		"max_participants":    strconv.FormatUint(uint64(config.MaxParticipants), 10),
+		"screen_share":        config.ScreenShare,
+		"lobby":               strconv.FormatBool(config.Lobby),
+		"slow_mode_seconds":   strconv.Itoa(config.SlowModeSeconds),
+		"hand_expiry_minutes": strconv.Itoa(config.HandExpiryMinutes),
+		"noise_suppression":   config.Audio.NoiseSuppression,
+		"echo_cancellation":   strconv.FormatBool(config.Audio.EchoCancellation),
+		"auto_gain_control":   strconv.FormatBool(config.Audio.AutoGainControl),
+		"red":                 strconv.FormatBool(config.Audio.RED),
+		"dtx":                 strconv.FormatBool(config.Audio.DTX),
+	}})
+}
+
+// updateRoomConfig replaces the config in the metadata of the room, if it
+// is open, keeping the rest of the metadata.
+func (s *RoomService) updateRoomConfig(ctx context.Context, roomName string, config *RoomConfig) error {
+	room, err := s.openRoom(ctx, roomName)
+	if room == nil || err != nil {
+		return err
+	}
+
+	var metadata roomMetadata
+	if room.Metadata != "" {
+		if err := json.Unmarshal([]byte(room.Metadata), &metadata); err != nil {
+			return fmt.Errorf("failed to read room metadata: %w", err)
+		}
+	}
+	metadata.Config = config
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.UpdateRoomMetadata(ctx, &livekit.UpdateRoomMetadataRequest{Room: roomName, Metadata: string(data)})
+	metrics.ObserveLiveKit("UpdateRoomMetadata", err)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// openRoom returns the room as LiveKit has it, or nil if LiveKit has no
+// such room open.
+func (s *RoomService) openRoom(ctx context.Context, roomName string) (*livekit.Room, error) {
+	resp, err := s.client.ListRooms(ctx, &livekit.ListRoomsRequest{Names: []string{roomName}})
+	metrics.ObserveLiveKit("ListRooms", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+	if len(resp.Rooms) == 0 {
+		return nil, nil
+	}
+	return resp.Rooms[0], nil
+}
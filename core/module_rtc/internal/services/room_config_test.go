@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestRoomService_PublishRoomConfig(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	s := NewRoomService(url, "key", "secret", store, events)
+	features := NewCallFeaturesService(s, store, nil, events)
+
+	if _, err := s.SaveTemplate(ctx, &RoomTemplate{CommunityID: 1, Name: "stage", Codecs: []string{"audio/opus", "video/vp8"}, IsDefault: true}); err != nil {
+		t.Fatalf("Failed to save template: %v", err)
+	}
+	room, err := s.CreateRoom(ctx, 1, "stage", "", 50)
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	roomEvents, unsubscribe := events.Subscribe(room.RoomName)
+	defer unsubscribe()
+
+	metadata := func() roomMetadata {
+		var m roomMetadata
+		if err := json.Unmarshal([]byte(lk.rooms[room.RoomName].Metadata), &m); err != nil || m.Config == nil {
+			t.Fatalf("Expected the room's config in its metadata, got %q", lk.rooms[room.RoomName].Metadata)
+		}
+		return m
+	}
+	if config := metadata().Config; config.Locked || config.MaxParticipants != 50 || config.ScreenShare != ScreenShareSpeakers {
+		t.Errorf("Expected the room created with its config, got %+v", config)
+	}
+
+	if err := features.LockRoom(ctx, room.RoomName, "mod"); err != nil {
+		t.Fatalf("Failed to lock room: %v", err)
+	}
+	if m := metadata(); !m.Config.Locked || len(m.Codecs) != 2 {
+		t.Errorf("Expected the lock in the metadata with the codecs kept, got %+v", m)
+	}
+	<-roomEvents
+	if event := <-roomEvents; event.Type != EventRoomConfigChanged || event.Data["changed"] != ConfigLocked || event.Data["locked"] != "true" || event.ActorID != "mod" {
+		t.Errorf("Expected a room_config_changed event for the lock, got %+v", event)
+	}
+
+	if err := s.SetMaxParticipants(ctx, room.RoomName, 10, "mod"); err != nil {
+		t.Fatalf("Failed to set max participants: %v", err)
+	}
+	if lk.rooms[room.RoomName].MaxParticipants != 10 || metadata().Config.MaxParticipants != 10 {
+		t.Errorf("Expected LiveKit to hold the room to the new limit, got %+v", lk.rooms[room.RoomName])
+	}
+	if record, _ := store.GetRoom(ctx, room.RoomName); record.MaxParticipants != 10 {
+		t.Errorf("Expected the new limit stored, got %d", record.MaxParticipants)
+	}
+	if event := <-roomEvents; event.Data["changed"] != ConfigMaxParticipants || event.Data["max_participants"] != "10" || event.Data["locked"] != "true" {
+		t.Errorf("Expected a room_config_changed event with the whole config, got %+v", event)
+	}
+
+	for _, max := range []uint32{0, maxRoomParticipants + 1} {
+		if err := s.SetMaxParticipants(ctx, room.RoomName, max, "mod"); !errors.Is(err, ErrInvalidRoom) {
+			t.Errorf("Expected a limit of %d refused, got %v", max, err)
+		}
+	}
+	if err := s.SetMaxParticipants(ctx, "community_1_missing", 10, "mod"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected an unknown room to be not found, got %v", err)
+	}
+
+	// A room LiveKit has closed keeps the limit for when it is opened again
+	delete(lk.rooms, room.RoomName)
+	if err := s.SetMaxParticipants(ctx, room.RoomName, 20, "mod"); err != nil {
+		t.Fatalf("Failed to set max participants of a closed room: %v", err)
+	}
+	if _, open := lk.rooms[room.RoomName]; open {
+		t.Error("Expected a closed room not to be opened")
+	}
+	if config, _ := s.RoomConfig(ctx, room.RoomName); config.MaxParticipants != 20 || !config.Locked {
+		t.Errorf("Expected the stored config, got %+v", config)
+	}
+}
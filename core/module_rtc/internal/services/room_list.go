@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+)
+
+// States a listed room can be filtered on. A room is active while anyone is
+// in it, and recording while a recording of it is running.
+const (
+	RoomActive    = "active"
+	RoomLocked    = "locked"
+	RoomRecording = "recording"
+)
+
+// Orders listed rooms can be sorted in.
+const (
+	SortRoomsByCreated      = "created_at"
+	SortRoomsByName         = "name"
+	SortRoomsByParticipants = "participants"
+)
+
+const (
+	DefaultRoomPageSize = 50
+	maxRoomPageSize     = 200
+)
+
+var ErrInvalidRoomFilter = errors.New("invalid room filter")
+
+// RoomFilter picks a page of a community's rooms. A room must be in every
+// state listed to be returned. Rooms are sorted oldest first by default.
+type RoomFilter struct {
+	States     []string
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+}
+
+// RoomPage is a page of a community's rooms. Total counts every room
+// matching the filter, not only those on the page.
+type RoomPage struct {
+	Rooms  []*RoomInfo `json:"rooms"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// ListRooms returns a page of the community's rooms, including those
+// LiveKit has closed since everyone left.
+func (s *RoomService) ListRooms(ctx context.Context, communityID int, filter RoomFilter) (*RoomPage, error) {
+	if err := checkRoomFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	records, err := s.store.ListRooms(ctx, communityID)
+	if err != nil {
+		return nil, err
+	}
+	page := &RoomPage{Rooms: []*RoomInfo{}, Limit: filter.Limit, Offset: filter.Offset}
+	if len(records) == 0 {
+		return page, nil
+	}
+
+	names := make([]string, len(records))
+	for i, record := range records {
+		names[i] = record.RoomName
+	}
+	resp, err := s.client.ListRooms(ctx, &livekit.ListRoomsRequest{Names: names})
+	metrics.ObserveLiveKit("ListRooms", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rooms: %w", err)
+	}
+	open := make(map[string]*livekit.Room, len(resp.Rooms))
+	for _, room := range resp.Rooms {
+		open[room.Name] = room
+	}
+
+	rooms := []*RoomInfo{}
+	for _, record := range records {
+		info := &RoomInfo{
+			RoomID:      record.RoomID,
+			RoomName:    record.RoomName,
+			CommunityID: record.CommunityID,
+			CreatedAt:   record.CreatedAt,
+		}
+		state, err := s.store.GetRoomState(ctx, record.RoomName)
+		if err != nil {
+			return nil, err
+		}
+		info.IsLocked = state.IsLocked
+		info.ScreenShare = screenShareOrDefault(state.ScreenShare)
+		info.Template = state.Template
+		info.Lobby = state.Lobby
+
+		// Only rooms LiveKit has open can be recording
+		if room, ok := open[record.RoomName]; ok {
+			info.RoomID = room.Sid
+			info.Participants = int(room.NumParticipants)
+			info.Active = room.NumParticipants > 0
+			if info.Recording, err = s.recording(ctx, record.RoomName); err != nil {
+				return nil, err
+			}
+		}
+
+		if roomInStates(info, filter.States) {
+			rooms = append(rooms, info)
+		}
+	}
+
+	sortRooms(rooms, filter.Sort, filter.Descending)
+	page.Total = len(rooms)
+	if filter.Offset < len(rooms) {
+		rooms = rooms[filter.Offset:]
+		if len(rooms) > filter.Limit {
+			rooms = rooms[:filter.Limit]
+		}
+		page.Rooms = rooms
+	}
+	return page, nil
+}
+
+// recording reports whether a recording of the room is running.
+func (s *RoomService) recording(ctx context.Context, roomName string) (bool, error) {
+	recordings, err := s.store.ListRecordings(ctx, roomName)
+	if err != nil {
+		return false, err
+	}
+	for _, recording := range recordings {
+		if recording.Status == RecordingStarting || recording.Status == RecordingActive {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkRoomFilter checks the filter, filling in its defaults.
+func checkRoomFilter(filter *RoomFilter) error {
+	for _, state := range filter.States {
+		switch state {
+		case RoomActive, RoomLocked, RoomRecording:
+		default:
+			return fmt.Errorf("%w: state must be active, locked or recording", ErrInvalidRoomFilter)
+		}
+	}
+	switch filter.Sort {
+	case "":
+		filter.Sort = SortRoomsByCreated
+	case SortRoomsByCreated, SortRoomsByName, SortRoomsByParticipants:
+	default:
+		return fmt.Errorf("%w: sort must be created_at, name or participants", ErrInvalidRoomFilter)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultRoomPageSize
+	}
+	if filter.Limit < 1 || filter.Limit > maxRoomPageSize {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidRoomFilter, maxRoomPageSize)
+	}
+	if filter.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidRoomFilter)
+	}
+	return nil
+}
+
+func roomInStates(room *RoomInfo, states []string) bool {
+	for _, state := range states {
+		switch {
+		case state == RoomActive && !room.Active,
+			state == RoomLocked && !room.IsLocked,
+			state == RoomRecording && !room.Recording:
+			return false
+		}
+	}
+	return true
+}
+
+// sortRooms sorts the rooms by the field, breaking ties by name so pages
+// stay stable.
+func sortRooms(rooms []*RoomInfo, by string, descending bool) {
+	sort.Slice(rooms, func(i, j int) bool {
+		a, b := rooms[i], rooms[j]
+		if descending {
+			a, b = b, a
+		}
+		switch {
+		case by == SortRoomsByCreated && !a.CreatedAt.Equal(b.CreatedAt):
+			return a.CreatedAt.Before(b.CreatedAt)
+		case by == SortRoomsByParticipants && a.Participants != b.Participants:
+			return a.Participants < b.Participants
+		}
+		return a.RoomName < b.RoomName
+	})
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func roomNames(page *RoomPage) []string {
+	names := []string{}
+	for _, room := range page.Rooms {
+		names = append(names, room.RoomName)
+	}
+	return names
+}
+
+func TestRoomService_ListRooms(t *testing.T) {
+	ctx := context.Background()
+	lk, lkURL := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	s := NewRoomService(lkURL, "key", "secret", store, nil)
+
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{"community_7_stage", "community_7_lounge", "community_7_archive", "community_7_office"} {
+		store.SaveRoom(ctx, &storage.Room{RoomName: name, RoomID: "RM_" + name, CommunityID: 7, CreatedAt: created.Add(time.Duration(i) * time.Hour)})
+	}
+	store.SaveRoom(ctx, &storage.Room{RoomName: "community_8_stage", CommunityID: 8, CreatedAt: created})
+
+	// LiveKit has closed the archive; the office is open but empty
+	lk.rooms["community_7_stage"] = &livekit.Room{Sid: "RM_stage", Name: "community_7_stage", NumParticipants: 12}
+	lk.rooms["community_7_lounge"] = &livekit.Room{Sid: "RM_lounge", Name: "community_7_lounge", NumParticipants: 3}
+	lk.rooms["community_7_office"] = &livekit.Room{Sid: "RM_office", Name: "community_7_office"}
+	store.SaveRoomState(ctx, &storage.RoomState{RoomName: "community_7_lounge", IsLocked: true})
+	store.SaveRoomState(ctx, &storage.RoomState{RoomName: "community_7_archive", IsLocked: true})
+	store.SaveRecording(ctx, &storage.Recording{EgressID: "EG_1", RoomName: "community_7_stage", Status: RecordingActive})
+	store.SaveRecording(ctx, &storage.Recording{EgressID: "EG_2", RoomName: "community_7_lounge", Status: RecordingComplete})
+
+	page, err := s.ListRooms(ctx, 7, RoomFilter{})
+	if err != nil {
+		t.Fatalf("Failed to list rooms: %v", err)
+	}
+	if got := roomNames(page); len(got) != 4 || got[0] != "community_7_stage" || got[3] != "community_7_office" || page.Total != 4 || page.Limit != DefaultRoomPageSize {
+		t.Fatalf("Expected the community's rooms oldest first, got %v of %d", got, page.Total)
+	}
+	stage := page.Rooms[0]
+	if !stage.Active || !stage.Recording || stage.Participants != 12 || stage.RoomID != "RM_stage" {
+		t.Errorf("Expected the stage to be active and recording, got %+v", stage)
+	}
+	if archive := page.Rooms[2]; archive.Active || !archive.IsLocked || archive.RoomID != "RM_community_7_archive" {
+		t.Errorf("Expected the closed archive from its stored record, got %+v", archive)
+	}
+	if office := page.Rooms[3]; office.Active {
+		t.Errorf("Expected an empty room not to be active, got %+v", office)
+	}
+
+	for _, tc := range []struct {
+		filter RoomFilter
+		want   []string
+		total  int
+	}{
+		{RoomFilter{States: []string{RoomActive}}, []string{"community_7_stage", "community_7_lounge"}, 2},
+		{RoomFilter{States: []string{RoomActive, RoomLocked}}, []string{"community_7_lounge"}, 1},
+		{RoomFilter{States: []string{RoomRecording}}, []string{"community_7_stage"}, 1},
+		{RoomFilter{Sort: SortRoomsByName}, []string{"community_7_archive", "community_7_lounge", "community_7_office", "community_7_stage"}, 4},
+		{RoomFilter{Sort: SortRoomsByParticipants, Descending: true, Limit: 2}, []string{"community_7_stage", "community_7_lounge"}, 4},
+		{RoomFilter{Sort: SortRoomsByCreated, Descending: true, Limit: 2, Offset: 1}, []string{"community_7_archive", "community_7_lounge"}, 4},
+		{RoomFilter{Offset: 10}, []string{}, 4},
+	} {
+		page, err := s.ListRooms(ctx, 7, tc.filter)
+		if err != nil {
+			t.Fatalf("Failed to list rooms with %+v: %v", tc.filter, err)
+		}
+		got := roomNames(page)
+		if len(got) != len(tc.want) || page.Total != tc.total {
+			t.Errorf("Expected %v of %d with %+v, got %v of %d", tc.want, tc.total, tc.filter, got, page.Total)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("Expected %v with %+v, got %v", tc.want, tc.filter, got)
+				break
+			}
+		}
+	}
+
+	for _, filter := range []RoomFilter{
+		{States: []string{"open"}},
+		{Sort: "size"},
+		{Limit: maxRoomPageSize + 1},
+		{Offset: -1},
+	} {
+		if _, err := s.ListRooms(ctx, 7, filter); !errors.Is(err, ErrInvalidRoomFilter) {
+			t.Errorf("Expected %+v to be refused, got %v", filter, err)
+		}
+	}
+
+	if page, err := s.ListRooms(ctx, 9, RoomFilter{}); err != nil || page.Total != 0 || page.Rooms == nil {
+		t.Errorf("Expected no rooms for a community without any, got %+v, %v", page, err)
+	}
+}
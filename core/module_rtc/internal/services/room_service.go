@@ -2,19 +2,63 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"regexp"
 	"time"
 
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	lksdk "github.com/livekit/server-sdk-go"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+	"github.com/twitchtv/twirp"
 )
 
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrInvalidRoom  = errors.New("invalid room")
+)
+
+// Room names are a community's name for the room when it is created, and
+// the full LiveKit name, with the community and any breakout prefixed, after.
+const (
+	maxRoomNameLength     = 64
+	maxFullRoomNameLength = 255
+)
+
+var roomNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidRoomName reports whether a full room name could name a room.
+func ValidRoomName(name string) bool {
+	return len(name) <= maxFullRoomNameLength && roomNamePattern.MatchString(name)
+}
+
+// checkRoomName checks a name a community gave a room.
+func checkRoomName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("room_name is required")
+	case len(name) > maxRoomNameLength:
+		return fmt.Errorf("room_name must be at most %d characters", maxRoomNameLength)
+	case !roomNamePattern.MatchString(name):
+		return errors.New("room_name may only contain letters, digits, underscores and hyphens, and must start with a letter or digit")
+	}
+	return nil
+}
+
+// RoleNoticeTopic is the data topic participants are told of role changes on.
+const RoleNoticeTopic = "waddlebot.role"
+
 type RoomService struct {
 	client    *lksdk.RoomServiceClient
 	apiKey    string
 	apiSecret string
 	host      string
+	store     storage.Store
+	events    *EventBus
 }
 
 type RoomInfo struct {
@@ -24,92 +68,190 @@ type RoomInfo struct {
 	Participants int       `json:"participants"`
 	CreatedAt    time.Time `json:"created_at"`
 	IsLocked     bool      `json:"is_locked"`
+	ScreenShare  string    `json:"screen_share"`
+	Template     string    `json:"template,omitempty"`
+	Lobby        bool      `json:"lobby"`
+	Active       bool      `json:"active"`
+	Recording    bool      `json:"recording"`
 }
 
+// ParticipantInfo is a participant as LiveKit sees them. IsMuted means they
+// publish no unmuted microphone, and MediaRevoked that a moderator took away
+// the camera and microphone their role gives.
 type ParticipantInfo struct {
-	UserID   string `json:"user_id"`
-	Identity string `json:"identity"`
-	Role     string `json:"role"`
-	JoinedAt int64  `json:"joined_at"`
-	IsMuted  bool   `json:"is_muted"`
+	UserID       string        `json:"user_id"`
+	Identity     string        `json:"identity"`
+	Role         string        `json:"role"`
+	JoinedAt     int64         `json:"joined_at"`
+	IsMuted      bool          `json:"is_muted"`
+	MediaRevoked bool          `json:"media_revoked"`
+	InLobby      bool          `json:"in_lobby"`
+	Tracks       []*TrackState `json:"tracks"`
 }
 
+// JoinToken is what a client needs to join a room, with the audio settings
+// it should publish with. The token must be used to connect before
+// ExpiresAt.
 type JoinToken struct {
-	Token    string `json:"token"`
-	RoomName string `json:"room_name"`
-	Identity string `json:"identity"`
+	Token     string        `json:"token"`
+	RoomName  string        `json:"room_name"`
+	Identity  string        `json:"identity"`
+	Lobby     bool          `json:"lobby"`
+	Audio     AudioSettings `json:"audio"`
+	ExpiresAt time.Time     `json:"expires_at"`
 }
 
-func NewRoomService(host, apiKey, apiSecret string) *RoomService {
+func NewRoomService(host, apiKey, apiSecret string, store storage.Store, events *EventBus) *RoomService {
 	client := lksdk.NewRoomServiceClient(host, apiKey, apiSecret)
 	return &RoomService{
 		client:    client,
 		apiKey:    apiKey,
 		apiSecret: apiSecret,
 		host:      host,
+		store:     store,
+		events:    events,
 	}
 }
 
-func (s *RoomService) CreateRoom(ctx context.Context, communityID int, roomName string, maxParticipants uint32) (*RoomInfo, error) {
-	fullRoomName := fmt.Sprintf("community_%d_%s", communityID, roomName)
+// CreateRoom creates a room from the community's template of the name, or
+// its default template if templateName is empty. A maxParticipants other
+// than zero overrides the template's.
+func (s *RoomService) CreateRoom(ctx context.Context, communityID int, roomName, templateName string, maxParticipants uint32) (*RoomInfo, error) {
+	if err := checkRoomName(roomName); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRoom, err)
+	}
+	if maxParticipants > maxRoomParticipants {
+		return nil, fmt.Errorf("%w: max_participants must be at most %d", ErrInvalidRoom, maxRoomParticipants)
+	}
+	template, err := s.Template(ctx, communityID, templateName)
+	if err != nil {
+		return nil, err
+	}
+	return s.createRoom(ctx, communityID, fmt.Sprintf("community_%d_%s", communityID, roomName), maxParticipants, template)
+}
 
-	room, err := s.client.CreateRoom(ctx, &livekit.CreateRoomRequest{
-		Name:            fullRoomName,
-		MaxParticipants: maxParticipants,
-		EmptyTimeout:    300,
-	})
+// createRoom creates a room with its full LiveKit name from the template,
+// which may be nil.
+func (s *RoomService) createRoom(ctx context.Context, communityID int, fullRoomName string, maxParticipants uint32, template *RoomTemplate) (*RoomInfo, error) {
+	req := roomSettings(template, maxParticipants)
+	req.Name = fullRoomName
+	room, err := s.client.CreateRoom(ctx, req)
+	metrics.ObserveLiveKit("CreateRoom", err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create room: %w", err)
 	}
 
-	return &RoomInfo{
+	record := &storage.Room{
+		RoomName:        room.Name,
+		RoomID:          room.Sid,
+		CommunityID:     communityID,
+		MaxParticipants: req.MaxParticipants,
+		CreatedAt:       time.Now(),
+	}
+	if err := s.store.SaveRoom(ctx, record); err != nil {
+		return nil, err
+	}
+	info := &RoomInfo{
 		RoomID:       room.Sid,
 		RoomName:     room.Name,
 		CommunityID:  communityID,
 		Participants: 0,
-		CreatedAt:    time.Now(),
+		CreatedAt:    record.CreatedAt,
 		IsLocked:     false,
-	}, nil
+		ScreenShare:  ScreenShareSpeakers,
+	}
+	if template != nil {
+		if err := s.applyTemplate(ctx, room.Name, template); err != nil {
+			return nil, err
+		}
+		info.Template = template.Name
+		info.Lobby = template.Lobby
+	}
+	s.events.Publish(RoomEvent{Type: EventRoomCreated, RoomName: room.Name})
+
+	return info, nil
 }
 
 func (s *RoomService) JoinRoom(ctx context.Context, roomName, userID, userName, role string) (*JoinToken, error) {
-	at := auth.NewAccessToken(s.apiKey, s.apiSecret)
+	screenShare, err := s.ScreenShare(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	return s.joinToken(ctx, roomName, userID, userName, role, rolePermission(role, screenShare), roleMetadata(role))
+}
 
-	canPublish := role == "host" || role == "moderator" || role == "speaker"
-	canSubscribe := true
-	canPublishData := role == "host" || role == "moderator"
+func (s *RoomService) joinToken(ctx context.Context, roomName, userID, userName, role string, permission *livekit.ParticipantPermission, metadata string) (*JoinToken, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	ttl := tokenTTLOrDefault(state.TokenTTLSeconds)
+
+	at := auth.NewAccessToken(s.apiKey, s.apiSecret)
 
 	grant := &auth.VideoGrant{
 		RoomJoin:       true,
 		Room:           roomName,
-		CanPublish:     &canPublish,
-		CanSubscribe:   &canSubscribe,
-		CanPublishData: &canPublishData,
+		CanPublish:     &permission.CanPublish,
+		CanSubscribe:   &permission.CanSubscribe,
+		CanPublishData: &permission.CanPublishData,
 	}
+	grant.SetCanPublishSources(permission.CanPublishSources)
 
 	at.AddGrant(grant).
 		SetIdentity(userID).
 		SetName(userName).
-		SetValidFor(24 * time.Hour).
-		SetMetadata(fmt.Sprintf(`{"role":"%s"}`, role))
+		SetValidFor(ttl).
+		SetMetadata(metadata)
 
+	issuedAt := time.Now()
 	token, err := at.ToJWT()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
+	// Tokens no record was kept of cannot be revoked, so none are handed out
+	requester := tokenRequester(ctx)
+	issued := &IssuedToken{
+		RoomName:    roomName,
+		Identity:    userID,
+		Name:        userName,
+		Role:        role,
+		Lobby:       metadataLobby(metadata),
+		RequestedBy: requester.UserID,
+		ClientIP:    requester.ClientIP,
+		IssuedAt:    issuedAt,
+		ExpiresAt:   issuedAt.Add(ttl),
+	}
+	if err := s.store.AddIssuedToken(ctx, issued); err != nil {
+		return nil, err
+	}
+	metrics.TokenIssued(role)
+	log.Printf("Issued %s token %d for %s in %s, requested by %q from %q, expiring %s",
+		role, issued.ID, userID, roomName, requester.UserID, requester.ClientIP, issued.ExpiresAt.UTC().Format(time.RFC3339))
 
 	return &JoinToken{
-		Token:    token,
-		RoomName: roomName,
-		Identity: userID,
+		Token:     token,
+		RoomName:  roomName,
+		Identity:  userID,
+		Audio:     audioOrDefault(state.Audio),
+		ExpiresAt: issued.ExpiresAt,
 	}, nil
 }
 
+// Ping checks that the LiveKit API can be reached with the module's
+// credentials.
+func (s *RoomService) Ping(ctx context.Context) error {
+	_, err := s.client.ListRooms(ctx, &livekit.ListRoomsRequest{})
+	metrics.ObserveLiveKit("ListRooms", err)
+	return err
+}
+
 func (s *RoomService) LeaveRoom(ctx context.Context, roomName, userID string) error {
 	_, err := s.client.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{
 		Room:     roomName,
 		Identity: userID,
 	})
+	metrics.ObserveLiveKit("RemoveParticipant", err)
 	return err
 }
 
@@ -117,6 +259,7 @@ func (s *RoomService) ListParticipants(ctx context.Context, roomName string) ([]
 	resp, err := s.client.ListParticipants(ctx, &livekit.ListParticipantsRequest{
 		Room: roomName,
 	})
+	metrics.ObserveLiveKit("ListParticipants", err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list participants: %w", err)
 	}
@@ -124,11 +267,14 @@ func (s *RoomService) ListParticipants(ctx context.Context, roomName string) ([]
 	participants := make([]*ParticipantInfo, 0, len(resp.Participants))
 	for _, p := range resp.Participants {
 		participants = append(participants, &ParticipantInfo{
-			UserID:   p.Sid,
-			Identity: p.Identity,
-			Role:     "viewer",
-			JoinedAt: p.JoinedAt,
-			IsMuted:  !p.Permission.CanPublish,
+			UserID:       p.Sid,
+			Identity:     p.Identity,
+			Role:         participantRole(p),
+			JoinedAt:     p.JoinedAt,
+			IsMuted:      microphoneMuted(p),
+			MediaRevoked: mediaRevoked(p),
+			InLobby:      metadataLobby(p.Metadata),
+			Tracks:       trackStates(p.Tracks),
 		})
 	}
 
@@ -139,40 +285,174 @@ func (s *RoomService) GetRoomInfo(ctx context.Context, roomName string) (*RoomIn
 	rooms, err := s.client.ListRooms(ctx, &livekit.ListRoomsRequest{
 		Names: []string{roomName},
 	})
+	metrics.ObserveLiveKit("ListRooms", err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get room info: %w", err)
 	}
 
 	if len(rooms.Rooms) == 0 {
-		return nil, fmt.Errorf("room not found")
+		return nil, ErrRoomNotFound
 	}
 
 	room := rooms.Rooms[0]
-	return &RoomInfo{
+	info := &RoomInfo{
 		RoomID:       room.Sid,
 		RoomName:     room.Name,
 		Participants: int(room.NumParticipants),
 		CreatedAt:    time.Unix(room.CreationTime, 0),
-	}, nil
+		Active:       room.NumParticipants > 0,
+	}
+
+	record, err := s.store.GetRoom(ctx, roomName)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if record != nil {
+		info.CommunityID = record.CommunityID
+		info.CreatedAt = record.CreatedAt
+	}
+
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	info.IsLocked = state.IsLocked
+	info.ScreenShare = screenShareOrDefault(state.ScreenShare)
+	info.Template = state.Template
+	info.Lobby = state.Lobby
+
+	if info.Recording, err = s.recording(ctx, roomName); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// CommunityID returns the community a room belongs to, from its stored record
+// or else its name, or 0 if neither says.
+func (s *RoomService) CommunityID(ctx context.Context, roomName string) (int, error) {
+	return lookupCommunityID(ctx, s.store, roomName)
 }
 
 func (s *RoomService) DeleteRoom(ctx context.Context, roomName string) error {
 	_, err := s.client.DeleteRoom(ctx, &livekit.DeleteRoomRequest{
 		Room: roomName,
 	})
-	return err
+	metrics.ObserveLiveKit("DeleteRoom", err)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteRoom(ctx, roomName); err != nil {
+		return err
+	}
+	s.events.Publish(RoomEvent{Type: EventRoomDeleted, RoomName: roomName})
+	return nil
 }
 
-func (s *RoomService) MuteParticipant(ctx context.Context, roomName, userID string, muted bool) error {
-	_, err := s.client.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+// ParticipantRole returns the role of the user in the room, phone for
+// callers who dialed in, reporting false if they are not in it.
+func (s *RoomService) ParticipantRole(ctx context.Context, roomName, userID string) (string, bool, error) {
+	p, err := s.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{
 		Room:     roomName,
 		Identity: userID,
-		Permission: &livekit.ParticipantPermission{
-			CanPublish:   !muted,
-			CanSubscribe: true,
-		},
 	})
-	return err
+	metrics.ObserveLiveKit("GetParticipant", err)
+	if isNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get participant: %w", err)
+	}
+	return participantRole(p), true, nil
+}
+
+// UpdateParticipantRole grants the user the permissions of the role and
+// tells them about it. It reports false if they are not in the room, in
+// which case nothing is changed in LiveKit.
+func (s *RoomService) UpdateParticipantRole(ctx context.Context, roomName, userID, role, previousRole, actorID string) (bool, error) {
+	p, err := s.grantRole(ctx, roomName, userID, role)
+	if p == nil || err != nil {
+		return false, err
+	}
+
+	err = s.sendData(ctx, roomName, RoleNoticeTopic, map[string]string{
+		"type":          EventRoleChanged,
+		"role":          role,
+		"previous_role": previousRole,
+		"changed_by":    actorID,
+	}, []string{p.Sid})
+	if err != nil {
+		log.Printf("Failed to notify %s of their role in %s: %v", userID, roomName, err)
+	}
+	return true, nil
+}
+
+// grantRole gives the user the role's metadata and permissions, returning
+// nil if they are not in the room.
+func (s *RoomService) grantRole(ctx context.Context, roomName, userID, role string) (*livekit.ParticipantInfo, error) {
+	screenShare, err := s.ScreenShare(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.client.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room:       roomName,
+		Identity:   userID,
+		Metadata:   roleMetadata(role),
+		Permission: rolePermission(role, screenShare),
+	})
+	metrics.ObserveLiveKit("UpdateParticipant", err)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update participant: %w", err)
+	}
+	return p, nil
+}
+
+// NotifyParticipant sends the notice to the user as JSON on the topic,
+// reporting false if they are not in the room.
+func (s *RoomService) NotifyParticipant(ctx context.Context, roomName, userID, topic string, notice interface{}) (bool, error) {
+	p, err := s.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{
+		Room:     roomName,
+		Identity: userID,
+	})
+	metrics.ObserveLiveKit("GetParticipant", err)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get participant: %w", err)
+	}
+	if err := s.sendData(ctx, roomName, topic, notice, []string{p.Sid}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NotifyRoom sends the notice to everyone in the room as JSON on the topic.
+func (s *RoomService) NotifyRoom(ctx context.Context, roomName, topic string, notice interface{}) error {
+	return s.sendData(ctx, roomName, topic, notice, nil)
+}
+
+func (s *RoomService) sendData(ctx context.Context, roomName, topic string, notice interface{}, destinationSids []string) error {
+	data, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.SendData(ctx, &livekit.SendDataRequest{
+		Room:            roomName,
+		Data:            data,
+		Kind:            livekit.DataPacket_RELIABLE,
+		DestinationSids: destinationSids,
+		Topic:           &topic,
+	})
+	metrics.ObserveLiveKit("SendData", err)
+	if err != nil {
+		return fmt.Errorf("failed to send data: %w", err)
+	}
+	return nil
 }
 
 func (s *RoomService) KickParticipant(ctx context.Context, roomName, userID string) error {
@@ -180,5 +460,39 @@ func (s *RoomService) KickParticipant(ctx context.Context, roomName, userID stri
 		Room:     roomName,
 		Identity: userID,
 	})
+	metrics.ObserveLiveKit("RemoveParticipant", err)
 	return err
 }
+
+// rolePermission is what the role may do in a room with the screen share
+// setting. Viewers publish nothing unless everyone may share their screen.
+func rolePermission(role, screenShare string) *livekit.ParticipantPermission {
+	sources := publishSources(RoleRank(role) >= RoleRank(RoleSpeaker), CanShareScreen(role, screenShare))
+	return &livekit.ParticipantPermission{
+		CanPublish:        len(sources) > 0,
+		CanPublishSources: sources,
+		CanSubscribe:      true,
+		CanPublishData:    role == RoleHost || role == RoleModerator,
+	}
+}
+
+func roleMetadata(role string) string {
+	metadata, _ := json.Marshal(map[string]string{"role": role})
+	return string(metadata)
+}
+
+// metadataRole reads the role from participant metadata, defaulting to viewer.
+func metadataRole(metadata string) string {
+	var m struct {
+		Role string `json:"role"`
+	}
+	if json.Unmarshal([]byte(metadata), &m) != nil || RoleRank(m.Role) < 0 {
+		return RoleViewer
+	}
+	return m.Role
+}
+
+func isNotFound(err error) bool {
+	var twirpErr twirp.Error
+	return errors.As(err, &twirpErr) && twirpErr.Code() == twirp.NotFound
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+// Settings of rooms created without a template, or with one leaving them
+// unset.
+const (
+	DefaultMaxParticipants     = 100
+	DefaultEmptyTimeoutSeconds = 300
+)
+
+const (
+	maxRoomParticipants     = 1000
+	maxEmptyTimeoutSeconds  = 24 * 60 * 60
+	maxRoomTemplateNameSize = 64
+)
+
+var (
+	ErrInvalidRoomTemplate  = errors.New("invalid room template")
+	ErrRoomTemplateNotFound = errors.New("room template not found")
+)
+
+var roomTemplateName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// roomCodecs are the codecs a template may allow, by lower case MIME type,
+// as LiveKit names them.
+var roomCodecs = map[string]string{
+	"audio/opus": "audio/opus",
+	"audio/red":  "audio/red",
+	"video/vp8":  "video/VP8",
+	"video/h264": "video/H264",
+	"video/vp9":  "video/VP9",
+	"video/av1":  "video/AV1",
+}
+
+// CodecTopic is the data topic participants are told on that a track was
+// muted for its codec.
+const CodecTopic = "waddlebot.codec"
+
+type RoomTemplate = storage.RoomTemplate
+
+// roomMetadata is the LiveKit room metadata, which every participant can
+// read. Clients should publish only with codecs it lists, and follow the
+// room's config.
+type roomMetadata struct {
+	Codecs []string    `json:"codecs,omitempty"`
+	Config *RoomConfig `json:"config,omitempty"`
+}
+
+// SaveTemplate validates the template and stores it for its community,
+// replacing any of the same name.
+func (s *RoomService) SaveTemplate(ctx context.Context, template *RoomTemplate) (*RoomTemplate, error) {
+	if err := normalizeRoomTemplate(template); err != nil {
+		return nil, err
+	}
+	template.UpdatedAt = time.Now()
+	if err := s.store.SaveRoomTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Room template %s saved for community %d by %s", template.Name, template.CommunityID, template.UpdatedBy)
+	return template, nil
+}
+
+func normalizeRoomTemplate(template *RoomTemplate) error {
+	template.Name = strings.ToLower(strings.TrimSpace(template.Name))
+	if template.DefaultRole == "" {
+		template.DefaultRole = RoleViewer
+	}
+
+	switch {
+	case len(template.Name) > maxRoomTemplateNameSize || !roomTemplateName.MatchString(template.Name):
+		return fmt.Errorf("%w: name must be up to %d letters, digits, dashes and underscores", ErrInvalidRoomTemplate, maxRoomTemplateNameSize)
+	case template.MaxParticipants > maxRoomParticipants:
+		return fmt.Errorf("%w: max_participants must be at most %d", ErrInvalidRoomTemplate, maxRoomParticipants)
+	case template.EmptyTimeoutSeconds > maxEmptyTimeoutSeconds:
+		return fmt.Errorf("%w: empty_timeout_seconds must be at most %d", ErrInvalidRoomTemplate, maxEmptyTimeoutSeconds)
+	case template.DefaultRole != RoleViewer && template.DefaultRole != RoleSpeaker:
+		return fmt.Errorf("%w: default_role must be viewer or speaker", ErrInvalidRoomTemplate)
+	}
+
+	// Rooms need a codec of each kind, or nobody could be heard or seen
+	codecs := []string{}
+	audio, video := false, false
+	for _, codec := range template.Codecs {
+		mime, ok := roomCodecs[strings.ToLower(codec)]
+		if !ok {
+			return fmt.Errorf("%w: unknown codec %q", ErrInvalidRoomTemplate, codec)
+		}
+		audio = audio || strings.HasPrefix(mime, "audio/")
+		video = video || strings.HasPrefix(mime, "video/")
+		codecs = append(codecs, mime)
+	}
+	if len(codecs) > 0 && (!audio || !video) {
+		return fmt.Errorf("%w: codecs must include an audio and a video codec", ErrInvalidRoomTemplate)
+	}
+	template.Codecs = codecs
+	return nil
+}
+
+func (s *RoomService) ListTemplates(ctx context.Context, communityID int) ([]*RoomTemplate, error) {
+	return s.store.ListRoomTemplates(ctx, communityID)
+}
+
+// DeleteTemplate removes one of the community's templates. Rooms created
+// from it keep their settings.
+func (s *RoomService) DeleteTemplate(ctx context.Context, communityID int, name string) error {
+	if _, err := s.store.GetRoomTemplate(ctx, communityID, name); errors.Is(err, storage.ErrNotFound) {
+		return ErrRoomTemplateNotFound
+	} else if err != nil {
+		return err
+	}
+	return s.store.DeleteRoomTemplate(ctx, communityID, name)
+}
+
+// Template returns the community's template of the name, or its default
+// template if name is empty. It returns nil without error if the community
+// has no default.
+func (s *RoomService) Template(ctx context.Context, communityID int, name string) (*RoomTemplate, error) {
+	if name == "" {
+		template, err := s.store.GetDefaultRoomTemplate(ctx, communityID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return template, err
+	}
+
+	template, err := s.store.GetRoomTemplate(ctx, communityID, strings.ToLower(name))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrRoomTemplateNotFound
+	}
+	return template, err
+}
+
+// roomSettings is how a room is created from the template, which may be
+// nil. A maxParticipants other than zero overrides the template's.
+func roomSettings(template *RoomTemplate, maxParticipants uint32) *livekit.CreateRoomRequest {
+	req := &livekit.CreateRoomRequest{
+		MaxParticipants: maxParticipants,
+		EmptyTimeout:    DefaultEmptyTimeoutSeconds,
+	}
+	metadata := roomMetadata{Config: &RoomConfig{ScreenShare: ScreenShareSpeakers, Audio: DefaultAudioSettings()}}
+	if template != nil {
+		if req.MaxParticipants == 0 {
+			req.MaxParticipants = template.MaxParticipants
+		}
+		if template.EmptyTimeoutSeconds != 0 {
+			req.EmptyTimeout = template.EmptyTimeoutSeconds
+		}
+		// This LiveKit API cannot limit a room's codecs, so they go in its
+		// metadata and tracks using others are muted when published
+		metadata.Codecs = template.Codecs
+		metadata.Config.Lobby = template.Lobby
+	}
+	if req.MaxParticipants == 0 {
+		req.MaxParticipants = DefaultMaxParticipants
+	}
+	metadata.Config.MaxParticipants = req.MaxParticipants
+	data, _ := json.Marshal(metadata)
+	req.Metadata = string(data)
+	return req
+}
+
+// applyTemplate keeps the template's in-room settings with the room's state.
+func (s *RoomService) applyTemplate(ctx context.Context, roomName string, template *RoomTemplate) error {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	state.Template = template.Name
+	state.DefaultRole = template.DefaultRole
+	state.Lobby = template.Lobby
+	state.RecordingDisabled = !template.Recording
+	state.UpdatedAt = time.Now()
+	return s.store.SaveRoomState(ctx, state)
+}
+
+// EnforceCodecs mutes a track just published in the room if the room's
+// codecs do not include its own, and tells its publisher. It reports whether
+// the track was muted.
+func (s *RoomService) EnforceCodecs(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, track *livekit.TrackInfo) (bool, error) {
+	var metadata roomMetadata
+	if json.Unmarshal([]byte(room.Metadata), &metadata) != nil || len(metadata.Codecs) == 0 || track.MimeType == "" {
+		return false, nil
+	}
+	for _, codec := range metadata.Codecs {
+		if strings.EqualFold(codec, track.MimeType) {
+			return false, nil
+		}
+	}
+
+	_, err := s.client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+		Room:     room.Name,
+		Identity: participant.Identity,
+		TrackSid: track.Sid,
+		Muted:    true,
+	})
+	metrics.ObserveLiveKit("MutePublishedTrack", err)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to mute track: %w", err)
+	}
+
+	err = s.sendData(ctx, room.Name, CodecTopic, map[string]interface{}{
+		"type":      EventCodecRejected,
+		"track_sid": track.Sid,
+		"mime_type": track.MimeType,
+		"codecs":    metadata.Codecs,
+	}, []string{participant.Sid})
+	if err != nil {
+		log.Printf("Failed to tell %s their track in %s was muted for its codec: %v", participant.Identity, room.Name, err)
+	}
+	s.events.Publish(RoomEvent{Type: EventCodecRejected, RoomName: room.Name, UserID: participant.Identity, Data: map[string]string{
+		"track_sid": track.Sid,
+		"mime_type": track.MimeType,
+	}})
+	return true, nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestRoomService_SaveTemplate(t *testing.T) {
+	ctx := context.Background()
+	s := NewRoomService("http://localhost:7880", "key", "secret", storage.NewMemoryStore(), nil)
+
+	for _, template := range []*RoomTemplate{
+		{CommunityID: 1, Name: "bad name"},
+		{CommunityID: 1, Name: "town-hall", DefaultRole: RoleModerator},
+		{CommunityID: 1, Name: "town-hall", MaxParticipants: maxRoomParticipants + 1},
+		{CommunityID: 1, Name: "town-hall", Codecs: []string{"audio/opus"}},
+		{CommunityID: 1, Name: "town-hall", Codecs: []string{"audio/opus", "video/theora"}},
+	} {
+		if _, err := s.SaveTemplate(ctx, template); !errors.Is(err, ErrInvalidRoomTemplate) {
+			t.Errorf("Expected %+v to be refused, got %v", template, err)
+		}
+	}
+
+	saved, err := s.SaveTemplate(ctx, &RoomTemplate{CommunityID: 1, Name: " Town-Hall ", Codecs: []string{"AUDIO/OPUS", "video/vp8"}, IsDefault: true})
+	if err != nil {
+		t.Fatalf("Failed to save template: %v", err)
+	}
+	if saved.Name != "town-hall" || saved.DefaultRole != RoleViewer || saved.Codecs[0] != "audio/opus" || saved.Codecs[1] != "video/VP8" {
+		t.Errorf("Expected the template normalized, got %+v", saved)
+	}
+
+	// A new default replaces the old one
+	if _, err := s.SaveTemplate(ctx, &RoomTemplate{CommunityID: 1, Name: "standup", IsDefault: true}); err != nil {
+		t.Fatalf("Failed to save template: %v", err)
+	}
+	if template, err := s.Template(ctx, 1, ""); err != nil || template.Name != "standup" {
+		t.Errorf("Expected standup as the default, got %+v, %v", template, err)
+	}
+	templates, _ := s.ListTemplates(ctx, 1)
+	for _, template := range templates {
+		if template.Name == "town-hall" && template.IsDefault {
+			t.Error("Expected town-hall to stop being the default")
+		}
+	}
+
+	if template, err := s.Template(ctx, 2, ""); template != nil || err != nil {
+		t.Errorf("Expected no default for a community without templates, got %+v, %v", template, err)
+	}
+	if _, err := s.Template(ctx, 1, "missing"); !errors.Is(err, ErrRoomTemplateNotFound) {
+		t.Errorf("Expected a missing template to be reported, got %v", err)
+	}
+	if err := s.DeleteTemplate(ctx, 1, "standup"); err != nil {
+		t.Fatalf("Failed to delete template: %v", err)
+	}
+	if err := s.DeleteTemplate(ctx, 1, "standup"); !errors.Is(err, ErrRoomTemplateNotFound) {
+		t.Errorf("Expected deleting twice to fail, got %v", err)
+	}
+}
+
+func TestRoomService_CreateRoomFromTemplate(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	s := NewRoomService(url, "key", "secret", store, nil)
+	features := NewCallFeaturesService(s, store, nil, nil)
+	recordings := NewRecordingService(url, "key", "secret", RecordingOutput{LocalDir: "/out"}, store, nil, nil)
+
+	room, err := s.CreateRoom(ctx, 1, "open", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	if lk.rooms[room.RoomName].MaxParticipants != DefaultMaxParticipants || room.Template != "" {
+		t.Errorf("Expected the defaults without a template, got %+v", lk.rooms[room.RoomName])
+	}
+
+	if _, err := s.SaveTemplate(ctx, &RoomTemplate{
+		CommunityID:     1,
+		Name:            "stage",
+		MaxParticipants: 20,
+		DefaultRole:     RoleSpeaker,
+		Lobby:           true,
+		Codecs:          []string{"audio/opus", "video/vp8"},
+		IsDefault:       true,
+	}); err != nil {
+		t.Fatalf("Failed to save template: %v", err)
+	}
+
+	room, err = s.CreateRoom(ctx, 1, "stage", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	created := lk.rooms[room.RoomName]
+	wantMetadata := `{"codecs":["audio/opus","video/VP8"],"config":{"locked":false,"max_participants":20,"screen_share":"speakers","lobby":true,"slow_mode_seconds":0,"hand_expiry_minutes":0,"audio":{"noise_suppression":"browser","echo_cancellation":true,"auto_gain_control":true,"red":true,"dtx":true}}}`
+	if created.MaxParticipants != 20 || created.Metadata != wantMetadata {
+		t.Errorf("Expected the default template's settings, got %+v", created)
+	}
+	if room.Template != "stage" || !room.Lobby {
+		t.Errorf("Expected the room to report its template, got %+v", room)
+	}
+
+	if role, _ := features.AssignedRole(ctx, room.RoomName, "u1"); role != RoleSpeaker {
+		t.Errorf("Expected the template's default role, got %s", role)
+	}
+	if waiting, _ := features.InLobby(ctx, room.RoomName, "u1"); !waiting {
+		t.Error("Expected new participants to wait in the lobby")
+	}
+	if _, err := recordings.StartRoomRecording(ctx, room.RoomName, "mod"); !errors.Is(err, ErrRecordingDisabled) {
+		t.Errorf("Expected recording to be disabled, got %v", err)
+	}
+
+	if room, err := s.CreateRoom(ctx, 1, "big", "stage", 50); err != nil || lk.rooms[room.RoomName].MaxParticipants != 50 {
+		t.Errorf("Expected max participants to override the template, got %+v, %v", room, err)
+	}
+	if _, err := s.CreateRoom(ctx, 1, "other", "missing", 0); !errors.Is(err, ErrRoomTemplateNotFound) {
+		t.Errorf("Expected a missing template to be refused, got %v", err)
+	}
+}
+
+func TestCallFeaturesService_AdmitParticipant(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+	s := NewCallFeaturesService(NewRoomService(url, "key", "secret", store, events), store, nil, events)
+
+	store.SaveRoomState(ctx, &storage.RoomState{RoomName: "room", Lobby: true, DefaultRole: RoleSpeaker})
+	lk.participants["u1"] = &livekit.ParticipantInfo{Sid: "PA_1", Identity: "u1", Metadata: lobbyMetadata(), Permission: &livekit.ParticipantPermission{}}
+
+	change, err := s.AdmitParticipant(ctx, "room", "u1", "mod")
+	if err != nil {
+		t.Fatalf("Failed to admit: %v", err)
+	}
+	if change.Role != RoleSpeaker || !change.InRoom {
+		t.Errorf("Expected u1 let in as a speaker, got %+v", change)
+	}
+	if p := lk.participants["u1"]; metadataLobby(p.Metadata) || !p.Permission.CanPublish || !p.Permission.CanSubscribe {
+		t.Errorf("Expected speaker permissions in LiveKit, got %+v, %s", p.Permission, p.Metadata)
+	}
+	if len(lk.sent) != 1 || lk.sent[0].GetTopic() != LobbyTopic {
+		t.Errorf("Expected u1 told on the lobby topic, got %+v", lk.sent)
+	}
+	if event := <-roomEvents; event.Type != EventParticipantAdmitted || event.ActorID != "mod" {
+		t.Errorf("Expected a participant_admitted event, got %+v", event)
+	}
+	if waiting, _ := s.InLobby(ctx, "room", "u1"); waiting {
+		t.Error("Expected u1 no longer in the lobby")
+	}
+	if _, err := s.AdmitParticipant(ctx, "room", "u1", "mod"); !errors.Is(err, ErrAlreadyAdmitted) {
+		t.Errorf("Expected admitting twice to fail, got %v", err)
+	}
+
+	// Users may be let in before they join
+	if change, err := s.AdmitParticipant(ctx, "room", "u2", "mod"); err != nil || change.InRoom {
+		t.Errorf("Expected u2 admitted ahead, got %+v, %v", change, err)
+	}
+	if waiting, _ := s.InLobby(ctx, "room", "u2"); waiting {
+		t.Error("Expected u2 to skip the lobby")
+	}
+}
+
+func TestRoomService_EnforceCodecs(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	s := NewRoomService(url, "key", "secret", storage.NewMemoryStore(), nil)
+
+	room := &livekit.Room{Name: "room", Metadata: `{"codecs":["audio/opus","video/VP8"]}`}
+	p := &livekit.ParticipantInfo{
+		Sid:      "PA_1",
+		Identity: "u1",
+		Tracks: []*livekit.TrackInfo{
+			{Sid: "TR_vp8", Type: livekit.TrackType_VIDEO, MimeType: "video/vp8"},
+			{Sid: "TR_h264", Type: livekit.TrackType_VIDEO, MimeType: "video/H264"},
+		},
+	}
+	lk.participants["u1"] = p
+
+	if muted, err := s.EnforceCodecs(ctx, room, p, p.Tracks[0]); err != nil || muted {
+		t.Errorf("Expected an allowed codec left alone, got %v, %v", muted, err)
+	}
+	if muted, err := s.EnforceCodecs(ctx, room, p, p.Tracks[1]); err != nil || !muted {
+		t.Errorf("Expected a disallowed codec muted, got %v, %v", muted, err)
+	}
+	if len(lk.mutedTracks) != 1 || lk.mutedTracks[0].TrackSid != "TR_h264" {
+		t.Errorf("Expected only the H264 track muted, got %+v", lk.mutedTracks)
+	}
+	if len(lk.sent) != 1 || lk.sent[0].GetTopic() != CodecTopic {
+		t.Errorf("Expected the publisher told on the codec topic, got %+v", lk.sent)
+	}
+
+	// Rooms without codecs allow any
+	if muted, _ := s.EnforceCodecs(ctx, &livekit.Room{Name: "open"}, p, p.Tracks[1]); muted {
+		t.Error("Expected a room without codecs to allow any")
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/calendar"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+var (
+	ErrInvalidSchedule  = errors.New("invalid room schedule")
+	ErrScheduleNotFound = errors.New("room schedule not found")
+)
+
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+const (
+	defaultScheduleDuration = time.Hour
+	// maxUpcomingOccurrences bounds how far one schedule is expanded.
+	maxUpcomingOccurrences = 100
+)
+
+type RoomSchedule = storage.RoomSchedule
+
+// UpcomingRoom is one occurrence of a scheduled room.
+type UpcomingRoom struct {
+	ScheduleID      string    `json:"schedule_id"`
+	CommunityID     int       `json:"community_id"`
+	RoomName        string    `json:"room_name"`
+	Title           string    `json:"title"`
+	StartsAt        time.Time `json:"starts_at"`
+	EndsAt          time.Time `json:"ends_at"`
+	IsOpen          bool      `json:"is_open"`
+	CalendarEventID int       `json:"calendar_event_id,omitempty"`
+}
+
+// ScheduleService opens and closes rooms on their schedules. Run checks the
+// schedules periodically; each one holds its next occurrence, moved on once
+// the current one ends.
+type ScheduleService struct {
+	roomService *RoomService
+	store       storage.Store
+	calendar    *calendar.Client
+}
+
+func NewScheduleService(roomService *RoomService, store storage.Store, calendarClient *calendar.Client) *ScheduleService {
+	return &ScheduleService{
+		roomService: roomService,
+		store:       store,
+		calendar:    calendarClient,
+	}
+}
+
+// CreateSchedule validates and saves a schedule. RoomName is the room's name
+// within the community. With a calendar event, a missing title and times are
+// taken from the event.
+func (s *ScheduleService) CreateSchedule(ctx context.Context, schedule *RoomSchedule) (*RoomSchedule, error) {
+	if schedule.CalendarEventID != 0 {
+		if err := s.applyCalendarEvent(ctx, schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	schedule.RoomName = strings.TrimSpace(schedule.RoomName)
+	if err := checkRoomName(schedule.RoomName); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	if strings.TrimSpace(schedule.Title) == "" {
+		schedule.Title = schedule.RoomName
+	}
+	schedule.RoomName = fmt.Sprintf("community_%d_%s", schedule.CommunityID, schedule.RoomName)
+
+	if schedule.StartsAt.IsZero() {
+		return nil, fmt.Errorf("%w: starts_at is required", ErrInvalidSchedule)
+	}
+	if schedule.EndsAt.IsZero() {
+		schedule.EndsAt = schedule.StartsAt.Add(defaultScheduleDuration)
+	}
+	if !schedule.EndsAt.After(schedule.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidSchedule)
+	}
+	switch schedule.Recurrence {
+	case "":
+		schedule.RecurUntil = nil
+	case RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+		if schedule.RecurUntil != nil && schedule.RecurUntil.Before(schedule.StartsAt) {
+			return nil, fmt.Errorf("%w: recur_until must be after starts_at", ErrInvalidSchedule)
+		}
+	default:
+		return nil, fmt.Errorf("%w: recurrence must be daily, weekly or monthly", ErrInvalidSchedule)
+	}
+	if schedule.MaxParticipants == 0 {
+		schedule.MaxParticipants = 100
+	}
+
+	now := time.Now()
+	if !advanceSchedule(schedule, now) {
+		return nil, fmt.Errorf("%w: schedule has no occurrences left", ErrInvalidSchedule)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	schedule.ID = id
+	schedule.IsOpen = false
+	schedule.Ended = false
+	schedule.CreatedAt = now
+	if err := s.store.SaveRoomSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled %s for community %d from %s", schedule.RoomName, schedule.CommunityID, schedule.StartsAt.Format(time.RFC3339))
+	return schedule, nil
+}
+
+func (s *ScheduleService) applyCalendarEvent(ctx context.Context, schedule *RoomSchedule) error {
+	if !s.calendar.Enabled() {
+		return fmt.Errorf("%w: calendar integration is not configured", ErrInvalidSchedule)
+	}
+	event, err := s.calendar.GetEvent(ctx, schedule.CommunityID, schedule.CalendarEventID)
+	if errors.Is(err, calendar.ErrEventNotFound) {
+		return fmt.Errorf("%w: calendar event %d not found", ErrInvalidSchedule, schedule.CalendarEventID)
+	}
+	if err != nil {
+		return err
+	}
+	if event.Status == "cancelled" {
+		return fmt.Errorf("%w: calendar event %d is cancelled", ErrInvalidSchedule, event.ID)
+	}
+
+	if schedule.RoomName == "" {
+		schedule.RoomName = fmt.Sprintf("event_%d", event.ID)
+	}
+	if schedule.Title == "" {
+		schedule.Title = event.Title
+	}
+	if schedule.StartsAt.IsZero() {
+		schedule.StartsAt = event.StartsAt
+		if schedule.EndsAt.IsZero() {
+			schedule.EndsAt = event.EndsAt
+		}
+	}
+	return nil
+}
+
+func (s *ScheduleService) ListSchedules(ctx context.Context, communityID int) ([]*RoomSchedule, error) {
+	return s.store.ListRoomSchedules(ctx, communityID)
+}
+
+// DeleteSchedule stops a schedule. A room it has open is left open.
+func (s *ScheduleService) DeleteSchedule(ctx context.Context, communityID int, id string) error {
+	schedule, err := s.store.GetRoomSchedule(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && schedule.CommunityID != communityID) {
+		return ErrScheduleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteRoomSchedule(ctx, id)
+}
+
+// UpcomingRooms returns the community's scheduled room occurrences that
+// overlap from to to, by start.
+func (s *ScheduleService) UpcomingRooms(ctx context.Context, communityID int, from, to time.Time) ([]*UpcomingRoom, error) {
+	schedules, err := s.store.ListRoomSchedules(ctx, communityID)
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := []*UpcomingRoom{}
+	for _, schedule := range schedules {
+		if schedule.Ended {
+			continue
+		}
+		occurrence := *schedule
+		for i := 0; i < maxUpcomingOccurrences && occurrence.StartsAt.Before(to); i++ {
+			if occurrence.EndsAt.After(from) {
+				upcoming = append(upcoming, &UpcomingRoom{
+					ScheduleID:      schedule.ID,
+					CommunityID:     schedule.CommunityID,
+					RoomName:        schedule.RoomName,
+					Title:           schedule.Title,
+					StartsAt:        occurrence.StartsAt,
+					EndsAt:          occurrence.EndsAt,
+					IsOpen:          i == 0 && schedule.IsOpen,
+					CalendarEventID: schedule.CalendarEventID,
+				})
+			}
+			if !nextOccurrence(&occurrence) {
+				break
+			}
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].StartsAt.Before(upcoming[j].StartsAt)
+	})
+	return upcoming, nil
+}
+
+// Run checks the schedules every interval until ctx is done.
+func (s *ScheduleService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunDue(ctx, time.Now()); err != nil {
+			log.Printf("Failed to run room schedules: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue closes the rooms whose occurrence has ended and opens those whose
+// occurrence has started. Occurrences missed while the scheduler was down
+// are skipped.
+func (s *ScheduleService) RunDue(ctx context.Context, now time.Time) error {
+	schedules, err := s.store.ListActiveRoomSchedules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		if err := s.runSchedule(ctx, schedule, now); err != nil {
+			log.Printf("Failed to run schedule %s for %s: %v", schedule.ID, schedule.RoomName, err)
+		}
+	}
+	return nil
+}
+
+func (s *ScheduleService) runSchedule(ctx context.Context, schedule *RoomSchedule, now time.Time) error {
+	changed := false
+
+	if !now.Before(schedule.EndsAt) {
+		if schedule.IsOpen && schedule.AutoClose {
+			if err := s.roomService.DeleteRoom(ctx, schedule.RoomName); err != nil && !isNotFound(err) {
+				return fmt.Errorf("failed to close room: %w", err)
+			}
+			log.Printf("Closed scheduled room %s", schedule.RoomName)
+		}
+		schedule.IsOpen = false
+		schedule.Ended = !advanceSchedule(schedule, now)
+		changed = true
+	}
+
+	// A failed open is retried on the next run
+	var openErr error
+	if !schedule.Ended && schedule.AutoOpen && !schedule.IsOpen && !now.Before(schedule.StartsAt) {
+		template, err := s.roomService.Template(ctx, schedule.CommunityID, "")
+		if err == nil {
+			_, err = s.roomService.createRoom(ctx, schedule.CommunityID, schedule.RoomName, schedule.MaxParticipants, template)
+		}
+		if err != nil {
+			openErr = fmt.Errorf("failed to open room: %w", err)
+		} else {
+			log.Printf("Opened scheduled room %s until %s", schedule.RoomName, schedule.EndsAt.Format(time.RFC3339))
+			schedule.IsOpen = true
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := s.store.SaveRoomSchedule(ctx, schedule); err != nil {
+			return err
+		}
+	}
+	return openErr
+}
+
+// advanceSchedule moves the schedule to its first occurrence not ended by
+// now, reporting false if there is none.
+func advanceSchedule(schedule *RoomSchedule, now time.Time) bool {
+	for !schedule.EndsAt.After(now) {
+		if !nextOccurrence(schedule) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextOccurrence moves the schedule to its next occurrence, reporting false
+// if it does not recur again.
+func nextOccurrence(schedule *RoomSchedule) bool {
+	var start time.Time
+	switch schedule.Recurrence {
+	case RecurrenceDaily:
+		start = schedule.StartsAt.AddDate(0, 0, 1)
+	case RecurrenceWeekly:
+		start = schedule.StartsAt.AddDate(0, 0, 7)
+	case RecurrenceMonthly:
+		start = schedule.StartsAt.AddDate(0, 1, 0)
+	default:
+		return false
+	}
+	if schedule.RecurUntil != nil && start.After(*schedule.RecurUntil) {
+		return false
+	}
+	schedule.EndsAt = start.Add(schedule.EndsAt.Sub(schedule.StartsAt))
+	schedule.StartsAt = start
+	return true
+}
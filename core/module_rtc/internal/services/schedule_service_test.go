@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/calendar"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestScheduleService(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	s := NewScheduleService(NewRoomService(url, "key", "secret", store, nil), store, nil)
+
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	if _, err := s.CreateSchedule(ctx, &RoomSchedule{CommunityID: 7, RoomName: "standup", StartsAt: start, Recurrence: "hourly"}); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("Expected an unknown recurrence to be refused, got %v", err)
+	}
+	if _, err := s.CreateSchedule(ctx, &RoomSchedule{CommunityID: 7, RoomName: "standup", StartsAt: start.Add(-3 * time.Hour)}); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("Expected a schedule already over to be refused, got %v", err)
+	}
+
+	schedule, err := s.CreateSchedule(ctx, &RoomSchedule{
+		CommunityID: 7,
+		RoomName:    "standup",
+		StartsAt:    start,
+		EndsAt:      start.Add(30 * time.Minute),
+		Recurrence:  RecurrenceWeekly,
+		AutoOpen:    true,
+		AutoClose:   true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	if schedule.RoomName != "community_7_standup" || schedule.Title != "standup" {
+		t.Errorf("Unexpected schedule: %+v", schedule)
+	}
+
+	upcoming, err := s.UpcomingRooms(ctx, 7, time.Now(), time.Now().AddDate(0, 0, 15))
+	if err != nil || len(upcoming) != 3 || !upcoming[2].StartsAt.Equal(start.AddDate(0, 0, 14)) {
+		t.Fatalf("Expected three weekly occurrences, got %+v, %v", upcoming, err)
+	}
+
+	// Nothing happens before the start
+	s.RunDue(ctx, start.Add(-time.Minute))
+	if len(lk.rooms) != 0 {
+		t.Errorf("Expected no room before the start, got %v", lk.rooms)
+	}
+	s.RunDue(ctx, start.Add(time.Minute))
+	if lk.rooms["community_7_standup"] == nil {
+		t.Fatal("Expected the room opened at the start")
+	}
+	if schedule, _ := store.GetRoomSchedule(ctx, schedule.ID); !schedule.IsOpen {
+		t.Error("Expected the schedule marked open")
+	}
+
+	s.RunDue(ctx, start.Add(31*time.Minute))
+	if len(lk.rooms) != 0 {
+		t.Error("Expected the room closed at the end")
+	}
+	next, _ := store.GetRoomSchedule(ctx, schedule.ID)
+	if next.IsOpen || !next.StartsAt.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("Expected the next week's occurrence, got %+v", next)
+	}
+
+	// Occurrences missed while down are skipped
+	s.RunDue(ctx, start.AddDate(0, 0, 21).Add(time.Minute))
+	next, _ = store.GetRoomSchedule(ctx, schedule.ID)
+	if !next.IsOpen || !next.StartsAt.Equal(start.AddDate(0, 0, 21)) || lk.rooms["community_7_standup"] == nil {
+		t.Errorf("Expected the current occurrence opened, got %+v", next)
+	}
+
+	if err := s.DeleteSchedule(ctx, 8, schedule.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Expected another community's schedule to be hidden, got %v", err)
+	}
+}
+
+func TestScheduleService_CalendarEvent(t *testing.T) {
+	ctx := context.Background()
+	_, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/calendar/7/events/42" && r.URL.Path != "/api/v1/calendar/8/events/42" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{"id":42,"community_id":7,"title":"Game night","status":"approved",` +
+			`"event_date":"` + start.Format("2006-01-02T15:04:05") + `","end_date":null}}`))
+	}))
+	defer server.Close()
+
+	s := NewScheduleService(NewRoomService(url, "key", "secret", store, nil), store, calendar.NewClient(server.URL))
+
+	schedule, err := s.CreateSchedule(ctx, &RoomSchedule{CommunityID: 7, CalendarEventID: 42})
+	if err != nil {
+		t.Fatalf("Failed to schedule the event's room: %v", err)
+	}
+	if schedule.RoomName != "community_7_event_42" || schedule.Title != "Game night" || !schedule.StartsAt.Equal(start) || !schedule.EndsAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected the room taken from the event, got %+v", schedule)
+	}
+
+	if _, err := s.CreateSchedule(ctx, &RoomSchedule{CommunityID: 8, CalendarEventID: 42}); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("Expected another community's event to be refused, got %v", err)
+	}
+	if _, err := s.CreateSchedule(ctx, &RoomSchedule{CommunityID: 7, CalendarEventID: 43}); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("Expected a missing event to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+	"google.golang.org/protobuf/proto"
+)
+
+// Who may share their screen in a room.
+const (
+	ScreenShareHosts    = "hosts"
+	ScreenShareSpeakers = "speakers"
+	ScreenShareEveryone = "everyone"
+)
+
+// ScreenShareTopic is the data topic participants are told their share was
+// stopped on.
+const ScreenShareTopic = "waddlebot.screen_share"
+
+var (
+	ErrInvalidScreenShare  = errors.New("screen share must be hosts, speakers or everyone")
+	ErrParticipantNotFound = errors.New("participant is not in the room")
+	ErrNotSharingScreen    = errors.New("participant is not sharing their screen")
+)
+
+var (
+	mediaSources  = []livekit.TrackSource{livekit.TrackSource_CAMERA, livekit.TrackSource_MICROPHONE}
+	screenSources = []livekit.TrackSource{livekit.TrackSource_SCREEN_SHARE, livekit.TrackSource_SCREEN_SHARE_AUDIO}
+)
+
+func validScreenShare(screenShare string) bool {
+	return screenShare == ScreenShareHosts || screenShare == ScreenShareSpeakers || screenShare == ScreenShareEveryone
+}
+
+// CanShareScreen reports whether the room's screen share setting lets the
+// role share. Moderators count as hosts, and an empty setting as speakers.
+func CanShareScreen(role, screenShare string) bool {
+	switch screenShare {
+	case ScreenShareEveryone:
+		return true
+	case ScreenShareHosts:
+		return RoleRank(role) >= RoleRank(RoleModerator)
+	default:
+		return RoleRank(role) >= RoleRank(RoleSpeaker)
+	}
+}
+
+// publishSources lists what a participant may publish: their camera and
+// microphone if media is allowed, and their screen if shareScreen is.
+func publishSources(media, shareScreen bool) []livekit.TrackSource {
+	var sources []livekit.TrackSource
+	if media {
+		sources = append(sources, mediaSources...)
+	}
+	if shareScreen {
+		sources = append(sources, screenSources...)
+	}
+	return sources
+}
+
+func isScreenSource(source livekit.TrackSource) bool {
+	return source == livekit.TrackSource_SCREEN_SHARE || source == livekit.TrackSource_SCREEN_SHARE_AUDIO
+}
+
+func screenShareOrDefault(screenShare string) string {
+	if screenShare == "" {
+		return ScreenShareSpeakers
+	}
+	return screenShare
+}
+
+// ScreenShare returns who may share their screen in the room.
+func (s *RoomService) ScreenShare(ctx context.Context, roomName string) (string, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return "", err
+	}
+	return screenShareOrDefault(state.ScreenShare), nil
+}
+
+// applyScreenShare updates the permissions of everyone in the room to the
+// screen share setting, leaving muted participants muted, and stops the
+// shares of those no longer allowed to.
+func (s *RoomService) applyScreenShare(ctx context.Context, roomName, screenShare, actorID string) error {
+	resp, err := s.client.ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: roomName})
+	metrics.ObserveLiveKit("ListParticipants", err)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list participants: %w", err)
+	}
+
+	for _, p := range resp.Participants {
+		permission := &livekit.ParticipantPermission{CanSubscribe: true}
+		if p.Permission != nil {
+			permission = proto.Clone(p.Permission).(*livekit.ParticipantPermission)
+		}
+		media := canPublishMedia(permission)
+		allowed := CanShareScreen(metadataRole(p.Metadata), screenShare)
+		permission.CanPublishSources = publishSources(media, allowed)
+		permission.CanPublish = len(permission.CanPublishSources) > 0
+
+		if proto.Equal(permission, p.Permission) {
+			continue
+		}
+		_, err := s.client.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+			Room:       roomName,
+			Identity:   p.Identity,
+			Permission: permission,
+		})
+		metrics.ObserveLiveKit("UpdateParticipant", err)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to update participant: %w", err)
+		}
+		if !allowed {
+			if _, err := s.stopScreenShare(ctx, roomName, p, actorID); err != nil && !errors.Is(err, ErrNotSharingScreen) {
+				log.Printf("Failed to stop %s sharing their screen in %s: %v", p.Identity, roomName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// StopScreenShare mutes the user's screen share tracks and tells them to
+// unpublish them, returning how many were stopped.
+func (s *RoomService) StopScreenShare(ctx context.Context, roomName, userID, actorID string) (int, error) {
+	p, err := s.participant(ctx, roomName, userID)
+	if err != nil {
+		return 0, err
+	}
+	return s.stopScreenShare(ctx, roomName, p, actorID)
+}
+
+func (s *RoomService) stopScreenShare(ctx context.Context, roomName string, p *livekit.ParticipantInfo, actorID string) (int, error) {
+	stopped := 0
+	for _, track := range p.Tracks {
+		if !isScreenSource(track.Source) || track.Muted {
+			continue
+		}
+		_, err := s.client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+			Room:     roomName,
+			Identity: p.Identity,
+			TrackSid: track.Sid,
+			Muted:    true,
+		})
+		metrics.ObserveLiveKit("MutePublishedTrack", err)
+		if err != nil {
+			return stopped, fmt.Errorf("failed to mute screen share: %w", err)
+		}
+		stopped++
+	}
+	if stopped == 0 {
+		return 0, ErrNotSharingScreen
+	}
+
+	err := s.sendData(ctx, roomName, ScreenShareTopic, map[string]string{
+		"type":       EventScreenShareStopped,
+		"stopped_by": actorID,
+	}, []string{p.Sid})
+	if err != nil {
+		log.Printf("Failed to tell %s their screen share in %s was stopped: %v", p.Identity, roomName, err)
+	}
+	return stopped, nil
+}
+
+// SetScreenShare changes who may share their screen in the room, applying
+// it to everyone already in it.
+func (s *CallFeaturesService) SetScreenShare(ctx context.Context, roomName, screenShare, moderatorID string) error {
+	if !validScreenShare(screenShare) {
+		return ErrInvalidScreenShare
+	}
+
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	state.ScreenShare = screenShare
+	state.UpdatedAt = time.Now()
+	if err := s.store.SaveRoomState(ctx, state); err != nil {
+		return err
+	}
+	if err := s.roomService.applyScreenShare(ctx, roomName, screenShare, moderatorID); err != nil {
+		return err
+	}
+
+	s.events.Publish(RoomEvent{Type: EventScreenShareChanged, RoomName: roomName, ActorID: moderatorID, Data: map[string]string{
+		"screen_share": screenShare,
+	}})
+	s.roomService.PublishRoomConfig(ctx, roomName, ConfigScreenShare, moderatorID)
+	return nil
+}
+
+// StopScreenShare ends the user's screen share. They may share again if the
+// room allows them to.
+func (s *CallFeaturesService) StopScreenShare(ctx context.Context, roomName, userID, moderatorID string) (int, error) {
+	stopped, err := s.roomService.StopScreenShare(ctx, roomName, userID, moderatorID)
+	if err != nil {
+		return 0, err
+	}
+	s.events.Publish(RoomEvent{Type: EventScreenShareStopped, RoomName: roomName, UserID: userID, ActorID: moderatorID})
+	return stopped, nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestCallFeaturesService_ScreenShare(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+	roomService := NewRoomService(url, "key", "secret", store, events)
+	s := NewCallFeaturesService(roomService, store, nil, events)
+
+	sharing := func(identity, role string) *livekit.ParticipantInfo {
+		return &livekit.ParticipantInfo{
+			Sid:        "PA_" + identity,
+			Identity:   identity,
+			Metadata:   roleMetadata(role),
+			Permission: rolePermission(role, ScreenShareSpeakers),
+			Tracks: []*livekit.TrackInfo{
+				{Sid: "TR_cam_" + identity, Source: livekit.TrackSource_CAMERA},
+				{Sid: "TR_screen_" + identity, Source: livekit.TrackSource_SCREEN_SHARE},
+			},
+		}
+	}
+	lk.participants["host"] = sharing("host", RoleHost)
+	lk.participants["speaker"] = sharing("speaker", RoleSpeaker)
+	lk.participants["viewer"] = &livekit.ParticipantInfo{Sid: "PA_viewer", Identity: "viewer", Metadata: roleMetadata(RoleViewer), Permission: rolePermission(RoleViewer, ScreenShareSpeakers)}
+
+	// Speakers may share by default, and their grants say so
+	token, err := roomService.JoinRoom(ctx, "room", "speaker", "Speaker", RoleSpeaker)
+	if err != nil {
+		t.Fatalf("Failed to join: %v", err)
+	}
+	grants, err := auth.ParseAPIToken(token.Token)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if claims, _ := grants.Verify("secret"); !claims.Video.GetCanPublishSource(livekit.TrackSource_SCREEN_SHARE) {
+		t.Errorf("Expected a speaker allowed to share, got %v", claims.Video.CanPublishSources)
+	}
+
+	if err := s.SetScreenShare(ctx, "room", "nobody", "mod"); !errors.Is(err, ErrInvalidScreenShare) {
+		t.Errorf("Expected an unknown setting refused, got %v", err)
+	}
+
+	// Restricting to hosts takes the speaker's screen, but not their camera
+	if err := s.SetScreenShare(ctx, "room", ScreenShareHosts, "mod"); err != nil {
+		t.Fatalf("Failed to restrict screen share: %v", err)
+	}
+	if sources := lk.participants["speaker"].Permission.CanPublishSources; len(sources) != 2 || sources[0] != livekit.TrackSource_CAMERA {
+		t.Errorf("Expected the speaker left their camera and microphone, got %v", sources)
+	}
+	if len(lk.mutedTracks) != 1 || lk.mutedTracks[0].TrackSid != "TR_screen_speaker" {
+		t.Fatalf("Expected only the speaker's share stopped, got %+v", lk.mutedTracks)
+	}
+	var notice map[string]string
+	json.Unmarshal(lk.sent[0].Data, &notice)
+	if lk.sent[0].GetTopic() != ScreenShareTopic || notice["type"] != EventScreenShareStopped || notice["stopped_by"] != "mod" {
+		t.Errorf("Expected the speaker told their share was stopped, got %+v", notice)
+	}
+	if event := <-roomEvents; event.Type != EventScreenShareChanged || event.Data["screen_share"] != ScreenShareHosts {
+		t.Errorf("Expected a screen_share_changed event, got %+v", event)
+	}
+	if snapshot, _ := s.RoomSnapshot(ctx, "room"); snapshot.ScreenShare != ScreenShareHosts {
+		t.Errorf("Expected the setting in the snapshot, got %s", snapshot.ScreenShare)
+	}
+
+	// Restoring media does not give the screen back
+	s.RevokeMedia(ctx, "room", "speaker", "mod")
+	s.RestoreMedia(ctx, "room", "speaker", "mod")
+	if sources := lk.participants["speaker"].Permission.CanPublishSources; len(sources) != 2 || sources[0] != livekit.TrackSource_CAMERA {
+		t.Errorf("Expected restoring to give back only the camera and microphone, got %v", sources)
+	}
+
+	// Opening it to everyone lets viewers share, but not talk, and leaves
+	// revoked media revoked
+	s.RevokeMedia(ctx, "room", "speaker", "mod")
+	if err := s.SetScreenShare(ctx, "room", ScreenShareEveryone, "mod"); err != nil {
+		t.Fatalf("Failed to open screen share: %v", err)
+	}
+	if sources := lk.participants["viewer"].Permission.CanPublishSources; len(sources) != 2 || sources[0] != livekit.TrackSource_SCREEN_SHARE {
+		t.Errorf("Expected the viewer allowed only their screen, got %v", sources)
+	}
+	if sources := lk.participants["speaker"].Permission.CanPublishSources; len(sources) != 2 || sources[0] != livekit.TrackSource_SCREEN_SHARE {
+		t.Errorf("Expected the revoked speaker allowed only their screen, got %v", sources)
+	}
+
+	if _, err := s.StopScreenShare(ctx, "room", "nobody", "mod"); !errors.Is(err, ErrParticipantNotFound) {
+		t.Errorf("Expected stopping someone not in the room to fail, got %v", err)
+	}
+	if _, err := s.StopScreenShare(ctx, "room", "viewer", "mod"); !errors.Is(err, ErrNotSharingScreen) {
+		t.Errorf("Expected stopping someone not sharing to fail, got %v", err)
+	}
+	if n, err := s.StopScreenShare(ctx, "room", "host", "mod"); err != nil || n != 1 {
+		t.Errorf("Expected the host's share stopped, got %d, %v", n, err)
+	}
+}
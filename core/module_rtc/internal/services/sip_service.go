@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/sip"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+// RolePhone is the role reported for participants who dialed in. It is not
+// on the ladder, so phone callers cannot be promoted or demoted.
+const RolePhone = "phone"
+
+const (
+	dialInPINDigits   = 6
+	dialInPINAttempts = 5
+	// LiveKit names SIP participants after the caller's number
+	sipIdentityPrefix = "sip_"
+)
+
+var (
+	ErrDialInDisabled   = errors.New("phone dial-in is not configured")
+	ErrDialInNotEnabled = errors.New("dial-in is not enabled for this room")
+)
+
+type DialIn = storage.DialIn
+
+// DialInInfo is what callers need to dial into a room.
+type DialInInfo struct {
+	*DialIn
+	Numbers []string `json:"numbers"`
+}
+
+// SIPTrunk is the deployment's inbound SIP trunk: the phone numbers its
+// carrier routes to LiveKit, from the carrier's addresses and credentials.
+type SIPTrunk struct {
+	Name             string
+	Numbers          []string
+	AllowedAddresses []string
+	AuthUsername     string
+	AuthPassword     string
+}
+
+// SIPService lets participants dial into rooms by phone. The deployment has
+// one inbound trunk; each room with dial-in enabled has a PIN, routed to it
+// by a dispatch rule on the trunk.
+type SIPService struct {
+	client  *sip.Client
+	trunk   SIPTrunk
+	store   storage.Store
+	events  *EventBus
+	trunkID string
+	mu      sync.Mutex
+}
+
+func NewSIPService(client *sip.Client, trunk SIPTrunk, store storage.Store, events *EventBus) *SIPService {
+	return &SIPService{
+		client: client,
+		trunk:  trunk,
+		store:  store,
+		events: events,
+	}
+}
+
+// Enabled reports whether any phone numbers are configured.
+func (s *SIPService) Enabled() bool {
+	return s != nil && len(s.trunk.Numbers) > 0
+}
+
+// ProvisionTrunk finds the deployment's inbound trunk in LiveKit by name,
+// creating it if there is none, and returns its ID.
+func (s *SIPService) ProvisionTrunk(ctx context.Context) (string, error) {
+	if !s.Enabled() {
+		return "", ErrDialInDisabled
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.trunkID != "" {
+		return s.trunkID, nil
+	}
+
+	trunks, err := s.client.ListInboundTrunks(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list SIP trunks: %w", err)
+	}
+	for _, trunk := range trunks {
+		if trunk.Name == s.trunk.Name {
+			s.trunkID = trunk.ID
+			return s.trunkID, nil
+		}
+	}
+
+	trunk, err := s.client.CreateInboundTrunk(ctx, &sip.InboundTrunk{
+		Name:             s.trunk.Name,
+		Numbers:          s.trunk.Numbers,
+		AllowedAddresses: s.trunk.AllowedAddresses,
+		AuthUsername:     s.trunk.AuthUsername,
+		AuthPassword:     s.trunk.AuthPassword,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create SIP trunk: %w", err)
+	}
+	log.Printf("Created SIP trunk %s (%s) for %s", trunk.ID, s.trunk.Name, strings.Join(s.trunk.Numbers, ", "))
+	s.trunkID = trunk.ID
+	return s.trunkID, nil
+}
+
+// DialIn returns the room's PIN and the numbers to call.
+func (s *SIPService) DialIn(ctx context.Context, roomName string) (*DialInInfo, error) {
+	if !s.Enabled() {
+		return nil, ErrDialInDisabled
+	}
+	dialIn, err := s.store.GetDialIn(ctx, roomName)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrDialInNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DialInInfo{DialIn: dialIn, Numbers: s.trunk.Numbers}, nil
+}
+
+// EnableDialIn gives the room a new PIN, replacing any it had, so enabling
+// dial-in again is how a PIN is changed.
+func (s *SIPService) EnableDialIn(ctx context.Context, roomName, moderatorID string) (*DialInInfo, error) {
+	trunkID, err := s.ProvisionTrunk(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetRoom(ctx, roomName); errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrRoomNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	pin, err := s.newPIN(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := s.client.CreateDispatchRule(ctx, &sip.DispatchRule{
+		Name:     "waddlebot " + roomName,
+		TrunkIDs: []string{trunkID},
+		RoomName: roomName,
+		PIN:      pin,
+		Metadata: roleMetadata(RolePhone),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SIP dispatch rule: %w", err)
+	}
+
+	previous, err := s.store.GetDialIn(ctx, roomName)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	dialIn := &DialIn{
+		RoomName:       roomName,
+		PIN:            pin,
+		DispatchRuleID: rule.ID,
+		CreatedBy:      moderatorID,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.store.SaveDialIn(ctx, dialIn); err != nil {
+		s.deleteRule(ctx, rule.ID)
+		return nil, err
+	}
+	if previous != nil {
+		s.deleteRule(ctx, previous.DispatchRuleID)
+	}
+
+	s.events.Publish(RoomEvent{Type: EventDialInEnabled, RoomName: roomName, ActorID: moderatorID})
+	return &DialInInfo{DialIn: dialIn, Numbers: s.trunk.Numbers}, nil
+}
+
+// DisableDialIn removes the room's PIN. Callers already in the room stay.
+func (s *SIPService) DisableDialIn(ctx context.Context, roomName, moderatorID string) error {
+	if !s.Enabled() {
+		return ErrDialInDisabled
+	}
+	dialIn, err := s.store.GetDialIn(ctx, roomName)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrDialInNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.client.DeleteDispatchRule(ctx, dialIn.DispatchRuleID); err != nil && !sip.IsNotFound(err) {
+		return fmt.Errorf("failed to delete SIP dispatch rule: %w", err)
+	}
+	if err := s.store.DeleteDialIn(ctx, roomName); err != nil {
+		return err
+	}
+
+	s.events.Publish(RoomEvent{Type: EventDialInDisabled, RoomName: roomName, ActorID: moderatorID})
+	return nil
+}
+
+// RoomDeleted disables dial-in for a deleted room, if it had it.
+func (s *SIPService) RoomDeleted(ctx context.Context, roomName string) {
+	if !s.Enabled() {
+		return
+	}
+	if err := s.DisableDialIn(ctx, roomName, ""); err != nil && !errors.Is(err, ErrDialInNotEnabled) {
+		log.Printf("Failed to disable dial-in for deleted room %s: %v", roomName, err)
+	}
+}
+
+// newPIN returns a random PIN no other room uses.
+func (s *SIPService) newPIN(ctx context.Context) (string, error) {
+	limit := big.NewInt(1)
+	for i := 0; i < dialInPINDigits; i++ {
+		limit.Mul(limit, big.NewInt(10))
+	}
+	for i := 0; i < dialInPINAttempts; i++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		pin := fmt.Sprintf("%0*d", dialInPINDigits, n)
+		if _, err := s.store.GetDialInByPIN(ctx, pin); errors.Is(err, storage.ErrNotFound) {
+			return pin, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("failed to find an unused dial-in PIN")
+}
+
+func (s *SIPService) deleteRule(ctx context.Context, id string) {
+	if err := s.client.DeleteDispatchRule(ctx, id); err != nil && !sip.IsNotFound(err) {
+		log.Printf("Failed to delete SIP dispatch rule %s: %v", id, err)
+	}
+}
+
+// isPhoneParticipant reports whether the participant dialed in, going by
+// the metadata dial-in rules give them or LiveKit's identity for callers.
+func isPhoneParticipant(p *livekit.ParticipantInfo) bool {
+	if strings.HasPrefix(p.Identity, sipIdentityPrefix) {
+		return true
+	}
+	var m struct {
+		Role string `json:"role"`
+	}
+	return json.Unmarshal([]byte(p.Metadata), &m) == nil && m.Role == RolePhone
+}
+
+// participantRole is the participant's role, or phone if they dialed in.
+func participantRole(p *livekit.ParticipantInfo) string {
+	if isPhoneParticipant(p) {
+		return RolePhone
+	}
+	return metadataRole(p.Metadata)
+}
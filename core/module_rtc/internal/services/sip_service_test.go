@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/sip"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestSIPService_DialIn(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("community_7_stage")
+	defer unsubscribe()
+	trunk := SIPTrunk{Name: "waddlebot", Numbers: []string{"+15550100"}, AllowedAddresses: []string{"203.0.113.0/24"}}
+	s := NewSIPService(sip.NewClient(url, "key", "secret"), trunk, store, events)
+
+	if disabled := NewSIPService(sip.NewClient(url, "key", "secret"), SIPTrunk{}, store, events); disabled.Enabled() {
+		t.Error("Expected dial-in to be off without numbers")
+	} else if _, err := disabled.EnableDialIn(ctx, "community_7_stage", "mod"); !errors.Is(err, ErrDialInDisabled) {
+		t.Errorf("Expected enabling dial-in without numbers to fail, got %v", err)
+	}
+
+	// The trunk is created once, then found by name
+	trunkID, err := s.ProvisionTrunk(ctx)
+	if err != nil || trunkID != "ST_1" {
+		t.Fatalf("Failed to provision trunk: %s, %v", trunkID, err)
+	}
+	if again, err := NewSIPService(sip.NewClient(url, "key", "secret"), trunk, store, events).ProvisionTrunk(ctx); err != nil || again != trunkID || len(lk.sipTrunks) != 1 {
+		t.Errorf("Expected the existing trunk to be reused, got %s, %v and %d trunks", again, err, len(lk.sipTrunks))
+	}
+
+	if _, err := s.EnableDialIn(ctx, "community_7_stage", "mod"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected dial-in to a missing room to fail, got %v", err)
+	}
+	store.SaveRoom(ctx, &storage.Room{RoomName: "community_7_stage", CommunityID: 7})
+	if _, err := s.DialIn(ctx, "community_7_stage"); !errors.Is(err, ErrDialInNotEnabled) {
+		t.Errorf("Expected no dial-in yet, got %v", err)
+	}
+
+	dialIn, err := s.EnableDialIn(ctx, "community_7_stage", "mod")
+	if err != nil {
+		t.Fatalf("Failed to enable dial-in: %v", err)
+	}
+	if len(dialIn.PIN) != dialInPINDigits || len(dialIn.Numbers) != 1 || dialIn.Numbers[0] != "+15550100" {
+		t.Errorf("Expected a PIN and the number to call, got %+v", dialIn)
+	}
+	rule := lk.sipRules[dialIn.DispatchRuleID]
+	direct := rule["rule"].(map[string]interface{})["dispatch_rule_direct"].(map[string]interface{})
+	if direct["room_name"] != "community_7_stage" || direct["pin"] != dialIn.PIN || rule["metadata"] != roleMetadata(RolePhone) {
+		t.Errorf("Expected a rule sending the PIN to the room, got %+v", rule)
+	}
+	if event := <-roomEvents; event.Type != EventDialInEnabled || event.ActorID != "mod" {
+		t.Errorf("Expected a dial_in_enabled event, got %+v", event)
+	}
+
+	// Enabling again changes the PIN and replaces the rule
+	rotated, err := s.EnableDialIn(ctx, "community_7_stage", "mod")
+	if err != nil {
+		t.Fatalf("Failed to change the PIN: %v", err)
+	}
+	<-roomEvents
+	if _, ok := lk.sipRules[dialIn.DispatchRuleID]; ok || len(lk.sipRules) != 1 || rotated.DispatchRuleID == dialIn.DispatchRuleID {
+		t.Errorf("Expected the old rule to be deleted, got %+v", lk.sipRules)
+	}
+	if got, err := s.DialIn(ctx, "community_7_stage"); err != nil || got.PIN != rotated.PIN {
+		t.Errorf("Expected the new PIN stored, got %+v, %v", got, err)
+	}
+
+	if err := s.DisableDialIn(ctx, "community_7_stage", "mod"); err != nil {
+		t.Fatalf("Failed to disable dial-in: %v", err)
+	}
+	if event := <-roomEvents; event.Type != EventDialInDisabled {
+		t.Errorf("Expected a dial_in_disabled event, got %+v", event)
+	}
+	if len(lk.sipRules) != 0 {
+		t.Errorf("Expected the rule to be deleted, got %+v", lk.sipRules)
+	}
+	if err := s.DisableDialIn(ctx, "community_7_stage", "mod"); !errors.Is(err, ErrDialInNotEnabled) {
+		t.Errorf("Expected disabling twice to fail, got %v", err)
+	}
+}
+
+func TestPhoneParticipants(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	roomService := NewRoomService(url, "key", "secret", store, nil)
+	s := NewCallFeaturesService(roomService, store, nil, nil)
+
+	lk.participants["sip_+15550199"] = &livekit.ParticipantInfo{Identity: "sip_+15550199", Metadata: roleMetadata(RolePhone)}
+	lk.participants["u1"] = &livekit.ParticipantInfo{Identity: "u1", Metadata: roleMetadata(RoleSpeaker)}
+
+	participants, err := roomService.ListParticipants(ctx, "room")
+	if err != nil {
+		t.Fatalf("Failed to list participants: %v", err)
+	}
+	for _, p := range participants {
+		if want := map[string]string{"sip_+15550199": RolePhone, "u1": RoleSpeaker}[p.Identity]; p.Role != want {
+			t.Errorf("Expected %s to be a %s, got %s", p.Identity, want, p.Role)
+		}
+	}
+
+	if _, err := s.PromoteParticipant(ctx, "room", "sip_+15550199", "", "mod"); !errors.Is(err, ErrInvalidRoleChange) {
+		t.Errorf("Expected a phone caller not to be promoted, got %v", err)
+	}
+}
+
+func TestWebhookService_LockedRoomRemovesPhoneCallers(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	rooms := NewRoomService(url, "key", "secret", store, nil)
+	s := NewWebhookService("key", "secret", store, nil, rooms, nil, nil, nil, nil, nil)
+	store.SaveRoomState(ctx, &storage.RoomState{RoomName: "community_42_lobby", IsLocked: true})
+
+	lk.participants["sip_+15550199"] = &livekit.ParticipantInfo{Identity: "sip_+15550199"}
+	lk.participants["u1"] = &livekit.ParticipantInfo{Identity: "u1"}
+	for _, identity := range []string{"sip_+15550199", "u1"} {
+		event, err := s.Receive(signedWebhook(t, "secret", `{"event":"participant_joined","room":{"name":"community_42_lobby"},"participant":{"identity":"`+identity+`"}}`))
+		if err != nil {
+			t.Fatalf("Failed to receive webhook: %v", err)
+		}
+		if err := s.HandleEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to handle webhook: %v", err)
+		}
+	}
+
+	// Users joined through the API, which checked the lock when they did
+	if _, ok := lk.participants["sip_+15550199"]; ok {
+		t.Error("Expected the phone caller to be removed from the locked room")
+	}
+	if participants, _ := store.ListParticipants(ctx, "community_42_lobby"); len(participants) != 1 || participants[0].Identity != "u1" {
+		t.Errorf("Expected only the user to be recorded, got %+v", participants)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+type IssuedToken = storage.IssuedToken
+
+// Join tokens last DefaultTokenTTL unless their room sets its own lifetime,
+// from MinTokenTTL to MaxTokenTTL. LiveKit checks a token when a client
+// connects and refreshes it for participants already in the room, so a
+// short lifetime only limits how long a token can be used to join.
+const (
+	DefaultTokenTTL = time.Hour
+	MinTokenTTL     = time.Minute
+	MaxTokenTTL     = 24 * time.Hour
+)
+
+// MaxIssuedTokens is the most issued tokens listed at once.
+const MaxIssuedTokens = 500
+
+var ErrInvalidTokenTTL = errors.New("token lifetime must be between 1 minute and 24 hours")
+
+// TokenRequester is who asked for the tokens issued with a context, as kept
+// in their records.
+type TokenRequester struct {
+	UserID   string
+	ClientIP string
+}
+
+type tokenRequesterKey struct{}
+
+// WithTokenRequester returns a context recording tokens issued with it as
+// requested by the requester.
+func WithTokenRequester(ctx context.Context, requester TokenRequester) context.Context {
+	return context.WithValue(ctx, tokenRequesterKey{}, requester)
+}
+
+func tokenRequester(ctx context.Context) TokenRequester {
+	requester, _ := ctx.Value(tokenRequesterKey{}).(TokenRequester)
+	return requester
+}
+
+func tokenTTLOrDefault(seconds int) time.Duration {
+	if seconds == 0 {
+		return DefaultTokenTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// TokenTTL returns how long join tokens for the room last.
+func (s *RoomService) TokenTTL(ctx context.Context, roomName string) (time.Duration, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return 0, err
+	}
+	return tokenTTLOrDefault(state.TokenTTLSeconds), nil
+}
+
+// SetTokenTTL changes how long join tokens for the room last, in whole
+// seconds, or back to DefaultTokenTTL if ttl is zero. Tokens already issued
+// keep their lifetime.
+func (s *RoomService) SetTokenTTL(ctx context.Context, roomName string, ttl time.Duration, moderatorID string) error {
+	if ttl != 0 && (ttl < MinTokenTTL || ttl > MaxTokenTTL) {
+		return ErrInvalidTokenTTL
+	}
+
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	state.TokenTTLSeconds = int(ttl / time.Second)
+	state.UpdatedAt = time.Now()
+	if err := s.store.SaveRoomState(ctx, state); err != nil {
+		return err
+	}
+	log.Printf("Token lifetime in %s set to %s by %s", roomName, tokenTTLOrDefault(state.TokenTTLSeconds), moderatorID)
+	return nil
+}
+
+// ListIssuedTokens returns up to limit of the tokens issued for the room, or
+// for the identity in it if set, newest first.
+func (s *RoomService) ListIssuedTokens(ctx context.Context, roomName, identity string, limit int) ([]*IssuedToken, error) {
+	if limit <= 0 || limit > MaxIssuedTokens {
+		limit = MaxIssuedTokens
+	}
+	return s.store.ListIssuedTokens(ctx, roomName, identity, limit)
+}
+
+// RevokeTokens revokes the tokens issued for the user in the room that
+// have not expired and removes them from the room, returning how many
+// tokens were revoked. LiveKit accepts a token until it expires, so users
+// who join again with a revoked one are removed as soon as they are in,
+// unless they were issued a new token since.
+func (s *RoomService) RevokeTokens(ctx context.Context, roomName, userID, moderatorID string) (int, error) {
+	revoked, err := s.store.RevokeIssuedTokens(ctx, roomName, userID, moderatorID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if err := s.KickParticipant(ctx, roomName, userID); err != nil && !isNotFound(err) {
+		return 0, err
+	}
+
+	s.events.Publish(RoomEvent{Type: EventTokensRevoked, RoomName: roomName, UserID: userID, ActorID: moderatorID, Data: map[string]string{
+		"revoked": strconv.Itoa(revoked),
+	}})
+	log.Printf("%d tokens for %s in %s revoked by %s", revoked, userID, roomName, moderatorID)
+	return revoked, nil
+}
+
+// tokenRevoked reports whether the last token issued for the user in the
+// room was revoked before it expired.
+func tokenRevoked(ctx context.Context, store storage.Store, roomName, userID string) (bool, error) {
+	tokens, err := store.ListIssuedTokens(ctx, roomName, userID, 1)
+	if err != nil || len(tokens) == 0 {
+		return false, err
+	}
+	return tokens[0].RevokedAt != nil && tokens[0].ExpiresAt.After(time.Now()), nil
+}
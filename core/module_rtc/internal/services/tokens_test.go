@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestRoomService_IssuedTokens(t *testing.T) {
+	ctx := context.Background()
+	_, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	s := NewRoomService(url, "key", "secret", store, nil)
+
+	requested := WithTokenRequester(ctx, TokenRequester{UserID: "u1", ClientIP: "203.0.113.7"})
+	token, err := s.JoinRoom(requested, "room", "u1", "One", RoleSpeaker)
+	if err != nil {
+		t.Fatalf("Failed to join room: %v", err)
+	}
+	if ttl := time.Until(token.ExpiresAt); ttl < DefaultTokenTTL-time.Minute || ttl > DefaultTokenTTL {
+		t.Errorf("Expected the token to last %s, expires at %s", DefaultTokenTTL, token.ExpiresAt)
+	}
+	tokens, _ := s.ListIssuedTokens(ctx, "room", "", 0)
+	if len(tokens) != 1 || tokens[0].Identity != "u1" || tokens[0].Role != RoleSpeaker || tokens[0].RequestedBy != "u1" ||
+		tokens[0].ClientIP != "203.0.113.7" || !tokens[0].ExpiresAt.Equal(token.ExpiresAt) || tokens[0].Lobby {
+		t.Fatalf("Expected the token recorded with who asked for it, got %+v", tokens)
+	}
+
+	if err := s.SetTokenTTL(ctx, "room", 5*time.Minute, "mod"); err != nil {
+		t.Fatalf("Failed to set token lifetime: %v", err)
+	}
+	token, _ = s.JoinLobby(ctx, "room", "u2", "Two")
+	if ttl := time.Until(token.ExpiresAt); ttl < 4*time.Minute || ttl > 5*time.Minute {
+		t.Errorf("Expected the room's token lifetime, expires at %s", token.ExpiresAt)
+	}
+	tokens, _ = s.ListIssuedTokens(ctx, "room", "", 0)
+	if len(tokens) != 2 || tokens[0].Identity != "u2" || !tokens[0].Lobby || tokens[0].RequestedBy != "" {
+		t.Errorf("Expected the lobby token listed first, got %+v", tokens[0])
+	}
+	if tokens, _ := s.ListIssuedTokens(ctx, "room", "u1", 0); len(tokens) != 1 || tokens[0].Identity != "u1" {
+		t.Errorf("Expected only u1's tokens, got %+v", tokens)
+	}
+
+	for _, ttl := range []time.Duration{time.Second, 25 * time.Hour, -time.Minute} {
+		if err := s.SetTokenTTL(ctx, "room", ttl, "mod"); !errors.Is(err, ErrInvalidTokenTTL) {
+			t.Errorf("Expected a lifetime of %s refused, got %v", ttl, err)
+		}
+	}
+	if err := s.SetTokenTTL(ctx, "room", 0, "mod"); err != nil {
+		t.Fatalf("Failed to reset token lifetime: %v", err)
+	}
+	if ttl, _ := s.TokenTTL(ctx, "room"); ttl != DefaultTokenTTL {
+		t.Errorf("Expected the default lifetime back, got %s", ttl)
+	}
+}
+
+func TestRoomService_RevokeTokens(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	s := NewRoomService(url, "key", "secret", store, events)
+	webhooks := NewWebhookService("key", "secret", store, nil, s, nil, nil, nil, nil, events)
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+
+	joined := func(identity string) {
+		t.Helper()
+		lk.participants[identity] = &livekit.ParticipantInfo{Identity: identity}
+		event, err := webhooks.Receive(signedWebhook(t, "secret", `{"event":"participant_joined","room":{"name":"room"},"participant":{"identity":"`+identity+`"}}`))
+		if err != nil {
+			t.Fatalf("Failed to receive webhook: %v", err)
+		}
+		if err := webhooks.HandleEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to handle webhook: %v", err)
+		}
+	}
+
+	s.JoinRoom(ctx, "room", "u1", "One", RoleSpeaker)
+	s.JoinRoom(ctx, "room", "u1", "One", RoleSpeaker)
+	s.JoinRoom(ctx, "room", "u2", "Two", RoleSpeaker)
+	joined("u1")
+	<-roomEvents
+
+	revoked, err := s.RevokeTokens(ctx, "room", "u1", "mod")
+	if err != nil || revoked != 2 {
+		t.Fatalf("Expected both of u1's tokens revoked, got %d, %v", revoked, err)
+	}
+	if _, ok := lk.participants["u1"]; ok {
+		t.Error("Expected u1 removed from the room")
+	}
+	if event := <-roomEvents; event.Type != EventTokensRevoked || event.UserID != "u1" || event.Data["revoked"] != "2" || event.ActorID != "mod" {
+		t.Errorf("Expected a tokens_revoked event, got %+v", event)
+	}
+	if tokens, _ := s.ListIssuedTokens(ctx, "room", "u1", 0); tokens[0].RevokedAt == nil || tokens[0].RevokedBy != "mod" {
+		t.Errorf("Expected the tokens marked revoked, got %+v", tokens[0])
+	}
+	if tokens, _ := s.ListIssuedTokens(ctx, "room", "u2", 0); tokens[0].RevokedAt != nil {
+		t.Error("Expected u2's token left alone")
+	}
+
+	// Joining again with a revoked token
+	joined("u1")
+	if _, ok := lk.participants["u1"]; ok {
+		t.Error("Expected u1 removed on joining with a revoked token")
+	}
+	joined("u2")
+	if _, ok := lk.participants["u2"]; !ok {
+		t.Error("Expected u2 let in")
+	}
+
+	// A token issued after the revocation lets them back in
+	s.JoinRoom(ctx, "room", "u1", "One", RoleSpeaker)
+	joined("u1")
+	if _, ok := lk.participants["u1"]; !ok {
+		t.Error("Expected u1 let in with a new token")
+	}
+	if revoked, err := s.RevokeTokens(ctx, "room", "absent", "mod"); err != nil || revoked != 0 {
+		t.Errorf("Expected nothing to revoke for a user not in the room, got %d, %v", revoked, err)
+	}
+}
@@ -0,0 +1,544 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+	"github.com/penguintech/waddlebot/module_rtc/internal/metrics"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+// CaptionTopic is the data channel topic captions are sent to rooms on.
+const CaptionTopic = "waddlebot.captions"
+
+const (
+	DefaultTranscriptionChunk = 5 * time.Second
+
+	transcriptionIssuer  = "module_rtc"
+	transcriptionSubject = "transcription_stream"
+	// Egress connects as soon as it starts; the token is only checked then
+	transcriptionTokenTTL = 24 * time.Hour
+	// Chunks waiting for the backend; more are dropped rather than falling
+	// ever further behind the call
+	transcriptionQueueSize = 4
+	transcribeTimeout      = 30 * time.Second
+	// Chunks quieter than this RMS, out of 32768, are not transcribed
+	silenceThreshold = 200
+)
+
+var (
+	ErrTranscriptionDisabled = errors.New("transcription is not configured")
+	ErrAlreadyTranscribing   = errors.New("room is already being transcribed")
+	ErrNotTranscribing       = errors.New("room is not being transcribed")
+	ErrInvalidStream         = errors.New("invalid transcription stream")
+)
+
+type TranscriptSegment = storage.TranscriptSegment
+
+// Transcriber is a speech-to-text backend. The audio is a mono 16-bit WAV
+// file and the language an ISO-639-1 code, or empty to detect it.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio []byte, language string) (string, error)
+}
+
+// TranscriptionOptions say where egress streams audio to and how it is
+// transcribed.
+type TranscriptionOptions struct {
+	// StreamURL is the module's /transcription/ws endpoint as egress
+	// reaches it, such as ws://core-module-rtc:8093/transcription/ws.
+	StreamURL string
+	// Secret signs the tokens egress connects with.
+	Secret   string
+	Language string
+	Chunk    time.Duration
+}
+
+// AudioFormat is the raw audio egress streams: interleaved signed 16-bit
+// little-endian PCM.
+type AudioFormat struct {
+	SampleRate int
+	Channels   int
+}
+
+// DefaultAudioFormat is what egress streams unless its content type says
+// otherwise.
+var DefaultAudioFormat = AudioFormat{SampleRate: 48000, Channels: 2}
+
+// ParseAudioFormat reads the rate and channels from an egress stream's
+// content type, such as audio/x-raw,rate=48000,channels=2, keeping the
+// defaults for any it does not give.
+func ParseAudioFormat(contentType string) AudioFormat {
+	format := DefaultAudioFormat
+	for _, param := range strings.FieldsFunc(contentType, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n <= 0 {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "rate":
+			format.SampleRate = n
+		case "channels":
+			format.Channels = n
+		}
+	}
+	return format
+}
+
+// transcriptionClaims are the claims a stream token carries besides its
+// expiry: whose track egress is streaming.
+type transcriptionClaims struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	Name     string `json:"name,omitempty"`
+	Track    string `json:"track"`
+}
+
+// TranscriptionService captions rooms live. While a room is being
+// transcribed, each microphone track is streamed by a LiveKit track egress
+// to the module's websocket, transcribed a chunk at a time, sent to the room
+// as captions and kept as the room's transcript.
+type TranscriptionService struct {
+	client      *lksdk.EgressClient
+	rooms       *RoomService
+	transcriber Transcriber
+	options     TranscriptionOptions
+	store       storage.Store
+	events      *EventBus
+}
+
+func NewTranscriptionService(host, apiKey, apiSecret string, rooms *RoomService, transcriber Transcriber, options TranscriptionOptions, store storage.Store, events *EventBus) *TranscriptionService {
+	if options.Chunk <= 0 {
+		options.Chunk = DefaultTranscriptionChunk
+	}
+	return &TranscriptionService{
+		client:      lksdk.NewEgressClient(host, apiKey, apiSecret),
+		rooms:       rooms,
+		transcriber: transcriber,
+		options:     options,
+		store:       store,
+		events:      events,
+	}
+}
+
+// Enabled reports whether a backend and stream URL are configured.
+func (s *TranscriptionService) Enabled() bool {
+	return s != nil && s.transcriber != nil && s.options.StreamURL != ""
+}
+
+// StartTranscription starts transcribing every microphone in the room and
+// any published later, returning how many it started on.
+func (s *TranscriptionService) StartTranscription(ctx context.Context, roomName, moderatorID string) (int, error) {
+	if !s.Enabled() {
+		return 0, ErrTranscriptionDisabled
+	}
+	if _, err := s.store.GetRoom(ctx, roomName); errors.Is(err, storage.ErrNotFound) {
+		return 0, ErrRoomNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return 0, err
+	}
+	if state.Transcribing {
+		return 0, ErrAlreadyTranscribing
+	}
+	state.Transcribing = true
+	state.UpdatedAt = time.Now()
+	if err := s.store.SaveRoomState(ctx, state); err != nil {
+		return 0, err
+	}
+	s.events.Publish(RoomEvent{Type: EventTranscriptionStarted, RoomName: roomName, ActorID: moderatorID})
+	log.Printf("Transcription of %s started by %s", roomName, moderatorID)
+
+	resp, err := s.rooms.client.ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: roomName})
+	metrics.ObserveLiveKit("ListParticipants", err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list participants: %w", err)
+	}
+	streaming, err := s.streams(ctx, roomName)
+	if err != nil {
+		return 0, err
+	}
+	started := 0
+	for _, p := range resp.Participants {
+		for _, track := range p.Tracks {
+			if !isMicrophone(track) || streaming[track.Sid] != "" {
+				continue
+			}
+			if err := s.startStream(ctx, roomName, p, track); err != nil {
+				return started, err
+			}
+			started++
+		}
+	}
+	return started, nil
+}
+
+// StopTranscription stops the room's egress streams. Its transcript is kept.
+func (s *TranscriptionService) StopTranscription(ctx context.Context, roomName, moderatorID string) error {
+	if !s.Enabled() {
+		return ErrTranscriptionDisabled
+	}
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	if !state.Transcribing {
+		return ErrNotTranscribing
+	}
+	state.Transcribing = false
+	state.UpdatedAt = time.Now()
+	if err := s.store.SaveRoomState(ctx, state); err != nil {
+		return err
+	}
+
+	streaming, err := s.streams(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	for trackID, egressID := range streaming {
+		_, err := s.client.StopEgress(ctx, &livekit.StopEgressRequest{EgressId: egressID})
+		metrics.ObserveLiveKit("StopEgress", err)
+		if err != nil {
+			// An egress left running ends with the room
+			log.Printf("Failed to stop transcribing %s in %s: %v", trackID, roomName, err)
+		}
+	}
+	s.events.Publish(RoomEvent{Type: EventTranscriptionStopped, RoomName: roomName, ActorID: moderatorID})
+	log.Printf("Transcription of %s stopped by %s", roomName, moderatorID)
+	return nil
+}
+
+// IsTranscribing reports whether the room is being transcribed.
+func (s *TranscriptionService) IsTranscribing(ctx context.Context, roomName string) (bool, error) {
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return false, err
+	}
+	return state.Transcribing, nil
+}
+
+// TrackPublished starts transcribing a microphone published while its room
+// is being transcribed.
+func (s *TranscriptionService) TrackPublished(ctx context.Context, roomName string, p *livekit.ParticipantInfo, track *livekit.TrackInfo) error {
+	if !s.Enabled() || !isMicrophone(track) {
+		return nil
+	}
+	transcribing, err := s.IsTranscribing(ctx, roomName)
+	if err != nil || !transcribing {
+		return err
+	}
+	return s.startStream(ctx, roomName, p, track)
+}
+
+// RoomFinished stops transcribing the room. Its egress streams end with it.
+func (s *TranscriptionService) RoomFinished(ctx context.Context, roomName string) error {
+	if !s.Enabled() {
+		return nil
+	}
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil || !state.Transcribing {
+		return err
+	}
+	state.Transcribing = false
+	state.UpdatedAt = time.Now()
+	return s.store.SaveRoomState(ctx, state)
+}
+
+// Transcript returns the room's transcript in the order it was spoken.
+func (s *TranscriptionService) Transcript(ctx context.Context, roomName string) ([]*TranscriptSegment, error) {
+	return s.store.ListTranscript(ctx, roomName)
+}
+
+func (s *TranscriptionService) DeleteTranscript(ctx context.Context, roomName, deletedBy string) error {
+	if err := s.store.DeleteTranscript(ctx, roomName); err != nil {
+		return err
+	}
+	log.Printf("Transcript of %s deleted by %s", roomName, deletedBy)
+	return nil
+}
+
+// startStream has egress stream the track's audio to the module, with a
+// token saying whose it is.
+func (s *TranscriptionService) startStream(ctx context.Context, roomName string, p *livekit.ParticipantInfo, track *livekit.TrackInfo) error {
+	token, err := s.sign(transcriptionClaims{Room: roomName, Identity: p.Identity, Name: p.Name, Track: track.Sid})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.StartTrackEgress(ctx, &livekit.TrackEgressRequest{
+		RoomName: roomName,
+		TrackId:  track.Sid,
+		Output:   &livekit.TrackEgressRequest_WebsocketUrl{WebsocketUrl: s.options.StreamURL + "?token=" + url.QueryEscape(token)},
+	})
+	metrics.ObserveLiveKit("StartTrackEgress", err)
+	if err != nil {
+		return fmt.Errorf("failed to start transcription: %w", err)
+	}
+	return nil
+}
+
+// streams returns the room's running transcription egresses by track.
+func (s *TranscriptionService) streams(ctx context.Context, roomName string) (map[string]string, error) {
+	resp, err := s.client.ListEgress(ctx, &livekit.ListEgressRequest{RoomName: roomName, Active: true})
+	metrics.ObserveLiveKit("ListEgress", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list egress: %w", err)
+	}
+	streams := make(map[string]string)
+	for _, info := range resp.Items {
+		track := info.GetTrack()
+		if track == nil || !strings.HasPrefix(track.GetWebsocketUrl(), s.options.StreamURL+"?") {
+			continue
+		}
+		if info.Status == livekit.EgressStatus_EGRESS_STARTING || info.Status == livekit.EgressStatus_EGRESS_ACTIVE {
+			streams[track.TrackId] = info.EgressId
+		}
+	}
+	return streams, nil
+}
+
+func (s *TranscriptionService) sign(claims transcriptionClaims) (string, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(s.options.Secret)}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign stream token: %w", err)
+	}
+	now := time.Now()
+	token, err := jwt.Signed(signer).
+		Claims(jwt.Claims{
+			Issuer:   transcriptionIssuer,
+			Subject:  transcriptionSubject,
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(transcriptionTokenTTL)),
+		}).
+		Claims(claims).
+		CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign stream token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *TranscriptionService) verify(token string) (*transcriptionClaims, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, ErrInvalidStream
+	}
+	var registered jwt.Claims
+	var claims transcriptionClaims
+	if err := parsed.Claims([]byte(s.options.Secret), &registered, &claims); err != nil {
+		return nil, ErrInvalidStream
+	}
+	err = registered.ValidateWithLeeway(jwt.Expected{Issuer: transcriptionIssuer, Subject: transcriptionSubject, Time: time.Now()}, time.Minute)
+	if err != nil || claims.Room == "" || claims.Track == "" {
+		return nil, ErrInvalidStream
+	}
+	return &claims, nil
+}
+
+// TranscriptionStream is one track's audio as egress streams it. Audio
+// written to it is cut into chunks, which are transcribed in order in the
+// background.
+type TranscriptionStream struct {
+	service    *TranscriptionService
+	claims     *transcriptionClaims
+	format     AudioFormat
+	chunkBytes int
+	buf        []byte
+	bufStart   time.Time
+	chunks     chan audioChunk
+	done       chan struct{}
+}
+
+type audioChunk struct {
+	pcm       []byte
+	startedAt time.Time
+	endedAt   time.Time
+}
+
+// OpenStream checks an egress stream's token and that its room is still
+// being transcribed, and starts transcribing the audio written to it.
+func (s *TranscriptionService) OpenStream(ctx context.Context, token string, format AudioFormat) (*TranscriptionStream, error) {
+	if !s.Enabled() {
+		return nil, ErrTranscriptionDisabled
+	}
+	claims, err := s.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	transcribing, err := s.IsTranscribing(ctx, claims.Room)
+	if err != nil {
+		return nil, err
+	}
+	if !transcribing {
+		return nil, ErrNotTranscribing
+	}
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		format = DefaultAudioFormat
+	}
+
+	stream := &TranscriptionStream{
+		service:    s,
+		claims:     claims,
+		format:     format,
+		chunkBytes: int(s.options.Chunk.Seconds()*float64(format.SampleRate)) * format.Channels * 2,
+		chunks:     make(chan audioChunk, transcriptionQueueSize),
+		done:       make(chan struct{}),
+	}
+	go stream.run()
+	return stream, nil
+}
+
+// Write adds audio to the stream, queueing a chunk for transcription each
+// time there is enough.
+func (t *TranscriptionStream) Write(pcm []byte) {
+	if len(t.buf) == 0 {
+		t.bufStart = time.Now()
+	}
+	t.buf = append(t.buf, pcm...)
+	if len(t.buf) >= t.chunkBytes {
+		t.Flush()
+	}
+}
+
+// Flush queues the audio written so far, such as when the track is muted
+// mid-sentence.
+func (t *TranscriptionStream) Flush() {
+	frame := 2 * t.format.Channels
+	samples := len(t.buf) / frame
+	if samples == 0 {
+		return
+	}
+	chunk := audioChunk{
+		pcm:       t.buf[:samples*frame],
+		startedAt: t.bufStart,
+		endedAt:   t.bufStart.Add(time.Duration(samples) * time.Second / time.Duration(t.format.SampleRate)),
+	}
+	t.buf = nil
+	select {
+	case t.chunks <- chunk:
+	default:
+		log.Printf("Transcription of %s in %s is falling behind, dropping %s of audio",
+			t.claims.Track, t.claims.Room, chunk.endedAt.Sub(chunk.startedAt).Round(time.Millisecond))
+	}
+}
+
+// Close flushes the stream and waits for its queued chunks.
+func (t *TranscriptionStream) Close() {
+	t.Flush()
+	close(t.chunks)
+	<-t.done
+}
+
+func (t *TranscriptionStream) run() {
+	defer close(t.done)
+	for chunk := range t.chunks {
+		t.transcribe(chunk)
+	}
+}
+
+// transcribe sends a chunk to the backend and captions the room with any
+// speech in it. Failures are logged, losing only the chunk.
+func (t *TranscriptionStream) transcribe(chunk audioChunk) {
+	mono := downmix(chunk.pcm, t.format.Channels)
+	if rms(mono) < silenceThreshold {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transcribeTimeout)
+	defer cancel()
+	s := t.service
+	text, err := s.transcriber.Transcribe(ctx, wav(mono, t.format.SampleRate), s.options.Language)
+	if err != nil {
+		log.Printf("Failed to transcribe %s in %s: %v", t.claims.Track, t.claims.Room, err)
+		return
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return
+	}
+
+	segment := &TranscriptSegment{
+		RoomName:  t.claims.Room,
+		UserID:    t.claims.Identity,
+		UserName:  t.claims.Name,
+		TrackID:   t.claims.Track,
+		Text:      text,
+		StartedAt: chunk.startedAt,
+		EndedAt:   chunk.endedAt,
+	}
+	if err := s.store.AddTranscriptSegment(ctx, segment); err != nil {
+		log.Printf("Failed to store transcript of %s: %v", t.claims.Room, err)
+	}
+	if err := s.rooms.NotifyRoom(ctx, t.claims.Room, CaptionTopic, segment); err != nil {
+		log.Printf("Failed to caption %s: %v", t.claims.Room, err)
+	}
+	s.events.Publish(RoomEvent{
+		Type:     EventCaption,
+		RoomName: t.claims.Room,
+		UserID:   t.claims.Identity,
+		Data:     map[string]string{"name": t.claims.Name, "text": text, "track_id": t.claims.Track},
+	})
+}
+
+// downmix averages interleaved 16-bit PCM to mono.
+func downmix(pcm []byte, channels int) []int16 {
+	frame := 2 * channels
+	mono := make([]int16, len(pcm)/frame)
+	for i := range mono {
+		sum := 0
+		for c := 0; c < channels; c++ {
+			sum += int(int16(binary.LittleEndian.Uint16(pcm[i*frame+2*c:])))
+		}
+		mono[i] = int16(sum / channels)
+	}
+	return mono
+}
+
+func rms(samples []int16) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, sample := range samples {
+		sum += float64(sample) * float64(sample)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// wav wraps mono 16-bit PCM in a WAV header.
+func wav(samples []int16, sampleRate int) []byte {
+	size := 2 * len(samples)
+	data := make([]byte, 44+size)
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(36+size))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16) // fmt chunk size
+	binary.LittleEndian.PutUint16(data[20:], 1)  // PCM
+	binary.LittleEndian.PutUint16(data[22:], 1)  // mono
+	binary.LittleEndian.PutUint32(data[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(data[28:], uint32(2*sampleRate)) // byte rate
+	binary.LittleEndian.PutUint16(data[32:], 2)                    // block align
+	binary.LittleEndian.PutUint16(data[34:], 16)                   // bits per sample
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(size))
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[44+2*i:], uint16(sample))
+	}
+	return data
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+// fakeTranscriber hears the same words in every chunk it is sent.
+type fakeTranscriber struct {
+	audio [][]byte
+	mu    sync.Mutex
+}
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, audio []byte, language string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.audio = append(f.audio, audio)
+	return " hello there ", nil
+}
+
+// tone is the seconds of a loud mono 16-bit sine wave at the rate.
+func tone(seconds float64, rate int) []byte {
+	pcm := make([]byte, 2*int(seconds*float64(rate)))
+	for i := 0; i < len(pcm)/2; i++ {
+		sample := int16(10000 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(sample))
+	}
+	return pcm
+}
+
+// streamToken returns the token egress was given for the track's stream.
+func streamToken(t *testing.T, lk *fakeLiveKit, trackID string) string {
+	t.Helper()
+	for _, call := range lk.egressCalls {
+		req, ok := call.(*livekit.TrackEgressRequest)
+		if !ok || req.TrackId != trackID {
+			continue
+		}
+		u, err := url.Parse(req.GetWebsocketUrl())
+		if err != nil || !strings.HasPrefix(req.GetWebsocketUrl(), "ws://rtc.test/transcription/ws?") {
+			t.Fatalf("Unexpected stream URL %q", req.GetWebsocketUrl())
+		}
+		return u.Query().Get("token")
+	}
+	t.Fatalf("No egress was started for %s", trackID)
+	return ""
+}
+
+func TestTranscriptionService_Captions(t *testing.T) {
+	ctx := context.Background()
+	lk, lkURL := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	roomEvents, unsubscribe := events.Subscribe("community_7_stage")
+	defer unsubscribe()
+	rooms := NewRoomService(lkURL, "key", "secret", store, events)
+	stt := &fakeTranscriber{}
+	options := TranscriptionOptions{StreamURL: "ws://rtc.test/transcription/ws", Secret: "stream-secret", Chunk: time.Second}
+	s := NewTranscriptionService(lkURL, "key", "secret", rooms, stt, options, store, events)
+	webhooks := NewWebhookService("key", "secret", store, nil, rooms, nil, nil, s, nil, events)
+
+	if disabled := NewTranscriptionService(lkURL, "key", "secret", rooms, nil, options, store, events); disabled.Enabled() {
+		t.Error("Expected transcription to be off without a backend")
+	}
+
+	store.SaveRoom(ctx, &storage.Room{RoomName: "community_7_stage", CommunityID: 7})
+	lk.participants["u1"] = &livekit.ParticipantInfo{Identity: "u1", Name: "One", Tracks: []*livekit.TrackInfo{
+		{Sid: "TR_mic", Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_MICROPHONE},
+		{Sid: "TR_cam", Type: livekit.TrackType_VIDEO, Source: livekit.TrackSource_CAMERA},
+		{Sid: "TR_screen", Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_SCREEN_SHARE_AUDIO},
+	}}
+
+	started, err := s.StartTranscription(ctx, "community_7_stage", "mod")
+	if err != nil || started != 1 {
+		t.Fatalf("Expected only the microphone to be transcribed, got %d, %v", started, err)
+	}
+	if event := <-roomEvents; event.Type != EventTranscriptionStarted || event.ActorID != "mod" {
+		t.Errorf("Expected a transcription_started event, got %+v", event)
+	}
+	if _, err := s.StartTranscription(ctx, "community_7_stage", "mod"); !errors.Is(err, ErrAlreadyTranscribing) {
+		t.Errorf("Expected a second start to fail, got %v", err)
+	}
+
+	// Microphones published later are picked up from the webhook
+	event, err := webhooks.Receive(signedWebhook(t, "secret", `{"event":"track_published","room":{"name":"community_7_stage"},"participant":{"identity":"u2","name":"Two"},"track":{"sid":"TR_mic2","type":"AUDIO","source":"MICROPHONE"}}`))
+	if err != nil {
+		t.Fatalf("Failed to receive webhook: %v", err)
+	}
+	if err := webhooks.HandleEvent(ctx, event); err != nil {
+		t.Fatalf("Failed to handle webhook: %v", err)
+	}
+	if len(lk.egressCalls) != 2 {
+		t.Fatalf("Expected the new microphone to be transcribed, got %d egresses", len(lk.egressCalls))
+	}
+
+	if _, err := s.OpenStream(ctx, "not-a-token", DefaultAudioFormat); !errors.Is(err, ErrInvalidStream) {
+		t.Errorf("Expected a bad token to be refused, got %v", err)
+	}
+	stream, err := s.OpenStream(ctx, streamToken(t, lk, "TR_mic"), AudioFormat{SampleRate: 16000, Channels: 1})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	// A second of speech makes a chunk; the silence after it is skipped
+	stream.Write(tone(0.5, 16000))
+	stream.Write(tone(0.5, 16000))
+	stream.Write(make([]byte, 16000))
+	stream.Close()
+
+	if len(stt.audio) != 1 || !strings.HasPrefix(string(stt.audio[0]), "RIFF") || len(stt.audio[0]) != 44+2*16000 {
+		t.Fatalf("Expected one second of WAV to be transcribed, got %d chunks", len(stt.audio))
+	}
+	segments, _ := s.Transcript(ctx, "community_7_stage")
+	if len(segments) != 1 || segments[0].Text != "hello there" || segments[0].UserID != "u1" || segments[0].UserName != "One" || segments[0].TrackID != "TR_mic" {
+		t.Errorf("Expected the speech in the transcript, got %+v", segments)
+	}
+	if d := segments[0].EndedAt.Sub(segments[0].StartedAt); d != time.Second {
+		t.Errorf("Expected the segment to last a second, got %s", d)
+	}
+	if len(lk.sent) != 1 || lk.sent[0].GetTopic() != CaptionTopic || !strings.Contains(string(lk.sent[0].Data), `"text":"hello there"`) {
+		t.Errorf("Expected a caption sent to the room, got %+v", lk.sent)
+	}
+	if event := <-roomEvents; event.Type != EventCaption || event.UserID != "u1" || event.Data["text"] != "hello there" {
+		t.Errorf("Expected a caption event, got %+v", event)
+	}
+
+	if err := s.StopTranscription(ctx, "community_7_stage", "mod"); err != nil {
+		t.Fatalf("Failed to stop transcription: %v", err)
+	}
+	for id, info := range lk.egress {
+		if info.Status != livekit.EgressStatus_EGRESS_ENDING {
+			t.Errorf("Expected egress %s to be stopped", id)
+		}
+	}
+	if _, err := s.OpenStream(ctx, streamToken(t, lk, "TR_mic2"), DefaultAudioFormat); !errors.Is(err, ErrNotTranscribing) {
+		t.Errorf("Expected streams to be refused once stopped, got %v", err)
+	}
+	if err := s.StopTranscription(ctx, "community_7_stage", "mod"); !errors.Is(err, ErrNotTranscribing) {
+		t.Errorf("Expected a second stop to fail, got %v", err)
+	}
+}
+
+func TestParseAudioFormat(t *testing.T) {
+	for contentType, want := range map[string]AudioFormat{
+		"":                                   DefaultAudioFormat,
+		"audio/x-raw":                        DefaultAudioFormat,
+		"audio/x-raw,rate=16000,channels=1":  {SampleRate: 16000, Channels: 1},
+		"audio/x-raw; channels=1":            {SampleRate: 48000, Channels: 1},
+		"audio/x-raw; rate=nope; channels=0": DefaultAudioFormat,
+	} {
+		if got := ParseAudioFormat(contentType); got != want {
+			t.Errorf("ParseAudioFormat(%q) = %+v, want %+v", contentType, got, want)
+		}
+	}
+}
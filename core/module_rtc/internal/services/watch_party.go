@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+type WatchParty = storage.WatchParty
+
+// WatchTopic is the data topic participants are kept in sync on while the
+// room watches something together.
+const WatchTopic = "waddlebot.watch"
+
+// How a host controls playback.
+const (
+	WatchPlay  = "play"
+	WatchPause = "pause"
+	WatchSeek  = "seek"
+)
+
+const maxMediaURLLength = 2048
+
+var (
+	ErrInvalidWatchParty = errors.New("invalid watch party")
+	ErrNoWatchParty      = errors.New("the room is not watching anything")
+)
+
+// WatchSync is a watch party as it stood at ServerTime, in milliseconds
+// since the epoch: playing or paused Position seconds into the media.
+// Clients play from Position plus the time since ServerTime, correcting
+// their clock by its offset from the server's, so everyone follows the
+// server rather than each other.
+type WatchSync struct {
+	Type       string  `json:"type,omitempty"`
+	Action     string  `json:"action,omitempty"`
+	MediaURL   string  `json:"media_url,omitempty"`
+	Playing    bool    `json:"playing"`
+	Position   float64 `json:"position"`
+	ServerTime int64   `json:"server_time"`
+	UpdatedBy  string  `json:"updated_by,omitempty"`
+}
+
+// watchSync is the party's state at now.
+func watchSync(party *WatchParty, now time.Time) *WatchSync {
+	return &WatchSync{
+		MediaURL:   party.MediaURL,
+		Playing:    party.Playing,
+		Position:   watchPosition(party, now),
+		ServerTime: now.UnixMilli(),
+		UpdatedBy:  party.UpdatedBy,
+	}
+}
+
+// watchPosition is how far into the media playback is at now.
+func watchPosition(party *WatchParty, now time.Time) float64 {
+	if !party.Playing {
+		return party.Position
+	}
+	return party.Position + now.Sub(party.UpdatedAt).Seconds()
+}
+
+// StartWatchParty has the room watch the media at the URL, paused at its
+// start, replacing anything it was watching.
+func (s *CallFeaturesService) StartWatchParty(ctx context.Context, roomName, mediaURL, hostID string) (*WatchSync, error) {
+	if err := checkMediaURL(mediaURL); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	party := &WatchParty{
+		RoomName:  roomName,
+		MediaURL:  mediaURL,
+		StartedBy: hostID,
+		UpdatedBy: hostID,
+		UpdatedAt: now,
+	}
+	if err := s.store.SaveWatchParty(ctx, party); err != nil {
+		return nil, err
+	}
+
+	sync := watchSync(party, now)
+	sync.Type = EventWatchStarted
+	s.syncWatchParty(ctx, roomName, sync)
+	log.Printf("Watch party in %s started by %s", roomName, hostID)
+	return sync, nil
+}
+
+// ControlWatchParty plays, pauses or seeks what the room is watching.
+// Playing or pausing without a position does so where playback is now;
+// seeking needs one and keeps playing or paused.
+func (s *CallFeaturesService) ControlWatchParty(ctx context.Context, roomName, action string, position *float64, hostID string) (*WatchSync, error) {
+	if position != nil && (*position < 0 || math.IsNaN(*position) || math.IsInf(*position, 0)) {
+		return nil, fmt.Errorf("%w: position must be a number of seconds from the start", ErrInvalidWatchParty)
+	}
+	party, err := s.watchParty(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	party.Position = watchPosition(party, now)
+	switch action {
+	case WatchPlay:
+		party.Playing = true
+	case WatchPause:
+		party.Playing = false
+	case WatchSeek:
+		if position == nil {
+			return nil, fmt.Errorf("%w: seeking needs a position", ErrInvalidWatchParty)
+		}
+	default:
+		return nil, fmt.Errorf("%w: action must be play, pause or seek", ErrInvalidWatchParty)
+	}
+	if position != nil {
+		party.Position = *position
+	}
+	party.UpdatedBy = hostID
+	party.UpdatedAt = now
+	if err := s.store.SaveWatchParty(ctx, party); err != nil {
+		return nil, err
+	}
+
+	sync := watchSync(party, now)
+	sync.Type = EventWatchSynced
+	sync.Action = action
+	s.syncWatchParty(ctx, roomName, sync)
+	return sync, nil
+}
+
+// GetWatchParty returns what the room is watching, as it stands now.
+func (s *CallFeaturesService) GetWatchParty(ctx context.Context, roomName string) (*WatchSync, error) {
+	party, err := s.watchParty(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	return watchSync(party, time.Now()), nil
+}
+
+// StopWatchParty ends what the room is watching.
+func (s *CallFeaturesService) StopWatchParty(ctx context.Context, roomName, hostID string) error {
+	if _, err := s.watchParty(ctx, roomName); err != nil {
+		return err
+	}
+	if err := s.store.DeleteWatchParty(ctx, roomName); err != nil {
+		return err
+	}
+
+	s.syncWatchParty(ctx, roomName, &WatchSync{Type: EventWatchEnded, ServerTime: time.Now().UnixMilli(), UpdatedBy: hostID})
+	log.Printf("Watch party in %s stopped by %s", roomName, hostID)
+	return nil
+}
+
+func (s *CallFeaturesService) watchParty(ctx context.Context, roomName string) (*WatchParty, error) {
+	party, err := s.store.GetWatchParty(ctx, roomName)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNoWatchParty
+	}
+	return party, err
+}
+
+// syncWatchParty sends the sync to everyone in the room and publishes it as
+// an event. The party is saved by then, so clients that miss the message
+// can still get it, and a failure is only logged.
+func (s *CallFeaturesService) syncWatchParty(ctx context.Context, roomName string, sync *WatchSync) {
+	if err := s.roomService.NotifyRoom(ctx, roomName, WatchTopic, sync); err != nil && !isNotFound(err) {
+		log.Printf("Failed to send %s to %s: %v", sync.Type, roomName, err)
+	}
+
+	data := map[string]string{
+		"playing":  strconv.FormatBool(sync.Playing),
+		"position": strconv.FormatFloat(sync.Position, 'f', 3, 64),
+	}
+	if sync.MediaURL != "" {
+		data["media_url"] = sync.MediaURL
+	}
+	if sync.Action != "" {
+		data["action"] = sync.Action
+	}
+	s.events.Publish(RoomEvent{Type: sync.Type, RoomName: roomName, ActorID: sync.UpdatedBy, Data: data})
+}
+
+func checkMediaURL(mediaURL string) error {
+	if mediaURL == "" {
+		return fmt.Errorf("%w: media_url is required", ErrInvalidWatchParty)
+	}
+	if len(mediaURL) > maxMediaURLLength {
+		return fmt.Errorf("%w: media_url must be at most %d characters", ErrInvalidWatchParty, maxMediaURLLength)
+	}
+	u, err := url.Parse(mediaURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: media_url must be an http or https URL", ErrInvalidWatchParty)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func TestCallFeaturesService_WatchParty(t *testing.T) {
+	ctx := context.Background()
+	lk, url := newFakeLiveKit(t)
+	store := storage.NewMemoryStore()
+	events := NewEventBus()
+	s := NewCallFeaturesService(NewRoomService(url, "key", "secret", store, events), store, nil, events)
+	roomEvents, unsubscribe := events.Subscribe("room")
+	defer unsubscribe()
+
+	if _, err := s.GetWatchParty(ctx, "room"); !errors.Is(err, ErrNoWatchParty) {
+		t.Errorf("Expected no watch party before one is started, got %v", err)
+	}
+	for _, mediaURL := range []string{"", "ftp://example.com/film.mp4", "https://", "not a url"} {
+		if _, err := s.StartWatchParty(ctx, "room", mediaURL, "host"); !errors.Is(err, ErrInvalidWatchParty) {
+			t.Errorf("Expected %q refused, got %v", mediaURL, err)
+		}
+	}
+
+	sync, err := s.StartWatchParty(ctx, "room", "https://example.com/film.mp4", "host")
+	if err != nil {
+		t.Fatalf("Failed to start watch party: %v", err)
+	}
+	if sync.Playing || sync.Position != 0 || sync.ServerTime == 0 {
+		t.Errorf("Expected the party to start paused at the start, got %+v", sync)
+	}
+	var sent WatchSync
+	if len(lk.sent) != 1 || lk.sent[0].GetTopic() != WatchTopic || json.Unmarshal(lk.sent[0].Data, &sent) != nil || sent.Type != EventWatchStarted || sent.MediaURL != sync.MediaURL {
+		t.Fatalf("Expected the room sent the party on %s, got %+v", WatchTopic, lk.sent)
+	}
+	if event := <-roomEvents; event.Type != EventWatchStarted || event.Data["media_url"] != sync.MediaURL || event.ActorID != "host" {
+		t.Errorf("Expected a watch_started event, got %+v", event)
+	}
+
+	// Playback carries on from where it was played until it is paused
+	if _, err := s.ControlWatchParty(ctx, "room", WatchPlay, nil, "host"); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+	party, _ := store.GetWatchParty(ctx, "room")
+	party.UpdatedAt = party.UpdatedAt.Add(-10 * time.Second)
+	store.SaveWatchParty(ctx, party)
+	if sync, _ := s.GetWatchParty(ctx, "room"); !sync.Playing || sync.Position < 10 || sync.Position > 11 {
+		t.Errorf("Expected playback about 10 seconds in, got %+v", sync)
+	}
+	sync, err = s.ControlWatchParty(ctx, "room", WatchPause, nil, "cohost")
+	if err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+	if sync.Playing || sync.Position < 10 || sync.UpdatedBy != "cohost" {
+		t.Errorf("Expected playback paused where it was, got %+v", sync)
+	}
+	<-roomEvents
+	if event := <-roomEvents; event.Type != EventWatchSynced || event.Data["action"] != WatchPause || event.Data["playing"] != "false" {
+		t.Errorf("Expected a watch_synced event for the pause, got %+v", event)
+	}
+
+	position := 42.5
+	if sync, err := s.ControlWatchParty(ctx, "room", WatchSeek, &position, "host"); err != nil || sync.Playing || sync.Position != position {
+		t.Errorf("Expected a seek to keep playback paused at %v, got %+v, %v", position, sync, err)
+	}
+	if _, err := s.ControlWatchParty(ctx, "room", WatchSeek, nil, "host"); !errors.Is(err, ErrInvalidWatchParty) {
+		t.Errorf("Expected a seek without a position refused, got %v", err)
+	}
+	for _, position := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if _, err := s.ControlWatchParty(ctx, "room", WatchPlay, &position, "host"); !errors.Is(err, ErrInvalidWatchParty) {
+			t.Errorf("Expected position %v refused, got %v", position, err)
+		}
+	}
+	if _, err := s.ControlWatchParty(ctx, "room", "rewind", nil, "host"); !errors.Is(err, ErrInvalidWatchParty) {
+		t.Errorf("Expected an unknown action refused, got %v", err)
+	}
+
+	if err := s.StopWatchParty(ctx, "room", "host"); err != nil {
+		t.Fatalf("Failed to stop watch party: %v", err)
+	}
+	if _, err := s.GetWatchParty(ctx, "room"); !errors.Is(err, ErrNoWatchParty) {
+		t.Errorf("Expected the party gone once stopped, got %v", err)
+	}
+	if err := s.StopWatchParty(ctx, "room", "host"); !errors.Is(err, ErrNoWatchParty) {
+		t.Errorf("Expected stopping twice to find no party, got %v", err)
+	}
+	if _, err := s.ControlWatchParty(ctx, "room", WatchPlay, nil, "host"); !errors.Is(err, ErrNoWatchParty) {
+		t.Errorf("Expected no party to control once stopped, got %v", err)
+	}
+}
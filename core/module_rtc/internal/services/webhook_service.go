@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+	"github.com/penguintech/waddlebot/module_rtc/internal/hub"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+// WebhookService applies LiveKit room lifecycle and egress webhooks to the
+// stored state.
+type WebhookService struct {
+	keyProvider auth.KeyProvider
+	store       storage.Store
+	hub         *hub.Client
+	rooms       *RoomService
+	recordings  *RecordingService
+	broadcasts  *BroadcastService
+	transcripts *TranscriptionService
+	analytics   *AnalyticsService
+	events      *EventBus
+}
+
+func NewWebhookService(apiKey, apiSecret string, store storage.Store, hubClient *hub.Client, rooms *RoomService, recordings *RecordingService, broadcasts *BroadcastService, transcripts *TranscriptionService, analytics *AnalyticsService, events *EventBus) *WebhookService {
+	return &WebhookService{
+		keyProvider: auth.NewSimpleKeyProvider(apiKey, apiSecret),
+		store:       store,
+		hub:         hubClient,
+		rooms:       rooms,
+		recordings:  recordings,
+		broadcasts:  broadcasts,
+		transcripts: transcripts,
+		analytics:   analytics,
+		events:      events,
+	}
+}
+
+// Receive verifies the webhook's signature against the LiveKit API secret
+// and decodes its event.
+func (s *WebhookService) Receive(r *http.Request) (*livekit.WebhookEvent, error) {
+	return webhook.ReceiveWebhookEvent(r, s.keyProvider)
+}
+
+func (s *WebhookService) HandleEvent(ctx context.Context, event *livekit.WebhookEvent) error {
+	switch event.Event {
+	case webhook.EventEgressStarted, webhook.EventEgressUpdated, webhook.EventEgressEnded:
+		if event.EgressInfo == nil {
+			return nil
+		}
+		if s.recordings != nil {
+			if _, err := s.recordings.HandleEgressUpdate(ctx, event.EgressInfo); err != nil {
+				return err
+			}
+		}
+		if s.broadcasts != nil {
+			if _, err := s.broadcasts.HandleEgressUpdate(ctx, event.EgressInfo); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if event.Room == nil {
+		return nil
+	}
+	roomName := event.Room.Name
+
+	switch event.Event {
+	case webhook.EventRoomStarted:
+		if err := s.roomStarted(ctx, event.Room); err != nil {
+			return err
+		}
+		s.events.Publish(RoomEvent{Type: EventRoomStarted, RoomName: roomName})
+
+	case webhook.EventRoomFinished:
+		if err := s.store.ClearRaisedHands(ctx, roomName); err != nil {
+			return err
+		}
+		if err := s.store.ClearParticipants(ctx, roomName); err != nil {
+			return err
+		}
+		if err := s.transcripts.RoomFinished(ctx, roomName); err != nil {
+			return err
+		}
+		s.analytics.RoomFinished(ctx, roomName, time.Now())
+		s.events.Publish(RoomEvent{Type: EventRoomFinished, RoomName: roomName})
+
+	case webhook.EventParticipantJoined:
+		if event.Participant == nil {
+			return nil
+		}
+		// Join tokens outlive bans and revocation, so banned users or those
+		// holding a revoked token are removed as soon as they are in. Phone
+		// callers only need the room's PIN, so locked rooms are kept locked
+		// here too.
+		refused, err := s.refuseJoin(ctx, roomName, event.Participant)
+		if err != nil {
+			return err
+		}
+		if refused != "" {
+			if s.rooms == nil {
+				return nil
+			}
+			log.Printf("Removing %s from %s: %s", event.Participant.Identity, roomName, refused)
+			if err := s.rooms.KickParticipant(ctx, roomName, event.Participant.Identity); err != nil && !isNotFound(err) {
+				return err
+			}
+			return nil
+		}
+		joinedAt := time.Now()
+		if event.Participant.JoinedAt > 0 {
+			joinedAt = time.Unix(event.Participant.JoinedAt, 0)
+		}
+		if err := s.store.AddParticipant(ctx, roomName, &storage.Participant{
+			Identity: event.Participant.Identity,
+			Name:     event.Participant.Name,
+			JoinedAt: joinedAt,
+		}); err != nil {
+			return err
+		}
+		data := map[string]string{"name": event.Participant.Name}
+		if metadataLobby(event.Participant.Metadata) {
+			data["lobby"] = "true"
+		}
+		s.events.Publish(RoomEvent{
+			Type:     EventParticipantJoined,
+			RoomName: roomName,
+			UserID:   event.Participant.Identity,
+			Data:     data,
+		})
+		s.analytics.ParticipantJoined(ctx, roomName, event.Participant.Identity, event.Participant.Name, joinedAt)
+		s.recordHub(ctx, hub.EventJoin, roomName, event.Participant)
+
+	case webhook.EventParticipantLeft:
+		if event.Participant == nil {
+			return nil
+		}
+		if err := s.store.RemoveParticipant(ctx, roomName, event.Participant.Identity); err != nil {
+			return err
+		}
+		lowered, err := s.store.RemoveRaisedHand(ctx, roomName, event.Participant.Identity)
+		if err != nil {
+			return err
+		}
+		if lowered {
+			s.events.Publish(RoomEvent{Type: EventHandLowered, RoomName: roomName, UserID: event.Participant.Identity})
+		}
+		s.events.Publish(RoomEvent{Type: EventParticipantLeft, RoomName: roomName, UserID: event.Participant.Identity})
+		s.analytics.ParticipantLeft(ctx, roomName, event.Participant.Identity, time.Now())
+		s.recordHub(ctx, hub.EventLeave, roomName, event.Participant)
+
+	case webhook.EventTrackPublished:
+		if event.Room == nil || event.Participant == nil || event.Track == nil || s.rooms == nil {
+			return nil
+		}
+		rejected, err := s.rooms.EnforceCodecs(ctx, event.Room, event.Participant, event.Track)
+		if err != nil || rejected {
+			return err
+		}
+		if err := s.transcripts.TrackPublished(ctx, roomName, event.Participant, event.Track); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refuseJoin returns why the participant may not be in the room, or "" if
+// they may.
+func (s *WebhookService) refuseJoin(ctx context.Context, roomName string, p *livekit.ParticipantInfo) (string, error) {
+	ban, err := activeBan(ctx, s.store, roomName, p.Identity)
+	if err != nil || ban != nil {
+		return "banned", err
+	}
+	if !isPhoneParticipant(p) {
+		revoked, err := tokenRevoked(ctx, s.store, roomName, p.Identity)
+		if err != nil || !revoked {
+			return "", err
+		}
+		return "token revoked", nil
+	}
+	state, err := s.store.GetRoomState(ctx, roomName)
+	if err != nil || !state.IsLocked {
+		return "", err
+	}
+	return "room is locked", nil
+}
+
+// roomStarted records rooms LiveKit created on its own, such as on first join.
+func (s *WebhookService) roomStarted(ctx context.Context, room *livekit.Room) error {
+	_, err := s.store.GetRoom(ctx, room.Name)
+	if err == nil || !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	createdAt := time.Now()
+	if room.CreationTime > 0 {
+		createdAt = time.Unix(room.CreationTime, 0)
+	}
+	return s.store.SaveRoom(ctx, &storage.Room{
+		RoomName:        room.Name,
+		RoomID:          room.Sid,
+		CommunityID:     communityFromRoomName(room.Name),
+		MaxParticipants: room.MaxParticipants,
+		CreatedAt:       createdAt,
+	})
+}
+
+// recordHub reports a participant to the hub. Failures are logged rather than
+// returned so LiveKit does not retry a webhook the state already reflects.
+func (s *WebhookService) recordHub(ctx context.Context, eventType, roomName string, participant *livekit.ParticipantInfo) {
+	if !s.hub.Enabled() {
+		return
+	}
+
+	communityID, err := lookupCommunityID(ctx, s.store, roomName)
+	if err != nil || communityID == 0 {
+		return
+	}
+
+	if err := s.hub.RecordParticipant(ctx, eventType, communityID, roomName, participant.Identity, participant.Name); err != nil {
+		log.Printf("Failed to record %s for %s in %s with hub: %v", eventType, participant.Identity, roomName, err)
+	}
+}
+
+// lookupCommunityID returns the community a room belongs to, from its stored
+// record or else its name, or 0 if neither says.
+func lookupCommunityID(ctx context.Context, store storage.Store, roomName string) (int, error) {
+	room, err := store.GetRoom(ctx, roomName)
+	if err == nil {
+		return room.CommunityID, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return 0, err
+	}
+	return communityFromRoomName(roomName), nil
+}
+
+// communityFromRoomName reads the community from names made by CreateRoom,
+// community_<id>_<name>, returning 0 for others.
+func communityFromRoomName(roomName string) int {
+	rest, ok := strings.CutPrefix(roomName, "community_")
+	if !ok {
+		return 0
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return 0
+	}
+	communityID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0
+	}
+	return communityID
+}
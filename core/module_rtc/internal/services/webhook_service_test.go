@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/penguintech/waddlebot/module_rtc/internal/hub"
+	"github.com/penguintech/waddlebot/module_rtc/internal/storage"
+)
+
+func signedWebhook(t *testing.T, secret, body string) *http.Request {
+	t.Helper()
+	sum := sha256.Sum256([]byte(body))
+	token, err := auth.NewAccessToken("key", secret).SetSha256(base64.StdEncoding.EncodeToString(sum[:])).ToJWT()
+	if err != nil {
+		t.Fatalf("Failed to sign webhook: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/webhooks/livekit", strings.NewReader(body))
+	req.Header.Set("Authorization", token)
+	return req
+}
+
+func TestWebhookService(t *testing.T) {
+	var hubEvents []map[string]interface{}
+	hubServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Service-Key") != "service" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+		hubEvents = append(hubEvents, event)
+	}))
+	defer hubServer.Close()
+
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	analytics := NewAnalyticsService(store)
+	s := NewWebhookService("key", "secret", store, hub.NewClient(hubServer.URL, "service"), nil, nil, nil, nil, analytics, nil)
+	features := NewCallFeaturesService(nil, store, analytics, nil)
+
+	if _, err := s.Receive(signedWebhook(t, "wrong", `{"event":"room_started"}`)); err == nil {
+		t.Error("Expected a webhook signed with another secret to be rejected")
+	}
+
+	deliver := func(body string) {
+		t.Helper()
+		event, err := s.Receive(signedWebhook(t, "secret", body))
+		if err != nil {
+			t.Fatalf("Failed to receive webhook: %v", err)
+		}
+		if err := s.HandleEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to handle %s: %v", event.Event, err)
+		}
+	}
+
+	deliver(`{"event":"room_started","room":{"sid":"RM_1","name":"community_42_lobby"}}`)
+	room, err := store.GetRoom(ctx, "community_42_lobby")
+	if err != nil || room.CommunityID != 42 || room.RoomID != "RM_1" {
+		t.Fatalf("Expected the started room to be stored, got %+v, %v", room, err)
+	}
+
+	deliver(`{"event":"participant_joined","room":{"name":"community_42_lobby"},"participant":{"identity":"u1","name":"One"}}`)
+	deliver(`{"event":"participant_joined","room":{"name":"community_42_lobby"},"participant":{"identity":"u2","name":"Two"}}`)
+	features.RaiseHand(ctx, "community_42_lobby", "u1", "One")
+	features.RaiseHand(ctx, "community_42_lobby", "u2", "Two")
+
+	deliver(`{"event":"participant_left","room":{"name":"community_42_lobby"},"participant":{"identity":"u1","name":"One"}}`)
+	participants, _ := store.ListParticipants(ctx, "community_42_lobby")
+	if len(participants) != 1 || participants[0].Identity != "u2" {
+		t.Errorf("Expected only u2 to be left, got %+v", participants)
+	}
+	hands, _ := features.GetRaisedHands(ctx, "community_42_lobby")
+	if len(hands) != 1 || hands[0].UserID != "u2" {
+		t.Errorf("Expected u1's hand to be lowered on leaving, got %+v", hands)
+	}
+
+	if len(hubEvents) != 3 || hubEvents[2]["eventType"] != hub.EventLeave || hubEvents[2]["channelId"] != "community_42_lobby" || hubEvents[2]["communityId"] != float64(42) {
+		t.Errorf("Expected two joins and a leave sent to the hub, got %+v", hubEvents)
+	}
+
+	deliver(`{"event":"room_finished","room":{"name":"community_42_lobby"}}`)
+	participants, _ = store.ListParticipants(ctx, "community_42_lobby")
+	hands, _ = features.GetRaisedHands(ctx, "community_42_lobby")
+	if len(participants) != 0 || len(hands) != 0 {
+		t.Errorf("Expected the finished room to be cleared, got %+v and %+v", participants, hands)
+	}
+
+	stats, err := analytics.RoomAnalytics(ctx, "community_42_lobby", time.Now())
+	if err != nil || stats.CommunityID != 42 || stats.Participants != 2 || stats.PeakParticipants != 2 || stats.HandRaises != 2 || stats.CurrentParticipants != 0 {
+		t.Errorf("Expected the room's usage recorded, got %+v, %v", stats, err)
+	}
+}
+
+func TestCommunityFromRoomName(t *testing.T) {
+	tests := map[string]int{
+		"community_7_stage":      7,
+		"community_12_my_room":   12,
+		"community_x_stage":      0,
+		"community_7":            0,
+		"standalone":             0,
+		"community_007_archived": 7,
+	}
+	for name, want := range tests {
+		if got := communityFromRoomName(name); got != want {
+			t.Errorf("communityFromRoomName(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package sip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+const tokenTTL = 10 * time.Minute
+
+// Client manages LiveKit SIP trunks and dispatch rules. The LiveKit server
+// SDK this module uses predates SIP, so it calls LiveKit's Twirp API with
+// JSON bodies itself.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	apiSecret  string
+	httpClient *http.Client
+}
+
+func NewClient(host, apiKey, apiSecret string) *Client {
+	baseURL := strings.TrimRight(host, "/")
+	if strings.HasPrefix(baseURL, "ws") {
+		baseURL = strings.Replace(baseURL, "ws", "http", 1)
+	} else if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	return &Client{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		apiSecret:  apiSecret,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Error is an error returned by LiveKit's Twirp API.
+type Error struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("livekit sip: %s: %s", e.Code, e.Msg)
+}
+
+// IsNotFound reports whether LiveKit did not find the trunk or rule.
+func IsNotFound(err error) bool {
+	var sipErr *Error
+	return errors.As(err, &sipErr) && sipErr.Code == "not_found"
+}
+
+// InboundTrunk accepts calls to Numbers from the carrier's addresses, with
+// its credentials if set.
+type InboundTrunk struct {
+	ID               string   `json:"sip_trunk_id,omitempty"`
+	Name             string   `json:"name"`
+	Numbers          []string `json:"numbers"`
+	AllowedAddresses []string `json:"allowed_addresses,omitempty"`
+	AuthUsername     string   `json:"auth_username,omitempty"`
+	AuthPassword     string   `json:"auth_password,omitempty"`
+}
+
+// DispatchRule sends callers on its trunks who enter PIN to RoomName.
+// Participants it creates get Metadata.
+type DispatchRule struct {
+	ID       string
+	Name     string
+	TrunkIDs []string
+	RoomName string
+	PIN      string
+	Metadata string
+}
+
+type directRule struct {
+	RoomName string `json:"room_name"`
+	PIN      string `json:"pin,omitempty"`
+}
+
+type dispatchRule struct {
+	ID   string `json:"sip_dispatch_rule_id,omitempty"`
+	Rule struct {
+		Direct *directRule `json:"dispatch_rule_direct,omitempty"`
+	} `json:"rule"`
+	TrunkIDs []string `json:"trunk_ids,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Metadata string   `json:"metadata,omitempty"`
+}
+
+func (c *Client) CreateInboundTrunk(ctx context.Context, trunk *InboundTrunk) (*InboundTrunk, error) {
+	var created InboundTrunk
+	if err := c.call(ctx, "CreateSIPInboundTrunk", map[string]*InboundTrunk{"trunk": trunk}, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) ListInboundTrunks(ctx context.Context) ([]*InboundTrunk, error) {
+	var resp struct {
+		Items []*InboundTrunk `json:"items"`
+	}
+	if err := c.call(ctx, "ListSIPInboundTrunk", struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+func (c *Client) CreateDispatchRule(ctx context.Context, rule *DispatchRule) (*DispatchRule, error) {
+	req := dispatchRule{TrunkIDs: rule.TrunkIDs, Name: rule.Name, Metadata: rule.Metadata}
+	req.Rule.Direct = &directRule{RoomName: rule.RoomName, PIN: rule.PIN}
+
+	var resp dispatchRule
+	if err := c.call(ctx, "CreateSIPDispatchRule", req, &resp); err != nil {
+		return nil, err
+	}
+	created := *rule
+	created.ID = resp.ID
+	return &created, nil
+}
+
+func (c *Client) DeleteDispatchRule(ctx context.Context, id string) error {
+	return c.call(ctx, "DeleteSIPDispatchRule", map[string]string{"sip_dispatch_rule_id": id}, nil)
+}
+
+func (c *Client) call(ctx context.Context, method string, body, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/twirp/livekit.SIP/"+method, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		sipErr := &Error{}
+		if json.NewDecoder(resp.Body).Decode(sipErr) != nil || sipErr.Code == "" {
+			sipErr = &Error{Code: "unknown", Msg: resp.Status}
+		}
+		return sipErr
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	return nil
+}
+
+type sipClaims struct {
+	SIP struct {
+		Admin bool `json:"admin"`
+	} `json:"sip"`
+}
+
+// token signs a short-lived LiveKit access token granting SIP admin.
+func (c *Client) token() (string, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(c.apiSecret)}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token signer: %w", err)
+	}
+
+	var grant sipClaims
+	grant.SIP.Admin = true
+	now := time.Now()
+	token, err := jwt.Signed(signer).
+		Claims(jwt.Claims{
+			Issuer:    c.apiKey,
+			NotBefore: jwt.NewNumericDate(now),
+			Expiry:    jwt.NewNumericDate(now.Add(tokenTTL)),
+		}).
+		Claims(grant).
+		CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
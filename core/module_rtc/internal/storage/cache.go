@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	value    interface{}
+	loadedAt time.Time
+}
+
+// CachedStore reads through a short-lived cache in front of another store.
+// Writes go to the store first and drop the cached entry, so a replica's own
+// changes are seen at once and other replicas' changes within the TTL.
+type CachedStore struct {
+	Store
+	ttl     time.Duration
+	rooms   map[string]cacheEntry
+	states  map[string]cacheEntry
+	hands   map[string]cacheEntry
+	mu      sync.Mutex
+	nowFunc func() time.Time
+}
+
+func NewCachedStore(store Store, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		Store:   store,
+		ttl:     ttl,
+		rooms:   make(map[string]cacheEntry),
+		states:  make(map[string]cacheEntry),
+		hands:   make(map[string]cacheEntry),
+		nowFunc: time.Now,
+	}
+}
+
+func (s *CachedStore) lookup(cache map[string]cacheEntry, key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := cache[key]
+	if !ok || s.nowFunc().Sub(entry.loadedAt) >= s.ttl {
+		delete(cache, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (s *CachedStore) remember(cache map[string]cacheEntry, key string, value interface{}) {
+	if s.ttl <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cache[key] = cacheEntry{value: value, loadedAt: s.nowFunc()}
+}
+
+func (s *CachedStore) forget(roomName string, caches ...map[string]cacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cache := range caches {
+		delete(cache, roomName)
+	}
+}
+
+func (s *CachedStore) SaveRoom(ctx context.Context, room *Room) error {
+	defer s.forget(room.RoomName, s.rooms)
+	return s.Store.SaveRoom(ctx, room)
+}
+
+func (s *CachedStore) GetRoom(ctx context.Context, roomName string) (*Room, error) {
+	if value, ok := s.lookup(s.rooms, roomName); ok {
+		room := value.(Room)
+		return &room, nil
+	}
+
+	room, err := s.Store.GetRoom(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(s.rooms, roomName, *room)
+	return room, nil
+}
+
+func (s *CachedStore) DeleteRoom(ctx context.Context, roomName string) error {
+	defer s.forget(roomName, s.rooms, s.states, s.hands)
+	return s.Store.DeleteRoom(ctx, roomName)
+}
+
+func (s *CachedStore) GetRoomState(ctx context.Context, roomName string) (*RoomState, error) {
+	if value, ok := s.lookup(s.states, roomName); ok {
+		state := value.(RoomState)
+		return &state, nil
+	}
+
+	state, err := s.Store.GetRoomState(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(s.states, roomName, *state)
+	return state, nil
+}
+
+func (s *CachedStore) SaveRoomState(ctx context.Context, state *RoomState) error {
+	defer s.forget(state.RoomName, s.states)
+	return s.Store.SaveRoomState(ctx, state)
+}
+
+func (s *CachedStore) AddRaisedHand(ctx context.Context, roomName string, hand *RaisedHand) (bool, error) {
+	defer s.forget(roomName, s.hands)
+	return s.Store.AddRaisedHand(ctx, roomName, hand)
+}
+
+func (s *CachedStore) AcknowledgeHand(ctx context.Context, roomName, userID, moderatorID string, at time.Time) error {
+	defer s.forget(roomName, s.hands)
+	return s.Store.AcknowledgeHand(ctx, roomName, userID, moderatorID, at)
+}
+
+func (s *CachedStore) RemoveRaisedHand(ctx context.Context, roomName, userID string) (bool, error) {
+	defer s.forget(roomName, s.hands)
+	return s.Store.RemoveRaisedHand(ctx, roomName, userID)
+}
+
+func (s *CachedStore) ListRaisedHands(ctx context.Context, roomName string) ([]*RaisedHand, error) {
+	if value, ok := s.lookup(s.hands, roomName); ok {
+		return copyHands(value.([]RaisedHand)), nil
+	}
+
+	hands, err := s.Store.ListRaisedHands(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	cached := make([]RaisedHand, len(hands))
+	for i, h := range hands {
+		cached[i] = *h
+	}
+	s.remember(s.hands, roomName, cached)
+	return hands, nil
+}
+
+func (s *CachedStore) ClearRaisedHands(ctx context.Context, roomName string) error {
+	defer s.forget(roomName, s.hands)
+	return s.Store.ClearRaisedHands(ctx, roomName)
+}
+
+func copyHands(hands []RaisedHand) []*RaisedHand {
+	result := make([]*RaisedHand, len(hands))
+	for i := range hands {
+		hand := hands[i]
+		if hand.AcknowledgedAt != nil {
+			acknowledgedAt := *hand.AcknowledgedAt
+			hand.AcknowledgedAt = &acknowledgedAt
+		}
+		result[i] = &hand
+	}
+	return result
+}
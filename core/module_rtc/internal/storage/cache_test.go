@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type countingStore struct {
+	Store
+	stateReads int
+	handReads  int
+}
+
+func (s *countingStore) GetRoomState(ctx context.Context, roomName string) (*RoomState, error) {
+	s.stateReads++
+	return s.Store.GetRoomState(ctx, roomName)
+}
+
+func (s *countingStore) ListRaisedHands(ctx context.Context, roomName string) ([]*RaisedHand, error) {
+	s.handReads++
+	return s.Store.ListRaisedHands(ctx, roomName)
+}
+
+func TestCachedStore_ReadsThrough(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingStore{Store: NewMemoryStore()}
+	cached := NewCachedStore(backing, time.Minute)
+	now := time.Now()
+	cached.nowFunc = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if state, err := cached.GetRoomState(ctx, "room"); err != nil || state.IsLocked {
+			t.Fatalf("Expected an unlocked room, got %+v, %v", state, err)
+		}
+	}
+	if backing.stateReads != 1 {
+		t.Errorf("Expected one read from the store, got %d", backing.stateReads)
+	}
+
+	// A write is seen at once
+	cached.SaveRoomState(ctx, &RoomState{RoomName: "room", IsLocked: true})
+	if state, _ := cached.GetRoomState(ctx, "room"); !state.IsLocked {
+		t.Error("Expected the lock to be read after saving it")
+	}
+
+	// Another replica's write is seen once the entry expires
+	backing.Store.SaveRoomState(ctx, &RoomState{RoomName: "room"})
+	if state, _ := cached.GetRoomState(ctx, "room"); !state.IsLocked {
+		t.Error("Expected the cached lock within the TTL")
+	}
+	now = now.Add(time.Minute)
+	if state, _ := cached.GetRoomState(ctx, "room"); state.IsLocked {
+		t.Error("Expected the unlock to be read after the TTL")
+	}
+}
+
+func TestCachedStore_RaisedHands(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingStore{Store: NewMemoryStore()}
+	cached := NewCachedStore(backing, time.Minute)
+	start := time.Now()
+
+	cached.AddRaisedHand(ctx, "room", &RaisedHand{UserID: "b", RaisedAt: start.Add(time.Second)})
+	cached.AddRaisedHand(ctx, "room", &RaisedHand{UserID: "a", RaisedAt: start})
+	if added, _ := cached.AddRaisedHand(ctx, "room", &RaisedHand{UserID: "a", RaisedAt: start.Add(time.Minute)}); added {
+		t.Error("Expected a hand already raised not to be queued again")
+	}
+
+	hands, _ := cached.ListRaisedHands(ctx, "room")
+	if len(hands) != 2 || hands[0].UserID != "a" || hands[1].UserID != "b" {
+		t.Fatalf("Expected hands in the order raised, got %+v", hands)
+	}
+
+	// Changing a returned hand does not change the cache
+	hands[0].UserName = "changed"
+	cached.ListRaisedHands(ctx, "room")
+	hands, _ = cached.ListRaisedHands(ctx, "room")
+	if backing.handReads != 1 || hands[0].UserName != "" {
+		t.Errorf("Expected cached hands to be copied, got %d reads and %+v", backing.handReads, hands[0])
+	}
+
+	cached.AcknowledgeHand(ctx, "room", "b", "mod", start)
+	hands, _ = cached.ListRaisedHands(ctx, "room")
+	if hands[1].AcknowledgedBy != "mod" || hands[1].AcknowledgedAt == nil {
+		t.Errorf("Expected the hand to be acknowledged, got %+v", hands[1])
+	}
+
+	cached.SaveRoom(ctx, &Room{RoomName: "room", CommunityID: 7})
+	cached.DeleteRoom(ctx, "room")
+	if hands, _ := cached.ListRaisedHands(ctx, "room"); len(hands) != 0 {
+		t.Errorf("Expected deleting the room to clear its hands, got %+v", hands)
+	}
+	if _, err := cached.GetRoom(ctx, "room"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,1051 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps state in process, for running without a database.
+type MemoryStore struct {
+	rooms  map[string]Room
+	states map[string]RoomState
+	hands  map[string][]RaisedHand // roomName -> hands in raise order
+	people map[string]map[string]Participant
+	roles  map[string]map[string]RoleAssignment
+	recs   map[string]Recording // egressID -> recording
+	dests  map[string]StreamDestination
+	kits   map[int]map[string]RoomTemplate // communityID -> name -> template
+	passes map[string]Invite
+	bans   map[string]map[string]Ban // roomName -> userID -> ban
+	lines  map[string]DialIn         // roomName -> dial-in
+	shows  map[string]WatchParty     // roomName -> watch party
+	grants map[string][]IssuedToken  // roomName -> tokens by ID
+	tokeID int64
+	casts  map[string]Broadcast      // egressID -> broadcast
+	splits map[string][]BreakoutRoom // parentRoom -> breakout rooms
+	moves  map[string]map[string]BreakoutAssignment
+	plans  map[string]RoomSchedule
+	chats  map[string][]ChatMessage // roomName -> messages by ID
+	chatID int64
+	words  map[string][]TranscriptSegment // roomName -> segments by ID
+	wordID int64
+	usage  map[string]map[string]ParticipantStats // roomName -> userID -> stats
+	totals map[string]RoomStats
+	mu     sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		rooms:  make(map[string]Room),
+		states: make(map[string]RoomState),
+		hands:  make(map[string][]RaisedHand),
+		people: make(map[string]map[string]Participant),
+		roles:  make(map[string]map[string]RoleAssignment),
+		recs:   make(map[string]Recording),
+		dests:  make(map[string]StreamDestination),
+		kits:   make(map[int]map[string]RoomTemplate),
+		passes: make(map[string]Invite),
+		bans:   make(map[string]map[string]Ban),
+		lines:  make(map[string]DialIn),
+		shows:  make(map[string]WatchParty),
+		grants: make(map[string][]IssuedToken),
+		casts:  make(map[string]Broadcast),
+		splits: make(map[string][]BreakoutRoom),
+		moves:  make(map[string]map[string]BreakoutAssignment),
+		plans:  make(map[string]RoomSchedule),
+		chats:  make(map[string][]ChatMessage),
+		words:  make(map[string][]TranscriptSegment),
+		usage:  make(map[string]map[string]ParticipantStats),
+		totals: make(map[string]RoomStats),
+	}
+}
+
+func (s *MemoryStore) SaveRoom(ctx context.Context, room *Room) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rooms[room.RoomName] = *room
+	return nil
+}
+
+func (s *MemoryStore) GetRoom(ctx context.Context, roomName string) (*Room, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	room, ok := s.rooms[roomName]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &room, nil
+}
+
+func (s *MemoryStore) ListRooms(ctx context.Context, communityID int) ([]*Room, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*Room{}
+	for _, r := range s.rooms {
+		if r.CommunityID == communityID {
+			room := r
+			result = append(result, &room)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].RoomName < result[j].RoomName
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteRoom(ctx context.Context, roomName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rooms, roomName)
+	delete(s.states, roomName)
+	delete(s.hands, roomName)
+	delete(s.people, roomName)
+	delete(s.roles, roomName)
+	delete(s.splits, roomName)
+	delete(s.moves, roomName)
+	delete(s.shows, roomName)
+	return nil
+}
+
+func (s *MemoryStore) GetRoomState(ctx context.Context, roomName string) (*RoomState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.states[roomName]
+	if !ok {
+		state = RoomState{RoomName: roomName}
+	}
+	return &state, nil
+}
+
+func (s *MemoryStore) SaveRoomState(ctx context.Context, state *RoomState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[state.RoomName] = *state
+	return nil
+}
+
+func (s *MemoryStore) AddRaisedHand(ctx context.Context, roomName string, hand *RaisedHand) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hands := s.hands[roomName]
+	for _, h := range hands {
+		if h.UserID == hand.UserID {
+			return false, nil
+		}
+	}
+
+	hands = append(hands, *hand)
+	sort.SliceStable(hands, func(i, j int) bool { return hands[i].RaisedAt.Before(hands[j].RaisedAt) })
+	s.hands[roomName] = hands
+	return true, nil
+}
+
+func (s *MemoryStore) AcknowledgeHand(ctx context.Context, roomName, userID, moderatorID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hands := s.hands[roomName]
+	for i := range hands {
+		if hands[i].UserID == userID {
+			acknowledgedAt := at
+			hands[i].AcknowledgedAt = &acknowledgedAt
+			hands[i].AcknowledgedBy = moderatorID
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveRaisedHand(ctx context.Context, roomName, userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hands := s.hands[roomName]
+	for i, h := range hands {
+		if h.UserID == userID {
+			s.hands[roomName] = append(hands[:i:i], hands[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) ListRaisedHands(ctx context.Context, roomName string) ([]*RaisedHand, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hands := s.hands[roomName]
+	result := make([]*RaisedHand, 0, len(hands))
+	for _, h := range hands {
+		hand := h
+		result = append(result, &hand)
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) ListExpiredRaisedHands(ctx context.Context, now time.Time) (map[string][]*RaisedHand, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expired := make(map[string][]*RaisedHand)
+	for roomName, hands := range s.hands {
+		minutes := s.states[roomName].HandExpiryMinutes
+		if minutes <= 0 {
+			continue
+		}
+		for _, h := range hands {
+			if h.AcknowledgedAt == nil && !h.RaisedAt.Add(time.Duration(minutes)*time.Minute).After(now) {
+				hand := h
+				expired[roomName] = append(expired[roomName], &hand)
+			}
+		}
+	}
+	return expired, nil
+}
+
+func (s *MemoryStore) ClearRaisedHands(ctx context.Context, roomName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.hands, roomName)
+	return nil
+}
+
+func (s *MemoryStore) AddParticipant(ctx context.Context, roomName string, participant *Participant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.people[roomName] == nil {
+		s.people[roomName] = make(map[string]Participant)
+	}
+	s.people[roomName][participant.Identity] = *participant
+	return nil
+}
+
+func (s *MemoryStore) RemoveParticipant(ctx context.Context, roomName, identity string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.people[roomName], identity)
+	return nil
+}
+
+func (s *MemoryStore) ListParticipants(ctx context.Context, roomName string) ([]*Participant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Participant, 0, len(s.people[roomName]))
+	for _, p := range s.people[roomName] {
+		participant := p
+		result = append(result, &participant)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].JoinedAt.Equal(result[j].JoinedAt) {
+			return result[i].JoinedAt.Before(result[j].JoinedAt)
+		}
+		return result[i].Identity < result[j].Identity
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) ClearParticipants(ctx context.Context, roomName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.people, roomName)
+	return nil
+}
+
+func (s *MemoryStore) GetParticipantRole(ctx context.Context, roomName, userID string) (*RoleAssignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	assignment, ok := s.roles[roomName][userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &assignment, nil
+}
+
+func (s *MemoryStore) SetParticipantRole(ctx context.Context, roomName string, assignment *RoleAssignment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roles[roomName] == nil {
+		s.roles[roomName] = make(map[string]RoleAssignment)
+	}
+	s.roles[roomName][assignment.UserID] = *assignment
+	return nil
+}
+
+func (s *MemoryStore) ListParticipantRoles(ctx context.Context, roomName string) ([]*RoleAssignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*RoleAssignment, 0, len(s.roles[roomName]))
+	for _, a := range s.roles[roomName] {
+		assignment := a
+		result = append(result, &assignment)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result, nil
+}
+
+func (s *MemoryStore) SaveRecording(ctx context.Context, recording *Recording) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recs[recording.EgressID] = *recording
+	return nil
+}
+
+func (s *MemoryStore) GetRecording(ctx context.Context, egressID string) (*Recording, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recording, ok := s.recs[egressID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &recording, nil
+}
+
+func (s *MemoryStore) ListRecordings(ctx context.Context, roomName string) ([]*Recording, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*Recording{}
+	for _, r := range s.recs {
+		if r.RoomName == roomName {
+			recording := r
+			result = append(result, &recording)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].StartedAt.Equal(result[j].StartedAt) {
+			return result[i].StartedAt.After(result[j].StartedAt)
+		}
+		return result[i].EgressID < result[j].EgressID
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) SaveStreamDestination(ctx context.Context, destination *StreamDestination) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dests[destination.ID] = *destination
+	return nil
+}
+
+func (s *MemoryStore) GetStreamDestination(ctx context.Context, id string) (*StreamDestination, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	destination, ok := s.dests[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &destination, nil
+}
+
+func (s *MemoryStore) ListStreamDestinations(ctx context.Context, communityID int) ([]*StreamDestination, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*StreamDestination{}
+	for _, d := range s.dests {
+		if d.CommunityID == communityID {
+			destination := d
+			result = append(result, &destination)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteStreamDestination(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.dests, id)
+	return nil
+}
+
+func (s *MemoryStore) SaveRoomTemplate(ctx context.Context, template *RoomTemplate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, ok := s.kits[template.CommunityID]
+	if !ok {
+		templates = make(map[string]RoomTemplate)
+		s.kits[template.CommunityID] = templates
+	}
+	if template.IsDefault {
+		for name, t := range templates {
+			t.IsDefault = false
+			templates[name] = t
+		}
+	}
+	t := *template
+	t.Codecs = append([]string(nil), template.Codecs...)
+	templates[template.Name] = t
+	return nil
+}
+
+func (s *MemoryStore) GetRoomTemplate(ctx context.Context, communityID int, name string) (*RoomTemplate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	template, ok := s.kits[communityID][name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	template.Codecs = append([]string(nil), template.Codecs...)
+	return &template, nil
+}
+
+func (s *MemoryStore) GetDefaultRoomTemplate(ctx context.Context, communityID int) (*RoomTemplate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, template := range s.kits[communityID] {
+		if template.IsDefault {
+			template.Codecs = append([]string(nil), template.Codecs...)
+			return &template, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) ListRoomTemplates(ctx context.Context, communityID int) ([]*RoomTemplate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*RoomTemplate{}
+	for _, t := range s.kits[communityID] {
+		template := t
+		template.Codecs = append([]string(nil), t.Codecs...)
+		result = append(result, &template)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteRoomTemplate(ctx context.Context, communityID int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.kits[communityID], name)
+	return nil
+}
+
+func (s *MemoryStore) SaveBan(ctx context.Context, ban *Ban) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bans, ok := s.bans[ban.RoomName]
+	if !ok {
+		bans = make(map[string]Ban)
+		s.bans[ban.RoomName] = bans
+	}
+	bans[ban.UserID] = *ban
+	return nil
+}
+
+func (s *MemoryStore) GetBan(ctx context.Context, roomName, userID string) (*Ban, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ban, ok := s.bans[roomName][userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &ban, nil
+}
+
+func (s *MemoryStore) ListBans(ctx context.Context, roomName string) ([]*Ban, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*Ban{}
+	for _, b := range s.bans[roomName] {
+		ban := b
+		result = append(result, &ban)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].UserID < result[j].UserID
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteBan(ctx context.Context, roomName, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.bans[roomName], userID)
+	return nil
+}
+
+func (s *MemoryStore) AddTranscriptSegment(ctx context.Context, segment *TranscriptSegment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.wordID++
+	segment.ID = s.wordID
+	s.words[segment.RoomName] = append(s.words[segment.RoomName], *segment)
+	return nil
+}
+
+func (s *MemoryStore) ListTranscript(ctx context.Context, roomName string) ([]*TranscriptSegment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*TranscriptSegment{}
+	for _, w := range s.words[roomName] {
+		segment := w
+		result = append(result, &segment)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].StartedAt.Before(result[j].StartedAt) })
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteTranscript(ctx context.Context, roomName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.words, roomName)
+	return nil
+}
+
+func (s *MemoryStore) SaveDialIn(ctx context.Context, dialIn *DialIn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lines[dialIn.RoomName] = *dialIn
+	return nil
+}
+
+func (s *MemoryStore) GetDialIn(ctx context.Context, roomName string) (*DialIn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dialIn, ok := s.lines[roomName]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &dialIn, nil
+}
+
+func (s *MemoryStore) GetDialInByPIN(ctx context.Context, pin string) (*DialIn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, dialIn := range s.lines {
+		if dialIn.PIN == pin {
+			return &dialIn, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) DeleteDialIn(ctx context.Context, roomName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.lines, roomName)
+	return nil
+}
+
+func (s *MemoryStore) AddIssuedToken(ctx context.Context, token *IssuedToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokeID++
+	token.ID = s.tokeID
+	s.grants[token.RoomName] = append(s.grants[token.RoomName], *token)
+	return nil
+}
+
+func (s *MemoryStore) ListIssuedTokens(ctx context.Context, roomName, identity string, limit int) ([]*IssuedToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*IssuedToken{}
+	tokens := s.grants[roomName]
+	for i := len(tokens) - 1; i >= 0 && len(result) < limit; i-- {
+		if identity != "" && tokens[i].Identity != identity {
+			continue
+		}
+		token := tokens[i]
+		result = append(result, &token)
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) RevokeIssuedTokens(ctx context.Context, roomName, identity, revokedBy string, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revoked := 0
+	tokens := s.grants[roomName]
+	for i := range tokens {
+		if tokens[i].Identity != identity || tokens[i].RevokedAt != nil || !tokens[i].ExpiresAt.After(at) {
+			continue
+		}
+		revokedAt := at
+		tokens[i].RevokedAt = &revokedAt
+		tokens[i].RevokedBy = revokedBy
+		revoked++
+	}
+	return revoked, nil
+}
+
+func (s *MemoryStore) SaveWatchParty(ctx context.Context, party *WatchParty) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shows[party.RoomName] = *party
+	return nil
+}
+
+func (s *MemoryStore) GetWatchParty(ctx context.Context, roomName string) (*WatchParty, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	party, ok := s.shows[roomName]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &party, nil
+}
+
+func (s *MemoryStore) DeleteWatchParty(ctx context.Context, roomName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.shows, roomName)
+	return nil
+}
+
+func (s *MemoryStore) SaveInvite(ctx context.Context, invite *Invite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.passes[invite.ID] = *invite
+	return nil
+}
+
+func (s *MemoryStore) GetInvite(ctx context.Context, id string) (*Invite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invite, ok := s.passes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &invite, nil
+}
+
+func (s *MemoryStore) RedeemInvite(ctx context.Context, id, guestID string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.passes[id]
+	if !ok || invite.RedeemedAt != nil {
+		return false, nil
+	}
+	invite.RedeemedBy = guestID
+	invite.RedeemedAt = &at
+	s.passes[id] = invite
+	return true, nil
+}
+
+func (s *MemoryStore) ListInvites(ctx context.Context, roomName string) ([]*Invite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*Invite{}
+	for _, i := range s.passes {
+		if i.RoomName == roomName {
+			invite := i
+			result = append(result, &invite)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteInvite(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.passes, id)
+	return nil
+}
+
+func (s *MemoryStore) SaveBroadcast(ctx context.Context, broadcast *Broadcast) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := *broadcast
+	b.DestinationIDs = append([]string(nil), broadcast.DestinationIDs...)
+	s.casts[broadcast.EgressID] = b
+	return nil
+}
+
+func (s *MemoryStore) GetBroadcast(ctx context.Context, egressID string) (*Broadcast, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	broadcast, ok := s.casts[egressID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	broadcast.DestinationIDs = append([]string(nil), broadcast.DestinationIDs...)
+	return &broadcast, nil
+}
+
+func (s *MemoryStore) ListBroadcasts(ctx context.Context, roomName string) ([]*Broadcast, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*Broadcast{}
+	for _, b := range s.casts {
+		if b.RoomName == roomName {
+			broadcast := b
+			broadcast.DestinationIDs = append([]string(nil), b.DestinationIDs...)
+			result = append(result, &broadcast)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].StartedAt.Equal(result[j].StartedAt) {
+			return result[i].StartedAt.After(result[j].StartedAt)
+		}
+		return result[i].EgressID < result[j].EgressID
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) SaveBreakoutRoom(ctx context.Context, room *BreakoutRoom) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rooms := s.splits[room.ParentRoom]
+	for i, r := range rooms {
+		if r.RoomName == room.RoomName {
+			rooms[i] = *room
+			return nil
+		}
+	}
+	s.splits[room.ParentRoom] = append(rooms, *room)
+	return nil
+}
+
+func (s *MemoryStore) ListBreakoutRooms(ctx context.Context, parentRoom string) ([]*BreakoutRoom, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*BreakoutRoom, 0, len(s.splits[parentRoom]))
+	for _, r := range s.splits[parentRoom] {
+		room := r
+		result = append(result, &room)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Position < result[j].Position })
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteBreakoutRooms(ctx context.Context, parentRoom string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.splits, parentRoom)
+	delete(s.moves, parentRoom)
+	return nil
+}
+
+func (s *MemoryStore) SetBreakoutAssignment(ctx context.Context, parentRoom string, assignment *BreakoutAssignment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.moves[parentRoom] == nil {
+		s.moves[parentRoom] = make(map[string]BreakoutAssignment)
+	}
+	s.moves[parentRoom][assignment.UserID] = *assignment
+	return nil
+}
+
+func (s *MemoryStore) DeleteBreakoutAssignment(ctx context.Context, parentRoom, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.moves[parentRoom], userID)
+	return nil
+}
+
+func (s *MemoryStore) ListBreakoutAssignments(ctx context.Context, parentRoom string) ([]*BreakoutAssignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*BreakoutAssignment, 0, len(s.moves[parentRoom]))
+	for _, a := range s.moves[parentRoom] {
+		assignment := a
+		result = append(result, &assignment)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result, nil
+}
+
+func (s *MemoryStore) AddChatMessage(ctx context.Context, message *ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chatID++
+	message.ID = s.chatID
+	s.chats[message.RoomName] = append(s.chats[message.RoomName], *message)
+	return nil
+}
+
+func (s *MemoryStore) GetChatMessage(ctx context.Context, roomName string, id int64) (*ChatMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, m := range s.chats[roomName] {
+		if m.ID == id {
+			message := m
+			return &message, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) ListChatMessages(ctx context.Context, roomName string, afterID int64, limit int) ([]*ChatMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*ChatMessage{}
+	for _, m := range s.chats[roomName] {
+		if len(result) == limit {
+			break
+		}
+		if m.ID > afterID {
+			message := m
+			result = append(result, &message)
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) ListRecentChatMessages(ctx context.Context, roomName string, beforeID int64, limit int) ([]*ChatMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := s.chats[roomName]
+	end := len(messages)
+	if beforeID > 0 {
+		end = sort.Search(len(messages), func(i int) bool { return messages[i].ID >= beforeID })
+	}
+	start := max(end-limit, 0)
+
+	result := []*ChatMessage{}
+	for _, m := range messages[start:end] {
+		message := m
+		result = append(result, &message)
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteChatMessage(ctx context.Context, roomName string, id int64, deletedBy string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.chats[roomName]
+	for i := range messages {
+		if messages[i].ID == id {
+			messages[i].DeletedAt = &at
+			messages[i].DeletedBy = deletedBy
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryStore) LastChatMessageAt(ctx context.Context, roomName, userID string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := s.chats[roomName]
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].UserID == userID {
+			return messages[i].CreatedAt, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+func (s *MemoryStore) GetParticipantStats(ctx context.Context, roomName, userID string) (*ParticipantStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats, ok := s.usage[roomName][userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &stats, nil
+}
+
+func (s *MemoryStore) SaveParticipantStats(ctx context.Context, stats *ParticipantStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usage[stats.RoomName] == nil {
+		s.usage[stats.RoomName] = make(map[string]ParticipantStats)
+	}
+	s.usage[stats.RoomName][stats.UserID] = *stats
+	return nil
+}
+
+func (s *MemoryStore) ListParticipantStats(ctx context.Context, roomName string) ([]*ParticipantStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*ParticipantStats{}
+	for _, p := range s.usage[roomName] {
+		stats := p
+		result = append(result, &stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result, nil
+}
+
+func (s *MemoryStore) GetRoomStats(ctx context.Context, roomName string) (*RoomStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats, ok := s.totals[roomName]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &stats, nil
+}
+
+func (s *MemoryStore) SaveRoomStats(ctx context.Context, stats *RoomStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.totals[stats.RoomName] = *stats
+	return nil
+}
+
+func (s *MemoryStore) ListRoomStats(ctx context.Context, communityID int, since time.Time) ([]*RoomStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*RoomStats{}
+	for _, r := range s.totals {
+		if r.CommunityID == communityID && !r.LastActiveAt.Before(since) {
+			stats := r
+			result = append(result, &stats)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].LastActiveAt.After(result[j].LastActiveAt) })
+	return result, nil
+}
+
+func (s *MemoryStore) SaveRoomSchedule(ctx context.Context, schedule *RoomSchedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.plans[schedule.ID] = *schedule
+	return nil
+}
+
+func (s *MemoryStore) GetRoomSchedule(ctx context.Context, id string) (*RoomSchedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.plans[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &schedule, nil
+}
+
+func (s *MemoryStore) ListRoomSchedules(ctx context.Context, communityID int) ([]*RoomSchedule, error) {
+	return s.listRoomSchedules(func(schedule *RoomSchedule) bool { return schedule.CommunityID == communityID }), nil
+}
+
+func (s *MemoryStore) ListActiveRoomSchedules(ctx context.Context) ([]*RoomSchedule, error) {
+	return s.listRoomSchedules(func(schedule *RoomSchedule) bool { return !schedule.Ended }), nil
+}
+
+func (s *MemoryStore) listRoomSchedules(match func(*RoomSchedule) bool) []*RoomSchedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*RoomSchedule{}
+	for _, p := range s.plans {
+		schedule := p
+		if match(&schedule) {
+			result = append(result, &schedule)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].StartsAt.Equal(result[j].StartsAt) {
+			return result[i].StartsAt.Before(result[j].StartsAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func (s *MemoryStore) DeleteRoomSchedule(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.plans, id)
+	return nil
+}
+
+func (s *MemoryStore) CountRooms(ctx context.Context) (int, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	participants := 0
+	for _, people := range s.people {
+		participants += len(people)
+	}
+	return len(s.rooms), participants, nil
+}
+
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,1808 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS rtc_rooms (
+		room_name TEXT PRIMARY KEY,
+		room_id TEXT NOT NULL DEFAULT '',
+		community_id INTEGER NOT NULL,
+		max_participants INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_rooms_community_idx ON rtc_rooms (community_id)`,
+	`CREATE TABLE IF NOT EXISTS rtc_room_state (
+		room_name TEXT PRIMARY KEY,
+		is_locked BOOLEAN NOT NULL DEFAULT FALSE,
+		locked_by TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS slow_mode_seconds INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS screen_share TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS hand_expiry_minutes INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS template TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS default_role TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS lobby BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS recording_disabled BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS transcribing BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS audio JSONB NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE rtc_room_state ADD COLUMN IF NOT EXISTS token_ttl_seconds INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS rtc_raised_hands (
+		room_name TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		user_name TEXT NOT NULL DEFAULT '',
+		raised_at TIMESTAMPTZ NOT NULL,
+		acknowledged_at TIMESTAMPTZ,
+		acknowledged_by TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (room_name, user_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_raised_hands_queue_idx ON rtc_raised_hands (room_name, raised_at)`,
+	`CREATE TABLE IF NOT EXISTS rtc_participants (
+		room_name TEXT NOT NULL,
+		identity TEXT NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		joined_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (room_name, identity)
+	)`,
+	`CREATE TABLE IF NOT EXISTS rtc_participant_roles (
+		room_name TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		role TEXT NOT NULL,
+		assigned_by TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (room_name, user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS rtc_recordings (
+		egress_id TEXT PRIMARY KEY,
+		room_name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		track_id TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL,
+		destination TEXT NOT NULL,
+		location TEXT NOT NULL DEFAULT '',
+		filename TEXT NOT NULL DEFAULT '',
+		size BIGINT NOT NULL DEFAULT 0,
+		duration_seconds BIGINT NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		started_by TEXT NOT NULL DEFAULT '',
+		started_at TIMESTAMPTZ NOT NULL,
+		ended_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_recordings_room_idx ON rtc_recordings (room_name, started_at)`,
+	`CREATE TABLE IF NOT EXISTS rtc_stream_destinations (
+		id TEXT PRIMARY KEY,
+		community_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		platform TEXT NOT NULL,
+		rtmp_url TEXT NOT NULL,
+		stream_key TEXT NOT NULL,
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_stream_destinations_community_idx ON rtc_stream_destinations (community_id)`,
+	`CREATE TABLE IF NOT EXISTS rtc_room_templates (
+		community_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		max_participants INTEGER NOT NULL DEFAULT 0,
+		empty_timeout_seconds INTEGER NOT NULL DEFAULT 0,
+		default_role TEXT NOT NULL DEFAULT '',
+		lobby BOOLEAN NOT NULL DEFAULT FALSE,
+		recording BOOLEAN NOT NULL DEFAULT TRUE,
+		codecs JSONB NOT NULL DEFAULT '[]',
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		updated_by TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (community_id, name)
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS rtc_room_templates_default_idx ON rtc_room_templates (community_id) WHERE is_default`,
+	`CREATE TABLE IF NOT EXISTS rtc_bans (
+		room_name TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		banned_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ,
+		PRIMARY KEY (room_name, user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS rtc_dial_ins (
+		room_name TEXT PRIMARY KEY,
+		pin TEXT NOT NULL UNIQUE,
+		dispatch_rule_id TEXT NOT NULL,
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS rtc_invites (
+		id TEXT PRIMARY KEY,
+		room_name TEXT NOT NULL,
+		role TEXT NOT NULL,
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ NOT NULL,
+		redeemed_by TEXT NOT NULL DEFAULT '',
+		redeemed_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_invites_room_idx ON rtc_invites (room_name, created_at)`,
+	`CREATE TABLE IF NOT EXISTS rtc_broadcasts (
+		egress_id TEXT PRIMARY KEY,
+		room_name TEXT NOT NULL,
+		destination_ids JSONB NOT NULL DEFAULT '[]',
+		status TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		started_by TEXT NOT NULL DEFAULT '',
+		started_at TIMESTAMPTZ NOT NULL,
+		ended_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_broadcasts_room_idx ON rtc_broadcasts (room_name, started_at)`,
+	`CREATE TABLE IF NOT EXISTS rtc_breakout_rooms (
+		room_name TEXT PRIMARY KEY,
+		parent_room TEXT NOT NULL,
+		name TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_breakout_rooms_parent_idx ON rtc_breakout_rooms (parent_room, position)`,
+	`CREATE TABLE IF NOT EXISTS rtc_breakout_assignments (
+		parent_room TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		room_name TEXT NOT NULL,
+		assigned_by TEXT NOT NULL DEFAULT '',
+		assigned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (parent_room, user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS rtc_room_schedules (
+		id TEXT PRIMARY KEY,
+		community_id INTEGER NOT NULL,
+		room_name TEXT NOT NULL,
+		title TEXT NOT NULL,
+		starts_at TIMESTAMPTZ NOT NULL,
+		ends_at TIMESTAMPTZ NOT NULL,
+		recurrence TEXT NOT NULL DEFAULT '',
+		recur_until TIMESTAMPTZ,
+		auto_open BOOLEAN NOT NULL DEFAULT TRUE,
+		auto_close BOOLEAN NOT NULL DEFAULT TRUE,
+		max_participants INTEGER NOT NULL DEFAULT 100,
+		calendar_event_id INTEGER NOT NULL DEFAULT 0,
+		is_open BOOLEAN NOT NULL DEFAULT FALSE,
+		ended BOOLEAN NOT NULL DEFAULT FALSE,
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_room_schedules_community_idx ON rtc_room_schedules (community_id, starts_at)`,
+	`CREATE INDEX IF NOT EXISTS rtc_room_schedules_active_idx ON rtc_room_schedules (starts_at) WHERE NOT ended`,
+	`CREATE TABLE IF NOT EXISTS rtc_participant_stats (
+		room_name TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		user_name TEXT NOT NULL DEFAULT '',
+		sessions INTEGER NOT NULL DEFAULT 0,
+		connected_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+		speaking_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+		hand_raises INTEGER NOT NULL DEFAULT 0,
+		first_joined_at TIMESTAMPTZ NOT NULL,
+		connected_since TIMESTAMPTZ,
+		speaking_since TIMESTAMPTZ,
+		PRIMARY KEY (room_name, user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS rtc_room_stats (
+		room_name TEXT PRIMARY KEY,
+		community_id INTEGER NOT NULL,
+		participants INTEGER NOT NULL DEFAULT 0,
+		peak_participants INTEGER NOT NULL DEFAULT 0,
+		peak_at TIMESTAMPTZ,
+		connected_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+		speaking_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+		hand_raises INTEGER NOT NULL DEFAULT 0,
+		first_active_at TIMESTAMPTZ NOT NULL,
+		last_active_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_room_stats_community_idx ON rtc_room_stats (community_id, last_active_at)`,
+	`CREATE TABLE IF NOT EXISTS rtc_chat_messages (
+		id BIGSERIAL PRIMARY KEY,
+		room_name TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		user_name TEXT NOT NULL DEFAULT '',
+		message TEXT NOT NULL,
+		filtered BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		deleted_at TIMESTAMPTZ,
+		deleted_by TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_chat_messages_room_idx ON rtc_chat_messages (room_name, id)`,
+	`CREATE INDEX IF NOT EXISTS rtc_chat_messages_user_idx ON rtc_chat_messages (room_name, user_id, id)`,
+	`CREATE TABLE IF NOT EXISTS rtc_transcript_segments (
+		id BIGSERIAL PRIMARY KEY,
+		room_name TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		user_name TEXT NOT NULL DEFAULT '',
+		track_id TEXT NOT NULL DEFAULT '',
+		text TEXT NOT NULL,
+		started_at TIMESTAMPTZ NOT NULL,
+		ended_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_transcript_segments_room_idx ON rtc_transcript_segments (room_name, started_at)`,
+	`CREATE TABLE IF NOT EXISTS rtc_watch_parties (
+		room_name TEXT PRIMARY KEY,
+		media_url TEXT NOT NULL,
+		playing BOOLEAN NOT NULL DEFAULT FALSE,
+		position DOUBLE PRECISION NOT NULL DEFAULT 0,
+		started_by TEXT NOT NULL DEFAULT '',
+		updated_by TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS rtc_issued_tokens (
+		id BIGSERIAL PRIMARY KEY,
+		room_name TEXT NOT NULL,
+		identity TEXT NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		role TEXT NOT NULL DEFAULT '',
+		lobby BOOLEAN NOT NULL DEFAULT FALSE,
+		requested_by TEXT NOT NULL DEFAULT '',
+		client_ip TEXT NOT NULL DEFAULT '',
+		issued_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		revoked_at TIMESTAMPTZ,
+		revoked_by TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS rtc_issued_tokens_room_idx ON rtc_issued_tokens (room_name, identity, id)`,
+}
+
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(ctx context.Context, databaseURL string) (*PostgresStore, error) {
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	s := &PostgresStore{db: db}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *PostgresStore) migrate(ctx context.Context) error {
+	for _, stmt := range schema {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) SaveRoom(ctx context.Context, room *Room) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_rooms (room_name, room_id, community_id, max_participants, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (room_name) DO UPDATE SET
+			room_id = EXCLUDED.room_id,
+			community_id = EXCLUDED.community_id,
+			max_participants = EXCLUDED.max_participants`,
+		room.RoomName, room.RoomID, room.CommunityID, int64(room.MaxParticipants), room.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save room: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetRoom(ctx context.Context, roomName string) (*Room, error) {
+	var room Room
+	var maxParticipants int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT room_name, room_id, community_id, max_participants, created_at
+		FROM rtc_rooms WHERE room_name = $1`, roomName).
+		Scan(&room.RoomName, &room.RoomID, &room.CommunityID, &maxParticipants, &room.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+	room.MaxParticipants = uint32(maxParticipants)
+	return &room, nil
+}
+
+func (s *PostgresStore) ListRooms(ctx context.Context, communityID int) ([]*Room, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT room_name, room_id, community_id, max_participants, created_at
+		FROM rtc_rooms WHERE community_id = $1
+		ORDER BY created_at, room_name`, communityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rooms: %w", err)
+	}
+	defer rows.Close()
+
+	rooms := []*Room{}
+	for rows.Next() {
+		var room Room
+		var maxParticipants int64
+		if err := rows.Scan(&room.RoomName, &room.RoomID, &room.CommunityID, &maxParticipants, &room.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list rooms: %w", err)
+		}
+		room.MaxParticipants = uint32(maxParticipants)
+		rooms = append(rooms, &room)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list rooms: %w", err)
+	}
+	return rooms, nil
+}
+
+// DeleteRoom removes the room along with its state, raised hands and roles.
+func (s *PostgresStore) DeleteRoom(ctx context.Context, roomName string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete room: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"rtc_participant_roles", "rtc_participants", "rtc_raised_hands", "rtc_room_state", "rtc_watch_parties", "rtc_rooms"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE room_name = $1", roomName); err != nil {
+			return fmt.Errorf("failed to delete room: %w", err)
+		}
+	}
+	for _, table := range []string{"rtc_breakout_assignments", "rtc_breakout_rooms"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE parent_room = $1", roomName); err != nil {
+			return fmt.Errorf("failed to delete room: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete room: %w", err)
+	}
+	return nil
+}
+
+// GetRoomState returns the room's state, or an unlocked state if none is stored.
+func (s *PostgresStore) GetRoomState(ctx context.Context, roomName string) (*RoomState, error) {
+	state := RoomState{RoomName: roomName}
+	var audio []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT is_locked, locked_by, slow_mode_seconds, screen_share, hand_expiry_minutes,
+			template, default_role, lobby, recording_disabled, transcribing, audio, token_ttl_seconds, updated_at
+		FROM rtc_room_state WHERE room_name = $1`, roomName).
+		Scan(&state.IsLocked, &state.LockedBy, &state.SlowModeSeconds, &state.ScreenShare, &state.HandExpiryMinutes,
+			&state.Template, &state.DefaultRole, &state.Lobby, &state.RecordingDisabled, &state.Transcribing, &audio, &state.TokenTTLSeconds, &state.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room state: %w", err)
+	}
+	if err := json.Unmarshal(audio, &state.Audio); err != nil {
+		return nil, fmt.Errorf("failed to read room audio settings: %w", err)
+	}
+	return &state, nil
+}
+
+func (s *PostgresStore) SaveRoomState(ctx context.Context, state *RoomState) error {
+	audio, err := json.Marshal(state.Audio)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO rtc_room_state (room_name, is_locked, locked_by, slow_mode_seconds, screen_share, hand_expiry_minutes,
+			template, default_role, lobby, recording_disabled, transcribing, audio, token_ttl_seconds, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (room_name) DO UPDATE SET
+			is_locked = EXCLUDED.is_locked,
+			locked_by = EXCLUDED.locked_by,
+			slow_mode_seconds = EXCLUDED.slow_mode_seconds,
+			screen_share = EXCLUDED.screen_share,
+			hand_expiry_minutes = EXCLUDED.hand_expiry_minutes,
+			template = EXCLUDED.template,
+			default_role = EXCLUDED.default_role,
+			lobby = EXCLUDED.lobby,
+			recording_disabled = EXCLUDED.recording_disabled,
+			transcribing = EXCLUDED.transcribing,
+			audio = EXCLUDED.audio,
+			token_ttl_seconds = EXCLUDED.token_ttl_seconds,
+			updated_at = EXCLUDED.updated_at`,
+		state.RoomName, state.IsLocked, state.LockedBy, state.SlowModeSeconds, state.ScreenShare, state.HandExpiryMinutes,
+		state.Template, state.DefaultRole, state.Lobby, state.RecordingDisabled, state.Transcribing, audio, state.TokenTTLSeconds, state.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save room state: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) AddRaisedHand(ctx context.Context, roomName string, hand *RaisedHand) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_raised_hands (room_name, user_id, user_name, raised_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (room_name, user_id) DO NOTHING`,
+		roomName, hand.UserID, hand.UserName, hand.RaisedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to raise hand: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to raise hand: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *PostgresStore) AcknowledgeHand(ctx context.Context, roomName, userID, moderatorID string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE rtc_raised_hands SET acknowledged_at = $3, acknowledged_by = $4
+		WHERE room_name = $1 AND user_id = $2`,
+		roomName, userID, at, moderatorID)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge hand: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) RemoveRaisedHand(ctx context.Context, roomName, userID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM rtc_raised_hands WHERE room_name = $1 AND user_id = $2`, roomName, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to lower hand: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to lower hand: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListRaisedHands returns the room's raised hands in the order they were raised.
+func (s *PostgresStore) ListRaisedHands(ctx context.Context, roomName string) ([]*RaisedHand, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, user_name, raised_at, acknowledged_at, acknowledged_by
+		FROM rtc_raised_hands WHERE room_name = $1
+		ORDER BY raised_at, user_id`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list raised hands: %w", err)
+	}
+	defer rows.Close()
+
+	hands := []*RaisedHand{}
+	for rows.Next() {
+		var hand RaisedHand
+		var acknowledgedAt sql.NullTime
+		if err := rows.Scan(&hand.UserID, &hand.UserName, &hand.RaisedAt, &acknowledgedAt, &hand.AcknowledgedBy); err != nil {
+			return nil, fmt.Errorf("failed to list raised hands: %w", err)
+		}
+		if acknowledgedAt.Valid {
+			hand.AcknowledgedAt = &acknowledgedAt.Time
+		}
+		hands = append(hands, &hand)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list raised hands: %w", err)
+	}
+	return hands, nil
+}
+
+func (s *PostgresStore) ListExpiredRaisedHands(ctx context.Context, now time.Time) (map[string][]*RaisedHand, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT h.room_name, h.user_id, h.user_name, h.raised_at
+		FROM rtc_raised_hands h
+		JOIN rtc_room_state s ON s.room_name = h.room_name
+		WHERE s.hand_expiry_minutes > 0 AND h.acknowledged_at IS NULL
+			AND h.raised_at + s.hand_expiry_minutes * INTERVAL '1 minute' <= $1
+		ORDER BY h.room_name, h.raised_at, h.user_id`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired raised hands: %w", err)
+	}
+	defer rows.Close()
+
+	expired := make(map[string][]*RaisedHand)
+	for rows.Next() {
+		var roomName string
+		var hand RaisedHand
+		if err := rows.Scan(&roomName, &hand.UserID, &hand.UserName, &hand.RaisedAt); err != nil {
+			return nil, fmt.Errorf("failed to list expired raised hands: %w", err)
+		}
+		expired[roomName] = append(expired[roomName], &hand)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list expired raised hands: %w", err)
+	}
+	return expired, nil
+}
+
+func (s *PostgresStore) ClearRaisedHands(ctx context.Context, roomName string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_raised_hands WHERE room_name = $1`, roomName)
+	if err != nil {
+		return fmt.Errorf("failed to clear raised hands: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) AddParticipant(ctx context.Context, roomName string, participant *Participant) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_participants (room_name, identity, name, joined_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (room_name, identity) DO UPDATE SET
+			name = EXCLUDED.name,
+			joined_at = EXCLUDED.joined_at`,
+		roomName, participant.Identity, participant.Name, participant.JoinedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add participant: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) RemoveParticipant(ctx context.Context, roomName, identity string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_participants WHERE room_name = $1 AND identity = $2`, roomName, identity)
+	if err != nil {
+		return fmt.Errorf("failed to remove participant: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListParticipants(ctx context.Context, roomName string) ([]*Participant, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT identity, name, joined_at
+		FROM rtc_participants WHERE room_name = $1
+		ORDER BY joined_at, identity`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list participants: %w", err)
+	}
+	defer rows.Close()
+
+	participants := []*Participant{}
+	for rows.Next() {
+		var participant Participant
+		if err := rows.Scan(&participant.Identity, &participant.Name, &participant.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to list participants: %w", err)
+		}
+		participants = append(participants, &participant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list participants: %w", err)
+	}
+	return participants, nil
+}
+
+func (s *PostgresStore) ClearParticipants(ctx context.Context, roomName string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_participants WHERE room_name = $1`, roomName)
+	if err != nil {
+		return fmt.Errorf("failed to clear participants: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetParticipantRole(ctx context.Context, roomName, userID string) (*RoleAssignment, error) {
+	assignment := RoleAssignment{UserID: userID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT role, assigned_by, updated_at
+		FROM rtc_participant_roles WHERE room_name = $1 AND user_id = $2`, roomName, userID).
+		Scan(&assignment.Role, &assignment.AssignedBy, &assignment.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participant role: %w", err)
+	}
+	return &assignment, nil
+}
+
+func (s *PostgresStore) SetParticipantRole(ctx context.Context, roomName string, assignment *RoleAssignment) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_participant_roles (room_name, user_id, role, assigned_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (room_name, user_id) DO UPDATE SET
+			role = EXCLUDED.role,
+			assigned_by = EXCLUDED.assigned_by,
+			updated_at = EXCLUDED.updated_at`,
+		roomName, assignment.UserID, assignment.Role, assignment.AssignedBy, assignment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set participant role: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListParticipantRoles(ctx context.Context, roomName string) ([]*RoleAssignment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, role, assigned_by, updated_at
+		FROM rtc_participant_roles WHERE room_name = $1
+		ORDER BY user_id`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list participant roles: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []*RoleAssignment{}
+	for rows.Next() {
+		var assignment RoleAssignment
+		if err := rows.Scan(&assignment.UserID, &assignment.Role, &assignment.AssignedBy, &assignment.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list participant roles: %w", err)
+		}
+		assignments = append(assignments, &assignment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list participant roles: %w", err)
+	}
+	return assignments, nil
+}
+
+func (s *PostgresStore) SaveRecording(ctx context.Context, recording *Recording) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_recordings (egress_id, room_name, kind, track_id, status, destination, location,
+			filename, size, duration_seconds, error, started_by, started_at, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (egress_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			location = EXCLUDED.location,
+			filename = EXCLUDED.filename,
+			size = EXCLUDED.size,
+			duration_seconds = EXCLUDED.duration_seconds,
+			error = EXCLUDED.error,
+			ended_at = EXCLUDED.ended_at`,
+		recording.EgressID, recording.RoomName, recording.Kind, recording.TrackID, recording.Status,
+		recording.Destination, recording.Location, recording.Filename, recording.Size,
+		recording.DurationSeconds, recording.Error, recording.StartedBy, recording.StartedAt, recording.EndedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+	return nil
+}
+
+const recordingColumns = `egress_id, room_name, kind, track_id, status, destination, location,
+	filename, size, duration_seconds, error, started_by, started_at, ended_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecording(row rowScanner) (*Recording, error) {
+	var recording Recording
+	var endedAt sql.NullTime
+	err := row.Scan(&recording.EgressID, &recording.RoomName, &recording.Kind, &recording.TrackID,
+		&recording.Status, &recording.Destination, &recording.Location, &recording.Filename,
+		&recording.Size, &recording.DurationSeconds, &recording.Error, &recording.StartedBy,
+		&recording.StartedAt, &endedAt)
+	if err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		recording.EndedAt = &endedAt.Time
+	}
+	return &recording, nil
+}
+
+func (s *PostgresStore) GetRecording(ctx context.Context, egressID string) (*Recording, error) {
+	recording, err := scanRecording(s.db.QueryRowContext(ctx,
+		`SELECT `+recordingColumns+` FROM rtc_recordings WHERE egress_id = $1`, egressID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recording: %w", err)
+	}
+	return recording, nil
+}
+
+func (s *PostgresStore) ListRecordings(ctx context.Context, roomName string) ([]*Recording, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+recordingColumns+` FROM rtc_recordings WHERE room_name = $1
+		ORDER BY started_at DESC, egress_id`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	defer rows.Close()
+
+	recordings := []*Recording{}
+	for rows.Next() {
+		recording, err := scanRecording(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list recordings: %w", err)
+		}
+		recordings = append(recordings, recording)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	return recordings, nil
+}
+
+func (s *PostgresStore) SaveStreamDestination(ctx context.Context, destination *StreamDestination) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_stream_destinations (id, community_id, name, platform, rtmp_url, stream_key, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			platform = EXCLUDED.platform,
+			rtmp_url = EXCLUDED.rtmp_url,
+			stream_key = EXCLUDED.stream_key`,
+		destination.ID, destination.CommunityID, destination.Name, destination.Platform,
+		destination.RTMPURL, destination.StreamKey, destination.CreatedBy, destination.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save stream destination: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetStreamDestination(ctx context.Context, id string) (*StreamDestination, error) {
+	var destination StreamDestination
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, community_id, name, platform, rtmp_url, stream_key, created_by, created_at
+		FROM rtc_stream_destinations WHERE id = $1`, id).
+		Scan(&destination.ID, &destination.CommunityID, &destination.Name, &destination.Platform,
+			&destination.RTMPURL, &destination.StreamKey, &destination.CreatedBy, &destination.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream destination: %w", err)
+	}
+	return &destination, nil
+}
+
+func (s *PostgresStore) ListStreamDestinations(ctx context.Context, communityID int) ([]*StreamDestination, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, community_id, name, platform, rtmp_url, stream_key, created_by, created_at
+		FROM rtc_stream_destinations WHERE community_id = $1
+		ORDER BY created_at, id`, communityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stream destinations: %w", err)
+	}
+	defer rows.Close()
+
+	destinations := []*StreamDestination{}
+	for rows.Next() {
+		var destination StreamDestination
+		if err := rows.Scan(&destination.ID, &destination.CommunityID, &destination.Name, &destination.Platform,
+			&destination.RTMPURL, &destination.StreamKey, &destination.CreatedBy, &destination.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list stream destinations: %w", err)
+		}
+		destinations = append(destinations, &destination)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list stream destinations: %w", err)
+	}
+	return destinations, nil
+}
+
+func (s *PostgresStore) DeleteStreamDestination(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_stream_destinations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete stream destination: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) SaveRoomTemplate(ctx context.Context, template *RoomTemplate) error {
+	codecs, err := json.Marshal(template.Codecs)
+	if err != nil {
+		return fmt.Errorf("failed to save room template: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save room template: %w", err)
+	}
+	defer tx.Rollback()
+
+	if template.IsDefault {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE rtc_room_templates SET is_default = FALSE
+			WHERE community_id = $1 AND name <> $2 AND is_default`,
+			template.CommunityID, template.Name)
+		if err != nil {
+			return fmt.Errorf("failed to save room template: %w", err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rtc_room_templates (`+roomTemplateColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (community_id, name) DO UPDATE SET
+			max_participants = EXCLUDED.max_participants,
+			empty_timeout_seconds = EXCLUDED.empty_timeout_seconds,
+			default_role = EXCLUDED.default_role,
+			lobby = EXCLUDED.lobby,
+			recording = EXCLUDED.recording,
+			codecs = EXCLUDED.codecs,
+			is_default = EXCLUDED.is_default,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at`,
+		template.CommunityID, template.Name, int64(template.MaxParticipants), int64(template.EmptyTimeoutSeconds),
+		template.DefaultRole, template.Lobby, template.Recording, string(codecs), template.IsDefault,
+		template.UpdatedBy, template.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save room template: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save room template: %w", err)
+	}
+	return nil
+}
+
+const roomTemplateColumns = `community_id, name, max_participants, empty_timeout_seconds, default_role, lobby,
+	recording, codecs, is_default, updated_by, updated_at`
+
+func scanRoomTemplate(row rowScanner) (*RoomTemplate, error) {
+	var template RoomTemplate
+	var maxParticipants, emptyTimeout int64
+	var codecs []byte
+	err := row.Scan(&template.CommunityID, &template.Name, &maxParticipants, &emptyTimeout, &template.DefaultRole,
+		&template.Lobby, &template.Recording, &codecs, &template.IsDefault, &template.UpdatedBy, &template.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(codecs, &template.Codecs); err != nil {
+		return nil, err
+	}
+	template.MaxParticipants = uint32(maxParticipants)
+	template.EmptyTimeoutSeconds = uint32(emptyTimeout)
+	return &template, nil
+}
+
+func (s *PostgresStore) GetRoomTemplate(ctx context.Context, communityID int, name string) (*RoomTemplate, error) {
+	template, err := scanRoomTemplate(s.db.QueryRowContext(ctx,
+		`SELECT `+roomTemplateColumns+` FROM rtc_room_templates WHERE community_id = $1 AND name = $2`,
+		communityID, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room template: %w", err)
+	}
+	return template, nil
+}
+
+func (s *PostgresStore) GetDefaultRoomTemplate(ctx context.Context, communityID int) (*RoomTemplate, error) {
+	template, err := scanRoomTemplate(s.db.QueryRowContext(ctx,
+		`SELECT `+roomTemplateColumns+` FROM rtc_room_templates WHERE community_id = $1 AND is_default`,
+		communityID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default room template: %w", err)
+	}
+	return template, nil
+}
+
+func (s *PostgresStore) ListRoomTemplates(ctx context.Context, communityID int) ([]*RoomTemplate, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+roomTemplateColumns+` FROM rtc_room_templates WHERE community_id = $1 ORDER BY name`,
+		communityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list room templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []*RoomTemplate{}
+	for rows.Next() {
+		template, err := scanRoomTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list room templates: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list room templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (s *PostgresStore) DeleteRoomTemplate(ctx context.Context, communityID int, name string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM rtc_room_templates WHERE community_id = $1 AND name = $2`, communityID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete room template: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) SaveBan(ctx context.Context, ban *Ban) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_bans (room_name, user_id, reason, banned_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (room_name, user_id) DO UPDATE SET
+			reason = EXCLUDED.reason,
+			banned_by = EXCLUDED.banned_by,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at`,
+		ban.RoomName, ban.UserID, ban.Reason, ban.BannedBy, ban.CreatedAt, ban.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save ban: %w", err)
+	}
+	return nil
+}
+
+const banColumns = `room_name, user_id, reason, banned_by, created_at, expires_at`
+
+func scanBan(row rowScanner) (*Ban, error) {
+	var ban Ban
+	var expiresAt sql.NullTime
+	if err := row.Scan(&ban.RoomName, &ban.UserID, &ban.Reason, &ban.BannedBy, &ban.CreatedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		ban.ExpiresAt = &expiresAt.Time
+	}
+	return &ban, nil
+}
+
+func (s *PostgresStore) GetBan(ctx context.Context, roomName, userID string) (*Ban, error) {
+	ban, err := scanBan(s.db.QueryRowContext(ctx,
+		`SELECT `+banColumns+` FROM rtc_bans WHERE room_name = $1 AND user_id = $2`, roomName, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ban: %w", err)
+	}
+	return ban, nil
+}
+
+func (s *PostgresStore) ListBans(ctx context.Context, roomName string) ([]*Ban, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+banColumns+` FROM rtc_bans WHERE room_name = $1
+		ORDER BY created_at DESC, user_id`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+	defer rows.Close()
+
+	bans := []*Ban{}
+	for rows.Next() {
+		ban, err := scanBan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list bans: %w", err)
+		}
+		bans = append(bans, ban)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+	return bans, nil
+}
+
+func (s *PostgresStore) DeleteBan(ctx context.Context, roomName, userID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_bans WHERE room_name = $1 AND user_id = $2`, roomName, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete ban: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) AddTranscriptSegment(ctx context.Context, segment *TranscriptSegment) error {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO rtc_transcript_segments (room_name, user_id, user_name, track_id, text, started_at, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		segment.RoomName, segment.UserID, segment.UserName, segment.TrackID, segment.Text, segment.StartedAt, segment.EndedAt).
+		Scan(&segment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to add transcript segment: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListTranscript(ctx context.Context, roomName string) ([]*TranscriptSegment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, room_name, user_id, user_name, track_id, text, started_at, ended_at
+		FROM rtc_transcript_segments WHERE room_name = $1
+		ORDER BY started_at, id`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcript: %w", err)
+	}
+	defer rows.Close()
+
+	segments := []*TranscriptSegment{}
+	for rows.Next() {
+		var segment TranscriptSegment
+		if err := rows.Scan(&segment.ID, &segment.RoomName, &segment.UserID, &segment.UserName, &segment.TrackID,
+			&segment.Text, &segment.StartedAt, &segment.EndedAt); err != nil {
+			return nil, fmt.Errorf("failed to list transcript: %w", err)
+		}
+		segments = append(segments, &segment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list transcript: %w", err)
+	}
+	return segments, nil
+}
+
+func (s *PostgresStore) DeleteTranscript(ctx context.Context, roomName string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_transcript_segments WHERE room_name = $1`, roomName)
+	if err != nil {
+		return fmt.Errorf("failed to delete transcript: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) SaveDialIn(ctx context.Context, dialIn *DialIn) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_dial_ins (room_name, pin, dispatch_rule_id, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (room_name) DO UPDATE SET
+			pin = EXCLUDED.pin,
+			dispatch_rule_id = EXCLUDED.dispatch_rule_id,
+			created_by = EXCLUDED.created_by,
+			created_at = EXCLUDED.created_at`,
+		dialIn.RoomName, dialIn.PIN, dialIn.DispatchRuleID, dialIn.CreatedBy, dialIn.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save dial-in: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetDialIn(ctx context.Context, roomName string) (*DialIn, error) {
+	return s.getDialIn(ctx, `room_name = $1`, roomName)
+}
+
+func (s *PostgresStore) GetDialInByPIN(ctx context.Context, pin string) (*DialIn, error) {
+	return s.getDialIn(ctx, `pin = $1`, pin)
+}
+
+func (s *PostgresStore) getDialIn(ctx context.Context, where string, arg string) (*DialIn, error) {
+	var dialIn DialIn
+	err := s.db.QueryRowContext(ctx, `
+		SELECT room_name, pin, dispatch_rule_id, created_by, created_at
+		FROM rtc_dial_ins WHERE `+where, arg).
+		Scan(&dialIn.RoomName, &dialIn.PIN, &dialIn.DispatchRuleID, &dialIn.CreatedBy, &dialIn.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dial-in: %w", err)
+	}
+	return &dialIn, nil
+}
+
+func (s *PostgresStore) DeleteDialIn(ctx context.Context, roomName string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_dial_ins WHERE room_name = $1`, roomName)
+	if err != nil {
+		return fmt.Errorf("failed to delete dial-in: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) AddIssuedToken(ctx context.Context, token *IssuedToken) error {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO rtc_issued_tokens (room_name, identity, name, role, lobby, requested_by, client_ip, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`,
+		token.RoomName, token.Identity, token.Name, token.Role, token.Lobby, token.RequestedBy, token.ClientIP, token.IssuedAt, token.ExpiresAt).
+		Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("failed to add issued token: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListIssuedTokens(ctx context.Context, roomName, identity string, limit int) ([]*IssuedToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, room_name, identity, name, role, lobby, requested_by, client_ip, issued_at, expires_at, revoked_at, revoked_by
+		FROM rtc_issued_tokens WHERE room_name = $1 AND ($2 = '' OR identity = $2)
+		ORDER BY id DESC LIMIT $3`, roomName, identity, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issued tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*IssuedToken{}
+	for rows.Next() {
+		var token IssuedToken
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&token.ID, &token.RoomName, &token.Identity, &token.Name, &token.Role, &token.Lobby,
+			&token.RequestedBy, &token.ClientIP, &token.IssuedAt, &token.ExpiresAt, &revokedAt, &token.RevokedBy); err != nil {
+			return nil, fmt.Errorf("failed to list issued tokens: %w", err)
+		}
+		if revokedAt.Valid {
+			token.RevokedAt = &revokedAt.Time
+		}
+		tokens = append(tokens, &token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list issued tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *PostgresStore) RevokeIssuedTokens(ctx context.Context, roomName, identity, revokedBy string, at time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE rtc_issued_tokens SET revoked_at = $4, revoked_by = $3
+		WHERE room_name = $1 AND identity = $2 AND revoked_at IS NULL AND expires_at > $4`,
+		roomName, identity, revokedBy, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke issued tokens: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke issued tokens: %w", err)
+	}
+	return int(n), nil
+}
+
+func (s *PostgresStore) SaveWatchParty(ctx context.Context, party *WatchParty) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_watch_parties (room_name, media_url, playing, position, started_by, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (room_name) DO UPDATE SET
+			media_url = EXCLUDED.media_url,
+			playing = EXCLUDED.playing,
+			position = EXCLUDED.position,
+			started_by = EXCLUDED.started_by,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at`,
+		party.RoomName, party.MediaURL, party.Playing, party.Position, party.StartedBy, party.UpdatedBy, party.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save watch party: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetWatchParty(ctx context.Context, roomName string) (*WatchParty, error) {
+	var party WatchParty
+	err := s.db.QueryRowContext(ctx, `
+		SELECT room_name, media_url, playing, position, started_by, updated_by, updated_at
+		FROM rtc_watch_parties WHERE room_name = $1`, roomName).
+		Scan(&party.RoomName, &party.MediaURL, &party.Playing, &party.Position, &party.StartedBy, &party.UpdatedBy, &party.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watch party: %w", err)
+	}
+	return &party, nil
+}
+
+func (s *PostgresStore) DeleteWatchParty(ctx context.Context, roomName string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_watch_parties WHERE room_name = $1`, roomName)
+	if err != nil {
+		return fmt.Errorf("failed to delete watch party: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) SaveInvite(ctx context.Context, invite *Invite) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_invites (id, room_name, role, created_by, created_at, expires_at, redeemed_by, redeemed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			expires_at = EXCLUDED.expires_at,
+			redeemed_by = EXCLUDED.redeemed_by,
+			redeemed_at = EXCLUDED.redeemed_at`,
+		invite.ID, invite.RoomName, invite.Role, invite.CreatedBy, invite.CreatedAt,
+		invite.ExpiresAt, invite.RedeemedBy, invite.RedeemedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save invite: %w", err)
+	}
+	return nil
+}
+
+const inviteColumns = `id, room_name, role, created_by, created_at, expires_at, redeemed_by, redeemed_at`
+
+func scanInvite(row rowScanner) (*Invite, error) {
+	var invite Invite
+	var redeemedAt sql.NullTime
+	err := row.Scan(&invite.ID, &invite.RoomName, &invite.Role, &invite.CreatedBy, &invite.CreatedAt,
+		&invite.ExpiresAt, &invite.RedeemedBy, &redeemedAt)
+	if err != nil {
+		return nil, err
+	}
+	if redeemedAt.Valid {
+		invite.RedeemedAt = &redeemedAt.Time
+	}
+	return &invite, nil
+}
+
+func (s *PostgresStore) GetInvite(ctx context.Context, id string) (*Invite, error) {
+	invite, err := scanInvite(s.db.QueryRowContext(ctx,
+		`SELECT `+inviteColumns+` FROM rtc_invites WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+	return invite, nil
+}
+
+// RedeemInvite claims the invite in one statement, so of two guests
+// redeeming it at once only one succeeds.
+func (s *PostgresStore) RedeemInvite(ctx context.Context, id, guestID string, at time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE rtc_invites SET redeemed_by = $2, redeemed_at = $3
+		WHERE id = $1 AND redeemed_at IS NULL`, id, guestID, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to redeem invite: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to redeem invite: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *PostgresStore) ListInvites(ctx context.Context, roomName string) ([]*Invite, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+inviteColumns+` FROM rtc_invites WHERE room_name = $1
+		ORDER BY created_at DESC, id`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []*Invite{}
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list invites: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+	return invites, nil
+}
+
+func (s *PostgresStore) DeleteInvite(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_invites WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete invite: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) SaveBroadcast(ctx context.Context, broadcast *Broadcast) error {
+	destinationIDs, err := json.Marshal(broadcast.DestinationIDs)
+	if err != nil {
+		return fmt.Errorf("failed to save broadcast: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO rtc_broadcasts (egress_id, room_name, destination_ids, status, error, started_by, started_at, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (egress_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			error = EXCLUDED.error,
+			ended_at = EXCLUDED.ended_at`,
+		broadcast.EgressID, broadcast.RoomName, string(destinationIDs), broadcast.Status,
+		broadcast.Error, broadcast.StartedBy, broadcast.StartedAt, broadcast.EndedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save broadcast: %w", err)
+	}
+	return nil
+}
+
+const broadcastColumns = `egress_id, room_name, destination_ids, status, error, started_by, started_at, ended_at`
+
+func scanBroadcast(row rowScanner) (*Broadcast, error) {
+	var broadcast Broadcast
+	var destinationIDs []byte
+	var endedAt sql.NullTime
+	err := row.Scan(&broadcast.EgressID, &broadcast.RoomName, &destinationIDs, &broadcast.Status,
+		&broadcast.Error, &broadcast.StartedBy, &broadcast.StartedAt, &endedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(destinationIDs, &broadcast.DestinationIDs); err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		broadcast.EndedAt = &endedAt.Time
+	}
+	return &broadcast, nil
+}
+
+func (s *PostgresStore) GetBroadcast(ctx context.Context, egressID string) (*Broadcast, error) {
+	broadcast, err := scanBroadcast(s.db.QueryRowContext(ctx,
+		`SELECT `+broadcastColumns+` FROM rtc_broadcasts WHERE egress_id = $1`, egressID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get broadcast: %w", err)
+	}
+	return broadcast, nil
+}
+
+func (s *PostgresStore) ListBroadcasts(ctx context.Context, roomName string) ([]*Broadcast, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+broadcastColumns+` FROM rtc_broadcasts WHERE room_name = $1
+		ORDER BY started_at DESC, egress_id`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list broadcasts: %w", err)
+	}
+	defer rows.Close()
+
+	broadcasts := []*Broadcast{}
+	for rows.Next() {
+		broadcast, err := scanBroadcast(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list broadcasts: %w", err)
+		}
+		broadcasts = append(broadcasts, broadcast)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list broadcasts: %w", err)
+	}
+	return broadcasts, nil
+}
+
+func (s *PostgresStore) SaveBreakoutRoom(ctx context.Context, room *BreakoutRoom) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_breakout_rooms (room_name, parent_room, name, position, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (room_name) DO UPDATE SET
+			name = EXCLUDED.name,
+			position = EXCLUDED.position`,
+		room.RoomName, room.ParentRoom, room.Name, room.Position, room.CreatedBy, room.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save breakout room: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListBreakoutRooms(ctx context.Context, parentRoom string) ([]*BreakoutRoom, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT room_name, parent_room, name, position, created_by, created_at
+		FROM rtc_breakout_rooms WHERE parent_room = $1
+		ORDER BY position`, parentRoom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list breakout rooms: %w", err)
+	}
+	defer rows.Close()
+
+	rooms := []*BreakoutRoom{}
+	for rows.Next() {
+		var room BreakoutRoom
+		if err := rows.Scan(&room.RoomName, &room.ParentRoom, &room.Name, &room.Position, &room.CreatedBy, &room.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list breakout rooms: %w", err)
+		}
+		rooms = append(rooms, &room)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list breakout rooms: %w", err)
+	}
+	return rooms, nil
+}
+
+func (s *PostgresStore) DeleteBreakoutRooms(ctx context.Context, parentRoom string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete breakout rooms: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"rtc_breakout_assignments", "rtc_breakout_rooms"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE parent_room = $1", parentRoom); err != nil {
+			return fmt.Errorf("failed to delete breakout rooms: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete breakout rooms: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) SetBreakoutAssignment(ctx context.Context, parentRoom string, assignment *BreakoutAssignment) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_breakout_assignments (parent_room, user_id, room_name, assigned_by, assigned_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (parent_room, user_id) DO UPDATE SET
+			room_name = EXCLUDED.room_name,
+			assigned_by = EXCLUDED.assigned_by,
+			assigned_at = EXCLUDED.assigned_at`,
+		parentRoom, assignment.UserID, assignment.RoomName, assignment.AssignedBy, assignment.AssignedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set breakout assignment: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeleteBreakoutAssignment(ctx context.Context, parentRoom, userID string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM rtc_breakout_assignments WHERE parent_room = $1 AND user_id = $2`, parentRoom, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete breakout assignment: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListBreakoutAssignments(ctx context.Context, parentRoom string) ([]*BreakoutAssignment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, room_name, assigned_by, assigned_at
+		FROM rtc_breakout_assignments WHERE parent_room = $1
+		ORDER BY user_id`, parentRoom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list breakout assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []*BreakoutAssignment{}
+	for rows.Next() {
+		var assignment BreakoutAssignment
+		if err := rows.Scan(&assignment.UserID, &assignment.RoomName, &assignment.AssignedBy, &assignment.AssignedAt); err != nil {
+			return nil, fmt.Errorf("failed to list breakout assignments: %w", err)
+		}
+		assignments = append(assignments, &assignment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list breakout assignments: %w", err)
+	}
+	return assignments, nil
+}
+
+func (s *PostgresStore) AddChatMessage(ctx context.Context, message *ChatMessage) error {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO rtc_chat_messages (room_name, user_id, user_name, message, filtered, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		message.RoomName, message.UserID, message.UserName, message.Message, message.Filtered, message.CreatedAt).
+		Scan(&message.ID)
+	if err != nil {
+		return fmt.Errorf("failed to add chat message: %w", err)
+	}
+	return nil
+}
+
+const chatMessageColumns = `id, room_name, user_id, user_name, message, filtered, created_at, deleted_at, deleted_by`
+
+func scanChatMessage(row rowScanner) (*ChatMessage, error) {
+	var message ChatMessage
+	var deletedAt sql.NullTime
+	err := row.Scan(&message.ID, &message.RoomName, &message.UserID, &message.UserName, &message.Message,
+		&message.Filtered, &message.CreatedAt, &deletedAt, &message.DeletedBy)
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		message.DeletedAt = &deletedAt.Time
+	}
+	return &message, nil
+}
+
+func (s *PostgresStore) GetChatMessage(ctx context.Context, roomName string, id int64) (*ChatMessage, error) {
+	message, err := scanChatMessage(s.db.QueryRowContext(ctx,
+		`SELECT `+chatMessageColumns+` FROM rtc_chat_messages WHERE room_name = $1 AND id = $2`, roomName, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat message: %w", err)
+	}
+	return message, nil
+}
+
+func (s *PostgresStore) ListChatMessages(ctx context.Context, roomName string, afterID int64, limit int) ([]*ChatMessage, error) {
+	return s.listChatMessages(ctx, `
+		SELECT `+chatMessageColumns+` FROM rtc_chat_messages
+		WHERE room_name = $1 AND id > $2
+		ORDER BY id LIMIT $3`, roomName, afterID, limit)
+}
+
+func (s *PostgresStore) ListRecentChatMessages(ctx context.Context, roomName string, beforeID int64, limit int) ([]*ChatMessage, error) {
+	return s.listChatMessages(ctx, `
+		SELECT * FROM (
+			SELECT `+chatMessageColumns+` FROM rtc_chat_messages
+			WHERE room_name = $1 AND ($2 = 0 OR id < $2)
+			ORDER BY id DESC LIMIT $3
+		) recent ORDER BY id`, roomName, beforeID, limit)
+}
+
+func (s *PostgresStore) listChatMessages(ctx context.Context, query string, args ...interface{}) ([]*ChatMessage, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*ChatMessage{}
+	for rows.Next() {
+		message, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chat messages: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list chat messages: %w", err)
+	}
+	return messages, nil
+}
+
+func (s *PostgresStore) DeleteChatMessage(ctx context.Context, roomName string, id int64, deletedBy string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE rtc_chat_messages SET deleted_at = $3, deleted_by = $4
+		WHERE room_name = $1 AND id = $2`, roomName, id, at, deletedBy)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat message: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) LastChatMessageAt(ctx context.Context, roomName, userID string) (time.Time, error) {
+	var at time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT created_at FROM rtc_chat_messages
+		WHERE room_name = $1 AND user_id = $2
+		ORDER BY id DESC LIMIT 1`, roomName, userID).Scan(&at)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("failed to get last chat message: %w", err)
+	}
+	return at, nil
+}
+
+const participantStatsColumns = `room_name, user_id, user_name, sessions, connected_seconds, speaking_seconds,
+	hand_raises, first_joined_at, connected_since, speaking_since`
+
+func scanParticipantStats(row rowScanner) (*ParticipantStats, error) {
+	var stats ParticipantStats
+	var connectedSince, speakingSince sql.NullTime
+	err := row.Scan(&stats.RoomName, &stats.UserID, &stats.UserName, &stats.Sessions, &stats.ConnectedSeconds,
+		&stats.SpeakingSeconds, &stats.HandRaises, &stats.FirstJoinedAt, &connectedSince, &speakingSince)
+	if err != nil {
+		return nil, err
+	}
+	if connectedSince.Valid {
+		stats.ConnectedSince = &connectedSince.Time
+	}
+	if speakingSince.Valid {
+		stats.SpeakingSince = &speakingSince.Time
+	}
+	return &stats, nil
+}
+
+func (s *PostgresStore) GetParticipantStats(ctx context.Context, roomName, userID string) (*ParticipantStats, error) {
+	stats, err := scanParticipantStats(s.db.QueryRowContext(ctx,
+		`SELECT `+participantStatsColumns+` FROM rtc_participant_stats WHERE room_name = $1 AND user_id = $2`, roomName, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participant stats: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *PostgresStore) SaveParticipantStats(ctx context.Context, stats *ParticipantStats) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_participant_stats (`+participantStatsColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (room_name, user_id) DO UPDATE SET
+			user_name = EXCLUDED.user_name,
+			sessions = EXCLUDED.sessions,
+			connected_seconds = EXCLUDED.connected_seconds,
+			speaking_seconds = EXCLUDED.speaking_seconds,
+			hand_raises = EXCLUDED.hand_raises,
+			connected_since = EXCLUDED.connected_since,
+			speaking_since = EXCLUDED.speaking_since`,
+		stats.RoomName, stats.UserID, stats.UserName, stats.Sessions, stats.ConnectedSeconds, stats.SpeakingSeconds,
+		stats.HandRaises, stats.FirstJoinedAt, stats.ConnectedSince, stats.SpeakingSince)
+	if err != nil {
+		return fmt.Errorf("failed to save participant stats: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListParticipantStats(ctx context.Context, roomName string) ([]*ParticipantStats, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+participantStatsColumns+` FROM rtc_participant_stats WHERE room_name = $1 ORDER BY user_id`, roomName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list participant stats: %w", err)
+	}
+	defer rows.Close()
+
+	result := []*ParticipantStats{}
+	for rows.Next() {
+		stats, err := scanParticipantStats(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list participant stats: %w", err)
+		}
+		result = append(result, stats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list participant stats: %w", err)
+	}
+	return result, nil
+}
+
+const roomStatsColumns = `room_name, community_id, participants, peak_participants, peak_at, connected_seconds,
+	speaking_seconds, hand_raises, first_active_at, last_active_at`
+
+func scanRoomStats(row rowScanner) (*RoomStats, error) {
+	var stats RoomStats
+	var peakAt sql.NullTime
+	err := row.Scan(&stats.RoomName, &stats.CommunityID, &stats.Participants, &stats.PeakParticipants, &peakAt,
+		&stats.ConnectedSeconds, &stats.SpeakingSeconds, &stats.HandRaises, &stats.FirstActiveAt, &stats.LastActiveAt)
+	if err != nil {
+		return nil, err
+	}
+	if peakAt.Valid {
+		stats.PeakAt = &peakAt.Time
+	}
+	return &stats, nil
+}
+
+func (s *PostgresStore) GetRoomStats(ctx context.Context, roomName string) (*RoomStats, error) {
+	stats, err := scanRoomStats(s.db.QueryRowContext(ctx,
+		`SELECT `+roomStatsColumns+` FROM rtc_room_stats WHERE room_name = $1`, roomName))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room stats: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *PostgresStore) SaveRoomStats(ctx context.Context, stats *RoomStats) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_room_stats (`+roomStatsColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (room_name) DO UPDATE SET
+			community_id = EXCLUDED.community_id,
+			participants = EXCLUDED.participants,
+			peak_participants = EXCLUDED.peak_participants,
+			peak_at = EXCLUDED.peak_at,
+			connected_seconds = EXCLUDED.connected_seconds,
+			speaking_seconds = EXCLUDED.speaking_seconds,
+			hand_raises = EXCLUDED.hand_raises,
+			last_active_at = EXCLUDED.last_active_at`,
+		stats.RoomName, stats.CommunityID, stats.Participants, stats.PeakParticipants, stats.PeakAt,
+		stats.ConnectedSeconds, stats.SpeakingSeconds, stats.HandRaises, stats.FirstActiveAt, stats.LastActiveAt)
+	if err != nil {
+		return fmt.Errorf("failed to save room stats: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListRoomStats(ctx context.Context, communityID int, since time.Time) ([]*RoomStats, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+roomStatsColumns+` FROM rtc_room_stats
+		WHERE community_id = $1 AND last_active_at >= $2
+		ORDER BY last_active_at DESC`, communityID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list room stats: %w", err)
+	}
+	defer rows.Close()
+
+	result := []*RoomStats{}
+	for rows.Next() {
+		stats, err := scanRoomStats(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list room stats: %w", err)
+		}
+		result = append(result, stats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list room stats: %w", err)
+	}
+	return result, nil
+}
+
+func (s *PostgresStore) SaveRoomSchedule(ctx context.Context, schedule *RoomSchedule) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rtc_room_schedules (id, community_id, room_name, title, starts_at, ends_at, recurrence, recur_until,
+			auto_open, auto_close, max_participants, calendar_event_id, is_open, ended, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (id) DO UPDATE SET
+			title = EXCLUDED.title,
+			starts_at = EXCLUDED.starts_at,
+			ends_at = EXCLUDED.ends_at,
+			recurrence = EXCLUDED.recurrence,
+			recur_until = EXCLUDED.recur_until,
+			auto_open = EXCLUDED.auto_open,
+			auto_close = EXCLUDED.auto_close,
+			max_participants = EXCLUDED.max_participants,
+			is_open = EXCLUDED.is_open,
+			ended = EXCLUDED.ended`,
+		schedule.ID, schedule.CommunityID, schedule.RoomName, schedule.Title, schedule.StartsAt, schedule.EndsAt,
+		schedule.Recurrence, schedule.RecurUntil, schedule.AutoOpen, schedule.AutoClose, schedule.MaxParticipants,
+		schedule.CalendarEventID, schedule.IsOpen, schedule.Ended, schedule.CreatedBy, schedule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save room schedule: %w", err)
+	}
+	return nil
+}
+
+const roomScheduleColumns = `id, community_id, room_name, title, starts_at, ends_at, recurrence, recur_until,
+	auto_open, auto_close, max_participants, calendar_event_id, is_open, ended, created_by, created_at`
+
+func scanRoomSchedule(row rowScanner) (*RoomSchedule, error) {
+	var schedule RoomSchedule
+	var recurUntil sql.NullTime
+	err := row.Scan(&schedule.ID, &schedule.CommunityID, &schedule.RoomName, &schedule.Title,
+		&schedule.StartsAt, &schedule.EndsAt, &schedule.Recurrence, &recurUntil,
+		&schedule.AutoOpen, &schedule.AutoClose, &schedule.MaxParticipants, &schedule.CalendarEventID,
+		&schedule.IsOpen, &schedule.Ended, &schedule.CreatedBy, &schedule.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if recurUntil.Valid {
+		schedule.RecurUntil = &recurUntil.Time
+	}
+	return &schedule, nil
+}
+
+func (s *PostgresStore) GetRoomSchedule(ctx context.Context, id string) (*RoomSchedule, error) {
+	schedule, err := scanRoomSchedule(s.db.QueryRowContext(ctx,
+		`SELECT `+roomScheduleColumns+` FROM rtc_room_schedules WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+func (s *PostgresStore) ListRoomSchedules(ctx context.Context, communityID int) ([]*RoomSchedule, error) {
+	return s.listRoomSchedules(ctx,
+		`SELECT `+roomScheduleColumns+` FROM rtc_room_schedules WHERE community_id = $1 ORDER BY starts_at, id`, communityID)
+}
+
+func (s *PostgresStore) ListActiveRoomSchedules(ctx context.Context) ([]*RoomSchedule, error) {
+	return s.listRoomSchedules(ctx,
+		`SELECT `+roomScheduleColumns+` FROM rtc_room_schedules WHERE NOT ended ORDER BY starts_at, id`)
+}
+
+func (s *PostgresStore) listRoomSchedules(ctx context.Context, query string, args ...interface{}) ([]*RoomSchedule, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list room schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []*RoomSchedule{}
+	for rows.Next() {
+		schedule, err := scanRoomSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list room schedules: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list room schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+func (s *PostgresStore) DeleteRoomSchedule(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rtc_room_schedules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete room schedule: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) CountRooms(ctx context.Context) (int, int, error) {
+	var rooms, participants int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM rtc_rooms), (SELECT COUNT(*) FROM rtc_participants)`,
+	).Scan(&rooms, &participants)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count rooms: %w", err)
+	}
+	return rooms, participants, nil
+}
+
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
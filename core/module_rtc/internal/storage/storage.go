@@ -0,0 +1,451 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Room struct {
+	RoomName        string    `json:"room_name"`
+	RoomID          string    `json:"room_id"`
+	CommunityID     int       `json:"community_id"`
+	MaxParticipants uint32    `json:"max_participants"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type RoomState struct {
+	RoomName          string        `json:"room_name"`
+	IsLocked          bool          `json:"is_locked"`
+	LockedBy          string        `json:"locked_by,omitempty"`
+	SlowModeSeconds   int           `json:"slow_mode_seconds"`
+	ScreenShare       string        `json:"screen_share,omitempty"` // empty for the default
+	HandExpiryMinutes int           `json:"hand_expiry_minutes"`    // zero keeps hands up
+	Template          string        `json:"template,omitempty"`     // the room was created from
+	DefaultRole       string        `json:"default_role,omitempty"` // empty for viewer
+	Lobby             bool          `json:"lobby"`
+	RecordingDisabled bool          `json:"recording_disabled"`
+	Transcribing      bool          `json:"transcribing"`
+	Audio             AudioSettings `json:"audio"`             // zero for the defaults
+	TokenTTLSeconds   int           `json:"token_ttl_seconds"` // zero for the default
+	UpdatedAt         time.Time     `json:"updated_at"`
+}
+
+// AudioSettings are how clients in a room process the audio they publish.
+// Settings with no NoiseSuppression were never set.
+type AudioSettings struct {
+	NoiseSuppression string `json:"noise_suppression"`
+	EchoCancellation bool   `json:"echo_cancellation"`
+	AutoGainControl  bool   `json:"auto_gain_control"`
+	RED              bool   `json:"red"`
+	DTX              bool   `json:"dtx"`
+}
+
+type Participant struct {
+	Identity string    `json:"identity"`
+	Name     string    `json:"name"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// RaisedHand is a hand in a room's queue. Its Position and ExpiresAt are not
+// stored but worked out when the queue is listed.
+type RaisedHand struct {
+	UserID         string     `json:"user_id"`
+	UserName       string     `json:"user_name"`
+	RaisedAt       time.Time  `json:"raised_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	Position       int        `json:"position"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// RoleAssignment is a role a participant was promoted or demoted to in a room.
+type RoleAssignment struct {
+	UserID     string    `json:"user_id"`
+	Role       string    `json:"role"`
+	AssignedBy string    `json:"assigned_by"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Recording is a LiveKit egress recording of a room or of one track.
+type Recording struct {
+	EgressID        string     `json:"egress_id"`
+	RoomName        string     `json:"room_name"`
+	Kind            string     `json:"kind"`
+	TrackID         string     `json:"track_id,omitempty"`
+	Status          string     `json:"status"`
+	Destination     string     `json:"destination"`
+	Location        string     `json:"location,omitempty"`
+	Filename        string     `json:"filename,omitempty"`
+	Size            int64      `json:"size"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	Error           string     `json:"error,omitempty"`
+	StartedBy       string     `json:"started_by"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+}
+
+// StreamDestination is an RTMP endpoint a community's rooms can be
+// broadcast to.
+type StreamDestination struct {
+	ID          string    `json:"id"`
+	CommunityID int       `json:"community_id"`
+	Name        string    `json:"name"`
+	Platform    string    `json:"platform"`
+	RTMPURL     string    `json:"rtmp_url"`
+	StreamKey   string    `json:"-"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Ban keeps a user out of a room until it expires, or for good if
+// ExpiresAt is nil.
+type Ban struct {
+	RoomName  string     `json:"room_name"`
+	UserID    string     `json:"user_id"`
+	Reason    string     `json:"reason"`
+	BannedBy  string     `json:"banned_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// DialIn lets phone callers join a room by entering its PIN, through a
+// LiveKit SIP dispatch rule.
+type DialIn struct {
+	RoomName       string    `json:"room_name"`
+	PIN            string    `json:"pin"`
+	DispatchRuleID string    `json:"-"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// WatchParty is media a room plays in sync. Position is where playback was,
+// in seconds, at UpdatedAt, from which clients work out where it is now.
+type WatchParty struct {
+	RoomName  string    `json:"room_name"`
+	MediaURL  string    `json:"media_url"`
+	Playing   bool      `json:"playing"`
+	Position  float64   `json:"position"`
+	StartedBy string    `json:"started_by"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IssuedToken is a record of a join token the module issued. RequestedBy
+// and ClientIP are who asked for it over the API, if anyone did. Tokens are
+// revoked by removing the participant, and kept after their room is deleted.
+type IssuedToken struct {
+	ID          int64      `json:"id"`
+	RoomName    string     `json:"room_name"`
+	Identity    string     `json:"identity"`
+	Name        string     `json:"name"`
+	Role        string     `json:"role"`
+	Lobby       bool       `json:"lobby"`
+	RequestedBy string     `json:"requested_by,omitempty"`
+	ClientIP    string     `json:"client_ip,omitempty"`
+	IssuedAt    time.Time  `json:"issued_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy   string     `json:"revoked_by,omitempty"`
+}
+
+// Invite is a single-use link letting a guest without an account join a
+// room. The link carries a signed token; only its ID is stored.
+type Invite struct {
+	ID         string     `json:"id"`
+	RoomName   string     `json:"room_name"`
+	Role       string     `json:"role"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RedeemedBy string     `json:"redeemed_by,omitempty"`
+	RedeemedAt *time.Time `json:"redeemed_at,omitempty"`
+}
+
+// RoomTemplate is a named set of settings a community's rooms are created
+// with. Zero values leave the module's defaults.
+type RoomTemplate struct {
+	CommunityID         int       `json:"community_id"`
+	Name                string    `json:"name"`
+	MaxParticipants     uint32    `json:"max_participants"`
+	EmptyTimeoutSeconds uint32    `json:"empty_timeout_seconds"`
+	DefaultRole         string    `json:"default_role"`
+	Lobby               bool      `json:"lobby"`
+	Recording           bool      `json:"recording"`
+	Codecs              []string  `json:"codecs"`
+	IsDefault           bool      `json:"is_default"`
+	UpdatedBy           string    `json:"updated_by"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// Broadcast is a LiveKit egress streaming a room to stream destinations.
+type Broadcast struct {
+	EgressID       string     `json:"egress_id"`
+	RoomName       string     `json:"room_name"`
+	DestinationIDs []string   `json:"destination_ids"`
+	Status         string     `json:"status"`
+	Error          string     `json:"error,omitempty"`
+	StartedBy      string     `json:"started_by"`
+	StartedAt      time.Time  `json:"started_at"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+}
+
+// BreakoutRoom is a room split off from a parent room for a breakout
+// session.
+type BreakoutRoom struct {
+	RoomName   string    `json:"room_name"`
+	ParentRoom string    `json:"parent_room"`
+	Name       string    `json:"name"`
+	Position   int       `json:"position"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BreakoutAssignment is the breakout room a participant of the parent room
+// was sent to.
+type BreakoutAssignment struct {
+	UserID     string    `json:"user_id"`
+	RoomName   string    `json:"room_name"`
+	AssignedBy string    `json:"assigned_by"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+// RoomSchedule is a room opened and closed on a schedule. StartsAt and
+// EndsAt are its next occurrence, or the current one while it runs.
+type RoomSchedule struct {
+	ID              string     `json:"id"`
+	CommunityID     int        `json:"community_id"`
+	RoomName        string     `json:"room_name"`
+	Title           string     `json:"title"`
+	StartsAt        time.Time  `json:"starts_at"`
+	EndsAt          time.Time  `json:"ends_at"`
+	Recurrence      string     `json:"recurrence,omitempty"`
+	RecurUntil      *time.Time `json:"recur_until,omitempty"`
+	AutoOpen        bool       `json:"auto_open"`
+	AutoClose       bool       `json:"auto_close"`
+	MaxParticipants uint32     `json:"max_participants"`
+	CalendarEventID int        `json:"calendar_event_id,omitempty"`
+	IsOpen          bool       `json:"is_open"`
+	Ended           bool       `json:"ended"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ChatMessage is a message posted to a room's chat. Deleted messages are
+// kept for the archive.
+type ChatMessage struct {
+	ID        int64      `json:"id"`
+	RoomName  string     `json:"room_name"`
+	UserID    string     `json:"user_id"`
+	UserName  string     `json:"user_name"`
+	Message   string     `json:"message"`
+	Filtered  bool       `json:"filtered,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
+}
+
+// TranscriptSegment is a stretch of one participant's speech, transcribed
+// from their microphone while the room was being transcribed.
+type TranscriptSegment struct {
+	ID        int64     `json:"id"`
+	RoomName  string    `json:"room_name"`
+	UserID    string    `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	TrackID   string    `json:"track_id"`
+	Text      string    `json:"text"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+}
+
+// ParticipantStats is a participant's time in a room, summed over their
+// sessions. ConnectedSince and SpeakingSince are set while they are in the
+// room and speaking.
+type ParticipantStats struct {
+	RoomName         string     `json:"room_name"`
+	UserID           string     `json:"user_id"`
+	UserName         string     `json:"user_name"`
+	Sessions         int        `json:"sessions"`
+	ConnectedSeconds float64    `json:"connected_seconds"`
+	SpeakingSeconds  float64    `json:"speaking_seconds"`
+	HandRaises       int        `json:"hand_raises"`
+	FirstJoinedAt    time.Time  `json:"first_joined_at"`
+	ConnectedSince   *time.Time `json:"connected_since,omitempty"`
+	SpeakingSince    *time.Time `json:"speaking_since,omitempty"`
+}
+
+// RoomStats is a room's usage over its lifetime. The time totals count
+// finished sessions and speaking turns.
+type RoomStats struct {
+	RoomName         string     `json:"room_name"`
+	CommunityID      int        `json:"community_id"`
+	Participants     int        `json:"participants"`
+	PeakParticipants int        `json:"peak_participants"`
+	PeakAt           *time.Time `json:"peak_at,omitempty"`
+	ConnectedSeconds float64    `json:"connected_seconds"`
+	SpeakingSeconds  float64    `json:"speaking_seconds"`
+	HandRaises       int        `json:"hand_raises"`
+	FirstActiveAt    time.Time  `json:"first_active_at"`
+	LastActiveAt     time.Time  `json:"last_active_at"`
+}
+
+// Store persists rooms and call state so they survive restarts and are
+// shared between replicas.
+type Store interface {
+	SaveRoom(ctx context.Context, room *Room) error
+	GetRoom(ctx context.Context, roomName string) (*Room, error)
+	// ListRooms returns the community's rooms, oldest first.
+	ListRooms(ctx context.Context, communityID int) ([]*Room, error)
+	DeleteRoom(ctx context.Context, roomName string) error
+
+	GetRoomState(ctx context.Context, roomName string) (*RoomState, error)
+	SaveRoomState(ctx context.Context, state *RoomState) error
+
+	// AddRaisedHand queues a hand, reporting false if it was already raised.
+	AddRaisedHand(ctx context.Context, roomName string, hand *RaisedHand) (bool, error)
+	AcknowledgeHand(ctx context.Context, roomName, userID, moderatorID string, at time.Time) error
+	// RemoveRaisedHand lowers a hand, reporting false if it was not raised.
+	RemoveRaisedHand(ctx context.Context, roomName, userID string) (bool, error)
+	ListRaisedHands(ctx context.Context, roomName string) ([]*RaisedHand, error)
+	ClearRaisedHands(ctx context.Context, roomName string) error
+	// ListExpiredRaisedHands returns, by room, the unacknowledged hands
+	// raised longer ago than their room's hand expiry.
+	ListExpiredRaisedHands(ctx context.Context, now time.Time) (map[string][]*RaisedHand, error)
+
+	AddParticipant(ctx context.Context, roomName string, participant *Participant) error
+	RemoveParticipant(ctx context.Context, roomName, identity string) error
+	ListParticipants(ctx context.Context, roomName string) ([]*Participant, error)
+	ClearParticipants(ctx context.Context, roomName string) error
+
+	// GetParticipantRole returns the user's assigned role in the room, or
+	// ErrNotFound if none was assigned.
+	GetParticipantRole(ctx context.Context, roomName, userID string) (*RoleAssignment, error)
+	SetParticipantRole(ctx context.Context, roomName string, assignment *RoleAssignment) error
+	ListParticipantRoles(ctx context.Context, roomName string) ([]*RoleAssignment, error)
+
+	// Recordings are kept after their room is deleted.
+	SaveRecording(ctx context.Context, recording *Recording) error
+	GetRecording(ctx context.Context, egressID string) (*Recording, error)
+	// ListRecordings returns the room's recordings, newest first.
+	ListRecordings(ctx context.Context, roomName string) ([]*Recording, error)
+
+	SaveStreamDestination(ctx context.Context, destination *StreamDestination) error
+	GetStreamDestination(ctx context.Context, id string) (*StreamDestination, error)
+	ListStreamDestinations(ctx context.Context, communityID int) ([]*StreamDestination, error)
+	DeleteStreamDestination(ctx context.Context, id string) error
+
+	// SaveRoomTemplate stores the template by community and name. Saving a
+	// default template makes it the community's only default.
+	SaveRoomTemplate(ctx context.Context, template *RoomTemplate) error
+	GetRoomTemplate(ctx context.Context, communityID int, name string) (*RoomTemplate, error)
+	// GetDefaultRoomTemplate returns the community's default template, or
+	// ErrNotFound if it has none.
+	GetDefaultRoomTemplate(ctx context.Context, communityID int) (*RoomTemplate, error)
+	// ListRoomTemplates returns the community's templates by name.
+	ListRoomTemplates(ctx context.Context, communityID int) ([]*RoomTemplate, error)
+	DeleteRoomTemplate(ctx context.Context, communityID int, name string) error
+
+	// SaveBan stores the ban, replacing any of the user's in the room.
+	SaveBan(ctx context.Context, ban *Ban) error
+	// GetBan returns the user's ban from the room, expired or not, or
+	// ErrNotFound.
+	GetBan(ctx context.Context, roomName, userID string) (*Ban, error)
+	// ListBans returns the room's bans, newest first.
+	ListBans(ctx context.Context, roomName string) ([]*Ban, error)
+	DeleteBan(ctx context.Context, roomName, userID string) error
+
+	// SaveDialIn stores the room's dial-in, replacing any it had.
+	SaveDialIn(ctx context.Context, dialIn *DialIn) error
+	GetDialIn(ctx context.Context, roomName string) (*DialIn, error)
+	// GetDialInByPIN returns the dial-in using the PIN, or ErrNotFound.
+	GetDialInByPIN(ctx context.Context, pin string) (*DialIn, error)
+	DeleteDialIn(ctx context.Context, roomName string) error
+
+	// SaveWatchParty stores the room's watch party, replacing any it had.
+	SaveWatchParty(ctx context.Context, party *WatchParty) error
+	GetWatchParty(ctx context.Context, roomName string) (*WatchParty, error)
+	DeleteWatchParty(ctx context.Context, roomName string) error
+
+	// AddIssuedToken stores a record of a token, setting its ID.
+	AddIssuedToken(ctx context.Context, token *IssuedToken) error
+	// ListIssuedTokens returns up to limit of the room's tokens, or those of
+	// the identity if it is set, newest first.
+	ListIssuedTokens(ctx context.Context, roomName, identity string, limit int) ([]*IssuedToken, error)
+	// RevokeIssuedTokens marks the identity's tokens in the room that have
+	// not expired or been revoked by then revoked, returning how many were.
+	RevokeIssuedTokens(ctx context.Context, roomName, identity, revokedBy string, at time.Time) (int, error)
+
+	SaveInvite(ctx context.Context, invite *Invite) error
+	GetInvite(ctx context.Context, id string) (*Invite, error)
+	// RedeemInvite marks the invite used by the guest, reporting false if it
+	// already was or does not exist.
+	RedeemInvite(ctx context.Context, id, guestID string, at time.Time) (bool, error)
+	// ListInvites returns the room's invites, newest first.
+	ListInvites(ctx context.Context, roomName string) ([]*Invite, error)
+	DeleteInvite(ctx context.Context, id string) error
+
+	SaveBroadcast(ctx context.Context, broadcast *Broadcast) error
+	GetBroadcast(ctx context.Context, egressID string) (*Broadcast, error)
+	// ListBroadcasts returns the room's broadcasts, newest first.
+	ListBroadcasts(ctx context.Context, roomName string) ([]*Broadcast, error)
+
+	SaveBreakoutRoom(ctx context.Context, room *BreakoutRoom) error
+	// ListBreakoutRooms returns the parent room's breakout rooms by position.
+	ListBreakoutRooms(ctx context.Context, parentRoom string) ([]*BreakoutRoom, error)
+	// DeleteBreakoutRooms removes the parent room's breakout rooms and
+	// assignments.
+	DeleteBreakoutRooms(ctx context.Context, parentRoom string) error
+	SetBreakoutAssignment(ctx context.Context, parentRoom string, assignment *BreakoutAssignment) error
+	DeleteBreakoutAssignment(ctx context.Context, parentRoom, userID string) error
+	ListBreakoutAssignments(ctx context.Context, parentRoom string) ([]*BreakoutAssignment, error)
+
+	// AddChatMessage stores a message, setting its ID. IDs increase with
+	// each message.
+	AddChatMessage(ctx context.Context, message *ChatMessage) error
+	GetChatMessage(ctx context.Context, roomName string, id int64) (*ChatMessage, error)
+	// ListChatMessages returns up to limit messages after afterID, oldest
+	// first.
+	ListChatMessages(ctx context.Context, roomName string, afterID int64, limit int) ([]*ChatMessage, error)
+	// ListRecentChatMessages returns the last limit messages before
+	// beforeID, or the last limit if it is zero, oldest first.
+	ListRecentChatMessages(ctx context.Context, roomName string, beforeID int64, limit int) ([]*ChatMessage, error)
+	DeleteChatMessage(ctx context.Context, roomName string, id int64, deletedBy string, at time.Time) error
+	// LastChatMessageAt returns when the user last posted in the room, or
+	// the zero time.
+	LastChatMessageAt(ctx context.Context, roomName, userID string) (time.Time, error)
+
+	// AddTranscriptSegment stores a segment, setting its ID. Transcripts
+	// are kept after their room is deleted.
+	AddTranscriptSegment(ctx context.Context, segment *TranscriptSegment) error
+	// ListTranscript returns the room's segments in the order they were
+	// spoken.
+	ListTranscript(ctx context.Context, roomName string) ([]*TranscriptSegment, error)
+	DeleteTranscript(ctx context.Context, roomName string) error
+
+	GetParticipantStats(ctx context.Context, roomName, userID string) (*ParticipantStats, error)
+	SaveParticipantStats(ctx context.Context, stats *ParticipantStats) error
+	ListParticipantStats(ctx context.Context, roomName string) ([]*ParticipantStats, error)
+	GetRoomStats(ctx context.Context, roomName string) (*RoomStats, error)
+	SaveRoomStats(ctx context.Context, stats *RoomStats) error
+	// ListRoomStats returns the community's rooms active since the given
+	// time, most recently active first.
+	ListRoomStats(ctx context.Context, communityID int, since time.Time) ([]*RoomStats, error)
+
+	SaveRoomSchedule(ctx context.Context, schedule *RoomSchedule) error
+	GetRoomSchedule(ctx context.Context, id string) (*RoomSchedule, error)
+	// ListRoomSchedules returns the community's schedules by next start.
+	ListRoomSchedules(ctx context.Context, communityID int) ([]*RoomSchedule, error)
+	// ListActiveRoomSchedules returns every schedule that has not ended.
+	ListActiveRoomSchedules(ctx context.Context) ([]*RoomSchedule, error)
+	DeleteRoomSchedule(ctx context.Context, id string) error
+
+	// CountRooms returns how many rooms there are and how many participants
+	// are in rooms.
+	CountRooms(ctx context.Context) (rooms, participants int, err error)
+
+	// Ping checks that the store can be reached.
+	Ping(ctx context.Context) error
+	Close() error
+}
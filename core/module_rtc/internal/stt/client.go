@@ -0,0 +1,80 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client transcribes speech with an OpenAI-compatible speech-to-text API:
+// OpenAI's own, or a self-hosted Whisper server speaking the same protocol.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the API at baseURL, such as
+// https://api.openai.com. The key may be empty for servers without auth.
+func NewClient(baseURL, apiKey, model string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Transcribe returns the text spoken in the WAV audio. The language is an
+// ISO-639-1 code, or empty to have the backend detect it.
+func (c *Client) Transcribe(ctx context.Context, audio []byte, language string) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode transcription request: %w", err)
+	}
+	file.Write(audio)
+	form.WriteField("model", c.model)
+	form.WriteField("response_format", "json")
+	if language != "" {
+		form.WriteField("language", language)
+	}
+	if err := form.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode transcription request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/audio/transcriptions", &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create transcription request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("transcription request failed: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode transcription response: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
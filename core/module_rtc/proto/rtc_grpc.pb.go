@@ -0,0 +1,2411 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: rtc.proto
+
+package rtcpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	RTCService_CreateRoom_FullMethodName           = "/waddlebot.rtc.RTCService/CreateRoom"
+	RTCService_GetRoom_FullMethodName              = "/waddlebot.rtc.RTCService/GetRoom"
+	RTCService_DeleteRoom_FullMethodName           = "/waddlebot.rtc.RTCService/DeleteRoom"
+	RTCService_JoinRoom_FullMethodName             = "/waddlebot.rtc.RTCService/JoinRoom"
+	RTCService_LeaveRoom_FullMethodName            = "/waddlebot.rtc.RTCService/LeaveRoom"
+	RTCService_ListParticipants_FullMethodName     = "/waddlebot.rtc.RTCService/ListParticipants"
+	RTCService_PromoteParticipant_FullMethodName   = "/waddlebot.rtc.RTCService/PromoteParticipant"
+	RTCService_DemoteParticipant_FullMethodName    = "/waddlebot.rtc.RTCService/DemoteParticipant"
+	RTCService_RaiseHand_FullMethodName            = "/waddlebot.rtc.RTCService/RaiseHand"
+	RTCService_LowerHand_FullMethodName            = "/waddlebot.rtc.RTCService/LowerHand"
+	RTCService_GetRaisedHands_FullMethodName       = "/waddlebot.rtc.RTCService/GetRaisedHands"
+	RTCService_AcknowledgeHand_FullMethodName      = "/waddlebot.rtc.RTCService/AcknowledgeHand"
+	RTCService_NextSpeaker_FullMethodName          = "/waddlebot.rtc.RTCService/NextSpeaker"
+	RTCService_SetHandExpiry_FullMethodName        = "/waddlebot.rtc.RTCService/SetHandExpiry"
+	RTCService_MuteParticipant_FullMethodName      = "/waddlebot.rtc.RTCService/MuteParticipant"
+	RTCService_UnmuteParticipant_FullMethodName    = "/waddlebot.rtc.RTCService/UnmuteParticipant"
+	RTCService_MuteAll_FullMethodName              = "/waddlebot.rtc.RTCService/MuteAll"
+	RTCService_RevokeMedia_FullMethodName          = "/waddlebot.rtc.RTCService/RevokeMedia"
+	RTCService_RestoreMedia_FullMethodName         = "/waddlebot.rtc.RTCService/RestoreMedia"
+	RTCService_KickParticipant_FullMethodName      = "/waddlebot.rtc.RTCService/KickParticipant"
+	RTCService_BanParticipant_FullMethodName       = "/waddlebot.rtc.RTCService/BanParticipant"
+	RTCService_ListBans_FullMethodName             = "/waddlebot.rtc.RTCService/ListBans"
+	RTCService_LiftBan_FullMethodName              = "/waddlebot.rtc.RTCService/LiftBan"
+	RTCService_AdmitParticipant_FullMethodName     = "/waddlebot.rtc.RTCService/AdmitParticipant"
+	RTCService_RevokeTokens_FullMethodName         = "/waddlebot.rtc.RTCService/RevokeTokens"
+	RTCService_LockRoom_FullMethodName             = "/waddlebot.rtc.RTCService/LockRoom"
+	RTCService_UnlockRoom_FullMethodName           = "/waddlebot.rtc.RTCService/UnlockRoom"
+	RTCService_SetMaxParticipants_FullMethodName   = "/waddlebot.rtc.RTCService/SetMaxParticipants"
+	RTCService_SetAudioSettings_FullMethodName     = "/waddlebot.rtc.RTCService/SetAudioSettings"
+	RTCService_SetScreenShare_FullMethodName       = "/waddlebot.rtc.RTCService/SetScreenShare"
+	RTCService_StopScreenShare_FullMethodName      = "/waddlebot.rtc.RTCService/StopScreenShare"
+	RTCService_CreateInvite_FullMethodName         = "/waddlebot.rtc.RTCService/CreateInvite"
+	RTCService_RedeemInvite_FullMethodName         = "/waddlebot.rtc.RTCService/RedeemInvite"
+	RTCService_GetDialIn_FullMethodName            = "/waddlebot.rtc.RTCService/GetDialIn"
+	RTCService_EnableDialIn_FullMethodName         = "/waddlebot.rtc.RTCService/EnableDialIn"
+	RTCService_DisableDialIn_FullMethodName        = "/waddlebot.rtc.RTCService/DisableDialIn"
+	RTCService_StartRecording_FullMethodName       = "/waddlebot.rtc.RTCService/StartRecording"
+	RTCService_StopRecording_FullMethodName        = "/waddlebot.rtc.RTCService/StopRecording"
+	RTCService_ListRecordings_FullMethodName       = "/waddlebot.rtc.RTCService/ListRecordings"
+	RTCService_StartTranscription_FullMethodName   = "/waddlebot.rtc.RTCService/StartTranscription"
+	RTCService_StopTranscription_FullMethodName    = "/waddlebot.rtc.RTCService/StopTranscription"
+	RTCService_GetTranscript_FullMethodName        = "/waddlebot.rtc.RTCService/GetTranscript"
+	RTCService_StartBroadcast_FullMethodName       = "/waddlebot.rtc.RTCService/StartBroadcast"
+	RTCService_StopBroadcast_FullMethodName        = "/waddlebot.rtc.RTCService/StopBroadcast"
+	RTCService_ListBroadcasts_FullMethodName       = "/waddlebot.rtc.RTCService/ListBroadcasts"
+	RTCService_CreateBreakouts_FullMethodName      = "/waddlebot.rtc.RTCService/CreateBreakouts"
+	RTCService_GetBreakouts_FullMethodName         = "/waddlebot.rtc.RTCService/GetBreakouts"
+	RTCService_AssignBreakouts_FullMethodName      = "/waddlebot.rtc.RTCService/AssignBreakouts"
+	RTCService_AutoAssignBreakouts_FullMethodName  = "/waddlebot.rtc.RTCService/AutoAssignBreakouts"
+	RTCService_BroadcastToBreakouts_FullMethodName = "/waddlebot.rtc.RTCService/BroadcastToBreakouts"
+	RTCService_ReturnFromBreakouts_FullMethodName  = "/waddlebot.rtc.RTCService/ReturnFromBreakouts"
+	RTCService_CloseBreakouts_FullMethodName       = "/waddlebot.rtc.RTCService/CloseBreakouts"
+	RTCService_PostChatMessage_FullMethodName      = "/waddlebot.rtc.RTCService/PostChatMessage"
+	RTCService_ListChatMessages_FullMethodName     = "/waddlebot.rtc.RTCService/ListChatMessages"
+	RTCService_DeleteChatMessage_FullMethodName    = "/waddlebot.rtc.RTCService/DeleteChatMessage"
+	RTCService_StartWatchParty_FullMethodName      = "/waddlebot.rtc.RTCService/StartWatchParty"
+	RTCService_ControlWatchParty_FullMethodName    = "/waddlebot.rtc.RTCService/ControlWatchParty"
+	RTCService_GetWatchParty_FullMethodName        = "/waddlebot.rtc.RTCService/GetWatchParty"
+	RTCService_StopWatchParty_FullMethodName       = "/waddlebot.rtc.RTCService/StopWatchParty"
+	RTCService_ListUpcomingRooms_FullMethodName    = "/waddlebot.rtc.RTCService/ListUpcomingRooms"
+	RTCService_StreamRoomEvents_FullMethodName     = "/waddlebot.rtc.RTCService/StreamRoomEvents"
+)
+
+// RTCServiceClient is the client API for RTCService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RTCServiceClient interface {
+	// Rooms
+	CreateRoom(ctx context.Context, in *CreateRoomRequest, opts ...grpc.CallOption) (*Room, error)
+	GetRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*Room, error)
+	DeleteRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Participants
+	JoinRoom(ctx context.Context, in *JoinRoomRequest, opts ...grpc.CallOption) (*JoinToken, error)
+	LeaveRoom(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	ListParticipants(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*ListParticipantsResponse, error)
+	// An empty role moves one step along viewer, speaker, moderator
+	PromoteParticipant(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*RoleChange, error)
+	DemoteParticipant(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*RoleChange, error)
+	// Raised hands
+	RaiseHand(ctx context.Context, in *RaiseHandRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	LowerHand(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	GetRaisedHands(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*RaisedHandsResponse, error)
+	AcknowledgeHand(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Takes the first hand off the queue and unmutes its owner
+	NextSpeaker(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*RaisedHand, error)
+	// Lowers unacknowledged hands after minutes; 0 keeps them up
+	SetHandExpiry(ctx context.Context, in *HandExpiryRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Moderation
+	MuteParticipant(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	UnmuteParticipant(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	MuteAll(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Stops the user publishing their camera and microphone, unlike a mute
+	// they cannot undo themselves
+	RevokeMedia(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	RestoreMedia(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	KickParticipant(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Kicks the user and keeps them out until the ban expires or is lifted
+	BanParticipant(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*Ban, error)
+	ListBans(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*ListBansResponse, error)
+	LiftBan(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Lets a user waiting in the room's lobby in with its default role
+	AdmitParticipant(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*RoleChange, error)
+	// Revokes the user's join tokens for the room and removes them from it
+	RevokeTokens(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*RevokeTokensResponse, error)
+	LockRoom(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	UnlockRoom(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Those already in the room stay if there are more of them than the limit
+	SetMaxParticipants(ctx context.Context, in *MaxParticipantsRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Changes the audio settings the request sets, returning the room's
+	SetAudioSettings(ctx context.Context, in *AudioSettingsRequest, opts ...grpc.CallOption) (*AudioSettings, error)
+	// screen_share is hosts, speakers or everyone
+	SetScreenShare(ctx context.Context, in *ScreenShareRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	StopScreenShare(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Single-use guest invites; redeeming one returns a token for a new guest,
+	// or for user_id when set
+	CreateInvite(ctx context.Context, in *CreateInviteRequest, opts ...grpc.CallOption) (*Invite, error)
+	RedeemInvite(ctx context.Context, in *RedeemInviteRequest, opts ...grpc.CallOption) (*JoinToken, error)
+	// Phone dial-in; enabling it again gives the room a new PIN
+	GetDialIn(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*DialIn, error)
+	EnableDialIn(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*DialIn, error)
+	DisableDialIn(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Recordings; a track_id records only that track
+	StartRecording(ctx context.Context, in *StartRecordingRequest, opts ...grpc.CallOption) (*Recording, error)
+	StopRecording(ctx context.Context, in *StopRecordingRequest, opts ...grpc.CallOption) (*Recording, error)
+	ListRecordings(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*ListRecordingsResponse, error)
+	// Live captions; the transcript is kept after the call
+	StartTranscription(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	StopTranscription(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	GetTranscript(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*Transcript, error)
+	// RTMP broadcasts to the community's stream destinations
+	StartBroadcast(ctx context.Context, in *StartBroadcastRequest, opts ...grpc.CallOption) (*Broadcast, error)
+	StopBroadcast(ctx context.Context, in *StopBroadcastRequest, opts ...grpc.CallOption) (*Broadcast, error)
+	ListBroadcasts(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*ListBroadcastsResponse, error)
+	// Breakout rooms; participants are sent tokens for their new room
+	CreateBreakouts(ctx context.Context, in *CreateBreakoutsRequest, opts ...grpc.CallOption) (*Breakouts, error)
+	GetBreakouts(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*Breakouts, error)
+	AssignBreakouts(ctx context.Context, in *AssignBreakoutsRequest, opts ...grpc.CallOption) (*BreakoutMovesResponse, error)
+	AutoAssignBreakouts(ctx context.Context, in *AutoAssignBreakoutsRequest, opts ...grpc.CallOption) (*BreakoutMovesResponse, error)
+	BroadcastToBreakouts(ctx context.Context, in *BreakoutMessageRequest, opts ...grpc.CallOption) (*BreakoutMessageResponse, error)
+	ReturnFromBreakouts(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*BreakoutMovesResponse, error)
+	CloseBreakouts(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Room chat; callers post as any user and are not held to slow mode
+	PostChatMessage(ctx context.Context, in *PostChatMessageRequest, opts ...grpc.CallOption) (*ChatMessage, error)
+	ListChatMessages(ctx context.Context, in *ListChatMessagesRequest, opts ...grpc.CallOption) (*ListChatMessagesResponse, error)
+	DeleteChatMessage(ctx context.Context, in *DeleteChatMessageRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Watch parties; participants are kept in sync on the waddlebot.watch
+	// data topic, and action is play, pause or seek
+	StartWatchParty(ctx context.Context, in *StartWatchPartyRequest, opts ...grpc.CallOption) (*WatchSync, error)
+	ControlWatchParty(ctx context.Context, in *WatchControlRequest, opts ...grpc.CallOption) (*WatchSync, error)
+	GetWatchParty(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*WatchSync, error)
+	StopWatchParty(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error)
+	// Scheduled room occurrences over the next days (default 7, at most 90)
+	ListUpcomingRooms(ctx context.Context, in *UpcomingRoomsRequest, opts ...grpc.CallOption) (*UpcomingRoomsResponse, error)
+	// Streams room events as they happen; an empty room_name streams all rooms
+	StreamRoomEvents(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (RTCService_StreamRoomEventsClient, error)
+}
+
+type rTCServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRTCServiceClient(cc grpc.ClientConnInterface) RTCServiceClient {
+	return &rTCServiceClient{cc}
+}
+
+func (c *rTCServiceClient) CreateRoom(ctx context.Context, in *CreateRoomRequest, opts ...grpc.CallOption) (*Room, error) {
+	out := new(Room)
+	err := c.cc.Invoke(ctx, RTCService_CreateRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) GetRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*Room, error) {
+	out := new(Room)
+	err := c.cc.Invoke(ctx, RTCService_GetRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) DeleteRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_DeleteRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) JoinRoom(ctx context.Context, in *JoinRoomRequest, opts ...grpc.CallOption) (*JoinToken, error) {
+	out := new(JoinToken)
+	err := c.cc.Invoke(ctx, RTCService_JoinRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) LeaveRoom(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_LeaveRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) ListParticipants(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*ListParticipantsResponse, error) {
+	out := new(ListParticipantsResponse)
+	err := c.cc.Invoke(ctx, RTCService_ListParticipants_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) PromoteParticipant(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*RoleChange, error) {
+	out := new(RoleChange)
+	err := c.cc.Invoke(ctx, RTCService_PromoteParticipant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) DemoteParticipant(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*RoleChange, error) {
+	out := new(RoleChange)
+	err := c.cc.Invoke(ctx, RTCService_DemoteParticipant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) RaiseHand(ctx context.Context, in *RaiseHandRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_RaiseHand_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) LowerHand(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_LowerHand_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) GetRaisedHands(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*RaisedHandsResponse, error) {
+	out := new(RaisedHandsResponse)
+	err := c.cc.Invoke(ctx, RTCService_GetRaisedHands_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) AcknowledgeHand(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_AcknowledgeHand_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) NextSpeaker(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*RaisedHand, error) {
+	out := new(RaisedHand)
+	err := c.cc.Invoke(ctx, RTCService_NextSpeaker_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) SetHandExpiry(ctx context.Context, in *HandExpiryRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_SetHandExpiry_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) MuteParticipant(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_MuteParticipant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) UnmuteParticipant(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_UnmuteParticipant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) MuteAll(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_MuteAll_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) RevokeMedia(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_RevokeMedia_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) RestoreMedia(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_RestoreMedia_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) KickParticipant(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_KickParticipant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) BanParticipant(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*Ban, error) {
+	out := new(Ban)
+	err := c.cc.Invoke(ctx, RTCService_BanParticipant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) ListBans(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*ListBansResponse, error) {
+	out := new(ListBansResponse)
+	err := c.cc.Invoke(ctx, RTCService_ListBans_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) LiftBan(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_LiftBan_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) AdmitParticipant(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*RoleChange, error) {
+	out := new(RoleChange)
+	err := c.cc.Invoke(ctx, RTCService_AdmitParticipant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) RevokeTokens(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*RevokeTokensResponse, error) {
+	out := new(RevokeTokensResponse)
+	err := c.cc.Invoke(ctx, RTCService_RevokeTokens_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) LockRoom(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_LockRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) UnlockRoom(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_UnlockRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) SetMaxParticipants(ctx context.Context, in *MaxParticipantsRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_SetMaxParticipants_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) SetAudioSettings(ctx context.Context, in *AudioSettingsRequest, opts ...grpc.CallOption) (*AudioSettings, error) {
+	out := new(AudioSettings)
+	err := c.cc.Invoke(ctx, RTCService_SetAudioSettings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) SetScreenShare(ctx context.Context, in *ScreenShareRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_SetScreenShare_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StopScreenShare(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_StopScreenShare_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) CreateInvite(ctx context.Context, in *CreateInviteRequest, opts ...grpc.CallOption) (*Invite, error) {
+	out := new(Invite)
+	err := c.cc.Invoke(ctx, RTCService_CreateInvite_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) RedeemInvite(ctx context.Context, in *RedeemInviteRequest, opts ...grpc.CallOption) (*JoinToken, error) {
+	out := new(JoinToken)
+	err := c.cc.Invoke(ctx, RTCService_RedeemInvite_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) GetDialIn(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*DialIn, error) {
+	out := new(DialIn)
+	err := c.cc.Invoke(ctx, RTCService_GetDialIn_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) EnableDialIn(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*DialIn, error) {
+	out := new(DialIn)
+	err := c.cc.Invoke(ctx, RTCService_EnableDialIn_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) DisableDialIn(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_DisableDialIn_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StartRecording(ctx context.Context, in *StartRecordingRequest, opts ...grpc.CallOption) (*Recording, error) {
+	out := new(Recording)
+	err := c.cc.Invoke(ctx, RTCService_StartRecording_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StopRecording(ctx context.Context, in *StopRecordingRequest, opts ...grpc.CallOption) (*Recording, error) {
+	out := new(Recording)
+	err := c.cc.Invoke(ctx, RTCService_StopRecording_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) ListRecordings(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*ListRecordingsResponse, error) {
+	out := new(ListRecordingsResponse)
+	err := c.cc.Invoke(ctx, RTCService_ListRecordings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StartTranscription(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_StartTranscription_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StopTranscription(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_StopTranscription_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) GetTranscript(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*Transcript, error) {
+	out := new(Transcript)
+	err := c.cc.Invoke(ctx, RTCService_GetTranscript_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StartBroadcast(ctx context.Context, in *StartBroadcastRequest, opts ...grpc.CallOption) (*Broadcast, error) {
+	out := new(Broadcast)
+	err := c.cc.Invoke(ctx, RTCService_StartBroadcast_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StopBroadcast(ctx context.Context, in *StopBroadcastRequest, opts ...grpc.CallOption) (*Broadcast, error) {
+	out := new(Broadcast)
+	err := c.cc.Invoke(ctx, RTCService_StopBroadcast_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) ListBroadcasts(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*ListBroadcastsResponse, error) {
+	out := new(ListBroadcastsResponse)
+	err := c.cc.Invoke(ctx, RTCService_ListBroadcasts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) CreateBreakouts(ctx context.Context, in *CreateBreakoutsRequest, opts ...grpc.CallOption) (*Breakouts, error) {
+	out := new(Breakouts)
+	err := c.cc.Invoke(ctx, RTCService_CreateBreakouts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) GetBreakouts(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*Breakouts, error) {
+	out := new(Breakouts)
+	err := c.cc.Invoke(ctx, RTCService_GetBreakouts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) AssignBreakouts(ctx context.Context, in *AssignBreakoutsRequest, opts ...grpc.CallOption) (*BreakoutMovesResponse, error) {
+	out := new(BreakoutMovesResponse)
+	err := c.cc.Invoke(ctx, RTCService_AssignBreakouts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) AutoAssignBreakouts(ctx context.Context, in *AutoAssignBreakoutsRequest, opts ...grpc.CallOption) (*BreakoutMovesResponse, error) {
+	out := new(BreakoutMovesResponse)
+	err := c.cc.Invoke(ctx, RTCService_AutoAssignBreakouts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) BroadcastToBreakouts(ctx context.Context, in *BreakoutMessageRequest, opts ...grpc.CallOption) (*BreakoutMessageResponse, error) {
+	out := new(BreakoutMessageResponse)
+	err := c.cc.Invoke(ctx, RTCService_BroadcastToBreakouts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) ReturnFromBreakouts(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*BreakoutMovesResponse, error) {
+	out := new(BreakoutMovesResponse)
+	err := c.cc.Invoke(ctx, RTCService_ReturnFromBreakouts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) CloseBreakouts(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_CloseBreakouts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) PostChatMessage(ctx context.Context, in *PostChatMessageRequest, opts ...grpc.CallOption) (*ChatMessage, error) {
+	out := new(ChatMessage)
+	err := c.cc.Invoke(ctx, RTCService_PostChatMessage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) ListChatMessages(ctx context.Context, in *ListChatMessagesRequest, opts ...grpc.CallOption) (*ListChatMessagesResponse, error) {
+	out := new(ListChatMessagesResponse)
+	err := c.cc.Invoke(ctx, RTCService_ListChatMessages_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) DeleteChatMessage(ctx context.Context, in *DeleteChatMessageRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_DeleteChatMessage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StartWatchParty(ctx context.Context, in *StartWatchPartyRequest, opts ...grpc.CallOption) (*WatchSync, error) {
+	out := new(WatchSync)
+	err := c.cc.Invoke(ctx, RTCService_StartWatchParty_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) ControlWatchParty(ctx context.Context, in *WatchControlRequest, opts ...grpc.CallOption) (*WatchSync, error) {
+	out := new(WatchSync)
+	err := c.cc.Invoke(ctx, RTCService_ControlWatchParty_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) GetWatchParty(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*WatchSync, error) {
+	out := new(WatchSync)
+	err := c.cc.Invoke(ctx, RTCService_GetWatchParty_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StopWatchParty(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	err := c.cc.Invoke(ctx, RTCService_StopWatchParty_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) ListUpcomingRooms(ctx context.Context, in *UpcomingRoomsRequest, opts ...grpc.CallOption) (*UpcomingRoomsResponse, error) {
+	out := new(UpcomingRoomsResponse)
+	err := c.cc.Invoke(ctx, RTCService_ListUpcomingRooms_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rTCServiceClient) StreamRoomEvents(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (RTCService_StreamRoomEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RTCService_ServiceDesc.Streams[0], RTCService_StreamRoomEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rTCServiceStreamRoomEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RTCService_StreamRoomEventsClient interface {
+	Recv() (*RoomEvent, error)
+	grpc.ClientStream
+}
+
+type rTCServiceStreamRoomEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *rTCServiceStreamRoomEventsClient) Recv() (*RoomEvent, error) {
+	m := new(RoomEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RTCServiceServer is the server API for RTCService service.
+// All implementations must embed UnimplementedRTCServiceServer
+// for forward compatibility
+type RTCServiceServer interface {
+	// Rooms
+	CreateRoom(context.Context, *CreateRoomRequest) (*Room, error)
+	GetRoom(context.Context, *RoomRequest) (*Room, error)
+	DeleteRoom(context.Context, *RoomRequest) (*SuccessResponse, error)
+	// Participants
+	JoinRoom(context.Context, *JoinRoomRequest) (*JoinToken, error)
+	LeaveRoom(context.Context, *UserRequest) (*SuccessResponse, error)
+	ListParticipants(context.Context, *RoomRequest) (*ListParticipantsResponse, error)
+	// An empty role moves one step along viewer, speaker, moderator
+	PromoteParticipant(context.Context, *RoleRequest) (*RoleChange, error)
+	DemoteParticipant(context.Context, *RoleRequest) (*RoleChange, error)
+	// Raised hands
+	RaiseHand(context.Context, *RaiseHandRequest) (*SuccessResponse, error)
+	LowerHand(context.Context, *UserRequest) (*SuccessResponse, error)
+	GetRaisedHands(context.Context, *RoomRequest) (*RaisedHandsResponse, error)
+	AcknowledgeHand(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Takes the first hand off the queue and unmutes its owner
+	NextSpeaker(context.Context, *ModerationRequest) (*RaisedHand, error)
+	// Lowers unacknowledged hands after minutes; 0 keeps them up
+	SetHandExpiry(context.Context, *HandExpiryRequest) (*SuccessResponse, error)
+	// Moderation
+	MuteParticipant(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	UnmuteParticipant(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	MuteAll(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Stops the user publishing their camera and microphone, unlike a mute
+	// they cannot undo themselves
+	RevokeMedia(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	RestoreMedia(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	KickParticipant(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Kicks the user and keeps them out until the ban expires or is lifted
+	BanParticipant(context.Context, *BanRequest) (*Ban, error)
+	ListBans(context.Context, *RoomRequest) (*ListBansResponse, error)
+	LiftBan(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Lets a user waiting in the room's lobby in with its default role
+	AdmitParticipant(context.Context, *ModerationRequest) (*RoleChange, error)
+	// Revokes the user's join tokens for the room and removes them from it
+	RevokeTokens(context.Context, *ModerationRequest) (*RevokeTokensResponse, error)
+	LockRoom(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	UnlockRoom(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Those already in the room stay if there are more of them than the limit
+	SetMaxParticipants(context.Context, *MaxParticipantsRequest) (*SuccessResponse, error)
+	// Changes the audio settings the request sets, returning the room's
+	SetAudioSettings(context.Context, *AudioSettingsRequest) (*AudioSettings, error)
+	// screen_share is hosts, speakers or everyone
+	SetScreenShare(context.Context, *ScreenShareRequest) (*SuccessResponse, error)
+	StopScreenShare(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Single-use guest invites; redeeming one returns a token for a new guest,
+	// or for user_id when set
+	CreateInvite(context.Context, *CreateInviteRequest) (*Invite, error)
+	RedeemInvite(context.Context, *RedeemInviteRequest) (*JoinToken, error)
+	// Phone dial-in; enabling it again gives the room a new PIN
+	GetDialIn(context.Context, *RoomRequest) (*DialIn, error)
+	EnableDialIn(context.Context, *ModerationRequest) (*DialIn, error)
+	DisableDialIn(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Recordings; a track_id records only that track
+	StartRecording(context.Context, *StartRecordingRequest) (*Recording, error)
+	StopRecording(context.Context, *StopRecordingRequest) (*Recording, error)
+	ListRecordings(context.Context, *RoomRequest) (*ListRecordingsResponse, error)
+	// Live captions; the transcript is kept after the call
+	StartTranscription(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	StopTranscription(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	GetTranscript(context.Context, *RoomRequest) (*Transcript, error)
+	// RTMP broadcasts to the community's stream destinations
+	StartBroadcast(context.Context, *StartBroadcastRequest) (*Broadcast, error)
+	StopBroadcast(context.Context, *StopBroadcastRequest) (*Broadcast, error)
+	ListBroadcasts(context.Context, *RoomRequest) (*ListBroadcastsResponse, error)
+	// Breakout rooms; participants are sent tokens for their new room
+	CreateBreakouts(context.Context, *CreateBreakoutsRequest) (*Breakouts, error)
+	GetBreakouts(context.Context, *RoomRequest) (*Breakouts, error)
+	AssignBreakouts(context.Context, *AssignBreakoutsRequest) (*BreakoutMovesResponse, error)
+	AutoAssignBreakouts(context.Context, *AutoAssignBreakoutsRequest) (*BreakoutMovesResponse, error)
+	BroadcastToBreakouts(context.Context, *BreakoutMessageRequest) (*BreakoutMessageResponse, error)
+	ReturnFromBreakouts(context.Context, *ModerationRequest) (*BreakoutMovesResponse, error)
+	CloseBreakouts(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Room chat; callers post as any user and are not held to slow mode
+	PostChatMessage(context.Context, *PostChatMessageRequest) (*ChatMessage, error)
+	ListChatMessages(context.Context, *ListChatMessagesRequest) (*ListChatMessagesResponse, error)
+	DeleteChatMessage(context.Context, *DeleteChatMessageRequest) (*SuccessResponse, error)
+	// Watch parties; participants are kept in sync on the waddlebot.watch
+	// data topic, and action is play, pause or seek
+	StartWatchParty(context.Context, *StartWatchPartyRequest) (*WatchSync, error)
+	ControlWatchParty(context.Context, *WatchControlRequest) (*WatchSync, error)
+	GetWatchParty(context.Context, *RoomRequest) (*WatchSync, error)
+	StopWatchParty(context.Context, *ModerationRequest) (*SuccessResponse, error)
+	// Scheduled room occurrences over the next days (default 7, at most 90)
+	ListUpcomingRooms(context.Context, *UpcomingRoomsRequest) (*UpcomingRoomsResponse, error)
+	// Streams room events as they happen; an empty room_name streams all rooms
+	StreamRoomEvents(*RoomRequest, RTCService_StreamRoomEventsServer) error
+	mustEmbedUnimplementedRTCServiceServer()
+}
+
+// UnimplementedRTCServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedRTCServiceServer struct {
+}
+
+func (UnimplementedRTCServiceServer) CreateRoom(context.Context, *CreateRoomRequest) (*Room, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRoom not implemented")
+}
+func (UnimplementedRTCServiceServer) GetRoom(context.Context, *RoomRequest) (*Room, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoom not implemented")
+}
+func (UnimplementedRTCServiceServer) DeleteRoom(context.Context, *RoomRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRoom not implemented")
+}
+func (UnimplementedRTCServiceServer) JoinRoom(context.Context, *JoinRoomRequest) (*JoinToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method JoinRoom not implemented")
+}
+func (UnimplementedRTCServiceServer) LeaveRoom(context.Context, *UserRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeaveRoom not implemented")
+}
+func (UnimplementedRTCServiceServer) ListParticipants(context.Context, *RoomRequest) (*ListParticipantsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListParticipants not implemented")
+}
+func (UnimplementedRTCServiceServer) PromoteParticipant(context.Context, *RoleRequest) (*RoleChange, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PromoteParticipant not implemented")
+}
+func (UnimplementedRTCServiceServer) DemoteParticipant(context.Context, *RoleRequest) (*RoleChange, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DemoteParticipant not implemented")
+}
+func (UnimplementedRTCServiceServer) RaiseHand(context.Context, *RaiseHandRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RaiseHand not implemented")
+}
+func (UnimplementedRTCServiceServer) LowerHand(context.Context, *UserRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LowerHand not implemented")
+}
+func (UnimplementedRTCServiceServer) GetRaisedHands(context.Context, *RoomRequest) (*RaisedHandsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRaisedHands not implemented")
+}
+func (UnimplementedRTCServiceServer) AcknowledgeHand(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcknowledgeHand not implemented")
+}
+func (UnimplementedRTCServiceServer) NextSpeaker(context.Context, *ModerationRequest) (*RaisedHand, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NextSpeaker not implemented")
+}
+func (UnimplementedRTCServiceServer) SetHandExpiry(context.Context, *HandExpiryRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetHandExpiry not implemented")
+}
+func (UnimplementedRTCServiceServer) MuteParticipant(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MuteParticipant not implemented")
+}
+func (UnimplementedRTCServiceServer) UnmuteParticipant(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnmuteParticipant not implemented")
+}
+func (UnimplementedRTCServiceServer) MuteAll(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MuteAll not implemented")
+}
+func (UnimplementedRTCServiceServer) RevokeMedia(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeMedia not implemented")
+}
+func (UnimplementedRTCServiceServer) RestoreMedia(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreMedia not implemented")
+}
+func (UnimplementedRTCServiceServer) KickParticipant(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KickParticipant not implemented")
+}
+func (UnimplementedRTCServiceServer) BanParticipant(context.Context, *BanRequest) (*Ban, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BanParticipant not implemented")
+}
+func (UnimplementedRTCServiceServer) ListBans(context.Context, *RoomRequest) (*ListBansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBans not implemented")
+}
+func (UnimplementedRTCServiceServer) LiftBan(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LiftBan not implemented")
+}
+func (UnimplementedRTCServiceServer) AdmitParticipant(context.Context, *ModerationRequest) (*RoleChange, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdmitParticipant not implemented")
+}
+func (UnimplementedRTCServiceServer) RevokeTokens(context.Context, *ModerationRequest) (*RevokeTokensResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeTokens not implemented")
+}
+func (UnimplementedRTCServiceServer) LockRoom(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LockRoom not implemented")
+}
+func (UnimplementedRTCServiceServer) UnlockRoom(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockRoom not implemented")
+}
+func (UnimplementedRTCServiceServer) SetMaxParticipants(context.Context, *MaxParticipantsRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMaxParticipants not implemented")
+}
+func (UnimplementedRTCServiceServer) SetAudioSettings(context.Context, *AudioSettingsRequest) (*AudioSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAudioSettings not implemented")
+}
+func (UnimplementedRTCServiceServer) SetScreenShare(context.Context, *ScreenShareRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetScreenShare not implemented")
+}
+func (UnimplementedRTCServiceServer) StopScreenShare(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopScreenShare not implemented")
+}
+func (UnimplementedRTCServiceServer) CreateInvite(context.Context, *CreateInviteRequest) (*Invite, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateInvite not implemented")
+}
+func (UnimplementedRTCServiceServer) RedeemInvite(context.Context, *RedeemInviteRequest) (*JoinToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RedeemInvite not implemented")
+}
+func (UnimplementedRTCServiceServer) GetDialIn(context.Context, *RoomRequest) (*DialIn, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDialIn not implemented")
+}
+func (UnimplementedRTCServiceServer) EnableDialIn(context.Context, *ModerationRequest) (*DialIn, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnableDialIn not implemented")
+}
+func (UnimplementedRTCServiceServer) DisableDialIn(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableDialIn not implemented")
+}
+func (UnimplementedRTCServiceServer) StartRecording(context.Context, *StartRecordingRequest) (*Recording, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartRecording not implemented")
+}
+func (UnimplementedRTCServiceServer) StopRecording(context.Context, *StopRecordingRequest) (*Recording, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopRecording not implemented")
+}
+func (UnimplementedRTCServiceServer) ListRecordings(context.Context, *RoomRequest) (*ListRecordingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRecordings not implemented")
+}
+func (UnimplementedRTCServiceServer) StartTranscription(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartTranscription not implemented")
+}
+func (UnimplementedRTCServiceServer) StopTranscription(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopTranscription not implemented")
+}
+func (UnimplementedRTCServiceServer) GetTranscript(context.Context, *RoomRequest) (*Transcript, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTranscript not implemented")
+}
+func (UnimplementedRTCServiceServer) StartBroadcast(context.Context, *StartBroadcastRequest) (*Broadcast, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartBroadcast not implemented")
+}
+func (UnimplementedRTCServiceServer) StopBroadcast(context.Context, *StopBroadcastRequest) (*Broadcast, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopBroadcast not implemented")
+}
+func (UnimplementedRTCServiceServer) ListBroadcasts(context.Context, *RoomRequest) (*ListBroadcastsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBroadcasts not implemented")
+}
+func (UnimplementedRTCServiceServer) CreateBreakouts(context.Context, *CreateBreakoutsRequest) (*Breakouts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBreakouts not implemented")
+}
+func (UnimplementedRTCServiceServer) GetBreakouts(context.Context, *RoomRequest) (*Breakouts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBreakouts not implemented")
+}
+func (UnimplementedRTCServiceServer) AssignBreakouts(context.Context, *AssignBreakoutsRequest) (*BreakoutMovesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AssignBreakouts not implemented")
+}
+func (UnimplementedRTCServiceServer) AutoAssignBreakouts(context.Context, *AutoAssignBreakoutsRequest) (*BreakoutMovesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AutoAssignBreakouts not implemented")
+}
+func (UnimplementedRTCServiceServer) BroadcastToBreakouts(context.Context, *BreakoutMessageRequest) (*BreakoutMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BroadcastToBreakouts not implemented")
+}
+func (UnimplementedRTCServiceServer) ReturnFromBreakouts(context.Context, *ModerationRequest) (*BreakoutMovesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReturnFromBreakouts not implemented")
+}
+func (UnimplementedRTCServiceServer) CloseBreakouts(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseBreakouts not implemented")
+}
+func (UnimplementedRTCServiceServer) PostChatMessage(context.Context, *PostChatMessageRequest) (*ChatMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostChatMessage not implemented")
+}
+func (UnimplementedRTCServiceServer) ListChatMessages(context.Context, *ListChatMessagesRequest) (*ListChatMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChatMessages not implemented")
+}
+func (UnimplementedRTCServiceServer) DeleteChatMessage(context.Context, *DeleteChatMessageRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteChatMessage not implemented")
+}
+func (UnimplementedRTCServiceServer) StartWatchParty(context.Context, *StartWatchPartyRequest) (*WatchSync, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartWatchParty not implemented")
+}
+func (UnimplementedRTCServiceServer) ControlWatchParty(context.Context, *WatchControlRequest) (*WatchSync, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ControlWatchParty not implemented")
+}
+func (UnimplementedRTCServiceServer) GetWatchParty(context.Context, *RoomRequest) (*WatchSync, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWatchParty not implemented")
+}
+func (UnimplementedRTCServiceServer) StopWatchParty(context.Context, *ModerationRequest) (*SuccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopWatchParty not implemented")
+}
+func (UnimplementedRTCServiceServer) ListUpcomingRooms(context.Context, *UpcomingRoomsRequest) (*UpcomingRoomsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUpcomingRooms not implemented")
+}
+func (UnimplementedRTCServiceServer) StreamRoomEvents(*RoomRequest, RTCService_StreamRoomEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRoomEvents not implemented")
+}
+func (UnimplementedRTCServiceServer) mustEmbedUnimplementedRTCServiceServer() {}
+
+// UnsafeRTCServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RTCServiceServer will
+// result in compilation errors.
+type UnsafeRTCServiceServer interface {
+	mustEmbedUnimplementedRTCServiceServer()
+}
+
+func RegisterRTCServiceServer(s grpc.ServiceRegistrar, srv RTCServiceServer) {
+	s.RegisterService(&RTCService_ServiceDesc, srv)
+}
+
+func _RTCService_CreateRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).CreateRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_CreateRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).CreateRoom(ctx, req.(*CreateRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_GetRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).GetRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_GetRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).GetRoom(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_DeleteRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).DeleteRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_DeleteRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).DeleteRoom(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_JoinRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).JoinRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_JoinRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).JoinRoom(ctx, req.(*JoinRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_LeaveRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).LeaveRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_LeaveRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).LeaveRoom(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_ListParticipants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).ListParticipants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_ListParticipants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).ListParticipants(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_PromoteParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).PromoteParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_PromoteParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).PromoteParticipant(ctx, req.(*RoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_DemoteParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).DemoteParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_DemoteParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).DemoteParticipant(ctx, req.(*RoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_RaiseHand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaiseHandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).RaiseHand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_RaiseHand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).RaiseHand(ctx, req.(*RaiseHandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_LowerHand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).LowerHand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_LowerHand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).LowerHand(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_GetRaisedHands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).GetRaisedHands(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_GetRaisedHands_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).GetRaisedHands(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_AcknowledgeHand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).AcknowledgeHand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_AcknowledgeHand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).AcknowledgeHand(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_NextSpeaker_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).NextSpeaker(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_NextSpeaker_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).NextSpeaker(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_SetHandExpiry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandExpiryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).SetHandExpiry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_SetHandExpiry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).SetHandExpiry(ctx, req.(*HandExpiryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_MuteParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).MuteParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_MuteParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).MuteParticipant(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_UnmuteParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).UnmuteParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_UnmuteParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).UnmuteParticipant(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_MuteAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).MuteAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_MuteAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).MuteAll(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_RevokeMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).RevokeMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_RevokeMedia_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).RevokeMedia(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_RestoreMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).RestoreMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_RestoreMedia_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).RestoreMedia(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_KickParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).KickParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_KickParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).KickParticipant(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_BanParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).BanParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_BanParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).BanParticipant(ctx, req.(*BanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_ListBans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).ListBans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_ListBans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).ListBans(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_LiftBan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).LiftBan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_LiftBan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).LiftBan(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_AdmitParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).AdmitParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_AdmitParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).AdmitParticipant(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_RevokeTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).RevokeTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_RevokeTokens_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).RevokeTokens(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_LockRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).LockRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_LockRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).LockRoom(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_UnlockRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).UnlockRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_UnlockRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).UnlockRoom(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_SetMaxParticipants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MaxParticipantsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).SetMaxParticipants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_SetMaxParticipants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).SetMaxParticipants(ctx, req.(*MaxParticipantsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_SetAudioSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AudioSettingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).SetAudioSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_SetAudioSettings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).SetAudioSettings(ctx, req.(*AudioSettingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_SetScreenShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScreenShareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).SetScreenShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_SetScreenShare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).SetScreenShare(ctx, req.(*ScreenShareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StopScreenShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StopScreenShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StopScreenShare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StopScreenShare(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_CreateInvite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateInviteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).CreateInvite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_CreateInvite_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).CreateInvite(ctx, req.(*CreateInviteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_RedeemInvite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeemInviteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).RedeemInvite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_RedeemInvite_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).RedeemInvite(ctx, req.(*RedeemInviteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_GetDialIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).GetDialIn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_GetDialIn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).GetDialIn(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_EnableDialIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).EnableDialIn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_EnableDialIn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).EnableDialIn(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_DisableDialIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).DisableDialIn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_DisableDialIn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).DisableDialIn(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StartRecording_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartRecordingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StartRecording(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StartRecording_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StartRecording(ctx, req.(*StartRecordingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StopRecording_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopRecordingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StopRecording(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StopRecording_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StopRecording(ctx, req.(*StopRecordingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_ListRecordings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).ListRecordings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_ListRecordings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).ListRecordings(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StartTranscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StartTranscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StartTranscription_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StartTranscription(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StopTranscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StopTranscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StopTranscription_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StopTranscription(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_GetTranscript_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).GetTranscript(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_GetTranscript_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).GetTranscript(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StartBroadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartBroadcastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StartBroadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StartBroadcast_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StartBroadcast(ctx, req.(*StartBroadcastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StopBroadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopBroadcastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StopBroadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StopBroadcast_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StopBroadcast(ctx, req.(*StopBroadcastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_ListBroadcasts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).ListBroadcasts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_ListBroadcasts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).ListBroadcasts(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_CreateBreakouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBreakoutsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).CreateBreakouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_CreateBreakouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).CreateBreakouts(ctx, req.(*CreateBreakoutsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_GetBreakouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).GetBreakouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_GetBreakouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).GetBreakouts(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_AssignBreakouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssignBreakoutsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).AssignBreakouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_AssignBreakouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).AssignBreakouts(ctx, req.(*AssignBreakoutsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_AutoAssignBreakouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AutoAssignBreakoutsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).AutoAssignBreakouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_AutoAssignBreakouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).AutoAssignBreakouts(ctx, req.(*AutoAssignBreakoutsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_BroadcastToBreakouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BreakoutMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).BroadcastToBreakouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_BroadcastToBreakouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).BroadcastToBreakouts(ctx, req.(*BreakoutMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_ReturnFromBreakouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).ReturnFromBreakouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_ReturnFromBreakouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).ReturnFromBreakouts(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_CloseBreakouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).CloseBreakouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_CloseBreakouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).CloseBreakouts(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_PostChatMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostChatMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).PostChatMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_PostChatMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).PostChatMessage(ctx, req.(*PostChatMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_ListChatMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListChatMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).ListChatMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_ListChatMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).ListChatMessages(ctx, req.(*ListChatMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_DeleteChatMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteChatMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).DeleteChatMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_DeleteChatMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).DeleteChatMessage(ctx, req.(*DeleteChatMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StartWatchParty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartWatchPartyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StartWatchParty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StartWatchParty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StartWatchParty(ctx, req.(*StartWatchPartyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_ControlWatchParty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WatchControlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).ControlWatchParty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_ControlWatchParty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).ControlWatchParty(ctx, req.(*WatchControlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_GetWatchParty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).GetWatchParty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_GetWatchParty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).GetWatchParty(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StopWatchParty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).StopWatchParty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_StopWatchParty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).StopWatchParty(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_ListUpcomingRooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpcomingRoomsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RTCServiceServer).ListUpcomingRooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RTCService_ListUpcomingRooms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RTCServiceServer).ListUpcomingRooms(ctx, req.(*UpcomingRoomsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RTCService_StreamRoomEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RoomRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RTCServiceServer).StreamRoomEvents(m, &rTCServiceStreamRoomEventsServer{stream})
+}
+
+type RTCService_StreamRoomEventsServer interface {
+	Send(*RoomEvent) error
+	grpc.ServerStream
+}
+
+type rTCServiceStreamRoomEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *rTCServiceStreamRoomEventsServer) Send(m *RoomEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// RTCService_ServiceDesc is the grpc.ServiceDesc for RTCService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RTCService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "waddlebot.rtc.RTCService",
+	HandlerType: (*RTCServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateRoom",
+			Handler:    _RTCService_CreateRoom_Handler,
+		},
+		{
+			MethodName: "GetRoom",
+			Handler:    _RTCService_GetRoom_Handler,
+		},
+		{
+			MethodName: "DeleteRoom",
+			Handler:    _RTCService_DeleteRoom_Handler,
+		},
+		{
+			MethodName: "JoinRoom",
+			Handler:    _RTCService_JoinRoom_Handler,
+		},
+		{
+			MethodName: "LeaveRoom",
+			Handler:    _RTCService_LeaveRoom_Handler,
+		},
+		{
+			MethodName: "ListParticipants",
+			Handler:    _RTCService_ListParticipants_Handler,
+		},
+		{
+			MethodName: "PromoteParticipant",
+			Handler:    _RTCService_PromoteParticipant_Handler,
+		},
+		{
+			MethodName: "DemoteParticipant",
+			Handler:    _RTCService_DemoteParticipant_Handler,
+		},
+		{
+			MethodName: "RaiseHand",
+			Handler:    _RTCService_RaiseHand_Handler,
+		},
+		{
+			MethodName: "LowerHand",
+			Handler:    _RTCService_LowerHand_Handler,
+		},
+		{
+			MethodName: "GetRaisedHands",
+			Handler:    _RTCService_GetRaisedHands_Handler,
+		},
+		{
+			MethodName: "AcknowledgeHand",
+			Handler:    _RTCService_AcknowledgeHand_Handler,
+		},
+		{
+			MethodName: "NextSpeaker",
+			Handler:    _RTCService_NextSpeaker_Handler,
+		},
+		{
+			MethodName: "SetHandExpiry",
+			Handler:    _RTCService_SetHandExpiry_Handler,
+		},
+		{
+			MethodName: "MuteParticipant",
+			Handler:    _RTCService_MuteParticipant_Handler,
+		},
+		{
+			MethodName: "UnmuteParticipant",
+			Handler:    _RTCService_UnmuteParticipant_Handler,
+		},
+		{
+			MethodName: "MuteAll",
+			Handler:    _RTCService_MuteAll_Handler,
+		},
+		{
+			MethodName: "RevokeMedia",
+			Handler:    _RTCService_RevokeMedia_Handler,
+		},
+		{
+			MethodName: "RestoreMedia",
+			Handler:    _RTCService_RestoreMedia_Handler,
+		},
+		{
+			MethodName: "KickParticipant",
+			Handler:    _RTCService_KickParticipant_Handler,
+		},
+		{
+			MethodName: "BanParticipant",
+			Handler:    _RTCService_BanParticipant_Handler,
+		},
+		{
+			MethodName: "ListBans",
+			Handler:    _RTCService_ListBans_Handler,
+		},
+		{
+			MethodName: "LiftBan",
+			Handler:    _RTCService_LiftBan_Handler,
+		},
+		{
+			MethodName: "AdmitParticipant",
+			Handler:    _RTCService_AdmitParticipant_Handler,
+		},
+		{
+			MethodName: "RevokeTokens",
+			Handler:    _RTCService_RevokeTokens_Handler,
+		},
+		{
+			MethodName: "LockRoom",
+			Handler:    _RTCService_LockRoom_Handler,
+		},
+		{
+			MethodName: "UnlockRoom",
+			Handler:    _RTCService_UnlockRoom_Handler,
+		},
+		{
+			MethodName: "SetMaxParticipants",
+			Handler:    _RTCService_SetMaxParticipants_Handler,
+		},
+		{
+			MethodName: "SetAudioSettings",
+			Handler:    _RTCService_SetAudioSettings_Handler,
+		},
+		{
+			MethodName: "SetScreenShare",
+			Handler:    _RTCService_SetScreenShare_Handler,
+		},
+		{
+			MethodName: "StopScreenShare",
+			Handler:    _RTCService_StopScreenShare_Handler,
+		},
+		{
+			MethodName: "CreateInvite",
+			Handler:    _RTCService_CreateInvite_Handler,
+		},
+		{
+			MethodName: "RedeemInvite",
+			Handler:    _RTCService_RedeemInvite_Handler,
+		},
+		{
+			MethodName: "GetDialIn",
+			Handler:    _RTCService_GetDialIn_Handler,
+		},
+		{
+			MethodName: "EnableDialIn",
+			Handler:    _RTCService_EnableDialIn_Handler,
+		},
+		{
+			MethodName: "DisableDialIn",
+			Handler:    _RTCService_DisableDialIn_Handler,
+		},
+		{
+			MethodName: "StartRecording",
+			Handler:    _RTCService_StartRecording_Handler,
+		},
+		{
+			MethodName: "StopRecording",
+			Handler:    _RTCService_StopRecording_Handler,
+		},
+		{
+			MethodName: "ListRecordings",
+			Handler:    _RTCService_ListRecordings_Handler,
+		},
+		{
+			MethodName: "StartTranscription",
+			Handler:    _RTCService_StartTranscription_Handler,
+		},
+		{
+			MethodName: "StopTranscription",
+			Handler:    _RTCService_StopTranscription_Handler,
+		},
+		{
+			MethodName: "GetTranscript",
+			Handler:    _RTCService_GetTranscript_Handler,
+		},
+		{
+			MethodName: "StartBroadcast",
+			Handler:    _RTCService_StartBroadcast_Handler,
+		},
+		{
+			MethodName: "StopBroadcast",
+			Handler:    _RTCService_StopBroadcast_Handler,
+		},
+		{
+			MethodName: "ListBroadcasts",
+			Handler:    _RTCService_ListBroadcasts_Handler,
+		},
+		{
+			MethodName: "CreateBreakouts",
+			Handler:    _RTCService_CreateBreakouts_Handler,
+		},
+		{
+			MethodName: "GetBreakouts",
+			Handler:    _RTCService_GetBreakouts_Handler,
+		},
+		{
+			MethodName: "AssignBreakouts",
+			Handler:    _RTCService_AssignBreakouts_Handler,
+		},
+		{
+			MethodName: "AutoAssignBreakouts",
+			Handler:    _RTCService_AutoAssignBreakouts_Handler,
+		},
+		{
+			MethodName: "BroadcastToBreakouts",
+			Handler:    _RTCService_BroadcastToBreakouts_Handler,
+		},
+		{
+			MethodName: "ReturnFromBreakouts",
+			Handler:    _RTCService_ReturnFromBreakouts_Handler,
+		},
+		{
+			MethodName: "CloseBreakouts",
+			Handler:    _RTCService_CloseBreakouts_Handler,
+		},
+		{
+			MethodName: "PostChatMessage",
+			Handler:    _RTCService_PostChatMessage_Handler,
+		},
+		{
+			MethodName: "ListChatMessages",
+			Handler:    _RTCService_ListChatMessages_Handler,
+		},
+		{
+			MethodName: "DeleteChatMessage",
+			Handler:    _RTCService_DeleteChatMessage_Handler,
+		},
+		{
+			MethodName: "StartWatchParty",
+			Handler:    _RTCService_StartWatchParty_Handler,
+		},
+		{
+			MethodName: "ControlWatchParty",
+			Handler:    _RTCService_ControlWatchParty_Handler,
+		},
+		{
+			MethodName: "GetWatchParty",
+			Handler:    _RTCService_GetWatchParty_Handler,
+		},
+		{
+			MethodName: "StopWatchParty",
+			Handler:    _RTCService_StopWatchParty_Handler,
+		},
+		{
+			MethodName: "ListUpcomingRooms",
+			Handler:    _RTCService_ListUpcomingRooms_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamRoomEvents",
+			Handler:       _RTCService_StreamRoomEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rtc.proto",
+}